@@ -0,0 +1,59 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+)
+
+// disabledBindAddress is the bind address that disables the metrics or health probe endpoint.
+const disabledBindAddress = "0"
+
+// errInvalidBindAddress is used to denote that a bind address is not a host and port.
+var errInvalidBindAddress = errors.New("invalid bind address")
+
+// parseBindAddress splits a bind address into its host and port.
+// The host may be empty, to bind to every address of the pod, both IPv4 and IPv6. IPv6 addresses must be
+// enclosed in square brackets, for example `[::]:9443` or `[fd00::1]:9443`, as the port could not otherwise be
+// told apart from the address.
+func parseBindAddress(address string) (string, int, error) {
+	host, portString, err := net.SplitHostPort(address)
+	if err != nil {
+		return "", 0, fmt.Errorf("%w %q: expected host:port, with IPv6 addresses enclosed in square brackets: %s", errInvalidBindAddress, address, err.Error())
+	}
+
+	port, err := strconv.Atoi(portString)
+	if err != nil || port < 1 || port > 65535 {
+		return "", 0, fmt.Errorf("%w %q: port %q is not between 1 and 65535", errInvalidBindAddress, address, portString)
+	}
+
+	return host, port, nil
+}
+
+// validateBindAddress checks that the bind address of an endpoint that may be disabled is a valid host and port.
+func validateBindAddress(address string) error {
+	if address == disabledBindAddress {
+		return nil
+	}
+
+	_, _, err := parseBindAddress(address)
+
+	return err
+}
@@ -0,0 +1,147 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+
+	cpmscontroller "github.com/openshift/cluster-control-plane-machine-set-operator/pkg/controllers/controlplanemachineset"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/restorebundle"
+)
+
+const (
+	// exportCommand is the name of the subcommand that exports a restore bundle.
+	exportCommand = "export"
+)
+
+// runExport runs the export subcommand, which writes a restore bundle of the ControlPlaneMachineSet, its Control
+// Plane Machines and their recorded indexes, to be replayed once the cluster has been restored from backup.
+// It returns the exit code of the subcommand.
+func runExport(args []string) int {
+	flags := flag.NewFlagSet(exportCommand, flag.ContinueOnError)
+
+	var (
+		kubeconfig string
+		namespace  string
+		name       string
+		output     string
+	)
+
+	flags.StringVar(&kubeconfig, "kubeconfig", "",
+		"The path to the kubeconfig of the cluster. When not set, the KUBECONFIG environment variable, or the "+
+			"in cluster configuration, is used.")
+	flags.StringVar(&namespace, "namespace", "openshift-machine-api",
+		"The namespace of the control plane machine set to export.")
+	flags.StringVar(&name, "name", cpmscontroller.DefaultControlPlaneMachineSetName,
+		"The name of the control plane machine set to export.")
+	flags.StringVar(&output, "output", "-",
+		"The path of the file to write the restore bundle to. Set to - to write to standard output.")
+
+	if err := flags.Parse(args); errors.Is(err, flag.ErrHelp) {
+		return 0
+	} else if err != nil {
+		return 2
+	}
+
+	if err := exportRestoreBundle(context.Background(), kubeconfig, namespace, name, output); err != nil {
+		fmt.Fprintf(os.Stderr, "error exporting restore bundle: %v\n", err)
+
+		return 1
+	}
+
+	return 0
+}
+
+// exportRestoreBundle exports the restore bundle of the ControlPlaneMachineSet with the given namespace and name, and
+// writes it, as YAML, to the output path.
+func exportRestoreBundle(ctx context.Context, kubeconfig, namespace, name, output string) error {
+	cfg, err := loadConfig(kubeconfig)
+	if err != nil {
+		return err
+	}
+
+	scheme := runtime.NewScheme()
+	if err := setupScheme(scheme); err != nil {
+		return err
+	}
+
+	cl, err := client.New(cfg, client.Options{Scheme: scheme})
+	if err != nil {
+		return fmt.Errorf("unable to create client: %w", err)
+	}
+
+	bundle, err := restorebundle.Export(ctx, cl, namespace, name)
+	if err != nil {
+		return fmt.Errorf("unable to build restore bundle: %w", err)
+	}
+
+	data, err := yaml.Marshal(bundle)
+	if err != nil {
+		return fmt.Errorf("unable to serialize restore bundle: %w", err)
+	}
+
+	var out io.Writer = os.Stdout
+
+	if output != "-" {
+		file, err := os.Create(output)
+		if err != nil {
+			return fmt.Errorf("unable to create output file: %w", err)
+		}
+		defer file.Close()
+
+		out = file
+	}
+
+	if _, err := out.Write(data); err != nil {
+		return fmt.Errorf("unable to write restore bundle: %w", err)
+	}
+
+	return nil
+}
+
+// loadConfig loads the configuration of the cluster from the kubeconfig at the given path, or, when no path is given,
+// as the operator itself does.
+func loadConfig(kubeconfig string) (*rest.Config, error) {
+	var (
+		cfg *rest.Config
+		err error
+	)
+
+	if kubeconfig == "" {
+		cfg, err = ctrl.GetConfig()
+	} else {
+		cfg, err = clientcmd.BuildConfigFromFlags("", kubeconfig)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("unable to load kubeconfig: %w", err)
+	}
+
+	return cfg, nil
+}
@@ -0,0 +1,175 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	machinev1 "github.com/openshift/api/machine/v1"
+	"sigs.k8s.io/yaml"
+
+	cpmscontroller "github.com/openshift/cluster-control-plane-machine-set-operator/pkg/controllers/controlplanemachineset"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/lint"
+	cpmswebhook "github.com/openshift/cluster-control-plane-machine-set-operator/pkg/webhooks/controlplanemachineset"
+)
+
+const (
+	// lintCommand is the name of the subcommand that lints a ControlPlaneMachineSet manifest.
+	lintCommand = "lint"
+
+	// controlPlaneMachineSetKind is the kind of the manifests that can be linted.
+	controlPlaneMachineSetKind = "ControlPlaneMachineSet"
+)
+
+var (
+	// errNotControlPlaneMachineSet is returned when the manifest to lint is not a ControlPlaneMachineSet.
+	errNotControlPlaneMachineSet = errors.New("manifest is not a ControlPlaneMachineSet")
+
+	// errLintFindings is returned when the manifest has findings that should fail the subcommand.
+	errLintFindings = errors.New("manifest has findings")
+)
+
+// runLint runs the lint subcommand, which checks a ControlPlaneMachineSet manifest, without access to a cluster, with
+// the validations of the admission webhook and the best practice rules for its platform.
+// It returns the exit code of the subcommand.
+func runLint(args []string) int {
+	flags := flag.NewFlagSet(lintCommand, flag.ContinueOnError)
+
+	var (
+		file                       string
+		controlPlaneMachineSetName string
+		rejectUndersizedMachines   bool
+		azureDiskSKUCatalogFile    string
+		awsKMSKeyCatalogFile       string
+		strict                     bool
+	)
+
+	flags.StringVar(&file, "file", "-",
+		"The path of the control plane machine set manifest to lint. Set to - to read from standard input.")
+	flags.StringVar(&controlPlaneMachineSetName, "control-plane-machine-set-name", cpmscontroller.DefaultControlPlaneMachineSetName,
+		"The name that the control plane machine set must have, as configured on the operator.")
+	flags.BoolVar(&rejectUndersizedMachines, "reject-undersized-machines", false,
+		"Report templates smaller than the minimum control plane machine size for the platform as errors, as the "+
+			"operator does when the flag of the same name is set.")
+	flags.StringVar(&azureDiskSKUCatalogFile, "azure-disk-sku-catalog-file", "",
+		"The path of a YAML or JSON file listing the zones in which Azure disk SKUs are available, as configured on "+
+			"the operator.")
+	flags.StringVar(&awsKMSKeyCatalogFile, "aws-kms-key-catalog-file", "",
+		"The path of a YAML or JSON file listing the KMS keys within each AWS region, as configured on the operator.")
+	flags.BoolVar(&strict, "strict", false,
+		"Fail when any warning is found, as well as when any error is found.")
+
+	if err := flags.Parse(args); errors.Is(err, flag.ErrHelp) {
+		return 0
+	} else if err != nil {
+		return 2
+	}
+
+	webhook := &cpmswebhook.ControlPlaneMachineSetWebhook{
+		RejectUndersizedMachines:   rejectUndersizedMachines,
+		ControlPlaneMachineSetName: controlPlaneMachineSetName,
+	}
+
+	if azureDiskSKUCatalogFile != "" {
+		catalog, err := cpmswebhook.LoadAzureDiskSKUCatalog(azureDiskSKUCatalogFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error loading Azure disk SKU catalog: %v\n", err)
+
+			return 1
+		}
+
+		webhook.AzureDiskSKUCatalog = catalog
+	}
+
+	if awsKMSKeyCatalogFile != "" {
+		catalog, err := cpmswebhook.LoadAWSKMSKeyCatalog(awsKMSKeyCatalogFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error loading AWS KMS key catalog: %v\n", err)
+
+			return 1
+		}
+
+		webhook.AWSKMSKeyCatalog = catalog
+	}
+
+	if err := lintManifest(context.Background(), webhook, file, strict, os.Stdout); err != nil {
+		fmt.Fprintf(os.Stderr, "error linting control plane machine set: %v\n", err)
+
+		return 1
+	}
+
+	return 0
+}
+
+// lintManifest reads the ControlPlaneMachineSet manifest at the given path, and writes its findings to the output.
+// An error is returned when the manifest cannot be read, or has any error, or, when strict, any warning.
+func lintManifest(ctx context.Context, webhook *cpmswebhook.ControlPlaneMachineSetWebhook, file string, strict bool, out io.Writer) error {
+	cpms, err := readControlPlaneMachineSetManifest(file)
+	if err != nil {
+		return err
+	}
+
+	findings := lint.Lint(ctx, webhook, cpms)
+
+	for _, finding := range findings {
+		if _, err := fmt.Fprintln(out, finding.String()); err != nil {
+			return fmt.Errorf("unable to write findings: %w", err)
+		}
+	}
+
+	if lint.HasErrors(findings) || (strict && len(findings) > 0) {
+		return fmt.Errorf("%w: %d finding(s)", errLintFindings, len(findings))
+	}
+
+	return nil
+}
+
+// readControlPlaneMachineSetManifest reads the ControlPlaneMachineSet manifest at the given path, or from standard
+// input when the path is -.
+// An error is returned when the manifest cannot be read or parsed, or is not a ControlPlaneMachineSet.
+func readControlPlaneMachineSetManifest(file string) (*machinev1.ControlPlaneMachineSet, error) {
+	var (
+		data []byte
+		err  error
+	)
+
+	if file == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(file)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("unable to read manifest: %w", err)
+	}
+
+	cpms := &machinev1.ControlPlaneMachineSet{}
+	if err := yaml.Unmarshal(data, cpms); err != nil {
+		return nil, fmt.Errorf("unable to parse manifest: %w", err)
+	}
+
+	if cpms.Kind != controlPlaneMachineSetKind {
+		return nil, fmt.Errorf("%w: found kind %q", errNotControlPlaneMachineSet, cpms.Kind)
+	}
+
+	return cpms, nil
+}
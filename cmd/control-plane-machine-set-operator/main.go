@@ -20,11 +20,13 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"time"
 
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
 	// to ensure that exec-entrypoint and run can make use of them.
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
 
+	uberzap "go.uber.org/zap"
 	"k8s.io/apimachinery/pkg/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	ctrl "sigs.k8s.io/controller-runtime"
@@ -32,6 +34,10 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 
 	cpmscontroller "github.com/openshift/cluster-control-plane-machine-set-operator/pkg/controllers/controlplanemachineset"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/controllers/operatorconfig"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders/failuredomainsource"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders/faultinjection"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/permissions"
 	cpmswebhook "github.com/openshift/cluster-control-plane-machine-set-operator/pkg/webhooks/controlplanemachineset"
 
 	//+kubebuilder:scaffold:imports
@@ -42,6 +48,18 @@ import (
 )
 
 func main() { //nolint:funlen
+	if len(os.Args) > 1 && os.Args[1] == exportCommand {
+		os.Exit(runExport(os.Args[2:]))
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == lintCommand {
+		os.Exit(runLint(os.Args[2:]))
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == whatIfCommand {
+		os.Exit(runWhatIf(os.Args[2:]))
+	}
+
 	scheme := runtime.NewScheme()
 	setupLog := ctrl.Log.WithName("setup")
 
@@ -51,16 +69,81 @@ func main() { //nolint:funlen
 	}
 
 	var (
-		metricsAddr          string
-		enableLeaderElection bool
-		probeAddr            string
+		componentName                string
+		metricsAddr                  string
+		webhookAddr                  string
+		enableLeaderElection         bool
+		probeAddr                    string
+		instanceVerificationInterval time.Duration
+		stuckDeletionTimeout         time.Duration
+		drainTimeout                 time.Duration
+		drainEscalationPolicy        string
+		abandonedMachinePolicy       string
+		notReadyMachinePolicy        string
+		deleteDepartedNodes          bool
+		repairMissingTags            bool
+		priceCatalogFile             string
+		enableScaleDown              bool
+		rejectUndersizedMachines     bool
+		azureDiskSKUCatalogFile      string
+		awsKMSKeyCatalogFile         string
+		controlPlaneMachineSetName   string
 	)
 
-	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
-	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
+	flag.StringVar(&componentName, "component", string(permissions.ComponentAll),
+		"The component of the operator to run. One of all, controller or webhook. The controller and webhook "+
+			"components may be run separately, each with its own service account and permissions.")
+	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to. "+
+		"IPv6 addresses must be enclosed in square brackets, for example [::]:8080. Set to 0 to disable the metrics endpoint.")
+	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to. "+
+		"IPv6 addresses must be enclosed in square brackets, for example [::]:8081. Set to 0 to disable the probe endpoint.")
+	flag.StringVar(&webhookAddr, "webhook-bind-address", ":9443", "The address the webhook server binds to. "+
+		"IPv6 addresses must be enclosed in square brackets, for example [::]:9443. An empty host binds to every "+
+		"address of the pod, both IPv4 and IPv6.")
 	flag.BoolVar(&enableLeaderElection, "leader-elect", false,
 		"Enable leader election for controller manager. "+
 			"Enabling this will ensure there is only one active controller manager.")
+	flag.DurationVar(&instanceVerificationInterval, "instance-verification-interval", 0,
+		"The interval at which to verify that the cloud instance of each control plane machine still exists. "+
+			"Set to zero to disable instance verification.")
+	flag.DurationVar(&stuckDeletionTimeout, "stuck-deletion-timeout", time.Hour,
+		"The duration after which a deleted control plane machine, still held by finalizers of other controllers, "+
+			"is reported as stuck. Set to zero to disable stuck deletion detection.")
+	flag.DurationVar(&drainTimeout, "drain-timeout", 30*time.Minute,
+		"The duration after which the drain of a deleted control plane machine's node, that has not yet completed, "+
+			"is escalated as per the drain escalation policy. Set to zero to never escalate drains.")
+	flag.StringVar(&drainEscalationPolicy, "drain-escalation-policy", string(cpmscontroller.DrainEscalationPolicyReport),
+		"How drains that have not completed within the drain timeout are escalated. One of Report, Event or SkipDrain.")
+	flag.StringVar(&abandonedMachinePolicy, "abandoned-machine-policy", string(cpmscontroller.AbandonedMachinePolicyIgnore),
+		"How surge machines, abandoned by earlier versions of the operator alongside a ready, up to date machine, "+
+			"are handled on startup. One of Ignore, Adopt or Delete. Ignore only reports them, through a warning event.")
+	flag.StringVar(&notReadyMachinePolicy, "not-ready-machine-policy", string(cpmscontroller.NotReadyMachinePolicySkip),
+		"How up to date machines whose nodes are not ready are handled when a rolling update is about to replace the "+
+			"next outdated machine. One of Skip, Wait or ReplaceFirst.")
+	flag.BoolVar(&deleteDepartedNodes, "delete-departed-nodes", false,
+		"Delete control plane nodes left behind once their machine has been removed, the node is no longer ready "+
+			"and its etcd member has been removed.")
+	flag.BoolVar(&repairMissingTags, "repair-missing-tags", false,
+		"Add the cluster ownership tag and the user defined tags of the cluster to control plane machines missing "+
+			"them, in place. When disabled, missing tags are only reported.")
+	flag.StringVar(&priceCatalogFile, "price-catalog-file", "",
+		"The path to a file containing the hourly price of each instance type, used to estimate the monthly cost "+
+			"change of pending rollouts that change the instance type. Leave empty to disable cost estimation.")
+	flag.BoolVar(&enableScaleDown, "enable-control-plane-scale-down", false,
+		"Allow the replicas of the control plane machine set to be decreased. The operator does not remove the "+
+			"machines beyond the new replica count, these must be removed manually.")
+	flag.BoolVar(&rejectUndersizedMachines, "reject-undersized-control-plane-machines", false,
+		"Reject control plane machine set templates that configure machines smaller than the minimum for the "+
+			"platform. When not set, such templates are admitted with a warning.")
+	flag.StringVar(&azureDiskSKUCatalogFile, "azure-disk-sku-catalog-file", "",
+		"The path to a file containing the zones in which each Azure disk SKU is available, used to reject templates "+
+			"with premium or ultra disks that are unavailable in one of their failure domains. Leave empty to disable.")
+	flag.StringVar(&awsKMSKeyCatalogFile, "aws-kms-key-catalog-file", "",
+		"The path to a file containing the KMS keys within each AWS region, used to reject templates that encrypt "+
+			"volumes with a KMS key that does not exist. Leave empty to disable.")
+	flag.StringVar(&controlPlaneMachineSetName, "control-plane-machine-set-name", cpmscontroller.DefaultControlPlaneMachineSetName,
+		"The name of the control plane machine set singleton. The controller only reconciles, and the webhook only "+
+			"admits the creation of, a control plane machine set with this name.")
 
 	opts := zap.Options{
 		Development: true,
@@ -68,12 +151,108 @@ func main() { //nolint:funlen
 	opts.BindFlags(flag.CommandLine)
 	flag.Parse()
 
+	// The log level is held as an atomic level so that the operator configuration can change it at runtime.
+	logLevel, ok := opts.Level.(uberzap.AtomicLevel)
+	if !ok {
+		logLevel = uberzap.NewAtomicLevelAt(uberzap.DebugLevel)
+		opts.Level = logLevel
+	}
+
 	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts)))
 
+	operatorConfig := operatorconfig.NewStore(logLevel)
+
+	component, err := permissions.ParseComponent(componentName)
+	if err != nil {
+		setupLog.Error(err, "invalid value for --component")
+		os.Exit(1)
+	}
+
+	if err := validateBindAddress(metricsAddr); err != nil {
+		setupLog.Error(err, "invalid value for --metrics-bind-address")
+		os.Exit(1)
+	}
+
+	if err := validateBindAddress(probeAddr); err != nil {
+		setupLog.Error(err, "invalid value for --health-probe-bind-address")
+		os.Exit(1)
+	}
+
+	webhookHost, webhookPort, err := parseBindAddress(webhookAddr)
+	if err != nil {
+		setupLog.Error(err, "invalid value for --webhook-bind-address")
+		os.Exit(1)
+	}
+
+	policy, err := cpmscontroller.ParseAbandonedMachinePolicy(abandonedMachinePolicy)
+	if err != nil {
+		setupLog.Error(err, "invalid value for --abandoned-machine-policy")
+		os.Exit(1)
+	}
+
+	drainPolicy, err := cpmscontroller.ParseDrainEscalationPolicy(drainEscalationPolicy)
+	if err != nil {
+		setupLog.Error(err, "invalid value for --drain-escalation-policy")
+		os.Exit(1)
+	}
+
+	notReadyPolicy, err := cpmscontroller.ParseNotReadyMachinePolicy(notReadyMachinePolicy)
+	if err != nil {
+		setupLog.Error(err, "invalid value for --not-ready-machine-policy")
+		os.Exit(1)
+	}
+
+	var priceCatalog cpmscontroller.PriceCatalog
+	if priceCatalogFile != "" {
+		priceCatalog, err = cpmscontroller.LoadPriceCatalog(priceCatalogFile)
+		if err != nil {
+			setupLog.Error(err, "invalid value for --price-catalog-file")
+			os.Exit(1)
+		}
+	}
+
+	var azureDiskSKUCatalog cpmswebhook.AzureDiskSKUCatalog
+	if azureDiskSKUCatalogFile != "" {
+		azureDiskSKUCatalog, err = cpmswebhook.LoadAzureDiskSKUCatalog(azureDiskSKUCatalogFile)
+		if err != nil {
+			setupLog.Error(err, "invalid value for --azure-disk-sku-catalog-file")
+			os.Exit(1)
+		}
+	}
+
+	var awsKMSKeyCatalog cpmswebhook.AWSKMSKeyCatalog
+	if awsKMSKeyCatalogFile != "" {
+		awsKMSKeyCatalog, err = cpmswebhook.LoadAWSKMSKeyCatalog(awsKMSKeyCatalogFile)
+		if err != nil {
+			setupLog.Error(err, "invalid value for --aws-kms-key-catalog-file")
+			os.Exit(1)
+		}
+	}
+
+	// The webhook holds no state, so every replica of the webhook serves admission requests at once.
+	if !component.RunsController() {
+		enableLeaderElection = false
+	}
+
+	faultInjection, err := faultinjection.ConfigFromEnvironment(os.Getenv)
+	if err != nil {
+		setupLog.Error(err, "invalid fault injection configuration")
+		os.Exit(1)
+	}
+
+	if faultInjection != nil {
+		setupLog.Info("Fault injection is enabled, this must never be used in production",
+			"createDelay", faultInjection.CreateDelay,
+			"deleteFailureRate", faultInjection.DeleteFailureRate,
+			"falseReadinessRate", faultInjection.FalseReadinessRate,
+		)
+	}
+
 	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
 		Scheme:                 scheme,
 		MetricsBindAddress:     metricsAddr,
-		Port:                   9443,
+		Host:                   webhookHost,
+		Port:                   webhookPort,
 		HealthProbeBindAddress: probeAddr,
 		LeaderElection:         enableLeaderElection,
 		LeaderElectionID:       "control-plane-machine-set-operator",
@@ -84,21 +263,76 @@ func main() { //nolint:funlen
 		os.Exit(1)
 	}
 
-	if err := (&cpmscontroller.ControlPlaneMachineSetReconciler{
-		Client:       mgr.GetClient(),
-		Scheme:       mgr.GetScheme(),
-		Namespace:    "openshift-machine-api",
-		OperatorName: "control-plane-machine-set",
-	}).SetupWithManager(mgr); err != nil {
-		setupLog.Error(err, "unable to create controller", "controller", "ControlPlaneMachineSet")
+	ctx := ctrl.SetupSignalHandler()
+
+	controllerMissing, webhookMissing, err := checkPermissions(ctx, mgr.GetClient(), component, "openshift-machine-api")
+	if err != nil {
+		setupLog.Error(err, "unable to check permissions")
 		os.Exit(1)
 	}
 
-	if err := (&cpmswebhook.ControlPlaneMachineSetWebhook{}).SetupWebhookWithManager(mgr); err != nil {
-		setupLog.Error(err, "unable to create webhook", "webhook", "ControlPlaneMachineSet")
+	if len(controllerMissing) > 0 || len(webhookMissing) > 0 {
+		setupLog.Info("Missing permissions, the operator will not take any action until these have been granted and the operator restarted",
+			"component", component, "controller", permissions.Summarise(controllerMissing), "webhook", permissions.Summarise(webhookMissing))
+	}
+
+	if component.RunsController() {
+		if err := (&cpmscontroller.ControlPlaneMachineSetReconciler{
+			Client:       mgr.GetClient(),
+			Scheme:       mgr.GetScheme(),
+			Namespace:    "openshift-machine-api",
+			Name:         controlPlaneMachineSetName,
+			OperatorName: "control-plane-machine-set",
+
+			InstanceVerificationInterval: instanceVerificationInterval,
+			StuckDeletionTimeout:         stuckDeletionTimeout,
+			DrainTimeout:                 drainTimeout,
+			DrainEscalationPolicy:        drainPolicy,
+			AbandonedMachinePolicy:       policy,
+			NotReadyMachinePolicy:        notReadyPolicy,
+			DeleteDepartedNodes:          deleteDepartedNodes,
+			RepairMissingTags:            repairMissingTags,
+			PriceCatalog:                 priceCatalog,
+			OperatorConfig:               operatorConfig,
+			FaultInjection:               faultInjection,
+			MissingPermissions:           controllerMissing,
+			FailureDomainSources:         failuredomainsource.DefaultRegistry(),
+		}).SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create controller", "controller", "ControlPlaneMachineSet")
+			os.Exit(1)
+		}
+	}
+
+	// The operator configuration controller runs alongside the webhook too, as the webhook reads its runtime
+	// configuration from the store.
+	if err := (&operatorconfig.OperatorConfigReconciler{
+		Client:    mgr.GetClient(),
+		Namespace: "openshift-machine-api",
+		Store:     operatorConfig,
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "OperatorConfig")
 		os.Exit(1)
 	}
 
+	if component.RunsWebhook() {
+		if err := (&cpmswebhook.ControlPlaneMachineSetWebhook{
+			EnableScaleDown:            enableScaleDown,
+			RejectUndersizedMachines:   rejectUndersizedMachines,
+			AzureDiskSKUCatalog:        azureDiskSKUCatalog,
+			AWSKMSKeyCatalog:           awsKMSKeyCatalog,
+			OperatorConfig:             operatorConfig,
+			ControlPlaneMachineSetName: controlPlaneMachineSetName,
+		}).SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "ControlPlaneMachineSet")
+			os.Exit(1)
+		}
+
+		if err := mgr.AddReadyzCheck("permissions", webhookPermissionsCheck(webhookMissing)); err != nil {
+			setupLog.Error(err, "unable to set up permissions ready check")
+			os.Exit(1)
+		}
+	}
+
 	//+kubebuilder:scaffold:builder
 
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
@@ -113,7 +347,7 @@ func main() { //nolint:funlen
 
 	setupLog.Info("starting manager")
 
-	if err := mgr.Start(ctrl.SetupSignalHandler()); err != nil {
+	if err := mgr.Start(ctx); err != nil {
 		setupLog.Error(err, "problem running manager")
 		os.Exit(1)
 	}
@@ -0,0 +1,71 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/permissions"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/healthz"
+)
+
+// errMissingWebhookPermissions is used to denote that the webhook service account has not been granted every
+// permission that the webhook requires.
+var errMissingWebhookPermissions = errors.New("the webhook service account is missing permissions, grant them and restart the webhook")
+
+// checkPermissions determines which of the permissions required by the component have not been granted to the
+// service account of the operator. The missing permissions of the controller and of the webhook are returned
+// separately, as the controller reports its missing permissions through its status, whereas the webhook reports
+// them through its readiness.
+func checkPermissions(ctx context.Context, cl client.Client, component permissions.Component, namespace string) ([]permissions.Permission, []permissions.Permission, error) {
+	var controllerMissing, webhookMissing []permissions.Permission
+
+	var err error
+
+	if component.RunsController() {
+		controllerMissing, err = permissions.Missing(ctx, cl, permissions.Controller(namespace))
+		if err != nil {
+			return nil, nil, fmt.Errorf("could not check controller permissions: %w", err)
+		}
+	}
+
+	if component.RunsWebhook() {
+		webhookMissing, err = permissions.Missing(ctx, cl, permissions.Webhook(namespace))
+		if err != nil {
+			return nil, nil, fmt.Errorf("could not check webhook permissions: %w", err)
+		}
+	}
+
+	return controllerMissing, webhookMissing, nil
+}
+
+// webhookPermissionsCheck is a ready check that fails while the webhook is missing permissions, so that the
+// webhook does not receive admission requests that it cannot validate. Permissions are only checked on startup,
+// so the webhook must be restarted once the permissions have been granted.
+func webhookPermissionsCheck(missing []permissions.Permission) healthz.Checker {
+	return func(_ *http.Request) error {
+		if len(missing) > 0 {
+			return fmt.Errorf("%w: %s", errMissingWebhookPermissions, permissions.Summarise(missing))
+		}
+
+		return nil
+	}
+}
@@ -0,0 +1,195 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/go-logr/logr"
+	machinev1 "github.com/openshift/api/machine/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	cpmscontroller "github.com/openshift/cluster-control-plane-machine-set-operator/pkg/controllers/controlplanemachineset"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders/failuredomainsource"
+)
+
+const (
+	// whatIfCommand is the name of the subcommand that reports the actions the operator would take for a proposed
+	// ControlPlaneMachineSet.
+	whatIfCommand = "what-if"
+
+	// whatIfTextFormat is the output format that writes each step on its own line.
+	whatIfTextFormat = "text"
+
+	// whatIfJSONFormat is the output format that writes the report as a JSON document.
+	whatIfJSONFormat = "json"
+)
+
+// errUnknownOutputFormat is returned when the output format of the what-if subcommand is not recognised.
+var errUnknownOutputFormat = errors.New("unknown output format")
+
+// runWhatIf runs the what-if subcommand, which reports which Control Plane Machines would be created, replaced and
+// removed, into which failure domains and in what order, if a proposed change to the ControlPlaneMachineSet were
+// applied. The proposed change is never persisted.
+// It returns the exit code of the subcommand.
+func runWhatIf(args []string) int {
+	flags := flag.NewFlagSet(whatIfCommand, flag.ContinueOnError)
+
+	var (
+		kubeconfig   string
+		namespace    string
+		name         string
+		file         string
+		replicas     int
+		outputFormat string
+	)
+
+	flags.StringVar(&kubeconfig, "kubeconfig", "",
+		"The path to the kubeconfig of the cluster. When not set, the KUBECONFIG environment variable, or the "+
+			"in cluster configuration, is used.")
+	flags.StringVar(&namespace, "namespace", "openshift-machine-api",
+		"The namespace of the control plane machine set to analyse.")
+	flags.StringVar(&name, "name", cpmscontroller.DefaultControlPlaneMachineSetName,
+		"The name of the control plane machine set to analyse.")
+	flags.StringVar(&file, "file", "",
+		"The path of a proposed control plane machine set manifest, whose spec, and annotations when set, replace "+
+			"those of the control plane machine set within the cluster. Set to - to read from standard input.")
+	flags.IntVar(&replicas, "replicas", 0,
+		"The proposed number of replicas. When not set, the replicas are left unchanged.")
+	flags.StringVar(&outputFormat, "output-format", whatIfTextFormat,
+		"The format of the report, either text or json.")
+
+	if err := flags.Parse(args); errors.Is(err, flag.ErrHelp) {
+		return 0
+	} else if err != nil {
+		return 2
+	}
+
+	if outputFormat != whatIfTextFormat && outputFormat != whatIfJSONFormat {
+		fmt.Fprintf(os.Stderr, "%v: %q\n", errUnknownOutputFormat, outputFormat)
+
+		return 2
+	}
+
+	if err := whatIf(context.Background(), kubeconfig, namespace, name, file, int32(replicas), outputFormat, os.Stdout); err != nil {
+		fmt.Fprintf(os.Stderr, "error analysing control plane machine set: %v\n", err)
+
+		return 1
+	}
+
+	return 0
+}
+
+// whatIf applies the proposed changes to the ControlPlaneMachineSet with the given namespace and name, in memory,
+// and writes the actions that the operator would take to the output.
+// All requests are made through a dry run client, so that the cluster is never modified.
+func whatIf(ctx context.Context, kubeconfig, namespace, name, file string, replicas int32, outputFormat string, out io.Writer) error {
+	cfg, err := loadConfig(kubeconfig)
+	if err != nil {
+		return err
+	}
+
+	scheme := runtime.NewScheme()
+	if err := setupScheme(scheme); err != nil {
+		return err
+	}
+
+	cl, err := client.New(cfg, client.Options{Scheme: scheme})
+	if err != nil {
+		return fmt.Errorf("unable to create client: %w", err)
+	}
+
+	dryRunClient := client.NewDryRunClient(cl)
+
+	cpms := &machinev1.ControlPlaneMachineSet{}
+	if err := dryRunClient.Get(ctx, client.ObjectKey{Namespace: namespace, Name: name}, cpms); err != nil {
+		return fmt.Errorf("error fetching control plane machine set %s/%s: %w", namespace, name, err)
+	}
+
+	if file != "" {
+		manifest, err := readControlPlaneMachineSetManifest(file)
+		if err != nil {
+			return err
+		}
+
+		cpms.Spec = manifest.Spec
+
+		if manifest.GetAnnotations() != nil {
+			cpms.SetAnnotations(manifest.GetAnnotations())
+		}
+	}
+
+	if replicas > 0 {
+		cpms.Spec.Replicas = &replicas
+	}
+
+	reconciler := &cpmscontroller.ControlPlaneMachineSetReconciler{
+		Client:    dryRunClient,
+		APIReader: dryRunClient,
+		Scheme:    scheme,
+		Namespace: namespace,
+		Name:      name,
+
+		FailureDomainSources: failuredomainsource.DefaultRegistry(),
+	}
+
+	report, err := reconciler.WhatIf(ctx, logr.Discard(), cpms)
+	if err != nil {
+		return err
+	}
+
+	return writeWhatIfReport(report, outputFormat, out)
+}
+
+// writeWhatIfReport writes the report to the output in the given format.
+func writeWhatIfReport(report *cpmscontroller.WhatIfReport, outputFormat string, out io.Writer) error {
+	if outputFormat == whatIfJSONFormat {
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+
+		if err := encoder.Encode(report); err != nil {
+			return fmt.Errorf("unable to write report: %w", err)
+		}
+
+		return nil
+	}
+
+	lines := []string{fmt.Sprintf("Strategy %s with %d replicas:", report.Strategy, report.Replicas)}
+
+	for _, step := range report.Steps {
+		lines = append(lines, fmt.Sprintf("%d. %s", step.Order, step))
+	}
+
+	if len(report.Steps) == 0 {
+		lines = append(lines, "No Machines would be created, replaced or removed.")
+	}
+
+	for _, line := range lines {
+		if _, err := fmt.Fprintln(out, line); err != nil {
+			return fmt.Errorf("unable to write report: %w", err)
+		}
+	}
+
+	return nil
+}
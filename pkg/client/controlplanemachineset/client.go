@@ -0,0 +1,149 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controlplanemachineset
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	configv1 "github.com/openshift/api/config/v1"
+	machinev1 "github.com/openshift/api/machine/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Client provides typed access to a single ControlPlaneMachineSet.
+type Client struct {
+	// client is used to read and patch the ControlPlaneMachineSet.
+	// Its scheme must include the machine.openshift.io/v1 types.
+	client client.Client
+
+	// key identifies the ControlPlaneMachineSet.
+	key client.ObjectKey
+}
+
+// NewClient creates a new Client for the ControlPlaneMachineSet with the given namespace and name.
+// An empty namespace or name is defaulted to DefaultNamespace or DefaultName respectively.
+func NewClient(cl client.Client, namespace, name string) *Client {
+	if namespace == "" {
+		namespace = DefaultNamespace
+	}
+
+	if name == "" {
+		name = DefaultName
+	}
+
+	return &Client{
+		client: cl,
+		key:    client.ObjectKey{Namespace: namespace, Name: name},
+	}
+}
+
+// Get fetches the current state of the ControlPlaneMachineSet.
+func (c *Client) Get(ctx context.Context) (*machinev1.ControlPlaneMachineSet, error) {
+	cpms := &machinev1.ControlPlaneMachineSet{}
+	if err := c.client.Get(ctx, c.key, cpms); err != nil {
+		return nil, fmt.Errorf("error fetching control plane machine set %s: %w", c.key, err)
+	}
+
+	return cpms, nil
+}
+
+// GetSupportMatrix fetches the support matrix published by the operator on the ClusterOperator named
+// DefaultOperatorName. The scheme of the client must include the config.openshift.io/v1 types.
+// ErrSupportMatrixNotPublished is returned when the ClusterOperator does not carry a support matrix.
+func (c *Client) GetSupportMatrix(ctx context.Context) (*SupportMatrix, error) {
+	co := &configv1.ClusterOperator{}
+	if err := c.client.Get(ctx, client.ObjectKey{Name: DefaultOperatorName}, co); err != nil {
+		return nil, fmt.Errorf("error fetching cluster operator %s: %w", DefaultOperatorName, err)
+	}
+
+	return SupportMatrixFromClusterOperator(co)
+}
+
+// Pause pauses the replacement of Control Plane Machines by the ControlPlaneMachineSet.
+// The status of the ControlPlaneMachineSet continues to be updated while it is paused.
+func (c *Client) Pause(ctx context.Context) error {
+	return c.setPaused(ctx, true)
+}
+
+// Resume resumes the replacement of Control Plane Machines by a paused ControlPlaneMachineSet.
+func (c *Client) Resume(ctx context.Context) error {
+	return c.setPaused(ctx, false)
+}
+
+// setPaused patches the paused annotation of the ControlPlaneMachineSet. A merge patch is used so that the
+// annotations set by others are not overwritten.
+func (c *Client) setPaused(ctx context.Context, paused bool) error {
+	cpms, err := c.Get(ctx)
+	if err != nil {
+		return err
+	}
+
+	if IsPaused(cpms) == paused {
+		return nil
+	}
+
+	patchBase := client.MergeFrom(cpms.DeepCopy())
+
+	annotations := cpms.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+
+	if paused {
+		annotations[PausedAnnotation] = "true"
+	} else {
+		delete(annotations, PausedAnnotation)
+	}
+
+	cpms.SetAnnotations(annotations)
+
+	if err := c.client.Patch(ctx, cpms, patchBase); err != nil {
+		return fmt.Errorf("error patching control plane machine set %s: %w", c.key, err)
+	}
+
+	return nil
+}
+
+// WaitForRolloutComplete polls the ControlPlaneMachineSet, at the given interval, until the rollout of its current
+// generation is complete, as determined by IsRolloutComplete. It returns the completed ControlPlaneMachineSet.
+// If the context is done before the rollout completes, the error describes why the rollout was incomplete.
+func (c *Client) WaitForRolloutComplete(ctx context.Context, interval time.Duration) (*machinev1.ControlPlaneMachineSet, error) {
+	var cpms *machinev1.ControlPlaneMachineSet
+
+	err := wait.PollImmediateUntilWithContext(ctx, interval, func(ctx context.Context) (bool, error) {
+		latest, err := c.Get(ctx)
+		if err != nil {
+			return false, err
+		}
+
+		cpms = latest
+
+		return IsRolloutComplete(cpms), nil
+	})
+
+	switch {
+	case err != nil && cpms != nil:
+		return nil, fmt.Errorf("error waiting for rollout to complete: %s: %w", RolloutIncompleteReason(cpms), err)
+	case err != nil:
+		return nil, fmt.Errorf("error waiting for rollout to complete: %w", err)
+	}
+
+	return cpms, nil
+}
@@ -0,0 +1,132 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controlplanemachineset
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	machinev1 "github.com/openshift/api/machine/v1"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/test"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/test/resourcebuilder"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/envtest/komega"
+)
+
+var _ = Describe("Client", func() {
+	var namespaceName string
+	var cpms *machinev1.ControlPlaneMachineSet
+	var cpmsClient *Client
+
+	BeforeEach(func() {
+		By("Setting up a namespace for the test")
+		ns := resourcebuilder.Namespace().WithGenerateName("control-plane-machine-set-client-").Build()
+		Expect(k8sClient.Create(ctx, ns)).To(Succeed())
+		namespaceName = ns.GetName()
+
+		By("Creating a ControlPlaneMachineSet")
+		cpms = resourcebuilder.ControlPlaneMachineSet().WithNamespace(namespaceName).
+			WithAnnotations(map[string]string{"example.com/other": "value"}).Build()
+		Expect(k8sClient.Create(ctx, cpms)).To(Succeed())
+
+		cpmsClient = NewClient(k8sClient, namespaceName, "")
+	})
+
+	AfterEach(func() {
+		test.CleanupResources(Default, ctx, cfg, k8sClient, namespaceName,
+			&machinev1.ControlPlaneMachineSet{},
+		)
+	})
+
+	It("defaults the namespace and name", func() {
+		Expect(NewClient(k8sClient, "", "").key).To(Equal(client.ObjectKey{Namespace: DefaultNamespace, Name: DefaultName}))
+	})
+
+	Context("Get", func() {
+		It("fetches the ControlPlaneMachineSet", func() {
+			got, err := cpmsClient.Get(ctx)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(got.GetUID()).To(Equal(cpms.GetUID()))
+		})
+
+		It("returns a not found error for a missing ControlPlaneMachineSet", func() {
+			_, err := NewClient(k8sClient, namespaceName, "missing").Get(ctx)
+			Expect(apierrors.IsNotFound(err)).To(BeTrue())
+		})
+	})
+
+	Context("Pause and Resume", func() {
+		It("sets the paused annotation, preserving other annotations", func() {
+			Expect(cpmsClient.Pause(ctx)).To(Succeed())
+
+			Eventually(komega.Object(cpms)).Should(HaveField("ObjectMeta.Annotations", Equal(map[string]string{
+				"example.com/other": "value",
+				PausedAnnotation:    "true",
+			})))
+		})
+
+		It("is a no-op when already paused", func() {
+			Expect(cpmsClient.Pause(ctx)).To(Succeed())
+			Expect(komega.Get(cpms)()).To(Succeed())
+			resourceVersion := cpms.GetResourceVersion()
+
+			Expect(cpmsClient.Pause(ctx)).To(Succeed())
+
+			Consistently(komega.Object(cpms)).Should(HaveField("ObjectMeta.ResourceVersion", Equal(resourceVersion)))
+		})
+
+		It("removes the paused annotation on resume", func() {
+			Expect(cpmsClient.Pause(ctx)).To(Succeed())
+			Expect(cpmsClient.Resume(ctx)).To(Succeed())
+
+			Eventually(komega.Object(cpms)).Should(HaveField("ObjectMeta.Annotations", Equal(map[string]string{
+				"example.com/other": "value",
+			})))
+		})
+	})
+
+	Context("WaitForRolloutComplete", func() {
+		It("returns once the rollout has completed", func() {
+			go func() {
+				defer GinkgoRecover()
+
+				Expect(komega.UpdateStatus(cpms, func() {
+					cpms.Status = completedStatus(cpms.GetGeneration())
+				})()).To(Succeed())
+			}()
+
+			waitCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+			defer cancel()
+
+			completed, err := cpmsClient.WaitForRolloutComplete(waitCtx, 100*time.Millisecond)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(IsRolloutComplete(completed)).To(BeTrue())
+		})
+
+		It("describes why the rollout is incomplete when the context is done", func() {
+			waitCtx, cancel := context.WithTimeout(ctx, 500*time.Millisecond)
+			defer cancel()
+
+			_, err := cpmsClient.WaitForRolloutComplete(waitCtx, 100*time.Millisecond)
+			Expect(err).To(MatchError(ContainSubstring("error waiting for rollout to complete: generation 1 has not yet been observed, observed generation is 0")))
+		})
+	})
+})
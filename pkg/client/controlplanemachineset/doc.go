@@ -0,0 +1,21 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package controlplanemachineset provides a typed client, and helpers, for consumers of the ControlPlaneMachineSet,
+// such as other operators and end to end test suites.
+// The helpers encapsulate the meaning of the status and conditions reported by the ControlPlaneMachineSet operator,
+// so that consumers need not reimplement them.
+package controlplanemachineset
@@ -0,0 +1,115 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controlplanemachineset
+
+import (
+	"fmt"
+
+	machinev1 "github.com/openshift/api/machine/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// DefaultNamespace is the namespace in which the ControlPlaneMachineSet is created.
+	DefaultNamespace = "openshift-machine-api"
+
+	// DefaultName is the name of the ControlPlaneMachineSet, unless the operator is configured with a different name.
+	DefaultName = "cluster"
+
+	// PausedAnnotation is the annotation used to pause the replacement of Control Plane Machines.
+	// While set to "true", the ControlPlaneMachineSet does not create or delete any Machine.
+	PausedAnnotation = "controlplanemachineset.machine.openshift.io/paused"
+
+	// ConditionAvailable is the condition reporting whether all Control Plane Machines are available.
+	ConditionAvailable = "Available"
+
+	// ConditionDegraded is the condition reporting whether the ControlPlaneMachineSet has observed an error that
+	// prevents it from taking action.
+	ConditionDegraded = "Degraded"
+
+	// ConditionProgressing is the condition reporting whether any Control Plane Machine is in need of an update, or
+	// is yet to be removed.
+	ConditionProgressing = "Progressing"
+
+	// ConditionRolloutPhase is the condition reporting how far the ControlPlaneMachineSet has progressed through a
+	// rollout. Its reason is the current rollout phase.
+	ConditionRolloutPhase = "RolloutPhase"
+
+	// ConditionGatedBy is the condition reporting what, if anything, is blocking the replacement of outdated
+	// Control Plane Machines.
+	ConditionGatedBy = "GatedBy"
+
+	// ConditionMachineAPIPaused is the condition reporting that the Machine API has paused some Control Plane
+	// Machines, and so the ControlPlaneMachineSet is holding all actions.
+	ConditionMachineAPIPaused = "MachineAPIPaused"
+
+	// RolloutPhaseIdle is the rollout phase reported while no Control Plane Machine needs an update.
+	RolloutPhaseIdle = "Idle"
+)
+
+// IsPaused determines whether the replacement of Control Plane Machines has been paused.
+func IsPaused(cpms *machinev1.ControlPlaneMachineSet) bool {
+	return cpms.GetAnnotations()[PausedAnnotation] == "true"
+}
+
+// RolloutPhase returns the rollout phase most recently reported by the ControlPlaneMachineSet, or an empty string
+// when no rollout phase has been reported.
+func RolloutPhase(cpms *machinev1.ControlPlaneMachineSet) string {
+	if condition := meta.FindStatusCondition(cpms.Status.Conditions, ConditionRolloutPhase); condition != nil {
+		return condition.Reason
+	}
+
+	return ""
+}
+
+// IsRolloutComplete determines whether the ControlPlaneMachineSet has finished rolling out its current generation.
+func IsRolloutComplete(cpms *machinev1.ControlPlaneMachineSet) bool {
+	return RolloutIncompleteReason(cpms) == ""
+}
+
+// RolloutIncompleteReason describes why the ControlPlaneMachineSet has not yet finished rolling out its current
+// generation, or returns an empty string once the rollout is complete.
+// A rollout is complete once the operator has observed the current generation, no Control Plane Machine needs an
+// update or is yet to be removed, and every desired replica is updated and ready.
+// Conditions observed for an earlier generation are disregarded, as they do not yet reflect the current spec.
+func RolloutIncompleteReason(cpms *machinev1.ControlPlaneMachineSet) string {
+	if cpms.Status.ObservedGeneration < cpms.GetGeneration() {
+		return fmt.Sprintf("generation %d has not yet been observed, observed generation is %d", cpms.GetGeneration(), cpms.Status.ObservedGeneration)
+	}
+
+	progressing := meta.FindStatusCondition(cpms.Status.Conditions, ConditionProgressing)
+
+	switch {
+	case progressing == nil || progressing.ObservedGeneration < cpms.GetGeneration():
+		return "progressing condition has not yet been reported for the current generation"
+	case progressing.Status != metav1.ConditionFalse:
+		return fmt.Sprintf("rollout is progressing: %s: %s", progressing.Reason, progressing.Message)
+	}
+
+	desiredReplicas := int32(0)
+	if cpms.Spec.Replicas != nil {
+		desiredReplicas = *cpms.Spec.Replicas
+	}
+
+	status := cpms.Status
+	if status.Replicas != desiredReplicas || status.UpdatedReplicas != desiredReplicas || status.ReadyReplicas != desiredReplicas {
+		return fmt.Sprintf("expected %d replicas to be updated and ready, found %d replicas, %d updated and %d ready", desiredReplicas, status.Replicas, status.UpdatedReplicas, status.ReadyReplicas)
+	}
+
+	return ""
+}
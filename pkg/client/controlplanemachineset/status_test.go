@@ -0,0 +1,119 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controlplanemachineset
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	machinev1 "github.com/openshift/api/machine/v1"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/test/resourcebuilder"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// completedStatus returns the status of a three replica ControlPlaneMachineSet that has completed the rollout of
+// the given generation.
+func completedStatus(generation int64) machinev1.ControlPlaneMachineSetStatus {
+	return machinev1.ControlPlaneMachineSetStatus{
+		ObservedGeneration: generation,
+		Replicas:           3,
+		UpdatedReplicas:    3,
+		ReadyReplicas:      3,
+		Conditions: []metav1.Condition{
+			{Type: ConditionProgressing, Status: metav1.ConditionFalse, Reason: "AllReplicasUpdated", ObservedGeneration: generation, LastTransitionTime: metav1.Now()},
+			{Type: ConditionRolloutPhase, Status: metav1.ConditionFalse, Reason: RolloutPhaseIdle, ObservedGeneration: generation, LastTransitionTime: metav1.Now()},
+		},
+	}
+}
+
+var _ = Describe("Status helpers", func() {
+	var cpms *machinev1.ControlPlaneMachineSet
+
+	BeforeEach(func() {
+		cpms = resourcebuilder.ControlPlaneMachineSet().WithReplicas(3).WithGeneration(2).Build()
+		cpms.Status = completedStatus(2)
+	})
+
+	DescribeTable("IsPaused", func(annotations map[string]string, expectedPaused bool) {
+		cpms.SetAnnotations(annotations)
+
+		Expect(IsPaused(cpms)).To(Equal(expectedPaused))
+	},
+		Entry("with no annotations", nil, false),
+		Entry("with the paused annotation set to true", map[string]string{PausedAnnotation: "true"}, true),
+		Entry("with the paused annotation set to false", map[string]string{PausedAnnotation: "false"}, false),
+	)
+
+	Context("RolloutPhase", func() {
+		It("returns the reason of the rollout phase condition", func() {
+			Expect(RolloutPhase(cpms)).To(Equal(RolloutPhaseIdle))
+		})
+
+		It("returns an empty string when no rollout phase has been reported", func() {
+			cpms.Status.Conditions = nil
+
+			Expect(RolloutPhase(cpms)).To(BeEmpty())
+		})
+	})
+
+	Context("IsRolloutComplete", func() {
+		It("is complete when all replicas are updated and ready for the current generation", func() {
+			Expect(IsRolloutComplete(cpms)).To(BeTrue())
+			Expect(RolloutIncompleteReason(cpms)).To(BeEmpty())
+		})
+
+		It("is incomplete when the current generation has not been observed", func() {
+			cpms.Status = completedStatus(1)
+
+			Expect(IsRolloutComplete(cpms)).To(BeFalse())
+			Expect(RolloutIncompleteReason(cpms)).To(Equal("generation 2 has not yet been observed, observed generation is 1"))
+		})
+
+		It("is incomplete when the progressing condition is from an earlier generation", func() {
+			cpms.Status.Conditions[0].ObservedGeneration = 1
+
+			Expect(IsRolloutComplete(cpms)).To(BeFalse())
+			Expect(RolloutIncompleteReason(cpms)).To(Equal("progressing condition has not yet been reported for the current generation"))
+		})
+
+		It("is incomplete while the rollout is progressing", func() {
+			cpms.Status.Conditions[0] = metav1.Condition{
+				Type:               ConditionProgressing,
+				Status:             metav1.ConditionTrue,
+				Reason:             "NeedsUpdateReplicas",
+				Message:            "Observed 1 replica(s) in need of update",
+				ObservedGeneration: 2,
+			}
+
+			Expect(IsRolloutComplete(cpms)).To(BeFalse())
+			Expect(RolloutIncompleteReason(cpms)).To(Equal("rollout is progressing: NeedsUpdateReplicas: Observed 1 replica(s) in need of update"))
+		})
+
+		It("is incomplete while a replica is not ready", func() {
+			cpms.Status.ReadyReplicas = 2
+
+			Expect(IsRolloutComplete(cpms)).To(BeFalse())
+			Expect(RolloutIncompleteReason(cpms)).To(Equal("expected 3 replicas to be updated and ready, found 3 replicas, 3 updated and 2 ready"))
+		})
+
+		It("is incomplete while an old replica is yet to be removed", func() {
+			cpms.Status.Replicas = 4
+
+			Expect(IsRolloutComplete(cpms)).To(BeFalse())
+		})
+	})
+})
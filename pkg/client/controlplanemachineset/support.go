@@ -0,0 +1,122 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controlplanemachineset
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	configv1 "github.com/openshift/api/config/v1"
+	machinev1 "github.com/openshift/api/machine/v1"
+)
+
+const (
+	// DefaultOperatorName is the name of the ClusterOperator with which the ControlPlaneMachineSet operator reports
+	// its status, and publishes its support matrix.
+	DefaultOperatorName = "control-plane-machine-set"
+)
+
+// Maturity describes how mature the support for a platform, update strategy or feature is.
+type Maturity string
+
+const (
+	// MaturityStable denotes support that is complete and enabled by default.
+	MaturityStable Maturity = "Stable"
+
+	// MaturityTechPreview denotes support that is incomplete, or must be explicitly enabled, and may change.
+	MaturityTechPreview Maturity = "TechPreview"
+
+	// MaturityUnsupported denotes that there is no support. Anything not listed within the support matrix is
+	// unsupported.
+	MaturityUnsupported Maturity = "Unsupported"
+)
+
+var (
+	// ErrSupportMatrixNotPublished is returned when the ClusterOperator does not carry a support matrix, for example,
+	// when the operator has not yet reported its status, or the operator build predates the support matrix.
+	ErrSupportMatrixNotPublished = errors.New("support matrix has not been published")
+)
+
+// SupportMatrix describes the platforms, update strategies and features supported by a build of the
+// ControlPlaneMachineSet operator, and their maturity. It is published within the extension of the status of the
+// ClusterOperator so that cluster tooling can decide whether to activate the ControlPlaneMachineSet.
+type SupportMatrix struct {
+	// Platforms lists the supported platforms, by platform type.
+	Platforms []SupportEntry `json:"platforms"`
+
+	// Strategies lists the supported update strategies, by strategy type.
+	Strategies []SupportEntry `json:"strategies"`
+
+	// Features lists the supported features, by name.
+	Features []SupportEntry `json:"features"`
+}
+
+// SupportEntry describes the maturity of the support for a single platform, update strategy or feature.
+type SupportEntry struct {
+	// Name is the name of the platform, update strategy or feature.
+	Name string `json:"name"`
+
+	// Maturity is how mature the support is.
+	Maturity Maturity `json:"maturity"`
+}
+
+// IsSupported returns true when the maturity denotes any level of support.
+func (m Maturity) IsSupported() bool {
+	return m != "" && m != MaturityUnsupported
+}
+
+// PlatformMaturity returns the maturity of the support for the platform type.
+func (s SupportMatrix) PlatformMaturity(platform configv1.PlatformType) Maturity {
+	return maturityOf(s.Platforms, string(platform))
+}
+
+// StrategyMaturity returns the maturity of the support for the update strategy type.
+func (s SupportMatrix) StrategyMaturity(strategy machinev1.ControlPlaneMachineSetStrategyType) Maturity {
+	return maturityOf(s.Strategies, string(strategy))
+}
+
+// FeatureMaturity returns the maturity of the support for the named feature.
+func (s SupportMatrix) FeatureMaturity(feature string) Maturity {
+	return maturityOf(s.Features, feature)
+}
+
+// maturityOf returns the maturity of the named entry, or MaturityUnsupported when it is not listed.
+func maturityOf(entries []SupportEntry, name string) Maturity {
+	for _, entry := range entries {
+		if entry.Name == name {
+			return entry.Maturity
+		}
+	}
+
+	return MaturityUnsupported
+}
+
+// SupportMatrixFromClusterOperator decodes the support matrix published within the extension of the status of the
+// ClusterOperator.
+func SupportMatrixFromClusterOperator(co *configv1.ClusterOperator) (*SupportMatrix, error) {
+	if len(co.Status.Extension.Raw) == 0 || string(co.Status.Extension.Raw) == "null" {
+		return nil, fmt.Errorf("%w on cluster operator %s", ErrSupportMatrixNotPublished, co.GetName())
+	}
+
+	supportMatrix := &SupportMatrix{}
+	if err := json.Unmarshal(co.Status.Extension.Raw, supportMatrix); err != nil {
+		return nil, fmt.Errorf("error decoding support matrix of cluster operator %s: %w", co.GetName(), err)
+	}
+
+	return supportMatrix, nil
+}
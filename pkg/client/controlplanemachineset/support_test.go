@@ -0,0 +1,87 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controlplanemachineset
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	configv1 "github.com/openshift/api/config/v1"
+	machinev1 "github.com/openshift/api/machine/v1"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/test/resourcebuilder"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+var _ = Describe("Support matrix", func() {
+	supportMatrix := SupportMatrix{
+		Platforms:  []SupportEntry{{Name: "AWS", Maturity: MaturityStable}, {Name: "Azure", Maturity: MaturityTechPreview}},
+		Strategies: []SupportEntry{{Name: "RollingUpdate", Maturity: MaturityStable}, {Name: "Recreate", Maturity: MaturityUnsupported}},
+		Features:   []SupportEntry{{Name: "UserPause", Maturity: MaturityStable}},
+	}
+
+	DescribeTable("PlatformMaturity", func(platform configv1.PlatformType, expected Maturity, expectedSupported bool) {
+		Expect(supportMatrix.PlatformMaturity(platform)).To(Equal(expected))
+		Expect(supportMatrix.PlatformMaturity(platform).IsSupported()).To(Equal(expectedSupported))
+	},
+		Entry("with a stable platform", configv1.AWSPlatformType, MaturityStable, true),
+		Entry("with a tech preview platform", configv1.AzurePlatformType, MaturityTechPreview, true),
+		Entry("with an unlisted platform", configv1.BareMetalPlatformType, MaturityUnsupported, false),
+	)
+
+	DescribeTable("StrategyMaturity", func(strategy machinev1.ControlPlaneMachineSetStrategyType, expected Maturity) {
+		Expect(supportMatrix.StrategyMaturity(strategy)).To(Equal(expected))
+	},
+		Entry("with a stable strategy", machinev1.RollingUpdate, MaturityStable),
+		Entry("with an unsupported strategy", machinev1.Recreate, MaturityUnsupported),
+		Entry("with an unlisted strategy", machinev1.OnDelete, MaturityUnsupported),
+	)
+
+	It("FeatureMaturity", func() {
+		Expect(supportMatrix.FeatureMaturity("UserPause")).To(Equal(MaturityStable))
+		Expect(supportMatrix.FeatureMaturity("Unknown")).To(Equal(MaturityUnsupported))
+	})
+
+	Context("SupportMatrixFromClusterOperator", func() {
+		var co *configv1.ClusterOperator
+
+		BeforeEach(func() {
+			co = resourcebuilder.ClusterOperator().WithName(DefaultOperatorName).Build()
+		})
+
+		It("decodes the published support matrix", func() {
+			co.Status.Extension = runtime.RawExtension{Raw: []byte(`{"platforms":[{"name":"AWS","maturity":"Stable"}],"strategies":[],"features":[]}`)}
+
+			Expect(SupportMatrixFromClusterOperator(co)).To(Equal(&SupportMatrix{
+				Platforms:  []SupportEntry{{Name: "AWS", Maturity: MaturityStable}},
+				Strategies: []SupportEntry{},
+				Features:   []SupportEntry{},
+			}))
+		})
+
+		It("returns an error when no support matrix is published", func() {
+			_, err := SupportMatrixFromClusterOperator(co)
+			Expect(err).To(MatchError(ErrSupportMatrixNotPublished))
+		})
+
+		It("returns an error when the support matrix cannot be decoded", func() {
+			co.Status.Extension = runtime.RawExtension{Raw: []byte(`{"platforms":"AWS"}`)}
+
+			_, err := SupportMatrixFromClusterOperator(co)
+			Expect(err).To(MatchError(ContainSubstring("error decoding support matrix of cluster operator control-plane-machine-set")))
+		})
+	})
+})
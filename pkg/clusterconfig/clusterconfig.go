@@ -0,0 +1,23 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package clusterconfig holds the names of the cluster scoped configuration resources that are read by more than one
+// component of the operator.
+package clusterconfig
+
+// InfrastructureName is the name of the cluster Infrastructure resource. The Infrastructure is a singleton that
+// describes the platform, the cluster ID, the user defined tags and the control plane topology of the cluster.
+const InfrastructureName = "cluster"
@@ -0,0 +1,269 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controlplanemachineset
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/go-logr/logr"
+	machinev1 "github.com/openshift/api/machine/v1"
+	machinev1beta1 "github.com/openshift/api/machine/v1beta1"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// AbandonedMachinePolicy determines how the ControlPlaneMachineSet handles abandoned surge Machines.
+// An abandoned surge Machine is a replacement Machine, created by a ControlPlaneMachineSet, within an index that
+// already has another ready, up to date Machine. These are left behind when an earlier version of the operator
+// failed to complete a replacement.
+type AbandonedMachinePolicy string
+
+const (
+	// AbandonedMachinePolicyIgnore means that abandoned surge Machines are reported, through a warning event on the
+	// ControlPlaneMachineSet, but otherwise left in place. This is the default policy.
+	AbandonedMachinePolicyIgnore AbandonedMachinePolicy = "Ignore"
+
+	// AbandonedMachinePolicyAdopt means that the abandoned surge Machine is adopted as the Machine for its index,
+	// completing the replacement it was created for. The other Machines within the index are removed.
+	AbandonedMachinePolicyAdopt AbandonedMachinePolicy = "Adopt"
+
+	// AbandonedMachinePolicyDelete means that the abandoned surge Machine is removed, leaving the Machine that it was
+	// created to replace.
+	AbandonedMachinePolicyDelete AbandonedMachinePolicy = "Delete"
+
+	// observedAbandonedMachines is a log message used to inform the user that an index contains abandoned surge
+	// Machines.
+	observedAbandonedMachines = "Observed abandoned surge machines"
+
+	// removingRedundantMachine is a log message used to inform the user that a redundant Machine has been deleted to
+	// resolve an abandoned surge Machine.
+	removingRedundantMachine = "Removing redundant machine"
+)
+
+// errUnknownAbandonedMachinePolicy is used to inform users that the abandoned machine policy they have provided is
+// not recognised.
+var errUnknownAbandonedMachinePolicy = errors.New("unknown abandoned machine policy")
+
+// ParseAbandonedMachinePolicy parses the abandoned machine policy given, as provided on the command line.
+func ParseAbandonedMachinePolicy(policy string) (AbandonedMachinePolicy, error) {
+	switch p := AbandonedMachinePolicy(policy); p {
+	case AbandonedMachinePolicyIgnore, AbandonedMachinePolicyAdopt, AbandonedMachinePolicyDelete:
+		return p, nil
+	default:
+		return "", fmt.Errorf("%w: %q", errUnknownAbandonedMachinePolicy, policy)
+	}
+}
+
+// redundantMachines describes an index that contains abandoned surge Machines.
+type redundantMachines struct {
+	// index is the index containing the abandoned surge Machines.
+	index int32
+
+	// keep is the Machine that will remain within the index.
+	keep machineproviders.MachineInfo
+
+	// remove are the Machines that will be removed from the index, as per the abandoned machine policy.
+	remove []machineproviders.MachineInfo
+}
+
+// reconcileAbandonedMachines detects abandoned surge Machines, and handles them as per the abandoned machine policy.
+// This runs when the operator starts, until the first time that no abandoned surge Machines are found, so that
+// indexes left with redundant Machines by earlier versions of the operator are recovered without manual intervention.
+// Machines are only removed, one at a time, while every Machine is ready and up to date.
+// It returns true when a Machine was removed.
+func (r *ControlPlaneMachineSetReconciler) reconcileAbandonedMachines(ctx context.Context, logger logr.Logger, cpms *machinev1.ControlPlaneMachineSet, machineProvider machineproviders.MachineProvider, machineInfos map[int32][]machineproviders.MachineInfo) (bool, error) {
+	if r.abandonedMachinesCollected {
+		return false, nil
+	}
+
+	// Wait for any ongoing operation to complete, the rollout logic is responsible for surge Machines in flight.
+	if !allMachinesSettled(machineInfos) {
+		return false, nil
+	}
+
+	redundant := findRedundantMachines(cpms, machineInfos, r.AbandonedMachinePolicy)
+	if len(redundant) == 0 {
+		r.abandonedMachinesCollected = true
+		return false, nil
+	}
+
+	for _, index := range redundant {
+		logger.V(1).Info(observedAbandonedMachines,
+			"index", index.index,
+			"policy", string(r.AbandonedMachinePolicy),
+			"keepMachineName", index.keep.MachineRef.ObjectMeta.GetName(),
+			"redundantMachineNames", strings.Join(machineInfoNames(index.remove), ","),
+		)
+	}
+
+	if r.AbandonedMachinePolicy != AbandonedMachinePolicyAdopt && r.AbandonedMachinePolicy != AbandonedMachinePolicyDelete {
+		// The search stops here, so publish an event to make sure the abandoned Machines are not only logged.
+		r.publishEvent(cpms, corev1.EventTypeWarning, reasonAbandonedSurgeMachinesIgnored, ignoredAbandonedMachinesMessage(redundant))
+
+		r.abandonedMachinesCollected = true
+		return false, nil
+	}
+
+	machineInfo := redundant[0].remove[0]
+	machineLogger := machineInfoLogger(logger, machineInfo)
+
+	if err := machineProvider.DeleteMachine(ctx, machineLogger, machineInfo.MachineRef); err != nil {
+		werr := fmt.Errorf("error deleting redundant machine %s: %w", machineInfo.MachineRef.ObjectMeta.GetName(), err)
+		machineLogger.Error(werr, errorDeletingMachine)
+
+		return false, werr
+	}
+
+	machineLogger.V(2).Info(removingRedundantMachine, "keepMachineName", redundant[0].keep.MachineRef.ObjectMeta.GetName())
+
+	return true, nil
+}
+
+// ignoredAbandonedMachinesMessage describes the abandoned surge Machines left in place by the Ignore policy.
+func ignoredAbandonedMachinesMessage(redundant []redundantMachines) string {
+	indexes := []string{}
+
+	for _, index := range redundant {
+		indexes = append(indexes, fmt.Sprintf("index %d has %s alongside %s",
+			index.index, strings.Join(machineInfoNames(index.remove), ", "), index.keep.MachineRef.ObjectMeta.GetName()))
+	}
+
+	return fmt.Sprintf("Ignoring abandoned surge machines as per the abandoned machine policy: %s", strings.Join(indexes, "; "))
+}
+
+// findRedundantMachines finds the indexes that contain an abandoned surge Machine alongside other Machines, and
+// determines which Machine within each index should be kept as per the abandoned machine policy.
+// With the Adopt policy, the oldest surge Machine is kept. Otherwise, the oldest Machine not created by the
+// ControlPlaneMachineSet is kept, and only surge Machines are removed.
+func findRedundantMachines(cpms *machinev1.ControlPlaneMachineSet, machineInfos map[int32][]machineproviders.MachineInfo, policy AbandonedMachinePolicy) []redundantMachines {
+	redundant := []redundantMachines{}
+	preferSurge := policy == AbandonedMachinePolicyAdopt
+
+	for _, idx := range sortedIndexes(machineInfos) {
+		if len(machineInfos[idx]) < 2 {
+			continue
+		}
+
+		candidates := append([]machineproviders.MachineInfo{}, machineInfos[idx]...)
+		surge := map[string]bool{}
+		hasSurge := false
+
+		for _, machineInfo := range candidates {
+			surge[machineInfo.MachineRef.ObjectMeta.GetName()] = isSurgeMachine(cpms, machineInfo)
+			hasSurge = hasSurge || surge[machineInfo.MachineRef.ObjectMeta.GetName()]
+		}
+
+		// Only indexes containing an abandoned surge Machine are redundant, other duplicates are not ours to resolve.
+		if !hasSurge {
+			continue
+		}
+
+		sort.SliceStable(candidates, func(i, j int) bool {
+			iName, jName := candidates[i].MachineRef.ObjectMeta.GetName(), candidates[j].MachineRef.ObjectMeta.GetName()
+			if surge[iName] != surge[jName] {
+				return surge[iName] == preferSurge
+			}
+
+			iCreated, jCreated := candidates[i].MachineRef.ObjectMeta.GetCreationTimestamp(), candidates[j].MachineRef.ObjectMeta.GetCreationTimestamp()
+			if !iCreated.Equal(&jCreated) {
+				return iCreated.Before(&jCreated)
+			}
+
+			return iName < jName
+		})
+
+		remove := []machineproviders.MachineInfo{}
+
+		for _, machineInfo := range candidates[1:] {
+			if preferSurge || surge[machineInfo.MachineRef.ObjectMeta.GetName()] {
+				remove = append(remove, machineInfo)
+			}
+		}
+
+		redundant = append(redundant, redundantMachines{
+			index:  idx,
+			keep:   candidates[0],
+			remove: remove,
+		})
+	}
+
+	return redundant
+}
+
+// allMachinesSettled determines whether every Machine is ready, up to date and not being deleted.
+func allMachinesSettled(machineInfos map[int32][]machineproviders.MachineInfo) bool {
+	for _, indexMachineInfos := range machineInfos {
+		for _, machineInfo := range indexMachineInfos {
+			if machineInfo.MachineRef == nil || !machineInfo.Ready || machineInfo.NeedsUpdate || machineInfo.MachineRef.ObjectMeta.GetDeletionTimestamp() != nil {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// isSurgeMachine determines whether the Machine was created by the ControlPlaneMachineSet as a replacement.
+// Replacements are controlled by the ControlPlaneMachineSet and are named with a random suffix ahead of their index,
+// for example "<cluster-id>-master-abcde-0", whereas the installer names Machines "<cluster-id>-master-0".
+func isSurgeMachine(cpms *machinev1.ControlPlaneMachineSet, machineInfo machineproviders.MachineInfo) bool {
+	return isSurgeMachineName(cpms, machineInfo.MachineRef.ObjectMeta.GetName()) && isControlledBy(cpms, machineInfo)
+}
+
+// isSurgeMachineName determines whether the Machine name matches the naming of replacement Machines.
+func isSurgeMachineName(cpms *machinev1.ControlPlaneMachineSet, name string) bool {
+	if cpms.Spec.Template.OpenShiftMachineV1Beta1Machine == nil {
+		return false
+	}
+
+	clusterID, ok := cpms.Spec.Template.OpenShiftMachineV1Beta1Machine.ObjectMeta.Labels[machinev1beta1.MachineClusterIDLabel]
+	prefix := fmt.Sprintf("%s-master-", clusterID)
+
+	if !ok || !strings.HasPrefix(name, prefix) {
+		return false
+	}
+
+	parts := strings.Split(strings.TrimPrefix(name, prefix), "-")
+
+	return len(parts) == 2 && parts[0] != "" && parts[1] != ""
+}
+
+// isControlledBy determines whether the Machine has a controller owner reference to the ControlPlaneMachineSet.
+func isControlledBy(cpms *machinev1.ControlPlaneMachineSet, machineInfo machineproviders.MachineInfo) bool {
+	for _, ownerRef := range machineInfo.MachineRef.ObjectMeta.GetOwnerReferences() {
+		if ownerRef.Controller != nil && *ownerRef.Controller && ownerRef.Kind == "ControlPlaneMachineSet" && ownerRef.Name == cpms.GetName() {
+			return true
+		}
+	}
+
+	return false
+}
+
+// machineInfoNames returns the names of the Machines given.
+func machineInfoNames(machineInfos []machineproviders.MachineInfo) []string {
+	names := []string{}
+
+	for _, machineInfo := range machineInfos {
+		names = append(names, machineInfo.MachineRef.ObjectMeta.GetName())
+	}
+
+	return names
+}
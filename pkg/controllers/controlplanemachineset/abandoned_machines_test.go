@@ -0,0 +1,229 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controlplanemachineset
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	machinev1 "github.com/openshift/api/machine/v1"
+	machinev1beta1 "github.com/openshift/api/machine/v1beta1"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders/mock"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/test"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/test/resourcebuilder"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/pointer"
+)
+
+var _ = Describe("reconcileAbandonedMachines", func() {
+	var logger test.TestLogger
+	var reconciler *ControlPlaneMachineSetReconciler
+	var cpms *machinev1.ControlPlaneMachineSet
+
+	var mockCtrl *gomock.Controller
+	var mockMachineProvider *mock.MockMachineProvider
+
+	machineGVR := machinev1beta1.GroupVersion.WithResource("machines")
+	created := time.Date(2022, time.June, 1, 0, 0, 0, 0, time.UTC)
+
+	cpmsOwnerReference := metav1.OwnerReference{
+		APIVersion: machinev1.GroupVersion.String(),
+		Kind:       "ControlPlaneMachineSet",
+		Name:       DefaultControlPlaneMachineSetName,
+		Controller: pointer.Bool(true),
+	}
+
+	machineBuilder := resourcebuilder.MachineInfo().
+		WithMachineGVR(machineGVR).
+		WithReady(true).
+		WithNeedsUpdate(false)
+
+	originalMachine := func(idx int32) machineproviders.MachineInfo {
+		return machineBuilder.WithIndex(idx).
+			WithMachineName(fmt.Sprintf("cpms-cluster-test-id-master-%d", idx)).
+			WithMachineCreationTimestamp(metav1.NewTime(created)).
+			Build()
+	}
+
+	surgeMachine := func(idx int32) machineproviders.MachineInfo {
+		return machineBuilder.WithIndex(idx).
+			WithMachineName(fmt.Sprintf("cpms-cluster-test-id-master-abcde-%d", idx)).
+			WithMachineCreationTimestamp(metav1.NewTime(created.Add(time.Hour))).
+			WithMachineOwnerReference(cpmsOwnerReference).
+			Build()
+	}
+
+	BeforeEach(func() {
+		logger = test.NewTestLogger()
+		reconciler = &ControlPlaneMachineSetReconciler{}
+		cpms = resourcebuilder.ControlPlaneMachineSet().WithName(DefaultControlPlaneMachineSetName).Build()
+
+		mockCtrl = gomock.NewController(GinkgoT())
+		mockMachineProvider = mock.NewMockMachineProvider(mockCtrl)
+	})
+
+	Context("with an abandoned surge machine", func() {
+		var machineInfos map[int32][]machineproviders.MachineInfo
+
+		BeforeEach(func() {
+			machineInfos = map[int32][]machineproviders.MachineInfo{
+				0: {originalMachine(0)},
+				1: {originalMachine(1), surgeMachine(1)},
+				2: {originalMachine(2)},
+			}
+		})
+
+		It("should remove the surge machine with the Delete policy", func() {
+			reconciler.AbandonedMachinePolicy = AbandonedMachinePolicyDelete
+			mockMachineProvider.EXPECT().DeleteMachine(gomock.Any(), gomock.Any(), surgeMachine(1).MachineRef).Return(nil).Times(1)
+
+			Expect(reconciler.reconcileAbandonedMachines(ctx, logger.Logger(), cpms, mockMachineProvider, machineInfos)).To(BeTrue())
+			Expect(reconciler.abandonedMachinesCollected).To(BeFalse())
+			Expect(logger.Entries()).To(ConsistOf(
+				test.LogEntry{
+					Level: 1,
+					KeysAndValues: []interface{}{
+						"index", int32(1),
+						"policy", "Delete",
+						"keepMachineName", "cpms-cluster-test-id-master-1",
+						"redundantMachineNames", "cpms-cluster-test-id-master-abcde-1",
+					},
+					Message: observedAbandonedMachines,
+				},
+				test.LogEntry{
+					Level:         2,
+					KeysAndValues: []interface{}{"index", int32(1), "namespace", "", "name", "cpms-cluster-test-id-master-abcde-1", "keepMachineName", "cpms-cluster-test-id-master-1"},
+					Message:       removingRedundantMachine,
+				},
+			))
+		})
+
+		It("should remove the original machine with the Adopt policy", func() {
+			reconciler.AbandonedMachinePolicy = AbandonedMachinePolicyAdopt
+			mockMachineProvider.EXPECT().DeleteMachine(gomock.Any(), gomock.Any(), originalMachine(1).MachineRef).Return(nil).Times(1)
+
+			Expect(reconciler.reconcileAbandonedMachines(ctx, logger.Logger(), cpms, mockMachineProvider, machineInfos)).To(BeTrue())
+		})
+
+		It("should only report the surge machine with the Ignore policy", func() {
+			recorder := record.NewFakeRecorder(10)
+			reconciler.Recorder = recorder
+			reconciler.AbandonedMachinePolicy = AbandonedMachinePolicyIgnore
+			mockMachineProvider.EXPECT().DeleteMachine(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+
+			Expect(reconciler.reconcileAbandonedMachines(ctx, logger.Logger(), cpms, mockMachineProvider, machineInfos)).To(BeFalse())
+			Expect(reconciler.abandonedMachinesCollected).To(BeTrue())
+			Expect(logger.Entries()).To(HaveLen(1))
+			Expect(recorder.Events).To(Receive(Equal("Warning AbandonedSurgeMachinesIgnored Ignoring abandoned surge machines as per the abandoned machine policy: " +
+				"index 1 has cpms-cluster-test-id-master-abcde-1 alongside cpms-cluster-test-id-master-1")))
+
+			By("Not reporting the surge machine again once abandoned machines have been collected")
+			Expect(reconciler.reconcileAbandonedMachines(ctx, logger.Logger(), cpms, mockMachineProvider, machineInfos)).To(BeFalse())
+			Expect(recorder.Events).To(BeEmpty())
+		})
+
+		It("should not publish an event when the surge machine is removed", func() {
+			recorder := record.NewFakeRecorder(10)
+			reconciler.Recorder = recorder
+			reconciler.AbandonedMachinePolicy = AbandonedMachinePolicyDelete
+			mockMachineProvider.EXPECT().DeleteMachine(gomock.Any(), gomock.Any(), surgeMachine(1).MachineRef).Return(nil).Times(1)
+
+			Expect(reconciler.reconcileAbandonedMachines(ctx, logger.Logger(), cpms, mockMachineProvider, machineInfos)).To(BeTrue())
+			Expect(recorder.Events).To(BeEmpty())
+		})
+
+		It("should not act once abandoned machines have been collected", func() {
+			reconciler.AbandonedMachinePolicy = AbandonedMachinePolicyDelete
+			reconciler.abandonedMachinesCollected = true
+			mockMachineProvider.EXPECT().DeleteMachine(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+
+			Expect(reconciler.reconcileAbandonedMachines(ctx, logger.Logger(), cpms, mockMachineProvider, machineInfos)).To(BeFalse())
+			Expect(logger.Entries()).To(BeEmpty())
+		})
+
+		It("should wait while another index is being updated", func() {
+			reconciler.AbandonedMachinePolicy = AbandonedMachinePolicyDelete
+			machineInfos[2] = []machineproviders.MachineInfo{machineBuilder.WithIndex(2).WithMachineName("cpms-cluster-test-id-master-2").WithNeedsUpdate(true).Build()}
+			mockMachineProvider.EXPECT().DeleteMachine(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+
+			Expect(reconciler.reconcileAbandonedMachines(ctx, logger.Logger(), cpms, mockMachineProvider, machineInfos)).To(BeFalse())
+			Expect(reconciler.abandonedMachinesCollected).To(BeFalse())
+		})
+
+		It("should return an error when the machine cannot be deleted", func() {
+			reconciler.AbandonedMachinePolicy = AbandonedMachinePolicyDelete
+			transientError := errors.New("transient error")
+			mockMachineProvider.EXPECT().DeleteMachine(gomock.Any(), gomock.Any(), gomock.Any()).Return(transientError).Times(1)
+
+			removed, err := reconciler.reconcileAbandonedMachines(ctx, logger.Logger(), cpms, mockMachineProvider, machineInfos)
+			Expect(removed).To(BeFalse())
+			Expect(err).To(MatchError(transientError))
+		})
+	})
+
+	It("should not treat a surge named machine without a controller reference as abandoned", func() {
+		reconciler.AbandonedMachinePolicy = AbandonedMachinePolicyDelete
+		unowned := machineBuilder.WithIndex(1).WithMachineName("cpms-cluster-test-id-master-abcde-1").Build()
+		machineInfos := map[int32][]machineproviders.MachineInfo{
+			0: {originalMachine(0)},
+			1: {originalMachine(1), unowned},
+		}
+		mockMachineProvider.EXPECT().DeleteMachine(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+
+		Expect(reconciler.reconcileAbandonedMachines(ctx, logger.Logger(), cpms, mockMachineProvider, machineInfos)).To(BeFalse())
+		Expect(reconciler.abandonedMachinesCollected).To(BeTrue())
+	})
+
+	It("should stop searching when there are no abandoned machines", func() {
+		reconciler.AbandonedMachinePolicy = AbandonedMachinePolicyDelete
+		machineInfos := map[int32][]machineproviders.MachineInfo{
+			0: {originalMachine(0)},
+			1: {surgeMachine(1)},
+			2: {originalMachine(2)},
+		}
+		mockMachineProvider.EXPECT().DeleteMachine(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+
+		Expect(reconciler.reconcileAbandonedMachines(ctx, logger.Logger(), cpms, mockMachineProvider, machineInfos)).To(BeFalse())
+		Expect(reconciler.abandonedMachinesCollected).To(BeTrue())
+		Expect(logger.Entries()).To(BeEmpty())
+	})
+})
+
+var _ = Describe("ParseAbandonedMachinePolicy", func() {
+	DescribeTable("should parse the policy", func(policy string, expected AbandonedMachinePolicy, expectedErr error) {
+		parsed, err := ParseAbandonedMachinePolicy(policy)
+		if expectedErr != nil {
+			Expect(err).To(MatchError(expectedErr))
+		} else {
+			Expect(err).ToNot(HaveOccurred())
+		}
+
+		Expect(parsed).To(Equal(expected))
+	},
+		Entry("with Ignore", "Ignore", AbandonedMachinePolicyIgnore, nil),
+		Entry("with Adopt", "Adopt", AbandonedMachinePolicyAdopt, nil),
+		Entry("with Delete", "Delete", AbandonedMachinePolicyDelete, nil),
+		Entry("with an unknown policy", "Orphan", AbandonedMachinePolicy(""), errUnknownAbandonedMachinePolicy),
+	)
+})
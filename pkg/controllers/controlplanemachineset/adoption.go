@@ -0,0 +1,157 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controlplanemachineset
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/go-logr/logr"
+	machinev1 "github.com/openshift/api/machine/v1"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func init() {
+	registerInformationalCondition(conditionAdoption)
+}
+
+const (
+	// adoptionModeAnnotation is the annotation used to adopt Control Plane Machines that were previously managed
+	// manually. When set to "true", the ControlPlaneMachineSet begins in an adoption phase, in which no Machine is
+	// created or deleted, until the existing Machines have been verified and the adoption has been confirmed.
+	adoptionModeAnnotation = "controlplanemachineset.machine.openshift.io/adoption-mode"
+
+	// adoptionConfirmedAnnotation is the annotation used to confirm the adoption of the existing Control Plane
+	// Machines. When set to "true", the adoption completes as soon as the existing Machines have been verified.
+	adoptionConfirmedAnnotation = "controlplanemachineset.machine.openshift.io/adoption-confirmed"
+
+	// adoptionPendingState is a log message used to inform the user that the ControlPlaneMachineSet will not take
+	// any action, as the adoption of the existing Machines has not completed.
+	adoptionPendingState = "Control plane machine set is adopting existing machines. The control plane machine set will not take any action until the adoption is complete."
+
+	// completedAdoption is a log message used to inform the user that the adoption of the existing Machines has
+	// completed.
+	completedAdoption = "Completed adoption of existing control plane machines"
+)
+
+// reconcileAdoption verifies the existing Control Plane Machines while the ControlPlaneMachineSet is in its adoption
+// phase, and reports the outcome within the Adoption condition.
+// The existing Machines are verified when every Machine is mapped to an index within the replicas, every index has a
+// Machine, and at least one Machine matches the template, so that completing the adoption does not, by itself,
+// cause Machines to be created or the whole Control Plane to be replaced.
+// The adoption completes once the existing Machines are verified and the adoption has been confirmed. Once complete,
+// the Machines are not verified again, so that later changes to the template roll out as normal.
+// Without the adoption mode annotation, there is no adoption phase and the condition is removed.
+func reconcileAdoption(logger logr.Logger, cpms *machinev1.ControlPlaneMachineSet, machineInfos map[int32][]machineproviders.MachineInfo) {
+	if cpms.GetAnnotations()[adoptionModeAnnotation] != "true" {
+		meta.RemoveStatusCondition(&cpms.Status.Conditions, conditionAdoption)
+
+		return
+	}
+
+	if meta.IsStatusConditionTrue(cpms.Status.Conditions, conditionAdoption) {
+		return
+	}
+
+	condition := metav1.Condition{
+		Type:               conditionAdoption,
+		Status:             metav1.ConditionFalse,
+		ObservedGeneration: cpms.GetGeneration(),
+	}
+
+	machineCount, problems := verifyAdoptedMachines(cpms, machineInfos)
+
+	switch {
+	case len(problems) > 0:
+		condition.Reason = reasonAdoptionVerificationFailed
+		condition.Message = fmt.Sprintf("Existing control plane machines cannot be adopted: %s", strings.Join(problems, "; "))
+	case cpms.GetAnnotations()[adoptionConfirmedAnnotation] != "true":
+		condition.Reason = reasonAwaitingAdoptionConfirmation
+		condition.Message = fmt.Sprintf("Verified %d existing control plane machine(s), set the %s annotation to \"true\" to complete the adoption", machineCount, adoptionConfirmedAnnotation)
+	default:
+		condition.Status = metav1.ConditionTrue
+		condition.Reason = reasonAdopted
+		condition.Message = fmt.Sprintf("Adopted %d existing control plane machine(s)", machineCount)
+
+		logger.Info(completedAdoption, "machineCount", machineCount)
+	}
+
+	meta.SetStatusCondition(&cpms.Status.Conditions, condition)
+}
+
+// verifyAdoptedMachines verifies the existing Control Plane Machines for adoption. It returns the number of existing
+// Machines, and a description of each problem that prevents their adoption.
+// Machines pending deletion are ignored, as they are about to be removed.
+func verifyAdoptedMachines(cpms *machinev1.ControlPlaneMachineSet, machineInfos map[int32][]machineproviders.MachineInfo) (int, []string) {
+	replicas := int32(0)
+	if cpms.Spec.Replicas != nil {
+		replicas = *cpms.Spec.Replicas
+	}
+
+	problems := []string{}
+	machineCount := 0
+	matchesTemplate := false
+
+	for _, idx := range sortedIndexes(machineInfos) {
+		indexMachines := 0
+
+		for _, machineInfo := range machineInfos[idx] {
+			if machineInfo.MachineRef == nil || machineInfo.MachineRef.ObjectMeta.GetDeletionTimestamp() != nil {
+				continue
+			}
+
+			indexMachines++
+
+			if idx >= replicas {
+				problems = append(problems, fmt.Sprintf("machine %s is mapped to index %d, outside of the %d replicas", machineInfo.MachineRef.ObjectMeta.GetName(), idx, replicas))
+			}
+
+			if !machineInfo.NeedsUpdate {
+				matchesTemplate = true
+			}
+		}
+
+		machineCount += indexMachines
+
+		if indexMachines == 0 && idx < replicas {
+			problems = append(problems, fmt.Sprintf("no machine is mapped to index %d", idx))
+		}
+	}
+
+	if machineCount > 0 && !matchesTemplate {
+		problems = append(problems, "the template does not match any existing machine")
+	}
+
+	return machineCount, problems
+}
+
+// isAdoptionPending determines whether the ControlPlaneMachineSet is in its adoption phase, and the adoption has
+// not yet completed.
+func isAdoptionPending(cpms *machinev1.ControlPlaneMachineSet) bool {
+	return cpms.GetAnnotations()[adoptionModeAnnotation] == "true" && !meta.IsStatusConditionTrue(cpms.Status.Conditions, conditionAdoption)
+}
+
+// adoptionGate creates a gate for a ControlPlaneMachineSet whose adoption of the existing Machines has not
+// completed, which blocks all replacements until the adoption completes.
+func adoptionGate() gate {
+	return gate{
+		reason:      reasonGatedByAdoption,
+		description: fmt.Sprintf("Replacements are blocked until the adoption of the existing machines is complete, see the %s condition", conditionAdoption),
+	}
+}
@@ -0,0 +1,187 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controlplanemachineset
+
+import (
+	"github.com/golang/mock/gomock"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	machinev1 "github.com/openshift/api/machine/v1"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders/mock"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/test"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/test/resourcebuilder"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("Adoption", func() {
+	machineBuilder := resourcebuilder.MachineInfo().WithReady(true).WithNodeName("node")
+
+	adoptionAnnotations := func(confirmed bool) map[string]string {
+		annotations := map[string]string{adoptionModeAnnotation: "true"}
+		if confirmed {
+			annotations[adoptionConfirmedAnnotation] = "true"
+		}
+
+		return annotations
+	}
+
+	Context("reconcileAdoption", func() {
+		var logger test.TestLogger
+
+		BeforeEach(func() {
+			logger = test.NewTestLogger()
+		})
+
+		verifiedMachineInfos := func() map[int32][]machineproviders.MachineInfo {
+			return map[int32][]machineproviders.MachineInfo{
+				0: {machineBuilder.WithIndex(0).WithMachineName("machine-0").WithNeedsUpdate(true).Build()},
+				1: {machineBuilder.WithIndex(1).WithMachineName("machine-1").Build()},
+				2: {machineBuilder.WithIndex(2).WithMachineName("machine-2").Build()},
+			}
+		}
+
+		It("does not set the condition without the adoption mode annotation", func() {
+			cpms := resourcebuilder.ControlPlaneMachineSet().Build()
+
+			reconcileAdoption(logger.Logger(), cpms, verifiedMachineInfos())
+
+			Expect(meta.FindStatusCondition(cpms.Status.Conditions, conditionAdoption)).To(BeNil())
+			Expect(isAdoptionPending(cpms)).To(BeFalse())
+		})
+
+		It("waits for confirmation once the machines are verified", func() {
+			cpms := resourcebuilder.ControlPlaneMachineSet().WithAnnotations(adoptionAnnotations(false)).Build()
+
+			reconcileAdoption(logger.Logger(), cpms, verifiedMachineInfos())
+
+			Expect(cpms.Status.Conditions).To(ConsistOf(test.MatchCondition(metav1.Condition{
+				Type:    conditionAdoption,
+				Status:  metav1.ConditionFalse,
+				Reason:  reasonAwaitingAdoptionConfirmation,
+				Message: "Verified 3 existing control plane machine(s), set the controlplanemachineset.machine.openshift.io/adoption-confirmed annotation to \"true\" to complete the adoption",
+			})))
+			Expect(isAdoptionPending(cpms)).To(BeTrue())
+		})
+
+		It("completes the adoption once confirmed", func() {
+			cpms := resourcebuilder.ControlPlaneMachineSet().WithAnnotations(adoptionAnnotations(true)).Build()
+
+			reconcileAdoption(logger.Logger(), cpms, verifiedMachineInfos())
+
+			Expect(cpms.Status.Conditions).To(ConsistOf(test.MatchCondition(metav1.Condition{
+				Type:    conditionAdoption,
+				Status:  metav1.ConditionTrue,
+				Reason:  reasonAdopted,
+				Message: "Adopted 3 existing control plane machine(s)",
+			})))
+			Expect(isAdoptionPending(cpms)).To(BeFalse())
+			Expect(logger.Entries()).To(ConsistOf(test.LogEntry{
+				KeysAndValues: []interface{}{"machineCount", 3},
+				Message:       completedAdoption,
+			}))
+		})
+
+		It("does not verify the machines again once adopted", func() {
+			cpms := resourcebuilder.ControlPlaneMachineSet().WithAnnotations(adoptionAnnotations(true)).Build()
+			reconcileAdoption(logger.Logger(), cpms, verifiedMachineInfos())
+
+			reconcileAdoption(logger.Logger(), cpms, map[int32][]machineproviders.MachineInfo{
+				0: {machineBuilder.WithIndex(0).WithMachineName("machine-0").WithNeedsUpdate(true).Build()},
+				1: {machineBuilder.WithIndex(1).WithMachineName("machine-1").WithNeedsUpdate(true).Build()},
+				2: {machineBuilder.WithIndex(2).WithMachineName("machine-2").WithNeedsUpdate(true).Build()},
+			})
+
+			Expect(meta.FindStatusCondition(cpms.Status.Conditions, conditionAdoption)).To(HaveField("Reason", Equal(reasonAdopted)))
+		})
+
+		It("does not complete the adoption when the machines cannot be verified", func() {
+			cpms := resourcebuilder.ControlPlaneMachineSet().WithAnnotations(adoptionAnnotations(true)).Build()
+
+			reconcileAdoption(logger.Logger(), cpms, map[int32][]machineproviders.MachineInfo{
+				0: {machineBuilder.WithIndex(0).WithMachineName("machine-0").WithNeedsUpdate(true).Build()},
+				1: {},
+				2: {machineBuilder.WithIndex(2).WithMachineName("machine-2").WithNeedsUpdate(true).Build()},
+				3: {machineBuilder.WithIndex(3).WithMachineName("machine-3").WithNeedsUpdate(true).Build()},
+			})
+
+			Expect(cpms.Status.Conditions).To(ConsistOf(test.MatchCondition(metav1.Condition{
+				Type:    conditionAdoption,
+				Status:  metav1.ConditionFalse,
+				Reason:  reasonAdoptionVerificationFailed,
+				Message: "Existing control plane machines cannot be adopted: no machine is mapped to index 1; machine machine-3 is mapped to index 3, outside of the 3 replicas; the template does not match any existing machine",
+			})))
+			Expect(isAdoptionPending(cpms)).To(BeTrue())
+		})
+	})
+
+	Context("when reconciling machines with the adoption pending", func() {
+		var logger test.TestLogger
+		var reconciler *ControlPlaneMachineSetReconciler
+		var cpms *machinev1.ControlPlaneMachineSet
+		var namespaceName string
+
+		BeforeEach(func() {
+			By("Setting up a namespace for the test")
+			ns := resourcebuilder.Namespace().WithGenerateName("control-plane-machine-set-adoption-").Build()
+			Expect(k8sClient.Create(ctx, ns)).To(Succeed())
+			namespaceName = ns.GetName()
+
+			logger = test.NewTestLogger()
+			reconciler = &ControlPlaneMachineSetReconciler{
+				Client: k8sClient,
+				Scheme: testScheme,
+			}
+
+			cpms = resourcebuilder.ControlPlaneMachineSet().WithNamespace(namespaceName).WithStrategyType(machinev1.RollingUpdate).WithReplicas(3).
+				WithAnnotations(adoptionAnnotations(false)).Build()
+
+			machineInfos := map[int32][]machineproviders.MachineInfo{
+				0: {machineBuilder.WithIndex(0).WithMachineName("machine-0").WithNeedsUpdate(true).Build()},
+				1: {machineBuilder.WithIndex(1).WithMachineName("machine-1").Build()},
+				2: {machineBuilder.WithIndex(2).WithMachineName("machine-2").Build()},
+			}
+
+			// The mock machine provider fails the test on any unexpected call, so no Machine may be created or
+			// deleted while the adoption is pending.
+			mockMachineProvider := mock.NewMockMachineProvider(gomock.NewController(GinkgoT()))
+
+			_, err := reconciler.reconcileMachines(ctx, logger.Logger(), cpms, mockMachineProvider, machineInfos)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		AfterEach(func() {
+			test.CleanupResources(Default, ctx, cfg, k8sClient, namespaceName,
+				&corev1.ConfigMap{},
+			)
+		})
+
+		It("sets the gated by condition", func() {
+			Expect(meta.FindStatusCondition(cpms.Status.Conditions, conditionGatedBy)).To(HaveField("Reason", Equal(reasonGatedByAdoption)))
+		})
+
+		It("logs that no action will be taken", func() {
+			Expect(logger.Entries()).To(ContainElement(test.LogEntry{
+				Level:   1,
+				Message: adoptionPendingState,
+			}))
+		})
+	})
+})
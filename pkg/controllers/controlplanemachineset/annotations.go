@@ -0,0 +1,38 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controlplanemachineset
+
+import (
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// ValidateAnnotations checks the annotations of a ControlPlaneMachineSet that configure the controller.
+// This allows the webhook to reject an invalid value when the ControlPlaneMachineSet is admitted, rather than the
+// controller marking the ControlPlaneMachineSet as degraded once it is reconciled.
+func ValidateAnnotations(fldPath *field.Path, annotations map[string]string) field.ErrorList {
+	errs := field.ErrorList{}
+
+	if _, err := parseStageTimeouts(annotations); err != nil {
+		errs = append(errs, field.Invalid(fldPath.Key(stageTimeoutsAnnotation), annotations[stageTimeoutsAnnotation], err.Error()))
+	}
+
+	if _, err := parseReplacementBudget(annotations); err != nil {
+		errs = append(errs, field.Invalid(fldPath.Key(replacementBudgetAnnotation), annotations[replacementBudgetAnnotation], err.Error()))
+	}
+
+	return errs
+}
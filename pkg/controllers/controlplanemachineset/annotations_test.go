@@ -0,0 +1,74 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controlplanemachineset
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+var _ = Describe("ValidateAnnotations", func() {
+	type validateAnnotationsTableInput struct {
+		annotations    map[string]string
+		expectedFields []string
+	}
+
+	DescribeTable("should report each invalid annotation", func(in validateAnnotationsTableInput) {
+		errs := ValidateAnnotations(field.NewPath("metadata", "annotations"), in.annotations)
+
+		fields := []string{}
+		for _, err := range errs {
+			Expect(err.Type).To(Equal(field.ErrorTypeInvalid))
+			fields = append(fields, err.Field)
+		}
+
+		Expect(fields).To(ConsistOf(in.expectedFields))
+	},
+		Entry("with no annotations", validateAnnotationsTableInput{
+			annotations:    nil,
+			expectedFields: []string{},
+		}),
+		Entry("with valid stage timeouts", validateAnnotationsTableInput{
+			annotations:    map[string]string{stageTimeoutsAnnotation: "deletion=2h,drain=45m"},
+			expectedFields: []string{},
+		}),
+		Entry("with invalid stage timeouts", validateAnnotationsTableInput{
+			annotations:    map[string]string{stageTimeoutsAnnotation: "node=20m"},
+			expectedFields: []string{"metadata.annotations[controlplanemachineset.machine.openshift.io/timeouts]"},
+		}),
+		Entry("with a valid replacement budget", validateAnnotationsTableInput{
+			annotations:    map[string]string{replacementBudgetAnnotation: "1/24h"},
+			expectedFields: []string{},
+		}),
+		Entry("with an invalid replacement budget", validateAnnotationsTableInput{
+			annotations:    map[string]string{replacementBudgetAnnotation: "0/24h"},
+			expectedFields: []string{"metadata.annotations[controlplanemachineset.machine.openshift.io/replacement-budget]"},
+		}),
+		Entry("with several invalid annotations", validateAnnotationsTableInput{
+			annotations: map[string]string{
+				stageTimeoutsAnnotation:     "drain",
+				replacementBudgetAnnotation: "daily",
+			},
+			expectedFields: []string{
+				"metadata.annotations[controlplanemachineset.machine.openshift.io/timeouts]",
+				"metadata.annotations[controlplanemachineset.machine.openshift.io/replacement-budget]",
+			},
+		}),
+	)
+})
@@ -0,0 +1,258 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controlplanemachineset
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/go-logr/logr"
+	machinev1 "github.com/openshift/api/machine/v1"
+	machinev1beta1 "github.com/openshift/api/machine/v1beta1"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func init() {
+	registerInformationalCondition(conditionAutoscalerIncompatibility)
+}
+
+const (
+	// scaleDownDisabledAnnotation is the annotation that the cluster autoscaler honours to exclude a Node from scale
+	// down. It is set on the Control Plane Machines, and their Nodes, so that the autoscaler never removes them.
+	scaleDownDisabledAnnotation = "cluster-autoscaler.kubernetes.io/scale-down-disabled"
+
+	// controlPlaneMachineSetKind is the kind of the scale target of a MachineAutoscaler that targets the
+	// ControlPlaneMachineSet itself.
+	controlPlaneMachineSetKind = "ControlPlaneMachineSet"
+
+	// machineSetKind is the kind of the scale target of a MachineAutoscaler that targets a MachineSet.
+	machineSetKind = "MachineSet"
+
+	// observedControlPlaneAutoscaler is a log message used to inform the user that a MachineAutoscaler targets the
+	// Control Plane Machines.
+	observedControlPlaneAutoscaler = "Observed machine autoscaler targeting control plane machines"
+
+	// disabledMachineScaleDown is a log message used to inform the user that a Control Plane Machine has been
+	// annotated to exclude it from autoscaler scale down.
+	disabledMachineScaleDown = "Disabled autoscaler scale down for machine"
+
+	// disabledNodeScaleDown is a log message used to inform the user that the Node of a Control Plane Machine has
+	// been annotated to exclude it from autoscaler scale down.
+	disabledNodeScaleDown = "Disabled autoscaler scale down for node"
+)
+
+// machineAutoscalerListGVK is the GroupVersionKind of a list of MachineAutoscalers. The autoscaling API is not
+// vendored, and is only served once the cluster autoscaler operator is installed, so MachineAutoscalers are read as
+// unstructured objects.
+var machineAutoscalerListGVK = schema.GroupVersionKind{
+	Group:   "autoscaling.openshift.io",
+	Version: "v1beta1",
+	Kind:    "MachineAutoscalerList",
+}
+
+// reconcileAutoscalerCompatibility protects the Control Plane Machines from the cluster autoscaler.
+// Each Control Plane Machine, and its Node, is annotated to disable autoscaler scale down, so that the autoscaler
+// never removes a Control Plane Node. MachineAutoscalers that target the Control Plane Machines are reported within
+// the AutoscalerIncompatibility condition, and a warning event is published when they are first observed.
+// When the APIReader is nil, the Control Plane Machines are not protected. When the autoscaling API is not served, no
+// MachineAutoscalers are reported.
+func (r *ControlPlaneMachineSetReconciler) reconcileAutoscalerCompatibility(ctx context.Context, logger logr.Logger, cpms *machinev1.ControlPlaneMachineSet, machineInfos map[int32][]machineproviders.MachineInfo) error {
+	if r.APIReader == nil {
+		return nil
+	}
+
+	if err := r.ensureScaleDownDisabled(ctx, logger, machineInfos); err != nil {
+		return err
+	}
+
+	autoscalers, err := r.listMachineAutoscalers(ctx, cpms.GetNamespace())
+	if err != nil {
+		return err
+	}
+
+	machineSets := &machinev1beta1.MachineSetList{}
+	if len(autoscalers) > 0 {
+		if err := r.List(ctx, machineSets, client.InNamespace(cpms.GetNamespace())); err != nil {
+			return fmt.Errorf("could not list machine sets: %w", err)
+		}
+	}
+
+	targeting, err := controlPlaneMachineAutoscalers(cpms, autoscalers, machineSets.Items)
+	if err != nil {
+		return err
+	}
+
+	for _, autoscaler := range targeting {
+		logger.Info(observedControlPlaneAutoscaler, "machineAutoscaler", autoscaler)
+	}
+
+	r.setAutoscalerIncompatibilityCondition(cpms, targeting)
+
+	return nil
+}
+
+// listMachineAutoscalers lists the MachineAutoscalers within the namespace. When the autoscaling API is not served,
+// there are no MachineAutoscalers.
+func (r *ControlPlaneMachineSetReconciler) listMachineAutoscalers(ctx context.Context, namespace string) ([]unstructured.Unstructured, error) {
+	autoscalerList := &unstructured.UnstructuredList{}
+	autoscalerList.SetGroupVersionKind(machineAutoscalerListGVK)
+
+	if err := r.APIReader.List(ctx, autoscalerList, client.InNamespace(namespace)); meta.IsNoMatchError(err) || apierrors.IsNotFound(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("could not list machine autoscalers: %w", err)
+	}
+
+	return autoscalerList.Items, nil
+}
+
+// controlPlaneMachineAutoscalers returns the MachineAutoscalers that target the Control Plane Machines, as a sorted
+// list of descriptions of each MachineAutoscaler and its scale target.
+// A MachineAutoscaler targets the Control Plane Machines when its scale target is a ControlPlaneMachineSet, or is a
+// MachineSet whose template labels are matched by the selector of the ControlPlaneMachineSet.
+func controlPlaneMachineAutoscalers(cpms *machinev1.ControlPlaneMachineSet, autoscalers []unstructured.Unstructured, machineSets []machinev1beta1.MachineSet) ([]string, error) {
+	selector, err := metav1.LabelSelectorAsSelector(&cpms.Spec.Selector)
+	if err != nil {
+		return nil, fmt.Errorf("could not convert control plane machine set selector: %w", err)
+	}
+
+	controlPlaneMachineSets := map[string]bool{}
+
+	for _, machineSet := range machineSets {
+		if !selector.Empty() && selector.Matches(labels.Set(machineSet.Spec.Template.ObjectMeta.Labels)) {
+			controlPlaneMachineSets[machineSet.GetName()] = true
+		}
+	}
+
+	targeting := []string{}
+
+	for _, autoscaler := range autoscalers {
+		kind, _, _ := unstructured.NestedString(autoscaler.Object, "spec", "scaleTargetRef", "kind")
+		name, _, _ := unstructured.NestedString(autoscaler.Object, "spec", "scaleTargetRef", "name")
+
+		if kind == controlPlaneMachineSetKind || (kind == machineSetKind && controlPlaneMachineSets[name]) {
+			targeting = append(targeting, fmt.Sprintf("%s (%s %s)", autoscaler.GetName(), kind, name))
+		}
+	}
+
+	sort.Strings(targeting)
+
+	return targeting, nil
+}
+
+// setAutoscalerIncompatibilityCondition reports the MachineAutoscalers that target the Control Plane Machines.
+// A warning event is published whenever the reported MachineAutoscalers change, so that each newly observed
+// MachineAutoscaler is surfaced once. The condition is removed when no MachineAutoscaler targets the Control Plane
+// Machines.
+func (r *ControlPlaneMachineSetReconciler) setAutoscalerIncompatibilityCondition(cpms *machinev1.ControlPlaneMachineSet, targeting []string) {
+	if len(targeting) == 0 {
+		meta.RemoveStatusCondition(&cpms.Status.Conditions, conditionAutoscalerIncompatibility)
+
+		return
+	}
+
+	message := fmt.Sprintf("Found %d machine autoscaler(s) targeting control plane machines, which must not be autoscaled: %s", len(targeting), strings.Join(targeting, ", "))
+
+	if previous := meta.FindStatusCondition(cpms.Status.Conditions, conditionAutoscalerIncompatibility); previous == nil || previous.Message != message {
+		r.publishEvent(cpms, corev1.EventTypeWarning, reasonControlPlaneTargetedByAutoscaler, message)
+	}
+
+	meta.SetStatusCondition(&cpms.Status.Conditions, metav1.Condition{
+		Type:               conditionAutoscalerIncompatibility,
+		Status:             metav1.ConditionTrue,
+		Reason:             reasonControlPlaneTargetedByAutoscaler,
+		ObservedGeneration: cpms.GetGeneration(),
+		Message:            message,
+	})
+}
+
+// ensureScaleDownDisabled annotates each Control Plane Machine, and its Node, to disable autoscaler scale down.
+// Machines pending deletion are ignored, as they are about to be removed.
+func (r *ControlPlaneMachineSetReconciler) ensureScaleDownDisabled(ctx context.Context, logger logr.Logger, machineInfos map[int32][]machineproviders.MachineInfo) error {
+	for _, idx := range sortedIndexes(machineInfos) {
+		for _, machineInfo := range machineInfos[idx] {
+			if machineInfo.MachineRef == nil || machineInfo.MachineRef.ObjectMeta.GetDeletionTimestamp() != nil {
+				continue
+			}
+
+			machineName := machineInfo.MachineRef.ObjectMeta.GetName()
+
+			if machineInfo.MachineRef.ObjectMeta.GetAnnotations()[scaleDownDisabledAnnotation] != "true" {
+				if err := r.patchMachineAnnotations(ctx, machineInfo.MachineRef, func(annotations map[string]string) {
+					annotations[scaleDownDisabledAnnotation] = "true"
+				}); err != nil {
+					return fmt.Errorf("error disabling scale down for machine %s: %w", machineName, err)
+				}
+
+				logger.V(2).Info(disabledMachineScaleDown, "machineName", machineName)
+			}
+
+			if machineInfo.NodeRef == nil {
+				continue
+			}
+
+			if err := r.ensureNodeScaleDownDisabled(ctx, logger, machineInfo.NodeRef.ObjectMeta.GetName()); err != nil {
+				return fmt.Errorf("error disabling scale down for node %s: %w", machineInfo.NodeRef.ObjectMeta.GetName(), err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// ensureNodeScaleDownDisabled annotates a single Node to disable autoscaler scale down, if required.
+// Nodes that no longer exist are ignored.
+func (r *ControlPlaneMachineSetReconciler) ensureNodeScaleDownDisabled(ctx context.Context, logger logr.Logger, nodeName string) error {
+	node := &corev1.Node{}
+	if err := r.Get(ctx, client.ObjectKey{Name: nodeName}, node); apierrors.IsNotFound(err) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("could not fetch node: %w", err)
+	}
+
+	if node.GetAnnotations()[scaleDownDisabledAnnotation] == "true" {
+		return nil
+	}
+
+	patchBase := client.MergeFrom(node.DeepCopy())
+
+	annotations := node.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+
+	annotations[scaleDownDisabledAnnotation] = "true"
+	node.SetAnnotations(annotations)
+
+	if err := r.Patch(ctx, node, patchBase); err != nil {
+		return fmt.Errorf("could not patch node annotations: %w", err)
+	}
+
+	logger.V(2).Info(disabledNodeScaleDown, "nodeName", nodeName)
+
+	return nil
+}
@@ -0,0 +1,221 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controlplanemachineset
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	machinev1 "github.com/openshift/api/machine/v1"
+	machinev1beta1 "github.com/openshift/api/machine/v1beta1"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/test"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/test/resourcebuilder"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/envtest/komega"
+)
+
+var _ = Describe("Autoscaler compatibility", func() {
+	machineAutoscaler := func(name, kind, targetName string) unstructured.Unstructured {
+		autoscaler := unstructured.Unstructured{Object: map[string]interface{}{
+			"spec": map[string]interface{}{
+				"scaleTargetRef": map[string]interface{}{
+					"apiVersion": "machine.openshift.io/v1beta1",
+					"kind":       kind,
+					"name":       targetName,
+				},
+			},
+		}}
+		autoscaler.SetName(name)
+
+		return autoscaler
+	}
+
+	machineSet := func(name, role string) machinev1beta1.MachineSet {
+		return machinev1beta1.MachineSet{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec: machinev1beta1.MachineSetSpec{
+				Template: machinev1beta1.MachineTemplateSpec{
+					ObjectMeta: machinev1beta1.ObjectMeta{
+						Labels: map[string]string{
+							"machine.openshift.io/cluster-api-machine-role": role,
+							"machine.openshift.io/cluster-api-machine-type": role,
+						},
+					},
+				},
+			},
+		}
+	}
+
+	Context("controlPlaneMachineAutoscalers", func() {
+		cpms := resourcebuilder.ControlPlaneMachineSet().Build()
+		machineSets := []machinev1beta1.MachineSet{
+			machineSet("master-us-east-1a", "master"),
+			machineSet("worker-us-east-1a", "worker"),
+		}
+
+		It("reports autoscalers targeting the ControlPlaneMachineSet", func() {
+			Expect(controlPlaneMachineAutoscalers(cpms, []unstructured.Unstructured{
+				machineAutoscaler("cpms", controlPlaneMachineSetKind, "cluster"),
+			}, machineSets)).To(Equal([]string{"cpms (ControlPlaneMachineSet cluster)"}))
+		})
+
+		It("reports autoscalers targeting MachineSets of Control Plane Machines, in order", func() {
+			Expect(controlPlaneMachineAutoscalers(cpms, []unstructured.Unstructured{
+				machineAutoscaler("worker", machineSetKind, "worker-us-east-1a"),
+				machineAutoscaler("master", machineSetKind, "master-us-east-1a"),
+				machineAutoscaler("cpms", controlPlaneMachineSetKind, "cluster"),
+			}, machineSets)).To(Equal([]string{
+				"cpms (ControlPlaneMachineSet cluster)",
+				"master (MachineSet master-us-east-1a)",
+			}))
+		})
+
+		It("ignores autoscalers targeting MachineSets that do not exist", func() {
+			Expect(controlPlaneMachineAutoscalers(cpms, []unstructured.Unstructured{
+				machineAutoscaler("missing", machineSetKind, "master-us-east-1b"),
+			}, machineSets)).To(BeEmpty())
+		})
+	})
+
+	Context("setAutoscalerIncompatibilityCondition", func() {
+		var reconciler *ControlPlaneMachineSetReconciler
+		var recorder *record.FakeRecorder
+		var cpms *machinev1.ControlPlaneMachineSet
+
+		BeforeEach(func() {
+			recorder = record.NewFakeRecorder(10)
+			reconciler = &ControlPlaneMachineSetReconciler{Recorder: recorder}
+			cpms = resourcebuilder.ControlPlaneMachineSet().Build()
+
+			reconciler.setAutoscalerIncompatibilityCondition(cpms, []string{"master (MachineSet master-us-east-1a)"})
+		})
+
+		It("sets the condition", func() {
+			Expect(cpms.Status.Conditions).To(ConsistOf(test.MatchCondition(metav1.Condition{
+				Type:    conditionAutoscalerIncompatibility,
+				Status:  metav1.ConditionTrue,
+				Reason:  reasonControlPlaneTargetedByAutoscaler,
+				Message: "Found 1 machine autoscaler(s) targeting control plane machines, which must not be autoscaled: master (MachineSet master-us-east-1a)",
+			})))
+		})
+
+		It("publishes a warning event", func() {
+			Expect(recorder.Events).To(Receive(Equal("Warning ControlPlaneTargetedByAutoscaler Found 1 machine autoscaler(s) targeting control plane machines, which must not be autoscaled: master (MachineSet master-us-east-1a)")))
+		})
+
+		It("does not publish the event again while the autoscalers are unchanged", func() {
+			Expect(recorder.Events).To(Receive())
+
+			reconciler.setAutoscalerIncompatibilityCondition(cpms, []string{"master (MachineSet master-us-east-1a)"})
+
+			Expect(recorder.Events).ToNot(Receive())
+		})
+
+		It("removes the condition once no autoscaler targets the Control Plane Machines", func() {
+			reconciler.setAutoscalerIncompatibilityCondition(cpms, []string{})
+
+			Expect(meta.FindStatusCondition(cpms.Status.Conditions, conditionAutoscalerIncompatibility)).To(BeNil())
+		})
+	})
+
+	Context("reconcileAutoscalerCompatibility", func() {
+		var namespaceName string
+		var reconciler *ControlPlaneMachineSetReconciler
+		var logger test.TestLogger
+		var cpms *machinev1.ControlPlaneMachineSet
+
+		var machine *machinev1beta1.Machine
+		var node *corev1.Node
+
+		BeforeEach(func() {
+			By("Setting up a namespace for the test")
+			ns := resourcebuilder.Namespace().WithGenerateName("control-plane-machine-set-autoscaler-").Build()
+			Expect(k8sClient.Create(ctx, ns)).To(Succeed())
+			namespaceName = ns.GetName()
+
+			reconciler = &ControlPlaneMachineSetReconciler{
+				Client:     k8sClient,
+				APIReader:  k8sClient,
+				Scheme:     testScheme,
+				RESTMapper: testRESTMapper,
+				Namespace:  namespaceName,
+			}
+
+			logger = test.NewTestLogger()
+			cpms = resourcebuilder.ControlPlaneMachineSet().WithNamespace(namespaceName).Build()
+
+			By("Creating a machine and node to protect")
+			machine = resourcebuilder.Machine().AsMaster().WithNamespace(namespaceName).WithGenerateName("autoscaler-test-").Build()
+			Expect(k8sClient.Create(ctx, machine)).To(Succeed())
+
+			node = resourcebuilder.Node().AsMaster().WithGenerateName("autoscaler-test-").Build()
+			Expect(k8sClient.Create(ctx, node)).To(Succeed())
+
+			machineInfos := map[int32][]machineproviders.MachineInfo{
+				0: {resourcebuilder.MachineInfo().
+					WithMachineGVR(machinev1beta1.GroupVersion.WithResource("machines")).
+					WithMachineName(machine.GetName()).
+					WithMachineNamespace(namespaceName).
+					WithNodeGVR(corev1.SchemeGroupVersion.WithResource("nodes")).
+					WithNodeName(node.GetName()).
+					Build(),
+				},
+			}
+
+			Expect(reconciler.reconcileAutoscalerCompatibility(ctx, logger.Logger(), cpms, machineInfos)).To(Succeed())
+		})
+
+		AfterEach(func() {
+			test.CleanupResources(Default, ctx, cfg, k8sClient, namespaceName,
+				&corev1.Node{},
+				&machinev1beta1.Machine{},
+			)
+		})
+
+		It("disables scale down for the machine", func() {
+			Eventually(komega.Object(machine)).Should(HaveField("ObjectMeta.Annotations", HaveKeyWithValue(scaleDownDisabledAnnotation, "true")))
+		})
+
+		It("disables scale down for the node", func() {
+			Eventually(komega.Object(node)).Should(HaveField("ObjectMeta.Annotations", HaveKeyWithValue(scaleDownDisabledAnnotation, "true")))
+		})
+
+		It("logs the machine and node that were protected", func() {
+			Expect(logger.Entries()).To(ConsistOf(
+				test.LogEntry{
+					KeysAndValues: []interface{}{"machineName", machine.GetName()},
+					Level:         2,
+					Message:       disabledMachineScaleDown,
+				},
+				test.LogEntry{
+					KeysAndValues: []interface{}{"nodeName", node.GetName()},
+					Level:         2,
+					Message:       disabledNodeScaleDown,
+				},
+			))
+		})
+
+		It("does not set the condition when the autoscaling API is not served", func() {
+			Expect(meta.FindStatusCondition(cpms.Status.Conditions, conditionAutoscalerIncompatibility)).To(BeNil())
+		})
+	})
+})
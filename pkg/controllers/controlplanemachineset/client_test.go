@@ -0,0 +1,41 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controlplanemachineset
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	cpmsclient "github.com/openshift/cluster-control-plane-machine-set-operator/pkg/client/controlplanemachineset"
+)
+
+// The client package is imported by consumers that need not depend on the controller, so it declares its own copy
+// of the names used by the controller. These must be kept in sync.
+var _ = DescribeTable("Client package constants",
+	func(clientValue, controllerValue string) {
+		Expect(clientValue).To(Equal(controllerValue))
+	},
+	Entry("default name", cpmsclient.DefaultName, DefaultControlPlaneMachineSetName),
+	Entry("paused annotation", cpmsclient.PausedAnnotation, pausedAnnotation),
+	Entry("available condition", cpmsclient.ConditionAvailable, conditionAvailable),
+	Entry("degraded condition", cpmsclient.ConditionDegraded, conditionDegraded),
+	Entry("progressing condition", cpmsclient.ConditionProgressing, conditionProgressing),
+	Entry("rollout phase condition", cpmsclient.ConditionRolloutPhase, conditionRolloutPhase),
+	Entry("gated by condition", cpmsclient.ConditionGatedBy, conditionGatedBy),
+	Entry("machine API paused condition", cpmsclient.ConditionMachineAPIPaused, conditionMachineAPIPaused),
+	Entry("idle rollout phase", cpmsclient.RolloutPhaseIdle, string(phaseIdle)),
+)
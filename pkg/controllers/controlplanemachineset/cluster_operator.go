@@ -24,22 +24,41 @@ import (
 	configv1 "github.com/openshift/api/config/v1"
 	machinev1 "github.com/openshift/api/machine/v1"
 	"github.com/openshift/library-go/pkg/config/clusteroperator/v1helpers"
+	"k8s.io/apimachinery/pkg/api/equality"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
+// informationalConditionTypes are the types of the ControlPlaneMachineSet conditions that are informational. These
+// are not status conditions understood by the ClusterOperator, so they are not copied onto it.
+// Each feature that adds an informational condition registers it with registerInformationalCondition.
+var informationalConditionTypes = sets.NewString()
+
+// registerInformationalCondition registers the condition type as informational, so that it is not reflected on the
+// ClusterOperator. This is expected to be called from the init function of the feature owning the condition.
+func registerInformationalCondition(conditionType string) {
+	informationalConditionTypes.Insert(conditionType)
+}
+
 // setClusterOperatorAvailable sets the control-plane-machine-set cluster operator status to available.
-// This is used primarily when a ControlPlaneMachineSet doesn't exist.
+// This is used primarily when a ControlPlaneMachineSet doesn't exist. The cluster operator is still reported as
+// degraded when the controller is missing any of the permissions it requires.
 func (r *ControlPlaneMachineSetReconciler) setClusterOperatorAvailable(ctx context.Context, logger logr.Logger) error {
 	co, err := r.getClusterOperator(ctx, logger)
 	if err != nil {
 		return fmt.Errorf("cannot get cluster operator: %w", err)
 	}
 
+	degraded := newClusterOperatorStatusCondition(configv1.OperatorDegraded, configv1.ConditionFalse, reasonAsExpected, "")
+	if len(r.MissingPermissions) > 0 {
+		degraded = newClusterOperatorStatusCondition(configv1.OperatorDegraded, configv1.ConditionTrue, reasonMissingPermissions, missingPermissionsMessage(r.MissingPermissions))
+	}
+
 	conds := []configv1.ClusterOperatorStatusCondition{
 		newClusterOperatorStatusCondition(configv1.OperatorAvailable, configv1.ConditionTrue, reasonAsExpected, "cluster operator is available"),
 		newClusterOperatorStatusCondition(configv1.OperatorProgressing, configv1.ConditionFalse, reasonAsExpected, ""),
-		newClusterOperatorStatusCondition(configv1.OperatorDegraded, configv1.ConditionFalse, reasonAsExpected, ""),
+		degraded,
 		newClusterOperatorStatusCondition(configv1.OperatorUpgradeable, configv1.ConditionTrue, reasonAsExpected, "cluster operator is upgradable"),
 	}
 
@@ -59,6 +78,10 @@ func (r *ControlPlaneMachineSetReconciler) updateClusterOperatorStatus(ctx conte
 	// Copying status conditions from control plane machine set to cluster operator
 	conds := []configv1.ClusterOperatorStatusCondition{}
 	for _, c := range cpms.Status.Conditions {
+		if informationalConditionTypes.Has(c.Type) {
+			continue
+		}
+
 		conds = append(conds, newClusterOperatorStatusCondition(
 			configv1.ClusterStatusConditionType(c.Type),
 			configv1.ConditionStatus(c.Status),
@@ -110,19 +133,21 @@ func newClusterOperatorStatusCondition(conditionType configv1.ClusterStatusCondi
 }
 
 // patchClusterOperatorConditions updates cluster operator status with given conditions.
+// The status is only written when it semantically differs from the existing status of the cluster operator.
 func (r *ControlPlaneMachineSetReconciler) patchClusterOperatorConditions(ctx context.Context, logger logr.Logger, co *configv1.ClusterOperator, conds []configv1.ClusterOperatorStatusCondition) error {
-	// We need to perform update only if conditions have been changed.
-	needUpdate := false
+	originalStatus := co.Status.DeepCopy()
 
+	// SetStatusCondition only moves the transition time when the status of the condition changes.
 	for _, c := range conds {
-		if !isStatusConditionPresentAndEqual(co.Status.Conditions, c.Type, c.Status, c.Message, c.Reason) {
-			needUpdate = true
+		v1helpers.SetStatusCondition(&co.Status.Conditions, c)
+	}
 
-			v1helpers.SetStatusCondition(&co.Status.Conditions, c)
-		}
+	if err := setSupportMatrixExtension(co); err != nil {
+		return fmt.Errorf("failed to publish support matrix for cluster operator %s: %w", r.OperatorName, err)
 	}
 
-	if !needUpdate {
+	// We need to perform update only if the status has been changed.
+	if equality.Semantic.DeepEqual(*originalStatus, co.Status) {
 		return nil
 	}
 
@@ -141,14 +166,3 @@ func (r *ControlPlaneMachineSetReconciler) patchClusterOperatorConditions(ctx co
 
 	return nil
 }
-
-// isStatusConditionPresentAndEqual returns true when conditionType is present and has the same status, message and reason.
-func isStatusConditionPresentAndEqual(conditions []configv1.ClusterOperatorStatusCondition, conditionType configv1.ClusterStatusConditionType, status configv1.ConditionStatus, message, reason string) bool {
-	for _, condition := range conditions {
-		if condition.Type == conditionType {
-			return condition.Status == status && condition.Message == message && condition.Reason == reason
-		}
-	}
-
-	return false
-}
@@ -0,0 +1,125 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controlplanemachineset
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-logr/logr"
+	machinev1 "github.com/openshift/api/machine/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// clusterOperatorEvent is a high level event, describing a transition of the rollout of the Control Plane Machines,
+// to be published on the ClusterOperator.
+type clusterOperatorEvent struct {
+	// eventType is the type of the event, either Normal or Warning.
+	eventType string
+
+	// reason is the reason of the event.
+	reason string
+
+	// message is the message of the event.
+	message string
+}
+
+// clusterOperatorEventsFor determines the events to publish on the ClusterOperator from the transitions between the
+// original status of the ControlPlaneMachineSet and its status once reconciled.
+// A rollout starts when the RolloutPhase condition becomes true, and completes when it is no longer true. A rollout
+// stalls when the rollout banner first reports that it is blocked until the user, or the Machine API, takes action.
+func clusterOperatorEventsFor(original, cpms *machinev1.ControlPlaneMachineSet) []clusterOperatorEvent {
+	events := []clusterOperatorEvent{}
+
+	wasRolling := isRolling(original.Status.Conditions)
+	rolling := isRolling(cpms.Status.Conditions)
+
+	replicas := int32(0)
+	if cpms.Spec.Replicas != nil {
+		replicas = *cpms.Spec.Replicas
+	}
+
+	name := client.ObjectKeyFromObject(cpms).String()
+
+	switch {
+	case rolling && !wasRolling:
+		events = append(events, clusterOperatorEvent{
+			eventType: corev1.EventTypeNormal,
+			reason:    reasonRolloutStarted,
+			message:   fmt.Sprintf("Rollout of control plane machines started for control plane machine set %s, %d of %d up to date", name, cpms.Status.UpdatedReplicas, replicas),
+		})
+	case !rolling && wasRolling:
+		events = append(events, clusterOperatorEvent{
+			eventType: corev1.EventTypeNormal,
+			reason:    reasonRolloutCompleted,
+			message:   fmt.Sprintf("Rollout of control plane machines completed for control plane machine set %s, %d of %d up to date", name, cpms.Status.UpdatedReplicas, replicas),
+		})
+	}
+
+	if rolling && isStalled(cpms.Status.Conditions) && !(wasRolling && isStalled(original.Status.Conditions)) {
+		events = append(events, clusterOperatorEvent{
+			eventType: corev1.EventTypeWarning,
+			reason:    reasonRolloutStalled,
+			message:   fmt.Sprintf("Rollout of control plane machines stalled for control plane machine set %s: %s", name, meta.FindStatusCondition(cpms.Status.Conditions, conditionRolloutBanner).Message),
+		})
+	}
+
+	return events
+}
+
+// isRolling determines whether the conditions report that a rollout is in progress, that is, that the RolloutPhase
+// condition is true.
+func isRolling(conditions []metav1.Condition) bool {
+	return meta.IsStatusConditionTrue(conditions, conditionRolloutPhase)
+}
+
+// isStalled determines whether the rollout banner within the conditions reports that the rollout is blocked until
+// the user, or the Machine API, takes action.
+func isStalled(conditions []metav1.Condition) bool {
+	banner := meta.FindStatusCondition(conditions, conditionRolloutBanner)
+
+	return banner != nil && (banner.Reason == string(bannerLevelWarning) || banner.Reason == string(bannerLevelDanger))
+}
+
+// publishClusterOperatorEvents publishes, on the ClusterOperator, the events for the transitions of the rollout
+// between the original status of the ControlPlaneMachineSet and its status once reconciled. This allows fleet level
+// monitoring, that only watches ClusterOperators, to follow rollouts of the Control Plane Machines.
+// The events are published in addition to any event published on the ControlPlaneMachineSet.
+func (r *ControlPlaneMachineSetReconciler) publishClusterOperatorEvents(ctx context.Context, logger logr.Logger, original, cpms *machinev1.ControlPlaneMachineSet) error {
+	if r.Recorder == nil {
+		return nil
+	}
+
+	events := clusterOperatorEventsFor(original, cpms)
+	if len(events) == 0 {
+		return nil
+	}
+
+	co, err := r.getClusterOperator(ctx, logger)
+	if err != nil {
+		return fmt.Errorf("cannot get cluster operator: %w", err)
+	}
+
+	for _, event := range events {
+		r.Recorder.Event(co, event.eventType, event.reason, event.message)
+	}
+
+	return nil
+}
@@ -0,0 +1,165 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controlplanemachineset
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	configv1 "github.com/openshift/api/config/v1"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/test"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/test/resourcebuilder"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+var _ = Describe("ClusterOperator events", func() {
+	rolling := metav1.Condition{Type: conditionRolloutPhase, Status: metav1.ConditionTrue, Reason: string(phaseSurgeCreating)}
+	idle := metav1.Condition{Type: conditionRolloutPhase, Status: metav1.ConditionFalse, Reason: string(phaseIdle)}
+	progressingBanner := metav1.Condition{Type: conditionRolloutBanner, Status: metav1.ConditionTrue, Reason: string(bannerLevelInfo), Message: "Updating control plane machines, 1 of 3 up to date"}
+	stalledBanner := metav1.Condition{Type: conditionRolloutBanner, Status: metav1.ConditionTrue, Reason: string(bannerLevelWarning),
+		Message: "Updating control plane machines, 1 of 3 up to date. Blocked by MachineAPIPaused: 1 control plane machine(s) paused by the Machine API"}
+
+	type clusterOperatorEventsTableInput struct {
+		originalConditions []metav1.Condition
+		conditions         []metav1.Condition
+		updatedReplicas    int32
+		expectedEvents     []clusterOperatorEvent
+	}
+
+	DescribeTable("clusterOperatorEventsFor", func(in clusterOperatorEventsTableInput) {
+		original := resourcebuilder.ControlPlaneMachineSet().WithNamespace("openshift-machine-api").WithReplicas(3).Build()
+		original.Status.Conditions = in.originalConditions
+
+		cpms := original.DeepCopy()
+		cpms.Status.Conditions = in.conditions
+		cpms.Status.UpdatedReplicas = in.updatedReplicas
+
+		Expect(clusterOperatorEventsFor(original, cpms)).To(Equal(in.expectedEvents))
+	},
+		Entry("with no rollout", clusterOperatorEventsTableInput{
+			originalConditions: []metav1.Condition{idle},
+			conditions:         []metav1.Condition{idle},
+			updatedReplicas:    3,
+			expectedEvents:     []clusterOperatorEvent{},
+		}),
+		Entry("when a rollout starts", clusterOperatorEventsTableInput{
+			originalConditions: []metav1.Condition{idle},
+			conditions:         []metav1.Condition{rolling, progressingBanner},
+			updatedReplicas:    0,
+			expectedEvents: []clusterOperatorEvent{
+				{eventType: "Normal", reason: "RolloutStarted", message: "Rollout of control plane machines started for control plane machine set openshift-machine-api/cluster, 0 of 3 up to date"},
+			},
+		}),
+		Entry("when the first reconcile observes a rollout", clusterOperatorEventsTableInput{
+			originalConditions: nil,
+			conditions:         []metav1.Condition{rolling, progressingBanner},
+			updatedReplicas:    2,
+			expectedEvents: []clusterOperatorEvent{
+				{eventType: "Normal", reason: "RolloutStarted", message: "Rollout of control plane machines started for control plane machine set openshift-machine-api/cluster, 2 of 3 up to date"},
+			},
+		}),
+		Entry("while a rollout progresses", clusterOperatorEventsTableInput{
+			originalConditions: []metav1.Condition{rolling, progressingBanner},
+			conditions:         []metav1.Condition{rolling, progressingBanner},
+			updatedReplicas:    1,
+			expectedEvents:     []clusterOperatorEvent{},
+		}),
+		Entry("when a rollout stalls", clusterOperatorEventsTableInput{
+			originalConditions: []metav1.Condition{rolling, progressingBanner},
+			conditions:         []metav1.Condition{rolling, stalledBanner},
+			updatedReplicas:    1,
+			expectedEvents: []clusterOperatorEvent{
+				{eventType: "Warning", reason: "RolloutStalled", message: "Rollout of control plane machines stalled for control plane machine set openshift-machine-api/cluster: " + stalledBanner.Message},
+			},
+		}),
+		Entry("when a rollout starts stalled", clusterOperatorEventsTableInput{
+			originalConditions: []metav1.Condition{idle},
+			conditions:         []metav1.Condition{rolling, stalledBanner},
+			updatedReplicas:    1,
+			expectedEvents: []clusterOperatorEvent{
+				{eventType: "Normal", reason: "RolloutStarted", message: "Rollout of control plane machines started for control plane machine set openshift-machine-api/cluster, 1 of 3 up to date"},
+				{eventType: "Warning", reason: "RolloutStalled", message: "Rollout of control plane machines stalled for control plane machine set openshift-machine-api/cluster: " + stalledBanner.Message},
+			},
+		}),
+		Entry("while a rollout remains stalled", clusterOperatorEventsTableInput{
+			originalConditions: []metav1.Condition{rolling, stalledBanner},
+			conditions:         []metav1.Condition{rolling, stalledBanner},
+			updatedReplicas:    1,
+			expectedEvents:     []clusterOperatorEvent{},
+		}),
+		Entry("when a degraded control plane machine set has no rollout", clusterOperatorEventsTableInput{
+			originalConditions: []metav1.Condition{idle},
+			conditions: []metav1.Condition{idle, {Type: conditionRolloutBanner, Status: metav1.ConditionTrue, Reason: string(bannerLevelDanger),
+				Message: "No control plane machine updates are in progress. Blocked by OperatorDegraded: The control plane machine set is degraded"}},
+			updatedReplicas: 3,
+			expectedEvents:  []clusterOperatorEvent{},
+		}),
+		Entry("when a rollout completes", clusterOperatorEventsTableInput{
+			originalConditions: []metav1.Condition{rolling, progressingBanner},
+			conditions:         []metav1.Condition{idle},
+			updatedReplicas:    3,
+			expectedEvents: []clusterOperatorEvent{
+				{eventType: "Normal", reason: "RolloutCompleted", message: "Rollout of control plane machines completed for control plane machine set openshift-machine-api/cluster, 3 of 3 up to date"},
+			},
+		}),
+	)
+
+	Context("publishClusterOperatorEvents", func() {
+		const operatorName = "control-plane-machine-set-events"
+
+		var logger test.TestLogger
+		var recorder *record.FakeRecorder
+		var reconciler *ControlPlaneMachineSetReconciler
+
+		BeforeEach(func() {
+			Expect(k8sClient.Create(ctx, resourcebuilder.ClusterOperator().WithName(operatorName).Build())).To(Succeed())
+
+			logger = test.NewTestLogger()
+			recorder = record.NewFakeRecorder(10)
+			reconciler = &ControlPlaneMachineSetReconciler{
+				Client:       k8sClient,
+				OperatorName: operatorName,
+				Recorder:     recorder,
+			}
+		})
+
+		AfterEach(func() {
+			Expect(k8sClient.Delete(ctx, &configv1.ClusterOperator{ObjectMeta: metav1.ObjectMeta{Name: operatorName}})).To(Succeed())
+		})
+
+		It("publishes the events for the transitions of the rollout", func() {
+			original := resourcebuilder.ControlPlaneMachineSet().WithNamespace("openshift-machine-api").WithReplicas(3).Build()
+			original.Status.Conditions = []metav1.Condition{idle}
+
+			cpms := original.DeepCopy()
+			cpms.Status.Conditions = []metav1.Condition{rolling, progressingBanner}
+
+			Expect(reconciler.publishClusterOperatorEvents(ctx, logger.Logger(), original, cpms)).To(Succeed())
+			Expect(recorder.Events).To(Receive(Equal("Normal RolloutStarted Rollout of control plane machines started for control plane machine set openshift-machine-api/cluster, 0 of 3 up to date")))
+			Expect(recorder.Events).ToNot(Receive())
+		})
+
+		It("does not publish any events without a transition", func() {
+			original := resourcebuilder.ControlPlaneMachineSet().WithNamespace("openshift-machine-api").WithReplicas(3).Build()
+			original.Status.Conditions = []metav1.Condition{rolling, progressingBanner}
+
+			Expect(reconciler.publishClusterOperatorEvents(ctx, logger.Logger(), original, original.DeepCopy())).To(Succeed())
+			Expect(recorder.Events).ToNot(Receive())
+		})
+	})
+})
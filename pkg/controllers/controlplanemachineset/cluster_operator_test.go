@@ -25,6 +25,8 @@ import (
 	configv1 "github.com/openshift/api/config/v1"
 	machinev1 "github.com/openshift/api/machine/v1"
 	machinev1beta1 "github.com/openshift/api/machine/v1beta1"
+	cpmsclient "github.com/openshift/cluster-control-plane-machine-set-operator/pkg/client/controlplanemachineset"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/permissions"
 	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/test"
 	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/test/resourcebuilder"
 	corev1 "k8s.io/api/core/v1"
@@ -140,6 +142,14 @@ var _ = Describe("Cluster Operator Status with a running controller", func() {
 			})))
 		})
 
+		It("Publishes the support matrix", func() {
+			co := resourcebuilder.ClusterOperator().WithName(operatorName).Build()
+
+			Eventually(komega.Object(co)).Should(WithTransform(func(co *configv1.ClusterOperator) (*cpmsclient.SupportMatrix, error) {
+				return cpmsclient.SupportMatrixFromClusterOperator(co)
+			}, Equal(&supportMatrix)))
+		})
+
 		Context("And an invalid cluster operator", func() {
 			BeforeEach(func() {
 				coStatus := resourcebuilder.ClusterOperatorStatus().Build()
@@ -193,7 +203,7 @@ var _ = Describe("Cluster Operator Status", func() {
 		Expect(k8sClient.Create(ctx, ns)).To(Succeed())
 		namespaceName = ns.GetName()
 
-		cpmsBuilder = resourcebuilder.ControlPlaneMachineSet().WithName(clusterControlPlaneMachineSetName).WithNamespace(namespaceName)
+		cpmsBuilder = resourcebuilder.ControlPlaneMachineSet().WithName(DefaultControlPlaneMachineSetName).WithNamespace(namespaceName)
 
 		reconciler = &ControlPlaneMachineSetReconciler{
 			Client:       k8sClient,
@@ -277,6 +287,50 @@ var _ = Describe("Cluster Operator Status", func() {
 					},
 				},
 			}),
+			Entry("with a rollout phase condition, which should not be reflected", updateClusterOperatorStatusTableInput{
+				cpms: cpmsBuilder.WithConditions([]metav1.Condition{statusConditionAvailable, statusConditionNotProgressing, statusConditionNotDegraded, {
+					Type:    conditionRolloutPhase,
+					Status:  metav1.ConditionFalse,
+					Reason:  string(phaseIdle),
+					Message: "0=Idle, 1=Idle, 2=Idle",
+				}}).Build(),
+				expectedConditions: []configv1.ClusterOperatorStatusCondition{
+					{
+						Type:    configv1.OperatorAvailable,
+						Status:  configv1.ConditionTrue,
+						Reason:  reasonAllReplicasAvailable,
+						Message: "",
+					},
+					{
+						Type:   configv1.OperatorProgressing,
+						Status: configv1.ConditionFalse,
+						Reason: reasonAllReplicasUpdated,
+					},
+					{
+						Type:   configv1.OperatorDegraded,
+						Status: configv1.ConditionFalse,
+						Reason: reasonAsExpected,
+					},
+					{
+						Type:    configv1.OperatorUpgradeable,
+						Status:  configv1.ConditionTrue,
+						Reason:  reasonAsExpected,
+						Message: "cluster operator is upgradable",
+					},
+				},
+				expectedLogs: []test.LogEntry{
+					{
+						Level:   4,
+						Message: "Syncing cluster operator status",
+						KeysAndValues: []interface{}{
+							"available", string(metav1.ConditionTrue),
+							"progressing", string(metav1.ConditionFalse),
+							"degraded", string(metav1.ConditionFalse),
+							"upgradable", string(metav1.ConditionTrue),
+						},
+					},
+				},
+			}),
 			Entry("with a degraded control plane machine set", updateClusterOperatorStatusTableInput{
 				cpms: cpmsBuilder.WithConditions([]metav1.Condition{statusConditionNotAvailable, statusConditionNotProgressing, statusConditionDegraded}).Build(),
 				expectedConditions: []configv1.ClusterOperatorStatusCondition{
@@ -358,5 +412,91 @@ var _ = Describe("Cluster Operator Status", func() {
 				},
 			}),
 		)
+
+		It("should not reflect any of the informational conditions", func() {
+			Expect(informationalConditionTypes.List()).To(ContainElements(
+				conditionRolloutPhase, conditionRolloutCostEstimate, conditionMachineInstances, conditionEtcdMembers,
+				conditionRecoveryGuidance, conditionGatedBy, conditionLastRollout, conditionMachineAPIPaused,
+				conditionMissingTags, conditionTemplateTagDrift, conditionDrainProgress, conditionRolloutBanner,
+				conditionStrategyTransition, conditionFailureDomainBalance, conditionFailureDomainDrift,
+				conditionFailureDomainDiscovery, conditionAutoscalerIncompatibility, conditionAdoption,
+				conditionCSRPendingApproval, conditionIndexReadiness, conditionFailedMachines,
+			))
+
+			conditions := []metav1.Condition{statusConditionAvailable, statusConditionNotProgressing, statusConditionNotDegraded}
+			for _, conditionType := range informationalConditionTypes.List() {
+				conditions = append(conditions, resourcebuilder.StatusCondition().WithType(conditionType).WithStatus(metav1.ConditionTrue).WithReason("Informational").Build())
+			}
+
+			Expect(reconciler.updateClusterOperatorStatus(ctx, logger.Logger(), cpmsBuilder.WithConditions(conditions).Build())).To(Succeed())
+
+			Eventually(komega.Object(co)).Should(HaveField("Status.Conditions", test.MatchClusterOperatorStatusConditions([]configv1.ClusterOperatorStatusCondition{
+				{
+					Type:    configv1.OperatorAvailable,
+					Status:  configv1.ConditionTrue,
+					Reason:  reasonAllReplicasAvailable,
+					Message: "",
+				},
+				{
+					Type:   configv1.OperatorProgressing,
+					Status: configv1.ConditionFalse,
+					Reason: reasonAllReplicasUpdated,
+				},
+				{
+					Type:   configv1.OperatorDegraded,
+					Status: configv1.ConditionFalse,
+					Reason: reasonAsExpected,
+				},
+				{
+					Type:    configv1.OperatorUpgradeable,
+					Status:  configv1.ConditionTrue,
+					Reason:  reasonAsExpected,
+					Message: "cluster operator is upgradable",
+				},
+			})))
+		})
+
+		Context("when the cluster operator status is already up to date", func() {
+			var cpms *machinev1.ControlPlaneMachineSet
+
+			BeforeEach(func() {
+				cpms = cpmsBuilder.WithConditions([]metav1.Condition{statusConditionAvailable, statusConditionNotProgressing, statusConditionNotDegraded}).Build()
+
+				Expect(reconciler.updateClusterOperatorStatus(ctx, logger.Logger(), cpms)).To(Succeed())
+				Eventually(komega.Get(co)).Should(Succeed())
+
+				logger = test.NewTestLogger()
+			})
+
+			It("should not update the cluster operator", func() {
+				resourceVersion := co.GetResourceVersion()
+
+				Expect(reconciler.updateClusterOperatorStatus(ctx, logger.Logger(), cpms)).To(Succeed())
+
+				Consistently(komega.Object(co)).Should(HaveField("ObjectMeta.ResourceVersion", Equal(resourceVersion)))
+				Expect(logger.Entries()).To(BeEmpty())
+			})
+		})
+	})
+
+	Context("setClusterOperatorAvailable", func() {
+		Context("when the controller is missing permissions", func() {
+			BeforeEach(func() {
+				reconciler.MissingPermissions = []permissions.Permission{
+					{Group: "machine.openshift.io", Resource: "machines", Namespace: namespaceName, Verb: "delete"},
+				}
+			})
+
+			It("should set the cluster operator degraded", func() {
+				Expect(reconciler.setClusterOperatorAvailable(ctx, logger.Logger())).To(Succeed())
+
+				Eventually(komega.Object(co)).Should(HaveField("Status.Conditions", ContainElement(test.MatchClusterOperatorStatusCondition(configv1.ClusterOperatorStatusCondition{
+					Type:    configv1.OperatorDegraded,
+					Status:  configv1.ConditionTrue,
+					Reason:  reasonMissingPermissions,
+					Message: "The operator service account is missing 1 permission(s) required by the controller, grant them and restart the operator: delete machines.machine.openshift.io in namespace " + namespaceName,
+				}))))
+			})
+		})
 	})
 })
@@ -0,0 +1,51 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controlplanemachineset
+
+import (
+	"github.com/go-logr/logr"
+	machinev1 "github.com/openshift/api/machine/v1"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders"
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	// invalidConfiguration is a log message used to inform the user that the ControlPlaneMachineSet cannot continue
+	// until they have corrected its configuration, or the configuration of the Control Plane Machines.
+	invalidConfiguration = "Invalid configuration, the control plane machine set will not take any action until this has been resolved"
+)
+
+// handleConfigurationError determines whether the error was caused by a configuration error and, if so, marks the
+// ControlPlaneMachineSet as degraded with the reason of the configuration error, and publishes a warning event with
+// the same reason.
+// It returns true when the error has been handled and should not be returned to the caller.
+func (r *ControlPlaneMachineSetReconciler) handleConfigurationError(logger logr.Logger, cpms *machinev1.ControlPlaneMachineSet, err error) bool {
+	reason, ok := machineproviders.ConfigurationErrorReason(err)
+	if !ok {
+		return false
+	}
+
+	logger.Error(err, invalidConfiguration, "reason", string(reason))
+
+	setDegradedCondition(cpms, string(reason), err.Error())
+
+	if r.Recorder != nil {
+		r.Recorder.Event(cpms, corev1.EventTypeWarning, string(reason), err.Error())
+	}
+
+	return true
+}
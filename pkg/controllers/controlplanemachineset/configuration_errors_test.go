@@ -0,0 +1,129 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controlplanemachineset
+
+import (
+	"errors"
+	"fmt"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/test"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/test/resourcebuilder"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+var _ = Describe("handleConfigurationError", func() {
+	var logger test.TestLogger
+	var recorder *record.FakeRecorder
+	var reconciler *ControlPlaneMachineSetReconciler
+
+	BeforeEach(func() {
+		logger = test.NewTestLogger()
+		recorder = record.NewFakeRecorder(10)
+		reconciler = &ControlPlaneMachineSetReconciler{
+			Recorder: recorder,
+		}
+	})
+
+	Context("with a wrapped configuration error", func() {
+		configErr := machineproviders.NewConfigurationError(machineproviders.ReasonTemplatePlatformMismatch, "mismatched platform types")
+		err := fmt.Errorf("error reconciling machines: %w", configErr)
+
+		It("should handle the error", func() {
+			cpms := resourcebuilder.ControlPlaneMachineSet().Build()
+
+			Expect(reconciler.handleConfigurationError(logger.Logger(), cpms, err)).To(BeTrue())
+		})
+
+		It("should set the degraded condition with the reason of the error", func() {
+			cpms := resourcebuilder.ControlPlaneMachineSet().Build()
+			reconciler.handleConfigurationError(logger.Logger(), cpms, err)
+
+			Expect(cpms.Status.Conditions).To(ConsistOf(
+				test.MatchCondition(metav1.Condition{
+					Type:    conditionDegraded,
+					Status:  metav1.ConditionTrue,
+					Reason:  "TemplatePlatformMismatch",
+					Message: "error reconciling machines: mismatched platform types",
+				}),
+				test.MatchCondition(metav1.Condition{
+					Type:   conditionProgressing,
+					Status: metav1.ConditionFalse,
+					Reason: reasonOperatorDegraded,
+				}),
+			))
+		})
+
+		It("should publish a warning event with the reason of the error", func() {
+			cpms := resourcebuilder.ControlPlaneMachineSet().Build()
+			reconciler.handleConfigurationError(logger.Logger(), cpms, err)
+
+			Expect(recorder.Events).To(Receive(Equal("Warning TemplatePlatformMismatch error reconciling machines: mismatched platform types")))
+		})
+
+		It("should log the error", func() {
+			cpms := resourcebuilder.ControlPlaneMachineSet().Build()
+			reconciler.handleConfigurationError(logger.Logger(), cpms, err)
+
+			Expect(logger.Entries()).To(ConsistOf(test.LogEntry{
+				Error:         err,
+				KeysAndValues: []interface{}{"reason", "TemplatePlatformMismatch"},
+				Message:       invalidConfiguration,
+			}))
+		})
+
+		It("should still match the sentinel error", func() {
+			Expect(errors.Is(err, configErr)).To(BeTrue())
+		})
+	})
+
+	Context("without a recorder", func() {
+		BeforeEach(func() {
+			reconciler.Recorder = nil
+		})
+
+		It("should still set the degraded condition", func() {
+			cpms := resourcebuilder.ControlPlaneMachineSet().Build()
+			err := machineproviders.NewConfigurationError(machineproviders.ReasonUnsupportedPlatform, "unsupported platform type")
+
+			Expect(reconciler.handleConfigurationError(logger.Logger(), cpms, err)).To(BeTrue())
+			Expect(cpms.Status.Conditions).To(ContainElement(test.MatchCondition(metav1.Condition{
+				Type:    conditionDegraded,
+				Status:  metav1.ConditionTrue,
+				Reason:  "UnsupportedPlatform",
+				Message: "unsupported platform type",
+			})))
+		})
+	})
+
+	DescribeTable("with errors that are not configuration errors", func(err error) {
+		cpms := resourcebuilder.ControlPlaneMachineSet().Build()
+
+		Expect(reconciler.handleConfigurationError(logger.Logger(), cpms, err)).To(BeFalse())
+		Expect(cpms.Status.Conditions).To(BeEmpty())
+		Expect(recorder.Events).ToNot(Receive())
+		Expect(logger.Entries()).To(BeEmpty())
+	},
+		Entry("with no error", nil),
+		Entry("with a transient error", errors.New("connection refused")),
+		Entry("with a wrapped transient error", fmt.Errorf("error fetching machine info: %w", errors.New("connection refused"))),
+	)
+})
@@ -42,6 +42,163 @@ const (
 	// This condition may be false with a reason, such as when an update is needed
 	// but the rollout strategy is configured to OnDelete.
 	conditionProgressing = "Progressing"
+
+	// conditionRolloutPhase is used to denote how far the ControlPlaneMachineSet
+	// has progressed through the replacement of its Machines. The reason of the
+	// condition is the overall rollout phase, and the message lists the rollout
+	// phase of each index. This condition is true whenever any index is not Idle.
+	// Unlike the other conditions, this condition is not reflected on the
+	// ClusterOperator.
+	conditionRolloutPhase = "RolloutPhase"
+
+	// conditionRolloutCostEstimate is used to denote the estimated change in the
+	// monthly cost of the Control Plane once a pending rollout, that changes the
+	// instance type of the Control Plane Machines, has completed. This condition
+	// is only present when a price catalog has been configured and a pending
+	// rollout changes the instance type. Like the rollout phase, this condition
+	// is not reflected on the ClusterOperator.
+	conditionRolloutCostEstimate = "RolloutCostEstimate"
+
+	// conditionMachineInstances is used to map each index of the ControlPlaneMachineSet
+	// to the cloud instances backing its Machines. The message lists the provider ID
+	// of each Machine, by index. This condition is true once every Machine has a
+	// provider ID. Like the rollout phase, this condition is not reflected on the
+	// ClusterOperator.
+	conditionMachineInstances = "MachineInstances"
+
+	// conditionEtcdMembers is used to map each index of the ControlPlaneMachineSet
+	// to the etcd members running on the Nodes of its Machines. The message lists
+	// the ID, name and peer URL of the etcd member of each Machine, by index, and
+	// marks members that are pending removal. This condition is only present while
+	// the etcd membership is known. Like the rollout phase, this condition is not
+	// reflected on the ClusterOperator.
+	conditionEtcdMembers = "EtcdMembers"
+
+	// conditionRecoveryGuidance is used to denote that multiple Control Plane
+	// Machines have failed at once, for example during a zone outage. The message
+	// lists the failed indexes and guides the user through the recovery. While this
+	// condition is present, no Control Plane Machine is deleted automatically. Like
+	// the rollout phase, this condition is not reflected on the ClusterOperator.
+	conditionRecoveryGuidance = "RecoveryGuidance"
+
+	// conditionGatedBy is used to denote that Control Plane Machines need to be
+	// replaced, but that a gate is blocking the replacements. The reason names
+	// the gate, and the message explains when the gate is expected to unblock.
+	// This condition is only present while a gate is blocking replacements. Like
+	// the rollout phase, this condition is not reflected on the ClusterOperator.
+	conditionGatedBy = "GatedBy"
+
+	// conditionLastRollout is used to summarise the most recently completed rollout
+	// of the Control Plane Machines. The message records when the rollout started
+	// and completed, the number of Machines replaced and the disruption windows
+	// observed. This condition is only present once a rollout has completed. Like
+	// the rollout phase, this condition is not reflected on the ClusterOperator.
+	conditionLastRollout = "LastRollout"
+
+	// conditionMachineAPIPaused is used to denote that the Machine API has paused
+	// the reconciliation of some Control Plane Machines, for example while they are
+	// migrated to the Cluster API. The message lists the paused Machines. While this
+	// condition is present, the ControlPlaneMachineSet takes no action on the Control
+	// Plane Machines. Like the rollout phase, this condition is not reflected on the
+	// ClusterOperator.
+	conditionMachineAPIPaused = "MachineAPIPaused"
+
+	// conditionMissingTags is used to denote that some Control Plane Machines are
+	// missing the tags they are required to carry, such as the cluster ownership
+	// tag or the user defined tags of the cluster. The message lists the Machines
+	// and their missing tags. This condition is only present while tags are missing.
+	// Like the rollout phase, this condition is not reflected on the ClusterOperator.
+	conditionMissingTags = "MissingTags"
+
+	// conditionTemplateTagDrift is used to denote that the Machine template does not
+	// carry the tags required by the cluster, as described by the Infrastructure
+	// resource. The message lists the drifted tags. This condition is only present
+	// while the template has drifted. Like the rollout phase, this condition is not
+	// reflected on the ClusterOperator.
+	conditionTemplateTagDrift = "TemplateTagDrift"
+
+	// conditionDrainProgress is used to report the progress of the drain of the
+	// Nodes of deleted Control Plane Machines. The message lists, for each Node,
+	// the Pods remaining and the PodDisruptionBudgets blocking their eviction.
+	// This condition is only present while a Node is draining. Like the rollout
+	// phase, this condition is not reflected on the ClusterOperator.
+	conditionDrainProgress = "DrainProgress"
+
+	// conditionRolloutBanner is used to summarise the rollout of the Control Plane
+	// Machines for the OpenShift console. The reason is the level of the banner and
+	// the message summarises the rollout and names anything blocking it. This
+	// condition is only present while there is something to report. Like the rollout
+	// phase, this condition is not reflected on the ClusterOperator.
+	conditionRolloutBanner = "RolloutBanner"
+
+	// conditionStrategyTransition is used to report that replacements started by
+	// the RollingUpdate strategy are being completed after the update strategy was
+	// changed to OnDelete. The message lists the indexes of the replacements. This
+	// condition is only present while such a replacement is in flight. Like the
+	// rollout phase, this condition is not reflected on the ClusterOperator.
+	conditionStrategyTransition = "StrategyTransition"
+
+	// conditionFailureDomainBalance is used to report Control Plane Machines that
+	// unbalance the configured spread of the failure domains, for example after
+	// manual intervention. The message lists the Machines and the failure domains
+	// of their indexes. This condition is only present while the Machines are
+	// unbalanced. Like the rollout phase, this condition is not reflected on the
+	// ClusterOperator.
+	conditionFailureDomainBalance = "FailureDomainBalance"
+
+	// conditionFailureDomainDrift is used to report Control Plane Machines that are
+	// not within any of the failure domains of the ControlPlaneMachineSet, for example
+	// because a failure domain has been removed from the spec. The message lists the
+	// Machines and whether each needs an update. This condition is only present while
+	// such a Machine exists. Like the rollout phase, this condition is not reflected
+	// on the ClusterOperator.
+	conditionFailureDomainDrift = "FailureDomainDrift"
+
+	// conditionFailureDomainDiscovery is used to report the failure domains that
+	// have been discovered from the MachineSets and appended to the failure domains
+	// of the ControlPlaneMachineSet. The message lists the availability zones and
+	// their subnets. This condition is only present while a failure domain is
+	// discovered. Like the rollout phase, this condition is not reflected on the
+	// ClusterOperator.
+	conditionFailureDomainDiscovery = "FailureDomainDiscovery"
+
+	// conditionAutoscalerIncompatibility is used to report MachineAutoscalers that
+	// target the Control Plane Machines, either through the ControlPlaneMachineSet
+	// itself or through a MachineSet that selects Control Plane Machines. The
+	// message lists the MachineAutoscalers. This condition is only present while
+	// such a MachineAutoscaler exists. Like the rollout phase, this condition is
+	// not reflected on the ClusterOperator.
+	conditionAutoscalerIncompatibility = "AutoscalerIncompatibility"
+
+	// conditionAdoption is used to report the adoption of existing Control Plane
+	// Machines, when the ControlPlaneMachineSet begins in its adoption phase. While
+	// the adoption is pending, the condition is false and its message reports the
+	// outcome of the verification of the existing Machines. Like the rollout phase,
+	// this condition is not reflected on the ClusterOperator.
+	conditionAdoption = "Adoption"
+
+	// conditionCSRPendingApproval is used to report Control Plane Machines that are
+	// not ready because the kubelet of their Node is waiting for a certificate
+	// signing request to be approved. The message lists the Machines and their
+	// pending certificate signing requests. This condition is only present while
+	// such a Machine exists. Like the rollout phase, this condition is not
+	// reflected on the ClusterOperator.
+	conditionCSRPendingApproval = "CSRPendingApproval"
+
+	// conditionIndexReadiness is used to report, for each Control Plane Machine of
+	// each index, whether its cloud instance is running, its Node has joined the
+	// cluster and its Node is ready. The message lists the Machines of each index
+	// with the first of these that they have not reached. Like the rollout phase,
+	// this condition is not reflected on the ClusterOperator.
+	conditionIndexReadiness = "IndexReadiness"
+
+	// conditionFailedMachines is used to report Control Plane Machines that the
+	// Machine API has marked as failed. The message lists the Machines with the
+	// error reason and error message from their status, so that the cause reported
+	// by the cloud provider is visible on the ControlPlaneMachineSet. This condition
+	// is only present while such a Machine exists. Like the rollout phase, this
+	// condition is not reflected on the ClusterOperator.
+	conditionFailedMachines = "FailedMachines"
 )
 
 // Condition reasons for use in the ControlPlaneMachineSet status.
@@ -89,6 +246,49 @@ const (
 	// configuration, the ControlPlaneMachineSet will cease all operations.
 	reasonUnmanagedNodes = "UnmanagedNodes"
 
+	// reasonNodeTopologyMismatch denotes that the ControlPlaneMachineSet has identified some
+	// Control Plane Node with topology labels, such as the zone or region, that do not match
+	// the failure domain of its Machine. This is typically caused by the cloud provider
+	// labelling the Node incorrectly and breaks zone aware scheduling on the Control Plane.
+	// To prevent further replacements from spreading the issue, the ControlPlaneMachineSet
+	// will cease all operations until the labels are corrected.
+	reasonNodeTopologyMismatch = "NodeTopologyMismatch"
+
+	// reasonMissingInstance denotes that the ControlPlaneMachineSet has identified some
+	// Machines that report that they are running, but where the cloud instance backing the
+	// Machine no longer exists. This is only checked when instance verification is enabled.
+	reasonMissingInstance = "MissingInstance"
+
+	// reasonInvalidReplacementBudget denotes that the ControlPlaneMachineSet has identified an
+	// invalid value for the replacement budget annotation.
+	// This must be resolved by the user before operation of the ControlPlaneMachineSet
+	// can continue.
+	reasonInvalidReplacementBudget = "InvalidReplacementBudget"
+
+	// reasonInvalidStageTimeouts denotes that the ControlPlaneMachineSet has identified an
+	// invalid value for the stage timeouts annotation.
+	// This must be resolved by the user before operation of the ControlPlaneMachineSet
+	// can continue.
+	reasonInvalidStageTimeouts = "InvalidStageTimeouts"
+
+	// reasonSelectorMatchesNoMachines denotes that the ControlPlaneMachineSet has identified
+	// Control Plane Machines, by their role label, but that none of them are matched by the
+	// selector of the ControlPlaneMachineSet. Rather than replacing every Control Plane Machine,
+	// the ControlPlaneMachineSet will cease all operations until the selector is corrected.
+	reasonSelectorMatchesNoMachines = "SelectorMatchesNoMachines"
+
+	// reasonInconsistentControlPlane denotes that the ControlPlaneMachineSet has identified more
+	// etcd members than Control Plane Machines with Nodes, or Control Plane Nodes. As the Machines
+	// do not reflect the actual Control Plane, the ControlPlaneMachineSet will cease all operations
+	// until the etcd membership matches the Control Plane Machines and Nodes.
+	reasonInconsistentControlPlane = "InconsistentControlPlane"
+
+	// reasonMissingPermissions denotes that the service account of the operator was found to be
+	// missing permissions required by the controller when the operator started. The
+	// ControlPlaneMachineSet will cease all operations until the permissions have been granted and
+	// the operator has been restarted.
+	reasonMissingPermissions = "MissingPermissions"
+
 	// END: Degraded reasons.
 
 	// BEGIN: Progressing reasons.
@@ -111,5 +311,257 @@ const (
 	// replicas under its management that are currently in need of an update.
 	reasonNeedsUpdateReplicas = "NeedsUpdateReplicas"
 
+	// reasonDeletionBlocked denotes that the ControlPlaneMachineSet has identified
+	// Machines whose deletion has been blocked, for longer than the stuck deletion
+	// timeout, by finalizers belonging to other controllers. The rollout cannot
+	// complete until these finalizers are removed.
+	reasonDeletionBlocked = "DeletionBlocked"
+
+	// reasonReplacementBudgetExhausted denotes that the ControlPlaneMachineSet has identified
+	// replicas in need of an update, but that the replacement budget does not currently permit
+	// any further replacements. The rollout will continue once the budget permits.
+	reasonReplacementBudgetExhausted = "ReplacementBudgetExhausted"
+
 	// END: Progressing reasons.
+
+	// BEGIN: RolloutCostEstimate reasons.
+
+	// reasonCostEstimated denotes that the change in the monthly cost of the pending
+	// rollout has been estimated from the prices within the price catalog.
+	reasonCostEstimated = "CostEstimated"
+
+	// reasonPriceUnknown denotes that the change in the monthly cost of the pending
+	// rollout could not be estimated as the price catalog does not contain the price
+	// of at least one of the instance types involved.
+	reasonPriceUnknown = "PriceUnknown"
+
+	// END: RolloutCostEstimate reasons.
+
+	// BEGIN: MachineInstances reasons.
+
+	// reasonProviderIDsKnown denotes that every Machine managed by the ControlPlaneMachineSet
+	// has a provider ID.
+	reasonProviderIDsKnown = "ProviderIDsKnown"
+
+	// reasonProviderIDsPending denotes that at least one Machine managed by the
+	// ControlPlaneMachineSet does not yet have a provider ID, typically because its
+	// cloud instance is still being created.
+	reasonProviderIDsPending = "ProviderIDsPending"
+
+	// END: MachineInstances reasons.
+
+	// BEGIN: EtcdMembers reasons.
+
+	// reasonEtcdMembersMapped denotes that every etcd member runs on the Node of a
+	// Machine managed by the ControlPlaneMachineSet.
+	reasonEtcdMembersMapped = "EtcdMembersMapped"
+
+	// reasonUnmatchedEtcdMembers denotes that at least one etcd member does not run
+	// on the Node of any Machine managed by the ControlPlaneMachineSet.
+	reasonUnmatchedEtcdMembers = "UnmatchedEtcdMembers"
+
+	// END: EtcdMembers reasons.
+
+	// BEGIN: RecoveryGuidance reasons.
+
+	// reasonMultipleMachineFailures denotes that at least two indexes of the
+	// ControlPlaneMachineSet have no ready Machine, as their Machines have failed.
+	// This is also used as the reason of the warning event published when the
+	// failures are first observed.
+	reasonMultipleMachineFailures = "MultipleMachineFailures"
+
+	// END: RecoveryGuidance reasons.
+
+	// BEGIN: GatedBy reasons.
+
+	// reasonGatedByReplacementBudget denotes that replacements are blocked until the
+	// replacement budget next permits a replacement.
+	reasonGatedByReplacementBudget = "ReplacementBudget"
+
+	// reasonGatedByUserDeletion denotes that, with the OnDelete strategy, replacements
+	// are blocked until the user deletes the outdated Machines.
+	reasonGatedByUserDeletion = "UserDeletion"
+
+	// reasonGatedByOperatorDegraded denotes that replacements are paused until the
+	// ControlPlaneMachineSet is no longer degraded.
+	reasonGatedByOperatorDegraded = "OperatorDegraded"
+
+	// reasonGatedByUserPause denotes that replacements are blocked until the user
+	// resumes the ControlPlaneMachineSet by removing the paused annotation.
+	reasonGatedByUserPause = "UserPause"
+
+	// reasonGatedByMultipleMachineFailures denotes that replacements are suspended
+	// until fewer indexes of the ControlPlaneMachineSet have failed.
+	reasonGatedByMultipleMachineFailures = "MultipleMachineFailures"
+
+	// reasonGatedByAdoption denotes that replacements are blocked until the adoption
+	// of the existing Control Plane Machines is complete.
+	reasonGatedByAdoption = "Adoption"
+
+	// reasonGatedByNotReadyMachines denotes that, with the Wait not ready machine policy,
+	// replacements are blocked until the Nodes of the up to date Machines are ready.
+	reasonGatedByNotReadyMachines = "NotReadyMachines"
+
+	// END: GatedBy reasons.
+
+	// BEGIN: LastRollout reasons.
+
+	// reasonRolloutCompleted denotes that a rollout, that replaced at least one
+	// Control Plane Machine, has completed.
+	reasonRolloutCompleted = "RolloutCompleted"
+
+	// END: LastRollout reasons.
+
+	// BEGIN: MachineAPIPaused reasons.
+
+	// reasonMachinesPaused denotes that the Machine API has paused the reconciliation
+	// of at least one Control Plane Machine.
+	reasonMachinesPaused = "MachinesPaused"
+
+	// END: MachineAPIPaused reasons.
+
+	// BEGIN: MissingTags reasons.
+
+	// reasonRequiredTagsMissing denotes that at least one Control Plane Machine is
+	// missing tags it is required to carry.
+	reasonRequiredTagsMissing = "RequiredTagsMissing"
+
+	// END: MissingTags reasons.
+
+	// BEGIN: TemplateTagDrift reasons.
+
+	// reasonTemplateTagsDrifted denotes that the Machine template is missing, or
+	// carries a different value for, at least one tag required by the cluster.
+	reasonTemplateTagsDrifted = "TemplateTagsDrifted"
+
+	// END: TemplateTagDrift reasons.
+
+	// BEGIN: DrainProgress reasons.
+
+	// reasonDrainInProgress denotes that at least one Control Plane Node is draining,
+	// and that no drain has exceeded the drain timeout.
+	reasonDrainInProgress = "DrainInProgress"
+
+	// reasonDrainTimedOut denotes that the drain of at least one Control Plane Node
+	// has not completed within the drain timeout.
+	reasonDrainTimedOut = "DrainTimedOut"
+
+	// END: DrainProgress reasons.
+
+	// START: StrategyTransition reasons.
+
+	// reasonCompletingInFlightReplacements denotes that replacements started by the
+	// RollingUpdate strategy are being completed after the update strategy was changed.
+	reasonCompletingInFlightReplacements = "CompletingInFlightReplacements"
+
+	// END: StrategyTransition reasons.
+
+	// BEGIN: FailureDomainBalance reasons.
+
+	// reasonFailureDomainsUnbalanced denotes that at least one Control Plane Machine
+	// unbalances the spread of the failure domains, and rebalancing is not enabled.
+	reasonFailureDomainsUnbalanced = "FailureDomainsUnbalanced"
+
+	// reasonRebalancingFailureDomains denotes that the Control Plane Machines that
+	// unbalance the spread of the failure domains need an update, and are replaced
+	// according to the update strategy.
+	reasonRebalancingFailureDomains = "RebalancingFailureDomains"
+
+	// END: FailureDomainBalance reasons.
+
+	// BEGIN: FailureDomainDrift reasons.
+
+	// reasonMachinesOutsideFailureDomains denotes that at least one Control Plane
+	// Machine is not within any of the failure domains of the ControlPlaneMachineSet.
+	reasonMachinesOutsideFailureDomains = "MachinesOutsideFailureDomains"
+
+	// END: FailureDomainDrift reasons.
+
+	// BEGIN: FailureDomainDiscovery reasons.
+
+	// reasonDiscoveredFailureDomains denotes that at least one failure domain has
+	// been discovered from the MachineSets.
+	reasonDiscoveredFailureDomains = "DiscoveredFailureDomains"
+
+	// END: FailureDomainDiscovery reasons.
+
+	// BEGIN: AutoscalerIncompatibility reasons.
+
+	// reasonControlPlaneTargetedByAutoscaler denotes that at least one MachineAutoscaler
+	// targets the Control Plane Machines.
+	reasonControlPlaneTargetedByAutoscaler = "ControlPlaneTargetedByAutoscaler"
+
+	// END: AutoscalerIncompatibility reasons.
+
+	// BEGIN: Adoption reasons.
+
+	// reasonAdoptionVerificationFailed denotes that the existing Control Plane Machines
+	// cannot be adopted as they are, for example because an index has no Machine.
+	reasonAdoptionVerificationFailed = "AdoptionVerificationFailed"
+
+	// reasonAwaitingAdoptionConfirmation denotes that the existing Control Plane
+	// Machines have been verified, and the adoption is waiting for the user to
+	// confirm it.
+	reasonAwaitingAdoptionConfirmation = "AwaitingAdoptionConfirmation"
+
+	// reasonAdopted denotes that the existing Control Plane Machines have been adopted.
+	reasonAdopted = "Adopted"
+
+	// END: Adoption reasons.
+
+	// BEGIN: CSRPendingApproval reasons.
+
+	// reasonCertificateSigningRequestsPending denotes that at least one Control Plane
+	// Machine is waiting for a certificate signing request for its Node to be approved.
+	reasonCertificateSigningRequestsPending = "CertificateSigningRequestsPending"
+
+	// END: CSRPendingApproval reasons.
+
+	// BEGIN: IndexReadiness reasons.
+
+	// reasonInstancesNotRunning denotes that the cloud instance of at least one
+	// Control Plane Machine is not running.
+	reasonInstancesNotRunning = "InstancesNotRunning"
+
+	// reasonNodesNotJoined denotes that the cloud instance of every Control Plane
+	// Machine is running, but the Node of at least one has not joined the cluster.
+	reasonNodesNotJoined = "NodesNotJoined"
+
+	// reasonNodesNotReady denotes that the Node of every Control Plane Machine has
+	// joined the cluster, but at least one is not ready.
+	reasonNodesNotReady = "NodesNotReady"
+
+	// reasonIndexesReady denotes that the Node of every Control Plane Machine is ready.
+	reasonIndexesReady = "IndexesReady"
+
+	// END: IndexReadiness reasons.
+
+	// BEGIN: FailedMachines reasons.
+
+	// reasonMachinesFailed denotes that at least one Control Plane Machine has
+	// failed, and reports an error within its status.
+	reasonMachinesFailed = "MachinesFailed"
+
+	// END: FailedMachines reasons.
+
+	// BEGIN: ClusterOperator event reasons.
+
+	// reasonRolloutStarted denotes that a Control Plane Machine first needed to be
+	// replaced. The completion of the rollout is published with reasonRolloutCompleted.
+	reasonRolloutStarted = "RolloutStarted"
+
+	// reasonRolloutStalled denotes that a rollout is blocked until the user, or the
+	// Machine API, takes action.
+	reasonRolloutStalled = "RolloutStalled"
+
+	// END: ClusterOperator event reasons.
+
+	// BEGIN: AbandonedMachines event reasons.
+
+	// reasonAbandonedSurgeMachinesIgnored denotes that abandoned surge Machines were found
+	// when the operator started, and were left in place as per the Ignore abandoned
+	// machine policy.
+	reasonAbandonedSurgeMachinesIgnored = "AbandonedSurgeMachinesIgnored"
+
+	// END: AbandonedMachines event reasons.
 )
@@ -18,19 +18,30 @@ package controlplanemachineset
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/go-logr/logr"
 	configv1 "github.com/openshift/api/config/v1"
 	machinev1 "github.com/openshift/api/machine/v1"
 	machinev1beta1 "github.com/openshift/api/machine/v1beta1"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/controllers/operatorconfig"
 	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders/failuredomainsource"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders/faultinjection"
 	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders/providers"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/permissions"
 
+	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	errorutils "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/client-go/tools/record"
 
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/builder"
@@ -41,10 +52,14 @@ import (
 )
 
 const (
-	// clusterControlPlaneMachineSetName is the name of the ControlPlaneMachineSet.
-	// As ControlPlaneMachineSets are singletons within the namespace, only ControlPlaneMachineSets
-	// with this name should be reconciled.
-	clusterControlPlaneMachineSetName = "cluster"
+	// DefaultControlPlaneMachineSetName is the default name of the ControlPlaneMachineSet.
+	// As ControlPlaneMachineSets are singletons within the namespace, only the ControlPlaneMachineSet
+	// with the configured name, this name unless configured otherwise, should be reconciled.
+	DefaultControlPlaneMachineSetName = "cluster"
+
+	// controllerName is the name of the ControlPlaneMachineSet controller, used as the source of the events it
+	// publishes.
+	controllerName = "control-plane-machine-set-controller"
 
 	// controlPlaneMachineSetFinalizer is the finalizer used by the ControlPlaneMachineSet operator
 	// to prevent deletion until the operator has cleaned up owner references on the Control Plane Machines.
@@ -53,6 +68,59 @@ const (
 	// degradedClusterState is used to denote that the control plane machine set has detected a degraded cluster.
 	// In this case, the controller will not perform any further actions.
 	degradedClusterState = "Cluster state is degraded. The control plane machine set will not take any action until issues have been resolved."
+
+	// unmanagedFieldsAnnotation is the annotation used to publish, on each Control Plane Machine, the comma separated
+	// list of provider spec fields that differ from the desired spec, but where the difference is deliberately
+	// tolerated and does not cause the Machine to be replaced.
+	unmanagedFieldsAnnotation = "controlplanemachineset.machine.openshift.io/unmanaged-fields"
+
+	// updatedUnmanagedFields is a log message used to inform the user that the unmanaged fields annotation
+	// on a Machine has been updated.
+	updatedUnmanagedFields = "Updated unmanaged fields annotation on machine"
+
+	// masterNodeRoleLabel is the label used to identify Control Plane Nodes.
+	masterNodeRoleLabel = "node-role.kubernetes.io/master"
+
+	// noReadyMachines is a log message used to inform the user that none of the Control Plane Machines are ready.
+	noReadyMachines = "No ready control plane machines found"
+
+	// observedUnmanagedNodes is a log message used to inform the user that some Control Plane Nodes do not
+	// have an associated Control Plane Machine.
+	observedUnmanagedNodes = "Observed unmanaged control plane nodes"
+
+	// observedNodeTopologyMismatch is a log message used to inform the user that some Control Plane Nodes have
+	// topology labels that do not match the failure domain of their Machine.
+	observedNodeTopologyMismatch = "Observed control plane nodes with topology labels not matching their machine"
+
+	// observedUnselectedMachines is a log message used to inform the user that none of the Control Plane Machines are
+	// matched by the selector of the ControlPlaneMachineSet.
+	observedUnselectedMachines = "Observed control plane machines not matched by the selector"
+
+	// observedMissingInstances is a log message used to inform the user that some Control Plane Machines are
+	// running, but that their cloud instance no longer exists.
+	observedMissingInstances = "Observed control plane machines with missing instances"
+)
+
+var (
+	// errNoReadyControlPlaneMachines is used to inform users that none of the Control Plane Machines are ready.
+	errNoReadyControlPlaneMachines = errors.New("no ready control plane machines")
+
+	// errUnmanagedControlPlaneNodes is used to inform users that some Control Plane Nodes do not have an associated
+	// Control Plane Machine.
+	errUnmanagedControlPlaneNodes = errors.New("found unmanaged control plane nodes")
+
+	// errNodeTopologyMismatch is used to inform users that some Control Plane Nodes have topology labels that do
+	// not match the failure domain of their Machine. This is typically caused by the cloud provider labelling the
+	// Node incorrectly, and will break zone aware scheduling of workloads on the Control Plane.
+	errNodeTopologyMismatch = errors.New("found control plane nodes with topology labels not matching their machine")
+
+	// errSelectorMatchesNoMachines is used to inform users that Control Plane Machines exist, but that none of them
+	// are matched by the selector of the ControlPlaneMachineSet.
+	errSelectorMatchesNoMachines = errors.New("selector does not match any control plane machines")
+
+	// errMissingInstances is used to inform users that some Control Plane Machines are running, but that the cloud
+	// instance identified by their provider ID no longer exists.
+	errMissingInstances = errors.New("found control plane machines with missing instances")
 )
 
 // ControlPlaneMachineSetReconciler reconciles a ControlPlaneMachineSet object.
@@ -65,22 +133,125 @@ type ControlPlaneMachineSetReconciler struct {
 	// Any ControlPlaneMachineSet not in this namespace should be ignored.
 	Namespace string
 
+	// Name is the name of the ControlPlaneMachineSet singleton that the controller should reconcile.
+	// Any ControlPlaneMachineSet with a different name should be ignored. When empty,
+	// DefaultControlPlaneMachineSetName is used.
+	Name string
+
 	// OperatorName is the name of the ClusterOperator with which the controller should report
 	// its status.
 	OperatorName string
+
+	// InstanceVerificationInterval is the interval at which the controller periodically resyncs the
+	// ControlPlaneMachineSet to verify that the cloud instance backing each Control Plane Machine still exists.
+	// When an instance has vanished behind a Machine that is still running, the ControlPlaneMachineSet is
+	// degraded. When zero, instance verification is disabled.
+	InstanceVerificationInterval time.Duration
+
+	// StuckDeletionTimeout is the duration after which a deleted Control Plane Machine, still held by finalizers
+	// belonging to other controllers, is considered stuck. Stuck Machines are reported within the status of the
+	// ControlPlaneMachineSet and, once the user has opted in via an annotation on the Machine, the blocking
+	// finalizers may be removed so that the rollout can continue. When zero, stuck deletions are not detected.
+	StuckDeletionTimeout time.Duration
+
+	// DrainTimeout is the duration after which the drain of the Node of a deleted Control Plane Machine, that has not
+	// yet completed, is escalated as per the DrainEscalationPolicy. The progress of each drain is reported within
+	// the status of the ControlPlaneMachineSet regardless. When zero, drains are never escalated.
+	DrainTimeout time.Duration
+
+	// DrainEscalationPolicy determines how a drain that has not completed within the DrainTimeout is escalated.
+	// When empty, drains that have timed out are only reported.
+	DrainEscalationPolicy DrainEscalationPolicy
+
+	// APIReader is used to read objects outside of the namespace of the ControlPlaneMachineSet, which are not held
+	// within the cache of the manager. For example, the etcd membership published by the etcd operator.
+	// When nil, the etcd membership is not compared with the Control Plane Machines and Nodes.
+	APIReader client.Reader
+
+	// FailureDomainSources holds the failure domain sources that the ControlPlaneMachineSet may source its failure
+	// domains from, by annotating the ControlPlaneMachineSet with the name of the source.
+	// When nil, no sources are registered, and a ControlPlaneMachineSet that selects one is degraded.
+	FailureDomainSources *failuredomainsource.Registry
+
+	// DeleteDepartedNodes enables the removal of departed Control Plane Nodes. Some platforms leave the Node behind
+	// once the Machine backing it has been removed. Such a Node is only deleted once its Machine no longer exists, it
+	// is no longer ready, and the etcd operator has removed its etcd member. When false, departed Nodes are reported
+	// as unmanaged Nodes and must be removed by the user.
+	DeleteDepartedNodes bool
+
+	// RepairMissingTags enables the repair of Control Plane Machines missing the tags they are required to carry,
+	// such as the cluster ownership tag. The missing tags are added to the spec of the Machines in place, without
+	// replacing them. When false, Machines missing tags are reported but otherwise left unchanged.
+	RepairMissingTags bool
+
+	// PriceCatalog provides the prices of instance types, used to estimate the change in the monthly cost of the
+	// Control Plane when a pending rollout changes the instance type of the Control Plane Machines. When nil, the
+	// cost of pending rollouts is not estimated.
+	PriceCatalog PriceCatalog
+
+	// OperatorConfig holds the runtime configuration of the operator, which may override DeleteDepartedNodes and
+	// RepairMissingTags without restarting the operator. When nil, the fields of the reconciler apply.
+	OperatorConfig *operatorconfig.Store
+
+	// FaultInjection describes the faults injected into the MachineProvider, so that the resilience of the rollout
+	// can be tested in CI and by chaos tooling. When nil, no faults are injected. This must never be set in
+	// production.
+	FaultInjection *faultinjection.Config
+
+	// MissingPermissions are the permissions required by the controller that the service account of the operator
+	// was found to be missing when the operator started. While any permission is missing, the ControlPlaneMachineSet
+	// and the cluster operator are reported as degraded, and no action is taken.
+	MissingPermissions []permissions.Permission
+
+	// Recorder is used to publish events about the ControlPlaneMachineSet. For example, to inform the user of
+	// configuration errors that must be corrected before the ControlPlaneMachineSet can continue.
+	Recorder record.EventRecorder
+
+	// AbandonedMachinePolicy determines how abandoned surge Machines, left alongside a ready, up to date Machine by
+	// earlier versions of the operator, are handled when the operator starts. When empty, the Ignore policy is used:
+	// abandoned surge Machines are reported through a warning event, but otherwise left in place.
+	AbandonedMachinePolicy AbandonedMachinePolicy
+
+	// NotReadyMachinePolicy determines how the RollingUpdate strategy handles up to date Control Plane Machines whose
+	// Nodes are not ready when it is about to replace the next outdated Machine. When empty, such Machines are
+	// skipped and the rollout continues regardless.
+	NotReadyMachinePolicy NotReadyMachinePolicy
+
+	// rolloutDisruption accumulates the disruption windows observed during the rollout in progress, so that they can
+	// be summarised once the rollout completes.
+	rolloutDisruption rolloutDisruption
+
+	// abandonedMachinesCollected is set once no abandoned surge Machines remain, after which they are no longer
+	// searched for until the operator is restarted.
+	abandonedMachinesCollected bool
 }
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *ControlPlaneMachineSetReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	// All predicates are executed before the event handler is called
 	if err := ctrl.NewControllerManagedBy(mgr).
-		For(&machinev1.ControlPlaneMachineSet{}, builder.WithPredicates(filterControlPlaneMachineSet(r.Namespace))).
+		For(&machinev1.ControlPlaneMachineSet{}, builder.WithPredicates(filterControlPlaneMachineSet(r.Namespace, r.controlPlaneMachineSetName()))).
 		Owns(&machinev1beta1.Machine{}, builder.WithPredicates(filterControlPlaneMachines(r.Namespace))).
+		Watches(
+			&source.Kind{Type: &machinev1beta1.Machine{}},
+			handler.EnqueueRequestsFromMapFunc(machineToControlPlaneMachineSet(r.Namespace, r.controlPlaneMachineSetName())),
+			builder.WithPredicates(filterControlPlaneMachines(r.Namespace), filterReconcileRequests()),
+		).
+		Watches(
+			&source.Kind{Type: &corev1.ConfigMap{}},
+			handler.EnqueueRequestsFromMapFunc(configMapToControlPlaneMachineSet(mgr.GetClient(), r.Namespace, r.controlPlaneMachineSetName())),
+			builder.WithPredicates(filterNamespace(r.Namespace)),
+		).
 		Watches(
 			&source.Kind{Type: &configv1.ClusterOperator{}},
-			handler.EnqueueRequestsFromMapFunc(clusterOperatorToControlPlaneMachineSet(r.Namespace)),
+			handler.EnqueueRequestsFromMapFunc(clusterOperatorToControlPlaneMachineSet(r.Namespace, r.controlPlaneMachineSetName())),
 			builder.WithPredicates(filterClusterOperator(r.OperatorName)),
 		).
+		Watches(
+			&source.Kind{Type: &configv1.Infrastructure{}},
+			handler.EnqueueRequestsFromMapFunc(infrastructureToControlPlaneMachineSet(r.Namespace, r.controlPlaneMachineSetName())),
+			builder.WithPredicates(filterInfrastructureStatusChanges()),
+		).
 		Complete(r); err != nil {
 		return fmt.Errorf("could not set up controller for control plane machine set: %w", err)
 	}
@@ -88,10 +259,22 @@ func (r *ControlPlaneMachineSetReconciler) SetupWithManager(mgr ctrl.Manager) er
 	// Set up API helpers from the manager.
 	r.Scheme = mgr.GetScheme()
 	r.RESTMapper = mgr.GetRESTMapper()
+	r.APIReader = mgr.GetAPIReader()
+	r.Recorder = mgr.GetEventRecorderFor(controllerName)
 
 	return nil
 }
 
+// controlPlaneMachineSetName returns the name of the ControlPlaneMachineSet singleton that the controller
+// reconciles, defaulting to DefaultControlPlaneMachineSetName when no name is configured.
+func (r *ControlPlaneMachineSetReconciler) controlPlaneMachineSetName() string {
+	if r.Name == "" {
+		return DefaultControlPlaneMachineSetName
+	}
+
+	return r.Name
+}
+
 // Reconcile reconciles the ControlPlaneMachineSet object.
 func (r *ControlPlaneMachineSetReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	logger := log.FromContext(ctx, "namespace", req.Namespace, "name", req.Name)
@@ -104,8 +287,21 @@ func (r *ControlPlaneMachineSetReconciler) Reconcile(ctx context.Context, req ct
 
 	// Fetch the ControlPlaneMachineSet and set the cluster operator to available if it doesn't exist.
 	if err := r.Get(ctx, cpmsKey, cpms); apierrors.IsNotFound(err) {
+		// A ControlPlaneMachineSet that opted in to being restored is re-created from its last applied configuration.
+		// Creating the ControlPlaneMachineSet triggers a new reconcile.
+		if restored, err := r.restoreControlPlaneMachineSet(ctx, logger, cpmsKey); err != nil {
+			return ctrl.Result{}, fmt.Errorf("unable to restore control plane machine set: %w", err)
+		} else if restored {
+			return ctrl.Result{}, nil
+		}
+
 		logger.V(1).Info("No control plane machine set found, setting operator status available")
 
+		// Without a ControlPlaneMachineSet, nothing orders the removal of the Control Plane Machines.
+		if err := r.releaseMachineFinalizers(ctx, logger); err != nil {
+			return ctrl.Result{}, fmt.Errorf("unable to release machine finalizers: %w", err)
+		}
+
 		if err := r.setClusterOperatorAvailable(ctx, logger); err != nil {
 			return ctrl.Result{}, fmt.Errorf("unable to reconcile cluster operator status: %w", err)
 		}
@@ -115,19 +311,26 @@ func (r *ControlPlaneMachineSetReconciler) Reconcile(ctx context.Context, req ct
 		return ctrl.Result{}, fmt.Errorf("unable to fetch control plane machine set: %w", err)
 	}
 
-	// Take a copy of the original object to be able to create a patch for the status at the end.
-	patchBase := client.MergeFrom(cpms)
+	// Take a copy of the original object to be able to compare the status, and create a patch for it, at the end.
+	original := cpms.DeepCopy()
 
 	// Collect errors as an aggregate to return together after all patches have been performed.
 	var errs []error
 
 	result, err := r.reconcile(ctx, logger, cpms)
-	if err != nil {
+	if r.handleConfigurationError(logger, cpms, err) {
+		// Configuration errors cannot be resolved by retrying, the user must correct the configuration, which will
+		// trigger a new reconcile. The error is surfaced within the status rather than forcing a requeue.
+		result = ctrl.Result{}
+	} else if err != nil {
 		// Don't return an error here so that we have an opportunity to update the status and cluster operator status.
 		errs = append(errs, fmt.Errorf("error reconciling control plane machine set: %w", err))
 	}
 
-	if err := r.updateControlPlaneMachineSetStatus(ctx, logger, cpms, patchBase); err != nil {
+	// The rollout banner summarises the other conditions, so it is set once they have all been set.
+	setRolloutBannerCondition(cpms)
+
+	if err := r.updateControlPlaneMachineSetStatus(ctx, logger, cpms, original); err != nil {
 		// Don't return an error here so that we have an opportunity to update the cluster operator status.
 		errs = append(errs, fmt.Errorf("error updating control plane machine set status: %w", err))
 	}
@@ -137,17 +340,44 @@ func (r *ControlPlaneMachineSetReconciler) Reconcile(ctx context.Context, req ct
 		errs = append(errs, fmt.Errorf("error updating control plane machine set status: %w", err))
 	}
 
+	if err := r.publishClusterOperatorEvents(ctx, logger, original, cpms); err != nil {
+		errs = append(errs, fmt.Errorf("error publishing cluster operator events: %w", err))
+	}
+
 	if len(errs) > 0 {
 		return ctrl.Result{}, errorutils.NewAggregate(errs)
 	}
 
-	return result, nil
+	return r.requeueForInstanceVerification(result), nil
+}
+
+// requeueForInstanceVerification ensures that, when instance verification is enabled, the ControlPlaneMachineSet
+// is reconciled again within the instance verification interval, even when nothing on the cluster changes.
+// Changes to the cloud instances are not reflected on the cluster until the Machine API next observes them, so a
+// periodic resync is required to notice instances that have vanished.
+func (r *ControlPlaneMachineSetReconciler) requeueForInstanceVerification(result ctrl.Result) ctrl.Result {
+	if r.InstanceVerificationInterval <= 0 || result.Requeue {
+		return result
+	}
+
+	if result.RequeueAfter == 0 || result.RequeueAfter > r.InstanceVerificationInterval {
+		result.RequeueAfter = r.InstanceVerificationInterval
+	}
+
+	return result
 }
 
 // reconcile performs the main business logic of the ControlPlaneMachineSet operator.
 // Notably it actions the various parts of the business logic without performing any status updates on the
 // ControlPlaneMachineSet object itself, these updates are handled at the parent scope.
 func (r *ControlPlaneMachineSetReconciler) reconcile(ctx context.Context, logger logr.Logger, cpms *machinev1.ControlPlaneMachineSet) (ctrl.Result, error) {
+	// Without the permissions it requires, any action may fail part way through, so none is taken.
+	if len(r.MissingPermissions) > 0 {
+		r.setMissingPermissions(logger, cpms)
+
+		return ctrl.Result{}, nil
+	}
+
 	// If the control plane machine set is being deleted, we need to handle that rather than the regular reconcile flow.
 	if cpms.GetDeletionTimestamp() != nil {
 		return r.reconcileDelete(ctx, logger, cpms)
@@ -161,22 +391,49 @@ func (r *ControlPlaneMachineSetReconciler) reconcile(ctx context.Context, logger
 		return ctrl.Result{Requeue: true}, nil
 	}
 
-	machineProvider, err := providers.NewMachineProvider(ctx, logger, r.Client, cpms)
+	if err := r.ensureLastAppliedConfigMap(ctx, logger, cpms); err != nil {
+		return ctrl.Result{}, fmt.Errorf("error recording last applied control plane machine set: %w", err)
+	}
+
+	providerCPMS, err := r.resolveFailureDomains(ctx, cpms)
+	if err != nil {
+		return ctrl.Result{}, fmt.Errorf("error resolving failure domains: %w", err)
+	}
+
+	providerCPMS, err = r.discoverFailureDomains(ctx, logger, cpms, providerCPMS)
+	if err != nil {
+		return ctrl.Result{}, fmt.Errorf("error discovering failure domains: %w", err)
+	}
+
+	machineProvider, err := providers.NewMachineProvider(ctx, logger, r.Client, providerCPMS)
 	if err != nil {
 		return ctrl.Result{}, fmt.Errorf("error constructing machine provider: %w", err)
 	}
 
+	if r.FaultInjection != nil {
+		machineProvider = faultinjection.NewMachineProvider(machineProvider, *r.FaultInjection)
+	}
+
 	machineInfos, err := machineProvider.GetMachineInfos(ctx, logger)
 	if err != nil {
 		return ctrl.Result{}, fmt.Errorf("error fetching machine info: %w", err)
 	}
 
+	setTemplateTagDriftCondition(cpms, machineProvider.TemplateTagDrift())
+
 	indexedMachineInfos, err := machineInfosByIndex(cpms, machineInfos)
 	if err != nil {
 		return ctrl.Result{}, fmt.Errorf("could not sort machine info by index: %w", err)
 	}
 
-	result, err := r.reconcileMachines(ctx, logger, cpms, machineProvider, indexedMachineInfos)
+	// Record the inputs to, and outcome of, the reconcile so that it can be summarised within a single log line.
+	machineDecision := newDecision(cpms, indexedMachineInfos)
+
+	result, err := r.reconcileMachines(ctx, logger, cpms, machineDecision.recordActions(machineProvider), indexedMachineInfos)
+
+	machineDecision.complete(cpms, err)
+	machineDecision.log(logger)
+
 	if err != nil {
 		return ctrl.Result{}, fmt.Errorf("error reconciling machines: %w", err)
 	}
@@ -192,27 +449,167 @@ func (r *ControlPlaneMachineSetReconciler) reconcileMachines(ctx context.Context
 		return ctrl.Result{}, fmt.Errorf("error reconciling machine info with status: %w", err)
 	}
 
+	r.observeRollout(cpms, machineInfos, time.Now())
+	setRolloutPhaseCondition(cpms, machineInfos)
+	setRolloutCostEstimateCondition(cpms, machineInfos, r.PriceCatalog)
+	setMachineInstancesCondition(cpms, machineInfos)
+
+	if err := r.reconcileIndexReadiness(ctx, cpms, machineInfos); err != nil {
+		return ctrl.Result{}, fmt.Errorf("error reconciling index readiness: %w", err)
+	}
+
+	membership, err := r.etcdMembership(ctx)
+	if err != nil {
+		return ctrl.Result{}, fmt.Errorf("failed to determine etcd membership: %w", err)
+	}
+
+	if err := r.reconcileEtcdMembers(ctx, cpms, machineInfos, membership); err != nil {
+		return ctrl.Result{}, fmt.Errorf("error mapping etcd members: %w", err)
+	}
+
+	// While the Machine API has paused any Control Plane Machine, no action is taken on the Control Plane Machines.
+	// Any replacement could neither be created nor removed by the Machine API, and would otherwise time out.
+	// The Machines are watched, so the ControlPlaneMachineSet is reconciled again once they are unpaused.
+	if pausedMachines := pausedMachineNames(machineInfos); len(pausedMachines) > 0 {
+		clearGatedByCondition(cpms)
+		setMachineAPIPausedCondition(cpms, pausedMachines)
+		logger.V(1).Info(machineAPIPausedState, "pausedMachines", pausedMachines)
+
+		return ctrl.Result{}, nil
+	}
+
+	clearMachineAPIPausedCondition(cpms)
+
 	if err := r.ensureOwnerReferences(ctx, logger, cpms, machineInfos); err != nil {
 		return ctrl.Result{}, fmt.Errorf("error ensuring owner references: %w", err)
 	}
 
-	if err := r.validateClusterState(ctx, logger, cpms, machineInfos); err != nil {
+	if err := r.ensureUnmanagedFieldsAnnotations(ctx, logger, machineInfos); err != nil {
+		return ctrl.Result{}, fmt.Errorf("error publishing unmanaged fields: %w", err)
+	}
+
+	if err := r.ensureTemplateAnnotations(ctx, logger, cpms, machineInfos); err != nil {
+		return ctrl.Result{}, fmt.Errorf("error propagating template annotations: %w", err)
+	}
+
+	if err := r.reconcileMissingTags(ctx, logger, cpms, machineProvider, machineInfos); err != nil {
+		return ctrl.Result{}, fmt.Errorf("error reconciling missing machine tags: %w", err)
+	}
+
+	setFailureDomainBalanceCondition(logger, cpms, machineInfos)
+	r.setFailureDomainDriftCondition(logger, cpms, machineInfos)
+
+	if err := r.reconcileAutoscalerCompatibility(ctx, logger, cpms, machineInfos); err != nil {
+		return ctrl.Result{}, fmt.Errorf("error reconciling autoscaler compatibility: %w", err)
+	}
+
+	reconcileAdoption(logger, cpms, machineInfos)
+
+	if err := r.reconcileCSRPendingApproval(ctx, logger, cpms, machineInfos); err != nil {
+		return ctrl.Result{}, fmt.Errorf("error reconciling pending certificate signing requests: %w", err)
+	}
+
+	r.reconcileFailedMachines(logger, cpms, machineInfos)
+
+	if err := r.validateClusterState(ctx, logger, cpms, machineInfos, membership); err != nil {
 		return ctrl.Result{}, fmt.Errorf("error validating cluster state: %w", err)
 	}
 
-	if isControlPlaneMachineSetDegraded(cpms) {
-		logger.V(1).Info(degradedClusterState)
+	if _, err := parseStageTimeouts(cpms.GetAnnotations()); err != nil {
+		setInvalidStageTimeoutsCondition(cpms, err)
+		logger.Error(err, invalidStageTimeoutsMessage)
+
+		// Do not return an error here as the timeouts will need user intervention to resolve.
 		return ctrl.Result{}, nil
 	}
 
-	result, err := r.reconcileMachineUpdates(ctx, logger, cpms, machineProvider, machineInfos)
+	if _, err := parseReplacementBudget(cpms.GetAnnotations()); err != nil {
+		setInvalidReplacementBudgetCondition(cpms, err)
+		logger.Error(err, invalidReplacementBudgetMessage)
+
+		// Do not return an error here as the budget will need user intervention to resolve.
+		return ctrl.Result{}, nil
+	}
+
+	// While multiple indexes have failed, losing any further etcd member could break quorum, so the remaining
+	// reconciliation, which may delete Machines or remove their protections, is replaced by a guided recovery.
+	if failed := failedMachineIndexes(machineInfos); len(failed) >= minimumConcurrentFailures {
+		return r.reconcileMultipleMachineFailures(ctx, logger, cpms, machineProvider, machineInfos, failed)
+	}
+
+	clearRecoveryGuidanceCondition(cpms)
+
+	result, err := r.reconcileStuckDeletions(ctx, logger, cpms, machineInfos)
+	if err != nil {
+		return ctrl.Result{}, fmt.Errorf("error reconciling stuck machine deletions: %w", err)
+	}
+
+	finalizerResult, err := r.reconcileMachineFinalizers(ctx, logger, cpms, machineInfos, membership)
 	if err != nil {
-		return ctrl.Result{}, fmt.Errorf("error reconciling machine updates: %w", err)
+		return ctrl.Result{}, fmt.Errorf("error reconciling machine finalizers: %w", err)
+	}
+
+	result = mergeResults(result, finalizerResult)
+
+	drainResult, err := r.reconcileDrainProgress(ctx, logger, cpms, machineInfos)
+	if err != nil {
+		return ctrl.Result{}, fmt.Errorf("error reconciling drain progress: %w", err)
+	}
+
+	result = mergeResults(result, drainResult)
+
+	clearGatedByCondition(cpms)
+
+	// The indexes are only recorded once the cluster state has been validated, so that an inconsistent view of the
+	// Control Plane is never recorded.
+	if !isControlPlaneMachineSetDegraded(cpms) {
+		if err := r.recordMachineIndexes(ctx, logger, cpms, machineInfos); err != nil {
+			return ctrl.Result{}, fmt.Errorf("error recording machine indexes: %w", err)
+		}
+	}
+
+	if isControlPlaneMachineSetDegraded(cpms) {
+		logger.V(1).Info(degradedClusterState)
+	} else if isControlPlaneMachineSetPaused(cpms) {
+		logger.V(1).Info(pausedClusterState)
+	} else if isAdoptionPending(cpms) {
+		logger.V(1).Info(adoptionPendingState)
+	} else if removedAbandoned, err := r.reconcileAbandonedMachines(ctx, logger, cpms, machineProvider, machineInfos); err != nil {
+		return ctrl.Result{}, fmt.Errorf("error reconciling abandoned machines: %w", err)
+	} else if !removedAbandoned {
+		// Updates are only considered once any redundant Machine removed on this pass has been observed deleting.
+		updateResult, err := r.reconcileMachineUpdates(ctx, logger, cpms, machineProvider, machineInfos)
+		if err != nil {
+			return ctrl.Result{}, fmt.Errorf("error reconciling machine updates: %w", err)
+		}
+
+		result = mergeResults(result, updateResult)
+	}
+
+	setStrategyGatedByCondition(cpms, machineInfos)
+
+	if err := r.ensureReconcileRequestsObserved(ctx, logger, machineInfos); err != nil {
+		return ctrl.Result{}, fmt.Errorf("error acknowledging reconcile requests: %w", err)
 	}
 
 	return result, nil
 }
 
+// mergeResults combines two results so that the ControlPlaneMachineSet is requeued as soon as either result
+// requires.
+func mergeResults(a, b ctrl.Result) ctrl.Result {
+	result := ctrl.Result{
+		Requeue:      a.Requeue || b.Requeue,
+		RequeueAfter: a.RequeueAfter,
+	}
+
+	if result.RequeueAfter == 0 || (b.RequeueAfter > 0 && b.RequeueAfter < result.RequeueAfter) {
+		result.RequeueAfter = b.RequeueAfter
+	}
+
+	return result
+}
+
 // reconcileDelete handles the removal logic for the ControlPlaneMachineSet resource.
 // During the deletion process, the controller is expected to remove any owner references from Machines
 // that are owned by the ControlPlaneMachineSet.
@@ -241,14 +638,360 @@ func (r *ControlPlaneMachineSetReconciler) ensureOwnerReferences(ctx context.Con
 	return nil
 }
 
+// ensureUnmanagedFieldsAnnotations publishes the unmanaged fields of each Machine within the machineInfos as an
+// annotation on the Machine. This allows users to see which differences between the Machine and the desired spec
+// are being deliberately tolerated. When a Machine has no unmanaged fields, the annotation is removed.
+func (r *ControlPlaneMachineSetReconciler) ensureUnmanagedFieldsAnnotations(ctx context.Context, logger logr.Logger, machineInfos map[int32][]machineproviders.MachineInfo) error {
+	for _, indexMachineInfos := range machineInfos {
+		for _, machineInfo := range indexMachineInfos {
+			if machineInfo.MachineRef == nil {
+				continue
+			}
+
+			if err := r.ensureUnmanagedFieldsAnnotation(ctx, logger, machineInfo); err != nil {
+				return fmt.Errorf("error updating unmanaged fields annotation on machine %s: %w", machineInfo.MachineRef.ObjectMeta.GetName(), err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// ensureUnmanagedFieldsAnnotation updates the unmanaged fields annotation on a single Machine, if required.
+func (r *ControlPlaneMachineSetReconciler) ensureUnmanagedFieldsAnnotation(ctx context.Context, logger logr.Logger, machineInfo machineproviders.MachineInfo) error {
+	machineRef := machineInfo.MachineRef
+	unmanagedFields := strings.Join(machineInfo.UnmanagedFields, ",")
+
+	if unmanagedFieldsAnnotationUpToDate(machineRef.ObjectMeta.GetAnnotations(), unmanagedFields) {
+		return nil
+	}
+
+	if err := r.patchMachineAnnotations(ctx, machineRef, func(annotations map[string]string) {
+		if unmanagedFields == "" {
+			delete(annotations, unmanagedFieldsAnnotation)
+		} else {
+			annotations[unmanagedFieldsAnnotation] = unmanagedFields
+		}
+	}); err != nil {
+		return err
+	}
+
+	logger.V(2).Info(updatedUnmanagedFields,
+		"machineNamespace", machineRef.ObjectMeta.GetNamespace(),
+		"machineName", machineRef.ObjectMeta.GetName(),
+		"unmanagedFields", unmanagedFields,
+	)
+
+	return nil
+}
+
+// patchMachineAnnotations patches the annotations of the Machine referenced by the machineRef.
+// The mutate function is passed a copy of the existing annotations of the Machine to modify.
+// It uses PartialObjectMetadata so that the annotations can be updated on any type, given the GVR and existing
+// ObjectMeta.
+func (r *ControlPlaneMachineSetReconciler) patchMachineAnnotations(ctx context.Context, machineRef *machineproviders.ObjectRef, mutate func(map[string]string)) error {
+	gvk, err := r.RESTMapper.KindFor(machineRef.GroupVersionResource)
+	if err != nil {
+		return fmt.Errorf("could not get GroupVersionKind for machine: %w", err)
+	}
+
+	machine := &metav1.PartialObjectMetadata{}
+	machine.SetGroupVersionKind(gvk)
+	machine.SetName(machineRef.ObjectMeta.GetName())
+	machine.SetNamespace(machineRef.ObjectMeta.GetNamespace())
+
+	annotations := map[string]string{}
+	for key, value := range machineRef.ObjectMeta.GetAnnotations() {
+		annotations[key] = value
+	}
+
+	machine.SetAnnotations(annotations)
+	patchBase := client.MergeFrom(machine.DeepCopy())
+
+	mutate(annotations)
+	machine.SetAnnotations(annotations)
+
+	if err := r.Patch(ctx, machine, patchBase); err != nil {
+		return fmt.Errorf("could not patch machine annotations: %w", err)
+	}
+
+	return nil
+}
+
+// unmanagedFieldsAnnotationUpToDate determines whether the unmanaged fields annotation within the annotations
+// already reflects the unmanaged fields. When there are no unmanaged fields, the annotation should not be present.
+func unmanagedFieldsAnnotationUpToDate(annotations map[string]string, unmanagedFields string) bool {
+	current, ok := annotations[unmanagedFieldsAnnotation]
+	if unmanagedFields == "" {
+		return !ok
+	}
+
+	return ok && current == unmanagedFields
+}
+
 // validateClusterState uses the machineInfos to validate that:
 // - All Nodes in the cluster claiming to be control plane nodes have a valid machine
+// - When any control plane machines exist, the selector matches at least 1 of them (otherwise the selector is
+//   likely misconfigured and every control plane machine would be replaced)
 // - At least 1 of the control plane machines is in the ready state (if there are no ready Machines then the cluster
 //   is likely misconfigured)
-func (r *ControlPlaneMachineSetReconciler) validateClusterState(ctx context.Context, logger logr.Logger, cpms *machinev1.ControlPlaneMachineSet, machineInfos map[int32][]machineproviders.MachineInfo) error {
+// - There are no more etcd members than control plane machines with a node, nor than control plane nodes (otherwise
+//   the machines and nodes do not reflect the actual control plane, and acting upon them could break etcd quorum)
+// - All Nodes backing control plane machines carry the topology labels expected from the failure domain of the
+//   Machine
+// - When instance verification is enabled, no running control plane machine has lost its cloud instance
+// When the cluster state is not valid, the ControlPlaneMachineSet is marked as degraded.
+// When enabled, departed control plane Nodes, left behind by removed Machines, are deleted before the Nodes are
+// validated.
+func (r *ControlPlaneMachineSetReconciler) validateClusterState(ctx context.Context, logger logr.Logger, cpms *machinev1.ControlPlaneMachineSet, machineInfos map[int32][]machineproviders.MachineInfo, membership etcdMembership) error {
+	unselectedMachines, err := r.unselectedMachineNames(ctx, machineInfos)
+	if err != nil {
+		return fmt.Errorf("failed to list control plane machines: %w", err)
+	}
+
+	if len(unselectedMachines) > 0 {
+		setDegradedCondition(cpms, reasonSelectorMatchesNoMachines, fmt.Sprintf("Found %d control plane machine(s) not matched by spec.selector: ensure spec.selector matches the labels of the existing control plane machines", len(unselectedMachines)))
+		logger.Error(errSelectorMatchesNoMachines, observedUnselectedMachines, "unselectedMachines", strings.Join(unselectedMachines, ","))
+
+		return nil
+	}
+
+	if unreadyMachines, anyReady := unreadyMachineNames(machineInfos); !anyReady {
+		setDegradedCondition(cpms, reasonNoReadyMachines, noReadyMachines)
+		logger.Error(errNoReadyControlPlaneMachines, noReadyMachines, "unreadyMachines", strings.Join(unreadyMachines, ","))
+
+		return nil
+	}
+
+	if missingInstances := missingInstanceMachineNames(machineInfos); r.InstanceVerificationInterval > 0 && len(missingInstances) > 0 {
+		setDegradedCondition(cpms, reasonMissingInstance, fmt.Sprintf("Found %d running machine(s) with missing instances: %s", len(missingInstances), strings.Join(missingInstances, ", ")))
+		logger.Error(errMissingInstances, observedMissingInstances, "missingInstances", strings.Join(missingInstances, ","))
+
+		return nil
+	}
+
+	nodeList := &corev1.NodeList{}
+	if err := r.List(ctx, nodeList, client.HasLabels{masterNodeRoleLabel}); err != nil {
+		return fmt.Errorf("failed to list control plane nodes: %w", err)
+	}
+
+	nodes := nodeList.Items
+
+	if r.OperatorConfig.DeleteDepartedNodes(r.DeleteDepartedNodes) && membership.known {
+		nodes, err = r.removeDepartedNodes(ctx, logger, nodes, machineInfos, membership.endpoints)
+		if err != nil {
+			return fmt.Errorf("failed to remove departed control plane nodes: %w", err)
+		}
+	}
+
+	if unmanagedNodes := unmanagedNodeNames(nodes, machineInfos); len(unmanagedNodes) > 0 {
+		setDegradedCondition(cpms, reasonUnmanagedNodes, fmt.Sprintf("Found %d unmanaged node(s)", len(unmanagedNodes)))
+
+		err := fmt.Errorf("%w, the following node(s) do not have associated machines: %s", errUnmanagedControlPlaneNodes, strings.Join(unmanagedNodes, ", "))
+		logger.Error(err, observedUnmanagedNodes, "unmanagedNodes", strings.Join(unmanagedNodes, ","))
+
+		return nil
+	}
+
+	if message, inconsistent := controlPlaneInconsistency(len(membership.endpoints), nodes, machineInfos); membership.known && inconsistent {
+		setDegradedCondition(cpms, reasonInconsistentControlPlane, message)
+		logger.Error(errInconsistentControlPlane, observedInconsistentControlPlane, "etcdMembers", len(membership.endpoints))
+
+		return nil
+	}
+
+	if mismatchedNodes, details := mismatchedTopologyNodes(nodes, machineInfos); len(mismatchedNodes) > 0 {
+		setDegradedCondition(cpms, reasonNodeTopologyMismatch, fmt.Sprintf("Found %d node(s) with topology labels not matching their machine: %s", len(mismatchedNodes), strings.Join(details, "; ")))
+
+		err := fmt.Errorf("%w: %s", errNodeTopologyMismatch, strings.Join(details, "; "))
+		logger.Error(err, observedNodeTopologyMismatch, "mismatchedNodes", strings.Join(mismatchedNodes, ","))
+	}
+
 	return nil
 }
 
+// unselectedMachineNames returns the sorted names of the Control Plane Machines, identified by their role label,
+// when none of the Control Plane Machines have been matched by the selector of the ControlPlaneMachineSet.
+// When the selector matches at least 1 Machine, or when there are no Control Plane Machines, no names are returned.
+func (r *ControlPlaneMachineSetReconciler) unselectedMachineNames(ctx context.Context, machineInfos map[int32][]machineproviders.MachineInfo) ([]string, error) {
+	for _, indexMachineInfos := range machineInfos {
+		for _, machineInfo := range indexMachineInfos {
+			if machineInfo.MachineRef != nil {
+				return nil, nil
+			}
+		}
+	}
+
+	machineList := &machinev1beta1.MachineList{}
+	if err := r.List(ctx, machineList, client.InNamespace(r.Namespace), client.MatchingLabels{machineRoleLabelName: machineMasterRoleLabelName}); err != nil {
+		return nil, fmt.Errorf("could not list machines: %w", err)
+	}
+
+	names := []string{}
+	for _, machine := range machineList.Items {
+		names = append(names, machine.GetName())
+	}
+
+	sort.Strings(names)
+
+	return names, nil
+}
+
+// setDegradedCondition marks the ControlPlaneMachineSet as degraded with the given reason and message.
+// As the operator will not take any action while degraded, the progressing condition is also updated to reflect this.
+func setDegradedCondition(cpms *machinev1.ControlPlaneMachineSet, reason, message string) {
+	meta.SetStatusCondition(&cpms.Status.Conditions, metav1.Condition{
+		Type:               conditionDegraded,
+		Status:             metav1.ConditionTrue,
+		Reason:             reason,
+		ObservedGeneration: cpms.GetGeneration(),
+		Message:            message,
+	})
+
+	meta.SetStatusCondition(&cpms.Status.Conditions, metav1.Condition{
+		Type:               conditionProgressing,
+		Status:             metav1.ConditionFalse,
+		Reason:             reasonOperatorDegraded,
+		ObservedGeneration: cpms.GetGeneration(),
+	})
+}
+
+// unreadyMachineNames returns the names of the Machines within the machineInfos that are not ready, in order
+// of index, and whether any of the Machines are ready.
+func unreadyMachineNames(machineInfos map[int32][]machineproviders.MachineInfo) ([]string, bool) {
+	unreadyMachines := []string{}
+	anyReady := false
+
+	for _, index := range sortedIndexes(machineInfos) {
+		for _, machineInfo := range machineInfos[index] {
+			if machineInfo.Ready {
+				anyReady = true
+				continue
+			}
+
+			if machineInfo.MachineRef != nil {
+				unreadyMachines = append(unreadyMachines, machineInfo.MachineRef.ObjectMeta.GetName())
+			}
+		}
+	}
+
+	return unreadyMachines, anyReady
+}
+
+// missingInstanceMachineNames returns the names of the Machines within the machineInfos that are running, but
+// where the cloud instance backing the Machine no longer exists.
+func missingInstanceMachineNames(machineInfos map[int32][]machineproviders.MachineInfo) []string {
+	missingInstances := []string{}
+
+	for _, index := range sortedIndexes(machineInfos) {
+		for _, machineInfo := range machineInfos[index] {
+			if machineInfo.InstanceMissing && machineInfo.MachineRef != nil {
+				missingInstances = append(missingInstances, machineInfo.MachineRef.ObjectMeta.GetName())
+			}
+		}
+	}
+
+	return missingInstances
+}
+
+// unmanagedNodeNames returns the names of the Control Plane Nodes that are not referenced by any of the
+// Machines within the machineInfos.
+func unmanagedNodeNames(nodes []corev1.Node, machineInfos map[int32][]machineproviders.MachineInfo) []string {
+	managedNodes := make(map[string]struct{})
+
+	for _, indexMachineInfos := range machineInfos {
+		for _, machineInfo := range indexMachineInfos {
+			if machineInfo.NodeRef != nil {
+				managedNodes[machineInfo.NodeRef.ObjectMeta.GetName()] = struct{}{}
+			}
+		}
+	}
+
+	unmanagedNodes := []string{}
+
+	for _, node := range nodes {
+		if _, ok := managedNodes[node.GetName()]; !ok {
+			unmanagedNodes = append(unmanagedNodes, node.GetName())
+		}
+	}
+
+	sort.Strings(unmanagedNodes)
+
+	return unmanagedNodes
+}
+
+// mismatchedTopologyNodes returns the names of the Control Plane Nodes whose topology labels do not match the
+// topology labels expected from the failure domain of their Machine, along with a description of each mismatch.
+// A missing topology label is treated as a mismatch.
+func mismatchedTopologyNodes(nodes []corev1.Node, machineInfos map[int32][]machineproviders.MachineInfo) ([]string, []string) {
+	nodesByName := make(map[string]corev1.Node, len(nodes))
+	for _, node := range nodes {
+		nodesByName[node.GetName()] = node
+	}
+
+	mismatchedNodes := []string{}
+	details := []string{}
+
+	for _, index := range sortedIndexes(machineInfos) {
+		for _, machineInfo := range machineInfos[index] {
+			if machineInfo.NodeRef == nil || len(machineInfo.NodeTopologyLabels) == 0 {
+				continue
+			}
+
+			node, ok := nodesByName[machineInfo.NodeRef.ObjectMeta.GetName()]
+			if !ok {
+				continue
+			}
+
+			if nodeDetails := nodeTopologyMismatches(node, machineInfo.NodeTopologyLabels); len(nodeDetails) > 0 {
+				mismatchedNodes = append(mismatchedNodes, node.GetName())
+				details = append(details, fmt.Sprintf("node %s has %s", node.GetName(), strings.Join(nodeDetails, ", ")))
+			}
+		}
+	}
+
+	return mismatchedNodes, details
+}
+
+// nodeTopologyMismatches describes each of the expected topology labels that the Node does not carry.
+func nodeTopologyMismatches(node corev1.Node, expectedLabels map[string]string) []string {
+	keys := []string{}
+	for key := range expectedLabels {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	mismatches := []string{}
+
+	for _, key := range keys {
+		value, ok := node.GetLabels()[key]
+
+		switch {
+		case !ok:
+			mismatches = append(mismatches, fmt.Sprintf("no %s label, expected %q", key, expectedLabels[key]))
+		case value != expectedLabels[key]:
+			mismatches = append(mismatches, fmt.Sprintf("%s=%q, expected %q", key, value, expectedLabels[key]))
+		}
+	}
+
+	return mismatches
+}
+
+// sortedIndexes returns the indexes of the machineInfos in ascending order.
+func sortedIndexes(machineInfos map[int32][]machineproviders.MachineInfo) []int32 {
+	indexes := []int32{}
+	for index := range machineInfos {
+		indexes = append(indexes, index)
+	}
+
+	sort.Slice(indexes, func(i, j int) bool {
+		return indexes[i] < indexes[j]
+	})
+
+	return indexes
+}
+
 // machineInfosByIndex groups MachineInfo entries by index inside a map of index to MachineInfo.
 // This allows the update strategies to process each index in turn.
 // It is expected to add an entry for each expected index (0-(replicas-1)) so that later logic of updates can process
@@ -275,5 +1018,5 @@ func machineInfosByIndex(cpms *machinev1.ControlPlaneMachineSet, machineInfos []
 // isControlPlaneMachineSetDegraded determines whether or not the ControlPlaneMachineSet
 // has a true, degraded condition.
 func isControlPlaneMachineSetDegraded(cpms *machinev1.ControlPlaneMachineSet) bool {
-	return false
+	return meta.IsStatusConditionTrue(cpms.Status.Conditions, conditionDegraded)
 }
@@ -20,6 +20,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"time"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
@@ -31,6 +32,7 @@ import (
 	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/test"
 	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/test/resourcebuilder"
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	ctrl "sigs.k8s.io/controller-runtime"
@@ -644,6 +646,140 @@ var _ = Describe("ensureOwnerRefrences", func() {
 	})
 })
 
+var _ = Describe("ensureUnmanagedFieldsAnnotations", func() {
+	var namespaceName string
+	var reconciler *ControlPlaneMachineSetReconciler
+	var logger test.TestLogger
+
+	var machines []*machinev1beta1.Machine
+	var machineInfoBuilders []resourcebuilder.MachineInfoBuilder
+	machineGVR := machinev1beta1.GroupVersion.WithResource("machines")
+
+	BeforeEach(func() {
+		By("Setting up a namespace for the test")
+		ns := resourcebuilder.Namespace().WithGenerateName("control-plane-machine-set-ensure-unmanaged-fields-").Build()
+		Expect(k8sClient.Create(ctx, ns)).To(Succeed())
+		namespaceName = ns.GetName()
+
+		reconciler = &ControlPlaneMachineSetReconciler{
+			Client:     k8sClient,
+			Scheme:     testScheme,
+			RESTMapper: testRESTMapper,
+			Namespace:  namespaceName,
+		}
+
+		logger = test.NewTestLogger()
+
+		By("Creating machines to publish unmanaged fields on")
+		machines = []*machinev1beta1.Machine{}
+		machineInfoBuilders = []resourcebuilder.MachineInfoBuilder{}
+		machineBuilder := resourcebuilder.Machine().WithNamespace(namespaceName).WithGenerateName("ensure-unmanaged-fields-test-")
+
+		for i := 0; i < 2; i++ {
+			machine := machineBuilder.Build()
+			Expect(k8sClient.Create(ctx, machine)).To(Succeed())
+
+			machines = append(machines, machine)
+			machineInfoBuilders = append(machineInfoBuilders, resourcebuilder.MachineInfo().
+				WithIndex(int32(i)).
+				WithMachineGVR(machineGVR).
+				WithMachineName(machine.GetName()).
+				WithMachineNamespace(namespaceName),
+			)
+		}
+	})
+
+	AfterEach(func() {
+		test.CleanupResources(Default, ctx, cfg, k8sClient, namespaceName,
+			&machinev1beta1.Machine{},
+		)
+	})
+
+	Context("when the machines have unmanaged fields", func() {
+		BeforeEach(func() {
+			machineInfos := map[int32][]machineproviders.MachineInfo{
+				0: {machineInfoBuilders[0].WithUnmanagedFields("apiVersion", "placement.tenancy").Build()},
+				1: {machineInfoBuilders[1].Build()},
+			}
+
+			Expect(reconciler.ensureUnmanagedFieldsAnnotations(ctx, logger.Logger(), machineInfos)).To(Succeed())
+		})
+
+		It("should publish the unmanaged fields on the machine", func() {
+			Eventually(komega.Object(machines[0])).Should(HaveField("ObjectMeta.Annotations", HaveKeyWithValue(unmanagedFieldsAnnotation, "apiVersion,placement.tenancy")))
+		})
+
+		It("should not add the annotation to machines without unmanaged fields", func() {
+			Consistently(komega.Object(machines[1])).ShouldNot(HaveField("ObjectMeta.Annotations", HaveKey(unmanagedFieldsAnnotation)))
+		})
+
+		It("should log that it has updated the annotation", func() {
+			Expect(logger.Entries()).To(ConsistOf(test.LogEntry{
+				KeysAndValues: []interface{}{"machineNamespace", namespaceName, "machineName", machines[0].GetName(), "unmanagedFields", "apiVersion,placement.tenancy"},
+				Level:         2,
+				Message:       "Updated unmanaged fields annotation on machine",
+			}))
+		})
+	})
+
+	Context("when the annotation is already up to date", func() {
+		BeforeEach(func() {
+			annotations := map[string]string{unmanagedFieldsAnnotation: "apiVersion"}
+
+			machines[0].SetAnnotations(annotations)
+			Expect(k8sClient.Update(ctx, machines[0])).To(Succeed())
+
+			machineInfos := map[int32][]machineproviders.MachineInfo{
+				0: {machineInfoBuilders[0].WithMachineAnnotations(annotations).WithUnmanagedFields("apiVersion").Build()},
+			}
+
+			Expect(reconciler.ensureUnmanagedFieldsAnnotations(ctx, logger.Logger(), machineInfos)).To(Succeed())
+		})
+
+		It("should keep the annotation on the machine", func() {
+			Consistently(komega.Object(machines[0])).Should(HaveField("ObjectMeta.Annotations", HaveKeyWithValue(unmanagedFieldsAnnotation, "apiVersion")))
+		})
+
+		It("should not log", func() {
+			Expect(logger.Entries()).To(BeEmpty())
+		})
+	})
+
+	Context("when the machine no longer has unmanaged fields", func() {
+		BeforeEach(func() {
+			annotations := map[string]string{
+				unmanagedFieldsAnnotation: "apiVersion",
+				"other-annotation":        "value",
+			}
+
+			machines[0].SetAnnotations(annotations)
+			Expect(k8sClient.Update(ctx, machines[0])).To(Succeed())
+
+			machineInfos := map[int32][]machineproviders.MachineInfo{
+				0: {machineInfoBuilders[0].WithMachineAnnotations(annotations).Build()},
+			}
+
+			Expect(reconciler.ensureUnmanagedFieldsAnnotations(ctx, logger.Logger(), machineInfos)).To(Succeed())
+		})
+
+		It("should remove the annotation from the machine", func() {
+			Eventually(komega.Object(machines[0])).ShouldNot(HaveField("ObjectMeta.Annotations", HaveKey(unmanagedFieldsAnnotation)))
+		})
+
+		It("should not remove other annotations from the machine", func() {
+			Eventually(komega.Object(machines[0])).Should(HaveField("ObjectMeta.Annotations", HaveKeyWithValue("other-annotation", "value")))
+		})
+
+		It("should log that it has updated the annotation", func() {
+			Expect(logger.Entries()).To(ConsistOf(test.LogEntry{
+				KeysAndValues: []interface{}{"machineNamespace", namespaceName, "machineName", machines[0].GetName(), "unmanagedFields", ""},
+				Level:         2,
+				Message:       "Updated unmanaged fields annotation on machine",
+			}))
+		})
+	})
+})
+
 var _ = Describe("machineInfosByIndex", func() {
 	i0m0 := resourcebuilder.MachineInfo().WithIndex(0).WithMachineName("machine-0-0").Build()
 	i0m1 := resourcebuilder.MachineInfo().WithIndex(0).WithMachineName("machine-0-1").Build()
@@ -747,6 +883,13 @@ var _ = Describe("validateClusterState", func() {
 		WithReady(false).
 		WithNeedsUpdate(false)
 
+	topologyLabels := func(zone string) map[string]string {
+		return map[string]string{
+			corev1.LabelTopologyRegion: "us-east-1",
+			corev1.LabelTopologyZone:   zone,
+		}
+	}
+
 	BeforeEach(func() {
 		By("Setting up a namespace for the test")
 		ns := resourcebuilder.Namespace().WithGenerateName("control-plane-machine-set-controller-").Build()
@@ -762,12 +905,15 @@ var _ = Describe("validateClusterState", func() {
 	})
 
 	type validateClusterTableInput struct {
-		cpms               *machinev1.ControlPlaneMachineSet
-		machineInfos       map[int32][]machineproviders.MachineInfo
-		nodes              []*corev1.Node
-		expectedError      error
-		expectedConditions []metav1.Condition
-		expectedLogs       []test.LogEntry
+		cpms                         *machinev1.ControlPlaneMachineSet
+		machineInfos                 map[int32][]machineproviders.MachineInfo
+		nodes                        []*corev1.Node
+		machines                     []*machinev1beta1.Machine
+		instanceVerificationInterval time.Duration
+		etcdMembers                  map[string]string
+		expectedError                error
+		expectedConditions           []metav1.Condition
+		expectedLogs                 []test.LogEntry
 	}
 
 	DescribeTable("should validate the cluster state", func(in validateClusterTableInput) {
@@ -777,12 +923,39 @@ var _ = Describe("validateClusterState", func() {
 			Expect(k8sClient.Create(ctx, node)).To(Succeed())
 		}
 
+		for _, machine := range in.machines {
+			machine.SetNamespace(namespaceName)
+			Expect(k8sClient.Create(ctx, machine)).To(Succeed())
+		}
+
+		if in.etcdMembers != nil {
+			ns := resourcebuilder.Namespace().WithName(etcdNamespace).Build()
+			if err := k8sClient.Create(ctx, ns); !apierrors.IsAlreadyExists(err) {
+				Expect(err).ToNot(HaveOccurred())
+			}
+
+			etcdEndpoints := &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: etcdEndpointsConfigMapName, Namespace: etcdNamespace},
+				Data:       in.etcdMembers,
+			}
+			Expect(k8sClient.Create(ctx, etcdEndpoints)).To(Succeed())
+
+			DeferCleanup(func() {
+				Expect(k8sClient.Delete(ctx, etcdEndpoints)).To(Succeed())
+			})
+		}
+
 		reconciler := &ControlPlaneMachineSetReconciler{
-			Client:    k8sClient,
-			Namespace: namespaceName,
+			Client:                       k8sClient,
+			APIReader:                    k8sClient,
+			Namespace:                    namespaceName,
+			InstanceVerificationInterval: in.instanceVerificationInterval,
 		}
 
-		err := reconciler.validateClusterState(ctx, logger.Logger(), in.cpms, in.machineInfos)
+		membership, err := reconciler.etcdMembership(ctx)
+		Expect(err).ToNot(HaveOccurred())
+
+		err = reconciler.validateClusterState(ctx, logger.Logger(), in.cpms, in.machineInfos, membership)
 
 		if in.expectedError != nil {
 			Expect(err).To(MatchError(in.expectedError))
@@ -791,9 +964,9 @@ var _ = Describe("validateClusterState", func() {
 		}
 
 		Expect(in.cpms.Status.Conditions).To(test.MatchConditions(in.expectedConditions))
-		Expect(in.expectedLogs).To(ConsistOf(in.expectedLogs))
+		Expect(logger.Entries()).To(ConsistOf(in.expectedLogs))
 	},
-		PEntry("with a valid cluster state", validateClusterTableInput{
+		Entry("with a valid cluster state", validateClusterTableInput{
 			cpms: cpmsBuilder.WithConditions([]metav1.Condition{
 				degradedConditionBuilder.WithStatus(metav1.ConditionFalse).Build(),
 				progressingConditionBuilder.WithStatus(metav1.ConditionFalse).Build(),
@@ -818,7 +991,7 @@ var _ = Describe("validateClusterState", func() {
 			},
 			expectedLogs: []test.LogEntry{},
 		}),
-		PEntry("with a valid cluster state and pre-existing conditions", validateClusterTableInput{
+		Entry("with a valid cluster state and pre-existing conditions", validateClusterTableInput{
 			cpms: cpmsBuilder.WithConditions([]metav1.Condition{
 				degradedConditionBuilder.WithStatus(metav1.ConditionTrue).WithReason(reasonMachinesAlreadyOwned).Build(),
 				progressingConditionBuilder.WithStatus(metav1.ConditionFalse).WithReason(reasonOperatorDegraded).Build(),
@@ -843,7 +1016,7 @@ var _ = Describe("validateClusterState", func() {
 			},
 			expectedLogs: []test.LogEntry{},
 		}),
-		PEntry("with no machines are ready", validateClusterTableInput{
+		Entry("with no machines are ready", validateClusterTableInput{
 			cpms: cpmsBuilder.WithConditions([]metav1.Condition{
 				degradedConditionBuilder.WithStatus(metav1.ConditionFalse).Build(),
 				progressingConditionBuilder.WithStatus(metav1.ConditionTrue).Build(),
@@ -876,7 +1049,71 @@ var _ = Describe("validateClusterState", func() {
 				},
 			},
 		}),
-		PEntry("with only 1 machine is ready", validateClusterTableInput{
+		Entry("with control plane machines, none of which are matched by the selector", validateClusterTableInput{
+			cpms: cpmsBuilder.WithConditions([]metav1.Condition{
+				degradedConditionBuilder.WithStatus(metav1.ConditionFalse).Build(),
+				progressingConditionBuilder.WithStatus(metav1.ConditionTrue).Build(),
+			}).Build(),
+			machineInfos: map[int32][]machineproviders.MachineInfo{
+				0: {},
+				1: {},
+				2: {},
+			},
+			machines: []*machinev1beta1.Machine{
+				resourcebuilder.Machine().AsMaster().WithName("master-0").Build(),
+				resourcebuilder.Machine().AsMaster().WithName("master-1").Build(),
+				resourcebuilder.Machine().AsMaster().WithName("master-2").Build(),
+				resourcebuilder.Machine().AsWorker().WithName("worker-0").Build(),
+			},
+			nodes: []*corev1.Node{
+				masterNodeBuilder.WithName("master-0").Build(),
+				masterNodeBuilder.WithName("master-1").Build(),
+				masterNodeBuilder.WithName("master-2").Build(),
+			},
+			expectedError: nil,
+			expectedConditions: []metav1.Condition{
+				degradedConditionBuilder.WithStatus(metav1.ConditionTrue).WithReason(reasonSelectorMatchesNoMachines).WithMessage("Found 3 control plane machine(s) not matched by spec.selector: ensure spec.selector matches the labels of the existing control plane machines").Build(),
+				progressingConditionBuilder.WithStatus(metav1.ConditionFalse).WithReason(reasonOperatorDegraded).Build(),
+			},
+			expectedLogs: []test.LogEntry{
+				{
+					Error: errors.New("selector does not match any control plane machines"),
+					KeysAndValues: []interface{}{
+						"unselectedMachines", "master-0,master-1,master-2",
+					},
+					Message: "Observed control plane machines not matched by the selector",
+				},
+			},
+		}),
+		Entry("with no control plane machines", validateClusterTableInput{
+			cpms: cpmsBuilder.WithConditions([]metav1.Condition{
+				degradedConditionBuilder.WithStatus(metav1.ConditionFalse).Build(),
+				progressingConditionBuilder.WithStatus(metav1.ConditionTrue).Build(),
+			}).Build(),
+			machineInfos: map[int32][]machineproviders.MachineInfo{
+				0: {},
+				1: {},
+				2: {},
+			},
+			machines: []*machinev1beta1.Machine{
+				resourcebuilder.Machine().AsWorker().WithName("worker-0").Build(),
+			},
+			expectedError: nil,
+			expectedConditions: []metav1.Condition{
+				degradedConditionBuilder.WithStatus(metav1.ConditionTrue).WithReason(reasonNoReadyMachines).WithMessage("No ready control plane machines found").Build(),
+				progressingConditionBuilder.WithStatus(metav1.ConditionFalse).WithReason(reasonOperatorDegraded).Build(),
+			},
+			expectedLogs: []test.LogEntry{
+				{
+					Error: errors.New("no ready control plane machines"),
+					KeysAndValues: []interface{}{
+						"unreadyMachines", "",
+					},
+					Message: "No ready control plane machines found",
+				},
+			},
+		}),
+		Entry("with only 1 machine is ready", validateClusterTableInput{
 			cpms: cpmsBuilder.WithConditions([]metav1.Condition{
 				degradedConditionBuilder.WithStatus(metav1.ConditionFalse).Build(),
 				progressingConditionBuilder.WithStatus(metav1.ConditionTrue).Build(),
@@ -901,7 +1138,7 @@ var _ = Describe("validateClusterState", func() {
 			},
 			expectedLogs: []test.LogEntry{
 				{
-					Error: errors.New("found unmanaged control plane nodes, the following node(s) do not have associated machines: master-0, master-2"),
+					Error: fmt.Errorf("%w, the following node(s) do not have associated machines: master-0, master-2", errUnmanagedControlPlaneNodes),
 					KeysAndValues: []interface{}{
 						"unmanagedNodes", "master-0,master-2",
 					},
@@ -909,7 +1146,7 @@ var _ = Describe("validateClusterState", func() {
 				},
 			},
 		}),
-		PEntry("with an additional unowned master node", validateClusterTableInput{
+		Entry("with an additional unowned master node", validateClusterTableInput{
 			cpms: cpmsBuilder.WithConditions([]metav1.Condition{
 				degradedConditionBuilder.WithStatus(metav1.ConditionFalse).Build(),
 				progressingConditionBuilder.WithStatus(metav1.ConditionTrue).Build(),
@@ -935,7 +1172,7 @@ var _ = Describe("validateClusterState", func() {
 			},
 			expectedLogs: []test.LogEntry{
 				{
-					Error: errors.New("found unmanaged control plane nodes, the following node(s) do not have associated machines: master-3"),
+					Error: fmt.Errorf("%w, the following node(s) do not have associated machines: master-3", errUnmanagedControlPlaneNodes),
 					KeysAndValues: []interface{}{
 						"unmanagedNodes", "master-3",
 					},
@@ -943,6 +1180,265 @@ var _ = Describe("validateClusterState", func() {
 				},
 			},
 		}),
+		Entry("with nodes carrying the topology labels expected from their machines", validateClusterTableInput{
+			cpms: cpmsBuilder.WithConditions([]metav1.Condition{
+				degradedConditionBuilder.WithStatus(metav1.ConditionFalse).Build(),
+				progressingConditionBuilder.WithStatus(metav1.ConditionFalse).Build(),
+			}).Build(),
+			machineInfos: map[int32][]machineproviders.MachineInfo{
+				0: {healthyMachineBuilder.WithIndex(0).WithMachineName("machine-0").WithNodeName("master-0").WithNodeTopologyLabels(topologyLabels("us-east-1a")).Build()},
+				1: {healthyMachineBuilder.WithIndex(1).WithMachineName("machine-1").WithNodeName("master-1").WithNodeTopologyLabels(topologyLabels("us-east-1b")).Build()},
+				2: {healthyMachineBuilder.WithIndex(2).WithMachineName("machine-2").WithNodeName("master-2").WithNodeTopologyLabels(topologyLabels("us-east-1c")).Build()},
+			},
+			nodes: []*corev1.Node{
+				masterNodeBuilder.WithName("master-0").WithLabels(topologyLabels("us-east-1a")).AsMaster().Build(),
+				masterNodeBuilder.WithName("master-1").WithLabels(topologyLabels("us-east-1b")).AsMaster().Build(),
+				masterNodeBuilder.WithName("master-2").WithLabels(topologyLabels("us-east-1c")).AsMaster().Build(),
+			},
+			expectedError: nil,
+			expectedConditions: []metav1.Condition{
+				degradedConditionBuilder.WithStatus(metav1.ConditionFalse).Build(),
+				progressingConditionBuilder.WithStatus(metav1.ConditionFalse).Build(),
+			},
+			expectedLogs: []test.LogEntry{},
+		}),
+		Entry("with a node with a zone label not matching its machine", validateClusterTableInput{
+			cpms: cpmsBuilder.WithConditions([]metav1.Condition{
+				degradedConditionBuilder.WithStatus(metav1.ConditionFalse).Build(),
+				progressingConditionBuilder.WithStatus(metav1.ConditionFalse).Build(),
+			}).Build(),
+			machineInfos: map[int32][]machineproviders.MachineInfo{
+				0: {healthyMachineBuilder.WithIndex(0).WithMachineName("machine-0").WithNodeName("master-0").WithNodeTopologyLabels(topologyLabels("us-east-1a")).Build()},
+				1: {healthyMachineBuilder.WithIndex(1).WithMachineName("machine-1").WithNodeName("master-1").WithNodeTopologyLabels(topologyLabels("us-east-1b")).Build()},
+				2: {healthyMachineBuilder.WithIndex(2).WithMachineName("machine-2").WithNodeName("master-2").WithNodeTopologyLabels(topologyLabels("us-east-1c")).Build()},
+			},
+			nodes: []*corev1.Node{
+				masterNodeBuilder.WithName("master-0").WithLabels(topologyLabels("us-east-1a")).AsMaster().Build(),
+				masterNodeBuilder.WithName("master-1").WithLabels(topologyLabels("us-east-1c")).AsMaster().Build(),
+				masterNodeBuilder.WithName("master-2").WithLabels(topologyLabels("us-east-1c")).AsMaster().Build(),
+			},
+			expectedError: nil,
+			expectedConditions: []metav1.Condition{
+				degradedConditionBuilder.WithStatus(metav1.ConditionTrue).WithReason(reasonNodeTopologyMismatch).
+					WithMessage(`Found 1 node(s) with topology labels not matching their machine: node master-1 has topology.kubernetes.io/zone="us-east-1c", expected "us-east-1b"`).Build(),
+				progressingConditionBuilder.WithStatus(metav1.ConditionFalse).WithReason(reasonOperatorDegraded).Build(),
+			},
+			expectedLogs: []test.LogEntry{
+				{
+					Error: fmt.Errorf("%w: %s", errNodeTopologyMismatch, `node master-1 has topology.kubernetes.io/zone="us-east-1c", expected "us-east-1b"`),
+					KeysAndValues: []interface{}{
+						"mismatchedNodes", "master-1",
+					},
+					Message: "Observed control plane nodes with topology labels not matching their machine",
+				},
+			},
+		}),
+		Entry("with nodes missing the topology labels expected from their machines", validateClusterTableInput{
+			cpms: cpmsBuilder.WithConditions([]metav1.Condition{
+				degradedConditionBuilder.WithStatus(metav1.ConditionFalse).Build(),
+				progressingConditionBuilder.WithStatus(metav1.ConditionFalse).Build(),
+			}).Build(),
+			machineInfos: map[int32][]machineproviders.MachineInfo{
+				0: {healthyMachineBuilder.WithIndex(0).WithMachineName("machine-0").WithNodeName("master-0").WithNodeTopologyLabels(topologyLabels("us-east-1a")).Build()},
+				1: {healthyMachineBuilder.WithIndex(1).WithMachineName("machine-1").WithNodeName("master-1").WithNodeTopologyLabels(topologyLabels("us-east-1b")).Build()},
+				2: {healthyMachineBuilder.WithIndex(2).WithMachineName("machine-2").WithNodeName("master-2").WithNodeTopologyLabels(topologyLabels("us-east-1c")).Build()},
+			},
+			nodes: []*corev1.Node{
+				masterNodeBuilder.WithName("master-0").Build(),
+				masterNodeBuilder.WithName("master-1").WithLabels(topologyLabels("us-east-1b")).AsMaster().Build(),
+				masterNodeBuilder.WithName("master-2").WithLabel(corev1.LabelTopologyRegion, "us-east-1").Build(),
+			},
+			expectedError: nil,
+			expectedConditions: []metav1.Condition{
+				degradedConditionBuilder.WithStatus(metav1.ConditionTrue).WithReason(reasonNodeTopologyMismatch).
+					WithMessage(`Found 2 node(s) with topology labels not matching their machine: ` +
+						`node master-0 has no topology.kubernetes.io/region label, expected "us-east-1", no topology.kubernetes.io/zone label, expected "us-east-1a"; ` +
+						`node master-2 has no topology.kubernetes.io/zone label, expected "us-east-1c"`).Build(),
+				progressingConditionBuilder.WithStatus(metav1.ConditionFalse).WithReason(reasonOperatorDegraded).Build(),
+			},
+			expectedLogs: []test.LogEntry{
+				{
+					Error: fmt.Errorf("%w: %s", errNodeTopologyMismatch,
+						`node master-0 has no topology.kubernetes.io/region label, expected "us-east-1", no topology.kubernetes.io/zone label, expected "us-east-1a"; `+
+							`node master-2 has no topology.kubernetes.io/zone label, expected "us-east-1c"`),
+					KeysAndValues: []interface{}{
+						"mismatchedNodes", "master-0,master-2",
+					},
+					Message: "Observed control plane nodes with topology labels not matching their machine",
+				},
+			},
+		}),
+		Entry("with a running machine whose instance is missing and instance verification enabled", validateClusterTableInput{
+			cpms: cpmsBuilder.WithConditions([]metav1.Condition{
+				degradedConditionBuilder.WithStatus(metav1.ConditionFalse).Build(),
+				progressingConditionBuilder.WithStatus(metav1.ConditionFalse).Build(),
+			}).Build(),
+			machineInfos: map[int32][]machineproviders.MachineInfo{
+				0: {healthyMachineBuilder.WithIndex(0).WithMachineName("machine-0").WithNodeName("master-0").Build()},
+				1: {healthyMachineBuilder.WithIndex(1).WithMachineName("machine-1").WithNodeName("master-1").WithInstanceMissing(true).Build()},
+				2: {healthyMachineBuilder.WithIndex(2).WithMachineName("machine-2").WithNodeName("master-2").Build()},
+			},
+			nodes: []*corev1.Node{
+				masterNodeBuilder.WithName("master-0").Build(),
+				masterNodeBuilder.WithName("master-1").Build(),
+				masterNodeBuilder.WithName("master-2").Build(),
+			},
+			instanceVerificationInterval: 10 * time.Minute,
+			expectedError:                nil,
+			expectedConditions: []metav1.Condition{
+				degradedConditionBuilder.WithStatus(metav1.ConditionTrue).WithReason(reasonMissingInstance).
+					WithMessage("Found 1 running machine(s) with missing instances: machine-1").Build(),
+				progressingConditionBuilder.WithStatus(metav1.ConditionFalse).WithReason(reasonOperatorDegraded).Build(),
+			},
+			expectedLogs: []test.LogEntry{
+				{
+					Error: errMissingInstances,
+					KeysAndValues: []interface{}{
+						"missingInstances", "machine-1",
+					},
+					Message: "Observed control plane machines with missing instances",
+				},
+			},
+		}),
+		Entry("with a running machine whose instance is missing and instance verification disabled", validateClusterTableInput{
+			cpms: cpmsBuilder.WithConditions([]metav1.Condition{
+				degradedConditionBuilder.WithStatus(metav1.ConditionFalse).Build(),
+				progressingConditionBuilder.WithStatus(metav1.ConditionFalse).Build(),
+			}).Build(),
+			machineInfos: map[int32][]machineproviders.MachineInfo{
+				0: {healthyMachineBuilder.WithIndex(0).WithMachineName("machine-0").WithNodeName("master-0").Build()},
+				1: {healthyMachineBuilder.WithIndex(1).WithMachineName("machine-1").WithNodeName("master-1").WithInstanceMissing(true).Build()},
+				2: {healthyMachineBuilder.WithIndex(2).WithMachineName("machine-2").WithNodeName("master-2").Build()},
+			},
+			nodes: []*corev1.Node{
+				masterNodeBuilder.WithName("master-0").Build(),
+				masterNodeBuilder.WithName("master-1").Build(),
+				masterNodeBuilder.WithName("master-2").Build(),
+			},
+			expectedError: nil,
+			expectedConditions: []metav1.Condition{
+				degradedConditionBuilder.WithStatus(metav1.ConditionFalse).Build(),
+				progressingConditionBuilder.WithStatus(metav1.ConditionFalse).Build(),
+			},
+			expectedLogs: []test.LogEntry{},
+		}),
+		Entry("with an etcd member for each control plane machine and node", validateClusterTableInput{
+			cpms: cpmsBuilder.WithConditions([]metav1.Condition{
+				degradedConditionBuilder.WithStatus(metav1.ConditionFalse).Build(),
+				progressingConditionBuilder.WithStatus(metav1.ConditionFalse).Build(),
+			}).Build(),
+			machineInfos: map[int32][]machineproviders.MachineInfo{
+				0: {healthyMachineBuilder.WithIndex(0).WithMachineName("machine-0").WithNodeName("master-0").Build()},
+				1: {healthyMachineBuilder.WithIndex(1).WithMachineName("machine-1").WithNodeName("master-1").Build()},
+				2: {healthyMachineBuilder.WithIndex(2).WithMachineName("machine-2").WithNodeName("master-2").Build()},
+			},
+			nodes: []*corev1.Node{
+				masterNodeBuilder.WithName("master-0").Build(),
+				masterNodeBuilder.WithName("master-1").Build(),
+				masterNodeBuilder.WithName("master-2").Build(),
+			},
+			etcdMembers: map[string]string{
+				"13c2a8d4c1e0d3a1": "10.0.0.10",
+				"2f6b8e9d0a1c4b52": "10.0.0.11",
+				"8a1d3c5e7f9b0d24": "10.0.0.12",
+			},
+			expectedError: nil,
+			expectedConditions: []metav1.Condition{
+				degradedConditionBuilder.WithStatus(metav1.ConditionFalse).Build(),
+				progressingConditionBuilder.WithStatus(metav1.ConditionFalse).Build(),
+			},
+			expectedLogs: []test.LogEntry{},
+		}),
+		Entry("with more etcd members than control plane machines", validateClusterTableInput{
+			cpms: cpmsBuilder.WithConditions([]metav1.Condition{
+				degradedConditionBuilder.WithStatus(metav1.ConditionFalse).Build(),
+				progressingConditionBuilder.WithStatus(metav1.ConditionFalse).Build(),
+			}).Build(),
+			machineInfos: map[int32][]machineproviders.MachineInfo{
+				0: {healthyMachineBuilder.WithIndex(0).WithMachineName("machine-0").WithNodeName("master-0").Build()},
+				1: {healthyMachineBuilder.WithIndex(1).WithMachineName("machine-1").WithNodeName("master-1").Build()},
+				2: {healthyMachineBuilder.WithIndex(2).WithMachineName("machine-2").WithNodeName("master-2").Build()},
+			},
+			nodes: []*corev1.Node{
+				masterNodeBuilder.WithName("master-0").Build(),
+				masterNodeBuilder.WithName("master-1").Build(),
+				masterNodeBuilder.WithName("master-2").Build(),
+			},
+			etcdMembers: map[string]string{
+				"13c2a8d4c1e0d3a1": "10.0.0.10",
+				"2f6b8e9d0a1c4b52": "10.0.0.11",
+				"8a1d3c5e7f9b0d24": "10.0.0.12",
+				"c4e6a8b0d2f41635": "10.0.0.13",
+			},
+			expectedError: nil,
+			expectedConditions: []metav1.Condition{
+				degradedConditionBuilder.WithStatus(metav1.ConditionTrue).WithReason(reasonInconsistentControlPlane).
+					WithMessage("Found 4 etcd member(s), but 3 control plane machine(s) with a node and 3 control plane node(s)").Build(),
+				progressingConditionBuilder.WithStatus(metav1.ConditionFalse).WithReason(reasonOperatorDegraded).Build(),
+			},
+			expectedLogs: []test.LogEntry{
+				{
+					Error: errInconsistentControlPlane,
+					KeysAndValues: []interface{}{
+						"etcdMembers", 4,
+					},
+					Message: "Observed more etcd members than control plane machines and nodes",
+				},
+			},
+		}),
+	)
+})
+
+var _ = Describe("requeueForInstanceVerification", func() {
+	type requeueTableInput struct {
+		interval       time.Duration
+		result         ctrl.Result
+		expectedResult ctrl.Result
+	}
+
+	DescribeTable("should requeue within the instance verification interval", func(in requeueTableInput) {
+		reconciler := &ControlPlaneMachineSetReconciler{
+			InstanceVerificationInterval: in.interval,
+		}
+
+		Expect(reconciler.requeueForInstanceVerification(in.result)).To(Equal(in.expectedResult))
+	},
+		Entry("with instance verification disabled", requeueTableInput{
+			result:         ctrl.Result{},
+			expectedResult: ctrl.Result{},
+		}),
+		Entry("with instance verification enabled", requeueTableInput{
+			interval:       10 * time.Minute,
+			result:         ctrl.Result{},
+			expectedResult: ctrl.Result{RequeueAfter: 10 * time.Minute},
+		}),
+		Entry("with an existing shorter requeue", requeueTableInput{
+			interval:       10 * time.Minute,
+			result:         ctrl.Result{RequeueAfter: time.Minute},
+			expectedResult: ctrl.Result{RequeueAfter: time.Minute},
+		}),
+		Entry("with an existing longer requeue", requeueTableInput{
+			interval:       10 * time.Minute,
+			result:         ctrl.Result{RequeueAfter: time.Hour},
+			expectedResult: ctrl.Result{RequeueAfter: 10 * time.Minute},
+		}),
+		Entry("with an immediate requeue", requeueTableInput{
+			interval:       10 * time.Minute,
+			result:         ctrl.Result{Requeue: true},
+			expectedResult: ctrl.Result{Requeue: true},
+		}),
+	)
+})
+
+var _ = Describe("mergeResults", func() {
+	DescribeTable("should requeue as soon as either result requires", func(a, b, expected ctrl.Result) {
+		Expect(mergeResults(a, b)).To(Equal(expected))
+		Expect(mergeResults(b, a)).To(Equal(expected))
+	},
+		Entry("with two empty results", ctrl.Result{}, ctrl.Result{}, ctrl.Result{}),
+		Entry("with a single delayed requeue", ctrl.Result{RequeueAfter: time.Minute}, ctrl.Result{}, ctrl.Result{RequeueAfter: time.Minute}),
+		Entry("with two delayed requeues", ctrl.Result{RequeueAfter: time.Minute}, ctrl.Result{RequeueAfter: time.Hour}, ctrl.Result{RequeueAfter: time.Minute}),
+		Entry("with an immediate requeue", ctrl.Result{Requeue: true}, ctrl.Result{RequeueAfter: time.Hour}, ctrl.Result{Requeue: true, RequeueAfter: time.Hour}),
 	)
 })
 
@@ -953,15 +1449,15 @@ var _ = Describe("isControlPlaneMachineSetDegraded", func() {
 	DescribeTable("should determine if the ControlPlaneMachineSet is degraded", func(cpms *machinev1.ControlPlaneMachineSet, expectDegraded bool) {
 		Expect(isControlPlaneMachineSetDegraded(cpms)).To(Equal(expectDegraded), "Degraded state of ControlPlaneMachineSet was not as expected")
 	},
-		PEntry("with a CPMS without a degraded condition",
+		Entry("with a CPMS without a degraded condition",
 			cpmsBuilder.WithConditions([]metav1.Condition{}).Build(),
 			false,
 		),
-		PEntry("with a CPMS with a degraded condition with status false",
+		Entry("with a CPMS with a degraded condition with status false",
 			cpmsBuilder.WithConditions([]metav1.Condition{degradedConditionBuilder.WithStatus(metav1.ConditionFalse).Build()}).Build(),
 			false,
 		),
-		PEntry("with a CPMS with a degraded condition with status true",
+		Entry("with a CPMS with a degraded condition with status true",
 			cpmsBuilder.WithConditions([]metav1.Condition{degradedConditionBuilder.WithStatus(metav1.ConditionTrue).Build()}).Build(),
 			true,
 		),
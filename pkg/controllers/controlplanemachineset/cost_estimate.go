@@ -0,0 +1,195 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controlplanemachineset
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	machinev1 "github.com/openshift/api/machine/v1"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/yaml"
+)
+
+func init() {
+	registerInformationalCondition(conditionRolloutCostEstimate)
+}
+
+// hoursPerMonth is the number of hours used to convert hourly prices into monthly prices.
+// This matches the convention used by the major cloud providers when quoting monthly prices.
+const hoursPerMonth = 730
+
+var (
+	// errMissingCurrency is used to inform users that the price catalog does not specify the currency of its prices.
+	errMissingCurrency = errors.New("price catalog must specify a currency")
+
+	// errNegativePrice is used to inform users that the price catalog contains a negative price.
+	errNegativePrice = errors.New("price catalog must not contain negative prices")
+)
+
+// PriceCatalog provides the prices of cloud instance types so that the change in the cost of the Control Plane, due
+// to a pending rollout, can be estimated.
+type PriceCatalog interface {
+	// HourlyPrice returns the hourly price of the instance type, and whether the instance type is known to the
+	// catalog.
+	HourlyPrice(instanceType string) (float64, bool)
+
+	// Currency returns the currency in which the prices are given, for example, USD.
+	Currency() string
+}
+
+// staticPriceCatalog is a PriceCatalog with a fixed set of prices, loaded from a file.
+type staticPriceCatalog struct {
+	// CurrencyCode is the currency in which the prices are given.
+	CurrencyCode string `json:"currency"`
+
+	// HourlyPrices maps each instance type to its hourly price.
+	HourlyPrices map[string]float64 `json:"hourlyPrices"`
+}
+
+// LoadPriceCatalog loads a PriceCatalog from the YAML or JSON file at the path given.
+// The file specifies the currency and the hourly price of each instance type, eg:
+//
+//	currency: USD
+//	hourlyPrices:
+//	  m6i.xlarge: 0.192
+//	  m6i.2xlarge: 0.384
+func LoadPriceCatalog(path string) (PriceCatalog, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read price catalog: %w", err)
+	}
+
+	catalog := &staticPriceCatalog{}
+	if err := yaml.UnmarshalStrict(data, catalog); err != nil {
+		return nil, fmt.Errorf("could not parse price catalog: %w", err)
+	}
+
+	if catalog.CurrencyCode == "" {
+		return nil, errMissingCurrency
+	}
+
+	for instanceType, price := range catalog.HourlyPrices {
+		if price < 0 {
+			return nil, fmt.Errorf("%w: %s", errNegativePrice, instanceType)
+		}
+	}
+
+	return catalog, nil
+}
+
+// HourlyPrice returns the hourly price of the instance type, and whether the instance type is known to the catalog.
+func (c *staticPriceCatalog) HourlyPrice(instanceType string) (float64, bool) {
+	price, ok := c.HourlyPrices[instanceType]
+	return price, ok
+}
+
+// Currency returns the currency in which the prices are given.
+func (c *staticPriceCatalog) Currency() string {
+	return c.CurrencyCode
+}
+
+// setRolloutCostEstimateCondition sets the rollout cost estimate condition to report the estimated change in the
+// monthly cost of the Control Plane once the Machines in need of an update, whose instance type differs from the
+// desired instance type, have been replaced.
+// When no price catalog is configured, or no pending replacement changes the instance type, the condition is
+// removed.
+func setRolloutCostEstimateCondition(cpms *machinev1.ControlPlaneMachineSet, machineInfos map[int32][]machineproviders.MachineInfo, catalog PriceCatalog) {
+	changes := instanceTypeChanges(machineInfos)
+	if catalog == nil || len(changes) == 0 {
+		meta.RemoveStatusCondition(&cpms.Status.Conditions, conditionRolloutCostEstimate)
+		return
+	}
+
+	var monthlyDelta float64
+
+	transitions := map[string]struct{}{}
+	unknown := map[string]struct{}{}
+
+	for _, change := range changes {
+		transitions[fmt.Sprintf("%s to %s", change.InstanceType, change.DesiredInstanceType)] = struct{}{}
+
+		currentPrice, currentKnown := catalog.HourlyPrice(change.InstanceType)
+		if !currentKnown {
+			unknown[change.InstanceType] = struct{}{}
+		}
+
+		desiredPrice, desiredKnown := catalog.HourlyPrice(change.DesiredInstanceType)
+		if !desiredKnown {
+			unknown[change.DesiredInstanceType] = struct{}{}
+		}
+
+		monthlyDelta += (desiredPrice - currentPrice) * hoursPerMonth
+	}
+
+	condition := metav1.Condition{
+		Type:               conditionRolloutCostEstimate,
+		Status:             metav1.ConditionTrue,
+		Reason:             reasonCostEstimated,
+		ObservedGeneration: cpms.GetGeneration(),
+		Message: fmt.Sprintf("Estimated monthly cost change of %+.2f %s for %d machine(s) changing instance type: %s",
+			monthlyDelta, catalog.Currency(), len(changes), strings.Join(sortedKeys(transitions), ", ")),
+	}
+
+	if len(unknown) > 0 {
+		condition.Status = metav1.ConditionUnknown
+		condition.Reason = reasonPriceUnknown
+		condition.Message = fmt.Sprintf("No price found for instance type(s): %s", strings.Join(sortedKeys(unknown), ", "))
+	}
+
+	meta.SetStatusCondition(&cpms.Status.Conditions, condition)
+}
+
+// instanceTypeChanges returns the MachineInfos of the Machines in need of an update whose instance type differs from
+// the desired instance type. Machines that are already being deleted are excluded, as their replacement is no longer
+// pending.
+func instanceTypeChanges(machineInfos map[int32][]machineproviders.MachineInfo) []machineproviders.MachineInfo {
+	changes := []machineproviders.MachineInfo{}
+
+	for _, idx := range sortedIndexes(machineInfos) {
+		for _, machineInfo := range machineInfos[idx] {
+			if machineInfo.MachineRef == nil || machineInfo.MachineRef.ObjectMeta.GetDeletionTimestamp() != nil || !machineInfo.NeedsUpdate {
+				continue
+			}
+
+			if machineInfo.InstanceType == "" || machineInfo.DesiredInstanceType == "" || machineInfo.InstanceType == machineInfo.DesiredInstanceType {
+				continue
+			}
+
+			changes = append(changes, machineInfo)
+		}
+	}
+
+	return changes
+}
+
+// sortedKeys returns the keys of the set in ascending order.
+func sortedKeys(set map[string]struct{}) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
@@ -0,0 +1,190 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controlplanemachineset
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	machinev1beta1 "github.com/openshift/api/machine/v1beta1"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/test"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/test/resourcebuilder"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("Rollout cost estimate", func() {
+	Context("LoadPriceCatalog", func() {
+		writeCatalog := func(content string) string {
+			dir, err := os.MkdirTemp("", "price-catalog-")
+			Expect(err).ToNot(HaveOccurred())
+
+			DeferCleanup(func() {
+				Expect(os.RemoveAll(dir)).To(Succeed())
+			})
+
+			path := filepath.Join(dir, "catalog.yaml")
+			Expect(os.WriteFile(path, []byte(content), 0600)).To(Succeed())
+
+			return path
+		}
+
+		It("should load the prices from the file", func() {
+			catalog, err := LoadPriceCatalog(writeCatalog("currency: USD\nhourlyPrices:\n  m6i.xlarge: 0.192\n"))
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(catalog.Currency()).To(Equal("USD"))
+
+			price, ok := catalog.HourlyPrice("m6i.xlarge")
+			Expect(ok).To(BeTrue())
+			Expect(price).To(Equal(0.192))
+
+			_, ok = catalog.HourlyPrice("m6i.2xlarge")
+			Expect(ok).To(BeFalse())
+		})
+
+		It("should require a currency", func() {
+			_, err := LoadPriceCatalog(writeCatalog("hourlyPrices:\n  m6i.xlarge: 0.192\n"))
+			Expect(err).To(MatchError(errMissingCurrency))
+		})
+
+		It("should reject negative prices", func() {
+			_, err := LoadPriceCatalog(writeCatalog("currency: USD\nhourlyPrices:\n  m6i.xlarge: -1\n"))
+			Expect(err).To(MatchError(errNegativePrice))
+		})
+
+		It("should reject unknown fields", func() {
+			_, err := LoadPriceCatalog(writeCatalog("currency: USD\nprices:\n  m6i.xlarge: 0.192\n"))
+			Expect(err).To(MatchError(ContainSubstring("could not parse price catalog")))
+		})
+
+		It("should return an error when the file does not exist", func() {
+			_, err := LoadPriceCatalog(filepath.Join(os.TempDir(), "does-not-exist", "catalog.yaml"))
+			Expect(err).To(MatchError(ContainSubstring("could not read price catalog")))
+		})
+	})
+
+	Context("setRolloutCostEstimateCondition", func() {
+		machineGVR := machinev1beta1.GroupVersion.WithResource("machines")
+
+		machineBuilder := resourcebuilder.MachineInfo().
+			WithMachineGVR(machineGVR).
+			WithReady(true).
+			WithInstanceType("m6i.xlarge").
+			WithDesiredInstanceType("m6i.xlarge")
+
+		outdatedBuilder := machineBuilder.WithNeedsUpdate(true).WithDesiredInstanceType("m6i.2xlarge")
+
+		catalog := &staticPriceCatalog{
+			CurrencyCode: "USD",
+			HourlyPrices: map[string]float64{
+				"m6i.xlarge":  0.192,
+				"m6i.2xlarge": 0.384,
+			},
+		}
+
+		type costEstimateTableInput struct {
+			machineInfos      map[int32][]machineproviders.MachineInfo
+			catalog           PriceCatalog
+			existingCondition *metav1.Condition
+			expectedCondition *metav1.Condition
+		}
+
+		DescribeTable("should estimate the cost of the pending rollout", func(in costEstimateTableInput) {
+			cpms := resourcebuilder.ControlPlaneMachineSet().WithGeneration(2).Build()
+			if in.existingCondition != nil {
+				cpms.Status.Conditions = []metav1.Condition{*in.existingCondition}
+			}
+
+			setRolloutCostEstimateCondition(cpms, in.machineInfos, in.catalog)
+
+			if in.expectedCondition == nil {
+				Expect(cpms.Status.Conditions).To(BeEmpty())
+			} else {
+				Expect(cpms.Status.Conditions).To(test.MatchConditions([]metav1.Condition{*in.expectedCondition}))
+			}
+		},
+			Entry("with a pending instance type change", costEstimateTableInput{
+				machineInfos: map[int32][]machineproviders.MachineInfo{
+					0: {outdatedBuilder.WithIndex(0).WithMachineName("machine-0").Build()},
+					1: {outdatedBuilder.WithIndex(1).WithMachineName("machine-1").Build()},
+					2: {machineBuilder.WithIndex(2).WithMachineName("machine-2").WithDesiredInstanceType("m6i.2xlarge").Build()},
+				},
+				catalog: catalog,
+				expectedCondition: &metav1.Condition{
+					Type:               conditionRolloutCostEstimate,
+					Status:             metav1.ConditionTrue,
+					Reason:             reasonCostEstimated,
+					ObservedGeneration: 2,
+					Message:            "Estimated monthly cost change of +280.32 USD for 2 machine(s) changing instance type: m6i.xlarge to m6i.2xlarge",
+				},
+			}),
+			Entry("with a pending instance type decrease", costEstimateTableInput{
+				machineInfos: map[int32][]machineproviders.MachineInfo{
+					0: {outdatedBuilder.WithIndex(0).WithMachineName("machine-0").WithInstanceType("m6i.2xlarge").WithDesiredInstanceType("m6i.xlarge").Build()},
+				},
+				catalog: catalog,
+				expectedCondition: &metav1.Condition{
+					Type:               conditionRolloutCostEstimate,
+					Status:             metav1.ConditionTrue,
+					Reason:             reasonCostEstimated,
+					ObservedGeneration: 2,
+					Message:            "Estimated monthly cost change of -140.16 USD for 1 machine(s) changing instance type: m6i.2xlarge to m6i.xlarge",
+				},
+			}),
+			Entry("with an instance type missing from the catalog", costEstimateTableInput{
+				machineInfos: map[int32][]machineproviders.MachineInfo{
+					0: {outdatedBuilder.WithIndex(0).WithMachineName("machine-0").WithDesiredInstanceType("c5.4xlarge").Build()},
+				},
+				catalog: catalog,
+				expectedCondition: &metav1.Condition{
+					Type:               conditionRolloutCostEstimate,
+					Status:             metav1.ConditionUnknown,
+					Reason:             reasonPriceUnknown,
+					ObservedGeneration: 2,
+					Message:            "No price found for instance type(s): c5.4xlarge",
+				},
+			}),
+			Entry("with a pending rollout that does not change the instance type", costEstimateTableInput{
+				machineInfos: map[int32][]machineproviders.MachineInfo{
+					0: {machineBuilder.WithIndex(0).WithMachineName("machine-0").WithNeedsUpdate(true).Build()},
+				},
+				catalog: catalog,
+			}),
+			Entry("with a Machine being deleted", costEstimateTableInput{
+				machineInfos: map[int32][]machineproviders.MachineInfo{
+					0: {outdatedBuilder.WithIndex(0).WithMachineName("machine-0").WithMachineDeletionTimestamp(metav1.Now()).Build()},
+				},
+				catalog: catalog,
+			}),
+			Entry("without a price catalog, removing an existing estimate", costEstimateTableInput{
+				machineInfos: map[int32][]machineproviders.MachineInfo{
+					0: {outdatedBuilder.WithIndex(0).WithMachineName("machine-0").Build()},
+				},
+				existingCondition: &metav1.Condition{
+					Type:    conditionRolloutCostEstimate,
+					Status:  metav1.ConditionTrue,
+					Reason:  reasonCostEstimated,
+					Message: "Estimated monthly cost change of +140.16 USD for 1 machine(s) changing instance type: m6i.xlarge to m6i.2xlarge",
+				},
+			}),
+		)
+	})
+})
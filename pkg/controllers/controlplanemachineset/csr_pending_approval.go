@@ -0,0 +1,265 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controlplanemachineset
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/go-logr/logr"
+	machinev1 "github.com/openshift/api/machine/v1"
+	machinev1beta1 "github.com/openshift/api/machine/v1beta1"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders"
+	certificatesv1 "k8s.io/api/certificates/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func init() {
+	registerInformationalCondition(conditionCSRPendingApproval)
+}
+
+const (
+	// nodeUserPrefix is the prefix of the user, and of the common name of the certificate, that a kubelet requests a
+	// certificate for. The remainder is the name of the Node.
+	nodeUserPrefix = "system:node:"
+
+	// certificateRequestPEMType is the type of the PEM block that holds the certificate request of a certificate
+	// signing request.
+	certificateRequestPEMType = "CERTIFICATE REQUEST"
+
+	// observedPendingCSRs is a log message used to inform the user that the Node of a Control Plane Machine is
+	// waiting for its certificate signing requests to be approved.
+	observedPendingCSRs = "Observed pending certificate signing requests for control plane machine"
+)
+
+// pendingCSRMachine describes a Control Plane Machine whose Node is waiting for certificate signing requests to be
+// approved.
+type pendingCSRMachine struct {
+	// machineName is the name of the Machine.
+	machineName string
+
+	// csrNames are the sorted names of the pending certificate signing requests for the Node of the Machine.
+	csrNames []string
+}
+
+// reconcileCSRPendingApproval reports, within the CSRPendingApproval condition, the Control Plane Machines that are
+// not ready because the kubelet of their Node is waiting for a certificate signing request to be approved. Without
+// an approved client certificate the Node cannot register, and without an approved serving certificate the Node
+// cannot be used, so the replacement would otherwise only be seen to not become ready.
+// A warning event is published whenever the reported Machines change, and the number of reported Machines is exposed
+// as a metric. When the APIReader is nil, pending certificate signing requests are not reported.
+func (r *ControlPlaneMachineSetReconciler) reconcileCSRPendingApproval(ctx context.Context, logger logr.Logger, cpms *machinev1.ControlPlaneMachineSet, machineInfos map[int32][]machineproviders.MachineInfo) error {
+	candidates := notReadyMachines(machineInfos)
+	if r.APIReader == nil || len(candidates) == 0 {
+		r.setCSRPendingApprovalCondition(cpms, nil)
+		return nil
+	}
+
+	csrList := &certificatesv1.CertificateSigningRequestList{}
+	if err := r.APIReader.List(ctx, csrList); err != nil {
+		return fmt.Errorf("failed to list certificate signing requests: %w", err)
+	}
+
+	pendingByNode := pendingNodeCSRs(csrList.Items)
+
+	pending := []pendingCSRMachine{}
+
+	for _, machineInfo := range candidates {
+		nodeNames, err := r.machineNodeNames(ctx, machineInfo)
+		if err != nil {
+			return err
+		}
+
+		csrNames := []string{}
+		for _, nodeName := range nodeNames {
+			csrNames = append(csrNames, pendingByNode[nodeName]...)
+		}
+
+		if len(csrNames) == 0 {
+			continue
+		}
+
+		sort.Strings(csrNames)
+
+		p := pendingCSRMachine{
+			machineName: machineInfo.MachineRef.ObjectMeta.GetName(),
+			csrNames:    csrNames,
+		}
+
+		logger.V(1).Info(observedPendingCSRs,
+			"index", machineInfo.Index,
+			"machineName", p.machineName,
+			"certificateSigningRequests", strings.Join(p.csrNames, ","),
+		)
+
+		pending = append(pending, p)
+	}
+
+	r.setCSRPendingApprovalCondition(cpms, pending)
+
+	return nil
+}
+
+// notReadyMachines returns the MachineInfos, in index order, of the Control Plane Machines that are not ready, and
+// are not pending deletion.
+func notReadyMachines(machineInfos map[int32][]machineproviders.MachineInfo) []machineproviders.MachineInfo {
+	notReady := []machineproviders.MachineInfo{}
+
+	for _, idx := range sortedIndexes(machineInfos) {
+		for _, machineInfo := range machineInfos[idx] {
+			if machineInfo.MachineRef == nil || machineInfo.Ready || machineInfo.MachineRef.ObjectMeta.GetDeletionTimestamp() != nil {
+				continue
+			}
+
+			notReady = append(notReady, machineInfo)
+		}
+	}
+
+	return notReady
+}
+
+// machineNodeNames returns the names that the Node of the Machine may be registered with. These are the name of the
+// Node linked to the Machine, if any, and the host names that the Machine reports within its addresses. Machines that
+// no longer exist have no names.
+func (r *ControlPlaneMachineSetReconciler) machineNodeNames(ctx context.Context, machineInfo machineproviders.MachineInfo) ([]string, error) {
+	names := []string{}
+
+	if machineInfo.NodeRef != nil {
+		names = append(names, machineInfo.NodeRef.ObjectMeta.GetName())
+	}
+
+	machineName := machineInfo.MachineRef.ObjectMeta.GetName()
+
+	machine := &machinev1beta1.Machine{}
+	if err := r.Get(ctx, client.ObjectKey{Namespace: machineInfo.MachineRef.ObjectMeta.GetNamespace(), Name: machineName}, machine); apierrors.IsNotFound(err) {
+		return names, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("error fetching machine %s: %w", machineName, err)
+	}
+
+	for _, address := range machine.Status.Addresses {
+		if address.Type == corev1.NodeHostName || address.Type == corev1.NodeInternalDNS {
+			names = append(names, address.Address)
+		}
+	}
+
+	return names, nil
+}
+
+// pendingNodeCSRs returns the names of the pending kubelet certificate signing requests, keyed by the name of the
+// Node that they were requested for. A certificate signing request is pending until it has been approved, denied or
+// has failed.
+// Kubelet serving certificates are requested by the Node itself, so are matched by the requesting user. Kubelet
+// client certificates are requested by the bootstrap user before the Node exists, so are matched by the common name
+// of the requested certificate.
+func pendingNodeCSRs(csrs []certificatesv1.CertificateSigningRequest) map[string][]string {
+	pending := map[string][]string{}
+
+	for _, csr := range csrs {
+		if !isCSRPending(csr) {
+			continue
+		}
+
+		var user string
+
+		switch csr.Spec.SignerName {
+		case certificatesv1.KubeletServingSignerName:
+			user = csr.Spec.Username
+		case certificatesv1.KubeAPIServerClientKubeletSignerName:
+			user = certificateRequestCommonName(csr.Spec.Request)
+		default:
+			continue
+		}
+
+		nodeName := strings.TrimPrefix(user, nodeUserPrefix)
+		if nodeName == "" || nodeName == user {
+			continue
+		}
+
+		pending[nodeName] = append(pending[nodeName], csr.GetName())
+	}
+
+	return pending
+}
+
+// isCSRPending determines whether the certificate signing request has yet to be approved, denied or failed.
+func isCSRPending(csr certificatesv1.CertificateSigningRequest) bool {
+	for _, condition := range csr.Status.Conditions {
+		switch condition.Type {
+		case certificatesv1.CertificateApproved, certificatesv1.CertificateDenied, certificatesv1.CertificateFailed:
+			return false
+		}
+	}
+
+	return true
+}
+
+// certificateRequestCommonName returns the common name of the PEM encoded certificate request given. An empty string
+// is returned when the request cannot be parsed.
+func certificateRequestCommonName(request []byte) string {
+	block, _ := pem.Decode(request)
+	if block == nil || block.Type != certificateRequestPEMType {
+		return ""
+	}
+
+	certificateRequest, err := x509.ParseCertificateRequest(block.Bytes)
+	if err != nil {
+		return ""
+	}
+
+	return certificateRequest.Subject.CommonName
+}
+
+// setCSRPendingApprovalCondition reports the Control Plane Machines whose Node is waiting for certificate signing
+// requests to be approved, and updates the metric of their number. A warning event is published whenever the
+// reported Machines change. The condition is removed when no Machine is waiting.
+func (r *ControlPlaneMachineSetReconciler) setCSRPendingApprovalCondition(cpms *machinev1.ControlPlaneMachineSet, pending []pendingCSRMachine) {
+	csrPendingApprovalMachines.Set(float64(len(pending)))
+
+	if len(pending) == 0 {
+		meta.RemoveStatusCondition(&cpms.Status.Conditions, conditionCSRPendingApproval)
+
+		return
+	}
+
+	summaries := []string{}
+	for _, p := range pending {
+		summaries = append(summaries, fmt.Sprintf("%s (%s)", p.machineName, strings.Join(p.csrNames, ", ")))
+	}
+
+	message := fmt.Sprintf("Waiting for certificate signing requests to be approved for %d machine(s): %s", len(pending), strings.Join(summaries, "; "))
+
+	if previous := meta.FindStatusCondition(cpms.Status.Conditions, conditionCSRPendingApproval); previous == nil || previous.Message != message {
+		r.publishEvent(cpms, corev1.EventTypeWarning, reasonCertificateSigningRequestsPending, message)
+	}
+
+	meta.SetStatusCondition(&cpms.Status.Conditions, metav1.Condition{
+		Type:               conditionCSRPendingApproval,
+		Status:             metav1.ConditionTrue,
+		Reason:             reasonCertificateSigningRequestsPending,
+		ObservedGeneration: cpms.GetGeneration(),
+		Message:            message,
+	})
+}
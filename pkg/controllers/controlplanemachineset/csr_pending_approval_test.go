@@ -0,0 +1,285 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controlplanemachineset
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	machinev1 "github.com/openshift/api/machine/v1"
+	machinev1beta1 "github.com/openshift/api/machine/v1beta1"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/test"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/test/resourcebuilder"
+	dto "github.com/prometheus/client_model/go"
+	certificatesv1 "k8s.io/api/certificates/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+var _ = Describe("CSR pending approval", func() {
+	certificateRequest := func(commonName string) []byte {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		Expect(err).ToNot(HaveOccurred())
+
+		der, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+			Subject: pkix.Name{CommonName: commonName, Organization: []string{"system:nodes"}},
+		}, key)
+		Expect(err).ToNot(HaveOccurred())
+
+		return pem.EncodeToMemory(&pem.Block{Type: certificateRequestPEMType, Bytes: der})
+	}
+
+	clientCSR := func(name, nodeName string) certificatesv1.CertificateSigningRequest {
+		return certificatesv1.CertificateSigningRequest{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec: certificatesv1.CertificateSigningRequestSpec{
+				Request:    certificateRequest(nodeUserPrefix + nodeName),
+				SignerName: certificatesv1.KubeAPIServerClientKubeletSignerName,
+				Username:   "system:serviceaccount:openshift-machine-config-operator:node-bootstrapper",
+				Usages:     []certificatesv1.KeyUsage{certificatesv1.UsageDigitalSignature, certificatesv1.UsageClientAuth},
+			},
+		}
+	}
+
+	servingCSR := func(name, nodeName string) certificatesv1.CertificateSigningRequest {
+		return certificatesv1.CertificateSigningRequest{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec: certificatesv1.CertificateSigningRequestSpec{
+				Request:    certificateRequest(nodeUserPrefix + nodeName),
+				SignerName: certificatesv1.KubeletServingSignerName,
+				Username:   nodeUserPrefix + nodeName,
+				Usages:     []certificatesv1.KeyUsage{certificatesv1.UsageDigitalSignature, certificatesv1.UsageServerAuth},
+			},
+		}
+	}
+
+	withCondition := func(csr certificatesv1.CertificateSigningRequest, conditionType certificatesv1.RequestConditionType) certificatesv1.CertificateSigningRequest {
+		csr.Status.Conditions = append(csr.Status.Conditions, certificatesv1.CertificateSigningRequestCondition{
+			Type:   conditionType,
+			Status: corev1.ConditionTrue,
+		})
+
+		return csr
+	}
+
+	gaugeValue := func() float64 {
+		metric := &dto.Metric{}
+		Expect(csrPendingApprovalMachines.Write(metric)).To(Succeed())
+
+		return metric.GetGauge().GetValue()
+	}
+
+	Context("pendingNodeCSRs", func() {
+		It("matches client certificate requests by their common name", func() {
+			Expect(pendingNodeCSRs([]certificatesv1.CertificateSigningRequest{
+				clientCSR("csr-client", "master-0"),
+			})).To(Equal(map[string][]string{"master-0": {"csr-client"}}))
+		})
+
+		It("matches serving certificate requests by their requesting user", func() {
+			Expect(pendingNodeCSRs([]certificatesv1.CertificateSigningRequest{
+				servingCSR("csr-serving", "master-0"),
+			})).To(Equal(map[string][]string{"master-0": {"csr-serving"}}))
+		})
+
+		It("ignores certificate requests that have been approved, denied or have failed", func() {
+			Expect(pendingNodeCSRs([]certificatesv1.CertificateSigningRequest{
+				withCondition(clientCSR("csr-approved", "master-0"), certificatesv1.CertificateApproved),
+				withCondition(clientCSR("csr-denied", "master-0"), certificatesv1.CertificateDenied),
+				withCondition(servingCSR("csr-failed", "master-0"), certificatesv1.CertificateFailed),
+			})).To(BeEmpty())
+		})
+
+		It("ignores certificate requests for other signers", func() {
+			csr := clientCSR("csr-other", "master-0")
+			csr.Spec.SignerName = certificatesv1.KubeAPIServerClientSignerName
+
+			Expect(pendingNodeCSRs([]certificatesv1.CertificateSigningRequest{csr})).To(BeEmpty())
+		})
+
+		It("ignores certificate requests that are not for a node", func() {
+			csr := servingCSR("csr-user", "master-0")
+			csr.Spec.Username = "system:admin"
+
+			invalid := clientCSR("csr-invalid", "master-0")
+			invalid.Spec.Request = []byte("not a certificate request")
+
+			Expect(pendingNodeCSRs([]certificatesv1.CertificateSigningRequest{csr, invalid})).To(BeEmpty())
+		})
+	})
+
+	Context("setCSRPendingApprovalCondition", func() {
+		var reconciler *ControlPlaneMachineSetReconciler
+		var recorder *record.FakeRecorder
+		var cpms *machinev1.ControlPlaneMachineSet
+
+		pending := []pendingCSRMachine{
+			{machineName: "master-3", csrNames: []string{"csr-a", "csr-b"}},
+		}
+
+		BeforeEach(func() {
+			recorder = record.NewFakeRecorder(10)
+			reconciler = &ControlPlaneMachineSetReconciler{Recorder: recorder}
+			cpms = resourcebuilder.ControlPlaneMachineSet().Build()
+
+			reconciler.setCSRPendingApprovalCondition(cpms, pending)
+		})
+
+		It("sets the condition", func() {
+			Expect(cpms.Status.Conditions).To(ContainElement(test.MatchCondition(metav1.Condition{
+				Type:    conditionCSRPendingApproval,
+				Status:  metav1.ConditionTrue,
+				Reason:  reasonCertificateSigningRequestsPending,
+				Message: "Waiting for certificate signing requests to be approved for 1 machine(s): master-3 (csr-a, csr-b)",
+			})))
+		})
+
+		It("sets the metric", func() {
+			Expect(gaugeValue()).To(Equal(float64(1)))
+		})
+
+		It("publishes a warning event", func() {
+			Expect(recorder.Events).To(Receive(Equal("Warning CertificateSigningRequestsPending Waiting for certificate signing requests to be approved for 1 machine(s): master-3 (csr-a, csr-b)")))
+		})
+
+		It("does not publish another event when the pending requests are unchanged", func() {
+			Expect(recorder.Events).To(Receive())
+
+			reconciler.setCSRPendingApprovalCondition(cpms, pending)
+
+			Expect(recorder.Events).ToNot(Receive())
+		})
+
+		It("removes the condition and resets the metric once no machine is waiting", func() {
+			reconciler.setCSRPendingApprovalCondition(cpms, nil)
+
+			Expect(meta.FindStatusCondition(cpms.Status.Conditions, conditionCSRPendingApproval)).To(BeNil())
+			Expect(gaugeValue()).To(BeZero())
+		})
+	})
+
+	Context("reconcileCSRPendingApproval", func() {
+		var namespaceName string
+		var reconciler *ControlPlaneMachineSetReconciler
+		var logger test.TestLogger
+		var cpms *machinev1.ControlPlaneMachineSet
+		var machine *machinev1beta1.Machine
+		var machineInfos map[int32][]machineproviders.MachineInfo
+
+		BeforeEach(func() {
+			By("Setting up a namespace for the test")
+			ns := resourcebuilder.Namespace().WithGenerateName("control-plane-machine-set-csr-").Build()
+			Expect(k8sClient.Create(ctx, ns)).To(Succeed())
+			namespaceName = ns.GetName()
+
+			reconciler = &ControlPlaneMachineSetReconciler{
+				Client:    k8sClient,
+				APIReader: k8sClient,
+				Scheme:    testScheme,
+				Recorder:  record.NewFakeRecorder(10),
+				Namespace: namespaceName,
+			}
+
+			logger = test.NewTestLogger()
+			cpms = resourcebuilder.ControlPlaneMachineSet().WithNamespace(namespaceName).Build()
+
+			By("Creating a replacement machine whose node has not joined")
+			machine = resourcebuilder.Machine().AsMaster().WithNamespace(namespaceName).WithGenerateName("csr-test-").Build()
+			Expect(k8sClient.Create(ctx, machine)).To(Succeed())
+
+			machine.Status.Addresses = []corev1.NodeAddress{
+				{Type: corev1.NodeInternalIP, Address: "10.0.1.10"},
+				{Type: corev1.NodeInternalDNS, Address: "ip-10-0-1-10.ec2.internal"},
+			}
+			Expect(k8sClient.Status().Update(ctx, machine)).To(Succeed())
+
+			machineInfos = map[int32][]machineproviders.MachineInfo{
+				0: {resourcebuilder.MachineInfo().
+					WithMachineGVR(machinev1beta1.GroupVersion.WithResource("machines")).
+					WithMachineName(machine.GetName()).
+					WithMachineNamespace(namespaceName).
+					WithReady(false).
+					Build(),
+				},
+			}
+		})
+
+		AfterEach(func() {
+			test.CleanupResources(Default, ctx, cfg, k8sClient, namespaceName,
+				&certificatesv1.CertificateSigningRequest{},
+				&machinev1beta1.Machine{},
+			)
+		})
+
+		It("reports the pending client certificate request of the machine", func() {
+			csr := clientCSR("", "ip-10-0-1-10.ec2.internal")
+			csr.SetGenerateName("csr-")
+			Expect(k8sClient.Create(ctx, &csr)).To(Succeed())
+
+			Expect(reconciler.reconcileCSRPendingApproval(ctx, logger.Logger(), cpms, machineInfos)).To(Succeed())
+
+			Expect(cpms.Status.Conditions).To(ContainElement(test.MatchCondition(metav1.Condition{
+				Type:    conditionCSRPendingApproval,
+				Status:  metav1.ConditionTrue,
+				Reason:  reasonCertificateSigningRequestsPending,
+				Message: "Waiting for certificate signing requests to be approved for 1 machine(s): " + machine.GetName() + " (" + csr.GetName() + ")",
+			})))
+
+			Expect(logger.Entries()).To(ContainElement(test.LogEntry{
+				Level: 1,
+				KeysAndValues: []interface{}{
+					"index", int32(0),
+					"machineName", machine.GetName(),
+					"certificateSigningRequests", csr.GetName(),
+				},
+				Message: observedPendingCSRs,
+			}))
+		})
+
+		It("does not report pending certificate requests for other nodes", func() {
+			csr := clientCSR("", "ip-10-0-2-20.ec2.internal")
+			csr.SetGenerateName("csr-")
+			Expect(k8sClient.Create(ctx, &csr)).To(Succeed())
+
+			Expect(reconciler.reconcileCSRPendingApproval(ctx, logger.Logger(), cpms, machineInfos)).To(Succeed())
+
+			Expect(meta.FindStatusCondition(cpms.Status.Conditions, conditionCSRPendingApproval)).To(BeNil())
+		})
+
+		It("does not report pending certificate requests when the APIReader is nil", func() {
+			csr := clientCSR("", "ip-10-0-1-10.ec2.internal")
+			csr.SetGenerateName("csr-")
+			Expect(k8sClient.Create(ctx, &csr)).To(Succeed())
+
+			reconciler.APIReader = nil
+
+			Expect(reconciler.reconcileCSRPendingApproval(ctx, logger.Logger(), cpms, machineInfos)).To(Succeed())
+
+			Expect(meta.FindStatusCondition(cpms.Status.Conditions, conditionCSRPendingApproval)).To(BeNil())
+		})
+	})
+})
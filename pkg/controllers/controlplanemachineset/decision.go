@@ -0,0 +1,256 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controlplanemachineset
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/go-logr/logr"
+	machinev1 "github.com/openshift/api/machine/v1"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// reconcileDecision is a log message used to summarise, in a single line, the inputs to and the outcome of the
+	// decisions taken while reconciling the Control Plane Machines.
+	reconcileDecision = "Reconcile decision"
+
+	// errorMarshallingDecision is a log message used to inform the user that the reconcile decision could not be
+	// logged.
+	errorMarshallingDecision = "Error marshalling reconcile decision"
+
+	// actionPaused denotes that no action was taken as the ControlPlaneMachineSet is degraded, has been paused by
+	// the user, or has not completed the adoption of the existing Machines.
+	actionPaused = "Paused"
+
+	// actionCreateMachine denotes that at least one Machine was created.
+	actionCreateMachine = "CreateMachine"
+
+	// actionDeleteMachine denotes that at least one Machine was deleted.
+	actionDeleteMachine = "DeleteMachine"
+
+	// actionWait denotes that no action was taken while waiting for an ongoing operation to progress, for example,
+	// for a replacement to become ready, or for the user to delete an outdated Machine.
+	actionWait = "Wait"
+
+	// actionNone denotes that no action was required.
+	actionNone = "None"
+)
+
+// decision collates the inputs to, and the outcome of, a single reconcile of the Control Plane Machines so that it
+// may be logged as a single structured record.
+type decision struct {
+	// Generation is the generation of the ControlPlaneMachineSet that was reconciled.
+	Generation int64 `json:"generation"`
+
+	// Strategy is the update strategy of the ControlPlaneMachineSet.
+	Strategy machinev1.ControlPlaneMachineSetStrategyType `json:"strategy"`
+
+	// Replicas is the desired number of replicas of the ControlPlaneMachineSet.
+	Replicas int32 `json:"replicas"`
+
+	// Indexes describes the state of each index observed before any action was taken.
+	Indexes []indexDecisionInput `json:"indexes"`
+
+	// OutdatedIndexes are the indexes that contain at least one Machine in need of an update.
+	OutdatedIndexes []int32 `json:"outdatedIndexes"`
+
+	// DegradedReason is the reason of the Degraded condition, when the ControlPlaneMachineSet is degraded.
+	DegradedReason string `json:"degradedReason,omitempty"`
+
+	// Paused denotes that the user has paused the ControlPlaneMachineSet.
+	Paused bool `json:"paused,omitempty"`
+
+	// AdoptionPending denotes that the ControlPlaneMachineSet has not completed the adoption of the existing
+	// Machines.
+	AdoptionPending bool `json:"adoptionPending,omitempty"`
+
+	// CostEstimate is the estimated change in the monthly cost of the Control Plane once the pending rollout has
+	// completed, when the pending rollout changes the instance type and a price catalog is configured.
+	CostEstimate string `json:"costEstimate,omitempty"`
+
+	// Action summarises the outcome of the reconcile.
+	Action string `json:"action"`
+
+	// CreatedIndexes are the indexes for which a Machine was created.
+	CreatedIndexes []int32 `json:"createdIndexes,omitempty"`
+
+	// DeletedMachines are the names of the Machines that were deleted.
+	DeletedMachines []string `json:"deletedMachines,omitempty"`
+
+	// Error is the error that interrupted the reconcile, if any.
+	Error string `json:"error,omitempty"`
+}
+
+// indexDecisionInput describes the state of a single index as an input to the decision.
+type indexDecisionInput struct {
+	// Index is the index being described.
+	Index int32 `json:"index"`
+
+	// Machines is the number of Machines within the index.
+	Machines int `json:"machines"`
+
+	// Ready is the number of ready Machines within the index.
+	Ready int `json:"ready"`
+
+	// Outdated is the number of Machines within the index in need of an update.
+	Outdated int `json:"outdated"`
+
+	// Phase is the rollout phase of the index.
+	Phase rolloutPhase `json:"phase"`
+}
+
+// newDecision records the inputs to the decision from the ControlPlaneMachineSet and the MachineInfos.
+func newDecision(cpms *machinev1.ControlPlaneMachineSet, machineInfos map[int32][]machineproviders.MachineInfo) *decision {
+	d := &decision{
+		Generation:      cpms.GetGeneration(),
+		Strategy:        cpms.Spec.Strategy.Type,
+		Indexes:         []indexDecisionInput{},
+		OutdatedIndexes: []int32{},
+	}
+
+	if cpms.Spec.Replicas != nil {
+		d.Replicas = *cpms.Spec.Replicas
+	}
+
+	for _, idx := range sortedIndexes(machineInfos) {
+		input := indexDecisionInput{
+			Index: idx,
+			Phase: indexRolloutPhase(machineInfos[idx]),
+		}
+
+		for _, machineInfo := range machineInfos[idx] {
+			if machineInfo.MachineRef == nil {
+				continue
+			}
+
+			input.Machines++
+
+			if machineInfo.Ready {
+				input.Ready++
+			}
+
+			if machineInfo.NeedsUpdate {
+				input.Outdated++
+			}
+		}
+
+		if input.Outdated > 0 {
+			d.OutdatedIndexes = append(d.OutdatedIndexes, idx)
+		}
+
+		d.Indexes = append(d.Indexes, input)
+	}
+
+	return d
+}
+
+// complete records the outcome of the decision once the reconcile of the Control Plane Machines has finished.
+func (d *decision) complete(cpms *machinev1.ControlPlaneMachineSet, err error) {
+	if err != nil {
+		d.Error = err.Error()
+	}
+
+	if degraded := meta.FindStatusCondition(cpms.Status.Conditions, conditionDegraded); degraded != nil && degraded.Status == metav1.ConditionTrue {
+		d.DegradedReason = degraded.Reason
+	}
+
+	d.Paused = isControlPlaneMachineSetPaused(cpms)
+	d.AdoptionPending = isAdoptionPending(cpms)
+
+	if costEstimate := meta.FindStatusCondition(cpms.Status.Conditions, conditionRolloutCostEstimate); costEstimate != nil {
+		d.CostEstimate = costEstimate.Message
+	}
+
+	switch {
+	case len(d.CreatedIndexes) > 0:
+		d.Action = actionCreateMachine
+	case len(d.DeletedMachines) > 0:
+		d.Action = actionDeleteMachine
+	case d.DegradedReason != "" || d.Paused || d.AdoptionPending:
+		d.Action = actionPaused
+	case d.inProgress():
+		d.Action = actionWait
+	default:
+		d.Action = actionNone
+	}
+}
+
+// inProgress determines whether any index requires an update, or is part way through a replacement.
+func (d *decision) inProgress() bool {
+	for _, input := range d.Indexes {
+		if input.Phase != phaseIdle {
+			return true
+		}
+	}
+
+	return false
+}
+
+// log emits the decision as a single structured log line, with the decision marshalled to JSON.
+func (d *decision) log(logger logr.Logger) {
+	data, err := json.Marshal(d)
+	if err != nil {
+		// The decision only contains basic types so this should never happen.
+		logger.Error(err, errorMarshallingDecision)
+		return
+	}
+
+	logger.V(2).Info(reconcileDecision, "decision", string(data))
+}
+
+// recordActions wraps the MachineProvider so that any Machine created or deleted is recorded within the decision.
+func (d *decision) recordActions(machineProvider machineproviders.MachineProvider) machineproviders.MachineProvider {
+	return &decisionRecorder{
+		MachineProvider: machineProvider,
+		decision:        d,
+	}
+}
+
+// decisionRecorder is a MachineProvider that records, within a decision, the Machines that were created and deleted
+// by the underlying MachineProvider.
+type decisionRecorder struct {
+	machineproviders.MachineProvider
+
+	// decision is the decision in which to record the actions taken.
+	decision *decision
+}
+
+// CreateMachine creates a Machine using the underlying MachineProvider, and records the index when successful.
+func (r *decisionRecorder) CreateMachine(ctx context.Context, logger logr.Logger, idx int32) error {
+	if err := r.MachineProvider.CreateMachine(ctx, logger, idx); err != nil {
+		return err //nolint:wrapcheck // Errors are returned unchanged so the recorder is transparent to the caller.
+	}
+
+	r.decision.CreatedIndexes = append(r.decision.CreatedIndexes, idx)
+
+	return nil
+}
+
+// DeleteMachine deletes a Machine using the underlying MachineProvider, and records the Machine name when successful.
+func (r *decisionRecorder) DeleteMachine(ctx context.Context, logger logr.Logger, machineRef *machineproviders.ObjectRef) error {
+	if err := r.MachineProvider.DeleteMachine(ctx, logger, machineRef); err != nil {
+		return err //nolint:wrapcheck // Errors are returned unchanged so the recorder is transparent to the caller.
+	}
+
+	r.decision.DeletedMachines = append(r.decision.DeletedMachines, machineRef.ObjectMeta.GetName())
+
+	return nil
+}
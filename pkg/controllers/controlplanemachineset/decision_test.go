@@ -0,0 +1,193 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controlplanemachineset
+
+import (
+	"errors"
+
+	"github.com/golang/mock/gomock"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	machinev1 "github.com/openshift/api/machine/v1"
+	machinev1beta1 "github.com/openshift/api/machine/v1beta1"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders/mock"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/test"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/test/resourcebuilder"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("Reconcile decision", func() {
+	machineGVR := machinev1beta1.GroupVersion.WithResource("machines")
+	nodeGVR := corev1.SchemeGroupVersion.WithResource("nodes")
+
+	healthyMachineBuilder := resourcebuilder.MachineInfo().
+		WithMachineGVR(machineGVR).
+		WithNodeGVR(nodeGVR).
+		WithReady(true).
+		WithNeedsUpdate(false)
+
+	updatingMachineInfos := func() map[int32][]machineproviders.MachineInfo {
+		return map[int32][]machineproviders.MachineInfo{
+			0: {healthyMachineBuilder.WithIndex(0).WithMachineName("machine-0").Build()},
+			1: {healthyMachineBuilder.WithIndex(1).WithMachineName("machine-1").WithNeedsUpdate(true).Build()},
+			2: {healthyMachineBuilder.WithIndex(2).WithMachineName("machine-2").Build()},
+		}
+	}
+
+	var cpms *machinev1.ControlPlaneMachineSet
+
+	BeforeEach(func() {
+		cpms = resourcebuilder.ControlPlaneMachineSet().WithGeneration(2).WithReplicas(3).Build()
+	})
+
+	It("should record the inputs of each index", func() {
+		d := newDecision(cpms, updatingMachineInfos())
+
+		Expect(d.Generation).To(Equal(int64(2)))
+		Expect(d.Strategy).To(Equal(machinev1.RollingUpdate))
+		Expect(d.Replicas).To(Equal(int32(3)))
+		Expect(d.OutdatedIndexes).To(Equal([]int32{1}))
+		Expect(d.Indexes).To(Equal([]indexDecisionInput{
+			{Index: 0, Machines: 1, Ready: 1, Outdated: 0, Phase: phaseIdle},
+			{Index: 1, Machines: 1, Ready: 1, Outdated: 1, Phase: phasePlanPending},
+			{Index: 2, Machines: 1, Ready: 1, Outdated: 0, Phase: phaseIdle},
+		}))
+	})
+
+	Context("when completing the decision", func() {
+		It("should report no action when all indexes are idle", func() {
+			machineInfos := updatingMachineInfos()
+			machineInfos[1] = []machineproviders.MachineInfo{healthyMachineBuilder.WithIndex(1).WithMachineName("machine-1").Build()}
+
+			d := newDecision(cpms, machineInfos)
+			d.complete(cpms, nil)
+
+			Expect(d.Action).To(Equal(actionNone))
+		})
+
+		It("should report waiting when an index is outdated and no action was taken", func() {
+			d := newDecision(cpms, updatingMachineInfos())
+			d.complete(cpms, nil)
+
+			Expect(d.Action).To(Equal(actionWait))
+		})
+
+		It("should report paused with the degraded reason", func() {
+			setDegradedCondition(cpms, reasonNoReadyMachines, "No ready control plane machines found")
+
+			d := newDecision(cpms, updatingMachineInfos())
+			d.complete(cpms, nil)
+
+			Expect(d.Action).To(Equal(actionPaused))
+			Expect(d.DegradedReason).To(Equal(reasonNoReadyMachines))
+		})
+
+		It("should report paused when the control plane machine set is paused", func() {
+			cpms.SetAnnotations(map[string]string{pausedAnnotation: "true"})
+
+			d := newDecision(cpms, updatingMachineInfos())
+			d.complete(cpms, nil)
+
+			Expect(d.Action).To(Equal(actionPaused))
+			Expect(d.Paused).To(BeTrue())
+		})
+
+		It("should record the cost estimate", func() {
+			cpms.Status.Conditions = []metav1.Condition{{Type: conditionRolloutCostEstimate, Status: metav1.ConditionTrue, Reason: reasonCostEstimated, Message: "Estimated monthly cost change of +140.16 USD"}}
+
+			d := newDecision(cpms, updatingMachineInfos())
+			d.complete(cpms, nil)
+
+			Expect(d.CostEstimate).To(Equal("Estimated monthly cost change of +140.16 USD"))
+		})
+
+		It("should record the error", func() {
+			d := newDecision(cpms, updatingMachineInfos())
+			d.complete(cpms, errors.New("transient error"))
+
+			Expect(d.Error).To(Equal("transient error"))
+		})
+	})
+
+	Context("when recording actions", func() {
+		var mockMachineProvider *mock.MockMachineProvider
+
+		BeforeEach(func() {
+			mockMachineProvider = mock.NewMockMachineProvider(gomock.NewController(GinkgoT()))
+		})
+
+		It("should record created indexes and deleted machines", func() {
+			d := newDecision(cpms, updatingMachineInfos())
+			machineRef := healthyMachineBuilder.WithMachineName("machine-1").Build().MachineRef
+
+			mockMachineProvider.EXPECT().CreateMachine(gomock.Any(), gomock.Any(), int32(1)).Return(nil).Times(1)
+			mockMachineProvider.EXPECT().DeleteMachine(gomock.Any(), gomock.Any(), machineRef).Return(nil).Times(1)
+
+			machineProvider := d.recordActions(mockMachineProvider)
+			Expect(machineProvider.CreateMachine(ctx, test.NewTestLogger().Logger(), 1)).To(Succeed())
+			Expect(machineProvider.DeleteMachine(ctx, test.NewTestLogger().Logger(), machineRef)).To(Succeed())
+
+			d.complete(cpms, nil)
+
+			Expect(d.CreatedIndexes).To(Equal([]int32{1}))
+			Expect(d.DeletedMachines).To(Equal([]string{"machine-1"}))
+			Expect(d.Action).To(Equal(actionCreateMachine))
+		})
+
+		It("should not record failed actions", func() {
+			d := newDecision(cpms, updatingMachineInfos())
+			transientError := errors.New("transient error")
+
+			mockMachineProvider.EXPECT().CreateMachine(gomock.Any(), gomock.Any(), int32(1)).Return(transientError).Times(1)
+
+			Expect(d.recordActions(mockMachineProvider).CreateMachine(ctx, test.NewTestLogger().Logger(), 1)).To(MatchError(transientError))
+			Expect(d.CreatedIndexes).To(BeEmpty())
+		})
+	})
+
+	It("should log the decision as a single JSON line", func() {
+		logger := test.NewTestLogger()
+
+		d := newDecision(cpms, updatingMachineInfos())
+		d.complete(cpms, nil)
+		d.log(logger.Logger())
+
+		Expect(logger.Entries()).To(ConsistOf(test.LogEntry{
+			Level: 2,
+			KeysAndValues: []interface{}{
+				"decision", `{"generation":2,"strategy":"RollingUpdate","replicas":3,"indexes":[` +
+					`{"index":0,"machines":1,"ready":1,"outdated":0,"phase":"Idle"},` +
+					`{"index":1,"machines":1,"ready":1,"outdated":1,"phase":"PlanPending"},` +
+					`{"index":2,"machines":1,"ready":1,"outdated":0,"phase":"Idle"}],` +
+					`"outdatedIndexes":[1],"action":"Wait"}`,
+			},
+			Message: reconcileDecision,
+		}))
+	})
+
+	It("should not mark the decision degraded when the degraded condition is false", func() {
+		cpms.Status.Conditions = []metav1.Condition{{Type: conditionDegraded, Status: metav1.ConditionFalse, Reason: reasonAsExpected}}
+
+		d := newDecision(cpms, updatingMachineInfos())
+		d.complete(cpms, nil)
+
+		Expect(d.DegradedReason).To(BeEmpty())
+	})
+})
@@ -0,0 +1,131 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controlplanemachineset
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/go-logr/logr"
+	machinev1beta1 "github.com/openshift/api/machine/v1beta1"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// nodeMachineAnnotation is the annotation set on each Node, by the Machine API, to link the Node to the Machine
+	// backing it. The value is the namespace and name of the Machine, separated by a slash.
+	nodeMachineAnnotation = "machine.openshift.io/machine"
+
+	// removedDepartedNode is a log message used to inform the user that a departed Control Plane Node has been
+	// deleted.
+	removedDepartedNode = "Removed departed control plane node"
+)
+
+// removeDepartedNodes deletes the Control Plane Nodes that have departed the cluster, and returns the remaining
+// Nodes. A Node has departed when all of the following are true:
+// - No Machine within the ControlPlaneMachineSet references the Node
+// - The Machine linked to the Node, by the Machine API, no longer exists
+// - The Node is no longer ready
+// - None of the addresses of the Node are in the etcd membership, so the etcd operator has removed its member
+// Any Node that cannot be verified to have departed is left for the unmanaged Nodes check.
+func (r *ControlPlaneMachineSetReconciler) removeDepartedNodes(ctx context.Context, logger logr.Logger, nodes []corev1.Node, machineInfos map[int32][]machineproviders.MachineInfo, etcdEndpoints map[string]string) ([]corev1.Node, error) {
+	unmanagedNodes := make(map[string]struct{})
+	for _, name := range unmanagedNodeNames(nodes, machineInfos) {
+		unmanagedNodes[name] = struct{}{}
+	}
+
+	etcdAddresses := make(map[string]struct{}, len(etcdEndpoints))
+	for _, address := range etcdEndpoints {
+		etcdAddresses[address] = struct{}{}
+	}
+
+	remaining := []corev1.Node{}
+
+	for i := range nodes {
+		node := &nodes[i]
+
+		if _, ok := unmanagedNodes[node.GetName()]; !ok || isNodeReady(node) || hasEtcdMember(node, etcdAddresses) {
+			remaining = append(remaining, *node)
+			continue
+		}
+
+		machineName, departed, err := r.nodeMachineRemoved(ctx, node)
+		if err != nil {
+			return nil, err
+		}
+
+		if !departed {
+			remaining = append(remaining, *node)
+			continue
+		}
+
+		if err := r.Delete(ctx, node); err != nil && !apierrors.IsNotFound(err) {
+			return nil, fmt.Errorf("error deleting node %s: %w", node.GetName(), err)
+		}
+
+		logger.V(1).Info(removedDepartedNode, "nodeName", node.GetName(), "machineName", machineName)
+	}
+
+	return remaining, nil
+}
+
+// nodeMachineRemoved determines whether the Machine linked to the Node, by the Machine API, has been removed.
+// Nodes are only linked to Machines within the namespace of the ControlPlaneMachineSet. When the Node is not linked
+// to such a Machine, false is returned as the Node cannot be verified to have departed.
+func (r *ControlPlaneMachineSetReconciler) nodeMachineRemoved(ctx context.Context, node *corev1.Node) (string, bool, error) {
+	parts := strings.SplitN(node.GetAnnotations()[nodeMachineAnnotation], "/", 2)
+	if len(parts) != 2 || parts[0] != r.Namespace || parts[1] == "" {
+		return "", false, nil
+	}
+
+	namespace, name := parts[0], parts[1]
+
+	machine := &machinev1beta1.Machine{}
+	if err := r.Get(ctx, client.ObjectKey{Namespace: namespace, Name: name}, machine); apierrors.IsNotFound(err) {
+		return name, true, nil
+	} else if err != nil {
+		return "", false, fmt.Errorf("error fetching machine %s for node %s: %w", name, node.GetName(), err)
+	}
+
+	return name, false, nil
+}
+
+// isNodeReady determines whether the Node reports that it is ready.
+func isNodeReady(node *corev1.Node) bool {
+	for _, condition := range node.Status.Conditions {
+		if condition.Type == corev1.NodeReady {
+			return condition.Status == corev1.ConditionTrue
+		}
+	}
+
+	return false
+}
+
+// hasEtcdMember determines whether any of the addresses of the Node belong to a member of etcd.
+func hasEtcdMember(node *corev1.Node, etcdAddresses map[string]struct{}) bool {
+	for _, address := range node.Status.Addresses {
+		if _, ok := etcdAddresses[address.Address]; ok {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,193 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controlplanemachineset
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	machinev1beta1 "github.com/openshift/api/machine/v1beta1"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/test"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/test/resourcebuilder"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var _ = Describe("removeDepartedNodes", func() {
+	const departedNodeName = "master-departed"
+	const departedNodeAddress = "10.0.0.4"
+
+	machineGVR := machinev1beta1.GroupVersion.WithResource("machines")
+	nodeGVR := corev1.SchemeGroupVersion.WithResource("nodes")
+
+	healthyMachineBuilder := resourcebuilder.MachineInfo().
+		WithMachineGVR(machineGVR).
+		WithNodeGVR(nodeGVR).
+		WithReady(true)
+
+	machineInfos := map[int32][]machineproviders.MachineInfo{
+		0: {healthyMachineBuilder.WithIndex(0).WithMachineName("machine-0").WithNodeName("master-0").Build()},
+		1: {healthyMachineBuilder.WithIndex(1).WithMachineName("machine-1").WithNodeName("master-1").Build()},
+		2: {healthyMachineBuilder.WithIndex(2).WithMachineName("machine-2").WithNodeName("master-2").Build()},
+	}
+
+	etcdEndpoints := map[string]string{
+		"member-0": "10.0.0.1",
+		"member-1": "10.0.0.2",
+		"member-2": "10.0.0.3",
+	}
+
+	var namespaceName string
+	var reconciler *ControlPlaneMachineSetReconciler
+	var logger test.TestLogger
+	var departedNode *corev1.Node
+
+	BeforeEach(func() {
+		By("Setting up a namespace for the test")
+		ns := resourcebuilder.Namespace().WithGenerateName("control-plane-machine-set-controller-").Build()
+		Expect(k8sClient.Create(ctx, ns)).To(Succeed())
+		namespaceName = ns.GetName()
+
+		reconciler = &ControlPlaneMachineSetReconciler{
+			Client:    k8sClient,
+			Namespace: namespaceName,
+		}
+
+		logger = test.NewTestLogger()
+
+		By("Creating a Node left behind by a removed Machine")
+		departedNode = resourcebuilder.Node().AsMaster().WithName(departedNodeName).Build()
+		departedNode.SetAnnotations(map[string]string{nodeMachineAnnotation: namespaceName + "/machine-departed"})
+		Expect(k8sClient.Create(ctx, departedNode)).To(Succeed())
+
+		departedNode.Status.Conditions = []corev1.NodeCondition{{Type: corev1.NodeReady, Status: corev1.ConditionUnknown}}
+		departedNode.Status.Addresses = []corev1.NodeAddress{{Type: corev1.NodeInternalIP, Address: departedNodeAddress}}
+		Expect(k8sClient.Status().Update(ctx, departedNode)).To(Succeed())
+	})
+
+	AfterEach(func() {
+		test.CleanupResources(Default, ctx, cfg, k8sClient, namespaceName,
+			&corev1.Node{},
+			&machinev1beta1.Machine{},
+		)
+	})
+
+	// removeDepartedNode runs removeDepartedNodes against the departed Node, and returns the remaining Nodes.
+	removeDepartedNode := func(etcdEndpoints map[string]string) []corev1.Node {
+		remaining, err := reconciler.removeDepartedNodes(ctx, logger.Logger(), []corev1.Node{*departedNode}, machineInfos, etcdEndpoints)
+		Expect(err).ToNot(HaveOccurred())
+
+		return remaining
+	}
+
+	// expectNodeRetained checks that the departed Node was neither deleted nor removed from the remaining Nodes.
+	expectNodeRetained := func(remaining []corev1.Node) {
+		Expect(remaining).To(HaveLen(1))
+		Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(departedNode), &corev1.Node{})).To(Succeed())
+		Expect(logger.Entries()).To(BeEmpty())
+	}
+
+	It("should delete the departed Node", func() {
+		Expect(removeDepartedNode(etcdEndpoints)).To(BeEmpty())
+
+		Expect(apierrors.IsNotFound(k8sClient.Get(ctx, client.ObjectKeyFromObject(departedNode), &corev1.Node{}))).To(BeTrue())
+		Expect(logger.Entries()).To(ConsistOf(test.LogEntry{
+			Level:         1,
+			KeysAndValues: []interface{}{"nodeName", departedNodeName, "machineName", "machine-departed"},
+			Message:       removedDepartedNode,
+		}))
+	})
+
+	It("should not delete the Node while its etcd member remains", func() {
+		expectNodeRetained(removeDepartedNode(map[string]string{"member-3": departedNodeAddress}))
+	})
+
+	It("should not delete the Node while it is ready", func() {
+		departedNode.Status.Conditions = []corev1.NodeCondition{{Type: corev1.NodeReady, Status: corev1.ConditionTrue}}
+		Expect(k8sClient.Status().Update(ctx, departedNode)).To(Succeed())
+
+		expectNodeRetained(removeDepartedNode(etcdEndpoints))
+	})
+
+	It("should not delete the Node while its Machine exists", func() {
+		machine := resourcebuilder.Machine().AsMaster().WithNamespace(namespaceName).WithName("machine-departed").Build()
+		Expect(k8sClient.Create(ctx, machine)).To(Succeed())
+
+		expectNodeRetained(removeDepartedNode(etcdEndpoints))
+	})
+
+	It("should not delete the Node when it is not linked to a Machine", func() {
+		departedNode.SetAnnotations(nil)
+		Expect(k8sClient.Update(ctx, departedNode)).To(Succeed())
+
+		expectNodeRetained(removeDepartedNode(etcdEndpoints))
+	})
+
+	It("should not delete the Node when it is linked to a Machine in another namespace", func() {
+		departedNode.SetAnnotations(map[string]string{nodeMachineAnnotation: "openshift-cluster-api/machine-departed"})
+		Expect(k8sClient.Update(ctx, departedNode)).To(Succeed())
+
+		expectNodeRetained(removeDepartedNode(etcdEndpoints))
+	})
+
+	It("should not delete Nodes referenced by a Control Plane Machine", func() {
+		managedInfos := map[int32][]machineproviders.MachineInfo{
+			0: {healthyMachineBuilder.WithIndex(0).WithMachineName("machine-0").WithNodeName(departedNodeName).Build()},
+		}
+
+		remaining, err := reconciler.removeDepartedNodes(ctx, logger.Logger(), []corev1.Node{*departedNode}, managedInfos, etcdEndpoints)
+		Expect(err).ToNot(HaveOccurred())
+
+		expectNodeRetained(remaining)
+	})
+
+	It("should not report the departed Node as unmanaged when validating the cluster state", func() {
+		ns := resourcebuilder.Namespace().WithName(etcdNamespace).Build()
+		if err := k8sClient.Create(ctx, ns); !apierrors.IsAlreadyExists(err) {
+			Expect(err).ToNot(HaveOccurred())
+		}
+
+		configMap := &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: etcdEndpointsConfigMapName, Namespace: etcdNamespace},
+			Data:       etcdEndpoints,
+		}
+		Expect(k8sClient.Create(ctx, configMap)).To(Succeed())
+
+		DeferCleanup(func() {
+			Expect(k8sClient.Delete(ctx, configMap)).To(Succeed())
+		})
+
+		for _, name := range []string{"master-0", "master-1", "master-2"} {
+			Expect(k8sClient.Create(ctx, resourcebuilder.Node().AsMaster().WithName(name).Build())).To(Succeed())
+		}
+
+		reconciler.APIReader = k8sClient
+		reconciler.DeleteDepartedNodes = true
+
+		cpms := resourcebuilder.ControlPlaneMachineSet().Build()
+		membership, err := reconciler.etcdMembership(ctx)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(reconciler.validateClusterState(ctx, logger.Logger(), cpms, machineInfos, membership)).To(Succeed())
+
+		Expect(isControlPlaneMachineSetDegraded(cpms)).To(BeFalse())
+		Expect(apierrors.IsNotFound(k8sClient.Get(ctx, client.ObjectKeyFromObject(departedNode), &corev1.Node{}))).To(BeTrue())
+	})
+})
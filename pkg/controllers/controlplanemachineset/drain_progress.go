@@ -0,0 +1,380 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controlplanemachineset
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	machinev1 "github.com/openshift/api/machine/v1"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func init() {
+	registerInformationalCondition(conditionDrainProgress)
+}
+
+// DrainEscalationPolicy determines how the ControlPlaneMachineSet escalates the drain of a Control Plane Node that
+// has not completed within the drain timeout.
+type DrainEscalationPolicy string
+
+const (
+	// DrainEscalationPolicyReport means that drains that have timed out are reported within the DrainProgress
+	// condition, but otherwise left to continue.
+	DrainEscalationPolicyReport DrainEscalationPolicy = "Report"
+
+	// DrainEscalationPolicyEvent means that, in addition to being reported, a warning event is published on the
+	// ControlPlaneMachineSet when a drain times out.
+	DrainEscalationPolicyEvent DrainEscalationPolicy = "Event"
+
+	// DrainEscalationPolicySkipDrain means that, in addition to publishing a warning event, the Machine API is asked
+	// to skip the remainder of the drain once it is safe to do so, so that the replacement can complete.
+	DrainEscalationPolicySkipDrain DrainEscalationPolicy = "SkipDrain"
+
+	// excludeNodeDrainingAnnotation is the annotation that, when present on a Machine, causes the Machine API to skip
+	// draining the Node of the Machine as it is deleted.
+	excludeNodeDrainingAnnotation = "machine.openshift.io/exclude-node-draining"
+
+	// drainProgressResyncPeriod is the period at which the ControlPlaneMachineSet is resynced while a Control Plane
+	// Node is draining. Pods are not watched, so this is how often the drain progress is refreshed.
+	drainProgressResyncPeriod = 30 * time.Second
+
+	// observedDrainProgress is a log message used to inform the user of the progress of the drain of a Control Plane
+	// Node.
+	observedDrainProgress = "Observed control plane node drain progress"
+
+	// drainTimedOut is a log message used to inform the user that the drain of a Control Plane Node has not completed
+	// within the drain timeout.
+	drainTimedOut = "Control plane node drain timed out"
+
+	// skippedNodeDrain is a log message used to inform the user that the Machine API has been asked to skip the
+	// remainder of the drain of a Control Plane Node.
+	skippedNodeDrain = "Skipping drain of control plane node"
+)
+
+// errUnknownDrainEscalationPolicy is used to inform users that the drain escalation policy they have provided is not
+// recognised.
+var errUnknownDrainEscalationPolicy = errors.New("unknown drain escalation policy")
+
+// ParseDrainEscalationPolicy parses the drain escalation policy given, as provided on the command line.
+func ParseDrainEscalationPolicy(policy string) (DrainEscalationPolicy, error) {
+	switch p := DrainEscalationPolicy(policy); p {
+	case DrainEscalationPolicyReport, DrainEscalationPolicyEvent, DrainEscalationPolicySkipDrain:
+		return p, nil
+	default:
+		return "", fmt.Errorf("%w: %q", errUnknownDrainEscalationPolicy, policy)
+	}
+}
+
+// drainProgress describes the progress of the drain of the Node of a deleted Control Plane Machine.
+type drainProgress struct {
+	// machineInfo is the MachineInfo of the Machine being deleted.
+	machineInfo machineproviders.MachineInfo
+
+	// remainingPods is the number of Pods that remain to be evicted from the Node.
+	remainingPods int
+
+	// blockingPDBs are the PodDisruptionBudgets, by namespace and name, that currently allow no disruptions to any of
+	// the remaining Pods.
+	blockingPDBs []string
+
+	// timedOut is set once the drain has not completed within the drain timeout.
+	timedOut bool
+}
+
+// nodeName returns the name of the Node being drained.
+func (d drainProgress) nodeName() string {
+	return d.machineInfo.NodeRef.ObjectMeta.GetName()
+}
+
+// summary describes the remaining Pods and blocking PodDisruptionBudgets of the drain.
+func (d drainProgress) summary() string {
+	summary := fmt.Sprintf("%d pod(s) remaining", d.remainingPods)
+	if len(d.blockingPDBs) > 0 {
+		summary = fmt.Sprintf("%s, blocked by %s", summary, strings.Join(d.blockingPDBs, ", "))
+	}
+
+	return summary
+}
+
+// reconcileDrainProgress reports, within the DrainProgress condition, the progress of the drain of the Node of each
+// deleted Control Plane Machine. The progress is made up of the Pods that remain to be evicted, and the
+// PodDisruptionBudgets that currently prevent their eviction. A Normal event is published whenever the progress
+// changes.
+// Once a drain has not completed within the drain timeout, it is escalated as per the drain escalation policy.
+// The returned result requeues the ControlPlaneMachineSet so that the progress is refreshed while any Node is
+// draining. When the APIReader is nil, the drain progress is not reported.
+func (r *ControlPlaneMachineSetReconciler) reconcileDrainProgress(ctx context.Context, logger logr.Logger, cpms *machinev1.ControlPlaneMachineSet, machineInfos map[int32][]machineproviders.MachineInfo) (ctrl.Result, error) {
+	draining := drainingMachines(machineInfos)
+	if r.APIReader == nil || len(draining) == 0 {
+		meta.RemoveStatusCondition(&cpms.Status.Conditions, conditionDrainProgress)
+		return ctrl.Result{}, nil
+	}
+
+	pdbList := &policyv1.PodDisruptionBudgetList{}
+	if err := r.APIReader.List(ctx, pdbList); err != nil {
+		return ctrl.Result{}, fmt.Errorf("failed to list pod disruption budgets: %w", err)
+	}
+
+	now := time.Now()
+	drainTimeout := r.drainTimeoutFor(cpms)
+	requeueAfter := drainProgressResyncPeriod
+	progress := []drainProgress{}
+
+	for _, machineInfo := range draining {
+		p, err := r.nodeDrainProgress(ctx, machineInfo, pdbList.Items)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+
+		if drainTimeout > 0 {
+			timeoutAt := machineInfo.MachineRef.ObjectMeta.GetDeletionTimestamp().Add(drainTimeout)
+			if untilTimeout := timeoutAt.Sub(now); untilTimeout > 0 && untilTimeout < requeueAfter {
+				requeueAfter = untilTimeout
+			}
+
+			p.timedOut = !now.Before(timeoutAt)
+		}
+
+		logger.V(2).Info(observedDrainProgress,
+			"index", machineInfo.Index,
+			"machineName", machineInfo.MachineRef.ObjectMeta.GetName(),
+			"nodeName", p.nodeName(),
+			"remainingPods", p.remainingPods,
+			"blockingPDBs", strings.Join(p.blockingPDBs, ","),
+		)
+
+		progress = append(progress, p)
+	}
+
+	previous := meta.FindStatusCondition(cpms.Status.Conditions, conditionDrainProgress)
+
+	var previousMessage, previousReason string
+	if previous != nil {
+		previousMessage, previousReason = previous.Message, previous.Reason
+	}
+
+	setDrainProgressCondition(cpms, progress)
+
+	current := meta.FindStatusCondition(cpms.Status.Conditions, conditionDrainProgress)
+	if current.Message != previousMessage {
+		for _, p := range progress {
+			r.publishEvent(cpms, corev1.EventTypeNormal, reasonDrainInProgress, fmt.Sprintf("Draining node %s of machine %s: %s", p.nodeName(), p.machineInfo.MachineRef.ObjectMeta.GetName(), p.summary()))
+		}
+	}
+
+	for _, p := range progress {
+		if !p.timedOut {
+			continue
+		}
+
+		if err := r.escalateDrain(ctx, logger, cpms, p, machineInfos[p.machineInfo.Index], drainTimeout, previousReason != reasonDrainTimedOut); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	return ctrl.Result{RequeueAfter: requeueAfter}, nil
+}
+
+// drainingMachines returns the MachineInfos, in index order, of the deleted Control Plane Machines whose Node is
+// still registered, and so is being drained.
+func drainingMachines(machineInfos map[int32][]machineproviders.MachineInfo) []machineproviders.MachineInfo {
+	draining := []machineproviders.MachineInfo{}
+
+	for _, idx := range sortedIndexes(machineInfos) {
+		for _, machineInfo := range machineInfos[idx] {
+			if machineInfo.MachineRef == nil || machineInfo.NodeRef == nil || machineInfo.MachineRef.ObjectMeta.GetDeletionTimestamp() == nil {
+				continue
+			}
+
+			draining = append(draining, machineInfo)
+		}
+	}
+
+	return draining
+}
+
+// nodeDrainProgress determines the Pods that remain to be evicted from the Node of the Machine, and which of the
+// PodDisruptionBudgets given currently prevent their eviction.
+func (r *ControlPlaneMachineSetReconciler) nodeDrainProgress(ctx context.Context, machineInfo machineproviders.MachineInfo, pdbs []policyv1.PodDisruptionBudget) (drainProgress, error) {
+	nodeName := machineInfo.NodeRef.ObjectMeta.GetName()
+
+	podList := &corev1.PodList{}
+	if err := r.APIReader.List(ctx, podList, client.MatchingFields{"spec.nodeName": nodeName}); err != nil {
+		return drainProgress{}, fmt.Errorf("failed to list pods on node %s: %w", nodeName, err)
+	}
+
+	remaining := evictablePods(podList.Items)
+
+	return drainProgress{
+		machineInfo:   machineInfo,
+		remainingPods: len(remaining),
+		blockingPDBs:  blockingPodDisruptionBudgets(remaining, pdbs),
+	}, nil
+}
+
+// evictablePods filters the Pods to those that a drain must evict. Pods that have completed, mirror Pods and Pods
+// managed by a DaemonSet are not evicted by a drain, so do not hold up its completion.
+func evictablePods(pods []corev1.Pod) []corev1.Pod {
+	evictable := []corev1.Pod{}
+
+	for i := range pods {
+		pod := pods[i]
+
+		if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			continue
+		}
+
+		if _, ok := pod.GetAnnotations()[corev1.MirrorPodAnnotationKey]; ok {
+			continue
+		}
+
+		if owner := metav1.GetControllerOf(&pod); owner != nil && owner.Kind == "DaemonSet" {
+			continue
+		}
+
+		evictable = append(evictable, pod)
+	}
+
+	return evictable
+}
+
+// blockingPodDisruptionBudgets returns the sorted namespace and name of each PodDisruptionBudget that allows no
+// disruptions, and that selects at least one of the Pods given.
+func blockingPodDisruptionBudgets(pods []corev1.Pod, pdbs []policyv1.PodDisruptionBudget) []string {
+	blocking := []string{}
+
+	for _, pdb := range pdbs {
+		if pdb.Status.DisruptionsAllowed > 0 {
+			continue
+		}
+
+		selector, err := metav1.LabelSelectorAsSelector(pdb.Spec.Selector)
+		if err != nil {
+			continue
+		}
+
+		for _, pod := range pods {
+			if pod.GetNamespace() == pdb.GetNamespace() && selector.Matches(labels.Set(pod.GetLabels())) {
+				blocking = append(blocking, fmt.Sprintf("%s/%s", pdb.GetNamespace(), pdb.GetName()))
+				break
+			}
+		}
+	}
+
+	sort.Strings(blocking)
+
+	return blocking
+}
+
+// escalateDrain escalates a drain that has not completed within the drain timeout, as per the drain escalation
+// policy, given the drain timeout that it has exceeded. The warning event is only published when the drain has
+// newly timed out.
+// With the SkipDrain policy, the Machine API is only asked to skip the drain when the Machine is outdated, another
+// Machine within the same index is ready and up to date, and the ControlPlaneMachineSet is not degraded, so that
+// skipping the drain does not reduce the availability of the Control Plane.
+func (r *ControlPlaneMachineSetReconciler) escalateDrain(ctx context.Context, logger logr.Logger, cpms *machinev1.ControlPlaneMachineSet, p drainProgress, indexMachineInfos []machineproviders.MachineInfo, drainTimeout time.Duration, newlyTimedOut bool) error {
+	machineRef := p.machineInfo.MachineRef
+
+	logger.V(1).Info(drainTimedOut,
+		"index", p.machineInfo.Index,
+		"machineName", machineRef.ObjectMeta.GetName(),
+		"nodeName", p.nodeName(),
+		"remainingPods", p.remainingPods,
+		"blockingPDBs", strings.Join(p.blockingPDBs, ","),
+	)
+
+	if r.DrainEscalationPolicy != DrainEscalationPolicyEvent && r.DrainEscalationPolicy != DrainEscalationPolicySkipDrain {
+		return nil
+	}
+
+	if newlyTimedOut {
+		r.publishEvent(cpms, corev1.EventTypeWarning, reasonDrainTimedOut, fmt.Sprintf("Drain of node %s of machine %s has not completed within %s: %s", p.nodeName(), machineRef.ObjectMeta.GetName(), drainTimeout, p.summary()))
+	}
+
+	if r.DrainEscalationPolicy != DrainEscalationPolicySkipDrain || isControlPlaneMachineSetDegraded(cpms) {
+		return nil
+	}
+
+	if _, ok := machineRef.ObjectMeta.GetAnnotations()[excludeNodeDrainingAnnotation]; ok {
+		return nil
+	}
+
+	if !p.machineInfo.NeedsUpdate || !hasReadyReplacement(p.machineInfo, indexMachineInfos) {
+		return nil
+	}
+
+	if err := r.patchMachineAnnotations(ctx, machineRef, func(annotations map[string]string) {
+		annotations[excludeNodeDrainingAnnotation] = ""
+	}); err != nil {
+		return fmt.Errorf("error excluding node %s of machine %s from draining: %w", p.nodeName(), machineRef.ObjectMeta.GetName(), err)
+	}
+
+	logger.V(1).Info(skippedNodeDrain,
+		"index", p.machineInfo.Index,
+		"machineName", machineRef.ObjectMeta.GetName(),
+		"nodeName", p.nodeName(),
+	)
+
+	return nil
+}
+
+// publishEvent publishes an event on the ControlPlaneMachineSet, when an event recorder has been configured.
+func (r *ControlPlaneMachineSetReconciler) publishEvent(cpms *machinev1.ControlPlaneMachineSet, eventType, reason, message string) {
+	if r.Recorder != nil {
+		r.Recorder.Event(cpms, eventType, reason, message)
+	}
+}
+
+// setDrainProgressCondition sets the drain progress condition to report the progress of each drain, eg
+// `Draining 1 node(s): master-0 (3 pod(s) remaining, blocked by openshift-etcd/etcd-guard-pdb) since
+// 2022-10-14T10:00:00Z`. The reason is DrainTimedOut when any drain has timed out, and DrainInProgress otherwise.
+func setDrainProgressCondition(cpms *machinev1.ControlPlaneMachineSet, progress []drainProgress) {
+	reason := reasonDrainInProgress
+	drains := []string{}
+
+	for _, p := range progress {
+		drain := fmt.Sprintf("%s (%s) since %s", p.nodeName(), p.summary(), p.machineInfo.MachineRef.ObjectMeta.GetDeletionTimestamp().UTC().Format(time.RFC3339))
+
+		if p.timedOut {
+			reason = reasonDrainTimedOut
+			drain += ", timed out"
+		}
+
+		drains = append(drains, drain)
+	}
+
+	meta.SetStatusCondition(&cpms.Status.Conditions, metav1.Condition{
+		Type:               conditionDrainProgress,
+		Status:             metav1.ConditionTrue,
+		Reason:             reason,
+		ObservedGeneration: cpms.GetGeneration(),
+		Message:            fmt.Sprintf("Draining %d node(s): %s", len(progress), strings.Join(drains, "; ")),
+	})
+}
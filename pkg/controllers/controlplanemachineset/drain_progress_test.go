@@ -0,0 +1,356 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controlplanemachineset
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	machinev1 "github.com/openshift/api/machine/v1"
+	machinev1beta1 "github.com/openshift/api/machine/v1beta1"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/test"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/test/resourcebuilder"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/pointer"
+
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/envtest/komega"
+)
+
+var _ = Describe("Drain progress", func() {
+	const nodeName = "drain-progress-master-1"
+
+	machineGVR := machinev1beta1.GroupVersion.WithResource("machines")
+	nodeGVR := corev1.SchemeGroupVersion.WithResource("nodes")
+
+	pod := func(name string, labels map[string]string) corev1.Pod {
+		return corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "openshift-etcd", Name: name, Labels: labels},
+		}
+	}
+
+	DescribeTable("ParseDrainEscalationPolicy", func(policy string, expected DrainEscalationPolicy, expectedErr string) {
+		parsed, err := ParseDrainEscalationPolicy(policy)
+		if expectedErr != "" {
+			Expect(err).To(MatchError(expectedErr))
+			return
+		}
+
+		Expect(err).ToNot(HaveOccurred())
+		Expect(parsed).To(Equal(expected))
+	},
+		Entry("with Report", "Report", DrainEscalationPolicyReport, ""),
+		Entry("with Event", "Event", DrainEscalationPolicyEvent, ""),
+		Entry("with SkipDrain", "SkipDrain", DrainEscalationPolicySkipDrain, ""),
+		Entry("with an unknown policy", "Evict", DrainEscalationPolicy(""), `unknown drain escalation policy: "Evict"`),
+	)
+
+	It("evictablePods ignores completed, mirror and DaemonSet pods", func() {
+		completed := pod("completed", nil)
+		completed.Status.Phase = corev1.PodSucceeded
+
+		mirror := pod("mirror", nil)
+		mirror.Annotations = map[string]string{corev1.MirrorPodAnnotationKey: "hash"}
+
+		daemonSet := pod("daemonset", nil)
+		daemonSet.OwnerReferences = []metav1.OwnerReference{{APIVersion: "apps/v1", Kind: "DaemonSet", Name: "ds", UID: "uid", Controller: pointer.Bool(true)}}
+
+		running := pod("running", nil)
+		running.Status.Phase = corev1.PodRunning
+
+		Expect(evictablePods([]corev1.Pod{completed, mirror, daemonSet, running})).To(ConsistOf(
+			HaveField("ObjectMeta.Name", "running"),
+		))
+	})
+
+	It("blockingPodDisruptionBudgets returns the budgets allowing no disruptions that select a pod", func() {
+		pods := []corev1.Pod{pod("etcd-guard", map[string]string{"app": "guard"})}
+
+		budget := func(namespace, name string, app string, disruptionsAllowed int32) policyv1.PodDisruptionBudget {
+			return policyv1.PodDisruptionBudget{
+				ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name},
+				Spec:       policyv1.PodDisruptionBudgetSpec{Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": app}}},
+				Status:     policyv1.PodDisruptionBudgetStatus{DisruptionsAllowed: disruptionsAllowed},
+			}
+		}
+
+		Expect(blockingPodDisruptionBudgets(pods, []policyv1.PodDisruptionBudget{
+			budget("openshift-etcd", "guard-pdb", "guard", 0),
+			budget("openshift-etcd", "allowing-pdb", "guard", 1),
+			budget("openshift-etcd", "other-app-pdb", "other", 0),
+			budget("other-namespace", "guard-pdb", "guard", 0),
+		})).To(Equal([]string{"openshift-etcd/guard-pdb"}))
+	})
+
+	Context("reconcileDrainProgress", func() {
+		var namespaceName string
+		var reconciler *ControlPlaneMachineSetReconciler
+		var recorder *record.FakeRecorder
+		var logger test.TestLogger
+		var cpms *machinev1.ControlPlaneMachineSet
+
+		var machine *machinev1beta1.Machine
+		var drainingMachineBuilder resourcebuilder.MachineInfoBuilder
+		var replacementMachineBuilder resourcebuilder.MachineInfoBuilder
+
+		var result ctrl.Result
+		var err error
+
+		deletedAt := func(ago time.Duration) metav1.Time {
+			return metav1.NewTime(time.Now().Add(-ago).Truncate(time.Second))
+		}
+
+		BeforeEach(func() {
+			By("Setting up a namespace for the test")
+			ns := resourcebuilder.Namespace().WithGenerateName("control-plane-machine-set-drain-progress-").Build()
+			Expect(k8sClient.Create(ctx, ns)).To(Succeed())
+			namespaceName = ns.GetName()
+
+			recorder = record.NewFakeRecorder(10)
+
+			reconciler = &ControlPlaneMachineSetReconciler{
+				Client:                k8sClient,
+				APIReader:             k8sClient,
+				Scheme:                testScheme,
+				RESTMapper:            testRESTMapper,
+				Recorder:              recorder,
+				Namespace:             namespaceName,
+				DrainTimeout:          30 * time.Minute,
+				DrainEscalationPolicy: DrainEscalationPolicyReport,
+			}
+
+			logger = test.NewTestLogger()
+			cpms = resourcebuilder.ControlPlaneMachineSet().WithNamespace(namespaceName).Build()
+
+			By("Creating the machine being replaced")
+			machine = resourcebuilder.Machine().WithNamespace(namespaceName).WithGenerateName("drain-progress-test-").Build()
+			Expect(k8sClient.Create(ctx, machine)).To(Succeed())
+
+			By("Creating the pods remaining on the node, and a budget blocking their eviction")
+			for _, name := range []string{"guard-0", "workload-0"} {
+				Expect(k8sClient.Create(ctx, &corev1.Pod{
+					ObjectMeta: metav1.ObjectMeta{Namespace: namespaceName, Name: name, Labels: map[string]string{"app": name}},
+					Spec: corev1.PodSpec{
+						NodeName:   nodeName,
+						Containers: []corev1.Container{{Name: "container", Image: "image"}},
+					},
+				})).To(Succeed())
+			}
+
+			minAvailable := intstr.FromInt(1)
+			Expect(k8sClient.Create(ctx, &policyv1.PodDisruptionBudget{
+				ObjectMeta: metav1.ObjectMeta{Namespace: namespaceName, Name: "guard-pdb"},
+				Spec: policyv1.PodDisruptionBudgetSpec{
+					MinAvailable: &minAvailable,
+					Selector:     &metav1.LabelSelector{MatchLabels: map[string]string{"app": "guard-0"}},
+				},
+			})).To(Succeed())
+
+			drainingMachineBuilder = resourcebuilder.MachineInfo().
+				WithIndex(1).
+				WithMachineGVR(machineGVR).
+				WithNodeGVR(nodeGVR).
+				WithMachineName(machine.GetName()).
+				WithMachineNamespace(namespaceName).
+				WithNodeName(nodeName).
+				WithMachineDeletionTimestamp(deletedAt(10 * time.Minute)).
+				WithNeedsUpdate(true)
+
+			replacementMachineBuilder = resourcebuilder.MachineInfo().
+				WithIndex(1).
+				WithMachineGVR(machineGVR).
+				WithMachineName("replacement-1").
+				WithMachineNamespace(namespaceName).
+				WithReady(true).
+				WithNeedsUpdate(false)
+		})
+
+		AfterEach(func() {
+			// Pods bound to a Node are only removed once the kubelet confirms they have stopped, so are forcibly
+			// removed without a grace period.
+			Expect(k8sClient.DeleteAllOf(ctx, &corev1.Pod{}, client.InNamespace(namespaceName), client.GracePeriodSeconds(0))).To(Succeed())
+
+			test.CleanupResources(Default, ctx, cfg, k8sClient, namespaceName,
+				&corev1.Pod{},
+				&policyv1.PodDisruptionBudget{},
+				&machinev1beta1.Machine{},
+			)
+		})
+
+		reconcileDrainProgress := func(drainingMachine machineproviders.MachineInfo) {
+			machineInfos := map[int32][]machineproviders.MachineInfo{
+				1: {drainingMachine, replacementMachineBuilder.Build()},
+			}
+
+			result, err = reconciler.reconcileDrainProgress(ctx, logger.Logger(), cpms, machineInfos)
+		}
+
+		Context("when the drain is within the timeout", func() {
+			var drainingMachine machineproviders.MachineInfo
+
+			BeforeEach(func() {
+				drainingMachine = drainingMachineBuilder.Build()
+				reconcileDrainProgress(drainingMachine)
+			})
+
+			It("should not return an error", func() {
+				Expect(err).ToNot(HaveOccurred())
+			})
+
+			It("should requeue to refresh the progress", func() {
+				Expect(result).To(Equal(ctrl.Result{RequeueAfter: drainProgressResyncPeriod}))
+			})
+
+			It("should report the remaining pods and blocking budgets", func() {
+				Expect(cpms.Status.Conditions).To(ConsistOf(test.MatchCondition(metav1.Condition{
+					Type:   conditionDrainProgress,
+					Status: metav1.ConditionTrue,
+					Reason: reasonDrainInProgress,
+					Message: "Draining 1 node(s): " + nodeName + " (2 pod(s) remaining, blocked by " + namespaceName + "/guard-pdb) since " +
+						drainingMachine.MachineRef.ObjectMeta.GetDeletionTimestamp().UTC().Format(time.RFC3339),
+				})))
+			})
+
+			It("should publish the progress as an event", func() {
+				Expect(recorder.Events).To(Receive(Equal("Normal DrainInProgress Draining node " + nodeName + " of machine " + machine.GetName() + ": 2 pod(s) remaining, blocked by " + namespaceName + "/guard-pdb")))
+			})
+
+			It("should not publish the progress again when it has not changed", func() {
+				Expect(recorder.Events).To(Receive())
+
+				reconcileDrainProgress(drainingMachine)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(recorder.Events).ToNot(Receive())
+			})
+		})
+
+		Context("when the drain has timed out", func() {
+			var drainingMachine machineproviders.MachineInfo
+
+			BeforeEach(func() {
+				drainingMachine = drainingMachineBuilder.WithMachineDeletionTimestamp(deletedAt(2 * time.Hour)).Build()
+			})
+
+			Context("with the Report policy", func() {
+				BeforeEach(func() {
+					reconcileDrainProgress(drainingMachine)
+				})
+
+				It("should report that the drain timed out", func() {
+					Expect(meta.FindStatusCondition(cpms.Status.Conditions, conditionDrainProgress)).To(SatisfyAll(
+						HaveField("Reason", reasonDrainTimedOut),
+						HaveField("Message", HaveSuffix(", timed out")),
+					))
+				})
+
+				It("should log that the drain timed out", func() {
+					Expect(logger.Entries()).To(ContainElement(test.LogEntry{
+						KeysAndValues: []interface{}{"index", int32(1), "machineName", machine.GetName(), "nodeName", nodeName, "remainingPods", 2, "blockingPDBs", namespaceName + "/guard-pdb"},
+						Level:         1,
+						Message:       drainTimedOut,
+					}))
+				})
+
+				It("should only publish the progress event", func() {
+					Expect(recorder.Events).To(Receive(HavePrefix("Normal DrainInProgress")))
+					Expect(recorder.Events).ToNot(Receive())
+				})
+			})
+
+			Context("with the Event policy", func() {
+				BeforeEach(func() {
+					reconciler.DrainEscalationPolicy = DrainEscalationPolicyEvent
+					reconcileDrainProgress(drainingMachine)
+				})
+
+				It("should publish a warning event", func() {
+					Expect(recorder.Events).To(Receive(HavePrefix("Normal DrainInProgress")))
+					Expect(recorder.Events).To(Receive(Equal("Warning DrainTimedOut Drain of node " + nodeName + " of machine " + machine.GetName() + " has not completed within 30m0s: 2 pod(s) remaining, blocked by " + namespaceName + "/guard-pdb")))
+				})
+
+				It("should not publish the warning event again once the drain has already timed out", func() {
+					Expect(recorder.Events).To(Receive())
+					Expect(recorder.Events).To(Receive())
+
+					reconcileDrainProgress(drainingMachine)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(recorder.Events).ToNot(Receive())
+				})
+
+				It("should not exclude the node from draining", func() {
+					Consistently(komega.Object(machine)).ShouldNot(HaveField("ObjectMeta.Annotations", HaveKey(excludeNodeDrainingAnnotation)))
+				})
+			})
+
+			Context("with the SkipDrain policy", func() {
+				BeforeEach(func() {
+					reconciler.DrainEscalationPolicy = DrainEscalationPolicySkipDrain
+				})
+
+				It("should exclude the node from draining when a ready replacement exists", func() {
+					reconcileDrainProgress(drainingMachine)
+					Expect(err).ToNot(HaveOccurred())
+
+					Eventually(komega.Object(machine)).Should(HaveField("ObjectMeta.Annotations", HaveKey(excludeNodeDrainingAnnotation)))
+					Expect(logger.Entries()).To(ContainElement(test.LogEntry{
+						KeysAndValues: []interface{}{"index", int32(1), "machineName", machine.GetName(), "nodeName", nodeName},
+						Level:         1,
+						Message:       skippedNodeDrain,
+					}))
+				})
+
+				It("should not exclude the node from draining without a ready replacement", func() {
+					replacementMachineBuilder = replacementMachineBuilder.WithReady(false)
+
+					reconcileDrainProgress(drainingMachine)
+					Expect(err).ToNot(HaveOccurred())
+
+					Consistently(komega.Object(machine)).ShouldNot(HaveField("ObjectMeta.Annotations", HaveKey(excludeNodeDrainingAnnotation)))
+				})
+			})
+		})
+
+		It("should remove the condition once no node is draining", func() {
+			reconcileDrainProgress(drainingMachineBuilder.Build())
+			Expect(err).ToNot(HaveOccurred())
+
+			result, err = reconciler.reconcileDrainProgress(ctx, logger.Logger(), cpms, map[int32][]machineproviders.MachineInfo{
+				1: {replacementMachineBuilder.Build()},
+			})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result).To(Equal(ctrl.Result{}))
+			Expect(meta.FindStatusCondition(cpms.Status.Conditions, conditionDrainProgress)).To(BeNil())
+		})
+
+		It("should not report drain progress without an API reader", func() {
+			reconciler.APIReader = nil
+
+			reconcileDrainProgress(drainingMachineBuilder.Build())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cpms.Status.Conditions).To(BeEmpty())
+		})
+	})
+})
@@ -0,0 +1,193 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controlplanemachineset
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+
+	machinev1 "github.com/openshift/api/machine/v1"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func init() {
+	registerInformationalCondition(conditionEtcdMembers)
+}
+
+const (
+	// etcdPeerPort is the port on which etcd members serve their peer URL.
+	etcdPeerPort = "2380"
+
+	// noEtcdMember is used within the etcd members condition in place of the etcd member of a Machine whose Node
+	// is not a member of etcd, for example while a new Node is still joining the cluster.
+	noEtcdMember = "no member"
+
+	// pendingEtcdMemberRemoval is used within the etcd members condition to mark the etcd member of a Machine that is
+	// being removed, or is due to be removed as its replacement is ready.
+	pendingEtcdMemberRemoval = "pending removal"
+)
+
+// reconcileEtcdMembers maps each index of the ControlPlaneMachineSet to the etcd members of its Machines, and
+// reflects the mapping within the etcd members condition.
+// When the etcd membership cannot be determined, the condition is removed.
+func (r *ControlPlaneMachineSetReconciler) reconcileEtcdMembers(ctx context.Context, cpms *machinev1.ControlPlaneMachineSet, machineInfos map[int32][]machineproviders.MachineInfo, membership etcdMembership) error {
+	if !membership.known {
+		meta.RemoveStatusCondition(&cpms.Status.Conditions, conditionEtcdMembers)
+		return nil
+	}
+
+	nodeList := &corev1.NodeList{}
+	if err := r.List(ctx, nodeList, client.HasLabels{masterNodeRoleLabel}); err != nil {
+		return fmt.Errorf("failed to list control plane nodes: %w", err)
+	}
+
+	setEtcdMembersCondition(cpms, machineInfos, nodeList.Items, membership.endpoints)
+
+	return nil
+}
+
+// setEtcdMembersCondition sets the etcd members condition to map each index to the etcd members of its Machines. The
+// message lists the Machines of each index, in index order, with the ID, name and peer URL of their etcd member, eg
+// `0=master-0 (8e9e05c52164694d ip-10-0-1-10 https://10.0.1.10:2380), 1=master-1 (no member)`.
+// The etcd member of a Machine is found by matching the addresses of its Node with the etcd endpoints, and is named
+// after the Node, as etcd members are. Members of Machines being deleted, or outdated Machines whose index has a
+// ready, up to date replacement, are marked as pending removal.
+// Any etcd member that does not belong to a Machine is listed as unmatched, and the condition is then false.
+// The condition is removed when there are no Machines.
+func setEtcdMembersCondition(cpms *machinev1.ControlPlaneMachineSet, machineInfos map[int32][]machineproviders.MachineInfo, nodes []corev1.Node, etcdEndpoints map[string]string) {
+	memberIDs := make(map[string]string, len(etcdEndpoints))
+	for memberID, address := range etcdEndpoints {
+		memberIDs[address] = memberID
+	}
+
+	nodesByName := make(map[string]*corev1.Node, len(nodes))
+	for i := range nodes {
+		nodesByName[nodes[i].GetName()] = &nodes[i]
+	}
+
+	matchedMembers := map[string]struct{}{}
+	indexMembers := []string{}
+
+	for _, idx := range sortedIndexes(machineInfos) {
+		indexMachineInfos := sortedByMachineName(machineInfos[idx])
+		members := []string{}
+
+		for _, machineInfo := range indexMachineInfos {
+			member := noEtcdMember
+
+			if memberID, address, ok := nodeEtcdMember(machineInfo, nodesByName, memberIDs); ok {
+				matchedMembers[memberID] = struct{}{}
+				member = fmt.Sprintf("%s %s https://%s", memberID, machineInfo.NodeRef.ObjectMeta.GetName(), net.JoinHostPort(address, etcdPeerPort))
+
+				if isPendingRemoval(machineInfo, indexMachineInfos) {
+					member = fmt.Sprintf("%s, %s", member, pendingEtcdMemberRemoval)
+				}
+			}
+
+			members = append(members, fmt.Sprintf("%s (%s)", machineInfo.MachineRef.ObjectMeta.GetName(), member))
+		}
+
+		if len(members) > 0 {
+			indexMembers = append(indexMembers, fmt.Sprintf("%d=%s", idx, strings.Join(members, " + ")))
+		}
+	}
+
+	if len(indexMembers) == 0 {
+		meta.RemoveStatusCondition(&cpms.Status.Conditions, conditionEtcdMembers)
+		return
+	}
+
+	condition := metav1.Condition{
+		Type:               conditionEtcdMembers,
+		Status:             metav1.ConditionTrue,
+		Reason:             reasonEtcdMembersMapped,
+		ObservedGeneration: cpms.GetGeneration(),
+		Message:            strings.Join(indexMembers, ", "),
+	}
+
+	if unmatched := unmatchedEtcdMembers(etcdEndpoints, matchedMembers); len(unmatched) > 0 {
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = reasonUnmatchedEtcdMembers
+		condition.Message = fmt.Sprintf("%s, unmatched=%s", condition.Message, strings.Join(unmatched, " + "))
+	}
+
+	meta.SetStatusCondition(&cpms.Status.Conditions, condition)
+}
+
+// nodeEtcdMember returns the ID and address of the etcd member running on the Node of the Machine.
+// It returns false when the Machine has no Node, or none of the addresses of the Node belong to an etcd member.
+func nodeEtcdMember(machineInfo machineproviders.MachineInfo, nodesByName map[string]*corev1.Node, memberIDs map[string]string) (string, string, bool) {
+	if machineInfo.NodeRef == nil {
+		return "", "", false
+	}
+
+	node, ok := nodesByName[machineInfo.NodeRef.ObjectMeta.GetName()]
+	if !ok {
+		return "", "", false
+	}
+
+	for _, address := range node.Status.Addresses {
+		if memberID, ok := memberIDs[address.Address]; ok {
+			return memberID, address.Address, true
+		}
+	}
+
+	return "", "", false
+}
+
+// isPendingRemoval determines whether the Machine is being removed, either as it is being deleted, or as it is
+// outdated and another Machine within the same index is ready and up to date.
+func isPendingRemoval(machineInfo machineproviders.MachineInfo, indexMachineInfos []machineproviders.MachineInfo) bool {
+	if machineInfo.MachineRef.ObjectMeta.GetDeletionTimestamp() != nil {
+		return true
+	}
+
+	if !machineInfo.NeedsUpdate {
+		return false
+	}
+
+	for _, other := range indexMachineInfos {
+		if other.Ready && !other.NeedsUpdate && other.MachineRef.ObjectMeta.GetDeletionTimestamp() == nil {
+			return true
+		}
+	}
+
+	return false
+}
+
+// unmatchedEtcdMembers returns the etcd members, as their ID and address, that do not belong to any Machine, ordered
+// by ID.
+func unmatchedEtcdMembers(etcdEndpoints map[string]string, matchedMembers map[string]struct{}) []string {
+	unmatched := []string{}
+
+	for memberID, address := range etcdEndpoints {
+		if _, ok := matchedMembers[memberID]; !ok {
+			unmatched = append(unmatched, fmt.Sprintf("%s (https://%s)", memberID, net.JoinHostPort(address, etcdPeerPort)))
+		}
+	}
+
+	sort.Strings(unmatched)
+
+	return unmatched
+}
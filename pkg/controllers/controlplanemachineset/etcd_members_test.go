@@ -0,0 +1,192 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controlplanemachineset
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	machinev1beta1 "github.com/openshift/api/machine/v1beta1"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/test"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/test/resourcebuilder"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("setEtcdMembersCondition", func() {
+	machineBuilder := resourcebuilder.MachineInfo().
+		WithMachineGVR(machinev1beta1.GroupVersion.WithResource("machines")).
+		WithNodeGVR(corev1.SchemeGroupVersion.WithResource("nodes")).
+		WithReady(true)
+
+	node := func(name string, addresses ...string) corev1.Node {
+		node := corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: name}}
+		for _, address := range addresses {
+			node.Status.Addresses = append(node.Status.Addresses, corev1.NodeAddress{Type: corev1.NodeInternalIP, Address: address})
+		}
+
+		return node
+	}
+
+	nodes := []corev1.Node{
+		node("master-0", "10.0.1.10"),
+		node("master-1", "10.0.2.20"),
+		node("master-2", "10.0.3.30"),
+		node("master-3", "10.0.2.40"),
+	}
+
+	threeMembers := map[string]string{
+		"8e9e05c52164694d": "10.0.1.10",
+		"91bc3c398fb3c146": "10.0.2.20",
+		"fd422379fda50e48": "10.0.3.30",
+	}
+
+	type etcdMembersTableInput struct {
+		machineInfos      map[int32][]machineproviders.MachineInfo
+		nodes             []corev1.Node
+		etcdEndpoints     map[string]string
+		existingCondition *metav1.Condition
+		expectedCondition *metav1.Condition
+	}
+
+	DescribeTable("should map each index to its etcd members", func(in etcdMembersTableInput) {
+		cpms := resourcebuilder.ControlPlaneMachineSet().WithGeneration(2).Build()
+		if in.existingCondition != nil {
+			cpms.Status.Conditions = []metav1.Condition{*in.existingCondition}
+		}
+
+		setEtcdMembersCondition(cpms, in.machineInfos, in.nodes, in.etcdEndpoints)
+
+		if in.expectedCondition == nil {
+			Expect(cpms.Status.Conditions).To(BeEmpty())
+		} else {
+			Expect(cpms.Status.Conditions).To(test.MatchConditions([]metav1.Condition{*in.expectedCondition}))
+		}
+	},
+		Entry("with an etcd member for every Machine", etcdMembersTableInput{
+			machineInfos: map[int32][]machineproviders.MachineInfo{
+				0: {machineBuilder.WithIndex(0).WithMachineName("machine-0").WithNodeName("master-0").Build()},
+				1: {machineBuilder.WithIndex(1).WithMachineName("machine-1").WithNodeName("master-1").Build()},
+				2: {machineBuilder.WithIndex(2).WithMachineName("machine-2").WithNodeName("master-2").Build()},
+			},
+			nodes:         nodes,
+			etcdEndpoints: threeMembers,
+			expectedCondition: &metav1.Condition{
+				Type:               conditionEtcdMembers,
+				Status:             metav1.ConditionTrue,
+				Reason:             reasonEtcdMembersMapped,
+				ObservedGeneration: 2,
+				Message: "0=machine-0 (8e9e05c52164694d master-0 https://10.0.1.10:2380), " +
+					"1=machine-1 (91bc3c398fb3c146 master-1 https://10.0.2.20:2380), " +
+					"2=machine-2 (fd422379fda50e48 master-2 https://10.0.3.30:2380)",
+			},
+		}),
+		Entry("with a replacement Machine that has not yet joined etcd", etcdMembersTableInput{
+			machineInfos: map[int32][]machineproviders.MachineInfo{
+				0: {machineBuilder.WithIndex(0).WithMachineName("machine-0").WithNodeName("master-0").Build()},
+				1: {
+					machineBuilder.WithIndex(1).WithMachineName("machine-1").WithNodeName("master-1").WithNeedsUpdate(true).Build(),
+					machineBuilder.WithIndex(1).WithMachineName("machine-4").WithNodeName("master-3").WithReady(false).Build(),
+				},
+				2: {machineBuilder.WithIndex(2).WithMachineName("machine-2").WithNodeName("master-2").Build()},
+			},
+			nodes:         nodes,
+			etcdEndpoints: threeMembers,
+			expectedCondition: &metav1.Condition{
+				Type:               conditionEtcdMembers,
+				Status:             metav1.ConditionTrue,
+				Reason:             reasonEtcdMembersMapped,
+				ObservedGeneration: 2,
+				Message: "0=machine-0 (8e9e05c52164694d master-0 https://10.0.1.10:2380), " +
+					"1=machine-1 (91bc3c398fb3c146 master-1 https://10.0.2.20:2380) + machine-4 (no member), " +
+					"2=machine-2 (fd422379fda50e48 master-2 https://10.0.3.30:2380)",
+			},
+		}),
+		Entry("with an outdated Machine whose replacement is ready", etcdMembersTableInput{
+			machineInfos: map[int32][]machineproviders.MachineInfo{
+				1: {
+					machineBuilder.WithIndex(1).WithMachineName("machine-1").WithNodeName("master-1").WithNeedsUpdate(true).Build(),
+					machineBuilder.WithIndex(1).WithMachineName("machine-4").WithNodeName("master-3").Build(),
+				},
+			},
+			nodes:         nodes,
+			etcdEndpoints: map[string]string{"91bc3c398fb3c146": "10.0.2.20", "2d2b69cb7d32cf3e": "10.0.2.40"},
+			expectedCondition: &metav1.Condition{
+				Type:               conditionEtcdMembers,
+				Status:             metav1.ConditionTrue,
+				Reason:             reasonEtcdMembersMapped,
+				ObservedGeneration: 2,
+				Message: "1=machine-1 (91bc3c398fb3c146 master-1 https://10.0.2.20:2380, pending removal) + " +
+					"machine-4 (2d2b69cb7d32cf3e master-3 https://10.0.2.40:2380)",
+			},
+		}),
+		Entry("with a Machine being deleted", etcdMembersTableInput{
+			machineInfos: map[int32][]machineproviders.MachineInfo{
+				0: {machineBuilder.WithIndex(0).WithMachineName("machine-0").WithNodeName("master-0").WithMachineDeletionTimestamp(metav1.Now()).Build()},
+			},
+			nodes:         nodes,
+			etcdEndpoints: map[string]string{"8e9e05c52164694d": "10.0.1.10"},
+			expectedCondition: &metav1.Condition{
+				Type:               conditionEtcdMembers,
+				Status:             metav1.ConditionTrue,
+				Reason:             reasonEtcdMembersMapped,
+				ObservedGeneration: 2,
+				Message:            "0=machine-0 (8e9e05c52164694d master-0 https://10.0.1.10:2380, pending removal)",
+			},
+		}),
+		Entry("with an etcd member that does not belong to any Machine", etcdMembersTableInput{
+			machineInfos: map[int32][]machineproviders.MachineInfo{
+				0: {machineBuilder.WithIndex(0).WithMachineName("machine-0").WithNodeName("master-0").Build()},
+			},
+			nodes:         nodes,
+			etcdEndpoints: map[string]string{"8e9e05c52164694d": "10.0.1.10", "3d5a7e0c1b2f4a6d": "10.0.9.90"},
+			expectedCondition: &metav1.Condition{
+				Type:               conditionEtcdMembers,
+				Status:             metav1.ConditionFalse,
+				Reason:             reasonUnmatchedEtcdMembers,
+				ObservedGeneration: 2,
+				Message:            "0=machine-0 (8e9e05c52164694d master-0 https://10.0.1.10:2380), unmatched=3d5a7e0c1b2f4a6d (https://10.0.9.90:2380)",
+			},
+		}),
+		Entry("with an IPv6 etcd member", etcdMembersTableInput{
+			machineInfos: map[int32][]machineproviders.MachineInfo{
+				0: {machineBuilder.WithIndex(0).WithMachineName("machine-0").WithNodeName("master-0").Build()},
+			},
+			nodes:         []corev1.Node{node("master-0", "fd00::10")},
+			etcdEndpoints: map[string]string{"8e9e05c52164694d": "fd00::10"},
+			expectedCondition: &metav1.Condition{
+				Type:               conditionEtcdMembers,
+				Status:             metav1.ConditionTrue,
+				Reason:             reasonEtcdMembersMapped,
+				ObservedGeneration: 2,
+				Message:            "0=machine-0 (8e9e05c52164694d master-0 https://[fd00::10]:2380)",
+			},
+		}),
+		Entry("with no Machines", etcdMembersTableInput{
+			machineInfos:  map[int32][]machineproviders.MachineInfo{},
+			nodes:         nodes,
+			etcdEndpoints: threeMembers,
+			existingCondition: &metav1.Condition{
+				Type:   conditionEtcdMembers,
+				Status: metav1.ConditionTrue,
+				Reason: reasonEtcdMembersMapped,
+			},
+			expectedCondition: nil,
+		}),
+	)
+})
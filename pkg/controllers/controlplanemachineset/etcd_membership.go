@@ -0,0 +1,100 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controlplanemachineset
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// etcdNamespace is the namespace in which the etcd operator publishes the etcd membership.
+	etcdNamespace = "openshift-etcd"
+
+	// etcdEndpointsConfigMapName is the name of the ConfigMap in which the etcd operator publishes the etcd
+	// membership. Each key within the data of the ConfigMap is the ID of an etcd member, and the value is the IP
+	// address of the member.
+	etcdEndpointsConfigMapName = "etcd-endpoints"
+
+	// observedInconsistentControlPlane is a log message used to inform the user that the etcd membership does not
+	// match the Control Plane Machines and Nodes.
+	observedInconsistentControlPlane = "Observed more etcd members than control plane machines and nodes"
+)
+
+// errInconsistentControlPlane is used to inform users that there are more etcd members than Control Plane Machines
+// with Nodes, or Control Plane Nodes. In this case, the view of the Control Plane from the Machine API cannot be
+// trusted, and acting upon it could remove an etcd member that is still required for quorum.
+var errInconsistentControlPlane = errors.New("found more etcd members than control plane machines and nodes")
+
+// etcdMembership is the etcd membership published by the etcd operator.
+type etcdMembership struct {
+	// endpoints holds the members of etcd, keyed by the ID of each member, with the IP address of the member as the
+	// value.
+	endpoints map[string]string
+
+	// known is false when the membership cannot be determined, for example when the etcd operator is not installed.
+	known bool
+}
+
+// etcdMembership reads the etcd membership published by the etcd operator.
+// The etcd membership lives outside of the namespace of the ControlPlaneMachineSet, so it is read directly from the
+// API rather than from the cache of the manager. It is read once per reconcile, and the same membership is used by
+// every step of the reconcile that compares it with the Control Plane Machines and Nodes.
+func (r *ControlPlaneMachineSetReconciler) etcdMembership(ctx context.Context) (etcdMembership, error) {
+	if r.APIReader == nil {
+		return etcdMembership{}, nil
+	}
+
+	configMap := &corev1.ConfigMap{}
+	configMapKey := client.ObjectKey{Namespace: etcdNamespace, Name: etcdEndpointsConfigMapName}
+
+	if err := r.APIReader.Get(ctx, configMapKey, configMap); apierrors.IsNotFound(err) {
+		return etcdMembership{}, nil
+	} else if err != nil {
+		return etcdMembership{}, fmt.Errorf("could not fetch etcd endpoints: %w", err)
+	}
+
+	return etcdMembership{endpoints: configMap.Data, known: true}, nil
+}
+
+// controlPlaneInconsistency compares the etcd membership with the Control Plane Machines and Nodes.
+// There should never be more etcd members than Control Plane Machines with a Node, nor than Control Plane Nodes.
+// Fewer etcd members is expected while a new Node joins the cluster, before it is added as a member of etcd.
+// When the membership is inconsistent, a message describing the inconsistency is returned.
+func controlPlaneInconsistency(etcdMembers int, nodes []corev1.Node, machineInfos map[int32][]machineproviders.MachineInfo) (string, bool) {
+	machinesWithNodes := 0
+
+	for _, indexMachineInfos := range machineInfos {
+		for _, machineInfo := range indexMachineInfos {
+			if machineInfo.MachineRef != nil && machineInfo.NodeRef != nil {
+				machinesWithNodes++
+			}
+		}
+	}
+
+	if etcdMembers <= machinesWithNodes && etcdMembers <= len(nodes) {
+		return "", false
+	}
+
+	return fmt.Sprintf("Found %d etcd member(s), but %d control plane machine(s) with a node and %d control plane node(s)", etcdMembers, machinesWithNodes, len(nodes)), true
+}
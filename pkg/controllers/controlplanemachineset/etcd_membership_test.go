@@ -0,0 +1,125 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controlplanemachineset
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	machinev1beta1 "github.com/openshift/api/machine/v1beta1"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/test/resourcebuilder"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("etcdMembership", func() {
+	var reconciler *ControlPlaneMachineSetReconciler
+
+	BeforeEach(func() {
+		reconciler = &ControlPlaneMachineSetReconciler{
+			Client:    k8sClient,
+			APIReader: k8sClient,
+		}
+	})
+
+	It("is not known when the etcd endpoints do not exist", func() {
+		Expect(reconciler.etcdMembership(ctx)).To(Equal(etcdMembership{}))
+	})
+
+	It("is not known without an API reader", func() {
+		reconciler.APIReader = nil
+
+		Expect(reconciler.etcdMembership(ctx)).To(Equal(etcdMembership{}))
+	})
+
+	Context("when the etcd endpoints exist", func() {
+		BeforeEach(func() {
+			ns := resourcebuilder.Namespace().WithName(etcdNamespace).Build()
+			if err := k8sClient.Create(ctx, ns); !apierrors.IsAlreadyExists(err) {
+				Expect(err).ToNot(HaveOccurred())
+			}
+
+			configMap := &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: etcdEndpointsConfigMapName, Namespace: etcdNamespace},
+				Data:       map[string]string{"member-0": "10.0.0.10", "member-1": "10.0.0.11"},
+			}
+			Expect(k8sClient.Create(ctx, configMap)).To(Succeed())
+
+			DeferCleanup(func() {
+				Expect(k8sClient.Delete(ctx, configMap)).To(Succeed())
+			})
+		})
+
+		It("returns the members of etcd", func() {
+			Expect(reconciler.etcdMembership(ctx)).To(Equal(etcdMembership{
+				endpoints: map[string]string{"member-0": "10.0.0.10", "member-1": "10.0.0.11"},
+				known:     true,
+			}))
+		})
+	})
+})
+
+var _ = Describe("controlPlaneInconsistency", func() {
+	machineGVR := machinev1beta1.GroupVersion.WithResource("machines")
+	nodeGVR := corev1.SchemeGroupVersion.WithResource("nodes")
+
+	machineBuilder := resourcebuilder.MachineInfo().
+		WithMachineGVR(machineGVR).
+		WithNodeGVR(nodeGVR).
+		WithReady(true)
+
+	nodes := func(count int) []corev1.Node {
+		nodes := []corev1.Node{}
+		for i := 0; i < count; i++ {
+			nodes = append(nodes, corev1.Node{})
+		}
+
+		return nodes
+	}
+
+	threeMachines := map[int32][]machineproviders.MachineInfo{
+		0: {machineBuilder.WithIndex(0).WithMachineName("machine-0").WithNodeName("master-0").Build()},
+		1: {machineBuilder.WithIndex(1).WithMachineName("machine-1").WithNodeName("master-1").Build()},
+		2: {machineBuilder.WithIndex(2).WithMachineName("machine-2").WithNodeName("master-2").Build()},
+	}
+
+	surgingMachines := map[int32][]machineproviders.MachineInfo{
+		0: {
+			machineBuilder.WithIndex(0).WithMachineName("machine-0").WithNodeName("master-0").WithNeedsUpdate(true).Build(),
+			machineBuilder.WithIndex(0).WithMachineName("machine-replacement-0").WithNodeName("master-3").Build(),
+		},
+		1: {machineBuilder.WithIndex(1).WithMachineName("machine-1").WithNodeName("master-1").Build()},
+		2: {machineBuilder.WithIndex(2).WithMachineName("machine-2").WithNodeName("master-2").Build()},
+	}
+
+	DescribeTable("should compare the etcd membership with the machines and nodes", func(etcdMembers int, nodes []corev1.Node, machineInfos map[int32][]machineproviders.MachineInfo, expectedMessage string) {
+		message, inconsistent := controlPlaneInconsistency(etcdMembers, nodes, machineInfos)
+
+		Expect(inconsistent).To(Equal(expectedMessage != ""))
+		Expect(message).To(Equal(expectedMessage))
+	},
+		Entry("with a member for each machine and node", 3, nodes(3), threeMachines, ""),
+		Entry("with a new node not yet added as a member", 3, nodes(4), surgingMachines, ""),
+		Entry("with a new member during a surge", 4, nodes(4), surgingMachines, ""),
+		Entry("with more members than machines", 4, nodes(4), threeMachines,
+			"Found 4 etcd member(s), but 3 control plane machine(s) with a node and 4 control plane node(s)"),
+		Entry("with more members than nodes", 4, nodes(3), surgingMachines,
+			"Found 4 etcd member(s), but 4 control plane machine(s) with a node and 3 control plane node(s)"),
+	)
+})
@@ -0,0 +1,153 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controlplanemachineset
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/go-logr/logr"
+	machinev1 "github.com/openshift/api/machine/v1"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func init() {
+	registerInformationalCondition(conditionFailedMachines)
+}
+
+const (
+	// failedMachinesReasonLabel is the label of the failed machines metric that holds the error reason of the
+	// failed Machines.
+	failedMachinesReasonLabel = "reason"
+
+	// unknownFailureReason is the error reason reported for failed Machines that have an error message, but no
+	// error reason, within their status.
+	unknownFailureReason = "Unknown"
+
+	// observedFailedMachine is a log message used to inform the user that a Control Plane Machine has failed.
+	observedFailedMachine = "Observed failed control plane machine"
+)
+
+// failedMachine describes a Control Plane Machine that reports an error within its status.
+type failedMachine struct {
+	// machineName is the name of the Machine.
+	machineName string
+
+	// reason is the error reason of the Machine, for example InsufficientResources.
+	reason string
+
+	// message is the error message of the Machine, as reported by the cloud provider.
+	message string
+}
+
+// reconcileFailedMachines reports, within the FailedMachines condition, the Control Plane Machines that the Machine
+// API has marked as failed, along with the error reason and error message from their status. When a replacement
+// fails, for example because the cloud provider has no capacity for the instance type, the cause is otherwise only
+// visible on the Machine itself.
+// A warning event is published whenever the reported failures change, and the number of failed Machines is exposed as
+// a metric for each error reason.
+func (r *ControlPlaneMachineSetReconciler) reconcileFailedMachines(logger logr.Logger, cpms *machinev1.ControlPlaneMachineSet, machineInfos map[int32][]machineproviders.MachineInfo) {
+	failed := []failedMachine{}
+
+	for _, idx := range sortedIndexes(machineInfos) {
+		for _, machineInfo := range machineInfos[idx] {
+			if machineInfo.MachineRef == nil || machineInfo.MachineRef.ObjectMeta.GetDeletionTimestamp() != nil {
+				continue
+			}
+
+			if machineInfo.ErrorReason == "" && machineInfo.ErrorMessage == "" {
+				continue
+			}
+
+			f := failedMachine{
+				machineName: machineInfo.MachineRef.ObjectMeta.GetName(),
+				reason:      machineInfo.ErrorReason,
+				message:     machineInfo.ErrorMessage,
+			}
+
+			logger.V(1).Info(observedFailedMachine,
+				"index", machineInfo.Index,
+				"machineName", f.machineName,
+				"errorReason", f.reason,
+				"errorMessage", f.message,
+			)
+
+			failed = append(failed, f)
+		}
+	}
+
+	r.setFailedMachinesCondition(cpms, failed)
+}
+
+// summary returns the error reason and error message of the failed Machine, eg
+// `master-3 (InsufficientResources: insufficient capacity)`.
+func (f failedMachine) summary() string {
+	details := []string{}
+
+	if f.reason != "" {
+		details = append(details, f.reason)
+	}
+
+	if f.message != "" {
+		details = append(details, f.message)
+	}
+
+	return fmt.Sprintf("%s (%s)", f.machineName, strings.Join(details, ": "))
+}
+
+// setFailedMachinesCondition reports the failed Control Plane Machines, and updates the metric of their number for
+// each error reason. A warning event is published whenever the reported failures change. The condition is removed
+// when no Machine has failed.
+func (r *ControlPlaneMachineSetReconciler) setFailedMachinesCondition(cpms *machinev1.ControlPlaneMachineSet, failed []failedMachine) {
+	failedMachines.Reset()
+
+	summaries := []string{}
+
+	for _, f := range failed {
+		reason := f.reason
+		if reason == "" {
+			reason = unknownFailureReason
+		}
+
+		failedMachines.WithLabelValues(reason).Inc()
+
+		summaries = append(summaries, f.summary())
+	}
+
+	if len(failed) == 0 {
+		meta.RemoveStatusCondition(&cpms.Status.Conditions, conditionFailedMachines)
+
+		return
+	}
+
+	message := fmt.Sprintf("%d machine(s) have failed: %s", len(failed), strings.Join(summaries, "; "))
+
+	if previous := meta.FindStatusCondition(cpms.Status.Conditions, conditionFailedMachines); previous == nil || previous.Message != message {
+		r.publishEvent(cpms, corev1.EventTypeWarning, reasonMachinesFailed, message)
+	}
+
+	meta.SetStatusCondition(&cpms.Status.Conditions, metav1.Condition{
+		Type:               conditionFailedMachines,
+		Status:             metav1.ConditionTrue,
+		Reason:             reasonMachinesFailed,
+		ObservedGeneration: cpms.GetGeneration(),
+		Message:            message,
+	})
+}
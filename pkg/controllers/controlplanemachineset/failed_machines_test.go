@@ -0,0 +1,118 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controlplanemachineset
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	machinev1 "github.com/openshift/api/machine/v1"
+	machinev1beta1 "github.com/openshift/api/machine/v1beta1"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/test"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/test/resourcebuilder"
+	dto "github.com/prometheus/client_model/go"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+var _ = Describe("Failed machines", func() {
+	var reconciler *ControlPlaneMachineSetReconciler
+	var recorder *record.FakeRecorder
+	var logger test.TestLogger
+	var cpms *machinev1.ControlPlaneMachineSet
+
+	machineInfoBuilder := resourcebuilder.MachineInfo().WithMachineGVR(machinev1beta1.GroupVersion.WithResource("machines"))
+
+	failedMachineInfos := map[int32][]machineproviders.MachineInfo{
+		0: {machineInfoBuilder.WithIndex(0).WithMachineName("master-0").WithReady(true).Build()},
+		1: {
+			machineInfoBuilder.WithIndex(1).WithMachineName("master-1").WithErrorReason("InsufficientResources").
+				WithErrorMessage("error launching instance: InsufficientInstanceCapacity").Build(),
+			machineInfoBuilder.WithIndex(1).WithMachineName("master-3").WithMachineDeletionTimestamp(metav1.Now()).
+				WithErrorReason("InsufficientResources").WithErrorMessage("error launching instance: InsufficientInstanceCapacity").Build(),
+		},
+		2: {machineInfoBuilder.WithIndex(2).WithMachineName("master-2").WithErrorMessage("instance terminated").Build()},
+	}
+
+	expectedMessage := "2 machine(s) have failed: master-1 (InsufficientResources: error launching instance: InsufficientInstanceCapacity); master-2 (instance terminated)"
+
+	gaugeValue := func(reason string) float64 {
+		metric := &dto.Metric{}
+		Expect(failedMachines.WithLabelValues(reason).Write(metric)).To(Succeed())
+
+		return metric.GetGauge().GetValue()
+	}
+
+	BeforeEach(func() {
+		recorder = record.NewFakeRecorder(10)
+		reconciler = &ControlPlaneMachineSetReconciler{Recorder: recorder}
+		logger = test.NewTestLogger()
+		cpms = resourcebuilder.ControlPlaneMachineSet().Build()
+
+		reconciler.reconcileFailedMachines(logger.Logger(), cpms, failedMachineInfos)
+	})
+
+	It("sets the condition with the error of each failed machine that is not pending deletion", func() {
+		Expect(cpms.Status.Conditions).To(ContainElement(test.MatchCondition(metav1.Condition{
+			Type:    conditionFailedMachines,
+			Status:  metav1.ConditionTrue,
+			Reason:  reasonMachinesFailed,
+			Message: expectedMessage,
+		})))
+	})
+
+	It("sets the metric for each error reason", func() {
+		Expect(gaugeValue("InsufficientResources")).To(Equal(float64(1)))
+		Expect(gaugeValue(unknownFailureReason)).To(Equal(float64(1)))
+	})
+
+	It("publishes a warning event", func() {
+		Expect(recorder.Events).To(Receive(Equal("Warning MachinesFailed " + expectedMessage)))
+	})
+
+	It("does not publish another event when the failures are unchanged", func() {
+		Expect(recorder.Events).To(Receive())
+
+		reconciler.reconcileFailedMachines(logger.Logger(), cpms, failedMachineInfos)
+
+		Expect(recorder.Events).ToNot(Receive())
+	})
+
+	It("logs each failed machine", func() {
+		Expect(logger.Entries()).To(ContainElement(test.LogEntry{
+			Level: 1,
+			KeysAndValues: []interface{}{
+				"index", int32(1),
+				"machineName", "master-1",
+				"errorReason", "InsufficientResources",
+				"errorMessage", "error launching instance: InsufficientInstanceCapacity",
+			},
+			Message: observedFailedMachine,
+		}))
+	})
+
+	It("removes the condition and resets the metric once no machine has failed", func() {
+		reconciler.reconcileFailedMachines(logger.Logger(), cpms, map[int32][]machineproviders.MachineInfo{
+			0: {machineInfoBuilder.WithIndex(0).WithMachineName("master-0").WithReady(true).Build()},
+		})
+
+		Expect(meta.FindStatusCondition(cpms.Status.Conditions, conditionFailedMachines)).To(BeNil())
+		Expect(gaugeValue("InsufficientResources")).To(BeZero())
+	})
+})
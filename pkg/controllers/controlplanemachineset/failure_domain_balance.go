@@ -0,0 +1,83 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controlplanemachineset
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/go-logr/logr"
+	machinev1 "github.com/openshift/api/machine/v1"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func init() {
+	registerInformationalCondition(conditionFailureDomainBalance)
+}
+
+const (
+	// observedMisplacedMachine is a log message used to inform the user that a Control Plane Machine unbalances the
+	// spread of the failure domains.
+	observedMisplacedMachine = "Observed machine outside the failure domain of its index"
+)
+
+// setFailureDomainBalanceCondition reports the Control Plane Machines that unbalance the spread of the failure
+// domains, as determined by the machine provider. When every such Machine needs an update, rebalancing is enabled and
+// the Machines are replaced according to the update strategy. Otherwise, the imbalance is only reported.
+// Machines pending deletion are ignored, as they are about to be removed.
+// The condition is removed once the Machines are balanced across the failure domains.
+func setFailureDomainBalanceCondition(logger logr.Logger, cpms *machinev1.ControlPlaneMachineSet, machineInfos map[int32][]machineproviders.MachineInfo) {
+	misplaced := []string{}
+	rebalancing := true
+
+	for _, idx := range sortedIndexes(machineInfos) {
+		for _, machineInfo := range machineInfos[idx] {
+			if machineInfo.MachineRef == nil || machineInfo.MachineRef.ObjectMeta.GetDeletionTimestamp() != nil || !machineInfo.MisplacedFailureDomain {
+				continue
+			}
+
+			machineName := machineInfo.MachineRef.ObjectMeta.GetName()
+			logger.Info(observedMisplacedMachine, "machineName", machineName, "index", idx)
+
+			misplaced = append(misplaced, fmt.Sprintf("%s (index %d)", machineName, idx))
+			rebalancing = rebalancing && machineInfo.NeedsUpdate
+		}
+	}
+
+	if len(misplaced) == 0 {
+		meta.RemoveStatusCondition(&cpms.Status.Conditions, conditionFailureDomainBalance)
+
+		return
+	}
+
+	condition := metav1.Condition{
+		Type:               conditionFailureDomainBalance,
+		Status:             metav1.ConditionFalse,
+		Reason:             reasonFailureDomainsUnbalanced,
+		ObservedGeneration: cpms.GetGeneration(),
+		Message:            fmt.Sprintf("Found %d machine(s) outside the failure domain of their index: %s", len(misplaced), strings.Join(misplaced, "; ")),
+	}
+
+	if rebalancing {
+		condition.Reason = reasonRebalancingFailureDomains
+		condition.Message = fmt.Sprintf("Rebalancing %d machine(s) into the failure domain of their index: %s", len(misplaced), strings.Join(misplaced, "; "))
+	}
+
+	meta.SetStatusCondition(&cpms.Status.Conditions, condition)
+}
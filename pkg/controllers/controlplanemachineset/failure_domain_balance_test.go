@@ -0,0 +1,107 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controlplanemachineset
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	machinev1 "github.com/openshift/api/machine/v1"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/test"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/test/resourcebuilder"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("FailureDomainBalance condition", func() {
+	machineBuilder := resourcebuilder.MachineInfo().WithReady(true).WithNodeName("node")
+
+	var logger test.TestLogger
+	var cpms *machinev1.ControlPlaneMachineSet
+
+	BeforeEach(func() {
+		logger = test.NewTestLogger()
+		cpms = resourcebuilder.ControlPlaneMachineSet().WithReplicas(3).Build()
+	})
+
+	It("reports the misplaced machines when rebalancing is not enabled", func() {
+		machineInfos := map[int32][]machineproviders.MachineInfo{
+			0: {machineBuilder.WithIndex(0).WithMachineName("machine-0").Build()},
+			1: {machineBuilder.WithIndex(1).WithMachineName("machine-1").Build()},
+			2: {machineBuilder.WithIndex(2).WithMachineName("machine-2").WithMisplacedFailureDomain(true).Build()},
+		}
+
+		setFailureDomainBalanceCondition(logger.Logger(), cpms, machineInfos)
+
+		Expect(cpms.Status.Conditions).To(ConsistOf(test.MatchCondition(metav1.Condition{
+			Type:    conditionFailureDomainBalance,
+			Status:  metav1.ConditionFalse,
+			Reason:  reasonFailureDomainsUnbalanced,
+			Message: "Found 1 machine(s) outside the failure domain of their index: machine-2 (index 2)",
+		})))
+
+		Expect(logger.Entries()).To(ConsistOf(test.LogEntry{
+			Level:         0,
+			KeysAndValues: []interface{}{"machineName", "machine-2", "index", int32(2)},
+			Message:       observedMisplacedMachine,
+		}))
+	})
+
+	It("reports the machines being rebalanced when they need an update", func() {
+		machineInfos := map[int32][]machineproviders.MachineInfo{
+			0: {machineBuilder.WithIndex(0).WithMachineName("machine-0").Build()},
+			1: {machineBuilder.WithIndex(1).WithMachineName("machine-1").WithMisplacedFailureDomain(true).WithNeedsUpdate(true).Build()},
+			2: {machineBuilder.WithIndex(2).WithMachineName("machine-2").WithMisplacedFailureDomain(true).WithNeedsUpdate(true).Build()},
+		}
+
+		setFailureDomainBalanceCondition(logger.Logger(), cpms, machineInfos)
+
+		Expect(cpms.Status.Conditions).To(ConsistOf(test.MatchCondition(metav1.Condition{
+			Type:    conditionFailureDomainBalance,
+			Status:  metav1.ConditionFalse,
+			Reason:  reasonRebalancingFailureDomains,
+			Message: "Rebalancing 2 machine(s) into the failure domain of their index: machine-1 (index 1); machine-2 (index 2)",
+		})))
+	})
+
+	It("ignores misplaced machines that are pending deletion", func() {
+		machineInfos := map[int32][]machineproviders.MachineInfo{
+			0: {machineBuilder.WithIndex(0).WithMachineName("machine-0").WithMisplacedFailureDomain(true).WithMachineDeletionTimestamp(metav1.Now()).Build()},
+		}
+
+		setFailureDomainBalanceCondition(logger.Logger(), cpms, machineInfos)
+
+		Expect(cpms.Status.Conditions).To(BeEmpty())
+	})
+
+	It("removes the condition once the machines are balanced", func() {
+		meta.SetStatusCondition(&cpms.Status.Conditions, metav1.Condition{
+			Type:   conditionFailureDomainBalance,
+			Status: metav1.ConditionFalse,
+			Reason: reasonFailureDomainsUnbalanced,
+		})
+
+		machineInfos := map[int32][]machineproviders.MachineInfo{
+			0: {machineBuilder.WithIndex(0).WithMachineName("machine-0").Build()},
+		}
+
+		setFailureDomainBalanceCondition(logger.Logger(), cpms, machineInfos)
+
+		Expect(cpms.Status.Conditions).To(BeEmpty())
+	})
+})
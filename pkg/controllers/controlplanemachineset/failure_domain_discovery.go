@@ -0,0 +1,241 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controlplanemachineset
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/go-logr/logr"
+	configv1 "github.com/openshift/api/config/v1"
+	machinev1 "github.com/openshift/api/machine/v1"
+	machinev1beta1 "github.com/openshift/api/machine/v1beta1"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders/providers/openshift/machine/v1beta1/failuredomain"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders/providers/openshift/machine/v1beta1/providerconfig"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func init() {
+	registerInformationalCondition(conditionFailureDomainDiscovery)
+}
+
+const (
+	// discoverFailureDomainsAnnotation is the annotation on the ControlPlaneMachineSet used to opt in to the discovery
+	// of failure domains. When set to `true`, availability zones that the worker MachineSets are placed in, but that
+	// are not within the failure domains of the ControlPlaneMachineSet, are appended to its failure domains.
+	discoverFailureDomainsAnnotation = "controlplanemachineset.machine.openshift.io/discover-failure-domains"
+
+	// observedDiscoveredFailureDomain is a log message used to inform the user that a failure domain has been
+	// discovered from a MachineSet.
+	observedDiscoveredFailureDomain = "Discovered failure domain from machine set"
+
+	// observedAmbiguousFailureDomain is a log message used to inform the user that a failure domain could not be
+	// discovered because the MachineSets within its availability zone do not agree on its subnet.
+	observedAmbiguousFailureDomain = "Ignoring availability zone with machine sets in different subnets"
+)
+
+// errInvalidDiscoverFailureDomains is used to denote that the discover failure domains annotation is not a boolean.
+var errInvalidDiscoverFailureDomains = machineproviders.NewConfigurationError(machineproviders.ReasonInvalidFailureDomains, fmt.Sprintf("invalid value for annotation %s: expected true or false", discoverFailureDomainsAnnotation))
+
+// discoveredFailureDomain is an AWS failure domain discovered from a MachineSet.
+type discoveredFailureDomain struct {
+	machineSetName string
+	failureDomain  machinev1.AWSFailureDomain
+}
+
+// parseDiscoverFailureDomains determines whether the discovery of failure domains is enabled by the annotations of
+// the ControlPlaneMachineSet. Discovery is disabled when the annotation is not present.
+func parseDiscoverFailureDomains(annotations map[string]string) (bool, error) {
+	value, ok := annotations[discoverFailureDomainsAnnotation]
+	if !ok {
+		return false, nil
+	}
+
+	discover, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("%w, got %q", errInvalidDiscoverFailureDomains, value)
+	}
+
+	return discover, nil
+}
+
+// discoverFailureDomains returns the ControlPlaneMachineSet that the machine provider should be constructed from once
+// any discovered failure domains have been appended to the failure domains of the resolved ControlPlaneMachineSet.
+// The operator cannot query the cloud for new subnets, so the worker MachineSets are used as the source of truth for
+// the subnets of the cluster: an availability zone that a MachineSet is placed in, but that is not within the AWS
+// failure domains, is discovered along with the subnet of the MachineSet.
+// Discovery only extends existing AWS failure domains, so that a ControlPlaneMachineSet without failure domains is
+// never spread across availability zones. The spec of the ControlPlaneMachineSet itself is never modified, the
+// discovered failure domains are instead reported by the FailureDomainDiscovery condition, and by an event whenever
+// they change.
+func (r *ControlPlaneMachineSetReconciler) discoverFailureDomains(ctx context.Context, logger logr.Logger, cpms, resolved *machinev1.ControlPlaneMachineSet) (*machinev1.ControlPlaneMachineSet, error) {
+	discover, err := parseDiscoverFailureDomains(cpms.GetAnnotations())
+	if err != nil {
+		return nil, err
+	}
+
+	template := resolved.Spec.Template.OpenShiftMachineV1Beta1Machine
+	if !discover || r.APIReader == nil || template == nil || template.FailureDomains.Platform != configv1.AWSPlatformType ||
+		template.FailureDomains.AWS == nil || len(*template.FailureDomains.AWS) == 0 {
+		meta.RemoveStatusCondition(&cpms.Status.Conditions, conditionFailureDomainDiscovery)
+
+		return resolved, nil
+	}
+
+	machineSets := &machinev1beta1.MachineSetList{}
+	if err := r.List(ctx, machineSets, client.InNamespace(cpms.GetNamespace())); err != nil {
+		return nil, fmt.Errorf("could not list machine sets: %w", err)
+	}
+
+	discovered := discoverAWSFailureDomains(logger, *template.FailureDomains.AWS, machineSets.Items)
+
+	r.setFailureDomainDiscoveryCondition(cpms, discovered)
+
+	if len(discovered) == 0 {
+		return resolved, nil
+	}
+
+	failureDomains := append([]machinev1.AWSFailureDomain{}, *template.FailureDomains.AWS...)
+	for _, fd := range discovered {
+		failureDomains = append(failureDomains, fd.failureDomain)
+	}
+
+	out := resolved.DeepCopy()
+	out.Spec.Template.OpenShiftMachineV1Beta1Machine.FailureDomains.AWS = &failureDomains
+
+	return out, nil
+}
+
+// discoverAWSFailureDomains returns the failure domains of the MachineSets whose availability zones are not within
+// any of the existing failure domains, sorted by availability zone.
+// MachineSets that are being deleted, that are not on AWS, or that do not set both an availability zone and a subnet
+// are ignored, as are MachineSets within Local Zones or Wavelength Zones, which typically host edge compute pools
+// rather than zones suitable for the control plane. When the MachineSets within an availability zone do not all use the same subnet, the subnet of the
+// control plane cannot be determined, so the availability zone is not discovered.
+func discoverAWSFailureDomains(logger logr.Logger, existing []machinev1.AWSFailureDomain, machineSets []machinev1beta1.MachineSet) []discoveredFailureDomain {
+	knownZones := map[string]struct{}{}
+	for _, fd := range existing {
+		knownZones[fd.Placement.AvailabilityZone] = struct{}{}
+	}
+
+	sort.Slice(machineSets, func(i, j int) bool {
+		return machineSets[i].GetName() < machineSets[j].GetName()
+	})
+
+	byZone := map[string]discoveredFailureDomain{}
+	ambiguous := map[string]struct{}{}
+
+	for _, machineSet := range machineSets {
+		if machineSet.GetDeletionTimestamp() != nil {
+			continue
+		}
+
+		providerConfig, err := providerconfig.NewProviderConfigFromMachineSpec(machineSet.Spec.Template.Spec)
+		if err != nil || providerConfig.Type() != configv1.AWSPlatformType {
+			continue
+		}
+
+		fd := providerConfig.AWS().ExtractFailureDomain()
+		zone := fd.Placement.AvailabilityZone
+
+		if _, ok := knownZones[zone]; ok || zone == "" || fd.Subnet == nil || failuredomain.IsAWSEdgeZone(zone) {
+			continue
+		}
+
+		previous, ok := byZone[zone]
+		if !ok {
+			byZone[zone] = discoveredFailureDomain{machineSetName: machineSet.GetName(), failureDomain: fd}
+			continue
+		}
+
+		if !equality.Semantic.DeepEqual(previous.failureDomain.Subnet, fd.Subnet) {
+			ambiguous[zone] = struct{}{}
+		}
+	}
+
+	discovered := []discoveredFailureDomain{}
+
+	for zone, fd := range byZone {
+		if _, ok := ambiguous[zone]; ok {
+			logger.Info(observedAmbiguousFailureDomain, "availabilityZone", zone)
+			continue
+		}
+
+		logger.Info(observedDiscoveredFailureDomain, "availabilityZone", zone, "subnet", awsSubnetReferenceToString(fd.failureDomain.Subnet), "machineSet", fd.machineSetName)
+
+		discovered = append(discovered, fd)
+	}
+
+	sort.Slice(discovered, func(i, j int) bool {
+		return discovered[i].failureDomain.Placement.AvailabilityZone < discovered[j].failureDomain.Placement.AvailabilityZone
+	})
+
+	return discovered
+}
+
+// setFailureDomainDiscoveryCondition reports the failure domains discovered from the MachineSets, eg `Discovered 1
+// failure domain(s) from machine sets: us-east-1d (subnet subnet-0123, machine set cluster-worker-us-east-1d)`.
+// An event is published whenever the discovered failure domains change, and the condition is removed once no failure
+// domain is discovered, for example because it has been added to the spec.
+func (r *ControlPlaneMachineSetReconciler) setFailureDomainDiscoveryCondition(cpms *machinev1.ControlPlaneMachineSet, discovered []discoveredFailureDomain) {
+	if len(discovered) == 0 {
+		meta.RemoveStatusCondition(&cpms.Status.Conditions, conditionFailureDomainDiscovery)
+
+		return
+	}
+
+	summaries := []string{}
+	for _, fd := range discovered {
+		summaries = append(summaries, fmt.Sprintf("%s (subnet %s, machine set %s)", fd.failureDomain.Placement.AvailabilityZone, awsSubnetReferenceToString(fd.failureDomain.Subnet), fd.machineSetName))
+	}
+
+	message := fmt.Sprintf("Discovered %d failure domain(s) from machine sets: %s", len(discovered), strings.Join(summaries, "; "))
+
+	if previous := meta.FindStatusCondition(cpms.Status.Conditions, conditionFailureDomainDiscovery); previous == nil || previous.Message != message {
+		r.publishEvent(cpms, corev1.EventTypeNormal, reasonDiscoveredFailureDomains, message)
+	}
+
+	meta.SetStatusCondition(&cpms.Status.Conditions, metav1.Condition{
+		Type:               conditionFailureDomainDiscovery,
+		Status:             metav1.ConditionTrue,
+		Reason:             reasonDiscoveredFailureDomains,
+		ObservedGeneration: cpms.GetGeneration(),
+		Message:            message,
+	})
+}
+
+// awsSubnetReferenceToString describes a subnet by its ID or ARN, or by its filters.
+func awsSubnetReferenceToString(ref *machinev1.AWSResourceReference) string {
+	switch {
+	case ref.ID != nil:
+		return *ref.ID
+	case ref.ARN != nil:
+		return *ref.ARN
+	case ref.Filters != nil:
+		return fmt.Sprintf("%+v", *ref.Filters)
+	default:
+		return string(ref.Type)
+	}
+}
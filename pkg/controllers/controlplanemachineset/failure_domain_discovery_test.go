@@ -0,0 +1,245 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controlplanemachineset
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	configv1 "github.com/openshift/api/config/v1"
+	machinev1 "github.com/openshift/api/machine/v1"
+	machinev1beta1 "github.com/openshift/api/machine/v1beta1"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/test"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/test/resourcebuilder"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/pointer"
+)
+
+var _ = Describe("Failure domain discovery", func() {
+	const expectedMessage = "Discovered 1 failure domain(s) from machine sets: us-east-1d (subnet subnet-us-east-1d, machine set worker-us-east-1d)"
+
+	subnetID := func(id string) machinev1beta1.AWSResourceReference {
+		return machinev1beta1.AWSResourceReference{ID: pointer.String(id)}
+	}
+
+	machineSet := func(name, zone string, subnet machinev1beta1.AWSResourceReference) machinev1beta1.MachineSet {
+		providerSpec := resourcebuilder.AWSProviderSpec().WithAvailabilityZone(zone).WithSubnet(subnet)
+
+		return machinev1beta1.MachineSet{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec: machinev1beta1.MachineSetSpec{
+				Template: machinev1beta1.MachineTemplateSpec{
+					Spec: machinev1beta1.MachineSpec{
+						ProviderSpec: machinev1beta1.ProviderSpec{Value: providerSpec.BuildRawExtension()},
+					},
+				},
+			},
+		}
+	}
+
+	existingFailureDomains := func() []machinev1.AWSFailureDomain {
+		return *resourcebuilder.AWSFailureDomains().BuildFailureDomains().AWS
+	}
+
+	Context("parseDiscoverFailureDomains", func() {
+		It("is disabled without the annotation", func() {
+			Expect(parseDiscoverFailureDomains(nil)).To(BeFalse())
+		})
+
+		It("is enabled when the annotation is true", func() {
+			Expect(parseDiscoverFailureDomains(map[string]string{discoverFailureDomainsAnnotation: "true"})).To(BeTrue())
+		})
+
+		It("returns a configuration error when the annotation is not a boolean", func() {
+			_, err := parseDiscoverFailureDomains(map[string]string{discoverFailureDomainsAnnotation: "yes please"})
+			Expect(err).To(MatchError(errInvalidDiscoverFailureDomains))
+
+			reason, ok := machineproviders.ConfigurationErrorReason(err)
+			Expect(ok).To(BeTrue())
+			Expect(reason).To(Equal(machineproviders.ReasonInvalidFailureDomains))
+		})
+	})
+
+	Context("discoverAWSFailureDomains", func() {
+		var logger test.TestLogger
+
+		BeforeEach(func() {
+			logger = test.NewTestLogger()
+		})
+
+		It("discovers availability zones that are not within the failure domains", func() {
+			discovered := discoverAWSFailureDomains(logger.Logger(), existingFailureDomains(), []machinev1beta1.MachineSet{
+				machineSet("worker-us-east-1e", "us-east-1e", subnetID("subnet-us-east-1e")),
+				machineSet("worker-us-east-1a", "us-east-1a", subnetID("subnet-us-east-1a")),
+				machineSet("worker-us-east-1d", "us-east-1d", subnetID("subnet-us-east-1d")),
+			})
+
+			Expect(discovered).To(Equal([]discoveredFailureDomain{
+				{
+					machineSetName: "worker-us-east-1d",
+					failureDomain: machinev1.AWSFailureDomain{
+						Placement: machinev1.AWSFailureDomainPlacement{AvailabilityZone: "us-east-1d"},
+						Subnet:    &machinev1.AWSResourceReference{Type: machinev1.AWSIDReferenceType, ID: pointer.String("subnet-us-east-1d")},
+					},
+				},
+				{
+					machineSetName: "worker-us-east-1e",
+					failureDomain: machinev1.AWSFailureDomain{
+						Placement: machinev1.AWSFailureDomainPlacement{AvailabilityZone: "us-east-1e"},
+						Subnet:    &machinev1.AWSResourceReference{Type: machinev1.AWSIDReferenceType, ID: pointer.String("subnet-us-east-1e")},
+					},
+				},
+			}))
+		})
+
+		It("uses the first machine set, by name, when machine sets in an availability zone share a subnet", func() {
+			discovered := discoverAWSFailureDomains(logger.Logger(), existingFailureDomains(), []machinev1beta1.MachineSet{
+				machineSet("worker-us-east-1d-2", "us-east-1d", subnetID("subnet-us-east-1d")),
+				machineSet("worker-us-east-1d-1", "us-east-1d", subnetID("subnet-us-east-1d")),
+			})
+
+			Expect(discovered).To(HaveLen(1))
+			Expect(discovered[0].machineSetName).To(Equal("worker-us-east-1d-1"))
+		})
+
+		It("ignores availability zones whose machine sets use different subnets", func() {
+			discovered := discoverAWSFailureDomains(logger.Logger(), existingFailureDomains(), []machinev1beta1.MachineSet{
+				machineSet("worker-us-east-1d-1", "us-east-1d", subnetID("subnet-us-east-1d-private")),
+				machineSet("worker-us-east-1d-2", "us-east-1d", subnetID("subnet-us-east-1d-public")),
+			})
+
+			Expect(discovered).To(BeEmpty())
+			Expect(logger.Entries()).To(ConsistOf(test.LogEntry{
+				Level:         0,
+				KeysAndValues: []interface{}{"availabilityZone", "us-east-1d"},
+				Message:       observedAmbiguousFailureDomain,
+			}))
+		})
+
+		It("ignores machine sets within Local Zones and Wavelength Zones", func() {
+			discovered := discoverAWSFailureDomains(logger.Logger(), existingFailureDomains(), []machinev1beta1.MachineSet{
+				machineSet("edge-us-east-1-bos-1a", "us-east-1-bos-1a", subnetID("subnet-us-east-1-bos-1a")),
+				machineSet("edge-us-east-1-wl1-bos-wlz-1", "us-east-1-wl1-bos-wlz-1", subnetID("subnet-us-east-1-wl1-bos-wlz-1")),
+			})
+
+			Expect(discovered).To(BeEmpty())
+		})
+
+		It("ignores machine sets without an availability zone or subnet", func() {
+			discovered := discoverAWSFailureDomains(logger.Logger(), existingFailureDomains(), []machinev1beta1.MachineSet{
+				machineSet("worker-no-zone", "", subnetID("subnet-us-east-1d")),
+				machineSet("worker-no-subnet", "us-east-1d", machinev1beta1.AWSResourceReference{}),
+			})
+
+			Expect(discovered).To(BeEmpty())
+		})
+	})
+
+	Context("discoverFailureDomains", func() {
+		var namespaceName string
+		var recorder *record.FakeRecorder
+		var reconciler *ControlPlaneMachineSetReconciler
+		var cpms *machinev1.ControlPlaneMachineSet
+
+		BeforeEach(func() {
+			By("Setting up a namespace for the test")
+			ns := resourcebuilder.Namespace().WithGenerateName("control-plane-machine-set-controller-").Build()
+			Expect(k8sClient.Create(ctx, ns)).To(Succeed())
+			namespaceName = ns.GetName()
+
+			recorder = record.NewFakeRecorder(10)
+			reconciler = &ControlPlaneMachineSetReconciler{
+				Client:    k8sClient,
+				APIReader: k8sClient,
+				Scheme:    testScheme,
+				Namespace: namespaceName,
+				Recorder:  recorder,
+			}
+
+			cpms = resourcebuilder.ControlPlaneMachineSet().WithNamespace(namespaceName).
+				WithAnnotations(map[string]string{discoverFailureDomainsAnnotation: "true"}).Build()
+
+			ms := machineSet("worker-us-east-1d", "us-east-1d", subnetID("subnet-us-east-1d"))
+			ms.SetNamespace(namespaceName)
+			Expect(k8sClient.Create(ctx, &ms)).To(Succeed())
+		})
+
+		AfterEach(func() {
+			test.CleanupResources(Default, ctx, cfg, k8sClient, namespaceName,
+				&machinev1beta1.MachineSet{},
+			)
+		})
+
+		It("appends the discovered failure domains without modifying the ControlPlaneMachineSet spec", func() {
+			logger := test.NewTestLogger()
+			original := cpms.DeepCopy()
+
+			resolved, err := reconciler.discoverFailureDomains(ctx, logger.Logger(), cpms, cpms)
+			Expect(err).ToNot(HaveOccurred())
+
+			failureDomains := resolved.Spec.Template.OpenShiftMachineV1Beta1Machine.FailureDomains
+			Expect(failureDomains.Platform).To(Equal(configv1.AWSPlatformType))
+			Expect(*failureDomains.AWS).To(HaveLen(4))
+			Expect((*failureDomains.AWS)[3].Placement.AvailabilityZone).To(Equal("us-east-1d"))
+
+			By("Not modifying the spec of the original ControlPlaneMachineSet")
+			Expect(cpms.Spec).To(Equal(original.Spec))
+
+			Expect(cpms.Status.Conditions).To(ConsistOf(test.MatchCondition(metav1.Condition{
+				Type:    conditionFailureDomainDiscovery,
+				Status:  metav1.ConditionTrue,
+				Reason:  reasonDiscoveredFailureDomains,
+				Message: expectedMessage,
+			})))
+			Expect(recorder.Events).To(Receive(Equal("Normal " + reasonDiscoveredFailureDomains + " " + expectedMessage)))
+		})
+
+		It("does not publish another event when the discovered failure domains are unchanged", func() {
+			logger := test.NewTestLogger()
+
+			_, err := reconciler.discoverFailureDomains(ctx, logger.Logger(), cpms, cpms)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(recorder.Events).To(Receive())
+
+			_, err = reconciler.discoverFailureDomains(ctx, logger.Logger(), cpms, cpms)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(recorder.Events).ToNot(Receive())
+		})
+
+		It("does not discover failure domains without the annotation", func() {
+			logger := test.NewTestLogger()
+			cpms.SetAnnotations(nil)
+			cpms.Status.Conditions = []metav1.Condition{{Type: conditionFailureDomainDiscovery, Status: metav1.ConditionTrue}}
+
+			resolved, err := reconciler.discoverFailureDomains(ctx, logger.Logger(), cpms, cpms)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(resolved).To(BeIdenticalTo(cpms))
+			Expect(cpms.Status.Conditions).To(BeEmpty())
+		})
+
+		It("does not discover failure domains when the template has no failure domains", func() {
+			logger := test.NewTestLogger()
+			cpms.Spec.Template.OpenShiftMachineV1Beta1Machine.FailureDomains = machinev1.FailureDomains{}
+
+			resolved, err := reconciler.discoverFailureDomains(ctx, logger.Logger(), cpms, cpms)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(resolved).To(BeIdenticalTo(cpms))
+		})
+	})
+})
@@ -0,0 +1,88 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controlplanemachineset
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/go-logr/logr"
+	machinev1 "github.com/openshift/api/machine/v1"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func init() {
+	registerInformationalCondition(conditionFailureDomainDrift)
+}
+
+const (
+	// observedDriftedMachine is a log message used to inform the user that a Control Plane Machine is not within any
+	// of the failure domains of the ControlPlaneMachineSet.
+	observedDriftedMachine = "Observed machine outside every failure domain"
+)
+
+// setFailureDomainDriftCondition reports the Control Plane Machines that are not within any of the failure domains of
+// the ControlPlaneMachineSet, as determined by the machine provider. This typically follows the removal of a failure
+// domain from the spec. Such Machines need an update into the failure domain of their index, so the condition, and a
+// warning event published whenever the reported Machines change, signal the drift ahead of, or alongside, their
+// replacement according to the update strategy.
+// Machines pending deletion are ignored, as they are about to be removed.
+// The condition is removed once every Machine is within one of the failure domains.
+func (r *ControlPlaneMachineSetReconciler) setFailureDomainDriftCondition(logger logr.Logger, cpms *machinev1.ControlPlaneMachineSet, machineInfos map[int32][]machineproviders.MachineInfo) {
+	drifted := []string{}
+
+	for _, idx := range sortedIndexes(machineInfos) {
+		for _, machineInfo := range machineInfos[idx] {
+			if machineInfo.MachineRef == nil || machineInfo.MachineRef.ObjectMeta.GetDeletionTimestamp() != nil || !machineInfo.OutsideFailureDomains {
+				continue
+			}
+
+			machineName := machineInfo.MachineRef.ObjectMeta.GetName()
+			logger.Info(observedDriftedMachine, "machineName", machineName, "index", idx, "needsUpdate", machineInfo.NeedsUpdate)
+
+			summary := fmt.Sprintf("%s (index %d)", machineName, idx)
+			if machineInfo.NeedsUpdate {
+				summary = fmt.Sprintf("%s (index %d, needs update)", machineName, idx)
+			}
+
+			drifted = append(drifted, summary)
+		}
+	}
+
+	if len(drifted) == 0 {
+		meta.RemoveStatusCondition(&cpms.Status.Conditions, conditionFailureDomainDrift)
+
+		return
+	}
+
+	message := fmt.Sprintf("Found %d machine(s) outside every failure domain of the control plane machine set: %s", len(drifted), strings.Join(drifted, "; "))
+
+	if previous := meta.FindStatusCondition(cpms.Status.Conditions, conditionFailureDomainDrift); previous == nil || previous.Message != message {
+		r.publishEvent(cpms, corev1.EventTypeWarning, reasonMachinesOutsideFailureDomains, message)
+	}
+
+	meta.SetStatusCondition(&cpms.Status.Conditions, metav1.Condition{
+		Type:               conditionFailureDomainDrift,
+		Status:             metav1.ConditionTrue,
+		Reason:             reasonMachinesOutsideFailureDomains,
+		ObservedGeneration: cpms.GetGeneration(),
+		Message:            message,
+	})
+}
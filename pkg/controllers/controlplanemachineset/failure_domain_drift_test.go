@@ -0,0 +1,108 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controlplanemachineset
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	machinev1 "github.com/openshift/api/machine/v1"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/test"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/test/resourcebuilder"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+var _ = Describe("FailureDomainDrift condition", func() {
+	machineBuilder := resourcebuilder.MachineInfo().WithReady(true).WithNodeName("node")
+
+	var logger test.TestLogger
+	var recorder *record.FakeRecorder
+	var reconciler *ControlPlaneMachineSetReconciler
+	var cpms *machinev1.ControlPlaneMachineSet
+
+	driftedMachineInfos := map[int32][]machineproviders.MachineInfo{
+		0: {machineBuilder.WithIndex(0).WithMachineName("machine-0").Build()},
+		1: {machineBuilder.WithIndex(1).WithMachineName("machine-1").WithOutsideFailureDomains(true).Build()},
+		2: {machineBuilder.WithIndex(2).WithMachineName("machine-2").WithOutsideFailureDomains(true).WithNeedsUpdate(true).Build()},
+	}
+
+	expectedMessage := "Found 2 machine(s) outside every failure domain of the control plane machine set: machine-1 (index 1); machine-2 (index 2, needs update)"
+
+	BeforeEach(func() {
+		logger = test.NewTestLogger()
+		recorder = record.NewFakeRecorder(10)
+		reconciler = &ControlPlaneMachineSetReconciler{Recorder: recorder}
+		cpms = resourcebuilder.ControlPlaneMachineSet().WithReplicas(3).Build()
+
+		reconciler.setFailureDomainDriftCondition(logger.Logger(), cpms, driftedMachineInfos)
+	})
+
+	It("reports the machines outside every failure domain", func() {
+		Expect(cpms.Status.Conditions).To(ConsistOf(test.MatchCondition(metav1.Condition{
+			Type:    conditionFailureDomainDrift,
+			Status:  metav1.ConditionTrue,
+			Reason:  reasonMachinesOutsideFailureDomains,
+			Message: expectedMessage,
+		})))
+	})
+
+	It("logs each machine outside every failure domain", func() {
+		Expect(logger.Entries()).To(ConsistOf(
+			test.LogEntry{
+				Level:         0,
+				KeysAndValues: []interface{}{"machineName", "machine-1", "index", int32(1), "needsUpdate", false},
+				Message:       observedDriftedMachine,
+			},
+			test.LogEntry{
+				Level:         0,
+				KeysAndValues: []interface{}{"machineName", "machine-2", "index", int32(2), "needsUpdate", true},
+				Message:       observedDriftedMachine,
+			},
+		))
+	})
+
+	It("publishes a warning event", func() {
+		Expect(recorder.Events).To(Receive(Equal("Warning MachinesOutsideFailureDomains " + expectedMessage)))
+	})
+
+	It("does not publish another event when the drifted machines are unchanged", func() {
+		Expect(recorder.Events).To(Receive())
+
+		reconciler.setFailureDomainDriftCondition(logger.Logger(), cpms, driftedMachineInfos)
+
+		Expect(recorder.Events).ToNot(Receive())
+	})
+
+	It("ignores machines pending deletion", func() {
+		reconciler.setFailureDomainDriftCondition(logger.Logger(), cpms, map[int32][]machineproviders.MachineInfo{
+			0: {machineBuilder.WithIndex(0).WithMachineName("machine-0").WithOutsideFailureDomains(true).WithMachineDeletionTimestamp(metav1.Now()).Build()},
+		})
+
+		Expect(meta.FindStatusCondition(cpms.Status.Conditions, conditionFailureDomainDrift)).To(BeNil())
+	})
+
+	It("removes the condition once every machine is within a failure domain", func() {
+		reconciler.setFailureDomainDriftCondition(logger.Logger(), cpms, map[int32][]machineproviders.MachineInfo{
+			0: {machineBuilder.WithIndex(0).WithMachineName("machine-0").Build()},
+		})
+
+		Expect(meta.FindStatusCondition(cpms.Status.Conditions, conditionFailureDomainDrift)).To(BeNil())
+	})
+})
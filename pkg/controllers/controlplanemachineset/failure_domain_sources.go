@@ -0,0 +1,82 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controlplanemachineset
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	machinev1 "github.com/openshift/api/machine/v1"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// failureDomainSourceAnnotation is the annotation on the ControlPlaneMachineSet used to source the failure domains
+	// from a failure domain source registered by a downstream build of the operator, rather than from the template.
+	// The value is the name of the registered source.
+	failureDomainSourceAnnotation = "controlplanemachineset.machine.openshift.io/failure-domain-source"
+)
+
+var (
+	// errUnknownFailureDomainSource is used to inform users that the failure domain source selected by the failure
+	// domain source annotation has not been registered.
+	errUnknownFailureDomainSource = machineproviders.NewConfigurationError(machineproviders.ReasonInvalidFailureDomains, "unknown failure domain source")
+
+	// errConflictingFailureDomainSources is used to inform users that the failure domains are sourced from both a
+	// ConfigMap and a failure domain source.
+	errConflictingFailureDomainSources = machineproviders.NewConfigurationError(machineproviders.ReasonInvalidFailureDomains,
+		fmt.Sprintf("annotations %s and %s cannot both be set", failureDomainsConfigMapAnnotation, failureDomainSourceAnnotation))
+)
+
+// resolveFailureDomainSource returns a copy of the ControlPlaneMachineSet, with the failure domains within the
+// template replaced by those supplied by the failure domain source selected by the failure domain source annotation.
+// Errors returned by the source are wrapped, so that configuration errors of the source still degrade the
+// ControlPlaneMachineSet, while any other error is retried.
+func (r *ControlPlaneMachineSetReconciler) resolveFailureDomainSource(ctx context.Context, cpms *machinev1.ControlPlaneMachineSet) (*machinev1.ControlPlaneMachineSet, error) {
+	if _, ok := cpms.GetAnnotations()[failureDomainsConfigMapAnnotation]; ok {
+		return nil, errConflictingFailureDomainSources
+	}
+
+	name := cpms.GetAnnotations()[failureDomainSourceAnnotation]
+
+	source, ok := r.FailureDomainSources.Source(name)
+	if !ok {
+		registered := "none"
+		if names := r.FailureDomainSources.Names(); len(names) > 0 {
+			registered = strings.Join(names, ", ")
+		}
+
+		return nil, fmt.Errorf("%w %q, registered sources: %s", errUnknownFailureDomainSource, name, registered)
+	}
+
+	var reader client.Reader = r.Client
+	if r.APIReader != nil {
+		reader = r.APIReader
+	}
+
+	failureDomains, err := source.FailureDomains(ctx, reader, cpms.DeepCopy())
+	if err != nil {
+		return nil, fmt.Errorf("error sourcing failure domains from %s: %w", name, err)
+	}
+
+	resolved := cpms.DeepCopy()
+	resolved.Spec.Template.OpenShiftMachineV1Beta1Machine.FailureDomains = failureDomains
+
+	return resolved, nil
+}
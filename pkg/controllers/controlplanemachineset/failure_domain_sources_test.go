@@ -0,0 +1,153 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controlplanemachineset
+
+import (
+	"context"
+	"errors"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	configv1 "github.com/openshift/api/config/v1"
+	machinev1 "github.com/openshift/api/machine/v1"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders/failuredomainsource"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/test/resourcebuilder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var _ = Describe("Failure domain sources", func() {
+	const sourceName = "inventory"
+
+	inventoryFailureDomains := machinev1.FailureDomains{
+		Platform: configv1.AzurePlatformType,
+		Azure: &[]machinev1.AzureFailureDomain{
+			{Zone: "1"},
+			{Zone: "3"},
+		},
+	}
+
+	var registry *failuredomainsource.Registry
+	var reconciler *ControlPlaneMachineSetReconciler
+	var cpms *machinev1.ControlPlaneMachineSet
+
+	var sourceErr error
+	var sourceReader client.Reader
+	var sourceCPMS *machinev1.ControlPlaneMachineSet
+
+	BeforeEach(func() {
+		sourceErr = nil
+		sourceReader = nil
+		sourceCPMS = nil
+
+		registry = failuredomainsource.NewRegistry()
+		Expect(registry.Register(sourceName, failuredomainsource.SourceFunc(func(_ context.Context, cl client.Reader, in *machinev1.ControlPlaneMachineSet) (machinev1.FailureDomains, error) {
+			sourceReader = cl
+			sourceCPMS = in
+
+			if sourceErr != nil {
+				return machinev1.FailureDomains{}, sourceErr
+			}
+
+			return inventoryFailureDomains, nil
+		}))).To(Succeed())
+
+		reconciler = &ControlPlaneMachineSetReconciler{
+			Client:               k8sClient,
+			APIReader:            k8sClient,
+			FailureDomainSources: registry,
+		}
+
+		cpms = resourcebuilder.ControlPlaneMachineSet().
+			WithAnnotations(map[string]string{failureDomainSourceAnnotation: sourceName}).Build()
+	})
+
+	It("should replace the failure domains with those from the source", func() {
+		original := cpms.DeepCopy()
+
+		resolved, err := reconciler.resolveFailureDomains(ctx, cpms)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(resolved.Spec.Template.OpenShiftMachineV1Beta1Machine.FailureDomains).To(Equal(inventoryFailureDomains))
+
+		By("Not modifying the original ControlPlaneMachineSet")
+		Expect(cpms).To(Equal(original))
+	})
+
+	It("should pass the uncached reader and a copy of the ControlPlaneMachineSet to the source", func() {
+		_, err := reconciler.resolveFailureDomains(ctx, cpms)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(sourceReader).To(BeIdenticalTo(reconciler.APIReader))
+		Expect(sourceCPMS).To(Equal(cpms))
+		Expect(sourceCPMS).ToNot(BeIdenticalTo(cpms))
+	})
+
+	It("should return a configuration error when the source is not registered", func() {
+		cpms.SetAnnotations(map[string]string{failureDomainSourceAnnotation: "cmdb"})
+
+		_, err := reconciler.resolveFailureDomains(ctx, cpms)
+		Expect(err).To(MatchError(errUnknownFailureDomainSource))
+		Expect(err).To(MatchError(ContainSubstring(`"cmdb", registered sources: inventory`)))
+
+		reason, ok := machineproviders.ConfigurationErrorReason(err)
+		Expect(ok).To(BeTrue())
+		Expect(reason).To(Equal(machineproviders.ReasonInvalidFailureDomains))
+	})
+
+	It("should return a configuration error when no sources are registered", func() {
+		reconciler.FailureDomainSources = nil
+
+		_, err := reconciler.resolveFailureDomains(ctx, cpms)
+		Expect(err).To(MatchError(errUnknownFailureDomainSource))
+		Expect(err).To(MatchError(ContainSubstring("registered sources: none")))
+	})
+
+	It("should return a configuration error when the failure domains are also sourced from a ConfigMap", func() {
+		cpms.SetAnnotations(map[string]string{
+			failureDomainSourceAnnotation:     sourceName,
+			failureDomainsConfigMapAnnotation: "control-plane-failure-domains",
+		})
+
+		_, err := reconciler.resolveFailureDomains(ctx, cpms)
+		Expect(err).To(MatchError(errConflictingFailureDomainSources))
+	})
+
+	It("should pass through configuration errors of the source", func() {
+		errMissingEntry := machineproviders.NewConfigurationError(machineproviders.ReasonInvalidFailureDomains, "cluster is missing from the inventory")
+		sourceErr = errMissingEntry
+
+		_, err := reconciler.resolveFailureDomains(ctx, cpms)
+		Expect(err).To(MatchError(errMissingEntry))
+		Expect(err).To(MatchError("error sourcing failure domains from inventory: cluster is missing from the inventory"))
+
+		reason, ok := machineproviders.ConfigurationErrorReason(err)
+		Expect(ok).To(BeTrue())
+		Expect(reason).To(Equal(machineproviders.ReasonInvalidFailureDomains))
+	})
+
+	It("should treat other errors of the source as transient", func() {
+		sourceErr = errors.New("inventory unavailable")
+
+		_, err := reconciler.resolveFailureDomains(ctx, cpms)
+		Expect(err).To(MatchError("error sourcing failure domains from inventory: inventory unavailable"))
+
+		_, ok := machineproviders.ConfigurationErrorReason(err)
+		Expect(ok).To(BeFalse())
+	})
+})
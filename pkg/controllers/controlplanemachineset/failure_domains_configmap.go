@@ -0,0 +1,119 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controlplanemachineset
+
+import (
+	"context"
+	"fmt"
+
+	machinev1 "github.com/openshift/api/machine/v1"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/util/yaml"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+const (
+	// failureDomainsConfigMapAnnotation is the annotation on the ControlPlaneMachineSet used to source the failure
+	// domains from a ConfigMap, rather than from the template. This allows the failure domains to be maintained by
+	// infrastructure automation, for example, publishing the approved subnets within each availability zone.
+	// The value is the name of a ConfigMap within the namespace of the ControlPlaneMachineSet.
+	failureDomainsConfigMapAnnotation = "controlplanemachineset.machine.openshift.io/failure-domains-configmap"
+
+	// failureDomainsConfigMapKey is the key within the failure domains ConfigMap that holds the failure domains,
+	// in the same YAML or JSON format as the failure domains within the template.
+	failureDomainsConfigMapKey = "failureDomains"
+)
+
+var (
+	// errFailureDomainsConfigMapNotFound is used to inform users that the ConfigMap referenced by the failure domains
+	// annotation does not exist.
+	errFailureDomainsConfigMapNotFound = machineproviders.NewConfigurationError(machineproviders.ReasonInvalidFailureDomains, "failure domains configmap not found")
+
+	// errFailureDomainsConfigMapMissingKey is used to inform users that the ConfigMap referenced by the failure domains
+	// annotation does not contain the failure domains.
+	errFailureDomainsConfigMapMissingKey = machineproviders.NewConfigurationError(machineproviders.ReasonInvalidFailureDomains, fmt.Sprintf("failure domains configmap is missing key %q", failureDomainsConfigMapKey))
+
+	// errFailureDomainsConfigMapInvalid is used to inform users that the failure domains within the ConfigMap could
+	// not be parsed.
+	errFailureDomainsConfigMapInvalid = machineproviders.NewConfigurationError(machineproviders.ReasonInvalidFailureDomains, "could not parse failure domains from configmap")
+)
+
+// resolveFailureDomains returns the ControlPlaneMachineSet that the machine provider should be constructed from.
+// When the failure domains annotation is present, a copy of the ControlPlaneMachineSet is returned, with the failure
+// domains within the template replaced by those within the referenced ConfigMap. Likewise, when the failure domain
+// source annotation is present, they are replaced by those supplied by the selected failure domain source. The spec
+// of the ControlPlaneMachineSet itself is never modified.
+func (r *ControlPlaneMachineSetReconciler) resolveFailureDomains(ctx context.Context, cpms *machinev1.ControlPlaneMachineSet) (*machinev1.ControlPlaneMachineSet, error) {
+	if cpms.Spec.Template.OpenShiftMachineV1Beta1Machine == nil {
+		return cpms, nil
+	}
+
+	if _, ok := cpms.GetAnnotations()[failureDomainSourceAnnotation]; ok {
+		return r.resolveFailureDomainSource(ctx, cpms)
+	}
+
+	configMapName, ok := cpms.GetAnnotations()[failureDomainsConfigMapAnnotation]
+	if !ok {
+		return cpms, nil
+	}
+
+	configMap := &corev1.ConfigMap{}
+	configMapKey := client.ObjectKey{Namespace: cpms.GetNamespace(), Name: configMapName}
+
+	if err := r.Get(ctx, configMapKey, configMap); apierrors.IsNotFound(err) {
+		return nil, fmt.Errorf("%w: %s", errFailureDomainsConfigMapNotFound, configMapName)
+	} else if err != nil {
+		return nil, fmt.Errorf("could not fetch failure domains configmap %s: %w", configMapName, err)
+	}
+
+	data, ok := configMap.Data[failureDomainsConfigMapKey]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", errFailureDomainsConfigMapMissingKey, configMapName)
+	}
+
+	failureDomains := machinev1.FailureDomains{}
+	if err := yaml.UnmarshalStrict([]byte(data), &failureDomains); err != nil {
+		return nil, fmt.Errorf("%w %s: %s", errFailureDomainsConfigMapInvalid, configMapName, err.Error())
+	}
+
+	resolved := cpms.DeepCopy()
+	resolved.Spec.Template.OpenShiftMachineV1Beta1Machine.FailureDomains = failureDomains
+
+	return resolved, nil
+}
+
+// configMapToControlPlaneMachineSet maps a ConfigMap to the control plane machine set singleton with the name, in
+// the namespace, provided, when the control plane machine set sources its failure domains from the ConfigMap.
+func configMapToControlPlaneMachineSet(cl client.Reader, namespace, name string) func(client.Object) []reconcile.Request {
+	return func(obj client.Object) []reconcile.Request {
+		cpms := &machinev1.ControlPlaneMachineSet{}
+		cpmsKey := client.ObjectKey{Namespace: namespace, Name: name}
+
+		if err := cl.Get(context.Background(), cpmsKey, cpms); err != nil {
+			return nil
+		}
+
+		if cpms.GetAnnotations()[failureDomainsConfigMapAnnotation] != obj.GetName() {
+			return nil
+		}
+
+		return []reconcile.Request{{NamespacedName: cpmsKey}}
+	}
+}
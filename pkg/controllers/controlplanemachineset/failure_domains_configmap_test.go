@@ -0,0 +1,167 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controlplanemachineset
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	configv1 "github.com/openshift/api/config/v1"
+	machinev1 "github.com/openshift/api/machine/v1"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/test"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/test/resourcebuilder"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+var _ = Describe("Failure domains from a ConfigMap", func() {
+	const configMapName = "control-plane-failure-domains"
+
+	const failureDomainsYAML = `platform: AWS
+aws:
+- placement:
+    availabilityZone: us-east-1d
+  subnet:
+    type: id
+    id: subnet-us-east-1d
+`
+
+	var namespaceName string
+	var reconciler *ControlPlaneMachineSetReconciler
+
+	BeforeEach(func() {
+		By("Setting up a namespace for the test")
+		ns := resourcebuilder.Namespace().WithGenerateName("control-plane-machine-set-controller-").Build()
+		Expect(k8sClient.Create(ctx, ns)).To(Succeed())
+		namespaceName = ns.GetName()
+
+		reconciler = &ControlPlaneMachineSetReconciler{
+			Client:    k8sClient,
+			Scheme:    testScheme,
+			Namespace: namespaceName,
+		}
+	})
+
+	AfterEach(func() {
+		test.CleanupResources(Default, ctx, cfg, k8sClient, namespaceName,
+			&machinev1.ControlPlaneMachineSet{},
+			&corev1.ConfigMap{},
+		)
+	})
+
+	createConfigMap := func(data map[string]string) {
+		configMap := &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: configMapName, Namespace: namespaceName},
+			Data:       data,
+		}
+		Expect(k8sClient.Create(ctx, configMap)).To(Succeed())
+	}
+
+	Context("resolveFailureDomains", func() {
+		var cpms *machinev1.ControlPlaneMachineSet
+
+		BeforeEach(func() {
+			cpms = resourcebuilder.ControlPlaneMachineSet().WithNamespace(namespaceName).
+				WithAnnotations(map[string]string{failureDomainsConfigMapAnnotation: configMapName}).Build()
+		})
+
+		It("should return the ControlPlaneMachineSet unchanged without the annotation", func() {
+			cpms.SetAnnotations(nil)
+
+			resolved, err := reconciler.resolveFailureDomains(ctx, cpms)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(resolved).To(BeIdenticalTo(cpms))
+		})
+
+		It("should replace the failure domains with those from the ConfigMap", func() {
+			createConfigMap(map[string]string{failureDomainsConfigMapKey: failureDomainsYAML})
+			original := cpms.DeepCopy()
+
+			resolved, err := reconciler.resolveFailureDomains(ctx, cpms)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(resolved.Spec.Template.OpenShiftMachineV1Beta1Machine.FailureDomains).To(Equal(machinev1.FailureDomains{
+				Platform: configv1.AWSPlatformType,
+				AWS: &[]machinev1.AWSFailureDomain{{
+					Placement: machinev1.AWSFailureDomainPlacement{AvailabilityZone: "us-east-1d"},
+					Subnet: &machinev1.AWSResourceReference{
+						Type: machinev1.AWSIDReferenceType,
+						ID:   pointer.String("subnet-us-east-1d"),
+					},
+				}},
+			}))
+
+			By("Not modifying the original ControlPlaneMachineSet")
+			Expect(cpms).To(Equal(original))
+		})
+
+		It("should return a configuration error when the ConfigMap does not exist", func() {
+			_, err := reconciler.resolveFailureDomains(ctx, cpms)
+			Expect(err).To(MatchError(errFailureDomainsConfigMapNotFound))
+
+			reason, ok := machineproviders.ConfigurationErrorReason(err)
+			Expect(ok).To(BeTrue())
+			Expect(reason).To(Equal(machineproviders.ReasonInvalidFailureDomains))
+		})
+
+		It("should return a configuration error when the ConfigMap is missing the key", func() {
+			createConfigMap(map[string]string{"subnets": failureDomainsYAML})
+
+			_, err := reconciler.resolveFailureDomains(ctx, cpms)
+			Expect(err).To(MatchError(errFailureDomainsConfigMapMissingKey))
+		})
+
+		It("should return a configuration error when the failure domains cannot be parsed", func() {
+			createConfigMap(map[string]string{failureDomainsConfigMapKey: "platform: AWS\nzones: [us-east-1a]\n"})
+
+			_, err := reconciler.resolveFailureDomains(ctx, cpms)
+			Expect(err).To(MatchError(errFailureDomainsConfigMapInvalid))
+		})
+	})
+
+	Context("configMapToControlPlaneMachineSet", func() {
+		configMapNamed := func(name string) *corev1.ConfigMap {
+			return &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespaceName}}
+		}
+
+		Context("when the ControlPlaneMachineSet references a ConfigMap", func() {
+			BeforeEach(func() {
+				cpms := resourcebuilder.ControlPlaneMachineSet().WithNamespace(namespaceName).
+					WithAnnotations(map[string]string{failureDomainsConfigMapAnnotation: configMapName}).Build()
+				Expect(k8sClient.Create(ctx, cpms)).To(Succeed())
+			})
+
+			It("should map the referenced ConfigMap to the ControlPlaneMachineSet", func() {
+				Expect(configMapToControlPlaneMachineSet(k8sClient, namespaceName, DefaultControlPlaneMachineSetName)(configMapNamed(configMapName))).To(ConsistOf(reconcile.Request{
+					NamespacedName: client.ObjectKey{Namespace: namespaceName, Name: DefaultControlPlaneMachineSetName},
+				}))
+			})
+
+			It("should not map other ConfigMaps", func() {
+				Expect(configMapToControlPlaneMachineSet(k8sClient, namespaceName, DefaultControlPlaneMachineSetName)(configMapNamed("kube-root-ca.crt"))).To(BeEmpty())
+			})
+		})
+
+		It("should not map ConfigMaps when there is no ControlPlaneMachineSet", func() {
+			Expect(configMapToControlPlaneMachineSet(k8sClient, namespaceName, DefaultControlPlaneMachineSetName)(configMapNamed(configMapName))).To(BeEmpty())
+		})
+	})
+})
@@ -0,0 +1,190 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controlplanemachineset
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	machinev1 "github.com/openshift/api/machine/v1"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func init() {
+	registerInformationalCondition(conditionGatedBy)
+}
+
+// gate describes why the ControlPlaneMachineSet is not replacing Control Plane Machines that need an update.
+type gate struct {
+	// reason identifies the gate and is used as the reason of the GatedBy condition.
+	reason string
+
+	// description explains, to the user, what is blocking the replacements.
+	description string
+
+	// until is the time at which the gate is expected to unblock.
+	// The zero time denotes that the gate will only unblock once the user takes action.
+	until time.Time
+}
+
+// message returns the message of the GatedBy condition for the gate.
+func (g gate) message() string {
+	if g.until.IsZero() {
+		return fmt.Sprintf("%s, waiting for user action", g.description)
+	}
+
+	return fmt.Sprintf("%s, expected to unblock at %s", g.description, g.until.UTC().Format(time.RFC3339))
+}
+
+// replacementBudgetGate creates a gate for a replacement budget which does not currently permit any replacements.
+func replacementBudgetGate(budget replacementBudget, next time.Time) gate {
+	return gate{
+		reason:      reasonGatedByReplacementBudget,
+		description: fmt.Sprintf("Replacements are gated by the replacement budget of %s", budget),
+		until:       next,
+	}
+}
+
+// userDeletionGate creates a gate for outdated Machines which, with the OnDelete strategy, are only replaced once
+// the user deletes them.
+func userDeletionGate(machineNames []string) gate {
+	return gate{
+		reason:      reasonGatedByUserDeletion,
+		description: fmt.Sprintf("Replacements are gated by the OnDelete strategy, delete the outdated machines to continue: %s", strings.Join(machineNames, ", ")),
+	}
+}
+
+// operatorDegradedGate creates a gate for a degraded ControlPlaneMachineSet, which pauses all replacements until
+// the degraded condition is resolved.
+func operatorDegradedGate(cpms *machinev1.ControlPlaneMachineSet) gate {
+	reason := ""
+	if degraded := meta.FindStatusCondition(cpms.Status.Conditions, conditionDegraded); degraded != nil {
+		reason = degraded.Reason
+	}
+
+	return gate{
+		reason:      reasonGatedByOperatorDegraded,
+		description: fmt.Sprintf("Replacements are paused while the control plane machine set is degraded: %s", reason),
+	}
+}
+
+// multipleMachineFailuresGate creates a gate for multiple failed indexes, which suspends all replacements until
+// fewer indexes have failed, as replacing a Machine requires the outdated Machine to be deleted.
+func multipleMachineFailuresGate(failed []int32) gate {
+	return gate{
+		reason:      reasonGatedByMultipleMachineFailures,
+		description: fmt.Sprintf("Replacements are suspended while %d control plane indexes have failed", len(failed)),
+	}
+}
+
+// notReadyMachinesGate creates a gate for up to date Machines whose Nodes are not ready, which, with the Wait not
+// ready machine policy, hold the rollout until their Nodes are ready.
+func notReadyMachinesGate(machineNames []string) gate {
+	return gate{
+		reason:      reasonGatedByNotReadyMachines,
+		description: fmt.Sprintf("Replacements are gated by up to date machines whose nodes are not ready: %s", strings.Join(machineNames, ", ")),
+	}
+}
+
+// setGatedByCondition sets the gated by condition to report the gate blocking the replacement of Control Plane
+// Machines. The condition is only present while a gate is blocking replacements. It is removed at the start of each
+// reconcile by clearGatedByCondition.
+func setGatedByCondition(cpms *machinev1.ControlPlaneMachineSet, g gate) {
+	meta.SetStatusCondition(&cpms.Status.Conditions, metav1.Condition{
+		Type:               conditionGatedBy,
+		Status:             metav1.ConditionTrue,
+		Reason:             g.reason,
+		ObservedGeneration: cpms.GetGeneration(),
+		Message:            g.message(),
+	})
+}
+
+// setStrategyGatedByCondition sets the gated by condition when replacements are blocked by the state of the
+// ControlPlaneMachineSet, rather than by a gate evaluated while reconciling updates, such as the replacement budget.
+// Pending replacements are paused while the ControlPlaneMachineSet is degraded, paused or adopting the existing
+// Machines and, with the OnDelete
+// strategy, outdated Machines are only replaced once the user deletes them.
+func setStrategyGatedByCondition(cpms *machinev1.ControlPlaneMachineSet, indexedMachineInfos map[int32][]machineproviders.MachineInfo) {
+	switch {
+	case isControlPlaneMachineSetDegraded(cpms):
+		if hasPendingReplacements(indexedMachineInfos) {
+			setGatedByCondition(cpms, operatorDegradedGate(cpms))
+		}
+	case isControlPlaneMachineSetPaused(cpms):
+		if hasPendingReplacements(indexedMachineInfos) {
+			setGatedByCondition(cpms, userPauseGate())
+		}
+	case isAdoptionPending(cpms):
+		if hasPendingReplacements(indexedMachineInfos) {
+			setGatedByCondition(cpms, adoptionGate())
+		}
+	case cpms.Spec.Strategy.Type == machinev1.OnDelete:
+		preCreate := cpms.GetAnnotations()[preCreateReplacementsAnnotation] == "true"
+
+		if awaitingDeletion := machinesAwaitingUserDeletion(indexedMachineInfos, preCreate); len(awaitingDeletion) > 0 {
+			setGatedByCondition(cpms, userDeletionGate(awaitingDeletion))
+		}
+	}
+}
+
+// clearGatedByCondition removes the gated by condition, so that it is only present when a gate is found to be
+// blocking replacements on the current reconcile.
+func clearGatedByCondition(cpms *machinev1.ControlPlaneMachineSet) {
+	meta.RemoveStatusCondition(&cpms.Status.Conditions, conditionGatedBy)
+}
+
+// hasPendingReplacements determines whether any index has a Machine which needs an update, or has no Machine.
+func hasPendingReplacements(indexedMachineInfos map[int32][]machineproviders.MachineInfo) bool {
+	for _, machineInfos := range indexedMachineInfos {
+		if len(machineInfos) == 0 {
+			return true
+		}
+
+		for _, machineInfo := range machineInfos {
+			if machineInfo.NeedsUpdate {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// machinesAwaitingUserDeletion returns the names, in index order, of the outdated Machines that the OnDelete strategy
+// is waiting for the user to delete. When replacements are pre-created, an outdated Machine is only awaiting the user
+// once its replacement is ready.
+func machinesAwaitingUserDeletion(indexedMachineInfos map[int32][]machineproviders.MachineInfo, preCreate bool) []string {
+	names := []string{}
+
+	for _, idx := range sortedIndexes(indexedMachineInfos) {
+		outdatedMachines, updatedMachines := splitOutdatedMachines(indexedMachineInfos[idx])
+		if len(outdatedMachines) == 0 || outdatedMachines[0].MachineRef.ObjectMeta.GetDeletionTimestamp() != nil {
+			continue
+		}
+
+		if preCreate && !hasReadyMachine(updatedMachines) {
+			continue
+		}
+
+		names = append(names, outdatedMachines[0].MachineRef.ObjectMeta.GetName())
+	}
+
+	return names
+}
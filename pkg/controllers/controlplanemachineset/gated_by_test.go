@@ -0,0 +1,216 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controlplanemachineset
+
+import (
+	"time"
+
+	"github.com/golang/mock/gomock"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	machinev1 "github.com/openshift/api/machine/v1"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders/mock"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/test"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/test/resourcebuilder"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("GatedBy condition", func() {
+	machineBuilder := resourcebuilder.MachineInfo().WithReady(true)
+
+	Context("gate messages", func() {
+		It("should include the expected unblock time", func() {
+			next := time.Date(2022, time.June, 1, 12, 0, 0, 0, time.UTC)
+			g := replacementBudgetGate(replacementBudget{replacements: 1, period: 24 * time.Hour}, next)
+
+			Expect(g.reason).To(Equal(reasonGatedByReplacementBudget))
+			Expect(g.message()).To(Equal("Replacements are gated by the replacement budget of 1/24h0m0s, expected to unblock at 2022-06-01T12:00:00Z"))
+		})
+
+		It("should report that user action is required when there is no unblock time", func() {
+			g := userDeletionGate([]string{"machine-0", "machine-2"})
+
+			Expect(g.reason).To(Equal(reasonGatedByUserDeletion))
+			Expect(g.message()).To(Equal("Replacements are gated by the OnDelete strategy, delete the outdated machines to continue: machine-0, machine-2, waiting for user action"))
+		})
+
+		It("should name the reason the control plane machine set is degraded", func() {
+			cpms := resourcebuilder.ControlPlaneMachineSet().Build()
+			cpms.Status.Conditions = []metav1.Condition{{Type: conditionDegraded, Status: metav1.ConditionTrue, Reason: reasonUnmanagedNodes}}
+
+			g := operatorDegradedGate(cpms)
+
+			Expect(g.reason).To(Equal(reasonGatedByOperatorDegraded))
+			Expect(g.message()).To(Equal("Replacements are paused while the control plane machine set is degraded: UnmanagedNodes, waiting for user action"))
+		})
+	})
+
+	type pendingReplacementsTableInput struct {
+		machineInfos map[int32][]machineproviders.MachineInfo
+		expected     bool
+	}
+
+	DescribeTable("hasPendingReplacements", func(in pendingReplacementsTableInput) {
+		Expect(hasPendingReplacements(in.machineInfos)).To(Equal(in.expected))
+	},
+		Entry("with up to date Machines", pendingReplacementsTableInput{
+			machineInfos: map[int32][]machineproviders.MachineInfo{
+				0: {machineBuilder.WithIndex(0).WithMachineName("machine-0").Build()},
+				1: {machineBuilder.WithIndex(1).WithMachineName("machine-1").Build()},
+			},
+			expected: false,
+		}),
+		Entry("with an outdated Machine", pendingReplacementsTableInput{
+			machineInfos: map[int32][]machineproviders.MachineInfo{
+				0: {machineBuilder.WithIndex(0).WithMachineName("machine-0").Build()},
+				1: {machineBuilder.WithIndex(1).WithMachineName("machine-1").WithNeedsUpdate(true).Build()},
+			},
+			expected: true,
+		}),
+		Entry("with an empty index", pendingReplacementsTableInput{
+			machineInfos: map[int32][]machineproviders.MachineInfo{
+				0: {machineBuilder.WithIndex(0).WithMachineName("machine-0").Build()},
+				1: {},
+			},
+			expected: true,
+		}),
+	)
+
+	Context("when reconciling updates", func() {
+		var logger test.TestLogger
+		var reconciler *ControlPlaneMachineSetReconciler
+		var mockMachineProvider *mock.MockMachineProvider
+
+		BeforeEach(func() {
+			logger = test.NewTestLogger()
+			reconciler = &ControlPlaneMachineSetReconciler{
+				Scheme: testScheme,
+			}
+
+			mockMachineProvider = mock.NewMockMachineProvider(gomock.NewController(GinkgoT()))
+		})
+
+		It("should be gated by an exhausted replacement budget", func() {
+			cpms := resourcebuilder.ControlPlaneMachineSet().WithStrategyType(machinev1.RollingUpdate).WithReplicas(2).
+				WithAnnotations(map[string]string{replacementBudgetAnnotation: "1/24h"}).Build()
+
+			recentBuilder := machineBuilder.WithMachineCreationTimestamp(metav1.NewTime(time.Now().Add(-4 * time.Hour)))
+			machineInfos := map[int32][]machineproviders.MachineInfo{
+				0: {recentBuilder.WithIndex(0).WithMachineName("machine-0").WithTemplateHash("hash").Build()},
+				1: {recentBuilder.WithIndex(1).WithMachineName("machine-1").WithNeedsUpdate(true).Build()},
+			}
+
+			mockMachineProvider.EXPECT().CreateMachine(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+
+			_, err := reconciler.reconcileMachineUpdates(ctx, logger.Logger(), cpms, mockMachineProvider, machineInfos)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(cpms.Status.Conditions).To(ContainElement(SatisfyAll(
+				HaveField("Type", Equal(conditionGatedBy)),
+				HaveField("Status", Equal(metav1.ConditionTrue)),
+				HaveField("Reason", Equal(reasonGatedByReplacementBudget)),
+				HaveField("Message", HavePrefix("Replacements are gated by the replacement budget of 1/24h0m0s, expected to unblock at ")),
+			)))
+		})
+	})
+
+	Context("setStrategyGatedByCondition", func() {
+		It("should be gated by user deletion with the OnDelete strategy", func() {
+			cpms := resourcebuilder.ControlPlaneMachineSet().WithStrategyType(machinev1.OnDelete).WithReplicas(3).Build()
+
+			machineInfos := map[int32][]machineproviders.MachineInfo{
+				0: {machineBuilder.WithIndex(0).WithMachineName("machine-0").WithNeedsUpdate(true).Build()},
+				1: {machineBuilder.WithIndex(1).WithMachineName("machine-1").Build()},
+				2: {machineBuilder.WithIndex(2).WithMachineName("machine-2").WithNeedsUpdate(true).Build()},
+			}
+
+			setStrategyGatedByCondition(cpms, machineInfos)
+
+			Expect(cpms.Status.Conditions).To(ContainElement(SatisfyAll(
+				HaveField("Type", Equal(conditionGatedBy)),
+				HaveField("Status", Equal(metav1.ConditionTrue)),
+				HaveField("Reason", Equal(reasonGatedByUserDeletion)),
+				HaveField("Message", ContainSubstring("machine-0, machine-2")),
+			)))
+		})
+
+		It("should not be gated while a pre-created replacement is not yet ready", func() {
+			cpms := resourcebuilder.ControlPlaneMachineSet().WithStrategyType(machinev1.OnDelete).WithReplicas(1).
+				WithAnnotations(map[string]string{preCreateReplacementsAnnotation: "true"}).Build()
+
+			machineInfos := map[int32][]machineproviders.MachineInfo{
+				0: {
+					machineBuilder.WithIndex(0).WithMachineName("machine-0").WithNeedsUpdate(true).Build(),
+					machineBuilder.WithIndex(0).WithMachineName("machine-3").WithReady(false).Build(),
+				},
+			}
+
+			setStrategyGatedByCondition(cpms, machineInfos)
+
+			Expect(cpms.Status.Conditions).ToNot(ContainElement(HaveField("Type", Equal(conditionGatedBy))))
+		})
+
+		It("should be gated by a degraded control plane machine set with pending replacements", func() {
+			cpms := resourcebuilder.ControlPlaneMachineSet().WithStrategyType(machinev1.RollingUpdate).WithReplicas(1).Build()
+			cpms.Status.Conditions = []metav1.Condition{{Type: conditionDegraded, Status: metav1.ConditionTrue, Reason: reasonMachinesAlreadyOwned}}
+
+			machineInfos := map[int32][]machineproviders.MachineInfo{
+				0: {machineBuilder.WithIndex(0).WithMachineName("machine-0").WithNeedsUpdate(true).Build()},
+			}
+
+			setStrategyGatedByCondition(cpms, machineInfos)
+
+			Expect(cpms.Status.Conditions).To(ContainElement(SatisfyAll(
+				HaveField("Type", Equal(conditionGatedBy)),
+				HaveField("Reason", Equal(reasonGatedByOperatorDegraded)),
+				HaveField("Message", ContainSubstring(reasonMachinesAlreadyOwned)),
+			)))
+		})
+
+		It("should be gated by a paused control plane machine set with pending replacements", func() {
+			cpms := resourcebuilder.ControlPlaneMachineSet().WithStrategyType(machinev1.RollingUpdate).WithReplicas(1).
+				WithAnnotations(map[string]string{pausedAnnotation: "true"}).Build()
+
+			machineInfos := map[int32][]machineproviders.MachineInfo{
+				0: {machineBuilder.WithIndex(0).WithMachineName("machine-0").WithNeedsUpdate(true).Build()},
+			}
+
+			setStrategyGatedByCondition(cpms, machineInfos)
+
+			Expect(cpms.Status.Conditions).To(ContainElement(SatisfyAll(
+				HaveField("Type", Equal(conditionGatedBy)),
+				HaveField("Reason", Equal(reasonGatedByUserPause)),
+				HaveField("Message", Equal("Replacements are paused by the controlplanemachineset.machine.openshift.io/paused annotation, remove it to continue, waiting for user action")),
+			)))
+		})
+
+		It("should not be gated by a degraded control plane machine set without pending replacements", func() {
+			cpms := resourcebuilder.ControlPlaneMachineSet().WithStrategyType(machinev1.RollingUpdate).WithReplicas(1).Build()
+			cpms.Status.Conditions = []metav1.Condition{{Type: conditionDegraded, Status: metav1.ConditionTrue, Reason: reasonMachinesAlreadyOwned}}
+
+			machineInfos := map[int32][]machineproviders.MachineInfo{
+				0: {machineBuilder.WithIndex(0).WithMachineName("machine-0").Build()},
+			}
+
+			setStrategyGatedByCondition(cpms, machineInfos)
+
+			Expect(cpms.Status.Conditions).ToNot(ContainElement(HaveField("Type", Equal(conditionGatedBy))))
+		})
+	})
+})
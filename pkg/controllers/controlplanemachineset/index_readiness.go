@@ -0,0 +1,179 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controlplanemachineset
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	machinev1 "github.com/openshift/api/machine/v1"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func init() {
+	registerInformationalCondition(conditionIndexReadiness)
+}
+
+// machineReadiness is the first stage of readiness that a Control Plane Machine has not reached, or that it is ready.
+// The stages are reached in order: the cloud instance is running, the Node has joined the cluster, and the Node is
+// ready.
+type machineReadiness int
+
+const (
+	// readinessInstanceNotRunning denotes that the cloud instance of the Machine is not running.
+	readinessInstanceNotRunning machineReadiness = iota
+
+	// readinessNodeNotJoined denotes that the cloud instance of the Machine is running, but no Node has joined the
+	// cluster for it.
+	readinessNodeNotJoined
+
+	// readinessNodeNotReady denotes that the Node of the Machine has joined the cluster, but is not ready.
+	readinessNodeNotReady
+
+	// readinessReady denotes that the Node of the Machine is ready.
+	readinessReady
+)
+
+// String returns the description of the readiness used within the index readiness condition.
+func (r machineReadiness) String() string {
+	switch r {
+	case readinessInstanceNotRunning:
+		return "InstanceNotRunning"
+	case readinessNodeNotJoined:
+		return "NodeNotJoined"
+	case readinessNodeNotReady:
+		return "NodeNotReady"
+	default:
+		return "Ready"
+	}
+}
+
+// reason returns the reason of the index readiness condition when the readiness is the earliest stage not reached by
+// any Control Plane Machine.
+func (r machineReadiness) reason() string {
+	switch r {
+	case readinessInstanceNotRunning:
+		return reasonInstancesNotRunning
+	case readinessNodeNotJoined:
+		return reasonNodesNotJoined
+	case readinessNodeNotReady:
+		return reasonNodesNotReady
+	default:
+		return reasonIndexesReady
+	}
+}
+
+// reconcileIndexReadiness sets the index readiness condition to report, for each Machine of each index, whether its
+// cloud instance is running, its Node has joined the cluster and its Node is ready. The message lists the Machines of
+// each index, in index order, with the first stage of readiness that they have not reached, eg
+// `0=master-0 (Ready), 1=master-1 (Ready) + master-4 (NodeNotJoined), 2=master-2 (Ready)`.
+// This allows a stalled replacement to be triaged as a cloud, bootstrap or kubelet problem from the status alone.
+// Machines pending deletion are not included. The condition is true once every Machine is ready, otherwise its reason
+// names the earliest stage not reached by any Machine. The condition is removed when there are no Machines. When the
+// APIReader is nil, the readiness of the Nodes cannot be read, so the index readiness is not reported.
+func (r *ControlPlaneMachineSetReconciler) reconcileIndexReadiness(ctx context.Context, cpms *machinev1.ControlPlaneMachineSet, machineInfos map[int32][]machineproviders.MachineInfo) error {
+	if r.APIReader == nil {
+		meta.RemoveStatusCondition(&cpms.Status.Conditions, conditionIndexReadiness)
+		return nil
+	}
+
+	indexReadiness := []string{}
+	earliest := readinessReady
+
+	for _, idx := range sortedIndexes(machineInfos) {
+		machines := []string{}
+
+		for _, machineInfo := range sortedByMachineName(machineInfos[idx]) {
+			if machineInfo.MachineRef.ObjectMeta.GetDeletionTimestamp() != nil {
+				continue
+			}
+
+			readiness, err := r.readinessOf(ctx, machineInfo)
+			if err != nil {
+				return err
+			}
+
+			if readiness < earliest {
+				earliest = readiness
+			}
+
+			machines = append(machines, fmt.Sprintf("%s (%s)", machineInfo.MachineRef.ObjectMeta.GetName(), readiness))
+		}
+
+		if len(machines) > 0 {
+			indexReadiness = append(indexReadiness, fmt.Sprintf("%d=%s", idx, strings.Join(machines, " + ")))
+		}
+	}
+
+	if len(indexReadiness) == 0 {
+		meta.RemoveStatusCondition(&cpms.Status.Conditions, conditionIndexReadiness)
+		return nil
+	}
+
+	status := metav1.ConditionFalse
+	if earliest == readinessReady {
+		status = metav1.ConditionTrue
+	}
+
+	meta.SetStatusCondition(&cpms.Status.Conditions, metav1.Condition{
+		Type:               conditionIndexReadiness,
+		Status:             status,
+		Reason:             earliest.reason(),
+		ObservedGeneration: cpms.GetGeneration(),
+		Message:            strings.Join(indexReadiness, ", "),
+	})
+
+	return nil
+}
+
+// readinessOf determines the first stage of readiness that the Machine has not reached. A Node that no longer exists
+// is not ready.
+func (r *ControlPlaneMachineSetReconciler) readinessOf(ctx context.Context, machineInfo machineproviders.MachineInfo) (machineReadiness, error) {
+	if !machineInfo.InstanceRunning {
+		return readinessInstanceNotRunning, nil
+	}
+
+	if machineInfo.NodeRef == nil {
+		return readinessNodeNotJoined, nil
+	}
+
+	ready, err := r.isNamedNodeReady(ctx, machineInfo.NodeRef.ObjectMeta.GetName())
+	if err != nil || !ready {
+		return readinessNodeNotReady, err
+	}
+
+	return readinessReady, nil
+}
+
+// isNamedNodeReady fetches the Node with the given name, from the cache of the manager, and determines whether it is
+// ready. A Node that no longer exists is not ready.
+func (r *ControlPlaneMachineSetReconciler) isNamedNodeReady(ctx context.Context, nodeName string) (bool, error) {
+	node := &corev1.Node{}
+	if err := r.Get(ctx, client.ObjectKey{Name: nodeName}, node); apierrors.IsNotFound(err) {
+		return false, nil
+	} else if err != nil {
+		return false, fmt.Errorf("could not fetch node %s: %w", nodeName, err)
+	}
+
+	return isNodeReady(node), nil
+}
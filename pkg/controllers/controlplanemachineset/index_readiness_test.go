@@ -0,0 +1,165 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controlplanemachineset
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	machinev1 "github.com/openshift/api/machine/v1"
+	machinev1beta1 "github.com/openshift/api/machine/v1beta1"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/test"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/test/resourcebuilder"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("reconcileIndexReadiness", func() {
+	var namespaceName string
+	var reconciler *ControlPlaneMachineSetReconciler
+	var cpms *machinev1.ControlPlaneMachineSet
+	var readyNodeName, notReadyNodeName string
+
+	machineBuilder := resourcebuilder.MachineInfo().
+		WithMachineGVR(machinev1beta1.GroupVersion.WithResource("machines")).
+		WithNodeGVR(corev1.SchemeGroupVersion.WithResource("nodes")).
+		WithInstanceRunning(true)
+
+	BeforeEach(func() {
+		By("Setting up a namespace for the test")
+		ns := resourcebuilder.Namespace().WithGenerateName("control-plane-machine-set-index-readiness-").Build()
+		Expect(k8sClient.Create(ctx, ns)).To(Succeed())
+		namespaceName = ns.GetName()
+
+		reconciler = &ControlPlaneMachineSetReconciler{
+			Client:    k8sClient,
+			APIReader: k8sClient,
+			Scheme:    testScheme,
+			Namespace: namespaceName,
+		}
+
+		cpms = resourcebuilder.ControlPlaneMachineSet().WithNamespace(namespaceName).WithGeneration(2).Build()
+
+		By("Creating a ready node and a node that is not ready")
+		readyNode := resourcebuilder.Node().AsMaster().WithGenerateName("index-readiness-ready-").Build()
+		Expect(k8sClient.Create(ctx, readyNode)).To(Succeed())
+		readyNode.Status.Conditions = []corev1.NodeCondition{{Type: corev1.NodeReady, Status: corev1.ConditionTrue}}
+		Expect(k8sClient.Status().Update(ctx, readyNode)).To(Succeed())
+		readyNodeName = readyNode.GetName()
+
+		notReadyNode := resourcebuilder.Node().AsMaster().WithGenerateName("index-readiness-not-ready-").Build()
+		Expect(k8sClient.Create(ctx, notReadyNode)).To(Succeed())
+		notReadyNode.Status.Conditions = []corev1.NodeCondition{{Type: corev1.NodeReady, Status: corev1.ConditionFalse}}
+		Expect(k8sClient.Status().Update(ctx, notReadyNode)).To(Succeed())
+		notReadyNodeName = notReadyNode.GetName()
+	})
+
+	AfterEach(func() {
+		test.CleanupResources(Default, ctx, cfg, k8sClient, namespaceName,
+			&corev1.Node{},
+		)
+	})
+
+	It("reports every index as ready once every node is ready", func() {
+		Expect(reconciler.reconcileIndexReadiness(ctx, cpms, map[int32][]machineproviders.MachineInfo{
+			0: {machineBuilder.WithIndex(0).WithMachineName("machine-0").WithNodeName(readyNodeName).Build()},
+		})).To(Succeed())
+
+		Expect(cpms.Status.Conditions).To(test.MatchConditions([]metav1.Condition{{
+			Type:               conditionIndexReadiness,
+			Status:             metav1.ConditionTrue,
+			Reason:             reasonIndexesReady,
+			ObservedGeneration: 2,
+			Message:            "0=machine-0 (Ready)",
+		}}))
+	})
+
+	It("reports the stage of readiness that each machine has not reached", func() {
+		Expect(reconciler.reconcileIndexReadiness(ctx, cpms, map[int32][]machineproviders.MachineInfo{
+			0: {machineBuilder.WithIndex(0).WithMachineName("machine-0").WithNodeName(readyNodeName).Build()},
+			1: {
+				machineBuilder.WithIndex(1).WithMachineName("machine-1").WithNodeName(notReadyNodeName).WithReady(false).Build(),
+				machineBuilder.WithIndex(1).WithMachineName("machine-4").WithReady(false).Build(),
+			},
+			2: {machineBuilder.WithIndex(2).WithMachineName("machine-2").WithNodeName("departed-node").WithReady(false).Build()},
+		})).To(Succeed())
+
+		Expect(cpms.Status.Conditions).To(test.MatchConditions([]metav1.Condition{{
+			Type:               conditionIndexReadiness,
+			Status:             metav1.ConditionFalse,
+			Reason:             reasonNodesNotJoined,
+			ObservedGeneration: 2,
+			Message:            "0=machine-0 (Ready), 1=machine-1 (NodeNotReady) + machine-4 (NodeNotJoined), 2=machine-2 (NodeNotReady)",
+		}}))
+	})
+
+	It("reports machines whose instance is not running before any other stage", func() {
+		Expect(reconciler.reconcileIndexReadiness(ctx, cpms, map[int32][]machineproviders.MachineInfo{
+			0: {machineBuilder.WithIndex(0).WithMachineName("machine-0").WithNodeName(notReadyNodeName).WithReady(false).Build()},
+			1: {machineBuilder.WithIndex(1).WithMachineName("machine-1").WithNodeName(readyNodeName).WithInstanceRunning(false).Build()},
+		})).To(Succeed())
+
+		Expect(cpms.Status.Conditions).To(test.MatchConditions([]metav1.Condition{{
+			Type:               conditionIndexReadiness,
+			Status:             metav1.ConditionFalse,
+			Reason:             reasonInstancesNotRunning,
+			ObservedGeneration: 2,
+			Message:            "0=machine-0 (NodeNotReady), 1=machine-1 (InstanceNotRunning)",
+		}}))
+	})
+
+	It("does not report machines pending deletion", func() {
+		Expect(reconciler.reconcileIndexReadiness(ctx, cpms, map[int32][]machineproviders.MachineInfo{
+			0: {
+				machineBuilder.WithIndex(0).WithMachineName("machine-0").WithNodeName(notReadyNodeName).WithMachineDeletionTimestamp(metav1.Now()).Build(),
+				machineBuilder.WithIndex(0).WithMachineName("machine-3").WithNodeName(readyNodeName).Build(),
+			},
+		})).To(Succeed())
+
+		Expect(cpms.Status.Conditions).To(test.MatchConditions([]metav1.Condition{{
+			Type:               conditionIndexReadiness,
+			Status:             metav1.ConditionTrue,
+			Reason:             reasonIndexesReady,
+			ObservedGeneration: 2,
+			Message:            "0=machine-3 (Ready)",
+		}}))
+	})
+
+	It("does not report the index readiness when the APIReader is nil", func() {
+		reconciler.APIReader = nil
+
+		Expect(reconciler.reconcileIndexReadiness(ctx, cpms, map[int32][]machineproviders.MachineInfo{
+			0: {machineBuilder.WithIndex(0).WithMachineName("machine-0").WithNodeName(readyNodeName).Build()},
+		})).To(Succeed())
+
+		Expect(cpms.Status.Conditions).To(BeEmpty())
+	})
+
+	It("removes the condition when there are no machines", func() {
+		meta.SetStatusCondition(&cpms.Status.Conditions, metav1.Condition{
+			Type:   conditionIndexReadiness,
+			Status: metav1.ConditionTrue,
+			Reason: reasonIndexesReady,
+		})
+
+		Expect(reconciler.reconcileIndexReadiness(ctx, cpms, map[int32][]machineproviders.MachineInfo{})).To(Succeed())
+
+		Expect(cpms.Status.Conditions).To(BeEmpty())
+	})
+})
@@ -0,0 +1,72 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controlplanemachineset
+
+import (
+	"fmt"
+	"strings"
+
+	machinev1 "github.com/openshift/api/machine/v1"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func init() {
+	registerInformationalCondition(conditionMachineAPIPaused)
+}
+
+const (
+	// machineAPIPausedState is a log message used to inform the user that the Machine API has paused some Control
+	// Plane Machines, and that the control plane machine set will not take any action until they are unpaused.
+	machineAPIPausedState = "Machine API has paused control plane machines. The control plane machine set will not take any action until they are unpaused."
+)
+
+// pausedMachineNames returns the names, in index order, of the Control Plane Machines whose reconciliation has been
+// paused by the Machine API.
+func pausedMachineNames(indexedMachineInfos map[int32][]machineproviders.MachineInfo) []string {
+	names := []string{}
+
+	for _, idx := range sortedIndexes(indexedMachineInfos) {
+		for _, machineInfo := range indexedMachineInfos[idx] {
+			if machineInfo.MachineAPIPaused && machineInfo.MachineRef != nil {
+				names = append(names, machineInfo.MachineRef.ObjectMeta.GetName())
+			}
+		}
+	}
+
+	return names
+}
+
+// setMachineAPIPausedCondition sets the machine API paused condition to report the Control Plane Machines that the
+// Machine API has paused. While any Machine is paused, the ControlPlaneMachineSet holds all actions on the Control
+// Plane Machines, as a replacement could neither be created nor removed by the Machine API.
+func setMachineAPIPausedCondition(cpms *machinev1.ControlPlaneMachineSet, machineNames []string) {
+	meta.SetStatusCondition(&cpms.Status.Conditions, metav1.Condition{
+		Type:               conditionMachineAPIPaused,
+		Status:             metav1.ConditionTrue,
+		Reason:             reasonMachinesPaused,
+		ObservedGeneration: cpms.GetGeneration(),
+		Message:            fmt.Sprintf("All actions are held while the Machine API has paused control plane machines: %s", strings.Join(machineNames, ", ")),
+	})
+}
+
+// clearMachineAPIPausedCondition removes the machine API paused condition, so that it is only present while the
+// Machine API has paused some Control Plane Machines.
+func clearMachineAPIPausedCondition(cpms *machinev1.ControlPlaneMachineSet) {
+	meta.RemoveStatusCondition(&cpms.Status.Conditions, conditionMachineAPIPaused)
+}
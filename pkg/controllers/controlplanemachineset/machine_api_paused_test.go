@@ -0,0 +1,139 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controlplanemachineset
+
+import (
+	"github.com/golang/mock/gomock"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	machinev1 "github.com/openshift/api/machine/v1"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders/mock"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/test"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/test/resourcebuilder"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+var _ = Describe("MachineAPIPaused condition", func() {
+	machineBuilder := resourcebuilder.MachineInfo().WithReady(true).WithNodeName("node")
+
+	Context("pausedMachineNames", func() {
+		It("returns the paused machines in index order", func() {
+			machineInfos := map[int32][]machineproviders.MachineInfo{
+				2: {machineBuilder.WithIndex(2).WithMachineName("machine-2").WithMachineAPIPaused(true).Build()},
+				0: {machineBuilder.WithIndex(0).WithMachineName("machine-0").WithMachineAPIPaused(true).Build()},
+				1: {machineBuilder.WithIndex(1).WithMachineName("machine-1").Build()},
+			}
+
+			Expect(pausedMachineNames(machineInfos)).To(Equal([]string{"machine-0", "machine-2"}))
+		})
+
+		It("returns no machines when none are paused", func() {
+			machineInfos := map[int32][]machineproviders.MachineInfo{
+				0: {machineBuilder.WithIndex(0).WithMachineName("machine-0").Build()},
+				1: {},
+			}
+
+			Expect(pausedMachineNames(machineInfos)).To(BeEmpty())
+		})
+	})
+
+	Context("when reconciling machines", func() {
+		var logger test.TestLogger
+		var reconciler *ControlPlaneMachineSetReconciler
+		var mockMachineProvider *mock.MockMachineProvider
+		var cpms *machinev1.ControlPlaneMachineSet
+
+		BeforeEach(func() {
+			logger = test.NewTestLogger()
+			reconciler = &ControlPlaneMachineSetReconciler{
+				Scheme: testScheme,
+			}
+
+			mockMachineProvider = mock.NewMockMachineProvider(gomock.NewController(GinkgoT()))
+			cpms = resourcebuilder.ControlPlaneMachineSet().WithStrategyType(machinev1.RollingUpdate).WithReplicas(3).Build()
+		})
+
+		Context("with a paused machine that needs an update", func() {
+			var result ctrl.Result
+
+			BeforeEach(func() {
+				cpms.Status.Conditions = []metav1.Condition{{
+					Type:   conditionGatedBy,
+					Status: metav1.ConditionTrue,
+					Reason: reasonGatedByReplacementBudget,
+				}}
+
+				machineInfos := map[int32][]machineproviders.MachineInfo{
+					0: {machineBuilder.WithIndex(0).WithMachineName("machine-0").WithNeedsUpdate(true).WithMachineAPIPaused(true).Build()},
+					1: {machineBuilder.WithIndex(1).WithMachineName("machine-1").Build()},
+					2: {machineBuilder.WithIndex(2).WithMachineName("machine-2").Build()},
+				}
+
+				// The mock machine provider fails the test on any unexpected call, so no Machine may be created or
+				// deleted while the machine is paused.
+				var err error
+				result, err = reconciler.reconcileMachines(ctx, logger.Logger(), cpms, mockMachineProvider, machineInfos)
+				Expect(err).ToNot(HaveOccurred())
+			})
+
+			It("does not requeue", func() {
+				Expect(result).To(Equal(ctrl.Result{}))
+			})
+
+			It("sets the machine API paused condition", func() {
+				Expect(cpms.Status.Conditions).To(ContainElement(test.MatchCondition(metav1.Condition{
+					Type:    conditionMachineAPIPaused,
+					Status:  metav1.ConditionTrue,
+					Reason:  reasonMachinesPaused,
+					Message: "All actions are held while the Machine API has paused control plane machines: machine-0",
+				})))
+			})
+
+			It("removes the gated by condition", func() {
+				Expect(meta.FindStatusCondition(cpms.Status.Conditions, conditionGatedBy)).To(BeNil())
+			})
+
+			It("still reports the status of the machines", func() {
+				Expect(cpms.Status.UpdatedReplicas).To(BeEquivalentTo(2))
+				Expect(meta.FindStatusCondition(cpms.Status.Conditions, conditionRolloutPhase)).To(HaveField("Reason", Equal(string(phasePlanPending))))
+			})
+
+			It("logs that all actions are held", func() {
+				Expect(logger.Entries()).To(ContainElement(test.LogEntry{
+					Level:         1,
+					KeysAndValues: []interface{}{"pausedMachines", []string{"machine-0"}},
+					Message:       machineAPIPausedState,
+				}))
+			})
+		})
+	})
+
+	Context("clearMachineAPIPausedCondition", func() {
+		It("removes the condition once the machines are unpaused", func() {
+			cpms := resourcebuilder.ControlPlaneMachineSet().Build()
+			setMachineAPIPausedCondition(cpms, []string{"machine-0"})
+
+			clearMachineAPIPausedCondition(cpms)
+
+			Expect(meta.FindStatusCondition(cpms.Status.Conditions, conditionMachineAPIPaused)).To(BeNil())
+		})
+	})
+})
@@ -0,0 +1,219 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controlplanemachineset
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-logr/logr"
+	machinev1 "github.com/openshift/api/machine/v1"
+	machinev1beta1 "github.com/openshift/api/machine/v1beta1"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// managedMachineFinalizer is the finalizer that the ControlPlaneMachineSet operator adds to the Machines that it
+	// manages. It is only removed from a deleted Machine once the etcd member of its Node has been removed, so that
+	// the removal of the Machine is ordered after the removal of its etcd member.
+	managedMachineFinalizer = "controlplanemachineset.machine.openshift.io/ordered-teardown"
+
+	// managedMachineFinalizerResyncPeriod is the period after which a deleted Machine, whose etcd member has not yet
+	// been removed, is checked again. The etcd membership is not watched.
+	managedMachineFinalizerResyncPeriod = 30 * time.Second
+
+	// addedMachineFinalizer is a log message used to inform the user that the managed Machine finalizer has been
+	// added to a Machine.
+	addedMachineFinalizer = "Added managed finalizer to machine"
+
+	// waitingForEtcdMemberRemoval is a log message used to inform the user that a deleted Machine is held by the
+	// managed Machine finalizer until its etcd member is removed.
+	waitingForEtcdMemberRemoval = "Waiting for etcd member removal before releasing deleted machine"
+
+	// releasedMachineFinalizer is a log message used to inform the user that the managed Machine finalizer has been
+	// removed from a Machine.
+	releasedMachineFinalizer = "Released managed finalizer from machine"
+)
+
+// reconcileMachineFinalizers adds the managed Machine finalizer to each Control Plane Machine, and removes it from
+// deleted Machines once their etcd member has been removed.
+// When the etcd membership is not known, deleted Machines are released immediately, as the ordering cannot be
+// verified. The finalizer is only added when the etcd membership can be read, see etcdMembership.
+// The returned result requeues the ControlPlaneMachineSet while any deleted Machine is held.
+func (r *ControlPlaneMachineSetReconciler) reconcileMachineFinalizers(ctx context.Context, logger logr.Logger, cpms *machinev1.ControlPlaneMachineSet, machineInfos map[int32][]machineproviders.MachineInfo, membership etcdMembership) (ctrl.Result, error) {
+	var retained func(machineproviders.MachineInfo) bool
+
+	if membership.known {
+		nodeList := &corev1.NodeList{}
+		if err := r.List(ctx, nodeList, client.HasLabels{masterNodeRoleLabel}); err != nil {
+			return ctrl.Result{}, fmt.Errorf("failed to list control plane nodes: %w", err)
+		}
+
+		retained = etcdMemberRetained(machineInfos, nodeList.Items, membership.endpoints)
+	}
+
+	result := ctrl.Result{}
+
+	for _, idx := range sortedIndexes(machineInfos) {
+		for _, machineInfo := range machineInfos[idx] {
+			machineRef := machineInfo.MachineRef
+			if machineRef == nil {
+				continue
+			}
+
+			machineLogger := logger.WithValues("index", idx, "machineNamespace", machineRef.ObjectMeta.GetNamespace(), "machineName", machineRef.ObjectMeta.GetName())
+			hasFinalizer := containsString(machineRef.ObjectMeta.GetFinalizers(), managedMachineFinalizer)
+
+			switch {
+			case machineRef.ObjectMeta.GetDeletionTimestamp() == nil:
+				if hasFinalizer || r.APIReader == nil {
+					continue
+				}
+
+				if err := r.addMachineFinalizer(ctx, machineRef, managedMachineFinalizer); err != nil {
+					return ctrl.Result{}, fmt.Errorf("error adding finalizer to machine %s: %w", machineRef.ObjectMeta.GetName(), err)
+				}
+
+				machineLogger.V(4).Info(addedMachineFinalizer)
+			case !hasFinalizer:
+				continue
+			case retained != nil && retained(machineInfo):
+				machineLogger.V(1).Info(waitingForEtcdMemberRemoval)
+
+				result = mergeResults(result, ctrl.Result{RequeueAfter: managedMachineFinalizerResyncPeriod})
+			default:
+				if err := r.removeMachineFinalizers(ctx, machineRef, []string{managedMachineFinalizer}); err != nil {
+					return ctrl.Result{}, fmt.Errorf("error removing finalizer from machine %s: %w", machineRef.ObjectMeta.GetName(), err)
+				}
+
+				machineLogger.V(1).Info(releasedMachineFinalizer)
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// etcdMemberRetained returns a function that determines whether the etcd member of a Machine is still part of the
+// etcd membership.
+// The member of a Machine whose Node still exists is found by the addresses of the Node. Once the Node has been
+// removed, its member can no longer be identified, so the member is considered retained while any etcd member does
+// not belong to another Machine.
+func etcdMemberRetained(machineInfos map[int32][]machineproviders.MachineInfo, nodes []corev1.Node, etcdEndpoints map[string]string) func(machineproviders.MachineInfo) bool {
+	memberIDs := make(map[string]string, len(etcdEndpoints))
+	for memberID, address := range etcdEndpoints {
+		memberIDs[address] = memberID
+	}
+
+	nodesByName := make(map[string]*corev1.Node, len(nodes))
+	for i := range nodes {
+		nodesByName[nodes[i].GetName()] = &nodes[i]
+	}
+
+	matchedMembers := map[string]struct{}{}
+
+	for _, indexMachineInfos := range machineInfos {
+		for _, machineInfo := range indexMachineInfos {
+			if memberID, _, ok := nodeEtcdMember(machineInfo, nodesByName, memberIDs); ok {
+				matchedMembers[memberID] = struct{}{}
+			}
+		}
+	}
+
+	unmatchedMembers := len(unmatchedEtcdMembers(etcdEndpoints, matchedMembers)) > 0
+
+	return func(machineInfo machineproviders.MachineInfo) bool {
+		if machineInfo.NodeRef == nil {
+			return false
+		}
+
+		if _, ok := nodesByName[machineInfo.NodeRef.ObjectMeta.GetName()]; !ok {
+			return unmatchedMembers
+		}
+
+		_, _, ok := nodeEtcdMember(machineInfo, nodesByName, memberIDs)
+
+		return ok
+	}
+}
+
+// addMachineFinalizer adds the finalizer given to the Machine referenced by the machineRef.
+// It uses PartialObjectMetadata so that the finalizers can be updated on any type, given the GVR and existing
+// ObjectMeta.
+func (r *ControlPlaneMachineSetReconciler) addMachineFinalizer(ctx context.Context, machineRef *machineproviders.ObjectRef, finalizer string) error {
+	gvk, err := r.RESTMapper.KindFor(machineRef.GroupVersionResource)
+	if err != nil {
+		return fmt.Errorf("could not get GroupVersionKind for machine: %w", err)
+	}
+
+	machine := &metav1.PartialObjectMetadata{}
+	machine.SetGroupVersionKind(gvk)
+	machine.SetName(machineRef.ObjectMeta.GetName())
+	machine.SetNamespace(machineRef.ObjectMeta.GetNamespace())
+	machine.SetFinalizers(machineRef.ObjectMeta.GetFinalizers())
+
+	patchBase := client.MergeFrom(machine.DeepCopy())
+
+	machine.SetFinalizers(append(machineRef.ObjectMeta.GetFinalizers(), finalizer))
+
+	if err := r.Patch(ctx, machine, patchBase); err != nil {
+		return fmt.Errorf("could not patch machine finalizers: %w", err)
+	}
+
+	return nil
+}
+
+// releaseMachineFinalizers removes the managed Machine finalizer from every Control Plane Machine, identified by its
+// role label, when there is no ControlPlaneMachineSet to order their removal.
+func (r *ControlPlaneMachineSetReconciler) releaseMachineFinalizers(ctx context.Context, logger logr.Logger) error {
+	machineList := &machinev1beta1.MachineList{}
+	if err := r.List(ctx, machineList, client.InNamespace(r.Namespace), client.MatchingLabels{machineRoleLabelName: machineMasterRoleLabelName}); err != nil {
+		return fmt.Errorf("could not list machines: %w", err)
+	}
+
+	for i := range machineList.Items {
+		machine := &machineList.Items[i]
+		if !containsString(machine.GetFinalizers(), managedMachineFinalizer) {
+			continue
+		}
+
+		patchBase := client.MergeFrom(machine.DeepCopy())
+
+		finalizers := []string{}
+
+		for _, finalizer := range machine.GetFinalizers() {
+			if finalizer != managedMachineFinalizer {
+				finalizers = append(finalizers, finalizer)
+			}
+		}
+
+		machine.SetFinalizers(finalizers)
+
+		if err := r.Patch(ctx, machine, patchBase); err != nil {
+			return fmt.Errorf("could not patch finalizers of machine %s: %w", machine.GetName(), err)
+		}
+
+		logger.V(1).Info(releasedMachineFinalizer, "machineNamespace", machine.GetNamespace(), "machineName", machine.GetName())
+	}
+
+	return nil
+}
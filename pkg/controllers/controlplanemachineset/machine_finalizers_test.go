@@ -0,0 +1,268 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controlplanemachineset
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	machinev1 "github.com/openshift/api/machine/v1"
+	machinev1beta1 "github.com/openshift/api/machine/v1beta1"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/test"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/test/resourcebuilder"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/envtest/komega"
+)
+
+var _ = Describe("Managed machine finalizers", func() {
+	machineGVR := machinev1beta1.GroupVersion.WithResource("machines")
+
+	Context("etcdMemberRetained", func() {
+		machineBuilder := resourcebuilder.MachineInfo().
+			WithMachineGVR(machineGVR).
+			WithNodeGVR(corev1.SchemeGroupVersion.WithResource("nodes"))
+
+		node := func(name, address string) corev1.Node {
+			return corev1.Node{
+				ObjectMeta: metav1.ObjectMeta{Name: name},
+				Status:     corev1.NodeStatus{Addresses: []corev1.NodeAddress{{Type: corev1.NodeInternalIP, Address: address}}},
+			}
+		}
+
+		remaining := machineBuilder.WithIndex(0).WithMachineName("machine-0").WithNodeName("master-0").Build()
+		nodes := []corev1.Node{node("master-0", "10.0.0.10"), node("master-1", "10.0.0.11")}
+
+		type etcdMemberRetainedTableInput struct {
+			deleted       machineproviders.MachineInfo
+			etcdEndpoints map[string]string
+			expected      bool
+		}
+
+		DescribeTable("should determine whether the etcd member of the machine remains", func(in etcdMemberRetainedTableInput) {
+			machineInfos := map[int32][]machineproviders.MachineInfo{
+				0: {remaining},
+				1: {in.deleted},
+			}
+
+			Expect(etcdMemberRetained(machineInfos, nodes, in.etcdEndpoints)(in.deleted)).To(Equal(in.expected))
+		},
+			Entry("with a node that is still a member", etcdMemberRetainedTableInput{
+				deleted:       machineBuilder.WithIndex(1).WithMachineName("machine-1").WithNodeName("master-1").Build(),
+				etcdEndpoints: map[string]string{"member-0": "10.0.0.10", "member-1": "10.0.0.11"},
+				expected:      true,
+			}),
+			Entry("with a node whose member has been removed", etcdMemberRetainedTableInput{
+				deleted:       machineBuilder.WithIndex(1).WithMachineName("machine-1").WithNodeName("master-1").Build(),
+				etcdEndpoints: map[string]string{"member-0": "10.0.0.10"},
+				expected:      false,
+			}),
+			Entry("with no node", etcdMemberRetainedTableInput{
+				deleted:       machineBuilder.WithIndex(1).WithMachineName("machine-1").Build(),
+				etcdEndpoints: map[string]string{"member-0": "10.0.0.10", "member-1": "10.0.0.11"},
+				expected:      false,
+			}),
+			Entry("with a removed node, while a member belongs to no other machine", etcdMemberRetainedTableInput{
+				deleted:       machineBuilder.WithIndex(1).WithMachineName("machine-1").WithNodeName("master-3").Build(),
+				etcdEndpoints: map[string]string{"member-0": "10.0.0.10", "member-3": "10.0.0.13"},
+				expected:      true,
+			}),
+			Entry("with a removed node, when every member belongs to another machine", etcdMemberRetainedTableInput{
+				deleted:       machineBuilder.WithIndex(1).WithMachineName("machine-1").WithNodeName("master-3").Build(),
+				etcdEndpoints: map[string]string{"member-0": "10.0.0.10"},
+				expected:      false,
+			}),
+		)
+	})
+
+	Context("reconcileMachineFinalizers", func() {
+		var namespaceName string
+		var reconciler *ControlPlaneMachineSetReconciler
+		var logger test.TestLogger
+		var cpms *machinev1.ControlPlaneMachineSet
+		var machine *machinev1beta1.Machine
+		var nodeName string
+
+		machineInfoFor := func(machine *machinev1beta1.Machine) machineproviders.MachineInfo {
+			Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(machine), machine)).To(Succeed())
+
+			builder := resourcebuilder.MachineInfo().
+				WithIndex(0).
+				WithMachineGVR(machineGVR).
+				WithMachineName(machine.GetName()).
+				WithMachineNamespace(machine.GetNamespace()).
+				WithMachineFinalizers(machine.GetFinalizers()...).
+				WithNodeGVR(corev1.SchemeGroupVersion.WithResource("nodes")).
+				WithNodeName(nodeName)
+
+			if deletionTimestamp := machine.GetDeletionTimestamp(); deletionTimestamp != nil {
+				builder = builder.WithMachineDeletionTimestamp(*deletionTimestamp)
+			}
+
+			return builder.Build()
+		}
+
+		createEtcdEndpoints := func(data map[string]string) {
+			ns := resourcebuilder.Namespace().WithName(etcdNamespace).Build()
+			if err := k8sClient.Create(ctx, ns); !apierrors.IsAlreadyExists(err) {
+				Expect(err).ToNot(HaveOccurred())
+			}
+
+			configMap := &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: etcdEndpointsConfigMapName, Namespace: etcdNamespace},
+				Data:       data,
+			}
+			Expect(k8sClient.Create(ctx, configMap)).To(Succeed())
+
+			DeferCleanup(func() {
+				Expect(k8sClient.Delete(ctx, configMap)).To(Succeed())
+			})
+		}
+
+		// reconcileFinalizers reads the etcd membership, as the reconcile does, before reconciling the finalizers.
+		reconcileFinalizers := func() (ctrl.Result, error) {
+			membership, err := reconciler.etcdMembership(ctx)
+			Expect(err).ToNot(HaveOccurred())
+
+			return reconciler.reconcileMachineFinalizers(ctx, logger.Logger(), cpms, map[int32][]machineproviders.MachineInfo{0: {machineInfoFor(machine)}}, membership)
+		}
+
+		BeforeEach(func() {
+			By("Setting up a namespace for the test")
+			ns := resourcebuilder.Namespace().WithGenerateName("control-plane-machine-set-machine-finalizers-").Build()
+			Expect(k8sClient.Create(ctx, ns)).To(Succeed())
+			namespaceName = ns.GetName()
+
+			reconciler = &ControlPlaneMachineSetReconciler{
+				Client:     k8sClient,
+				APIReader:  k8sClient,
+				Scheme:     testScheme,
+				RESTMapper: testRESTMapper,
+				Namespace:  namespaceName,
+			}
+
+			logger = test.NewTestLogger()
+			cpms = resourcebuilder.ControlPlaneMachineSet().WithNamespace(namespaceName).Build()
+
+			By("Creating a control plane node with an address")
+			node := resourcebuilder.Node().AsMaster().WithGenerateName("machine-finalizers-").Build()
+			Expect(k8sClient.Create(ctx, node)).To(Succeed())
+			node.Status.Addresses = []corev1.NodeAddress{{Type: corev1.NodeInternalIP, Address: "10.0.0.10"}}
+			Expect(k8sClient.Status().Update(ctx, node)).To(Succeed())
+			nodeName = node.GetName()
+
+			machine = resourcebuilder.Machine().WithNamespace(namespaceName).WithGenerateName("machine-finalizers-test-").Build()
+			Expect(k8sClient.Create(ctx, machine)).To(Succeed())
+		})
+
+		AfterEach(func() {
+			test.CleanupResources(Default, ctx, cfg, k8sClient, namespaceName,
+				&machinev1beta1.Machine{},
+				&corev1.Node{},
+			)
+		})
+
+		It("adds the finalizer to a managed machine", func() {
+			result, err := reconcileFinalizers()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result).To(Equal(ctrl.Result{}))
+
+			Eventually(komega.Object(machine)).Should(HaveField("ObjectMeta.Finalizers", ConsistOf(managedMachineFinalizer)))
+		})
+
+		It("does not add the finalizer without an API reader", func() {
+			reconciler.APIReader = nil
+
+			_, err := reconcileFinalizers()
+			Expect(err).ToNot(HaveOccurred())
+
+			Consistently(komega.Object(machine)).Should(HaveField("ObjectMeta.Finalizers", BeEmpty()))
+		})
+
+		Context("with a deleted machine holding the finalizer", func() {
+			BeforeEach(func() {
+				Eventually(komega.Update(machine, func() {
+					machine.SetFinalizers([]string{managedMachineFinalizer})
+				})).Should(Succeed())
+				Expect(k8sClient.Delete(ctx, machine)).To(Succeed())
+			})
+
+			It("holds the machine while its etcd member remains", func() {
+				createEtcdEndpoints(map[string]string{"member-0": "10.0.0.10"})
+
+				result, err := reconcileFinalizers()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(result).To(Equal(ctrl.Result{RequeueAfter: managedMachineFinalizerResyncPeriod}))
+
+				Consistently(komega.Object(machine)).Should(HaveField("ObjectMeta.Finalizers", ConsistOf(managedMachineFinalizer)))
+				Expect(logger.Entries()).To(ConsistOf(test.LogEntry{
+					KeysAndValues: []interface{}{"index", int32(0), "machineNamespace", namespaceName, "machineName", machine.GetName()},
+					Level:         1,
+					Message:       waitingForEtcdMemberRemoval,
+				}))
+			})
+
+			It("releases the machine once its etcd member has been removed", func() {
+				createEtcdEndpoints(map[string]string{"member-1": "10.0.0.11"})
+
+				result, err := reconcileFinalizers()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(result).To(Equal(ctrl.Result{}))
+
+				Eventually(komega.Get(machine)).Should(MatchError(ContainSubstring("not found")))
+			})
+
+			It("releases the machine when the etcd membership is not known", func() {
+				_, err := reconcileFinalizers()
+				Expect(err).ToNot(HaveOccurred())
+
+				Eventually(komega.Get(machine)).Should(MatchError(ContainSubstring("not found")))
+				Expect(logger.Entries()).To(ConsistOf(test.LogEntry{
+					KeysAndValues: []interface{}{"index", int32(0), "machineNamespace", namespaceName, "machineName", machine.GetName()},
+					Level:         1,
+					Message:       releasedMachineFinalizer,
+				}))
+			})
+		})
+	})
+
+	Context("releaseMachineFinalizers", func() {
+		It("removes the finalizer from every control plane machine", func() {
+			ns := resourcebuilder.Namespace().WithGenerateName("control-plane-machine-set-machine-finalizers-").Build()
+			Expect(k8sClient.Create(ctx, ns)).To(Succeed())
+
+			DeferCleanup(func() {
+				test.CleanupResources(Default, ctx, cfg, k8sClient, ns.GetName(), &machinev1beta1.Machine{})
+			})
+
+			reconciler := &ControlPlaneMachineSetReconciler{Client: k8sClient, Scheme: testScheme, Namespace: ns.GetName()}
+
+			machine := resourcebuilder.Machine().AsMaster().WithNamespace(ns.GetName()).WithGenerateName("machine-finalizers-test-").Build()
+			machine.SetFinalizers([]string{managedMachineFinalizer, machinev1beta1.MachineFinalizer})
+			Expect(k8sClient.Create(ctx, machine)).To(Succeed())
+
+			Expect(reconciler.releaseMachineFinalizers(ctx, test.NewTestLogger().Logger())).To(Succeed())
+
+			Eventually(komega.Object(machine)).Should(HaveField("ObjectMeta.Finalizers", ConsistOf(machinev1beta1.MachineFinalizer)))
+		})
+	})
+})
@@ -0,0 +1,109 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controlplanemachineset
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/go-logr/logr"
+	machinev1 "github.com/openshift/api/machine/v1"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// recordedMachineIndexes is a log message used to inform the user that the indexes of the Control Plane Machines
+	// have been recorded.
+	recordedMachineIndexes = "Recorded control plane machine indexes"
+)
+
+// recordMachineIndexes records the index of each Control Plane Machine with a Node, keyed by the name of the Node.
+// When a Machine is renamed, for example when Machines are recreated after a cluster restore, its index can then no
+// longer be parsed from its name, and is instead determined by the machine provider from the record.
+// Indexes recorded for Nodes that still exist, but are no longer backed by a Machine, are kept, so that a Machine
+// recreated for such a Node is mapped back to the same index. Indexes recorded for Nodes that no longer exist are
+// removed. The record is only written when it changes.
+func (r *ControlPlaneMachineSetReconciler) recordMachineIndexes(ctx context.Context, logger logr.Logger, cpms *machinev1.ControlPlaneMachineSet, machineInfos map[int32][]machineproviders.MachineInfo) error {
+	configMap := &corev1.ConfigMap{}
+	configMapKey := client.ObjectKey{Namespace: cpms.GetNamespace(), Name: machineproviders.MachineIndexesConfigMapName}
+
+	exists := true
+	if err := r.Get(ctx, configMapKey, configMap); apierrors.IsNotFound(err) {
+		exists = false
+	} else if err != nil {
+		return fmt.Errorf("could not fetch recorded machine indexes: %w", err)
+	}
+
+	nodeList := &corev1.NodeList{}
+	if err := r.List(ctx, nodeList, client.HasLabels{masterNodeRoleLabel}); err != nil {
+		return fmt.Errorf("failed to list control plane nodes: %w", err)
+	}
+
+	nodeNames := map[string]struct{}{}
+	for _, node := range nodeList.Items {
+		nodeNames[node.GetName()] = struct{}{}
+	}
+
+	desired := map[string]string{}
+
+	for nodeName, index := range configMap.Data {
+		if _, ok := nodeNames[nodeName]; ok {
+			desired[nodeName] = index
+		}
+	}
+
+	for _, idx := range sortedIndexes(machineInfos) {
+		for _, machineInfo := range machineInfos[idx] {
+			if machineInfo.MachineRef == nil || machineInfo.NodeRef == nil || machineInfo.MachineRef.ObjectMeta.GetDeletionTimestamp() != nil {
+				continue
+			}
+
+			desired[machineInfo.NodeRef.ObjectMeta.GetName()] = strconv.Itoa(int(idx))
+		}
+	}
+
+	if !exists && len(desired) == 0 || exists && equality.Semantic.DeepEqual(configMap.Data, desired) {
+		return nil
+	}
+
+	if !exists {
+		configMap = &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Namespace: configMapKey.Namespace, Name: configMapKey.Name},
+			Data:       desired,
+		}
+
+		if err := r.Create(ctx, configMap); err != nil {
+			return fmt.Errorf("could not create recorded machine indexes: %w", err)
+		}
+	} else {
+		configMap.Data = desired
+
+		if err := r.Update(ctx, configMap); err != nil {
+			return fmt.Errorf("could not update recorded machine indexes: %w", err)
+		}
+	}
+
+	logger.V(2).Info(recordedMachineIndexes, "indexes", len(desired))
+
+	return nil
+}
@@ -0,0 +1,151 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controlplanemachineset
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	machinev1 "github.com/openshift/api/machine/v1"
+	machinev1beta1 "github.com/openshift/api/machine/v1beta1"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/test"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/test/resourcebuilder"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/envtest/komega"
+)
+
+var _ = Describe("recordMachineIndexes", func() {
+	machineGVR := machinev1beta1.GroupVersion.WithResource("machines")
+	nodeGVR := corev1.SchemeGroupVersion.WithResource("nodes")
+
+	healthyMachineBuilder := resourcebuilder.MachineInfo().
+		WithMachineGVR(machineGVR).
+		WithNodeGVR(nodeGVR).
+		WithReady(true)
+
+	var namespaceName string
+	var reconciler *ControlPlaneMachineSetReconciler
+	var cpms *machinev1.ControlPlaneMachineSet
+	var logger test.TestLogger
+	var machineInfos map[int32][]machineproviders.MachineInfo
+
+	BeforeEach(func() {
+		By("Setting up a namespace for the test")
+		ns := resourcebuilder.Namespace().WithGenerateName("control-plane-machine-set-controller-").Build()
+		Expect(k8sClient.Create(ctx, ns)).To(Succeed())
+		namespaceName = ns.GetName()
+
+		reconciler = &ControlPlaneMachineSetReconciler{
+			Client:    k8sClient,
+			Namespace: namespaceName,
+		}
+
+		cpms = resourcebuilder.ControlPlaneMachineSet().WithNamespace(namespaceName).Build()
+		logger = test.NewTestLogger()
+
+		By("Creating the control plane Nodes")
+		for _, nodeName := range []string{"master-0", "master-1", "master-2", "master-restored"} {
+			Expect(k8sClient.Create(ctx, resourcebuilder.Node().AsMaster().WithName(nodeName).Build())).To(Succeed())
+		}
+
+		machineInfos = map[int32][]machineproviders.MachineInfo{
+			0: {healthyMachineBuilder.WithIndex(0).WithMachineName("machine-0").WithNodeName("master-0").Build()},
+			1: {healthyMachineBuilder.WithIndex(1).WithMachineName("machine-1").WithNodeName("master-1").Build()},
+			2: {healthyMachineBuilder.WithIndex(2).WithMachineName("machine-2").WithNodeName("master-2").Build()},
+		}
+	})
+
+	AfterEach(func() {
+		test.CleanupResources(Default, ctx, cfg, k8sClient, namespaceName,
+			&corev1.ConfigMap{},
+			&corev1.Node{},
+		)
+	})
+
+	configMap := func() *corev1.ConfigMap {
+		return &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: namespaceName, Name: machineproviders.MachineIndexesConfigMapName}}
+	}
+
+	It("records the index of each machine by the name of its node", func() {
+		Expect(reconciler.recordMachineIndexes(ctx, logger.Logger(), cpms, machineInfos)).To(Succeed())
+
+		Expect(komega.Object(configMap())()).To(HaveField("Data", Equal(map[string]string{
+			"master-0": "0",
+			"master-1": "1",
+			"master-2": "2",
+		})))
+		Expect(logger.Entries()).To(ConsistOf(test.LogEntry{
+			Level:         2,
+			KeysAndValues: []interface{}{"indexes", 3},
+			Message:       recordedMachineIndexes,
+		}))
+	})
+
+	It("does not update the record when it has not changed", func() {
+		Expect(reconciler.recordMachineIndexes(ctx, logger.Logger(), cpms, machineInfos)).To(Succeed())
+
+		cm := configMap()
+		Expect(komega.Get(cm)()).To(Succeed())
+		resourceVersion := cm.GetResourceVersion()
+
+		logger = test.NewTestLogger()
+		Expect(reconciler.recordMachineIndexes(ctx, logger.Logger(), cpms, machineInfos)).To(Succeed())
+
+		Expect(komega.Object(configMap())()).To(HaveField("ObjectMeta.ResourceVersion", Equal(resourceVersion)))
+		Expect(logger.Entries()).To(BeEmpty())
+	})
+
+	It("ignores machines without a node, or pending deletion", func() {
+		deleting := machineInfos[2][0]
+		deleting.MachineRef.ObjectMeta.DeletionTimestamp = &metav1.Time{}
+		machineInfos[1] = []machineproviders.MachineInfo{healthyMachineBuilder.WithIndex(1).WithMachineName("machine-1").WithReady(false).Build()}
+		machineInfos[2] = []machineproviders.MachineInfo{deleting}
+
+		Expect(reconciler.recordMachineIndexes(ctx, logger.Logger(), cpms, machineInfos)).To(Succeed())
+
+		Expect(komega.Object(configMap())()).To(HaveField("Data", Equal(map[string]string{
+			"master-0": "0",
+		})))
+	})
+
+	It("keeps indexes recorded for nodes that still exist, and removes those for nodes that do not", func() {
+		cm := configMap()
+		cm.Data = map[string]string{
+			"master-restored": "1",
+			"master-removed":  "2",
+		}
+		Expect(k8sClient.Create(ctx, cm)).To(Succeed())
+
+		delete(machineInfos, 1)
+
+		Expect(reconciler.recordMachineIndexes(ctx, logger.Logger(), cpms, machineInfos)).To(Succeed())
+
+		Expect(komega.Object(configMap())()).To(HaveField("Data", Equal(map[string]string{
+			"master-0":        "0",
+			"master-2":        "2",
+			"master-restored": "1",
+		})))
+	})
+
+	It("does not create a record when there are no machines with nodes", func() {
+		Expect(reconciler.recordMachineIndexes(ctx, logger.Logger(), cpms, map[int32][]machineproviders.MachineInfo{})).To(Succeed())
+
+		Expect(komega.Get(configMap())()).ToNot(Succeed())
+	})
+})
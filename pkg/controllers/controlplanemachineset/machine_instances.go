@@ -0,0 +1,101 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controlplanemachineset
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	machinev1 "github.com/openshift/api/machine/v1"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func init() {
+	registerInformationalCondition(conditionMachineInstances)
+}
+
+// pendingProviderID is used within the machine instances condition in place of the provider ID of a Machine whose
+// cloud instance has not yet been created.
+const pendingProviderID = "pending"
+
+// setMachineInstancesCondition sets the machine instances condition to map each index to the cloud instances backing
+// its Machines. The message lists the Machines of each index, in index order, with their provider IDs, eg
+// `0=master-0 (aws:///us-east-1a/i-0), 1=master-1 (aws:///us-east-1b/i-1) + master-4 (pending)`.
+// Machines being deleted are included, as their cloud instances still exist until the deletion completes.
+// The condition is true once every Machine has a provider ID, and is removed when there are no Machines.
+func setMachineInstancesCondition(cpms *machinev1.ControlPlaneMachineSet, machineInfos map[int32][]machineproviders.MachineInfo) {
+	indexInstances := []string{}
+	pending := false
+
+	for _, idx := range sortedIndexes(machineInfos) {
+		instances := []string{}
+
+		for _, machineInfo := range sortedByMachineName(machineInfos[idx]) {
+			providerID := machineInfo.ProviderID
+			if providerID == "" {
+				providerID = pendingProviderID
+				pending = true
+			}
+
+			instances = append(instances, fmt.Sprintf("%s (%s)", machineInfo.MachineRef.ObjectMeta.GetName(), providerID))
+		}
+
+		if len(instances) > 0 {
+			indexInstances = append(indexInstances, fmt.Sprintf("%d=%s", idx, strings.Join(instances, " + ")))
+		}
+	}
+
+	if len(indexInstances) == 0 {
+		meta.RemoveStatusCondition(&cpms.Status.Conditions, conditionMachineInstances)
+		return
+	}
+
+	condition := metav1.Condition{
+		Type:               conditionMachineInstances,
+		Status:             metav1.ConditionTrue,
+		Reason:             reasonProviderIDsKnown,
+		ObservedGeneration: cpms.GetGeneration(),
+		Message:            strings.Join(indexInstances, ", "),
+	}
+
+	if pending {
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = reasonProviderIDsPending
+	}
+
+	meta.SetStatusCondition(&cpms.Status.Conditions, condition)
+}
+
+// sortedByMachineName returns the MachineInfos that reference a Machine, ordered by the name of the Machine.
+func sortedByMachineName(machineInfos []machineproviders.MachineInfo) []machineproviders.MachineInfo {
+	sorted := []machineproviders.MachineInfo{}
+
+	for _, machineInfo := range machineInfos {
+		if machineInfo.MachineRef != nil {
+			sorted = append(sorted, machineInfo)
+		}
+	}
+
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].MachineRef.ObjectMeta.GetName() < sorted[j].MachineRef.ObjectMeta.GetName()
+	})
+
+	return sorted
+}
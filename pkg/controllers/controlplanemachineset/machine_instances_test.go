@@ -0,0 +1,108 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controlplanemachineset
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	machinev1beta1 "github.com/openshift/api/machine/v1beta1"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/test"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/test/resourcebuilder"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("setMachineInstancesCondition", func() {
+	machineBuilder := resourcebuilder.MachineInfo().
+		WithMachineGVR(machinev1beta1.GroupVersion.WithResource("machines")).
+		WithReady(true)
+
+	type machineInstancesTableInput struct {
+		machineInfos      map[int32][]machineproviders.MachineInfo
+		existingCondition *metav1.Condition
+		expectedCondition *metav1.Condition
+	}
+
+	DescribeTable("should map each index to its cloud instances", func(in machineInstancesTableInput) {
+		cpms := resourcebuilder.ControlPlaneMachineSet().WithGeneration(2).Build()
+		if in.existingCondition != nil {
+			cpms.Status.Conditions = []metav1.Condition{*in.existingCondition}
+		}
+
+		setMachineInstancesCondition(cpms, in.machineInfos)
+
+		if in.expectedCondition == nil {
+			Expect(cpms.Status.Conditions).To(BeEmpty())
+		} else {
+			Expect(cpms.Status.Conditions).To(test.MatchConditions([]metav1.Condition{*in.expectedCondition}))
+		}
+	},
+		Entry("with every Machine having a provider ID", machineInstancesTableInput{
+			machineInfos: map[int32][]machineproviders.MachineInfo{
+				0: {machineBuilder.WithIndex(0).WithMachineName("machine-0").WithProviderID("aws:///us-east-1a/i-0").Build()},
+				1: {machineBuilder.WithIndex(1).WithMachineName("machine-1").WithProviderID("aws:///us-east-1b/i-1").Build()},
+				2: {machineBuilder.WithIndex(2).WithMachineName("machine-2").WithProviderID("aws:///us-east-1c/i-2").Build()},
+			},
+			expectedCondition: &metav1.Condition{
+				Type:               conditionMachineInstances,
+				Status:             metav1.ConditionTrue,
+				Reason:             reasonProviderIDsKnown,
+				ObservedGeneration: 2,
+				Message:            "0=machine-0 (aws:///us-east-1a/i-0), 1=machine-1 (aws:///us-east-1b/i-1), 2=machine-2 (aws:///us-east-1c/i-2)",
+			},
+		}),
+		Entry("with a replacement Machine awaiting its instance", machineInstancesTableInput{
+			machineInfos: map[int32][]machineproviders.MachineInfo{
+				0: {machineBuilder.WithIndex(0).WithMachineName("machine-0").WithProviderID("aws:///us-east-1a/i-0").Build()},
+				1: {
+					machineBuilder.WithIndex(1).WithMachineName("machine-4").WithReady(false).Build(),
+					machineBuilder.WithIndex(1).WithMachineName("machine-1").WithNeedsUpdate(true).WithProviderID("aws:///us-east-1b/i-1").Build(),
+				},
+			},
+			expectedCondition: &metav1.Condition{
+				Type:               conditionMachineInstances,
+				Status:             metav1.ConditionFalse,
+				Reason:             reasonProviderIDsPending,
+				ObservedGeneration: 2,
+				Message:            "0=machine-0 (aws:///us-east-1a/i-0), 1=machine-1 (aws:///us-east-1b/i-1) + machine-4 (pending)",
+			},
+		}),
+		Entry("with an index that has no Machine", machineInstancesTableInput{
+			machineInfos: map[int32][]machineproviders.MachineInfo{
+				0: {machineBuilder.WithIndex(0).WithMachineName("machine-0").WithProviderID("aws:///us-east-1a/i-0").Build()},
+				1: {},
+			},
+			expectedCondition: &metav1.Condition{
+				Type:               conditionMachineInstances,
+				Status:             metav1.ConditionTrue,
+				Reason:             reasonProviderIDsKnown,
+				ObservedGeneration: 2,
+				Message:            "0=machine-0 (aws:///us-east-1a/i-0)",
+			},
+		}),
+		Entry("with no Machines, removing an existing condition", machineInstancesTableInput{
+			machineInfos: map[int32][]machineproviders.MachineInfo{},
+			existingCondition: &metav1.Condition{
+				Type:    conditionMachineInstances,
+				Status:  metav1.ConditionTrue,
+				Reason:  reasonProviderIDsKnown,
+				Message: "0=machine-0 (aws:///us-east-1a/i-0)",
+			},
+		}),
+	)
+})
@@ -0,0 +1,42 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controlplanemachineset
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+var (
+	// csrPendingApprovalMachines is the number of Control Plane Machines whose Node cannot join the cluster until a
+	// pending certificate signing request is approved.
+	csrPendingApprovalMachines = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "control_plane_machine_set_csr_pending_approval_machines",
+		Help: "Number of control plane machines whose node is waiting for a certificate signing request to be approved.",
+	})
+
+	// failedMachines is the number of failed Control Plane Machines, labelled by the error reason reported within the
+	// status of each Machine.
+	failedMachines = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "control_plane_machine_set_failed_machines",
+		Help: "Number of failed control plane machines, by the error reason reported by the machine.",
+	}, []string{failedMachinesReasonLabel})
+)
+
+func init() {
+	metrics.Registry.MustRegister(csrPendingApprovalMachines, failedMachines)
+}
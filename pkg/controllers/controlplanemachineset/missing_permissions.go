@@ -0,0 +1,53 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controlplanemachineset
+
+import (
+	"fmt"
+
+	"github.com/go-logr/logr"
+	machinev1 "github.com/openshift/api/machine/v1"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/permissions"
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	// missingPermissions is a log message used to inform the user that the operator is missing permissions that it
+	// requires, and so will not take any action until they have been granted.
+	missingPermissions = "Missing permissions, the control plane machine set will not take any action until these have been granted and the operator restarted"
+)
+
+// missingPermissionsMessage describes the missing permissions, for the degraded conditions of the
+// ControlPlaneMachineSet and the cluster operator.
+func missingPermissionsMessage(missing []permissions.Permission) string {
+	return fmt.Sprintf("The operator service account is missing %d permission(s) required by the controller, "+
+		"grant them and restart the operator: %s", len(missing), permissions.Summarise(missing))
+}
+
+// setMissingPermissions marks the ControlPlaneMachineSet as degraded with the missing permissions, and publishes a
+// warning event with the same reason.
+func (r *ControlPlaneMachineSetReconciler) setMissingPermissions(logger logr.Logger, cpms *machinev1.ControlPlaneMachineSet) {
+	message := missingPermissionsMessage(r.MissingPermissions)
+
+	logger.Info(missingPermissions, "permissions", permissions.Summarise(r.MissingPermissions))
+
+	setDegradedCondition(cpms, reasonMissingPermissions, message)
+
+	if r.Recorder != nil {
+		r.Recorder.Event(cpms, corev1.EventTypeWarning, reasonMissingPermissions, message)
+	}
+}
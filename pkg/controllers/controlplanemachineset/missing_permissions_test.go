@@ -0,0 +1,103 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controlplanemachineset
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/permissions"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/test"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/test/resourcebuilder"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+var _ = Describe("Missing permissions", func() {
+	const expectedMessage = "The operator service account is missing 2 permission(s) required by the controller, grant them and restart the operator: " +
+		"delete machines.machine.openshift.io in namespace openshift-machine-api, get configmaps etcd-endpoints in namespace openshift-etcd"
+
+	var logger test.TestLogger
+	var recorder *record.FakeRecorder
+	var reconciler *ControlPlaneMachineSetReconciler
+
+	BeforeEach(func() {
+		logger = test.NewTestLogger()
+		recorder = record.NewFakeRecorder(10)
+		reconciler = &ControlPlaneMachineSetReconciler{
+			Recorder: recorder,
+			MissingPermissions: []permissions.Permission{
+				{Group: "machine.openshift.io", Resource: "machines", Namespace: "openshift-machine-api", Verb: "delete"},
+				{Resource: "configmaps", Name: "etcd-endpoints", Namespace: "openshift-etcd", Verb: "get"},
+			},
+		}
+	})
+
+	It("should set the degraded condition with the missing permissions", func() {
+		cpms := resourcebuilder.ControlPlaneMachineSet().Build()
+		reconciler.setMissingPermissions(logger.Logger(), cpms)
+
+		Expect(cpms.Status.Conditions).To(ConsistOf(
+			test.MatchCondition(metav1.Condition{
+				Type:    conditionDegraded,
+				Status:  metav1.ConditionTrue,
+				Reason:  reasonMissingPermissions,
+				Message: expectedMessage,
+			}),
+			test.MatchCondition(metav1.Condition{
+				Type:   conditionProgressing,
+				Status: metav1.ConditionFalse,
+				Reason: reasonOperatorDegraded,
+			}),
+		))
+	})
+
+	It("should publish a warning event with the missing permissions", func() {
+		cpms := resourcebuilder.ControlPlaneMachineSet().Build()
+		reconciler.setMissingPermissions(logger.Logger(), cpms)
+
+		Expect(recorder.Events).To(Receive(Equal("Warning MissingPermissions " + expectedMessage)))
+	})
+
+	It("should log the missing permissions", func() {
+		cpms := resourcebuilder.ControlPlaneMachineSet().Build()
+		reconciler.setMissingPermissions(logger.Logger(), cpms)
+
+		Expect(logger.Entries()).To(ConsistOf(test.LogEntry{
+			KeysAndValues: []interface{}{"permissions", "delete machines.machine.openshift.io in namespace openshift-machine-api, get configmaps etcd-endpoints in namespace openshift-etcd"},
+			Level:         0,
+			Message:       missingPermissions,
+		}))
+	})
+
+	It("should not take any action while permissions are missing", func() {
+		// The reconciler has no client, so any action would fail.
+		cpms := resourcebuilder.ControlPlaneMachineSet().Build()
+
+		result, err := reconciler.reconcile(ctx, logger.Logger(), cpms)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result).To(Equal(ctrl.Result{}))
+		Expect(cpms.GetFinalizers()).To(BeEmpty())
+		Expect(cpms.Status.Conditions).To(ContainElement(test.MatchCondition(metav1.Condition{
+			Type:    conditionDegraded,
+			Status:  metav1.ConditionTrue,
+			Reason:  reasonMissingPermissions,
+			Message: expectedMessage,
+		})))
+	})
+})
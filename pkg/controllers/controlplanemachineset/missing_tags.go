@@ -0,0 +1,107 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controlplanemachineset
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/go-logr/logr"
+	machinev1 "github.com/openshift/api/machine/v1"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func init() {
+	registerInformationalCondition(conditionMissingTags)
+	registerInformationalCondition(conditionTemplateTagDrift)
+}
+
+const (
+	// observedMissingTags is a log message used to inform the user that a Control Plane Machine is missing tags it
+	// is required to carry.
+	observedMissingTags = "Observed machine missing required tags"
+)
+
+// reconcileMissingTags reports the Control Plane Machines that are missing tags they are required to carry, such as
+// the cluster ownership tag. When RepairMissingTags is enabled, the missing tags are instead added to the Machines
+// in place, and only the Machines that could not be repaired on this reconcile are reported.
+// Machines pending deletion are ignored, as they are about to be removed.
+func (r *ControlPlaneMachineSetReconciler) reconcileMissingTags(ctx context.Context, logger logr.Logger, cpms *machinev1.ControlPlaneMachineSet, machineProvider machineproviders.MachineProvider, machineInfos map[int32][]machineproviders.MachineInfo) error {
+	missing := []string{}
+
+	for _, idx := range sortedIndexes(machineInfos) {
+		for _, machineInfo := range machineInfos[idx] {
+			if machineInfo.MachineRef == nil || machineInfo.MachineRef.ObjectMeta.GetDeletionTimestamp() != nil || len(machineInfo.MissingTags) == 0 {
+				continue
+			}
+
+			machineName := machineInfo.MachineRef.ObjectMeta.GetName()
+
+			if r.OperatorConfig.RepairMissingTags(r.RepairMissingTags) {
+				if err := machineProvider.RepairMachineTags(ctx, logger, machineInfo.MachineRef); err != nil {
+					return fmt.Errorf("error repairing tags of machine %s: %w", machineName, err)
+				}
+
+				continue
+			}
+
+			logger.Info(observedMissingTags, "machineName", machineName, "missingTags", strings.Join(machineInfo.MissingTags, ","))
+
+			missing = append(missing, fmt.Sprintf("%s (%s)", machineName, strings.Join(machineInfo.MissingTags, ", ")))
+		}
+	}
+
+	if len(missing) == 0 {
+		meta.RemoveStatusCondition(&cpms.Status.Conditions, conditionMissingTags)
+
+		return nil
+	}
+
+	meta.SetStatusCondition(&cpms.Status.Conditions, metav1.Condition{
+		Type:               conditionMissingTags,
+		Status:             metav1.ConditionTrue,
+		Reason:             reasonRequiredTagsMissing,
+		ObservedGeneration: cpms.GetGeneration(),
+		Message:            fmt.Sprintf("Found %d machine(s) missing required tags: %s", len(missing), strings.Join(missing, "; ")),
+	})
+
+	return nil
+}
+
+// setTemplateTagDriftCondition reports the required tags, by name, that the Machine template does not carry, or
+// carries with a different value. The required tags are read from the Infrastructure resource whenever the
+// ControlPlaneMachineSet is reconciled, and the Infrastructure resource is watched, so changes to the cluster tag
+// policy are reflected without any change to the ControlPlaneMachineSet.
+// The condition is removed when the template carries every required tag.
+func setTemplateTagDriftCondition(cpms *machinev1.ControlPlaneMachineSet, drifted []string) {
+	if len(drifted) == 0 {
+		meta.RemoveStatusCondition(&cpms.Status.Conditions, conditionTemplateTagDrift)
+
+		return
+	}
+
+	meta.SetStatusCondition(&cpms.Status.Conditions, metav1.Condition{
+		Type:               conditionTemplateTagDrift,
+		Status:             metav1.ConditionTrue,
+		Reason:             reasonTemplateTagsDrifted,
+		ObservedGeneration: cpms.GetGeneration(),
+		Message:            fmt.Sprintf("The machine template does not carry %d tag(s) required by the cluster: %s", len(drifted), strings.Join(drifted, ", ")),
+	})
+}
@@ -0,0 +1,164 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controlplanemachineset
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/golang/mock/gomock"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	machinev1 "github.com/openshift/api/machine/v1"
+	machinev1beta1 "github.com/openshift/api/machine/v1beta1"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders/mock"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/test"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/test/resourcebuilder"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("reconcileMissingTags", func() {
+	var logger test.TestLogger
+	var reconciler *ControlPlaneMachineSetReconciler
+	var cpms *machinev1.ControlPlaneMachineSet
+	var machineInfos map[int32][]machineproviders.MachineInfo
+
+	var mockCtrl *gomock.Controller
+	var mockMachineProvider *mock.MockMachineProvider
+
+	machineGVR := machinev1beta1.GroupVersion.WithResource("machines")
+
+	machineInfo := func(idx int32, missing ...string) machineproviders.MachineInfo {
+		return resourcebuilder.MachineInfo().
+			WithIndex(idx).
+			WithMachineGVR(machineGVR).
+			WithMachineName(fmt.Sprintf("cpms-cluster-test-id-master-%d", idx)).
+			WithReady(true).
+			WithMissingTags(missing).
+			Build()
+	}
+
+	BeforeEach(func() {
+		logger = test.NewTestLogger()
+		reconciler = &ControlPlaneMachineSetReconciler{}
+		cpms = resourcebuilder.ControlPlaneMachineSet().WithGeneration(2).Build()
+
+		mockCtrl = gomock.NewController(GinkgoT())
+		mockMachineProvider = mock.NewMockMachineProvider(mockCtrl)
+
+		machineInfos = map[int32][]machineproviders.MachineInfo{
+			0: {machineInfo(0)},
+			1: {machineInfo(1, "example.com/owner", "kubernetes.io/cluster/cluster-id")},
+			2: {machineInfo(2, "example.com/owner")},
+		}
+	})
+
+	Context("when reporting missing tags", func() {
+		BeforeEach(func() {
+			mockMachineProvider.EXPECT().RepairMachineTags(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+
+			Expect(reconciler.reconcileMissingTags(ctx, logger.Logger(), cpms, mockMachineProvider, machineInfos)).To(Succeed())
+		})
+
+		It("sets the missing tags condition", func() {
+			condition := meta.FindStatusCondition(cpms.Status.Conditions, conditionMissingTags)
+			Expect(condition).ToNot(BeNil())
+			Expect(condition.Status).To(Equal(metav1.ConditionTrue))
+			Expect(condition.Reason).To(Equal(reasonRequiredTagsMissing))
+			Expect(condition.ObservedGeneration).To(Equal(int64(2)))
+			Expect(condition.Message).To(Equal("Found 2 machine(s) missing required tags: " +
+				"cpms-cluster-test-id-master-1 (example.com/owner, kubernetes.io/cluster/cluster-id); " +
+				"cpms-cluster-test-id-master-2 (example.com/owner)"))
+		})
+
+		It("logs the machines missing tags", func() {
+			Expect(logger.Entries()).To(ConsistOf(
+				test.LogEntry{
+					Level:         0,
+					KeysAndValues: []interface{}{"machineName", "cpms-cluster-test-id-master-1", "missingTags", "example.com/owner,kubernetes.io/cluster/cluster-id"},
+					Message:       observedMissingTags,
+				},
+				test.LogEntry{
+					Level:         0,
+					KeysAndValues: []interface{}{"machineName", "cpms-cluster-test-id-master-2", "missingTags", "example.com/owner"},
+					Message:       observedMissingTags,
+				},
+			))
+		})
+	})
+
+	Context("when repairing missing tags", func() {
+		BeforeEach(func() {
+			reconciler.RepairMissingTags = true
+		})
+
+		It("repairs the machines and removes the condition", func() {
+			meta.SetStatusCondition(&cpms.Status.Conditions, metav1.Condition{Type: conditionMissingTags, Status: metav1.ConditionTrue, Reason: reasonRequiredTagsMissing})
+
+			mockMachineProvider.EXPECT().RepairMachineTags(gomock.Any(), gomock.Any(), machineInfos[1][0].MachineRef).Return(nil).Times(1)
+			mockMachineProvider.EXPECT().RepairMachineTags(gomock.Any(), gomock.Any(), machineInfos[2][0].MachineRef).Return(nil).Times(1)
+
+			Expect(reconciler.reconcileMissingTags(ctx, logger.Logger(), cpms, mockMachineProvider, machineInfos)).To(Succeed())
+			Expect(meta.FindStatusCondition(cpms.Status.Conditions, conditionMissingTags)).To(BeNil())
+		})
+
+		It("returns an error when a repair fails", func() {
+			mockMachineProvider.EXPECT().RepairMachineTags(gomock.Any(), gomock.Any(), machineInfos[1][0].MachineRef).Return(errors.New("patch failed")).Times(1)
+
+			Expect(reconciler.reconcileMissingTags(ctx, logger.Logger(), cpms, mockMachineProvider, machineInfos)).To(MatchError("error repairing tags of machine cpms-cluster-test-id-master-1: patch failed"))
+		})
+	})
+
+	It("ignores machines pending deletion", func() {
+		deleting := machineInfos[1][0]
+		deleting.MachineRef.ObjectMeta.DeletionTimestamp = &metav1.Time{}
+		machineInfos[1] = []machineproviders.MachineInfo{deleting}
+		delete(machineInfos, 2)
+
+		Expect(reconciler.reconcileMissingTags(ctx, logger.Logger(), cpms, mockMachineProvider, machineInfos)).To(Succeed())
+		Expect(meta.FindStatusCondition(cpms.Status.Conditions, conditionMissingTags)).To(BeNil())
+	})
+})
+
+var _ = Describe("setTemplateTagDriftCondition", func() {
+	var cpms *machinev1.ControlPlaneMachineSet
+
+	BeforeEach(func() {
+		cpms = resourcebuilder.ControlPlaneMachineSet().WithGeneration(2).Build()
+	})
+
+	It("sets the condition when the template has drifted", func() {
+		setTemplateTagDriftCondition(cpms, []string{"example.com/owner", "kubernetes.io/cluster/cluster-id"})
+
+		condition := meta.FindStatusCondition(cpms.Status.Conditions, conditionTemplateTagDrift)
+		Expect(condition).ToNot(BeNil())
+		Expect(condition.Status).To(Equal(metav1.ConditionTrue))
+		Expect(condition.Reason).To(Equal(reasonTemplateTagsDrifted))
+		Expect(condition.ObservedGeneration).To(Equal(int64(2)))
+		Expect(condition.Message).To(Equal("The machine template does not carry 2 tag(s) required by the cluster: example.com/owner, kubernetes.io/cluster/cluster-id"))
+	})
+
+	It("removes the condition once the template carries every required tag", func() {
+		setTemplateTagDriftCondition(cpms, []string{"example.com/owner"})
+		setTemplateTagDriftCondition(cpms, nil)
+
+		Expect(meta.FindStatusCondition(cpms.Status.Conditions, conditionTemplateTagDrift)).To(BeNil())
+	})
+})
@@ -0,0 +1,144 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controlplanemachineset
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/go-logr/logr"
+	machinev1 "github.com/openshift/api/machine/v1"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+func init() {
+	registerInformationalCondition(conditionRecoveryGuidance)
+}
+
+const (
+	// minimumConcurrentFailures is the number of failed indexes at which the failures are handled together, as
+	// losing one further etcd member could then lose quorum, rather than each failure being handled on its own.
+	minimumConcurrentFailures = 2
+
+	// observedMultipleMachineFailures is a log message used to inform the user that multiple Control Plane Machines
+	// have failed, and that automated deletions have been suspended.
+	observedMultipleMachineFailures = "Observed multiple failed control plane machines, suspending automated deletions"
+)
+
+// errMultipleMachineFailures is used to inform users that multiple Control Plane Machines have failed at once, for
+// example during a zone outage. Deleting any further Control Plane Machine could then break etcd quorum.
+var errMultipleMachineFailures = errors.New("multiple control plane machines have failed")
+
+// failedMachineIndexes returns, in order, the indexes in which every Machine has failed.
+// An index has failed when none of its Machines are ready, and at least one of them has either joined the cluster,
+// and so has stopped being ready, or reports an error. Indexes without Machines, or whose only Machines are still
+// being provisioned, have not failed.
+func failedMachineIndexes(machineInfos map[int32][]machineproviders.MachineInfo) []int32 {
+	failed := []int32{}
+
+	for _, idx := range sortedIndexes(machineInfos) {
+		indexMachineInfos := sortedByMachineName(machineInfos[idx])
+		if len(indexMachineInfos) == 0 || hasReadyMachine(indexMachineInfos) {
+			continue
+		}
+
+		for _, machineInfo := range indexMachineInfos {
+			if machineInfo.NodeRef != nil || machineInfo.ErrorMessage != "" {
+				failed = append(failed, idx)
+				break
+			}
+		}
+	}
+
+	return failed
+}
+
+// reconcileMultipleMachineFailures handles the Control Plane while multiple indexes have failed.
+// The recovery guidance condition is set, and a warning event published when the failures are first observed.
+// No Machine is deleted, nor protection removed, as deleting any further Control Plane Machine, or allowing it to
+// be drained regardless of its disruption budgets, could break etcd quorum.
+// The only action taken is to create a Machine for any index without one, as a new Machine cannot remove an etcd
+// member. Even this is not taken while the ControlPlaneMachineSet is degraded or paused.
+func (r *ControlPlaneMachineSetReconciler) reconcileMultipleMachineFailures(ctx context.Context, logger logr.Logger, cpms *machinev1.ControlPlaneMachineSet, machineProvider machineproviders.MachineProvider, machineInfos map[int32][]machineproviders.MachineInfo, failed []int32) (ctrl.Result, error) {
+	newlyObserved := meta.FindStatusCondition(cpms.Status.Conditions, conditionRecoveryGuidance) == nil
+
+	setRecoveryGuidanceCondition(cpms, machineInfos, failed)
+	logger.Error(errMultipleMachineFailures, observedMultipleMachineFailures, "failedIndexes", failed)
+
+	if newlyObserved && r.Recorder != nil {
+		r.Recorder.Event(cpms, corev1.EventTypeWarning, reasonMultipleMachineFailures, meta.FindStatusCondition(cpms.Status.Conditions, conditionRecoveryGuidance).Message)
+	}
+
+	clearGatedByCondition(cpms)
+
+	switch {
+	case isControlPlaneMachineSetDegraded(cpms):
+		logger.V(1).Info(degradedClusterState)
+	case isControlPlaneMachineSetPaused(cpms):
+		logger.V(1).Info(pausedClusterState)
+	case isAdoptionPending(cpms):
+		logger.V(1).Info(adoptionPendingState)
+	default:
+		if _, err := r.reconcileMissingAndPendingIndexes(ctx, logger, cpms, machineProvider, machineInfos); err != nil {
+			return ctrl.Result{}, fmt.Errorf("error creating machines for missing indexes: %w", err)
+		}
+	}
+
+	if hasPendingReplacements(machineInfos) {
+		setGatedByCondition(cpms, multipleMachineFailuresGate(failed))
+	}
+
+	return ctrl.Result{}, nil
+}
+
+// setRecoveryGuidanceCondition sets the recovery guidance condition to describe the failed indexes, lists the
+// Machines of each, and guides the user through the recovery, eg
+// `2 of 3 control plane indexes have failed: 1=master-1, 2=master-2. 1 etcd member(s) remain healthy, where 2 are
+// required for quorum. ...`.
+func setRecoveryGuidanceCondition(cpms *machinev1.ControlPlaneMachineSet, machineInfos map[int32][]machineproviders.MachineInfo, failed []int32) {
+	failedMachines := []string{}
+
+	for _, idx := range failed {
+		failedMachines = append(failedMachines, fmt.Sprintf("%d=%s", idx, strings.Join(machineInfoNames(sortedByMachineName(machineInfos[idx])), " + ")))
+	}
+
+	indexes := len(machineInfos)
+	quorum := indexes/2 + 1
+
+	meta.SetStatusCondition(&cpms.Status.Conditions, metav1.Condition{
+		Type:               conditionRecoveryGuidance,
+		Status:             metav1.ConditionTrue,
+		Reason:             reasonMultipleMachineFailures,
+		ObservedGeneration: cpms.GetGeneration(),
+		Message: fmt.Sprintf("%d of %d control plane indexes have failed: %s. %d etcd member(s) remain healthy, where %d are required for quorum. "+
+			"Automated deletions are suspended: restore the failed machines, or follow the etcd disaster recovery procedure, "+
+			"and only delete a failed machine once its etcd member has been removed",
+			len(failed), indexes, strings.Join(failedMachines, ", "), indexes-len(failed), quorum),
+	})
+}
+
+// clearRecoveryGuidanceCondition removes the recovery guidance condition once fewer than the minimum number of
+// concurrent failures remain.
+func clearRecoveryGuidanceCondition(cpms *machinev1.ControlPlaneMachineSet) {
+	meta.RemoveStatusCondition(&cpms.Status.Conditions, conditionRecoveryGuidance)
+}
@@ -0,0 +1,177 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controlplanemachineset
+
+import (
+	"github.com/golang/mock/gomock"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	machinev1 "github.com/openshift/api/machine/v1"
+	machinev1beta1 "github.com/openshift/api/machine/v1beta1"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders/mock"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/test"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/test/resourcebuilder"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+var _ = Describe("Multiple machine failures", func() {
+	machineGVR := machinev1beta1.GroupVersion.WithResource("machines")
+	nodeGVR := corev1.SchemeGroupVersion.WithResource("nodes")
+
+	healthyMachineBuilder := resourcebuilder.MachineInfo().
+		WithMachineGVR(machineGVR).
+		WithNodeGVR(nodeGVR).
+		WithReady(true)
+
+	failedMachineBuilder := healthyMachineBuilder.WithReady(false)
+
+	pendingMachineBuilder := resourcebuilder.MachineInfo().
+		WithMachineGVR(machineGVR).
+		WithReady(false)
+
+	Context("failedMachineIndexes", func() {
+		DescribeTable("should find the indexes in which every machine has failed", func(machineInfos map[int32][]machineproviders.MachineInfo, expected []int32) {
+			Expect(failedMachineIndexes(machineInfos)).To(Equal(expected))
+		},
+			Entry("with every machine ready", map[int32][]machineproviders.MachineInfo{
+				0: {healthyMachineBuilder.WithIndex(0).WithMachineName("machine-0").WithNodeName("node-0").Build()},
+				1: {healthyMachineBuilder.WithIndex(1).WithMachineName("machine-1").WithNodeName("node-1").Build()},
+			}, []int32{}),
+			Entry("with machines whose nodes are no longer ready", map[int32][]machineproviders.MachineInfo{
+				0: {healthyMachineBuilder.WithIndex(0).WithMachineName("machine-0").WithNodeName("node-0").Build()},
+				1: {failedMachineBuilder.WithIndex(1).WithMachineName("machine-1").WithNodeName("node-1").Build()},
+				2: {failedMachineBuilder.WithIndex(2).WithMachineName("machine-2").WithNodeName("node-2").Build()},
+			}, []int32{1, 2}),
+			Entry("with a machine that reports an error", map[int32][]machineproviders.MachineInfo{
+				0: {pendingMachineBuilder.WithIndex(0).WithMachineName("machine-0").WithErrorMessage("instance terminated").Build()},
+			}, []int32{0}),
+			Entry("with a machine that is still being provisioned", map[int32][]machineproviders.MachineInfo{
+				0: {pendingMachineBuilder.WithIndex(0).WithMachineName("machine-0").Build()},
+			}, []int32{}),
+			Entry("with a failed machine alongside a ready replacement", map[int32][]machineproviders.MachineInfo{
+				0: {
+					failedMachineBuilder.WithIndex(0).WithMachineName("machine-0").WithNodeName("node-0").Build(),
+					healthyMachineBuilder.WithIndex(0).WithMachineName("machine-3").WithNodeName("node-3").Build(),
+				},
+			}, []int32{}),
+			Entry("with an index without machines", map[int32][]machineproviders.MachineInfo{
+				0: {},
+			}, []int32{}),
+		)
+	})
+
+	Context("reconcileMachines", func() {
+		var logger test.TestLogger
+		var reconciler *ControlPlaneMachineSetReconciler
+		var recorder *record.FakeRecorder
+		var mockMachineProvider *mock.MockMachineProvider
+		var cpms *machinev1.ControlPlaneMachineSet
+
+		failedMachineInfos := func() map[int32][]machineproviders.MachineInfo {
+			return map[int32][]machineproviders.MachineInfo{
+				0: {healthyMachineBuilder.WithIndex(0).WithMachineName("machine-0").WithNodeName("node-0").Build()},
+				1: {failedMachineBuilder.WithIndex(1).WithMachineName("machine-1").WithNodeName("node-1").WithNeedsUpdate(true).Build()},
+				2: {failedMachineBuilder.WithIndex(2).WithMachineName("machine-2").WithNodeName("node-2").Build()},
+			}
+		}
+
+		BeforeEach(func() {
+			logger = test.NewTestLogger()
+			recorder = record.NewFakeRecorder(10)
+			reconciler = &ControlPlaneMachineSetReconciler{
+				Scheme:   testScheme,
+				Recorder: recorder,
+			}
+
+			mockMachineProvider = mock.NewMockMachineProvider(gomock.NewController(GinkgoT()))
+			cpms = resourcebuilder.ControlPlaneMachineSet().WithReplicas(3).WithGeneration(2).Build()
+		})
+
+		It("should refuse every automated deletion and guide the recovery", func() {
+			mockMachineProvider.EXPECT().CreateMachine(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+			mockMachineProvider.EXPECT().DeleteMachine(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+
+			_, err := reconciler.reconcileMultipleMachineFailures(ctx, logger.Logger(), cpms, mockMachineProvider, failedMachineInfos(), []int32{1, 2})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(meta.FindStatusCondition(cpms.Status.Conditions, conditionRecoveryGuidance)).To(SatisfyAll(
+				HaveField("Status", Equal(metav1.ConditionTrue)),
+				HaveField("Reason", Equal(reasonMultipleMachineFailures)),
+				HaveField("ObservedGeneration", Equal(int64(2))),
+				HaveField("Message", Equal("2 of 3 control plane indexes have failed: 1=machine-1, 2=machine-2. "+
+					"1 etcd member(s) remain healthy, where 2 are required for quorum. "+
+					"Automated deletions are suspended: restore the failed machines, or follow the etcd disaster recovery procedure, "+
+					"and only delete a failed machine once its etcd member has been removed")),
+			))
+
+			Expect(meta.FindStatusCondition(cpms.Status.Conditions, conditionGatedBy)).To(SatisfyAll(
+				HaveField("Reason", Equal(reasonGatedByMultipleMachineFailures)),
+				HaveField("Message", Equal("Replacements are suspended while 2 control plane indexes have failed, waiting for user action")),
+			))
+
+			Expect(logger.Entries()).To(ContainElement(SatisfyAll(
+				HaveField("Error", MatchError(errMultipleMachineFailures)),
+				HaveField("Message", observedMultipleMachineFailures),
+			)))
+
+			Expect(recorder.Events).To(Receive(HavePrefix("Warning MultipleMachineFailures 2 of 3 control plane indexes have failed")))
+		})
+
+		It("should only publish the warning event when the failures are first observed", func() {
+			mockMachineProvider.EXPECT().CreateMachine(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+			mockMachineProvider.EXPECT().DeleteMachine(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+
+			for i := 0; i < 2; i++ {
+				_, err := reconciler.reconcileMultipleMachineFailures(ctx, logger.Logger(), cpms, mockMachineProvider, failedMachineInfos(), []int32{1, 2})
+				Expect(err).ToNot(HaveOccurred())
+			}
+
+			Expect(recorder.Events).To(HaveLen(1))
+		})
+
+		It("should create machines for indexes without any machine", func() {
+			machineInfos := failedMachineInfos()
+			machineInfos[0] = []machineproviders.MachineInfo{}
+
+			mockMachineProvider.EXPECT().CreateMachine(gomock.Any(), gomock.Any(), int32(0)).Return(nil).Times(1)
+			mockMachineProvider.EXPECT().DeleteMachine(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+
+			_, err := reconciler.reconcileMultipleMachineFailures(ctx, logger.Logger(), cpms, mockMachineProvider, machineInfos, []int32{1, 2})
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("should not create machines while the control plane machine set is degraded", func() {
+			machineInfos := failedMachineInfos()
+			machineInfos[0] = []machineproviders.MachineInfo{}
+
+			setDegradedCondition(cpms, reasonNoReadyMachines, noReadyMachines)
+
+			mockMachineProvider.EXPECT().CreateMachine(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+			mockMachineProvider.EXPECT().DeleteMachine(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+
+			_, err := reconciler.reconcileMultipleMachineFailures(ctx, logger.Logger(), cpms, mockMachineProvider, machineInfos, []int32{1, 2})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(meta.FindStatusCondition(cpms.Status.Conditions, conditionRecoveryGuidance)).ToNot(BeNil())
+		})
+	})
+})
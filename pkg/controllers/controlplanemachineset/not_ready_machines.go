@@ -0,0 +1,244 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controlplanemachineset
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-logr/logr"
+	machinev1 "github.com/openshift/api/machine/v1"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders"
+)
+
+// NotReadyMachinePolicy determines how the RollingUpdate strategy handles Control Plane Machines that are up to date,
+// but whose Nodes are not ready, when the rollout is about to start the replacement of the next index.
+type NotReadyMachinePolicy string
+
+const (
+	// NotReadyMachinePolicySkip means that up to date Machines whose Nodes are not ready are left as they are, and the
+	// rollout replaces the outdated Machines regardless.
+	NotReadyMachinePolicySkip NotReadyMachinePolicy = "Skip"
+
+	// NotReadyMachinePolicyWait means that the rollout does not replace any outdated Machine until the Nodes of the up
+	// to date Machines are ready.
+	NotReadyMachinePolicyWait NotReadyMachinePolicy = "Wait"
+
+	// NotReadyMachinePolicyReplaceFirst means that up to date Machines whose Nodes are not ready are replaced, one at
+	// a time, before any outdated Machine.
+	NotReadyMachinePolicyReplaceFirst NotReadyMachinePolicy = "ReplaceFirst"
+
+	// notReadyMachineResyncPeriod is how often the Nodes of not ready Machines are checked while the rollout is held
+	// by them, as Nodes are not watched.
+	notReadyMachineResyncPeriod = 30 * time.Second
+
+	// waitingForNotReadyMachines is a log message used to inform the user that the rollout is waiting for the Nodes
+	// of up to date Machines to become ready before replacing any outdated Machine.
+	waitingForNotReadyMachines = "Waiting for nodes of up to date machines to become ready before replacing outdated machines"
+
+	// replacingNotReadyMachine is a log message used to inform the user that an up to date Machine whose Node is not
+	// ready is being replaced before any outdated Machine.
+	replacingNotReadyMachine = "Replacing up to date machine with a node that is not ready before outdated machines"
+
+	// removingNotReadyMachine is a log message used to inform the user that an up to date Machine whose Node is not
+	// ready has been deleted, as its replacement is ready.
+	removingNotReadyMachine = "Replacement machine is ready, removing machine with a node that is not ready"
+)
+
+// errUnknownNotReadyMachinePolicy is used to inform users that the not ready machine policy they have provided is not
+// recognised.
+var errUnknownNotReadyMachinePolicy = errors.New("unknown not ready machine policy")
+
+// ParseNotReadyMachinePolicy parses the not ready machine policy given, as provided on the command line.
+func ParseNotReadyMachinePolicy(policy string) (NotReadyMachinePolicy, error) {
+	switch p := NotReadyMachinePolicy(policy); p {
+	case NotReadyMachinePolicySkip, NotReadyMachinePolicyWait, NotReadyMachinePolicyReplaceFirst:
+		return p, nil
+	default:
+		return "", fmt.Errorf("%w: %q", errUnknownNotReadyMachinePolicy, policy)
+	}
+}
+
+// notReadyIndex describes an index, without outdated Machines, that holds Machines whose Nodes are not ready.
+type notReadyIndex struct {
+	// index is the index holding the not ready Machines.
+	index int32
+
+	// notReady are the Machines of the index that have a Node which is not ready, including those pending deletion.
+	notReady []machineproviders.MachineInfo
+
+	// others are the remaining Machines of the index.
+	others []machineproviders.MachineInfo
+}
+
+// pendingNotReadyMachine returns the first Machine of the index whose Node is not ready that is not pending deletion.
+func (n notReadyIndex) pendingNotReadyMachine() (machineproviders.MachineInfo, bool) {
+	for _, machineInfo := range n.notReady {
+		if machineInfo.MachineRef.ObjectMeta.GetDeletionTimestamp() == nil {
+			return machineInfo, true
+		}
+	}
+
+	return machineproviders.MachineInfo{}, false
+}
+
+// hasReadyReplacement determines whether any other Machine of the index, which is not pending deletion, is ready.
+func (n notReadyIndex) hasReadyReplacement() bool {
+	for _, machineInfo := range n.others {
+		if machineInfo.Ready && machineInfo.NodeRef != nil && machineInfo.MachineRef.ObjectMeta.GetDeletionTimestamp() == nil {
+			return true
+		}
+	}
+
+	return false
+}
+
+// notReadyIndexesAtRolloutStart finds the indexes to which the not ready machine policy applies, when the
+// RollingUpdate strategy is about to start the replacement of the next outdated index. Only indexes without outdated
+// Machines are considered, as outdated Machines are replaced by the rollout regardless.
+// With the Skip policy, or when the APIReader is nil and so the readiness of the Nodes cannot be read, no indexes are
+// returned and the policy is not applied.
+func (r *ControlPlaneMachineSetReconciler) notReadyIndexesAtRolloutStart(ctx context.Context, indexedMachineInfos map[int32][]machineproviders.MachineInfo) ([]notReadyIndex, error) {
+	if r.APIReader == nil || (r.NotReadyMachinePolicy != NotReadyMachinePolicyWait && r.NotReadyMachinePolicy != NotReadyMachinePolicyReplaceFirst) {
+		return nil, nil
+	}
+
+	return r.findNotReadyIndexes(ctx, indexedMachineInfos)
+}
+
+// holdForNotReadyMachines determines whether the rollout must be held, rather than start the replacement of the next
+// index, as per the not ready machine policy.
+// With the Wait policy, the rollout is held while any index has a Machine, not pending deletion, whose Node is not
+// ready. With the ReplaceFirst policy, the rollout is held while a not ready Machine is being replaced, and the not
+// ready Machine is removed once its replacement is ready.
+// It returns true when the rollout is held.
+func (r *ControlPlaneMachineSetReconciler) holdForNotReadyMachines(ctx context.Context, logger logr.Logger, cpms *machinev1.ControlPlaneMachineSet, machineProvider machineproviders.MachineProvider, notReadyIndexes []notReadyIndex) (bool, error) {
+	if r.NotReadyMachinePolicy == NotReadyMachinePolicyWait {
+		names := []string{}
+
+		for _, notReady := range notReadyIndexes {
+			if machineInfo, ok := notReady.pendingNotReadyMachine(); ok {
+				names = append(names, machineInfo.MachineRef.ObjectMeta.GetName())
+			}
+		}
+
+		if len(names) == 0 {
+			return false, nil
+		}
+
+		setGatedByCondition(cpms, notReadyMachinesGate(names))
+		logger.V(2).Info(waitingForNotReadyMachines, "notReadyMachineNames", names)
+
+		return true, nil
+	}
+
+	for _, notReady := range notReadyIndexes {
+		// An index with a single not ready Machine has not yet been replaced.
+		if len(notReady.others) == 0 && len(notReady.notReady) == 1 {
+			continue
+		}
+
+		return true, completeNotReadyReplacement(ctx, logger, machineProvider, notReady)
+	}
+
+	return false, nil
+}
+
+// nextNotReadyReplacement returns, with the ReplaceFirst policy, the first Machine whose Node is not ready and which
+// is alone within its index. It should be replaced in place of the next outdated index.
+func (r *ControlPlaneMachineSetReconciler) nextNotReadyReplacement(notReadyIndexes []notReadyIndex) (machineproviders.MachineInfo, bool) {
+	if r.NotReadyMachinePolicy != NotReadyMachinePolicyReplaceFirst {
+		return machineproviders.MachineInfo{}, false
+	}
+
+	for _, notReady := range notReadyIndexes {
+		if machineInfo, ok := notReady.pendingNotReadyMachine(); ok && len(notReady.others) == 0 && len(notReady.notReady) == 1 {
+			return machineInfo, true
+		}
+	}
+
+	return machineproviders.MachineInfo{}, false
+}
+
+// completeNotReadyReplacement removes the not ready Machine of the index once its replacement is ready. Otherwise, the
+// user is informed of the state of the replacement.
+func completeNotReadyReplacement(ctx context.Context, logger logr.Logger, machineProvider machineproviders.MachineProvider, notReady notReadyIndex) error {
+	machineInfo, pending := notReady.pendingNotReadyMachine()
+	machineLogger := machineInfoLogger(logger, notReady.notReady[0])
+
+	switch {
+	case !pending:
+		machineLogger.V(2).Info(waitingForRemoved)
+	case !notReady.hasReadyReplacement():
+		machineInfoLogger(logger, machineInfo).V(2).Info(waitingForReplacement)
+	default:
+		machineLogger = machineInfoLogger(logger, machineInfo)
+
+		if err := machineProvider.DeleteMachine(ctx, machineLogger, machineInfo.MachineRef); err != nil {
+			err := fmt.Errorf("error deleting Machine %s/%s: %w", machineInfo.MachineRef.ObjectMeta.GetNamespace(), machineInfo.MachineRef.ObjectMeta.GetName(), err)
+			machineLogger.Error(err, errorDeletingMachine)
+
+			return err
+		}
+
+		machineLogger.V(2).Info(removingNotReadyMachine)
+	}
+
+	return nil
+}
+
+// findNotReadyIndexes finds, in index order, the indexes without outdated Machines that hold at least one Machine
+// with a Node that is not ready. Machines without a Node are still joining the cluster, so are not considered not
+// ready here.
+func (r *ControlPlaneMachineSetReconciler) findNotReadyIndexes(ctx context.Context, indexedMachineInfos map[int32][]machineproviders.MachineInfo) ([]notReadyIndex, error) {
+	notReadyIndexes := []notReadyIndex{}
+
+	for _, idx := range sortedIndexes(indexedMachineInfos) {
+		outdatedMachines, updatedMachines := splitOutdatedMachines(indexedMachineInfos[idx])
+		if len(outdatedMachines) > 0 {
+			continue
+		}
+
+		notReady := notReadyIndex{index: idx}
+
+		for _, machineInfo := range sortedByMachineName(updatedMachines) {
+			if machineInfo.NodeRef == nil {
+				notReady.others = append(notReady.others, machineInfo)
+				continue
+			}
+
+			ready, err := r.isNamedNodeReady(ctx, machineInfo.NodeRef.ObjectMeta.GetName())
+			if err != nil {
+				return nil, err
+			}
+
+			if ready {
+				notReady.others = append(notReady.others, machineInfo)
+			} else {
+				notReady.notReady = append(notReady.notReady, machineInfo)
+			}
+		}
+
+		if len(notReady.notReady) > 0 {
+			notReadyIndexes = append(notReadyIndexes, notReady)
+		}
+	}
+
+	return notReadyIndexes, nil
+}
@@ -0,0 +1,233 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controlplanemachineset
+
+import (
+	"errors"
+
+	"github.com/golang/mock/gomock"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	machinev1 "github.com/openshift/api/machine/v1"
+	machinev1beta1 "github.com/openshift/api/machine/v1beta1"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders/mock"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/test"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/test/resourcebuilder"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+var _ = Describe("NotReadyMachinePolicy", func() {
+	var namespaceName string
+	var logger test.TestLogger
+	var reconciler *ControlPlaneMachineSetReconciler
+	var cpms *machinev1.ControlPlaneMachineSet
+	var mockMachineProvider *mock.MockMachineProvider
+	var readyNodeName, notReadyNodeName string
+
+	machineBuilder := resourcebuilder.MachineInfo().
+		WithMachineGVR(machinev1beta1.GroupVersion.WithResource("machines")).
+		WithMachineNamespace("openshift-machine-api").
+		WithNodeGVR(corev1.SchemeGroupVersion.WithResource("nodes")).
+		WithReady(true)
+
+	// machineInfos returns Machines for three indexes, where index 0 is outdated, index 1 is up to date with a Node
+	// that is not ready, and index 2 is up to date and ready. The Machines given are added to index 1.
+	machineInfos := func(index1 ...machineproviders.MachineInfo) map[int32][]machineproviders.MachineInfo {
+		return map[int32][]machineproviders.MachineInfo{
+			0: {machineBuilder.WithIndex(0).WithMachineName("machine-0").WithNodeName(readyNodeName).WithNeedsUpdate(true).Build()},
+			1: append([]machineproviders.MachineInfo{
+				machineBuilder.WithIndex(1).WithMachineName("machine-1").WithNodeName(notReadyNodeName).Build(),
+			}, index1...),
+			2: {machineBuilder.WithIndex(2).WithMachineName("machine-2").WithNodeName(readyNodeName).Build()},
+		}
+	}
+
+	BeforeEach(func() {
+		By("Setting up a namespace for the test")
+		ns := resourcebuilder.Namespace().WithGenerateName("control-plane-machine-set-not-ready-machines-").Build()
+		Expect(k8sClient.Create(ctx, ns)).To(Succeed())
+		namespaceName = ns.GetName()
+
+		logger = test.NewTestLogger()
+		reconciler = &ControlPlaneMachineSetReconciler{
+			Client:    k8sClient,
+			APIReader: k8sClient,
+			Scheme:    testScheme,
+			Namespace: namespaceName,
+		}
+
+		cpms = resourcebuilder.ControlPlaneMachineSet().WithNamespace(namespaceName).WithStrategyType(machinev1.RollingUpdate).Build()
+		mockMachineProvider = mock.NewMockMachineProvider(gomock.NewController(GinkgoT()))
+
+		By("Creating a ready node and a node that is not ready")
+		readyNode := resourcebuilder.Node().AsMaster().WithGenerateName("not-ready-machines-ready-").Build()
+		Expect(k8sClient.Create(ctx, readyNode)).To(Succeed())
+		readyNode.Status.Conditions = []corev1.NodeCondition{{Type: corev1.NodeReady, Status: corev1.ConditionTrue}}
+		Expect(k8sClient.Status().Update(ctx, readyNode)).To(Succeed())
+		readyNodeName = readyNode.GetName()
+
+		notReadyNode := resourcebuilder.Node().AsMaster().WithGenerateName("not-ready-machines-not-ready-").Build()
+		Expect(k8sClient.Create(ctx, notReadyNode)).To(Succeed())
+		notReadyNode.Status.Conditions = []corev1.NodeCondition{{Type: corev1.NodeReady, Status: corev1.ConditionFalse}}
+		Expect(k8sClient.Status().Update(ctx, notReadyNode)).To(Succeed())
+		notReadyNodeName = notReadyNode.GetName()
+	})
+
+	AfterEach(func() {
+		test.CleanupResources(Default, ctx, cfg, k8sClient, namespaceName,
+			&corev1.Node{},
+		)
+	})
+
+	Context("with the Skip policy", func() {
+		BeforeEach(func() {
+			reconciler.NotReadyMachinePolicy = NotReadyMachinePolicySkip
+		})
+
+		It("replaces the outdated machine regardless", func() {
+			mockMachineProvider.EXPECT().CreateMachine(gomock.Any(), gomock.Any(), int32(0)).Return(nil).Times(1)
+
+			result, err := reconciler.reconcileMachineUpdates(ctx, logger.Logger(), cpms, mockMachineProvider, machineInfos())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result).To(Equal(ctrl.Result{}))
+		})
+	})
+
+	Context("with the Wait policy", func() {
+		BeforeEach(func() {
+			reconciler.NotReadyMachinePolicy = NotReadyMachinePolicyWait
+		})
+
+		It("holds the rollout until the node is ready", func() {
+			mockMachineProvider.EXPECT().CreateMachine(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+
+			result, err := reconciler.reconcileMachineUpdates(ctx, logger.Logger(), cpms, mockMachineProvider, machineInfos())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result).To(Equal(ctrl.Result{RequeueAfter: notReadyMachineResyncPeriod}))
+
+			Expect(cpms.Status.Conditions).To(ContainElement(test.MatchCondition(metav1.Condition{
+				Type:    conditionGatedBy,
+				Status:  metav1.ConditionTrue,
+				Reason:  reasonGatedByNotReadyMachines,
+				Message: "Replacements are gated by up to date machines whose nodes are not ready: machine-1, waiting for user action",
+			})))
+			Expect(logger.Entries()).To(ContainElement(test.LogEntry{
+				Level:         2,
+				KeysAndValues: []interface{}{"updateStrategy", machinev1.RollingUpdate, "notReadyMachineNames", []string{"machine-1"}},
+				Message:       waitingForNotReadyMachines,
+			}))
+		})
+
+		It("does not apply the policy without an API reader", func() {
+			reconciler.APIReader = nil
+
+			mockMachineProvider.EXPECT().CreateMachine(gomock.Any(), gomock.Any(), int32(0)).Return(nil).Times(1)
+
+			_, err := reconciler.reconcileMachineUpdates(ctx, logger.Logger(), cpms, mockMachineProvider, machineInfos())
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("does not hold a rollout that is not pending", func() {
+			infos := machineInfos()
+			infos[0] = []machineproviders.MachineInfo{machineBuilder.WithIndex(0).WithMachineName("machine-0").WithNodeName(readyNodeName).Build()}
+
+			mockMachineProvider.EXPECT().CreateMachine(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+
+			result, err := reconciler.reconcileMachineUpdates(ctx, logger.Logger(), cpms, mockMachineProvider, infos)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result).To(Equal(ctrl.Result{}))
+			Expect(cpms.Status.Conditions).To(BeEmpty())
+		})
+	})
+
+	Context("with the ReplaceFirst policy", func() {
+		BeforeEach(func() {
+			reconciler.NotReadyMachinePolicy = NotReadyMachinePolicyReplaceFirst
+		})
+
+		It("replaces the not ready machine before the outdated machine", func() {
+			mockMachineProvider.EXPECT().CreateMachine(gomock.Any(), gomock.Any(), int32(1)).Return(nil).Times(1)
+
+			result, err := reconciler.reconcileMachineUpdates(ctx, logger.Logger(), cpms, mockMachineProvider, machineInfos())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result).To(Equal(ctrl.Result{}))
+			Expect(logger.Entries()).To(ContainElement(test.LogEntry{
+				Level:         2,
+				KeysAndValues: []interface{}{"updateStrategy", machinev1.RollingUpdate, "index", int32(1), "namespace", "openshift-machine-api", "name", "machine-1"},
+				Message:       replacingNotReadyMachine,
+			}))
+		})
+
+		It("waits for the replacement of the not ready machine to become ready", func() {
+			mockMachineProvider.EXPECT().CreateMachine(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+			mockMachineProvider.EXPECT().DeleteMachine(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+
+			replacement := machineBuilder.WithIndex(1).WithMachineName("machine-replacement-1").WithReady(false).Build()
+
+			result, err := reconciler.reconcileMachineUpdates(ctx, logger.Logger(), cpms, mockMachineProvider, machineInfos(replacement))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result).To(Equal(ctrl.Result{RequeueAfter: notReadyMachineResyncPeriod}))
+		})
+
+		It("removes the not ready machine once its replacement is ready", func() {
+			replacement := machineBuilder.WithIndex(1).WithMachineName("machine-replacement-1").WithNodeName(readyNodeName).Build()
+			infos := machineInfos(replacement)
+
+			mockMachineProvider.EXPECT().CreateMachine(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+			mockMachineProvider.EXPECT().DeleteMachine(gomock.Any(), gomock.Any(), infos[1][0].MachineRef).Return(nil).Times(1)
+
+			result, err := reconciler.reconcileMachineUpdates(ctx, logger.Logger(), cpms, mockMachineProvider, infos)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result).To(Equal(ctrl.Result{RequeueAfter: notReadyMachineResyncPeriod}))
+			Expect(logger.Entries()).To(ContainElement(test.LogEntry{
+				Level:         2,
+				KeysAndValues: []interface{}{"updateStrategy", machinev1.RollingUpdate, "index", int32(1), "namespace", "openshift-machine-api", "name", "machine-1"},
+				Message:       removingNotReadyMachine,
+			}))
+		})
+
+		It("returns the error without a requeue when removing the not ready machine fails", func() {
+			transientError := errors.New("transient error")
+			replacement := machineBuilder.WithIndex(1).WithMachineName("machine-replacement-1").WithNodeName(readyNodeName).Build()
+			infos := machineInfos(replacement)
+
+			mockMachineProvider.EXPECT().CreateMachine(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+			mockMachineProvider.EXPECT().DeleteMachine(gomock.Any(), gomock.Any(), infos[1][0].MachineRef).Return(transientError).Times(1)
+
+			result, err := reconciler.reconcileMachineUpdates(ctx, logger.Logger(), cpms, mockMachineProvider, infos)
+			Expect(err).To(MatchError(ContainSubstring(transientError.Error())))
+			Expect(result).To(Equal(ctrl.Result{}))
+		})
+	})
+})
+
+var _ = Describe("ParseNotReadyMachinePolicy", func() {
+	It("parses each policy", func() {
+		for _, policy := range []NotReadyMachinePolicy{NotReadyMachinePolicySkip, NotReadyMachinePolicyWait, NotReadyMachinePolicyReplaceFirst} {
+			Expect(ParseNotReadyMachinePolicy(string(policy))).To(Equal(policy))
+		}
+	})
+
+	It("rejects an unknown policy", func() {
+		_, err := ParseNotReadyMachinePolicy("Replace")
+		Expect(err).To(MatchError(`unknown not ready machine policy: "Replace"`))
+	})
+})
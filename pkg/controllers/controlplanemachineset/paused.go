@@ -0,0 +1,48 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controlplanemachineset
+
+import (
+	"fmt"
+
+	machinev1 "github.com/openshift/api/machine/v1"
+)
+
+const (
+	// pausedAnnotation is the annotation used to pause the replacement of Control Plane Machines by the
+	// ControlPlaneMachineSet. When set to "true", no Machine is created or deleted until the annotation is removed,
+	// though the status of the ControlPlaneMachineSet continues to be updated.
+	pausedAnnotation = "controlplanemachineset.machine.openshift.io/paused"
+
+	// pausedClusterState is a log message used to inform the user that the ControlPlaneMachineSet will not take any
+	// action, as it has been paused.
+	pausedClusterState = "Control plane machine set has been paused. The control plane machine set will not take any action until it is resumed."
+)
+
+// isControlPlaneMachineSetPaused determines whether the user has paused the ControlPlaneMachineSet.
+func isControlPlaneMachineSetPaused(cpms *machinev1.ControlPlaneMachineSet) bool {
+	return cpms.GetAnnotations()[pausedAnnotation] == "true"
+}
+
+// userPauseGate creates a gate for a paused ControlPlaneMachineSet, which blocks all replacements until the user
+// resumes the ControlPlaneMachineSet.
+func userPauseGate() gate {
+	return gate{
+		reason:      reasonGatedByUserPause,
+		description: fmt.Sprintf("Replacements are paused by the %s annotation, remove it to continue", pausedAnnotation),
+	}
+}
@@ -0,0 +1,111 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controlplanemachineset
+
+import (
+	"github.com/golang/mock/gomock"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	machinev1 "github.com/openshift/api/machine/v1"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders/mock"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/test"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/test/resourcebuilder"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+var _ = Describe("Paused ControlPlaneMachineSet", func() {
+	machineBuilder := resourcebuilder.MachineInfo().WithReady(true).WithNodeName("node")
+
+	DescribeTable("isControlPlaneMachineSetPaused", func(annotations map[string]string, expectedPaused bool) {
+		cpms := resourcebuilder.ControlPlaneMachineSet().WithAnnotations(annotations).Build()
+
+		Expect(isControlPlaneMachineSetPaused(cpms)).To(Equal(expectedPaused))
+	},
+		Entry("with no annotations", nil, false),
+		Entry("with the paused annotation set to true", map[string]string{pausedAnnotation: "true"}, true),
+		Entry("with the paused annotation set to false", map[string]string{pausedAnnotation: "false"}, false),
+		Entry("with an empty paused annotation", map[string]string{pausedAnnotation: ""}, false),
+	)
+
+	Context("when reconciling machines", func() {
+		var logger test.TestLogger
+		var reconciler *ControlPlaneMachineSetReconciler
+		var mockMachineProvider *mock.MockMachineProvider
+		var cpms *machinev1.ControlPlaneMachineSet
+		var result ctrl.Result
+		var namespaceName string
+
+		BeforeEach(func() {
+			By("Setting up a namespace for the test")
+			ns := resourcebuilder.Namespace().WithGenerateName("control-plane-machine-set-paused-").Build()
+			Expect(k8sClient.Create(ctx, ns)).To(Succeed())
+			namespaceName = ns.GetName()
+
+			logger = test.NewTestLogger()
+			reconciler = &ControlPlaneMachineSetReconciler{
+				Client: k8sClient,
+				Scheme: testScheme,
+			}
+
+			mockMachineProvider = mock.NewMockMachineProvider(gomock.NewController(GinkgoT()))
+			cpms = resourcebuilder.ControlPlaneMachineSet().WithNamespace(namespaceName).WithStrategyType(machinev1.RollingUpdate).WithReplicas(3).
+				WithAnnotations(map[string]string{pausedAnnotation: "true"}).Build()
+
+			machineInfos := map[int32][]machineproviders.MachineInfo{
+				0: {machineBuilder.WithIndex(0).WithMachineName("machine-0").WithNeedsUpdate(true).Build()},
+				1: {machineBuilder.WithIndex(1).WithMachineName("machine-1").Build()},
+				2: {machineBuilder.WithIndex(2).WithMachineName("machine-2").Build()},
+			}
+
+			// The mock machine provider fails the test on any unexpected call, so no Machine may be created or
+			// deleted while the control plane machine set is paused.
+			var err error
+			result, err = reconciler.reconcileMachines(ctx, logger.Logger(), cpms, mockMachineProvider, machineInfos)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		AfterEach(func() {
+			test.CleanupResources(Default, ctx, cfg, k8sClient, namespaceName,
+				&corev1.ConfigMap{},
+			)
+		})
+
+		It("does not requeue", func() {
+			Expect(result).To(Equal(ctrl.Result{}))
+		})
+
+		It("sets the gated by condition", func() {
+			Expect(meta.FindStatusCondition(cpms.Status.Conditions, conditionGatedBy)).To(HaveField("Reason", Equal(reasonGatedByUserPause)))
+		})
+
+		It("still reports the status of the machines", func() {
+			Expect(cpms.Status.UpdatedReplicas).To(BeEquivalentTo(2))
+			Expect(meta.FindStatusCondition(cpms.Status.Conditions, conditionRolloutPhase)).To(HaveField("Reason", Equal(string(phasePlanPending))))
+		})
+
+		It("logs that no action will be taken", func() {
+			Expect(logger.Entries()).To(ContainElement(test.LogEntry{
+				Level:   1,
+				Message: pausedClusterState,
+			}))
+		})
+	})
+})
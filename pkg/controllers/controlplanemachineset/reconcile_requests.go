@@ -0,0 +1,89 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controlplanemachineset
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-logr/logr"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders"
+)
+
+const (
+	// reconcileRequestAnnotation is the annotation that external controllers may set on a Control Plane Machine to
+	// request that the ControlPlaneMachineSet re-evaluates the index of the Machine, for example after they have
+	// mutated the Machine. The value is an opaque token, such as a timestamp, and should be changed each time a
+	// new re-evaluation is requested.
+	// Setting the annotation on the Machine, rather than modifying the ControlPlaneMachineSet, means the external
+	// controller does not need permission to, or risk conflicts when, updating the ControlPlaneMachineSet.
+	reconcileRequestAnnotation = "controlplanemachineset.machine.openshift.io/reconcile-request"
+
+	// reconcileRequestObservedAnnotation is the annotation used to acknowledge a reconcile request. Once the index of
+	// the Machine has been re-evaluated, the token from the reconcile request annotation is copied into this
+	// annotation so that the external controller can observe that its request has been handled.
+	reconcileRequestObservedAnnotation = "controlplanemachineset.machine.openshift.io/reconcile-request-observed"
+
+	// observedReconcileRequest is a log message used to inform the user that a reconcile request on a Machine has
+	// been handled.
+	observedReconcileRequest = "Observed reconcile request for index"
+)
+
+// ensureReconcileRequestsObserved acknowledges any outstanding reconcile request on the Machines within the
+// machineInfos. This should be called once the indexes of the Machines have been re-evaluated.
+func (r *ControlPlaneMachineSetReconciler) ensureReconcileRequestsObserved(ctx context.Context, logger logr.Logger, machineInfos map[int32][]machineproviders.MachineInfo) error {
+	for _, idx := range sortedIndexes(machineInfos) {
+		for _, machineInfo := range machineInfos[idx] {
+			if machineInfo.MachineRef == nil {
+				continue
+			}
+
+			if err := r.ensureReconcileRequestObserved(ctx, logger, machineInfo); err != nil {
+				return fmt.Errorf("error acknowledging reconcile request on machine %s: %w", machineInfo.MachineRef.ObjectMeta.GetName(), err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// ensureReconcileRequestObserved copies the token from the reconcile request annotation into the reconcile request
+// observed annotation on a single Machine, if required.
+func (r *ControlPlaneMachineSetReconciler) ensureReconcileRequestObserved(ctx context.Context, logger logr.Logger, machineInfo machineproviders.MachineInfo) error {
+	machineRef := machineInfo.MachineRef
+	annotations := machineRef.ObjectMeta.GetAnnotations()
+
+	token, ok := annotations[reconcileRequestAnnotation]
+	if !ok || annotations[reconcileRequestObservedAnnotation] == token {
+		return nil
+	}
+
+	if err := r.patchMachineAnnotations(ctx, machineRef, func(annotations map[string]string) {
+		annotations[reconcileRequestObservedAnnotation] = token
+	}); err != nil {
+		return err
+	}
+
+	logger.V(2).Info(observedReconcileRequest,
+		"index", machineInfo.Index,
+		"machineNamespace", machineRef.ObjectMeta.GetNamespace(),
+		"machineName", machineRef.ObjectMeta.GetName(),
+		"reconcileRequest", token,
+	)
+
+	return nil
+}
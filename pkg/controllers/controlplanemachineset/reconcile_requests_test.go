@@ -0,0 +1,169 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controlplanemachineset
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	machinev1beta1 "github.com/openshift/api/machine/v1beta1"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/test"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/test/resourcebuilder"
+
+	"sigs.k8s.io/controller-runtime/pkg/envtest/komega"
+)
+
+var _ = Describe("ensureReconcileRequestsObserved", func() {
+	var namespaceName string
+	var reconciler *ControlPlaneMachineSetReconciler
+	var logger test.TestLogger
+
+	var machines []*machinev1beta1.Machine
+	var machineInfoBuilders []resourcebuilder.MachineInfoBuilder
+	machineGVR := machinev1beta1.GroupVersion.WithResource("machines")
+
+	BeforeEach(func() {
+		By("Setting up a namespace for the test")
+		ns := resourcebuilder.Namespace().WithGenerateName("control-plane-machine-set-reconcile-requests-").Build()
+		Expect(k8sClient.Create(ctx, ns)).To(Succeed())
+		namespaceName = ns.GetName()
+
+		reconciler = &ControlPlaneMachineSetReconciler{
+			Client:     k8sClient,
+			Scheme:     testScheme,
+			RESTMapper: testRESTMapper,
+			Namespace:  namespaceName,
+		}
+
+		logger = test.NewTestLogger()
+
+		By("Creating machines to request reconciles on")
+		machines = []*machinev1beta1.Machine{}
+		machineInfoBuilders = []resourcebuilder.MachineInfoBuilder{}
+		machineBuilder := resourcebuilder.Machine().WithNamespace(namespaceName).WithGenerateName("reconcile-requests-test-")
+
+		for i := 0; i < 2; i++ {
+			machine := machineBuilder.Build()
+			Expect(k8sClient.Create(ctx, machine)).To(Succeed())
+
+			machines = append(machines, machine)
+			machineInfoBuilders = append(machineInfoBuilders, resourcebuilder.MachineInfo().
+				WithIndex(int32(i)).
+				WithMachineGVR(machineGVR).
+				WithMachineName(machine.GetName()).
+				WithMachineNamespace(namespaceName),
+			)
+		}
+	})
+
+	AfterEach(func() {
+		test.CleanupResources(Default, ctx, cfg, k8sClient, namespaceName,
+			&machinev1beta1.Machine{},
+		)
+	})
+
+	Context("when a machine has an outstanding reconcile request", func() {
+		BeforeEach(func() {
+			annotations := map[string]string{
+				reconcileRequestAnnotation: "2022-08-01T12:00:00Z",
+				"other-annotation":         "value",
+			}
+
+			machines[1].SetAnnotations(annotations)
+			Expect(k8sClient.Update(ctx, machines[1])).To(Succeed())
+
+			machineInfos := map[int32][]machineproviders.MachineInfo{
+				0: {machineInfoBuilders[0].Build()},
+				1: {machineInfoBuilders[1].WithMachineAnnotations(annotations).Build()},
+			}
+
+			Expect(reconciler.ensureReconcileRequestsObserved(ctx, logger.Logger(), machineInfos)).To(Succeed())
+		})
+
+		It("should acknowledge the reconcile request on the machine", func() {
+			Eventually(komega.Object(machines[1])).Should(HaveField("ObjectMeta.Annotations", HaveKeyWithValue(reconcileRequestObservedAnnotation, "2022-08-01T12:00:00Z")))
+		})
+
+		It("should not remove other annotations from the machine", func() {
+			Eventually(komega.Object(machines[1])).Should(HaveField("ObjectMeta.Annotations", SatisfyAll(
+				HaveKeyWithValue(reconcileRequestAnnotation, "2022-08-01T12:00:00Z"),
+				HaveKeyWithValue("other-annotation", "value"),
+			)))
+		})
+
+		It("should not annotate machines without a reconcile request", func() {
+			Consistently(komega.Object(machines[0])).ShouldNot(HaveField("ObjectMeta.Annotations", HaveKey(reconcileRequestObservedAnnotation)))
+		})
+
+		It("should log that it has observed the reconcile request", func() {
+			Expect(logger.Entries()).To(ConsistOf(test.LogEntry{
+				KeysAndValues: []interface{}{"index", int32(1), "machineNamespace", namespaceName, "machineName", machines[1].GetName(), "reconcileRequest", "2022-08-01T12:00:00Z"},
+				Level:         2,
+				Message:       "Observed reconcile request for index",
+			}))
+		})
+	})
+
+	Context("when the reconcile request has already been observed", func() {
+		BeforeEach(func() {
+			annotations := map[string]string{
+				reconcileRequestAnnotation:         "1",
+				reconcileRequestObservedAnnotation: "1",
+			}
+
+			machines[0].SetAnnotations(annotations)
+			Expect(k8sClient.Update(ctx, machines[0])).To(Succeed())
+
+			machineInfos := map[int32][]machineproviders.MachineInfo{
+				0: {machineInfoBuilders[0].WithMachineAnnotations(annotations).Build()},
+			}
+
+			Expect(reconciler.ensureReconcileRequestsObserved(ctx, logger.Logger(), machineInfos)).To(Succeed())
+		})
+
+		It("should keep the annotation on the machine", func() {
+			Consistently(komega.Object(machines[0])).Should(HaveField("ObjectMeta.Annotations", HaveKeyWithValue(reconcileRequestObservedAnnotation, "1")))
+		})
+
+		It("should not log", func() {
+			Expect(logger.Entries()).To(BeEmpty())
+		})
+	})
+
+	Context("when a new reconcile request is made", func() {
+		BeforeEach(func() {
+			annotations := map[string]string{
+				reconcileRequestAnnotation:         "2",
+				reconcileRequestObservedAnnotation: "1",
+			}
+
+			machines[0].SetAnnotations(annotations)
+			Expect(k8sClient.Update(ctx, machines[0])).To(Succeed())
+
+			machineInfos := map[int32][]machineproviders.MachineInfo{
+				0: {machineInfoBuilders[0].WithMachineAnnotations(annotations).Build()},
+			}
+
+			Expect(reconciler.ensureReconcileRequestsObserved(ctx, logger.Logger(), machineInfos)).To(Succeed())
+		})
+
+		It("should update the acknowledgement on the machine", func() {
+			Eventually(komega.Object(machines[0])).Should(HaveField("ObjectMeta.Annotations", HaveKeyWithValue(reconcileRequestObservedAnnotation, "2")))
+		})
+	})
+})
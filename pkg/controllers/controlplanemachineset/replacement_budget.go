@@ -0,0 +1,148 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controlplanemachineset
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	machinev1 "github.com/openshift/api/machine/v1"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// replacementBudgetAnnotation is the annotation on the ControlPlaneMachineSet used to limit the rate at which
+	// the RollingUpdate strategy replaces outdated Machines. The value is the number of replacements permitted,
+	// followed by a slash and the period over which they are permitted, eg `1/24h` permits a single replacement
+	// each day. Only Control Plane Machines created by the ControlPlaneMachineSet within the period count towards
+	// the budget, so Machines created by the installer, or by the user, do not consume it.
+	replacementBudgetAnnotation = "controlplanemachineset.machine.openshift.io/replacement-budget"
+
+	// invalidReplacementBudgetMessage is used to inform the user that they have provided an invalid value for the
+	// replacement budget annotation.
+	invalidReplacementBudgetMessage = "invalid value for replacement budget"
+
+	// replacementBudgetExhausted is a log message used to inform the user that an outdated Machine is not being
+	// replaced because the replacement budget has been exhausted.
+	replacementBudgetExhausted = "Replacement budget exhausted, waiting to replace machine"
+)
+
+// errInvalidReplacementBudget is used to denote that the replacement budget annotation is not in the expected format.
+var errInvalidReplacementBudget = fmt.Errorf("invalid value for annotation %s: expected <replacements>/<period>", replacementBudgetAnnotation)
+
+// replacementBudget limits the number of Control Plane Machines that may be created within a rolling period.
+type replacementBudget struct {
+	// replacements is the number of Machines that may be created within the period.
+	replacements int
+
+	// period is the duration of the rolling window over which the replacements are counted.
+	period time.Duration
+}
+
+// String returns a string representation of the replacement budget.
+func (b replacementBudget) String() string {
+	return fmt.Sprintf("%d/%s", b.replacements, b.period)
+}
+
+// parseReplacementBudget parses the value of the replacement budget annotation.
+// When the annotation is not present, no budget is returned.
+func parseReplacementBudget(annotations map[string]string) (*replacementBudget, error) {
+	value, ok := annotations[replacementBudgetAnnotation]
+	if !ok {
+		return nil, nil //nolint:nilnil
+	}
+
+	parts := strings.Split(value, "/")
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("%w, got %q", errInvalidReplacementBudget, value)
+	}
+
+	replacements, err := strconv.Atoi(parts[0])
+	if err != nil || replacements < 1 {
+		return nil, fmt.Errorf("%w, got %q: replacements must be a positive integer", errInvalidReplacementBudget, value)
+	}
+
+	period, err := time.ParseDuration(parts[1])
+	if err != nil || period <= 0 {
+		return nil, fmt.Errorf("%w, got %q: period must be a positive duration", errInvalidReplacementBudget, value)
+	}
+
+	return &replacementBudget{replacements: replacements, period: period}, nil
+}
+
+// nextReplacementTime determines when the budget next permits a replacement, based on the creation timestamps of
+// the Machines within the machineInfos that were created by the ControlPlaneMachineSet. These are recognised by
+// their template hash, which is only recorded on the Machines the ControlPlaneMachineSet creates.
+// When a replacement is permitted now, the zero time is returned.
+func (b replacementBudget) nextReplacementTime(indexedMachineInfos map[int32][]machineproviders.MachineInfo, now time.Time) time.Time {
+	windowStart := now.Add(-b.period)
+	createdInWindow := []time.Time{}
+
+	for _, machineInfos := range indexedMachineInfos {
+		for _, machineInfo := range machineInfos {
+			if machineInfo.MachineRef == nil || machineInfo.TemplateHash == "" {
+				continue
+			}
+
+			created := machineInfo.MachineRef.ObjectMeta.GetCreationTimestamp().Time
+			if created.After(windowStart) {
+				createdInWindow = append(createdInWindow, created)
+			}
+		}
+	}
+
+	if len(createdInWindow) < b.replacements {
+		return time.Time{}
+	}
+
+	sort.Slice(createdInWindow, func(i, j int) bool {
+		return createdInWindow[i].Before(createdInWindow[j])
+	})
+
+	// A replacement is next permitted once enough Machines have aged out of the window to bring the count below
+	// the number of replacements permitted.
+	return createdInWindow[len(createdInWindow)-b.replacements].Add(b.period)
+}
+
+// setInvalidReplacementBudgetCondition sets the degraded condition to report that the replacement budget
+// annotation is invalid.
+func setInvalidReplacementBudgetCondition(cpms *machinev1.ControlPlaneMachineSet, err error) {
+	meta.SetStatusCondition(&cpms.Status.Conditions, metav1.Condition{
+		Type:               conditionDegraded,
+		Status:             metav1.ConditionTrue,
+		Reason:             reasonInvalidReplacementBudget,
+		ObservedGeneration: cpms.GetGeneration(),
+		Message:            fmt.Sprintf("%s: %s", invalidReplacementBudgetMessage, err),
+	})
+}
+
+// setReplacementBudgetExhaustedCondition sets the progressing condition to report that the rollout is paused
+// until the replacement budget permits the next replacement.
+func setReplacementBudgetExhaustedCondition(cpms *machinev1.ControlPlaneMachineSet, budget replacementBudget, next time.Time) {
+	meta.SetStatusCondition(&cpms.Status.Conditions, metav1.Condition{
+		Type:               conditionProgressing,
+		Status:             metav1.ConditionTrue,
+		Reason:             reasonReplacementBudgetExhausted,
+		ObservedGeneration: cpms.GetGeneration(),
+		Message:            fmt.Sprintf("Replacement budget of %s exhausted, next replacement permitted at %s", budget, next.UTC().Format(time.RFC3339)),
+	})
+}
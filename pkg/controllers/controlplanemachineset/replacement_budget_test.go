@@ -0,0 +1,314 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controlplanemachineset
+
+import (
+	"time"
+
+	"github.com/golang/mock/gomock"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	machinev1 "github.com/openshift/api/machine/v1"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders/mock"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/test"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/test/resourcebuilder"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+var _ = Describe("Replacement budget", func() {
+	type parseReplacementBudgetTableInput struct {
+		annotations    map[string]string
+		expectedBudget *replacementBudget
+		expectedError  error
+	}
+
+	DescribeTable("parseReplacementBudget", func(in parseReplacementBudgetTableInput) {
+		budget, err := parseReplacementBudget(in.annotations)
+
+		if in.expectedError != nil {
+			Expect(err).To(MatchError(in.expectedError))
+		} else {
+			Expect(err).ToNot(HaveOccurred())
+		}
+
+		Expect(budget).To(Equal(in.expectedBudget))
+	},
+		Entry("with no annotations", parseReplacementBudgetTableInput{
+			annotations:    nil,
+			expectedBudget: nil,
+		}),
+		Entry("with a single replacement per day", parseReplacementBudgetTableInput{
+			annotations:    map[string]string{replacementBudgetAnnotation: "1/24h"},
+			expectedBudget: &replacementBudget{replacements: 1, period: 24 * time.Hour},
+		}),
+		Entry("with multiple replacements per week", parseReplacementBudgetTableInput{
+			annotations:    map[string]string{replacementBudgetAnnotation: "3/168h"},
+			expectedBudget: &replacementBudget{replacements: 3, period: 168 * time.Hour},
+		}),
+		Entry("with no period", parseReplacementBudgetTableInput{
+			annotations:   map[string]string{replacementBudgetAnnotation: "1"},
+			expectedError: errInvalidReplacementBudget,
+		}),
+		Entry("with zero replacements", parseReplacementBudgetTableInput{
+			annotations:   map[string]string{replacementBudgetAnnotation: "0/24h"},
+			expectedError: errInvalidReplacementBudget,
+		}),
+		Entry("with an invalid period", parseReplacementBudgetTableInput{
+			annotations:   map[string]string{replacementBudgetAnnotation: "1/day"},
+			expectedError: errInvalidReplacementBudget,
+		}),
+		Entry("with a negative period", parseReplacementBudgetTableInput{
+			annotations:   map[string]string{replacementBudgetAnnotation: "1/-24h"},
+			expectedError: errInvalidReplacementBudget,
+		}),
+	)
+
+	Context("nextReplacementTime", func() {
+		now := time.Date(2022, 8, 1, 12, 0, 0, 0, time.UTC)
+
+		machineBuilder := resourcebuilder.MachineInfo().WithMachineName("machine")
+
+		machineCreatedAgo := func(index int32, ago time.Duration) machineproviders.MachineInfo {
+			return machineBuilder.WithIndex(index).WithTemplateHash("hash").WithMachineCreationTimestamp(metav1.NewTime(now.Add(-ago))).Build()
+		}
+
+		externalMachineCreatedAgo := func(index int32, ago time.Duration) machineproviders.MachineInfo {
+			return machineBuilder.WithIndex(index).WithMachineCreationTimestamp(metav1.NewTime(now.Add(-ago))).Build()
+		}
+
+		type nextReplacementTimeTableInput struct {
+			budget       replacementBudget
+			machineInfos map[int32][]machineproviders.MachineInfo
+			expectedTime time.Time
+		}
+
+		DescribeTable("should determine when the next replacement is permitted", func(in nextReplacementTimeTableInput) {
+			Expect(in.budget.nextReplacementTime(in.machineInfos, now)).To(Equal(in.expectedTime))
+		},
+			Entry("with no machines created within the period", nextReplacementTimeTableInput{
+				budget: replacementBudget{replacements: 1, period: 24 * time.Hour},
+				machineInfos: map[int32][]machineproviders.MachineInfo{
+					0: {machineCreatedAgo(0, 30*24*time.Hour)},
+					1: {machineCreatedAgo(1, 30*24*time.Hour)},
+					2: {machineCreatedAgo(2, 25*time.Hour)},
+				},
+				expectedTime: time.Time{},
+			}),
+			Entry("with a machine created within the period", nextReplacementTimeTableInput{
+				budget: replacementBudget{replacements: 1, period: 24 * time.Hour},
+				machineInfos: map[int32][]machineproviders.MachineInfo{
+					0: {machineCreatedAgo(0, 30*24*time.Hour)},
+					1: {machineCreatedAgo(1, 30*24*time.Hour)},
+					2: {machineCreatedAgo(2, 4*time.Hour)},
+				},
+				expectedTime: now.Add(20 * time.Hour),
+			}),
+			Entry("with fewer machines created within the period than the budget permits", nextReplacementTimeTableInput{
+				budget: replacementBudget{replacements: 2, period: 24 * time.Hour},
+				machineInfos: map[int32][]machineproviders.MachineInfo{
+					0: {machineCreatedAgo(0, 30*24*time.Hour)},
+					1: {machineCreatedAgo(1, 30*24*time.Hour)},
+					2: {machineCreatedAgo(2, 4*time.Hour)},
+				},
+				expectedTime: time.Time{},
+			}),
+			Entry("with more machines created within the period than the budget permits", nextReplacementTimeTableInput{
+				budget: replacementBudget{replacements: 2, period: 24 * time.Hour},
+				machineInfos: map[int32][]machineproviders.MachineInfo{
+					0: {machineCreatedAgo(0, 10*time.Hour)},
+					1: {machineCreatedAgo(1, 6*time.Hour)},
+					2: {machineCreatedAgo(2, 4*time.Hour)},
+				},
+				expectedTime: now.Add(18 * time.Hour),
+			}),
+			Entry("with three fresh machines not created by the control plane machine set", nextReplacementTimeTableInput{
+				budget: replacementBudget{replacements: 1, period: 24 * time.Hour},
+				machineInfos: map[int32][]machineproviders.MachineInfo{
+					0: {externalMachineCreatedAgo(0, 3*time.Hour)},
+					1: {externalMachineCreatedAgo(1, 2*time.Hour)},
+					2: {externalMachineCreatedAgo(2, 1*time.Hour)},
+				},
+				expectedTime: time.Time{},
+			}),
+			Entry("with fresh machines, of which only one was created by the control plane machine set", nextReplacementTimeTableInput{
+				budget: replacementBudget{replacements: 1, period: 24 * time.Hour},
+				machineInfos: map[int32][]machineproviders.MachineInfo{
+					0: {externalMachineCreatedAgo(0, 3*time.Hour)},
+					1: {externalMachineCreatedAgo(1, 2*time.Hour)},
+					2: {machineCreatedAgo(2, 4*time.Hour)},
+				},
+				expectedTime: now.Add(20 * time.Hour),
+			}),
+		)
+	})
+
+	Context("with a rolling update", func() {
+		var logger test.TestLogger
+		var reconciler *ControlPlaneMachineSetReconciler
+		var mockMachineProvider *mock.MockMachineProvider
+
+		var cpmsBuilder resourcebuilder.ControlPlaneMachineSetBuilder
+		var machineInfos map[int32][]machineproviders.MachineInfo
+
+		BeforeEach(func() {
+			logger = test.NewTestLogger()
+			reconciler = &ControlPlaneMachineSetReconciler{
+				Scheme: testScheme,
+			}
+
+			mockMachineProvider = mock.NewMockMachineProvider(gomock.NewController(GinkgoT()))
+			cpmsBuilder = resourcebuilder.ControlPlaneMachineSet().WithStrategyType(machinev1.RollingUpdate).WithReplicas(3)
+
+			machineBuilder := resourcebuilder.MachineInfo().WithReady(true).WithMachineCreationTimestamp(metav1.NewTime(time.Now().Add(-30 * 24 * time.Hour)))
+
+			machineInfos = map[int32][]machineproviders.MachineInfo{
+				0: {machineBuilder.WithIndex(0).WithMachineName("machine-0").Build()},
+				1: {machineBuilder.WithIndex(1).WithMachineName("machine-1").WithNeedsUpdate(true).Build()},
+				2: {machineBuilder.WithIndex(2).WithMachineName("machine-2").WithTemplateHash("hash").WithMachineCreationTimestamp(metav1.NewTime(time.Now().Add(-4 * time.Hour))).Build()},
+			}
+		})
+
+		Context("when the budget permits a replacement", func() {
+			It("should create the replacement", func() {
+				cpms := cpmsBuilder.WithAnnotations(map[string]string{replacementBudgetAnnotation: "2/24h"}).Build()
+
+				mockMachineProvider.EXPECT().CreateMachine(gomock.Any(), gomock.Any(), int32(1)).Return(nil).Times(1)
+
+				result, err := reconciler.reconcileMachineUpdates(ctx, logger.Logger(), cpms, mockMachineProvider, machineInfos)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(result.RequeueAfter).To(BeZero())
+			})
+		})
+
+		Context("when the budget has been exhausted", func() {
+			var cpms *machinev1.ControlPlaneMachineSet
+
+			BeforeEach(func() {
+				cpms = cpmsBuilder.WithAnnotations(map[string]string{replacementBudgetAnnotation: "1/24h"}).Build()
+
+				mockMachineProvider.EXPECT().CreateMachine(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+			})
+
+			It("should requeue for when the budget next permits a replacement", func() {
+				result, err := reconciler.reconcileMachineUpdates(ctx, logger.Logger(), cpms, mockMachineProvider, machineInfos)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(result.RequeueAfter).To(BeNumerically("~", 20*time.Hour, time.Minute))
+			})
+
+			It("should set the progressing condition", func() {
+				_, err := reconciler.reconcileMachineUpdates(ctx, logger.Logger(), cpms, mockMachineProvider, machineInfos)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(cpms.Status.Conditions).To(ContainElement(SatisfyAll(
+					HaveField("Type", Equal(conditionProgressing)),
+					HaveField("Status", Equal(metav1.ConditionTrue)),
+					HaveField("Reason", Equal(reasonReplacementBudgetExhausted)),
+					HaveField("Message", HavePrefix("Replacement budget of 1/24h0m0s exhausted, next replacement permitted at ")),
+				)))
+			})
+
+			It("should log that the budget is exhausted", func() {
+				_, err := reconciler.reconcileMachineUpdates(ctx, logger.Logger(), cpms, mockMachineProvider, machineInfos)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(logger.Entries()).To(ConsistOf(SatisfyAll(
+					HaveField("Level", Equal(2)),
+					HaveField("Message", Equal(replacementBudgetExhausted)),
+					HaveField("KeysAndValues", ContainElements("index", int32(1), "name", "machine-1", "nextReplacement")),
+				)))
+			})
+
+			It("should still fill an empty index", func() {
+				machineInfos[2] = []machineproviders.MachineInfo{}
+
+				mockMachineProvider.EXPECT().CreateMachine(gomock.Any(), gomock.Any(), int32(2)).Return(nil).Times(1)
+
+				_, err := reconciler.reconcileMachineUpdates(ctx, logger.Logger(), cpms, mockMachineProvider, machineInfos)
+				Expect(err).ToNot(HaveOccurred())
+			})
+		})
+
+	})
+
+	Context("when reconciling machines with an invalid budget", func() {
+		var logger test.TestLogger
+		var reconciler *ControlPlaneMachineSetReconciler
+		var mockMachineProvider *mock.MockMachineProvider
+		var cpms *machinev1.ControlPlaneMachineSet
+		var result ctrl.Result
+		var namespaceName string
+
+		BeforeEach(func() {
+			By("Setting up a namespace for the test")
+			ns := resourcebuilder.Namespace().WithGenerateName("control-plane-machine-set-replacement-budget-").Build()
+			Expect(k8sClient.Create(ctx, ns)).To(Succeed())
+			namespaceName = ns.GetName()
+
+			logger = test.NewTestLogger()
+			reconciler = &ControlPlaneMachineSetReconciler{
+				Client: k8sClient,
+				Scheme: testScheme,
+			}
+
+			// The mock machine provider fails the test on any unexpected call, so no Machine may be created or
+			// deleted while the budget is invalid.
+			mockMachineProvider = mock.NewMockMachineProvider(gomock.NewController(GinkgoT()))
+			cpms = resourcebuilder.ControlPlaneMachineSet().WithNamespace(namespaceName).WithStrategyType(machinev1.RollingUpdate).WithReplicas(3).
+				WithAnnotations(map[string]string{replacementBudgetAnnotation: "one/day"}).Build()
+
+			machineBuilder := resourcebuilder.MachineInfo().WithReady(true).WithNodeName("node")
+			machineInfos := map[int32][]machineproviders.MachineInfo{
+				0: {machineBuilder.WithIndex(0).WithMachineName("machine-0").Build()},
+				1: {machineBuilder.WithIndex(1).WithMachineName("machine-1").WithNeedsUpdate(true).Build()},
+				2: {},
+			}
+
+			var err error
+			result, err = reconciler.reconcileMachines(ctx, logger.Logger(), cpms, mockMachineProvider, machineInfos)
+			Expect(err).ToNot(HaveOccurred(), "This is a terminal error, returning an error would force a requeue which is not desired")
+		})
+
+		AfterEach(func() {
+			test.CleanupResources(Default, ctx, cfg, k8sClient, namespaceName,
+				&corev1.ConfigMap{},
+			)
+		})
+
+		It("does not requeue", func() {
+			Expect(result).To(Equal(ctrl.Result{}))
+		})
+
+		It("sets the degraded condition", func() {
+			Expect(cpms.Status.Conditions).To(ContainElement(SatisfyAll(
+				HaveField("Type", Equal(conditionDegraded)),
+				HaveField("Status", Equal(metav1.ConditionTrue)),
+				HaveField("Reason", Equal(reasonInvalidReplacementBudget)),
+			)))
+		})
+
+		It("logs the invalid budget", func() {
+			Expect(logger.Entries()).To(ContainElement(SatisfyAll(
+				HaveField("Error", MatchError(errInvalidReplacementBudget)),
+				HaveField("Message", Equal(invalidReplacementBudgetMessage)),
+			)))
+		})
+	})
+})
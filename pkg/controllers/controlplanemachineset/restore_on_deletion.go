@@ -0,0 +1,169 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controlplanemachineset
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/go-logr/logr"
+	machinev1 "github.com/openshift/api/machine/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// restoreOnDeletionAnnotation is the annotation on the ControlPlaneMachineSet used to opt in to the ControlPlaneMachineSet
+	// being restored, from its last applied configuration, should it be deleted. The only accepted value is "true".
+	restoreOnDeletionAnnotation = "controlplanemachineset.machine.openshift.io/restore-on-deletion"
+
+	// lastAppliedConfigMapName is the name of the ConfigMap, within the namespace of the ControlPlaneMachineSet, used to
+	// record the last applied configuration of the ControlPlaneMachineSet when the restore on deletion annotation is present.
+	lastAppliedConfigMapName = "control-plane-machine-set-last-applied"
+
+	// lastAppliedConfigMapKey is the key within the last applied ConfigMap that holds the ControlPlaneMachineSet, in JSON.
+	lastAppliedConfigMapKey = "controlPlaneMachineSet"
+
+	// recordedLastApplied is a log message used to inform the user that the last applied configuration of the
+	// ControlPlaneMachineSet has been recorded.
+	recordedLastApplied = "Recorded last applied control plane machine set"
+
+	// removedLastApplied is a log message used to inform the user that the last applied configuration of the
+	// ControlPlaneMachineSet has been removed, as the restore on deletion annotation is no longer present.
+	removedLastApplied = "Removed last applied control plane machine set"
+
+	// restoredControlPlaneMachineSet is a log message used to inform the user that a deleted ControlPlaneMachineSet has
+	// been restored from its last applied configuration.
+	restoredControlPlaneMachineSet = "Restored control plane machine set from its last applied configuration"
+)
+
+// ensureLastAppliedConfigMap records the labels, annotations and spec of the ControlPlaneMachineSet within the last
+// applied ConfigMap, when the restore on deletion annotation is present, so that the ControlPlaneMachineSet can be
+// restored should it be deleted by mistake. When the annotation is not present, any previously recorded ConfigMap is
+// removed so that a deliberately deleted ControlPlaneMachineSet is not restored.
+func (r *ControlPlaneMachineSetReconciler) ensureLastAppliedConfigMap(ctx context.Context, logger logr.Logger, cpms *machinev1.ControlPlaneMachineSet) error {
+	configMap := &corev1.ConfigMap{}
+	configMapKey := client.ObjectKey{Namespace: cpms.GetNamespace(), Name: lastAppliedConfigMapName}
+
+	getErr := r.Get(ctx, configMapKey, configMap)
+	if getErr != nil && !apierrors.IsNotFound(getErr) {
+		return fmt.Errorf("could not fetch last applied configmap: %w", getErr)
+	}
+
+	if cpms.GetAnnotations()[restoreOnDeletionAnnotation] != "true" {
+		if apierrors.IsNotFound(getErr) {
+			return nil
+		}
+
+		if err := r.Delete(ctx, configMap); err != nil && !apierrors.IsNotFound(err) {
+			return fmt.Errorf("could not remove last applied configmap: %w", err)
+		}
+
+		logger.V(2).Info(removedLastApplied, "configMap", lastAppliedConfigMapName)
+
+		return nil
+	}
+
+	lastApplied, err := json.Marshal(lastAppliedControlPlaneMachineSet(cpms))
+	if err != nil {
+		return fmt.Errorf("could not marshal last applied control plane machine set: %w", err)
+	}
+
+	if apierrors.IsNotFound(getErr) {
+		configMap = &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: lastAppliedConfigMapName, Namespace: cpms.GetNamespace()},
+			Data:       map[string]string{lastAppliedConfigMapKey: string(lastApplied)},
+		}
+
+		if err := r.Create(ctx, configMap); err != nil {
+			return fmt.Errorf("could not create last applied configmap: %w", err)
+		}
+
+		logger.V(2).Info(recordedLastApplied, "configMap", lastAppliedConfigMapName)
+
+		return nil
+	}
+
+	if configMap.Data[lastAppliedConfigMapKey] == string(lastApplied) {
+		return nil
+	}
+
+	configMap.Data = map[string]string{lastAppliedConfigMapKey: string(lastApplied)}
+
+	if err := r.Update(ctx, configMap); err != nil {
+		return fmt.Errorf("could not update last applied configmap: %w", err)
+	}
+
+	logger.V(2).Info(recordedLastApplied, "configMap", lastAppliedConfigMapName)
+
+	return nil
+}
+
+// restoreControlPlaneMachineSet re-creates the ControlPlaneMachineSet, given by the key, from the last applied
+// ConfigMap. It returns true when the ControlPlaneMachineSet was restored, and false when no last applied
+// configuration was recorded for it.
+func (r *ControlPlaneMachineSetReconciler) restoreControlPlaneMachineSet(ctx context.Context, logger logr.Logger, cpmsKey client.ObjectKey) (bool, error) {
+	configMap := &corev1.ConfigMap{}
+	configMapKey := client.ObjectKey{Namespace: cpmsKey.Namespace, Name: lastAppliedConfigMapName}
+
+	if err := r.Get(ctx, configMapKey, configMap); apierrors.IsNotFound(err) {
+		return false, nil
+	} else if err != nil {
+		return false, fmt.Errorf("could not fetch last applied configmap: %w", err)
+	}
+
+	data, ok := configMap.Data[lastAppliedConfigMapKey]
+	if !ok {
+		return false, nil
+	}
+
+	cpms := &machinev1.ControlPlaneMachineSet{}
+	if err := json.Unmarshal([]byte(data), cpms); err != nil {
+		return false, fmt.Errorf("could not parse last applied control plane machine set: %w", err)
+	}
+
+	if cpms.GetName() != cpmsKey.Name {
+		return false, nil
+	}
+
+	cpms.SetNamespace(cpmsKey.Namespace)
+
+	if err := r.Create(ctx, cpms); err != nil && !apierrors.IsAlreadyExists(err) {
+		return false, fmt.Errorf("could not restore control plane machine set: %w", err)
+	}
+
+	logger.V(1).Info(restoredControlPlaneMachineSet, "configMap", lastAppliedConfigMapName)
+
+	return true, nil
+}
+
+// lastAppliedControlPlaneMachineSet returns the parts of the ControlPlaneMachineSet that are restored should it be
+// deleted. Server populated metadata, such as the UID and resource version, and the status are not recorded.
+func lastAppliedControlPlaneMachineSet(cpms *machinev1.ControlPlaneMachineSet) *machinev1.ControlPlaneMachineSet {
+	return &machinev1.ControlPlaneMachineSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        cpms.GetName(),
+			Namespace:   cpms.GetNamespace(),
+			Labels:      cpms.GetLabels(),
+			Annotations: cpms.GetAnnotations(),
+		},
+		Spec: *cpms.Spec.DeepCopy(),
+	}
+}
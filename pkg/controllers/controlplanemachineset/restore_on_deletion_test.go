@@ -0,0 +1,187 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controlplanemachineset
+
+import (
+	"encoding/json"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/go-logr/logr"
+	machinev1 "github.com/openshift/api/machine/v1"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/test"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/test/resourcebuilder"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var _ = Describe("Restore on deletion", func() {
+	var namespaceName string
+	var reconciler *ControlPlaneMachineSetReconciler
+	var configMapKey client.ObjectKey
+
+	BeforeEach(func() {
+		By("Setting up a namespace for the test")
+		ns := resourcebuilder.Namespace().WithGenerateName("control-plane-machine-set-controller-").Build()
+		Expect(k8sClient.Create(ctx, ns)).To(Succeed())
+		namespaceName = ns.GetName()
+
+		reconciler = &ControlPlaneMachineSetReconciler{
+			Client:    k8sClient,
+			Scheme:    testScheme,
+			Namespace: namespaceName,
+		}
+
+		configMapKey = client.ObjectKey{Namespace: namespaceName, Name: lastAppliedConfigMapName}
+	})
+
+	AfterEach(func() {
+		test.CleanupResources(Default, ctx, cfg, k8sClient, namespaceName,
+			&machinev1.ControlPlaneMachineSet{},
+			&corev1.ConfigMap{},
+		)
+	})
+
+	restoreAnnotations := map[string]string{restoreOnDeletionAnnotation: "true"}
+
+	Context("ensureLastAppliedConfigMap", func() {
+		It("should not record the ControlPlaneMachineSet without the annotation", func() {
+			cpms := resourcebuilder.ControlPlaneMachineSet().WithNamespace(namespaceName).Build()
+
+			Expect(reconciler.ensureLastAppliedConfigMap(ctx, logr.Discard(), cpms)).To(Succeed())
+
+			err := k8sClient.Get(ctx, configMapKey, &corev1.ConfigMap{})
+			Expect(apierrors.IsNotFound(err)).To(BeTrue(), "expected the last applied configmap not to exist, got: %v", err)
+		})
+
+		It("should not record the ControlPlaneMachineSet when the annotation is not true", func() {
+			cpms := resourcebuilder.ControlPlaneMachineSet().WithNamespace(namespaceName).
+				WithAnnotations(map[string]string{restoreOnDeletionAnnotation: "yes"}).Build()
+
+			Expect(reconciler.ensureLastAppliedConfigMap(ctx, logr.Discard(), cpms)).To(Succeed())
+
+			err := k8sClient.Get(ctx, configMapKey, &corev1.ConfigMap{})
+			Expect(apierrors.IsNotFound(err)).To(BeTrue(), "expected the last applied configmap not to exist, got: %v", err)
+		})
+
+		Context("with the annotation", func() {
+			var cpms *machinev1.ControlPlaneMachineSet
+
+			BeforeEach(func() {
+				cpms = resourcebuilder.ControlPlaneMachineSet().WithNamespace(namespaceName).WithReplicas(3).
+					WithAnnotations(restoreAnnotations).Build()
+				Expect(k8sClient.Create(ctx, cpms)).To(Succeed())
+
+				Expect(reconciler.ensureLastAppliedConfigMap(ctx, logr.Discard(), cpms)).To(Succeed())
+			})
+
+			lastApplied := func() *machinev1.ControlPlaneMachineSet {
+				configMap := &corev1.ConfigMap{}
+				Expect(k8sClient.Get(ctx, configMapKey, configMap)).To(Succeed())
+
+				recorded := &machinev1.ControlPlaneMachineSet{}
+				Expect(json.Unmarshal([]byte(configMap.Data[lastAppliedConfigMapKey]), recorded)).To(Succeed())
+
+				return recorded
+			}
+
+			It("should record the spec, labels and annotations", func() {
+				recorded := lastApplied()
+
+				Expect(recorded.Spec).To(Equal(cpms.Spec))
+				Expect(recorded.GetName()).To(Equal(cpms.GetName()))
+				Expect(recorded.GetAnnotations()).To(Equal(cpms.GetAnnotations()))
+				Expect(recorded.GetLabels()).To(Equal(cpms.GetLabels()))
+			})
+
+			It("should not record server populated metadata", func() {
+				recorded := lastApplied()
+
+				Expect(recorded.GetUID()).To(BeEmpty())
+				Expect(recorded.GetResourceVersion()).To(BeEmpty())
+				Expect(recorded.GetCreationTimestamp()).To(Equal(metav1.Time{}))
+			})
+
+			It("should update the record when the spec changes", func() {
+				cpms.Spec.Template.OpenShiftMachineV1Beta1Machine.ObjectMeta.Labels["example.com/updated"] = "true"
+
+				Expect(reconciler.ensureLastAppliedConfigMap(ctx, logr.Discard(), cpms)).To(Succeed())
+
+				Expect(lastApplied().Spec).To(Equal(cpms.Spec))
+			})
+
+			It("should remove the record once the annotation is removed", func() {
+				cpms.SetAnnotations(nil)
+
+				Expect(reconciler.ensureLastAppliedConfigMap(ctx, logr.Discard(), cpms)).To(Succeed())
+
+				err := k8sClient.Get(ctx, configMapKey, &corev1.ConfigMap{})
+				Expect(apierrors.IsNotFound(err)).To(BeTrue(), "expected the last applied configmap to be removed, got: %v", err)
+			})
+
+			Context("once the ControlPlaneMachineSet is deleted", func() {
+				var cpmsKey client.ObjectKey
+
+				BeforeEach(func() {
+					cpmsKey = client.ObjectKeyFromObject(cpms)
+					Expect(k8sClient.Delete(ctx, cpms)).To(Succeed())
+				})
+
+				It("should restore the ControlPlaneMachineSet from the record", func() {
+					restored, err := reconciler.restoreControlPlaneMachineSet(ctx, logr.Discard(), cpmsKey)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(restored).To(BeTrue())
+
+					restoredCPMS := &machinev1.ControlPlaneMachineSet{}
+					Expect(k8sClient.Get(ctx, cpmsKey, restoredCPMS)).To(Succeed())
+
+					Expect(restoredCPMS.Spec).To(Equal(cpms.Spec))
+					Expect(restoredCPMS.GetAnnotations()).To(HaveKeyWithValue(restoreOnDeletionAnnotation, "true"))
+					Expect(restoredCPMS.GetUID()).ToNot(Equal(cpms.GetUID()))
+				})
+			})
+		})
+	})
+
+	Context("restoreControlPlaneMachineSet", func() {
+		It("should not restore the ControlPlaneMachineSet without a record", func() {
+			cpmsKey := client.ObjectKey{Namespace: namespaceName, Name: DefaultControlPlaneMachineSetName}
+
+			restored, err := reconciler.restoreControlPlaneMachineSet(ctx, logr.Discard(), cpmsKey)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(restored).To(BeFalse())
+
+			err = k8sClient.Get(ctx, cpmsKey, &machinev1.ControlPlaneMachineSet{})
+			Expect(apierrors.IsNotFound(err)).To(BeTrue(), "expected the control plane machine set not to exist, got: %v", err)
+		})
+
+		It("should return an error when the record cannot be parsed", func() {
+			Expect(k8sClient.Create(ctx, &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: lastAppliedConfigMapName, Namespace: namespaceName},
+				Data:       map[string]string{lastAppliedConfigMapKey: "{"},
+			})).To(Succeed())
+
+			cpmsKey := client.ObjectKey{Namespace: namespaceName, Name: DefaultControlPlaneMachineSetName}
+
+			_, err := reconciler.restoreControlPlaneMachineSet(ctx, logr.Discard(), cpmsKey)
+			Expect(err).To(MatchError(ContainSubstring("could not parse last applied control plane machine set")))
+		})
+	})
+})
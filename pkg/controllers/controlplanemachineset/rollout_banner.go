@@ -0,0 +1,159 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controlplanemachineset
+
+import (
+	"fmt"
+
+	machinev1 "github.com/openshift/api/machine/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func init() {
+	registerInformationalCondition(conditionRolloutBanner)
+}
+
+// bannerLevel is how urgently the rollout banner should be brought to the attention of the user. The levels match
+// the variants of the alerts rendered by the OpenShift console.
+type bannerLevel string
+
+const (
+	// bannerLevelInfo denotes that the rollout is progressing, or is blocked by a gate that will unblock without any
+	// user action.
+	bannerLevelInfo bannerLevel = "Info"
+
+	// bannerLevelWarning denotes that the rollout is blocked until the user, or the Machine API, takes action.
+	bannerLevelWarning bannerLevel = "Warning"
+
+	// bannerLevelDanger denotes that the ControlPlaneMachineSet is degraded, and no action is being taken on the
+	// Control Plane Machines.
+	bannerLevelDanger bannerLevel = "Danger"
+)
+
+const (
+	// blockingReasonMachineAPIPaused is the blocking reason of the rollout banner while the Machine API has paused a
+	// Control Plane Machine.
+	blockingReasonMachineAPIPaused = "MachineAPIPaused"
+
+	// blockingReasonDrainTimedOut is the blocking reason of the rollout banner while the drain of a Control Plane
+	// Node has timed out.
+	blockingReasonDrainTimedOut = "DrainTimedOut"
+)
+
+// rolloutBanner describes the state of the rollout of the Control Plane Machines so that the OpenShift console can
+// render a banner for it without inferring the state from the other conditions.
+type rolloutBanner struct {
+	// level is how urgently the banner should be brought to the attention of the user.
+	level bannerLevel
+
+	// summary describes, to the user, what the operator is doing.
+	summary string
+
+	// blockingReason identifies what is blocking the rollout, or is empty when nothing is blocking the rollout.
+	blockingReason string
+
+	// blockingDetail explains, to the user, what the blocking reason is waiting for.
+	blockingDetail string
+}
+
+// message returns the message of the RolloutBanner condition for the banner, eg `Updating control plane machines, 1
+// of 3 up to date. Blocked by ReplacementBudget: Replacements are gated by the replacement budget of 1/24h0m0s,
+// expected to unblock at 2022-06-01T12:00:00Z`.
+func (b rolloutBanner) message() string {
+	if b.blockingReason == "" {
+		return b.summary
+	}
+
+	return fmt.Sprintf("%s. Blocked by %s: %s", b.summary, b.blockingReason, b.blockingDetail)
+}
+
+// rolloutBannerFor determines the rollout banner from the status of the ControlPlaneMachineSet, once all other
+// conditions have been set for the current reconcile. It returns false when there is nothing to report, that is when
+// no rollout is pending and nothing is blocking the Control Plane Machines.
+func rolloutBannerFor(cpms *machinev1.ControlPlaneMachineSet) (rolloutBanner, bool) {
+	conditions := cpms.Status.Conditions
+
+	rolloutPhase := meta.FindStatusCondition(conditions, conditionRolloutPhase)
+	gatedBy := meta.FindStatusCondition(conditions, conditionGatedBy)
+
+	rolling := gatedBy != nil || (rolloutPhase != nil && rolloutPhase.Status == metav1.ConditionTrue)
+
+	banner := rolloutBanner{
+		level:   bannerLevelInfo,
+		summary: "No control plane machine updates are in progress",
+	}
+
+	if rolling {
+		replicas := int32(0)
+		if cpms.Spec.Replicas != nil {
+			replicas = *cpms.Spec.Replicas
+		}
+
+		banner.summary = fmt.Sprintf("Updating control plane machines, %d of %d up to date", cpms.Status.UpdatedReplicas, replicas)
+	}
+
+	degraded := meta.FindStatusCondition(conditions, conditionDegraded)
+	machineAPIPaused := meta.FindStatusCondition(conditions, conditionMachineAPIPaused)
+	drainProgress := meta.FindStatusCondition(conditions, conditionDrainProgress)
+
+	switch {
+	case degraded != nil && degraded.Status == metav1.ConditionTrue:
+		banner.level = bannerLevelDanger
+		banner.blockingReason = reasonGatedByOperatorDegraded
+		banner.blockingDetail = fmt.Sprintf("The control plane machine set is degraded (%s): %s", degraded.Reason, degraded.Message)
+	case machineAPIPaused != nil:
+		banner.level = bannerLevelWarning
+		banner.blockingReason = blockingReasonMachineAPIPaused
+		banner.blockingDetail = machineAPIPaused.Message
+	case drainProgress != nil && drainProgress.Reason == reasonDrainTimedOut:
+		banner.level = bannerLevelWarning
+		banner.blockingReason = blockingReasonDrainTimedOut
+		banner.blockingDetail = drainProgress.Message
+	case gatedBy != nil:
+		// The replacement budget unblocks on its own, every other gate waits for action by the user.
+		if gatedBy.Reason != reasonGatedByReplacementBudget {
+			banner.level = bannerLevelWarning
+		}
+
+		banner.blockingReason = gatedBy.Reason
+		banner.blockingDetail = gatedBy.Message
+	case !rolling:
+		return rolloutBanner{}, false
+	}
+
+	return banner, true
+}
+
+// setRolloutBannerCondition sets the rollout banner condition to report the rollout banner, with the level as the
+// reason and the summary and any blocking reason as the message. The condition is removed when there is nothing to
+// report. This must be called once every other condition has been set for the current reconcile.
+func setRolloutBannerCondition(cpms *machinev1.ControlPlaneMachineSet) {
+	banner, ok := rolloutBannerFor(cpms)
+	if !ok {
+		meta.RemoveStatusCondition(&cpms.Status.Conditions, conditionRolloutBanner)
+		return
+	}
+
+	meta.SetStatusCondition(&cpms.Status.Conditions, metav1.Condition{
+		Type:               conditionRolloutBanner,
+		Status:             metav1.ConditionTrue,
+		Reason:             string(banner.level),
+		ObservedGeneration: cpms.GetGeneration(),
+		Message:            banner.message(),
+	})
+}
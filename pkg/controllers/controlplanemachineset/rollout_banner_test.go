@@ -0,0 +1,143 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controlplanemachineset
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/test/resourcebuilder"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("RolloutBanner condition", func() {
+	rolloutPhase := metav1.Condition{Type: conditionRolloutPhase, Status: metav1.ConditionTrue, Reason: string(phaseSurgeCreating)}
+	idlePhase := metav1.Condition{Type: conditionRolloutPhase, Status: metav1.ConditionFalse, Reason: string(phaseIdle)}
+
+	type rolloutBannerTableInput struct {
+		conditions      []metav1.Condition
+		updatedReplicas int32
+		expectBanner    bool
+		expectedReason  string
+		expectedMessage string
+	}
+
+	DescribeTable("setRolloutBannerCondition", func(in rolloutBannerTableInput) {
+		cpms := resourcebuilder.ControlPlaneMachineSet().WithReplicas(3).Build()
+		cpms.Status.Conditions = in.conditions
+		cpms.Status.UpdatedReplicas = in.updatedReplicas
+
+		// Any banner from an earlier reconcile should be replaced, or removed.
+		cpms.Status.Conditions = append(cpms.Status.Conditions, metav1.Condition{Type: conditionRolloutBanner, Status: metav1.ConditionTrue, Reason: string(bannerLevelDanger), Message: "previous"})
+
+		setRolloutBannerCondition(cpms)
+
+		banner := meta.FindStatusCondition(cpms.Status.Conditions, conditionRolloutBanner)
+		if !in.expectBanner {
+			Expect(banner).To(BeNil())
+			return
+		}
+
+		Expect(banner).ToNot(BeNil())
+		Expect(banner.Status).To(Equal(metav1.ConditionTrue))
+		Expect(banner.Reason).To(Equal(in.expectedReason))
+		Expect(banner.Message).To(Equal(in.expectedMessage))
+	},
+		Entry("with no rollout", rolloutBannerTableInput{
+			conditions:      []metav1.Condition{idlePhase},
+			updatedReplicas: 3,
+			expectBanner:    false,
+		}),
+		Entry("with a rollout in progress", rolloutBannerTableInput{
+			conditions:      []metav1.Condition{rolloutPhase},
+			updatedReplicas: 1,
+			expectBanner:    true,
+			expectedReason:  string(bannerLevelInfo),
+			expectedMessage: "Updating control plane machines, 1 of 3 up to date",
+		}),
+		Entry("with a rollout gated by the replacement budget", rolloutBannerTableInput{
+			conditions: []metav1.Condition{
+				idlePhase,
+				{Type: conditionGatedBy, Status: metav1.ConditionTrue, Reason: reasonGatedByReplacementBudget, Message: "Replacements are gated by the replacement budget of 1/24h0m0s, expected to unblock at 2022-06-01T12:00:00Z"},
+			},
+			updatedReplicas: 2,
+			expectBanner:    true,
+			expectedReason:  string(bannerLevelInfo),
+			expectedMessage: "Updating control plane machines, 2 of 3 up to date. Blocked by ReplacementBudget: Replacements are gated by the replacement budget of 1/24h0m0s, expected to unblock at 2022-06-01T12:00:00Z",
+		}),
+		Entry("with a rollout waiting for the user to delete machines", rolloutBannerTableInput{
+			conditions: []metav1.Condition{
+				idlePhase,
+				{Type: conditionGatedBy, Status: metav1.ConditionTrue, Reason: reasonGatedByUserDeletion, Message: "Replacements are gated by the OnDelete strategy, delete the outdated machines to continue: machine-0, waiting for user action"},
+			},
+			updatedReplicas: 2,
+			expectBanner:    true,
+			expectedReason:  string(bannerLevelWarning),
+			expectedMessage: "Updating control plane machines, 2 of 3 up to date. Blocked by UserDeletion: Replacements are gated by the OnDelete strategy, delete the outdated machines to continue: machine-0, waiting for user action",
+		}),
+		Entry("with a timed out drain", rolloutBannerTableInput{
+			conditions: []metav1.Condition{
+				rolloutPhase,
+				{Type: conditionDrainProgress, Status: metav1.ConditionTrue, Reason: reasonDrainTimedOut, Message: "Draining 1 node(s): master-1 (2 pod(s) remaining) since 2022-10-14T10:00:00Z, timed out"},
+			},
+			updatedReplicas: 2,
+			expectBanner:    true,
+			expectedReason:  string(bannerLevelWarning),
+			expectedMessage: "Updating control plane machines, 2 of 3 up to date. Blocked by DrainTimedOut: Draining 1 node(s): master-1 (2 pod(s) remaining) since 2022-10-14T10:00:00Z, timed out",
+		}),
+		Entry("with a drain in progress", rolloutBannerTableInput{
+			conditions: []metav1.Condition{
+				rolloutPhase,
+				{Type: conditionDrainProgress, Status: metav1.ConditionTrue, Reason: reasonDrainInProgress, Message: "Draining 1 node(s): master-1 (2 pod(s) remaining) since 2022-10-14T10:00:00Z"},
+			},
+			updatedReplicas: 2,
+			expectBanner:    true,
+			expectedReason:  string(bannerLevelInfo),
+			expectedMessage: "Updating control plane machines, 2 of 3 up to date",
+		}),
+		Entry("with machines paused by the Machine API", rolloutBannerTableInput{
+			conditions: []metav1.Condition{
+				idlePhase,
+				{Type: conditionMachineAPIPaused, Status: metav1.ConditionTrue, Reason: reasonMachinesPaused, Message: "machine-0 is paused"},
+			},
+			updatedReplicas: 3,
+			expectBanner:    true,
+			expectedReason:  string(bannerLevelWarning),
+			expectedMessage: "No control plane machine updates are in progress. Blocked by MachineAPIPaused: machine-0 is paused",
+		}),
+		Entry("with a degraded control plane machine set", rolloutBannerTableInput{
+			conditions: []metav1.Condition{
+				rolloutPhase,
+				{Type: conditionDegraded, Status: metav1.ConditionTrue, Reason: reasonUnmanagedNodes, Message: "Found 1 unmanaged node(s)"},
+				{Type: conditionGatedBy, Status: metav1.ConditionTrue, Reason: reasonGatedByOperatorDegraded, Message: "Replacements are paused while the control plane machine set is degraded: UnmanagedNodes, waiting for user action"},
+			},
+			updatedReplicas: 1,
+			expectBanner:    true,
+			expectedReason:  string(bannerLevelDanger),
+			expectedMessage: "Updating control plane machines, 1 of 3 up to date. Blocked by OperatorDegraded: The control plane machine set is degraded (UnmanagedNodes): Found 1 unmanaged node(s)",
+		}),
+		Entry("with a control plane machine set that is not degraded", rolloutBannerTableInput{
+			conditions: []metav1.Condition{
+				idlePhase,
+				{Type: conditionDegraded, Status: metav1.ConditionFalse, Reason: reasonAsExpected},
+			},
+			updatedReplicas: 3,
+			expectBanner:    false,
+		}),
+	)
+})
@@ -0,0 +1,171 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controlplanemachineset
+
+import (
+	"fmt"
+	"strings"
+
+	machinev1 "github.com/openshift/api/machine/v1"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func init() {
+	registerInformationalCondition(conditionRolloutPhase)
+}
+
+// rolloutPhase describes how far a Control Plane Machine index has progressed through the replacement of its Machine.
+// The phases are surfaced as the reason of the RolloutPhase condition.
+type rolloutPhase string
+
+const (
+	// phaseIdle denotes that the index has a ready, up to date Machine and no Machine awaiting removal.
+	phaseIdle rolloutPhase = "Idle"
+
+	// phasePlanPending denotes that the index requires a new Machine, but that the new Machine has not yet
+	// been created. For example, when the rollout is waiting on another index, on the replacement budget, or, with the
+	// OnDelete strategy, on the user to delete the outdated Machine.
+	phasePlanPending rolloutPhase = "PlanPending"
+
+	// phaseSurgeCreating denotes that a new Machine has been created for the index, but that it does not yet have
+	// a Node.
+	phaseSurgeCreating rolloutPhase = "SurgeCreating"
+
+	// phaseWaitingReady denotes that the Node of the new Machine has joined the cluster, but that the new Machine is
+	// not yet ready.
+	phaseWaitingReady rolloutPhase = "WaitingReady"
+
+	// phaseDraining denotes that the Machine being replaced has been deleted, but that its Node is still
+	// registered while workloads are drained from it.
+	phaseDraining rolloutPhase = "Draining"
+
+	// phaseDeleting denotes that the Node of the Machine being replaced has been removed, and that the Machine is
+	// waiting for its instance to be removed.
+	phaseDeleting rolloutPhase = "Deleting"
+
+	// phaseVerifying denotes that the new Machine is ready, and that the Machine being replaced is awaiting deletion.
+	phaseVerifying rolloutPhase = "Verifying"
+)
+
+// indexRolloutPhase determines the rollout phase of a single Control Plane Machine index from the MachineInfos
+// within the index.
+// The phase of the new Machine takes precedence over the phase of any Machine being replaced, as the removal of the
+// Machine being replaced is typically blocked until the new Machine is ready.
+func indexRolloutPhase(machineInfos []machineproviders.MachineInfo) rolloutPhase {
+	var newMachines, outgoingMachines []machineproviders.MachineInfo
+
+	for _, machineInfo := range machineInfos {
+		if machineInfo.MachineRef == nil {
+			continue
+		}
+
+		if machineInfo.NeedsUpdate || machineInfo.MachineRef.ObjectMeta.GetDeletionTimestamp() != nil {
+			outgoingMachines = append(outgoingMachines, machineInfo)
+		} else {
+			newMachines = append(newMachines, machineInfo)
+		}
+	}
+
+	switch {
+	case len(newMachines) == 0:
+		return phasePlanPending
+	case !hasReadyMachine(newMachines):
+		for _, machineInfo := range newMachines {
+			if machineInfo.NodeRef != nil {
+				return phaseWaitingReady
+			}
+		}
+
+		return phaseSurgeCreating
+	case len(outgoingMachines) == 0:
+		return phaseIdle
+	}
+
+	deleting := false
+
+	for _, machineInfo := range outgoingMachines {
+		if machineInfo.MachineRef.ObjectMeta.GetDeletionTimestamp() == nil {
+			continue
+		}
+
+		if machineInfo.NodeRef != nil {
+			return phaseDraining
+		}
+
+		deleting = true
+	}
+
+	if deleting {
+		return phaseDeleting
+	}
+
+	return phaseVerifying
+}
+
+// rolloutPhases determines the rollout phase of each Control Plane Machine index, and the overall rollout phase of
+// the ControlPlaneMachineSet.
+// The overall phase is the phase of the lowest index in which a replacement is in progress. When no replacement is in
+// progress, it is PlanPending if any index is waiting for a replacement, and Idle otherwise.
+func rolloutPhases(machineInfos map[int32][]machineproviders.MachineInfo) (rolloutPhase, map[int32]rolloutPhase) {
+	overall := phaseIdle
+	phases := map[int32]rolloutPhase{}
+
+	for _, idx := range sortedIndexes(machineInfos) {
+		phase := indexRolloutPhase(machineInfos[idx])
+		phases[idx] = phase
+
+		switch {
+		case phase == phaseIdle:
+		case phase == phasePlanPending:
+			if overall == phaseIdle {
+				overall = phasePlanPending
+			}
+		case overall == phaseIdle || overall == phasePlanPending:
+			overall = phase
+		}
+	}
+
+	return overall, phases
+}
+
+// setRolloutPhaseCondition sets the rollout phase condition to report the overall rollout phase, as the reason, and
+// the phase of each index, within the message. The message lists the phases in index order, eg
+// `0=Idle, 1=SurgeCreating, 2=PlanPending`.
+// The condition is true whenever the overall phase is not Idle.
+func setRolloutPhaseCondition(cpms *machinev1.ControlPlaneMachineSet, machineInfos map[int32][]machineproviders.MachineInfo) {
+	overall, phases := rolloutPhases(machineInfos)
+
+	indexPhases := []string{}
+	for _, idx := range sortedIndexes(machineInfos) {
+		indexPhases = append(indexPhases, fmt.Sprintf("%d=%s", idx, phases[idx]))
+	}
+
+	status := metav1.ConditionTrue
+	if overall == phaseIdle {
+		status = metav1.ConditionFalse
+	}
+
+	meta.SetStatusCondition(&cpms.Status.Conditions, metav1.Condition{
+		Type:               conditionRolloutPhase,
+		Status:             status,
+		Reason:             string(overall),
+		ObservedGeneration: cpms.GetGeneration(),
+		Message:            strings.Join(indexPhases, ", "),
+	})
+}
@@ -0,0 +1,190 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controlplanemachineset
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/test"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/test/resourcebuilder"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("Rollout phase", func() {
+	healthyMachineBuilder := resourcebuilder.MachineInfo().WithReady(true)
+	pendingMachineBuilder := resourcebuilder.MachineInfo().WithReady(false)
+
+	outdatedMachine := healthyMachineBuilder.WithMachineName("machine-0").WithNodeName("node-0").WithNeedsUpdate(true)
+	deletedMachine := outdatedMachine.WithReady(false).WithMachineDeletionTimestamp(metav1.Now())
+	updatedMachine := healthyMachineBuilder.WithMachineName("machine-replacement-0").WithNodeName("node-replacement-0")
+
+	type indexRolloutPhaseTableInput struct {
+		machineInfos  []machineproviders.MachineInfo
+		expectedPhase rolloutPhase
+	}
+
+	DescribeTable("indexRolloutPhase", func(in indexRolloutPhaseTableInput) {
+		Expect(indexRolloutPhase(in.machineInfos)).To(Equal(in.expectedPhase))
+	},
+		Entry("with a single ready, up to date machine", indexRolloutPhaseTableInput{
+			machineInfos:  []machineproviders.MachineInfo{updatedMachine.Build()},
+			expectedPhase: phaseIdle,
+		}),
+		Entry("with no machines", indexRolloutPhaseTableInput{
+			machineInfos:  []machineproviders.MachineInfo{},
+			expectedPhase: phasePlanPending,
+		}),
+		Entry("with an outdated machine and no replacement", indexRolloutPhaseTableInput{
+			machineInfos:  []machineproviders.MachineInfo{outdatedMachine.Build()},
+			expectedPhase: phasePlanPending,
+		}),
+		Entry("with a deleted machine and no replacement", indexRolloutPhaseTableInput{
+			machineInfos:  []machineproviders.MachineInfo{deletedMachine.Build()},
+			expectedPhase: phasePlanPending,
+		}),
+		Entry("with an outdated machine and a replacement without a node", indexRolloutPhaseTableInput{
+			machineInfos: []machineproviders.MachineInfo{
+				outdatedMachine.Build(),
+				pendingMachineBuilder.WithMachineName("machine-replacement-0").Build(),
+			},
+			expectedPhase: phaseSurgeCreating,
+		}),
+		Entry("with no machine other than a new machine without a node", indexRolloutPhaseTableInput{
+			machineInfos:  []machineproviders.MachineInfo{pendingMachineBuilder.WithMachineName("machine-replacement-0").Build()},
+			expectedPhase: phaseSurgeCreating,
+		}),
+		Entry("with an outdated machine and a replacement with a node that is not ready", indexRolloutPhaseTableInput{
+			machineInfos: []machineproviders.MachineInfo{
+				outdatedMachine.Build(),
+				pendingMachineBuilder.WithMachineName("machine-replacement-0").WithNodeName("node-replacement-0").Build(),
+			},
+			expectedPhase: phaseWaitingReady,
+		}),
+		Entry("with a deleted machine and a replacement with a node that is not ready", indexRolloutPhaseTableInput{
+			machineInfos: []machineproviders.MachineInfo{
+				deletedMachine.Build(),
+				pendingMachineBuilder.WithMachineName("machine-replacement-0").WithNodeName("node-replacement-0").Build(),
+			},
+			expectedPhase: phaseWaitingReady,
+		}),
+		Entry("with an outdated machine and a ready replacement", indexRolloutPhaseTableInput{
+			machineInfos: []machineproviders.MachineInfo{
+				outdatedMachine.Build(),
+				updatedMachine.Build(),
+			},
+			expectedPhase: phaseVerifying,
+		}),
+		Entry("with a deleted machine with a node and a ready replacement", indexRolloutPhaseTableInput{
+			machineInfos: []machineproviders.MachineInfo{
+				deletedMachine.Build(),
+				updatedMachine.Build(),
+			},
+			expectedPhase: phaseDraining,
+		}),
+		Entry("with a deleted machine without a node and a ready replacement", indexRolloutPhaseTableInput{
+			machineInfos: []machineproviders.MachineInfo{
+				deletedMachine.WithNodeName("").Build(),
+				updatedMachine.Build(),
+			},
+			expectedPhase: phaseDeleting,
+		}),
+		Entry("with an up to date machine that has been deleted and a ready replacement", indexRolloutPhaseTableInput{
+			machineInfos: []machineproviders.MachineInfo{
+				deletedMachine.WithNeedsUpdate(false).Build(),
+				updatedMachine.Build(),
+			},
+			expectedPhase: phaseDraining,
+		}),
+	)
+
+	type setRolloutPhaseConditionTableInput struct {
+		machineInfos      map[int32][]machineproviders.MachineInfo
+		expectedCondition metav1.Condition
+	}
+
+	DescribeTable("setRolloutPhaseCondition", func(in setRolloutPhaseConditionTableInput) {
+		cpms := resourcebuilder.ControlPlaneMachineSet().Build()
+
+		setRolloutPhaseCondition(cpms, in.machineInfos)
+
+		Expect(cpms.Status.Conditions).To(ConsistOf(test.MatchCondition(in.expectedCondition)))
+	},
+		Entry("with all indexes idle", setRolloutPhaseConditionTableInput{
+			machineInfos: map[int32][]machineproviders.MachineInfo{
+				0: {updatedMachine.WithIndex(0).Build()},
+				1: {updatedMachine.WithIndex(1).Build()},
+				2: {updatedMachine.WithIndex(2).Build()},
+			},
+			expectedCondition: metav1.Condition{
+				Type:    conditionRolloutPhase,
+				Status:  metav1.ConditionFalse,
+				Reason:  string(phaseIdle),
+				Message: "0=Idle, 1=Idle, 2=Idle",
+			},
+		}),
+		Entry("with indexes waiting for a replacement", setRolloutPhaseConditionTableInput{
+			machineInfos: map[int32][]machineproviders.MachineInfo{
+				0: {updatedMachine.WithIndex(0).Build()},
+				1: {outdatedMachine.WithIndex(1).Build()},
+				2: {outdatedMachine.WithIndex(2).Build()},
+			},
+			expectedCondition: metav1.Condition{
+				Type:    conditionRolloutPhase,
+				Status:  metav1.ConditionTrue,
+				Reason:  string(phasePlanPending),
+				Message: "0=Idle, 1=PlanPending, 2=PlanPending",
+			},
+		}),
+		Entry("with a replacement in progress", setRolloutPhaseConditionTableInput{
+			machineInfos: map[int32][]machineproviders.MachineInfo{
+				0: {outdatedMachine.WithIndex(0).Build()},
+				1: {
+					outdatedMachine.WithIndex(1).Build(),
+					pendingMachineBuilder.WithIndex(1).WithMachineName("machine-replacement-1").Build(),
+				},
+				2: {updatedMachine.WithIndex(2).Build()},
+			},
+			expectedCondition: metav1.Condition{
+				Type:    conditionRolloutPhase,
+				Status:  metav1.ConditionTrue,
+				Reason:  string(phaseSurgeCreating),
+				Message: "0=PlanPending, 1=SurgeCreating, 2=Idle",
+			},
+		}),
+		Entry("with replacements in progress in multiple indexes", setRolloutPhaseConditionTableInput{
+			machineInfos: map[int32][]machineproviders.MachineInfo{
+				0: {
+					deletedMachine.WithIndex(0).Build(),
+					updatedMachine.WithIndex(0).Build(),
+				},
+				1: {
+					outdatedMachine.WithIndex(1).Build(),
+					pendingMachineBuilder.WithIndex(1).WithMachineName("machine-replacement-1").Build(),
+				},
+				2: {updatedMachine.WithIndex(2).Build()},
+			},
+			expectedCondition: metav1.Condition{
+				Type:    conditionRolloutPhase,
+				Status:  metav1.ConditionTrue,
+				Reason:  string(phaseDraining),
+				Message: "0=Draining, 1=SurgeCreating, 2=Idle",
+			},
+		}),
+	)
+})
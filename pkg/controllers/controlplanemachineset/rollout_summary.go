@@ -0,0 +1,147 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controlplanemachineset
+
+import (
+	"fmt"
+	"time"
+
+	machinev1 "github.com/openshift/api/machine/v1"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func init() {
+	registerInformationalCondition(conditionLastRollout)
+}
+
+// rolloutDisruption accumulates the disruption windows observed while a rollout is in progress.
+// A disruption window is a period during which at least one Control Plane Machine is being removed, that is, during
+// which at least one index is Draining or Deleting.
+// The accumulated windows are held in memory, so windows observed before the operator restarts are not counted.
+type rolloutDisruption struct {
+	// windows is the number of disruption windows observed.
+	windows int
+
+	// total is the total duration of the disruption windows that have closed.
+	total time.Duration
+
+	// openSince is the time at which the current disruption window opened, or the zero time when no disruption
+	// window is open.
+	openSince time.Time
+}
+
+// observe records whether the Control Plane is disrupted at the time provided, opening or closing a disruption
+// window as required.
+func (d *rolloutDisruption) observe(disrupted bool, now time.Time) {
+	switch {
+	case disrupted && d.openSince.IsZero():
+		d.windows++
+		d.openSince = now
+	case !disrupted && !d.openSince.IsZero():
+		d.total += now.Sub(d.openSince)
+		d.openSince = time.Time{}
+	}
+}
+
+// isDisruptivePhase returns whether the rollout phase denotes that a Control Plane Machine is being removed.
+func isDisruptivePhase(phase rolloutPhase) bool {
+	return phase == phaseDraining || phase == phaseDeleting
+}
+
+// observeRollout records the disruption of the Control Plane in the current reconcile and, once a rollout has
+// completed, sets the last rollout condition to summarise it.
+// A rollout has completed when the rollout phase condition, from the previous reconcile, is true and every index is
+// now Idle. This must therefore be called before the rollout phase condition is updated.
+// The rollout started when the rollout phase condition last became true, and the Machines replaced are the Machines
+// created since. A rollout that completes without replacing any Machine, for example because the template was
+// reverted before any replacement was created, is not recorded.
+func (r *ControlPlaneMachineSetReconciler) observeRollout(cpms *machinev1.ControlPlaneMachineSet, machineInfos map[int32][]machineproviders.MachineInfo, now time.Time) {
+	overall, phases := rolloutPhases(machineInfos)
+
+	disrupted := false
+
+	for _, phase := range phases {
+		if isDisruptivePhase(phase) {
+			disrupted = true
+		}
+	}
+
+	r.rolloutDisruption.observe(disrupted, now)
+
+	previous := meta.FindStatusCondition(cpms.Status.Conditions, conditionRolloutPhase)
+	if overall != phaseIdle || previous == nil || previous.Status != metav1.ConditionTrue {
+		return
+	}
+
+	disruption := r.rolloutDisruption
+	r.rolloutDisruption = rolloutDisruption{}
+
+	started := previous.LastTransitionTime.Time
+	replaced := machinesCreatedSince(machineInfos, started)
+
+	if replaced == 0 {
+		return
+	}
+
+	setLastRolloutCondition(cpms, started, now, replaced, disruption)
+}
+
+// machinesCreatedSince counts the Machines within the machineInfos that were created at, or after, the time
+// provided.
+func machinesCreatedSince(indexedMachineInfos map[int32][]machineproviders.MachineInfo, since time.Time) int {
+	count := 0
+
+	for _, machineInfos := range indexedMachineInfos {
+		for _, machineInfo := range machineInfos {
+			if machineInfo.MachineRef == nil {
+				continue
+			}
+
+			if !machineInfo.MachineRef.ObjectMeta.GetCreationTimestamp().Time.Before(since) {
+				count++
+			}
+		}
+	}
+
+	return count
+}
+
+// setLastRolloutCondition sets the last rollout condition to summarise a completed rollout, eg
+// `Rollout started at 2022-10-14T09:00:00Z and completed at 2022-10-14T10:00:00Z, replacing 3 machines with 3
+// disruption windows totalling 12m0s`.
+// The condition replaces the summary of any earlier rollout, and its last transition time is the time at which the
+// rollout completed.
+func setLastRolloutCondition(cpms *machinev1.ControlPlaneMachineSet, started, completed time.Time, replaced int, disruption rolloutDisruption) {
+	meta.RemoveStatusCondition(&cpms.Status.Conditions, conditionLastRollout)
+
+	meta.SetStatusCondition(&cpms.Status.Conditions, metav1.Condition{
+		Type:               conditionLastRollout,
+		Status:             metav1.ConditionTrue,
+		Reason:             reasonRolloutCompleted,
+		ObservedGeneration: cpms.GetGeneration(),
+		LastTransitionTime: metav1.NewTime(completed),
+		Message: fmt.Sprintf("Rollout started at %s and completed at %s, replacing %d machines with %d disruption windows totalling %s",
+			started.UTC().Format(time.RFC3339),
+			completed.UTC().Format(time.RFC3339),
+			replaced,
+			disruption.windows,
+			disruption.total.Round(time.Second),
+		),
+	})
+}
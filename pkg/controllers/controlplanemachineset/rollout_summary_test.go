@@ -0,0 +1,188 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controlplanemachineset
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	machinev1 "github.com/openshift/api/machine/v1"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/test"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/test/resourcebuilder"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("Rollout summary", func() {
+	started := time.Date(2022, time.October, 14, 9, 0, 0, 0, time.UTC)
+	completed := started.Add(time.Hour)
+
+	outdatedMachine := resourcebuilder.MachineInfo().WithReady(true).WithNeedsUpdate(true).
+		WithMachineCreationTimestamp(metav1.NewTime(started.Add(-24 * time.Hour)))
+	deletedMachine := outdatedMachine.WithReady(false).WithNodeName("node-0").WithMachineDeletionTimestamp(metav1.NewTime(started))
+	replacementMachine := resourcebuilder.MachineInfo().WithReady(true).WithNodeName("node-replacement").
+		WithMachineCreationTimestamp(metav1.NewTime(started.Add(time.Minute)))
+	existingMachine := replacementMachine.WithMachineCreationTimestamp(metav1.NewTime(started.Add(-24 * time.Hour)))
+
+	var reconciler *ControlPlaneMachineSetReconciler
+	var cpms *machinev1.ControlPlaneMachineSet
+
+	// withRolloutInProgress sets the rollout phase condition as it was set by the reconcile in which the rollout
+	// started.
+	withRolloutInProgress := func() {
+		cpms.Status.Conditions = []metav1.Condition{{
+			Type:               conditionRolloutPhase,
+			Status:             metav1.ConditionTrue,
+			Reason:             string(phasePlanPending),
+			LastTransitionTime: metav1.NewTime(started),
+		}}
+	}
+
+	idleMachineInfos := map[int32][]machineproviders.MachineInfo{
+		0: {replacementMachine.WithIndex(0).WithMachineName("machine-replacement-0").Build()},
+		1: {replacementMachine.WithIndex(1).WithMachineName("machine-replacement-1").Build()},
+		2: {existingMachine.WithIndex(2).WithMachineName("machine-2").Build()},
+	}
+
+	drainingMachineInfos := map[int32][]machineproviders.MachineInfo{
+		0: {
+			deletedMachine.WithIndex(0).WithMachineName("machine-0").Build(),
+			replacementMachine.WithIndex(0).WithMachineName("machine-replacement-0").Build(),
+		},
+		1: {outdatedMachine.WithIndex(1).WithMachineName("machine-1").Build()},
+		2: {existingMachine.WithIndex(2).WithMachineName("machine-2").Build()},
+	}
+
+	verifyingMachineInfos := map[int32][]machineproviders.MachineInfo{
+		0: {
+			outdatedMachine.WithIndex(0).WithMachineName("machine-0").Build(),
+			replacementMachine.WithIndex(0).WithMachineName("machine-replacement-0").Build(),
+		},
+		1: {outdatedMachine.WithIndex(1).WithMachineName("machine-1").Build()},
+		2: {existingMachine.WithIndex(2).WithMachineName("machine-2").Build()},
+	}
+
+	BeforeEach(func() {
+		reconciler = &ControlPlaneMachineSetReconciler{}
+		cpms = resourcebuilder.ControlPlaneMachineSet().Build()
+	})
+
+	Context("when the rollout completes", func() {
+		BeforeEach(func() {
+			withRolloutInProgress()
+
+			reconciler.observeRollout(cpms, drainingMachineInfos, started.Add(10*time.Minute))
+			reconciler.observeRollout(cpms, verifyingMachineInfos, started.Add(15*time.Minute))
+			reconciler.observeRollout(cpms, drainingMachineInfos, started.Add(30*time.Minute))
+			reconciler.observeRollout(cpms, idleMachineInfos, completed)
+		})
+
+		It("sets the last rollout condition", func() {
+			Expect(cpms.Status.Conditions).To(ContainElement(test.MatchCondition(metav1.Condition{
+				Type:    conditionLastRollout,
+				Status:  metav1.ConditionTrue,
+				Reason:  reasonRolloutCompleted,
+				Message: "Rollout started at 2022-10-14T09:00:00Z and completed at 2022-10-14T10:00:00Z, replacing 2 machines with 2 disruption windows totalling 35m0s",
+			})))
+		})
+
+		It("sets the last transition time to the completion time", func() {
+			condition := meta.FindStatusCondition(cpms.Status.Conditions, conditionLastRollout)
+			Expect(condition).ToNot(BeNil())
+			Expect(condition.LastTransitionTime.Time).To(BeTemporally("==", completed))
+		})
+
+		It("resets the disruption windows for the next rollout", func() {
+			Expect(reconciler.rolloutDisruption).To(Equal(rolloutDisruption{}))
+		})
+	})
+
+	Context("when a later rollout completes", func() {
+		BeforeEach(func() {
+			setLastRolloutCondition(cpms, started.Add(-48*time.Hour), started.Add(-47*time.Hour), 3, rolloutDisruption{windows: 3, total: time.Minute})
+			meta.SetStatusCondition(&cpms.Status.Conditions, metav1.Condition{
+				Type:               conditionRolloutPhase,
+				Status:             metav1.ConditionTrue,
+				Reason:             string(phasePlanPending),
+				LastTransitionTime: metav1.NewTime(started),
+			})
+
+			reconciler.observeRollout(cpms, idleMachineInfos, completed)
+		})
+
+		It("replaces the summary of the earlier rollout", func() {
+			condition := meta.FindStatusCondition(cpms.Status.Conditions, conditionLastRollout)
+			Expect(condition).ToNot(BeNil())
+			Expect(condition.Message).To(Equal("Rollout started at 2022-10-14T09:00:00Z and completed at 2022-10-14T10:00:00Z, replacing 2 machines with 0 disruption windows totalling 0s"))
+			Expect(condition.LastTransitionTime.Time).To(BeTemporally("==", completed))
+		})
+	})
+
+	Context("when the rollout is still in progress", func() {
+		BeforeEach(func() {
+			withRolloutInProgress()
+
+			reconciler.observeRollout(cpms, drainingMachineInfos, started.Add(10*time.Minute))
+		})
+
+		It("does not set the last rollout condition", func() {
+			Expect(meta.FindStatusCondition(cpms.Status.Conditions, conditionLastRollout)).To(BeNil())
+		})
+
+		It("opens a disruption window", func() {
+			Expect(reconciler.rolloutDisruption).To(Equal(rolloutDisruption{
+				windows:   1,
+				openSince: started.Add(10 * time.Minute),
+			}))
+		})
+	})
+
+	Context("when no rollout was in progress", func() {
+		BeforeEach(func() {
+			meta.SetStatusCondition(&cpms.Status.Conditions, metav1.Condition{
+				Type:   conditionRolloutPhase,
+				Status: metav1.ConditionFalse,
+				Reason: string(phaseIdle),
+			})
+
+			reconciler.observeRollout(cpms, idleMachineInfos, completed)
+		})
+
+		It("does not set the last rollout condition", func() {
+			Expect(meta.FindStatusCondition(cpms.Status.Conditions, conditionLastRollout)).To(BeNil())
+		})
+	})
+
+	Context("when the rollout completes without replacing any machine", func() {
+		BeforeEach(func() {
+			withRolloutInProgress()
+
+			reconciler.observeRollout(cpms, map[int32][]machineproviders.MachineInfo{
+				0: {existingMachine.WithIndex(0).WithMachineName("machine-0").Build()},
+				1: {existingMachine.WithIndex(1).WithMachineName("machine-1").Build()},
+				2: {existingMachine.WithIndex(2).WithMachineName("machine-2").Build()},
+			}, completed)
+		})
+
+		It("does not set the last rollout condition", func() {
+			Expect(meta.FindStatusCondition(cpms.Status.Conditions, conditionLastRollout)).To(BeNil())
+		})
+	})
+})
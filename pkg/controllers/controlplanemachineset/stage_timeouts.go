@@ -0,0 +1,136 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controlplanemachineset
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	machinev1 "github.com/openshift/api/machine/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// stageTimeoutsAnnotation is the annotation on the ControlPlaneMachineSet used to override the timeouts of the
+	// stages of a replacement, for this ControlPlaneMachineSet alone. The value is a comma separated list of
+	// `<stage>=<duration>` pairs, eg `deletion=2h,drain=45m`. Stages that are not listed keep the timeout configured
+	// by the operator flags. A duration of zero disables the timeout of that stage.
+	stageTimeoutsAnnotation = "controlplanemachineset.machine.openshift.io/timeouts"
+
+	// stageDeletion is the stage of a replacement in which a deleted Machine waits for its finalizers to be removed.
+	// Its timeout is the stuck deletion timeout.
+	stageDeletion = "deletion"
+
+	// stageDrain is the stage of a replacement in which the Node of a deleted Machine is drained.
+	// Its timeout is the drain timeout.
+	stageDrain = "drain"
+
+	// invalidStageTimeoutsMessage is used to inform the user that they have provided an invalid value for the stage
+	// timeouts annotation.
+	invalidStageTimeoutsMessage = "invalid value for stage timeouts"
+)
+
+// errInvalidStageTimeouts is used to denote that the stage timeouts annotation is not in the expected format.
+var errInvalidStageTimeouts = fmt.Errorf("invalid value for annotation %s: expected <stage>=<duration>[,<stage>=<duration>]", stageTimeoutsAnnotation)
+
+// stageTimeouts holds the timeouts of the stages of a replacement that are overridden by the stage timeouts
+// annotation. A nil timeout is not overridden.
+type stageTimeouts struct {
+	// deletion is the override for the stuck deletion timeout.
+	deletion *time.Duration
+
+	// drain is the override for the drain timeout.
+	drain *time.Duration
+}
+
+// parseStageTimeouts parses the value of the stage timeouts annotation.
+// When the annotation is not present, no timeouts are overridden.
+func parseStageTimeouts(annotations map[string]string) (stageTimeouts, error) {
+	timeouts := stageTimeouts{}
+
+	value, ok := annotations[stageTimeoutsAnnotation]
+	if !ok {
+		return timeouts, nil
+	}
+
+	for _, pair := range strings.Split(value, ",") {
+		stage, rawDuration, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok {
+			return stageTimeouts{}, fmt.Errorf("%w, got %q", errInvalidStageTimeouts, value)
+		}
+
+		duration, err := time.ParseDuration(rawDuration)
+		if err != nil || duration < 0 {
+			return stageTimeouts{}, fmt.Errorf("%w, got %q: timeout of stage %s must be a non-negative duration", errInvalidStageTimeouts, value, stage)
+		}
+
+		var target **time.Duration
+
+		switch stage {
+		case stageDeletion:
+			target = &timeouts.deletion
+		case stageDrain:
+			target = &timeouts.drain
+		default:
+			return stageTimeouts{}, fmt.Errorf("%w, got %q: unknown stage %s, expected %s or %s", errInvalidStageTimeouts, value, stage, stageDeletion, stageDrain)
+		}
+
+		if *target != nil {
+			return stageTimeouts{}, fmt.Errorf("%w, got %q: stage %s is listed more than once", errInvalidStageTimeouts, value, stage)
+		}
+
+		*target = &duration
+	}
+
+	return timeouts, nil
+}
+
+// stuckDeletionTimeoutFor returns the stuck deletion timeout for the ControlPlaneMachineSet. This is the timeout of
+// the deletion stage when it is overridden by the stage timeouts annotation, and the StuckDeletionTimeout otherwise.
+// An invalid annotation is reported by the Reconcile, and so is not overridden here.
+func (r *ControlPlaneMachineSetReconciler) stuckDeletionTimeoutFor(cpms *machinev1.ControlPlaneMachineSet) time.Duration {
+	if timeouts, err := parseStageTimeouts(cpms.GetAnnotations()); err == nil && timeouts.deletion != nil {
+		return *timeouts.deletion
+	}
+
+	return r.StuckDeletionTimeout
+}
+
+// drainTimeoutFor returns the drain timeout for the ControlPlaneMachineSet. This is the timeout of the drain stage
+// when it is overridden by the stage timeouts annotation, and the DrainTimeout otherwise.
+// An invalid annotation is reported by the Reconcile, and so is not overridden here.
+func (r *ControlPlaneMachineSetReconciler) drainTimeoutFor(cpms *machinev1.ControlPlaneMachineSet) time.Duration {
+	if timeouts, err := parseStageTimeouts(cpms.GetAnnotations()); err == nil && timeouts.drain != nil {
+		return *timeouts.drain
+	}
+
+	return r.DrainTimeout
+}
+
+// setInvalidStageTimeoutsCondition sets the degraded condition to report that the stage timeouts annotation is
+// invalid.
+func setInvalidStageTimeoutsCondition(cpms *machinev1.ControlPlaneMachineSet, err error) {
+	meta.SetStatusCondition(&cpms.Status.Conditions, metav1.Condition{
+		Type:               conditionDegraded,
+		Status:             metav1.ConditionTrue,
+		Reason:             reasonInvalidStageTimeouts,
+		ObservedGeneration: cpms.GetGeneration(),
+		Message:            fmt.Sprintf("%s: %s", invalidStageTimeoutsMessage, err),
+	})
+}
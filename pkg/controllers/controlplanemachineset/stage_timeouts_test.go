@@ -0,0 +1,125 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controlplanemachineset
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/test/resourcebuilder"
+)
+
+var _ = Describe("Stage timeouts", func() {
+	type parseStageTimeoutsTableInput struct {
+		annotations      map[string]string
+		expectedTimeouts stageTimeouts
+		expectedError    error
+	}
+
+	DescribeTable("parseStageTimeouts", func(in parseStageTimeoutsTableInput) {
+		timeouts, err := parseStageTimeouts(in.annotations)
+
+		if in.expectedError != nil {
+			Expect(err).To(MatchError(in.expectedError))
+		} else {
+			Expect(err).ToNot(HaveOccurred())
+		}
+
+		Expect(timeouts).To(Equal(in.expectedTimeouts))
+	},
+		Entry("with no annotations", parseStageTimeoutsTableInput{
+			annotations:      nil,
+			expectedTimeouts: stageTimeouts{},
+		}),
+		Entry("with a single stage", parseStageTimeoutsTableInput{
+			annotations:      map[string]string{stageTimeoutsAnnotation: "drain=45m"},
+			expectedTimeouts: stageTimeouts{drain: durationPtr(45 * time.Minute)},
+		}),
+		Entry("with every stage", parseStageTimeoutsTableInput{
+			annotations:      map[string]string{stageTimeoutsAnnotation: "deletion=2h, drain=45m"},
+			expectedTimeouts: stageTimeouts{deletion: durationPtr(2 * time.Hour), drain: durationPtr(45 * time.Minute)},
+		}),
+		Entry("with a disabled stage", parseStageTimeoutsTableInput{
+			annotations:      map[string]string{stageTimeoutsAnnotation: "deletion=0s"},
+			expectedTimeouts: stageTimeouts{deletion: durationPtr(0)},
+		}),
+		Entry("with no duration", parseStageTimeoutsTableInput{
+			annotations:   map[string]string{stageTimeoutsAnnotation: "drain"},
+			expectedError: errInvalidStageTimeouts,
+		}),
+		Entry("with an invalid duration", parseStageTimeoutsTableInput{
+			annotations:   map[string]string{stageTimeoutsAnnotation: "drain=an hour"},
+			expectedError: errInvalidStageTimeouts,
+		}),
+		Entry("with a negative duration", parseStageTimeoutsTableInput{
+			annotations:   map[string]string{stageTimeoutsAnnotation: "drain=-1h"},
+			expectedError: errInvalidStageTimeouts,
+		}),
+		Entry("with an unknown stage", parseStageTimeoutsTableInput{
+			annotations:   map[string]string{stageTimeoutsAnnotation: "node=20m"},
+			expectedError: errInvalidStageTimeouts,
+		}),
+		Entry("with a stage listed more than once", parseStageTimeoutsTableInput{
+			annotations:   map[string]string{stageTimeoutsAnnotation: "drain=45m,drain=1h"},
+			expectedError: errInvalidStageTimeouts,
+		}),
+	)
+
+	Context("with timeouts configured by the operator flags", func() {
+		reconciler := &ControlPlaneMachineSetReconciler{
+			StuckDeletionTimeout: time.Hour,
+			DrainTimeout:         30 * time.Minute,
+		}
+
+		cpmsBuilder := resourcebuilder.ControlPlaneMachineSet()
+
+		It("uses the flag timeouts when the annotation is not present", func() {
+			cpms := cpmsBuilder.Build()
+
+			Expect(reconciler.stuckDeletionTimeoutFor(cpms)).To(Equal(time.Hour))
+			Expect(reconciler.drainTimeoutFor(cpms)).To(Equal(30 * time.Minute))
+		})
+
+		It("uses the overridden timeouts from the annotation", func() {
+			cpms := cpmsBuilder.WithAnnotations(map[string]string{stageTimeoutsAnnotation: "deletion=3h,drain=0s"}).Build()
+
+			Expect(reconciler.stuckDeletionTimeoutFor(cpms)).To(Equal(3 * time.Hour))
+			Expect(reconciler.drainTimeoutFor(cpms)).To(BeZero())
+		})
+
+		It("keeps the flag timeouts of stages that are not listed", func() {
+			cpms := cpmsBuilder.WithAnnotations(map[string]string{stageTimeoutsAnnotation: "drain=2h"}).Build()
+
+			Expect(reconciler.stuckDeletionTimeoutFor(cpms)).To(Equal(time.Hour))
+			Expect(reconciler.drainTimeoutFor(cpms)).To(Equal(2 * time.Hour))
+		})
+
+		It("uses the flag timeouts when the annotation is invalid", func() {
+			cpms := cpmsBuilder.WithAnnotations(map[string]string{stageTimeoutsAnnotation: "drain=2h,node=1h"}).Build()
+
+			Expect(reconciler.stuckDeletionTimeoutFor(cpms)).To(Equal(time.Hour))
+			Expect(reconciler.drainTimeoutFor(cpms)).To(Equal(30 * time.Minute))
+		})
+	})
+})
+
+// durationPtr returns a pointer to the duration given.
+func durationPtr(d time.Duration) *time.Duration {
+	return &d
+}
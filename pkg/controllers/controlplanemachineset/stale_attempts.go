@@ -0,0 +1,75 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controlplanemachineset
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-logr/logr"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders"
+)
+
+// removingStaleReplacementAttempt is a log message used to inform the user that a Machine, created by an earlier
+// replacement attempt from an outdated template, has been deleted as it never joined the cluster.
+const removingStaleReplacementAttempt = "Removing stale replacement attempt"
+
+// isStaleReplacementAttempt determines whether the Machine was created by an earlier replacement attempt, from a
+// version of the template that is no longer current, and has never joined the cluster.
+// Such a Machine will never become the up to date Machine for its index, so it is removed rather than replaced.
+// A replacement attempt whose template hash matches the current template is not stale, and is reused as the
+// replacement for its index.
+// Machines without a recorded template hash are never considered stale, as their origin cannot be determined.
+func isStaleReplacementAttempt(machineInfo machineproviders.MachineInfo) bool {
+	return machineInfo.MachineRef != nil &&
+		machineInfo.MachineRef.ObjectMeta.GetDeletionTimestamp() == nil &&
+		machineInfo.NodeRef == nil &&
+		!machineInfo.Ready &&
+		machineInfo.NeedsUpdate &&
+		machineInfo.TemplateHash != "" &&
+		machineInfo.TemplateHash != machineInfo.DesiredTemplateHash
+}
+
+// reconcileStaleReplacementAttempts deletes, in index order, any stale replacement attempts, so that no further
+// Machines are created for an index while a stale replacement attempt remains within it.
+// As stale replacement attempts never joined the cluster, they are deleted regardless of the update strategy.
+// It returns true when any stale replacement attempt was deleted.
+func (r *ControlPlaneMachineSetReconciler) reconcileStaleReplacementAttempts(ctx context.Context, logger logr.Logger, machineProvider machineproviders.MachineProvider, indexedMachineInfos map[int32][]machineproviders.MachineInfo) (bool, error) {
+	var handled bool
+
+	for _, idx := range sortedIndexes(indexedMachineInfos) {
+		for _, machineInfo := range sortedByMachineName(indexedMachineInfos[idx]) {
+			if !isStaleReplacementAttempt(machineInfo) {
+				continue
+			}
+
+			handled = true
+			machineLogger := machineInfoLogger(logger, machineInfo)
+
+			if err := machineProvider.DeleteMachine(ctx, machineLogger, machineInfo.MachineRef); err != nil {
+				err := fmt.Errorf("error deleting stale replacement attempt %s: %w", machineInfo.MachineRef.ObjectMeta.GetName(), err)
+				machineLogger.Error(err, errorDeletingMachine)
+
+				return true, err
+			}
+
+			machineLogger.V(2).Info(removingStaleReplacementAttempt, "templateHash", machineInfo.TemplateHash, "desiredTemplateHash", machineInfo.DesiredTemplateHash)
+		}
+	}
+
+	return handled, nil
+}
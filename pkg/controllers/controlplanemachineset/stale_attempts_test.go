@@ -0,0 +1,119 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controlplanemachineset
+
+import (
+	"github.com/golang/mock/gomock"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	machinev1 "github.com/openshift/api/machine/v1"
+	machinev1beta1 "github.com/openshift/api/machine/v1beta1"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders/mock"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/test"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/test/resourcebuilder"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("Stale replacement attempts", func() {
+	machineGVR := machinev1beta1.GroupVersion.WithResource("machines")
+
+	healthyMachineBuilder := resourcebuilder.MachineInfo().
+		WithMachineGVR(machineGVR).
+		WithReady(true).
+		WithDesiredTemplateHash("current")
+
+	staleAttemptBuilder := resourcebuilder.MachineInfo().
+		WithMachineGVR(machineGVR).
+		WithReady(false).
+		WithNeedsUpdate(true).
+		WithTemplateHash("previous").
+		WithDesiredTemplateHash("current")
+
+	DescribeTable("isStaleReplacementAttempt", func(machineInfo machineproviders.MachineInfo, expected bool) {
+		Expect(isStaleReplacementAttempt(machineInfo)).To(Equal(expected))
+	},
+		Entry("with an outdated attempt that never joined", staleAttemptBuilder.WithMachineName("machine-3").Build(), true),
+		Entry("with an attempt from the current template", staleAttemptBuilder.WithMachineName("machine-3").WithTemplateHash("current").WithNeedsUpdate(false).Build(), false),
+		Entry("with an outdated attempt that has a Node", staleAttemptBuilder.WithMachineName("machine-3").WithNodeName("node-3").Build(), false),
+		Entry("with an outdated attempt that is being deleted", staleAttemptBuilder.WithMachineName("machine-3").WithMachineDeletionTimestamp(metav1.Now()).Build(), false),
+		Entry("with an outdated Machine without a template hash", staleAttemptBuilder.WithMachineName("machine-3").WithTemplateHash("").Build(), false),
+		Entry("with a ready, outdated Machine", staleAttemptBuilder.WithMachineName("machine-3").WithReady(true).Build(), false),
+	)
+
+	Context("when reconciling updates", func() {
+		var logger test.TestLogger
+		var reconciler *ControlPlaneMachineSetReconciler
+		var mockMachineProvider *mock.MockMachineProvider
+
+		var machineInfos map[int32][]machineproviders.MachineInfo
+
+		BeforeEach(func() {
+			logger = test.NewTestLogger()
+			reconciler = &ControlPlaneMachineSetReconciler{
+				Scheme: testScheme,
+			}
+
+			mockMachineProvider = mock.NewMockMachineProvider(gomock.NewController(GinkgoT()))
+
+			machineInfos = map[int32][]machineproviders.MachineInfo{
+				0: {healthyMachineBuilder.WithIndex(0).WithMachineName("machine-0").WithNodeName("node-0").Build()},
+				1: {
+					healthyMachineBuilder.WithIndex(1).WithMachineName("machine-1").WithNodeName("node-1").WithNeedsUpdate(true).Build(),
+					staleAttemptBuilder.WithIndex(1).WithMachineName("machine-3").Build(),
+				},
+				2: {healthyMachineBuilder.WithIndex(2).WithMachineName("machine-2").WithNodeName("node-2").Build()},
+			}
+		})
+
+		for _, strategy := range []machinev1.ControlPlaneMachineSetStrategyType{machinev1.RollingUpdate, machinev1.OnDelete} {
+			strategy := strategy
+
+			Context(string(strategy), func() {
+				It("should delete the stale attempt rather than create another Machine", func() {
+					cpms := resourcebuilder.ControlPlaneMachineSet().WithStrategyType(strategy).WithReplicas(3).Build()
+
+					mockMachineProvider.EXPECT().DeleteMachine(gomock.Any(), gomock.Any(), machineInfos[1][1].MachineRef).Return(nil).Times(1)
+					mockMachineProvider.EXPECT().CreateMachine(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+
+					_, err := reconciler.reconcileMachineUpdates(ctx, logger.Logger(), cpms, mockMachineProvider, machineInfos)
+					Expect(err).ToNot(HaveOccurred())
+
+					Expect(logger.Entries()).To(ContainElement(SatisfyAll(
+						HaveField("Level", Equal(2)),
+						HaveField("Message", Equal(removingStaleReplacementAttempt)),
+						HaveField("KeysAndValues", ContainElements("name", "machine-3", "templateHash", "previous", "desiredTemplateHash", "current")),
+					)))
+				})
+			})
+		}
+
+		It("should reuse an attempt from the current template", func() {
+			cpms := resourcebuilder.ControlPlaneMachineSet().WithStrategyType(machinev1.RollingUpdate).WithReplicas(3).Build()
+			machineInfos[1][1] = staleAttemptBuilder.WithIndex(1).WithMachineName("machine-3").WithTemplateHash("current").WithNeedsUpdate(false).Build()
+
+			mockMachineProvider.EXPECT().DeleteMachine(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+			mockMachineProvider.EXPECT().CreateMachine(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+
+			_, err := reconciler.reconcileMachineUpdates(ctx, logger.Logger(), cpms, mockMachineProvider, machineInfos)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(logger.Entries()).To(ContainElement(HaveField("Message", Equal(waitingForReplacement))))
+		})
+	})
+})
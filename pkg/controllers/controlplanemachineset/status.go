@@ -19,10 +19,14 @@ package controlplanemachineset
 import (
 	"context"
 	"fmt"
+	"strconv"
 
 	"github.com/go-logr/logr"
 	machinev1 "github.com/openshift/api/machine/v1"
 	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
@@ -32,23 +36,32 @@ const (
 
 	// notUpdatingStatus is a log message used to inform users that the ControlPlaneMachineSet status is not being updated.
 	notUpdatingStatus = "No update to control plane machine set status required"
+
+	// observedMachineConfiguration is a log message used to inform users about the replica counts observed
+	// from the Control Plane Machines.
+	observedMachineConfiguration = "Observed Machine Configuration"
 )
 
 // updateControlPlaneMachineSetStatus ensures that the status of the ControlPlaneMachineSet is up to date after
 // the resource has been reconciled.
-func (r *ControlPlaneMachineSetReconciler) updateControlPlaneMachineSetStatus(ctx context.Context, logger logr.Logger, cpms *machinev1.ControlPlaneMachineSet, patchBase client.Patch) error {
-	data, err := patchBase.Data(cpms)
-	if err != nil {
-		return fmt.Errorf("cannot calculate patch data from control plane machine set object: %w", err)
-	}
+// The status is only written when it semantically differs from the status of the original ControlPlaneMachineSet,
+// so that changes elsewhere on the object, such as to the finalizers or the resource version, do not trigger a write.
+// Conditions that were removed and set again while reconciling, without a change in status, keep their original
+// transition time so that they do not cause a write either.
+func (r *ControlPlaneMachineSetReconciler) updateControlPlaneMachineSetStatus(ctx context.Context, logger logr.Logger, cpms, original *machinev1.ControlPlaneMachineSet) error {
+	preserveConditionTransitionTimes(original.Status.Conditions, cpms.Status.Conditions)
 
-	// Apply changes only if the patch is not empty
-	if string(data) == "{}" {
+	if equality.Semantic.DeepEqual(original.Status, cpms.Status) {
 		logger.V(3).Info(notUpdatingStatus)
 
 		return nil
 	}
 
+	data, err := client.MergeFrom(original).Data(cpms)
+	if err != nil {
+		return fmt.Errorf("cannot calculate patch data from control plane machine set object: %w", err)
+	}
+
 	if err := r.Status().Update(ctx, cpms); err != nil {
 		return fmt.Errorf("failed to sync status for control plane machine set object: %w", err)
 	}
@@ -58,6 +71,16 @@ func (r *ControlPlaneMachineSetReconciler) updateControlPlaneMachineSetStatus(ct
 	return nil
 }
 
+// preserveConditionTransitionTimes copies the last transition time of each original condition onto the updated
+// condition of the same type, when the status of the condition has not changed.
+func preserveConditionTransitionTimes(original, updated []metav1.Condition) {
+	for i := range updated {
+		if previous := meta.FindStatusCondition(original, updated[i].Type); previous != nil && previous.Status == updated[i].Status {
+			updated[i].LastTransitionTime = previous.LastTransitionTime
+		}
+	}
+}
+
 // reconcileStatusWithMachineInfo takes the information gathered in the machineInfos and reconciles the status of the
 // ControlPlaneMachineSet to match the data gathered.
 // In particular, it will update the ObservedGeneration, Replicas, ReadyReplicas, UnreadyReplicas and UpdatedReplicas
@@ -70,5 +93,115 @@ func (r *ControlPlaneMachineSetReconciler) updateControlPlaneMachineSetStatus(ct
 //   index. Eg. if one index has no ready replicas, this is 1, if an index has 2 ready replicas, this does not count as
 //   2 available replicas.
 func reconcileStatusWithMachineInfo(logger logr.Logger, cpms *machinev1.ControlPlaneMachineSet, machineInfosByIndex map[int32][]machineproviders.MachineInfo) error {
+	var replicas, readyReplicas, updatedReplicas, unavailableReplicas, indexesNeedingUpdate int32
+
+	for _, machineInfos := range machineInfosByIndex {
+		var indexReady, indexUpdated bool
+
+		for _, machineInfo := range machineInfos {
+			replicas++
+
+			if !machineInfo.Ready {
+				continue
+			}
+
+			readyReplicas++
+			indexReady = true
+
+			if !machineInfo.NeedsUpdate {
+				indexUpdated = true
+			}
+		}
+
+		if !indexReady {
+			unavailableReplicas++
+		}
+
+		// Only one updated replica is counted per index, any other replicas are waiting to be removed.
+		if indexUpdated {
+			updatedReplicas++
+		} else {
+			indexesNeedingUpdate++
+		}
+	}
+
+	cpms.Status.ObservedGeneration = cpms.GetGeneration()
+	cpms.Status.Replicas = replicas
+	cpms.Status.ReadyReplicas = readyReplicas
+	cpms.Status.UpdatedReplicas = updatedReplicas
+	cpms.Status.UnavailableReplicas = unavailableReplicas
+
+	setAvailableCondition(cpms, unavailableReplicas)
+	setProgressingCondition(cpms, indexesNeedingUpdate, replicas-int32(len(machineInfosByIndex)))
+
+	// The degraded condition is reset on each reconcile. Later stages of the reconcile will set the
+	// degraded condition if they observe any issues with the cluster state.
+	meta.SetStatusCondition(&cpms.Status.Conditions, metav1.Condition{
+		Type:               conditionDegraded,
+		Status:             metav1.ConditionFalse,
+		Reason:             reasonAsExpected,
+		ObservedGeneration: cpms.GetGeneration(),
+	})
+
+	logger.V(4).Info(observedMachineConfiguration,
+		"observedGeneration", strconv.FormatInt(cpms.Status.ObservedGeneration, 10),
+		"replicas", strconv.Itoa(int(replicas)),
+		"readyReplicas", strconv.Itoa(int(readyReplicas)),
+		"updatedReplicas", strconv.Itoa(int(updatedReplicas)),
+		"unavailableReplicas", strconv.Itoa(int(unavailableReplicas)),
+	)
+
 	return nil
 }
+
+// setAvailableCondition sets the available condition based on the number of indexes without a ready replica.
+func setAvailableCondition(cpms *machinev1.ControlPlaneMachineSet, unavailableReplicas int32) {
+	if unavailableReplicas > 0 {
+		meta.SetStatusCondition(&cpms.Status.Conditions, metav1.Condition{
+			Type:               conditionAvailable,
+			Status:             metav1.ConditionFalse,
+			Reason:             reasonUnavailableReplicas,
+			ObservedGeneration: cpms.GetGeneration(),
+			Message:            fmt.Sprintf("Missing %d available replica(s)", unavailableReplicas),
+		})
+
+		return
+	}
+
+	meta.SetStatusCondition(&cpms.Status.Conditions, metav1.Condition{
+		Type:               conditionAvailable,
+		Status:             metav1.ConditionTrue,
+		Reason:             reasonAllReplicasAvailable,
+		ObservedGeneration: cpms.GetGeneration(),
+	})
+}
+
+// setProgressingCondition sets the progressing condition based on the number of indexes without an updated
+// replica, and the number of replicas in excess of one replica per index.
+func setProgressingCondition(cpms *machinev1.ControlPlaneMachineSet, indexesNeedingUpdate, excessReplicas int32) {
+	switch {
+	case indexesNeedingUpdate > 0:
+		meta.SetStatusCondition(&cpms.Status.Conditions, metav1.Condition{
+			Type:               conditionProgressing,
+			Status:             metav1.ConditionTrue,
+			Reason:             reasonNeedsUpdateReplicas,
+			ObservedGeneration: cpms.GetGeneration(),
+			Message:            fmt.Sprintf("Observed %d replica(s) in need of update", indexesNeedingUpdate),
+		})
+	case excessReplicas > 0:
+		meta.SetStatusCondition(&cpms.Status.Conditions, metav1.Condition{
+			Type:               conditionProgressing,
+			Status:             metav1.ConditionTrue,
+			Reason:             reasonExcessReplicas,
+			ObservedGeneration: cpms.GetGeneration(),
+			Message:            fmt.Sprintf("Waiting for %d old replica(s) to be removed", excessReplicas),
+		})
+	default:
+		meta.SetStatusCondition(&cpms.Status.Conditions, metav1.Condition{
+			Type:               conditionProgressing,
+			Status:             metav1.ConditionFalse,
+			Reason:             reasonAllReplicasUpdated,
+			ObservedGeneration: cpms.GetGeneration(),
+		})
+	}
+}
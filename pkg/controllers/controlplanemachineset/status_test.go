@@ -17,6 +17,8 @@ limitations under the License.
 package controlplanemachineset
 
 import (
+	"time"
+
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 
@@ -38,7 +40,7 @@ var _ = Describe("Status", func() {
 		var logger test.TestLogger
 		var reconciler *ControlPlaneMachineSetReconciler
 		var cpms *machinev1.ControlPlaneMachineSet
-		var patchBase client.Patch
+		var original *machinev1.ControlPlaneMachineSet
 
 		BeforeEach(func() {
 			By("Setting up a namespace for the test")
@@ -66,7 +68,7 @@ var _ = Describe("Status", func() {
 			cpms.Status.ReadyReplicas = 3
 			Expect(k8sClient.Status().Update(ctx, cpms)).To(Succeed())
 
-			patchBase = client.MergeFrom(cpms.DeepCopy())
+			original = cpms.DeepCopy()
 			logger = test.NewTestLogger()
 		})
 
@@ -85,7 +87,7 @@ var _ = Describe("Status", func() {
 				cpms.Status.ReadyReplicas = 4
 
 				// Use a DeepCopy of the CPMS to avoid any reflection from the update affecting the test cases.
-				Expect(reconciler.updateControlPlaneMachineSetStatus(ctx, logger.Logger(), cpms.DeepCopy(), patchBase)).To(Succeed())
+				Expect(reconciler.updateControlPlaneMachineSetStatus(ctx, logger.Logger(), cpms.DeepCopy(), original)).To(Succeed())
 			})
 
 			It("updates the status on the API", func() {
@@ -97,7 +99,7 @@ var _ = Describe("Status", func() {
 			})
 
 			It("should log the patch data", func() {
-				data, err := patchBase.Data(cpms)
+				data, err := client.MergeFrom(original).Data(cpms)
 				Expect(err).ToNot(HaveOccurred())
 
 				Expect(logger.Entries()).To(ConsistOf(test.LogEntry{
@@ -112,18 +114,18 @@ var _ = Describe("Status", func() {
 
 		Context("when the status has not changed", func() {
 			BeforeEach(func() {
-				// Use different values to what is set on the API, but a different patch base to prove
-				// that when the status is not considered updated, we don't send a patch.
+				// Use different values to what is set on the API, but a different original to prove
+				// that when the status is not considered updated, we don't send an update.
 				cpms.Status.ObservedGeneration = 2
 				cpms.Status.Replicas = 3
 				cpms.Status.ReadyReplicas = 4
 
-				// Override the patchbase so that the input CPMS and patchbase create a no-change update.
-				// We should be detecting that the update isn't required and not patching when we don't need to.
-				patchBase = client.MergeFrom(cpms.DeepCopy())
+				// Override the original so that the input CPMS and original create a no-change update.
+				// We should be detecting that the update isn't required and not updating when we don't need to.
+				original = cpms.DeepCopy()
 
 				// Use a DeepCopy of the CPMS to avoid any reflection from the update affecting the test cases.
-				Expect(reconciler.updateControlPlaneMachineSetStatus(ctx, logger.Logger(), cpms.DeepCopy(), patchBase)).To(Succeed())
+				Expect(reconciler.updateControlPlaneMachineSetStatus(ctx, logger.Logger(), cpms.DeepCopy(), original)).To(Succeed())
 			})
 
 			It("does not update the status on the API", func() {
@@ -141,6 +143,71 @@ var _ = Describe("Status", func() {
 				}))
 			})
 		})
+
+		Context("when only the metadata has changed", func() {
+			BeforeEach(func() {
+				updated := cpms.DeepCopy()
+				updated.SetAnnotations(map[string]string{"example.com/annotation": "value"})
+				updated.SetResourceVersion("")
+
+				// Use a DeepCopy of the CPMS to avoid any reflection from the update affecting the test cases.
+				Expect(reconciler.updateControlPlaneMachineSetStatus(ctx, logger.Logger(), updated, original)).To(Succeed())
+			})
+
+			It("should log that no status update was required", func() {
+				Expect(logger.Entries()).To(ConsistOf(test.LogEntry{
+					Level:   3,
+					Message: notUpdatingStatus,
+				}))
+			})
+		})
+
+		Context("when a condition has been set again without changing its status", func() {
+			BeforeEach(func() {
+				original.Status.Conditions = []metav1.Condition{{
+					Type:               conditionGatedBy,
+					Status:             metav1.ConditionTrue,
+					Reason:             reasonGatedByUserDeletion,
+					LastTransitionTime: metav1.NewTime(metav1.Now().Add(-time.Hour)),
+				}}
+
+				updated := original.DeepCopy()
+				updated.Status.Conditions[0].LastTransitionTime = metav1.Now()
+
+				// Use a DeepCopy of the CPMS to avoid any reflection from the update affecting the test cases.
+				Expect(reconciler.updateControlPlaneMachineSetStatus(ctx, logger.Logger(), updated, original)).To(Succeed())
+			})
+
+			It("should log that no status update was required", func() {
+				Expect(logger.Entries()).To(ConsistOf(test.LogEntry{
+					Level:   3,
+					Message: notUpdatingStatus,
+				}))
+			})
+		})
+	})
+
+	Context("preserveConditionTransitionTimes", func() {
+		earlier := metav1.NewTime(metav1.Now().Add(-time.Hour).Truncate(time.Second))
+		later := metav1.NewTime(metav1.Now().Truncate(time.Second))
+
+		It("should keep the original transition time when the status is unchanged", func() {
+			original := []metav1.Condition{{Type: conditionGatedBy, Status: metav1.ConditionTrue, LastTransitionTime: earlier}}
+			updated := []metav1.Condition{{Type: conditionGatedBy, Status: metav1.ConditionTrue, LastTransitionTime: later}}
+
+			preserveConditionTransitionTimes(original, updated)
+
+			Expect(updated[0].LastTransitionTime).To(Equal(earlier))
+		})
+
+		It("should keep the new transition time when the status has changed", func() {
+			original := []metav1.Condition{{Type: conditionRolloutPhase, Status: metav1.ConditionFalse, LastTransitionTime: earlier}}
+			updated := []metav1.Condition{{Type: conditionRolloutPhase, Status: metav1.ConditionTrue, LastTransitionTime: later}}
+
+			preserveConditionTransitionTimes(original, updated)
+
+			Expect(updated[0].LastTransitionTime).To(Equal(later))
+		})
 	})
 
 	Context("reconcileStatusWithMachineInfo", func() {
@@ -190,8 +257,9 @@ var _ = Describe("Status", func() {
 			Expect(cpms.Status.ReadyReplicas).To(Equal(in.expectedStatus.ReadyReplicas))
 			Expect(cpms.Status.UpdatedReplicas).To(Equal(in.expectedStatus.UpdatedReplicas))
 			Expect(cpms.Status.UnavailableReplicas).To(Equal(in.expectedStatus.UnavailableReplicas))
+			Expect(logger.Entries()).To(ConsistOf(in.expectedLogs))
 		},
-			PEntry("with up to date Machines", &reconcileStatusTableInput{
+			Entry("with up to date Machines", &reconcileStatusTableInput{
 				cpms: resourcebuilder.ControlPlaneMachineSet().WithGeneration(1).Build(),
 				machineInfos: map[int32][]machineproviders.MachineInfo{
 					0: {healthyMachineBuilder.WithIndex(0).WithMachineName("machine-0").WithNodeName("node-0").Build()},
@@ -240,7 +308,7 @@ var _ = Describe("Status", func() {
 					},
 				},
 			}),
-			PEntry("when Machines need updates", &reconcileStatusTableInput{
+			Entry("when Machines need updates", &reconcileStatusTableInput{
 				cpms: resourcebuilder.ControlPlaneMachineSet().WithGeneration(2).Build(),
 				machineInfos: map[int32][]machineproviders.MachineInfo{
 					0: {healthyMachineBuilder.WithIndex(0).WithMachineName("machine-0").WithNodeName("node-0").Build()},
@@ -290,7 +358,7 @@ var _ = Describe("Status", func() {
 					},
 				},
 			}),
-			PEntry("with pending replacement replicas", &reconcileStatusTableInput{
+			Entry("with pending replacement replicas", &reconcileStatusTableInput{
 				cpms: resourcebuilder.ControlPlaneMachineSet().WithGeneration(3).Build(),
 				machineInfos: map[int32][]machineproviders.MachineInfo{
 					0: {healthyMachineBuilder.WithIndex(0).WithMachineName("machine-0").WithNodeName("node-0").Build()},
@@ -346,7 +414,7 @@ var _ = Describe("Status", func() {
 					},
 				},
 			}),
-			PEntry("with ready replacement replicas", &reconcileStatusTableInput{
+			Entry("with ready replacement replicas", &reconcileStatusTableInput{
 				cpms: resourcebuilder.ControlPlaneMachineSet().WithGeneration(4).Build(),
 				machineInfos: map[int32][]machineproviders.MachineInfo{
 					0: {healthyMachineBuilder.WithIndex(0).WithMachineName("machine-0").WithNodeName("node-0").Build()},
@@ -402,7 +470,7 @@ var _ = Describe("Status", func() {
 					},
 				},
 			}),
-			PEntry("with no MachineInfos", &reconcileStatusTableInput{
+			Entry("with no MachineInfos", &reconcileStatusTableInput{
 				cpms: resourcebuilder.ControlPlaneMachineSet().WithGeneration(5).Build(),
 				machineInfos: map[int32][]machineproviders.MachineInfo{
 					0: {},
@@ -453,7 +521,7 @@ var _ = Describe("Status", func() {
 					},
 				},
 			}),
-			PEntry("with an unhealthy Machine", &reconcileStatusTableInput{
+			Entry("with an unhealthy Machine", &reconcileStatusTableInput{
 				cpms: resourcebuilder.ControlPlaneMachineSet().WithGeneration(7).Build(),
 				machineInfos: map[int32][]machineproviders.MachineInfo{
 					0: {healthyMachineBuilder.WithIndex(0).WithMachineName("machine-0").WithNodeName("node-0").Build()},
@@ -504,7 +572,7 @@ var _ = Describe("Status", func() {
 					},
 				},
 			}),
-			PEntry("with an unhealthy index (failure domain)", &reconcileStatusTableInput{
+			Entry("with an unhealthy index (failure domain)", &reconcileStatusTableInput{
 				cpms: resourcebuilder.ControlPlaneMachineSet().WithGeneration(8).Build(),
 				machineInfos: map[int32][]machineproviders.MachineInfo{
 					0: {healthyMachineBuilder.WithIndex(0).WithMachineName("machine-0").WithNodeName("node-0").Build()},
@@ -541,11 +609,11 @@ var _ = Describe("Status", func() {
 							Message:            "Observed 1 replica(s) in need of update",
 						},
 					},
-					ObservedGeneration:  4,
+					ObservedGeneration:  8,
 					Replicas:            5,
-					ReadyReplicas:       5,
-					UpdatedReplicas:     3,
-					UnavailableReplicas: 0,
+					ReadyReplicas:       3,
+					UpdatedReplicas:     2,
+					UnavailableReplicas: 1,
 				},
 				expectedLogs: []test.LogEntry{
 					{
@@ -561,7 +629,7 @@ var _ = Describe("Status", func() {
 					},
 				},
 			}),
-			PEntry("with an empty index", &reconcileStatusTableInput{
+			Entry("with an empty index", &reconcileStatusTableInput{
 				cpms: resourcebuilder.ControlPlaneMachineSet().WithGeneration(9).Build(),
 				machineInfos: map[int32][]machineproviders.MachineInfo{
 					0: {healthyMachineBuilder.WithIndex(0).WithMachineName("machine-0").WithNodeName("node-0").Build()},
@@ -575,20 +643,20 @@ var _ = Describe("Status", func() {
 							Type:               conditionAvailable,
 							Status:             metav1.ConditionFalse,
 							Reason:             reasonUnavailableReplicas,
-							ObservedGeneration: 8,
+							ObservedGeneration: 9,
 							Message:            "Missing 1 available replica(s)",
 						},
 						{
 							Type:               conditionDegraded,
 							Status:             metav1.ConditionFalse,
 							Reason:             reasonAsExpected,
-							ObservedGeneration: 8,
+							ObservedGeneration: 9,
 						},
 						{
 							Type:               conditionProgressing,
 							Status:             metav1.ConditionTrue,
 							Reason:             reasonNeedsUpdateReplicas,
-							ObservedGeneration: 8,
+							ObservedGeneration: 9,
 							Message:            "Observed 1 replica(s) in need of update",
 						},
 					},
@@ -602,7 +670,7 @@ var _ = Describe("Status", func() {
 					{
 						Level: 4,
 						KeysAndValues: []interface{}{
-							"observedGeneration", "7",
+							"observedGeneration", "9",
 							"replicas", "2",
 							"readyReplicas", "2",
 							"updatedReplicas", "2",
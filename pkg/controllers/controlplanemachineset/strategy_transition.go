@@ -0,0 +1,100 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controlplanemachineset
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/go-logr/logr"
+	machinev1 "github.com/openshift/api/machine/v1"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func init() {
+	registerInformationalCondition(conditionStrategyTransition)
+}
+
+const (
+	// completingInFlightReplacement is a log message used to inform the user that an outdated Machine, which has not
+	// been deleted, has an updated replacement alongside it while the update strategy is OnDelete. The replacement
+	// was created by the RollingUpdate strategy, before the strategy was changed, and so the replacement is completed.
+	completingInFlightReplacement = "Completing in-flight replacement started before the update strategy changed"
+)
+
+// isInFlightReplacement determines whether the outdated Machine, which has not been deleted, has an updated
+// replacement alongside it, while the update strategy is OnDelete and replacements are not pre-created.
+// With the OnDelete strategy, replacements are otherwise only created once the outdated Machine is deleted, so the
+// replacement must have been created by the RollingUpdate strategy before the strategy was changed.
+func isInFlightReplacement(cpms *machinev1.ControlPlaneMachineSet, outdatedMachine machineproviders.MachineInfo, updatedMachines []machineproviders.MachineInfo) bool {
+	return cpms.Spec.Strategy.Type == machinev1.OnDelete &&
+		cpms.GetAnnotations()[preCreateReplacementsAnnotation] != "true" &&
+		outdatedMachine.MachineRef.ObjectMeta.GetDeletionTimestamp() == nil &&
+		len(updatedMachines) > 0
+}
+
+// completeInFlightReplacement completes a replacement that was started by the RollingUpdate strategy before the update
+// strategy was changed to OnDelete. As with the RollingUpdate strategy, the outdated Machine is deleted once its
+// replacement is ready. The replacement is completed, rather than rolled back, so that the work already done by the
+// replacement is not lost, and so that the index is never left without a ready Machine.
+func completeInFlightReplacement(ctx context.Context, logger logr.Logger, machineProvider machineproviders.MachineProvider, outdatedMachine, replacementMachine machineproviders.MachineInfo) error {
+	logger = logger.WithValues("replacementName", replacementMachine.MachineRef.ObjectMeta.GetName())
+
+	if !replacementMachine.Ready {
+		logger.V(2).Info(waitingForReplacement)
+		return nil
+	}
+
+	logger.V(2).Info(completingInFlightReplacement)
+
+	if err := machineProvider.DeleteMachine(ctx, logger, outdatedMachine.MachineRef); err != nil {
+		err := fmt.Errorf("error deleting Machine %s/%s: %w", outdatedMachine.MachineRef.ObjectMeta.GetNamespace(), outdatedMachine.MachineRef.ObjectMeta.GetName(), err)
+		logger.Error(err, errorDeletingMachine)
+
+		return err
+	}
+
+	logger.V(2).Info(removingOldMachine)
+
+	return nil
+}
+
+// setStrategyTransitionCondition sets the StrategyTransition condition to report the indexes whose in-flight
+// replacements are being completed following a change of the update strategy. When there are no such indexes, the
+// condition is removed.
+func setStrategyTransitionCondition(cpms *machinev1.ControlPlaneMachineSet, indexes []int32) {
+	if len(indexes) == 0 {
+		meta.RemoveStatusCondition(&cpms.Status.Conditions, conditionStrategyTransition)
+		return
+	}
+
+	indexNames := []string{}
+	for _, idx := range indexes {
+		indexNames = append(indexNames, fmt.Sprintf("%d", idx))
+	}
+
+	meta.SetStatusCondition(&cpms.Status.Conditions, metav1.Condition{
+		Type:               conditionStrategyTransition,
+		Status:             metav1.ConditionTrue,
+		Reason:             reasonCompletingInFlightReplacements,
+		ObservedGeneration: cpms.GetGeneration(),
+		Message:            fmt.Sprintf("Completing %d in-flight replacement(s) started before the update strategy changed to %s, in index(es): %s", len(indexes), cpms.Spec.Strategy.Type, strings.Join(indexNames, ", ")),
+	})
+}
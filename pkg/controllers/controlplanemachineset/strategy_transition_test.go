@@ -0,0 +1,224 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controlplanemachineset
+
+import (
+	"errors"
+
+	"github.com/golang/mock/gomock"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	machinev1 "github.com/openshift/api/machine/v1"
+	machinev1beta1 "github.com/openshift/api/machine/v1beta1"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders/mock"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/test"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/test/resourcebuilder"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("Strategy transitions", func() {
+	var logger test.TestLogger
+	var reconciler *ControlPlaneMachineSetReconciler
+
+	var mockCtrl *gomock.Controller
+	var mockMachineProvider *mock.MockMachineProvider
+
+	machineGVR := machinev1beta1.GroupVersion.WithResource("machines")
+	nodeGVR := corev1.SchemeGroupVersion.WithResource("nodes")
+
+	const machineNamespace = "openshift-machine-api"
+
+	healthyMachineBuilder := resourcebuilder.MachineInfo().
+		WithMachineGVR(machineGVR).
+		WithMachineNamespace(machineNamespace).
+		WithNodeGVR(nodeGVR).
+		WithReady(true).
+		WithNeedsUpdate(false)
+
+	pendingMachineBuilder := resourcebuilder.MachineInfo().
+		WithMachineGVR(machineGVR).
+		WithMachineNamespace(machineNamespace).
+		WithReady(false).
+		WithNeedsUpdate(false)
+
+	outdatedMachine := healthyMachineBuilder.WithIndex(1).WithMachineName("machine-1").WithNodeName("node-1").WithNeedsUpdate(true).Build()
+
+	// inFlightMachineInfos returns the Machines of a Control Plane where a replacement, started by the RollingUpdate
+	// strategy, is in flight within index 1.
+	inFlightMachineInfos := func(replacement machineproviders.MachineInfo) map[int32][]machineproviders.MachineInfo {
+		return map[int32][]machineproviders.MachineInfo{
+			0: {healthyMachineBuilder.WithIndex(0).WithMachineName("machine-0").WithNodeName("node-0").Build()},
+			1: {
+				outdatedMachine,
+				replacement,
+			},
+			2: {healthyMachineBuilder.WithIndex(2).WithMachineName("machine-2").WithNodeName("node-2").Build()},
+		}
+	}
+
+	readyReplacement := healthyMachineBuilder.WithIndex(1).WithMachineName("machine-replacement-1").WithNodeName("node-replacement-1").Build()
+	pendingReplacement := pendingMachineBuilder.WithIndex(1).WithMachineName("machine-replacement-1").Build()
+
+	BeforeEach(func() {
+		logger = test.NewTestLogger()
+		reconciler = &ControlPlaneMachineSetReconciler{
+			Scheme: testScheme,
+		}
+
+		mockCtrl = gomock.NewController(GinkgoT())
+		mockMachineProvider = mock.NewMockMachineProvider(mockCtrl)
+	})
+
+	Context("when the update strategy has changed from RollingUpdate to OnDelete", func() {
+		var cpms *machinev1.ControlPlaneMachineSet
+
+		BeforeEach(func() {
+			cpms = resourcebuilder.ControlPlaneMachineSet().WithStrategyType(machinev1.OnDelete).WithReplicas(3).Build()
+		})
+
+		Context("and the in-flight replacement is ready", func() {
+			BeforeEach(func() {
+				mockMachineProvider.EXPECT().CreateMachine(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+				mockMachineProvider.EXPECT().DeleteMachine(gomock.Any(), gomock.Any(), outdatedMachine.MachineRef).Return(nil).Times(1)
+
+				_, err := reconciler.reconcileMachineUpdates(ctx, logger.Logger(), cpms, mockMachineProvider, inFlightMachineInfos(readyReplacement))
+				Expect(err).ToNot(HaveOccurred())
+			})
+
+			It("should set the strategy transition condition", func() {
+				Expect(meta.FindStatusCondition(cpms.Status.Conditions, conditionStrategyTransition)).To(SatisfyAll(
+					HaveField("Status", Equal(metav1.ConditionTrue)),
+					HaveField("Reason", Equal(reasonCompletingInFlightReplacements)),
+					HaveField("Message", Equal("Completing 1 in-flight replacement(s) started before the update strategy changed to OnDelete, in index(es): 1")),
+				))
+			})
+
+			It("should log that the replacement is being completed", func() {
+				Expect(logger.Entries()).To(ContainElements(
+					HaveField("Message", completingInFlightReplacement),
+					HaveField("Message", removingOldMachine),
+				))
+			})
+		})
+
+		Context("and the in-flight replacement is not ready", func() {
+			BeforeEach(func() {
+				mockMachineProvider.EXPECT().CreateMachine(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+				mockMachineProvider.EXPECT().DeleteMachine(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+
+				_, err := reconciler.reconcileMachineUpdates(ctx, logger.Logger(), cpms, mockMachineProvider, inFlightMachineInfos(pendingReplacement))
+				Expect(err).ToNot(HaveOccurred())
+			})
+
+			It("should set the strategy transition condition", func() {
+				Expect(meta.FindStatusCondition(cpms.Status.Conditions, conditionStrategyTransition)).To(HaveField("Reason", Equal(reasonCompletingInFlightReplacements)))
+			})
+
+			It("should wait for the replacement to become ready", func() {
+				Expect(logger.Entries()).To(ConsistOf(test.LogEntry{
+					Level: 2,
+					KeysAndValues: []interface{}{
+						"updateStrategy", machinev1.OnDelete,
+						"index", int32(1),
+						"namespace", machineNamespace,
+						"name", "machine-1",
+						"replacementName", "machine-replacement-1",
+					},
+					Message: waitingForReplacement,
+				}))
+			})
+		})
+
+		Context("and deleting the outdated machine fails", func() {
+			It("should return the error", func() {
+				mockMachineProvider.EXPECT().DeleteMachine(gomock.Any(), gomock.Any(), gomock.Any()).Return(errors.New("transient error")).Times(1)
+
+				_, err := reconciler.reconcileMachineUpdates(ctx, logger.Logger(), cpms, mockMachineProvider, inFlightMachineInfos(readyReplacement))
+				Expect(err).To(MatchError(ContainSubstring("error deleting Machine openshift-machine-api/machine-1: transient error")))
+			})
+		})
+
+		Context("and replacements are pre-created", func() {
+			BeforeEach(func() {
+				cpms.SetAnnotations(map[string]string{preCreateReplacementsAnnotation: "true"})
+
+				mockMachineProvider.EXPECT().CreateMachine(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+				mockMachineProvider.EXPECT().DeleteMachine(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+
+				_, err := reconciler.reconcileMachineUpdates(ctx, logger.Logger(), cpms, mockMachineProvider, inFlightMachineInfos(readyReplacement))
+				Expect(err).ToNot(HaveOccurred())
+			})
+
+			It("should leave the deletion of the outdated machine to the user", func() {
+				Expect(logger.Entries()).To(ContainElement(HaveField("Message", replacementReadyForDeletion)))
+			})
+
+			It("should not set the strategy transition condition", func() {
+				Expect(meta.FindStatusCondition(cpms.Status.Conditions, conditionStrategyTransition)).To(BeNil())
+			})
+		})
+
+		Context("and the in-flight replacement has completed", func() {
+			BeforeEach(func() {
+				setStrategyTransitionCondition(cpms, []int32{1})
+
+				mockMachineProvider.EXPECT().CreateMachine(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+				mockMachineProvider.EXPECT().DeleteMachine(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+
+				machineInfos := map[int32][]machineproviders.MachineInfo{
+					0: {healthyMachineBuilder.WithIndex(0).WithMachineName("machine-0").WithNodeName("node-0").Build()},
+					1: {readyReplacement},
+					2: {healthyMachineBuilder.WithIndex(2).WithMachineName("machine-2").WithNodeName("node-2").Build()},
+				}
+
+				_, err := reconciler.reconcileMachineUpdates(ctx, logger.Logger(), cpms, mockMachineProvider, machineInfos)
+				Expect(err).ToNot(HaveOccurred())
+			})
+
+			It("should remove the strategy transition condition", func() {
+				Expect(meta.FindStatusCondition(cpms.Status.Conditions, conditionStrategyTransition)).To(BeNil())
+			})
+		})
+	})
+
+	Context("when the update strategy has changed back to RollingUpdate", func() {
+		var cpms *machinev1.ControlPlaneMachineSet
+
+		BeforeEach(func() {
+			cpms = resourcebuilder.ControlPlaneMachineSet().WithStrategyType(machinev1.RollingUpdate).WithReplicas(3).Build()
+			setStrategyTransitionCondition(cpms, []int32{1})
+
+			mockMachineProvider.EXPECT().CreateMachine(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+			mockMachineProvider.EXPECT().DeleteMachine(gomock.Any(), gomock.Any(), outdatedMachine.MachineRef).Return(nil).Times(1)
+
+			_, err := reconciler.reconcileMachineUpdates(ctx, logger.Logger(), cpms, mockMachineProvider, inFlightMachineInfos(readyReplacement))
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("should complete the replacement as part of the rolling update", func() {
+			Expect(logger.Entries()).To(ContainElement(HaveField("Message", removingOldMachine)))
+		})
+
+		It("should remove the strategy transition condition", func() {
+			Expect(meta.FindStatusCondition(cpms.Status.Conditions, conditionStrategyTransition)).To(BeNil())
+		})
+	})
+})
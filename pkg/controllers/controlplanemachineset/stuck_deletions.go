@@ -0,0 +1,274 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controlplanemachineset
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	machinev1 "github.com/openshift/api/machine/v1"
+	machinev1beta1 "github.com/openshift/api/machine/v1beta1"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// forceRemoveFinalizersAnnotation is the annotation that users may set on a deleted Control Plane Machine to allow
+	// the ControlPlaneMachineSet to remove finalizers, belonging to other controllers, that are blocking the removal
+	// of the Machine. The value is a comma separated list of the finalizers that may be removed.
+	// The finalizers are only removed once the stuck deletion timeout has elapsed, the Machine is outdated and a
+	// ready replacement exists within the same index. The Machine API finalizer is never removed so that the
+	// instance backing the Machine is still cleaned up.
+	forceRemoveFinalizersAnnotation = "controlplanemachineset.machine.openshift.io/force-remove-finalizers"
+
+	// machineDeletionBlocked is a log message used to inform the user that the deletion of a Machine has been
+	// blocked by finalizers belonging to other controllers for longer than the stuck deletion timeout.
+	machineDeletionBlocked = "Machine deletion blocked by finalizers"
+
+	// removedBlockingFinalizers is a log message used to inform the user that finalizers blocking the deletion of
+	// a Machine have been forcibly removed.
+	removedBlockingFinalizers = "Removed finalizers blocking machine deletion"
+)
+
+// stuckDeletion describes a Machine whose deletion has been blocked by finalizers for longer than the stuck
+// deletion timeout.
+type stuckDeletion struct {
+	// machineInfo is the MachineInfo of the Machine being deleted.
+	machineInfo machineproviders.MachineInfo
+
+	// blockingFinalizers are the finalizers, belonging to other controllers, that remain on the Machine.
+	blockingFinalizers []string
+}
+
+// reconcileStuckDeletions surfaces, within the Progressing condition, any Machine whose deletion has been blocked by
+// finalizers belonging to other controllers for longer than the stuck deletion timeout.
+// When the user has opted in, via the force remove finalizers annotation, and it is safe to do so, the blocking
+// finalizers are removed so that the rollout can continue.
+// The returned result requeues the ControlPlaneMachineSet for when the next deleting Machine would become stuck.
+func (r *ControlPlaneMachineSetReconciler) reconcileStuckDeletions(ctx context.Context, logger logr.Logger, cpms *machinev1.ControlPlaneMachineSet, machineInfos map[int32][]machineproviders.MachineInfo) (ctrl.Result, error) {
+	timeout := r.stuckDeletionTimeoutFor(cpms)
+	if timeout <= 0 {
+		return ctrl.Result{}, nil
+	}
+
+	stuckDeletions, requeueAfter := findStuckDeletions(machineInfos, timeout, time.Now())
+	if len(stuckDeletions) == 0 {
+		return ctrl.Result{RequeueAfter: requeueAfter}, nil
+	}
+
+	setDeletionBlockedCondition(cpms, stuckDeletions)
+
+	for _, stuck := range stuckDeletions {
+		machineRef := stuck.machineInfo.MachineRef
+
+		logger.V(1).Info(machineDeletionBlocked,
+			"index", stuck.machineInfo.Index,
+			"machineNamespace", machineRef.ObjectMeta.GetNamespace(),
+			"machineName", machineRef.ObjectMeta.GetName(),
+			"finalizers", strings.Join(stuck.blockingFinalizers, ","),
+		)
+
+		// Finalizers are never forcibly removed while the cluster is in an unexpected state.
+		if isControlPlaneMachineSetDegraded(cpms) {
+			continue
+		}
+
+		removable := removableFinalizers(stuck, machineInfos[stuck.machineInfo.Index])
+		if len(removable) == 0 {
+			continue
+		}
+
+		if err := r.removeMachineFinalizers(ctx, machineRef, removable); err != nil {
+			return ctrl.Result{}, fmt.Errorf("error removing finalizers from machine %s: %w", machineRef.ObjectMeta.GetName(), err)
+		}
+
+		logger.V(1).Info(removedBlockingFinalizers,
+			"index", stuck.machineInfo.Index,
+			"machineNamespace", machineRef.ObjectMeta.GetNamespace(),
+			"machineName", machineRef.ObjectMeta.GetName(),
+			"finalizers", strings.Join(removable, ","),
+		)
+	}
+
+	return ctrl.Result{RequeueAfter: requeueAfter}, nil
+}
+
+// findStuckDeletions finds the Machines that have been deleted for longer than the stuck deletion timeout and that
+// are still held by finalizers belonging to other controllers.
+// It also returns the duration until the next deleting Machine, held by such finalizers, would become stuck, or zero
+// when there is no such Machine.
+func findStuckDeletions(machineInfos map[int32][]machineproviders.MachineInfo, timeout time.Duration, now time.Time) ([]stuckDeletion, time.Duration) {
+	stuckDeletions := []stuckDeletion{}
+
+	var requeueAfter time.Duration
+
+	for _, idx := range sortedIndexes(machineInfos) {
+		for _, machineInfo := range machineInfos[idx] {
+			if machineInfo.MachineRef == nil || machineInfo.MachineRef.ObjectMeta.GetDeletionTimestamp() == nil {
+				continue
+			}
+
+			blockingFinalizers := foreignFinalizers(machineInfo.MachineRef.ObjectMeta.GetFinalizers())
+			if len(blockingFinalizers) == 0 {
+				continue
+			}
+
+			stuckAt := machineInfo.MachineRef.ObjectMeta.GetDeletionTimestamp().Add(timeout)
+			if now.Before(stuckAt) {
+				if untilStuck := stuckAt.Sub(now); requeueAfter == 0 || untilStuck < requeueAfter {
+					requeueAfter = untilStuck
+				}
+
+				continue
+			}
+
+			stuckDeletions = append(stuckDeletions, stuckDeletion{
+				machineInfo:        machineInfo,
+				blockingFinalizers: blockingFinalizers,
+			})
+		}
+	}
+
+	return stuckDeletions, requeueAfter
+}
+
+// foreignFinalizers returns the sorted finalizers that do not belong to the Machine API.
+func foreignFinalizers(finalizers []string) []string {
+	foreign := []string{}
+
+	for _, finalizer := range finalizers {
+		if finalizer != machinev1beta1.MachineFinalizer {
+			foreign = append(foreign, finalizer)
+		}
+	}
+
+	sort.Strings(foreign)
+
+	return foreign
+}
+
+// removableFinalizers determines which of the blocking finalizers on a stuck Machine may be removed.
+// Finalizers may only be removed when the user has listed them within the force remove finalizers annotation, the
+// Machine is outdated, and another Machine within the same index is ready and up to date, so that removing the
+// Machine does not reduce the availability of the Control Plane.
+func removableFinalizers(stuck stuckDeletion, indexMachineInfos []machineproviders.MachineInfo) []string {
+	value, ok := stuck.machineInfo.MachineRef.ObjectMeta.GetAnnotations()[forceRemoveFinalizersAnnotation]
+	if !ok || !stuck.machineInfo.NeedsUpdate || !hasReadyReplacement(stuck.machineInfo, indexMachineInfos) {
+		return nil
+	}
+
+	allowed := map[string]bool{}
+	for _, finalizer := range strings.Split(value, ",") {
+		allowed[strings.TrimSpace(finalizer)] = true
+	}
+
+	removable := []string{}
+
+	for _, finalizer := range stuck.blockingFinalizers {
+		if allowed[finalizer] {
+			removable = append(removable, finalizer)
+		}
+	}
+
+	return removable
+}
+
+// hasReadyReplacement determines whether the index contains a ready, up to date Machine, that is not being deleted,
+// other than the Machine given.
+func hasReadyReplacement(machineInfo machineproviders.MachineInfo, indexMachineInfos []machineproviders.MachineInfo) bool {
+	for _, other := range indexMachineInfos {
+		if other.MachineRef == nil || other.MachineRef.ObjectMeta.GetName() == machineInfo.MachineRef.ObjectMeta.GetName() {
+			continue
+		}
+
+		if other.Ready && !other.NeedsUpdate && other.MachineRef.ObjectMeta.GetDeletionTimestamp() == nil {
+			return true
+		}
+	}
+
+	return false
+}
+
+// removeMachineFinalizers removes the finalizers given from the Machine referenced by the machineRef.
+// It uses PartialObjectMetadata so that the finalizers can be updated on any type, given the GVR and existing
+// ObjectMeta.
+func (r *ControlPlaneMachineSetReconciler) removeMachineFinalizers(ctx context.Context, machineRef *machineproviders.ObjectRef, remove []string) error {
+	gvk, err := r.RESTMapper.KindFor(machineRef.GroupVersionResource)
+	if err != nil {
+		return fmt.Errorf("could not get GroupVersionKind for machine: %w", err)
+	}
+
+	machine := &metav1.PartialObjectMetadata{}
+	machine.SetGroupVersionKind(gvk)
+	machine.SetName(machineRef.ObjectMeta.GetName())
+	machine.SetNamespace(machineRef.ObjectMeta.GetNamespace())
+	machine.SetFinalizers(machineRef.ObjectMeta.GetFinalizers())
+
+	patchBase := client.MergeFrom(machine.DeepCopy())
+
+	finalizers := []string{}
+
+	for _, finalizer := range machineRef.ObjectMeta.GetFinalizers() {
+		if !containsString(remove, finalizer) {
+			finalizers = append(finalizers, finalizer)
+		}
+	}
+
+	machine.SetFinalizers(finalizers)
+
+	if err := r.Patch(ctx, machine, patchBase); err != nil {
+		return fmt.Errorf("could not patch machine finalizers: %w", err)
+	}
+
+	return nil
+}
+
+// setDeletionBlockedCondition sets the progressing condition to report which finalizers are blocking the
+// deletion of the stuck Machines.
+func setDeletionBlockedCondition(cpms *machinev1.ControlPlaneMachineSet, stuckDeletions []stuckDeletion) {
+	blocked := []string{}
+
+	for _, stuck := range stuckDeletions {
+		blocked = append(blocked, fmt.Sprintf("%s (%s)", stuck.machineInfo.MachineRef.ObjectMeta.GetName(), strings.Join(stuck.blockingFinalizers, ", ")))
+	}
+
+	meta.SetStatusCondition(&cpms.Status.Conditions, metav1.Condition{
+		Type:               conditionProgressing,
+		Status:             metav1.ConditionTrue,
+		Reason:             reasonDeletionBlocked,
+		ObservedGeneration: cpms.GetGeneration(),
+		Message:            fmt.Sprintf("Deletion of %d machine(s) blocked by finalizers: %s", len(stuckDeletions), strings.Join(blocked, ", ")),
+	})
+}
+
+// containsString determines whether the slice contains the string given.
+func containsString(slice []string, s string) bool {
+	for _, item := range slice {
+		if item == s {
+			return true
+		}
+	}
+
+	return false
+}
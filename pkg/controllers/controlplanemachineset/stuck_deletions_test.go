@@ -0,0 +1,386 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controlplanemachineset
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	machinev1 "github.com/openshift/api/machine/v1"
+	machinev1beta1 "github.com/openshift/api/machine/v1beta1"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/test"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/test/resourcebuilder"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/envtest/komega"
+)
+
+var _ = Describe("Stuck deletions", func() {
+	const foreignFinalizer = "example.com/backup"
+
+	machineGVR := machinev1beta1.GroupVersion.WithResource("machines")
+
+	Context("findStuckDeletions", func() {
+		now := time.Date(2022, 8, 1, 12, 0, 0, 0, time.UTC)
+
+		machineInfoBuilder := resourcebuilder.MachineInfo().
+			WithMachineGVR(machineGVR).
+			WithIndex(0)
+
+		type findStuckDeletionsTableInput struct {
+			machineInfos          map[int32][]machineproviders.MachineInfo
+			expectedStuckMachines []string
+			expectedRequeueAfter  time.Duration
+		}
+
+		DescribeTable("should find machines blocked by foreign finalizers", func(in findStuckDeletionsTableInput) {
+			stuckDeletions, requeueAfter := findStuckDeletions(in.machineInfos, time.Hour, now)
+
+			stuckMachines := []string{}
+			for _, stuck := range stuckDeletions {
+				stuckMachines = append(stuckMachines, stuck.machineInfo.MachineRef.ObjectMeta.GetName())
+			}
+
+			Expect(stuckMachines).To(Equal(in.expectedStuckMachines))
+			Expect(requeueAfter).To(Equal(in.expectedRequeueAfter))
+		},
+			Entry("with no deleted machines", findStuckDeletionsTableInput{
+				machineInfos: map[int32][]machineproviders.MachineInfo{
+					0: {machineInfoBuilder.WithMachineName("machine-0").WithMachineFinalizers(foreignFinalizer).Build()},
+				},
+				expectedStuckMachines: []string{},
+			}),
+			Entry("with a deleted machine held only by the machine api finalizer", findStuckDeletionsTableInput{
+				machineInfos: map[int32][]machineproviders.MachineInfo{
+					0: {machineInfoBuilder.WithMachineName("machine-0").
+						WithMachineDeletionTimestamp(metav1.NewTime(now.Add(-2 * time.Hour))).
+						WithMachineFinalizers(machinev1beta1.MachineFinalizer).
+						Build(),
+					},
+				},
+				expectedStuckMachines: []string{},
+			}),
+			Entry("with a deleted machine held by a foreign finalizer within the timeout", findStuckDeletionsTableInput{
+				machineInfos: map[int32][]machineproviders.MachineInfo{
+					0: {machineInfoBuilder.WithMachineName("machine-0").
+						WithMachineDeletionTimestamp(metav1.NewTime(now.Add(-20*time.Minute))).
+						WithMachineFinalizers(machinev1beta1.MachineFinalizer, foreignFinalizer).
+						Build(),
+					},
+				},
+				expectedStuckMachines: []string{},
+				expectedRequeueAfter:  40 * time.Minute,
+			}),
+			Entry("with a deleted machine held by a foreign finalizer beyond the timeout", findStuckDeletionsTableInput{
+				machineInfos: map[int32][]machineproviders.MachineInfo{
+					0: {machineInfoBuilder.WithMachineName("machine-0").
+						WithMachineDeletionTimestamp(metav1.NewTime(now.Add(-2*time.Hour))).
+						WithMachineFinalizers(machinev1beta1.MachineFinalizer, foreignFinalizer).
+						Build(),
+					},
+				},
+				expectedStuckMachines: []string{"machine-0"},
+			}),
+			Entry("with multiple deleted machines across indexes", findStuckDeletionsTableInput{
+				machineInfos: map[int32][]machineproviders.MachineInfo{
+					0: {machineInfoBuilder.WithMachineName("machine-0").
+						WithMachineDeletionTimestamp(metav1.NewTime(now.Add(-2 * time.Hour))).
+						WithMachineFinalizers(foreignFinalizer).
+						Build(),
+					},
+					1: {machineInfoBuilder.WithIndex(1).WithMachineName("machine-1").
+						WithMachineDeletionTimestamp(metav1.NewTime(now.Add(-50 * time.Minute))).
+						WithMachineFinalizers(foreignFinalizer).
+						Build(),
+					},
+					2: {machineInfoBuilder.WithIndex(2).WithMachineName("machine-2").
+						WithMachineDeletionTimestamp(metav1.NewTime(now.Add(-30 * time.Minute))).
+						WithMachineFinalizers(foreignFinalizer).
+						Build(),
+					},
+				},
+				expectedStuckMachines: []string{"machine-0"},
+				expectedRequeueAfter:  10 * time.Minute,
+			}),
+		)
+	})
+
+	Context("reconcileStuckDeletions", func() {
+		var namespaceName string
+		var reconciler *ControlPlaneMachineSetReconciler
+		var logger test.TestLogger
+		var cpms *machinev1.ControlPlaneMachineSet
+
+		var machine *machinev1beta1.Machine
+		var stuckMachineBuilder resourcebuilder.MachineInfoBuilder
+		var replacementMachineBuilder resourcebuilder.MachineInfoBuilder
+
+		var result ctrl.Result
+		var err error
+
+		BeforeEach(func() {
+			By("Setting up a namespace for the test")
+			ns := resourcebuilder.Namespace().WithGenerateName("control-plane-machine-set-stuck-deletions-").Build()
+			Expect(k8sClient.Create(ctx, ns)).To(Succeed())
+			namespaceName = ns.GetName()
+
+			reconciler = &ControlPlaneMachineSetReconciler{
+				Client:               k8sClient,
+				Scheme:               testScheme,
+				RESTMapper:           testRESTMapper,
+				Namespace:            namespaceName,
+				StuckDeletionTimeout: time.Hour,
+			}
+
+			logger = test.NewTestLogger()
+			cpms = resourcebuilder.ControlPlaneMachineSet().WithNamespace(namespaceName).Build()
+
+			By("Creating a deleted machine held by finalizers")
+			machine = resourcebuilder.Machine().WithNamespace(namespaceName).WithGenerateName("stuck-deletions-test-").Build()
+			machine.SetFinalizers([]string{machinev1beta1.MachineFinalizer, foreignFinalizer})
+			Expect(k8sClient.Create(ctx, machine)).To(Succeed())
+			Expect(k8sClient.Delete(ctx, machine)).To(Succeed())
+
+			stuckMachineBuilder = resourcebuilder.MachineInfo().
+				WithIndex(1).
+				WithMachineGVR(machineGVR).
+				WithMachineName(machine.GetName()).
+				WithMachineNamespace(namespaceName).
+				WithMachineDeletionTimestamp(metav1.NewTime(time.Now().Add(-2*time.Hour))).
+				WithMachineFinalizers(machinev1beta1.MachineFinalizer, foreignFinalizer).
+				WithNeedsUpdate(true)
+
+			replacementMachineBuilder = resourcebuilder.MachineInfo().
+				WithIndex(1).
+				WithMachineGVR(machineGVR).
+				WithMachineName("replacement-1").
+				WithMachineNamespace(namespaceName).
+				WithReady(true).
+				WithNeedsUpdate(false)
+		})
+
+		AfterEach(func() {
+			test.CleanupResources(Default, ctx, cfg, k8sClient, namespaceName,
+				&machinev1beta1.Machine{},
+			)
+		})
+
+		Context("when the deletion is blocked and the user has not opted in", func() {
+			BeforeEach(func() {
+				machineInfos := map[int32][]machineproviders.MachineInfo{
+					1: {stuckMachineBuilder.Build(), replacementMachineBuilder.Build()},
+				}
+
+				result, err = reconciler.reconcileStuckDeletions(ctx, logger.Logger(), cpms, machineInfos)
+			})
+
+			It("should not return an error", func() {
+				Expect(err).ToNot(HaveOccurred())
+			})
+
+			It("should not requeue", func() {
+				Expect(result).To(Equal(ctrl.Result{}))
+			})
+
+			It("should report the blocking finalizer in the progressing condition", func() {
+				Expect(cpms.Status.Conditions).To(ConsistOf(test.MatchCondition(metav1.Condition{
+					Type:    conditionProgressing,
+					Status:  metav1.ConditionTrue,
+					Reason:  reasonDeletionBlocked,
+					Message: "Deletion of 1 machine(s) blocked by finalizers: " + machine.GetName() + " (" + foreignFinalizer + ")",
+				})))
+			})
+
+			It("should not remove the finalizers", func() {
+				Consistently(komega.Object(machine)).Should(HaveField("ObjectMeta.Finalizers", ConsistOf(machinev1beta1.MachineFinalizer, foreignFinalizer)))
+			})
+
+			It("should log that the deletion is blocked", func() {
+				Expect(logger.Entries()).To(ConsistOf(test.LogEntry{
+					KeysAndValues: []interface{}{"index", int32(1), "machineNamespace", namespaceName, "machineName", machine.GetName(), "finalizers", foreignFinalizer},
+					Level:         1,
+					Message:       machineDeletionBlocked,
+				}))
+			})
+		})
+
+		Context("when the deletion is blocked and the user has opted in", func() {
+			BeforeEach(func() {
+				stuckMachineBuilder = stuckMachineBuilder.WithMachineAnnotations(map[string]string{
+					forceRemoveFinalizersAnnotation: foreignFinalizer,
+				})
+			})
+
+			Context("and a ready replacement exists", func() {
+				BeforeEach(func() {
+					machineInfos := map[int32][]machineproviders.MachineInfo{
+						1: {stuckMachineBuilder.Build(), replacementMachineBuilder.Build()},
+					}
+
+					result, err = reconciler.reconcileStuckDeletions(ctx, logger.Logger(), cpms, machineInfos)
+				})
+
+				It("should not return an error", func() {
+					Expect(err).ToNot(HaveOccurred())
+				})
+
+				It("should remove only the foreign finalizer", func() {
+					Eventually(komega.Object(machine)).Should(HaveField("ObjectMeta.Finalizers", ConsistOf(machinev1beta1.MachineFinalizer)))
+				})
+
+				It("should log that the finalizer was removed", func() {
+					Expect(logger.Entries()).To(ContainElement(test.LogEntry{
+						KeysAndValues: []interface{}{"index", int32(1), "machineNamespace", namespaceName, "machineName", machine.GetName(), "finalizers", foreignFinalizer},
+						Level:         1,
+						Message:       removedBlockingFinalizers,
+					}))
+				})
+			})
+
+			Context("and the replacement is not yet ready", func() {
+				BeforeEach(func() {
+					machineInfos := map[int32][]machineproviders.MachineInfo{
+						1: {stuckMachineBuilder.Build(), replacementMachineBuilder.WithReady(false).Build()},
+					}
+
+					result, err = reconciler.reconcileStuckDeletions(ctx, logger.Logger(), cpms, machineInfos)
+				})
+
+				It("should not remove the finalizers", func() {
+					Consistently(komega.Object(machine)).Should(HaveField("ObjectMeta.Finalizers", ConsistOf(machinev1beta1.MachineFinalizer, foreignFinalizer)))
+				})
+			})
+
+			Context("and the machine is up to date", func() {
+				BeforeEach(func() {
+					machineInfos := map[int32][]machineproviders.MachineInfo{
+						1: {stuckMachineBuilder.WithNeedsUpdate(false).Build(), replacementMachineBuilder.Build()},
+					}
+
+					result, err = reconciler.reconcileStuckDeletions(ctx, logger.Logger(), cpms, machineInfos)
+				})
+
+				It("should not remove the finalizers", func() {
+					Consistently(komega.Object(machine)).Should(HaveField("ObjectMeta.Finalizers", ConsistOf(machinev1beta1.MachineFinalizer, foreignFinalizer)))
+				})
+			})
+
+			Context("and the annotation lists a different finalizer", func() {
+				BeforeEach(func() {
+					machineInfos := map[int32][]machineproviders.MachineInfo{
+						1: {
+							stuckMachineBuilder.WithMachineAnnotations(map[string]string{forceRemoveFinalizersAnnotation: "example.com/other"}).Build(),
+							replacementMachineBuilder.Build(),
+						},
+					}
+
+					result, err = reconciler.reconcileStuckDeletions(ctx, logger.Logger(), cpms, machineInfos)
+				})
+
+				It("should not remove the finalizers", func() {
+					Consistently(komega.Object(machine)).Should(HaveField("ObjectMeta.Finalizers", ConsistOf(machinev1beta1.MachineFinalizer, foreignFinalizer)))
+				})
+			})
+
+			Context("and the control plane machine set is degraded", func() {
+				BeforeEach(func() {
+					meta.SetStatusCondition(&cpms.Status.Conditions, metav1.Condition{
+						Type:   conditionDegraded,
+						Status: metav1.ConditionTrue,
+						Reason: reasonUnmanagedNodes,
+					})
+
+					machineInfos := map[int32][]machineproviders.MachineInfo{
+						1: {stuckMachineBuilder.Build(), replacementMachineBuilder.Build()},
+					}
+
+					result, err = reconciler.reconcileStuckDeletions(ctx, logger.Logger(), cpms, machineInfos)
+				})
+
+				It("should not remove the finalizers", func() {
+					Consistently(komega.Object(machine)).Should(HaveField("ObjectMeta.Finalizers", ConsistOf(machinev1beta1.MachineFinalizer, foreignFinalizer)))
+				})
+			})
+		})
+
+		Context("when stuck deletion detection is disabled by the stage timeouts annotation", func() {
+			BeforeEach(func() {
+				cpms.SetAnnotations(map[string]string{stageTimeoutsAnnotation: "deletion=0s"})
+
+				machineInfos := map[int32][]machineproviders.MachineInfo{
+					1: {stuckMachineBuilder.Build(), replacementMachineBuilder.Build()},
+				}
+
+				result, err = reconciler.reconcileStuckDeletions(ctx, logger.Logger(), cpms, machineInfos)
+			})
+
+			It("should not set any conditions", func() {
+				Expect(cpms.Status.Conditions).To(BeEmpty())
+			})
+		})
+
+		Context("when the stuck deletion timeout is extended by the stage timeouts annotation", func() {
+			BeforeEach(func() {
+				cpms.SetAnnotations(map[string]string{stageTimeoutsAnnotation: "deletion=3h"})
+
+				machineInfos := map[int32][]machineproviders.MachineInfo{
+					1: {stuckMachineBuilder.Build(), replacementMachineBuilder.Build()},
+				}
+
+				result, err = reconciler.reconcileStuckDeletions(ctx, logger.Logger(), cpms, machineInfos)
+			})
+
+			It("should not set any conditions", func() {
+				Expect(cpms.Status.Conditions).To(BeEmpty())
+			})
+
+			It("should requeue for when the machine would become stuck", func() {
+				Expect(err).ToNot(HaveOccurred())
+				Expect(result.RequeueAfter).To(BeNumerically("~", time.Hour, time.Minute))
+			})
+
+			It("should not remove the finalizers", func() {
+				Consistently(komega.Object(machine)).Should(HaveField("ObjectMeta.Finalizers", ConsistOf(machinev1beta1.MachineFinalizer, foreignFinalizer)))
+			})
+		})
+
+		Context("when stuck deletion detection is disabled", func() {
+			BeforeEach(func() {
+				reconciler.StuckDeletionTimeout = 0
+
+				machineInfos := map[int32][]machineproviders.MachineInfo{
+					1: {stuckMachineBuilder.Build(), replacementMachineBuilder.Build()},
+				}
+
+				result, err = reconciler.reconcileStuckDeletions(ctx, logger.Logger(), cpms, machineInfos)
+			})
+
+			It("should not set any conditions", func() {
+				Expect(cpms.Status.Conditions).To(BeEmpty())
+			})
+
+			It("should not log", func() {
+				Expect(logger.Entries()).To(BeEmpty())
+			})
+		})
+	})
+})
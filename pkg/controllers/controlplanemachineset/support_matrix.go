@@ -0,0 +1,85 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controlplanemachineset
+
+import (
+	"encoding/json"
+	"fmt"
+
+	configv1 "github.com/openshift/api/config/v1"
+	machinev1 "github.com/openshift/api/machine/v1"
+	cpmsclient "github.com/openshift/cluster-control-plane-machine-set-operator/pkg/client/controlplanemachineset"
+	"k8s.io/apimachinery/pkg/api/equality"
+)
+
+// supportMatrix describes the platforms, update strategies and features supported by this build of the operator.
+// AWS is the only platform with stable failure domain support. Azure, GCP and IBM Cloud failure domains, by zone,
+// OpenStack failure domains, by availability zone, and Nutanix failure domains, by Prism Element, are in tech preview
+// alongside their platforms, the other platforms, Alibaba Cloud, BareMetal, Equinix Metal, Power VS and vSphere, are
+// limited to a single failure domain.
+// Features that must be explicitly enabled by a flag are in tech preview.
+// This must be kept up to date as support is added, see docs/support-matrix.md.
+var supportMatrix = cpmsclient.SupportMatrix{
+	Platforms: []cpmsclient.SupportEntry{
+		{Name: string(configv1.AlibabaCloudPlatformType), Maturity: cpmsclient.MaturityTechPreview},
+		{Name: string(configv1.AWSPlatformType), Maturity: cpmsclient.MaturityStable},
+		{Name: string(configv1.AzurePlatformType), Maturity: cpmsclient.MaturityTechPreview},
+		{Name: string(configv1.BareMetalPlatformType), Maturity: cpmsclient.MaturityTechPreview},
+		{Name: string(configv1.EquinixMetalPlatformType), Maturity: cpmsclient.MaturityTechPreview},
+		{Name: string(configv1.GCPPlatformType), Maturity: cpmsclient.MaturityTechPreview},
+		{Name: string(configv1.IBMCloudPlatformType), Maturity: cpmsclient.MaturityTechPreview},
+		{Name: string(configv1.NutanixPlatformType), Maturity: cpmsclient.MaturityTechPreview},
+		{Name: string(configv1.OpenStackPlatformType), Maturity: cpmsclient.MaturityTechPreview},
+		{Name: string(configv1.PowerVSPlatformType), Maturity: cpmsclient.MaturityTechPreview},
+		{Name: string(configv1.VSpherePlatformType), Maturity: cpmsclient.MaturityTechPreview},
+	},
+	Strategies: []cpmsclient.SupportEntry{
+		{Name: string(machinev1.RollingUpdate), Maturity: cpmsclient.MaturityStable},
+		{Name: string(machinev1.OnDelete), Maturity: cpmsclient.MaturityStable},
+		{Name: string(machinev1.Recreate), Maturity: cpmsclient.MaturityUnsupported},
+	},
+	Features: []cpmsclient.SupportEntry{
+		{Name: "FailureDomainsConfigMap", Maturity: cpmsclient.MaturityStable},
+		{Name: "MachineAPIPauseDetection", Maturity: cpmsclient.MaturityStable},
+		{Name: "ReconcileRequests", Maturity: cpmsclient.MaturityStable},
+		{Name: "StuckDeletionRecovery", Maturity: cpmsclient.MaturityStable},
+		{Name: "UserPause", Maturity: cpmsclient.MaturityStable},
+		{Name: "ControlPlaneScaleDown", Maturity: cpmsclient.MaturityTechPreview},
+		{Name: "DepartedNodeDeletion", Maturity: cpmsclient.MaturityTechPreview},
+		{Name: "InstanceVerification", Maturity: cpmsclient.MaturityTechPreview},
+		{Name: "MissingTagRepair", Maturity: cpmsclient.MaturityTechPreview},
+		{Name: "RolloutCostEstimate", Maturity: cpmsclient.MaturityTechPreview},
+	},
+}
+
+// setSupportMatrixExtension publishes the support matrix within the extension of the status of the cluster operator.
+// The extension is only replaced when the published support matrix differs, as the API server does not preserve the
+// formatting of the extension.
+func setSupportMatrixExtension(co *configv1.ClusterOperator) error {
+	if published, err := cpmsclient.SupportMatrixFromClusterOperator(co); err == nil && equality.Semantic.DeepEqual(*published, supportMatrix) {
+		return nil
+	}
+
+	raw, err := json.Marshal(supportMatrix)
+	if err != nil {
+		return fmt.Errorf("could not encode support matrix: %w", err)
+	}
+
+	co.Status.Extension.Raw = raw
+
+	return nil
+}
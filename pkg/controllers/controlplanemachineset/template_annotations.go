@@ -0,0 +1,208 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controlplanemachineset
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/go-logr/logr"
+	machinev1 "github.com/openshift/api/machine/v1"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// nodeAnnotationsAnnotation is the annotation on the ControlPlaneMachineSet used to list, comma separated, the
+	// keys of the template annotations that should also be propagated to the Nodes of the Control Plane Machines.
+	nodeAnnotationsAnnotation = "controlplanemachineset.machine.openshift.io/node-annotations"
+
+	// reservedAnnotationDomain is the domain of the annotations managed by the Machine API and by the
+	// ControlPlaneMachineSet itself. Template annotations within this domain, or any of its subdomains, are not
+	// propagated to existing Machines or Nodes.
+	reservedAnnotationDomain = "machine.openshift.io"
+
+	// propagatedMachineAnnotations is a log message used to inform the user that template annotations have been
+	// propagated to an existing Machine.
+	propagatedMachineAnnotations = "Propagated template annotations to machine"
+
+	// propagatedNodeAnnotations is a log message used to inform the user that template annotations have been
+	// propagated to a Node.
+	propagatedNodeAnnotations = "Propagated template annotations to node"
+)
+
+// ensureTemplateAnnotations propagates the annotations within the metadata of the Machine template to the existing
+// Machines within the machineInfos, so that changes to the template annotations do not require the Machines to be
+// replaced. New Machines are created with the template annotations by the machine provider.
+// When listed within the node annotations annotation, template annotations are also propagated to the Nodes of the
+// Machines.
+// Annotations are only ever added or updated. Annotations removed from the template are not removed from existing
+// Machines and Nodes, as they cannot be distinguished from annotations added by other means.
+func (r *ControlPlaneMachineSetReconciler) ensureTemplateAnnotations(ctx context.Context, logger logr.Logger, cpms *machinev1.ControlPlaneMachineSet, machineInfos map[int32][]machineproviders.MachineInfo) error {
+	if cpms.Spec.Template.OpenShiftMachineV1Beta1Machine == nil {
+		return nil
+	}
+
+	machineAnnotations := propagatedAnnotations(cpms.Spec.Template.OpenShiftMachineV1Beta1Machine.ObjectMeta.Annotations)
+	nodeAnnotations := selectAnnotations(machineAnnotations, cpms.GetAnnotations()[nodeAnnotationsAnnotation])
+
+	for _, idx := range sortedIndexes(machineInfos) {
+		for _, machineInfo := range machineInfos[idx] {
+			if machineInfo.MachineRef == nil || machineInfo.MachineRef.ObjectMeta.GetDeletionTimestamp() != nil {
+				continue
+			}
+
+			if err := r.ensureMachineTemplateAnnotations(ctx, logger, machineInfo.MachineRef, machineAnnotations); err != nil {
+				return fmt.Errorf("error propagating annotations to machine %s: %w", machineInfo.MachineRef.ObjectMeta.GetName(), err)
+			}
+
+			if machineInfo.NodeRef == nil || len(nodeAnnotations) == 0 {
+				continue
+			}
+
+			if err := r.ensureNodeTemplateAnnotations(ctx, logger, machineInfo.NodeRef.ObjectMeta.GetName(), nodeAnnotations); err != nil {
+				return fmt.Errorf("error propagating annotations to node %s: %w", machineInfo.NodeRef.ObjectMeta.GetName(), err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// ensureMachineTemplateAnnotations updates the annotations on a single Machine, if required.
+func (r *ControlPlaneMachineSetReconciler) ensureMachineTemplateAnnotations(ctx context.Context, logger logr.Logger, machineRef *machineproviders.ObjectRef, desired map[string]string) error {
+	outdated := outdatedAnnotationKeys(machineRef.ObjectMeta.GetAnnotations(), desired)
+	if len(outdated) == 0 {
+		return nil
+	}
+
+	if err := r.patchMachineAnnotations(ctx, machineRef, func(annotations map[string]string) {
+		for _, key := range outdated {
+			annotations[key] = desired[key]
+		}
+	}); err != nil {
+		return err
+	}
+
+	logger.V(2).Info(propagatedMachineAnnotations,
+		"machineNamespace", machineRef.ObjectMeta.GetNamespace(),
+		"machineName", machineRef.ObjectMeta.GetName(),
+		"annotations", strings.Join(outdated, ","),
+	)
+
+	return nil
+}
+
+// ensureNodeTemplateAnnotations updates the annotations on a single Node, if required.
+// Nodes that no longer exist are ignored.
+func (r *ControlPlaneMachineSetReconciler) ensureNodeTemplateAnnotations(ctx context.Context, logger logr.Logger, nodeName string, desired map[string]string) error {
+	node := &corev1.Node{}
+	if err := r.Get(ctx, client.ObjectKey{Name: nodeName}, node); apierrors.IsNotFound(err) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("could not fetch node: %w", err)
+	}
+
+	outdated := outdatedAnnotationKeys(node.GetAnnotations(), desired)
+	if len(outdated) == 0 {
+		return nil
+	}
+
+	patchBase := client.MergeFrom(node.DeepCopy())
+
+	annotations := node.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+
+	for _, key := range outdated {
+		annotations[key] = desired[key]
+	}
+
+	node.SetAnnotations(annotations)
+
+	if err := r.Patch(ctx, node, patchBase); err != nil {
+		return fmt.Errorf("could not patch node annotations: %w", err)
+	}
+
+	logger.V(2).Info(propagatedNodeAnnotations,
+		"nodeName", nodeName,
+		"annotations", strings.Join(outdated, ","),
+	)
+
+	return nil
+}
+
+// propagatedAnnotations returns the template annotations that should be propagated to existing Machines.
+// Annotations within the reserved domain are managed by the Machine API and the ControlPlaneMachineSet, and so
+// are not propagated.
+func propagatedAnnotations(templateAnnotations map[string]string) map[string]string {
+	propagated := map[string]string{}
+
+	for key, value := range templateAnnotations {
+		if isReservedAnnotation(key) {
+			continue
+		}
+
+		propagated[key] = value
+	}
+
+	return propagated
+}
+
+// isReservedAnnotation determines whether the annotation key is within the reserved annotation domain.
+func isReservedAnnotation(key string) bool {
+	parts := strings.SplitN(key, "/", 2)
+	if len(parts) != 2 {
+		return false
+	}
+
+	return parts[0] == reservedAnnotationDomain || strings.HasSuffix(parts[0], "."+reservedAnnotationDomain)
+}
+
+// selectAnnotations returns the annotations whose keys are within the comma separated list of keys.
+func selectAnnotations(annotations map[string]string, keys string) map[string]string {
+	selected := map[string]string{}
+
+	for _, key := range strings.Split(keys, ",") {
+		if value, ok := annotations[strings.TrimSpace(key)]; ok {
+			selected[strings.TrimSpace(key)] = value
+		}
+	}
+
+	return selected
+}
+
+// outdatedAnnotationKeys returns the sorted keys of the desired annotations that are missing from, or have a
+// different value within, the current annotations.
+func outdatedAnnotationKeys(current, desired map[string]string) []string {
+	outdated := []string{}
+
+	for key, value := range desired {
+		if currentValue, ok := current[key]; !ok || currentValue != value {
+			outdated = append(outdated, key)
+		}
+	}
+
+	sort.Strings(outdated)
+
+	return outdated
+}
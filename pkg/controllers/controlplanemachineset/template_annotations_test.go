@@ -0,0 +1,172 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controlplanemachineset
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	machinev1 "github.com/openshift/api/machine/v1"
+	machinev1beta1 "github.com/openshift/api/machine/v1beta1"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/test"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/test/resourcebuilder"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/envtest/komega"
+)
+
+var _ = Describe("ensureTemplateAnnotations", func() {
+	const backupAnnotation = "backup.example.com/policy"
+	const costCentreAnnotation = "cost.example.com/centre"
+	const instanceStateAnnotation = "machine.openshift.io/instance-state"
+
+	templateAnnotations := map[string]string{
+		backupAnnotation:          "daily",
+		costCentreAnnotation:      "platform",
+		unmanagedFieldsAnnotation: "apiVersion",
+		instanceStateAnnotation:   "ignored",
+	}
+
+	var namespaceName string
+	var reconciler *ControlPlaneMachineSetReconciler
+	var logger test.TestLogger
+
+	var machine *machinev1beta1.Machine
+	var node *corev1.Node
+	var machineInfoBuilder resourcebuilder.MachineInfoBuilder
+
+	BeforeEach(func() {
+		By("Setting up a namespace for the test")
+		ns := resourcebuilder.Namespace().WithGenerateName("control-plane-machine-set-template-annotations-").Build()
+		Expect(k8sClient.Create(ctx, ns)).To(Succeed())
+		namespaceName = ns.GetName()
+
+		reconciler = &ControlPlaneMachineSetReconciler{
+			Client:     k8sClient,
+			Scheme:     testScheme,
+			RESTMapper: testRESTMapper,
+			Namespace:  namespaceName,
+		}
+
+		logger = test.NewTestLogger()
+
+		By("Creating a machine and node to propagate annotations to")
+		machine = resourcebuilder.Machine().AsMaster().WithNamespace(namespaceName).WithGenerateName("template-annotations-test-").Build()
+		machine.SetAnnotations(map[string]string{costCentreAnnotation: "platform"})
+		Expect(k8sClient.Create(ctx, machine)).To(Succeed())
+
+		node = resourcebuilder.Node().AsMaster().WithGenerateName("template-annotations-test-").Build()
+		Expect(k8sClient.Create(ctx, node)).To(Succeed())
+
+		machineInfoBuilder = resourcebuilder.MachineInfo().
+			WithMachineGVR(machinev1beta1.GroupVersion.WithResource("machines")).
+			WithMachineName(machine.GetName()).
+			WithMachineNamespace(namespaceName).
+			WithMachineAnnotations(machine.GetAnnotations()).
+			WithNodeGVR(corev1.SchemeGroupVersion.WithResource("nodes")).
+			WithNodeName(node.GetName())
+	})
+
+	AfterEach(func() {
+		test.CleanupResources(Default, ctx, cfg, k8sClient, namespaceName,
+			&corev1.Node{},
+			&machinev1beta1.Machine{},
+		)
+	})
+
+	ensureTemplateAnnotations := func(cpms *machinev1.ControlPlaneMachineSet) {
+		machineInfos := map[int32][]machineproviders.MachineInfo{
+			0: {machineInfoBuilder.Build()},
+		}
+
+		Expect(reconciler.ensureTemplateAnnotations(ctx, logger.Logger(), cpms, machineInfos)).To(Succeed())
+	}
+
+	Context("with annotations within the template", func() {
+		BeforeEach(func() {
+			ensureTemplateAnnotations(resourcebuilder.ControlPlaneMachineSet().WithMachineTemplateBuilder(
+				resourcebuilder.OpenShiftMachineV1Beta1Template().WithAnnotations(templateAnnotations),
+			).Build())
+		})
+
+		It("should propagate the annotations to the machine", func() {
+			Eventually(komega.Object(machine)).Should(HaveField("ObjectMeta.Annotations", SatisfyAll(
+				HaveKeyWithValue(backupAnnotation, "daily"),
+				HaveKeyWithValue(costCentreAnnotation, "platform"),
+			)))
+		})
+
+		It("should not propagate reserved annotations", func() {
+			Consistently(komega.Object(machine)).ShouldNot(HaveField("ObjectMeta.Annotations", SatisfyAny(
+				HaveKey(unmanagedFieldsAnnotation),
+				HaveKey(instanceStateAnnotation),
+			)))
+		})
+
+		It("should not propagate the annotations to the node", func() {
+			Consistently(komega.Object(node)).ShouldNot(HaveField("ObjectMeta.Annotations", HaveKey(backupAnnotation)))
+		})
+
+		It("should log the annotations that were propagated", func() {
+			Expect(logger.Entries()).To(ConsistOf(test.LogEntry{
+				KeysAndValues: []interface{}{"machineNamespace", namespaceName, "machineName", machine.GetName(), "annotations", backupAnnotation},
+				Level:         2,
+				Message:       propagatedMachineAnnotations,
+			}))
+		})
+	})
+
+	Context("with node annotations listed on the ControlPlaneMachineSet", func() {
+		BeforeEach(func() {
+			ensureTemplateAnnotations(resourcebuilder.ControlPlaneMachineSet().
+				WithAnnotations(map[string]string{nodeAnnotationsAnnotation: backupAnnotation + ", " + unmanagedFieldsAnnotation}).
+				WithMachineTemplateBuilder(resourcebuilder.OpenShiftMachineV1Beta1Template().WithAnnotations(templateAnnotations)).
+				Build())
+		})
+
+		It("should propagate the listed annotations to the node", func() {
+			Eventually(komega.Object(node)).Should(HaveField("ObjectMeta.Annotations", HaveKeyWithValue(backupAnnotation, "daily")))
+		})
+
+		It("should not propagate unlisted or reserved annotations to the node", func() {
+			Consistently(komega.Object(node)).ShouldNot(HaveField("ObjectMeta.Annotations", SatisfyAny(
+				HaveKey(costCentreAnnotation),
+				HaveKey(unmanagedFieldsAnnotation),
+			)))
+		})
+
+		It("should log the annotations that were propagated", func() {
+			Expect(logger.Entries()).To(ContainElement(test.LogEntry{
+				KeysAndValues: []interface{}{"nodeName", node.GetName(), "annotations", backupAnnotation},
+				Level:         2,
+				Message:       propagatedNodeAnnotations,
+			}))
+		})
+	})
+
+	Context("when the annotations are already up to date", func() {
+		BeforeEach(func() {
+			ensureTemplateAnnotations(resourcebuilder.ControlPlaneMachineSet().WithMachineTemplateBuilder(
+				resourcebuilder.OpenShiftMachineV1Beta1Template().WithAnnotations(map[string]string{costCentreAnnotation: "platform"}),
+			).Build())
+		})
+
+		It("should not update the machine", func() {
+			Expect(logger.Entries()).To(BeEmpty())
+		})
+	})
+})
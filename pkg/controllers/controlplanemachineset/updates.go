@@ -20,6 +20,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/go-logr/logr"
 	machinev1 "github.com/openshift/api/machine/v1"
@@ -72,6 +73,22 @@ const (
 	// place because the rollout is waiting for a replacement Machine to become ready.
 	// This is used when replacing a Machine within an index.
 	waitingForReplacement = "Waiting for replacement machine to become ready"
+
+	// preCreateReplacementsAnnotation is the annotation on the ControlPlaneMachineSet used to opt in to replacements
+	// being created ahead of time with the OnDelete strategy. When set to "true", the controller creates an updated
+	// replacement for an outdated index, one index at a time, and then waits for the user to delete the outdated
+	// Machine. This shrinks the window between the deletion of the outdated Machine and its replacement joining the
+	// cluster.
+	preCreateReplacementsAnnotation = "controlplanemachineset.machine.openshift.io/pre-create-replacements"
+
+	// replacementReadyForDeletion is a log message used to inform the user that a replacement Machine, created
+	// ahead of time, is ready and that they must now delete the outdated Machine to complete the update.
+	// This is used with the OnDelete replacement strategy when replacements are pre-created.
+	replacementReadyForDeletion = "Replacement machine is ready, delete the outdated machine to complete the update"
+
+	// unknownMachineName is used in place of the name of a Machine when logging about an index that does not yet
+	// have a Machine.
+	unknownMachineName = "<Unknown>"
 )
 
 var (
@@ -93,6 +110,9 @@ var (
 func (r *ControlPlaneMachineSetReconciler) reconcileMachineUpdates(ctx context.Context, logger logr.Logger, cpms *machinev1.ControlPlaneMachineSet, machineProvider machineproviders.MachineProvider, machineInfos map[int32][]machineproviders.MachineInfo) (ctrl.Result, error) {
 	switch cpms.Spec.Strategy.Type {
 	case machinev1.RollingUpdate:
+		// The RollingUpdate strategy completes in-flight replacements itself, so there is no transition to report.
+		setStrategyTransitionCondition(cpms, nil)
+
 		return r.reconcileMachineRollingUpdate(ctx, logger, cpms, machineProvider, machineInfos)
 	case machinev1.OnDelete:
 		return r.reconcileMachineOnDeleteUpdate(ctx, logger, cpms, machineProvider, machineInfos)
@@ -136,7 +156,116 @@ func (r *ControlPlaneMachineSetReconciler) reconcileMachineUpdates(ctx context.C
 //
 // In certain scenarios, there may be indexes with missing Machines. In these circumstances, the update should attempt
 // to create a new Machine to fulfil the requirement of that index.
+//
+// Replacement attempts that never joined the cluster, and were created from an outdated template, are removed
+// before any new Machine is created, rather than being left alongside another replacement for the same index.
 func (r *ControlPlaneMachineSetReconciler) reconcileMachineRollingUpdate(ctx context.Context, logger logr.Logger, cpms *machinev1.ControlPlaneMachineSet, machineProvider machineproviders.MachineProvider, indexedMachineInfos map[int32][]machineproviders.MachineInfo) (ctrl.Result, error) {
+	logger = logger.WithValues("updateStrategy", cpms.Spec.Strategy.Type)
+	sortedIndexedMachineInfos := sortedIndexes(indexedMachineInfos)
+
+	// Stale replacement attempts are removed before anything else, so that no further Machines are created for
+	// their indexes.
+	if handled, err := r.reconcileStaleReplacementAttempts(ctx, logger, machineProvider, indexedMachineInfos); err != nil || handled {
+		return ctrl.Result{}, err
+	}
+
+	// Missing and pending indexes take priority over updates, so that the Control Plane is fully populated with
+	// ready Machines before any existing Machine is replaced.
+	handled, err := r.reconcileMissingAndPendingIndexes(ctx, logger, cpms, machineProvider, indexedMachineInfos)
+	if err != nil || handled {
+		return ctrl.Result{}, err
+	}
+
+	var updateInProgress bool
+
+	indexesNeedingReplacement := []int32{}
+
+	for _, idx := range sortedIndexedMachineInfos {
+		outdatedMachines, updatedMachines := splitOutdatedMachines(indexedMachineInfos[idx])
+		if len(outdatedMachines) == 0 {
+			continue
+		}
+
+		outdatedMachine := outdatedMachines[0]
+
+		if len(updatedMachines) == 0 {
+			indexesNeedingReplacement = append(indexesNeedingReplacement, idx)
+			continue
+		}
+
+		// This index already has a replacement, so an update is in progress.
+		updateInProgress = true
+		replacementMachine := updatedMachines[0]
+		machineLogger := machineInfoLogger(logger, outdatedMachine)
+
+		switch {
+		case !replacementMachine.Ready:
+			machineLogger.V(2).Info(waitingForReplacement, "replacementName", replacementMachine.MachineRef.ObjectMeta.GetName())
+		case outdatedMachine.MachineRef.ObjectMeta.GetDeletionTimestamp() != nil:
+			machineLogger.V(2).Info(waitingForRemoved)
+		default:
+			if err := machineProvider.DeleteMachine(ctx, machineLogger, outdatedMachine.MachineRef); err != nil {
+				err := fmt.Errorf("error deleting Machine %s/%s: %w", outdatedMachine.MachineRef.ObjectMeta.GetNamespace(), outdatedMachine.MachineRef.ObjectMeta.GetName(), err)
+				machineLogger.Error(err, errorDeletingMachine)
+
+				return ctrl.Result{}, err
+			}
+
+			machineLogger.V(2).Info(removingOldMachine)
+		}
+	}
+
+	if updateInProgress {
+		return ctrl.Result{}, nil
+	}
+
+	if len(indexesNeedingReplacement) == 0 {
+		logger.V(4).Info(noUpdatesRequired)
+
+		return ctrl.Result{}, nil
+	}
+
+	notReadyIndexes, err := r.notReadyIndexesAtRolloutStart(ctx, indexedMachineInfos)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	held, err := r.holdForNotReadyMachines(ctx, logger, cpms, machineProvider, notReadyIndexes)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if held {
+		return ctrl.Result{RequeueAfter: notReadyMachineResyncPeriod}, nil
+	}
+
+	// Only a single replacement is created at a time to observe the surge semantics of the rolling update.
+	idx := indexesNeedingReplacement[0]
+	machineLogger := machineInfoLogger(logger, indexedMachineInfos[idx][0])
+
+	if notReadyMachine, ok := r.nextNotReadyReplacement(notReadyIndexes); ok {
+		idx = notReadyMachine.Index
+		machineLogger = machineInfoLogger(logger, notReadyMachine)
+		machineLogger.V(2).Info(replacingNotReadyMachine)
+	}
+
+	// The budget annotation has already been validated within reconcileMachines.
+	if budget, err := parseReplacementBudget(cpms.GetAnnotations()); err == nil && budget != nil {
+		now := time.Now()
+
+		if next := budget.nextReplacementTime(indexedMachineInfos, now); !next.IsZero() {
+			setReplacementBudgetExhaustedCondition(cpms, *budget, next)
+			setGatedByCondition(cpms, replacementBudgetGate(*budget, next))
+			machineLogger.V(2).Info(replacementBudgetExhausted, "nextReplacement", next.UTC().Format(time.RFC3339))
+
+			return ctrl.Result{RequeueAfter: next.Sub(now)}, nil
+		}
+	}
+
+	if err := createReplacementMachine(ctx, machineLogger, machineProvider, idx); err != nil {
+		return ctrl.Result{}, err
+	}
+
 	return ctrl.Result{}, nil
 }
 
@@ -146,9 +275,201 @@ func (r *ControlPlaneMachineSetReconciler) reconcileMachineRollingUpdate(ctx con
 //
 // For on-delete updates, a new Machine is required when a machine index has a Machine with a non-zero deletion
 // timestamp but does not yet have a replacement created.
+// When replacements are pre-created, a new Machine is also required when a machine index has a Machine which needs an
+// update, before it has been deleted. As with rolling updates, only a single replacement is pre-created at a time.
+// Otherwise, a replacement alongside an outdated Machine that has not been deleted was started by the RollingUpdate
+// strategy before the strategy was changed. Such replacements are completed, and reported within the
+// StrategyTransition condition.
 //
 // In certain scenarios, there may be indexes with missing Machines. In these circumstances, the update should attempt
 // to create a new Machine to fulfil the requirement of that index.
+//
+// As with rolling updates, stale replacement attempts are removed before any new Machine is created.
 func (r *ControlPlaneMachineSetReconciler) reconcileMachineOnDeleteUpdate(ctx context.Context, logger logr.Logger, cpms *machinev1.ControlPlaneMachineSet, machineProvider machineproviders.MachineProvider, indexedMachineInfos map[int32][]machineproviders.MachineInfo) (ctrl.Result, error) {
+	logger = logger.WithValues("updateStrategy", cpms.Spec.Strategy.Type)
+
+	if handled, err := r.reconcileStaleReplacementAttempts(ctx, logger, machineProvider, indexedMachineInfos); err != nil || handled {
+		return ctrl.Result{}, err
+	}
+
+	handled, err := r.reconcileMissingAndPendingIndexes(ctx, logger, cpms, machineProvider, indexedMachineInfos)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	preCreate := cpms.GetAnnotations()[preCreateReplacementsAnnotation] == "true"
+	surgeInProgress := preCreatedReplacementInProgress(indexedMachineInfos)
+	inFlightIndexes := []int32{}
+
+	for _, idx := range sortedIndexes(indexedMachineInfos) {
+		outdatedMachines, updatedMachines := splitOutdatedMachines(indexedMachineInfos[idx])
+		if len(outdatedMachines) == 0 {
+			continue
+		}
+
+		handled = true
+		outdatedMachine := outdatedMachines[0]
+		machineLogger := machineInfoLogger(logger, outdatedMachine)
+
+		switch {
+		case outdatedMachine.MachineRef.ObjectMeta.GetDeletionTimestamp() == nil && preCreate:
+			created, err := preCreateReplacementMachine(ctx, machineLogger, machineProvider, idx, updatedMachines, surgeInProgress)
+			if err != nil {
+				return ctrl.Result{}, err
+			}
+
+			surgeInProgress = surgeInProgress || created
+		case isInFlightReplacement(cpms, outdatedMachine, updatedMachines):
+			inFlightIndexes = append(inFlightIndexes, idx)
+
+			if err := completeInFlightReplacement(ctx, machineLogger, machineProvider, outdatedMachine, updatedMachines[0]); err != nil {
+				return ctrl.Result{}, err
+			}
+		case outdatedMachine.MachineRef.ObjectMeta.GetDeletionTimestamp() == nil:
+			machineLogger.V(2).Info(machineRequiresUpdate)
+		case len(updatedMachines) == 0:
+			// Each index is replaced independently as the user controls the rollout by deleting Machines.
+			if err := createReplacementMachine(ctx, machineLogger, machineProvider, idx); err != nil {
+				return ctrl.Result{}, err
+			}
+		case !updatedMachines[0].Ready:
+			machineLogger.V(2).Info(waitingForReplacement, "replacementName", updatedMachines[0].MachineRef.ObjectMeta.GetName())
+		default:
+			machineLogger.V(2).Info(waitingForRemoved)
+		}
+	}
+
+	setStrategyTransitionCondition(cpms, inFlightIndexes)
+
+	if !handled {
+		logger.V(4).Info(noUpdatesRequired)
+	}
+
 	return ctrl.Result{}, nil
 }
+
+// preCreateReplacementMachine handles an outdated Machine, that has not yet been deleted, when replacements are
+// pre-created with the OnDelete strategy. When the index has no replacement, and no other index has a pre-created
+// replacement in progress, a replacement is created. Otherwise, the user is informed of the state of the replacement.
+// It returns true when a replacement was created.
+func preCreateReplacementMachine(ctx context.Context, logger logr.Logger, machineProvider machineproviders.MachineProvider, idx int32, updatedMachines []machineproviders.MachineInfo, surgeInProgress bool) (bool, error) {
+	switch {
+	case len(updatedMachines) == 0 && surgeInProgress:
+		// Observe the surge semantics of the rolling update, only a single replacement is pre-created at a time.
+		logger.V(2).Info(machineRequiresUpdate)
+	case len(updatedMachines) == 0:
+		if err := createReplacementMachine(ctx, logger, machineProvider, idx); err != nil {
+			return false, err
+		}
+
+		return true, nil
+	case !updatedMachines[0].Ready:
+		logger.V(2).Info(waitingForReplacement, "replacementName", updatedMachines[0].MachineRef.ObjectMeta.GetName())
+	default:
+		logger.V(2).Info(replacementReadyForDeletion, "replacementName", updatedMachines[0].MachineRef.ObjectMeta.GetName())
+	}
+
+	return false, nil
+}
+
+// preCreatedReplacementInProgress determines whether any index has an outdated Machine, which has not yet been
+// deleted, alongside an updated replacement Machine.
+func preCreatedReplacementInProgress(indexedMachineInfos map[int32][]machineproviders.MachineInfo) bool {
+	for _, machineInfos := range indexedMachineInfos {
+		outdatedMachines, updatedMachines := splitOutdatedMachines(machineInfos)
+		if len(outdatedMachines) > 0 && len(updatedMachines) > 0 && outdatedMachines[0].MachineRef.ObjectMeta.GetDeletionTimestamp() == nil {
+			return true
+		}
+	}
+
+	return false
+}
+
+// reconcileMissingAndPendingIndexes creates a new Machine for any index without a Machine, and waits for indexes
+// whose only Machines are up to date but not yet ready.
+// It returns true when any such index was found.
+func (r *ControlPlaneMachineSetReconciler) reconcileMissingAndPendingIndexes(ctx context.Context, logger logr.Logger, cpms *machinev1.ControlPlaneMachineSet, machineProvider machineproviders.MachineProvider, indexedMachineInfos map[int32][]machineproviders.MachineInfo) (bool, error) {
+	var handled bool
+
+	for _, idx := range sortedIndexes(indexedMachineInfos) {
+		machineInfos := indexedMachineInfos[idx]
+
+		if len(machineInfos) == 0 {
+			handled = true
+
+			machineLogger := logger.WithValues("index", idx, "namespace", cpms.GetNamespace(), "name", unknownMachineName)
+			if err := createReplacementMachine(ctx, machineLogger, machineProvider, idx); err != nil {
+				return true, err
+			}
+
+			continue
+		}
+
+		outdatedMachines, updatedMachines := splitOutdatedMachines(machineInfos)
+		if len(outdatedMachines) > 0 || hasReadyMachine(updatedMachines) {
+			continue
+		}
+
+		handled = true
+
+		machineInfoLogger(logger, updatedMachines[0]).V(2).Info(waitingForReady)
+	}
+
+	return handled, nil
+}
+
+// createReplacementMachine uses the machine provider to create a new Machine for the index.
+// The logger is expected to already carry the details of the index and the Machine being replaced.
+func createReplacementMachine(ctx context.Context, logger logr.Logger, machineProvider machineproviders.MachineProvider, idx int32) error {
+	if err := machineProvider.CreateMachine(ctx, logger, idx); err != nil {
+		err := fmt.Errorf("error creating new Machine for index %d: %w", idx, err)
+		logger.Error(err, errorCreatingMachine)
+
+		return err
+	}
+
+	logger.V(2).Info(createdReplacement)
+
+	return nil
+}
+
+// splitOutdatedMachines splits the Machines within an index into those which need an update and those which are
+// up to date.
+func splitOutdatedMachines(machineInfos []machineproviders.MachineInfo) ([]machineproviders.MachineInfo, []machineproviders.MachineInfo) {
+	outdated := []machineproviders.MachineInfo{}
+	updated := []machineproviders.MachineInfo{}
+
+	for _, machineInfo := range machineInfos {
+		if machineInfo.NeedsUpdate {
+			outdated = append(outdated, machineInfo)
+		} else {
+			updated = append(updated, machineInfo)
+		}
+	}
+
+	return outdated, updated
+}
+
+// hasReadyMachine determines whether any of the Machines given are ready.
+func hasReadyMachine(machineInfos []machineproviders.MachineInfo) bool {
+	for _, machineInfo := range machineInfos {
+		if machineInfo.Ready {
+			return true
+		}
+	}
+
+	return false
+}
+
+// machineInfoLogger adds the index, namespace and name of the Machine to the logger.
+func machineInfoLogger(logger logr.Logger, machineInfo machineproviders.MachineInfo) logr.Logger {
+	name := unknownMachineName
+	namespace := ""
+
+	if machineInfo.MachineRef != nil {
+		name = machineInfo.MachineRef.ObjectMeta.GetName()
+		namespace = machineInfo.MachineRef.ObjectMeta.GetNamespace()
+	}
+
+	return logger.WithValues("index", machineInfo.Index, "namespace", namespace, "name", name)
+}
@@ -73,14 +73,20 @@ var _ = Describe("reconcileMachineUpdates", func() {
 	machineGVR := machinev1beta1.GroupVersion.WithResource("machines")
 	nodeGVR := corev1.SchemeGroupVersion.WithResource("nodes")
 
+	// machineNamespace is the namespace of the Machines within the tables.
+	// The tables are constructed before the test namespace is created, so a fixed namespace is used.
+	const machineNamespace = "openshift-machine-api"
+
 	healthyMachineBuilder := resourcebuilder.MachineInfo().
 		WithMachineGVR(machineGVR).
+		WithMachineNamespace(machineNamespace).
 		WithNodeGVR(nodeGVR).
 		WithReady(true).
 		WithNeedsUpdate(false)
 
 	pendingMachineBuilder := resourcebuilder.MachineInfo().
 		WithMachineGVR(machineGVR).
+		WithMachineNamespace(machineNamespace).
 		WithReady(false).
 		WithNeedsUpdate(false)
 
@@ -116,8 +122,8 @@ var _ = Describe("reconcileMachineUpdates", func() {
 			Expect(logger.Entries()).To(ConsistOf(in.expectedLogs))
 			Expect(in.cpms).To(Equal(originalCPMS), "The update functions should not modify the ControlPlaneMachineSet in any way")
 		},
-			PEntry("with no updates required", rollingUpdateTableInput{
-				cpms: cpmsBuilder.WithReplicas(3).Build(),
+			Entry("with no updates required", rollingUpdateTableInput{
+				cpms: resourcebuilder.ControlPlaneMachineSet().WithStrategyType(machinev1.RollingUpdate).WithReplicas(3).Build(),
 				machineInfos: map[int32][]machineproviders.MachineInfo{
 					0: {healthyMachineBuilder.WithIndex(0).WithMachineName("machine-0").WithNodeName("node-0").Build()},
 					1: {healthyMachineBuilder.WithIndex(1).WithMachineName("machine-1").WithNodeName("node-1").Build()},
@@ -137,15 +143,15 @@ var _ = Describe("reconcileMachineUpdates", func() {
 					},
 				},
 			}),
-			PEntry("with updates required in a single index", rollingUpdateTableInput{
-				cpms: cpmsBuilder.WithReplicas(3).Build(),
+			Entry("with updates required in a single index", rollingUpdateTableInput{
+				cpms: resourcebuilder.ControlPlaneMachineSet().WithStrategyType(machinev1.RollingUpdate).WithReplicas(3).Build(),
 				machineInfos: map[int32][]machineproviders.MachineInfo{
 					0: {healthyMachineBuilder.WithIndex(0).WithMachineName("machine-0").WithNodeName("node-0").Build()},
 					1: {healthyMachineBuilder.WithIndex(1).WithMachineName("machine-1").WithNodeName("node-1").WithNeedsUpdate(true).Build()},
 					2: {healthyMachineBuilder.WithIndex(2).WithMachineName("machine-2").WithNodeName("node-2").Build()},
 				},
 				setupMock: func() {
-					mockMachineProvider.EXPECT().CreateMachine(gomock.Any(), gomock.Any(), 1).Return(nil).Times(1)
+					mockMachineProvider.EXPECT().CreateMachine(gomock.Any(), gomock.Any(), int32(1)).Return(nil).Times(1)
 					mockMachineProvider.EXPECT().DeleteMachine(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
 				},
 				expectedLogs: []test.LogEntry{
@@ -153,16 +159,16 @@ var _ = Describe("reconcileMachineUpdates", func() {
 						Level: 2,
 						KeysAndValues: []interface{}{
 							"updateStrategy", machinev1.RollingUpdate,
-							"index", 1,
-							"namespace", namespaceName,
+							"index", int32(1),
+							"namespace", machineNamespace,
 							"name", "machine-1",
 						},
 						Message: createdReplacement,
 					},
 				},
 			}),
-			PEntry("with updates required in a single index, and an error occurs", rollingUpdateTableInput{
-				cpms:          cpmsBuilder.WithReplicas(3).Build(),
+			Entry("with updates required in a single index, and an error occurs", rollingUpdateTableInput{
+				cpms:          resourcebuilder.ControlPlaneMachineSet().WithStrategyType(machinev1.RollingUpdate).WithReplicas(3).Build(),
 				expectedError: fmt.Errorf("error creating new Machine for index %d: %w", 1, transientError),
 				machineInfos: map[int32][]machineproviders.MachineInfo{
 					0: {healthyMachineBuilder.WithIndex(0).WithMachineName("machine-0").WithNodeName("node-0").Build()},
@@ -170,7 +176,7 @@ var _ = Describe("reconcileMachineUpdates", func() {
 					2: {healthyMachineBuilder.WithIndex(2).WithMachineName("machine-2").WithNodeName("node-2").Build()},
 				},
 				setupMock: func() {
-					mockMachineProvider.EXPECT().CreateMachine(gomock.Any(), gomock.Any(), 1).Return(transientError).Times(1)
+					mockMachineProvider.EXPECT().CreateMachine(gomock.Any(), gomock.Any(), int32(1)).Return(transientError).Times(1)
 					mockMachineProvider.EXPECT().DeleteMachine(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
 				},
 				expectedLogs: []test.LogEntry{
@@ -178,16 +184,16 @@ var _ = Describe("reconcileMachineUpdates", func() {
 						Error: fmt.Errorf("error creating new Machine for index %d: %w", 1, transientError),
 						KeysAndValues: []interface{}{
 							"updateStrategy", machinev1.RollingUpdate,
-							"index", 1,
-							"namespace", namespaceName,
+							"index", int32(1),
+							"namespace", machineNamespace,
 							"name", "machine-1",
 						},
 						Message: errorCreatingMachine,
 					},
 				},
 			}),
-			PEntry("with updates required in a single index, but the replacement machine is pending", rollingUpdateTableInput{
-				cpms: cpmsBuilder.WithReplicas(3).Build(),
+			Entry("with updates required in a single index, but the replacement machine is pending", rollingUpdateTableInput{
+				cpms: resourcebuilder.ControlPlaneMachineSet().WithStrategyType(machinev1.RollingUpdate).WithReplicas(3).Build(),
 				machineInfos: map[int32][]machineproviders.MachineInfo{
 					0: {healthyMachineBuilder.WithIndex(0).WithMachineName("machine-0").WithNodeName("node-0").Build()},
 					1: {
@@ -205,8 +211,8 @@ var _ = Describe("reconcileMachineUpdates", func() {
 						Level: 2,
 						KeysAndValues: []interface{}{
 							"updateStrategy", machinev1.RollingUpdate,
-							"index", 1,
-							"namespace", namespaceName,
+							"index", int32(1),
+							"namespace", machineNamespace,
 							"name", "machine-1",
 							"replacementName", "machine-replacement-1",
 						},
@@ -214,8 +220,8 @@ var _ = Describe("reconcileMachineUpdates", func() {
 					},
 				},
 			}),
-			PEntry("with updates required in a single index, and the replacement machine is ready", rollingUpdateTableInput{
-				cpms: cpmsBuilder.WithReplicas(3).Build(),
+			Entry("with updates required in a single index, and the replacement machine is ready", rollingUpdateTableInput{
+				cpms: resourcebuilder.ControlPlaneMachineSet().WithStrategyType(machinev1.RollingUpdate).WithReplicas(3).Build(),
 				machineInfos: map[int32][]machineproviders.MachineInfo{
 					0: {healthyMachineBuilder.WithIndex(0).WithMachineName("machine-0").WithNodeName("node-0").Build()},
 					1: {
@@ -236,17 +242,17 @@ var _ = Describe("reconcileMachineUpdates", func() {
 						Level: 2,
 						KeysAndValues: []interface{}{
 							"updateStrategy", machinev1.RollingUpdate,
-							"index", 1,
-							"namespace", namespaceName,
+							"index", int32(1),
+							"namespace", machineNamespace,
 							"name", "machine-1",
 						},
 						Message: removingOldMachine,
 					},
 				},
 			}),
-			PEntry("with updates required in a single index, and the replacement machine is ready, and an error occurs", rollingUpdateTableInput{
-				cpms:          cpmsBuilder.WithReplicas(3).Build(),
-				expectedError: fmt.Errorf("error deleting Machine %s/%s: %w", namespaceName, "machine-1", transientError),
+			Entry("with updates required in a single index, and the replacement machine is ready, and an error occurs", rollingUpdateTableInput{
+				cpms:          resourcebuilder.ControlPlaneMachineSet().WithStrategyType(machinev1.RollingUpdate).WithReplicas(3).Build(),
+				expectedError: fmt.Errorf("error deleting Machine %s/%s: %w", machineNamespace, "machine-1", transientError),
 				machineInfos: map[int32][]machineproviders.MachineInfo{
 					0: {healthyMachineBuilder.WithIndex(0).WithMachineName("machine-0").WithNodeName("node-0").Build()},
 					1: {
@@ -264,19 +270,19 @@ var _ = Describe("reconcileMachineUpdates", func() {
 				},
 				expectedLogs: []test.LogEntry{
 					{
-						Error: fmt.Errorf("error deleting Machine %s/%s: %w", namespaceName, "machine-1", transientError),
+						Error: fmt.Errorf("error deleting Machine %s/%s: %w", machineNamespace, "machine-1", transientError),
 						KeysAndValues: []interface{}{
 							"updateStrategy", machinev1.RollingUpdate,
-							"index", 1,
-							"namespace", namespaceName,
+							"index", int32(1),
+							"namespace", machineNamespace,
 							"name", "machine-1",
 						},
 						Message: errorDeletingMachine,
 					},
 				},
 			}),
-			PEntry("with updates required in a single index, and the replacement machine is ready, and the old machine is already deleted", rollingUpdateTableInput{
-				cpms: cpmsBuilder.WithReplicas(3).Build(),
+			Entry("with updates required in a single index, and the replacement machine is ready, and the old machine is already deleted", rollingUpdateTableInput{
+				cpms: resourcebuilder.ControlPlaneMachineSet().WithStrategyType(machinev1.RollingUpdate).WithReplicas(3).Build(),
 				machineInfos: map[int32][]machineproviders.MachineInfo{
 					0: {healthyMachineBuilder.WithIndex(0).WithMachineName("machine-0").WithNodeName("node-0").Build()},
 					1: {
@@ -294,16 +300,16 @@ var _ = Describe("reconcileMachineUpdates", func() {
 						Level: 2,
 						KeysAndValues: []interface{}{
 							"updateStrategy", machinev1.RollingUpdate,
-							"index", 1,
-							"namespace", namespaceName,
+							"index", int32(1),
+							"namespace", machineNamespace,
 							"name", "machine-1",
 						},
 						Message: waitingForRemoved,
 					},
 				},
 			}),
-			PEntry("with updates are required in multiple indexes", rollingUpdateTableInput{
-				cpms: cpmsBuilder.WithReplicas(3).Build(),
+			Entry("with updates are required in multiple indexes", rollingUpdateTableInput{
+				cpms: resourcebuilder.ControlPlaneMachineSet().WithStrategyType(machinev1.RollingUpdate).WithReplicas(3).Build(),
 				machineInfos: map[int32][]machineproviders.MachineInfo{
 					0: {healthyMachineBuilder.WithIndex(0).WithMachineName("machine-0").WithNodeName("node-0").WithNeedsUpdate(true).Build()},
 					1: {healthyMachineBuilder.WithIndex(1).WithMachineName("machine-1").WithNodeName("node-1").WithNeedsUpdate(true).Build()},
@@ -311,7 +317,7 @@ var _ = Describe("reconcileMachineUpdates", func() {
 				},
 				setupMock: func() {
 					// Note, in this case it should only create a single machine.
-					mockMachineProvider.EXPECT().CreateMachine(gomock.Any(), gomock.Any(), 0).Return(nil).Times(1)
+					mockMachineProvider.EXPECT().CreateMachine(gomock.Any(), gomock.Any(), int32(0)).Return(nil).Times(1)
 					mockMachineProvider.EXPECT().DeleteMachine(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
 				},
 				expectedLogs: []test.LogEntry{
@@ -319,16 +325,16 @@ var _ = Describe("reconcileMachineUpdates", func() {
 						Level: 2,
 						KeysAndValues: []interface{}{
 							"updateStrategy", machinev1.RollingUpdate,
-							"index", 0,
-							"namespace", namespaceName,
+							"index", int32(0),
+							"namespace", machineNamespace,
 							"name", "machine-0",
 						},
 						Message: createdReplacement,
 					},
 				},
 			}),
-			PEntry("with updates are required in multiple indexes, but the replacement machine is pending", rollingUpdateTableInput{
-				cpms: cpmsBuilder.WithReplicas(3).Build(),
+			Entry("with updates are required in multiple indexes, but the replacement machine is pending", rollingUpdateTableInput{
+				cpms: resourcebuilder.ControlPlaneMachineSet().WithStrategyType(machinev1.RollingUpdate).WithReplicas(3).Build(),
 				machineInfos: map[int32][]machineproviders.MachineInfo{
 					0: {
 						healthyMachineBuilder.WithIndex(0).WithMachineName("machine-0").WithNodeName("node-0").WithNeedsUpdate(true).Build(),
@@ -347,8 +353,8 @@ var _ = Describe("reconcileMachineUpdates", func() {
 						Level: 2,
 						KeysAndValues: []interface{}{
 							"updateStrategy", machinev1.RollingUpdate,
-							"index", 0,
-							"namespace", namespaceName,
+							"index", int32(0),
+							"namespace", machineNamespace,
 							"name", "machine-0",
 							"replacementName", "machine-replacement-0",
 						},
@@ -356,8 +362,8 @@ var _ = Describe("reconcileMachineUpdates", func() {
 					},
 				},
 			}),
-			PEntry("with updates are required in multiple indexes, and the replacement machine is ready", rollingUpdateTableInput{
-				cpms: cpmsBuilder.WithReplicas(3).Build(),
+			Entry("with updates are required in multiple indexes, and the replacement machine is ready", rollingUpdateTableInput{
+				cpms: resourcebuilder.ControlPlaneMachineSet().WithStrategyType(machinev1.RollingUpdate).WithReplicas(3).Build(),
 				machineInfos: map[int32][]machineproviders.MachineInfo{
 					0: {
 						healthyMachineBuilder.WithIndex(0).WithMachineName("machine-0").WithNodeName("node-0").WithNeedsUpdate(true).Build(),
@@ -371,7 +377,7 @@ var _ = Describe("reconcileMachineUpdates", func() {
 					mockMachineProvider.EXPECT().CreateMachine(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
 
 					// We expect this particular machine to be called for deletion.
-					machineInfo := healthyMachineBuilder.WithIndex(1).WithMachineName("machine-1").WithNodeName("node-1").WithNeedsUpdate(true).Build()
+					machineInfo := healthyMachineBuilder.WithIndex(0).WithMachineName("machine-0").WithNodeName("node-0").WithNeedsUpdate(true).Build()
 					mockMachineProvider.EXPECT().DeleteMachine(gomock.Any(), gomock.Any(), machineInfo.MachineRef).Return(nil).Times(1)
 				},
 
@@ -380,16 +386,16 @@ var _ = Describe("reconcileMachineUpdates", func() {
 						Level: 2,
 						KeysAndValues: []interface{}{
 							"updateStrategy", machinev1.RollingUpdate,
-							"index", 0,
-							"namespace", namespaceName,
+							"index", int32(0),
+							"namespace", machineNamespace,
 							"name", "machine-0",
 						},
 						Message: removingOldMachine,
 					},
 				},
 			}),
-			PEntry("with updates required in multiple indexes, and the replacement machine is ready, and the old machine is already deleted", rollingUpdateTableInput{
-				cpms: cpmsBuilder.WithReplicas(3).Build(),
+			Entry("with updates required in multiple indexes, and the replacement machine is ready, and the old machine is already deleted", rollingUpdateTableInput{
+				cpms: resourcebuilder.ControlPlaneMachineSet().WithStrategyType(machinev1.RollingUpdate).WithReplicas(3).Build(),
 				machineInfos: map[int32][]machineproviders.MachineInfo{
 					0: {
 						healthyMachineBuilder.WithIndex(0).WithMachineName("machine-0").WithNodeName("node-0").WithNeedsUpdate(true).WithMachineDeletionTimestamp(metav1.Now()).Build(),
@@ -407,23 +413,23 @@ var _ = Describe("reconcileMachineUpdates", func() {
 						Level: 2,
 						KeysAndValues: []interface{}{
 							"updateStrategy", machinev1.RollingUpdate,
-							"index", 0,
-							"namespace", namespaceName,
+							"index", int32(0),
+							"namespace", machineNamespace,
 							"name", "machine-0",
 						},
 						Message: waitingForRemoved,
 					},
 				},
 			}),
-			PEntry("with an empty index", rollingUpdateTableInput{
-				cpms: cpmsBuilder.WithReplicas(3).Build(),
+			Entry("with an empty index", rollingUpdateTableInput{
+				cpms: resourcebuilder.ControlPlaneMachineSet().WithStrategyType(machinev1.RollingUpdate).WithReplicas(3).Build(),
 				machineInfos: map[int32][]machineproviders.MachineInfo{
 					0: {healthyMachineBuilder.WithIndex(0).WithMachineName("machine-0").WithNodeName("node-0").Build()},
 					1: {healthyMachineBuilder.WithIndex(1).WithMachineName("machine-1").WithNodeName("node-1").Build()},
 					2: {},
 				},
 				setupMock: func() {
-					mockMachineProvider.EXPECT().CreateMachine(gomock.Any(), gomock.Any(), 2).Return(nil).Times(1)
+					mockMachineProvider.EXPECT().CreateMachine(gomock.Any(), gomock.Any(), int32(2)).Return(nil).Times(1)
 					mockMachineProvider.EXPECT().DeleteMachine(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
 				},
 				expectedLogs: []test.LogEntry{
@@ -431,16 +437,16 @@ var _ = Describe("reconcileMachineUpdates", func() {
 						Level: 2,
 						KeysAndValues: []interface{}{
 							"updateStrategy", machinev1.RollingUpdate,
-							"index", 2,
-							"namespace", namespaceName,
+							"index", int32(2),
+							"namespace", machineNamespace,
 							"name", "<Unknown>",
 						},
 						Message: createdReplacement,
 					},
 				},
 			}),
-			PEntry("with a pending machine in an index", rollingUpdateTableInput{
-				cpms: cpmsBuilder.WithReplicas(3).Build(),
+			Entry("with a pending machine in an index", rollingUpdateTableInput{
+				cpms: resourcebuilder.ControlPlaneMachineSet().WithStrategyType(machinev1.RollingUpdate).WithReplicas(3).Build(),
 				machineInfos: map[int32][]machineproviders.MachineInfo{
 					0: {healthyMachineBuilder.WithIndex(0).WithMachineName("machine-0").WithNodeName("node-0").Build()},
 					1: {healthyMachineBuilder.WithIndex(1).WithMachineName("machine-1").WithNodeName("node-1").Build()},
@@ -455,16 +461,16 @@ var _ = Describe("reconcileMachineUpdates", func() {
 						Level: 2,
 						KeysAndValues: []interface{}{
 							"updateStrategy", machinev1.RollingUpdate,
-							"index", 2,
-							"namespace", namespaceName,
+							"index", int32(2),
+							"namespace", machineNamespace,
 							"name", "machine-replacement-2",
 						},
 						Message: waitingForReady,
 					},
 				},
 			}),
-			PEntry("with a missing index, and other indexes needing updates", rollingUpdateTableInput{
-				cpms: cpmsBuilder.WithReplicas(3).Build(),
+			Entry("with a missing index, and other indexes needing updates", rollingUpdateTableInput{
+				cpms: resourcebuilder.ControlPlaneMachineSet().WithStrategyType(machinev1.RollingUpdate).WithReplicas(3).Build(),
 				machineInfos: map[int32][]machineproviders.MachineInfo{
 					0: {healthyMachineBuilder.WithIndex(0).WithMachineName("machine-0").WithNodeName("node-0").Build()},
 					1: {healthyMachineBuilder.WithIndex(1).WithMachineName("machine-1").WithNodeName("node-1").WithNeedsUpdate(true).Build()},
@@ -472,7 +478,7 @@ var _ = Describe("reconcileMachineUpdates", func() {
 				},
 				setupMock: func() {
 					// The missing index should take priority over the index in need of an update.
-					mockMachineProvider.EXPECT().CreateMachine(gomock.Any(), gomock.Any(), 2).Return(nil).Times(1)
+					mockMachineProvider.EXPECT().CreateMachine(gomock.Any(), gomock.Any(), int32(2)).Return(nil).Times(1)
 					mockMachineProvider.EXPECT().DeleteMachine(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
 				},
 				expectedLogs: []test.LogEntry{
@@ -480,16 +486,16 @@ var _ = Describe("reconcileMachineUpdates", func() {
 						Level: 2,
 						KeysAndValues: []interface{}{
 							"updateStrategy", machinev1.RollingUpdate,
-							"index", 2,
-							"namespace", namespaceName,
+							"index", int32(2),
+							"namespace", machineNamespace,
 							"name", "<Unknown>",
 						},
 						Message: createdReplacement,
 					},
 				},
 			}),
-			PEntry("with a pending machine in an index, and other indexes needing updates", rollingUpdateTableInput{
-				cpms: cpmsBuilder.WithReplicas(3).Build(),
+			Entry("with a pending machine in an index, and other indexes needing updates", rollingUpdateTableInput{
+				cpms: resourcebuilder.ControlPlaneMachineSet().WithStrategyType(machinev1.RollingUpdate).WithReplicas(3).Build(),
 				machineInfos: map[int32][]machineproviders.MachineInfo{
 					0: {healthyMachineBuilder.WithIndex(0).WithMachineName("machine-0").WithNodeName("node-0").Build()},
 					1: {healthyMachineBuilder.WithIndex(1).WithMachineName("machine-1").WithNodeName("node-1").WithNeedsUpdate(true).Build()},
@@ -504,8 +510,8 @@ var _ = Describe("reconcileMachineUpdates", func() {
 						Level: 2,
 						KeysAndValues: []interface{}{
 							"updateStrategy", machinev1.RollingUpdate,
-							"index", 2,
-							"namespace", namespaceName,
+							"index", int32(2),
+							"namespace", machineNamespace,
 							"name", "machine-replacement-2",
 						},
 						Message: waitingForReady,
@@ -517,7 +523,7 @@ var _ = Describe("reconcileMachineUpdates", func() {
 
 	Context("When the update strategy is OnDelete", func() {
 		BeforeEach(func() {
-			cpmsBuilder = cpmsBuilder.WithStrategyType(machinev1.RollingUpdate)
+			cpmsBuilder = cpmsBuilder.WithStrategyType(machinev1.OnDelete)
 		})
 
 		type onDeleteUpdateTableInput struct {
@@ -546,8 +552,8 @@ var _ = Describe("reconcileMachineUpdates", func() {
 			Expect(logger.Entries()).To(ConsistOf(in.expectedLogs))
 			Expect(in.cpms).To(Equal(originalCPMS), "The update functions should not modify the ControlPlaneMachineSet in any way")
 		},
-			PEntry("with no updates required", onDeleteUpdateTableInput{
-				cpms: cpmsBuilder.WithReplicas(3).Build(),
+			Entry("with no updates required", onDeleteUpdateTableInput{
+				cpms: resourcebuilder.ControlPlaneMachineSet().WithStrategyType(machinev1.OnDelete).WithReplicas(3).Build(),
 				machineInfos: map[int32][]machineproviders.MachineInfo{
 					0: {healthyMachineBuilder.WithIndex(0).WithMachineName("machine-0").WithNodeName("node-0").Build()},
 					1: {healthyMachineBuilder.WithIndex(1).WithMachineName("machine-1").WithNodeName("node-1").Build()},
@@ -567,8 +573,8 @@ var _ = Describe("reconcileMachineUpdates", func() {
 					},
 				},
 			}),
-			PEntry("with updates required in a single index, and the machine is not yet deleted", onDeleteUpdateTableInput{
-				cpms: cpmsBuilder.WithReplicas(3).Build(),
+			Entry("with updates required in a single index, and the machine is not yet deleted", onDeleteUpdateTableInput{
+				cpms: resourcebuilder.ControlPlaneMachineSet().WithStrategyType(machinev1.OnDelete).WithReplicas(3).Build(),
 				machineInfos: map[int32][]machineproviders.MachineInfo{
 					0: {healthyMachineBuilder.WithIndex(0).WithMachineName("machine-0").WithNodeName("node-0").Build()},
 					1: {healthyMachineBuilder.WithIndex(1).WithMachineName("machine-1").WithNodeName("node-1").WithNeedsUpdate(true).Build()},
@@ -583,23 +589,23 @@ var _ = Describe("reconcileMachineUpdates", func() {
 						Level: 2,
 						KeysAndValues: []interface{}{
 							"updateStrategy", machinev1.OnDelete,
-							"index", 1,
-							"namespace", namespaceName,
+							"index", int32(1),
+							"namespace", machineNamespace,
 							"name", "machine-1",
 						},
 						Message: machineRequiresUpdate,
 					},
 				},
 			}),
-			PEntry("with updates required in a single index, and the machine has been deleted", onDeleteUpdateTableInput{
-				cpms: cpmsBuilder.WithReplicas(3).Build(),
+			Entry("with updates required in a single index, and the machine has been deleted", onDeleteUpdateTableInput{
+				cpms: resourcebuilder.ControlPlaneMachineSet().WithStrategyType(machinev1.OnDelete).WithReplicas(3).Build(),
 				machineInfos: map[int32][]machineproviders.MachineInfo{
 					0: {healthyMachineBuilder.WithIndex(0).WithMachineName("machine-0").WithNodeName("node-0").Build()},
 					1: {healthyMachineBuilder.WithIndex(1).WithMachineName("machine-1").WithNodeName("node-1").WithNeedsUpdate(true).WithMachineDeletionTimestamp(metav1.Now()).Build()},
 					2: {healthyMachineBuilder.WithIndex(2).WithMachineName("machine-2").WithNodeName("node-2").Build()},
 				},
 				setupMock: func() {
-					mockMachineProvider.EXPECT().CreateMachine(gomock.Any(), gomock.Any(), 1).Return(nil).Times(0)
+					mockMachineProvider.EXPECT().CreateMachine(gomock.Any(), gomock.Any(), int32(1)).Return(nil).Times(1)
 					mockMachineProvider.EXPECT().DeleteMachine(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
 				},
 				expectedLogs: []test.LogEntry{
@@ -607,23 +613,23 @@ var _ = Describe("reconcileMachineUpdates", func() {
 						Level: 2,
 						KeysAndValues: []interface{}{
 							"updateStrategy", machinev1.OnDelete,
-							"index", 1,
-							"namespace", namespaceName,
+							"index", int32(1),
+							"namespace", machineNamespace,
 							"name", "machine-1",
 						},
 						Message: createdReplacement,
 					},
 				},
 			}),
-			PEntry("with updates required in a single index, and the machine has been deleted, and an error occurrs", onDeleteUpdateTableInput{
-				cpms: cpmsBuilder.WithReplicas(3).Build(), expectedError: fmt.Errorf("error creating new Machine for index %d: %w", 1, transientError),
+			Entry("with updates required in a single index, and the machine has been deleted, and an error occurrs", onDeleteUpdateTableInput{
+				cpms: resourcebuilder.ControlPlaneMachineSet().WithStrategyType(machinev1.OnDelete).WithReplicas(3).Build(), expectedError: fmt.Errorf("error creating new Machine for index %d: %w", 1, transientError),
 				machineInfos: map[int32][]machineproviders.MachineInfo{
 					0: {healthyMachineBuilder.WithIndex(0).WithMachineName("machine-0").WithNodeName("node-0").Build()},
 					1: {healthyMachineBuilder.WithIndex(1).WithMachineName("machine-1").WithNodeName("node-1").WithNeedsUpdate(true).WithMachineDeletionTimestamp(metav1.Now()).Build()},
 					2: {healthyMachineBuilder.WithIndex(2).WithMachineName("machine-2").WithNodeName("node-2").Build()},
 				},
 				setupMock: func() {
-					mockMachineProvider.EXPECT().CreateMachine(gomock.Any(), gomock.Any(), 1).Return(nil).Times(0)
+					mockMachineProvider.EXPECT().CreateMachine(gomock.Any(), gomock.Any(), int32(1)).Return(transientError).Times(1)
 					mockMachineProvider.EXPECT().DeleteMachine(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
 				},
 				expectedLogs: []test.LogEntry{
@@ -631,16 +637,16 @@ var _ = Describe("reconcileMachineUpdates", func() {
 						Error: fmt.Errorf("error creating new Machine for index %d: %w", 1, transientError),
 						KeysAndValues: []interface{}{
 							"updateStrategy", machinev1.OnDelete,
-							"index", 1,
-							"namespace", namespaceName,
+							"index", int32(1),
+							"namespace", machineNamespace,
 							"name", "machine-1",
 						},
 						Message: errorCreatingMachine,
 					},
 				},
 			}),
-			PEntry("with updates required in a single index, and replacement machine is pending", onDeleteUpdateTableInput{
-				cpms: cpmsBuilder.WithReplicas(3).Build(),
+			Entry("with updates required in a single index, and replacement machine is pending", onDeleteUpdateTableInput{
+				cpms: resourcebuilder.ControlPlaneMachineSet().WithStrategyType(machinev1.OnDelete).WithReplicas(3).Build(),
 				machineInfos: map[int32][]machineproviders.MachineInfo{
 					0: {healthyMachineBuilder.WithIndex(0).WithMachineName("machine-0").WithNodeName("node-0").Build()},
 					1: {
@@ -650,7 +656,7 @@ var _ = Describe("reconcileMachineUpdates", func() {
 					2: {healthyMachineBuilder.WithIndex(2).WithMachineName("machine-2").WithNodeName("node-2").Build()},
 				},
 				setupMock: func() {
-					mockMachineProvider.EXPECT().CreateMachine(gomock.Any(), gomock.Any(), 0).Times(0)
+					mockMachineProvider.EXPECT().CreateMachine(gomock.Any(), gomock.Any(), int32(0)).Times(0)
 					mockMachineProvider.EXPECT().DeleteMachine(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
 				},
 				expectedLogs: []test.LogEntry{
@@ -658,8 +664,8 @@ var _ = Describe("reconcileMachineUpdates", func() {
 						Level: 2,
 						KeysAndValues: []interface{}{
 							"updateStrategy", machinev1.OnDelete,
-							"index", 1,
-							"namespace", namespaceName,
+							"index", int32(1),
+							"namespace", machineNamespace,
 							"name", "machine-1",
 							"replacementName", "machine-replacement-1",
 						},
@@ -667,8 +673,8 @@ var _ = Describe("reconcileMachineUpdates", func() {
 					},
 				},
 			}),
-			PEntry("with updates required in a single index, and replacement machine is ready", onDeleteUpdateTableInput{
-				cpms: cpmsBuilder.WithReplicas(3).Build(),
+			Entry("with updates required in a single index, and replacement machine is ready", onDeleteUpdateTableInput{
+				cpms: resourcebuilder.ControlPlaneMachineSet().WithStrategyType(machinev1.OnDelete).WithReplicas(3).Build(),
 				machineInfos: map[int32][]machineproviders.MachineInfo{
 					0: {healthyMachineBuilder.WithIndex(0).WithMachineName("machine-0").WithNodeName("node-0").Build()},
 					1: {
@@ -678,7 +684,7 @@ var _ = Describe("reconcileMachineUpdates", func() {
 					2: {healthyMachineBuilder.WithIndex(2).WithMachineName("machine-2").WithNodeName("node-2").Build()},
 				},
 				setupMock: func() {
-					mockMachineProvider.EXPECT().CreateMachine(gomock.Any(), gomock.Any(), 0).Times(0)
+					mockMachineProvider.EXPECT().CreateMachine(gomock.Any(), gomock.Any(), int32(0)).Times(0)
 					mockMachineProvider.EXPECT().DeleteMachine(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
 				},
 				expectedLogs: []test.LogEntry{
@@ -686,16 +692,16 @@ var _ = Describe("reconcileMachineUpdates", func() {
 						Level: 2,
 						KeysAndValues: []interface{}{
 							"updateStrategy", machinev1.OnDelete,
-							"index", 1,
-							"namespace", namespaceName,
+							"index", int32(1),
+							"namespace", machineNamespace,
 							"name", "machine-1",
 						},
 						Message: waitingForRemoved,
 					},
 				},
 			}),
-			PEntry("with updates required in multiple indexes, and the machines are not yet deleted", onDeleteUpdateTableInput{
-				cpms: cpmsBuilder.WithReplicas(3).Build(),
+			Entry("with updates required in multiple indexes, and the machines are not yet deleted", onDeleteUpdateTableInput{
+				cpms: resourcebuilder.ControlPlaneMachineSet().WithStrategyType(machinev1.OnDelete).WithReplicas(3).Build(),
 				machineInfos: map[int32][]machineproviders.MachineInfo{
 					0: {healthyMachineBuilder.WithIndex(0).WithMachineName("machine-0").WithNodeName("node-0").WithNeedsUpdate(true).Build()},
 					1: {healthyMachineBuilder.WithIndex(1).WithMachineName("machine-1").WithNodeName("node-1").WithNeedsUpdate(true).Build()},
@@ -710,8 +716,8 @@ var _ = Describe("reconcileMachineUpdates", func() {
 						Level: 2,
 						KeysAndValues: []interface{}{
 							"updateStrategy", machinev1.OnDelete,
-							"index", 0,
-							"namespace", namespaceName,
+							"index", int32(0),
+							"namespace", machineNamespace,
 							"name", "machine-0",
 						},
 						Message: machineRequiresUpdate,
@@ -720,23 +726,23 @@ var _ = Describe("reconcileMachineUpdates", func() {
 						Level: 2,
 						KeysAndValues: []interface{}{
 							"updateStrategy", machinev1.OnDelete,
-							"index", 1,
-							"namespace", namespaceName,
+							"index", int32(1),
+							"namespace", machineNamespace,
 							"name", "machine-1",
 						},
 						Message: machineRequiresUpdate,
 					},
 				},
 			}),
-			PEntry("with updates required in a multiple indexes, and machine has been deleted, and an error occurrs", onDeleteUpdateTableInput{
-				cpms: cpmsBuilder.WithReplicas(3).Build(), expectedError: fmt.Errorf("error creating new Machine for index %d: %w", 1, transientError),
+			Entry("with updates required in a multiple indexes, and machine has been deleted, and an error occurrs", onDeleteUpdateTableInput{
+				cpms: resourcebuilder.ControlPlaneMachineSet().WithStrategyType(machinev1.OnDelete).WithReplicas(3).Build(), expectedError: fmt.Errorf("error creating new Machine for index %d: %w", 1, transientError),
 				machineInfos: map[int32][]machineproviders.MachineInfo{
 					0: {healthyMachineBuilder.WithIndex(0).WithMachineName("machine-0").WithNodeName("node-0").WithNeedsUpdate(true).Build()},
 					1: {healthyMachineBuilder.WithIndex(1).WithMachineName("machine-1").WithNodeName("node-1").WithNeedsUpdate(true).WithMachineDeletionTimestamp(metav1.Now()).Build()},
 					2: {healthyMachineBuilder.WithIndex(2).WithMachineName("machine-2").WithNodeName("node-2").Build()},
 				},
 				setupMock: func() {
-					mockMachineProvider.EXPECT().CreateMachine(gomock.Any(), gomock.Any(), 1).Return(nil).Times(0)
+					mockMachineProvider.EXPECT().CreateMachine(gomock.Any(), gomock.Any(), int32(1)).Return(transientError).Times(1)
 					mockMachineProvider.EXPECT().DeleteMachine(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
 				},
 				expectedLogs: []test.LogEntry{
@@ -744,8 +750,8 @@ var _ = Describe("reconcileMachineUpdates", func() {
 						Level: 2,
 						KeysAndValues: []interface{}{
 							"updateStrategy", machinev1.OnDelete,
-							"index", 0,
-							"namespace", namespaceName,
+							"index", int32(0),
+							"namespace", machineNamespace,
 							"name", "machine-0",
 						},
 						Message: machineRequiresUpdate,
@@ -754,23 +760,23 @@ var _ = Describe("reconcileMachineUpdates", func() {
 						Error: fmt.Errorf("error creating new Machine for index %d: %w", 1, transientError),
 						KeysAndValues: []interface{}{
 							"updateStrategy", machinev1.OnDelete,
-							"index", 1,
-							"namespace", namespaceName,
+							"index", int32(1),
+							"namespace", machineNamespace,
 							"name", "machine-1",
 						},
 						Message: errorCreatingMachine,
 					},
 				},
 			}),
-			PEntry("with updates required in multiple indexes, and a machine has been deleted", onDeleteUpdateTableInput{
-				cpms: cpmsBuilder.WithReplicas(3).Build(),
+			Entry("with updates required in multiple indexes, and a machine has been deleted", onDeleteUpdateTableInput{
+				cpms: resourcebuilder.ControlPlaneMachineSet().WithStrategyType(machinev1.OnDelete).WithReplicas(3).Build(),
 				machineInfos: map[int32][]machineproviders.MachineInfo{
 					0: {healthyMachineBuilder.WithIndex(0).WithMachineName("machine-0").WithNodeName("node-0").WithNeedsUpdate(true).Build()},
 					1: {healthyMachineBuilder.WithIndex(1).WithMachineName("machine-1").WithNodeName("node-1").WithNeedsUpdate(true).WithMachineDeletionTimestamp(metav1.Now()).Build()},
 					2: {healthyMachineBuilder.WithIndex(2).WithMachineName("machine-2").WithNodeName("node-2").Build()},
 				},
 				setupMock: func() {
-					mockMachineProvider.EXPECT().CreateMachine(gomock.Any(), gomock.Any(), 1).Return(nil).Times(0)
+					mockMachineProvider.EXPECT().CreateMachine(gomock.Any(), gomock.Any(), int32(1)).Return(nil).Times(1)
 					mockMachineProvider.EXPECT().DeleteMachine(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
 				},
 				expectedLogs: []test.LogEntry{
@@ -778,8 +784,8 @@ var _ = Describe("reconcileMachineUpdates", func() {
 						Level: 2,
 						KeysAndValues: []interface{}{
 							"updateStrategy", machinev1.OnDelete,
-							"index", 0,
-							"namespace", namespaceName,
+							"index", int32(0),
+							"namespace", machineNamespace,
 							"name", "machine-0",
 						},
 						Message: machineRequiresUpdate,
@@ -788,24 +794,24 @@ var _ = Describe("reconcileMachineUpdates", func() {
 						Level: 2,
 						KeysAndValues: []interface{}{
 							"updateStrategy", machinev1.OnDelete,
-							"index", 1,
-							"namespace", namespaceName,
+							"index", int32(1),
+							"namespace", machineNamespace,
 							"name", "machine-1",
 						},
 						Message: createdReplacement,
 					},
 				},
 			}),
-			PEntry("with updates required in multiple indexes, and multiple machines have been deleted", onDeleteUpdateTableInput{
-				cpms: cpmsBuilder.WithReplicas(3).Build(),
+			Entry("with updates required in multiple indexes, and multiple machines have been deleted", onDeleteUpdateTableInput{
+				cpms: resourcebuilder.ControlPlaneMachineSet().WithStrategyType(machinev1.OnDelete).WithReplicas(3).Build(),
 				machineInfos: map[int32][]machineproviders.MachineInfo{
 					0: {healthyMachineBuilder.WithIndex(0).WithMachineName("machine-0").WithNodeName("node-0").WithNeedsUpdate(true).WithMachineDeletionTimestamp(metav1.Now()).Build()},
 					1: {healthyMachineBuilder.WithIndex(1).WithMachineName("machine-1").WithNodeName("node-1").WithNeedsUpdate(true).WithMachineDeletionTimestamp(metav1.Now()).Build()},
 					2: {healthyMachineBuilder.WithIndex(2).WithMachineName("machine-2").WithNodeName("node-2").Build()},
 				},
 				setupMock: func() {
-					mockMachineProvider.EXPECT().CreateMachine(gomock.Any(), gomock.Any(), 0).Return(nil).Times(0)
-					mockMachineProvider.EXPECT().CreateMachine(gomock.Any(), gomock.Any(), 1).Return(nil).Times(0)
+					mockMachineProvider.EXPECT().CreateMachine(gomock.Any(), gomock.Any(), int32(0)).Return(nil).Times(1)
+					mockMachineProvider.EXPECT().CreateMachine(gomock.Any(), gomock.Any(), int32(1)).Return(nil).Times(1)
 					mockMachineProvider.EXPECT().DeleteMachine(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
 				},
 				expectedLogs: []test.LogEntry{
@@ -813,8 +819,8 @@ var _ = Describe("reconcileMachineUpdates", func() {
 						Level: 2,
 						KeysAndValues: []interface{}{
 							"updateStrategy", machinev1.OnDelete,
-							"index", 0,
-							"namespace", namespaceName,
+							"index", int32(0),
+							"namespace", machineNamespace,
 							"name", "machine-0",
 						},
 						Message: createdReplacement,
@@ -823,16 +829,16 @@ var _ = Describe("reconcileMachineUpdates", func() {
 						Level: 2,
 						KeysAndValues: []interface{}{
 							"updateStrategy", machinev1.OnDelete,
-							"index", 1,
-							"namespace", namespaceName,
+							"index", int32(1),
+							"namespace", machineNamespace,
 							"name", "machine-1",
 						},
 						Message: createdReplacement,
 					},
 				},
 			}),
-			PEntry("with updates required in multiple indexes, and a single machine has been deleted, and the replacement machine is pending", onDeleteUpdateTableInput{
-				cpms: cpmsBuilder.WithReplicas(3).Build(),
+			Entry("with updates required in multiple indexes, and a single machine has been deleted, and the replacement machine is pending", onDeleteUpdateTableInput{
+				cpms: resourcebuilder.ControlPlaneMachineSet().WithStrategyType(machinev1.OnDelete).WithReplicas(3).Build(),
 				machineInfos: map[int32][]machineproviders.MachineInfo{
 					0: {healthyMachineBuilder.WithIndex(0).WithMachineName("machine-0").WithNodeName("node-0").WithNeedsUpdate(true).Build()},
 					1: {
@@ -850,8 +856,8 @@ var _ = Describe("reconcileMachineUpdates", func() {
 						Level: 2,
 						KeysAndValues: []interface{}{
 							"updateStrategy", machinev1.OnDelete,
-							"index", 0,
-							"namespace", namespaceName,
+							"index", int32(0),
+							"namespace", machineNamespace,
 							"name", "machine-0",
 						},
 						Message: machineRequiresUpdate,
@@ -860,8 +866,8 @@ var _ = Describe("reconcileMachineUpdates", func() {
 						Level: 2,
 						KeysAndValues: []interface{}{
 							"updateStrategy", machinev1.OnDelete,
-							"index", 1,
-							"namespace", namespaceName,
+							"index", int32(1),
+							"namespace", machineNamespace,
 							"name", "machine-1",
 							"replacementName", "machine-replacement-1",
 						},
@@ -869,8 +875,8 @@ var _ = Describe("reconcileMachineUpdates", func() {
 					},
 				},
 			}),
-			PEntry("with updates required in multiple indexes, and multiple machines have been deleted, and the replacement machines are pending", onDeleteUpdateTableInput{
-				cpms: cpmsBuilder.WithReplicas(3).Build(),
+			Entry("with updates required in multiple indexes, and multiple machines have been deleted, and the replacement machines are pending", onDeleteUpdateTableInput{
+				cpms: resourcebuilder.ControlPlaneMachineSet().WithStrategyType(machinev1.OnDelete).WithReplicas(3).Build(),
 				machineInfos: map[int32][]machineproviders.MachineInfo{
 					0: {
 						healthyMachineBuilder.WithIndex(0).WithMachineName("machine-0").WithNodeName("node-0").WithNeedsUpdate(true).WithMachineDeletionTimestamp(metav1.Now()).Build(),
@@ -891,8 +897,8 @@ var _ = Describe("reconcileMachineUpdates", func() {
 						Level: 2,
 						KeysAndValues: []interface{}{
 							"updateStrategy", machinev1.OnDelete,
-							"index", 0,
-							"namespace", namespaceName,
+							"index", int32(0),
+							"namespace", machineNamespace,
 							"name", "machine-0",
 							"replacementName", "machine-replacement-0",
 						},
@@ -902,8 +908,8 @@ var _ = Describe("reconcileMachineUpdates", func() {
 						Level: 2,
 						KeysAndValues: []interface{}{
 							"updateStrategy", machinev1.OnDelete,
-							"index", 1,
-							"namespace", namespaceName,
+							"index", int32(1),
+							"namespace", machineNamespace,
 							"name", "machine-1",
 							"replacementName", "machine-replacement-1",
 						},
@@ -911,8 +917,8 @@ var _ = Describe("reconcileMachineUpdates", func() {
 					},
 				},
 			}),
-			PEntry("with updates required in multiple indexes, and a single machine has been deleted, and the replacement machine is ready", onDeleteUpdateTableInput{
-				cpms: cpmsBuilder.WithReplicas(3).Build(),
+			Entry("with updates required in multiple indexes, and a single machine has been deleted, and the replacement machine is ready", onDeleteUpdateTableInput{
+				cpms: resourcebuilder.ControlPlaneMachineSet().WithStrategyType(machinev1.OnDelete).WithReplicas(3).Build(),
 				machineInfos: map[int32][]machineproviders.MachineInfo{
 					0: {healthyMachineBuilder.WithIndex(0).WithMachineName("machine-0").WithNodeName("node-0").WithNeedsUpdate(true).Build()},
 					1: {
@@ -930,8 +936,8 @@ var _ = Describe("reconcileMachineUpdates", func() {
 						Level: 2,
 						KeysAndValues: []interface{}{
 							"updateStrategy", machinev1.OnDelete,
-							"index", 0,
-							"namespace", namespaceName,
+							"index", int32(0),
+							"namespace", machineNamespace,
 							"name", "machine-0",
 						},
 						Message: machineRequiresUpdate,
@@ -940,16 +946,16 @@ var _ = Describe("reconcileMachineUpdates", func() {
 						Level: 2,
 						KeysAndValues: []interface{}{
 							"updateStrategy", machinev1.OnDelete,
-							"index", 1,
-							"namespace", namespaceName,
+							"index", int32(1),
+							"namespace", machineNamespace,
 							"name", "machine-1",
 						},
 						Message: waitingForRemoved,
 					},
 				},
 			}),
-			PEntry("with updates required in multiple indexes, and multiple machines have been deleted, and a replacement machine is ready, and a replacement machine is pending", onDeleteUpdateTableInput{
-				cpms: cpmsBuilder.WithReplicas(3).Build(),
+			Entry("with updates required in multiple indexes, and multiple machines have been deleted, and a replacement machine is ready, and a replacement machine is pending", onDeleteUpdateTableInput{
+				cpms: resourcebuilder.ControlPlaneMachineSet().WithStrategyType(machinev1.OnDelete).WithReplicas(3).Build(),
 				machineInfos: map[int32][]machineproviders.MachineInfo{
 					0: {
 						healthyMachineBuilder.WithIndex(0).WithMachineName("machine-0").WithNodeName("node-0").WithNeedsUpdate(true).WithMachineDeletionTimestamp(metav1.Now()).Build(),
@@ -970,8 +976,8 @@ var _ = Describe("reconcileMachineUpdates", func() {
 						Level: 2,
 						KeysAndValues: []interface{}{
 							"updateStrategy", machinev1.OnDelete,
-							"index", 0,
-							"namespace", namespaceName,
+							"index", int32(0),
+							"namespace", machineNamespace,
 							"name", "machine-0",
 							"replacementName", "machine-replacement-0",
 						},
@@ -981,16 +987,16 @@ var _ = Describe("reconcileMachineUpdates", func() {
 						Level: 2,
 						KeysAndValues: []interface{}{
 							"updateStrategy", machinev1.OnDelete,
-							"index", 1,
-							"namespace", namespaceName,
+							"index", int32(1),
+							"namespace", machineNamespace,
 							"name", "machine-1",
 						},
 						Message: waitingForRemoved,
 					},
 				},
 			}),
-			PEntry("with updates required in multiple indexes, and multiple machines have been deleted, and all replacement machines are ready", onDeleteUpdateTableInput{
-				cpms: cpmsBuilder.WithReplicas(3).Build(),
+			Entry("with updates required in multiple indexes, and multiple machines have been deleted, and all replacement machines are ready", onDeleteUpdateTableInput{
+				cpms: resourcebuilder.ControlPlaneMachineSet().WithStrategyType(machinev1.OnDelete).WithReplicas(3).Build(),
 				machineInfos: map[int32][]machineproviders.MachineInfo{
 					0: {
 						healthyMachineBuilder.WithIndex(0).WithMachineName("machine-0").WithNodeName("node-0").WithNeedsUpdate(true).WithMachineDeletionTimestamp(metav1.Now()).Build(),
@@ -1011,8 +1017,8 @@ var _ = Describe("reconcileMachineUpdates", func() {
 						Level: 2,
 						KeysAndValues: []interface{}{
 							"updateStrategy", machinev1.OnDelete,
-							"index", 0,
-							"namespace", namespaceName,
+							"index", int32(0),
+							"namespace", machineNamespace,
 							"name", "machine-0",
 						},
 						Message: waitingForRemoved,
@@ -1021,23 +1027,23 @@ var _ = Describe("reconcileMachineUpdates", func() {
 						Level: 2,
 						KeysAndValues: []interface{}{
 							"updateStrategy", machinev1.OnDelete,
-							"index", 1,
-							"namespace", namespaceName,
+							"index", int32(1),
+							"namespace", machineNamespace,
 							"name", "machine-1",
 						},
 						Message: waitingForRemoved,
 					},
 				},
 			}),
-			PEntry("with an empty index", onDeleteUpdateTableInput{
-				cpms: cpmsBuilder.WithReplicas(3).Build(),
+			Entry("with an empty index", onDeleteUpdateTableInput{
+				cpms: resourcebuilder.ControlPlaneMachineSet().WithStrategyType(machinev1.OnDelete).WithReplicas(3).Build(),
 				machineInfos: map[int32][]machineproviders.MachineInfo{
 					0: {healthyMachineBuilder.WithIndex(0).WithMachineName("machine-0").WithNodeName("node-0").Build()},
 					1: {healthyMachineBuilder.WithIndex(1).WithMachineName("machine-1").WithNodeName("node-1").Build()},
 					2: {},
 				},
 				setupMock: func() {
-					mockMachineProvider.EXPECT().CreateMachine(gomock.Any(), gomock.Any(), 2).Return(nil).Times(1)
+					mockMachineProvider.EXPECT().CreateMachine(gomock.Any(), gomock.Any(), int32(2)).Return(nil).Times(1)
 					mockMachineProvider.EXPECT().DeleteMachine(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
 				},
 				expectedLogs: []test.LogEntry{
@@ -1045,16 +1051,16 @@ var _ = Describe("reconcileMachineUpdates", func() {
 						Level: 2,
 						KeysAndValues: []interface{}{
 							"updateStrategy", machinev1.OnDelete,
-							"index", 2,
-							"namespace", namespaceName,
+							"index", int32(2),
+							"namespace", machineNamespace,
 							"name", "<Unknown>",
 						},
 						Message: createdReplacement,
 					},
 				},
 			}),
-			PEntry("with a pending machine in an index", onDeleteUpdateTableInput{
-				cpms: cpmsBuilder.WithReplicas(3).Build(),
+			Entry("with a pending machine in an index", onDeleteUpdateTableInput{
+				cpms: resourcebuilder.ControlPlaneMachineSet().WithStrategyType(machinev1.OnDelete).WithReplicas(3).Build(),
 				machineInfos: map[int32][]machineproviders.MachineInfo{
 					0: {healthyMachineBuilder.WithIndex(0).WithMachineName("machine-0").WithNodeName("node-0").Build()},
 					1: {healthyMachineBuilder.WithIndex(1).WithMachineName("machine-1").WithNodeName("node-1").Build()},
@@ -1069,23 +1075,23 @@ var _ = Describe("reconcileMachineUpdates", func() {
 						Level: 2,
 						KeysAndValues: []interface{}{
 							"updateStrategy", machinev1.OnDelete,
-							"index", 2,
-							"namespace", namespaceName,
+							"index", int32(2),
+							"namespace", machineNamespace,
 							"name", "machine-replacement-2",
 						},
 						Message: waitingForReady,
 					},
 				},
 			}),
-			PEntry("with a missing index, and other indexes need updating", onDeleteUpdateTableInput{
-				cpms: cpmsBuilder.WithReplicas(3).Build(),
+			Entry("with a missing index, and other indexes need updating", onDeleteUpdateTableInput{
+				cpms: resourcebuilder.ControlPlaneMachineSet().WithStrategyType(machinev1.OnDelete).WithReplicas(3).Build(),
 				machineInfos: map[int32][]machineproviders.MachineInfo{
 					0: {healthyMachineBuilder.WithIndex(0).WithMachineName("machine-0").WithNodeName("node-0").Build()},
 					1: {healthyMachineBuilder.WithIndex(1).WithMachineName("machine-1").WithNodeName("node-1").WithNeedsUpdate(true).Build()},
 					2: {},
 				},
 				setupMock: func() {
-					mockMachineProvider.EXPECT().CreateMachine(gomock.Any(), gomock.Any(), 2).Return(nil).Times(1)
+					mockMachineProvider.EXPECT().CreateMachine(gomock.Any(), gomock.Any(), int32(2)).Return(nil).Times(1)
 					mockMachineProvider.EXPECT().DeleteMachine(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
 				},
 				expectedLogs: []test.LogEntry{
@@ -1093,8 +1099,8 @@ var _ = Describe("reconcileMachineUpdates", func() {
 						Level: 2,
 						KeysAndValues: []interface{}{
 							"updateStrategy", machinev1.OnDelete,
-							"index", 1,
-							"namespace", namespaceName,
+							"index", int32(1),
+							"namespace", machineNamespace,
 							"name", "machine-1",
 						},
 						Message: machineRequiresUpdate,
@@ -1103,16 +1109,16 @@ var _ = Describe("reconcileMachineUpdates", func() {
 						Level: 2,
 						KeysAndValues: []interface{}{
 							"updateStrategy", machinev1.OnDelete,
-							"index", 2,
-							"namespace", namespaceName,
+							"index", int32(2),
+							"namespace", machineNamespace,
 							"name", "<Unknown>",
 						},
 						Message: createdReplacement,
 					},
 				},
 			}),
-			PEntry("with a pending machine in an index, and other indexes need updating", onDeleteUpdateTableInput{
-				cpms: cpmsBuilder.WithReplicas(3).Build(),
+			Entry("with a pending machine in an index, and other indexes need updating", onDeleteUpdateTableInput{
+				cpms: resourcebuilder.ControlPlaneMachineSet().WithStrategyType(machinev1.OnDelete).WithReplicas(3).Build(),
 				machineInfos: map[int32][]machineproviders.MachineInfo{
 					0: {healthyMachineBuilder.WithIndex(0).WithMachineName("machine-0").WithNodeName("node-0").Build()},
 					1: {healthyMachineBuilder.WithIndex(1).WithMachineName("machine-1").WithNodeName("node-1").WithNeedsUpdate(true).Build()},
@@ -1127,8 +1133,8 @@ var _ = Describe("reconcileMachineUpdates", func() {
 						Level: 2,
 						KeysAndValues: []interface{}{
 							"updateStrategy", machinev1.OnDelete,
-							"index", 1,
-							"namespace", namespaceName,
+							"index", int32(1),
+							"namespace", machineNamespace,
 							"name", "machine-1",
 						},
 						Message: machineRequiresUpdate,
@@ -1137,8 +1143,8 @@ var _ = Describe("reconcileMachineUpdates", func() {
 						Level: 2,
 						KeysAndValues: []interface{}{
 							"updateStrategy", machinev1.OnDelete,
-							"index", 2,
-							"namespace", namespaceName,
+							"index", int32(2),
+							"namespace", machineNamespace,
 							"name", "machine-replacement-2",
 						},
 						Message: waitingForReady,
@@ -1148,6 +1154,238 @@ var _ = Describe("reconcileMachineUpdates", func() {
 		)
 	})
 
+	Context("When the update strategy is OnDelete, and replacements are pre-created", func() {
+		type preCreateTableInput struct {
+			machineInfos  map[int32][]machineproviders.MachineInfo
+			setupMock     func()
+			expectedError error
+			expectedLogs  []test.LogEntry
+		}
+
+		DescribeTable("should implement the update strategy based on the MachineInfo", func(in preCreateTableInput) {
+			in.setupMock()
+
+			cpms := resourcebuilder.ControlPlaneMachineSet().
+				WithStrategyType(machinev1.OnDelete).
+				WithReplicas(3).
+				WithAnnotations(map[string]string{preCreateReplacementsAnnotation: "true"}).
+				Build()
+			originalCPMS := cpms.DeepCopy()
+
+			result, err := reconciler.reconcileMachineUpdates(ctx, logger.Logger(), cpms, mockMachineProvider, in.machineInfos)
+			if in.expectedError != nil {
+				Expect(err).To(MatchError(in.expectedError))
+			} else {
+				Expect(err).ToNot(HaveOccurred())
+			}
+
+			Expect(result).To(Equal(ctrl.Result{}))
+			Expect(logger.Entries()).To(ConsistOf(in.expectedLogs))
+			Expect(cpms).To(Equal(originalCPMS), "The update functions should not modify the ControlPlaneMachineSet in any way")
+		},
+			Entry("with updates required in a single index, and the machine is not yet deleted", preCreateTableInput{
+				machineInfos: map[int32][]machineproviders.MachineInfo{
+					0: {healthyMachineBuilder.WithIndex(0).WithMachineName("machine-0").WithNodeName("node-0").Build()},
+					1: {healthyMachineBuilder.WithIndex(1).WithMachineName("machine-1").WithNodeName("node-1").WithNeedsUpdate(true).Build()},
+					2: {healthyMachineBuilder.WithIndex(2).WithMachineName("machine-2").WithNodeName("node-2").Build()},
+				},
+				setupMock: func() {
+					mockMachineProvider.EXPECT().CreateMachine(gomock.Any(), gomock.Any(), int32(1)).Return(nil).Times(1)
+					mockMachineProvider.EXPECT().DeleteMachine(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+				},
+				expectedLogs: []test.LogEntry{
+					{
+						Level: 2,
+						KeysAndValues: []interface{}{
+							"updateStrategy", machinev1.OnDelete,
+							"index", int32(1),
+							"namespace", machineNamespace,
+							"name", "machine-1",
+						},
+						Message: createdReplacement,
+					},
+				},
+			}),
+			Entry("with updates required in a single index, and an error occurs", preCreateTableInput{
+				expectedError: fmt.Errorf("error creating new Machine for index %d: %w", 1, transientError),
+				machineInfos: map[int32][]machineproviders.MachineInfo{
+					0: {healthyMachineBuilder.WithIndex(0).WithMachineName("machine-0").WithNodeName("node-0").Build()},
+					1: {healthyMachineBuilder.WithIndex(1).WithMachineName("machine-1").WithNodeName("node-1").WithNeedsUpdate(true).Build()},
+					2: {healthyMachineBuilder.WithIndex(2).WithMachineName("machine-2").WithNodeName("node-2").Build()},
+				},
+				setupMock: func() {
+					mockMachineProvider.EXPECT().CreateMachine(gomock.Any(), gomock.Any(), int32(1)).Return(transientError).Times(1)
+					mockMachineProvider.EXPECT().DeleteMachine(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+				},
+				expectedLogs: []test.LogEntry{
+					{
+						Error: fmt.Errorf("error creating new Machine for index %d: %w", 1, transientError),
+						KeysAndValues: []interface{}{
+							"updateStrategy", machinev1.OnDelete,
+							"index", int32(1),
+							"namespace", machineNamespace,
+							"name", "machine-1",
+						},
+						Message: errorCreatingMachine,
+					},
+				},
+			}),
+			Entry("with updates required in a single index, and the replacement machine is pending", preCreateTableInput{
+				machineInfos: map[int32][]machineproviders.MachineInfo{
+					0: {healthyMachineBuilder.WithIndex(0).WithMachineName("machine-0").WithNodeName("node-0").Build()},
+					1: {
+						healthyMachineBuilder.WithIndex(1).WithMachineName("machine-1").WithNodeName("node-1").WithNeedsUpdate(true).Build(),
+						pendingMachineBuilder.WithIndex(1).WithMachineName("machine-replacement-1").Build(),
+					},
+					2: {healthyMachineBuilder.WithIndex(2).WithMachineName("machine-2").WithNodeName("node-2").Build()},
+				},
+				setupMock: func() {
+					mockMachineProvider.EXPECT().CreateMachine(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+					mockMachineProvider.EXPECT().DeleteMachine(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+				},
+				expectedLogs: []test.LogEntry{
+					{
+						Level: 2,
+						KeysAndValues: []interface{}{
+							"updateStrategy", machinev1.OnDelete,
+							"index", int32(1),
+							"namespace", machineNamespace,
+							"name", "machine-1",
+							"replacementName", "machine-replacement-1",
+						},
+						Message: waitingForReplacement,
+					},
+				},
+			}),
+			Entry("with updates required in a single index, and the replacement machine is ready", preCreateTableInput{
+				machineInfos: map[int32][]machineproviders.MachineInfo{
+					0: {healthyMachineBuilder.WithIndex(0).WithMachineName("machine-0").WithNodeName("node-0").Build()},
+					1: {
+						healthyMachineBuilder.WithIndex(1).WithMachineName("machine-1").WithNodeName("node-1").WithNeedsUpdate(true).Build(),
+						healthyMachineBuilder.WithIndex(1).WithMachineName("machine-replacement-1").WithNodeName("node-replacement-1").Build(),
+					},
+					2: {healthyMachineBuilder.WithIndex(2).WithMachineName("machine-2").WithNodeName("node-2").Build()},
+				},
+				setupMock: func() {
+					// The outdated machine must be deleted by the user, it is never deleted by the controller.
+					mockMachineProvider.EXPECT().CreateMachine(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+					mockMachineProvider.EXPECT().DeleteMachine(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+				},
+				expectedLogs: []test.LogEntry{
+					{
+						Level: 2,
+						KeysAndValues: []interface{}{
+							"updateStrategy", machinev1.OnDelete,
+							"index", int32(1),
+							"namespace", machineNamespace,
+							"name", "machine-1",
+							"replacementName", "machine-replacement-1",
+						},
+						Message: replacementReadyForDeletion,
+					},
+				},
+			}),
+			Entry("with updates required in a single index, and the replacement machine is ready, and the machine has been deleted", preCreateTableInput{
+				machineInfos: map[int32][]machineproviders.MachineInfo{
+					0: {healthyMachineBuilder.WithIndex(0).WithMachineName("machine-0").WithNodeName("node-0").Build()},
+					1: {
+						healthyMachineBuilder.WithIndex(1).WithMachineName("machine-1").WithNodeName("node-1").WithNeedsUpdate(true).WithMachineDeletionTimestamp(metav1.Now()).Build(),
+						healthyMachineBuilder.WithIndex(1).WithMachineName("machine-replacement-1").WithNodeName("node-replacement-1").Build(),
+					},
+					2: {healthyMachineBuilder.WithIndex(2).WithMachineName("machine-2").WithNodeName("node-2").Build()},
+				},
+				setupMock: func() {
+					mockMachineProvider.EXPECT().CreateMachine(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+					mockMachineProvider.EXPECT().DeleteMachine(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+				},
+				expectedLogs: []test.LogEntry{
+					{
+						Level: 2,
+						KeysAndValues: []interface{}{
+							"updateStrategy", machinev1.OnDelete,
+							"index", int32(1),
+							"namespace", machineNamespace,
+							"name", "machine-1",
+						},
+						Message: waitingForRemoved,
+					},
+				},
+			}),
+			Entry("with updates required in multiple indexes", preCreateTableInput{
+				machineInfos: map[int32][]machineproviders.MachineInfo{
+					0: {healthyMachineBuilder.WithIndex(0).WithMachineName("machine-0").WithNodeName("node-0").WithNeedsUpdate(true).Build()},
+					1: {healthyMachineBuilder.WithIndex(1).WithMachineName("machine-1").WithNodeName("node-1").WithNeedsUpdate(true).Build()},
+					2: {healthyMachineBuilder.WithIndex(2).WithMachineName("machine-2").WithNodeName("node-2").Build()},
+				},
+				setupMock: func() {
+					// Only a single replacement should be pre-created at a time.
+					mockMachineProvider.EXPECT().CreateMachine(gomock.Any(), gomock.Any(), int32(0)).Return(nil).Times(1)
+					mockMachineProvider.EXPECT().DeleteMachine(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+				},
+				expectedLogs: []test.LogEntry{
+					{
+						Level: 2,
+						KeysAndValues: []interface{}{
+							"updateStrategy", machinev1.OnDelete,
+							"index", int32(0),
+							"namespace", machineNamespace,
+							"name", "machine-0",
+						},
+						Message: createdReplacement,
+					},
+					{
+						Level: 2,
+						KeysAndValues: []interface{}{
+							"updateStrategy", machinev1.OnDelete,
+							"index", int32(1),
+							"namespace", machineNamespace,
+							"name", "machine-1",
+						},
+						Message: machineRequiresUpdate,
+					},
+				},
+			}),
+			Entry("with updates required in multiple indexes, and a replacement machine is ready", preCreateTableInput{
+				machineInfos: map[int32][]machineproviders.MachineInfo{
+					0: {
+						healthyMachineBuilder.WithIndex(0).WithMachineName("machine-0").WithNodeName("node-0").WithNeedsUpdate(true).Build(),
+						healthyMachineBuilder.WithIndex(0).WithMachineName("machine-replacement-0").WithNodeName("node-replacement-0").Build(),
+					},
+					1: {healthyMachineBuilder.WithIndex(1).WithMachineName("machine-1").WithNodeName("node-1").WithNeedsUpdate(true).Build()},
+					2: {healthyMachineBuilder.WithIndex(2).WithMachineName("machine-2").WithNodeName("node-2").Build()},
+				},
+				setupMock: func() {
+					// The next replacement is only created once the user has deleted the outdated machine.
+					mockMachineProvider.EXPECT().CreateMachine(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+					mockMachineProvider.EXPECT().DeleteMachine(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+				},
+				expectedLogs: []test.LogEntry{
+					{
+						Level: 2,
+						KeysAndValues: []interface{}{
+							"updateStrategy", machinev1.OnDelete,
+							"index", int32(0),
+							"namespace", machineNamespace,
+							"name", "machine-0",
+							"replacementName", "machine-replacement-0",
+						},
+						Message: replacementReadyForDeletion,
+					},
+					{
+						Level: 2,
+						KeysAndValues: []interface{}{
+							"updateStrategy", machinev1.OnDelete,
+							"index", int32(1),
+							"namespace", machineNamespace,
+							"name", "machine-1",
+						},
+						Message: machineRequiresUpdate,
+					},
+				},
+			}),
+		)
+	})
+
 	Context("When the update strategy is Recreate", func() {
 		var cpms *machinev1.ControlPlaneMachineSet
 		var result ctrl.Result
@@ -20,7 +20,10 @@ import (
 	configv1 "github.com/openshift/api/config/v1"
 	machinev1 "github.com/openshift/api/machine/v1"
 	machinev1beta1 "github.com/openshift/api/machine/v1beta1"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/clusterconfig"
+	"k8s.io/apimachinery/pkg/api/equality"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 )
@@ -40,11 +43,33 @@ const (
 )
 
 // clusterOperatorToControlPlaneMachineSet maps the cluster operator to the control
-// plane machine set singleton in the namespace provided.
-func clusterOperatorToControlPlaneMachineSet(namespace string) func(client.Object) []reconcile.Request {
+// plane machine set singleton with the name, in the namespace, provided.
+func clusterOperatorToControlPlaneMachineSet(namespace, name string) func(client.Object) []reconcile.Request {
 	return func(obj client.Object) []reconcile.Request {
 		return []reconcile.Request{{
-			NamespacedName: client.ObjectKey{Namespace: namespace, Name: clusterControlPlaneMachineSetName},
+			NamespacedName: client.ObjectKey{Namespace: namespace, Name: name},
+		}}
+	}
+}
+
+// infrastructureToControlPlaneMachineSet maps the cluster Infrastructure to the control
+// plane machine set singleton with the name, in the namespace, provided.
+func infrastructureToControlPlaneMachineSet(namespace, name string) func(client.Object) []reconcile.Request {
+	return func(obj client.Object) []reconcile.Request {
+		return []reconcile.Request{{
+			NamespacedName: client.ObjectKey{Namespace: namespace, Name: name},
+		}}
+	}
+}
+
+// machineToControlPlaneMachineSet maps a control plane machine to the control
+// plane machine set singleton with the name, in the namespace, provided.
+// Unlike the owner reference based mapping, this allows machines that are not
+// yet owned by the control plane machine set to trigger a reconcile.
+func machineToControlPlaneMachineSet(namespace, name string) func(client.Object) []reconcile.Request {
+	return func(obj client.Object) []reconcile.Request {
+		return []reconcile.Request{{
+			NamespacedName: client.ObjectKey{Namespace: namespace, Name: name},
 		}}
 	}
 }
@@ -62,16 +87,60 @@ func filterClusterOperator(name string) predicate.Predicate {
 	})
 }
 
+// filterInfrastructureStatusChanges filters infrastructure events to just those for the
+// cluster Infrastructure that may change the platform status, such as the region or the
+// user defined resource tags. Updates that do not change the status are ignored.
+func filterInfrastructureStatusChanges() predicate.Predicate {
+	isClusterInfrastructure := func(obj client.Object) bool {
+		if _, ok := obj.(*configv1.Infrastructure); !ok {
+			panic("expected to get an of object of type configv1.Infrastructure")
+		}
+
+		return obj.GetName() == clusterconfig.InfrastructureName
+	}
+
+	return predicate.Funcs{
+		CreateFunc: func(e event.CreateEvent) bool {
+			return isClusterInfrastructure(e.Object)
+		},
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			if !isClusterInfrastructure(e.ObjectNew) {
+				return false
+			}
+
+			oldInfrastructure, ok := e.ObjectOld.(*configv1.Infrastructure)
+			if !ok {
+				return true
+			}
+
+			return !equality.Semantic.DeepEqual(oldInfrastructure.Status, e.ObjectNew.(*configv1.Infrastructure).Status)
+		},
+		DeleteFunc: func(e event.DeleteEvent) bool {
+			return isClusterInfrastructure(e.Object)
+		},
+		GenericFunc: func(e event.GenericEvent) bool {
+			return isClusterInfrastructure(e.Object)
+		},
+	}
+}
+
 // filterControlPlaneMachineSet filters control plane machine set requests
-// to just the singleton within the namespace provided.
-func filterControlPlaneMachineSet(namespace string) predicate.Predicate {
+// to just the singleton with the name, within the namespace, provided.
+func filterControlPlaneMachineSet(namespace, name string) predicate.Predicate {
 	return predicate.NewPredicateFuncs(func(obj client.Object) bool {
 		cpms, ok := obj.(*machinev1.ControlPlaneMachineSet)
 		if !ok {
 			panic("expected to get an of object of type machinev1.ControlPlaneMachineSet")
 		}
 
-		return cpms.GetNamespace() == namespace && cpms.GetName() == clusterControlPlaneMachineSetName
+		return cpms.GetNamespace() == namespace && cpms.GetName() == name
+	})
+}
+
+// filterNamespace filters requests to just the objects within the namespace provided.
+func filterNamespace(namespace string) predicate.Predicate {
+	return predicate.NewPredicateFuncs(func(obj client.Object) bool {
+		return obj.GetNamespace() == namespace
 	})
 }
 
@@ -95,3 +164,31 @@ func filterControlPlaneMachines(namespace string) predicate.Predicate {
 		return labels[machineRoleLabelName] == machineMasterRoleLabelName && labels[machineTypeLabelName] == machineMasterTypeLabelName
 	})
 }
+
+// filterReconcileRequests filters machine events to just those where an external
+// controller has requested a re-evaluation of the machine's index, i.e. the machine
+// is created with, or updated to a new value of, the reconcile request annotation.
+func filterReconcileRequests() predicate.Predicate {
+	return predicate.Funcs{
+		CreateFunc: func(e event.CreateEvent) bool {
+			_, ok := e.Object.GetAnnotations()[reconcileRequestAnnotation]
+			return ok
+		},
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			newToken, ok := e.ObjectNew.GetAnnotations()[reconcileRequestAnnotation]
+			if !ok {
+				return false
+			}
+
+			oldToken, ok := e.ObjectOld.GetAnnotations()[reconcileRequestAnnotation]
+
+			return !ok || oldToken != newToken
+		},
+		DeleteFunc: func(e event.DeleteEvent) bool {
+			return false
+		},
+		GenericFunc: func(e event.GenericEvent) bool {
+			return false
+		},
+	}
+}
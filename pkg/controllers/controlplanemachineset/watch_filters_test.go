@@ -20,7 +20,10 @@ import (
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 
+	configv1 "github.com/openshift/api/config/v1"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/clusterconfig"
 	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/test/resourcebuilder"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -37,7 +40,7 @@ var _ = Describe("Watch Filters", func() {
 		var clusterOperatorFilter func(client.Object) []reconcile.Request
 
 		BeforeEach(func() {
-			clusterOperatorFilter = clusterOperatorToControlPlaneMachineSet(testNamespace)
+			clusterOperatorFilter = clusterOperatorToControlPlaneMachineSet(testNamespace, DefaultControlPlaneMachineSetName)
 		})
 
 		It("returns a correct request for the cluster ControlPlaneMachineSet", func() {
@@ -46,7 +49,7 @@ var _ = Describe("Watch Filters", func() {
 			Expect(clusterOperatorFilter(co)).To(ConsistOf(reconcile.Request{
 				NamespacedName: types.NamespacedName{
 					Namespace: testNamespace,
-					Name:      clusterControlPlaneMachineSetName,
+					Name:      DefaultControlPlaneMachineSetName,
 				},
 			}))
 		})
@@ -129,13 +132,96 @@ var _ = Describe("Watch Filters", func() {
 		})
 	})
 
+	Context("infrastructureToControlPlaneMachineSet", func() {
+		const testNamespace = "test"
+
+		It("returns a correct request for the cluster ControlPlaneMachineSet", func() {
+			infrastructure := &configv1.Infrastructure{ObjectMeta: metav1.ObjectMeta{Name: clusterconfig.InfrastructureName}}
+
+			Expect(infrastructureToControlPlaneMachineSet(testNamespace, DefaultControlPlaneMachineSetName)(infrastructure)).To(ConsistOf(reconcile.Request{
+				NamespacedName: types.NamespacedName{
+					Namespace: testNamespace,
+					Name:      DefaultControlPlaneMachineSetName,
+				},
+			}))
+		})
+	})
+
+	Context("filterInfrastructureStatusChanges", func() {
+		var infrastructurePredicate predicate.Predicate
+
+		// infrastructureWithTags builds an Infrastructure with the name and the AWS resource tags provided.
+		infrastructureWithTags := func(name string, tags ...configv1.AWSResourceTag) *configv1.Infrastructure {
+			return &configv1.Infrastructure{
+				ObjectMeta: metav1.ObjectMeta{Name: name},
+				Status: configv1.InfrastructureStatus{
+					PlatformStatus: &configv1.PlatformStatus{
+						Type: configv1.AWSPlatformType,
+						AWS:  &configv1.AWSPlatformStatus{Region: "us-east-1", ResourceTags: tags},
+					},
+				},
+			}
+		}
+
+		BeforeEach(func() {
+			infrastructurePredicate = filterInfrastructureStatusChanges()
+		})
+
+		It("Panics with the wrong object kind", func() {
+			expectedMessage := "expected to get an of object of type configv1.Infrastructure"
+			machine := resourcebuilder.Machine().Build()
+
+			Expect(func() {
+				infrastructurePredicate.Create(createEvent(machine))
+			}).To(PanicWith(expectedMessage), "A programming error occurs when passing the wrong object, the function should panic")
+
+			Expect(func() {
+				infrastructurePredicate.Update(updateEvent(machine))
+			}).To(PanicWith(expectedMessage), "A programming error occurs when passing the wrong object, the function should panic")
+		})
+
+		It("Returns false with the wrong name", func() {
+			infrastructure := infrastructureWithTags("other")
+
+			Expect(infrastructurePredicate.Create(createEvent(infrastructure))).To(BeFalse())
+			Expect(infrastructurePredicate.Update(updateEvent(infrastructure))).To(BeFalse())
+			Expect(infrastructurePredicate.Delete(deleteEvent(infrastructure))).To(BeFalse())
+			Expect(infrastructurePredicate.Generic(genericEvent(infrastructure))).To(BeFalse())
+		})
+
+		It("Returns true when the cluster Infrastructure is created or deleted", func() {
+			infrastructure := infrastructureWithTags(clusterconfig.InfrastructureName)
+
+			Expect(infrastructurePredicate.Create(createEvent(infrastructure))).To(BeTrue())
+			Expect(infrastructurePredicate.Delete(deleteEvent(infrastructure))).To(BeTrue())
+		})
+
+		It("Returns true when the resource tags change", func() {
+			Expect(infrastructurePredicate.Update(event.UpdateEvent{
+				ObjectOld: infrastructureWithTags(clusterconfig.InfrastructureName),
+				ObjectNew: infrastructureWithTags(clusterconfig.InfrastructureName, configv1.AWSResourceTag{Key: "example.com/owner", Value: "team"}),
+			})).To(BeTrue())
+		})
+
+		It("Returns false when the status is unchanged", func() {
+			oldInfrastructure := infrastructureWithTags(clusterconfig.InfrastructureName)
+			newInfrastructure := infrastructureWithTags(clusterconfig.InfrastructureName)
+			newInfrastructure.SetLabels(map[string]string{"example.com/label": "value"})
+
+			Expect(infrastructurePredicate.Update(event.UpdateEvent{
+				ObjectOld: oldInfrastructure,
+				ObjectNew: newInfrastructure,
+			})).To(BeFalse())
+		})
+	})
+
 	Context("filterControlPlaneMachineSet", func() {
 		const testNamespace = "test"
 
 		var cpmsPredicate predicate.Predicate
 
 		BeforeEach(func() {
-			cpmsPredicate = filterControlPlaneMachineSet(testNamespace)
+			cpmsPredicate = filterControlPlaneMachineSet(testNamespace, DefaultControlPlaneMachineSetName)
 		})
 
 		It("Panics with the wrong object kind", func() {
@@ -160,7 +246,7 @@ var _ = Describe("Watch Filters", func() {
 
 		It("Returns false with the wrong namespace", func() {
 			cpms := resourcebuilder.ControlPlaneMachineSet().
-				WithName(clusterControlPlaneMachineSetName).
+				WithName(DefaultControlPlaneMachineSetName).
 				WithNamespace("wrong-namespace").
 				Build()
 
@@ -184,7 +270,7 @@ var _ = Describe("Watch Filters", func() {
 
 		It("Returns true with the correct namespace and name", func() {
 			cpms := resourcebuilder.ControlPlaneMachineSet().
-				WithName(clusterControlPlaneMachineSetName).
+				WithName(DefaultControlPlaneMachineSetName).
 				WithNamespace(testNamespace).
 				Build()
 
@@ -193,6 +279,38 @@ var _ = Describe("Watch Filters", func() {
 			Expect(cpmsPredicate.Delete(deleteEvent(cpms))).To(BeTrue())
 			Expect(cpmsPredicate.Generic(genericEvent(cpms))).To(BeTrue())
 		})
+
+		Context("with a configured name", func() {
+			const configuredName = "recovered"
+
+			BeforeEach(func() {
+				cpmsPredicate = filterControlPlaneMachineSet(testNamespace, configuredName)
+			})
+
+			It("Returns false with the default name", func() {
+				cpms := resourcebuilder.ControlPlaneMachineSet().
+					WithName(DefaultControlPlaneMachineSetName).
+					WithNamespace(testNamespace).
+					Build()
+
+				Expect(cpmsPredicate.Create(createEvent(cpms))).To(BeFalse())
+				Expect(cpmsPredicate.Update(updateEvent(cpms))).To(BeFalse())
+				Expect(cpmsPredicate.Delete(deleteEvent(cpms))).To(BeFalse())
+				Expect(cpmsPredicate.Generic(genericEvent(cpms))).To(BeFalse())
+			})
+
+			It("Returns true with the configured name", func() {
+				cpms := resourcebuilder.ControlPlaneMachineSet().
+					WithName(configuredName).
+					WithNamespace(testNamespace).
+					Build()
+
+				Expect(cpmsPredicate.Create(createEvent(cpms))).To(BeTrue())
+				Expect(cpmsPredicate.Update(updateEvent(cpms))).To(BeTrue())
+				Expect(cpmsPredicate.Delete(deleteEvent(cpms))).To(BeTrue())
+				Expect(cpmsPredicate.Generic(genericEvent(cpms))).To(BeTrue())
+			})
+		})
 	})
 
 	Context("filterControlPlaneMachines", func() {
@@ -289,4 +407,91 @@ var _ = Describe("Watch Filters", func() {
 			Expect(machinePredicate.Generic(genericEvent(machine))).To(BeTrue())
 		})
 	})
+
+	Context("machineToControlPlaneMachineSet", func() {
+		const testNamespace = "test"
+
+		It("returns a correct request for the cluster ControlPlaneMachineSet", func() {
+			machine := resourcebuilder.Machine().WithNamespace(testNamespace).AsMaster().Build()
+
+			Expect(machineToControlPlaneMachineSet(testNamespace, DefaultControlPlaneMachineSetName)(machine)).To(ConsistOf(reconcile.Request{
+				NamespacedName: types.NamespacedName{
+					Namespace: testNamespace,
+					Name:      DefaultControlPlaneMachineSetName,
+				},
+			}))
+		})
+
+		It("returns a request for the ControlPlaneMachineSet with the configured name", func() {
+			machine := resourcebuilder.Machine().WithNamespace(testNamespace).AsMaster().Build()
+
+			Expect(machineToControlPlaneMachineSet(testNamespace, "recovered")(machine)).To(ConsistOf(reconcile.Request{
+				NamespacedName: types.NamespacedName{
+					Namespace: testNamespace,
+					Name:      "recovered",
+				},
+			}))
+		})
+	})
+
+	Context("filterReconcileRequests", func() {
+		var reconcileRequestPredicate predicate.Predicate
+
+		// machineWithReconcileRequest builds a machine with the reconcile request annotation set to the token,
+		// when the token is not empty.
+		machineWithReconcileRequest := func(token string) client.Object {
+			machine := resourcebuilder.Machine().AsMaster().Build()
+
+			if token != "" {
+				machine.SetAnnotations(map[string]string{reconcileRequestAnnotation: token})
+			}
+
+			return machine
+		}
+
+		BeforeEach(func() {
+			reconcileRequestPredicate = filterReconcileRequests()
+		})
+
+		It("Returns false when the machine has no reconcile request", func() {
+			machine := machineWithReconcileRequest("")
+
+			Expect(reconcileRequestPredicate.Create(createEvent(machine))).To(BeFalse())
+			Expect(reconcileRequestPredicate.Update(event.UpdateEvent{ObjectOld: machine, ObjectNew: machine})).To(BeFalse())
+			Expect(reconcileRequestPredicate.Delete(deleteEvent(machine))).To(BeFalse())
+			Expect(reconcileRequestPredicate.Generic(genericEvent(machine))).To(BeFalse())
+		})
+
+		It("Returns true when the machine is created with a reconcile request", func() {
+			Expect(reconcileRequestPredicate.Create(createEvent(machineWithReconcileRequest("1")))).To(BeTrue())
+		})
+
+		It("Returns true when a reconcile request is added", func() {
+			Expect(reconcileRequestPredicate.Update(event.UpdateEvent{
+				ObjectOld: machineWithReconcileRequest(""),
+				ObjectNew: machineWithReconcileRequest("1"),
+			})).To(BeTrue())
+		})
+
+		It("Returns true when the reconcile request is changed", func() {
+			Expect(reconcileRequestPredicate.Update(event.UpdateEvent{
+				ObjectOld: machineWithReconcileRequest("1"),
+				ObjectNew: machineWithReconcileRequest("2"),
+			})).To(BeTrue())
+		})
+
+		It("Returns false when the reconcile request is unchanged", func() {
+			Expect(reconcileRequestPredicate.Update(event.UpdateEvent{
+				ObjectOld: machineWithReconcileRequest("1"),
+				ObjectNew: machineWithReconcileRequest("1"),
+			})).To(BeFalse())
+		})
+
+		It("Returns false when the reconcile request is removed", func() {
+			Expect(reconcileRequestPredicate.Update(event.UpdateEvent{
+				ObjectOld: machineWithReconcileRequest("1"),
+				ObjectNew: machineWithReconcileRequest(""),
+			})).To(BeFalse())
+		})
+	})
 })
@@ -0,0 +1,245 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controlplanemachineset
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/go-logr/logr"
+	machinev1 "github.com/openshift/api/machine/v1"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders/providers"
+)
+
+// WhatIfAction is an action that the operator would take for a ControlPlaneMachineSet.
+type WhatIfAction string
+
+const (
+	// WhatIfCreate is the action of creating a Machine for an index without any Machine.
+	WhatIfCreate WhatIfAction = "Create"
+
+	// WhatIfReplace is the action of replacing a Machine that needs an update.
+	WhatIfReplace WhatIfAction = "Replace"
+
+	// WhatIfRemove is the action of removing a Machine in an index beyond the replicas. The operator does not remove
+	// these Machines, they must be removed manually once their etcd members have been removed.
+	WhatIfRemove WhatIfAction = "Remove"
+)
+
+// WhatIfReport describes the actions that the operator would take for a ControlPlaneMachineSet if it were applied,
+// in the order in which the operator would take them.
+type WhatIfReport struct {
+	// Strategy is the update strategy of the ControlPlaneMachineSet. With the OnDelete strategy, Machines are only
+	// replaced once they have been deleted.
+	Strategy machinev1.ControlPlaneMachineSetStrategyType `json:"strategy"`
+
+	// Replicas is the number of replicas of the ControlPlaneMachineSet.
+	Replicas int32 `json:"replicas"`
+
+	// Steps are the actions that the operator would take, in order.
+	Steps []WhatIfStep `json:"steps"`
+}
+
+// WhatIfStep is a single action that the operator would take for an index.
+type WhatIfStep struct {
+	// Order is the position of the step within the report, starting from 1.
+	Order int `json:"order"`
+
+	// Action is the action that would be taken.
+	Action WhatIfAction `json:"action"`
+
+	// Index is the index of the Machine.
+	Index int32 `json:"index"`
+
+	// MachineName is the name of the existing Machine. It is empty when a Machine would be created.
+	MachineName string `json:"machineName,omitempty"`
+
+	// FailureDomain is the failure domain that the existing Machine is within. It is empty when a Machine would be
+	// created, or the Machine is not within any of the failure domains.
+	FailureDomain string `json:"failureDomain,omitempty"`
+
+	// DesiredFailureDomain is the failure domain of the index, in which the new Machine would be created. It is empty
+	// when no failure domains are configured, or the Machine would be removed.
+	DesiredFailureDomain string `json:"desiredFailureDomain,omitempty"`
+
+	// InProgress is set when the Machine already has a replacement, so its replacement would be completed before any
+	// other Machine is replaced.
+	InProgress bool `json:"inProgress,omitempty"`
+}
+
+// MovesFailureDomain determines whether the step would replace a Machine into another failure domain.
+func (s WhatIfStep) MovesFailureDomain() bool {
+	return s.Action == WhatIfReplace && s.DesiredFailureDomain != "" && s.FailureDomain != s.DesiredFailureDomain
+}
+
+// String describes the step, eg `Replace Machine cluster-master-1 in index 1, moving from failure domain us-east-1b
+// to us-east-1d`.
+func (s WhatIfStep) String() string {
+	var b strings.Builder
+
+	switch s.Action {
+	case WhatIfCreate:
+		fmt.Fprintf(&b, "Create a Machine for index %d", s.Index)
+
+		if s.DesiredFailureDomain != "" {
+			fmt.Fprintf(&b, " in failure domain %s", s.DesiredFailureDomain)
+		}
+	case WhatIfReplace:
+		fmt.Fprintf(&b, "Replace Machine %s in index %d", s.MachineName, s.Index)
+
+		switch {
+		case s.MovesFailureDomain() && s.FailureDomain == "":
+			fmt.Fprintf(&b, ", moving into failure domain %s", s.DesiredFailureDomain)
+		case s.MovesFailureDomain():
+			fmt.Fprintf(&b, ", moving from failure domain %s to %s", s.FailureDomain, s.DesiredFailureDomain)
+		case s.DesiredFailureDomain != "":
+			fmt.Fprintf(&b, ", within failure domain %s", s.DesiredFailureDomain)
+		}
+
+		if s.InProgress {
+			b.WriteString(" (replacement in progress)")
+		}
+	case WhatIfRemove:
+		fmt.Fprintf(&b, "Remove Machine %s in index %d, beyond the replicas", s.MachineName, s.Index)
+
+		if s.FailureDomain != "" {
+			fmt.Fprintf(&b, ", from failure domain %s", s.FailureDomain)
+		}
+	}
+
+	return b.String()
+}
+
+// WhatIf determines the actions that the operator would take if the proposed ControlPlaneMachineSet were applied,
+// based on the Machines that currently exist within the cluster.
+// The failure domains of the proposed ControlPlaneMachineSet are resolved and discovered in the same way as during a
+// reconcile. Nothing is created, updated or deleted, and the proposed ControlPlaneMachineSet is left unmodified. To
+// guarantee that the cluster is not modified, the reconciler may be given a dry run client.
+func (r *ControlPlaneMachineSetReconciler) WhatIf(ctx context.Context, logger logr.Logger, proposed *machinev1.ControlPlaneMachineSet) (*WhatIfReport, error) {
+	// Failure domain discovery records its outcome within the status, which must not leak into the proposal.
+	cpms := proposed.DeepCopy()
+
+	if cpms.Spec.Replicas == nil {
+		return nil, errReplicasRequired
+	}
+
+	providerCPMS, err := r.resolveFailureDomains(ctx, cpms)
+	if err != nil {
+		return nil, fmt.Errorf("error resolving failure domains: %w", err)
+	}
+
+	providerCPMS, err = r.discoverFailureDomains(ctx, logger, cpms, providerCPMS)
+	if err != nil {
+		return nil, fmt.Errorf("error discovering failure domains: %w", err)
+	}
+
+	machineProvider, err := providers.NewMachineProvider(ctx, logger, r.Client, providerCPMS)
+	if err != nil {
+		return nil, fmt.Errorf("error constructing machine provider: %w", err)
+	}
+
+	machineInfos, err := machineProvider.GetMachineInfos(ctx, logger)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching machine info: %w", err)
+	}
+
+	indexedMachineInfos, err := machineInfosByIndex(cpms, machineInfos)
+	if err != nil {
+		return nil, fmt.Errorf("could not sort machine info by index: %w", err)
+	}
+
+	return &WhatIfReport{
+		Strategy: cpms.Spec.Strategy.Type,
+		Replicas: *cpms.Spec.Replicas,
+		Steps:    whatIfSteps(indexedMachineInfos, machineProvider.IndexFailureDomains(), *cpms.Spec.Replicas),
+	}, nil
+}
+
+// whatIfSteps determines the steps that the update strategies would take for the indexed Machines, in order.
+// As during a reconcile, Machines are created for indexes without any Machine first, then replacements that are
+// already in progress are completed, and then the outdated Machines are replaced in order of their index. Machines
+// beyond the replicas, that would need to be removed manually, are listed last, unless they are already being
+// deleted.
+func whatIfSteps(indexedMachineInfos map[int32][]machineproviders.MachineInfo, indexFailureDomains map[int32]string, replicas int32) []WhatIfStep {
+	var creates, inProgress, pending, removals []WhatIfStep
+
+	for _, idx := range sortedIndexes(indexedMachineInfos) {
+		machineInfos := indexedMachineInfos[idx]
+
+		if idx >= replicas {
+			for _, machineInfo := range machineInfos {
+				if machineInfo.MachineRef == nil || machineInfo.MachineRef.ObjectMeta.GetDeletionTimestamp() != nil {
+					continue
+				}
+
+				removals = append(removals, WhatIfStep{
+					Action:        WhatIfRemove,
+					Index:         idx,
+					MachineName:   machineInfo.MachineRef.ObjectMeta.GetName(),
+					FailureDomain: machineInfo.FailureDomain,
+				})
+			}
+
+			continue
+		}
+
+		if len(machineInfos) == 0 {
+			creates = append(creates, WhatIfStep{
+				Action:               WhatIfCreate,
+				Index:                idx,
+				DesiredFailureDomain: indexFailureDomains[idx],
+			})
+
+			continue
+		}
+
+		outdatedMachines, updatedMachines := splitOutdatedMachines(machineInfos)
+
+		for _, machineInfo := range outdatedMachines {
+			step := WhatIfStep{
+				Action:               WhatIfReplace,
+				Index:                idx,
+				FailureDomain:        machineInfo.FailureDomain,
+				DesiredFailureDomain: indexFailureDomains[idx],
+				InProgress:           len(updatedMachines) > 0,
+			}
+
+			if machineInfo.MachineRef != nil {
+				step.MachineName = machineInfo.MachineRef.ObjectMeta.GetName()
+			}
+
+			if step.InProgress {
+				inProgress = append(inProgress, step)
+			} else {
+				pending = append(pending, step)
+			}
+		}
+	}
+
+	steps := []WhatIfStep{}
+
+	for _, group := range [][]WhatIfStep{creates, inProgress, pending, removals} {
+		for _, step := range group {
+			step.Order = len(steps) + 1
+			steps = append(steps, step)
+		}
+	}
+
+	return steps
+}
@@ -0,0 +1,208 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controlplanemachineset
+
+import (
+	"fmt"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	machinev1 "github.com/openshift/api/machine/v1"
+	machinev1beta1 "github.com/openshift/api/machine/v1beta1"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/test"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/test/resourcebuilder"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var _ = Describe("What if", func() {
+	Context("whatIfSteps", func() {
+		machineBuilder := resourcebuilder.MachineInfo().WithMachineGVR(machinev1beta1.GroupVersion.WithResource("machines"))
+
+		indexFailureDomains := map[int32]string{0: "us-east-1a", 1: "us-east-1b", 2: "us-east-1c"}
+
+		machine := func(idx int32, failureDomain string, needsUpdate bool) machineproviders.MachineInfo {
+			return machineBuilder.WithIndex(idx).WithMachineName(fmt.Sprintf("master-%d", idx)).
+				WithFailureDomain(failureDomain).WithNeedsUpdate(needsUpdate).Build()
+		}
+
+		It("lists no steps when every Machine is up to date", func() {
+			steps := whatIfSteps(map[int32][]machineproviders.MachineInfo{
+				0: {machine(0, "us-east-1a", false)},
+				1: {machine(1, "us-east-1b", false)},
+				2: {machine(2, "us-east-1c", false)},
+			}, indexFailureDomains, 3)
+
+			Expect(steps).To(BeEmpty())
+		})
+
+		It("orders creations, then replacements in progress, then the remaining replacements by index", func() {
+			replacement := machineBuilder.WithIndex(2).WithMachineName("master-replacement-2").WithFailureDomain("us-east-1c").Build()
+
+			steps := whatIfSteps(map[int32][]machineproviders.MachineInfo{
+				0: {machine(0, "us-east-1a", true)},
+				1: {},
+				2: {machine(2, "us-east-1b", true), replacement},
+			}, indexFailureDomains, 3)
+
+			Expect(steps).To(Equal([]WhatIfStep{
+				{Order: 1, Action: WhatIfCreate, Index: 1, DesiredFailureDomain: "us-east-1b"},
+				{Order: 2, Action: WhatIfReplace, Index: 2, MachineName: "master-2", FailureDomain: "us-east-1b", DesiredFailureDomain: "us-east-1c", InProgress: true},
+				{Order: 3, Action: WhatIfReplace, Index: 0, MachineName: "master-0", FailureDomain: "us-east-1a", DesiredFailureDomain: "us-east-1a"},
+			}))
+		})
+
+		It("lists Machines beyond the replicas for removal, unless they are already being deleted", func() {
+			deleting := machineBuilder.WithIndex(4).WithMachineName("master-4").WithFailureDomain("us-east-1b").
+				WithMachineDeletionTimestamp(metav1.Now()).Build()
+
+			steps := whatIfSteps(map[int32][]machineproviders.MachineInfo{
+				0: {machine(0, "us-east-1a", false)},
+				1: {machine(1, "us-east-1b", false)},
+				2: {machine(2, "us-east-1c", false)},
+				3: {machine(3, "us-east-1a", false)},
+				4: {deleting},
+			}, indexFailureDomains, 3)
+
+			Expect(steps).To(Equal([]WhatIfStep{
+				{Order: 1, Action: WhatIfRemove, Index: 3, MachineName: "master-3", FailureDomain: "us-east-1a"},
+			}))
+		})
+	})
+
+	Context("WhatIfStep", func() {
+		DescribeTable("describes the step", func(step WhatIfStep, expected string) {
+			Expect(step.String()).To(Equal(expected))
+		},
+			Entry("when creating a Machine", WhatIfStep{Action: WhatIfCreate, Index: 3, DesiredFailureDomain: "us-east-1a"},
+				"Create a Machine for index 3 in failure domain us-east-1a"),
+			Entry("when creating a Machine without failure domains", WhatIfStep{Action: WhatIfCreate, Index: 3},
+				"Create a Machine for index 3"),
+			Entry("when replacing a Machine into another failure domain", WhatIfStep{Action: WhatIfReplace, Index: 1, MachineName: "master-1", FailureDomain: "us-east-1b", DesiredFailureDomain: "us-east-1d"},
+				"Replace Machine master-1 in index 1, moving from failure domain us-east-1b to us-east-1d"),
+			Entry("when replacing a Machine outside every failure domain", WhatIfStep{Action: WhatIfReplace, Index: 1, MachineName: "master-1", DesiredFailureDomain: "us-east-1d"},
+				"Replace Machine master-1 in index 1, moving into failure domain us-east-1d"),
+			Entry("when replacing a Machine within its failure domain", WhatIfStep{Action: WhatIfReplace, Index: 1, MachineName: "master-1", FailureDomain: "us-east-1b", DesiredFailureDomain: "us-east-1b", InProgress: true},
+				"Replace Machine master-1 in index 1, within failure domain us-east-1b (replacement in progress)"),
+			Entry("when removing a Machine", WhatIfStep{Action: WhatIfRemove, Index: 4, MachineName: "master-4", FailureDomain: "us-east-1b"},
+				"Remove Machine master-4 in index 4, beyond the replicas, from failure domain us-east-1b"),
+		)
+	})
+
+	Context("WhatIf", func() {
+		var namespaceName string
+		var reconciler *ControlPlaneMachineSetReconciler
+		var proposed *machinev1.ControlPlaneMachineSet
+
+		const clusterID = "cpms-cluster-test-id"
+
+		BeforeEach(func() {
+			By("Setting up a namespace for the test")
+			ns := resourcebuilder.Namespace().WithGenerateName("control-plane-machine-set-controller-").Build()
+			Expect(k8sClient.Create(ctx, ns)).To(Succeed())
+			namespaceName = ns.GetName()
+
+			reconciler = &ControlPlaneMachineSetReconciler{
+				Client:    k8sClient,
+				APIReader: k8sClient,
+				Scheme:    testScheme,
+				Namespace: namespaceName,
+			}
+
+			By("Creating Control Plane Machines matching the template")
+			for idx := 0; idx < 3; idx++ {
+				machine := resourcebuilder.Machine().AsMaster().WithNamespace(namespaceName).
+					WithName(fmt.Sprintf("%s-master-%d", clusterID, idx)).
+					WithProviderSpecBuilder(resourcebuilder.AWSProviderSpec()).Build()
+				Expect(k8sClient.Create(ctx, machine)).To(Succeed())
+			}
+
+			proposed = resourcebuilder.ControlPlaneMachineSet().WithNamespace(namespaceName).
+				WithMachineTemplateBuilder(resourcebuilder.OpenShiftMachineV1Beta1Template().
+					WithProviderSpecBuilder(resourcebuilder.AWSProviderSpec()),
+				).Build()
+		})
+
+		AfterEach(func() {
+			test.CleanupResources(Default, ctx, cfg, k8sClient, namespaceName,
+				&machinev1beta1.Machine{},
+			)
+		})
+
+		It("reports no steps when the proposal matches the Machines", func() {
+			report, err := reconciler.WhatIf(ctx, test.NewTestLogger().Logger(), proposed)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(report.Strategy).To(Equal(machinev1.RollingUpdate))
+			Expect(report.Replicas).To(BeEquivalentTo(3))
+			Expect(report.Steps).To(BeEmpty())
+		})
+
+		It("reports the Machines that would be created when the replicas are increased", func() {
+			proposed.Spec.Replicas = pointer.Int32(5)
+
+			report, err := reconciler.WhatIf(ctx, test.NewTestLogger().Logger(), proposed)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(report.Steps).To(Equal([]WhatIfStep{
+				{Order: 1, Action: WhatIfCreate, Index: 3},
+				{Order: 2, Action: WhatIfCreate, Index: 4},
+			}))
+		})
+
+		It("reports the Machines that would be replaced, in order of their index, when the template is changed", func() {
+			proposed.Spec.Template.OpenShiftMachineV1Beta1Machine.Spec.ProviderSpec.Value = resourcebuilder.AWSProviderSpec().
+				WithInstanceType("c5.2xlarge").BuildRawExtension()
+
+			report, err := reconciler.WhatIf(ctx, test.NewTestLogger().Logger(), proposed)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(report.Steps).To(Equal([]WhatIfStep{
+				{Order: 1, Action: WhatIfReplace, Index: 0, MachineName: clusterID + "-master-0"},
+				{Order: 2, Action: WhatIfReplace, Index: 1, MachineName: clusterID + "-master-1"},
+				{Order: 3, Action: WhatIfReplace, Index: 2, MachineName: clusterID + "-master-2"},
+			}))
+		})
+
+		It("reports the Machines beyond the replicas that would need to be removed", func() {
+			proposed.Spec.Replicas = pointer.Int32(2)
+
+			report, err := reconciler.WhatIf(ctx, test.NewTestLogger().Logger(), proposed)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(report.Steps).To(Equal([]WhatIfStep{
+				{Order: 1, Action: WhatIfRemove, Index: 2, MachineName: clusterID + "-master-2"},
+			}))
+		})
+
+		It("does not modify the proposal or the Machines", func() {
+			proposed.Spec.Replicas = pointer.Int32(5)
+			original := proposed.DeepCopy()
+
+			_, err := reconciler.WhatIf(ctx, test.NewTestLogger().Logger(), proposed)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(proposed).To(Equal(original))
+
+			machines := &machinev1beta1.MachineList{}
+			Expect(k8sClient.List(ctx, machines, client.InNamespace(namespaceName))).To(Succeed())
+			Expect(machines.Items).To(HaveLen(3))
+		})
+	})
+})
@@ -0,0 +1,267 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package operatorconfig
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"k8s.io/apimachinery/pkg/api/equality"
+)
+
+const (
+	// ConfigMapName is the name of the ConfigMap, within the namespace of the operator, that holds the runtime
+	// configuration of the operator.
+	ConfigMapName = "control-plane-machine-set-operator-config"
+
+	// logLevelKey is the key of the operator configuration that sets the verbosity of the operator logs.
+	logLevelKey = "logLevel"
+
+	// deleteDepartedNodesKey is the key of the operator configuration that overrides --delete-departed-nodes.
+	deleteDepartedNodesKey = "deleteDepartedNodes"
+
+	// repairMissingTagsKey is the key of the operator configuration that overrides --repair-missing-tags.
+	repairMissingTagsKey = "repairMissingTags"
+
+	// enableScaleDownKey is the key of the operator configuration that overrides --enable-control-plane-scale-down.
+	enableScaleDownKey = "enableControlPlaneScaleDown"
+
+	// rejectUndersizedMachinesKey is the key of the operator configuration that overrides
+	// --reject-undersized-control-plane-machines.
+	rejectUndersizedMachinesKey = "rejectUndersizedControlPlaneMachines"
+)
+
+// LogLevel is the verbosity of the operator logs.
+type LogLevel string
+
+const (
+	// LogLevelNormal logs the messages of verbosity 2 and below.
+	LogLevelNormal LogLevel = "Normal"
+
+	// LogLevelDebug logs the messages of verbosity 4 and below.
+	LogLevelDebug LogLevel = "Debug"
+
+	// LogLevelTrace logs the messages of verbosity 6 and below.
+	LogLevelTrace LogLevel = "Trace"
+
+	// LogLevelTraceAll logs the messages of verbosity 8 and below.
+	LogLevelTraceAll LogLevel = "TraceAll"
+)
+
+// logLevelVerbosity maps each log level to the highest verbosity of the messages that are logged.
+var logLevelVerbosity = map[LogLevel]int8{
+	LogLevelNormal:   2,
+	LogLevelDebug:    4,
+	LogLevelTrace:    6,
+	LogLevelTraceAll: 8,
+}
+
+// errInvalidConfig is used to denote that the operator configuration could not be parsed.
+var errInvalidConfig = errors.New("invalid operator configuration")
+
+// Config is the runtime configuration of the operator.
+// Unset fields leave the behaviour configured by the flags of the operator unchanged.
+type Config struct {
+	// LogLevel is the verbosity of the operator logs.
+	LogLevel LogLevel
+
+	// DeleteDepartedNodes overrides whether departed control plane Nodes are deleted.
+	DeleteDepartedNodes *bool
+
+	// RepairMissingTags overrides whether missing tags are repaired on the Control Plane Machines.
+	RepairMissingTags *bool
+
+	// EnableScaleDown overrides whether the webhook admits decreases to the replicas of the ControlPlaneMachineSet.
+	EnableScaleDown *bool
+
+	// RejectUndersizedMachines overrides whether the webhook rejects undersized Control Plane Machines.
+	RejectUndersizedMachines *bool
+}
+
+// ParseConfig parses the data of the operator configuration ConfigMap.
+// Each key of the data sets a single field of the configuration. Unknown keys are rejected, so that a misspelled key
+// is not silently ignored.
+func ParseConfig(data map[string]string) (Config, error) {
+	config := Config{}
+	errs := []string{}
+
+	boolFields := map[string]**bool{
+		deleteDepartedNodesKey:      &config.DeleteDepartedNodes,
+		repairMissingTagsKey:        &config.RepairMissingTags,
+		enableScaleDownKey:          &config.EnableScaleDown,
+		rejectUndersizedMachinesKey: &config.RejectUndersizedMachines,
+	}
+
+	keys := []string{}
+	for key := range data {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		value := strings.TrimSpace(data[key])
+
+		if key == logLevelKey {
+			if _, ok := logLevelVerbosity[LogLevel(value)]; !ok {
+				errs = append(errs, fmt.Sprintf("%s: expected one of Normal, Debug, Trace or TraceAll, got %q", key, value))
+				continue
+			}
+
+			config.LogLevel = LogLevel(value)
+
+			continue
+		}
+
+		field, ok := boolFields[key]
+		if !ok {
+			errs = append(errs, fmt.Sprintf("unknown key %q", key))
+			continue
+		}
+
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: expected true or false, got %q", key, value))
+			continue
+		}
+
+		*field = &parsed
+	}
+
+	if len(errs) > 0 {
+		return Config{}, fmt.Errorf("%w: %s", errInvalidConfig, strings.Join(errs, "; "))
+	}
+
+	return config, nil
+}
+
+// Store holds the runtime configuration of the operator, so that it can be changed while the operator is running.
+// A nil Store holds no configuration, so each accessor returns the default it is given.
+type Store struct {
+	lock   sync.RWMutex
+	config Config
+
+	level        zap.AtomicLevel
+	defaultLevel zapcore.Level
+}
+
+// NewStore creates a Store that applies the log level of the configuration to the level provided. The current
+// level is restored whenever the configuration does not set a log level.
+func NewStore(level zap.AtomicLevel) *Store {
+	return &Store{
+		level:        level,
+		defaultLevel: level.Level(),
+	}
+}
+
+// Set replaces the configuration held by the Store and applies its log level.
+// It returns whether the configuration changed.
+func (s *Store) Set(config Config) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	changed := !equality.Semantic.DeepEqual(s.config, config)
+	s.config = config
+
+	if verbosity, ok := logLevelVerbosity[config.LogLevel]; ok {
+		s.level.SetLevel(zapcore.Level(-verbosity))
+	} else {
+		s.level.SetLevel(s.defaultLevel)
+	}
+
+	return changed
+}
+
+// Config returns the configuration held by the Store.
+func (s *Store) Config() Config {
+	if s == nil {
+		return Config{}
+	}
+
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	return s.config
+}
+
+// DeleteDepartedNodes returns whether departed control plane Nodes are deleted, or the default provided when the
+// configuration does not override it.
+func (s *Store) DeleteDepartedNodes(defaultValue bool) bool {
+	return boolOrDefault(s.Config().DeleteDepartedNodes, defaultValue)
+}
+
+// RepairMissingTags returns whether missing tags are repaired, or the default provided when the configuration does
+// not override it.
+func (s *Store) RepairMissingTags(defaultValue bool) bool {
+	return boolOrDefault(s.Config().RepairMissingTags, defaultValue)
+}
+
+// EnableScaleDown returns whether decreases to the replicas are admitted, or the default provided when the
+// configuration does not override it.
+func (s *Store) EnableScaleDown(defaultValue bool) bool {
+	return boolOrDefault(s.Config().EnableScaleDown, defaultValue)
+}
+
+// RejectUndersizedMachines returns whether undersized Control Plane Machines are rejected, or the default provided
+// when the configuration does not override it.
+func (s *Store) RejectUndersizedMachines(defaultValue bool) bool {
+	return boolOrDefault(s.Config().RejectUndersizedMachines, defaultValue)
+}
+
+// boolOrDefault dereferences the value when it is set, and otherwise returns the default.
+func boolOrDefault(value *bool, defaultValue bool) bool {
+	if value == nil {
+		return defaultValue
+	}
+
+	return *value
+}
+
+// String describes the fields that the configuration sets, eg `logLevel=Debug, repairMissingTags=true`.
+func (c Config) String() string {
+	fields := []string{}
+
+	if c.LogLevel != "" {
+		fields = append(fields, fmt.Sprintf("%s=%s", logLevelKey, c.LogLevel))
+	}
+
+	for _, field := range []struct {
+		key   string
+		value *bool
+	}{
+		{key: deleteDepartedNodesKey, value: c.DeleteDepartedNodes},
+		{key: repairMissingTagsKey, value: c.RepairMissingTags},
+		{key: enableScaleDownKey, value: c.EnableScaleDown},
+		{key: rejectUndersizedMachinesKey, value: c.RejectUndersizedMachines},
+	} {
+		if field.value != nil {
+			fields = append(fields, fmt.Sprintf("%s=%t", field.key, *field.value))
+		}
+	}
+
+	if len(fields) == 0 {
+		return "defaults"
+	}
+
+	return strings.Join(fields, ", ")
+}
@@ -0,0 +1,140 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package operatorconfig
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"k8s.io/utils/pointer"
+)
+
+var _ = Describe("Operator configuration", func() {
+	type parseConfigTableInput struct {
+		data           map[string]string
+		expectedConfig Config
+		expectedError  string
+	}
+
+	DescribeTable("ParseConfig", func(in parseConfigTableInput) {
+		config, err := ParseConfig(in.data)
+
+		if in.expectedError != "" {
+			Expect(err).To(MatchError(errInvalidConfig))
+			Expect(err).To(MatchError(ContainSubstring(in.expectedError)))
+		} else {
+			Expect(err).ToNot(HaveOccurred())
+		}
+
+		Expect(config).To(Equal(in.expectedConfig))
+	},
+		Entry("with no data", parseConfigTableInput{
+			data:           nil,
+			expectedConfig: Config{},
+		}),
+		Entry("with every key", parseConfigTableInput{
+			data: map[string]string{
+				logLevelKey:                 "Debug",
+				deleteDepartedNodesKey:      "true",
+				repairMissingTagsKey:        "false",
+				enableScaleDownKey:          " true ",
+				rejectUndersizedMachinesKey: "true",
+			},
+			expectedConfig: Config{
+				LogLevel:                 LogLevelDebug,
+				DeleteDepartedNodes:      pointer.Bool(true),
+				RepairMissingTags:        pointer.Bool(false),
+				EnableScaleDown:          pointer.Bool(true),
+				RejectUndersizedMachines: pointer.Bool(true),
+			},
+		}),
+		Entry("with an invalid log level", parseConfigTableInput{
+			data:          map[string]string{logLevelKey: "debug"},
+			expectedError: `logLevel: expected one of Normal, Debug, Trace or TraceAll, got "debug"`,
+		}),
+		Entry("with an invalid boolean", parseConfigTableInput{
+			data:          map[string]string{repairMissingTagsKey: "yes"},
+			expectedError: `repairMissingTags: expected true or false, got "yes"`,
+		}),
+		Entry("with an unknown key", parseConfigTableInput{
+			data:          map[string]string{"repairMissingTag": "true"},
+			expectedError: `unknown key "repairMissingTag"`,
+		}),
+		Entry("with several errors", parseConfigTableInput{
+			data:          map[string]string{"a": "b", logLevelKey: "Loud"},
+			expectedError: `unknown key "a"; logLevel: expected one of Normal, Debug, Trace or TraceAll, got "Loud"`,
+		}),
+	)
+
+	Context("Store", func() {
+		var level zap.AtomicLevel
+		var store *Store
+
+		BeforeEach(func() {
+			level = zap.NewAtomicLevelAt(zapcore.InfoLevel)
+			store = NewStore(level)
+		})
+
+		It("returns the defaults without a configuration", func() {
+			Expect(store.DeleteDepartedNodes(true)).To(BeTrue())
+			Expect(store.RepairMissingTags(false)).To(BeFalse())
+			Expect(store.EnableScaleDown(true)).To(BeTrue())
+			Expect(store.RejectUndersizedMachines(false)).To(BeFalse())
+		})
+
+		It("returns the defaults when the store is nil", func() {
+			var nilStore *Store
+
+			Expect(nilStore.Config()).To(Equal(Config{}))
+			Expect(nilStore.RepairMissingTags(true)).To(BeTrue())
+		})
+
+		It("returns the overrides of the configuration", func() {
+			Expect(store.Set(Config{RepairMissingTags: pointer.Bool(true), EnableScaleDown: pointer.Bool(false)})).To(BeTrue())
+
+			Expect(store.RepairMissingTags(false)).To(BeTrue())
+			Expect(store.EnableScaleDown(true)).To(BeFalse())
+			Expect(store.DeleteDepartedNodes(true)).To(BeTrue())
+		})
+
+		It("reports whether the configuration changed", func() {
+			Expect(store.Set(Config{RepairMissingTags: pointer.Bool(true)})).To(BeTrue())
+			Expect(store.Set(Config{RepairMissingTags: pointer.Bool(true)})).To(BeFalse())
+			Expect(store.Set(Config{})).To(BeTrue())
+		})
+
+		It("applies the log level, and restores the original level once it is unset", func() {
+			store.Set(Config{LogLevel: LogLevelTrace})
+			Expect(level.Level()).To(Equal(zapcore.Level(-6)))
+
+			store.Set(Config{})
+			Expect(level.Level()).To(Equal(zapcore.InfoLevel))
+		})
+	})
+
+	Context("String", func() {
+		It("describes the fields that are set", func() {
+			Expect(Config{LogLevel: LogLevelDebug, RepairMissingTags: pointer.Bool(true)}.String()).To(Equal("logLevel=Debug, repairMissingTags=true"))
+		})
+
+		It("describes an empty configuration as the defaults", func() {
+			Expect(Config{}.String()).To(Equal("defaults"))
+		})
+	})
+})
@@ -0,0 +1,106 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package operatorconfig
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+)
+
+const (
+	// controllerName is the name of the operator configuration controller.
+	controllerName = "operator-config-controller"
+
+	// appliedConfig is a log message used to inform the user that the operator configuration has changed.
+	appliedConfig = "Applied operator configuration"
+
+	// ignoredInvalidConfig is a log message used to inform the user that the operator configuration is invalid, and
+	// that the previous configuration is still in effect.
+	ignoredInvalidConfig = "Ignoring invalid operator configuration, the previous configuration remains in effect"
+)
+
+// OperatorConfigReconciler applies the operator configuration ConfigMap to the Store, so that changes to the
+// configuration take effect without restarting the operator.
+type OperatorConfigReconciler struct {
+	client.Client
+
+	// Namespace is the namespace of the operator, in which the operator configuration ConfigMap is read.
+	Namespace string
+
+	// Store holds the configuration applied by the reconciler.
+	Store *Store
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *OperatorConfigReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	if err := ctrl.NewControllerManagedBy(mgr).
+		Named(controllerName).
+		For(&corev1.ConfigMap{}, builder.WithPredicates(filterOperatorConfig(r.Namespace))).
+		Complete(r); err != nil {
+		return fmt.Errorf("failed to create controller: %w", err)
+	}
+
+	return nil
+}
+
+// Reconcile reads the operator configuration ConfigMap and applies it to the Store.
+// When the ConfigMap does not exist, the configuration is cleared and the flags of the operator apply. An invalid
+// configuration is logged and ignored rather than retried, as it can only be resolved by the user correcting the
+// ConfigMap, which triggers a new reconcile.
+func (r *OperatorConfigReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := log.FromContext(ctx, "namespace", req.Namespace, "name", req.Name)
+
+	configMap := &corev1.ConfigMap{}
+	config := Config{}
+
+	err := r.Get(ctx, req.NamespacedName, configMap)
+
+	switch {
+	case apierrors.IsNotFound(err):
+		// Without the ConfigMap, the flags of the operator apply.
+	case err != nil:
+		return ctrl.Result{}, fmt.Errorf("could not fetch operator configuration: %w", err)
+	default:
+		config, err = ParseConfig(configMap.Data)
+		if err != nil {
+			logger.Error(err, ignoredInvalidConfig)
+
+			return ctrl.Result{}, nil
+		}
+	}
+
+	if r.Store.Set(config) {
+		logger.Info(appliedConfig, "config", config.String())
+	}
+
+	return ctrl.Result{}, nil
+}
+
+// filterOperatorConfig filters requests to just the operator configuration ConfigMap within the namespace.
+func filterOperatorConfig(namespace string) predicate.Predicate {
+	return predicate.NewPredicateFuncs(func(obj client.Object) bool {
+		return obj.GetNamespace() == namespace && obj.GetName() == ConfigMapName
+	})
+}
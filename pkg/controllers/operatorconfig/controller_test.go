@@ -0,0 +1,104 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package operatorconfig
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/pointer"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+)
+
+var _ = Describe("OperatorConfigReconciler", func() {
+	var namespaceName string
+	var store *Store
+	var reconciler *OperatorConfigReconciler
+	var request ctrl.Request
+
+	genericEvent := func(obj client.Object) event.GenericEvent {
+		return event.GenericEvent{Object: obj}
+	}
+
+	BeforeEach(func() {
+		By("Setting up a namespace for the test")
+		ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{GenerateName: "operator-config-controller-"}}
+		Expect(k8sClient.Create(ctx, ns)).To(Succeed())
+		namespaceName = ns.GetName()
+
+		store = NewStore(zap.NewAtomicLevelAt(zapcore.InfoLevel))
+		reconciler = &OperatorConfigReconciler{
+			Client:    k8sClient,
+			Namespace: namespaceName,
+			Store:     store,
+		}
+		request = ctrl.Request{NamespacedName: client.ObjectKey{Namespace: namespaceName, Name: ConfigMapName}}
+	})
+
+	createConfigMap := func(data map[string]string) {
+		configMap := &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: ConfigMapName, Namespace: namespaceName},
+			Data:       data,
+		}
+		Expect(k8sClient.Create(ctx, configMap)).To(Succeed())
+	}
+
+	It("applies the configuration of the ConfigMap", func() {
+		createConfigMap(map[string]string{repairMissingTagsKey: "true"})
+
+		Expect(reconciler.Reconcile(ctx, request)).To(Equal(ctrl.Result{}))
+		Expect(store.Config()).To(Equal(Config{RepairMissingTags: pointer.Bool(true)}))
+	})
+
+	It("clears the configuration when the ConfigMap does not exist", func() {
+		store.Set(Config{RepairMissingTags: pointer.Bool(true)})
+
+		Expect(reconciler.Reconcile(ctx, request)).To(Equal(ctrl.Result{}))
+		Expect(store.Config()).To(Equal(Config{}))
+	})
+
+	It("keeps the previous configuration when the ConfigMap is invalid", func() {
+		store.Set(Config{RepairMissingTags: pointer.Bool(true)})
+		createConfigMap(map[string]string{repairMissingTagsKey: "sometimes"})
+
+		Expect(reconciler.Reconcile(ctx, request)).To(Equal(ctrl.Result{}))
+		Expect(store.Config()).To(Equal(Config{RepairMissingTags: pointer.Bool(true)}))
+	})
+
+	Context("filterOperatorConfig", func() {
+		It("selects the operator configuration ConfigMap", func() {
+			configMap := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: ConfigMapName, Namespace: namespaceName}}
+			Expect(filterOperatorConfig(namespaceName).Generic(genericEvent(configMap))).To(BeTrue())
+		})
+
+		It("ignores other ConfigMaps", func() {
+			configMap := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "kube-root-ca.crt", Namespace: namespaceName}}
+			Expect(filterOperatorConfig(namespaceName).Generic(genericEvent(configMap))).To(BeFalse())
+		})
+
+		It("ignores the ConfigMap within other namespaces", func() {
+			configMap := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: ConfigMapName, Namespace: "default"}}
+			Expect(filterOperatorConfig(namespaceName).Generic(genericEvent(configMap))).To(BeFalse())
+		})
+	})
+})
@@ -0,0 +1,242 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package lint checks ControlPlaneMachineSet manifests offline, with the validations of the admission webhook and
+// with best practice rules for the platform of the template, so that manifests can be checked before they are
+// applied, for example within a GitOps pipeline.
+package lint
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	configv1 "github.com/openshift/api/config/v1"
+	machinev1 "github.com/openshift/api/machine/v1"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders/providers/openshift/machine/v1beta1/providerconfig"
+	cpmswebhook "github.com/openshift/cluster-control-plane-machine-set-operator/pkg/webhooks/controlplanemachineset"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// Severity is the severity of a Finding.
+type Severity string
+
+const (
+	// SeverityError is the severity of findings that would cause the manifest to be rejected by the admission webhook.
+	SeverityError Severity = "Error"
+
+	// SeverityWarning is the severity of findings that would be admitted, but do not follow best practices.
+	SeverityWarning Severity = "Warning"
+)
+
+const (
+	// RuleValidation is the rule of findings from the validations of the admission webhook.
+	RuleValidation = "Validation"
+
+	// RuleAdmissionWarning is the rule of findings from the warnings raised by the admission webhook.
+	RuleAdmissionWarning = "AdmissionWarning"
+
+	// RuleAWSEBSEncryption is the rule of findings for AWS block devices that are not encrypted at rest.
+	RuleAWSEBSEncryption = "AWSEBSEncryption"
+
+	// RuleAWSEBSOptimization is the rule of findings for AWS instances with EBS optimization disabled.
+	RuleAWSEBSOptimization = "AWSEBSOptimization"
+
+	// RuleAWSIMDSv2 is the rule of findings for AWS instances that do not require IMDSv2.
+	RuleAWSIMDSv2 = "AWSIMDSv2"
+
+	// RuleAzureEncryptionAtHost is the rule of findings for Azure virtual machines without encryption at host.
+	RuleAzureEncryptionAtHost = "AzureEncryptionAtHost"
+)
+
+const (
+	// awsMetadataServiceAuthenticationRequired is the authentication of the AWS instance metadata service that
+	// requires session tokens, and so IMDSv2.
+	awsMetadataServiceAuthenticationRequired = "Required"
+)
+
+// Finding is a single problem found within a ControlPlaneMachineSet manifest.
+type Finding struct {
+	// Severity is the severity of the finding.
+	Severity Severity `json:"severity"`
+
+	// Rule is the rule that raised the finding.
+	Rule string `json:"rule"`
+
+	// Field is the path of the field that the finding relates to, when known.
+	Field string `json:"field,omitempty"`
+
+	// Message describes the finding.
+	Message string `json:"message"`
+}
+
+// String formats the finding for display.
+func (f Finding) String() string {
+	if f.Field == "" {
+		return fmt.Sprintf("%s [%s]: %s", f.Severity, f.Rule, f.Message)
+	}
+
+	return fmt.Sprintf("%s [%s] %s: %s", f.Severity, f.Rule, f.Field, f.Message)
+}
+
+// HasErrors returns whether any of the findings is an error.
+func HasErrors(findings []Finding) bool {
+	for _, finding := range findings {
+		if finding.Severity == SeverityError {
+			return true
+		}
+	}
+
+	return false
+}
+
+// awsRawProviderSpec holds the fields of the AWS provider spec that are only read from the raw provider spec, as
+// the AWSMachineProviderConfig does not include them.
+type awsRawProviderSpec struct {
+	// EBSOptimized enables EBS optimization of the instance.
+	EBSOptimized *bool `json:"ebsOptimized,omitempty"`
+
+	// MetadataServiceOptions configures the instance metadata service of the instance.
+	MetadataServiceOptions struct {
+		// Authentication is whether session tokens are required by the instance metadata service.
+		Authentication string `json:"authentication,omitempty"`
+	} `json:"metadataServiceOptions,omitempty"`
+}
+
+// Lint checks the ControlPlaneMachineSet as the admission webhook would on creation, and against the best practice
+// rules for the platform of its template.
+// The webhook should have no client, so that the validations that depend on the state of the cluster are skipped.
+// Best practice rules are only checked when the provider spec of the template can be parsed.
+func Lint(ctx context.Context, webhook *cpmswebhook.ControlPlaneMachineSetWebhook, cpms *machinev1.ControlPlaneMachineSet) []Finding {
+	findings := validationFindings(webhook.ValidateCreate(ctx, cpms))
+
+	for _, warning := range cpmswebhook.TemplateWarnings(cpms) {
+		findings = append(findings, Finding{Severity: SeverityWarning, Rule: RuleAdmissionWarning, Message: warning})
+	}
+
+	return append(findings, bestPracticeFindings(cpms.Spec.Template)...)
+}
+
+// validationFindings converts the error returned by the validations of the admission webhook into findings, one per
+// invalid field.
+func validationFindings(err error) []Finding {
+	if err == nil {
+		return nil
+	}
+
+	var statusErr apierrors.APIStatus
+	if !errors.As(err, &statusErr) || statusErr.Status().Details == nil || len(statusErr.Status().Details.Causes) == 0 {
+		return []Finding{{Severity: SeverityError, Rule: RuleValidation, Message: err.Error()}}
+	}
+
+	findings := []Finding{}
+
+	for _, cause := range statusErr.Status().Details.Causes {
+		findings = append(findings, Finding{Severity: SeverityError, Rule: RuleValidation, Field: cause.Field, Message: cause.Message})
+	}
+
+	return findings
+}
+
+// bestPracticeFindings checks the template against the best practice rules for its platform.
+func bestPracticeFindings(template machinev1.ControlPlaneMachineSetTemplate) []Finding {
+	if template.OpenShiftMachineV1Beta1Machine == nil {
+		return nil
+	}
+
+	providerConfig, err := providerconfig.NewProviderConfig(*template.OpenShiftMachineV1Beta1Machine)
+	if err != nil {
+		return nil
+	}
+
+	providerSpecPath := field.NewPath("spec", "template", "machines_v1beta1_machine_openshift_io", "spec", "providerSpec", "value")
+
+	switch providerConfig.Type() {
+	case configv1.AWSPlatformType:
+		raw := awsRawProviderSpec{}
+
+		if value := template.OpenShiftMachineV1Beta1Machine.Spec.ProviderSpec.Value; value != nil {
+			// The provider spec has already been parsed, so it is valid JSON.
+			_ = json.Unmarshal(value.Raw, &raw)
+		}
+
+		return awsFindings(providerSpecPath, providerConfig.AWS(), raw)
+	case configv1.AzurePlatformType:
+		return azureFindings(providerSpecPath, providerConfig.Azure())
+	default:
+		return nil
+	}
+}
+
+// awsFindings checks that every EBS block device is encrypted, that EBS optimization is not disabled and that the
+// instance metadata service requires IMDSv2.
+func awsFindings(providerSpecPath *field.Path, config providerconfig.AWSProviderConfig, raw awsRawProviderSpec) []Finding {
+	findings := []Finding{}
+
+	for i, device := range config.Config().BlockDevices {
+		if device.EBS == nil {
+			continue
+		}
+
+		if device.EBS.Encrypted == nil || !*device.EBS.Encrypted {
+			findings = append(findings, Finding{
+				Severity: SeverityWarning,
+				Rule:     RuleAWSEBSEncryption,
+				Field:    providerSpecPath.Child("blockDevices").Index(i).Child("ebs", "encrypted").String(),
+				Message:  "the EBS volume is not encrypted at rest, set encrypted to true",
+			})
+		}
+	}
+
+	if raw.EBSOptimized != nil && !*raw.EBSOptimized {
+		findings = append(findings, Finding{
+			Severity: SeverityWarning,
+			Rule:     RuleAWSEBSOptimization,
+			Field:    providerSpecPath.Child("ebsOptimized").String(),
+			Message:  "EBS optimization is disabled, which may starve etcd of disk throughput",
+		})
+	}
+
+	if raw.MetadataServiceOptions.Authentication != awsMetadataServiceAuthenticationRequired {
+		findings = append(findings, Finding{
+			Severity: SeverityWarning,
+			Rule:     RuleAWSIMDSv2,
+			Field:    providerSpecPath.Child("metadataServiceOptions", "authentication").String(),
+			Message:  fmt.Sprintf("the instance metadata service allows IMDSv1, set authentication to %s to require IMDSv2", awsMetadataServiceAuthenticationRequired),
+		})
+	}
+
+	return findings
+}
+
+// azureFindings checks that the virtual machine is encrypted at host, so that its temporary and cache disks are
+// encrypted at rest.
+func azureFindings(providerSpecPath *field.Path, config providerconfig.AzureProviderConfig) []Finding {
+	securityProfile := config.Config().SecurityProfile
+
+	if securityProfile != nil && securityProfile.EncryptionAtHost != nil && *securityProfile.EncryptionAtHost {
+		return nil
+	}
+
+	return []Finding{{
+		Severity: SeverityWarning,
+		Rule:     RuleAzureEncryptionAtHost,
+		Field:    providerSpecPath.Child("securityProfile", "encryptionAtHost").String(),
+		Message:  "encryption at host is not enabled, so the temporary and cache disks are not encrypted at rest",
+	}}
+}
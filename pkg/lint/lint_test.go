@@ -0,0 +1,201 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lint
+
+import (
+	"context"
+	"encoding/json"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	machinev1 "github.com/openshift/api/machine/v1"
+	machinev1beta1 "github.com/openshift/api/machine/v1beta1"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/test/resourcebuilder"
+	cpmswebhook "github.com/openshift/cluster-control-plane-machine-set-operator/pkg/webhooks/controlplanemachineset"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/utils/pointer"
+)
+
+// rawProviderSpec is a RawExtensionBuilder that adds fields to the raw extension built by another builder, so that
+// fields not included within the typed provider specs can be set.
+type rawProviderSpec struct {
+	builder resourcebuilder.RawExtensionBuilder
+	fields  map[string]interface{}
+}
+
+// BuildRawExtension builds the raw extension of the builder, with the additional fields set.
+func (r rawProviderSpec) BuildRawExtension() *runtime.RawExtension {
+	fields := map[string]interface{}{}
+	Expect(json.Unmarshal(r.builder.BuildRawExtension().Raw, &fields)).To(Succeed())
+
+	for key, value := range r.fields {
+		fields[key] = value
+	}
+
+	raw, err := json.Marshal(fields)
+	Expect(err).ToNot(HaveOccurred())
+
+	return &runtime.RawExtension{Raw: raw}
+}
+
+var _ = Describe("Lint", func() {
+	var webhook *cpmswebhook.ControlPlaneMachineSetWebhook
+
+	// awsIMDSv2 sets the instance metadata service of an AWS provider spec to require IMDSv2.
+	awsIMDSv2 := map[string]interface{}{
+		"metadataServiceOptions": map[string]interface{}{"authentication": "Required"},
+	}
+
+	// cpmsWithProviderSpec builds a ControlPlaneMachineSet with the provider spec and failure domains given.
+	cpmsWithProviderSpec := func(providerSpec resourcebuilder.RawExtensionBuilder, failureDomains resourcebuilder.OpenShiftMachineV1Beta1FailureDomainsBuilder) *machinev1.ControlPlaneMachineSet {
+		return resourcebuilder.ControlPlaneMachineSet().WithMachineTemplateBuilder(
+			resourcebuilder.OpenShiftMachineV1Beta1Template().
+				WithFailureDomainsBuilder(failureDomains).
+				WithProviderSpecBuilder(providerSpec),
+		).Build()
+	}
+
+	// rules returns the rules of the findings.
+	rules := func(findings []Finding) []string {
+		out := []string{}
+
+		for _, finding := range findings {
+			out = append(out, finding.Rule)
+		}
+
+		return out
+	}
+
+	BeforeEach(func() {
+		webhook = &cpmswebhook.ControlPlaneMachineSetWebhook{
+			ControlPlaneMachineSetName: "cluster",
+		}
+	})
+
+	It("has no findings for an AWS template that follows best practices", func() {
+		cpms := cpmsWithProviderSpec(rawProviderSpec{builder: resourcebuilder.AWSProviderSpec(), fields: awsIMDSv2}, resourcebuilder.AWSFailureDomains())
+
+		Expect(Lint(context.Background(), webhook, cpms)).To(BeEmpty())
+	})
+
+	It("reports the validations of the admission webhook as errors", func() {
+		cpms := cpmsWithProviderSpec(rawProviderSpec{builder: resourcebuilder.AWSProviderSpec(), fields: awsIMDSv2}, resourcebuilder.AWSFailureDomains())
+		cpms.Name = "other"
+
+		findings := Lint(context.Background(), webhook, cpms)
+		Expect(findings).To(ConsistOf(Finding{
+			Severity: SeverityError,
+			Rule:     RuleValidation,
+			Field:    "metadata.name",
+			Message:  `Invalid value: "other": ControlPlaneMachineSet is a singleton and must be named "cluster"`,
+		}))
+		Expect(HasErrors(findings)).To(BeTrue())
+	})
+
+	It("does not check best practice rules when the template has no provider spec", func() {
+		cpms := cpmsWithProviderSpec(nil, nil)
+
+		Expect(Lint(context.Background(), webhook, cpms)).To(BeEmpty())
+	})
+
+	It("warns about unencrypted EBS volumes, disabled EBS optimization and IMDSv1", func() {
+		providerSpec := resourcebuilder.AWSProviderSpec().WithBlockDevices([]machinev1beta1.BlockDeviceMappingSpec{
+			{
+				EBS: &machinev1beta1.EBSBlockDeviceSpec{
+					VolumeSize: pointer.Int64(120),
+					VolumeType: pointer.String("gp3"),
+				},
+			},
+		})
+		cpms := cpmsWithProviderSpec(rawProviderSpec{builder: providerSpec, fields: map[string]interface{}{"ebsOptimized": false}}, resourcebuilder.AWSFailureDomains())
+
+		findings := Lint(context.Background(), webhook, cpms)
+		Expect(findings).To(ConsistOf(
+			Finding{
+				Severity: SeverityWarning,
+				Rule:     RuleAWSEBSEncryption,
+				Field:    "spec.template.machines_v1beta1_machine_openshift_io.spec.providerSpec.value.blockDevices[0].ebs.encrypted",
+				Message:  "the EBS volume is not encrypted at rest, set encrypted to true",
+			},
+			Finding{
+				Severity: SeverityWarning,
+				Rule:     RuleAWSEBSOptimization,
+				Field:    "spec.template.machines_v1beta1_machine_openshift_io.spec.providerSpec.value.ebsOptimized",
+				Message:  "EBS optimization is disabled, which may starve etcd of disk throughput",
+			},
+			Finding{
+				Severity: SeverityWarning,
+				Rule:     RuleAWSIMDSv2,
+				Field:    "spec.template.machines_v1beta1_machine_openshift_io.spec.providerSpec.value.metadataServiceOptions.authentication",
+				Message:  "the instance metadata service allows IMDSv1, set authentication to Required to require IMDSv2",
+			},
+		))
+		Expect(HasErrors(findings)).To(BeFalse())
+	})
+
+	It("reports undersized machines as warnings", func() {
+		providerSpec := resourcebuilder.AWSProviderSpec().WithInstanceType("m6i.large")
+		cpms := cpmsWithProviderSpec(rawProviderSpec{builder: providerSpec, fields: awsIMDSv2}, resourcebuilder.AWSFailureDomains())
+
+		findings := Lint(context.Background(), webhook, cpms)
+		Expect(rules(findings)).To(ConsistOf(RuleAdmissionWarning))
+	})
+
+	It("reports undersized machines as errors when undersized machines are rejected", func() {
+		webhook.RejectUndersizedMachines = true
+
+		providerSpec := resourcebuilder.AWSProviderSpec().WithInstanceType("m6i.large")
+		cpms := cpmsWithProviderSpec(rawProviderSpec{builder: providerSpec, fields: awsIMDSv2}, resourcebuilder.AWSFailureDomains())
+
+		findings := Lint(context.Background(), webhook, cpms)
+		Expect(rules(findings)).To(ConsistOf(RuleValidation, RuleAdmissionWarning))
+		Expect(HasErrors(findings)).To(BeTrue())
+	})
+
+	It("warns about Azure virtual machines without encryption at host", func() {
+		cpms := cpmsWithProviderSpec(resourcebuilder.AzureProviderSpec(), resourcebuilder.AzureFailureDomains())
+
+		Expect(Lint(context.Background(), webhook, cpms)).To(ConsistOf(Finding{
+			Severity: SeverityWarning,
+			Rule:     RuleAzureEncryptionAtHost,
+			Field:    "spec.template.machines_v1beta1_machine_openshift_io.spec.providerSpec.value.securityProfile.encryptionAtHost",
+			Message:  "encryption at host is not enabled, so the temporary and cache disks are not encrypted at rest",
+		}))
+	})
+
+	It("has no findings for an Azure virtual machine with encryption at host", func() {
+		providerSpec := rawProviderSpec{
+			builder: resourcebuilder.AzureProviderSpec(),
+			fields:  map[string]interface{}{"securityProfile": map[string]interface{}{"encryptionAtHost": true}},
+		}
+		cpms := cpmsWithProviderSpec(providerSpec, resourcebuilder.AzureFailureDomains())
+
+		Expect(Lint(context.Background(), webhook, cpms)).To(BeEmpty())
+	})
+})
+
+var _ = Describe("Finding", func() {
+	It("formats the field when known", func() {
+		finding := Finding{Severity: SeverityWarning, Rule: RuleAWSIMDSv2, Field: "spec.a", Message: "message"}
+		Expect(finding.String()).To(Equal("Warning [AWSIMDSv2] spec.a: message"))
+	})
+
+	It("omits the field when unknown", func() {
+		finding := Finding{Severity: SeverityError, Rule: RuleValidation, Message: "message"}
+		Expect(finding.String()).To(Equal("Error [Validation]: message"))
+	})
+})
@@ -0,0 +1,103 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package machineproviders
+
+import "errors"
+
+// ErrorReason categorises a ConfigurationError. Each reason is a CamelCase string suitable for use as the reason
+// of a condition or event, and describes to the user what must be corrected.
+type ErrorReason string
+
+const (
+	// ReasonTemplatePlatformMismatch denotes that the platform of a Machine does not match the platform of the
+	// Machine template within the ControlPlaneMachineSet.
+	ReasonTemplatePlatformMismatch ErrorReason = "TemplatePlatformMismatch"
+
+	// ReasonUnsupportedPlatform denotes that the platform of the Machine template, or of the failure domains, is
+	// not supported by the ControlPlaneMachineSet.
+	ReasonUnsupportedPlatform ErrorReason = "UnsupportedPlatform"
+
+	// ReasonInvalidProviderSpec denotes that the provider spec of the Machine template, or of a Machine, could not
+	// be interpreted. For example, it is missing or has an unknown kind or API version.
+	ReasonInvalidProviderSpec ErrorReason = "InvalidProviderSpec"
+
+	// ReasonUnsupportedMachineType denotes that the machine type of the Machine template is not supported.
+	ReasonUnsupportedMachineType ErrorReason = "UnsupportedMachineType"
+
+	// ReasonInvalidMachineTemplate denotes that the Machine template within the ControlPlaneMachineSet is missing
+	// configuration required to create Machines. For example, the cluster ID label.
+	ReasonInvalidMachineTemplate ErrorReason = "InvalidMachineTemplate"
+
+	// ReasonInvalidImageStream denotes that the image stream annotation on the ControlPlaneMachineSet is not in the
+	// expected format.
+	ReasonInvalidImageStream ErrorReason = "InvalidImageStream"
+
+	// ReasonInvalidOwnedFields denotes that the externally owned fields annotation on the ControlPlaneMachineSet is
+	// not in the expected format, or claims a field that cannot be owned by another controller.
+	ReasonInvalidOwnedFields ErrorReason = "InvalidOwnedFields"
+
+	// ReasonImageNotFound denotes that the image for a Machine could not be resolved from the image stream.
+	ReasonImageNotFound ErrorReason = "ImageNotFound"
+
+	// ReasonInvalidFailureDomains denotes that the failure domains of the ControlPlaneMachineSet are invalid, or could
+	// not be sourced from the ConfigMap or failure domain source referenced by the ControlPlaneMachineSet. For
+	// example, the failure domains for the platform are missing, the ConfigMap does not exist or its contents could
+	// not be parsed, or the failure domain user data secrets annotation is not in the expected format.
+	ReasonInvalidFailureDomains ErrorReason = "InvalidFailureDomains"
+
+	// ReasonUnknownMachineIndex denotes that the index of a Control Plane Machine could not be determined from
+	// either its name or its failure domain.
+	ReasonUnknownMachineIndex ErrorReason = "UnknownMachineIndex"
+)
+
+// ConfigurationError is an error caused by the configuration of the ControlPlaneMachineSet, or of the Control Plane
+// Machines, that the user must correct before the ControlPlaneMachineSet can continue.
+// Sentinel errors are declared as ConfigurationErrors so that they may still be compared with errors.Is once wrapped,
+// while the reason can be extracted anywhere in the chain with ConfigurationErrorReason.
+type ConfigurationError struct {
+	reason  ErrorReason
+	message string
+}
+
+// NewConfigurationError creates a new ConfigurationError with the given reason and message.
+func NewConfigurationError(reason ErrorReason, message string) error {
+	return &ConfigurationError{
+		reason:  reason,
+		message: message,
+	}
+}
+
+// Error returns the message of the ConfigurationError.
+func (e *ConfigurationError) Error() string {
+	return e.message
+}
+
+// Reason returns the reason of the ConfigurationError.
+func (e *ConfigurationError) Reason() ErrorReason {
+	return e.reason
+}
+
+// ConfigurationErrorReason returns the reason of the first ConfigurationError within the chain of the error given.
+// It returns false when the error was not caused by a ConfigurationError.
+func ConfigurationErrorReason(err error) (ErrorReason, bool) {
+	var configErr *ConfigurationError
+	if !errors.As(err, &configErr) {
+		return "", false
+	}
+
+	return configErr.Reason(), true
+}
@@ -0,0 +1,155 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package failuredomainsource allows downstream builds of the operator to supply the failure domains of the
+// ControlPlaneMachineSet from an alternative source, such as an external resource, cloud discovery or an inventory
+// system, without changing how the failure domains are mapped to the Control Plane Machines.
+//
+// A source is registered under a name, typically from an init function of the package implementing it, and is
+// selected by annotating the ControlPlaneMachineSet with that name.
+package failuredomainsource
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	machinev1 "github.com/openshift/api/machine/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var (
+	// errInvalidName is used to denote that a source is registered with a name that is not a DNS label.
+	errInvalidName = errors.New("invalid failure domain source name")
+
+	// errNilSource is used to denote that a nil source is registered.
+	errNilSource = errors.New("failure domain source must not be nil")
+
+	// errAlreadyRegistered is used to denote that a source is registered with the name of another source.
+	errAlreadyRegistered = errors.New("failure domain source already registered")
+)
+
+// defaultRegistry is the registry that sources are registered with by MustRegister.
+var defaultRegistry = NewRegistry()
+
+// Source supplies the failure domains of a ControlPlaneMachineSet.
+type Source interface {
+	// FailureDomains returns the failure domains that the Control Plane Machines of the ControlPlaneMachineSet are
+	// spread across. They replace the failure domains within the template of the ControlPlaneMachineSet, and are
+	// then validated, mapped and injected in the same way.
+	// The reader is not backed by the cache of the manager, so may read any resource that the service account of
+	// the operator has been granted permission to get.
+	// Errors that the user must correct, such as a missing inventory entry, should be returned as a
+	// machineproviders.ConfigurationError, so that the ControlPlaneMachineSet is marked as degraded rather than the
+	// reconcile retried.
+	FailureDomains(ctx context.Context, cl client.Reader, cpms *machinev1.ControlPlaneMachineSet) (machinev1.FailureDomains, error)
+}
+
+// SourceFunc is an adapter to allow the use of an ordinary function as a Source.
+type SourceFunc func(ctx context.Context, cl client.Reader, cpms *machinev1.ControlPlaneMachineSet) (machinev1.FailureDomains, error)
+
+// FailureDomains calls f(ctx, cl, cpms).
+func (f SourceFunc) FailureDomains(ctx context.Context, cl client.Reader, cpms *machinev1.ControlPlaneMachineSet) (machinev1.FailureDomains, error) {
+	return f(ctx, cl, cpms)
+}
+
+// Registry holds the failure domain sources that a ControlPlaneMachineSet may select, by name.
+// It is safe for concurrent use.
+type Registry struct {
+	mu      sync.RWMutex
+	sources map[string]Source
+}
+
+// NewRegistry creates a new, empty, Registry.
+func NewRegistry() *Registry {
+	return &Registry{
+		sources: map[string]Source{},
+	}
+}
+
+// DefaultRegistry returns the registry that sources are registered with by MustRegister.
+func DefaultRegistry() *Registry {
+	return defaultRegistry
+}
+
+// MustRegister registers the source with the default registry, under the given name.
+// It panics if the source cannot be registered, so is intended to be called from an init function.
+func MustRegister(name string, source Source) {
+	if err := defaultRegistry.Register(name, source); err != nil {
+		panic(err)
+	}
+}
+
+// Register registers the source under the given name. The name must be a DNS label, eg `inventory`, and must not
+// already be registered.
+func (r *Registry) Register(name string, source Source) error {
+	if errs := validation.IsDNS1123Label(name); len(errs) > 0 {
+		return fmt.Errorf("%w %q: %s", errInvalidName, name, strings.Join(errs, ", "))
+	}
+
+	if source == nil {
+		return fmt.Errorf("%w: %s", errNilSource, name)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.sources[name]; ok {
+		return fmt.Errorf("%w: %s", errAlreadyRegistered, name)
+	}
+
+	r.sources[name] = source
+
+	return nil
+}
+
+// Source returns the source registered under the given name, if any.
+// A nil Registry holds no sources.
+func (r *Registry) Source(name string) (Source, bool) {
+	if r == nil {
+		return nil, false
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	source, ok := r.sources[name]
+
+	return source, ok
+}
+
+// Names returns the names of the registered sources, in alphabetical order.
+func (r *Registry) Names() []string {
+	if r == nil {
+		return nil
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	names := make([]string, 0, len(r.sources))
+	for name := range r.sources {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
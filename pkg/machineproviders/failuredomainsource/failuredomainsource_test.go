@@ -0,0 +1,102 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package failuredomainsource
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	configv1 "github.com/openshift/api/config/v1"
+	machinev1 "github.com/openshift/api/machine/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var _ = Describe("Registry", func() {
+	var registry *Registry
+
+	source := SourceFunc(func(_ context.Context, _ client.Reader, _ *machinev1.ControlPlaneMachineSet) (machinev1.FailureDomains, error) {
+		return machinev1.FailureDomains{Platform: configv1.GCPPlatformType}, nil
+	})
+
+	BeforeEach(func() {
+		registry = NewRegistry()
+	})
+
+	It("returns a registered source by name", func() {
+		Expect(registry.Register("inventory", source)).To(Succeed())
+
+		registered, ok := registry.Source("inventory")
+		Expect(ok).To(BeTrue())
+		Expect(registered.FailureDomains(context.Background(), nil, nil)).To(Equal(machinev1.FailureDomains{Platform: configv1.GCPPlatformType}))
+	})
+
+	It("does not return sources that have not been registered", func() {
+		Expect(registry.Register("inventory", source)).To(Succeed())
+
+		_, ok := registry.Source("cmdb")
+		Expect(ok).To(BeFalse())
+	})
+
+	It("lists the registered sources in alphabetical order", func() {
+		Expect(registry.Register("inventory", source)).To(Succeed())
+		Expect(registry.Register("cmdb", source)).To(Succeed())
+
+		Expect(registry.Names()).To(Equal([]string{"cmdb", "inventory"}))
+	})
+
+	It("holds no sources when nil", func() {
+		var nilRegistry *Registry
+
+		_, ok := nilRegistry.Source("inventory")
+		Expect(ok).To(BeFalse())
+		Expect(nilRegistry.Names()).To(BeEmpty())
+	})
+
+	It("rejects a source registered twice under the same name", func() {
+		Expect(registry.Register("inventory", source)).To(Succeed())
+
+		Expect(registry.Register("inventory", source)).To(MatchError(errAlreadyRegistered))
+	})
+
+	It("rejects a nil source", func() {
+		Expect(registry.Register("inventory", nil)).To(MatchError(errNilSource))
+	})
+
+	DescribeTable("rejects names that are not DNS labels", func(name string) {
+		Expect(registry.Register(name, source)).To(MatchError(errInvalidName))
+	},
+		Entry("with an empty name", ""),
+		Entry("with upper case characters", "Inventory"),
+		Entry("with a dot", "inventory.example.com"),
+		Entry("with a slash", "example.com/inventory"),
+	)
+
+	Context("MustRegister", func() {
+		It("registers the source with the default registry", func() {
+			MustRegister("must-register-test", source)
+
+			_, ok := DefaultRegistry().Source("must-register-test")
+			Expect(ok).To(BeTrue())
+		})
+
+		It("panics when the source cannot be registered", func() {
+			Expect(func() { MustRegister("Invalid", source) }).To(Panic())
+		})
+	})
+})
@@ -0,0 +1,205 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package faultinjection
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders"
+)
+
+const (
+	// EnabledEnvVar is the environment variable that enables fault injection. Faults are only injected when it is
+	// set to "true". This is a developer mode, intended for CI and chaos testing, and must never be enabled in
+	// production.
+	EnabledEnvVar = "CPMS_FAULT_INJECTION_ENABLED"
+
+	// CreateDelayEnvVar is the environment variable holding the duration by which the creation of each Machine is
+	// delayed, eg `2m`.
+	CreateDelayEnvVar = "CPMS_FAULT_INJECTION_CREATE_DELAY"
+
+	// DeleteFailureRateEnvVar is the environment variable holding the fraction, between 0 and 1, of Machine
+	// deletions that fail.
+	DeleteFailureRateEnvVar = "CPMS_FAULT_INJECTION_DELETE_FAILURE_RATE"
+
+	// FalseReadinessRateEnvVar is the environment variable holding the fraction, between 0 and 1, of Machines whose
+	// readiness is reported as the opposite of their actual readiness.
+	FalseReadinessRateEnvVar = "CPMS_FAULT_INJECTION_FALSE_READINESS_RATE"
+
+	// delayingMachineCreation is a log message used to inform the user that the creation of a Machine is delayed
+	// by fault injection.
+	delayingMachineCreation = "Fault injection: delaying machine creation"
+
+	// failingMachineDeletion is a log message used to inform the user that the deletion of a Machine is failed by
+	// fault injection.
+	failingMachineDeletion = "Fault injection: failing machine deletion"
+
+	// reportingFalseReadiness is a log message used to inform the user that the readiness of a Machine is reported
+	// falsely by fault injection.
+	reportingFalseReadiness = "Fault injection: reporting false machine readiness"
+)
+
+var (
+	// ErrInjectedDeletionFailure is returned by DeleteMachine when the deletion is failed by fault injection.
+	ErrInjectedDeletionFailure = errors.New("injected machine deletion failure")
+
+	// errInvalidRate is used to denote that a fault injection rate is not a fraction between 0 and 1.
+	errInvalidRate = errors.New("must be a number between 0 and 1")
+
+	// errInvalidDelay is used to denote that a fault injection delay is not a non-negative duration.
+	errInvalidDelay = errors.New("must be a non-negative duration")
+)
+
+// Config describes the faults injected into the MachineProvider.
+type Config struct {
+	// CreateDelay is the duration by which the creation of each Machine is delayed.
+	CreateDelay time.Duration
+
+	// DeleteFailureRate is the fraction of Machine deletions that fail with ErrInjectedDeletionFailure.
+	DeleteFailureRate float64
+
+	// FalseReadinessRate is the fraction of Machines whose readiness is reported as the opposite of their actual
+	// readiness, each time the Machines are collected.
+	FalseReadinessRate float64
+}
+
+// ConfigFromEnvironment reads the fault injection configuration from the environment, via the getenv function given.
+// When fault injection is not enabled, no configuration is returned.
+func ConfigFromEnvironment(getenv func(string) string) (*Config, error) {
+	if getenv(EnabledEnvVar) != "true" {
+		return nil, nil //nolint:nilnil
+	}
+
+	config := &Config{}
+
+	if value := getenv(CreateDelayEnvVar); value != "" {
+		delay, err := time.ParseDuration(value)
+		if err != nil || delay < 0 {
+			return nil, fmt.Errorf("invalid value for %s, got %q: %w", CreateDelayEnvVar, value, errInvalidDelay)
+		}
+
+		config.CreateDelay = delay
+	}
+
+	var err error
+
+	if config.DeleteFailureRate, err = parseRate(getenv, DeleteFailureRateEnvVar); err != nil {
+		return nil, err
+	}
+
+	if config.FalseReadinessRate, err = parseRate(getenv, FalseReadinessRateEnvVar); err != nil {
+		return nil, err
+	}
+
+	return config, nil
+}
+
+// parseRate parses the fraction held by the environment variable. When the variable is not set, the rate is zero.
+func parseRate(getenv func(string) string, name string) (float64, error) {
+	value := getenv(name)
+	if value == "" {
+		return 0, nil
+	}
+
+	rate, err := strconv.ParseFloat(value, 64)
+	if err != nil || rate < 0 || rate > 1 {
+		return 0, fmt.Errorf("invalid value for %s, got %q: %w", name, value, errInvalidRate)
+	}
+
+	return rate, nil
+}
+
+// faultInjectingMachineProvider wraps a MachineProvider and injects the faults described by its Config.
+// Functions without faults are passed through to the wrapped MachineProvider.
+type faultInjectingMachineProvider struct {
+	machineproviders.MachineProvider
+
+	// config describes the faults to inject.
+	config Config
+
+	// random returns a random number in [0, 1), used to decide whether a fault is injected.
+	random func() float64
+}
+
+// NewMachineProvider wraps the MachineProvider so that the faults described by the Config are injected into it.
+func NewMachineProvider(provider machineproviders.MachineProvider, config Config) machineproviders.MachineProvider {
+	return &faultInjectingMachineProvider{
+		MachineProvider: provider,
+		config:          config,
+		random:          rand.Float64, //nolint:gosec // Faults do not need a cryptographically secure source of randomness.
+	}
+}
+
+// GetMachineInfos collects the MachineInfos from the wrapped MachineProvider, and falsely reports the readiness of
+// a fraction of them.
+func (f *faultInjectingMachineProvider) GetMachineInfos(ctx context.Context, logger logr.Logger) ([]machineproviders.MachineInfo, error) {
+	machineInfos, err := f.MachineProvider.GetMachineInfos(ctx, logger)
+	if err != nil {
+		return nil, err //nolint:wrapcheck // Errors are returned unchanged so the wrapper is transparent to the caller.
+	}
+
+	for i := range machineInfos {
+		if !f.inject(f.config.FalseReadinessRate) {
+			continue
+		}
+
+		machineInfos[i].Ready = !machineInfos[i].Ready
+
+		logger.V(1).Info(reportingFalseReadiness, "index", machineInfos[i].Index, "reportedReady", machineInfos[i].Ready)
+	}
+
+	return machineInfos, nil
+}
+
+// CreateMachine delays the creation of the Machine, and then creates it with the wrapped MachineProvider.
+// When the context is cancelled during the delay, the Machine is not created.
+func (f *faultInjectingMachineProvider) CreateMachine(ctx context.Context, logger logr.Logger, index int32) error {
+	if f.config.CreateDelay > 0 {
+		logger.V(1).Info(delayingMachineCreation, "index", index, "delay", f.config.CreateDelay)
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("error creating new Machine for index %d: %w", index, ctx.Err())
+		case <-time.After(f.config.CreateDelay):
+		}
+	}
+
+	return f.MachineProvider.CreateMachine(ctx, logger, index) //nolint:wrapcheck // Errors are returned unchanged so the wrapper is transparent to the caller.
+}
+
+// DeleteMachine fails a fraction of Machine deletions, and otherwise deletes the Machine with the wrapped
+// MachineProvider.
+func (f *faultInjectingMachineProvider) DeleteMachine(ctx context.Context, logger logr.Logger, machineRef *machineproviders.ObjectRef) error {
+	if f.inject(f.config.DeleteFailureRate) {
+		logger.V(1).Info(failingMachineDeletion, "name", machineRef.ObjectMeta.GetName())
+
+		return fmt.Errorf("%w: %s", ErrInjectedDeletionFailure, machineRef.ObjectMeta.GetName())
+	}
+
+	return f.MachineProvider.DeleteMachine(ctx, logger, machineRef) //nolint:wrapcheck // Errors are returned unchanged so the wrapper is transparent to the caller.
+}
+
+// inject decides whether to inject a fault with the given rate.
+func (f *faultInjectingMachineProvider) inject(rate float64) bool {
+	return rate > 0 && f.random() < rate
+}
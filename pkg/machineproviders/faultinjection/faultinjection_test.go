@@ -0,0 +1,184 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package faultinjection
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders/mock"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/test"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/test/resourcebuilder"
+)
+
+var _ = Describe("Fault injection", func() {
+	type configFromEnvironmentTableInput struct {
+		env            map[string]string
+		expectedConfig *Config
+		expectedError  error
+	}
+
+	DescribeTable("ConfigFromEnvironment", func(in configFromEnvironmentTableInput) {
+		config, err := ConfigFromEnvironment(func(name string) string { return in.env[name] })
+
+		if in.expectedError != nil {
+			Expect(err).To(MatchError(in.expectedError))
+		} else {
+			Expect(err).ToNot(HaveOccurred())
+		}
+
+		Expect(config).To(Equal(in.expectedConfig))
+	},
+		Entry("when fault injection is not enabled", configFromEnvironmentTableInput{
+			env:            map[string]string{DeleteFailureRateEnvVar: "1"},
+			expectedConfig: nil,
+		}),
+		Entry("when fault injection is enabled without any faults", configFromEnvironmentTableInput{
+			env:            map[string]string{EnabledEnvVar: "true"},
+			expectedConfig: &Config{},
+		}),
+		Entry("when fault injection is enabled with every fault", configFromEnvironmentTableInput{
+			env: map[string]string{
+				EnabledEnvVar:            "true",
+				CreateDelayEnvVar:        "2m",
+				DeleteFailureRateEnvVar:  "0.5",
+				FalseReadinessRateEnvVar: "1",
+			},
+			expectedConfig: &Config{CreateDelay: 2 * time.Minute, DeleteFailureRate: 0.5, FalseReadinessRate: 1},
+		}),
+		Entry("with an invalid delay", configFromEnvironmentTableInput{
+			env:           map[string]string{EnabledEnvVar: "true", CreateDelayEnvVar: "soon"},
+			expectedError: errInvalidDelay,
+		}),
+		Entry("with a negative delay", configFromEnvironmentTableInput{
+			env:           map[string]string{EnabledEnvVar: "true", CreateDelayEnvVar: "-1m"},
+			expectedError: errInvalidDelay,
+		}),
+		Entry("with an invalid rate", configFromEnvironmentTableInput{
+			env:           map[string]string{EnabledEnvVar: "true", DeleteFailureRateEnvVar: "half"},
+			expectedError: errInvalidRate,
+		}),
+		Entry("with a rate above one", configFromEnvironmentTableInput{
+			env:           map[string]string{EnabledEnvVar: "true", FalseReadinessRateEnvVar: "1.5"},
+			expectedError: errInvalidRate,
+		}),
+	)
+
+	Context("with a wrapped machine provider", func() {
+		var mockMachineProvider *mock.MockMachineProvider
+		var logger test.TestLogger
+
+		machineRef := &machineproviders.ObjectRef{}
+		machineRef.ObjectMeta.SetName("machine-1")
+
+		// newProvider wraps the mock machine provider, deciding whether each fault is injected by comparing the
+		// rate with the random value given.
+		newProvider := func(config Config, random float64) machineproviders.MachineProvider {
+			provider, ok := NewMachineProvider(mockMachineProvider, config).(*faultInjectingMachineProvider)
+			Expect(ok).To(BeTrue())
+
+			provider.random = func() float64 { return random }
+
+			return provider
+		}
+
+		BeforeEach(func() {
+			mockMachineProvider = mock.NewMockMachineProvider(gomock.NewController(GinkgoT()))
+			logger = test.NewTestLogger()
+		})
+
+		Context("GetMachineInfos", func() {
+			machineInfos := func() []machineproviders.MachineInfo {
+				return []machineproviders.MachineInfo{
+					resourcebuilder.MachineInfo().WithIndex(0).WithReady(true).Build(),
+					resourcebuilder.MachineInfo().WithIndex(1).WithReady(false).Build(),
+				}
+			}
+
+			It("reports the opposite readiness when the fault is injected", func() {
+				mockMachineProvider.EXPECT().GetMachineInfos(gomock.Any(), gomock.Any()).Return(machineInfos(), nil)
+
+				infos, err := newProvider(Config{FalseReadinessRate: 0.5}, 0.25).GetMachineInfos(context.Background(), logger.Logger())
+				Expect(err).ToNot(HaveOccurred())
+				Expect(infos).To(ConsistOf(
+					SatisfyAll(HaveField("Index", int32(0)), HaveField("Ready", false)),
+					SatisfyAll(HaveField("Index", int32(1)), HaveField("Ready", true)),
+				))
+				Expect(logger.Entries()).To(HaveEach(HaveField("Message", reportingFalseReadiness)))
+			})
+
+			It("reports the actual readiness when the fault is not injected", func() {
+				mockMachineProvider.EXPECT().GetMachineInfos(gomock.Any(), gomock.Any()).Return(machineInfos(), nil)
+
+				infos, err := newProvider(Config{FalseReadinessRate: 0.5}, 0.75).GetMachineInfos(context.Background(), logger.Logger())
+				Expect(err).ToNot(HaveOccurred())
+				Expect(infos).To(Equal(machineInfos()))
+				Expect(logger.Entries()).To(BeEmpty())
+			})
+		})
+
+		Context("CreateMachine", func() {
+			It("creates the machine once the delay has passed", func() {
+				mockMachineProvider.EXPECT().CreateMachine(gomock.Any(), gomock.Any(), int32(1)).Return(nil).Times(1)
+
+				start := time.Now()
+				Expect(newProvider(Config{CreateDelay: 100 * time.Millisecond}, 0).CreateMachine(context.Background(), logger.Logger(), 1)).To(Succeed())
+				Expect(time.Since(start)).To(BeNumerically(">=", 100*time.Millisecond))
+				Expect(logger.Entries()).To(ConsistOf(HaveField("Message", delayingMachineCreation)))
+			})
+
+			It("does not create the machine when the context is cancelled during the delay", func() {
+				mockMachineProvider.EXPECT().CreateMachine(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+
+				ctx, cancel := context.WithCancel(context.Background())
+				cancel()
+
+				err := newProvider(Config{CreateDelay: time.Hour}, 0).CreateMachine(ctx, logger.Logger(), 1)
+				Expect(err).To(MatchError(context.Canceled))
+			})
+		})
+
+		Context("DeleteMachine", func() {
+			It("fails the deletion when the fault is injected", func() {
+				mockMachineProvider.EXPECT().DeleteMachine(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+
+				err := newProvider(Config{DeleteFailureRate: 1}, 0.99).DeleteMachine(context.Background(), logger.Logger(), machineRef)
+				Expect(err).To(MatchError(ErrInjectedDeletionFailure))
+				Expect(err).To(MatchError("injected machine deletion failure: machine-1"))
+			})
+
+			It("deletes the machine when the fault is not injected", func() {
+				mockMachineProvider.EXPECT().DeleteMachine(gomock.Any(), gomock.Any(), machineRef).Return(nil).Times(1)
+
+				Expect(newProvider(Config{DeleteFailureRate: 0.5}, 0.5).DeleteMachine(context.Background(), logger.Logger(), machineRef)).To(Succeed())
+			})
+
+			It("never fails the deletion when the rate is zero", func() {
+				mockMachineProvider.EXPECT().DeleteMachine(gomock.Any(), gomock.Any(), machineRef).Return(errors.New("delete error")).Times(1)
+
+				err := newProvider(Config{}, 0).DeleteMachine(context.Background(), logger.Logger(), machineRef)
+				Expect(err).To(MatchError("delete error"))
+			})
+		})
+	})
+})
@@ -78,3 +78,45 @@ func (mr *MockMachineProviderMockRecorder) GetMachineInfos(arg0, arg1 interface{
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetMachineInfos", reflect.TypeOf((*MockMachineProvider)(nil).GetMachineInfos), arg0, arg1)
 }
+
+// IndexFailureDomains mocks base method.
+func (m *MockMachineProvider) IndexFailureDomains() map[int32]string {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IndexFailureDomains")
+	ret0, _ := ret[0].(map[int32]string)
+	return ret0
+}
+
+// IndexFailureDomains indicates an expected call of IndexFailureDomains.
+func (mr *MockMachineProviderMockRecorder) IndexFailureDomains() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IndexFailureDomains", reflect.TypeOf((*MockMachineProvider)(nil).IndexFailureDomains))
+}
+
+// RepairMachineTags mocks base method.
+func (m *MockMachineProvider) RepairMachineTags(arg0 context.Context, arg1 logr.Logger, arg2 *machineproviders.ObjectRef) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RepairMachineTags", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RepairMachineTags indicates an expected call of RepairMachineTags.
+func (mr *MockMachineProviderMockRecorder) RepairMachineTags(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RepairMachineTags", reflect.TypeOf((*MockMachineProvider)(nil).RepairMachineTags), arg0, arg1, arg2)
+}
+
+// TemplateTagDrift mocks base method.
+func (m *MockMachineProvider) TemplateTagDrift() []string {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TemplateTagDrift")
+	ret0, _ := ret[0].([]string)
+	return ret0
+}
+
+// TemplateTagDrift indicates an expected call of TemplateTagDrift.
+func (mr *MockMachineProviderMockRecorder) TemplateTagDrift() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TemplateTagDrift", reflect.TypeOf((*MockMachineProvider)(nil).TemplateTagDrift))
+}
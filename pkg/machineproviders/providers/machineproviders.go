@@ -18,7 +18,6 @@ package providers
 
 import (
 	"context"
-	"errors"
 	"fmt"
 
 	"github.com/go-logr/logr"
@@ -32,7 +31,7 @@ import (
 var (
 	// errUnexpectedMachineType is used to denote that the machine provider could not be
 	// constructed because an unknown machine type was requested.
-	errUnexpectedMachineType = errors.New("unexpected value for spec.template.machineType")
+	errUnexpectedMachineType = machineproviders.NewConfigurationError(machineproviders.ReasonUnsupportedMachineType, "unexpected value for spec.template.machineType")
 )
 
 // NewMachineProvider constructs a MachineProvider based on the machine type passed.
@@ -0,0 +1,113 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	configv1 "github.com/openshift/api/config/v1"
+	machinev1beta1 "github.com/openshift/api/machine/v1beta1"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders/providers/openshift/machine/v1beta1/providerconfig"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// amiResolutionsConfigMapName is the name of the ConfigMap, within the namespace of the ControlPlaneMachineSet,
+	// that records the ID of the AMI that a set of AMI filters resolves to within a region. Each value is a JSON
+	// encoded amiResolution, the keys are not significant.
+	// The record allows a template that selects the AMI by filters to be compared with Machines that carry the
+	// resolved ID of the AMI.
+	amiResolutionsConfigMapName = "control-plane-machine-set-ami-resolutions"
+)
+
+// amiResolution records the ID of the AMI that the filters resolve to within the region.
+type amiResolution struct {
+	// Region is the region in which the filters were resolved.
+	Region string `json:"region"`
+
+	// Filters are the filters used to select the AMI.
+	Filters []machinev1beta1.Filter `json:"filters"`
+
+	// ID is the ID of the AMI that the filters resolve to.
+	ID string `json:"id"`
+}
+
+// resolvedTemplateAMI returns the ID of the AMI that the filters within the template resolve to, within the region
+// of the template. The recorded resolutions are read once, when the machine provider is constructed, so that the
+// resolved ID is cached for the comparison of every Machine in the reconcile.
+// An empty ID is returned when the platform is not AWS, the template references the AMI by ID, or no resolution has
+// been recorded for the filters. Recorded values that cannot be decoded are ignored, so that a damaged record cannot
+// prevent the Machines from being reconciled.
+func resolvedTemplateAMI(ctx context.Context, cl client.Client, namespace string, pc providerconfig.ProviderConfig) (string, error) {
+	if pc.Type() != configv1.AWSPlatformType {
+		return "", nil
+	}
+
+	filters := pc.AWS().ExtractAMIFilters()
+	if len(filters) == 0 {
+		return "", nil
+	}
+
+	configMap := &corev1.ConfigMap{}
+	configMapKey := client.ObjectKey{Namespace: namespace, Name: amiResolutionsConfigMapName}
+
+	if err := cl.Get(ctx, configMapKey, configMap); apierrors.IsNotFound(err) {
+		return "", nil
+	} else if err != nil {
+		return "", fmt.Errorf("could not fetch recorded AMI resolutions: %w", err)
+	}
+
+	region := pc.AWS().Config().Placement.Region
+	key := amiFiltersKey(filters)
+
+	for _, value := range configMap.Data {
+		resolution := amiResolution{}
+		if err := json.Unmarshal([]byte(value), &resolution); err != nil || resolution.ID == "" {
+			continue
+		}
+
+		if resolution.Region == region && amiFiltersKey(resolution.Filters) == key {
+			return resolution.ID, nil
+		}
+	}
+
+	return "", nil
+}
+
+// amiFiltersKey returns a canonical representation of the AMI filters, so that filters listing the same names and
+// values in a different order compare as equal. The filters of an AWS resource reference are combined, and the
+// values of each filter are alternatives, so neither order is significant.
+func amiFiltersKey(filters []machinev1beta1.Filter) string {
+	parts := []string{}
+
+	for _, filter := range filters {
+		values := append([]string{}, filter.Values...)
+		sort.Strings(values)
+
+		parts = append(parts, fmt.Sprintf("%s=%s", filter.Name, strings.Join(values, ",")))
+	}
+
+	sort.Strings(parts)
+
+	return strings.Join(parts, ";")
+}
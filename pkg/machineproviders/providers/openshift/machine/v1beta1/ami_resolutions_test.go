@@ -0,0 +1,155 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	machinev1beta1 "github.com/openshift/api/machine/v1beta1"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders/providers/openshift/machine/v1beta1/providerconfig"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/test"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/test/resourcebuilder"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("AMI Resolutions", func() {
+	amiFilters := []machinev1beta1.Filter{
+		{Name: "tag:Name", Values: []string{"rhcos-4.12"}},
+		{Name: "architecture", Values: []string{"x86_64", "arm64"}},
+	}
+
+	// reorderedAMIFilters are the AMI filters, listing the filters and their values in a different order.
+	reorderedAMIFilters := []machinev1beta1.Filter{
+		{Name: "architecture", Values: []string{"arm64", "x86_64"}},
+		{Name: "tag:Name", Values: []string{"rhcos-4.12"}},
+	}
+
+	newProviderConfig := func(builder resourcebuilder.AWSProviderSpecBuilder) providerconfig.ProviderConfig {
+		template := resourcebuilder.OpenShiftMachineV1Beta1Template().WithProviderSpecBuilder(builder).BuildTemplate().OpenShiftMachineV1Beta1Machine
+		Expect(template).ToNot(BeNil())
+
+		pc, err := providerconfig.NewProviderConfig(*template)
+		Expect(err).ToNot(HaveOccurred())
+
+		return pc
+	}
+
+	Context("resolvedTemplateAMI", func() {
+		var namespaceName string
+
+		BeforeEach(func() {
+			By("Setting up a namespace for the test")
+			ns := resourcebuilder.Namespace().WithGenerateName("control-plane-machine-set-amis-").Build()
+			Expect(k8sClient.Create(ctx, ns)).To(Succeed())
+			namespaceName = ns.GetName()
+		})
+
+		AfterEach(func() {
+			test.CleanupResources(Default, ctx, cfg, k8sClient, namespaceName,
+				&corev1.ConfigMap{},
+			)
+		})
+
+		createResolutions := func(data map[string]string) {
+			Expect(k8sClient.Create(ctx, &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Namespace: namespaceName, Name: amiResolutionsConfigMapName},
+				Data:       data,
+			})).To(Succeed())
+		}
+
+		It("returns no ID when no resolutions have been recorded", func() {
+			Expect(resolvedTemplateAMI(ctx, k8sClient, namespaceName, newProviderConfig(resourcebuilder.AWSProviderSpec().WithAMIFilters(amiFilters...)))).To(BeEmpty())
+		})
+
+		It("returns no ID when the template references the AMI by ID", func() {
+			createResolutions(map[string]string{
+				"rhcos": `{"region":"us-east-1","filters":[{"name":"tag:Name","values":["rhcos-4.12"]}],"id":"ami-resolved"}`,
+			})
+
+			Expect(resolvedTemplateAMI(ctx, k8sClient, namespaceName, newProviderConfig(resourcebuilder.AWSProviderSpec()))).To(BeEmpty())
+		})
+
+		It("returns the ID recorded for the filters in the region of the template, ignoring invalid values", func() {
+			createResolutions(map[string]string{
+				"invalid":   `{"region":`,
+				"no-id":     `{"region":"us-east-1","filters":[{"name":"tag:Name","values":["rhcos-4.12"]},{"name":"architecture","values":["arm64","x86_64"]}]}`,
+				"us-west-2": `{"region":"us-west-2","filters":[{"name":"tag:Name","values":["rhcos-4.12"]},{"name":"architecture","values":["arm64","x86_64"]}],"id":"ami-us-west-2"}`,
+				"other":     `{"region":"us-east-1","filters":[{"name":"tag:Name","values":["rhcos-4.11"]}],"id":"ami-other"}`,
+				"us-east-1": `{"region":"us-east-1","filters":[{"name":"architecture","values":["arm64","x86_64"]},{"name":"tag:Name","values":["rhcos-4.12"]}],"id":"ami-us-east-1"}`,
+			})
+
+			Expect(resolvedTemplateAMI(ctx, k8sClient, namespaceName, newProviderConfig(resourcebuilder.AWSProviderSpec().WithAMIFilters(amiFilters...)))).To(Equal("ami-us-east-1"))
+		})
+	})
+
+	Context("amiFiltersKey", func() {
+		It("ignores the order of the filters and their values", func() {
+			Expect(amiFiltersKey(amiFilters)).To(Equal(amiFiltersKey(reorderedAMIFilters)))
+		})
+
+		It("distinguishes filters with different values", func() {
+			Expect(amiFiltersKey(amiFilters)).ToNot(Equal(amiFiltersKey(amiFilters[:1])))
+		})
+	})
+
+	Context("templateProviderConfigFor", func() {
+		templateProviderConfig := newProviderConfig(resourcebuilder.AWSProviderSpec().WithAMIFilters(amiFilters...))
+
+		type templateProviderConfigForTableInput struct {
+			resolvedAMI       string
+			machineAMI        string
+			expectedEqual     bool
+			expectedUnmanaged bool
+		}
+
+		DescribeTable("compares the template AMI filters with the AMI of the Machine", func(in templateProviderConfigForTableInput) {
+			provider := &openshiftMachineProvider{
+				providerConfig: templateProviderConfig,
+				resolvedAMI:    in.resolvedAMI,
+			}
+
+			machineProviderConfig := newProviderConfig(resourcebuilder.AWSProviderSpec().WithAMI(in.machineAMI))
+
+			pc, unmanaged, err := provider.templateProviderConfigFor(machineProviderConfig)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(unmanaged).To(Equal(in.expectedUnmanaged))
+
+			Expect(pc.Equal(machineProviderConfig)).To(Equal(in.expectedEqual))
+		},
+			Entry("with a Machine carrying the resolved ID", templateProviderConfigForTableInput{
+				resolvedAMI:       "ami-resolved",
+				machineAMI:        "ami-resolved",
+				expectedEqual:     true,
+				expectedUnmanaged: true,
+			}),
+			Entry("with a Machine carrying a different ID", templateProviderConfigForTableInput{
+				resolvedAMI:       "ami-resolved",
+				machineAMI:        "ami-outdated",
+				expectedEqual:     false,
+				expectedUnmanaged: false,
+			}),
+			Entry("with no recorded resolution", templateProviderConfigForTableInput{
+				machineAMI:        "ami-resolved",
+				expectedEqual:     false,
+				expectedUnmanaged: false,
+			}),
+		)
+	})
+})
@@ -0,0 +1,68 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	"context"
+	"fmt"
+
+	machinev1 "github.com/openshift/api/machine/v1"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders/providers/openshift/machine/v1beta1/providerconfig"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// ValidateAnnotations checks the annotations of a ControlPlaneMachineSet that configure the placement of the Control
+// Plane Machines across the failure domains.
+// This allows the webhook to reject an invalid value when the ControlPlaneMachineSet is admitted, rather than the
+// machine provider failing to be constructed once the ControlPlaneMachineSet is reconciled.
+// Annotations that name failure domains are checked against the failure domains of the template, which on vSphere
+// and Nutanix are read from the Infrastructure resource. When no client is given, or the template cannot be decoded,
+// these annotations are not checked.
+func ValidateAnnotations(ctx context.Context, cl client.Reader, fldPath *field.Path, cpms *machinev1.ControlPlaneMachineSet) field.ErrorList {
+	errs := field.ErrorList{}
+	annotations := cpms.GetAnnotations()
+
+	if _, err := parseRebalanceFailureDomains(annotations); err != nil {
+		errs = append(errs, field.Invalid(fldPath.Key(rebalanceFailureDomainsAnnotation), annotations[rebalanceFailureDomainsAnnotation], err.Error()))
+	}
+
+	if cl == nil || cpms.Spec.Template.OpenShiftMachineV1Beta1Machine == nil {
+		return errs
+	}
+
+	providerConfig, err := providerconfig.NewProviderConfig(*cpms.Spec.Template.OpenShiftMachineV1Beta1Machine)
+	if err != nil {
+		// An invalid template is reported by the validation of the template.
+		return errs
+	}
+
+	failureDomains, err := templateFailureDomains(ctx, cl, cpms, providerConfig)
+	if err != nil {
+		return append(errs, field.InternalError(fldPath, fmt.Errorf("could not determine the failure domains: %w", err)))
+	}
+
+	if _, err := parseFailureDomainWeights(annotations, failureDomains); err != nil {
+		errs = append(errs, field.Invalid(fldPath.Key(failureDomainWeightsAnnotation), annotations[failureDomainWeightsAnnotation], err.Error()))
+	}
+
+	if _, err := parseCordonedFailureDomains(annotations, failureDomains); err != nil {
+		errs = append(errs, field.Invalid(fldPath.Key(cordonedFailureDomainsAnnotation), annotations[cordonedFailureDomainsAnnotation], err.Error()))
+	}
+
+	return errs
+}
@@ -0,0 +1,112 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/test/resourcebuilder"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var _ = Describe("ValidateAnnotations", func() {
+	type validateAnnotationsTableInput struct {
+		annotations    map[string]string
+		noClient       bool
+		expectedFields []string
+	}
+
+	DescribeTable("should report each invalid annotation", func(in validateAnnotationsTableInput) {
+		var cl client.Reader = k8sClient
+		if in.noClient {
+			cl = nil
+		}
+
+		// The template has failure domains in us-central1-a, us-central1-b and us-central1-c.
+		cpms := resourcebuilder.ControlPlaneMachineSet().WithAnnotations(in.annotations).WithMachineTemplateBuilder(
+			resourcebuilder.OpenShiftMachineV1Beta1Template().
+				WithFailureDomainsBuilder(resourcebuilder.GCPFailureDomains()).
+				WithProviderSpecBuilder(resourcebuilder.GCPProviderSpec()),
+		).Build()
+
+		errs := ValidateAnnotations(ctx, cl, field.NewPath("metadata", "annotations"), cpms)
+
+		fields := []string{}
+		for _, err := range errs {
+			Expect(err.Type).To(Equal(field.ErrorTypeInvalid))
+			fields = append(fields, err.Field)
+		}
+
+		Expect(fields).To(ConsistOf(in.expectedFields))
+	},
+		Entry("with no annotations", validateAnnotationsTableInput{
+			annotations:    nil,
+			expectedFields: []string{},
+		}),
+		Entry("with rebalancing enabled", validateAnnotationsTableInput{
+			annotations:    map[string]string{rebalanceFailureDomainsAnnotation: "true"},
+			expectedFields: []string{},
+		}),
+		Entry("with an invalid rebalancing value", validateAnnotationsTableInput{
+			annotations:    map[string]string{rebalanceFailureDomainsAnnotation: "always"},
+			expectedFields: []string{"metadata.annotations[controlplanemachineset.machine.openshift.io/rebalance-failure-domains]"},
+		}),
+		Entry("with valid failure domain weights", validateAnnotationsTableInput{
+			annotations:    map[string]string{failureDomainWeightsAnnotation: "us-central1-a=2,us-central1-b=1"},
+			expectedFields: []string{},
+		}),
+		Entry("with weights for an unknown failure domain", validateAnnotationsTableInput{
+			annotations:    map[string]string{failureDomainWeightsAnnotation: "us-central1-d=2"},
+			expectedFields: []string{"metadata.annotations[controlplanemachineset.machine.openshift.io/failure-domain-weights]"},
+		}),
+		Entry("with weights of zero for every failure domain", validateAnnotationsTableInput{
+			annotations:    map[string]string{failureDomainWeightsAnnotation: "us-central1-a=0,us-central1-b=0,us-central1-c=0"},
+			expectedFields: []string{"metadata.annotations[controlplanemachineset.machine.openshift.io/failure-domain-weights]"},
+		}),
+		Entry("with a cordoned failure domain", validateAnnotationsTableInput{
+			annotations:    map[string]string{cordonedFailureDomainsAnnotation: "us-central1-a"},
+			expectedFields: []string{},
+		}),
+		Entry("with an unknown cordoned failure domain", validateAnnotationsTableInput{
+			annotations:    map[string]string{cordonedFailureDomainsAnnotation: "us-central1-d"},
+			expectedFields: []string{"metadata.annotations[controlplanemachineset.machine.openshift.io/cordoned-failure-domains]"},
+		}),
+		Entry("with every failure domain cordoned", validateAnnotationsTableInput{
+			annotations:    map[string]string{cordonedFailureDomainsAnnotation: "us-central1-a,us-central1-b,us-central1-c"},
+			expectedFields: []string{"metadata.annotations[controlplanemachineset.machine.openshift.io/cordoned-failure-domains]"},
+		}),
+		Entry("with several invalid annotations", validateAnnotationsTableInput{
+			annotations: map[string]string{
+				rebalanceFailureDomainsAnnotation: "always",
+				failureDomainWeightsAnnotation:    "us-central1-a",
+				cordonedFailureDomainsAnnotation:  "us-central1-a,us-central1-a",
+			},
+			expectedFields: []string{
+				"metadata.annotations[controlplanemachineset.machine.openshift.io/rebalance-failure-domains]",
+				"metadata.annotations[controlplanemachineset.machine.openshift.io/failure-domain-weights]",
+				"metadata.annotations[controlplanemachineset.machine.openshift.io/cordoned-failure-domains]",
+			},
+		}),
+		Entry("with weights for an unknown failure domain and no client", validateAnnotationsTableInput{
+			annotations:    map[string]string{failureDomainWeightsAnnotation: "us-central1-d=2"},
+			noClient:       true,
+			expectedFields: []string{},
+		}),
+	)
+})
@@ -0,0 +1,127 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	configv1 "github.com/openshift/api/config/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders/providers/openshift/machine/v1beta1/failuredomain"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders/providers/openshift/machine/v1beta1/providerconfig"
+)
+
+const (
+	// awsFailureDomainPlacementGroupsAnnotation is the annotation on the ControlPlaneMachineSet used to override the
+	// placement group of the template, and optionally the partition within it, for particular AWS failure domains.
+	// This allows a partition placement strategy to place the Machines of each zone within their own partition.
+	// The value is a comma separated list of availability zones and placement groups, each placement group
+	// optionally followed by its partition number, eg `us-east-1a=control-plane:1,us-east-1b=control-plane:2`.
+	awsFailureDomainPlacementGroupsAnnotation = "controlplanemachineset.machine.openshift.io/aws-failure-domain-placement-groups"
+
+	// awsMaxPlacementGroupPartitions is the maximum number of partitions that AWS allows within a partition
+	// placement group.
+	awsMaxPlacementGroupPartitions = 7
+)
+
+// errInvalidAWSFailureDomainPlacementGroups is used to denote that the AWS failure domain placement groups
+// annotation is not in the expected format, or is set on a platform other than AWS.
+var errInvalidAWSFailureDomainPlacementGroups = machineproviders.NewConfigurationError(machineproviders.ReasonInvalidFailureDomains, fmt.Sprintf("invalid value for annotation %s: expected <zone>=<placement-group>[:<partition>][,<zone>=<placement-group>[:<partition>]...]", awsFailureDomainPlacementGroupsAnnotation))
+
+// awsPlacementGroup is the AWSPlacementGroup resource, and optionally the partition within it, that the Machines of an AWS
+// failure domain are placed in.
+type awsPlacementGroup struct {
+	// name is the name of the AWSPlacementGroup resource, within the namespace of the Machine.
+	name string
+
+	// partitionNumber is the partition of the placement group. When zero, the partition of the template is used.
+	partitionNumber int32
+}
+
+// parseAWSFailureDomainPlacementGroups parses the value of the AWS failure domain placement groups annotation into
+// the placement groups, keyed by the availability zone of their failure domain.
+// When the annotation is not present, no overrides are returned. The annotation is only valid on AWS.
+func parseAWSFailureDomainPlacementGroups(annotations map[string]string, platformType configv1.PlatformType) (map[string]awsPlacementGroup, error) {
+	value, ok := annotations[awsFailureDomainPlacementGroupsAnnotation]
+	if !ok {
+		return nil, nil //nolint:nilnil
+	}
+
+	if platformType != configv1.AWSPlatformType {
+		return nil, fmt.Errorf("%w, the annotation is not supported on platform %s", errInvalidAWSFailureDomainPlacementGroups, platformType)
+	}
+
+	placementGroups := map[string]awsPlacementGroup{}
+
+	for _, entry := range strings.Split(value, ",") {
+		zone, reference, ok := strings.Cut(strings.TrimSpace(entry), "=")
+		name, partition, hasPartition := strings.Cut(strings.TrimSpace(reference), ":")
+		zone, name, partition = strings.TrimSpace(zone), strings.TrimSpace(name), strings.TrimSpace(partition)
+
+		if !ok || zone == "" || name == "" || (hasPartition && partition == "") {
+			return nil, fmt.Errorf("%w, got %q", errInvalidAWSFailureDomainPlacementGroups, value)
+		}
+
+		if _, duplicate := placementGroups[zone]; duplicate {
+			return nil, fmt.Errorf("%w, zone %q is listed more than once", errInvalidAWSFailureDomainPlacementGroups, zone)
+		}
+
+		if len(validation.IsDNS1123Subdomain(name)) > 0 {
+			return nil, fmt.Errorf("%w, placement group name %q is invalid", errInvalidAWSFailureDomainPlacementGroups, name)
+		}
+
+		placementGroup := awsPlacementGroup{name: name}
+
+		if hasPartition {
+			partitionNumber, err := strconv.ParseInt(partition, 10, 32)
+			if err != nil || partitionNumber < 1 || partitionNumber > awsMaxPlacementGroupPartitions {
+				return nil, fmt.Errorf("%w, partition %q must be between 1 and %d", errInvalidAWSFailureDomainPlacementGroups, partition, awsMaxPlacementGroupPartitions)
+			}
+
+			placementGroup.partitionNumber = int32(partitionNumber)
+		}
+
+		placementGroups[zone] = placementGroup
+	}
+
+	return placementGroups, nil
+}
+
+// injectAWSFailureDomainPlacementGroup injects the placement group of the failure domain into the provider config,
+// when the placement group of the failure domain is overridden.
+// The placement group is keyed by availability zone, so failure domains sharing a zone share a placement group.
+func (m *openshiftMachineProvider) injectAWSFailureDomainPlacementGroup(pc providerconfig.ProviderConfig, fd failuredomain.FailureDomain) (providerconfig.ProviderConfig, error) {
+	if fd == nil || fd.Type() != configv1.AWSPlatformType {
+		return pc, nil
+	}
+
+	placementGroup, ok := m.awsPlacementGroups[fd.AWS().Placement.AvailabilityZone]
+	if !ok {
+		return pc, nil
+	}
+
+	injected, err := pc.InjectPlacementGroup(placementGroup.name, placementGroup.partitionNumber)
+	if err != nil {
+		return nil, fmt.Errorf("could not inject placement group for failure domain %s: %w", fd.String(), err)
+	}
+
+	return injected, nil
+}
@@ -0,0 +1,220 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	configv1 "github.com/openshift/api/config/v1"
+
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders/providers/openshift/machine/v1beta1/failuredomain"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders/providers/openshift/machine/v1beta1/providerconfig"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/test/resourcebuilder"
+)
+
+var _ = Describe("AWS Failure Domain Placement Groups", func() {
+	type parseAWSFailureDomainPlacementGroupsTableInput struct {
+		annotations             map[string]string
+		platformType            configv1.PlatformType
+		expectedPlacementGroups map[string]awsPlacementGroup
+		expectedError           string
+	}
+
+	DescribeTable("parseAWSFailureDomainPlacementGroups", func(in parseAWSFailureDomainPlacementGroupsTableInput) {
+		platformType := in.platformType
+		if platformType == "" {
+			platformType = configv1.AWSPlatformType
+		}
+
+		placementGroups, err := parseAWSFailureDomainPlacementGroups(in.annotations, platformType)
+
+		if in.expectedError != "" {
+			Expect(err).To(MatchError(errInvalidAWSFailureDomainPlacementGroups))
+			Expect(err).To(MatchError(ContainSubstring(in.expectedError)))
+		} else {
+			Expect(err).ToNot(HaveOccurred())
+		}
+
+		Expect(placementGroups).To(Equal(in.expectedPlacementGroups))
+	},
+		Entry("with no annotations", parseAWSFailureDomainPlacementGroupsTableInput{
+			annotations:             nil,
+			expectedPlacementGroups: nil,
+		}),
+		Entry("with no annotations on another platform", parseAWSFailureDomainPlacementGroupsTableInput{
+			annotations:             nil,
+			platformType:            configv1.AzurePlatformType,
+			expectedPlacementGroups: nil,
+		}),
+		Entry("with a single zone", parseAWSFailureDomainPlacementGroupsTableInput{
+			annotations: map[string]string{
+				awsFailureDomainPlacementGroupsAnnotation: "us-east-1a=control-plane",
+			},
+			expectedPlacementGroups: map[string]awsPlacementGroup{"us-east-1a": {name: "control-plane"}},
+		}),
+		Entry("with partitions and surrounding whitespace", parseAWSFailureDomainPlacementGroupsTableInput{
+			annotations: map[string]string{
+				awsFailureDomainPlacementGroupsAnnotation: "us-east-1a = control-plane : 1, us-east-1b=control-plane:7,us-east-1c=other",
+			},
+			expectedPlacementGroups: map[string]awsPlacementGroup{
+				"us-east-1a": {name: "control-plane", partitionNumber: 1},
+				"us-east-1b": {name: "control-plane", partitionNumber: 7},
+				"us-east-1c": {name: "other"},
+			},
+		}),
+		Entry("with an empty value", parseAWSFailureDomainPlacementGroupsTableInput{
+			annotations: map[string]string{
+				awsFailureDomainPlacementGroupsAnnotation: "",
+			},
+			expectedError: `got ""`,
+		}),
+		Entry("with a missing placement group", parseAWSFailureDomainPlacementGroupsTableInput{
+			annotations: map[string]string{
+				awsFailureDomainPlacementGroupsAnnotation: "us-east-1a=:1",
+			},
+			expectedError: `got "us-east-1a=:1"`,
+		}),
+		Entry("with an empty partition", parseAWSFailureDomainPlacementGroupsTableInput{
+			annotations: map[string]string{
+				awsFailureDomainPlacementGroupsAnnotation: "us-east-1a=control-plane:",
+			},
+			expectedError: `got "us-east-1a=control-plane:"`,
+		}),
+		Entry("with a zone listed twice", parseAWSFailureDomainPlacementGroupsTableInput{
+			annotations: map[string]string{
+				awsFailureDomainPlacementGroupsAnnotation: "us-east-1a=control-plane:1,us-east-1a=control-plane:2",
+			},
+			expectedError: `zone "us-east-1a" is listed more than once`,
+		}),
+		Entry("with an invalid placement group name", parseAWSFailureDomainPlacementGroupsTableInput{
+			annotations: map[string]string{
+				awsFailureDomainPlacementGroupsAnnotation: "us-east-1a=Control_Plane",
+			},
+			expectedError: `placement group name "Control_Plane" is invalid`,
+		}),
+		Entry("with a partition that is not a number", parseAWSFailureDomainPlacementGroupsTableInput{
+			annotations: map[string]string{
+				awsFailureDomainPlacementGroupsAnnotation: "us-east-1a=control-plane:one",
+			},
+			expectedError: `partition "one" must be between 1 and 7`,
+		}),
+		Entry("with a partition of zero", parseAWSFailureDomainPlacementGroupsTableInput{
+			annotations: map[string]string{
+				awsFailureDomainPlacementGroupsAnnotation: "us-east-1a=control-plane:0",
+			},
+			expectedError: `partition "0" must be between 1 and 7`,
+		}),
+		Entry("with a partition above the maximum", parseAWSFailureDomainPlacementGroupsTableInput{
+			annotations: map[string]string{
+				awsFailureDomainPlacementGroupsAnnotation: "us-east-1a=control-plane:8",
+			},
+			expectedError: `partition "8" must be between 1 and 7`,
+		}),
+		Entry("on another platform", parseAWSFailureDomainPlacementGroupsTableInput{
+			annotations: map[string]string{
+				awsFailureDomainPlacementGroupsAnnotation: "1=control-plane:1",
+			},
+			platformType:  configv1.AzurePlatformType,
+			expectedError: "the annotation is not supported on platform Azure",
+		}),
+	)
+
+	Context("injectFailureDomain", func() {
+		var provider *openshiftMachineProvider
+
+		awsFailureDomain := func(zone string) failuredomain.FailureDomain {
+			return failuredomain.NewAWSFailureDomain(resourcebuilder.AWSFailureDomain().WithAvailabilityZone(zone).Build())
+		}
+
+		BeforeEach(func() {
+			providerConfig, err := providerconfig.NewProviderConfigFromMachineSpec(resourcebuilder.Machine().WithProviderSpecBuilder(resourcebuilder.AWSProviderSpec()).Build().Spec)
+			Expect(err).ToNot(HaveOccurred())
+
+			// The template places every Machine within the same partition of a shared placement group.
+			providerConfig, err = providerConfig.InjectPlacementGroup("template-placement-group", 3)
+			Expect(err).ToNot(HaveOccurred())
+
+			provider = &openshiftMachineProvider{
+				providerConfig: providerConfig,
+				awsPlacementGroups: map[string]awsPlacementGroup{
+					"us-east-1a": {name: "control-plane", partitionNumber: 1},
+					"us-east-1b": {name: "control-plane"},
+				},
+			}
+		})
+
+		It("injects the placement group and partition of the failure domain", func() {
+			injected, err := provider.injectFailureDomain(provider.providerConfig, awsFailureDomain("us-east-1a"))
+			Expect(err).ToNot(HaveOccurred())
+
+			placement := injected.AWS().Config().Placement
+			Expect(placement.Group.Name).To(Equal("control-plane"))
+			Expect(placement.PartitionNumber).To(BeEquivalentTo(1))
+			Expect(placement.AvailabilityZone).To(Equal("us-east-1a"))
+		})
+
+		It("keeps the partition of the template when the failure domain does not set one", func() {
+			injected, err := provider.injectFailureDomain(provider.providerConfig, awsFailureDomain("us-east-1b"))
+			Expect(err).ToNot(HaveOccurred())
+
+			placement := injected.AWS().Config().Placement
+			Expect(placement.Group.Name).To(Equal("control-plane"))
+			Expect(placement.PartitionNumber).To(BeEquivalentTo(3))
+		})
+
+		It("keeps the placement group of the template in other failure domains", func() {
+			injected, err := provider.injectFailureDomain(provider.providerConfig, awsFailureDomain("us-east-1c"))
+			Expect(err).ToNot(HaveOccurred())
+
+			placement := injected.AWS().Config().Placement
+			Expect(placement.Group.Name).To(Equal("template-placement-group"))
+			Expect(placement.PartitionNumber).To(BeEquivalentTo(3))
+		})
+
+		It("keeps the placement group of the template without a failure domain", func() {
+			injected, err := provider.injectFailureDomain(provider.providerConfig, nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(injected.AWS().Config().Placement.Group.Name).To(Equal("template-placement-group"))
+		})
+
+		It("requires an update for Machines in the failure domain with the placement group of the template", func() {
+			fd := awsFailureDomain("us-east-1a")
+			provider.indexToFailureDomain = map[int32]failuredomain.FailureDomain{0: fd}
+
+			machineProviderConfig, err := provider.providerConfig.InjectFailureDomain(fd)
+			Expect(err).ToNot(HaveOccurred())
+
+			_, needsUpdate, err := provider.desiredProviderConfig(provider.providerConfig, 0, machineProviderConfig)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(needsUpdate).To(BeTrue())
+		})
+
+		It("does not require an update for Machines in the failure domain with the placement group of the failure domain", func() {
+			fd := awsFailureDomain("us-east-1a")
+			provider.indexToFailureDomain = map[int32]failuredomain.FailureDomain{0: fd}
+
+			machineProviderConfig, err := provider.injectFailureDomain(provider.providerConfig, fd)
+			Expect(err).ToNot(HaveOccurred())
+
+			_, needsUpdate, err := provider.desiredProviderConfig(provider.providerConfig, 0, machineProviderConfig)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(needsUpdate).To(BeFalse())
+		})
+	})
+})
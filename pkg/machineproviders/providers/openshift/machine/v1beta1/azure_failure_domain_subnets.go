@@ -0,0 +1,124 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	configv1 "github.com/openshift/api/config/v1"
+
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders/providers/openshift/machine/v1beta1/failuredomain"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders/providers/openshift/machine/v1beta1/providerconfig"
+)
+
+const (
+	// azureFailureDomainSubnetsAnnotation is the annotation on the ControlPlaneMachineSet used to override the subnet
+	// of the template, and optionally the network resource group, within particular Azure failure domains. This
+	// allows clusters whose control plane subnets are segmented by zone to attach each Machine to the subnet of its
+	// zone.
+	// The value is a comma separated list of zones and subnets, each subnet optionally followed by its network
+	// resource group, eg `1=master-subnet-1,2=master-subnet-2:network-rg-2`.
+	azureFailureDomainSubnetsAnnotation = "controlplanemachineset.machine.openshift.io/azure-failure-domain-subnets"
+)
+
+var (
+	// errInvalidAzureFailureDomainSubnets is used to denote that the Azure failure domain subnets annotation is not
+	// in the expected format, or is set on a platform other than Azure.
+	errInvalidAzureFailureDomainSubnets = machineproviders.NewConfigurationError(machineproviders.ReasonInvalidFailureDomains, fmt.Sprintf("invalid value for annotation %s: expected <zone>=<subnet>[:<network-resource-group>][,<zone>=<subnet>[:<network-resource-group>]...]", azureFailureDomainSubnetsAnnotation))
+
+	// azureSubnetNamePattern matches the names that Azure allows for a subnet.
+	azureSubnetNamePattern = regexp.MustCompile(`^[A-Za-z0-9_.-]{1,80}$`)
+
+	// azureResourceGroupNamePattern matches the names that Azure allows for a resource group.
+	azureResourceGroupNamePattern = regexp.MustCompile(`^[A-Za-z0-9_.()-]{1,90}$`)
+)
+
+// azureSubnet is the subnet, and optionally the network resource group, that the Machines of an Azure failure
+// domain are attached to.
+type azureSubnet struct {
+	// subnet is the name of the subnet.
+	subnet string
+
+	// networkResourceGroup is the resource group of the network of the subnet. When empty, the network resource
+	// group of the template is used.
+	networkResourceGroup string
+}
+
+// parseAzureFailureDomainSubnets parses the value of the Azure failure domain subnets annotation into the subnets,
+// keyed by the zone of their failure domain.
+// When the annotation is not present, no overrides are returned. The annotation is only valid on Azure.
+func parseAzureFailureDomainSubnets(annotations map[string]string, platformType configv1.PlatformType) (map[string]azureSubnet, error) {
+	value, ok := annotations[azureFailureDomainSubnetsAnnotation]
+	if !ok {
+		return nil, nil //nolint:nilnil
+	}
+
+	if platformType != configv1.AzurePlatformType {
+		return nil, fmt.Errorf("%w, the annotation is not supported on platform %s", errInvalidAzureFailureDomainSubnets, platformType)
+	}
+
+	subnets := map[string]azureSubnet{}
+
+	for _, entry := range strings.Split(value, ",") {
+		zone, reference, ok := strings.Cut(strings.TrimSpace(entry), "=")
+		subnet, networkResourceGroup, hasResourceGroup := strings.Cut(strings.TrimSpace(reference), ":")
+		zone, subnet, networkResourceGroup = strings.TrimSpace(zone), strings.TrimSpace(subnet), strings.TrimSpace(networkResourceGroup)
+
+		if !ok || zone == "" || subnet == "" || (hasResourceGroup && networkResourceGroup == "") {
+			return nil, fmt.Errorf("%w, got %q", errInvalidAzureFailureDomainSubnets, value)
+		}
+
+		if _, duplicate := subnets[zone]; duplicate {
+			return nil, fmt.Errorf("%w, zone %q is listed more than once", errInvalidAzureFailureDomainSubnets, zone)
+		}
+
+		if !azureSubnetNamePattern.MatchString(subnet) {
+			return nil, fmt.Errorf("%w, subnet name %q is invalid", errInvalidAzureFailureDomainSubnets, subnet)
+		}
+
+		if hasResourceGroup && !azureResourceGroupNamePattern.MatchString(networkResourceGroup) {
+			return nil, fmt.Errorf("%w, network resource group name %q is invalid", errInvalidAzureFailureDomainSubnets, networkResourceGroup)
+		}
+
+		subnets[zone] = azureSubnet{subnet: subnet, networkResourceGroup: networkResourceGroup}
+	}
+
+	return subnets, nil
+}
+
+// injectAzureFailureDomainSubnet injects the subnet of the failure domain into the provider config, when the subnet
+// of the failure domain is overridden.
+func (m *openshiftMachineProvider) injectAzureFailureDomainSubnet(pc providerconfig.ProviderConfig, fd failuredomain.FailureDomain) (providerconfig.ProviderConfig, error) {
+	if fd == nil || fd.Type() != configv1.AzurePlatformType {
+		return pc, nil
+	}
+
+	subnet, ok := m.azureSubnets[fd.String()]
+	if !ok {
+		return pc, nil
+	}
+
+	injected, err := pc.InjectSubnet(subnet.subnet, subnet.networkResourceGroup)
+	if err != nil {
+		return nil, fmt.Errorf("could not inject subnet for failure domain %s: %w", fd.String(), err)
+	}
+
+	return injected, nil
+}
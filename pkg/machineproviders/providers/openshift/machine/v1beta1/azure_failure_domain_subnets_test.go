@@ -0,0 +1,202 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	configv1 "github.com/openshift/api/config/v1"
+	machinev1 "github.com/openshift/api/machine/v1"
+
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders/providers/openshift/machine/v1beta1/failuredomain"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders/providers/openshift/machine/v1beta1/providerconfig"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/test/resourcebuilder"
+)
+
+var _ = Describe("Azure Failure Domain Subnets", func() {
+	type parseAzureFailureDomainSubnetsTableInput struct {
+		annotations     map[string]string
+		platformType    configv1.PlatformType
+		expectedSubnets map[string]azureSubnet
+		expectedError   string
+	}
+
+	DescribeTable("parseAzureFailureDomainSubnets", func(in parseAzureFailureDomainSubnetsTableInput) {
+		platformType := in.platformType
+		if platformType == "" {
+			platformType = configv1.AzurePlatformType
+		}
+
+		subnets, err := parseAzureFailureDomainSubnets(in.annotations, platformType)
+
+		if in.expectedError != "" {
+			Expect(err).To(MatchError(errInvalidAzureFailureDomainSubnets))
+			Expect(err).To(MatchError(ContainSubstring(in.expectedError)))
+		} else {
+			Expect(err).ToNot(HaveOccurred())
+		}
+
+		Expect(subnets).To(Equal(in.expectedSubnets))
+	},
+		Entry("with no annotations", parseAzureFailureDomainSubnetsTableInput{
+			annotations:     nil,
+			expectedSubnets: nil,
+		}),
+		Entry("with no annotations on another platform", parseAzureFailureDomainSubnetsTableInput{
+			annotations:     nil,
+			platformType:    configv1.AWSPlatformType,
+			expectedSubnets: nil,
+		}),
+		Entry("with a single zone", parseAzureFailureDomainSubnetsTableInput{
+			annotations: map[string]string{
+				azureFailureDomainSubnetsAnnotation: "1=master-subnet-1",
+			},
+			expectedSubnets: map[string]azureSubnet{"1": {subnet: "master-subnet-1"}},
+		}),
+		Entry("with network resource groups and surrounding whitespace", parseAzureFailureDomainSubnetsTableInput{
+			annotations: map[string]string{
+				azureFailureDomainSubnetsAnnotation: "1 = master-subnet-1 : network-rg-1, 2=master-subnet-2",
+			},
+			expectedSubnets: map[string]azureSubnet{
+				"1": {subnet: "master-subnet-1", networkResourceGroup: "network-rg-1"},
+				"2": {subnet: "master-subnet-2"},
+			},
+		}),
+		Entry("with an empty value", parseAzureFailureDomainSubnetsTableInput{
+			annotations: map[string]string{
+				azureFailureDomainSubnetsAnnotation: "",
+			},
+			expectedError: `got ""`,
+		}),
+		Entry("with a missing subnet", parseAzureFailureDomainSubnetsTableInput{
+			annotations: map[string]string{
+				azureFailureDomainSubnetsAnnotation: "1=:network-rg-1",
+			},
+			expectedError: `got "1=:network-rg-1"`,
+		}),
+		Entry("with an empty network resource group", parseAzureFailureDomainSubnetsTableInput{
+			annotations: map[string]string{
+				azureFailureDomainSubnetsAnnotation: "1=master-subnet-1:",
+			},
+			expectedError: `got "1=master-subnet-1:"`,
+		}),
+		Entry("with a zone listed twice", parseAzureFailureDomainSubnetsTableInput{
+			annotations: map[string]string{
+				azureFailureDomainSubnetsAnnotation: "1=master-subnet-1,1=master-subnet",
+			},
+			expectedError: `zone "1" is listed more than once`,
+		}),
+		Entry("with an invalid subnet name", parseAzureFailureDomainSubnetsTableInput{
+			annotations: map[string]string{
+				azureFailureDomainSubnetsAnnotation: "1=master/subnet",
+			},
+			expectedError: `subnet name "master/subnet" is invalid`,
+		}),
+		Entry("with an invalid network resource group name", parseAzureFailureDomainSubnetsTableInput{
+			annotations: map[string]string{
+				azureFailureDomainSubnetsAnnotation: "1=master-subnet-1:network rg",
+			},
+			expectedError: `network resource group name "network rg" is invalid`,
+		}),
+		Entry("on another platform", parseAzureFailureDomainSubnetsTableInput{
+			annotations: map[string]string{
+				azureFailureDomainSubnetsAnnotation: "us-east-1a=master-subnet-1",
+			},
+			platformType:  configv1.AWSPlatformType,
+			expectedError: "the annotation is not supported on platform AWS",
+		}),
+	)
+
+	Context("injectFailureDomain", func() {
+		var provider *openshiftMachineProvider
+
+		azureFailureDomain := func(zone string) failuredomain.FailureDomain {
+			return failuredomain.NewAzureFailureDomain(machinev1.AzureFailureDomain{Zone: zone})
+		}
+
+		BeforeEach(func() {
+			providerConfig, err := providerconfig.NewProviderConfigFromMachineSpec(resourcebuilder.Machine().WithProviderSpecBuilder(resourcebuilder.AzureProviderSpec()).Build().Spec)
+			Expect(err).ToNot(HaveOccurred())
+
+			provider = &openshiftMachineProvider{
+				providerConfig: providerConfig,
+				azureSubnets: map[string]azureSubnet{
+					"1": {subnet: "master-subnet-1", networkResourceGroup: "network-rg-1"},
+					"2": {subnet: "master-subnet-2"},
+				},
+			}
+		})
+
+		It("injects the subnet and network resource group of the failure domain", func() {
+			injected, err := provider.injectFailureDomain(provider.providerConfig, azureFailureDomain("1"))
+			Expect(err).ToNot(HaveOccurred())
+
+			config := injected.Azure().Config()
+			Expect(config.Subnet).To(Equal("master-subnet-1"))
+			Expect(config.NetworkResourceGroup).To(Equal("network-rg-1"))
+			Expect(config.Zone).To(HaveValue(Equal("1")))
+		})
+
+		It("keeps the network resource group of the template when the failure domain does not set one", func() {
+			injected, err := provider.injectFailureDomain(provider.providerConfig, azureFailureDomain("2"))
+			Expect(err).ToNot(HaveOccurred())
+
+			config := injected.Azure().Config()
+			Expect(config.Subnet).To(Equal("master-subnet-2"))
+			Expect(config.NetworkResourceGroup).To(Equal("cluster-id-rg"))
+		})
+
+		It("keeps the subnet of the template in other failure domains", func() {
+			injected, err := provider.injectFailureDomain(provider.providerConfig, azureFailureDomain("3"))
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(injected.Azure().Config().Subnet).To(Equal("cluster-id-master-subnet"))
+		})
+
+		It("keeps the subnet of the template without a failure domain", func() {
+			injected, err := provider.injectFailureDomain(provider.providerConfig, nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(injected.Azure().Config().Subnet).To(Equal("cluster-id-master-subnet"))
+		})
+
+		It("requires an update for Machines in the failure domain with the subnet of the template", func() {
+			fd := azureFailureDomain("1")
+			provider.indexToFailureDomain = map[int32]failuredomain.FailureDomain{0: fd}
+
+			machineProviderConfig, err := provider.providerConfig.InjectFailureDomain(fd)
+			Expect(err).ToNot(HaveOccurred())
+
+			_, needsUpdate, err := provider.desiredProviderConfig(provider.providerConfig, 0, machineProviderConfig)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(needsUpdate).To(BeTrue())
+		})
+
+		It("does not require an update for Machines in the failure domain with the subnet of the failure domain", func() {
+			fd := azureFailureDomain("1")
+			provider.indexToFailureDomain = map[int32]failuredomain.FailureDomain{0: fd}
+
+			machineProviderConfig, err := provider.injectFailureDomain(provider.providerConfig, fd)
+			Expect(err).ToNot(HaveOccurred())
+
+			_, needsUpdate, err := provider.desiredProviderConfig(provider.providerConfig, 0, machineProviderConfig)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(needsUpdate).To(BeFalse())
+		})
+	})
+})
@@ -0,0 +1,47 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	"context"
+	"fmt"
+
+	configv1 "github.com/openshift/api/config/v1"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/clusterconfig"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders/providers/openshift/machine/v1beta1/providerconfig"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// isAzureStackHub determines whether the Control Plane Machines of an Azure template are on Azure Stack Hub, as
+// described by the Infrastructure resource.
+// Templates for other platforms are never on Azure Stack Hub, and when the Infrastructure resource does not exist,
+// the cluster is assumed to be on public Azure.
+func isAzureStackHub(ctx context.Context, cl client.Reader, pc providerconfig.ProviderConfig) (bool, error) {
+	if pc.Type() != configv1.AzurePlatformType {
+		return false, nil
+	}
+
+	infrastructure := &configv1.Infrastructure{}
+	if err := cl.Get(ctx, client.ObjectKey{Name: clusterconfig.InfrastructureName}, infrastructure); apierrors.IsNotFound(err) {
+		return false, nil
+	} else if err != nil {
+		return false, fmt.Errorf("failed to get infrastructure: %w", err)
+	}
+
+	return providerconfig.IsAzureStackHub(infrastructure), nil
+}
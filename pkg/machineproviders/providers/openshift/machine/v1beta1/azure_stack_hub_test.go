@@ -0,0 +1,95 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	configv1 "github.com/openshift/api/config/v1"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/clusterconfig"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders/providers/openshift/machine/v1beta1/providerconfig"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/test/resourcebuilder"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"sigs.k8s.io/controller-runtime/pkg/envtest/komega"
+)
+
+var _ = Describe("Azure Stack Hub", func() {
+	Context("isAzureStackHub", func() {
+		var azureProviderConfig providerconfig.ProviderConfig
+
+		BeforeEach(func() {
+			var err error
+			azureProviderConfig, err = providerconfig.NewProviderConfigFromMachineSpec(resourcebuilder.Machine().WithProviderSpecBuilder(resourcebuilder.AzureProviderSpec()).Build().Spec)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		Context("when the Infrastructure does not exist", func() {
+			It("is not on Azure Stack Hub", func() {
+				Expect(isAzureStackHub(ctx, k8sClient, azureProviderConfig)).To(BeFalse())
+			})
+		})
+
+		Context("when the Infrastructure exists", func() {
+			var infrastructure *configv1.Infrastructure
+
+			createInfrastructure := func(cloudName configv1.AzureCloudEnvironment) {
+				infrastructure = &configv1.Infrastructure{ObjectMeta: metav1.ObjectMeta{Name: clusterconfig.InfrastructureName}}
+				Expect(k8sClient.Create(ctx, infrastructure)).To(Succeed())
+
+				Expect(komega.UpdateStatus(infrastructure, func() {
+					infrastructure.Status.ControlPlaneTopology = configv1.HighlyAvailableTopologyMode
+					infrastructure.Status.InfrastructureTopology = configv1.HighlyAvailableTopologyMode
+					infrastructure.Status.PlatformStatus = &configv1.PlatformStatus{
+						Type: configv1.AzurePlatformType,
+						Azure: &configv1.AzurePlatformStatus{
+							ResourceGroupName: "cluster-id-rg",
+							CloudName:         cloudName,
+						},
+					}
+				})()).To(Succeed())
+			}
+
+			AfterEach(func() {
+				Expect(k8sClient.Delete(ctx, infrastructure)).To(Succeed())
+			})
+
+			It("is on Azure Stack Hub with the Azure Stack cloud", func() {
+				createInfrastructure(configv1.AzureStackCloud)
+
+				Expect(isAzureStackHub(ctx, k8sClient, azureProviderConfig)).To(BeTrue())
+			})
+
+			It("is not on Azure Stack Hub with the public cloud", func() {
+				createInfrastructure(configv1.AzurePublicCloud)
+
+				Expect(isAzureStackHub(ctx, k8sClient, azureProviderConfig)).To(BeFalse())
+			})
+
+			It("is not on Azure Stack Hub with a template for another platform", func() {
+				createInfrastructure(configv1.AzureStackCloud)
+
+				awsProviderConfig, err := providerconfig.NewProviderConfigFromMachineSpec(resourcebuilder.Machine().WithProviderSpecBuilder(resourcebuilder.AWSProviderSpec()).Build().Spec)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(isAzureStackHub(ctx, k8sClient, awsProviderConfig)).To(BeFalse())
+			})
+		})
+	})
+})
@@ -0,0 +1,173 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders/providers/openshift/machine/v1beta1/failuredomain"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders/providers/openshift/machine/v1beta1/providerconfig"
+)
+
+const (
+	// cordonedFailureDomainsAnnotation is the annotation on the ControlPlaneMachineSet used to temporarily exclude
+	// failure domains from new placements, for example during a zone outage, without removing them from the spec.
+	// The value is a comma separated list of failure domains, eg `us-east-1a,us-east-1b`, where each failure domain is
+	// named by its zone.
+	cordonedFailureDomainsAnnotation = "controlplanemachineset.machine.openshift.io/cordoned-failure-domains"
+)
+
+// errInvalidCordonedFailureDomains is used to denote that the cordoned failure domains annotation is not in the
+// expected format.
+var errInvalidCordonedFailureDomains = machineproviders.NewConfigurationError(machineproviders.ReasonInvalidFailureDomains, fmt.Sprintf("invalid value for annotation %s: expected <failure-domain>[,<failure-domain>...]", cordonedFailureDomainsAnnotation))
+
+// parseCordonedFailureDomains parses the value of the cordoned failure domains annotation into the cordoned failure
+// domains, in the order in which they are configured.
+// Each listed failure domain must be one of the failure domains, and at least one failure domain must be left
+// uncordoned. When the annotation is not present, no failure domains are cordoned.
+func parseCordonedFailureDomains(annotations map[string]string, failureDomains []failuredomain.FailureDomain) ([]failuredomain.FailureDomain, error) {
+	value, ok := annotations[cordonedFailureDomainsAnnotation]
+	if !ok {
+		return nil, nil
+	}
+
+	known := map[string]bool{}
+	for _, fd := range failureDomains {
+		known[fd.String()] = true
+	}
+
+	listed := map[string]bool{}
+
+	for _, entry := range strings.Split(value, ",") {
+		failureDomainName := strings.TrimSpace(entry)
+
+		if failureDomainName == "" {
+			return nil, fmt.Errorf("%w, got %q", errInvalidCordonedFailureDomains, value)
+		}
+
+		if listed[failureDomainName] {
+			return nil, fmt.Errorf("%w, failure domain %q is listed more than once", errInvalidCordonedFailureDomains, failureDomainName)
+		}
+
+		if !known[failureDomainName] {
+			return nil, fmt.Errorf("%w, failure domain %q is not one of the failure domains", errInvalidCordonedFailureDomains, failureDomainName)
+		}
+
+		listed[failureDomainName] = true
+	}
+
+	cordoned := []failuredomain.FailureDomain{}
+
+	for _, fd := range failureDomains {
+		if listed[fd.String()] {
+			cordoned = append(cordoned, fd)
+		}
+	}
+
+	if len(cordoned) == len(failureDomains) {
+		return nil, fmt.Errorf("%w, at least one failure domain must not be cordoned", errInvalidCordonedFailureDomains)
+	}
+
+	return cordoned, nil
+}
+
+// uncordonedFailureDomainMapping moves the indexes mapped to cordoned failure domains into the uncordoned failure
+// domains, so that no new Machine is placed within a cordoned failure domain.
+// Indexes are moved in ascending order, each into the uncordoned failure domain with the fewest indexes mapped to it.
+// Ties are broken by the name of the failure domain, so that the mapping is stable no matter the order of the input
+// failure domains. Indexes mapped to uncordoned failure domains are left unchanged.
+func uncordonedFailureDomainMapping(mapping map[int32]failuredomain.FailureDomain, failureDomains, cordoned []failuredomain.FailureDomain) map[int32]failuredomain.FailureDomain {
+	if len(cordoned) == 0 || len(mapping) == 0 {
+		return mapping
+	}
+
+	isCordoned := map[string]bool{}
+	for _, fd := range cordoned {
+		isCordoned[fd.String()] = true
+	}
+
+	uncordoned := []failuredomain.FailureDomain{}
+	spread := map[string]int{}
+
+	for _, fd := range failureDomains {
+		if !isCordoned[fd.String()] {
+			uncordoned = append(uncordoned, fd)
+		}
+	}
+
+	sort.SliceStable(uncordoned, func(i, j int) bool {
+		return uncordoned[i].String() < uncordoned[j].String()
+	})
+
+	indexes := []int32{}
+
+	for index, fd := range mapping {
+		indexes = append(indexes, index)
+
+		if fd != nil && !isCordoned[fd.String()] {
+			spread[fd.String()]++
+		}
+	}
+
+	sort.Slice(indexes, func(i, j int) bool { return indexes[i] < indexes[j] })
+
+	out := make(map[int32]failuredomain.FailureDomain, len(mapping))
+
+	for _, index := range indexes {
+		fd := mapping[index]
+		if fd == nil || !isCordoned[fd.String()] {
+			out[index] = fd
+			continue
+		}
+
+		target := uncordoned[0]
+		for _, candidate := range uncordoned[1:] {
+			if spread[candidate.String()] < spread[target.String()] {
+				target = candidate
+			}
+		}
+
+		spread[target.String()]++
+		out[index] = target
+	}
+
+	return out
+}
+
+// cordonedProviderConfig finds the cordoned failure domain, if any, that the Machine with the given provider config
+// matches the template within. Machines within a cordoned failure domain do not need an update because of their
+// failure domain, so that cordoning a failure domain does not replace the Machines within it. Once they need an
+// update for any other reason, they are replaced into the uncordoned failure domain of their index.
+func (m *openshiftMachineProvider) cordonedProviderConfig(templateProviderConfig, machineProviderConfig providerconfig.ProviderConfig) (providerconfig.ProviderConfig, bool, error) {
+	for _, fd := range m.cordonedFailureDomains {
+		cordoned, err := m.injectFailureDomain(templateProviderConfig, fd)
+		if err != nil {
+			return nil, false, fmt.Errorf("could not inject cordoned failure domain %s: %w", fd.String(), err)
+		}
+
+		if equal, err := m.equalExcludingOwnedFields(cordoned, machineProviderConfig); err != nil {
+			return nil, false, err
+		} else if equal {
+			return cordoned, true, nil
+		}
+	}
+
+	return nil, false, nil
+}
@@ -0,0 +1,185 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders/providers/openshift/machine/v1beta1/failuredomain"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders/providers/openshift/machine/v1beta1/providerconfig"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/test/resourcebuilder"
+)
+
+var _ = Describe("Cordoned Failure Domains", func() {
+	var usEast1a, usEast1b, usEast1c failuredomain.FailureDomain
+	var failureDomains []failuredomain.FailureDomain
+
+	BeforeEach(func() {
+		usEast1a = failuredomain.NewAWSFailureDomain(resourcebuilder.AWSFailureDomain().WithAvailabilityZone("us-east-1a").Build())
+		usEast1b = failuredomain.NewAWSFailureDomain(resourcebuilder.AWSFailureDomain().WithAvailabilityZone("us-east-1b").Build())
+		usEast1c = failuredomain.NewAWSFailureDomain(resourcebuilder.AWSFailureDomain().WithAvailabilityZone("us-east-1c").Build())
+
+		// The failure domains are deliberately out of order, as the mapping must not depend on their order.
+		failureDomains = []failuredomain.FailureDomain{usEast1c, usEast1a, usEast1b}
+	})
+
+	type parseCordonedFailureDomainsTableInput struct {
+		annotations      map[string]string
+		expectedCordoned []string
+		expectedError    string
+	}
+
+	DescribeTable("parseCordonedFailureDomains", func(in parseCordonedFailureDomainsTableInput) {
+		cordoned, err := parseCordonedFailureDomains(in.annotations, failureDomains)
+
+		if in.expectedError != "" {
+			Expect(err).To(MatchError(errInvalidCordonedFailureDomains))
+			Expect(err).To(MatchError(ContainSubstring(in.expectedError)))
+			Expect(cordoned).To(BeNil())
+
+			return
+		}
+
+		Expect(err).ToNot(HaveOccurred())
+
+		names := []string{}
+		for _, fd := range cordoned {
+			names = append(names, fd.String())
+		}
+
+		Expect(names).To(Equal(in.expectedCordoned))
+	},
+		Entry("with no annotations", parseCordonedFailureDomainsTableInput{
+			annotations:      nil,
+			expectedCordoned: []string{},
+		}),
+		Entry("with a single cordoned failure domain", parseCordonedFailureDomainsTableInput{
+			annotations: map[string]string{
+				cordonedFailureDomainsAnnotation: "us-east-1a",
+			},
+			expectedCordoned: []string{"us-east-1a"},
+		}),
+		Entry("with several failure domains and surrounding whitespace, in the order they are configured", parseCordonedFailureDomainsTableInput{
+			annotations: map[string]string{
+				cordonedFailureDomainsAnnotation: " us-east-1a , us-east-1c",
+			},
+			expectedCordoned: []string{"us-east-1c", "us-east-1a"},
+		}),
+		Entry("with an empty value", parseCordonedFailureDomainsTableInput{
+			annotations: map[string]string{
+				cordonedFailureDomainsAnnotation: "",
+			},
+			expectedError: `got ""`,
+		}),
+		Entry("with an empty entry", parseCordonedFailureDomainsTableInput{
+			annotations: map[string]string{
+				cordonedFailureDomainsAnnotation: "us-east-1a,",
+			},
+			expectedError: `got "us-east-1a,"`,
+		}),
+		Entry("with a failure domain listed twice", parseCordonedFailureDomainsTableInput{
+			annotations: map[string]string{
+				cordonedFailureDomainsAnnotation: "us-east-1a,us-east-1a",
+			},
+			expectedError: `failure domain "us-east-1a" is listed more than once`,
+		}),
+		Entry("with an unknown failure domain", parseCordonedFailureDomainsTableInput{
+			annotations: map[string]string{
+				cordonedFailureDomainsAnnotation: "us-east-1d",
+			},
+			expectedError: `failure domain "us-east-1d" is not one of the failure domains`,
+		}),
+		Entry("with every failure domain cordoned", parseCordonedFailureDomainsTableInput{
+			annotations: map[string]string{
+				cordonedFailureDomainsAnnotation: "us-east-1a,us-east-1b,us-east-1c",
+			},
+			expectedError: "at least one failure domain must not be cordoned",
+		}),
+	)
+
+	Context("uncordonedFailureDomainMapping", func() {
+		var mapping map[int32]failuredomain.FailureDomain
+
+		BeforeEach(func() {
+			mapping = map[int32]failuredomain.FailureDomain{0: usEast1a, 1: usEast1b, 2: usEast1c}
+		})
+
+		It("leaves the mapping unchanged when no failure domain is cordoned", func() {
+			Expect(uncordonedFailureDomainMapping(mapping, failureDomains, nil)).To(Equal(mapping))
+		})
+
+		It("moves the index of a cordoned failure domain into the first uncordoned failure domain by name, when the spread is even", func() {
+			Expect(uncordonedFailureDomainMapping(mapping, failureDomains, []failuredomain.FailureDomain{usEast1b})).To(Equal(map[int32]failuredomain.FailureDomain{
+				0: usEast1a, 1: usEast1a, 2: usEast1c,
+			}))
+		})
+
+		It("spreads the indexes of cordoned failure domains across the uncordoned failure domains", func() {
+			mapping = map[int32]failuredomain.FailureDomain{0: usEast1a, 1: usEast1b, 2: usEast1c, 3: usEast1a, 4: usEast1b}
+
+			Expect(uncordonedFailureDomainMapping(mapping, failureDomains, []failuredomain.FailureDomain{usEast1a})).To(Equal(map[int32]failuredomain.FailureDomain{
+				0: usEast1c, 1: usEast1b, 2: usEast1c, 3: usEast1b, 4: usEast1b,
+			}))
+		})
+
+		It("leaves an empty mapping empty", func() {
+			Expect(uncordonedFailureDomainMapping(map[int32]failuredomain.FailureDomain{}, failureDomains, []failuredomain.FailureDomain{usEast1a})).To(BeEmpty())
+		})
+	})
+
+	Context("desiredProviderConfig", func() {
+		var provider *openshiftMachineProvider
+
+		BeforeEach(func() {
+			providerConfig, err := providerconfig.NewProviderConfigFromMachineSpec(resourcebuilder.Machine().WithProviderSpecBuilder(resourcebuilder.AWSProviderSpec()).Build().Spec)
+			Expect(err).ToNot(HaveOccurred())
+
+			cordoned := []failuredomain.FailureDomain{usEast1b}
+
+			provider = &openshiftMachineProvider{
+				cordonedFailureDomains: cordoned,
+				failureDomains:         failureDomains,
+				indexToFailureDomain:   uncordonedFailureDomainMapping(map[int32]failuredomain.FailureDomain{0: usEast1a, 1: usEast1b, 2: usEast1c}, failureDomains, cordoned),
+				providerConfig:         providerConfig,
+			}
+		})
+
+		It("does not require an update for Machines that match the template within a cordoned failure domain", func() {
+			machineProviderConfig, err := provider.injectFailureDomain(provider.providerConfig, usEast1b)
+			Expect(err).ToNot(HaveOccurred())
+
+			desired, needsUpdate, err := provider.desiredProviderConfig(provider.providerConfig, 1, machineProviderConfig)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(needsUpdate).To(BeFalse())
+			Expect(desired.AWS().Config().Placement.AvailabilityZone).To(Equal("us-east-1b"))
+		})
+
+		It("replaces Machines within a cordoned failure domain into an uncordoned failure domain once they need an update", func() {
+			outdatedProviderConfig, err := providerconfig.NewProviderConfigFromMachineSpec(resourcebuilder.Machine().WithProviderSpecBuilder(resourcebuilder.AWSProviderSpec().WithInstanceType("c5.4xlarge")).Build().Spec)
+			Expect(err).ToNot(HaveOccurred())
+
+			machineProviderConfig, err := provider.injectFailureDomain(outdatedProviderConfig, usEast1b)
+			Expect(err).ToNot(HaveOccurred())
+
+			desired, needsUpdate, err := provider.desiredProviderConfig(provider.providerConfig, 1, machineProviderConfig)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(needsUpdate).To(BeTrue())
+			Expect(desired.AWS().Config().Placement.AvailabilityZone).To(Equal("us-east-1a"))
+		})
+	})
+})
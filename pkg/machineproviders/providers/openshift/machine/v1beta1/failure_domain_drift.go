@@ -0,0 +1,47 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	"fmt"
+
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders/providers/openshift/machine/v1beta1/providerconfig"
+)
+
+// outsideFailureDomains determines whether the Machine with the given provider config is outside every failure
+// domain configured for the ControlPlaneMachineSet, for example because the failure domain it was created in has
+// since been removed from the ControlPlaneMachineSet. Failure domains that are not mapped to any index are still
+// considered, as the Machine has not drifted from the configuration while it is within one of them.
+// Without any failure domains, no Machine is outside them.
+func (m *openshiftMachineProvider) outsideFailureDomains(machineProviderConfig providerconfig.ProviderConfig) (bool, error) {
+	if len(m.failureDomains) == 0 {
+		return false, nil
+	}
+
+	for _, fd := range m.failureDomains {
+		matches, err := failureDomainMatches(machineProviderConfig, fd)
+		if err != nil {
+			return false, fmt.Errorf("could not compare failure domain: %w", err)
+		}
+
+		if matches {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
@@ -0,0 +1,107 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	machinev1beta1 "github.com/openshift/api/machine/v1beta1"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders/providers/openshift/machine/v1beta1/failuredomain"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders/providers/openshift/machine/v1beta1/providerconfig"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/test/resourcebuilder"
+)
+
+var _ = Describe("Failure domain drift", func() {
+	const clusterID = "cpms-drift-cluster-id"
+
+	var provider *openshiftMachineProvider
+
+	providerSpecBuilder := resourcebuilder.AWSProviderSpec()
+
+	awsFailureDomain := func(zone string) failuredomain.FailureDomain {
+		return failuredomain.NewAWSFailureDomain(resourcebuilder.AWSFailureDomain().WithAvailabilityZone(zone).Build())
+	}
+
+	// machineInfoIn generates the MachineInfo of the Control Plane Machine of the first index, within the zone given.
+	machineInfoIn := func(zone string) (bool, bool) {
+		machine := *resourcebuilder.Machine().AsMaster().
+			WithName(clusterID + "-master-0").
+			WithProviderSpecBuilder(providerSpecBuilder.WithAvailabilityZone(zone)).
+			Build()
+
+		occupancy, err := provider.failureDomainOccupancy([]machinev1beta1.Machine{machine})
+		Expect(err).ToNot(HaveOccurred())
+
+		machineInfo, err := provider.generateMachineInfo(machine, occupancy)
+		Expect(err).ToNot(HaveOccurred())
+
+		return machineInfo.OutsideFailureDomains, machineInfo.NeedsUpdate
+	}
+
+	BeforeEach(func() {
+		template := resourcebuilder.OpenShiftMachineV1Beta1Template().
+			WithProviderSpecBuilder(providerSpecBuilder).
+			WithLabel(machinev1beta1.MachineClusterIDLabel, clusterID).
+			BuildTemplate().OpenShiftMachineV1Beta1Machine
+		Expect(template).ToNot(BeNil())
+
+		providerConfig, err := providerconfig.NewProviderConfig(*template)
+		Expect(err).ToNot(HaveOccurred())
+
+		provider = &openshiftMachineProvider{
+			failureDomains: []failuredomain.FailureDomain{
+				awsFailureDomain("us-east-1a"),
+				awsFailureDomain("us-east-1b"),
+				awsFailureDomain("us-east-1c"),
+				awsFailureDomain("us-east-1d"),
+			},
+			indexToFailureDomain: map[int32]failuredomain.FailureDomain{
+				0: awsFailureDomain("us-east-1a"),
+				1: awsFailureDomain("us-east-1b"),
+				2: awsFailureDomain("us-east-1c"),
+			},
+			machineTemplate: *template,
+			providerConfig:  providerConfig,
+		}
+	})
+
+	It("does not report a machine within the failure domain of its index", func() {
+		outside, needsUpdate := machineInfoIn("us-east-1a")
+		Expect(outside).To(BeFalse())
+		Expect(needsUpdate).To(BeFalse())
+	})
+
+	It("does not report a machine within a failure domain that is not mapped to an index", func() {
+		outside, _ := machineInfoIn("us-east-1d")
+		Expect(outside).To(BeFalse())
+	})
+
+	It("reports a machine outside every failure domain, which still needs an update", func() {
+		outside, needsUpdate := machineInfoIn("us-east-1e")
+		Expect(outside).To(BeTrue())
+		Expect(needsUpdate).To(BeTrue())
+	})
+
+	It("does not report machines without failure domains", func() {
+		provider.failureDomains = nil
+		provider.indexToFailureDomain = nil
+
+		outside, _ := machineInfoIn("us-east-1e")
+		Expect(outside).To(BeFalse())
+	})
+})
@@ -0,0 +1,58 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders/providers/openshift/machine/v1beta1/failuredomain"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders/providers/openshift/machine/v1beta1/providerconfig"
+)
+
+// injectFailureDomain injects the failure domain into the provider config, along with the subnet on Azure, the
+// placement group on AWS, the storage container on Nutanix, the additional networks on OpenStack and the user data
+// secret for the failure domain, when these are overridden for the failure domain.
+func (m *openshiftMachineProvider) injectFailureDomain(pc providerconfig.ProviderConfig, fd failuredomain.FailureDomain) (providerconfig.ProviderConfig, error) {
+	injected, err := pc.InjectFailureDomain(fd)
+	if err != nil {
+		return nil, err
+	}
+
+	if fd == nil {
+		return injected, nil
+	}
+
+	injected, err = m.injectAzureFailureDomainSubnet(injected, fd)
+	if err != nil {
+		return nil, err
+	}
+
+	injected, err = m.injectAWSFailureDomainPlacementGroup(injected, fd)
+	if err != nil {
+		return nil, err
+	}
+
+	injected, err = m.injectNutanixFailureDomainStorageContainer(injected, fd)
+	if err != nil {
+		return nil, err
+	}
+
+	injected, err = m.injectOpenStackFailureDomainNetworks(injected, fd)
+	if err != nil {
+		return nil, err
+	}
+
+	return m.injectFailureDomainUserDataSecret(injected, fd)
+}
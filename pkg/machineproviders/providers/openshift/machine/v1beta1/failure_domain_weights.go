@@ -0,0 +1,159 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders/providers/openshift/machine/v1beta1/failuredomain"
+)
+
+const (
+	// failureDomainWeightsAnnotation is the annotation on the ControlPlaneMachineSet used to weight the share of the
+	// replicas placed within each failure domain. This allows, for example, two replicas to be placed within a large
+	// zone and one within a small zone.
+	// The value is a comma separated list of failure domains and weights, eg `us-east-1a=2,us-east-1b=1`, where each
+	// failure domain is named by its zone. Failure domains that are not listed have a weight of 1.
+	failureDomainWeightsAnnotation = "controlplanemachineset.machine.openshift.io/failure-domain-weights"
+)
+
+// errInvalidFailureDomainWeights is used to denote that the failure domain weights annotation is not in the expected
+// format.
+var errInvalidFailureDomainWeights = machineproviders.NewConfigurationError(machineproviders.ReasonInvalidFailureDomains, fmt.Sprintf("invalid value for annotation %s: expected <failure-domain>=<weight>[,<failure-domain>=<weight>...]", failureDomainWeightsAnnotation))
+
+// parseFailureDomainWeights parses the value of the failure domain weights annotation into the weight of each of the
+// failure domains, keyed by the name of the failure domain.
+// Each listed failure domain must be one of the failure domains, and at least one failure domain must have a
+// non-zero weight. When the annotation is not present, no weights are returned.
+func parseFailureDomainWeights(annotations map[string]string, failureDomains []failuredomain.FailureDomain) (map[string]int, error) {
+	value, ok := annotations[failureDomainWeightsAnnotation]
+	if !ok {
+		return nil, nil //nolint:nilnil
+	}
+
+	weights := map[string]int{}
+	for _, fd := range failureDomains {
+		weights[fd.String()] = 1
+	}
+
+	listed := map[string]bool{}
+
+	for _, entry := range strings.Split(value, ",") {
+		failureDomainName, weightValue, ok := strings.Cut(strings.TrimSpace(entry), "=")
+		failureDomainName, weightValue = strings.TrimSpace(failureDomainName), strings.TrimSpace(weightValue)
+
+		if !ok || failureDomainName == "" {
+			return nil, fmt.Errorf("%w, got %q", errInvalidFailureDomainWeights, value)
+		}
+
+		if listed[failureDomainName] {
+			return nil, fmt.Errorf("%w, failure domain %q is listed more than once", errInvalidFailureDomainWeights, failureDomainName)
+		}
+
+		if _, known := weights[failureDomainName]; !known {
+			return nil, fmt.Errorf("%w, failure domain %q is not one of the failure domains", errInvalidFailureDomainWeights, failureDomainName)
+		}
+
+		weight, err := strconv.Atoi(weightValue)
+		if err != nil || weight < 0 {
+			return nil, fmt.Errorf("%w, weight %q of failure domain %q is not a non-negative integer", errInvalidFailureDomainWeights, weightValue, failureDomainName)
+		}
+
+		listed[failureDomainName] = true
+		weights[failureDomainName] = weight
+	}
+
+	total := 0
+	for _, weight := range weights {
+		total += weight
+	}
+
+	if total == 0 {
+		return nil, fmt.Errorf("%w, at least one failure domain must have a non-zero weight", errInvalidFailureDomainWeights)
+	}
+
+	return weights, nil
+}
+
+// weightedFailureDomainMapping maps each of the replicas to a failure domain, so that each failure domain holds a
+// share of the replicas in proportion to its weight.
+// The share of each failure domain is rounded down, and any remaining replicas are given to the failure domains with
+// the largest remainders. The indexes are then interleaved across the failure domains, so that consecutive indexes
+// are spread across failure domains wherever the weights allow. Ties are broken by the name of the failure domain,
+// so that the mapping is stable no matter the order of the input failure domains.
+func weightedFailureDomainMapping(replicas int32, failureDomains []failuredomain.FailureDomain, weights map[string]int) map[int32]failuredomain.FailureDomain {
+	sorted := make([]failuredomain.FailureDomain, len(failureDomains))
+	copy(sorted, failureDomains)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].String() < sorted[j].String()
+	})
+
+	total := 0
+	for _, fd := range sorted {
+		total += weights[fd.String()]
+	}
+
+	shares := make([]int, len(sorted))
+	remainders := make([]int, len(sorted))
+	allocated := 0
+
+	for i, fd := range sorted {
+		shares[i] = int(replicas) * weights[fd.String()] / total
+		remainders[i] = int(replicas) * weights[fd.String()] % total
+		allocated += shares[i]
+	}
+
+	byRemainder := make([]int, len(sorted))
+	for i := range byRemainder {
+		byRemainder[i] = i
+	}
+
+	sort.SliceStable(byRemainder, func(i, j int) bool {
+		return remainders[byRemainder[i]] > remainders[byRemainder[j]]
+	})
+
+	for i := 0; allocated < int(replicas); i++ {
+		shares[byRemainder[i]]++
+		allocated++
+	}
+
+	// Smooth weighted round robin over the shares places exactly the share of replicas within each failure domain,
+	// while interleaving the failure domains.
+	current := make([]int, len(sorted))
+	out := make(map[int32]failuredomain.FailureDomain)
+
+	for index := int32(0); index < replicas; index++ {
+		chosen := 0
+
+		for i := range sorted {
+			current[i] += shares[i]
+
+			if current[i] > current[chosen] {
+				chosen = i
+			}
+		}
+
+		current[chosen] -= int(replicas)
+		out[index] = sorted[chosen]
+	}
+
+	return out
+}
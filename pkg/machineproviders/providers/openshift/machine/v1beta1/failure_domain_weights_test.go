@@ -0,0 +1,165 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders/providers/openshift/machine/v1beta1/failuredomain"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/test/resourcebuilder"
+)
+
+var _ = Describe("Failure Domain Weights", func() {
+	var usEast1a, usEast1b, usEast1c failuredomain.FailureDomain
+	var failureDomains []failuredomain.FailureDomain
+
+	BeforeEach(func() {
+		usEast1a = failuredomain.NewAWSFailureDomain(resourcebuilder.AWSFailureDomain().WithAvailabilityZone("us-east-1a").Build())
+		usEast1b = failuredomain.NewAWSFailureDomain(resourcebuilder.AWSFailureDomain().WithAvailabilityZone("us-east-1b").Build())
+		usEast1c = failuredomain.NewAWSFailureDomain(resourcebuilder.AWSFailureDomain().WithAvailabilityZone("us-east-1c").Build())
+
+		// The failure domains are deliberately out of order, as the mapping must not depend on their order.
+		failureDomains = []failuredomain.FailureDomain{usEast1c, usEast1a, usEast1b}
+	})
+
+	type parseFailureDomainWeightsTableInput struct {
+		annotations     map[string]string
+		expectedWeights map[string]int
+		expectedError   string
+	}
+
+	DescribeTable("parseFailureDomainWeights", func(in parseFailureDomainWeightsTableInput) {
+		weights, err := parseFailureDomainWeights(in.annotations, failureDomains)
+
+		if in.expectedError != "" {
+			Expect(err).To(MatchError(errInvalidFailureDomainWeights))
+			Expect(err).To(MatchError(ContainSubstring(in.expectedError)))
+		} else {
+			Expect(err).ToNot(HaveOccurred())
+		}
+
+		Expect(weights).To(Equal(in.expectedWeights))
+	},
+		Entry("with no annotations", parseFailureDomainWeightsTableInput{
+			annotations:     nil,
+			expectedWeights: nil,
+		}),
+		Entry("with a single weighted failure domain", parseFailureDomainWeightsTableInput{
+			annotations: map[string]string{
+				failureDomainWeightsAnnotation: "us-east-1a=2",
+			},
+			expectedWeights: map[string]int{"us-east-1a": 2, "us-east-1b": 1, "us-east-1c": 1},
+		}),
+		Entry("with several failure domains and surrounding whitespace", parseFailureDomainWeightsTableInput{
+			annotations: map[string]string{
+				failureDomainWeightsAnnotation: "us-east-1a = 2, us-east-1c=0",
+			},
+			expectedWeights: map[string]int{"us-east-1a": 2, "us-east-1b": 1, "us-east-1c": 0},
+		}),
+		Entry("with an empty value", parseFailureDomainWeightsTableInput{
+			annotations: map[string]string{
+				failureDomainWeightsAnnotation: "",
+			},
+			expectedError: `got ""`,
+		}),
+		Entry("with a failure domain listed twice", parseFailureDomainWeightsTableInput{
+			annotations: map[string]string{
+				failureDomainWeightsAnnotation: "us-east-1a=2,us-east-1a=1",
+			},
+			expectedError: `failure domain "us-east-1a" is listed more than once`,
+		}),
+		Entry("with an unknown failure domain", parseFailureDomainWeightsTableInput{
+			annotations: map[string]string{
+				failureDomainWeightsAnnotation: "us-east-1d=2",
+			},
+			expectedError: `failure domain "us-east-1d" is not one of the failure domains`,
+		}),
+		Entry("with a negative weight", parseFailureDomainWeightsTableInput{
+			annotations: map[string]string{
+				failureDomainWeightsAnnotation: "us-east-1a=-1",
+			},
+			expectedError: `weight "-1" of failure domain "us-east-1a" is not a non-negative integer`,
+		}),
+		Entry("with every weight zero", parseFailureDomainWeightsTableInput{
+			annotations: map[string]string{
+				failureDomainWeightsAnnotation: "us-east-1a=0,us-east-1b=0,us-east-1c=0",
+			},
+			expectedError: "at least one failure domain must have a non-zero weight",
+		}),
+	)
+
+	Context("weightedFailureDomainMapping", func() {
+		It("places two replicas in the heavier failure domain", func() {
+			Expect(weightedFailureDomainMapping(3, failureDomains, map[string]int{"us-east-1a": 2, "us-east-1b": 1, "us-east-1c": 0})).To(Equal(map[int32]failuredomain.FailureDomain{
+				0: usEast1a,
+				1: usEast1b,
+				2: usEast1a,
+			}))
+		})
+
+		It("spreads evenly weighted failure domains in name order", func() {
+			Expect(weightedFailureDomainMapping(3, failureDomains, map[string]int{"us-east-1a": 1, "us-east-1b": 1, "us-east-1c": 1})).To(Equal(map[int32]failuredomain.FailureDomain{
+				0: usEast1a,
+				1: usEast1b,
+				2: usEast1c,
+			}))
+		})
+
+		It("shares the replicas in proportion to the weights when they do not divide evenly", func() {
+			mapping := weightedFailureDomainMapping(5, failureDomains, map[string]int{"us-east-1a": 3, "us-east-1b": 1, "us-east-1c": 1})
+
+			counts := map[string]int{}
+			for _, fd := range mapping {
+				counts[fd.String()]++
+			}
+
+			Expect(mapping).To(HaveLen(5))
+			Expect(counts).To(Equal(map[string]int{"us-east-1a": 3, "us-east-1b": 1, "us-east-1c": 1}))
+		})
+
+		It("places no replicas in a failure domain with a zero weight", func() {
+			mapping := weightedFailureDomainMapping(3, failureDomains, map[string]int{"us-east-1a": 1, "us-east-1b": 1, "us-east-1c": 0})
+
+			Expect(mapping).To(HaveLen(3))
+			Expect(mapping).ToNot(ContainElement(usEast1c))
+		})
+	})
+
+	Context("createBaseFailureDomainMapping", func() {
+		It("uses the weights when they are configured", func() {
+			cpms := resourcebuilder.ControlPlaneMachineSet().WithReplicas(3).WithAnnotations(map[string]string{
+				failureDomainWeightsAnnotation: "us-east-1a=2,us-east-1c=0",
+			}).Build()
+
+			Expect(createBaseFailureDomainMapping(cpms, failureDomains)).To(Equal(map[int32]failuredomain.FailureDomain{
+				0: usEast1a,
+				1: usEast1b,
+				2: usEast1a,
+			}))
+		})
+
+		It("returns a configuration error when the weights are invalid", func() {
+			cpms := resourcebuilder.ControlPlaneMachineSet().WithReplicas(3).WithAnnotations(map[string]string{
+				failureDomainWeightsAnnotation: "us-east-1d=2",
+			}).Build()
+
+			_, err := createBaseFailureDomainMapping(cpms, failureDomains)
+			Expect(err).To(MatchError(errInvalidFailureDomainWeights))
+		})
+	})
+})
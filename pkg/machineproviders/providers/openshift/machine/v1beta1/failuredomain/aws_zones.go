@@ -0,0 +1,66 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package failuredomain
+
+import (
+	"regexp"
+)
+
+// AWSZoneType is the type of an AWS zone, using the zone types of the EC2 API.
+type AWSZoneType string
+
+const (
+	// AWSAvailabilityZoneType is the type of an Availability Zone within an AWS region.
+	AWSAvailabilityZoneType AWSZoneType = "availability-zone"
+
+	// AWSLocalZoneType is the type of an AWS Local Zone, an extension of an AWS region into a metropolitan area.
+	AWSLocalZoneType AWSZoneType = "local-zone"
+
+	// AWSWavelengthZoneType is the type of an AWS Wavelength Zone, an extension of an AWS region into the network of
+	// a telecommunications carrier.
+	AWSWavelengthZoneType AWSZoneType = "wavelength-zone"
+)
+
+var (
+	// awsLocalZonePattern matches the names of AWS Local Zones, eg `us-east-1-bos-1a`, which extend the name of the
+	// parent region with the metropolitan area of the zone.
+	awsLocalZonePattern = regexp.MustCompile(`^[a-z]{2}(-gov)?-[a-z]+-[0-9]+-[a-z]+(-[a-z]+)?-[0-9]+[a-z]$`)
+
+	// awsWavelengthZonePattern matches the names of AWS Wavelength Zones, eg `us-east-1-wl1-bos-wlz-1`, which extend
+	// the name of the parent region with the carrier and metropolitan area of the zone.
+	awsWavelengthZonePattern = regexp.MustCompile(`^[a-z]{2}(-gov)?-[a-z]+-[0-9]+-wl[0-9]+-[a-z]+-wlz-[0-9]+$`)
+)
+
+// AWSZoneTypeOf determines the type of the AWS zone from its name.
+// The operator cannot query the zones of the region, so the type is determined from the naming scheme of each zone
+// type. Any zone that is not named as a Local Zone or Wavelength Zone is an Availability Zone.
+func AWSZoneTypeOf(zone string) AWSZoneType {
+	switch {
+	case awsWavelengthZonePattern.MatchString(zone):
+		return AWSWavelengthZoneType
+	case awsLocalZonePattern.MatchString(zone):
+		return AWSLocalZoneType
+	default:
+		return AWSAvailabilityZoneType
+	}
+}
+
+// IsAWSEdgeZone returns whether the AWS zone is a Local Zone or a Wavelength Zone, rather than an Availability Zone
+// of the region.
+func IsAWSEdgeZone(zone string) bool {
+	return AWSZoneTypeOf(zone) != AWSAvailabilityZoneType
+}
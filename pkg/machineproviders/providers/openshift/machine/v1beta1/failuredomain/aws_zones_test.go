@@ -0,0 +1,38 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package failuredomain
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("AWS zones", func() {
+	DescribeTable("AWSZoneTypeOf", func(zone string, expected AWSZoneType) {
+		Expect(AWSZoneTypeOf(zone)).To(Equal(expected))
+		Expect(IsAWSEdgeZone(zone)).To(Equal(expected != AWSAvailabilityZoneType))
+	},
+		Entry("with an availability zone", "us-east-1a", AWSAvailabilityZoneType),
+		Entry("with a GovCloud availability zone", "us-gov-west-1a", AWSAvailabilityZoneType),
+		Entry("with an empty zone", "", AWSAvailabilityZoneType),
+		Entry("with a local zone", "us-east-1-bos-1a", AWSLocalZoneType),
+		Entry("with a second local zone in a metropolitan area", "us-west-2-lax-1b", AWSLocalZoneType),
+		Entry("with a local zone outside of the US", "ap-northeast-1-tpe-1a", AWSLocalZoneType),
+		Entry("with a wavelength zone", "us-east-1-wl1-bos-wlz-1", AWSWavelengthZoneType),
+		Entry("with a wavelength zone outside of the US", "eu-west-2-wl1-lon-wlz-1", AWSWavelengthZoneType),
+	)
+})
@@ -17,11 +17,11 @@ limitations under the License.
 package failuredomain
 
 import (
-	"errors"
 	"fmt"
 
 	configv1 "github.com/openshift/api/config/v1"
 	machinev1 "github.com/openshift/api/machine/v1"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders"
 )
 
 const (
@@ -33,9 +33,115 @@ const (
 var (
 	// errUnsupportedPlatformType is an error used when an unknown platform
 	// type is configured within the failure domain config.
-	errUnsupportedPlatformType = errors.New("unsupported platform type")
+	errUnsupportedPlatformType = machineproviders.NewConfigurationError(machineproviders.ReasonUnsupportedPlatform, "unsupported platform type")
+
+	// errMissingAzureFailureDomains is an error used when the failure domains
+	// config is for the Azure platform but holds no Azure failure domains.
+	errMissingAzureFailureDomains = machineproviders.NewConfigurationError(machineproviders.ReasonInvalidFailureDomains, "missing configuration for Azure failure domains")
+
+	// errMissingGCPFailureDomains is an error used when the failure domains
+	// config is for the GCP platform but holds no GCP failure domains.
+	errMissingGCPFailureDomains = machineproviders.NewConfigurationError(machineproviders.ReasonInvalidFailureDomains, "missing configuration for GCP failure domains")
+
+	// errMissingNutanixFailureDomains is an error used when the failure domains
+	// config is for the Nutanix platform but the infrastructure resource defines
+	// no Nutanix failure domains.
+	errMissingNutanixFailureDomains = machineproviders.NewConfigurationError(machineproviders.ReasonInvalidFailureDomains, "missing Nutanix failure domains within the infrastructure resource")
+
+	// errNutanixFailureDomainsFromInfrastructure is an error used when Nutanix
+	// failure domains are constructed from the ControlPlaneMachineSet, rather
+	// than from the infrastructure resource.
+	errNutanixFailureDomainsFromInfrastructure = machineproviders.NewConfigurationError(machineproviders.ReasonInvalidFailureDomains, "Nutanix failure domains must be constructed from the infrastructure resource")
+
+	// errMultipleNutanixSubnets is an error used when a Nutanix failure domain
+	// lists more than one subnet, as a Nutanix Machine is attached to a single subnet.
+	errMultipleNutanixSubnets = machineproviders.NewConfigurationError(machineproviders.ReasonInvalidFailureDomains, "Nutanix failure domains must list at most one subnet")
+
+	// errMissingVSphereFailureDomains is an error used when the failure domains
+	// config is for the VSphere platform but the infrastructure resource defines
+	// no vSphere failure domains.
+	errMissingVSphereFailureDomains = machineproviders.NewConfigurationError(machineproviders.ReasonInvalidFailureDomains, "missing vSphere failure domains within the infrastructure resource")
+
+	// errVSphereFailureDomainsFromInfrastructure is an error used when vSphere
+	// failure domains are constructed from the ControlPlaneMachineSet, rather
+	// than from the infrastructure resource.
+	errVSphereFailureDomainsFromInfrastructure = machineproviders.NewConfigurationError(machineproviders.ReasonInvalidFailureDomains, "vSphere failure domains must be constructed from the infrastructure resource")
+
+	// errMissingOpenStackFailureDomains is an error used when the failure domains
+	// config is for the OpenStack platform but holds no OpenStack failure domains.
+	errMissingOpenStackFailureDomains = machineproviders.NewConfigurationError(machineproviders.ReasonInvalidFailureDomains, "missing configuration for OpenStack failure domains")
 )
 
+// VSphereFailureDomain describes a vSphere failure domain, as defined within the infrastructure resource.
+// The ControlPlaneMachineSet API does not define vSphere failure domains, so when the failure domains
+// platform is VSphere, the failure domains are read from the infrastructure resource.
+type VSphereFailureDomain struct {
+	// Name is the name of the failure domain within the infrastructure resource.
+	Name string
+
+	// Server is the vCenter server in which the virtual machines of the failure domain are created.
+	Server string
+
+	// Datacenter is the datacenter in which the virtual machines of the failure domain are created.
+	Datacenter string
+
+	// ComputeCluster is the path of the compute cluster in which the virtual machines of the failure
+	// domain are created.
+	ComputeCluster string
+
+	// Datastore is the path of the datastore in which the virtual machines of the failure domain are created.
+	Datastore string
+
+	// Folder is the path of the folder in which the virtual machines of the failure domain are created.
+	Folder string
+
+	// ResourcePool is the path of the resource pool in which the virtual machines of the failure
+	// domain are created.
+	ResourcePool string
+
+	// Networks are the names of the networks that the network devices of the virtual machines of the
+	// failure domain are attached to, in the order of the network devices.
+	Networks []string
+}
+
+// IBMCloudFailureDomain describes an IBM Cloud VPC failure domain.
+// The ControlPlaneMachineSet API does not define IBM Cloud failure domains, so they are configured
+// separately, as a list of the VPC zones across which the Control Plane Machines are spread.
+type IBMCloudFailureDomain struct {
+	// Zone is the VPC zone in which the instance is created.
+	Zone string
+}
+
+// NutanixFailureDomain describes a Nutanix failure domain, as defined within the infrastructure resource.
+// Each failure domain maps to a Prism Element (cluster), and the subnets that the virtual machines of the
+// failure domain are attached to.
+// The ControlPlaneMachineSet API does not define Nutanix failure domains, so when the failure domains
+// platform is Nutanix, the failure domains are read from the infrastructure resource.
+type NutanixFailureDomain struct {
+	// Name is the name of the failure domain within the infrastructure resource.
+	Name string
+
+	// Cluster identifies the Prism Element in which the virtual machines of the failure domain are created.
+	Cluster machinev1.NutanixResourceIdentifier
+
+	// Subnets identify the subnets that the virtual machines of the failure domain are attached to.
+	// A Nutanix Machine is attached to a single subnet, so at most one subnet may be listed.
+	Subnets []machinev1.NutanixResourceIdentifier
+}
+
+// OpenStackFailureDomain describes an OpenStack failure domain.
+// An OpenStack instance and its root volume are placed independently, by Nova and by Cinder, so the
+// failure domain holds the availability zone of each. The ControlPlaneMachineSet API only defines the
+// Nova availability zone, the root volume availability zone is configured separately.
+type OpenStackFailureDomain struct {
+	// AvailabilityZone is the Nova availability zone in which the instance is created.
+	AvailabilityZone string
+
+	// RootVolumeAvailabilityZone is the Cinder availability zone in which the root volume of the
+	// instance is created. When empty, the root volume availability zone is not part of the failure domain.
+	RootVolumeAvailabilityZone string
+}
+
 // FailureDomain is an interface that allows external code to interact with
 // failure domains across different platform types.
 type FailureDomain interface {
@@ -47,13 +153,37 @@ type FailureDomain interface {
 
 	// AWS returns the AWSFailureDomain if the platform type is AWS.
 	AWS() machinev1.AWSFailureDomain
+
+	// Azure returns the AzureFailureDomain if the platform type is Azure.
+	Azure() machinev1.AzureFailureDomain
+
+	// GCP returns the GCPFailureDomain if the platform type is GCP.
+	GCP() machinev1.GCPFailureDomain
+
+	// VSphere returns the VSphereFailureDomain if the platform type is VSphere.
+	VSphere() VSphereFailureDomain
+
+	// IBMCloud returns the IBMCloudFailureDomain if the platform type is IBMCloud.
+	IBMCloud() IBMCloudFailureDomain
+
+	// OpenStack returns the OpenStackFailureDomain if the platform type is OpenStack.
+	OpenStack() OpenStackFailureDomain
+
+	// Nutanix returns the NutanixFailureDomain if the platform type is Nutanix.
+	Nutanix() NutanixFailureDomain
 }
 
 // failureDomain holds an implementation of the FailureDomain interface.
 type failureDomain struct {
 	platformType configv1.PlatformType
 
-	aws machinev1.AWSFailureDomain
+	aws       machinev1.AWSFailureDomain
+	azure     machinev1.AzureFailureDomain
+	gcp       machinev1.GCPFailureDomain
+	vsphere   VSphereFailureDomain
+	ibmCloud  IBMCloudFailureDomain
+	openStack OpenStackFailureDomain
+	nutanix   NutanixFailureDomain
 }
 
 // String returns a string representation of the failure domain.
@@ -61,6 +191,18 @@ func (f failureDomain) String() string {
 	switch f.platformType {
 	case configv1.AWSPlatformType:
 		return awsFailureDomainToString(f.aws)
+	case configv1.AzurePlatformType:
+		return azureFailureDomainToString(f.azure)
+	case configv1.GCPPlatformType:
+		return gcpFailureDomainToString(f.gcp)
+	case configv1.VSpherePlatformType:
+		return vsphereFailureDomainToString(f.vsphere)
+	case configv1.IBMCloudPlatformType:
+		return ibmCloudFailureDomainToString(f.ibmCloud)
+	case configv1.OpenStackPlatformType:
+		return openStackFailureDomainToString(f.openStack)
+	case configv1.NutanixPlatformType:
+		return nutanixFailureDomainToString(f.nutanix)
 	default:
 		return unknownFailureDomain
 	}
@@ -76,12 +218,54 @@ func (f failureDomain) AWS() machinev1.AWSFailureDomain {
 	return f.aws
 }
 
+// Azure returns the AzureFailureDomain if the platform type is Azure.
+func (f failureDomain) Azure() machinev1.AzureFailureDomain {
+	return f.azure
+}
+
+// GCP returns the GCPFailureDomain if the platform type is GCP.
+func (f failureDomain) GCP() machinev1.GCPFailureDomain {
+	return f.gcp
+}
+
+// VSphere returns the VSphereFailureDomain if the platform type is VSphere.
+func (f failureDomain) VSphere() VSphereFailureDomain {
+	return f.vsphere
+}
+
+// IBMCloud returns the IBMCloudFailureDomain if the platform type is IBMCloud.
+func (f failureDomain) IBMCloud() IBMCloudFailureDomain {
+	return f.ibmCloud
+}
+
+// OpenStack returns the OpenStackFailureDomain if the platform type is OpenStack.
+func (f failureDomain) OpenStack() OpenStackFailureDomain {
+	return f.openStack
+}
+
+// Nutanix returns the NutanixFailureDomain if the platform type is Nutanix.
+func (f failureDomain) Nutanix() NutanixFailureDomain {
+	return f.nutanix
+}
+
 // NewFailureDomains creates a set of FailureDomains representing the input failure
 // domains held within the ControlPlaneMachineSet.
+// vSphere and Nutanix failure domains are not held within the ControlPlaneMachineSet, and are
+// instead constructed with NewVSphereFailureDomains and NewNutanixFailureDomains.
 func NewFailureDomains(failureDomains machinev1.FailureDomains) ([]FailureDomain, error) {
 	switch failureDomains.Platform {
 	case configv1.AWSPlatformType:
 		return newAWSFailureDomains(failureDomains)
+	case configv1.AzurePlatformType:
+		return newAzureFailureDomains(failureDomains)
+	case configv1.GCPPlatformType:
+		return newGCPFailureDomains(failureDomains)
+	case configv1.VSpherePlatformType:
+		return nil, errVSphereFailureDomainsFromInfrastructure
+	case configv1.OpenStackPlatformType:
+		return newOpenStackFailureDomains(failureDomains)
+	case configv1.NutanixPlatformType:
+		return nil, errNutanixFailureDomainsFromInfrastructure
 	case configv1.PlatformType(""):
 		// An empty failure domains definition is allowed.
 		return nil, nil
@@ -102,6 +286,92 @@ func newAWSFailureDomains(failureDomains machinev1.FailureDomains) ([]FailureDom
 	return []FailureDomain{dummyFailureDomains}, nil
 }
 
+// newAzureFailureDomains constructs a list of AzureFailureDomains from the provided
+// failure domains configuration.
+func newAzureFailureDomains(failureDomains machinev1.FailureDomains) ([]FailureDomain, error) {
+	if failureDomains.Azure == nil {
+		return nil, errMissingAzureFailureDomains
+	}
+
+	out := []FailureDomain{}
+
+	for _, fd := range *failureDomains.Azure {
+		out = append(out, NewAzureFailureDomain(fd))
+	}
+
+	return out, nil
+}
+
+// newGCPFailureDomains constructs a list of GCPFailureDomains from the provided
+// failure domains configuration.
+func newGCPFailureDomains(failureDomains machinev1.FailureDomains) ([]FailureDomain, error) {
+	if failureDomains.GCP == nil {
+		return nil, errMissingGCPFailureDomains
+	}
+
+	out := []FailureDomain{}
+
+	for _, fd := range *failureDomains.GCP {
+		out = append(out, NewGCPFailureDomain(fd))
+	}
+
+	return out, nil
+}
+
+// NewVSphereFailureDomains creates a set of FailureDomains from the vSphere failure domains
+// defined within the infrastructure resource.
+func NewVSphereFailureDomains(failureDomains []VSphereFailureDomain) ([]FailureDomain, error) {
+	if len(failureDomains) == 0 {
+		return nil, errMissingVSphereFailureDomains
+	}
+
+	out := []FailureDomain{}
+
+	for _, fd := range failureDomains {
+		out = append(out, NewVSphereFailureDomain(fd))
+	}
+
+	return out, nil
+}
+
+// newOpenStackFailureDomains constructs a list of OpenStackFailureDomains from the provided
+// failure domains configuration. The failure domains hold only the Nova availability zone.
+func newOpenStackFailureDomains(failureDomains machinev1.FailureDomains) ([]FailureDomain, error) {
+	if failureDomains.OpenStack == nil {
+		return nil, errMissingOpenStackFailureDomains
+	}
+
+	out := []FailureDomain{}
+
+	for _, fd := range *failureDomains.OpenStack {
+		out = append(out, NewOpenStackFailureDomain(OpenStackFailureDomain{
+			AvailabilityZone: fd.AvailabilityZone,
+		}))
+	}
+
+	return out, nil
+}
+
+// NewNutanixFailureDomains creates a set of FailureDomains from the Nutanix failure domains
+// defined within the infrastructure resource.
+func NewNutanixFailureDomains(failureDomains []NutanixFailureDomain) ([]FailureDomain, error) {
+	if len(failureDomains) == 0 {
+		return nil, errMissingNutanixFailureDomains
+	}
+
+	out := []FailureDomain{}
+
+	for _, fd := range failureDomains {
+		if len(fd.Subnets) > 1 {
+			return nil, fmt.Errorf("%w: failure domain %s lists %d subnets", errMultipleNutanixSubnets, fd.Name, len(fd.Subnets))
+		}
+
+		out = append(out, NewNutanixFailureDomain(fd))
+	}
+
+	return out, nil
+}
+
 // NewAWSFailureDomain creates an AWS failure domain from the machinev1.AWSFailureDomain.
 // Note this is exported to allow other packages to construct individual failure domains
 // in tests.
@@ -112,6 +382,66 @@ func NewAWSFailureDomain(fd machinev1.AWSFailureDomain) FailureDomain {
 	}
 }
 
+// NewAzureFailureDomain creates an Azure failure domain from the machinev1.AzureFailureDomain.
+// Note this is exported to allow other packages to construct individual failure domains
+// in tests.
+func NewAzureFailureDomain(fd machinev1.AzureFailureDomain) FailureDomain {
+	return &failureDomain{
+		platformType: configv1.AzurePlatformType,
+		azure:        fd,
+	}
+}
+
+// NewGCPFailureDomain creates a GCP failure domain from the machinev1.GCPFailureDomain.
+// Note this is exported to allow other packages to construct individual failure domains
+// in tests.
+func NewGCPFailureDomain(fd machinev1.GCPFailureDomain) FailureDomain {
+	return &failureDomain{
+		platformType: configv1.GCPPlatformType,
+		gcp:          fd,
+	}
+}
+
+// NewVSphereFailureDomain creates a vSphere failure domain from the VSphereFailureDomain.
+// Note this is exported to allow other packages to construct individual failure domains
+// in tests.
+func NewVSphereFailureDomain(fd VSphereFailureDomain) FailureDomain {
+	return &failureDomain{
+		platformType: configv1.VSpherePlatformType,
+		vsphere:      fd,
+	}
+}
+
+// NewIBMCloudFailureDomain creates an IBM Cloud failure domain from the IBMCloudFailureDomain.
+// IBM Cloud failure domains are not part of the ControlPlaneMachineSet API, so this is exported to allow
+// the machine provider to construct them from their own configuration.
+func NewIBMCloudFailureDomain(fd IBMCloudFailureDomain) FailureDomain {
+	return &failureDomain{
+		platformType: configv1.IBMCloudPlatformType,
+		ibmCloud:     fd,
+	}
+}
+
+// NewOpenStackFailureDomain creates an OpenStack failure domain from the OpenStackFailureDomain.
+// Note this is exported to allow other packages to construct individual failure domains
+// in tests.
+func NewOpenStackFailureDomain(fd OpenStackFailureDomain) FailureDomain {
+	return &failureDomain{
+		platformType: configv1.OpenStackPlatformType,
+		openStack:    fd,
+	}
+}
+
+// NewNutanixFailureDomain creates a Nutanix failure domain from the NutanixFailureDomain.
+// Note this is exported to allow other packages to construct individual failure domains
+// in tests.
+func NewNutanixFailureDomain(fd NutanixFailureDomain) FailureDomain {
+	return &failureDomain{
+		platformType: configv1.NutanixPlatformType,
+		nutanix:      fd,
+	}
+}
+
 // awsFailureDomainToString converts the AWSFailureDomain into a string.
 // Typically most failure domains are represented by their availability zone,
 // so we return the AWS AvailabilityZone if it is set.
@@ -142,3 +472,80 @@ func awsFailureDomainToString(fd machinev1.AWSFailureDomain) string {
 	// this should catch the fallthrough.
 	return unknownFailureDomain
 }
+
+// azureFailureDomainToString converts the AzureFailureDomain into a string.
+// Azure failure domains are represented by their zone.
+func azureFailureDomainToString(fd machinev1.AzureFailureDomain) string {
+	if fd.Zone != "" {
+		return fd.Zone
+	}
+
+	return unknownFailureDomain
+}
+
+// gcpFailureDomainToString converts the GCPFailureDomain into a string.
+// GCP failure domains are represented by their zone.
+func gcpFailureDomainToString(fd machinev1.GCPFailureDomain) string {
+	if fd.Zone != "" {
+		return fd.Zone
+	}
+
+	return unknownFailureDomain
+}
+
+// vsphereFailureDomainToString converts the VSphereFailureDomain into a string.
+// vSphere failure domains are represented by their name. Failure domains extracted
+// from a provider spec have no name, so are represented by their resource pool.
+func vsphereFailureDomainToString(fd VSphereFailureDomain) string {
+	switch {
+	case fd.Name != "":
+		return fd.Name
+	case fd.ResourcePool != "":
+		return fd.ResourcePool
+	case fd.ComputeCluster != "":
+		return fd.ComputeCluster
+	}
+
+	return unknownFailureDomain
+}
+
+// ibmCloudFailureDomainToString converts the IBMCloudFailureDomain into a string.
+// IBM Cloud failure domains are represented by their zone.
+func ibmCloudFailureDomainToString(fd IBMCloudFailureDomain) string {
+	if fd.Zone != "" {
+		return fd.Zone
+	}
+
+	return unknownFailureDomain
+}
+
+// openStackFailureDomainToString converts the OpenStackFailureDomain into a string.
+// OpenStack failure domains are represented by their Nova availability zone. When no
+// Nova availability zone is set, they are represented by their root volume availability zone.
+func openStackFailureDomainToString(fd OpenStackFailureDomain) string {
+	switch {
+	case fd.AvailabilityZone != "":
+		return fd.AvailabilityZone
+	case fd.RootVolumeAvailabilityZone != "":
+		return fd.RootVolumeAvailabilityZone
+	}
+
+	return unknownFailureDomain
+}
+
+// nutanixFailureDomainToString converts the NutanixFailureDomain into a string.
+// Nutanix failure domains are represented by their name. Failure domains extracted
+// from a provider spec have no name, so are represented by the name or UUID of their
+// Prism Element.
+func nutanixFailureDomainToString(fd NutanixFailureDomain) string {
+	switch {
+	case fd.Name != "":
+		return fd.Name
+	case fd.Cluster.Name != nil && *fd.Cluster.Name != "":
+		return *fd.Cluster.Name
+	case fd.Cluster.UUID != nil && *fd.Cluster.UUID != "":
+		return *fd.Cluster.UUID
+	}
+
+	return unknownFailureDomain
+}
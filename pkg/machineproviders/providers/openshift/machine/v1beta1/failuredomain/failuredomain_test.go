@@ -23,6 +23,7 @@ import (
 	configv1 "github.com/openshift/api/config/v1"
 	machinev1 "github.com/openshift/api/machine/v1"
 	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/test/resourcebuilder"
+	"k8s.io/utils/pointer"
 )
 
 var _ = Describe("FailureDomains", func() {
@@ -87,6 +88,135 @@ var _ = Describe("FailureDomains", func() {
 			})
 		})
 
+		Context("With Azure failure domain configuration", func() {
+			var failureDomains []FailureDomain
+			var err error
+
+			BeforeEach(func() {
+				config := resourcebuilder.AzureFailureDomains().BuildFailureDomains()
+
+				failureDomains, err = NewFailureDomains(config)
+			})
+
+			It("should not error", func() {
+				Expect(err).ToNot(HaveOccurred())
+			})
+
+			It("should construct a list of failure domains", func() {
+				Expect(failureDomains).To(ConsistOf(
+					HaveField("String()", "1"),
+					HaveField("String()", "2"),
+					HaveField("String()", "3"),
+				))
+			})
+		})
+
+		Context("With GCP failure domain configuration", func() {
+			var failureDomains []FailureDomain
+			var err error
+
+			BeforeEach(func() {
+				config := resourcebuilder.GCPFailureDomains().BuildFailureDomains()
+
+				failureDomains, err = NewFailureDomains(config)
+			})
+
+			It("should not error", func() {
+				Expect(err).ToNot(HaveOccurred())
+			})
+
+			It("should construct a list of failure domains", func() {
+				Expect(failureDomains).To(ConsistOf(
+					HaveField("String()", "us-central1-a"),
+					HaveField("String()", "us-central1-b"),
+					HaveField("String()", "us-central1-c"),
+				))
+			})
+		})
+
+		Context("With invalid GCP failure domain configuration", func() {
+			var failureDomains []FailureDomain
+			var err error
+
+			BeforeEach(func() {
+				config := resourcebuilder.GCPFailureDomains().BuildFailureDomains()
+				config.GCP = nil
+
+				failureDomains, err = NewFailureDomains(config)
+			})
+
+			It("returns an error", func() {
+				Expect(err).To(MatchError("missing configuration for GCP failure domains"))
+			})
+
+			It("returns an empty list of failure domains", func() {
+				Expect(failureDomains).To(BeEmpty())
+			})
+		})
+
+		Context("With invalid Azure failure domain configuration", func() {
+			var failureDomains []FailureDomain
+			var err error
+
+			BeforeEach(func() {
+				config := resourcebuilder.AzureFailureDomains().BuildFailureDomains()
+				config.Azure = nil
+
+				failureDomains, err = NewFailureDomains(config)
+			})
+
+			It("returns an error", func() {
+				Expect(err).To(MatchError("missing configuration for Azure failure domains"))
+			})
+
+			It("returns an empty list of failure domains", func() {
+				Expect(failureDomains).To(BeEmpty())
+			})
+		})
+
+		Context("With OpenStack failure domain configuration", func() {
+			var failureDomains []FailureDomain
+			var err error
+
+			BeforeEach(func() {
+				config := resourcebuilder.OpenStackFailureDomains().BuildFailureDomains()
+
+				failureDomains, err = NewFailureDomains(config)
+			})
+
+			It("should not error", func() {
+				Expect(err).ToNot(HaveOccurred())
+			})
+
+			It("should construct a list of failure domains", func() {
+				Expect(failureDomains).To(ConsistOf(
+					HaveField("OpenStack()", OpenStackFailureDomain{AvailabilityZone: "nova-az1"}),
+					HaveField("OpenStack()", OpenStackFailureDomain{AvailabilityZone: "nova-az2"}),
+					HaveField("OpenStack()", OpenStackFailureDomain{AvailabilityZone: "nova-az3"}),
+				))
+			})
+		})
+
+		Context("With invalid OpenStack failure domain configuration", func() {
+			var failureDomains []FailureDomain
+			var err error
+
+			BeforeEach(func() {
+				config := resourcebuilder.OpenStackFailureDomains().BuildFailureDomains()
+				config.OpenStack = nil
+
+				failureDomains, err = NewFailureDomains(config)
+			})
+
+			It("returns an error", func() {
+				Expect(err).To(MatchError("missing configuration for OpenStack failure domains"))
+			})
+
+			It("returns an empty list of failure domains", func() {
+				Expect(failureDomains).To(BeEmpty())
+			})
+		})
+
 		Context("With an unsupported platform type", func() {
 			var failureDomains []FailureDomain
 			var err error
@@ -107,6 +237,87 @@ var _ = Describe("FailureDomains", func() {
 				Expect(failureDomains).To(BeEmpty())
 			})
 		})
+
+		Context("With VSphere failure domain configuration", func() {
+			It("returns an error, as vSphere failure domains are read from the infrastructure resource", func() {
+				failureDomains, err := NewFailureDomains(machinev1.FailureDomains{
+					Platform: configv1.VSpherePlatformType,
+				})
+
+				Expect(err).To(MatchError("vSphere failure domains must be constructed from the infrastructure resource"))
+				Expect(failureDomains).To(BeEmpty())
+			})
+		})
+
+		Context("With Nutanix failure domain configuration", func() {
+			It("returns an error, as Nutanix failure domains are read from the infrastructure resource", func() {
+				failureDomains, err := NewFailureDomains(machinev1.FailureDomains{
+					Platform: configv1.NutanixPlatformType,
+				})
+
+				Expect(err).To(MatchError("Nutanix failure domains must be constructed from the infrastructure resource"))
+				Expect(failureDomains).To(BeEmpty())
+			})
+		})
+	})
+
+	Context("NewNutanixFailureDomains", func() {
+		subnet := func(name string) machinev1.NutanixResourceIdentifier {
+			return machinev1.NutanixResourceIdentifier{Type: machinev1.NutanixIdentifierName, Name: pointer.String(name)}
+		}
+
+		It("should construct a list of failure domains in the order they are defined", func() {
+			failureDomains, err := NewNutanixFailureDomains([]NutanixFailureDomain{
+				{Name: "fd-2", Cluster: subnet("nutanix-prism-element-2"), Subnets: []machinev1.NutanixResourceIdentifier{subnet("nutanix-subnet-2")}},
+				{Name: "fd-1", Cluster: subnet("nutanix-prism-element-1")},
+			})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(failureDomains).To(HaveLen(2))
+			Expect(failureDomains[0].String()).To(Equal("fd-2"))
+			Expect(failureDomains[1].String()).To(Equal("fd-1"))
+			Expect(failureDomains[0].Type()).To(Equal(configv1.NutanixPlatformType))
+			Expect(failureDomains[0].Nutanix().Subnets).To(ConsistOf(subnet("nutanix-subnet-2")))
+		})
+
+		It("returns an error when there are no failure domains", func() {
+			failureDomains, err := NewNutanixFailureDomains(nil)
+
+			Expect(err).To(MatchError("missing Nutanix failure domains within the infrastructure resource"))
+			Expect(failureDomains).To(BeEmpty())
+		})
+
+		It("returns an error when a failure domain lists more than one subnet", func() {
+			failureDomains, err := NewNutanixFailureDomains([]NutanixFailureDomain{
+				{Name: "fd-1", Subnets: []machinev1.NutanixResourceIdentifier{subnet("nutanix-subnet-1"), subnet("nutanix-subnet-2")}},
+			})
+
+			Expect(err).To(MatchError("Nutanix failure domains must list at most one subnet: failure domain fd-1 lists 2 subnets"))
+			Expect(failureDomains).To(BeEmpty())
+		})
+	})
+
+	Context("NewVSphereFailureDomains", func() {
+		It("should construct a list of failure domains in the order they are defined", func() {
+			failureDomains, err := NewVSphereFailureDomains([]VSphereFailureDomain{
+				{Name: "zone-b", ComputeCluster: "/dc1/host/cluster-b"},
+				{Name: "zone-a", ComputeCluster: "/dc1/host/cluster-a"},
+			})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(failureDomains).To(HaveLen(2))
+			Expect(failureDomains[0].String()).To(Equal("zone-b"))
+			Expect(failureDomains[1].String()).To(Equal("zone-a"))
+			Expect(failureDomains[0].Type()).To(Equal(configv1.VSpherePlatformType))
+			Expect(failureDomains[0].VSphere().ComputeCluster).To(Equal("/dc1/host/cluster-b"))
+		})
+
+		It("returns an error when there are no failure domains", func() {
+			failureDomains, err := NewVSphereFailureDomains(nil)
+
+			Expect(err).To(MatchError("missing vSphere failure domains within the infrastructure resource"))
+			Expect(failureDomains).To(BeEmpty())
+		})
 	})
 
 	Context("an AWS failure domain", func() {
@@ -178,4 +389,90 @@ var _ = Describe("FailureDomains", func() {
 			})
 		})
 	})
+
+	Context("an Azure failure domain", func() {
+		It("returns the zone for String()", func() {
+			fd := NewAzureFailureDomain(resourcebuilder.AzureFailureDomain().WithZone("2").Build())
+
+			Expect(fd.Type()).To(Equal(configv1.AzurePlatformType))
+			Expect(fd.String()).To(Equal("2"))
+		})
+
+		It("returns unknown for String() when there is no zone", func() {
+			Expect(NewAzureFailureDomain(machinev1.AzureFailureDomain{}).String()).To(Equal(unknownFailureDomain))
+		})
+	})
+
+	Context("a GCP failure domain", func() {
+		It("returns the zone for String()", func() {
+			fd := NewGCPFailureDomain(resourcebuilder.GCPFailureDomain().WithZone("us-central1-b").Build())
+
+			Expect(fd.Type()).To(Equal(configv1.GCPPlatformType))
+			Expect(fd.String()).To(Equal("us-central1-b"))
+		})
+
+		It("returns unknown for String() when there is no zone", func() {
+			Expect(NewGCPFailureDomain(machinev1.GCPFailureDomain{}).String()).To(Equal(unknownFailureDomain))
+		})
+	})
+	Context("a vSphere failure domain", func() {
+		It("returns the name for String()", func() {
+			Expect(NewVSphereFailureDomain(VSphereFailureDomain{Name: "zone-a", ResourcePool: "/dc1/host/cluster-a/Resources"}).String()).To(Equal("zone-a"))
+		})
+
+		It("returns the resource pool for String() when there is no name", func() {
+			Expect(NewVSphereFailureDomain(VSphereFailureDomain{ResourcePool: "/dc1/host/cluster-a/Resources"}).String()).To(Equal("/dc1/host/cluster-a/Resources"))
+		})
+
+		It("returns unknown for String() when it is empty", func() {
+			Expect(NewVSphereFailureDomain(VSphereFailureDomain{}).String()).To(Equal(unknownFailureDomain))
+		})
+	})
+
+	Context("an IBM Cloud failure domain", func() {
+		It("returns the zone for String()", func() {
+			fd := NewIBMCloudFailureDomain(IBMCloudFailureDomain{Zone: "us-south-2"})
+
+			Expect(fd.Type()).To(Equal(configv1.IBMCloudPlatformType))
+			Expect(fd.String()).To(Equal("us-south-2"))
+			Expect(fd.IBMCloud().Zone).To(Equal("us-south-2"))
+		})
+
+		It("returns unknown for String() when there is no zone", func() {
+			Expect(NewIBMCloudFailureDomain(IBMCloudFailureDomain{}).String()).To(Equal(unknownFailureDomain))
+		})
+	})
+
+	Context("an OpenStack failure domain", func() {
+		It("returns the availability zone for String()", func() {
+			fd := NewOpenStackFailureDomain(OpenStackFailureDomain{AvailabilityZone: "nova-az1", RootVolumeAvailabilityZone: "cinder-az1"})
+
+			Expect(fd.Type()).To(Equal(configv1.OpenStackPlatformType))
+			Expect(fd.String()).To(Equal("nova-az1"))
+		})
+
+		It("returns the root volume availability zone for String() when there is no availability zone", func() {
+			Expect(NewOpenStackFailureDomain(OpenStackFailureDomain{RootVolumeAvailabilityZone: "cinder-az1"}).String()).To(Equal("cinder-az1"))
+		})
+
+		It("returns unknown for String() when it is empty", func() {
+			Expect(NewOpenStackFailureDomain(OpenStackFailureDomain{}).String()).To(Equal(unknownFailureDomain))
+		})
+	})
+
+	Context("a Nutanix failure domain", func() {
+		It("returns the name for String()", func() {
+			Expect(NewNutanixFailureDomain(NutanixFailureDomain{Name: "fd-1", Cluster: machinev1.NutanixResourceIdentifier{Type: machinev1.NutanixIdentifierName, Name: pointer.String("nutanix-prism-element-1")}}).String()).To(Equal("fd-1"))
+		})
+
+		It("returns the Prism Element for String() when there is no name", func() {
+			Expect(NewNutanixFailureDomain(NutanixFailureDomain{Cluster: machinev1.NutanixResourceIdentifier{Type: machinev1.NutanixIdentifierName, Name: pointer.String("nutanix-prism-element-1")}}).String()).To(Equal("nutanix-prism-element-1"))
+			Expect(NewNutanixFailureDomain(NutanixFailureDomain{Cluster: machinev1.NutanixResourceIdentifier{Type: machinev1.NutanixIdentifierUUID, UUID: pointer.String("00000000-0000-0000-0000-000000000001")}}).String()).To(Equal("00000000-0000-0000-0000-000000000001"))
+		})
+
+		It("returns unknown for String() when it is empty", func() {
+			Expect(NewNutanixFailureDomain(NutanixFailureDomain{}).String()).To(Equal(unknownFailureDomain))
+		})
+	})
+
 })
@@ -0,0 +1,70 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	"fmt"
+	"strings"
+
+	configv1 "github.com/openshift/api/config/v1"
+
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders/providers/openshift/machine/v1beta1/failuredomain"
+)
+
+// ibmCloudFailureDomainZonesAnnotation is the annotation on the ControlPlaneMachineSet used to spread the Machines on
+// IBM Cloud across VPC zones. The ControlPlaneMachineSet API does not define IBM Cloud failure domains, so each zone
+// listed is used as a failure domain. The value is a comma separated list of zones, eg `us-south-1,us-south-2`.
+const ibmCloudFailureDomainZonesAnnotation = "controlplanemachineset.machine.openshift.io/ibmcloud-failure-domain-zones"
+
+// errInvalidIBMCloudFailureDomainZones is used to denote that the IBM Cloud failure domain zones annotation is not in
+// the expected format, or is set on a platform other than IBM Cloud.
+var errInvalidIBMCloudFailureDomainZones = machineproviders.NewConfigurationError(machineproviders.ReasonInvalidFailureDomains, fmt.Sprintf("invalid value for annotation %s: expected <zone>[,<zone>...]", ibmCloudFailureDomainZonesAnnotation))
+
+// parseIBMCloudFailureDomainZones parses the value of the IBM Cloud failure domain zones annotation into a failure
+// domain for each zone, in the order that the zones are listed.
+// When the annotation is not present, no failure domains are returned. The annotation is only valid on IBM Cloud.
+func parseIBMCloudFailureDomainZones(annotations map[string]string, platformType configv1.PlatformType) ([]failuredomain.FailureDomain, error) {
+	value, ok := annotations[ibmCloudFailureDomainZonesAnnotation]
+	if !ok {
+		return nil, nil
+	}
+
+	if platformType != configv1.IBMCloudPlatformType {
+		return nil, fmt.Errorf("%w, the annotation is not supported on platform %s", errInvalidIBMCloudFailureDomainZones, platformType)
+	}
+
+	failureDomains := []failuredomain.FailureDomain{}
+	zones := map[string]struct{}{}
+
+	for _, zone := range strings.Split(value, ",") {
+		zone = strings.TrimSpace(zone)
+
+		if zone == "" {
+			return nil, fmt.Errorf("%w, got %q", errInvalidIBMCloudFailureDomainZones, value)
+		}
+
+		if _, duplicate := zones[zone]; duplicate {
+			return nil, fmt.Errorf("%w, zone %q is listed more than once", errInvalidIBMCloudFailureDomainZones, zone)
+		}
+
+		zones[zone] = struct{}{}
+		failureDomains = append(failureDomains, failuredomain.NewIBMCloudFailureDomain(failuredomain.IBMCloudFailureDomain{Zone: zone}))
+	}
+
+	return failureDomains, nil
+}
@@ -0,0 +1,147 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	configv1 "github.com/openshift/api/config/v1"
+
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders/providers/openshift/machine/v1beta1/failuredomain"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders/providers/openshift/machine/v1beta1/providerconfig"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/test/resourcebuilder"
+)
+
+var _ = Describe("IBM Cloud Failure Domain Zones", func() {
+	ibmCloudFailureDomain := func(zone string) failuredomain.FailureDomain {
+		return failuredomain.NewIBMCloudFailureDomain(failuredomain.IBMCloudFailureDomain{Zone: zone})
+	}
+
+	type parseIBMCloudFailureDomainZonesTableInput struct {
+		annotations            map[string]string
+		platformType           configv1.PlatformType
+		expectedFailureDomains []failuredomain.FailureDomain
+		expectedError          string
+	}
+
+	DescribeTable("parseIBMCloudFailureDomainZones", func(in parseIBMCloudFailureDomainZonesTableInput) {
+		platformType := in.platformType
+		if platformType == "" {
+			platformType = configv1.IBMCloudPlatformType
+		}
+
+		failureDomains, err := parseIBMCloudFailureDomainZones(in.annotations, platformType)
+
+		if in.expectedError != "" {
+			Expect(err).To(MatchError(errInvalidIBMCloudFailureDomainZones))
+			Expect(err).To(MatchError(ContainSubstring(in.expectedError)))
+		} else {
+			Expect(err).ToNot(HaveOccurred())
+		}
+
+		Expect(failureDomains).To(Equal(in.expectedFailureDomains))
+	},
+		Entry("with no annotations", parseIBMCloudFailureDomainZonesTableInput{
+			annotations:            nil,
+			expectedFailureDomains: nil,
+		}),
+		Entry("with no annotations on another platform", parseIBMCloudFailureDomainZonesTableInput{
+			annotations:            nil,
+			platformType:           configv1.AWSPlatformType,
+			expectedFailureDomains: nil,
+		}),
+		Entry("with a single zone", parseIBMCloudFailureDomainZonesTableInput{
+			annotations: map[string]string{
+				ibmCloudFailureDomainZonesAnnotation: "us-south-1",
+			},
+			expectedFailureDomains: []failuredomain.FailureDomain{ibmCloudFailureDomain("us-south-1")},
+		}),
+		Entry("with multiple zones and surrounding whitespace", parseIBMCloudFailureDomainZonesTableInput{
+			annotations: map[string]string{
+				ibmCloudFailureDomainZonesAnnotation: "us-south-1, us-south-2 ,us-south-3",
+			},
+			expectedFailureDomains: []failuredomain.FailureDomain{
+				ibmCloudFailureDomain("us-south-1"),
+				ibmCloudFailureDomain("us-south-2"),
+				ibmCloudFailureDomain("us-south-3"),
+			},
+		}),
+		Entry("with an empty value", parseIBMCloudFailureDomainZonesTableInput{
+			annotations: map[string]string{
+				ibmCloudFailureDomainZonesAnnotation: "",
+			},
+			expectedError: `got ""`,
+		}),
+		Entry("with an empty zone", parseIBMCloudFailureDomainZonesTableInput{
+			annotations: map[string]string{
+				ibmCloudFailureDomainZonesAnnotation: "us-south-1,,us-south-2",
+			},
+			expectedError: `got "us-south-1,,us-south-2"`,
+		}),
+		Entry("with a zone listed more than once", parseIBMCloudFailureDomainZonesTableInput{
+			annotations: map[string]string{
+				ibmCloudFailureDomainZonesAnnotation: "us-south-1,us-south-1",
+			},
+			expectedError: `zone "us-south-1" is listed more than once`,
+		}),
+		Entry("on another platform", parseIBMCloudFailureDomainZonesTableInput{
+			annotations: map[string]string{
+				ibmCloudFailureDomainZonesAnnotation: "us-south-1",
+			},
+			platformType:  configv1.AWSPlatformType,
+			expectedError: "the annotation is not supported on platform AWS",
+		}),
+	)
+
+	Context("desiredProviderConfig", func() {
+		var provider *openshiftMachineProvider
+
+		BeforeEach(func() {
+			providerConfig, err := providerconfig.NewProviderConfigFromMachineSpec(resourcebuilder.Machine().WithProviderSpecBuilder(resourcebuilder.IBMCloudProviderSpec()).Build().Spec)
+			Expect(err).ToNot(HaveOccurred())
+
+			provider = &openshiftMachineProvider{
+				providerConfig: providerConfig,
+				indexToFailureDomain: map[int32]failuredomain.FailureDomain{
+					0: ibmCloudFailureDomain("us-south-1"),
+					1: ibmCloudFailureDomain("us-south-2"),
+					2: ibmCloudFailureDomain("us-south-3"),
+				},
+			}
+		})
+
+		It("does not require an update for Machines already spread across the zones", func() {
+			machineProviderConfig, err := provider.providerConfig.InjectFailureDomain(ibmCloudFailureDomain("us-south-3"))
+			Expect(err).ToNot(HaveOccurred())
+
+			_, needsUpdate, err := provider.desiredProviderConfig(provider.providerConfig, 2, machineProviderConfig)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(needsUpdate).To(BeFalse())
+		})
+
+		It("requires an update to the zone of its index for Machines in a zone that is not listed", func() {
+			machineProviderConfig, err := provider.providerConfig.InjectFailureDomain(ibmCloudFailureDomain("us-east-1"))
+			Expect(err).ToNot(HaveOccurred())
+
+			desired, needsUpdate, err := provider.desiredProviderConfig(provider.providerConfig, 1, machineProviderConfig)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(needsUpdate).To(BeTrue())
+			Expect(desired.IBMCloud().ExtractFailureDomain().Zone).To(Equal("us-south-2"))
+		})
+	})
+})
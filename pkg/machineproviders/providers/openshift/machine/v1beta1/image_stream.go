@@ -0,0 +1,183 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	configv1 "github.com/openshift/api/config/v1"
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders/providers/openshift/machine/v1beta1/providerconfig"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// imageStreamAnnotation is the annotation on the ControlPlaneMachineSet used to source the image for new
+	// Machines from a boot image stream rather than the image within the template.
+	// The value is the name of a ConfigMap, within the namespace of the ControlPlaneMachineSet, that holds the
+	// CoreOS stream metadata, optionally followed by a slash and the architecture of the image, eg
+	// `coreos-bootimages/aarch64`. When no architecture is given, x86_64 images are used.
+	imageStreamAnnotation = "controlplanemachineset.machine.openshift.io/image-stream"
+
+	// imageStreamConfigMapKey is the key within the image stream ConfigMap that holds the CoreOS stream metadata.
+	imageStreamConfigMapKey = "stream"
+
+	// defaultImageStreamArchitecture is the architecture used to resolve images when the image stream annotation
+	// does not specify an architecture.
+	defaultImageStreamArchitecture = "x86_64"
+
+	// resolvedImageAnnotation is the annotation used to record, on each Machine created from an image stream,
+	// the image that was resolved from the stream when the Machine was created.
+	resolvedImageAnnotation = "controlplanemachineset.machine.openshift.io/resolved-image"
+
+	// imageStreamUnmanagedField is the field reported as unmanaged when the image of a Machine differs from the
+	// template. When the image is sourced from an image stream, the image within the template is not used, so
+	// differences in the image do not cause the Machine to need an update.
+	imageStreamUnmanagedField = "image"
+)
+
+var (
+	// errImageNotFoundInStream is used to denote that the image stream does not contain an image for the
+	// architecture, platform or region of the Machine being created.
+	errImageNotFoundInStream = machineproviders.NewConfigurationError(machineproviders.ReasonImageNotFound, "image not found in image stream")
+
+	// errInvalidImageStreamReference is used to denote that the image stream annotation is not in the
+	// expected format.
+	errInvalidImageStreamReference = machineproviders.NewConfigurationError(machineproviders.ReasonInvalidImageStream, fmt.Sprintf("invalid value for annotation %s: expected <configmap-name>[/<architecture>]", imageStreamAnnotation))
+
+	// errImageStreamUnsupportedPlatform is used to denote that images cannot be resolved from an image stream
+	// for the platform of the ControlPlaneMachineSet.
+	errImageStreamUnsupportedPlatform = machineproviders.NewConfigurationError(machineproviders.ReasonUnsupportedPlatform, "image streams are not supported on platform")
+)
+
+// imageStreamReference identifies the image stream from which images for new Machines should be resolved.
+type imageStreamReference struct {
+	// configMapName is the name of the ConfigMap holding the CoreOS stream metadata.
+	configMapName string
+
+	// architecture is the architecture of the image to resolve from the stream.
+	architecture string
+}
+
+// String returns a string representation of the image stream reference.
+func (i imageStreamReference) String() string {
+	return fmt.Sprintf("%s/%s", i.configMapName, i.architecture)
+}
+
+// parseImageStreamReference parses the value of the image stream annotation.
+// When the annotation is not present, no reference is returned.
+func parseImageStreamReference(annotations map[string]string) (*imageStreamReference, error) {
+	value, ok := annotations[imageStreamAnnotation]
+	if !ok {
+		return nil, nil //nolint:nilnil
+	}
+
+	parts := strings.Split(value, "/")
+
+	switch {
+	case len(parts) == 1 && parts[0] != "":
+		return &imageStreamReference{configMapName: parts[0], architecture: defaultImageStreamArchitecture}, nil
+	case len(parts) == 2 && parts[0] != "" && parts[1] != "":
+		return &imageStreamReference{configMapName: parts[0], architecture: parts[1]}, nil
+	default:
+		return nil, fmt.Errorf("%w, got %q", errInvalidImageStreamReference, value)
+	}
+}
+
+// coreOSStream is the subset of the CoreOS stream metadata required to resolve images for Machines.
+type coreOSStream struct {
+	Architectures map[string]coreOSStreamArchitecture `json:"architectures"`
+}
+
+// coreOSStreamArchitecture holds the images within the stream for a particular architecture.
+type coreOSStreamArchitecture struct {
+	Images coreOSStreamImages `json:"images"`
+}
+
+// coreOSStreamImages holds the platform specific images within the stream.
+type coreOSStreamImages struct {
+	AWS *coreOSStreamRegionalImages `json:"aws,omitempty"`
+}
+
+// coreOSStreamRegionalImages holds images that are published separately into each region of a platform.
+type coreOSStreamRegionalImages struct {
+	Regions map[string]coreOSStreamImage `json:"regions"`
+}
+
+// coreOSStreamImage describes a single image within the stream.
+type coreOSStreamImage struct {
+	// Release is the CoreOS release that the image was built from.
+	Release string `json:"release"`
+
+	// Image is the platform specific identifier of the image.
+	Image string `json:"image"`
+}
+
+// resolveImage fetches the image stream and resolves the image to use for a new Machine based on the
+// platform, region and architecture of the Machine.
+func (m *openshiftMachineProvider) resolveImage(ctx context.Context, pc providerconfig.ProviderConfig) (coreOSStreamImage, error) {
+	configMap := &corev1.ConfigMap{}
+	configMapKey := client.ObjectKey{Namespace: m.ownerMetadata.Namespace, Name: m.imageStream.configMapName}
+
+	if err := m.client.Get(ctx, configMapKey, configMap); err != nil {
+		return coreOSStreamImage{}, fmt.Errorf("could not get image stream config map %s: %w", m.imageStream.configMapName, err)
+	}
+
+	rawStream, ok := configMap.Data[imageStreamConfigMapKey]
+	if !ok {
+		return coreOSStreamImage{}, fmt.Errorf("%w: config map %s has no %q key", errImageNotFoundInStream, m.imageStream.configMapName, imageStreamConfigMapKey)
+	}
+
+	stream := coreOSStream{}
+	if err := json.Unmarshal([]byte(rawStream), &stream); err != nil {
+		return coreOSStreamImage{}, fmt.Errorf("could not unmarshal image stream: %w", err)
+	}
+
+	return stream.imageFor(pc, m.imageStream.architecture)
+}
+
+// imageFor finds the image within the stream that matches the platform and region of the provider config.
+func (s coreOSStream) imageFor(pc providerconfig.ProviderConfig, architecture string) (coreOSStreamImage, error) {
+	arch, ok := s.Architectures[architecture]
+	if !ok {
+		return coreOSStreamImage{}, fmt.Errorf("%w: no images for architecture %s", errImageNotFoundInStream, architecture)
+	}
+
+	switch pc.Type() {
+	case configv1.AWSPlatformType:
+		region := pc.AWS().Config().Placement.Region
+
+		if arch.Images.AWS == nil {
+			return coreOSStreamImage{}, fmt.Errorf("%w: no AWS images for architecture %s", errImageNotFoundInStream, architecture)
+		}
+
+		image, ok := arch.Images.AWS.Regions[region]
+		if !ok || image.Image == "" {
+			return coreOSStreamImage{}, fmt.Errorf("%w: no AWS image for architecture %s in region %s", errImageNotFoundInStream, architecture, region)
+		}
+
+		return image, nil
+	default:
+		return coreOSStreamImage{}, fmt.Errorf("%w: %s", errImageStreamUnsupportedPlatform, pc.Type())
+	}
+}
@@ -0,0 +1,139 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	"encoding/json"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders/providers/openshift/machine/v1beta1/providerconfig"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/test/resourcebuilder"
+)
+
+var _ = Describe("Image Streams", func() {
+	type parseImageStreamReferenceTableInput struct {
+		annotations       map[string]string
+		expectedReference *imageStreamReference
+		expectedError     error
+	}
+
+	DescribeTable("parseImageStreamReference", func(in parseImageStreamReferenceTableInput) {
+		ref, err := parseImageStreamReference(in.annotations)
+
+		if in.expectedError != nil {
+			Expect(err).To(MatchError(in.expectedError))
+		} else {
+			Expect(err).ToNot(HaveOccurred())
+		}
+
+		Expect(ref).To(Equal(in.expectedReference))
+	},
+		Entry("with no annotations", parseImageStreamReferenceTableInput{
+			annotations:       nil,
+			expectedReference: nil,
+		}),
+		Entry("with a config map name only, defaults the architecture", parseImageStreamReferenceTableInput{
+			annotations: map[string]string{
+				imageStreamAnnotation: "coreos-bootimages",
+			},
+			expectedReference: &imageStreamReference{configMapName: "coreos-bootimages", architecture: "x86_64"},
+		}),
+		Entry("with a config map name and architecture", parseImageStreamReferenceTableInput{
+			annotations: map[string]string{
+				imageStreamAnnotation: "coreos-bootimages/aarch64",
+			},
+			expectedReference: &imageStreamReference{configMapName: "coreos-bootimages", architecture: "aarch64"},
+		}),
+		Entry("with an empty value", parseImageStreamReferenceTableInput{
+			annotations: map[string]string{
+				imageStreamAnnotation: "",
+			},
+			expectedError: errInvalidImageStreamReference,
+		}),
+		Entry("with an empty architecture", parseImageStreamReferenceTableInput{
+			annotations: map[string]string{
+				imageStreamAnnotation: "coreos-bootimages/",
+			},
+			expectedError: errInvalidImageStreamReference,
+		}),
+		Entry("with too many segments", parseImageStreamReferenceTableInput{
+			annotations: map[string]string{
+				imageStreamAnnotation: "coreos-bootimages/x86_64/aws",
+			},
+			expectedError: errInvalidImageStreamReference,
+		}),
+	)
+
+	Context("imageFor", func() {
+		const rawStream = `{
+			"architectures": {
+				"x86_64": {
+					"images": {
+						"aws": {
+							"regions": {
+								"us-east-1": {"release": "412.86.202208101039-0", "image": "ami-x86-us-east-1"},
+								"eu-west-1": {"release": "412.86.202208101039-0", "image": "ami-x86-eu-west-1"}
+							}
+						}
+					}
+				},
+				"aarch64": {
+					"images": {}
+				}
+			}
+		}`
+
+		var stream coreOSStream
+		var pc providerconfig.ProviderConfig
+
+		BeforeEach(func() {
+			Expect(json.Unmarshal([]byte(rawStream), &stream)).To(Succeed())
+
+			var err error
+			pc, err = providerconfig.NewProviderConfig(*resourcebuilder.OpenShiftMachineV1Beta1Template().
+				WithProviderSpecBuilder(resourcebuilder.AWSProviderSpec()).
+				BuildTemplate().OpenShiftMachineV1Beta1Machine)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("returns the image for the region of the provider config", func() {
+			Expect(stream.imageFor(pc, "x86_64")).To(Equal(coreOSStreamImage{
+				Release: "412.86.202208101039-0",
+				Image:   "ami-x86-us-east-1",
+			}))
+		})
+
+		It("returns an error when the architecture is not in the stream", func() {
+			_, err := stream.imageFor(pc, "ppc64le")
+			Expect(err).To(MatchError(errImageNotFoundInStream))
+		})
+
+		It("returns an error when the architecture has no images for the platform", func() {
+			_, err := stream.imageFor(pc, "aarch64")
+			Expect(err).To(MatchError(errImageNotFoundInStream))
+		})
+
+		It("returns an error when the region is not in the stream", func() {
+			delete(stream.Architectures["x86_64"].Images.AWS.Regions, "us-east-1")
+
+			_, err := stream.imageFor(pc, "x86_64")
+			Expect(err).To(MatchError(errImageNotFoundInStream))
+		})
+	})
+})
@@ -0,0 +1,98 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	"encoding/json"
+
+	configv1 "github.com/openshift/api/config/v1"
+	machinev1beta1 "github.com/openshift/api/machine/v1beta1"
+	"k8s.io/utils/pointer"
+
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders/providers/openshift/machine/v1beta1/providerconfig"
+)
+
+const (
+	// machineInstanceStateAnnotation is the annotation used by the Machine API to publish the state of the
+	// cloud instance backing a Machine.
+	machineInstanceStateAnnotation = "machine.openshift.io/instance-state"
+
+	// awsInstanceStateRunning is the state of an AWS instance that is running.
+	awsInstanceStateRunning = "running"
+
+	// awsInstanceStateShuttingDown is the state of an AWS instance that is in the process of being terminated.
+	awsInstanceStateShuttingDown = "shutting-down"
+
+	// awsInstanceStateTerminated is the state of an AWS instance that has been terminated.
+	awsInstanceStateTerminated = "terminated"
+)
+
+// instanceMissing determines whether the cloud instance backing a Machine has vanished while the Machine still
+// reports that it is running.
+// The state of the instance is taken from the Machine status, as last observed by the Machine API, so this does
+// not require any calls to the cloud provider.
+func instanceMissing(machine machinev1beta1.Machine, pc providerconfig.ProviderConfig) bool {
+	if pointer.StringDeref(machine.Status.Phase, "") != machinePhaseRunning || pointer.StringDeref(machine.Spec.ProviderID, "") == "" {
+		return false
+	}
+
+	switch pc.Type() {
+	case configv1.AWSPlatformType:
+		state := awsInstanceState(machine)
+		return state == awsInstanceStateShuttingDown || state == awsInstanceStateTerminated
+	default:
+		return false
+	}
+}
+
+// instanceRunning determines whether the cloud instance backing a Machine has been created and is running.
+// The instance is running once the Machine has a provider ID and is provisioned, or running, unless the instance has
+// since gone missing. On AWS, an instance whose observed state is anything other than running, such as one that is
+// pending or stopped, is not running.
+func instanceRunning(machine machinev1beta1.Machine, pc providerconfig.ProviderConfig) bool {
+	phase := pointer.StringDeref(machine.Status.Phase, "")
+	if (phase != machinePhaseProvisioned && phase != machinePhaseRunning) || pointer.StringDeref(machine.Spec.ProviderID, "") == "" {
+		return false
+	}
+
+	if instanceMissing(machine, pc) {
+		return false
+	}
+
+	if pc.Type() == configv1.AWSPlatformType {
+		if state := awsInstanceState(machine); state != "" && state != awsInstanceStateRunning {
+			return false
+		}
+	}
+
+	return true
+}
+
+// awsInstanceState returns the state of the AWS instance backing the Machine.
+// The provider status is preferred, when it does not contain the instance state, the instance state annotation
+// is used instead.
+func awsInstanceState(machine machinev1beta1.Machine) string {
+	if machine.Status.ProviderStatus != nil {
+		providerStatus := machinev1beta1.AWSMachineProviderStatus{}
+
+		if err := json.Unmarshal(machine.Status.ProviderStatus.Raw, &providerStatus); err == nil && providerStatus.InstanceState != nil {
+			return *providerStatus.InstanceState
+		}
+	}
+
+	return machine.GetAnnotations()[machineInstanceStateAnnotation]
+}
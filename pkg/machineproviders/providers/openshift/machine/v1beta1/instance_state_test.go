@@ -0,0 +1,138 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders/providers/openshift/machine/v1beta1/providerconfig"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/test/resourcebuilder"
+)
+
+var _ = Describe("Instance State", func() {
+	type instanceMissingTableInput struct {
+		machineBuilder  resourcebuilder.MachineBuilder
+		expectedMissing bool
+	}
+
+	providerSpecBuilder := resourcebuilder.AWSProviderSpec()
+	runningMachine := resourcebuilder.Machine().
+		WithProviderSpecBuilder(providerSpecBuilder).
+		WithPhase("Running").
+		WithProviderID("aws:///us-east-1a/i-0123456789abcdef0")
+
+	// awsProviderStatus builds an AWS provider status with the given instance state.
+	awsProviderStatus := func(state string) *runtime.RawExtension {
+		return &runtime.RawExtension{Raw: []byte(`{"kind":"AWSMachineProviderStatus","instanceId":"i-0123456789abcdef0","instanceState":"` + state + `"}`)}
+	}
+
+	DescribeTable("instanceMissing", func(in instanceMissingTableInput) {
+		machine := in.machineBuilder.Build()
+
+		pc, err := providerconfig.NewProviderConfigFromMachineSpec(machine.Spec)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(instanceMissing(*machine, pc)).To(Equal(in.expectedMissing))
+	},
+		Entry("with a running instance", instanceMissingTableInput{
+			machineBuilder:  runningMachine.WithProviderStatus(awsProviderStatus("running")),
+			expectedMissing: false,
+		}),
+		Entry("with a terminated instance", instanceMissingTableInput{
+			machineBuilder:  runningMachine.WithProviderStatus(awsProviderStatus("terminated")),
+			expectedMissing: true,
+		}),
+		Entry("with a shutting down instance", instanceMissingTableInput{
+			machineBuilder:  runningMachine.WithProviderStatus(awsProviderStatus("shutting-down")),
+			expectedMissing: true,
+		}),
+		Entry("with a terminated instance state annotation and no provider status", instanceMissingTableInput{
+			machineBuilder:  runningMachine.WithAnnotation(machineInstanceStateAnnotation, "terminated"),
+			expectedMissing: true,
+		}),
+		Entry("with a running provider status and a stale terminated annotation", instanceMissingTableInput{
+			machineBuilder:  runningMachine.WithProviderStatus(awsProviderStatus("running")).WithAnnotation(machineInstanceStateAnnotation, "terminated"),
+			expectedMissing: false,
+		}),
+		Entry("with no instance state", instanceMissingTableInput{
+			machineBuilder:  runningMachine,
+			expectedMissing: false,
+		}),
+		Entry("with a terminated instance on a machine that is not running", instanceMissingTableInput{
+			machineBuilder:  runningMachine.WithPhase("Failed").WithProviderStatus(awsProviderStatus("terminated")),
+			expectedMissing: false,
+		}),
+		Entry("with a terminated instance on a machine without a provider ID", instanceMissingTableInput{
+			machineBuilder:  resourcebuilder.Machine().WithProviderSpecBuilder(providerSpecBuilder).WithPhase("Running").WithProviderStatus(awsProviderStatus("terminated")),
+			expectedMissing: false,
+		}),
+	)
+
+	type instanceRunningTableInput struct {
+		machineBuilder  resourcebuilder.MachineBuilder
+		expectedRunning bool
+	}
+
+	DescribeTable("instanceRunning", func(in instanceRunningTableInput) {
+		machine := in.machineBuilder.Build()
+
+		pc, err := providerconfig.NewProviderConfigFromMachineSpec(machine.Spec)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(instanceRunning(*machine, pc)).To(Equal(in.expectedRunning))
+	},
+		Entry("with a running machine and instance", instanceRunningTableInput{
+			machineBuilder:  runningMachine.WithProviderStatus(awsProviderStatus("running")),
+			expectedRunning: true,
+		}),
+		Entry("with a provisioned machine whose node has not joined", instanceRunningTableInput{
+			machineBuilder:  runningMachine.WithPhase("Provisioned").WithProviderStatus(awsProviderStatus("running")),
+			expectedRunning: true,
+		}),
+		Entry("with no instance state", instanceRunningTableInput{
+			machineBuilder:  runningMachine.WithPhase("Provisioned"),
+			expectedRunning: true,
+		}),
+		Entry("with a pending instance", instanceRunningTableInput{
+			machineBuilder:  runningMachine.WithPhase("Provisioned").WithProviderStatus(awsProviderStatus("pending")),
+			expectedRunning: false,
+		}),
+		Entry("with a stopped instance annotation and no provider status", instanceRunningTableInput{
+			machineBuilder:  runningMachine.WithAnnotation(machineInstanceStateAnnotation, "stopped"),
+			expectedRunning: false,
+		}),
+		Entry("with a terminated instance", instanceRunningTableInput{
+			machineBuilder:  runningMachine.WithProviderStatus(awsProviderStatus("terminated")),
+			expectedRunning: false,
+		}),
+		Entry("with a provisioning machine", instanceRunningTableInput{
+			machineBuilder:  runningMachine.WithPhase("Provisioning"),
+			expectedRunning: false,
+		}),
+		Entry("with a failed machine", instanceRunningTableInput{
+			machineBuilder:  runningMachine.WithPhase("Failed"),
+			expectedRunning: false,
+		}),
+		Entry("with a machine without a provider ID", instanceRunningTableInput{
+			machineBuilder:  resourcebuilder.Machine().WithProviderSpecBuilder(providerSpecBuilder).WithPhase("Provisioned"),
+			expectedRunning: false,
+		}),
+	)
+})
@@ -0,0 +1,51 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	machinev1beta1 "github.com/openshift/api/machine/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	// machineAPIPausedAnnotation is the annotation used to pause the reconciliation of a Machine by the Machine API,
+	// for example while the Machine is migrated to the Cluster API. The presence of the annotation pauses the
+	// Machine, whatever its value.
+	machineAPIPausedAnnotation = "machine.openshift.io/paused"
+
+	// machinePausedCondition is the condition set by the Machine API on a Machine, to report that the Machine API
+	// has paused its reconciliation of the Machine.
+	machinePausedCondition machinev1beta1.ConditionType = "Paused"
+)
+
+// machineAPIPaused determines whether the Machine API has paused the reconciliation of the Machine.
+// A Machine is paused when it carries the paused annotation, or when the Machine API reports that it has paused the
+// Machine via the paused condition. Either is sufficient, as the condition lags behind the annotation until the
+// Machine API next observes the Machine, and the Machine API may pause a Machine without annotating it.
+func machineAPIPaused(machine machinev1beta1.Machine) bool {
+	if _, ok := machine.GetAnnotations()[machineAPIPausedAnnotation]; ok {
+		return true
+	}
+
+	for _, condition := range machine.Status.Conditions {
+		if condition.Type == machinePausedCondition {
+			return condition.Status == corev1.ConditionTrue
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,69 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	machinev1beta1 "github.com/openshift/api/machine/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/test/resourcebuilder"
+)
+
+var _ = Describe("Machine API Paused", func() {
+	type machineAPIPausedTableInput struct {
+		machineBuilder resourcebuilder.MachineBuilder
+		expectedPaused bool
+	}
+
+	machineBuilder := resourcebuilder.Machine().WithPhase("Running")
+
+	// pausedCondition builds the paused condition with the given status.
+	pausedCondition := func(status corev1.ConditionStatus) machinev1beta1.Conditions {
+		return machinev1beta1.Conditions{
+			{Type: "Ready", Status: corev1.ConditionTrue},
+			{Type: machinePausedCondition, Status: status},
+		}
+	}
+
+	DescribeTable("machineAPIPaused", func(in machineAPIPausedTableInput) {
+		Expect(machineAPIPaused(*in.machineBuilder.Build())).To(Equal(in.expectedPaused))
+	},
+		Entry("with no paused annotation or condition", machineAPIPausedTableInput{
+			machineBuilder: machineBuilder,
+			expectedPaused: false,
+		}),
+		Entry("with the paused annotation", machineAPIPausedTableInput{
+			machineBuilder: machineBuilder.WithAnnotation(machineAPIPausedAnnotation, ""),
+			expectedPaused: true,
+		}),
+		Entry("with a true paused condition", machineAPIPausedTableInput{
+			machineBuilder: machineBuilder.WithConditions(pausedCondition(corev1.ConditionTrue)),
+			expectedPaused: true,
+		}),
+		Entry("with a false paused condition", machineAPIPausedTableInput{
+			machineBuilder: machineBuilder.WithConditions(pausedCondition(corev1.ConditionFalse)),
+			expectedPaused: false,
+		}),
+		Entry("with the paused annotation and a false paused condition", machineAPIPausedTableInput{
+			machineBuilder: machineBuilder.WithAnnotation(machineAPIPausedAnnotation, "true").WithConditions(pausedCondition(corev1.ConditionFalse)),
+			expectedPaused: true,
+		}),
+	)
+})
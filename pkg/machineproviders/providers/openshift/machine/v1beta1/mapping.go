@@ -67,7 +67,22 @@ func mapMachineIndexesToFailureDomains(ctx context.Context, logger logr.Logger,
 // domains provided and the number of replicas within the ControlPlaneMachineSet.
 // To ensure consistency, we expect the function to create a stable output no matter the order of the input failure
 // domains.
+// When failure domain weights are configured, the replicas are instead shared across the failure domains in
+// proportion to their weights.
 func createBaseFailureDomainMapping(cpms *machinev1.ControlPlaneMachineSet, failureDomains []failuredomain.FailureDomain) (map[int32]failuredomain.FailureDomain, error) {
+	weights, err := parseFailureDomainWeights(cpms.GetAnnotations(), failureDomains)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing failure domain weights: %w", err)
+	}
+
+	if weights != nil {
+		if cpms.Spec.Replicas == nil {
+			return nil, errReplicasRequired
+		}
+
+		return weightedFailureDomainMapping(*cpms.Spec.Replicas, failureDomains, weights), nil
+	}
+
 	out := make(map[int32]failuredomain.FailureDomain)
 
 	// TODO: Check replicas is set, then sort the failure domains alphabetically, and use modulo arithmetic to set up the
@@ -0,0 +1,97 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	configv1 "github.com/openshift/api/config/v1"
+
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders/providers/openshift/machine/v1beta1/failuredomain"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders/providers/openshift/machine/v1beta1/providerconfig"
+)
+
+// nutanixFailureDomainStorageContainersAnnotation is the annotation on the ControlPlaneMachineSet used to place the
+// data disks of the Machines of particular Nutanix failure domains within a storage container of their own.
+// Storage containers belong to a single Prism Element, so a template that spans Prism Elements cannot name one.
+// The value is a comma separated list of failure domain names and storage container UUIDs,
+// eg `fd-1=00000000-0000-0000-0000-00000000000a,fd-2=00000000-0000-0000-0000-00000000000b`.
+const nutanixFailureDomainStorageContainersAnnotation = "controlplanemachineset.machine.openshift.io/nutanix-failure-domain-storage-containers"
+
+// errInvalidNutanixFailureDomainStorageContainers is used to denote that the Nutanix failure domain storage
+// containers annotation is not in the expected format, or is set on a platform other than Nutanix.
+var errInvalidNutanixFailureDomainStorageContainers = machineproviders.NewConfigurationError(machineproviders.ReasonInvalidFailureDomains, fmt.Sprintf("invalid value for annotation %s: expected <failure-domain>=<storage-container-uuid>[,<failure-domain>=<storage-container-uuid>...]", nutanixFailureDomainStorageContainersAnnotation))
+
+// parseNutanixFailureDomainStorageContainers parses the value of the Nutanix failure domain storage containers
+// annotation into the storage container UUIDs, keyed by the name of their failure domain.
+// When the annotation is not present, no storage containers are returned. The annotation is only valid on Nutanix.
+func parseNutanixFailureDomainStorageContainers(annotations map[string]string, platformType configv1.PlatformType) (map[string]string, error) {
+	value, ok := annotations[nutanixFailureDomainStorageContainersAnnotation]
+	if !ok {
+		return nil, nil //nolint:nilnil
+	}
+
+	if platformType != configv1.NutanixPlatformType {
+		return nil, fmt.Errorf("%w, the annotation is not supported on platform %s", errInvalidNutanixFailureDomainStorageContainers, platformType)
+	}
+
+	storageContainers := map[string]string{}
+
+	for _, entry := range strings.Split(value, ",") {
+		name, storageContainer, ok := strings.Cut(strings.TrimSpace(entry), "=")
+		name, storageContainer = strings.TrimSpace(name), strings.TrimSpace(storageContainer)
+
+		if !ok || name == "" || storageContainer == "" {
+			return nil, fmt.Errorf("%w, got %q", errInvalidNutanixFailureDomainStorageContainers, value)
+		}
+
+		if _, duplicate := storageContainers[name]; duplicate {
+			return nil, fmt.Errorf("%w, failure domain %q is listed more than once", errInvalidNutanixFailureDomainStorageContainers, name)
+		}
+
+		if _, err := uuid.Parse(storageContainer); err != nil {
+			return nil, fmt.Errorf("%w, storage container %q is not a valid UUID", errInvalidNutanixFailureDomainStorageContainers, storageContainer)
+		}
+
+		storageContainers[name] = storageContainer
+	}
+
+	return storageContainers, nil
+}
+
+// injectNutanixFailureDomainStorageContainer injects the storage container of the failure domain into the provider
+// config, when a storage container is set for the failure domain.
+func (m *openshiftMachineProvider) injectNutanixFailureDomainStorageContainer(pc providerconfig.ProviderConfig, fd failuredomain.FailureDomain) (providerconfig.ProviderConfig, error) {
+	if fd == nil || fd.Type() != configv1.NutanixPlatformType {
+		return pc, nil
+	}
+
+	storageContainer, ok := m.nutanixStorageContainers[fd.String()]
+	if !ok {
+		return pc, nil
+	}
+
+	injected, err := pc.InjectStorageContainer(storageContainer)
+	if err != nil {
+		return nil, fmt.Errorf("could not inject storage container for failure domain %s: %w", fd.String(), err)
+	}
+
+	return injected, nil
+}
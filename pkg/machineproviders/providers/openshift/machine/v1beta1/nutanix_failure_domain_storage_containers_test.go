@@ -0,0 +1,107 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	configv1 "github.com/openshift/api/config/v1"
+)
+
+var _ = Describe("Nutanix Failure Domain Storage Containers", func() {
+	type parseNutanixFailureDomainStorageContainersTableInput struct {
+		annotations               map[string]string
+		platformType              configv1.PlatformType
+		expectedStorageContainers map[string]string
+		expectedError             string
+	}
+
+	DescribeTable("parseNutanixFailureDomainStorageContainers", func(in parseNutanixFailureDomainStorageContainersTableInput) {
+		platformType := in.platformType
+		if platformType == "" {
+			platformType = configv1.NutanixPlatformType
+		}
+
+		storageContainers, err := parseNutanixFailureDomainStorageContainers(in.annotations, platformType)
+
+		if in.expectedError != "" {
+			Expect(err).To(MatchError(errInvalidNutanixFailureDomainStorageContainers))
+			Expect(err).To(MatchError(ContainSubstring(in.expectedError)))
+		} else {
+			Expect(err).ToNot(HaveOccurred())
+		}
+
+		Expect(storageContainers).To(Equal(in.expectedStorageContainers))
+	},
+		Entry("with no annotations", parseNutanixFailureDomainStorageContainersTableInput{
+			annotations:               nil,
+			expectedStorageContainers: nil,
+		}),
+		Entry("with no annotations on another platform", parseNutanixFailureDomainStorageContainersTableInput{
+			annotations:               nil,
+			platformType:              configv1.AWSPlatformType,
+			expectedStorageContainers: nil,
+		}),
+		Entry("with a single failure domain", parseNutanixFailureDomainStorageContainersTableInput{
+			annotations: map[string]string{
+				nutanixFailureDomainStorageContainersAnnotation: "fd-1=00000000-0000-0000-0000-00000000000a",
+			},
+			expectedStorageContainers: map[string]string{"fd-1": "00000000-0000-0000-0000-00000000000a"},
+		}),
+		Entry("with multiple failure domains and surrounding whitespace", parseNutanixFailureDomainStorageContainersTableInput{
+			annotations: map[string]string{
+				nutanixFailureDomainStorageContainersAnnotation: "fd-1 = 00000000-0000-0000-0000-00000000000a, fd-2=00000000-0000-0000-0000-00000000000b",
+			},
+			expectedStorageContainers: map[string]string{
+				"fd-1": "00000000-0000-0000-0000-00000000000a",
+				"fd-2": "00000000-0000-0000-0000-00000000000b",
+			},
+		}),
+		Entry("with an empty value", parseNutanixFailureDomainStorageContainersTableInput{
+			annotations: map[string]string{
+				nutanixFailureDomainStorageContainersAnnotation: "",
+			},
+			expectedError: `got ""`,
+		}),
+		Entry("with a missing storage container", parseNutanixFailureDomainStorageContainersTableInput{
+			annotations: map[string]string{
+				nutanixFailureDomainStorageContainersAnnotation: "fd-1=",
+			},
+			expectedError: `got "fd-1="`,
+		}),
+		Entry("with a failure domain listed twice", parseNutanixFailureDomainStorageContainersTableInput{
+			annotations: map[string]string{
+				nutanixFailureDomainStorageContainersAnnotation: "fd-1=00000000-0000-0000-0000-00000000000a,fd-1=00000000-0000-0000-0000-00000000000b",
+			},
+			expectedError: `failure domain "fd-1" is listed more than once`,
+		}),
+		Entry("with a storage container that is not a UUID", parseNutanixFailureDomainStorageContainersTableInput{
+			annotations: map[string]string{
+				nutanixFailureDomainStorageContainersAnnotation: "fd-1=default-container",
+			},
+			expectedError: `storage container "default-container" is not a valid UUID`,
+		}),
+		Entry("on another platform", parseNutanixFailureDomainStorageContainersTableInput{
+			annotations: map[string]string{
+				nutanixFailureDomainStorageContainersAnnotation: "fd-1=00000000-0000-0000-0000-00000000000a",
+			},
+			platformType:  configv1.AWSPlatformType,
+			expectedError: "the annotation is not supported on platform AWS",
+		}),
+	)
+})
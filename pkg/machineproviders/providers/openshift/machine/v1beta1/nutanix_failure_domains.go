@@ -0,0 +1,90 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	"context"
+	"fmt"
+
+	configv1 "github.com/openshift/api/config/v1"
+	machinev1 "github.com/openshift/api/machine/v1"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/clusterconfig"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders/providers/openshift/machine/v1beta1/failuredomain"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// infrastructureNutanixPlatformSpec is the Nutanix platform spec of the Infrastructure resource.
+// The vendored Infrastructure API does not yet describe Nutanix failure domains, so the platform spec
+// is decoded from the unstructured Infrastructure resource.
+type infrastructureNutanixPlatformSpec struct {
+	FailureDomains []infrastructureNutanixFailureDomain `json:"failureDomains,omitempty"`
+}
+
+// infrastructureNutanixFailureDomain is a Nutanix failure domain within the Infrastructure resource.
+// The Prism Element and subnets use the same resource identifiers as the Nutanix provider spec.
+type infrastructureNutanixFailureDomain struct {
+	Name    string                                `json:"name"`
+	Cluster machinev1.NutanixResourceIdentifier   `json:"cluster"`
+	Subnets []machinev1.NutanixResourceIdentifier `json:"subnets,omitempty"`
+}
+
+// infrastructureNutanixFailureDomains returns the Nutanix failure domains defined within the Infrastructure
+// resource, in the order in which they are defined.
+// When the Infrastructure resource does not exist, there are no failure domains.
+func infrastructureNutanixFailureDomains(ctx context.Context, cl client.Reader) ([]failuredomain.NutanixFailureDomain, error) {
+	infrastructure := &unstructured.Unstructured{}
+	infrastructure.SetGroupVersionKind(configv1.GroupVersion.WithKind("Infrastructure"))
+
+	if err := cl.Get(ctx, client.ObjectKey{Name: clusterconfig.InfrastructureName}, infrastructure); apierrors.IsNotFound(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to get infrastructure: %w", err)
+	}
+
+	return nutanixFailureDomainsFromInfrastructure(infrastructure)
+}
+
+// nutanixFailureDomainsFromInfrastructure decodes the Nutanix failure domains from the platform spec of the
+// unstructured Infrastructure resource.
+func nutanixFailureDomainsFromInfrastructure(infrastructure *unstructured.Unstructured) ([]failuredomain.NutanixFailureDomain, error) {
+	platformSpec, ok, err := unstructured.NestedMap(infrastructure.Object, "spec", "platformSpec", "nutanix")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read Nutanix platform spec: %w", err)
+	} else if !ok {
+		return nil, nil
+	}
+
+	spec := infrastructureNutanixPlatformSpec{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(platformSpec, &spec); err != nil {
+		return nil, fmt.Errorf("failed to decode Nutanix platform spec: %w", err)
+	}
+
+	out := []failuredomain.NutanixFailureDomain{}
+
+	for _, fd := range spec.FailureDomains {
+		out = append(out, failuredomain.NutanixFailureDomain{
+			Name:    fd.Name,
+			Cluster: fd.Cluster,
+			Subnets: fd.Subnets,
+		})
+	}
+
+	return out, nil
+}
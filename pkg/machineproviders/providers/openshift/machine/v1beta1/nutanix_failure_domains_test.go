@@ -0,0 +1,128 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	configv1 "github.com/openshift/api/config/v1"
+	machinev1 "github.com/openshift/api/machine/v1"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/clusterconfig"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders/providers/openshift/machine/v1beta1/failuredomain"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders/providers/openshift/machine/v1beta1/providerconfig"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/test/resourcebuilder"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/utils/pointer"
+)
+
+var _ = Describe("Nutanix failure domains", func() {
+	infrastructureWithPlatformSpec := func(platformSpec map[string]interface{}) *unstructured.Unstructured {
+		return &unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": "config.openshift.io/v1",
+			"kind":       "Infrastructure",
+			"metadata":   map[string]interface{}{"name": clusterconfig.InfrastructureName},
+			"spec":       map[string]interface{}{"platformSpec": platformSpec},
+		}}
+	}
+
+	prismElement2 := machinev1.NutanixResourceIdentifier{Type: machinev1.NutanixIdentifierName, Name: pointer.String("nutanix-prism-element-2")}
+	subnet2 := machinev1.NutanixResourceIdentifier{Type: machinev1.NutanixIdentifierUUID, UUID: pointer.String("00000000-0000-0000-0000-000000000002")}
+
+	Context("nutanixFailureDomainsFromInfrastructure", func() {
+		It("decodes the failure domains in the order they are defined", func() {
+			infrastructure := infrastructureWithPlatformSpec(map[string]interface{}{
+				"type": "Nutanix",
+				"nutanix": map[string]interface{}{
+					"failureDomains": []interface{}{
+						map[string]interface{}{
+							"name":    "fd-2",
+							"cluster": map[string]interface{}{"type": "name", "name": "nutanix-prism-element-2"},
+							"subnets": []interface{}{
+								map[string]interface{}{"type": "uuid", "uuid": "00000000-0000-0000-0000-000000000002"},
+							},
+						},
+						map[string]interface{}{
+							"name":    "fd-1",
+							"cluster": map[string]interface{}{"type": "name", "name": "nutanix-prism-element-1"},
+						},
+					},
+				},
+			})
+
+			Expect(nutanixFailureDomainsFromInfrastructure(infrastructure)).To(Equal([]failuredomain.NutanixFailureDomain{
+				{
+					Name:    "fd-2",
+					Cluster: prismElement2,
+					Subnets: []machinev1.NutanixResourceIdentifier{subnet2},
+				},
+				{
+					Name:    "fd-1",
+					Cluster: machinev1.NutanixResourceIdentifier{Type: machinev1.NutanixIdentifierName, Name: pointer.String("nutanix-prism-element-1")},
+				},
+			}))
+		})
+
+		It("returns no failure domains without a Nutanix platform spec", func() {
+			Expect(nutanixFailureDomainsFromInfrastructure(infrastructureWithPlatformSpec(map[string]interface{}{"type": "Nutanix"}))).To(BeEmpty())
+		})
+
+		It("returns an error when the failure domains cannot be decoded", func() {
+			infrastructure := infrastructureWithPlatformSpec(map[string]interface{}{
+				"nutanix": map[string]interface{}{"failureDomains": "fd-1"},
+			})
+
+			_, err := nutanixFailureDomainsFromInfrastructure(infrastructure)
+			Expect(err).To(MatchError(ContainSubstring("failed to decode Nutanix platform spec")))
+		})
+	})
+
+	Context("newFailureDomains", func() {
+		It("returns a configuration error when the Infrastructure does not exist", func() {
+			_, err := newFailureDomains(ctx, k8sClient, machinev1.FailureDomains{Platform: configv1.NutanixPlatformType})
+			Expect(err).To(MatchError("missing Nutanix failure domains within the infrastructure resource"))
+		})
+	})
+
+	Context("failureDomainMatches", func() {
+		var templateProviderConfig providerconfig.ProviderConfig
+
+		fd2 := failuredomain.NewNutanixFailureDomain(failuredomain.NutanixFailureDomain{
+			Name:    "fd-2",
+			Cluster: prismElement2,
+			Subnets: []machinev1.NutanixResourceIdentifier{subnet2},
+		})
+
+		BeforeEach(func() {
+			var err error
+			templateProviderConfig, err = providerconfig.NewProviderConfigFromMachineSpec(resourcebuilder.Machine().WithProviderSpecBuilder(resourcebuilder.NutanixProviderSpec()).Build().Spec)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("matches a Machine created within the failure domain", func() {
+			machineProviderConfig, err := templateProviderConfig.InjectFailureDomain(fd2)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(failureDomainMatches(machineProviderConfig, fd2)).To(BeTrue())
+		})
+
+		It("does not match a Machine within another Prism Element", func() {
+			Expect(failureDomainMatches(templateProviderConfig, fd2)).To(BeFalse())
+		})
+	})
+})
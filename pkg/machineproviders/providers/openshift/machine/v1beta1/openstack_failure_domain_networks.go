@@ -0,0 +1,100 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	configv1 "github.com/openshift/api/config/v1"
+
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders/providers/openshift/machine/v1beta1/failuredomain"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders/providers/openshift/machine/v1beta1/providerconfig"
+)
+
+// openStackFailureDomainNetworksAnnotation is the annotation on the ControlPlaneMachineSet used to attach the Machines
+// of particular OpenStack failure domains to additional networks, such as a second network local to each availability
+// zone. The value is a comma separated list of failure domain names and network UUIDs, with the networks of a failure
+// domain separated by colons, eg `nova-az1=<network-uuid>:<network-uuid>,nova-az2=<network-uuid>`.
+const openStackFailureDomainNetworksAnnotation = "controlplanemachineset.machine.openshift.io/openstack-failure-domain-networks"
+
+// errInvalidOpenStackFailureDomainNetworks is used to denote that the OpenStack failure domain networks annotation
+// is not in the expected format, or is set on a platform other than OpenStack.
+var errInvalidOpenStackFailureDomainNetworks = machineproviders.NewConfigurationError(machineproviders.ReasonInvalidFailureDomains, fmt.Sprintf("invalid value for annotation %s: expected <failure-domain>=<network-uuid>[:<network-uuid>...][,<failure-domain>=<network-uuid>[:<network-uuid>...]...]", openStackFailureDomainNetworksAnnotation))
+
+// parseOpenStackFailureDomainNetworks parses the value of the OpenStack failure domain networks annotation into the
+// UUIDs of the additional networks, keyed by the name of their failure domain.
+// When the annotation is not present, no networks are returned. The annotation is only valid on OpenStack.
+func parseOpenStackFailureDomainNetworks(annotations map[string]string, platformType configv1.PlatformType) (map[string][]string, error) {
+	value, ok := annotations[openStackFailureDomainNetworksAnnotation]
+	if !ok {
+		return nil, nil //nolint:nilnil
+	}
+
+	if platformType != configv1.OpenStackPlatformType {
+		return nil, fmt.Errorf("%w, the annotation is not supported on platform %s", errInvalidOpenStackFailureDomainNetworks, platformType)
+	}
+
+	networks := map[string][]string{}
+
+	for _, entry := range strings.Split(value, ",") {
+		name, list, ok := strings.Cut(strings.TrimSpace(entry), "=")
+		name, list = strings.TrimSpace(name), strings.TrimSpace(list)
+
+		if !ok || name == "" || list == "" {
+			return nil, fmt.Errorf("%w, got %q", errInvalidOpenStackFailureDomainNetworks, value)
+		}
+
+		if _, duplicate := networks[name]; duplicate {
+			return nil, fmt.Errorf("%w, failure domain %q is listed more than once", errInvalidOpenStackFailureDomainNetworks, name)
+		}
+
+		for _, network := range strings.Split(list, ":") {
+			network = strings.TrimSpace(network)
+
+			if _, err := uuid.Parse(network); err != nil {
+				return nil, fmt.Errorf("%w, network %q is not a valid UUID", errInvalidOpenStackFailureDomainNetworks, network)
+			}
+
+			networks[name] = append(networks[name], network)
+		}
+	}
+
+	return networks, nil
+}
+
+// injectOpenStackFailureDomainNetworks attaches the Machine to the additional networks of the failure domain, when
+// the failure domain has additional networks.
+func (m *openshiftMachineProvider) injectOpenStackFailureDomainNetworks(pc providerconfig.ProviderConfig, fd failuredomain.FailureDomain) (providerconfig.ProviderConfig, error) {
+	if fd == nil || fd.Type() != configv1.OpenStackPlatformType {
+		return pc, nil
+	}
+
+	networks, ok := m.openStackNetworks[fd.String()]
+	if !ok {
+		return pc, nil
+	}
+
+	injected, err := pc.InjectAdditionalNetworks(networks)
+	if err != nil {
+		return nil, fmt.Errorf("could not inject additional networks for failure domain %s: %w", fd.String(), err)
+	}
+
+	return injected, nil
+}
@@ -0,0 +1,183 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	configv1 "github.com/openshift/api/config/v1"
+
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders/providers/openshift/machine/v1beta1/failuredomain"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders/providers/openshift/machine/v1beta1/providerconfig"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/test/resourcebuilder"
+)
+
+var _ = Describe("OpenStack Failure Domain Networks", func() {
+	type parseOpenStackFailureDomainNetworksTableInput struct {
+		annotations      map[string]string
+		platformType     configv1.PlatformType
+		expectedNetworks map[string][]string
+		expectedError    string
+	}
+
+	DescribeTable("parseOpenStackFailureDomainNetworks", func(in parseOpenStackFailureDomainNetworksTableInput) {
+		platformType := in.platformType
+		if platformType == "" {
+			platformType = configv1.OpenStackPlatformType
+		}
+
+		networks, err := parseOpenStackFailureDomainNetworks(in.annotations, platformType)
+
+		if in.expectedError != "" {
+			Expect(err).To(MatchError(errInvalidOpenStackFailureDomainNetworks))
+			Expect(err).To(MatchError(ContainSubstring(in.expectedError)))
+		} else {
+			Expect(err).ToNot(HaveOccurred())
+		}
+
+		Expect(networks).To(Equal(in.expectedNetworks))
+	},
+		Entry("with no annotations", parseOpenStackFailureDomainNetworksTableInput{
+			annotations:      nil,
+			expectedNetworks: nil,
+		}),
+		Entry("with no annotations on another platform", parseOpenStackFailureDomainNetworksTableInput{
+			annotations:      nil,
+			platformType:     configv1.AWSPlatformType,
+			expectedNetworks: nil,
+		}),
+		Entry("with a single failure domain", parseOpenStackFailureDomainNetworksTableInput{
+			annotations: map[string]string{
+				openStackFailureDomainNetworksAnnotation: "nova-az1=00000000-0000-0000-0000-00000000000a",
+			},
+			expectedNetworks: map[string][]string{"nova-az1": {"00000000-0000-0000-0000-00000000000a"}},
+		}),
+		Entry("with multiple networks and surrounding whitespace", parseOpenStackFailureDomainNetworksTableInput{
+			annotations: map[string]string{
+				openStackFailureDomainNetworksAnnotation: "nova-az1 = 00000000-0000-0000-0000-00000000000a : 00000000-0000-0000-0000-00000000000b, nova-az2=00000000-0000-0000-0000-00000000000c",
+			},
+			expectedNetworks: map[string][]string{
+				"nova-az1": {"00000000-0000-0000-0000-00000000000a", "00000000-0000-0000-0000-00000000000b"},
+				"nova-az2": {"00000000-0000-0000-0000-00000000000c"},
+			},
+		}),
+		Entry("with an empty value", parseOpenStackFailureDomainNetworksTableInput{
+			annotations: map[string]string{
+				openStackFailureDomainNetworksAnnotation: "",
+			},
+			expectedError: `got ""`,
+		}),
+		Entry("with a missing network", parseOpenStackFailureDomainNetworksTableInput{
+			annotations: map[string]string{
+				openStackFailureDomainNetworksAnnotation: "nova-az1=",
+			},
+			expectedError: `got "nova-az1="`,
+		}),
+		Entry("with an empty network in the list", parseOpenStackFailureDomainNetworksTableInput{
+			annotations: map[string]string{
+				openStackFailureDomainNetworksAnnotation: "nova-az1=00000000-0000-0000-0000-00000000000a:",
+			},
+			expectedError: `network "" is not a valid UUID`,
+		}),
+		Entry("with a failure domain listed twice", parseOpenStackFailureDomainNetworksTableInput{
+			annotations: map[string]string{
+				openStackFailureDomainNetworksAnnotation: "nova-az1=00000000-0000-0000-0000-00000000000a,nova-az1=00000000-0000-0000-0000-00000000000b",
+			},
+			expectedError: `failure domain "nova-az1" is listed more than once`,
+		}),
+		Entry("with a network that is not a UUID", parseOpenStackFailureDomainNetworksTableInput{
+			annotations: map[string]string{
+				openStackFailureDomainNetworksAnnotation: "nova-az1=storage-network",
+			},
+			expectedError: `network "storage-network" is not a valid UUID`,
+		}),
+		Entry("on another platform", parseOpenStackFailureDomainNetworksTableInput{
+			annotations: map[string]string{
+				openStackFailureDomainNetworksAnnotation: "us-east-1a=00000000-0000-0000-0000-00000000000a",
+			},
+			platformType:  configv1.AWSPlatformType,
+			expectedError: "the annotation is not supported on platform AWS",
+		}),
+	)
+
+	Context("injectFailureDomain", func() {
+		var provider *openshiftMachineProvider
+
+		openStackFailureDomain := func(zone string) failuredomain.FailureDomain {
+			return failuredomain.NewOpenStackFailureDomain(failuredomain.OpenStackFailureDomain{AvailabilityZone: zone})
+		}
+
+		BeforeEach(func() {
+			providerConfig, err := providerconfig.NewProviderConfigFromMachineSpec(resourcebuilder.Machine().WithProviderSpecBuilder(resourcebuilder.OpenStackProviderSpec()).Build().Spec)
+			Expect(err).ToNot(HaveOccurred())
+
+			provider = &openshiftMachineProvider{
+				providerConfig: providerConfig,
+				openStackNetworks: map[string][]string{
+					"nova-az2": {"00000000-0000-0000-0000-00000000000a", "00000000-0000-0000-0000-00000000000b"},
+				},
+			}
+		})
+
+		It("attaches the Machine to the additional networks of the failure domain", func() {
+			injected, err := provider.injectFailureDomain(provider.providerConfig, openStackFailureDomain("nova-az2"))
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(injected.OpenStack().ExtractNetworks()).To(Equal([]string{"00000000-0000-0000-0000-00000000000a", "00000000-0000-0000-0000-00000000000b"}))
+			Expect(injected.ExtractFailureDomain().OpenStack().AvailabilityZone).To(Equal("nova-az2"))
+		})
+
+		It("does not attach Machines in other failure domains to additional networks", func() {
+			injected, err := provider.injectFailureDomain(provider.providerConfig, openStackFailureDomain("nova-az3"))
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(injected.OpenStack().ExtractNetworks()).To(BeEmpty())
+		})
+
+		It("does not attach Machines without a failure domain to additional networks", func() {
+			injected, err := provider.injectFailureDomain(provider.providerConfig, nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(injected.OpenStack().ExtractNetworks()).To(BeEmpty())
+		})
+
+		It("requires an update for Machines in the failure domain without the additional networks", func() {
+			fd := openStackFailureDomain("nova-az2")
+			provider.indexToFailureDomain = map[int32]failuredomain.FailureDomain{0: fd}
+
+			machineProviderConfig, err := provider.providerConfig.InjectFailureDomain(fd)
+			Expect(err).ToNot(HaveOccurred())
+
+			_, needsUpdate, err := provider.desiredProviderConfig(provider.providerConfig, 0, machineProviderConfig)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(needsUpdate).To(BeTrue())
+		})
+
+		It("does not require an update for Machines in the failure domain with the additional networks", func() {
+			fd := openStackFailureDomain("nova-az2")
+			provider.indexToFailureDomain = map[int32]failuredomain.FailureDomain{0: fd}
+
+			machineProviderConfig, err := provider.injectFailureDomain(provider.providerConfig, fd)
+			Expect(err).ToNot(HaveOccurred())
+
+			_, needsUpdate, err := provider.desiredProviderConfig(provider.providerConfig, 0, machineProviderConfig)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(needsUpdate).To(BeFalse())
+		})
+	})
+})
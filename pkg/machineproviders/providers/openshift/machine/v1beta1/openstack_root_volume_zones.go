@@ -0,0 +1,103 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	"fmt"
+	"strings"
+
+	configv1 "github.com/openshift/api/config/v1"
+
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders/providers/openshift/machine/v1beta1/failuredomain"
+)
+
+const (
+	// openStackRootVolumeZonesAnnotation is the annotation on the ControlPlaneMachineSet used to set the Cinder
+	// availability zone of the root volume within particular OpenStack failure domains. The ControlPlaneMachineSet
+	// API only defines the Nova availability zone of OpenStack failure domains, and on clouds whose Nova and Cinder
+	// availability zones are named differently, the root volume of each Machine must be created in the zone
+	// matching its instance.
+	// The value is a comma separated list of Nova and Cinder availability zones, eg `nova-az1=cinder-az1,nova-az2=cinder-az2`.
+	openStackRootVolumeZonesAnnotation = "controlplanemachineset.machine.openshift.io/openstack-root-volume-availability-zones"
+)
+
+// errInvalidOpenStackRootVolumeZones is used to denote that the OpenStack root volume availability zones annotation
+// is not in the expected format, or is set on a platform other than OpenStack.
+var errInvalidOpenStackRootVolumeZones = machineproviders.NewConfigurationError(machineproviders.ReasonInvalidFailureDomains, fmt.Sprintf("invalid value for annotation %s: expected <availability-zone>=<root-volume-availability-zone>[,<availability-zone>=<root-volume-availability-zone>...]", openStackRootVolumeZonesAnnotation))
+
+// parseOpenStackRootVolumeZones parses the value of the OpenStack root volume availability zones annotation into the
+// root volume availability zones, keyed by the Nova availability zone of their failure domain.
+// When the annotation is not present, no root volume availability zones are returned. The annotation is only valid
+// on OpenStack.
+func parseOpenStackRootVolumeZones(annotations map[string]string, platformType configv1.PlatformType) (map[string]string, error) {
+	value, ok := annotations[openStackRootVolumeZonesAnnotation]
+	if !ok {
+		return nil, nil //nolint:nilnil
+	}
+
+	if platformType != configv1.OpenStackPlatformType {
+		return nil, fmt.Errorf("%w, the annotation is not supported on platform %s", errInvalidOpenStackRootVolumeZones, platformType)
+	}
+
+	zones := map[string]string{}
+
+	for _, entry := range strings.Split(value, ",") {
+		zone, rootVolumeZone, ok := strings.Cut(strings.TrimSpace(entry), "=")
+		zone, rootVolumeZone = strings.TrimSpace(zone), strings.TrimSpace(rootVolumeZone)
+
+		if !ok || zone == "" || rootVolumeZone == "" {
+			return nil, fmt.Errorf("%w, got %q", errInvalidOpenStackRootVolumeZones, value)
+		}
+
+		if _, duplicate := zones[zone]; duplicate {
+			return nil, fmt.Errorf("%w, availability zone %q is listed more than once", errInvalidOpenStackRootVolumeZones, zone)
+		}
+
+		zones[zone] = rootVolumeZone
+	}
+
+	return zones, nil
+}
+
+// withOpenStackRootVolumeZones returns the failure domains with the root volume availability zone of each OpenStack
+// failure domain set, when it is listed within the root volume availability zones. Failure domains of other
+// platforms, and those whose availability zone is not listed, are returned unchanged.
+// As the root volume availability zone is part of the failure domain, it is both injected into new Machines, and
+// compared with the root volumes of existing Machines.
+func withOpenStackRootVolumeZones(failureDomains []failuredomain.FailureDomain, rootVolumeZones map[string]string) []failuredomain.FailureDomain {
+	if len(rootVolumeZones) == 0 {
+		return failureDomains
+	}
+
+	out := []failuredomain.FailureDomain{}
+
+	for _, fd := range failureDomains {
+		rootVolumeZone, ok := rootVolumeZones[fd.OpenStack().AvailabilityZone]
+		if fd.Type() != configv1.OpenStackPlatformType || !ok {
+			out = append(out, fd)
+			continue
+		}
+
+		out = append(out, failuredomain.NewOpenStackFailureDomain(failuredomain.OpenStackFailureDomain{
+			AvailabilityZone:           fd.OpenStack().AvailabilityZone,
+			RootVolumeAvailabilityZone: rootVolumeZone,
+		}))
+	}
+
+	return out
+}
@@ -0,0 +1,192 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	configv1 "github.com/openshift/api/config/v1"
+
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders/providers/openshift/machine/v1beta1/failuredomain"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders/providers/openshift/machine/v1beta1/providerconfig"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/test/resourcebuilder"
+)
+
+var _ = Describe("OpenStack Root Volume Availability Zones", func() {
+	type parseOpenStackRootVolumeZonesTableInput struct {
+		annotations   map[string]string
+		platformType  configv1.PlatformType
+		expectedZones map[string]string
+		expectedError string
+	}
+
+	DescribeTable("parseOpenStackRootVolumeZones", func(in parseOpenStackRootVolumeZonesTableInput) {
+		platformType := in.platformType
+		if platformType == "" {
+			platformType = configv1.OpenStackPlatformType
+		}
+
+		zones, err := parseOpenStackRootVolumeZones(in.annotations, platformType)
+
+		if in.expectedError != "" {
+			Expect(err).To(MatchError(errInvalidOpenStackRootVolumeZones))
+			Expect(err).To(MatchError(ContainSubstring(in.expectedError)))
+		} else {
+			Expect(err).ToNot(HaveOccurred())
+		}
+
+		Expect(zones).To(Equal(in.expectedZones))
+	},
+		Entry("with no annotations", parseOpenStackRootVolumeZonesTableInput{
+			annotations:   nil,
+			expectedZones: nil,
+		}),
+		Entry("with no annotations on another platform", parseOpenStackRootVolumeZonesTableInput{
+			annotations:   nil,
+			platformType:  configv1.AWSPlatformType,
+			expectedZones: nil,
+		}),
+		Entry("with multiple zones and surrounding whitespace", parseOpenStackRootVolumeZonesTableInput{
+			annotations: map[string]string{
+				openStackRootVolumeZonesAnnotation: "nova-az1=cinder-az1, nova-az2 = cinder-az2",
+			},
+			expectedZones: map[string]string{"nova-az1": "cinder-az1", "nova-az2": "cinder-az2"},
+		}),
+		Entry("with an empty value", parseOpenStackRootVolumeZonesTableInput{
+			annotations: map[string]string{
+				openStackRootVolumeZonesAnnotation: "",
+			},
+			expectedError: `got ""`,
+		}),
+		Entry("with a missing root volume availability zone", parseOpenStackRootVolumeZonesTableInput{
+			annotations: map[string]string{
+				openStackRootVolumeZonesAnnotation: "nova-az1=",
+			},
+			expectedError: `got "nova-az1="`,
+		}),
+		Entry("with a zone listed twice", parseOpenStackRootVolumeZonesTableInput{
+			annotations: map[string]string{
+				openStackRootVolumeZonesAnnotation: "nova-az1=cinder-az1,nova-az1=cinder-az2",
+			},
+			expectedError: `availability zone "nova-az1" is listed more than once`,
+		}),
+		Entry("on another platform", parseOpenStackRootVolumeZonesTableInput{
+			annotations: map[string]string{
+				openStackRootVolumeZonesAnnotation: "nova-az1=cinder-az1",
+			},
+			platformType:  configv1.AWSPlatformType,
+			expectedError: "the annotation is not supported on platform AWS",
+		}),
+	)
+
+	Context("withOpenStackRootVolumeZones", func() {
+		var failureDomains []failuredomain.FailureDomain
+
+		BeforeEach(func() {
+			var err error
+			failureDomains, err = failuredomain.NewFailureDomains(resourcebuilder.OpenStackFailureDomains().BuildFailureDomains())
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("sets the root volume availability zone of the listed failure domains", func() {
+			out := withOpenStackRootVolumeZones(failureDomains, map[string]string{
+				"nova-az1": "cinder-az1",
+				"nova-az3": "cinder-az3",
+			})
+
+			Expect(out).To(HaveLen(3))
+			Expect(out[0].OpenStack()).To(Equal(failuredomain.OpenStackFailureDomain{AvailabilityZone: "nova-az1", RootVolumeAvailabilityZone: "cinder-az1"}))
+			Expect(out[1].OpenStack()).To(Equal(failuredomain.OpenStackFailureDomain{AvailabilityZone: "nova-az2"}))
+			Expect(out[2].OpenStack()).To(Equal(failuredomain.OpenStackFailureDomain{AvailabilityZone: "nova-az3", RootVolumeAvailabilityZone: "cinder-az3"}))
+		})
+
+		It("returns the failure domains unchanged without root volume availability zones", func() {
+			Expect(withOpenStackRootVolumeZones(failureDomains, nil)).To(Equal(failureDomains))
+		})
+
+		It("returns failure domains of other platforms unchanged", func() {
+			awsFailureDomains := []failuredomain.FailureDomain{
+				failuredomain.NewAWSFailureDomain(resourcebuilder.AWSFailureDomain().WithAvailabilityZone("us-east-1a").Build()),
+			}
+
+			Expect(withOpenStackRootVolumeZones(awsFailureDomains, map[string]string{"us-east-1a": "cinder-az1"})).To(Equal(awsFailureDomains))
+		})
+	})
+
+	Context("desiredProviderConfig", func() {
+		var provider *openshiftMachineProvider
+
+		openStackFailureDomain := func(zone, rootVolumeZone string) failuredomain.FailureDomain {
+			return failuredomain.NewOpenStackFailureDomain(failuredomain.OpenStackFailureDomain{
+				AvailabilityZone:           zone,
+				RootVolumeAvailabilityZone: rootVolumeZone,
+			})
+		}
+
+		machineProviderConfig := func(builder resourcebuilder.OpenStackProviderSpecBuilder) providerconfig.ProviderConfig {
+			pc, err := providerconfig.NewProviderConfigFromMachineSpec(resourcebuilder.Machine().WithProviderSpecBuilder(builder).Build().Spec)
+			Expect(err).ToNot(HaveOccurred())
+
+			return pc
+		}
+
+		BeforeEach(func() {
+			provider = &openshiftMachineProvider{
+				providerConfig: machineProviderConfig(resourcebuilder.OpenStackProviderSpec()),
+				indexToFailureDomain: map[int32]failuredomain.FailureDomain{
+					0: openStackFailureDomain("nova-az2", "cinder-az2"),
+				},
+			}
+		})
+
+		It("injects both availability zones of the failure domain", func() {
+			desired, _, err := provider.desiredProviderConfig(provider.providerConfig, 0, provider.providerConfig)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(desired.ExtractFailureDomain().OpenStack()).To(Equal(failuredomain.OpenStackFailureDomain{
+				AvailabilityZone:           "nova-az2",
+				RootVolumeAvailabilityZone: "cinder-az2",
+			}))
+		})
+
+		It("does not require an update for Machines in both availability zones of the failure domain", func() {
+			current := machineProviderConfig(resourcebuilder.OpenStackProviderSpec().WithAvailabilityZone("nova-az2").WithRootVolumeAvailabilityZone("cinder-az2"))
+
+			_, needsUpdate, err := provider.desiredProviderConfig(provider.providerConfig, 0, current)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(needsUpdate).To(BeFalse())
+		})
+
+		It("requires an update for Machines whose root volume is in a different availability zone", func() {
+			current := machineProviderConfig(resourcebuilder.OpenStackProviderSpec().WithAvailabilityZone("nova-az2"))
+
+			_, needsUpdate, err := provider.desiredProviderConfig(provider.providerConfig, 0, current)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(needsUpdate).To(BeTrue())
+		})
+
+		It("does not require an update for Machines without a root volume availability zone when the failure domain has none", func() {
+			provider.indexToFailureDomain[0] = openStackFailureDomain("nova-az2", "")
+			current := machineProviderConfig(resourcebuilder.OpenStackProviderSpec().WithAvailabilityZone("nova-az2"))
+
+			_, needsUpdate, err := provider.desiredProviderConfig(provider.providerConfig, 0, current)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(needsUpdate).To(BeFalse())
+		})
+	})
+})
@@ -20,11 +20,19 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"sort"
+	"strconv"
+	"strings"
 
 	"github.com/go-logr/logr"
+	configv1 "github.com/openshift/api/config/v1"
 	machinev1 "github.com/openshift/api/machine/v1"
 	machinev1beta1 "github.com/openshift/api/machine/v1beta1"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/rand"
+	"k8s.io/utils/pointer"
 
 	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders"
 	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders/providers/openshift/machine/v1beta1/failuredomain"
@@ -33,25 +41,62 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
+const (
+	// couldNotGatherMachineInfo is a log message used to inform the user that the machine info
+	// for a Machine could not be constructed.
+	couldNotGatherMachineInfo = "Could not gather Machine Info"
+
+	// gatheredMachineInfo is a log message used to inform the user about the machine info
+	// constructed for a Machine.
+	gatheredMachineInfo = "Gathered Machine Info"
+
+	// machinePhaseRunning is the phase of a Machine once the instance has been created
+	// and the Node has joined the cluster.
+	machinePhaseRunning = "Running"
+
+	// machinePhaseProvisioned is the phase of a Machine once the instance has been created,
+	// but before the Node has joined the cluster.
+	machinePhaseProvisioned = "Provisioned"
+
+	// createdMachine is a log message used to inform the user that a new Machine was created.
+	createdMachine = "Created machine"
+
+	// resolvedImageFromStream is a log message used to inform the user that the image for a new Machine
+	// was resolved from the image stream.
+	resolvedImageFromStream = "Resolved image from image stream"
+
+	// machineNameSuffixLength is the length of the random suffix added to the names of new Machines.
+	// The random suffix prevents new Machines from clashing with Machines pending deletion in the same index.
+	machineNameSuffixLength = 5
+
+	// machineNameSuffixChars are the characters used to generate the random suffix of new Machine names.
+	machineNameSuffixChars = "abcdefghijklmnopqrstuvwxyz"
+
+	// masterMachineNameInfix is the part of the Control Plane Machine names that follows the cluster ID.
+	// Control Plane Machine names are expected to be of the form <cluster-id>-master-<index>, or for
+	// replacement Machines, <cluster-id>-master-<random-suffix>-<index>.
+	masterMachineNameInfix = "master"
+)
+
 var (
 	// errCouldNotDetermineMachineIndex is used to denote that the MachineProvider could not infer an
 	// index to assign to a Machine based on either the name or the failure domain.
 	// This means the Machine has been created in some manor outside of OpenShift norms and is in a failure domain
 	// not currently specified in the ControlPlaneMachineSet definition. User intervention is required here.
-	errCouldNotDetermineMachineIndex = errors.New("could not determine Machine index from name or failure domain")
+	errCouldNotDetermineMachineIndex = machineproviders.NewConfigurationError(machineproviders.ReasonUnknownMachineIndex, "could not determine Machine index from name or failure domain")
 
 	// errEmptyConfig is used to denote that the machine provider could not be constructed
 	// because no configuration was provided by the user.
-	errEmptyConfig = fmt.Errorf("cannot initialise %s provider with empty config", machinev1.OpenShiftMachineV1Beta1MachineType)
+	errEmptyConfig = machineproviders.NewConfigurationError(machineproviders.ReasonInvalidMachineTemplate, fmt.Sprintf("cannot initialise %s provider with empty config", machinev1.OpenShiftMachineV1Beta1MachineType))
 
 	// errMissingClusterIDLabel is used to denote that the cluster ID label, expected to be on the Machine template
 	// is not present and therefore a Machine cannot be created. The Cluster ID is required to construct the name for
 	// the new Machines.
-	errMissingClusterIDLabel = fmt.Errorf("missing required label on machine template metadata: %s", machinev1beta1.MachineClusterIDLabel)
+	errMissingClusterIDLabel = machineproviders.NewConfigurationError(machineproviders.ReasonInvalidMachineTemplate, fmt.Sprintf("missing required label on machine template metadata: %s", machinev1beta1.MachineClusterIDLabel))
 
 	// errUnexpectedMachineType is used to denote that the machine provider was requested
 	// for an unsupported machine provider type (ie not OpenShift Machine v1beta1).
-	errUnexpectedMachineType = fmt.Errorf("unexpected machine type while initialising %s provider", machinev1.OpenShiftMachineV1Beta1MachineType)
+	errUnexpectedMachineType = machineproviders.NewConfigurationError(machineproviders.ReasonUnsupportedMachineType, fmt.Sprintf("unexpected machine type while initialising %s provider", machinev1.OpenShiftMachineV1Beta1MachineType))
 
 	// errUnknownGroupVersionResource is used to denote that the machine provider received an
 	// unknown GroupVersionResource while processing a Machine deletion request.
@@ -73,9 +118,39 @@ func NewMachineProvider(ctx context.Context, logger logr.Logger, cl client.Clien
 		return nil, fmt.Errorf("error constructing provider config: %w", err)
 	}
 
-	failureDomains, err := failuredomain.NewFailureDomains(cpms.Spec.Template.OpenShiftMachineV1Beta1Machine.FailureDomains)
+	failureDomains, err := templateFailureDomains(ctx, cl, cpms, providerConfig)
 	if err != nil {
-		return nil, fmt.Errorf("error constructing failure domain config: %w", err)
+		return nil, err
+	}
+
+	imageStream, err := parseImageStreamReference(cpms.GetAnnotations())
+	if err != nil {
+		return nil, fmt.Errorf("error parsing image stream reference: %w", err)
+	}
+
+	nutanixStorageContainers, err := parseNutanixFailureDomainStorageContainers(cpms.GetAnnotations(), providerConfig.Type())
+	if err != nil {
+		return nil, fmt.Errorf("error parsing nutanix failure domain storage containers: %w", err)
+	}
+
+	openStackNetworks, err := parseOpenStackFailureDomainNetworks(cpms.GetAnnotations(), providerConfig.Type())
+	if err != nil {
+		return nil, fmt.Errorf("error parsing openstack failure domain networks: %w", err)
+	}
+
+	userDataSecrets, err := parseFailureDomainUserDataSecrets(cpms.GetAnnotations())
+	if err != nil {
+		return nil, fmt.Errorf("error parsing failure domain user data secrets: %w", err)
+	}
+
+	azureSubnets, err := parseAzureFailureDomainSubnets(cpms.GetAnnotations(), providerConfig.Type())
+	if err != nil {
+		return nil, fmt.Errorf("error parsing azure failure domain subnets: %w", err)
+	}
+
+	awsPlacementGroups, err := parseAWSFailureDomainPlacementGroups(cpms.GetAnnotations(), providerConfig.Type())
+	if err != nil {
+		return nil, fmt.Errorf("error parsing aws failure domain placement groups: %w", err)
 	}
 
 	indexToFailureDomain, err := mapMachineIndexesToFailureDomains(ctx, logger, cl, cpms, failureDomains)
@@ -83,21 +158,132 @@ func NewMachineProvider(ctx context.Context, logger logr.Logger, cl client.Clien
 		return nil, fmt.Errorf("error mapping machine indexes: %w", err)
 	}
 
+	cordonedFailureDomains, err := parseCordonedFailureDomains(cpms.GetAnnotations(), failureDomains)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing cordoned failure domains: %w", err)
+	}
+
+	indexToFailureDomain = uncordonedFailureDomainMapping(indexToFailureDomain, failureDomains, cordonedFailureDomains)
+
+	requiredTags, err := requiredMachineTags(ctx, cl, providerConfig)
+	if err != nil {
+		return nil, fmt.Errorf("error determining required machine tags: %w", err)
+	}
+
+	recordedIndexes, err := recordedNodeIndexes(ctx, cl, cpms.GetNamespace())
+	if err != nil {
+		return nil, fmt.Errorf("error reading recorded machine indexes: %w", err)
+	}
+
+	resolvedAMI, err := resolvedTemplateAMI(ctx, cl, cpms.GetNamespace(), providerConfig)
+	if err != nil {
+		return nil, fmt.Errorf("error resolving template AMI: %w", err)
+	}
+
+	rebalanceFailureDomains, err := parseRebalanceFailureDomains(cpms.GetAnnotations())
+	if err != nil {
+		return nil, fmt.Errorf("error parsing failure domain rebalancing: %w", err)
+	}
+
+	ownedFields, err := providerconfig.ParseOwnedFields(cpms.GetAnnotations())
+	if err != nil {
+		return nil, fmt.Errorf("error parsing externally owned fields: %w", err)
+	}
+
+	// Fields owned by other controllers are removed from the template, so that they are neither part of the
+	// template hash nor set on new Machines.
+	providerConfig, err = ownedFields.Remove(providerConfig)
+	if err != nil {
+		return nil, fmt.Errorf("error removing externally owned fields from template: %w", err)
+	}
+
 	return &openshiftMachineProvider{
-		client:               cl,
-		indexToFailureDomain: indexToFailureDomain,
-		machineSelector:      cpms.Spec.Selector,
-		machineTemplate:      *cpms.Spec.Template.OpenShiftMachineV1Beta1Machine,
-		ownerMetadata:        cpms.ObjectMeta,
-		providerConfig:       providerConfig,
+		awsPlacementGroups:       awsPlacementGroups,
+		azureSubnets:             azureSubnets,
+		client:                   cl,
+		cordonedFailureDomains:   cordonedFailureDomains,
+		failureDomains:           failureDomains,
+		imageStream:              imageStream,
+		indexToFailureDomain:     indexToFailureDomain,
+		machineSelector:          cpms.Spec.Selector,
+		machineTemplate:          *cpms.Spec.Template.OpenShiftMachineV1Beta1Machine,
+		nutanixStorageContainers: nutanixStorageContainers,
+		openStackNetworks:        openStackNetworks,
+		ownedFields:              ownedFields,
+		ownerMetadata:            cpms.ObjectMeta,
+		providerConfig:           providerConfig,
+		rebalanceFailureDomains:  rebalanceFailureDomains,
+		recordedIndexes:          recordedIndexes,
+		requiredTags:             requiredTags,
+		resolvedAMI:              resolvedAMI,
+		userDataSecrets:          userDataSecrets,
 	}, nil
 }
 
+// templateFailureDomains creates the failure domains in which the Control Plane Machines of the ControlPlaneMachineSet
+// are placed. These are the failure domains of the template, as overridden by the annotations of the
+// ControlPlaneMachineSet and by the platform of the cluster.
+func templateFailureDomains(ctx context.Context, cl client.Reader, cpms *machinev1.ControlPlaneMachineSet, providerConfig providerconfig.ProviderConfig) ([]failuredomain.FailureDomain, error) {
+	failureDomains, err := newFailureDomains(ctx, cl, cpms.Spec.Template.OpenShiftMachineV1Beta1Machine.FailureDomains)
+	if err != nil {
+		return nil, fmt.Errorf("error constructing failure domain config: %w", err)
+	}
+
+	ibmCloudFailureDomains, err := parseIBMCloudFailureDomainZones(cpms.GetAnnotations(), providerConfig.Type())
+	if err != nil {
+		return nil, fmt.Errorf("error parsing ibmcloud failure domain zones: %w", err)
+	}
+
+	if ibmCloudFailureDomains != nil {
+		// The ControlPlaneMachineSet API has no IBM Cloud failure domains, so they are only configured by annotation.
+		failureDomains = ibmCloudFailureDomains
+	}
+
+	rootVolumeZones, err := parseOpenStackRootVolumeZones(cpms.GetAnnotations(), providerConfig.Type())
+	if err != nil {
+		return nil, fmt.Errorf("error parsing openstack root volume availability zones: %w", err)
+	}
+
+	failureDomains = withOpenStackRootVolumeZones(failureDomains, rootVolumeZones)
+
+	azureStackHub, err := isAzureStackHub(ctx, cl, providerConfig)
+	if err != nil {
+		return nil, fmt.Errorf("error determining whether the cluster is on Azure Stack Hub: %w", err)
+	}
+
+	if azureStackHub {
+		// Azure Stack Hub has no availability zones, so zone based failure domains do not apply.
+		failureDomains = nil
+	}
+
+	return failureDomains, nil
+}
+
 // openshiftMachineProvider holds the implementation of the MachineProvider interface.
 type openshiftMachineProvider struct {
+	// awsPlacementGroups are the placement groups, keyed by the availability zone of their failure domain, that
+	// override the placement group of the template within AWS failure domains.
+	awsPlacementGroups map[string]awsPlacementGroup
+
+	// azureSubnets are the subnets, keyed by the zone of their failure domain, that override the subnet of the
+	// template within Azure failure domains.
+	azureSubnets map[string]azureSubnet
+
 	// client is used to make API calls to fetch Machines and Nodes.
 	client client.Client
 
+	// cordonedFailureDomains are the failure domains excluded from new placements. No index is mapped to a cordoned
+	// failure domain, but the Machines already within them are left in place.
+	cordonedFailureDomains []failuredomain.FailureDomain
+
+	// failureDomains are the failure domains configured for the ControlPlaneMachineSet, including any that are not
+	// mapped to an index.
+	failureDomains []failuredomain.FailureDomain
+
+	// imageStream, when set, identifies the image stream from which the images for new
+	// Machines are resolved, in place of the image within the template.
+	imageStream *imageStreamReference
+
 	// indexToFailureDomain creates a mapping of failure domains to an arbitrary index.
 	// This index is then used in MachineInfo to allow external code to request that a
 	// new Machine be created in the same failure domain as an existing Machine.
@@ -111,12 +297,45 @@ type openshiftMachineProvider struct {
 	// machineTemplate is used to create new Machines.
 	machineTemplate machinev1.OpenShiftMachineV1Beta1MachineTemplate
 
+	// nutanixStorageContainers are the storage containers, keyed by the name of their failure domain, in which the
+	// data disks of the Machines within Nutanix failure domains are placed.
+	nutanixStorageContainers map[string]string
+
+	// openStackNetworks are the additional networks, keyed by the name of their failure domain, that the Machines
+	// within OpenStack failure domains are attached to.
+	openStackNetworks map[string][]string
+
+	// ownedFields are the fields of the provider spec claimed by other controllers. These are excluded when
+	// comparing Machines with the template, and are not set on new Machines.
+	ownedFields providerconfig.OwnedFields
+
 	// ownerMetadata is used to allow newly created Machines to have an owner
 	// reference set upon creation.
 	ownerMetadata metav1.ObjectMeta
 
 	// providerConfig stores the providerConfig for creating new Machines.
 	providerConfig providerconfig.ProviderConfig
+
+	// rebalanceFailureDomains determines whether Machines that unbalance the spread of the failure domains need an
+	// update, so that they are replaced into the failure domain of their index.
+	rebalanceFailureDomains bool
+
+	// recordedIndexes are the indexes recorded for the Control Plane Machines, keyed by the name of their Node.
+	// These allow Machines whose names do not follow the naming pattern, such as Machines renamed after a cluster
+	// restore, to be mapped back to their index.
+	recordedIndexes map[string]int32
+
+	// requiredTags are the tags, by name, that every Control Plane Machine is required to carry.
+	// New Machines are created with these tags, and existing Machines are checked for them.
+	requiredTags map[string]string
+
+	// resolvedAMI is the recorded ID of the AMI that the filters within the template resolve to, or empty when
+	// the template references the AMI by ID or no resolution has been recorded.
+	resolvedAMI string
+
+	// userDataSecrets are the names of the user data secrets, keyed by the name of their failure domain, that
+	// override the user data secret of the template within those failure domains.
+	userDataSecrets map[string]string
 }
 
 // GetMachineInfos inspects the current state of the Machines matched by the selector
@@ -128,15 +347,545 @@ type openshiftMachineProvider struct {
 // - Which failure domain index does the Machine represent?
 // - Is the Machine in an error state?
 func (m *openshiftMachineProvider) GetMachineInfos(ctx context.Context, logger logr.Logger) ([]machineproviders.MachineInfo, error) {
-	return nil, nil
+	machineInfos := []machineproviders.MachineInfo{}
+
+	selector, err := metav1.LabelSelectorAsSelector(&m.machineSelector)
+	if err != nil {
+		return nil, fmt.Errorf("could not convert label selector to selector: %w", err)
+	}
+
+	machineList := &machinev1beta1.MachineList{}
+	if err := m.client.List(ctx, machineList, client.InNamespace(m.ownerMetadata.Namespace), client.MatchingLabelsSelector{Selector: selector}); err != nil {
+		return nil, fmt.Errorf("failed to list machines: %w", err)
+	}
+
+	occupancy, err := m.failureDomainOccupancy(machineList.Items)
+	if err != nil {
+		return nil, fmt.Errorf("could not determine failure domain occupancy: %w", err)
+	}
+
+	for _, machine := range machineList.Items {
+		machineInfo, err := m.generateMachineInfo(machine, occupancy)
+		if err != nil {
+			logger.Error(err, couldNotGatherMachineInfo, "machineName", machine.GetName())
+
+			return nil, fmt.Errorf("could not gather machine info for machine %s: %w", machine.GetName(), err)
+		}
+
+		nodeName := ""
+		if machineInfo.NodeRef != nil {
+			nodeName = machineInfo.NodeRef.ObjectMeta.GetName()
+		}
+
+		logger.V(4).Info(gatheredMachineInfo,
+			"machineName", machine.GetName(),
+			"nodeName", nodeName,
+			"index", machineInfo.Index,
+			"ready", machineInfo.Ready,
+			"needsUpdate", machineInfo.NeedsUpdate,
+			"errorMessage", machineInfo.ErrorMessage,
+		)
+
+		machineInfos = append(machineInfos, machineInfo)
+	}
+
+	return machineInfos, nil
+}
+
+// generateMachineInfo creates the MachineInfo for the Machine.
+// It determines the index of the Machine and compares the Machine with the desired configuration
+// for the index to determine whether or not the Machine needs an update.
+// The occupancy of the failure domains is used to determine whether the Machine unbalances their spread. When
+// rebalancing is enabled, such a Machine needs an update into the failure domain of its index.
+func (m *openshiftMachineProvider) generateMachineInfo(machine machinev1beta1.Machine, occupancy map[string]int) (machineproviders.MachineInfo, error) {
+	machineProviderConfig, err := providerconfig.NewProviderConfigFromMachineSpec(machine.Spec)
+	if err != nil {
+		return machineproviders.MachineInfo{}, fmt.Errorf("could not get provider config for machine: %w", err)
+	}
+
+	index, err := m.getMachineIndex(machine, machineProviderConfig)
+	if err != nil {
+		return machineproviders.MachineInfo{}, err
+	}
+
+	templateProviderConfig, imageUnmanaged, err := m.templateProviderConfigFor(machineProviderConfig)
+	if err != nil {
+		return machineproviders.MachineInfo{}, err
+	}
+
+	// Required tags that the Machine carries, but the template does not list, do not require an update, so that
+	// missing tags can be repaired in place.
+	machineTags := machineProviderConfig.ExtractTags()
+
+	templateProviderConfig, err = templateProviderConfig.InjectTags(carriedTags(machineTags, m.requiredTags))
+	if err != nil {
+		return machineproviders.MachineInfo{}, fmt.Errorf("could not inject required tags: %w", err)
+	}
+
+	// Fields owned by other controllers are excluded when comparing the Machine with the template.
+	comparableMachineProviderConfig, err := m.ownedFields.Remove(machineProviderConfig)
+	if err != nil {
+		return machineproviders.MachineInfo{}, fmt.Errorf("could not remove externally owned fields: %w", err)
+	}
+
+	desiredProviderConfig, needsUpdate, err := m.desiredProviderConfig(templateProviderConfig, index, comparableMachineProviderConfig)
+	if err != nil {
+		return machineproviders.MachineInfo{}, fmt.Errorf("could not determine desired provider config: %w", err)
+	}
+
+	fd, err := m.machineFailureDomain(machineProviderConfig)
+	if err != nil {
+		return machineproviders.MachineInfo{}, fmt.Errorf("could not determine failure domain: %w", err)
+	}
+
+	misplaced, err := m.misplacedFailureDomain(index, machineProviderConfig, occupancy)
+	if err != nil {
+		return machineproviders.MachineInfo{}, fmt.Errorf("could not determine failure domain balance: %w", err)
+	}
+
+	outside, err := m.outsideFailureDomains(machineProviderConfig)
+	if err != nil {
+		return machineproviders.MachineInfo{}, fmt.Errorf("could not determine failure domain drift: %w", err)
+	}
+
+	if misplaced && m.rebalanceFailureDomains {
+		needsUpdate = true
+
+		desiredProviderConfig, err = m.injectFailureDomain(templateProviderConfig, m.indexToFailureDomain[index])
+		if err != nil {
+			return machineproviders.MachineInfo{}, fmt.Errorf("could not inject failure domain for index %d: %w", index, err)
+		}
+	}
+
+	comparableDesiredProviderConfig, err := m.ownedFields.Remove(desiredProviderConfig)
+	if err != nil {
+		return machineproviders.MachineInfo{}, fmt.Errorf("could not remove externally owned fields: %w", err)
+	}
+
+	unmanagedFields, err := comparableDesiredProviderConfig.UnmanagedFields(comparableMachineProviderConfig)
+	if err != nil {
+		return machineproviders.MachineInfo{}, fmt.Errorf("could not determine unmanaged fields: %w", err)
+	}
+
+	if imageUnmanaged {
+		unmanagedFields = append(unmanagedFields, imageStreamUnmanagedField)
+	}
+
+	desiredHash, err := m.desiredTemplateHash(index)
+	if err != nil {
+		return machineproviders.MachineInfo{}, fmt.Errorf("could not compute desired template hash: %w", err)
+	}
+
+	machineInfo := machineproviders.MachineInfo{
+		MachineRef: &machineproviders.ObjectRef{
+			GroupVersionResource: machinev1beta1.GroupVersion.WithResource("machines"),
+			ObjectMeta: metav1.ObjectMeta{
+				Annotations:       machine.GetAnnotations(),
+				CreationTimestamp: machine.GetCreationTimestamp(),
+				DeletionTimestamp: machine.GetDeletionTimestamp(),
+				Finalizers:        machine.GetFinalizers(),
+				Labels:            machine.GetLabels(),
+				Name:              machine.GetName(),
+				Namespace:         machine.GetNamespace(),
+				OwnerReferences:   machine.GetOwnerReferences(),
+			},
+		},
+		Ready:                  pointer.StringDeref(machine.Status.Phase, "") == machinePhaseRunning,
+		NeedsUpdate:            needsUpdate,
+		MisplacedFailureDomain: misplaced,
+		OutsideFailureDomains:  outside,
+		FailureDomain:          failureDomainString(fd),
+		InstanceMissing:        instanceMissing(machine, machineProviderConfig),
+		InstanceRunning:        instanceRunning(machine, machineProviderConfig),
+		MachineAPIPaused:       machineAPIPaused(machine),
+		MissingTags:            missingTags(machineTags, m.requiredTags),
+		Index:                  index,
+		ErrorMessage:           pointer.StringDeref(machine.Status.ErrorMessage, ""),
+		ErrorReason:            machineErrorReason(machine),
+		UnmanagedFields:        unmanagedFields,
+		NodeTopologyLabels:     nodeTopologyLabels(machineProviderConfig),
+		InstanceType:           machineProviderConfig.ExtractInstanceType(),
+		DesiredInstanceType:    desiredProviderConfig.ExtractInstanceType(),
+		ProviderID:             pointer.StringDeref(machine.Spec.ProviderID, ""),
+		TemplateHash:           machine.GetAnnotations()[templateHashAnnotation],
+		DesiredTemplateHash:    desiredHash,
+	}
+
+	if machine.Status.NodeRef != nil {
+		machineInfo.NodeRef = &machineproviders.ObjectRef{
+			GroupVersionResource: corev1.SchemeGroupVersion.WithResource("nodes"),
+			ObjectMeta: metav1.ObjectMeta{
+				Name: machine.Status.NodeRef.Name,
+			},
+		}
+	}
+
+	return machineInfo, nil
+}
+
+// machineErrorReason returns the reason for the error within the status of the Machine, or an empty string when the
+// Machine has not failed.
+func machineErrorReason(machine machinev1beta1.Machine) string {
+	if machine.Status.ErrorReason == nil {
+		return ""
+	}
+
+	return string(*machine.Status.ErrorReason)
+}
+
+// nodeTopologyLabels determines the topology labels that the Node backing a Machine with the given provider config
+// is expected to carry. The labels are based on where the Machine has been created, rather than where it should be.
+func nodeTopologyLabels(pc providerconfig.ProviderConfig) map[string]string {
+	switch pc.Type() {
+	case configv1.AWSPlatformType:
+		return awsNodeTopologyLabels(pc.AWS().Config().Placement)
+	default:
+		return nil
+	}
+}
+
+// awsNodeTopologyLabels determines the topology labels expected on Nodes based on the AWS placement.
+func awsNodeTopologyLabels(placement machinev1beta1.Placement) map[string]string {
+	labels := map[string]string{}
+
+	if placement.Region != "" {
+		labels[corev1.LabelTopologyRegion] = placement.Region
+	}
+
+	if placement.AvailabilityZone != "" {
+		labels[corev1.LabelTopologyZone] = placement.AvailabilityZone
+	}
+
+	if len(labels) == 0 {
+		return nil
+	}
+
+	return labels
+}
+
+// getMachineIndex determines the index of the Machine.
+// When the name of the Machine follows the Control Plane Machine naming pattern, the index is taken
+// from the suffix of the name. Otherwise, when an index has been recorded for the Node of the Machine,
+// the recorded index is used. Otherwise, the index is inferred by finding a failure domain within
+// the failure domain mapping that matches the failure domain of the Machine.
+func (m *openshiftMachineProvider) getMachineIndex(machine machinev1beta1.Machine, machineProviderConfig providerconfig.ProviderConfig) (int32, error) {
+	if index, ok := m.machineNameIndex(machine.GetName()); ok {
+		return index, nil
+	}
+
+	if index, ok := m.recordedNodeIndex(machine); ok {
+		return index, nil
+	}
+
+	for _, index := range m.sortedIndexes() {
+		matches, err := failureDomainMatches(machineProviderConfig, m.indexToFailureDomain[index])
+		if err != nil {
+			return 0, fmt.Errorf("could not compare failure domain: %w", err)
+		}
+
+		if matches {
+			return index, nil
+		}
+	}
+
+	return 0, errCouldNotDetermineMachineIndex
+}
+
+// machineNameIndex parses the index of the Machine from the Machine name.
+// It returns false when the name does not follow the Control Plane Machine naming pattern,
+// or when the index parsed is not an index within the failure domain mapping.
+func (m *openshiftMachineProvider) machineNameIndex(machineName string) (int32, bool) {
+	clusterID, ok := m.machineTemplate.ObjectMeta.Labels[machinev1beta1.MachineClusterIDLabel]
+	if !ok || !strings.HasPrefix(machineName, fmt.Sprintf("%s-%s-", clusterID, masterMachineNameInfix)) {
+		return 0, false
+	}
+
+	index, err := strconv.ParseInt(machineName[strings.LastIndex(machineName, "-")+1:], 10, 32)
+	if err != nil || index < 0 {
+		return 0, false
+	}
+
+	if _, ok := m.indexToFailureDomain[int32(index)]; len(m.indexToFailureDomain) > 0 && !ok {
+		return 0, false
+	}
+
+	return int32(index), true
+}
+
+// templateProviderConfigFor returns the template provider config that the Machine should be compared with.
+// When images are resolved from an image stream, the image within the template is not used to create
+// Machines, so the image of the Machine is carried over into the template provider config.
+// When the template selects the AMI by filters and the Machine carries the ID that the filters are recorded to
+// resolve to, the Machine is using the AMI of the template, so the ID is carried over into the template too.
+// The boolean returned determines whether the image of the Machine differs from the image within the template.
+func (m *openshiftMachineProvider) templateProviderConfigFor(machineProviderConfig providerconfig.ProviderConfig) (providerconfig.ProviderConfig, bool, error) {
+	machineImage := machineProviderConfig.ExtractImage()
+	if machineImage == "" || (m.imageStream == nil && machineImage != m.resolvedAMI) {
+		return m.providerConfig, false, nil
+	}
+
+	templateProviderConfig, err := m.providerConfig.InjectImage(machineImage)
+	if err != nil {
+		return nil, false, fmt.Errorf("could not inject machine image into template provider config: %w", err)
+	}
+
+	return templateProviderConfig, machineImage != m.providerConfig.ExtractImage(), nil
+}
+
+// desiredProviderConfig determines the provider config that the Machine in the given index should have.
+// This is the template provider config with the failure domain, and its storage container, networks and user data
+// secret, for the index injected.
+// A Machine that matches the template within any of the known failure domains does not need an update,
+// as the failure domain mapping is expected to follow the Machines rather than the other way around. Neither does a
+// Machine that matches the template within a cordoned failure domain.
+// The provider config of the Machine is expected to have had the fields owned by other controllers removed already.
+// The boolean returned determines whether the Machine needs an update.
+func (m *openshiftMachineProvider) desiredProviderConfig(templateProviderConfig providerconfig.ProviderConfig, index int32, machineProviderConfig providerconfig.ProviderConfig) (providerconfig.ProviderConfig, bool, error) {
+	desired, err := m.injectFailureDomain(templateProviderConfig, m.indexToFailureDomain[index])
+	if err != nil {
+		return nil, false, fmt.Errorf("could not inject failure domain for index %d: %w", index, err)
+	}
+
+	if equal, err := m.equalExcludingOwnedFields(desired, machineProviderConfig); err != nil {
+		return nil, false, err
+	} else if equal {
+		return desired, false, nil
+	}
+
+	for _, otherIndex := range m.sortedIndexes() {
+		if otherIndex == index {
+			continue
+		}
+
+		other, err := m.injectFailureDomain(templateProviderConfig, m.indexToFailureDomain[otherIndex])
+		if err != nil {
+			return nil, false, fmt.Errorf("could not inject failure domain for index %d: %w", otherIndex, err)
+		}
+
+		if equal, err := m.equalExcludingOwnedFields(other, machineProviderConfig); err != nil {
+			return nil, false, err
+		} else if equal {
+			return other, false, nil
+		}
+	}
+
+	if cordoned, ok, err := m.cordonedProviderConfig(templateProviderConfig, machineProviderConfig); err != nil {
+		return nil, false, err
+	} else if ok {
+		return cordoned, false, nil
+	}
+
+	return desired, true, nil
+}
+
+// equalExcludingOwnedFields compares the desired provider config with the provider config of a Machine, from which the
+// owned fields have already been removed.
+// The owned fields are removed from the desired provider config too, as injecting the failure domain, image or tags
+// into the template may set fields that are owned by other controllers.
+func (m *openshiftMachineProvider) equalExcludingOwnedFields(desired, machineProviderConfig providerconfig.ProviderConfig) (bool, error) {
+	desired, err := m.ownedFields.Remove(desired)
+	if err != nil {
+		return false, fmt.Errorf("could not remove externally owned fields: %w", err)
+	}
+
+	equal, err := desired.Equal(machineProviderConfig)
+	if err != nil {
+		return false, fmt.Errorf("could not compare provider configs: %w", err)
+	}
+
+	return equal, nil
+}
+
+// sortedIndexes returns the indexes of the failure domain mapping in ascending order.
+// This ensures that indexes are inferred consistently when multiple indexes could match a Machine.
+func (m *openshiftMachineProvider) sortedIndexes() []int32 {
+	indexes := []int32{}
+	for index := range m.indexToFailureDomain {
+		indexes = append(indexes, index)
+	}
+
+	sort.Slice(indexes, func(i, j int) bool {
+		return indexes[i] < indexes[j]
+	})
+
+	return indexes
+}
+
+// IndexFailureDomains returns the failure domain mapped to each index.
+// Indexes without a failure domain, for example when no failure domains are configured, are omitted.
+func (m *openshiftMachineProvider) IndexFailureDomains() map[int32]string {
+	out := map[int32]string{}
+
+	for index, fd := range m.indexToFailureDomain {
+		if fd != nil {
+			out[index] = fd.String()
+		}
+	}
+
+	return out
+}
+
+// failureDomainString returns the string representation of the failure domain, or an empty string when there is no
+// failure domain.
+func failureDomainString(fd failuredomain.FailureDomain) string {
+	if fd == nil {
+		return ""
+	}
+
+	return fd.String()
+}
+
+// failureDomainMatches determines whether the failure domain matches the failure domain of the provider config.
+// The failure domain matches when injecting it into the provider config does not change the provider config.
+func failureDomainMatches(pc providerconfig.ProviderConfig, fd failuredomain.FailureDomain) (bool, error) {
+	injected, err := pc.InjectFailureDomain(fd)
+	if err != nil {
+		return false, fmt.Errorf("could not inject failure domain: %w", err)
+	}
+
+	equal, err := injected.Equal(pc)
+	if err != nil {
+		return false, fmt.Errorf("could not compare provider configs: %w", err)
+	}
+
+	return equal, nil
 }
 
 // CreateMachine creates a new Machine from the template provider config based on the
 // failure domain index provided.
+// When an image stream is configured, the image for the Machine is resolved from the image stream
+// and the resolved image is recorded in an annotation on the Machine.
+// The hash of the template provider config for the index is recorded in an annotation on the Machine.
+// Fields owned by other controllers are omitted from the provider spec of the Machine.
 func (m *openshiftMachineProvider) CreateMachine(ctx context.Context, logger logr.Logger, index int32) error {
+	clusterID, ok := m.machineTemplate.ObjectMeta.Labels[machinev1beta1.MachineClusterIDLabel]
+	if !ok {
+		return errMissingClusterIDLabel
+	}
+
+	failureDomain := m.indexToFailureDomain[index]
+
+	providerConfig, err := m.injectFailureDomain(m.providerConfig, failureDomain)
+	if err != nil {
+		return fmt.Errorf("could not inject failure domain into provider config: %w", err)
+	}
+
+	hash, err := templateHash(providerConfig)
+	if err != nil {
+		return fmt.Errorf("could not compute template hash: %w", err)
+	}
+
+	annotations := copyStringMap(m.machineTemplate.ObjectMeta.Annotations)
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+
+	annotations[templateHashAnnotation] = hash
+
+	// The required tags are injected after the template hash is computed, as they are not part of the template.
+	providerConfig, err = providerConfig.InjectTags(m.requiredTags)
+	if err != nil {
+		return fmt.Errorf("could not inject required tags into provider config: %w", err)
+	}
+
+	if m.imageStream != nil {
+		image, err := m.resolveImage(ctx, providerConfig)
+		if err != nil {
+			return fmt.Errorf("could not resolve image from image stream %s: %w", m.imageStream, err)
+		}
+
+		providerConfig, err = providerConfig.InjectImage(image.Image)
+		if err != nil {
+			return fmt.Errorf("could not inject image into provider config: %w", err)
+		}
+
+		annotations[resolvedImageAnnotation] = image.Image
+
+		logger.V(2).Info(resolvedImageFromStream,
+			"index", index,
+			"imageStream", m.imageStream.String(),
+			"image", image.Image,
+			"release", image.Release,
+		)
+	}
+
+	rawConfig, err := providerConfig.RawConfig()
+	if err != nil {
+		return fmt.Errorf("could not get raw provider config: %w", err)
+	}
+
+	// Injecting the failure domain, tags or image may have set fields owned by other controllers, which are left
+	// for those controllers to set.
+	rawConfig, err = m.ownedFields.RemoveFromRaw(rawConfig)
+	if err != nil {
+		return fmt.Errorf("could not remove externally owned fields from provider config: %w", err)
+	}
+
+	machine := &machinev1beta1.Machine{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        fmt.Sprintf("%s-%s-%s-%d", clusterID, masterMachineNameInfix, randomMachineNameSuffix(), index),
+			Namespace:   m.ownerMetadata.Namespace,
+			Labels:      copyStringMap(m.machineTemplate.ObjectMeta.Labels),
+			Annotations: annotations,
+			OwnerReferences: []metav1.OwnerReference{
+				{
+					APIVersion:         machinev1.GroupVersion.String(),
+					Kind:               "ControlPlaneMachineSet",
+					Name:               m.ownerMetadata.GetName(),
+					UID:                m.ownerMetadata.GetUID(),
+					Controller:         pointer.Bool(true),
+					BlockOwnerDeletion: pointer.Bool(true),
+				},
+			},
+		},
+		Spec: *m.machineTemplate.Spec.DeepCopy(),
+	}
+
+	machine.Spec.ProviderSpec.Value = &runtime.RawExtension{Raw: rawConfig}
+
+	if err := m.client.Create(ctx, machine); err != nil {
+		return fmt.Errorf("failed to create machine: %w", err)
+	}
+
+	failureDomainName := ""
+	if failureDomain != nil {
+		failureDomainName = failureDomain.String()
+	}
+
+	logger.V(2).Info(createdMachine,
+		"index", index,
+		"machineName", machine.GetName(),
+		"failureDomain", failureDomainName,
+	)
+
 	return nil
 }
 
+// randomMachineNameSuffix generates a random suffix for the name of a new Machine.
+func randomMachineNameSuffix() string {
+	suffix := make([]byte, machineNameSuffixLength)
+	for i := range suffix {
+		suffix[i] = machineNameSuffixChars[rand.Intn(len(machineNameSuffixChars))]
+	}
+
+	return string(suffix)
+}
+
+// copyStringMap returns a copy of the map so that the Machine template is not mutated
+// when the metadata of new Machines is modified.
+func copyStringMap(in map[string]string) map[string]string {
+	if in == nil {
+		return nil
+	}
+
+	out := make(map[string]string, len(in))
+	for k, v := range in {
+		out[k] = v
+	}
+
+	return out
+}
+
 // DeleteMachine deletes the Machine references in the machineRef provided.
 func (m *openshiftMachineProvider) DeleteMachine(ctx context.Context, logger logr.Logger, machineRef *machineproviders.ObjectRef) error {
 	return nil
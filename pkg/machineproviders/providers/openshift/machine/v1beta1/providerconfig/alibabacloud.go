@@ -0,0 +1,122 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package providerconfig
+
+import (
+	"fmt"
+
+	configv1 "github.com/openshift/api/config/v1"
+	machinev1 "github.com/openshift/api/machine/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// AlibabaCloudProviderConfig holds the provider spec of an Alibaba Cloud Machine.
+// It allows external code to extract and inject the zone of the Machine.
+// The ControlPlaneMachineSet API has no Alibaba Cloud failure domains, so the zone is not
+// extracted or injected as a failure domain.
+type AlibabaCloudProviderConfig struct {
+	providerConfig machinev1.AlibabaCloudMachineProviderConfig
+}
+
+// Config returns the stored AlibabaCloudMachineProviderConfig.
+func (a AlibabaCloudProviderConfig) Config() machinev1.AlibabaCloudMachineProviderConfig {
+	return a.providerConfig
+}
+
+// InjectZone returns a new AlibabaCloudProviderConfig configured to create the instance
+// within the given zone.
+func (a AlibabaCloudProviderConfig) InjectZone(zone string) AlibabaCloudProviderConfig {
+	newAlibabaCloudProviderConfig := a
+
+	newAlibabaCloudProviderConfig.providerConfig.ZoneID = zone
+
+	return newAlibabaCloudProviderConfig
+}
+
+// ExtractZone returns the ID of the zone in which the instance is created.
+func (a AlibabaCloudProviderConfig) ExtractZone() string {
+	return a.providerConfig.ZoneID
+}
+
+// ExtractInstanceType returns the instance type of the instance.
+func (a AlibabaCloudProviderConfig) ExtractInstanceType() string {
+	return a.providerConfig.InstanceType
+}
+
+// Equal compares the AlibabaCloudProviderConfig with another AlibabaCloudProviderConfig.
+// The type information of the provider specs is normalised before the comparison so that a
+// provider spec that omits it compares as equal to one that sets it.
+func (a AlibabaCloudProviderConfig) Equal(other AlibabaCloudProviderConfig) bool {
+	return equality.Semantic.DeepEqual(normalisedAlibabaCloudProviderConfig(a.providerConfig), normalisedAlibabaCloudProviderConfig(other.providerConfig))
+}
+
+// UnmanagedFields returns the paths of the fields that differ between the AlibabaCloudProviderConfigs
+// but where the difference is deliberately tolerated by Equal.
+// These are the type information of the provider specs.
+func (a AlibabaCloudProviderConfig) UnmanagedFields(other AlibabaCloudProviderConfig) []string {
+	var fields []string
+
+	if a.providerConfig.APIVersion != other.providerConfig.APIVersion {
+		fields = append(fields, "apiVersion")
+	}
+
+	if a.providerConfig.Kind != other.providerConfig.Kind {
+		fields = append(fields, "kind")
+	}
+
+	return fields
+}
+
+// ChangedFields returns the names of the top level fields of the provider spec that differ
+// between the AlibabaCloudProviderConfigs.
+// The provider specs are normalised in the same way as within Equal, so differences that Equal
+// tolerates are not reported.
+func (a AlibabaCloudProviderConfig) ChangedFields(other AlibabaCloudProviderConfig) ([]string, error) {
+	return changedTopLevelFields(normalisedAlibabaCloudProviderConfig(a.providerConfig), normalisedAlibabaCloudProviderConfig(other.providerConfig))
+}
+
+// normalisedAlibabaCloudProviderConfig returns a copy of the provider spec that is suitable for comparison.
+// The type information is normalised to the only API version that serves the Alibaba Cloud provider spec.
+func normalisedAlibabaCloudProviderConfig(cfg machinev1.AlibabaCloudMachineProviderConfig) *machinev1.AlibabaCloudMachineProviderConfig {
+	out := cfg.DeepCopy()
+	out.TypeMeta = metav1.TypeMeta{
+		APIVersion: machineV1APIVersion,
+		Kind:       alibabaCloudProviderConfigKind,
+	}
+
+	return out
+}
+
+// newAlibabaCloudProviderConfig creates an AlibabaCloudProviderConfig from the raw extension.
+// It should return an error if the provided RawExtension does not represent
+// an AlibabaCloudMachineProviderConfig.
+func newAlibabaCloudProviderConfig(raw *runtime.RawExtension) (ProviderConfig, error) {
+	alibabaCloudMachineProviderConfig := machinev1.AlibabaCloudMachineProviderConfig{}
+	if err := decodeProviderSpec(raw, alibabaCloudProviderConfigKind, &alibabaCloudMachineProviderConfig); err != nil {
+		return nil, fmt.Errorf("could not decode Alibaba Cloud provider spec: %w", err)
+	}
+
+	return providerConfig{
+		platformType: configv1.AlibabaCloudPlatformType,
+		raw:          raw.Raw,
+		alibabaCloud: AlibabaCloudProviderConfig{
+			providerConfig: alibabaCloudMachineProviderConfig,
+		},
+	}, nil
+}
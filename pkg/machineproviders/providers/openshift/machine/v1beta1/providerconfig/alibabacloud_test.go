@@ -0,0 +1,169 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package providerconfig
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	configv1 "github.com/openshift/api/config/v1"
+	machinev1 "github.com/openshift/api/machine/v1"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/test/resourcebuilder"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+var _ = Describe("Alibaba Cloud Provider Config", func() {
+	var providerConfig AlibabaCloudProviderConfig
+
+	BeforeEach(func() {
+		providerConfig = AlibabaCloudProviderConfig{
+			providerConfig: *resourcebuilder.AlibabaCloudProviderSpec().WithZone("cn-hangzhou-h").Build(),
+		}
+	})
+
+	Context("ExtractZone", func() {
+		It("returns the configured zone", func() {
+			Expect(providerConfig.ExtractZone()).To(Equal("cn-hangzhou-h"))
+		})
+	})
+
+	Context("InjectZone", func() {
+		It("returns a copy with the new zone", func() {
+			newConfig := providerConfig.InjectZone("cn-hangzhou-i")
+
+			Expect(newConfig.ExtractZone()).To(Equal("cn-hangzhou-i"))
+			Expect(newConfig.Config()).To(Equal(*resourcebuilder.AlibabaCloudProviderSpec().WithZone("cn-hangzhou-i").Build()))
+		})
+
+		It("does not modify the original provider config", func() {
+			providerConfig.InjectZone("cn-hangzhou-i")
+
+			Expect(providerConfig.ExtractZone()).To(Equal("cn-hangzhou-h"))
+		})
+	})
+
+	Context("Equal", func() {
+		type alibabaCloudEqualTableInput struct {
+			baseConfig    machinev1.AlibabaCloudMachineProviderConfig
+			compareConfig machinev1.AlibabaCloudMachineProviderConfig
+			expectedEqual bool
+
+			expectedUnmanagedFields []string
+			expectedChangedFields   []string
+		}
+
+		withoutTypeMeta := func(cfg *machinev1.AlibabaCloudMachineProviderConfig) machinev1.AlibabaCloudMachineProviderConfig {
+			cfg.APIVersion = ""
+			cfg.Kind = ""
+
+			return *cfg
+		}
+
+		DescribeTable("should compare the provider configs", func(in alibabaCloudEqualTableInput) {
+			baseConfig := AlibabaCloudProviderConfig{providerConfig: in.baseConfig}
+			compareConfig := AlibabaCloudProviderConfig{providerConfig: in.compareConfig}
+
+			Expect(baseConfig.Equal(compareConfig)).To(Equal(in.expectedEqual))
+			Expect(compareConfig.Equal(baseConfig)).To(Equal(in.expectedEqual), "Equality should be symmetric")
+
+			Expect(baseConfig.UnmanagedFields(compareConfig)).To(ConsistOf(in.expectedUnmanagedFields))
+			Expect(compareConfig.UnmanagedFields(baseConfig)).To(ConsistOf(in.expectedUnmanagedFields), "Unmanaged fields should be symmetric")
+
+			Expect(baseConfig.ChangedFields(compareConfig)).To(ConsistOf(in.expectedChangedFields))
+		},
+			Entry("with matching configs", alibabaCloudEqualTableInput{
+				baseConfig:              *resourcebuilder.AlibabaCloudProviderSpec().Build(),
+				compareConfig:           *resourcebuilder.AlibabaCloudProviderSpec().Build(),
+				expectedEqual:           true,
+				expectedUnmanagedFields: []string{},
+				expectedChangedFields:   []string{},
+			}),
+			Entry("with matching configs where one omits the type information", alibabaCloudEqualTableInput{
+				baseConfig:              withoutTypeMeta(resourcebuilder.AlibabaCloudProviderSpec().Build()),
+				compareConfig:           *resourcebuilder.AlibabaCloudProviderSpec().Build(),
+				expectedEqual:           true,
+				expectedUnmanagedFields: []string{"apiVersion", "kind"},
+				expectedChangedFields:   []string{},
+			}),
+			Entry("with a different instance type", alibabaCloudEqualTableInput{
+				baseConfig:              *resourcebuilder.AlibabaCloudProviderSpec().Build(),
+				compareConfig:           *resourcebuilder.AlibabaCloudProviderSpec().WithInstanceType("ecs.g6.2xlarge").Build(),
+				expectedEqual:           false,
+				expectedUnmanagedFields: []string{},
+				expectedChangedFields:   []string{"instanceType"},
+			}),
+			Entry("with a different zone and vSwitch", alibabaCloudEqualTableInput{
+				baseConfig:              *resourcebuilder.AlibabaCloudProviderSpec().Build(),
+				compareConfig:           *resourcebuilder.AlibabaCloudProviderSpec().WithZone("cn-hangzhou-i").WithVSwitchID("vsw-cn-hangzhou-i").Build(),
+				expectedEqual:           false,
+				expectedUnmanagedFields: []string{},
+				expectedChangedFields:   []string{"vSwitch", "zoneId"},
+			}),
+		)
+	})
+
+	Context("newAlibabaCloudProviderConfig", func() {
+		var providerConfig ProviderConfig
+		var expectedAlibabaCloudConfig machinev1.AlibabaCloudMachineProviderConfig
+
+		BeforeEach(func() {
+			configBuilder := resourcebuilder.AlibabaCloudProviderSpec()
+			expectedAlibabaCloudConfig = *configBuilder.Build()
+			rawConfig := configBuilder.BuildRawExtension()
+
+			var err error
+			providerConfig, err = newAlibabaCloudProviderConfig(rawConfig)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("sets the type to AlibabaCloud", func() {
+			Expect(providerConfig.Type()).To(Equal(configv1.AlibabaCloudPlatformType))
+		})
+
+		It("returns the correct Alibaba Cloud config", func() {
+			Expect(providerConfig.AlibabaCloud().Config()).To(Equal(expectedAlibabaCloudConfig))
+		})
+
+		It("extracts the instance type", func() {
+			Expect(providerConfig.ExtractInstanceType()).To(Equal("ecs.g6.xlarge"))
+		})
+
+		It("does not extract a failure domain", func() {
+			Expect(providerConfig.ExtractFailureDomain()).To(BeNil())
+		})
+
+		It("round trips through the raw config", func() {
+			rawConfig, err := providerConfig.RawConfig()
+			Expect(err).ToNot(HaveOccurred())
+
+			roundTripped, err := newAlibabaCloudProviderConfig(&runtime.RawExtension{Raw: rawConfig})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(roundTripped.Equal(providerConfig)).To(BeTrue())
+		})
+
+		Context("with a provider spec in the Machine API version", func() {
+			It("returns an error", func() {
+				_, err := newAlibabaCloudProviderConfig(&runtime.RawExtension{
+					Raw: []byte(`{"apiVersion":"machine.openshift.io/v1beta1","kind":"AlibabaCloudMachineProviderConfig"}`),
+				})
+
+				Expect(err).To(MatchError("could not decode Alibaba Cloud provider spec: unknown provider spec API version: machine.openshift.io/v1beta1 does not serve AlibabaCloudMachineProviderConfig"))
+			})
+		})
+	})
+})
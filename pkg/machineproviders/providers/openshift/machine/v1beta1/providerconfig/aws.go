@@ -17,9 +17,25 @@ limitations under the License.
 package providerconfig
 
 import (
+	"fmt"
+	"sort"
+
+	configv1 "github.com/openshift/api/config/v1"
 	machinev1 "github.com/openshift/api/machine/v1"
 	machinev1beta1 "github.com/openshift/api/machine/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/utils/pointer"
+)
+
+const (
+	// awsDefaultVolumeType is the EBS volume type used by AWS when no volume type is specified.
+	awsDefaultVolumeType = "standard"
+
+	// awsRootDeviceName is the name used to identify the root volume when matching block devices.
+	// The root volume is the only block device that does not set a device name.
+	awsRootDeviceName = "root"
 )
 
 // AWSProviderConfig holds the provider spec of an AWS Machine.
@@ -27,18 +43,160 @@ import (
 // as well as gathering the stored config.
 type AWSProviderConfig struct {
 	providerConfig machinev1beta1.AWSMachineProviderConfig
+
+	// instanceRequirements are the attributes used to select the instance type, when the
+	// instance type is not fixed.
+	instanceRequirements *AWSInstanceRequirements
 }
 
 // InjectFailureDomain returns a new AWSProviderConfig configured with the failure domain
 // information provided.
+// Fields that are not set within the failure domain are left unchanged.
 func (a AWSProviderConfig) InjectFailureDomain(fd machinev1.AWSFailureDomain) AWSProviderConfig {
-	return a
+	newAWSProviderConfig := AWSProviderConfig{
+		providerConfig:       *a.providerConfig.DeepCopy(),
+		instanceRequirements: a.instanceRequirements.DeepCopy(),
+	}
+
+	if fd.Placement.AvailabilityZone != "" {
+		newAWSProviderConfig.providerConfig.Placement.AvailabilityZone = fd.Placement.AvailabilityZone
+	}
+
+	if fd.Subnet != nil {
+		newAWSProviderConfig.providerConfig.Subnet = convertAWSResourceReferenceV1ToV1Beta1(*fd.Subnet)
+	}
+
+	return newAWSProviderConfig
 }
 
 // ExtractFailureDomain returns an AWSFailureDomain based on the failure domain
 // information stored within the AWSProviderConfig.
 func (a AWSProviderConfig) ExtractFailureDomain() machinev1.AWSFailureDomain {
-	return machinev1.AWSFailureDomain{}
+	return machinev1.AWSFailureDomain{
+		Placement: machinev1.AWSFailureDomainPlacement{
+			AvailabilityZone: a.providerConfig.Placement.AvailabilityZone,
+		},
+		Subnet: convertAWSResourceReferenceV1Beta1ToV1(a.providerConfig.Subnet),
+	}
+}
+
+// InjectPlacementGroup returns a new AWSProviderConfig configured to place the instance within the
+// named placement group, within the partition given.
+// When no partition is given, the partition is left unchanged.
+func (a AWSProviderConfig) InjectPlacementGroup(name string, partitionNumber int32) AWSProviderConfig {
+	newAWSProviderConfig := AWSProviderConfig{
+		providerConfig:       *a.providerConfig.DeepCopy(),
+		instanceRequirements: a.instanceRequirements.DeepCopy(),
+	}
+
+	newAWSProviderConfig.providerConfig.Placement.Group = machinev1beta1.LocalAWSPlacementGroupReference{Name: name}
+
+	if partitionNumber != 0 {
+		newAWSProviderConfig.providerConfig.Placement.PartitionNumber = partitionNumber
+	}
+
+	return newAWSProviderConfig
+}
+
+// InjectAMI returns a new AWSProviderConfig configured to use the AMI with the ID provided.
+// Any existing reference to an AMI, by ARN or filters, is replaced.
+func (a AWSProviderConfig) InjectAMI(id string) AWSProviderConfig {
+	newAWSProviderConfig := AWSProviderConfig{
+		providerConfig:       *a.providerConfig.DeepCopy(),
+		instanceRequirements: a.instanceRequirements.DeepCopy(),
+	}
+
+	newAWSProviderConfig.providerConfig.AMI = machinev1beta1.AWSResourceReference{
+		ID: &id,
+	}
+
+	return newAWSProviderConfig
+}
+
+// ExtractAMI returns the ID of the AMI used by the AWSProviderConfig.
+// When the AMI is not referenced by ID, an empty string is returned.
+func (a AWSProviderConfig) ExtractAMI() string {
+	return pointer.StringDeref(a.providerConfig.AMI.ID, "")
+}
+
+// ExtractAMIFilters returns the filters used to select the AMI of the AWSProviderConfig.
+// When the AMI is referenced by ID, no filters are returned, as the Machine API uses the ID in
+// preference to any filters.
+func (a AWSProviderConfig) ExtractAMIFilters() []machinev1beta1.Filter {
+	if a.providerConfig.AMI.ID != nil {
+		return nil
+	}
+
+	return a.providerConfig.AMI.Filters
+}
+
+// ExtractInstanceType returns the instance type used by the AWSProviderConfig.
+// When the instance type is selected by attribute, an empty string is returned.
+func (a AWSProviderConfig) ExtractInstanceType() string {
+	return a.providerConfig.InstanceType
+}
+
+// InjectTags returns a new AWSProviderConfig with the tags provided added to its tags.
+// Tags whose names are already present are left unchanged, even when their values differ.
+// The new tags are appended in name order.
+func (a AWSProviderConfig) InjectTags(tags map[string]string) AWSProviderConfig {
+	newAWSProviderConfig := AWSProviderConfig{
+		providerConfig:       *a.providerConfig.DeepCopy(),
+		instanceRequirements: a.instanceRequirements.DeepCopy(),
+	}
+
+	existing := a.ExtractTags()
+
+	names := []string{}
+	for name := range tags {
+		if _, ok := existing[name]; !ok {
+			names = append(names, name)
+		}
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		newAWSProviderConfig.providerConfig.Tags = append(newAWSProviderConfig.providerConfig.Tags, machinev1beta1.TagSpecification{
+			Name:  name,
+			Value: tags[name],
+		})
+	}
+
+	return newAWSProviderConfig
+}
+
+// ExtractTags returns the tags applied to the resources of the AWSProviderConfig, by name.
+func (a AWSProviderConfig) ExtractTags() map[string]string {
+	tags := map[string]string{}
+
+	for _, tag := range a.providerConfig.Tags {
+		tags[tag.Name] = tag.Value
+	}
+
+	return tags
+}
+
+// InjectUserDataSecret returns a new AWSProviderConfig configured to read the user data of the
+// instance from the named secret.
+func (a AWSProviderConfig) InjectUserDataSecret(name string) AWSProviderConfig {
+	newAWSProviderConfig := AWSProviderConfig{
+		providerConfig:       *a.providerConfig.DeepCopy(),
+		instanceRequirements: a.instanceRequirements.DeepCopy(),
+	}
+
+	newAWSProviderConfig.providerConfig.UserDataSecret = &corev1.LocalObjectReference{Name: name}
+
+	return newAWSProviderConfig
+}
+
+// ExtractUserDataSecret returns the name of the secret holding the user data of the instance.
+func (a AWSProviderConfig) ExtractUserDataSecret() string {
+	if a.providerConfig.UserDataSecret == nil {
+		return ""
+	}
+
+	return a.providerConfig.UserDataSecret.Name
 }
 
 // Config returns the stored AWSMachineProviderConfig.
@@ -46,11 +204,402 @@ func (a AWSProviderConfig) Config() machinev1beta1.AWSMachineProviderConfig {
 	return a.providerConfig
 }
 
+// InstanceRequirements returns the attributes used to select the instance type.
+// When the instance type is fixed, nil is returned.
+func (a AWSProviderConfig) InstanceRequirements() *AWSInstanceRequirements {
+	return a.instanceRequirements.DeepCopy()
+}
+
+// Equal compares the AWSProviderConfig with another AWSProviderConfig.
+// The type information of the provider specs is normalised, the AWS defaults are applied, and
+// the block devices are ordered by device name, the tags by name and the subnet filters by name
+// and value, before the comparison so that provider specs using different API versions of the
+// same kind, that omit a field that the other sets to its default value, or that list the same
+// block devices, tags or subnet filters in a different order, compare as equal.
+// The encryption of each EBS volume is compared by whether it is encrypted and by the KMS key that
+// the Machine API passes to EC2, so that the same key referenced by ID or by ARN compares as equal.
+// The instance requirements are compared too, ignoring the order of their lists.
+func (a AWSProviderConfig) Equal(other AWSProviderConfig) bool {
+	return equality.Semantic.DeepEqual(a.normalisedProviderSpec(), other.normalisedProviderSpec())
+}
+
+// UnmanagedFields returns the paths of the fields that differ between the AWSProviderConfigs
+// but where the difference is deliberately tolerated by Equal.
+// These are the type information of the provider specs and any field where one of the provider
+// specs omits the field and the other sets it to the AWS default value.
+// Block devices are matched by device name, the index within the paths is the index of the
+// block device within this AWSProviderConfig.
+func (a AWSProviderConfig) UnmanagedFields(other AWSProviderConfig) []string {
+	base := a.providerConfig
+	compare := other.providerConfig
+
+	defaultedBase := base.DeepCopy()
+	setAWSDefaults(defaultedBase)
+
+	defaultedCompare := compare.DeepCopy()
+	setAWSDefaults(defaultedCompare)
+
+	var fields []string
+
+	if base.APIVersion != compare.APIVersion {
+		fields = append(fields, "apiVersion")
+	}
+
+	if base.Kind != compare.Kind {
+		fields = append(fields, "kind")
+	}
+
+	if base.NetworkInterfaceType != compare.NetworkInterfaceType && defaultedBase.NetworkInterfaceType == defaultedCompare.NetworkInterfaceType {
+		fields = append(fields, "networkInterfaceType")
+	}
+
+	if base.Placement.Tenancy != compare.Placement.Tenancy && defaultedBase.Placement.Tenancy == defaultedCompare.Placement.Tenancy {
+		fields = append(fields, "placement.tenancy")
+	}
+
+	for i := range base.BlockDevices {
+		j, ok := findAWSBlockDevice(compare.BlockDevices, awsBlockDeviceName(base.BlockDevices[i]))
+		if !ok {
+			continue
+		}
+
+		baseEBS, compareEBS := base.BlockDevices[i].EBS, compare.BlockDevices[j].EBS
+		if baseEBS == nil || compareEBS == nil {
+			continue
+		}
+
+		if !pointer.StringEqual(baseEBS.VolumeType, compareEBS.VolumeType) &&
+			pointer.StringEqual(defaultedBase.BlockDevices[i].EBS.VolumeType, defaultedCompare.BlockDevices[j].EBS.VolumeType) {
+			fields = append(fields, fmt.Sprintf("blockDevices[%d].ebs.volumeType", i))
+		}
+
+		if !equality.Semantic.DeepEqual(baseEBS.KMSKey, compareEBS.KMSKey) && awsKMSKeyID(baseEBS.KMSKey) == awsKMSKeyID(compareEBS.KMSKey) {
+			fields = append(fields, fmt.Sprintf("blockDevices[%d].ebs.kmsKey", i))
+		}
+	}
+
+	return fields
+}
+
+// ChangedFields returns the names of the top level fields of the provider spec that differ
+// between the AWSProviderConfigs.
+// The provider specs are normalised in the same way as within Equal, so differences that Equal
+// tolerates are not reported.
+func (a AWSProviderConfig) ChangedFields(other AWSProviderConfig) ([]string, error) {
+	return changedTopLevelFields(a.normalisedProviderSpec(), other.normalisedProviderSpec())
+}
+
+// AWSBlockDeviceSizeDecrease describes a block device whose volume size is smaller within
+// a desired AWSProviderConfig than within the current AWSProviderConfig.
+type AWSBlockDeviceSizeDecrease struct {
+	// Index is the index of the block device within the desired AWSProviderConfig.
+	Index int
+
+	// DeviceName is the device name of the block device, or root for the root volume.
+	DeviceName string
+
+	// CurrentSize is the volume size, in GiB, within the current AWSProviderConfig.
+	CurrentSize int64
+
+	// DesiredSize is the volume size, in GiB, within the desired AWSProviderConfig.
+	DesiredSize int64
+}
+
+// BlockDeviceSizeDecreases compares the block devices of the AWSProviderConfig with those of the
+// desired AWSProviderConfig, matching them by device name, and returns any block device whose
+// volume size would be decreased.
+// Volume size increases are valid changes and are not reported.
+func (a AWSProviderConfig) BlockDeviceSizeDecreases(desired AWSProviderConfig) []AWSBlockDeviceSizeDecrease {
+	var decreases []AWSBlockDeviceSizeDecrease
+
+	for i, desiredDevice := range desired.providerConfig.BlockDevices {
+		deviceName := awsBlockDeviceName(desiredDevice)
+
+		j, ok := findAWSBlockDevice(a.providerConfig.BlockDevices, deviceName)
+		if !ok {
+			continue
+		}
+
+		currentEBS, desiredEBS := a.providerConfig.BlockDevices[j].EBS, desiredDevice.EBS
+		if currentEBS == nil || desiredEBS == nil || currentEBS.VolumeSize == nil || desiredEBS.VolumeSize == nil {
+			continue
+		}
+
+		if *desiredEBS.VolumeSize < *currentEBS.VolumeSize {
+			decreases = append(decreases, AWSBlockDeviceSizeDecrease{
+				Index:       i,
+				DeviceName:  deviceName,
+				CurrentSize: *currentEBS.VolumeSize,
+				DesiredSize: *desiredEBS.VolumeSize,
+			})
+		}
+	}
+
+	return decreases
+}
+
+// AWSBlockDeviceEncryption describes the encryption of the EBS volume of a block device.
+type AWSBlockDeviceEncryption struct {
+	// Index is the index of the block device within the AWSProviderConfig.
+	Index int
+
+	// DeviceName is the device name of the block device, or root for the root volume.
+	DeviceName string
+
+	// Encrypted is whether the volume is encrypted, or nil when the account default applies.
+	Encrypted *bool
+
+	// KMSKeyID is the ID, alias or ARN of the KMS key used to encrypt the volume, as passed to EC2.
+	// It is empty when the volume uses the default key.
+	KMSKeyID string
+
+	// KMSKeyFilters is true when the KMS key is referenced only by filters. The Machine API does not
+	// resolve filters for KMS keys, so such volumes use the default key.
+	KMSKeyFilters bool
+}
+
+// BlockDeviceEncryption returns the encryption of the EBS volume of each block device of the
+// AWSProviderConfig. Block devices without an EBS volume are omitted.
+func (a AWSProviderConfig) BlockDeviceEncryption() []AWSBlockDeviceEncryption {
+	var encryption []AWSBlockDeviceEncryption
+
+	for i, device := range a.providerConfig.BlockDevices {
+		if device.EBS == nil {
+			continue
+		}
+
+		kmsKeyID := awsKMSKeyID(device.EBS.KMSKey)
+
+		encryption = append(encryption, AWSBlockDeviceEncryption{
+			Index:         i,
+			DeviceName:    awsBlockDeviceName(device),
+			Encrypted:     device.EBS.Encrypted,
+			KMSKeyID:      kmsKeyID,
+			KMSKeyFilters: kmsKeyID == "" && len(device.EBS.KMSKey.Filters) > 0,
+		})
+	}
+
+	return encryption
+}
+
+// normalisedAWSProviderConfig returns a copy of the provider spec that is suitable for comparison.
+// The type information is normalised, the AWS defaults are applied, the block devices are
+// ordered by device name, the tags are ordered by name and the subnet filters are ordered by
+// name and value. The KMS key of each EBS volume is reduced to the key that the Machine API
+// passes to EC2.
+func normalisedAWSProviderConfig(cfg machinev1beta1.AWSMachineProviderConfig) *machinev1beta1.AWSMachineProviderConfig {
+	out := cfg.DeepCopy()
+	out.TypeMeta = normalisedTypeMeta(awsProviderConfigKind)
+	setAWSDefaults(out)
+
+	sort.SliceStable(out.BlockDevices, func(i, j int) bool {
+		return awsBlockDeviceName(out.BlockDevices[i]) < awsBlockDeviceName(out.BlockDevices[j])
+	})
+
+	for i := range out.BlockDevices {
+		if ebs := out.BlockDevices[i].EBS; ebs != nil {
+			ebs.KMSKey = normalisedAWSKMSKey(ebs.KMSKey)
+		}
+	}
+
+	sort.SliceStable(out.Tags, func(i, j int) bool {
+		return out.Tags[i].Name < out.Tags[j].Name
+	})
+
+	sortAWSFilters(out.Subnet.Filters)
+
+	return out
+}
+
+// normalisedProviderSpec returns the complete provider spec, including the instance requirements,
+// normalised so that it is suitable for comparison.
+func (a AWSProviderConfig) normalisedProviderSpec() awsProviderSpec {
+	return awsProviderSpec{
+		AWSMachineProviderConfig: *normalisedAWSProviderConfig(a.providerConfig),
+		InstanceRequirements:     normalisedAWSInstanceRequirements(a.instanceRequirements),
+	}
+}
+
+// rawProviderSpec returns the complete provider spec, including the instance requirements, as it
+// should be encoded into the raw provider spec.
+func (a AWSProviderConfig) rawProviderSpec() awsProviderSpec {
+	return awsProviderSpec{
+		AWSMachineProviderConfig: a.providerConfig,
+		InstanceRequirements:     a.instanceRequirements,
+	}
+}
+
+// awsBlockDeviceName returns the name used to match block devices between provider specs.
+// The root volume is the block device without a device name.
+func awsBlockDeviceName(device machinev1beta1.BlockDeviceMappingSpec) string {
+	return pointer.StringDeref(device.DeviceName, awsRootDeviceName)
+}
+
+// awsKMSKeyID returns the KMS key that the Machine API passes to EC2 when encrypting a volume.
+// The ID, which may also hold an alias or an ARN, is used in preference to the ARN. Filters are
+// not resolved for KMS keys, so a key referenced only by filters has no ID.
+func awsKMSKeyID(ref machinev1beta1.AWSResourceReference) string {
+	if id := pointer.StringDeref(ref.ID, ""); id != "" {
+		return id
+	}
+
+	return pointer.StringDeref(ref.ARN, "")
+}
+
+// normalisedAWSKMSKey returns a reference to the KMS key by the key passed to EC2 alone, so that
+// references that result in the same key compare as equal. Keys referenced only by filters are
+// left unchanged, so that changes to the filters are still reported.
+func normalisedAWSKMSKey(ref machinev1beta1.AWSResourceReference) machinev1beta1.AWSResourceReference {
+	id := awsKMSKeyID(ref)
+	if id == "" {
+		return ref
+	}
+
+	return machinev1beta1.AWSResourceReference{ID: &id}
+}
+
+// findAWSBlockDevice returns the index of the block device with the given device name.
+func findAWSBlockDevice(devices []machinev1beta1.BlockDeviceMappingSpec, deviceName string) (int, bool) {
+	for i, device := range devices {
+		if awsBlockDeviceName(device) == deviceName {
+			return i, true
+		}
+	}
+
+	return 0, false
+}
+
+// setAWSDefaults sets the values that AWS uses when the field is omitted from the provider spec.
+// A provider spec that omits one of these fields results in the same instance as a provider spec
+// that sets the field to the default value, so the two should not be considered different.
+// The provider spec is modified in place, callers should pass a copy.
+func setAWSDefaults(cfg *machinev1beta1.AWSMachineProviderConfig) {
+	if cfg.NetworkInterfaceType == "" {
+		cfg.NetworkInterfaceType = machinev1beta1.AWSENANetworkInterfaceType
+	}
+
+	if cfg.Placement.Tenancy == "" {
+		cfg.Placement.Tenancy = machinev1beta1.DefaultTenancy
+	}
+
+	for i := range cfg.BlockDevices {
+		ebs := cfg.BlockDevices[i].EBS
+		if ebs != nil && ebs.VolumeType == nil {
+			volumeType := awsDefaultVolumeType
+			ebs.VolumeType = &volumeType
+		}
+	}
+}
+
 // newAWSProviderConfig creates an AWSProviderConfig from the raw extension.
 // It should return an error if the provided RawExtension does not represent
 // an AWSMachineProviderConfig.
+// Any instance requirements within the raw extension are decoded alongside the
+// AWSMachineProviderConfig, so that they are preserved and compared.
 func newAWSProviderConfig(raw *runtime.RawExtension) (ProviderConfig, error) {
-	// TODO: replace this with actual logic to create the provider config from the raw extension.
-	// This is here as a dummy to keep the linter happy.
-	return providerConfig{}, nil
+	spec := awsProviderSpec{}
+	if err := decodeProviderSpec(raw, awsProviderConfigKind, &spec); err != nil {
+		return nil, fmt.Errorf("could not decode AWS provider spec: %w", err)
+	}
+
+	return providerConfig{
+		platformType: configv1.AWSPlatformType,
+		raw:          raw.Raw,
+		aws: AWSProviderConfig{
+			providerConfig:       spec.AWSMachineProviderConfig,
+			instanceRequirements: spec.InstanceRequirements,
+		},
+	}, nil
+}
+
+// convertAWSResourceReferenceV1ToV1Beta1 converts a v1 AWSResourceReference, as used within the
+// failure domains, into the v1beta1 AWSResourceReference used within the provider spec.
+// The reference is copied, so that the provider spec does not share the ID, ARN or filter values
+// of the failure domain.
+func convertAWSResourceReferenceV1ToV1Beta1(ref machinev1.AWSResourceReference) machinev1beta1.AWSResourceReference {
+	out := machinev1beta1.AWSResourceReference{}
+
+	switch ref.Type {
+	case machinev1.AWSIDReferenceType:
+		if ref.ID != nil {
+			out.ID = pointer.String(*ref.ID)
+		}
+	case machinev1.AWSARNReferenceType:
+		if ref.ARN != nil {
+			out.ARN = pointer.String(*ref.ARN)
+		}
+	case machinev1.AWSFiltersReferenceType:
+		if ref.Filters != nil {
+			for _, filter := range *ref.Filters {
+				out.Filters = append(out.Filters, machinev1beta1.Filter{
+					Name:   filter.Name,
+					Values: copyAWSFilterValues(filter.Values),
+				})
+			}
+		}
+	}
+
+	return out
+}
+
+// convertAWSResourceReferenceV1Beta1ToV1 converts a v1beta1 AWSResourceReference, as used within
+// the provider spec, into the v1 AWSResourceReference used within the failure domains.
+// When the reference contains more than one of an ID, ARN and filters, the ID is preferred over the
+// ARN, and the ARN over the filters, as is the case when the Machine API resolves the reference.
+// When the reference does not contain an ID, ARN or any filters, no reference is returned.
+// The reference is copied, so that the failure domain does not share the ID, ARN or filter values
+// of the provider spec.
+func convertAWSResourceReferenceV1Beta1ToV1(ref machinev1beta1.AWSResourceReference) *machinev1.AWSResourceReference {
+	switch {
+	case ref.ID != nil:
+		return &machinev1.AWSResourceReference{
+			Type: machinev1.AWSIDReferenceType,
+			ID:   pointer.String(*ref.ID),
+		}
+	case ref.ARN != nil:
+		return &machinev1.AWSResourceReference{
+			Type: machinev1.AWSARNReferenceType,
+			ARN:  pointer.String(*ref.ARN),
+		}
+	case len(ref.Filters) > 0:
+		filters := []machinev1.AWSResourceFilter{}
+		for _, filter := range ref.Filters {
+			filters = append(filters, machinev1.AWSResourceFilter{
+				Name:   filter.Name,
+				Values: copyAWSFilterValues(filter.Values),
+			})
+		}
+
+		return &machinev1.AWSResourceReference{
+			Type:    machinev1.AWSFiltersReferenceType,
+			Filters: &filters,
+		}
+	default:
+		return nil
+	}
+}
+
+// copyAWSFilterValues returns a copy of the values of an AWS filter.
+func copyAWSFilterValues(values []string) []string {
+	if values == nil {
+		return nil
+	}
+
+	out := make([]string, len(values))
+	copy(out, values)
+
+	return out
+}
+
+// sortAWSFilters orders the filters by name, and the values of each filter, in place.
+// AWS matches resources that satisfy every filter, and any of the values of each filter, so the
+// order of neither affects which resource is referenced.
+func sortAWSFilters(filters []machinev1beta1.Filter) {
+	for i := range filters {
+		sort.Strings(filters[i].Values)
+	}
+
+	sort.SliceStable(filters, func(i, j int) bool {
+		return filters[i].Name < filters[j].Name
+	})
 }
@@ -0,0 +1,119 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package providerconfig
+
+import (
+	"sort"
+
+	machinev1beta1 "github.com/openshift/api/machine/v1beta1"
+)
+
+// AWSInstanceRequirements describes the attributes of the instance types that may be used for
+// an AWS Machine, when the instance type is selected by attribute rather than being fixed.
+// The AWSMachineProviderConfig API does not yet include these attributes, so they are
+// decoded from, and encoded into, the raw provider spec alongside the AWSMachineProviderConfig.
+type AWSInstanceRequirements struct {
+	// VCPUCount is the range of vCPUs that the instance type may have.
+	VCPUCount *AWSInstanceRequirementsRange `json:"vCpuCount,omitempty"`
+
+	// MemoryMiB is the range of memory, in MiB, that the instance type may have.
+	MemoryMiB *AWSInstanceRequirementsRange `json:"memoryMiB,omitempty"`
+
+	// CPUManufacturers is the list of CPU manufacturers that the instance type may use.
+	CPUManufacturers []string `json:"cpuManufacturers,omitempty"`
+
+	// ExcludedInstanceTypes is the list of instance types that must not be used.
+	ExcludedInstanceTypes []string `json:"excludedInstanceTypes,omitempty"`
+}
+
+// AWSInstanceRequirementsRange is an inclusive range of values for an instance type attribute.
+// When the maximum is omitted, there is no upper bound.
+type AWSInstanceRequirementsRange struct {
+	// Min is the minimum value of the attribute.
+	Min *int64 `json:"min,omitempty"`
+
+	// Max is the maximum value of the attribute.
+	Max *int64 `json:"max,omitempty"`
+}
+
+// awsProviderSpec is the complete AWS provider spec, including the fields that are not yet part of
+// the AWSMachineProviderConfig API.
+// It is used to decode and encode the raw provider spec so that these fields are preserved.
+type awsProviderSpec struct {
+	machinev1beta1.AWSMachineProviderConfig `json:",inline"`
+
+	// InstanceRequirements are the attributes used to select the instance type.
+	InstanceRequirements *AWSInstanceRequirements `json:"instanceRequirements,omitempty"`
+}
+
+// DeepCopy returns a deep copy of the AWSInstanceRequirements.
+func (r *AWSInstanceRequirements) DeepCopy() *AWSInstanceRequirements {
+	if r == nil {
+		return nil
+	}
+
+	out := &AWSInstanceRequirements{
+		VCPUCount: r.VCPUCount.DeepCopy(),
+		MemoryMiB: r.MemoryMiB.DeepCopy(),
+	}
+
+	if r.CPUManufacturers != nil {
+		out.CPUManufacturers = append([]string{}, r.CPUManufacturers...)
+	}
+
+	if r.ExcludedInstanceTypes != nil {
+		out.ExcludedInstanceTypes = append([]string{}, r.ExcludedInstanceTypes...)
+	}
+
+	return out
+}
+
+// DeepCopy returns a deep copy of the AWSInstanceRequirementsRange.
+func (r *AWSInstanceRequirementsRange) DeepCopy() *AWSInstanceRequirementsRange {
+	if r == nil {
+		return nil
+	}
+
+	out := &AWSInstanceRequirementsRange{}
+
+	if r.Min != nil {
+		minValue := *r.Min
+		out.Min = &minValue
+	}
+
+	if r.Max != nil {
+		maxValue := *r.Max
+		out.Max = &maxValue
+	}
+
+	return out
+}
+
+// normalisedAWSInstanceRequirements returns a copy of the instance requirements that is suitable for
+// comparison. The order of the CPU manufacturers and excluded instance types has no effect on the
+// instance types that may be selected, so the lists are sorted.
+func normalisedAWSInstanceRequirements(requirements *AWSInstanceRequirements) *AWSInstanceRequirements {
+	out := requirements.DeepCopy()
+	if out == nil {
+		return nil
+	}
+
+	sort.Strings(out.CPUManufacturers)
+	sort.Strings(out.ExcludedInstanceTypes)
+
+	return out
+}
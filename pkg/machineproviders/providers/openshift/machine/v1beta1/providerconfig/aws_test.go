@@ -24,6 +24,8 @@ import (
 	machinev1 "github.com/openshift/api/machine/v1"
 	machinev1beta1 "github.com/openshift/api/machine/v1beta1"
 	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/test/resourcebuilder"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/utils/pointer"
 )
 
 var _ = Describe("AWS Provider Config", func() {
@@ -79,7 +81,7 @@ var _ = Describe("AWS Provider Config", func() {
 	})
 
 	Context("ExtractFailureDomain", func() {
-		PIt("returns the configured failure domain", func() {
+		It("returns the configured failure domain", func() {
 			expected := resourcebuilder.AWSFailureDomain().
 				WithAvailabilityZone(azUSEast1a).
 				WithSubnet(machinev1SubnetUSEast1a).
@@ -101,16 +103,16 @@ var _ = Describe("AWS Provider Config", func() {
 			changedProviderConfig = providerConfig.InjectFailureDomain(changedFailureDomain)
 		})
 
-		PIt("stores the new subnet in the provider config", func() {
+		It("stores the new subnet in the provider config", func() {
 			Expect(changedProviderConfig.Config().Subnet).To(Equal(machinev1beta1SubnetUSEast1b))
 		})
 
-		PIt("does not modify the original provider config", func() {
+		It("does not modify the original provider config", func() {
 			Expect(providerConfig.Config().Subnet).To(Equal(machinev1beta1SubnetUSEast1a))
 		})
 
 		Context("ExtractFailureDomain", func() {
-			PIt("returns the changed failure domain from the changed config", func() {
+			It("returns the changed failure domain from the changed config", func() {
 				expected := resourcebuilder.AWSFailureDomain().
 					WithAvailabilityZone(azUSEast1b).
 					WithSubnet(machinev1SubnetUSEast1b).
@@ -119,10 +121,10 @@ var _ = Describe("AWS Provider Config", func() {
 				Expect(changedProviderConfig.ExtractFailureDomain()).To(Equal(expected))
 			})
 
-			PIt("returns the original failure domain from the original config", func() {
+			It("returns the original failure domain from the original config", func() {
 				expected := resourcebuilder.AWSFailureDomain().
-					WithAvailabilityZone(azUSEast1b).
-					WithSubnet(machinev1SubnetUSEast1b).
+					WithAvailabilityZone(azUSEast1a).
+					WithSubnet(machinev1SubnetUSEast1a).
 					Build()
 
 				Expect(providerConfig.ExtractFailureDomain()).To(Equal(expected))
@@ -130,6 +132,408 @@ var _ = Describe("AWS Provider Config", func() {
 		})
 	})
 
+	Context("when the failure domain references the subnet", func() {
+		DescribeTable("round-trips through InjectFailureDomain and ExtractFailureDomain", func(subnet machinev1.AWSResourceReference, expectedSubnet machinev1beta1.AWSResourceReference) {
+			fd := resourcebuilder.AWSFailureDomain().
+				WithAvailabilityZone(azUSEast1b).
+				WithSubnet(subnet).
+				Build()
+
+			changedProviderConfig := providerConfig.InjectFailureDomain(fd)
+
+			Expect(changedProviderConfig.Config().Subnet).To(Equal(expectedSubnet))
+			Expect(changedProviderConfig.ExtractFailureDomain()).To(Equal(fd))
+		},
+			Entry("by ID", machinev1.AWSResourceReference{
+				Type: machinev1.AWSIDReferenceType,
+				ID:   pointer.String("subnet-0123456789abcdef0"),
+			}, machinev1beta1.AWSResourceReference{
+				ID: pointer.String("subnet-0123456789abcdef0"),
+			}),
+			Entry("by ARN", machinev1.AWSResourceReference{
+				Type: machinev1.AWSARNReferenceType,
+				ARN:  pointer.String("arn:aws:ec2:us-east-1:123456789012:subnet/subnet-0123456789abcdef0"),
+			}, machinev1beta1.AWSResourceReference{
+				ARN: pointer.String("arn:aws:ec2:us-east-1:123456789012:subnet/subnet-0123456789abcdef0"),
+			}),
+			Entry("by compound filters", machinev1.AWSResourceReference{
+				Type: machinev1.AWSFiltersReferenceType,
+				Filters: &[]machinev1.AWSResourceFilter{
+					{Name: "tag:Name", Values: []string{"subnet-private-us-east-1b", "subnet-public-us-east-1b"}},
+					{Name: "tag:kubernetes.io/cluster/cluster-id", Values: []string{"owned"}},
+					{Name: "availability-zone", Values: []string{azUSEast1b}},
+				},
+			}, machinev1beta1.AWSResourceReference{
+				Filters: []machinev1beta1.Filter{
+					{Name: "tag:Name", Values: []string{"subnet-private-us-east-1b", "subnet-public-us-east-1b"}},
+					{Name: "tag:kubernetes.io/cluster/cluster-id", Values: []string{"owned"}},
+					{Name: "availability-zone", Values: []string{azUSEast1b}},
+				},
+			}),
+		)
+
+		It("replaces subnet filters with an ID", func() {
+			changedProviderConfig := providerConfig.InjectFailureDomain(resourcebuilder.AWSFailureDomain().WithSubnet(machinev1.AWSResourceReference{
+				Type: machinev1.AWSIDReferenceType,
+				ID:   pointer.String("subnet-0123456789abcdef0"),
+			}).Build())
+
+			Expect(changedProviderConfig.Config().Subnet).To(Equal(machinev1beta1.AWSResourceReference{
+				ID: pointer.String("subnet-0123456789abcdef0"),
+			}))
+		})
+
+		It("prefers the ID when extracting a subnet referenced by both an ID and filters", func() {
+			providerConfig.providerConfig.Subnet.ID = pointer.String("subnet-0123456789abcdef0")
+
+			Expect(providerConfig.ExtractFailureDomain().Subnet).To(Equal(&machinev1.AWSResourceReference{
+				Type: machinev1.AWSIDReferenceType,
+				ID:   pointer.String("subnet-0123456789abcdef0"),
+			}))
+		})
+
+		It("does not share the filter values with the failure domain", func() {
+			fd := resourcebuilder.AWSFailureDomain().WithSubnet(machinev1SubnetUSEast1b).Build()
+
+			changedProviderConfig := providerConfig.InjectFailureDomain(fd)
+			(*fd.Subnet.Filters)[0].Values[0] = "modified"
+
+			Expect(changedProviderConfig.Config().Subnet).To(Equal(machinev1beta1SubnetUSEast1b))
+		})
+
+		It("compares as equal to the same subnet filters in a different order", func() {
+			reordered := providerConfig.InjectFailureDomain(resourcebuilder.AWSFailureDomain().WithSubnet(machinev1.AWSResourceReference{
+				Type: machinev1.AWSFiltersReferenceType,
+				Filters: &[]machinev1.AWSResourceFilter{
+					{Name: "tag:kubernetes.io/cluster/cluster-id", Values: []string{"owned"}},
+					{Name: "tag:Name", Values: []string{"subnet-public-us-east-1a", "subnet-us-east-1a"}},
+				},
+			}).Build())
+
+			original := providerConfig.InjectFailureDomain(resourcebuilder.AWSFailureDomain().WithSubnet(machinev1.AWSResourceReference{
+				Type: machinev1.AWSFiltersReferenceType,
+				Filters: &[]machinev1.AWSResourceFilter{
+					{Name: "tag:Name", Values: []string{"subnet-us-east-1a", "subnet-public-us-east-1a"}},
+					{Name: "tag:kubernetes.io/cluster/cluster-id", Values: []string{"owned"}},
+				},
+			}).Build())
+
+			Expect(reordered.Equal(original)).To(BeTrue())
+			Expect(original.Config().Subnet.Filters[0].Values).To(Equal([]string{"subnet-us-east-1a", "subnet-public-us-east-1a"}), "Equal should not modify the provider config")
+		})
+
+		It("compares as different to a subnet with different filter values", func() {
+			changedProviderConfig := providerConfig.InjectFailureDomain(resourcebuilder.AWSFailureDomain().WithSubnet(machinev1SubnetUSEast1b).Build())
+
+			Expect(changedProviderConfig.Equal(providerConfig)).To(BeFalse())
+		})
+	})
+
+	Context("ExtractAMI", func() {
+		It("returns the configured AMI ID", func() {
+			Expect(providerConfig.ExtractAMI()).To(Equal("aws-ami-12345678"))
+		})
+
+		It("returns an empty string when the AMI is not referenced by ID", func() {
+			providerConfig.providerConfig.AMI = machinev1beta1.AWSResourceReference{
+				ARN: pointer.String("arn:aws:ec2:us-east-1::image/aws-ami-12345678"),
+			}
+
+			Expect(providerConfig.ExtractAMI()).To(BeEmpty())
+		})
+	})
+
+	Context("ExtractAMIFilters", func() {
+		filters := []machinev1beta1.Filter{{Name: "tag:Name", Values: []string{"rhcos-4.12"}}}
+
+		It("returns the filters when the AMI is referenced by filters", func() {
+			providerConfig.providerConfig.AMI = machinev1beta1.AWSResourceReference{Filters: filters}
+
+			Expect(providerConfig.ExtractAMIFilters()).To(Equal(filters))
+		})
+
+		It("returns no filters when the AMI is referenced by ID", func() {
+			providerConfig.providerConfig.AMI.Filters = filters
+
+			Expect(providerConfig.ExtractAMIFilters()).To(BeEmpty())
+		})
+	})
+
+	Context("ExtractInstanceType", func() {
+		It("returns the configured instance type", func() {
+			Expect(providerConfig.ExtractInstanceType()).To(Equal("m6i.xlarge"))
+		})
+	})
+
+	Context("when the AMI is changed after initialisation", func() {
+		var changedProviderConfig AWSProviderConfig
+
+		BeforeEach(func() {
+			changedProviderConfig = providerConfig.InjectAMI("aws-ami-87654321")
+		})
+
+		It("stores the new AMI in the provider config", func() {
+			Expect(changedProviderConfig.Config().AMI).To(Equal(machinev1beta1.AWSResourceReference{
+				ID: pointer.String("aws-ami-87654321"),
+			}))
+		})
+
+		It("does not modify the original provider config", func() {
+			Expect(providerConfig.ExtractAMI()).To(Equal("aws-ami-12345678"))
+		})
+	})
+
+	Context("when tags are injected after initialisation", func() {
+		var changedProviderConfig AWSProviderConfig
+
+		BeforeEach(func() {
+			providerConfig.providerConfig.Tags = []machinev1beta1.TagSpecification{{Name: "existing", Value: "original"}}
+
+			changedProviderConfig = providerConfig.InjectTags(map[string]string{
+				"existing": "changed",
+				"new-b":    "b",
+				"new-a":    "a",
+			})
+		})
+
+		It("appends the new tags in name order, leaving existing tags unchanged", func() {
+			Expect(changedProviderConfig.Config().Tags).To(Equal([]machinev1beta1.TagSpecification{
+				{Name: "existing", Value: "original"},
+				{Name: "new-a", Value: "a"},
+				{Name: "new-b", Value: "b"},
+			}))
+		})
+
+		It("does not modify the original provider config", func() {
+			Expect(providerConfig.ExtractTags()).To(Equal(map[string]string{"existing": "original"}))
+		})
+	})
+
+	Context("Equal", func() {
+		type awsEqualTableInput struct {
+			modifyBase    func(*machinev1beta1.AWSMachineProviderConfig)
+			modifyCompare func(*machinev1beta1.AWSMachineProviderConfig)
+			expectedEqual bool
+
+			expectedUnmanagedFields []string
+		}
+
+		DescribeTable("should compare the provider configs after applying defaults", func(in awsEqualTableInput) {
+			base := resourcebuilder.AWSProviderSpec().Build()
+			if in.modifyBase != nil {
+				in.modifyBase(base)
+			}
+
+			compare := resourcebuilder.AWSProviderSpec().Build()
+			if in.modifyCompare != nil {
+				in.modifyCompare(compare)
+			}
+
+			baseConfig := AWSProviderConfig{providerConfig: *base}
+			compareConfig := AWSProviderConfig{providerConfig: *compare}
+
+			Expect(baseConfig.Equal(compareConfig)).To(Equal(in.expectedEqual))
+			Expect(compareConfig.Equal(baseConfig)).To(Equal(in.expectedEqual), "Equality should be symmetric")
+
+			Expect(baseConfig.Config().NetworkInterfaceType).To(Equal(base.NetworkInterfaceType), "Equal should not modify the provider config")
+
+			Expect(baseConfig.UnmanagedFields(compareConfig)).To(ConsistOf(in.expectedUnmanagedFields))
+			Expect(compareConfig.UnmanagedFields(baseConfig)).To(ConsistOf(in.expectedUnmanagedFields), "Unmanaged fields should be symmetric")
+		},
+			Entry("with an omitted and an explicit ENA network interface type", awsEqualTableInput{
+				modifyCompare: func(cfg *machinev1beta1.AWSMachineProviderConfig) {
+					cfg.NetworkInterfaceType = machinev1beta1.AWSENANetworkInterfaceType
+				},
+				expectedEqual:           true,
+				expectedUnmanagedFields: []string{"networkInterfaceType"},
+			}),
+			Entry("with an omitted and an explicit EFA network interface type", awsEqualTableInput{
+				modifyCompare: func(cfg *machinev1beta1.AWSMachineProviderConfig) {
+					cfg.NetworkInterfaceType = machinev1beta1.AWSEFANetworkInterfaceType
+				},
+				expectedEqual:           false,
+				expectedUnmanagedFields: []string{},
+			}),
+			Entry("with an omitted and an explicit default tenancy", awsEqualTableInput{
+				modifyCompare: func(cfg *machinev1beta1.AWSMachineProviderConfig) {
+					cfg.Placement.Tenancy = machinev1beta1.DefaultTenancy
+				},
+				expectedEqual:           true,
+				expectedUnmanagedFields: []string{"placement.tenancy"},
+			}),
+			Entry("with an omitted and an explicit dedicated tenancy", awsEqualTableInput{
+				modifyCompare: func(cfg *machinev1beta1.AWSMachineProviderConfig) {
+					cfg.Placement.Tenancy = machinev1beta1.DedicatedTenancy
+				},
+				expectedEqual:           false,
+				expectedUnmanagedFields: []string{},
+			}),
+			Entry("with an omitted and an explicit standard volume type", awsEqualTableInput{
+				modifyBase: func(cfg *machinev1beta1.AWSMachineProviderConfig) {
+					cfg.BlockDevices[0].EBS.VolumeType = nil
+				},
+				modifyCompare: func(cfg *machinev1beta1.AWSMachineProviderConfig) {
+					volumeType := "standard"
+					cfg.BlockDevices[0].EBS.VolumeType = &volumeType
+				},
+				expectedEqual:           true,
+				expectedUnmanagedFields: []string{"blockDevices[0].ebs.volumeType"},
+			}),
+			Entry("with an omitted and an explicit gp3 volume type", awsEqualTableInput{
+				modifyBase: func(cfg *machinev1beta1.AWSMachineProviderConfig) {
+					cfg.BlockDevices[0].EBS.VolumeType = nil
+				},
+				expectedEqual:           false,
+				expectedUnmanagedFields: []string{},
+			}),
+			Entry("with the same block devices in a different order", awsEqualTableInput{
+				modifyBase: func(cfg *machinev1beta1.AWSMachineProviderConfig) {
+					cfg.BlockDevices = append(cfg.BlockDevices, etcdBlockDevice(100))
+				},
+				modifyCompare: func(cfg *machinev1beta1.AWSMachineProviderConfig) {
+					cfg.BlockDevices = append([]machinev1beta1.BlockDeviceMappingSpec{etcdBlockDevice(100)}, cfg.BlockDevices...)
+				},
+				expectedEqual:           true,
+				expectedUnmanagedFields: []string{},
+			}),
+			Entry("with the same tags in a different order", awsEqualTableInput{
+				modifyBase: func(cfg *machinev1beta1.AWSMachineProviderConfig) {
+					cfg.Tags = []machinev1beta1.TagSpecification{{Name: "a", Value: "1"}, {Name: "b", Value: "2"}}
+				},
+				modifyCompare: func(cfg *machinev1beta1.AWSMachineProviderConfig) {
+					cfg.Tags = []machinev1beta1.TagSpecification{{Name: "b", Value: "2"}, {Name: "a", Value: "1"}}
+				},
+				expectedEqual:           true,
+				expectedUnmanagedFields: []string{},
+			}),
+			Entry("with an additional tag", awsEqualTableInput{
+				modifyCompare: func(cfg *machinev1beta1.AWSMachineProviderConfig) {
+					cfg.Tags = []machinev1beta1.TagSpecification{{Name: "a", Value: "1"}}
+				},
+				expectedEqual:           false,
+				expectedUnmanagedFields: []string{},
+			}),
+			Entry("with an increased root volume size", awsEqualTableInput{
+				modifyCompare: func(cfg *machinev1beta1.AWSMachineProviderConfig) {
+					cfg.BlockDevices[0].EBS.VolumeSize = pointer.Int64(240)
+				},
+				expectedEqual:           false,
+				expectedUnmanagedFields: []string{},
+			}),
+			Entry("with an unencrypted root volume", awsEqualTableInput{
+				modifyCompare: func(cfg *machinev1beta1.AWSMachineProviderConfig) {
+					cfg.BlockDevices[0].EBS.Encrypted = pointer.Bool(false)
+				},
+				expectedEqual:           false,
+				expectedUnmanagedFields: []string{},
+			}),
+			Entry("with the same KMS key referenced by ID and by ARN", awsEqualTableInput{
+				modifyBase: func(cfg *machinev1beta1.AWSMachineProviderConfig) {
+					cfg.BlockDevices[0].EBS.KMSKey = machinev1beta1.AWSResourceReference{ID: pointer.String(testKMSKeyARN)}
+				},
+				modifyCompare: func(cfg *machinev1beta1.AWSMachineProviderConfig) {
+					cfg.BlockDevices[0].EBS.KMSKey = machinev1beta1.AWSResourceReference{ARN: pointer.String(testKMSKeyARN)}
+				},
+				expectedEqual:           true,
+				expectedUnmanagedFields: []string{"blockDevices[0].ebs.kmsKey"},
+			}),
+			Entry("with a KMS key referenced by both ID and ARN", awsEqualTableInput{
+				modifyBase: func(cfg *machinev1beta1.AWSMachineProviderConfig) {
+					cfg.BlockDevices[0].EBS.KMSKey = machinev1beta1.AWSResourceReference{ID: pointer.String("alias/control-plane")}
+				},
+				modifyCompare: func(cfg *machinev1beta1.AWSMachineProviderConfig) {
+					cfg.BlockDevices[0].EBS.KMSKey = machinev1beta1.AWSResourceReference{ID: pointer.String("alias/control-plane"), ARN: pointer.String(testKMSKeyARN)}
+				},
+				expectedEqual:           true,
+				expectedUnmanagedFields: []string{"blockDevices[0].ebs.kmsKey"},
+			}),
+			Entry("with a different KMS key", awsEqualTableInput{
+				modifyBase: func(cfg *machinev1beta1.AWSMachineProviderConfig) {
+					cfg.BlockDevices[0].EBS.KMSKey = machinev1beta1.AWSResourceReference{ARN: pointer.String(testKMSKeyARN)}
+				},
+				modifyCompare: func(cfg *machinev1beta1.AWSMachineProviderConfig) {
+					cfg.BlockDevices[0].EBS.KMSKey = machinev1beta1.AWSResourceReference{ID: pointer.String("alias/control-plane")}
+				},
+				expectedEqual:           false,
+				expectedUnmanagedFields: []string{},
+			}),
+			Entry("with a KMS key and the default key", awsEqualTableInput{
+				modifyCompare: func(cfg *machinev1beta1.AWSMachineProviderConfig) {
+					cfg.BlockDevices[0].EBS.KMSKey = machinev1beta1.AWSResourceReference{ARN: pointer.String(testKMSKeyARN)}
+				},
+				expectedEqual:           false,
+				expectedUnmanagedFields: []string{},
+			}),
+		)
+	})
+
+	Context("BlockDeviceEncryption", func() {
+		It("reports the encryption of each EBS volume", func() {
+			config := AWSProviderConfig{providerConfig: *resourcebuilder.AWSProviderSpec().WithBlockDevices([]machinev1beta1.BlockDeviceMappingSpec{
+				rootBlockDevice(120),
+				{DeviceName: pointer.String("/dev/xvdb"), EBS: &machinev1beta1.EBSBlockDeviceSpec{
+					Encrypted: pointer.Bool(true),
+					KMSKey:    machinev1beta1.AWSResourceReference{ARN: pointer.String(testKMSKeyARN)},
+				}},
+				{DeviceName: pointer.String("/dev/xvdc"), EBS: &machinev1beta1.EBSBlockDeviceSpec{
+					KMSKey: machinev1beta1.AWSResourceReference{Filters: []machinev1beta1.Filter{{Name: "alias", Values: []string{"control-plane"}}}},
+				}},
+				{DeviceName: pointer.String("/dev/xvdd"), VirtualName: pointer.String("ephemeral0")},
+			}).Build()}
+
+			Expect(config.BlockDeviceEncryption()).To(Equal([]AWSBlockDeviceEncryption{
+				{Index: 0, DeviceName: "root", Encrypted: nil},
+				{Index: 1, DeviceName: "/dev/xvdb", Encrypted: pointer.Bool(true), KMSKeyID: testKMSKeyARN},
+				{Index: 2, DeviceName: "/dev/xvdc", KMSKeyFilters: true},
+			}))
+		})
+	})
+
+	Context("BlockDeviceSizeDecreases", func() {
+		type blockDeviceSizeDecreasesTableInput struct {
+			currentBlockDevices []machinev1beta1.BlockDeviceMappingSpec
+			desiredBlockDevices []machinev1beta1.BlockDeviceMappingSpec
+			expectedDecreases   []AWSBlockDeviceSizeDecrease
+		}
+
+		DescribeTable("should report block devices with decreased volume sizes", func(in blockDeviceSizeDecreasesTableInput) {
+			current := AWSProviderConfig{providerConfig: *resourcebuilder.AWSProviderSpec().WithBlockDevices(in.currentBlockDevices).Build()}
+			desired := AWSProviderConfig{providerConfig: *resourcebuilder.AWSProviderSpec().WithBlockDevices(in.desiredBlockDevices).Build()}
+
+			Expect(current.BlockDeviceSizeDecreases(desired)).To(Equal(in.expectedDecreases))
+		},
+			Entry("with matching block devices", blockDeviceSizeDecreasesTableInput{
+				currentBlockDevices: []machinev1beta1.BlockDeviceMappingSpec{rootBlockDevice(120), etcdBlockDevice(100)},
+				desiredBlockDevices: []machinev1beta1.BlockDeviceMappingSpec{rootBlockDevice(120), etcdBlockDevice(100)},
+				expectedDecreases:   nil,
+			}),
+			Entry("with an increased root volume size", blockDeviceSizeDecreasesTableInput{
+				currentBlockDevices: []machinev1beta1.BlockDeviceMappingSpec{rootBlockDevice(120)},
+				desiredBlockDevices: []machinev1beta1.BlockDeviceMappingSpec{rootBlockDevice(240)},
+				expectedDecreases:   nil,
+			}),
+			Entry("with a decreased root volume size", blockDeviceSizeDecreasesTableInput{
+				currentBlockDevices: []machinev1beta1.BlockDeviceMappingSpec{rootBlockDevice(120)},
+				desiredBlockDevices: []machinev1beta1.BlockDeviceMappingSpec{rootBlockDevice(60)},
+				expectedDecreases: []AWSBlockDeviceSizeDecrease{
+					{Index: 0, DeviceName: "root", CurrentSize: 120, DesiredSize: 60},
+				},
+			}),
+			Entry("with a decreased volume size on a reordered block device", blockDeviceSizeDecreasesTableInput{
+				currentBlockDevices: []machinev1beta1.BlockDeviceMappingSpec{rootBlockDevice(120), etcdBlockDevice(100)},
+				desiredBlockDevices: []machinev1beta1.BlockDeviceMappingSpec{etcdBlockDevice(50), rootBlockDevice(120)},
+				expectedDecreases: []AWSBlockDeviceSizeDecrease{
+					{Index: 0, DeviceName: "/dev/xvdb", CurrentSize: 100, DesiredSize: 50},
+				},
+			}),
+			Entry("with a new block device", blockDeviceSizeDecreasesTableInput{
+				currentBlockDevices: []machinev1beta1.BlockDeviceMappingSpec{rootBlockDevice(120)},
+				desiredBlockDevices: []machinev1beta1.BlockDeviceMappingSpec{rootBlockDevice(120), etcdBlockDevice(10)},
+				expectedDecreases:   nil,
+			}),
+		)
+	})
+
 	Context("newAWSProviderConfig", func() {
 		var providerConfig ProviderConfig
 		var expectedAWSConfig machinev1beta1.AWSMachineProviderConfig
@@ -144,13 +548,150 @@ var _ = Describe("AWS Provider Config", func() {
 			Expect(err).ToNot(HaveOccurred())
 		})
 
-		PIt("sets the type to AWS", func() {
+		It("sets the type to AWS", func() {
 			Expect(providerConfig.Type()).To(Equal(configv1.AWSPlatformType))
 		})
 
-		PIt("returns the correct AWS config", func() {
+		It("returns the correct AWS config", func() {
 			Expect(providerConfig.AWS()).ToNot(BeNil())
 			Expect(providerConfig.AWS().Config()).To(Equal(expectedAWSConfig))
 		})
+
+		Context("with the machine.openshift.io API version", func() {
+			BeforeEach(func() {
+				configBuilder := resourcebuilder.AWSProviderSpec().WithAPIVersion("machine.openshift.io/v1beta1")
+				expectedAWSConfig = *configBuilder.Build()
+
+				var err error
+				providerConfig, err = newAWSProviderConfig(configBuilder.BuildRawExtension())
+				Expect(err).ToNot(HaveOccurred())
+			})
+
+			It("returns the correct AWS config", func() {
+				Expect(providerConfig.AWS().Config()).To(Equal(expectedAWSConfig))
+			})
+
+			It("is equal to the same config with the legacy API version", func() {
+				legacyConfig, err := newAWSProviderConfig(resourcebuilder.AWSProviderSpec().BuildRawExtension())
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(providerConfig.Equal(legacyConfig)).To(BeTrue())
+			})
+		})
+
+		Context("with instance requirements", func() {
+			const requirements = `{"vCpuCount":{"min":4,"max":8},"memoryMiB":{"min":16384},"cpuManufacturers":["intel","amd"]}`
+
+			var configBuilder resourcebuilder.AWSProviderSpecBuilder
+
+			BeforeEach(func() {
+				configBuilder = resourcebuilder.AWSProviderSpec().WithInstanceType("").WithInstanceRequirements(requirements)
+
+				var err error
+				providerConfig, err = newAWSProviderConfig(configBuilder.BuildRawExtension())
+				Expect(err).ToNot(HaveOccurred())
+			})
+
+			It("returns the instance requirements", func() {
+				Expect(providerConfig.AWS().InstanceRequirements()).To(Equal(&AWSInstanceRequirements{
+					VCPUCount:        &AWSInstanceRequirementsRange{Min: pointer.Int64(4), Max: pointer.Int64(8)},
+					MemoryMiB:        &AWSInstanceRequirementsRange{Min: pointer.Int64(16384)},
+					CPUManufacturers: []string{"intel", "amd"},
+				}))
+			})
+
+			It("returns an empty instance type", func() {
+				Expect(providerConfig.ExtractInstanceType()).To(BeEmpty())
+			})
+
+			It("preserves the instance requirements within the raw config", func() {
+				rawConfig, err := providerConfig.RawConfig()
+				Expect(err).ToNot(HaveOccurred())
+
+				decoded, err := newAWSProviderConfig(&runtime.RawExtension{Raw: rawConfig})
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(decoded.Equal(providerConfig)).To(BeTrue())
+			})
+
+			It("preserves the instance requirements when injecting a failure domain", func() {
+				changed := providerConfig.AWS().InjectFailureDomain(resourcebuilder.AWSFailureDomain().WithAvailabilityZone(azUSEast1b).Build())
+				Expect(changed.InstanceRequirements()).To(Equal(providerConfig.AWS().InstanceRequirements()))
+			})
+
+			It("is equal to the same instance requirements in a different order", func() {
+				reordered, err := newAWSProviderConfig(configBuilder.WithInstanceRequirements(
+					`{"cpuManufacturers":["amd","intel"],"memoryMiB":{"min":16384},"vCpuCount":{"min":4,"max":8}}`,
+				).BuildRawExtension())
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(providerConfig.Equal(reordered)).To(BeTrue())
+			})
+
+			It("is not equal to different instance requirements", func() {
+				changed, err := newAWSProviderConfig(configBuilder.WithInstanceRequirements(
+					`{"vCpuCount":{"min":8,"max":16},"memoryMiB":{"min":16384},"cpuManufacturers":["intel","amd"]}`,
+				).BuildRawExtension())
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(providerConfig.Equal(changed)).To(BeFalse())
+				Expect(providerConfig.ChangedFields(changed)).To(ConsistOf("instanceRequirements"))
+			})
+
+			It("is not equal to a fixed instance type", func() {
+				fixed, err := newAWSProviderConfig(resourcebuilder.AWSProviderSpec().BuildRawExtension())
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(providerConfig.Equal(fixed)).To(BeFalse())
+				Expect(providerConfig.ChangedFields(fixed)).To(ConsistOf("instanceRequirements", "instanceType"))
+			})
+
+			It("returns an error when the instance requirements cannot be decoded", func() {
+				_, err := newAWSProviderConfig(configBuilder.WithInstanceRequirements(`{"vCpuCount":{"min":"four"}}`).BuildRawExtension())
+				Expect(err).To(MatchError(ContainSubstring("could not decode AWS provider spec: could not unmarshal provider spec")))
+			})
+		})
+
+		Context("with a different provider spec kind", func() {
+			It("returns an error", func() {
+				_, err := newAWSProviderConfig(&runtime.RawExtension{
+					Raw: []byte(`{"apiVersion":"machine.openshift.io/v1beta1","kind":"AzureMachineProviderSpec"}`),
+				})
+
+				Expect(err).To(MatchError("could not decode AWS provider spec: unexpected provider spec kind: expected AWSMachineProviderConfig, got AzureMachineProviderSpec"))
+			})
+		})
+
+		Context("with a nil provider spec", func() {
+			It("returns an error", func() {
+				_, err := newAWSProviderConfig(nil)
+
+				Expect(err).To(MatchError("could not decode AWS provider spec: provider spec is nil"))
+			})
+		})
 	})
 })
+
+// rootBlockDevice returns a root volume block device with the given volume size.
+func rootBlockDevice(size int64) machinev1beta1.BlockDeviceMappingSpec {
+	return machinev1beta1.BlockDeviceMappingSpec{
+		EBS: &machinev1beta1.EBSBlockDeviceSpec{
+			VolumeSize: pointer.Int64(size),
+			VolumeType: pointer.String("gp3"),
+		},
+	}
+}
+
+// testKMSKeyARN is the ARN of a KMS key used to encrypt EBS volumes.
+const testKMSKeyARN = "arn:aws:kms:us-east-1:111122223333:key/1234abcd-12ab-34cd-56ef-1234567890ab"
+
+// etcdBlockDevice returns a named block device, as may be used for etcd, with the given volume size.
+func etcdBlockDevice(size int64) machinev1beta1.BlockDeviceMappingSpec {
+	return machinev1beta1.BlockDeviceMappingSpec{
+		DeviceName: pointer.String("/dev/xvdb"),
+		EBS: &machinev1beta1.EBSBlockDeviceSpec{
+			VolumeSize: pointer.Int64(size),
+			VolumeType: pointer.String("gp3"),
+		},
+	}
+}
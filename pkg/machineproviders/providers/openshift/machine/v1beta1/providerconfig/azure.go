@@ -0,0 +1,261 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package providerconfig
+
+import (
+	"fmt"
+	"strings"
+
+	configv1 "github.com/openshift/api/config/v1"
+	machinev1 "github.com/openshift/api/machine/v1"
+	machinev1beta1 "github.com/openshift/api/machine/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// AzureProviderConfig holds the provider spec of an Azure Machine.
+// It allows external code to gather the stored config.
+type AzureProviderConfig struct {
+	providerConfig machinev1beta1.AzureMachineProviderSpec
+	diagnostics    *AzureDiagnostics
+}
+
+// Config returns the stored AzureMachineProviderSpec.
+func (a AzureProviderConfig) Config() machinev1beta1.AzureMachineProviderSpec {
+	return a.providerConfig
+}
+
+// Diagnostics returns the diagnostics settings, or nil when none are set.
+func (a AzureProviderConfig) Diagnostics() *AzureDiagnostics {
+	return a.diagnostics
+}
+
+// InjectFailureDomain returns a new AzureProviderConfig configured with the failure domain
+// information provided.
+// When the failure domain has no zone, the zone is left unchanged.
+func (a AzureProviderConfig) InjectFailureDomain(fd machinev1.AzureFailureDomain) AzureProviderConfig {
+	newAzureProviderConfig := AzureProviderConfig{
+		providerConfig: *a.providerConfig.DeepCopy(),
+		diagnostics:    a.diagnostics.DeepCopy(),
+	}
+
+	if fd.Zone != "" {
+		zone := fd.Zone
+		newAzureProviderConfig.providerConfig.Zone = &zone
+	}
+
+	return newAzureProviderConfig
+}
+
+// ExtractFailureDomain returns an AzureFailureDomain based on the failure domain
+// information stored within the AzureProviderConfig.
+func (a AzureProviderConfig) ExtractFailureDomain() machinev1.AzureFailureDomain {
+	zone := ""
+	if a.providerConfig.Zone != nil {
+		zone = *a.providerConfig.Zone
+	}
+
+	return machinev1.AzureFailureDomain{
+		Zone: zone,
+	}
+}
+
+// InjectUserDataSecret returns a new AzureProviderConfig configured to read the user data of the
+// virtual machine from the named secret.
+// The namespace of the secret, when set, is left unchanged.
+func (a AzureProviderConfig) InjectUserDataSecret(name string) AzureProviderConfig {
+	newAzureProviderConfig := AzureProviderConfig{
+		providerConfig: *a.providerConfig.DeepCopy(),
+		diagnostics:    a.diagnostics.DeepCopy(),
+	}
+
+	if newAzureProviderConfig.providerConfig.UserDataSecret == nil {
+		newAzureProviderConfig.providerConfig.UserDataSecret = &corev1.SecretReference{}
+	}
+
+	newAzureProviderConfig.providerConfig.UserDataSecret.Name = name
+
+	return newAzureProviderConfig
+}
+
+// ExtractUserDataSecret returns the name of the secret holding the user data of the virtual machine.
+func (a AzureProviderConfig) ExtractUserDataSecret() string {
+	if a.providerConfig.UserDataSecret == nil {
+		return ""
+	}
+
+	return a.providerConfig.UserDataSecret.Name
+}
+
+// InjectSubnet returns a new AzureProviderConfig configured to attach the virtual machine to the
+// named subnet, within the network resource group given.
+// When no network resource group is given, the network resource group is left unchanged.
+func (a AzureProviderConfig) InjectSubnet(subnet, networkResourceGroup string) AzureProviderConfig {
+	newAzureProviderConfig := AzureProviderConfig{
+		providerConfig: *a.providerConfig.DeepCopy(),
+		diagnostics:    a.diagnostics.DeepCopy(),
+	}
+
+	newAzureProviderConfig.providerConfig.Subnet = subnet
+
+	if networkResourceGroup != "" {
+		newAzureProviderConfig.providerConfig.NetworkResourceGroup = networkResourceGroup
+	}
+
+	return newAzureProviderConfig
+}
+
+// ExtractInstanceType returns the VM size from the AzureProviderConfig.
+func (a AzureProviderConfig) ExtractInstanceType() string {
+	return a.providerConfig.VMSize
+}
+
+// Equal compares the AzureProviderConfig with another AzureProviderConfig.
+// The type information of the provider specs is normalised, a managed identity given as the
+// resource ID of an identity within the resource group of the Machine is reduced to its name, and
+// diagnostics without boot diagnostics are dropped, before the comparison so that provider specs
+// that differ only in how they express the same configuration compare as equal.
+// Any other change to the managed identity, the boot diagnostics or accelerated networking is a
+// difference, so that Machines are replaced when these are changed.
+func (a AzureProviderConfig) Equal(other AzureProviderConfig) bool {
+	return equality.Semantic.DeepEqual(a.normalisedProviderSpec(), other.normalisedProviderSpec())
+}
+
+// UnmanagedFields returns the paths of the fields that differ between the AzureProviderConfigs
+// but where the difference is deliberately tolerated by Equal.
+// These are the type information of the provider specs, a managed identity given by name in one
+// provider spec and by resource ID in the other, and diagnostics that are omitted in one provider
+// spec and set without boot diagnostics in the other.
+func (a AzureProviderConfig) UnmanagedFields(other AzureProviderConfig) []string {
+	base := a.providerConfig
+	compare := other.providerConfig
+
+	var fields []string
+
+	if base.APIVersion != compare.APIVersion {
+		fields = append(fields, "apiVersion")
+	}
+
+	if base.Kind != compare.Kind {
+		fields = append(fields, "kind")
+	}
+
+	if base.ManagedIdentity != compare.ManagedIdentity &&
+		azureManagedIdentityName(base.ManagedIdentity, base.ResourceGroup) == azureManagedIdentityName(compare.ManagedIdentity, compare.ResourceGroup) {
+		fields = append(fields, "managedIdentity")
+	}
+
+	if (a.diagnostics == nil) != (other.diagnostics == nil) &&
+		normalisedAzureDiagnostics(a.diagnostics) == nil && normalisedAzureDiagnostics(other.diagnostics) == nil {
+		fields = append(fields, "diagnostics")
+	}
+
+	return fields
+}
+
+// ChangedFields returns the names of the top level fields of the provider spec that differ
+// between the AzureProviderConfigs.
+// The provider specs are normalised in the same way as within Equal, so differences that Equal
+// tolerates are not reported.
+func (a AzureProviderConfig) ChangedFields(other AzureProviderConfig) ([]string, error) {
+	return changedTopLevelFields(a.normalisedProviderSpec(), other.normalisedProviderSpec())
+}
+
+// normalisedProviderSpec returns the complete provider spec, including the diagnostics,
+// normalised so that it is suitable for comparison.
+func (a AzureProviderConfig) normalisedProviderSpec() azureProviderSpec {
+	out := a.providerConfig.DeepCopy()
+	out.TypeMeta = normalisedTypeMeta(azureProviderConfigKind)
+	out.ManagedIdentity = azureManagedIdentityName(out.ManagedIdentity, out.ResourceGroup)
+
+	return azureProviderSpec{
+		AzureMachineProviderSpec: *out,
+		Diagnostics:              normalisedAzureDiagnostics(a.diagnostics),
+	}
+}
+
+// rawProviderSpec returns the complete provider spec, including the diagnostics, as it should be
+// encoded into the raw provider spec.
+func (a AzureProviderConfig) rawProviderSpec() azureProviderSpec {
+	return azureProviderSpec{
+		AzureMachineProviderSpec: a.providerConfig,
+		Diagnostics:              a.diagnostics,
+	}
+}
+
+// ParseAzureUserAssignedIdentityID splits the resource ID of a user assigned managed identity, eg
+// /subscriptions/<subscription>/resourceGroups/<resource group>/providers/Microsoft.ManagedIdentity/userAssignedIdentities/<name>,
+// into the resource group and the name of the identity.
+// The boolean returned is false when the ID is not the resource ID of a user assigned managed identity.
+func ParseAzureUserAssignedIdentityID(id string) (string, string, bool) {
+	segments := strings.Split(id, "/")
+	if len(segments) != 9 || segments[0] != "" {
+		return "", "", false
+	}
+
+	if !strings.EqualFold(segments[1], "subscriptions") || segments[2] == "" ||
+		!strings.EqualFold(segments[3], "resourceGroups") || segments[4] == "" ||
+		!strings.EqualFold(segments[5], "providers") ||
+		!strings.EqualFold(segments[6], "Microsoft.ManagedIdentity") ||
+		!strings.EqualFold(segments[7], "userAssignedIdentities") || segments[8] == "" {
+		return "", "", false
+	}
+
+	return segments[4], segments[8], true
+}
+
+// azureManagedIdentityName returns the name of the managed identity when it is given as the resource
+// ID of an identity within the resource group of the Machine, as the Machine API resolves a bare name
+// to an identity within that resource group. Otherwise, the managed identity is returned unchanged.
+// Azure resource group names are case insensitive.
+func azureManagedIdentityName(identity, resourceGroup string) string {
+	identityResourceGroup, name, ok := ParseAzureUserAssignedIdentityID(identity)
+	if !ok || resourceGroup == "" || !strings.EqualFold(identityResourceGroup, resourceGroup) {
+		return identity
+	}
+
+	return name
+}
+
+// newAzureProviderConfig creates an AzureProviderConfig from the raw extension.
+// It should return an error if the provided RawExtension does not represent
+// an AzureMachineProviderSpec.
+func newAzureProviderConfig(raw *runtime.RawExtension) (ProviderConfig, error) {
+	spec := azureProviderSpec{}
+	if err := decodeProviderSpec(raw, azureProviderConfigKind, &spec); err != nil {
+		return nil, fmt.Errorf("could not decode Azure provider spec: %w", err)
+	}
+
+	return providerConfig{
+		platformType: configv1.AzurePlatformType,
+		raw:          raw.Raw,
+		azure: AzureProviderConfig{
+			providerConfig: spec.AzureMachineProviderSpec,
+			diagnostics:    spec.Diagnostics,
+		},
+	}, nil
+}
+
+// IsAzureStackHub determines whether the Infrastructure describes a cluster on Azure Stack Hub.
+// Azure Stack Hub uses the Azure provider spec, but has its own endpoints, has no availability zones, and supports
+// fewer features than the public Azure clouds.
+func IsAzureStackHub(infrastructure *configv1.Infrastructure) bool {
+	platformStatus := infrastructure.Status.PlatformStatus
+
+	return platformStatus != nil && platformStatus.Azure != nil && platformStatus.Azure.CloudName == configv1.AzureStackCloud
+}
@@ -0,0 +1,103 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package providerconfig
+
+import (
+	machinev1beta1 "github.com/openshift/api/machine/v1beta1"
+)
+
+// AzureBootDiagnosticsStorageAccountType is the type of storage account that stores the boot
+// diagnostics of an Azure Machine.
+type AzureBootDiagnosticsStorageAccountType string
+
+const (
+	// AzureManagedBootDiagnosticsStorage stores the boot diagnostics within a storage account
+	// managed by Azure.
+	AzureManagedBootDiagnosticsStorage AzureBootDiagnosticsStorageAccountType = "AzureManaged"
+
+	// CustomerManagedBootDiagnosticsStorage stores the boot diagnostics within a storage account
+	// provided by the user.
+	CustomerManagedBootDiagnosticsStorage AzureBootDiagnosticsStorageAccountType = "CustomerManaged"
+)
+
+// AzureDiagnostics describes the diagnostics settings of an Azure Machine.
+// The AzureMachineProviderSpec API does not yet include these settings, so they are
+// decoded from, and encoded into, the raw provider spec alongside the AzureMachineProviderSpec.
+type AzureDiagnostics struct {
+	// Boot configures the boot diagnostics of the virtual machine.
+	// When omitted, boot diagnostics are disabled.
+	Boot *AzureBootDiagnostics `json:"boot,omitempty"`
+}
+
+// AzureBootDiagnostics configures the storage of the boot diagnostics of an Azure Machine.
+type AzureBootDiagnostics struct {
+	// StorageAccountType is the type of storage account that stores the boot diagnostics.
+	StorageAccountType AzureBootDiagnosticsStorageAccountType `json:"storageAccountType,omitempty"`
+
+	// CustomerManaged is the storage account provided by the user, when the storage account
+	// type is CustomerManaged.
+	CustomerManaged *AzureCustomerManagedBootDiagnostics `json:"customerManaged,omitempty"`
+}
+
+// AzureCustomerManagedBootDiagnostics references the storage account provided by the user to
+// store the boot diagnostics.
+type AzureCustomerManagedBootDiagnostics struct {
+	// StorageAccountURI is the URI of the storage account, eg https://example.blob.core.windows.net/.
+	StorageAccountURI string `json:"storageAccountURI,omitempty"`
+}
+
+// azureProviderSpec is the complete Azure provider spec, including the fields that are not yet part
+// of the AzureMachineProviderSpec API.
+// It is used to decode and encode the raw provider spec so that these fields are preserved.
+type azureProviderSpec struct {
+	machinev1beta1.AzureMachineProviderSpec `json:",inline"`
+
+	// Diagnostics are the diagnostics settings of the virtual machine.
+	Diagnostics *AzureDiagnostics `json:"diagnostics,omitempty"`
+}
+
+// DeepCopy returns a deep copy of the AzureDiagnostics.
+func (d *AzureDiagnostics) DeepCopy() *AzureDiagnostics {
+	if d == nil {
+		return nil
+	}
+
+	out := &AzureDiagnostics{}
+
+	if d.Boot != nil {
+		out.Boot = &AzureBootDiagnostics{
+			StorageAccountType: d.Boot.StorageAccountType,
+		}
+
+		if d.Boot.CustomerManaged != nil {
+			customerManaged := *d.Boot.CustomerManaged
+			out.Boot.CustomerManaged = &customerManaged
+		}
+	}
+
+	return out
+}
+
+// normalisedAzureDiagnostics returns a copy of the diagnostics that is suitable for comparison.
+// Diagnostics without boot diagnostics have no effect on the virtual machine, so are dropped.
+func normalisedAzureDiagnostics(diagnostics *AzureDiagnostics) *AzureDiagnostics {
+	if diagnostics == nil || diagnostics.Boot == nil {
+		return nil
+	}
+
+	return diagnostics.DeepCopy()
+}
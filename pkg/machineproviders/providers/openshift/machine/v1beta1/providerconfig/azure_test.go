@@ -0,0 +1,245 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package providerconfig
+
+import (
+	"encoding/json"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	configv1 "github.com/openshift/api/config/v1"
+	machinev1 "github.com/openshift/api/machine/v1"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/test/resourcebuilder"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+var _ = Describe("Azure Provider Config", func() {
+	const (
+		identityID            = "/subscriptions/sub-12345678/resourceGroups/cluster-id-rg/providers/Microsoft.ManagedIdentity/userAssignedIdentities/cluster-id-identity"
+		azureManagedBoot      = `{"boot":{"storageAccountType":"AzureManaged"}}`
+		customerManagedBoot   = `{"boot":{"storageAccountType":"CustomerManaged","customerManaged":{"storageAccountURI":"https://example.blob.core.windows.net/"}}}`
+		emptyDiagnostics      = `{}`
+		otherResourceGroupID  = "/subscriptions/sub-12345678/resourceGroups/shared-rg/providers/Microsoft.ManagedIdentity/userAssignedIdentities/cluster-id-identity"
+		differentCaseIdentity = "/Subscriptions/sub-12345678/ResourceGroups/CLUSTER-ID-RG/providers/Microsoft.ManagedIdentity/userAssignedIdentities/cluster-id-identity"
+	)
+
+	newConfig := func(builder resourcebuilder.AzureProviderSpecBuilder) AzureProviderConfig {
+		providerConfig, err := newAzureProviderConfig(builder.BuildRawExtension())
+		Expect(err).ToNot(HaveOccurred())
+
+		return providerConfig.Azure()
+	}
+
+	Context("ExtractInstanceType", func() {
+		It("returns the configured VM size", func() {
+			Expect(newConfig(resourcebuilder.AzureProviderSpec().WithVMSize("Standard_D16s_v3")).ExtractInstanceType()).To(Equal("Standard_D16s_v3"))
+		})
+	})
+
+	Context("ExtractFailureDomain", func() {
+		It("returns the configured zone", func() {
+			Expect(newConfig(resourcebuilder.AzureProviderSpec().WithZone("1")).ExtractFailureDomain()).To(Equal(
+				resourcebuilder.AzureFailureDomain().WithZone("1").Build(),
+			))
+		})
+
+		It("returns an empty failure domain when no zone is configured", func() {
+			Expect(newConfig(resourcebuilder.AzureProviderSpec()).ExtractFailureDomain()).To(Equal(machinev1.AzureFailureDomain{}))
+		})
+	})
+
+	Context("when the failure domain is changed after initialisation", func() {
+		var providerConfig, changedProviderConfig AzureProviderConfig
+
+		BeforeEach(func() {
+			providerConfig = newConfig(resourcebuilder.AzureProviderSpec().WithZone("1").WithDiagnostics(azureManagedBoot))
+			changedProviderConfig = providerConfig.InjectFailureDomain(resourcebuilder.AzureFailureDomain().WithZone("2").Build())
+		})
+
+		It("stores the new zone in the provider config", func() {
+			Expect(changedProviderConfig.ExtractFailureDomain()).To(Equal(resourcebuilder.AzureFailureDomain().WithZone("2").Build()))
+		})
+
+		It("does not modify the original provider config", func() {
+			Expect(providerConfig.ExtractFailureDomain()).To(Equal(resourcebuilder.AzureFailureDomain().WithZone("1").Build()))
+		})
+
+		It("keeps the diagnostics", func() {
+			Expect(changedProviderConfig.Diagnostics()).To(Equal(providerConfig.Diagnostics()))
+		})
+
+		It("leaves the zone unchanged when the failure domain has no zone", func() {
+			Expect(providerConfig.InjectFailureDomain(machinev1.AzureFailureDomain{}).Equal(providerConfig)).To(BeTrue())
+		})
+	})
+
+	Context("Equal", func() {
+		type azureEqualTableInput struct {
+			baseBuilder    resourcebuilder.AzureProviderSpecBuilder
+			compareBuilder resourcebuilder.AzureProviderSpecBuilder
+			expectedEqual  bool
+
+			expectedUnmanagedFields []string
+			expectedChangedFields   []string
+		}
+
+		DescribeTable("should compare the managed identity, diagnostics and networking of the provider configs", func(in azureEqualTableInput) {
+			baseConfig := newConfig(in.baseBuilder)
+			compareConfig := newConfig(in.compareBuilder)
+
+			Expect(baseConfig.Equal(compareConfig)).To(Equal(in.expectedEqual))
+			Expect(compareConfig.Equal(baseConfig)).To(Equal(in.expectedEqual), "Equality should be symmetric")
+
+			Expect(baseConfig.UnmanagedFields(compareConfig)).To(ConsistOf(in.expectedUnmanagedFields))
+			Expect(compareConfig.UnmanagedFields(baseConfig)).To(ConsistOf(in.expectedUnmanagedFields), "Unmanaged fields should be symmetric")
+
+			Expect(baseConfig.ChangedFields(compareConfig)).To(ConsistOf(in.expectedChangedFields))
+		},
+			Entry("with matching configs using different API versions", azureEqualTableInput{
+				baseBuilder:             resourcebuilder.AzureProviderSpec().WithAPIVersion("azureproviderconfig.openshift.io/v1beta1"),
+				compareBuilder:          resourcebuilder.AzureProviderSpec(),
+				expectedEqual:           true,
+				expectedUnmanagedFields: []string{"apiVersion"},
+				expectedChangedFields:   []string{},
+			}),
+			Entry("with a managed identity given by name and by resource ID within the resource group", azureEqualTableInput{
+				baseBuilder:             resourcebuilder.AzureProviderSpec(),
+				compareBuilder:          resourcebuilder.AzureProviderSpec().WithManagedIdentity(identityID),
+				expectedEqual:           true,
+				expectedUnmanagedFields: []string{"managedIdentity"},
+				expectedChangedFields:   []string{},
+			}),
+			Entry("with a managed identity resource ID using a different case for the resource group", azureEqualTableInput{
+				baseBuilder:             resourcebuilder.AzureProviderSpec(),
+				compareBuilder:          resourcebuilder.AzureProviderSpec().WithManagedIdentity(differentCaseIdentity),
+				expectedEqual:           true,
+				expectedUnmanagedFields: []string{"managedIdentity"},
+				expectedChangedFields:   []string{},
+			}),
+			Entry("with a managed identity within a different resource group", azureEqualTableInput{
+				baseBuilder:             resourcebuilder.AzureProviderSpec(),
+				compareBuilder:          resourcebuilder.AzureProviderSpec().WithManagedIdentity(otherResourceGroupID),
+				expectedEqual:           false,
+				expectedUnmanagedFields: []string{},
+				expectedChangedFields:   []string{"managedIdentity"},
+			}),
+			Entry("with a different managed identity", azureEqualTableInput{
+				baseBuilder:             resourcebuilder.AzureProviderSpec(),
+				compareBuilder:          resourcebuilder.AzureProviderSpec().WithManagedIdentity("compliance-identity"),
+				expectedEqual:           false,
+				expectedUnmanagedFields: []string{},
+				expectedChangedFields:   []string{"managedIdentity"},
+			}),
+			Entry("with omitted and empty diagnostics", azureEqualTableInput{
+				baseBuilder:             resourcebuilder.AzureProviderSpec(),
+				compareBuilder:          resourcebuilder.AzureProviderSpec().WithDiagnostics(emptyDiagnostics),
+				expectedEqual:           true,
+				expectedUnmanagedFields: []string{"diagnostics"},
+				expectedChangedFields:   []string{},
+			}),
+			Entry("with boot diagnostics enabled", azureEqualTableInput{
+				baseBuilder:             resourcebuilder.AzureProviderSpec(),
+				compareBuilder:          resourcebuilder.AzureProviderSpec().WithDiagnostics(azureManagedBoot),
+				expectedEqual:           false,
+				expectedUnmanagedFields: []string{},
+				expectedChangedFields:   []string{"diagnostics"},
+			}),
+			Entry("with a different boot diagnostics storage account type", azureEqualTableInput{
+				baseBuilder:             resourcebuilder.AzureProviderSpec().WithDiagnostics(azureManagedBoot),
+				compareBuilder:          resourcebuilder.AzureProviderSpec().WithDiagnostics(customerManagedBoot),
+				expectedEqual:           false,
+				expectedUnmanagedFields: []string{},
+				expectedChangedFields:   []string{"diagnostics"},
+			}),
+			Entry("with accelerated networking enabled", azureEqualTableInput{
+				baseBuilder:             resourcebuilder.AzureProviderSpec(),
+				compareBuilder:          resourcebuilder.AzureProviderSpec().WithAcceleratedNetworking(true),
+				expectedEqual:           false,
+				expectedUnmanagedFields: []string{},
+				expectedChangedFields:   []string{"acceleratedNetworking"},
+			}),
+		)
+	})
+
+	Context("ParseAzureUserAssignedIdentityID", func() {
+		It("returns the resource group and name of a user assigned identity", func() {
+			resourceGroup, name, ok := ParseAzureUserAssignedIdentityID(identityID)
+			Expect(ok).To(BeTrue())
+			Expect(resourceGroup).To(Equal("cluster-id-rg"))
+			Expect(name).To(Equal("cluster-id-identity"))
+		})
+
+		It("rejects a bare name", func() {
+			_, _, ok := ParseAzureUserAssignedIdentityID("cluster-id-identity")
+			Expect(ok).To(BeFalse())
+		})
+
+		It("rejects the resource ID of a different resource type", func() {
+			_, _, ok := ParseAzureUserAssignedIdentityID("/subscriptions/sub-12345678/resourceGroups/cluster-id-rg/providers/Microsoft.Compute/virtualMachines/master-0")
+			Expect(ok).To(BeFalse())
+		})
+	})
+
+	Context("newAzureProviderConfig", func() {
+		var providerConfig ProviderConfig
+
+		BeforeEach(func() {
+			var err error
+			providerConfig, err = newAzureProviderConfig(resourcebuilder.AzureProviderSpec().WithDiagnostics(customerManagedBoot).BuildRawExtension())
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("sets the type to Azure", func() {
+			Expect(providerConfig.Type()).To(Equal(configv1.AzurePlatformType))
+		})
+
+		It("returns the correct Azure config", func() {
+			Expect(providerConfig.Azure().Config()).To(Equal(*resourcebuilder.AzureProviderSpec().Build()))
+		})
+
+		It("returns the diagnostics", func() {
+			Expect(providerConfig.Azure().Diagnostics()).To(Equal(&AzureDiagnostics{
+				Boot: &AzureBootDiagnostics{
+					StorageAccountType: CustomerManagedBootDiagnosticsStorage,
+					CustomerManaged: &AzureCustomerManagedBootDiagnostics{
+						StorageAccountURI: "https://example.blob.core.windows.net/",
+					},
+				},
+			}))
+		})
+
+		It("preserves the diagnostics within the raw config", func() {
+			rawConfig, err := providerConfig.RawConfig()
+			Expect(err).ToNot(HaveOccurred())
+
+			fields := map[string]json.RawMessage{}
+			Expect(json.Unmarshal(rawConfig, &fields)).To(Succeed())
+			Expect(fields).To(HaveKeyWithValue("diagnostics", MatchJSON(customerManagedBoot)))
+		})
+
+		Context("with a different provider spec kind", func() {
+			It("returns an error", func() {
+				_, err := newAzureProviderConfig(&runtime.RawExtension{
+					Raw: []byte(`{"apiVersion":"machine.openshift.io/v1beta1","kind":"GCPMachineProviderSpec"}`),
+				})
+
+				Expect(err).To(MatchError("could not decode Azure provider spec: unexpected provider spec kind: expected AzureMachineProviderSpec, got GCPMachineProviderSpec"))
+			})
+		})
+	})
+})
@@ -0,0 +1,185 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package providerconfig
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	configv1 "github.com/openshift/api/config/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+const (
+	// bareMetalHostSelectorField is the name of the host selector field of the bare metal provider spec.
+	bareMetalHostSelectorField = "hostSelector"
+
+	// bareMetalImageField is the name of the image field of the bare metal provider spec.
+	bareMetalImageField = "image"
+)
+
+// BareMetalProviderConfig holds the provider spec of a bare metal (Metal3) Machine.
+// The Metal3 API is not a dependency of the operator, so the provider spec is passed through
+// unchanged. Only the host selector and the image, which determine the host that is provisioned,
+// and what is provisioned onto it, are decoded and compared.
+type BareMetalProviderConfig struct {
+	providerConfig bareMetalProviderSpec
+
+	// fields holds every top level field of the provider spec, so that the provider spec can be
+	// passed through without losing the fields that are not decoded.
+	fields map[string]json.RawMessage
+}
+
+// bareMetalProviderSpec is the subset of the Metal3 BareMetalMachineProviderSpec that is compared.
+type bareMetalProviderSpec struct {
+	metav1.TypeMeta `json:",inline"`
+
+	// Image is the image provisioned onto the host.
+	Image BareMetalImage `json:"image"`
+
+	// HostSelector selects the BareMetalHost that the Machine is provisioned onto.
+	HostSelector BareMetalHostSelector `json:"hostSelector,omitempty"`
+}
+
+// BareMetalImage is the image provisioned onto a bare metal host.
+type BareMetalImage struct {
+	// URL is the location of the image.
+	URL string `json:"url"`
+
+	// Checksum is the checksum of the image, or the location of the checksum.
+	Checksum string `json:"checksum"`
+
+	// ChecksumType is the algorithm of the checksum.
+	ChecksumType string `json:"checksumType,omitempty"`
+
+	// DiskFormat is the format of the image.
+	DiskFormat string `json:"format,omitempty"`
+}
+
+// BareMetalHostSelector selects the BareMetalHost that a Machine is provisioned onto, by the labels of the host.
+type BareMetalHostSelector struct {
+	// MatchLabels are labels that the host must have, with matching values.
+	MatchLabels map[string]string `json:"matchLabels,omitempty"`
+
+	// MatchExpressions are requirements that the labels of the host must meet.
+	MatchExpressions []BareMetalHostSelectorRequirement `json:"matchExpressions,omitempty"`
+}
+
+// BareMetalHostSelectorRequirement is a requirement that the labels of a host must meet.
+type BareMetalHostSelectorRequirement struct {
+	// Key is the label key that the requirement applies to.
+	Key string `json:"key"`
+
+	// Operator is the relationship between the label and the values, for example in.
+	Operator string `json:"operator"`
+
+	// Values are the values that the operator is applied with.
+	Values []string `json:"values"`
+}
+
+// ExtractImage returns the image provisioned onto the host.
+func (b BareMetalProviderConfig) ExtractImage() BareMetalImage {
+	return b.providerConfig.Image
+}
+
+// ExtractHostSelector returns the selector of the host that the Machine is provisioned onto.
+func (b BareMetalProviderConfig) ExtractHostSelector() BareMetalHostSelector {
+	return b.providerConfig.HostSelector
+}
+
+// Equal compares the BareMetalProviderConfig with another BareMetalProviderConfig.
+// Only the host selector and the image are compared.
+func (b BareMetalProviderConfig) Equal(other BareMetalProviderConfig) bool {
+	return equality.Semantic.DeepEqual(normalisedBareMetalProviderSpec(b.providerConfig), normalisedBareMetalProviderSpec(other.providerConfig))
+}
+
+// UnmanagedFields returns the names of the top level fields that differ between the BareMetalProviderConfigs
+// but where the difference is deliberately tolerated by Equal.
+// These are all of the fields other than the host selector and the image, including the type information.
+func (b BareMetalProviderConfig) UnmanagedFields(other BareMetalProviderConfig) []string {
+	fields := []string{}
+
+	for name, value := range b.fields {
+		if otherValue, ok := other.fields[name]; !ok || string(value) != string(otherValue) {
+			fields = append(fields, name)
+		}
+	}
+
+	for name := range other.fields {
+		if _, ok := b.fields[name]; !ok {
+			fields = append(fields, name)
+		}
+	}
+
+	unmanaged := []string{}
+
+	for _, name := range fields {
+		if name != bareMetalHostSelectorField && name != bareMetalImageField {
+			unmanaged = append(unmanaged, name)
+		}
+	}
+
+	sort.Strings(unmanaged)
+
+	return unmanaged
+}
+
+// ChangedFields returns the names of the top level fields of the provider spec that differ
+// between the BareMetalProviderConfigs.
+// As within Equal, only the host selector and the image are compared.
+func (b BareMetalProviderConfig) ChangedFields(other BareMetalProviderConfig) ([]string, error) {
+	return changedTopLevelFields(normalisedBareMetalProviderSpec(b.providerConfig), normalisedBareMetalProviderSpec(other.providerConfig))
+}
+
+// normalisedBareMetalProviderSpec returns a copy of the compared subset of the provider spec that is
+// suitable for comparison. The type information is normalised to the only API version that serves
+// the bare metal provider spec.
+func normalisedBareMetalProviderSpec(spec bareMetalProviderSpec) bareMetalProviderSpec {
+	spec.TypeMeta = metav1.TypeMeta{
+		APIVersion: bareMetalAPIVersion,
+		Kind:       bareMetalProviderConfigKind,
+	}
+
+	return spec
+}
+
+// newBareMetalProviderConfig creates a BareMetalProviderConfig from the raw extension.
+// It should return an error if the provided RawExtension does not represent
+// a BareMetalMachineProviderSpec.
+func newBareMetalProviderConfig(raw *runtime.RawExtension) (ProviderConfig, error) {
+	bareMetalMachineProviderSpec := bareMetalProviderSpec{}
+	if err := decodeProviderSpec(raw, bareMetalProviderConfigKind, &bareMetalMachineProviderSpec); err != nil {
+		return nil, fmt.Errorf("could not decode bare metal provider spec: %w", err)
+	}
+
+	fields := map[string]json.RawMessage{}
+	if err := json.Unmarshal(raw.Raw, &fields); err != nil {
+		return nil, fmt.Errorf("could not decode bare metal provider spec: %w", err)
+	}
+
+	return providerConfig{
+		platformType: configv1.BareMetalPlatformType,
+		raw:          raw.Raw,
+		bareMetal: BareMetalProviderConfig{
+			providerConfig: bareMetalMachineProviderSpec,
+			fields:         fields,
+		},
+	}, nil
+}
@@ -0,0 +1,154 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package providerconfig
+
+import (
+	"encoding/json"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	configv1 "github.com/openshift/api/config/v1"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/test/resourcebuilder"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+var _ = Describe("BareMetal Provider Config", func() {
+	bareMetalConfig := func(builder resourcebuilder.BareMetalProviderSpecBuilder) BareMetalProviderConfig {
+		providerConfig, err := newBareMetalProviderConfig(builder.BuildRawExtension())
+		Expect(err).ToNot(HaveOccurred())
+
+		return providerConfig.BareMetal()
+	}
+
+	Context("ExtractImage and ExtractHostSelector", func() {
+		It("return the configured image and host selector", func() {
+			config := bareMetalConfig(resourcebuilder.BareMetalProviderSpec().WithImageURL("http://images/rhcos.qcow2").WithHostSelector(map[string]string{"rack": "r1"}))
+
+			Expect(config.ExtractImage()).To(Equal(BareMetalImage{
+				URL:      "http://images/rhcos.qcow2",
+				Checksum: "http://images/rhcos.qcow2/cached.md5sum",
+			}))
+			Expect(config.ExtractHostSelector()).To(Equal(BareMetalHostSelector{
+				MatchLabels: map[string]string{"rack": "r1"},
+			}))
+		})
+	})
+
+	Context("Equal", func() {
+		type bareMetalEqualTableInput struct {
+			baseConfig    resourcebuilder.BareMetalProviderSpecBuilder
+			compareConfig resourcebuilder.BareMetalProviderSpecBuilder
+			expectedEqual bool
+
+			expectedUnmanagedFields []string
+			expectedChangedFields   []string
+		}
+
+		DescribeTable("should compare the provider configs", func(in bareMetalEqualTableInput) {
+			baseConfig := bareMetalConfig(in.baseConfig)
+			compareConfig := bareMetalConfig(in.compareConfig)
+
+			Expect(baseConfig.Equal(compareConfig)).To(Equal(in.expectedEqual))
+			Expect(compareConfig.Equal(baseConfig)).To(Equal(in.expectedEqual), "Equality should be symmetric")
+
+			Expect(baseConfig.UnmanagedFields(compareConfig)).To(ConsistOf(in.expectedUnmanagedFields))
+			Expect(compareConfig.UnmanagedFields(baseConfig)).To(ConsistOf(in.expectedUnmanagedFields), "Unmanaged fields should be symmetric")
+
+			Expect(baseConfig.ChangedFields(compareConfig)).To(ConsistOf(in.expectedChangedFields))
+		},
+			Entry("with matching configs", bareMetalEqualTableInput{
+				baseConfig:              resourcebuilder.BareMetalProviderSpec(),
+				compareConfig:           resourcebuilder.BareMetalProviderSpec(),
+				expectedEqual:           true,
+				expectedUnmanagedFields: []string{},
+				expectedChangedFields:   []string{},
+			}),
+			Entry("with a different image", bareMetalEqualTableInput{
+				baseConfig:              resourcebuilder.BareMetalProviderSpec(),
+				compareConfig:           resourcebuilder.BareMetalProviderSpec().WithImageURL("http://images/rhcos-new.qcow2"),
+				expectedEqual:           false,
+				expectedUnmanagedFields: []string{},
+				expectedChangedFields:   []string{"image"},
+			}),
+			Entry("with a different host selector", bareMetalEqualTableInput{
+				baseConfig:              resourcebuilder.BareMetalProviderSpec(),
+				compareConfig:           resourcebuilder.BareMetalProviderSpec().WithHostSelector(map[string]string{"rack": "r1"}),
+				expectedEqual:           false,
+				expectedUnmanagedFields: []string{},
+				expectedChangedFields:   []string{"hostSelector"},
+			}),
+			Entry("with a different user data secret", bareMetalEqualTableInput{
+				baseConfig:              resourcebuilder.BareMetalProviderSpec(),
+				compareConfig:           resourcebuilder.BareMetalProviderSpec().WithUserData("master-user-data"),
+				expectedEqual:           true,
+				expectedUnmanagedFields: []string{"userData"},
+				expectedChangedFields:   []string{},
+			}),
+			Entry("with a different API version", bareMetalEqualTableInput{
+				baseConfig:              resourcebuilder.BareMetalProviderSpec(),
+				compareConfig:           resourcebuilder.BareMetalProviderSpec().WithAPIVersion(""),
+				expectedEqual:           true,
+				expectedUnmanagedFields: []string{"apiVersion"},
+				expectedChangedFields:   []string{},
+			}),
+		)
+	})
+
+	Context("newBareMetalProviderConfig", func() {
+		var providerConfig ProviderConfig
+		var rawConfig *runtime.RawExtension
+
+		BeforeEach(func() {
+			rawConfig = resourcebuilder.BareMetalProviderSpec().BuildRawExtension()
+
+			var err error
+			providerConfig, err = newBareMetalProviderConfig(rawConfig)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("sets the type to BareMetal", func() {
+			Expect(providerConfig.Type()).To(Equal(configv1.BareMetalPlatformType))
+		})
+
+		It("does not extract a failure domain", func() {
+			Expect(providerConfig.ExtractFailureDomain()).To(BeNil())
+		})
+
+		It("passes every field of the provider spec through the raw config", func() {
+			raw, err := providerConfig.RawConfig()
+			Expect(err).ToNot(HaveOccurred())
+
+			var fields, expectedFields map[string]interface{}
+			Expect(json.Unmarshal(raw, &fields)).To(Succeed())
+			Expect(json.Unmarshal(rawConfig.Raw, &expectedFields)).To(Succeed())
+
+			Expect(fields).To(Equal(expectedFields))
+			Expect(fields).To(HaveKey("customDeploy"))
+		})
+
+		Context("with a provider spec in the Machine API version", func() {
+			It("returns an error", func() {
+				_, err := newBareMetalProviderConfig(&runtime.RawExtension{
+					Raw: []byte(`{"apiVersion":"machine.openshift.io/v1beta1","kind":"BareMetalMachineProviderSpec"}`),
+				})
+
+				Expect(err).To(MatchError("could not decode bare metal provider spec: unknown provider spec API version: machine.openshift.io/v1beta1 does not serve BareMetalMachineProviderSpec"))
+			})
+		})
+	})
+})
@@ -0,0 +1,116 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package providerconfig
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	machinev1 "github.com/openshift/api/machine/v1"
+	machinev1beta1 "github.com/openshift/api/machine/v1beta1"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/test/resourcebuilder"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// benchmarkProviderSpecs are the raw provider specs, by platform, that the comparison benchmarks compare.
+var benchmarkProviderSpecs = []struct {
+	name string
+	raw  *runtime.RawExtension
+}{
+	{name: "AWS", raw: resourcebuilder.AWSProviderSpec().BuildRawExtension()},
+	{name: "Azure", raw: resourcebuilder.AzureProviderSpec().BuildRawExtension()},
+	{name: "GCP", raw: resourcebuilder.GCPProviderSpec().BuildRawExtension()},
+	{name: "VSphere", raw: resourcebuilder.VSphereProviderSpec().BuildRawExtension()},
+}
+
+// benchmarkComparison runs the comparison against pairs of provider configs for each platform.
+// The identical case compares provider configs decoded from identical raw provider specs, which short-circuits.
+// The equivalent case compares provider configs decoded from raw provider specs that differ only in their
+// formatting, which requires the provider specs to be normalised and compared.
+func benchmarkComparison(b *testing.B, compare func(base, other ProviderConfig) error) {
+	for _, spec := range benchmarkProviderSpecs {
+		indented := &bytes.Buffer{}
+		if err := json.Indent(indented, spec.raw.Raw, "", "  "); err != nil {
+			b.Fatal(err)
+		}
+
+		cases := []struct {
+			name  string
+			other *runtime.RawExtension
+		}{
+			{name: "identical", other: &runtime.RawExtension{Raw: append([]byte{}, spec.raw.Raw...)}},
+			{name: "equivalent", other: &runtime.RawExtension{Raw: indented.Bytes()}},
+		}
+
+		for _, c := range cases {
+			base := newBenchmarkProviderConfig(b, spec.raw)
+			other := newBenchmarkProviderConfig(b, c.other)
+
+			b.Run(spec.name+"/"+c.name, func(b *testing.B) {
+				b.ReportAllocs()
+
+				for i := 0; i < b.N; i++ {
+					if err := compare(base, other); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		}
+	}
+}
+
+// newBenchmarkProviderConfig creates a ProviderConfig from the raw provider spec, failing the benchmark on error.
+func newBenchmarkProviderConfig(b *testing.B, raw *runtime.RawExtension) ProviderConfig {
+	pc, err := NewProviderConfig(machinev1.OpenShiftMachineV1Beta1MachineTemplate{
+		Spec: machinev1beta1.MachineSpec{
+			ProviderSpec: machinev1beta1.ProviderSpec{
+				Value: raw,
+			},
+		},
+	})
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	return pc
+}
+
+func BenchmarkEqual(b *testing.B) {
+	benchmarkComparison(b, func(base, other ProviderConfig) error {
+		equal, err := base.Equal(other)
+		if err == nil && !equal {
+			b.Fatal("expected provider configs to be equal")
+		}
+
+		return err
+	})
+}
+
+func BenchmarkUnmanagedFields(b *testing.B) {
+	benchmarkComparison(b, func(base, other ProviderConfig) error {
+		_, err := base.UnmanagedFields(other)
+		return err
+	})
+}
+
+func BenchmarkChangedFields(b *testing.B) {
+	benchmarkComparison(b, func(base, other ProviderConfig) error {
+		_, err := base.ChangedFields(other)
+		return err
+	})
+}
@@ -0,0 +1,193 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package providerconfig
+
+import (
+	"encoding/json"
+	"fmt"
+
+	configv1 "github.com/openshift/api/config/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+const (
+	// equinixMetalMetroField is the name of the metro field of the Equinix Metal provider spec.
+	equinixMetalMetroField = "metro"
+
+	// equinixMetalFacilityField is the name of the facility field of the Equinix Metal provider spec.
+	equinixMetalFacilityField = "facility"
+)
+
+// EquinixMetalProviderConfig holds the provider spec of an Equinix Metal (formerly Packet) Machine.
+// The Equinix Metal API is not a dependency of the operator, so the provider spec is passed through
+// unchanged. It allows external code to extract and inject the location of the device, its metro and
+// facility, which is the Equinix Metal analogue of a failure domain.
+// The ControlPlaneMachineSet API has no Equinix Metal failure domains, so the location is not
+// extracted or injected as a failure domain.
+type EquinixMetalProviderConfig struct {
+	providerConfig equinixMetalProviderSpec
+
+	// fields holds every top level field of the provider spec, so that the provider spec can be
+	// passed through, and compared, without losing the fields that are not decoded.
+	fields map[string]interface{}
+}
+
+// equinixMetalProviderSpec is the subset of the PacketMachineProviderConfig that the operator reads.
+type equinixMetalProviderSpec struct {
+	metav1.TypeMeta `json:",inline"`
+
+	// MachineType is the plan, the hardware configuration, of the device.
+	MachineType string `json:"machineType,omitempty"`
+
+	// Metro is the metro in which the device is created.
+	Metro string `json:"metro,omitempty"`
+
+	// Facility is the facility, within the metro, in which the device is created.
+	Facility string `json:"facility,omitempty"`
+}
+
+// EquinixMetalLocation is the location of an Equinix Metal device.
+// A metro contains one or more facilities. Either may be omitted, in which case Equinix Metal
+// chooses the location within the remaining constraints.
+type EquinixMetalLocation struct {
+	// Metro is the metro in which the device is created.
+	Metro string
+
+	// Facility is the facility, within the metro, in which the device is created.
+	Facility string
+}
+
+// String returns a string representation of the location, for example "da/da11".
+func (l EquinixMetalLocation) String() string {
+	switch {
+	case l.Metro == "":
+		return l.Facility
+	case l.Facility == "":
+		return l.Metro
+	default:
+		return fmt.Sprintf("%s/%s", l.Metro, l.Facility)
+	}
+}
+
+// InjectLocation returns a new EquinixMetalProviderConfig configured to create the device within the
+// given location. A location with an empty metro or facility removes that field from the provider spec.
+func (e EquinixMetalProviderConfig) InjectLocation(location EquinixMetalLocation) EquinixMetalProviderConfig {
+	newEquinixMetalProviderConfig := e
+
+	newEquinixMetalProviderConfig.providerConfig.Metro = location.Metro
+	newEquinixMetalProviderConfig.providerConfig.Facility = location.Facility
+
+	fields := runtime.DeepCopyJSON(e.fields)
+	setOrRemoveField(fields, equinixMetalMetroField, location.Metro)
+	setOrRemoveField(fields, equinixMetalFacilityField, location.Facility)
+
+	newEquinixMetalProviderConfig.fields = fields
+
+	return newEquinixMetalProviderConfig
+}
+
+// ExtractLocation returns the location, the metro and facility, in which the device is created.
+func (e EquinixMetalProviderConfig) ExtractLocation() EquinixMetalLocation {
+	return EquinixMetalLocation{
+		Metro:    e.providerConfig.Metro,
+		Facility: e.providerConfig.Facility,
+	}
+}
+
+// ExtractMachineType returns the plan of the device.
+func (e EquinixMetalProviderConfig) ExtractMachineType() string {
+	return e.providerConfig.MachineType
+}
+
+// Equal compares the EquinixMetalProviderConfig with another EquinixMetalProviderConfig.
+// Every field of the provider spec is compared, other than the type information, so that a
+// provider spec that omits it compares as equal to one that sets it.
+func (e EquinixMetalProviderConfig) Equal(other EquinixMetalProviderConfig) bool {
+	return equality.Semantic.DeepEqual(normalisedEquinixMetalFields(e.fields), normalisedEquinixMetalFields(other.fields))
+}
+
+// UnmanagedFields returns the paths of the fields that differ between the EquinixMetalProviderConfigs
+// but where the difference is deliberately tolerated by Equal.
+// These are the type information of the provider specs.
+func (e EquinixMetalProviderConfig) UnmanagedFields(other EquinixMetalProviderConfig) []string {
+	var fields []string
+
+	if e.providerConfig.APIVersion != other.providerConfig.APIVersion {
+		fields = append(fields, "apiVersion")
+	}
+
+	if e.providerConfig.Kind != other.providerConfig.Kind {
+		fields = append(fields, "kind")
+	}
+
+	return fields
+}
+
+// ChangedFields returns the names of the top level fields of the provider spec that differ
+// between the EquinixMetalProviderConfigs.
+// The provider specs are normalised in the same way as within Equal, so differences that Equal
+// tolerates are not reported.
+func (e EquinixMetalProviderConfig) ChangedFields(other EquinixMetalProviderConfig) ([]string, error) {
+	return changedTopLevelFields(normalisedEquinixMetalFields(e.fields), normalisedEquinixMetalFields(other.fields))
+}
+
+// normalisedEquinixMetalFields returns a copy of the fields of the provider spec that is suitable for comparison.
+// The type information is removed, as the schema of the provider spec is identical across the API versions that
+// serve it.
+func normalisedEquinixMetalFields(fields map[string]interface{}) map[string]interface{} {
+	out := runtime.DeepCopyJSON(fields)
+	delete(out, "apiVersion")
+	delete(out, "kind")
+
+	return out
+}
+
+// setOrRemoveField sets the named field to the value, or removes the field when the value is empty.
+func setOrRemoveField(fields map[string]interface{}, name, value string) {
+	if value == "" {
+		delete(fields, name)
+		return
+	}
+
+	fields[name] = value
+}
+
+// newEquinixMetalProviderConfig creates an EquinixMetalProviderConfig from the raw extension.
+// It should return an error if the provided RawExtension does not represent
+// a PacketMachineProviderConfig.
+func newEquinixMetalProviderConfig(raw *runtime.RawExtension) (ProviderConfig, error) {
+	equinixMetalMachineProviderSpec := equinixMetalProviderSpec{}
+	if err := decodeProviderSpec(raw, equinixMetalProviderConfigKind, &equinixMetalMachineProviderSpec); err != nil {
+		return nil, fmt.Errorf("could not decode Equinix Metal provider spec: %w", err)
+	}
+
+	fields := map[string]interface{}{}
+	if err := json.Unmarshal(raw.Raw, &fields); err != nil {
+		return nil, fmt.Errorf("could not decode Equinix Metal provider spec: %w", err)
+	}
+
+	return providerConfig{
+		platformType: configv1.EquinixMetalPlatformType,
+		raw:          raw.Raw,
+		equinixMetal: EquinixMetalProviderConfig{
+			providerConfig: equinixMetalMachineProviderSpec,
+			fields:         fields,
+		},
+	}, nil
+}
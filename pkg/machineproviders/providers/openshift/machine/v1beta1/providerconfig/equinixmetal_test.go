@@ -0,0 +1,179 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package providerconfig
+
+import (
+	"encoding/json"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	configv1 "github.com/openshift/api/config/v1"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/test/resourcebuilder"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+var _ = Describe("Equinix Metal Provider Config", func() {
+	equinixMetalConfig := func(builder resourcebuilder.EquinixMetalProviderSpecBuilder) EquinixMetalProviderConfig {
+		providerConfig, err := newEquinixMetalProviderConfig(builder.BuildRawExtension())
+		Expect(err).ToNot(HaveOccurred())
+
+		return providerConfig.EquinixMetal()
+	}
+
+	Context("ExtractLocation", func() {
+		It("returns the configured metro and facility", func() {
+			config := equinixMetalConfig(resourcebuilder.EquinixMetalProviderSpec().WithMetro("sv").WithFacility("sv15"))
+
+			Expect(config.ExtractLocation()).To(Equal(EquinixMetalLocation{Metro: "sv", Facility: "sv15"}))
+			Expect(config.ExtractLocation().String()).To(Equal("sv/sv15"))
+		})
+
+		It("returns only the metro when no facility is configured", func() {
+			config := equinixMetalConfig(resourcebuilder.EquinixMetalProviderSpec().WithFacility(""))
+
+			Expect(config.ExtractLocation()).To(Equal(EquinixMetalLocation{Metro: "da"}))
+			Expect(config.ExtractLocation().String()).To(Equal("da"))
+		})
+	})
+
+	Context("InjectLocation", func() {
+		It("replaces the metro and facility", func() {
+			config := equinixMetalConfig(resourcebuilder.EquinixMetalProviderSpec())
+			injected := config.InjectLocation(EquinixMetalLocation{Metro: "sv", Facility: "sv15"})
+
+			Expect(injected.ExtractLocation()).To(Equal(EquinixMetalLocation{Metro: "sv", Facility: "sv15"}))
+			Expect(injected.Equal(equinixMetalConfig(resourcebuilder.EquinixMetalProviderSpec().WithMetro("sv").WithFacility("sv15")))).To(BeTrue())
+			Expect(config.ExtractLocation()).To(Equal(EquinixMetalLocation{Metro: "da", Facility: "da11"}), "The original config should not be modified")
+		})
+
+		It("removes the facility when it is empty", func() {
+			config := equinixMetalConfig(resourcebuilder.EquinixMetalProviderSpec())
+			injected := config.InjectLocation(EquinixMetalLocation{Metro: "da"})
+
+			Expect(injected.fields).ToNot(HaveKey("facility"))
+			Expect(injected.Equal(equinixMetalConfig(resourcebuilder.EquinixMetalProviderSpec().WithFacility("")))).To(BeTrue())
+		})
+	})
+
+	Context("Equal", func() {
+		type equinixMetalEqualTableInput struct {
+			baseConfig    resourcebuilder.EquinixMetalProviderSpecBuilder
+			compareConfig resourcebuilder.EquinixMetalProviderSpecBuilder
+			expectedEqual bool
+
+			expectedUnmanagedFields []string
+			expectedChangedFields   []string
+		}
+
+		DescribeTable("should compare the provider configs", func(in equinixMetalEqualTableInput) {
+			baseConfig := equinixMetalConfig(in.baseConfig)
+			compareConfig := equinixMetalConfig(in.compareConfig)
+
+			Expect(baseConfig.Equal(compareConfig)).To(Equal(in.expectedEqual))
+			Expect(compareConfig.Equal(baseConfig)).To(Equal(in.expectedEqual), "Equality should be symmetric")
+
+			Expect(baseConfig.UnmanagedFields(compareConfig)).To(ConsistOf(in.expectedUnmanagedFields))
+			Expect(compareConfig.UnmanagedFields(baseConfig)).To(ConsistOf(in.expectedUnmanagedFields), "Unmanaged fields should be symmetric")
+
+			Expect(baseConfig.ChangedFields(compareConfig)).To(ConsistOf(in.expectedChangedFields))
+		},
+			Entry("with matching configs", equinixMetalEqualTableInput{
+				baseConfig:              resourcebuilder.EquinixMetalProviderSpec(),
+				compareConfig:           resourcebuilder.EquinixMetalProviderSpec(),
+				expectedEqual:           true,
+				expectedUnmanagedFields: []string{},
+				expectedChangedFields:   []string{},
+			}),
+			Entry("with a different machine type", equinixMetalEqualTableInput{
+				baseConfig:              resourcebuilder.EquinixMetalProviderSpec(),
+				compareConfig:           resourcebuilder.EquinixMetalProviderSpec().WithMachineType("m3.large.x86"),
+				expectedEqual:           false,
+				expectedUnmanagedFields: []string{},
+				expectedChangedFields:   []string{"machineType"},
+			}),
+			Entry("with a different metro", equinixMetalEqualTableInput{
+				baseConfig:              resourcebuilder.EquinixMetalProviderSpec(),
+				compareConfig:           resourcebuilder.EquinixMetalProviderSpec().WithMetro("sv").WithFacility("sv15"),
+				expectedEqual:           false,
+				expectedUnmanagedFields: []string{},
+				expectedChangedFields:   []string{"facility", "metro"},
+			}),
+			Entry("with a different operating system", equinixMetalEqualTableInput{
+				baseConfig:              resourcebuilder.EquinixMetalProviderSpec(),
+				compareConfig:           resourcebuilder.EquinixMetalProviderSpec().WithOS("rhcos_4_13"),
+				expectedEqual:           false,
+				expectedUnmanagedFields: []string{},
+				expectedChangedFields:   []string{"os"},
+			}),
+			Entry("with the legacy API version", equinixMetalEqualTableInput{
+				baseConfig:              resourcebuilder.EquinixMetalProviderSpec(),
+				compareConfig:           resourcebuilder.EquinixMetalProviderSpec().WithAPIVersion("packetprovider.openshift.io/v1alpha1"),
+				expectedEqual:           true,
+				expectedUnmanagedFields: []string{"apiVersion"},
+				expectedChangedFields:   []string{},
+			}),
+		)
+	})
+
+	Context("newEquinixMetalProviderConfig", func() {
+		var providerConfig ProviderConfig
+		var rawConfig *runtime.RawExtension
+
+		BeforeEach(func() {
+			rawConfig = resourcebuilder.EquinixMetalProviderSpec().BuildRawExtension()
+
+			var err error
+			providerConfig, err = newEquinixMetalProviderConfig(rawConfig)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("sets the type to EquinixMetal", func() {
+			Expect(providerConfig.Type()).To(Equal(configv1.EquinixMetalPlatformType))
+		})
+
+		It("extracts the machine type as the instance type", func() {
+			Expect(providerConfig.ExtractInstanceType()).To(Equal("c3.small.x86"))
+		})
+
+		It("does not extract a failure domain", func() {
+			Expect(providerConfig.ExtractFailureDomain()).To(BeNil())
+		})
+
+		It("passes every field of the provider spec through the raw config", func() {
+			raw, err := providerConfig.RawConfig()
+			Expect(err).ToNot(HaveOccurred())
+
+			var fields, expectedFields map[string]interface{}
+			Expect(json.Unmarshal(raw, &fields)).To(Succeed())
+			Expect(json.Unmarshal(rawConfig.Raw, &expectedFields)).To(Succeed())
+
+			Expect(fields).To(Equal(expectedFields))
+			Expect(fields).To(HaveKey("userDataSecret"))
+		})
+
+		Context("with a provider spec in an unknown API version", func() {
+			It("returns an error", func() {
+				_, err := newEquinixMetalProviderConfig(&runtime.RawExtension{
+					Raw: []byte(`{"apiVersion":"machine.openshift.io/v1","kind":"PacketMachineProviderConfig"}`),
+				})
+
+				Expect(err).To(MatchError("could not decode Equinix Metal provider spec: unknown provider spec API version: machine.openshift.io/v1 does not serve PacketMachineProviderConfig"))
+			})
+		})
+	})
+})
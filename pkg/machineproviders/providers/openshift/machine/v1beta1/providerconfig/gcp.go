@@ -0,0 +1,287 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package providerconfig
+
+import (
+	"fmt"
+
+	configv1 "github.com/openshift/api/config/v1"
+	machinev1 "github.com/openshift/api/machine/v1"
+	machinev1beta1 "github.com/openshift/api/machine/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// gcpBootDiskKey is the key used to match the boot disk between provider specs.
+const gcpBootDiskKey = "boot"
+
+// GCPProviderConfig holds the provider spec of a GCP Machine.
+// It allows external code to gather the stored config.
+type GCPProviderConfig struct {
+	providerConfig machinev1beta1.GCPMachineProviderSpec
+}
+
+// Config returns the stored GCPMachineProviderSpec.
+func (g GCPProviderConfig) Config() machinev1beta1.GCPMachineProviderSpec {
+	return g.providerConfig
+}
+
+// InjectFailureDomain returns a new GCPProviderConfig configured with the failure domain
+// information provided.
+// When the failure domain has no zone, the zone is left unchanged.
+func (g GCPProviderConfig) InjectFailureDomain(fd machinev1.GCPFailureDomain) GCPProviderConfig {
+	newGCPProviderConfig := GCPProviderConfig{
+		providerConfig: *g.providerConfig.DeepCopy(),
+	}
+
+	if fd.Zone != "" {
+		newGCPProviderConfig.providerConfig.Zone = fd.Zone
+	}
+
+	return newGCPProviderConfig
+}
+
+// ExtractFailureDomain returns a GCPFailureDomain based on the failure domain
+// information stored within the GCPProviderConfig.
+func (g GCPProviderConfig) ExtractFailureDomain() machinev1.GCPFailureDomain {
+	return machinev1.GCPFailureDomain{
+		Zone: g.providerConfig.Zone,
+	}
+}
+
+// InjectUserDataSecret returns a new GCPProviderConfig configured to read the user data of the
+// instance from the named secret.
+func (g GCPProviderConfig) InjectUserDataSecret(name string) GCPProviderConfig {
+	newGCPProviderConfig := GCPProviderConfig{
+		providerConfig: *g.providerConfig.DeepCopy(),
+	}
+
+	newGCPProviderConfig.providerConfig.UserDataSecret = &corev1.LocalObjectReference{Name: name}
+
+	return newGCPProviderConfig
+}
+
+// ExtractUserDataSecret returns the name of the secret holding the user data of the instance.
+func (g GCPProviderConfig) ExtractUserDataSecret() string {
+	if g.providerConfig.UserDataSecret == nil {
+		return ""
+	}
+
+	return g.providerConfig.UserDataSecret.Name
+}
+
+// ExtractInstanceType returns the machine type from the GCPProviderConfig.
+func (g GCPProviderConfig) ExtractInstanceType() string {
+	return g.providerConfig.MachineType
+}
+
+// Equal compares the GCPProviderConfig with another GCPProviderConfig.
+// The type information of the provider specs is normalised, and the project of any KMS key
+// that omits it is defaulted to the project of the Machine, before the comparison so that
+// provider specs using different API versions of the same kind, or that omit the project of a
+// KMS key that the other sets to the project of the Machine, compare as equal.
+// Any change to the type, size or encryption key of a disk is a difference, so that Machines
+// are replaced when the storage of the control plane is changed.
+func (g GCPProviderConfig) Equal(other GCPProviderConfig) bool {
+	return equality.Semantic.DeepEqual(normalisedGCPProviderConfig(g.providerConfig), normalisedGCPProviderConfig(other.providerConfig))
+}
+
+// UnmanagedFields returns the paths of the fields that differ between the GCPProviderConfigs
+// but where the difference is deliberately tolerated by Equal.
+// These are the type information of the provider specs and the project of any KMS key where one
+// of the provider specs omits the project and the other sets it to the project of the Machine.
+// Disks are matched as within DiskSizeDecreases, the index within the paths is the index of the
+// disk within this GCPProviderConfig.
+func (g GCPProviderConfig) UnmanagedFields(other GCPProviderConfig) []string {
+	base := g.providerConfig
+	compare := other.providerConfig
+
+	var fields []string
+
+	if base.APIVersion != compare.APIVersion {
+		fields = append(fields, "apiVersion")
+	}
+
+	if base.Kind != compare.Kind {
+		fields = append(fields, "kind")
+	}
+
+	compareKeys := gcpDiskKeys(compare.Disks)
+
+	for i, key := range gcpDiskKeys(base.Disks) {
+		j, ok := findGCPDisk(compareKeys, key)
+		if !ok {
+			continue
+		}
+
+		baseKMSKey, compareKMSKey := gcpKMSKey(base.Disks[i]), gcpKMSKey(compare.Disks[j])
+		if baseKMSKey == nil || compareKMSKey == nil {
+			continue
+		}
+
+		if baseKMSKey.ProjectID != compareKMSKey.ProjectID &&
+			gcpKMSKeyProjectID(baseKMSKey, base.ProjectID) == gcpKMSKeyProjectID(compareKMSKey, compare.ProjectID) {
+			fields = append(fields, fmt.Sprintf("disks[%d].encryptionKey.kmsKey.projectID", i))
+		}
+	}
+
+	return fields
+}
+
+// ChangedFields returns the names of the top level fields of the provider spec that differ
+// between the GCPProviderConfigs.
+// The provider specs are normalised in the same way as within Equal, so differences that Equal
+// tolerates are not reported.
+func (g GCPProviderConfig) ChangedFields(other GCPProviderConfig) ([]string, error) {
+	return changedTopLevelFields(normalisedGCPProviderConfig(g.providerConfig), normalisedGCPProviderConfig(other.providerConfig))
+}
+
+// GCPDiskSizeDecrease describes a disk whose size is smaller within a desired GCPProviderConfig
+// than within the current GCPProviderConfig.
+type GCPDiskSizeDecrease struct {
+	// Index is the index of the disk within the desired GCPProviderConfig.
+	Index int
+
+	// CurrentSize is the disk size, in GB, within the current GCPProviderConfig.
+	CurrentSize int64
+
+	// DesiredSize is the disk size, in GB, within the desired GCPProviderConfig.
+	DesiredSize int64
+}
+
+// DiskSizeDecreases compares the disks of the GCPProviderConfig with those of the desired
+// GCPProviderConfig and returns any disk whose size would be decreased.
+// GCP disks have no name within the provider spec, so the boot disk is matched with the boot disk
+// and the remaining disks are matched by their position amongst the disks that are not boot disks.
+// Disk size increases are valid changes and are not reported, nor are disks that omit the size.
+func (g GCPProviderConfig) DiskSizeDecreases(desired GCPProviderConfig) []GCPDiskSizeDecrease {
+	var decreases []GCPDiskSizeDecrease
+
+	currentKeys := gcpDiskKeys(g.providerConfig.Disks)
+
+	for i, key := range gcpDiskKeys(desired.providerConfig.Disks) {
+		j, ok := findGCPDisk(currentKeys, key)
+		if !ok {
+			continue
+		}
+
+		currentDisk, desiredDisk := g.providerConfig.Disks[j], desired.providerConfig.Disks[i]
+		if currentDisk.SizeGB == 0 || desiredDisk.SizeGB == 0 {
+			continue
+		}
+
+		if desiredDisk.SizeGB < currentDisk.SizeGB {
+			decreases = append(decreases, GCPDiskSizeDecrease{
+				Index:       i,
+				CurrentSize: currentDisk.SizeGB,
+				DesiredSize: desiredDisk.SizeGB,
+			})
+		}
+	}
+
+	return decreases
+}
+
+// normalisedGCPProviderConfig returns a copy of the provider spec that is suitable for comparison.
+// The type information is normalised and the project of any KMS key that omits it is defaulted to
+// the project of the Machine, as GCP does when the disk is created.
+func normalisedGCPProviderConfig(cfg machinev1beta1.GCPMachineProviderSpec) *machinev1beta1.GCPMachineProviderSpec {
+	out := cfg.DeepCopy()
+	out.TypeMeta = normalisedTypeMeta(gcpProviderConfigKind)
+
+	for _, disk := range out.Disks {
+		if kmsKey := gcpKMSKey(disk); kmsKey != nil {
+			kmsKey.ProjectID = gcpKMSKeyProjectID(kmsKey, out.ProjectID)
+		}
+	}
+
+	return out
+}
+
+// gcpDiskKeys returns the keys used to match each of the disks between provider specs.
+// The boot disk is keyed as boot, and the remaining disks are keyed by their position amongst the
+// disks that are not boot disks. Nil disks have an empty key and are never matched.
+func gcpDiskKeys(disks []*machinev1beta1.GCPDisk) []string {
+	keys := make([]string, len(disks))
+	position := 0
+
+	for i, disk := range disks {
+		switch {
+		case disk == nil:
+			continue
+		case disk.Boot:
+			keys[i] = gcpBootDiskKey
+		default:
+			keys[i] = fmt.Sprintf("disk-%d", position)
+			position++
+		}
+	}
+
+	return keys
+}
+
+// findGCPDisk returns the index of the disk with the given key.
+func findGCPDisk(keys []string, key string) (int, bool) {
+	if key == "" {
+		return 0, false
+	}
+
+	for i := range keys {
+		if keys[i] == key {
+			return i, true
+		}
+	}
+
+	return 0, false
+}
+
+// gcpKMSKey returns the KMS key used to encrypt the disk, if any.
+func gcpKMSKey(disk *machinev1beta1.GCPDisk) *machinev1beta1.GCPKMSKeyReference {
+	if disk == nil || disk.EncryptionKey == nil {
+		return nil
+	}
+
+	return disk.EncryptionKey.KMSKey
+}
+
+// gcpKMSKeyProjectID returns the project of the KMS key, defaulting to the project of the Machine.
+func gcpKMSKeyProjectID(kmsKey *machinev1beta1.GCPKMSKeyReference, machineProjectID string) string {
+	if kmsKey.ProjectID == "" {
+		return machineProjectID
+	}
+
+	return kmsKey.ProjectID
+}
+
+// newGCPProviderConfig creates a GCPProviderConfig from the raw extension.
+// It should return an error if the provided RawExtension does not represent
+// a GCPMachineProviderSpec.
+func newGCPProviderConfig(raw *runtime.RawExtension) (ProviderConfig, error) {
+	gcpMachineProviderSpec := machinev1beta1.GCPMachineProviderSpec{}
+	if err := decodeProviderSpec(raw, gcpProviderConfigKind, &gcpMachineProviderSpec); err != nil {
+		return nil, fmt.Errorf("could not decode GCP provider spec: %w", err)
+	}
+
+	return providerConfig{
+		platformType: configv1.GCPPlatformType,
+		raw:          raw.Raw,
+		gcp: GCPProviderConfig{
+			providerConfig: gcpMachineProviderSpec,
+		},
+	}, nil
+}
@@ -0,0 +1,250 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package providerconfig
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	configv1 "github.com/openshift/api/config/v1"
+	machinev1 "github.com/openshift/api/machine/v1"
+	machinev1beta1 "github.com/openshift/api/machine/v1beta1"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/test/resourcebuilder"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+var _ = Describe("GCP Provider Config", func() {
+	kmsKey := machinev1beta1.GCPKMSKeyReference{
+		Name:     "master-key",
+		KeyRing:  "master-key-ring",
+		Location: "global",
+	}
+
+	Context("ExtractInstanceType", func() {
+		It("returns the configured machine type", func() {
+			gcpConfig := GCPProviderConfig{providerConfig: *resourcebuilder.GCPProviderSpec().WithMachineType("n2-standard-8").Build()}
+
+			Expect(gcpConfig.ExtractInstanceType()).To(Equal("n2-standard-8"))
+		})
+	})
+
+	Context("ExtractFailureDomain", func() {
+		It("returns the configured zone", func() {
+			gcpConfig := GCPProviderConfig{providerConfig: *resourcebuilder.GCPProviderSpec().WithZone("us-central1-b").Build()}
+
+			Expect(gcpConfig.ExtractFailureDomain()).To(Equal(resourcebuilder.GCPFailureDomain().WithZone("us-central1-b").Build()))
+		})
+	})
+
+	Context("when the failure domain is changed after initialisation", func() {
+		var providerConfig, changedProviderConfig GCPProviderConfig
+
+		BeforeEach(func() {
+			providerConfig = GCPProviderConfig{providerConfig: *resourcebuilder.GCPProviderSpec().WithZone("us-central1-a").Build()}
+			changedProviderConfig = providerConfig.InjectFailureDomain(resourcebuilder.GCPFailureDomain().WithZone("us-central1-c").Build())
+		})
+
+		It("stores the new zone in the provider config", func() {
+			Expect(changedProviderConfig.Config().Zone).To(Equal("us-central1-c"))
+		})
+
+		It("does not modify the original provider config", func() {
+			Expect(providerConfig.Config().Zone).To(Equal("us-central1-a"))
+		})
+
+		It("leaves the zone unchanged when the failure domain has no zone", func() {
+			Expect(providerConfig.InjectFailureDomain(machinev1.GCPFailureDomain{}).Equal(providerConfig)).To(BeTrue())
+		})
+
+		It("is equal to the original provider config apart from the zone", func() {
+			Expect(changedProviderConfig.InjectFailureDomain(providerConfig.ExtractFailureDomain()).Equal(providerConfig)).To(BeTrue())
+		})
+	})
+
+	Context("Equal", func() {
+		type gcpEqualTableInput struct {
+			baseBuilder    resourcebuilder.GCPProviderSpecBuilder
+			compareBuilder resourcebuilder.GCPProviderSpecBuilder
+			expectedEqual  bool
+
+			expectedUnmanagedFields []string
+			expectedChangedFields   []string
+		}
+
+		DescribeTable("should compare the disks of the provider configs", func(in gcpEqualTableInput) {
+			baseConfig := GCPProviderConfig{providerConfig: *in.baseBuilder.Build()}
+			compareConfig := GCPProviderConfig{providerConfig: *in.compareBuilder.Build()}
+
+			Expect(baseConfig.Equal(compareConfig)).To(Equal(in.expectedEqual))
+			Expect(compareConfig.Equal(baseConfig)).To(Equal(in.expectedEqual), "Equality should be symmetric")
+
+			Expect(baseConfig.UnmanagedFields(compareConfig)).To(ConsistOf(in.expectedUnmanagedFields))
+			Expect(compareConfig.UnmanagedFields(baseConfig)).To(ConsistOf(in.expectedUnmanagedFields), "Unmanaged fields should be symmetric")
+
+			Expect(baseConfig.ChangedFields(compareConfig)).To(ConsistOf(in.expectedChangedFields))
+		},
+			Entry("with matching configs using different API versions", gcpEqualTableInput{
+				baseBuilder:             resourcebuilder.GCPProviderSpec().WithAPIVersion("gcpprovider.openshift.io/v1beta1"),
+				compareBuilder:          resourcebuilder.GCPProviderSpec(),
+				expectedEqual:           true,
+				expectedUnmanagedFields: []string{"apiVersion"},
+				expectedChangedFields:   []string{},
+			}),
+			Entry("with a different boot disk type", gcpEqualTableInput{
+				baseBuilder:             resourcebuilder.GCPProviderSpec(),
+				compareBuilder:          resourcebuilder.GCPProviderSpec().WithBootDisk(resourcebuilder.GCPDisk().WithType("pd-balanced")),
+				expectedEqual:           false,
+				expectedUnmanagedFields: []string{},
+				expectedChangedFields:   []string{"disks"},
+			}),
+			Entry("with an increased boot disk size", gcpEqualTableInput{
+				baseBuilder:             resourcebuilder.GCPProviderSpec(),
+				compareBuilder:          resourcebuilder.GCPProviderSpec().WithBootDisk(resourcebuilder.GCPDisk().WithSizeGB(256)),
+				expectedEqual:           false,
+				expectedUnmanagedFields: []string{},
+				expectedChangedFields:   []string{"disks"},
+			}),
+			Entry("with a KMS key added to the boot disk", gcpEqualTableInput{
+				baseBuilder:             resourcebuilder.GCPProviderSpec(),
+				compareBuilder:          resourcebuilder.GCPProviderSpec().WithBootDisk(resourcebuilder.GCPDisk().WithKMSKey(kmsKey)),
+				expectedEqual:           false,
+				expectedUnmanagedFields: []string{},
+				expectedChangedFields:   []string{"disks"},
+			}),
+			Entry("with an omitted and an explicit KMS key project matching the Machine project", gcpEqualTableInput{
+				baseBuilder:             resourcebuilder.GCPProviderSpec().WithBootDisk(resourcebuilder.GCPDisk().WithKMSKey(kmsKey)),
+				compareBuilder:          resourcebuilder.GCPProviderSpec().WithBootDisk(resourcebuilder.GCPDisk().WithKMSKey(withKMSKeyProjectID(kmsKey, "gcp-project-12345678"))),
+				expectedEqual:           true,
+				expectedUnmanagedFields: []string{"disks[0].encryptionKey.kmsKey.projectID"},
+				expectedChangedFields:   []string{},
+			}),
+			Entry("with an omitted and an explicit KMS key project in a different project", gcpEqualTableInput{
+				baseBuilder:             resourcebuilder.GCPProviderSpec().WithBootDisk(resourcebuilder.GCPDisk().WithKMSKey(kmsKey)),
+				compareBuilder:          resourcebuilder.GCPProviderSpec().WithBootDisk(resourcebuilder.GCPDisk().WithKMSKey(withKMSKeyProjectID(kmsKey, "gcp-kms-project"))),
+				expectedEqual:           false,
+				expectedUnmanagedFields: []string{},
+				expectedChangedFields:   []string{"disks"},
+			}),
+			Entry("with a different machine type", gcpEqualTableInput{
+				baseBuilder:             resourcebuilder.GCPProviderSpec(),
+				compareBuilder:          resourcebuilder.GCPProviderSpec().WithMachineType("n2-standard-8"),
+				expectedEqual:           false,
+				expectedUnmanagedFields: []string{},
+				expectedChangedFields:   []string{"machineType"},
+			}),
+		)
+	})
+
+	Context("DiskSizeDecreases", func() {
+		type diskSizeDecreasesTableInput struct {
+			currentBuilder    resourcebuilder.GCPProviderSpecBuilder
+			desiredBuilder    resourcebuilder.GCPProviderSpecBuilder
+			expectedDecreases []GCPDiskSizeDecrease
+		}
+
+		DescribeTable("should report disks with decreased sizes", func(in diskSizeDecreasesTableInput) {
+			current := GCPProviderConfig{providerConfig: *in.currentBuilder.Build()}
+			desired := GCPProviderConfig{providerConfig: *in.desiredBuilder.Build()}
+
+			Expect(current.DiskSizeDecreases(desired)).To(Equal(in.expectedDecreases))
+		},
+			Entry("with matching disks", diskSizeDecreasesTableInput{
+				currentBuilder:    resourcebuilder.GCPProviderSpec().WithExtraDisks(resourcebuilder.GCPDisk().WithSizeGB(100)),
+				desiredBuilder:    resourcebuilder.GCPProviderSpec().WithExtraDisks(resourcebuilder.GCPDisk().WithSizeGB(100)),
+				expectedDecreases: nil,
+			}),
+			Entry("with an increased boot disk size", diskSizeDecreasesTableInput{
+				currentBuilder:    resourcebuilder.GCPProviderSpec(),
+				desiredBuilder:    resourcebuilder.GCPProviderSpec().WithBootDisk(resourcebuilder.GCPDisk().WithSizeGB(256)),
+				expectedDecreases: nil,
+			}),
+			Entry("with a decreased boot disk size", diskSizeDecreasesTableInput{
+				currentBuilder: resourcebuilder.GCPProviderSpec(),
+				desiredBuilder: resourcebuilder.GCPProviderSpec().WithBootDisk(resourcebuilder.GCPDisk().WithSizeGB(64)),
+				expectedDecreases: []GCPDiskSizeDecrease{
+					{Index: 0, CurrentSize: 128, DesiredSize: 64},
+				},
+			}),
+			Entry("with a decreased size on an additional disk", diskSizeDecreasesTableInput{
+				currentBuilder: resourcebuilder.GCPProviderSpec().WithExtraDisks(resourcebuilder.GCPDisk().WithSizeGB(100), resourcebuilder.GCPDisk().WithSizeGB(200)),
+				desiredBuilder: resourcebuilder.GCPProviderSpec().WithExtraDisks(resourcebuilder.GCPDisk().WithSizeGB(100), resourcebuilder.GCPDisk().WithSizeGB(50)),
+				expectedDecreases: []GCPDiskSizeDecrease{
+					{Index: 2, CurrentSize: 200, DesiredSize: 50},
+				},
+			}),
+			Entry("with an omitted disk size", diskSizeDecreasesTableInput{
+				currentBuilder:    resourcebuilder.GCPProviderSpec(),
+				desiredBuilder:    resourcebuilder.GCPProviderSpec().WithBootDisk(resourcebuilder.GCPDisk().WithSizeGB(0)),
+				expectedDecreases: nil,
+			}),
+			Entry("with a new disk", diskSizeDecreasesTableInput{
+				currentBuilder:    resourcebuilder.GCPProviderSpec(),
+				desiredBuilder:    resourcebuilder.GCPProviderSpec().WithExtraDisks(resourcebuilder.GCPDisk().WithSizeGB(10)),
+				expectedDecreases: nil,
+			}),
+		)
+	})
+
+	Context("newGCPProviderConfig", func() {
+		var providerConfig ProviderConfig
+		var expectedGCPConfig machinev1beta1.GCPMachineProviderSpec
+
+		BeforeEach(func() {
+			configBuilder := resourcebuilder.GCPProviderSpec()
+			expectedGCPConfig = *configBuilder.Build()
+			rawConfig := configBuilder.BuildRawExtension()
+
+			var err error
+			providerConfig, err = newGCPProviderConfig(rawConfig)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("sets the type to GCP", func() {
+			Expect(providerConfig.Type()).To(Equal(configv1.GCPPlatformType))
+		})
+
+		It("returns the correct GCP config", func() {
+			Expect(providerConfig.GCP().Config()).To(Equal(expectedGCPConfig))
+		})
+
+		It("round trips through the raw config", func() {
+			rawConfig, err := providerConfig.RawConfig()
+			Expect(err).ToNot(HaveOccurred())
+
+			roundTripped, err := newGCPProviderConfig(&runtime.RawExtension{Raw: rawConfig})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(roundTripped.Equal(providerConfig)).To(BeTrue())
+		})
+
+		Context("with a different provider spec kind", func() {
+			It("returns an error", func() {
+				_, err := newGCPProviderConfig(&runtime.RawExtension{
+					Raw: []byte(`{"apiVersion":"machine.openshift.io/v1beta1","kind":"AzureMachineProviderSpec"}`),
+				})
+
+				Expect(err).To(MatchError("could not decode GCP provider spec: unexpected provider spec kind: expected GCPMachineProviderSpec, got AzureMachineProviderSpec"))
+			})
+		})
+	})
+})
+
+// withKMSKeyProjectID returns a copy of the KMS key reference with the given project.
+func withKMSKeyProjectID(kmsKey machinev1beta1.GCPKMSKeyReference, projectID string) machinev1beta1.GCPKMSKeyReference {
+	kmsKey.ProjectID = projectID
+	return kmsKey
+}
@@ -0,0 +1,153 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package providerconfig
+
+import (
+	"encoding/json"
+	"fmt"
+
+	configv1 "github.com/openshift/api/config/v1"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders/providers/openshift/machine/v1beta1/failuredomain"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+const (
+	// ibmCloudZoneField is the name of the field of the IBM Cloud provider spec holding the VPC zone of the
+	// instance.
+	ibmCloudZoneField = "zone"
+)
+
+// IBMCloudProviderConfig holds the provider spec of an IBM Cloud VPC Machine.
+// The IBM Cloud API is not a dependency of the operator, so the provider spec is passed through unchanged,
+// other than the VPC zone of the instance, which is its failure domain.
+type IBMCloudProviderConfig struct {
+	providerConfig ibmCloudProviderSpec
+
+	// fields holds every top level field of the provider spec, so that the provider spec can be
+	// passed through, and compared, without losing the fields that are not decoded.
+	fields map[string]interface{}
+}
+
+// ibmCloudProviderSpec is the subset of the IBMCloudMachineProviderSpec that the operator reads.
+type ibmCloudProviderSpec struct {
+	metav1.TypeMeta `json:",inline"`
+
+	// Profile is the instance profile, the hardware configuration, of the instance.
+	Profile string `json:"profile,omitempty"`
+
+	// Region is the IBM Cloud region in which the VPC of the instance is located.
+	Region string `json:"region,omitempty"`
+
+	// Zone is the VPC zone in which the instance is created.
+	Zone string `json:"zone,omitempty"`
+}
+
+// InjectFailureDomain returns a new IBMCloudProviderConfig configured with the zone of the failure domain.
+// A failure domain without a zone leaves the zone of the template unchanged.
+func (i IBMCloudProviderConfig) InjectFailureDomain(fd failuredomain.IBMCloudFailureDomain) IBMCloudProviderConfig {
+	newIBMCloudProviderConfig := i
+	fields := runtime.DeepCopyJSON(i.fields)
+
+	if fd.Zone != "" {
+		newIBMCloudProviderConfig.providerConfig.Zone = fd.Zone
+		fields[ibmCloudZoneField] = fd.Zone
+	}
+
+	newIBMCloudProviderConfig.fields = fields
+
+	return newIBMCloudProviderConfig
+}
+
+// ExtractFailureDomain returns the failure domain of the instance, its VPC zone.
+func (i IBMCloudProviderConfig) ExtractFailureDomain() failuredomain.IBMCloudFailureDomain {
+	return failuredomain.IBMCloudFailureDomain{
+		Zone: i.providerConfig.Zone,
+	}
+}
+
+// ExtractProfile returns the instance profile of the instance.
+func (i IBMCloudProviderConfig) ExtractProfile() string {
+	return i.providerConfig.Profile
+}
+
+// Equal compares the IBMCloudProviderConfig with another IBMCloudProviderConfig.
+// Every field of the provider spec is compared, other than the type information, so that a
+// provider spec that omits it compares as equal to one that sets it.
+func (i IBMCloudProviderConfig) Equal(other IBMCloudProviderConfig) bool {
+	return equality.Semantic.DeepEqual(normalisedIBMCloudFields(i.fields), normalisedIBMCloudFields(other.fields))
+}
+
+// UnmanagedFields returns the paths of the fields that differ between the IBMCloudProviderConfigs
+// but where the difference is deliberately tolerated by Equal.
+// These are the type information of the provider specs.
+func (i IBMCloudProviderConfig) UnmanagedFields(other IBMCloudProviderConfig) []string {
+	var fields []string
+
+	if i.providerConfig.APIVersion != other.providerConfig.APIVersion {
+		fields = append(fields, "apiVersion")
+	}
+
+	if i.providerConfig.Kind != other.providerConfig.Kind {
+		fields = append(fields, "kind")
+	}
+
+	return fields
+}
+
+// ChangedFields returns the names of the top level fields of the provider spec that differ
+// between the IBMCloudProviderConfigs.
+// The provider specs are normalised in the same way as within Equal, so differences that Equal
+// tolerates are not reported.
+func (i IBMCloudProviderConfig) ChangedFields(other IBMCloudProviderConfig) ([]string, error) {
+	return changedTopLevelFields(normalisedIBMCloudFields(i.fields), normalisedIBMCloudFields(other.fields))
+}
+
+// normalisedIBMCloudFields returns a copy of the fields of the provider spec that is suitable for comparison.
+// The type information is removed, so that a provider spec that omits it compares as equal to one that sets it.
+func normalisedIBMCloudFields(fields map[string]interface{}) map[string]interface{} {
+	out := runtime.DeepCopyJSON(fields)
+	delete(out, "apiVersion")
+	delete(out, "kind")
+
+	return out
+}
+
+// newIBMCloudProviderConfig creates an IBMCloudProviderConfig from the raw extension.
+// It should return an error if the provided RawExtension does not represent
+// an IBMCloudMachineProviderSpec.
+func newIBMCloudProviderConfig(raw *runtime.RawExtension) (ProviderConfig, error) {
+	ibmCloudMachineProviderSpec := ibmCloudProviderSpec{}
+	if err := decodeProviderSpec(raw, ibmCloudProviderConfigKind, &ibmCloudMachineProviderSpec); err != nil {
+		return nil, fmt.Errorf("could not decode IBM Cloud provider spec: %w", err)
+	}
+
+	fields := map[string]interface{}{}
+	if err := json.Unmarshal(raw.Raw, &fields); err != nil {
+		return nil, fmt.Errorf("could not decode IBM Cloud provider spec: %w", err)
+	}
+
+	return providerConfig{
+		platformType: configv1.IBMCloudPlatformType,
+		raw:          raw.Raw,
+		ibmCloud: IBMCloudProviderConfig{
+			providerConfig: ibmCloudMachineProviderSpec,
+			fields:         fields,
+		},
+	}, nil
+}
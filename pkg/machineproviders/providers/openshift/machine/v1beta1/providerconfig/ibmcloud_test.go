@@ -0,0 +1,193 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package providerconfig
+
+import (
+	"encoding/json"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	configv1 "github.com/openshift/api/config/v1"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders/providers/openshift/machine/v1beta1/failuredomain"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/test/resourcebuilder"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+var _ = Describe("IBM Cloud Provider Config", func() {
+	ibmCloudConfig := func(builder resourcebuilder.IBMCloudProviderSpecBuilder) IBMCloudProviderConfig {
+		providerConfig, err := newIBMCloudProviderConfig(builder.BuildRawExtension())
+		Expect(err).ToNot(HaveOccurred())
+
+		return providerConfig.IBMCloud()
+	}
+
+	Context("ExtractFailureDomain", func() {
+		It("returns the zone of the instance", func() {
+			config := ibmCloudConfig(resourcebuilder.IBMCloudProviderSpec())
+
+			Expect(config.ExtractFailureDomain()).To(Equal(failuredomain.IBMCloudFailureDomain{Zone: "us-south-1"}))
+		})
+	})
+
+	Context("InjectFailureDomain", func() {
+		It("replaces the zone of the instance", func() {
+			config := ibmCloudConfig(resourcebuilder.IBMCloudProviderSpec())
+			injected := config.InjectFailureDomain(failuredomain.IBMCloudFailureDomain{Zone: "us-south-2"})
+
+			Expect(injected.ExtractFailureDomain()).To(Equal(failuredomain.IBMCloudFailureDomain{Zone: "us-south-2"}))
+			Expect(injected.fields).To(HaveKeyWithValue("zone", "us-south-2"))
+			Expect(config.ExtractFailureDomain().Zone).To(Equal("us-south-1"), "The original config should not be modified")
+			Expect(config.fields).To(HaveKeyWithValue("zone", "us-south-1"), "The original config should not be modified")
+		})
+
+		It("leaves the zone unchanged when the failure domain does not set it", func() {
+			config := ibmCloudConfig(resourcebuilder.IBMCloudProviderSpec())
+
+			Expect(config.InjectFailureDomain(failuredomain.IBMCloudFailureDomain{}).Equal(config)).To(BeTrue())
+		})
+
+		It("does not change the provider config when injecting its own failure domain", func() {
+			config := ibmCloudConfig(resourcebuilder.IBMCloudProviderSpec())
+
+			Expect(config.InjectFailureDomain(config.ExtractFailureDomain()).Equal(config)).To(BeTrue())
+		})
+	})
+
+	Context("Equal", func() {
+		type ibmCloudEqualTableInput struct {
+			baseConfig    resourcebuilder.IBMCloudProviderSpecBuilder
+			compareConfig resourcebuilder.IBMCloudProviderSpecBuilder
+			expectedEqual bool
+
+			expectedUnmanagedFields []string
+			expectedChangedFields   []string
+		}
+
+		DescribeTable("should compare the provider configs", func(in ibmCloudEqualTableInput) {
+			baseConfig := ibmCloudConfig(in.baseConfig)
+			compareConfig := ibmCloudConfig(in.compareConfig)
+
+			Expect(baseConfig.Equal(compareConfig)).To(Equal(in.expectedEqual))
+			Expect(compareConfig.Equal(baseConfig)).To(Equal(in.expectedEqual), "Equality should be symmetric")
+
+			Expect(baseConfig.UnmanagedFields(compareConfig)).To(ConsistOf(in.expectedUnmanagedFields))
+			Expect(compareConfig.UnmanagedFields(baseConfig)).To(ConsistOf(in.expectedUnmanagedFields), "Unmanaged fields should be symmetric")
+
+			Expect(baseConfig.ChangedFields(compareConfig)).To(ConsistOf(in.expectedChangedFields))
+		},
+			Entry("with matching configs", ibmCloudEqualTableInput{
+				baseConfig:              resourcebuilder.IBMCloudProviderSpec(),
+				compareConfig:           resourcebuilder.IBMCloudProviderSpec(),
+				expectedEqual:           true,
+				expectedUnmanagedFields: []string{},
+				expectedChangedFields:   []string{},
+			}),
+			Entry("with a different profile", ibmCloudEqualTableInput{
+				baseConfig:              resourcebuilder.IBMCloudProviderSpec(),
+				compareConfig:           resourcebuilder.IBMCloudProviderSpec().WithProfile("bx2-8x32"),
+				expectedEqual:           false,
+				expectedUnmanagedFields: []string{},
+				expectedChangedFields:   []string{"profile"},
+			}),
+			Entry("with a different API version", ibmCloudEqualTableInput{
+				baseConfig:              resourcebuilder.IBMCloudProviderSpec(),
+				compareConfig:           resourcebuilder.IBMCloudProviderSpec().WithAPIVersion(""),
+				expectedEqual:           true,
+				expectedUnmanagedFields: []string{"apiVersion"},
+				expectedChangedFields:   []string{},
+			}),
+		)
+
+		It("compares the fields that are not decoded", func() {
+			config := ibmCloudConfig(resourcebuilder.IBMCloudProviderSpec())
+			withDedicatedHost := config
+			withDedicatedHost.fields = runtime.DeepCopyJSON(config.fields)
+			withDedicatedHost.fields["dedicatedHost"] = "dedicated-host-1"
+
+			Expect(config.Equal(withDedicatedHost)).To(BeFalse())
+			Expect(config.ChangedFields(withDedicatedHost)).To(ConsistOf("dedicatedHost"))
+		})
+	})
+
+	Context("newIBMCloudProviderConfig", func() {
+		var providerConfig ProviderConfig
+		var rawConfig *runtime.RawExtension
+
+		BeforeEach(func() {
+			rawConfig = resourcebuilder.IBMCloudProviderSpec().BuildRawExtension()
+
+			var err error
+			providerConfig, err = newIBMCloudProviderConfig(rawConfig)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("sets the type to IBMCloud", func() {
+			Expect(providerConfig.Type()).To(Equal(configv1.IBMCloudPlatformType))
+		})
+
+		It("extracts the profile as the instance type", func() {
+			Expect(providerConfig.ExtractInstanceType()).To(Equal("bx2-4x16"))
+		})
+
+		It("extracts the failure domain", func() {
+			Expect(providerConfig.ExtractFailureDomain()).To(Equal(failuredomain.NewIBMCloudFailureDomain(failuredomain.IBMCloudFailureDomain{
+				Zone: "us-south-1",
+			})))
+		})
+
+		It("passes every field of the provider spec through the raw config", func() {
+			raw, err := providerConfig.RawConfig()
+			Expect(err).ToNot(HaveOccurred())
+
+			var fields, expectedFields map[string]interface{}
+			Expect(json.Unmarshal(raw, &fields)).To(Succeed())
+			Expect(json.Unmarshal(rawConfig.Raw, &expectedFields)).To(Succeed())
+
+			Expect(fields).To(Equal(expectedFields))
+			Expect(fields).To(HaveKey("primaryNetworkInterface"))
+		})
+
+		Context("when injecting a failure domain", func() {
+			It("updates the raw config with the zone", func() {
+				injected, err := providerConfig.InjectFailureDomain(failuredomain.NewIBMCloudFailureDomain(failuredomain.IBMCloudFailureDomain{
+					Zone: "us-south-3",
+				}))
+				Expect(err).ToNot(HaveOccurred())
+
+				raw, err := injected.RawConfig()
+				Expect(err).ToNot(HaveOccurred())
+
+				var fields map[string]interface{}
+				Expect(json.Unmarshal(raw, &fields)).To(Succeed())
+
+				Expect(fields).To(HaveKeyWithValue("zone", "us-south-3"))
+				Expect(fields).To(HaveKeyWithValue("vpc", "cluster-vpc"))
+			})
+		})
+
+		Context("with a provider spec in an unknown API version", func() {
+			It("returns an error", func() {
+				_, err := newIBMCloudProviderConfig(&runtime.RawExtension{
+					Raw: []byte(`{"apiVersion":"machine.openshift.io/v1beta1","kind":"IBMCloudMachineProviderSpec"}`),
+				})
+
+				Expect(err).To(MatchError("could not decode IBM Cloud provider spec: unknown provider spec API version: machine.openshift.io/v1beta1 does not serve IBMCloudMachineProviderSpec"))
+			})
+		})
+	})
+})
@@ -0,0 +1,197 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package providerconfig
+
+import (
+	"fmt"
+
+	configv1 "github.com/openshift/api/config/v1"
+	machinev1 "github.com/openshift/api/machine/v1"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders/providers/openshift/machine/v1beta1/failuredomain"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// NutanixProviderConfig holds the provider spec of a Nutanix Machine.
+// It allows external code to gather the stored config.
+// The failure domain of a Nutanix Machine is its Prism Element (cluster) and subnet.
+type NutanixProviderConfig struct {
+	providerConfig machinev1.NutanixMachineProviderConfig
+
+	// dataDisks are the data disks attached to the virtual machine.
+	// The NutanixMachineProviderConfig API does not yet include data disks, so they are decoded from, and
+	// encoded into, the raw provider spec alongside the NutanixMachineProviderConfig.
+	dataDisks []map[string]interface{}
+}
+
+// Config returns the stored NutanixMachineProviderConfig.
+func (n NutanixProviderConfig) Config() machinev1.NutanixMachineProviderConfig {
+	return n.providerConfig
+}
+
+// ExtractCluster returns the identifier of the Prism Element (cluster) in which the
+// virtual machine is created.
+func (n NutanixProviderConfig) ExtractCluster() machinev1.NutanixResourceIdentifier {
+	return n.providerConfig.Cluster
+}
+
+// ExtractSubnet returns the identifier of the subnet to which the virtual machine is attached.
+func (n NutanixProviderConfig) ExtractSubnet() machinev1.NutanixResourceIdentifier {
+	return n.providerConfig.Subnet
+}
+
+// ExtractStorageContainers returns the UUID of the storage container of each data disk of the virtual machine.
+// The UUID is empty for a data disk that does not set its storage container by UUID.
+func (n NutanixProviderConfig) ExtractStorageContainers() []string {
+	storageContainers := make([]string, 0, len(n.dataDisks))
+	for _, disk := range n.dataDisks {
+		storageContainers = append(storageContainers, nutanixDataDiskStorageContainer(disk))
+	}
+
+	return storageContainers
+}
+
+// InjectStorageContainer returns a new NutanixProviderConfig with each of the data disks placed in the storage
+// container with the UUID. The system disk is always placed within the storage container of the image, so it is
+// left unchanged.
+func (n NutanixProviderConfig) InjectStorageContainer(uuid string) NutanixProviderConfig {
+	newNutanixProviderConfig := n
+	newNutanixProviderConfig.providerConfig = *n.providerConfig.DeepCopy()
+	newNutanixProviderConfig.dataDisks = deepCopyNutanixDataDisks(n.dataDisks)
+
+	for _, disk := range newNutanixProviderConfig.dataDisks {
+		setNutanixDataDiskStorageContainer(disk, uuid)
+	}
+
+	return newNutanixProviderConfig
+}
+
+// InjectFailureDomain returns a new NutanixProviderConfig configured with the Prism Element and
+// subnet of the failure domain. A failure domain without a Prism Element or without subnets leaves
+// the respective field of the template unchanged. Nutanix Machines are attached to a single subnet,
+// so only the first subnet of the failure domain is injected.
+func (n NutanixProviderConfig) InjectFailureDomain(fd failuredomain.NutanixFailureDomain) NutanixProviderConfig {
+	newNutanixProviderConfig := n
+	newNutanixProviderConfig.providerConfig = *n.providerConfig.DeepCopy()
+
+	if fd.Cluster.Type != "" {
+		newNutanixProviderConfig.providerConfig.Cluster = *fd.Cluster.DeepCopy()
+	}
+
+	if len(fd.Subnets) > 0 {
+		newNutanixProviderConfig.providerConfig.Subnet = *fd.Subnets[0].DeepCopy()
+	}
+
+	return newNutanixProviderConfig
+}
+
+// ExtractFailureDomain returns the failure domain of the virtual machine, its Prism Element and subnet.
+func (n NutanixProviderConfig) ExtractFailureDomain() failuredomain.NutanixFailureDomain {
+	return failuredomain.NutanixFailureDomain{
+		Cluster: *n.providerConfig.Cluster.DeepCopy(),
+		Subnets: []machinev1.NutanixResourceIdentifier{*n.providerConfig.Subnet.DeepCopy()},
+	}
+}
+
+// Equal compares the NutanixProviderConfig with another NutanixProviderConfig.
+// The type information of the provider specs is normalised before the comparison so that a
+// provider spec that omits it compares as equal to one that sets it.
+// Memory and disk sizes are compared by their value, so 16Gi and 16384Mi are equal.
+// Data disks are compared as they are written.
+func (n NutanixProviderConfig) Equal(other NutanixProviderConfig) bool {
+	return equality.Semantic.DeepEqual(n.normalisedProviderSpec(), other.normalisedProviderSpec())
+}
+
+// UnmanagedFields returns the paths of the fields that differ between the NutanixProviderConfigs
+// but where the difference is deliberately tolerated by Equal.
+// These are the type information of the provider specs.
+func (n NutanixProviderConfig) UnmanagedFields(other NutanixProviderConfig) []string {
+	var fields []string
+
+	if n.providerConfig.APIVersion != other.providerConfig.APIVersion {
+		fields = append(fields, "apiVersion")
+	}
+
+	if n.providerConfig.Kind != other.providerConfig.Kind {
+		fields = append(fields, "kind")
+	}
+
+	return fields
+}
+
+// ChangedFields returns the names of the top level fields of the provider spec that differ
+// between the NutanixProviderConfigs.
+// The provider specs are normalised in the same way as within Equal, so differences that Equal
+// tolerates are not reported.
+func (n NutanixProviderConfig) ChangedFields(other NutanixProviderConfig) ([]string, error) {
+	return changedTopLevelFields(n.normalisedProviderSpec(), other.normalisedProviderSpec())
+}
+
+// normalisedProviderSpec returns the complete provider spec, including the data disks, normalised for comparison.
+// A provider spec without data disks compares as equal to one with an empty list of data disks.
+func (n NutanixProviderConfig) normalisedProviderSpec() nutanixProviderSpec {
+	spec := nutanixProviderSpec{
+		NutanixMachineProviderConfig: *normalisedNutanixProviderConfig(n.providerConfig),
+	}
+
+	if len(n.dataDisks) > 0 {
+		spec.DataDisks = n.dataDisks
+	}
+
+	return spec
+}
+
+// rawProviderSpec returns the complete provider spec, including the data disks, as it should be encoded into
+// the raw provider spec.
+func (n NutanixProviderConfig) rawProviderSpec() nutanixProviderSpec {
+	return nutanixProviderSpec{
+		NutanixMachineProviderConfig: n.providerConfig,
+		DataDisks:                    n.dataDisks,
+	}
+}
+
+// normalisedNutanixProviderConfig returns a copy of the provider spec that is suitable for comparison.
+// The type information is normalised to the only API version that serves the Nutanix provider spec.
+func normalisedNutanixProviderConfig(cfg machinev1.NutanixMachineProviderConfig) *machinev1.NutanixMachineProviderConfig {
+	out := cfg.DeepCopy()
+	out.TypeMeta = metav1.TypeMeta{
+		APIVersion: machineV1APIVersion,
+		Kind:       nutanixProviderConfigKind,
+	}
+
+	return out
+}
+
+// newNutanixProviderConfig creates a NutanixProviderConfig from the raw extension.
+// It should return an error if the provided RawExtension does not represent
+// a NutanixMachineProviderConfig.
+func newNutanixProviderConfig(raw *runtime.RawExtension) (ProviderConfig, error) {
+	spec := nutanixProviderSpec{}
+	if err := decodeProviderSpec(raw, nutanixProviderConfigKind, &spec); err != nil {
+		return nil, fmt.Errorf("could not decode Nutanix provider spec: %w", err)
+	}
+
+	return providerConfig{
+		platformType: configv1.NutanixPlatformType,
+		raw:          raw.Raw,
+		nutanix: NutanixProviderConfig{
+			providerConfig: spec.NutanixMachineProviderConfig,
+			dataDisks:      spec.DataDisks,
+		},
+	}, nil
+}
@@ -0,0 +1,88 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package providerconfig
+
+import (
+	machinev1 "github.com/openshift/api/machine/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+const (
+	// nutanixStorageContainerUUIDType is the type of a storage container identified by its UUID.
+	nutanixStorageContainerUUIDType = "uuid"
+
+	// nutanixDefaultDiskMode is the disk mode of a data disk that does not configure its storage.
+	nutanixDefaultDiskMode = "Standard"
+)
+
+// nutanixProviderSpec is the complete Nutanix provider spec, including the fields that are not yet part of
+// the NutanixMachineProviderConfig API.
+// It is used to decode and encode the raw provider spec so that these fields are preserved.
+type nutanixProviderSpec struct {
+	machinev1.NutanixMachineProviderConfig `json:",inline"`
+
+	// DataDisks are the data disks attached to the virtual machine.
+	// They are kept as written, so that the fields of the disks the operator does not manage are preserved.
+	DataDisks []map[string]interface{} `json:"dataDisks,omitempty"`
+}
+
+// deepCopyNutanixDataDisks returns a deep copy of the data disks.
+func deepCopyNutanixDataDisks(disks []map[string]interface{}) []map[string]interface{} {
+	if disks == nil {
+		return nil
+	}
+
+	out := make([]map[string]interface{}, len(disks))
+	for i, disk := range disks {
+		out[i] = runtime.DeepCopyJSON(disk)
+	}
+
+	return out
+}
+
+// nutanixDataDiskStorageContainer returns the UUID of the storage container of the data disk, or an empty string
+// when the data disk does not set a storage container by UUID.
+func nutanixDataDiskStorageContainer(disk map[string]interface{}) string {
+	storageConfig, _ := disk["storageConfig"].(map[string]interface{})
+	storageContainer, _ := storageConfig["storageContainer"].(map[string]interface{})
+
+	if storageContainer["type"] != nutanixStorageContainerUUIDType {
+		return ""
+	}
+
+	uuid, _ := storageContainer["uuid"].(string)
+
+	return uuid
+}
+
+// setNutanixDataDiskStorageContainer sets the storage container of the data disk to the storage container with the
+// UUID. A data disk without storage configuration is given the default disk mode, as the disk mode is required
+// alongside the storage container.
+func setNutanixDataDiskStorageContainer(disk map[string]interface{}, uuid string) {
+	storageConfig, ok := disk["storageConfig"].(map[string]interface{})
+	if !ok {
+		storageConfig = map[string]interface{}{
+			"diskMode": nutanixDefaultDiskMode,
+		}
+		disk["storageConfig"] = storageConfig
+	}
+
+	storageConfig["storageContainer"] = map[string]interface{}{
+		"type": nutanixStorageContainerUUIDType,
+		"uuid": uuid,
+	}
+}
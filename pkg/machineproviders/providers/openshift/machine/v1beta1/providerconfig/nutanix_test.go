@@ -0,0 +1,301 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package providerconfig
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	configv1 "github.com/openshift/api/config/v1"
+	machinev1 "github.com/openshift/api/machine/v1"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders/providers/openshift/machine/v1beta1/failuredomain"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/test/resourcebuilder"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/utils/pointer"
+)
+
+var _ = Describe("Nutanix Provider Config", func() {
+	Context("ExtractCluster and ExtractSubnet", func() {
+		It("return the configured Prism Element and subnet", func() {
+			nutanixConfig := NutanixProviderConfig{
+				providerConfig: *resourcebuilder.NutanixProviderSpec().WithClusterName("nutanix-prism-element-2").WithSubnetName("nutanix-subnet-2").Build(),
+			}
+
+			Expect(nutanixConfig.ExtractCluster()).To(Equal(machinev1.NutanixResourceIdentifier{
+				Type: machinev1.NutanixIdentifierName,
+				Name: pointer.String("nutanix-prism-element-2"),
+			}))
+			Expect(nutanixConfig.ExtractSubnet()).To(Equal(machinev1.NutanixResourceIdentifier{
+				Type: machinev1.NutanixIdentifierName,
+				Name: pointer.String("nutanix-subnet-2"),
+			}))
+		})
+	})
+
+	Context("InjectStorageContainer", func() {
+		var nutanixConfig NutanixProviderConfig
+
+		BeforeEach(func() {
+			nutanixConfig = NutanixProviderConfig{
+				providerConfig: *resourcebuilder.NutanixProviderSpec().Build(),
+				dataDisks: []map[string]interface{}{
+					{
+						"diskSize": "100Gi",
+						"storageConfig": map[string]interface{}{
+							"diskMode": "Flash",
+							"storageContainer": map[string]interface{}{
+								"type": "uuid",
+								"uuid": "00000000-0000-0000-0000-00000000000a",
+							},
+						},
+					},
+					{
+						"diskSize": "50Gi",
+					},
+				},
+			}
+		})
+
+		It("places each data disk in the storage container", func() {
+			injected := nutanixConfig.InjectStorageContainer("00000000-0000-0000-0000-00000000000b")
+
+			Expect(injected.ExtractStorageContainers()).To(Equal([]string{
+				"00000000-0000-0000-0000-00000000000b",
+				"00000000-0000-0000-0000-00000000000b",
+			}))
+			Expect(nutanixConfig.ExtractStorageContainers()).To(Equal([]string{"00000000-0000-0000-0000-00000000000a", ""}), "The original config should not be modified")
+		})
+
+		It("keeps the disk mode of a data disk, or defaults it when the data disk has no storage configuration", func() {
+			injected := nutanixConfig.InjectStorageContainer("00000000-0000-0000-0000-00000000000b")
+
+			Expect(injected.dataDisks).To(ConsistOf(
+				HaveKeyWithValue("storageConfig", HaveKeyWithValue("diskMode", "Flash")),
+				HaveKeyWithValue("storageConfig", HaveKeyWithValue("diskMode", "Standard")),
+			))
+		})
+
+		It("is not equal to the template once the storage container changes", func() {
+			injected := nutanixConfig.InjectStorageContainer("00000000-0000-0000-0000-00000000000b")
+
+			Expect(injected.Equal(nutanixConfig)).To(BeFalse())
+			Expect(injected.ChangedFields(nutanixConfig)).To(ConsistOf("dataDisks"))
+		})
+
+		It("leaves a provider config without data disks unchanged", func() {
+			withoutDataDisks := NutanixProviderConfig{providerConfig: *resourcebuilder.NutanixProviderSpec().Build()}
+
+			Expect(withoutDataDisks.InjectStorageContainer("00000000-0000-0000-0000-00000000000b").Equal(withoutDataDisks)).To(BeTrue())
+		})
+	})
+
+	Context("InjectFailureDomain", func() {
+		var nutanixConfig NutanixProviderConfig
+
+		BeforeEach(func() {
+			nutanixConfig = NutanixProviderConfig{providerConfig: *resourcebuilder.NutanixProviderSpec().Build()}
+		})
+
+		It("replaces the Prism Element and subnet", func() {
+			injected := nutanixConfig.InjectFailureDomain(failuredomain.NutanixFailureDomain{
+				Name:    "fd-2",
+				Cluster: machinev1.NutanixResourceIdentifier{Type: machinev1.NutanixIdentifierUUID, UUID: pointer.String("00000000-0000-0000-0000-000000000002")},
+				Subnets: []machinev1.NutanixResourceIdentifier{{Type: machinev1.NutanixIdentifierName, Name: pointer.String("nutanix-subnet-2")}},
+			})
+
+			Expect(injected.ExtractCluster()).To(Equal(machinev1.NutanixResourceIdentifier{Type: machinev1.NutanixIdentifierUUID, UUID: pointer.String("00000000-0000-0000-0000-000000000002")}))
+			Expect(injected.ExtractSubnet()).To(Equal(machinev1.NutanixResourceIdentifier{Type: machinev1.NutanixIdentifierName, Name: pointer.String("nutanix-subnet-2")}))
+			Expect(nutanixConfig.ExtractCluster().Name).To(HaveValue(Equal("nutanix-prism-element-1")), "The original config should not be modified")
+		})
+
+		It("keeps the Prism Element and subnet of the template when the failure domain does not set them", func() {
+			injected := nutanixConfig.InjectFailureDomain(failuredomain.NutanixFailureDomain{Name: "fd-1"})
+
+			Expect(injected.Equal(nutanixConfig)).To(BeTrue())
+		})
+
+		It("does not change the provider config when injecting its own failure domain", func() {
+			Expect(nutanixConfig.InjectFailureDomain(nutanixConfig.ExtractFailureDomain()).Equal(nutanixConfig)).To(BeTrue())
+		})
+	})
+
+	Context("Equal", func() {
+		type nutanixEqualTableInput struct {
+			baseConfig    machinev1.NutanixMachineProviderConfig
+			compareConfig machinev1.NutanixMachineProviderConfig
+			expectedEqual bool
+
+			expectedUnmanagedFields []string
+			expectedChangedFields   []string
+		}
+
+		withoutTypeMeta := func(cfg *machinev1.NutanixMachineProviderConfig) machinev1.NutanixMachineProviderConfig {
+			cfg.APIVersion = ""
+			cfg.Kind = ""
+
+			return *cfg
+		}
+
+		DescribeTable("should compare the provider configs", func(in nutanixEqualTableInput) {
+			baseConfig := NutanixProviderConfig{providerConfig: in.baseConfig}
+			compareConfig := NutanixProviderConfig{providerConfig: in.compareConfig}
+
+			Expect(baseConfig.Equal(compareConfig)).To(Equal(in.expectedEqual))
+			Expect(compareConfig.Equal(baseConfig)).To(Equal(in.expectedEqual), "Equality should be symmetric")
+
+			Expect(baseConfig.UnmanagedFields(compareConfig)).To(ConsistOf(in.expectedUnmanagedFields))
+			Expect(compareConfig.UnmanagedFields(baseConfig)).To(ConsistOf(in.expectedUnmanagedFields), "Unmanaged fields should be symmetric")
+
+			Expect(baseConfig.ChangedFields(compareConfig)).To(ConsistOf(in.expectedChangedFields))
+		},
+			Entry("with matching configs", nutanixEqualTableInput{
+				baseConfig:              *resourcebuilder.NutanixProviderSpec().Build(),
+				compareConfig:           *resourcebuilder.NutanixProviderSpec().Build(),
+				expectedEqual:           true,
+				expectedUnmanagedFields: []string{},
+				expectedChangedFields:   []string{},
+			}),
+			Entry("with matching configs where one omits the type information", nutanixEqualTableInput{
+				baseConfig:              withoutTypeMeta(resourcebuilder.NutanixProviderSpec().Build()),
+				compareConfig:           *resourcebuilder.NutanixProviderSpec().Build(),
+				expectedEqual:           true,
+				expectedUnmanagedFields: []string{"apiVersion", "kind"},
+				expectedChangedFields:   []string{},
+			}),
+			Entry("with the same memory size in different units", nutanixEqualTableInput{
+				baseConfig:              *resourcebuilder.NutanixProviderSpec().WithMemorySize(resource.MustParse("16Gi")).Build(),
+				compareConfig:           *resourcebuilder.NutanixProviderSpec().WithMemorySize(resource.MustParse("16384Mi")).Build(),
+				expectedEqual:           true,
+				expectedUnmanagedFields: []string{},
+				expectedChangedFields:   []string{},
+			}),
+			Entry("with a different memory size", nutanixEqualTableInput{
+				baseConfig:              *resourcebuilder.NutanixProviderSpec().Build(),
+				compareConfig:           *resourcebuilder.NutanixProviderSpec().WithMemorySize(resource.MustParse("32Gi")).Build(),
+				expectedEqual:           false,
+				expectedUnmanagedFields: []string{},
+				expectedChangedFields:   []string{"memorySize"},
+			}),
+			Entry("with a different Prism Element", nutanixEqualTableInput{
+				baseConfig:              *resourcebuilder.NutanixProviderSpec().Build(),
+				compareConfig:           *resourcebuilder.NutanixProviderSpec().WithClusterName("nutanix-prism-element-2").Build(),
+				expectedEqual:           false,
+				expectedUnmanagedFields: []string{},
+				expectedChangedFields:   []string{"cluster"},
+			}),
+			Entry("with a different subnet", nutanixEqualTableInput{
+				baseConfig:              *resourcebuilder.NutanixProviderSpec().Build(),
+				compareConfig:           *resourcebuilder.NutanixProviderSpec().WithSubnetName("nutanix-subnet-2").Build(),
+				expectedEqual:           false,
+				expectedUnmanagedFields: []string{},
+				expectedChangedFields:   []string{"subnet"},
+			}),
+			Entry("with a different number of vCPU sockets", nutanixEqualTableInput{
+				baseConfig:              *resourcebuilder.NutanixProviderSpec().Build(),
+				compareConfig:           *resourcebuilder.NutanixProviderSpec().WithVCPUSockets(8).Build(),
+				expectedEqual:           false,
+				expectedUnmanagedFields: []string{},
+				expectedChangedFields:   []string{"vcpuSockets"},
+			}),
+		)
+	})
+
+	Context("newNutanixProviderConfig", func() {
+		var providerConfig ProviderConfig
+		var expectedNutanixConfig machinev1.NutanixMachineProviderConfig
+
+		BeforeEach(func() {
+			configBuilder := resourcebuilder.NutanixProviderSpec()
+			expectedNutanixConfig = *configBuilder.Build()
+			rawConfig := configBuilder.BuildRawExtension()
+
+			var err error
+			providerConfig, err = newNutanixProviderConfig(rawConfig)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("sets the type to Nutanix", func() {
+			Expect(providerConfig.Type()).To(Equal(configv1.NutanixPlatformType))
+		})
+
+		It("returns the correct Nutanix config", func() {
+			Expect(providerConfig.Nutanix().Equal(NutanixProviderConfig{providerConfig: expectedNutanixConfig})).To(BeTrue())
+		})
+
+		It("extracts the Prism Element and subnet as the failure domain", func() {
+			Expect(providerConfig.ExtractFailureDomain()).To(Equal(failuredomain.NewNutanixFailureDomain(failuredomain.NutanixFailureDomain{
+				Cluster: machinev1.NutanixResourceIdentifier{Type: machinev1.NutanixIdentifierName, Name: pointer.String("nutanix-prism-element-1")},
+				Subnets: []machinev1.NutanixResourceIdentifier{{Type: machinev1.NutanixIdentifierName, Name: pointer.String("nutanix-subnet-1")}},
+			})))
+		})
+
+		It("round trips through the raw config", func() {
+			rawConfig, err := providerConfig.RawConfig()
+			Expect(err).ToNot(HaveOccurred())
+
+			roundTripped, err := newNutanixProviderConfig(&runtime.RawExtension{Raw: rawConfig})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(roundTripped.Equal(providerConfig)).To(BeTrue())
+		})
+
+		Context("with data disks", func() {
+			BeforeEach(func() {
+				var err error
+				providerConfig, err = newNutanixProviderConfig(&runtime.RawExtension{
+					Raw: []byte(`{"apiVersion":"machine.openshift.io/v1","kind":"NutanixMachineProviderConfig","dataDisks":[{"diskSize":"100Gi","storageConfig":{"diskMode":"Standard","storageContainer":{"type":"uuid","uuid":"00000000-0000-0000-0000-00000000000a"}}}]}`),
+				})
+				Expect(err).ToNot(HaveOccurred())
+			})
+
+			It("extracts the storage container of the data disks", func() {
+				Expect(providerConfig.Nutanix().ExtractStorageContainers()).To(Equal([]string{"00000000-0000-0000-0000-00000000000a"}))
+			})
+
+			It("preserves the data disks in the raw config", func() {
+				injected, err := providerConfig.InjectStorageContainer("00000000-0000-0000-0000-00000000000b")
+				Expect(err).ToNot(HaveOccurred())
+
+				rawConfig, err := injected.RawConfig()
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(string(rawConfig)).To(ContainSubstring(`"dataDisks":[{"diskSize":"100Gi","storageConfig":{"diskMode":"Standard","storageContainer":{"type":"uuid","uuid":"00000000-0000-0000-0000-00000000000b"}}}]`))
+			})
+
+			It("is not equal to the provider config without the data disks", func() {
+				withoutDataDisks, err := newNutanixProviderConfig(&runtime.RawExtension{
+					Raw: []byte(`{"apiVersion":"machine.openshift.io/v1","kind":"NutanixMachineProviderConfig"}`),
+				})
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(providerConfig.Equal(withoutDataDisks)).To(BeFalse())
+				Expect(providerConfig.ChangedFields(withoutDataDisks)).To(ConsistOf("dataDisks"))
+			})
+		})
+
+		Context("with a provider spec in the Machine API version", func() {
+			It("returns an error", func() {
+				_, err := newNutanixProviderConfig(&runtime.RawExtension{
+					Raw: []byte(`{"apiVersion":"machine.openshift.io/v1beta1","kind":"NutanixMachineProviderConfig"}`),
+				})
+
+				Expect(err).To(MatchError("could not decode Nutanix provider spec: unknown provider spec API version: machine.openshift.io/v1beta1 does not serve NutanixMachineProviderConfig"))
+			})
+		})
+	})
+})
@@ -0,0 +1,83 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package providerconfig
+
+import (
+	"encoding/json"
+	"fmt"
+
+	configv1 "github.com/openshift/api/config/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+const (
+	// externalPlatformType is the platform type of provider specs whose kind is not known to the Machine API.
+	// The External platform has no provider spec kind of its own, and is not known to the vendored API.
+	externalPlatformType configv1.PlatformType = "External"
+)
+
+// OpaqueProviderConfig holds the provider spec of a Machine on a platform without first class support, such as the
+// External platform. The provider spec is treated as an opaque JSON document. It is passed through to new Machines
+// unchanged, and compared by semantic equality, so that the formatting and field order of the provider spec do not
+// matter. Failure domains, images and tags are not supported.
+type OpaqueProviderConfig struct {
+	providerConfig map[string]interface{}
+}
+
+// Config returns a copy of the provider spec, decoded as a generic JSON document.
+func (o OpaqueProviderConfig) Config() map[string]interface{} {
+	return runtime.DeepCopyJSON(o.providerConfig)
+}
+
+// Equal compares two OpaqueProviderConfigs to determine whether or not they are equal.
+// Every field of the provider spec is compared.
+func (o OpaqueProviderConfig) Equal(other OpaqueProviderConfig) bool {
+	return equality.Semantic.DeepEqual(o.providerConfig, other.providerConfig)
+}
+
+// UnmanagedFields returns the paths of the fields that differ between the two OpaqueProviderConfigs, but where the
+// difference is tolerated by Equal. As every field is compared, there are never any unmanaged fields.
+func (o OpaqueProviderConfig) UnmanagedFields(other OpaqueProviderConfig) []string {
+	return nil
+}
+
+// ChangedFields returns the names of the top level fields that differ between the two OpaqueProviderConfigs.
+func (o OpaqueProviderConfig) ChangedFields(other OpaqueProviderConfig) ([]string, error) {
+	return changedTopLevelFields(o.providerConfig, other.providerConfig)
+}
+
+// newOpaqueProviderConfig creates an OpaqueProviderConfig, for the platform type given, from the raw extension.
+// It should return an error if the provided RawExtension is not a JSON object.
+func newOpaqueProviderConfig(platformType configv1.PlatformType, raw *runtime.RawExtension) (ProviderConfig, error) {
+	if raw == nil {
+		return nil, errNilProviderSpec
+	}
+
+	spec := map[string]interface{}{}
+	if err := json.Unmarshal(raw.Raw, &spec); err != nil {
+		return nil, fmt.Errorf("could not decode %s provider spec: %w", platformType, err)
+	}
+
+	return providerConfig{
+		platformType: platformType,
+		raw:          raw.Raw,
+		opaque: OpaqueProviderConfig{
+			providerConfig: spec,
+		},
+	}, nil
+}
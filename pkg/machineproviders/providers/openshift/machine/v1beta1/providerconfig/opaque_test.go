@@ -0,0 +1,113 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package providerconfig
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	configv1 "github.com/openshift/api/config/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+var _ = Describe("Opaque Provider Config", func() {
+	const externalSpec = `{"apiVersion":"example.com/v1","kind":"ExampleMachineProviderSpec","size":"large","network":{"name":"control-plane","mtu":1500}}`
+
+	newConfig := func(raw string) ProviderConfig {
+		providerConfig, err := newOpaqueProviderConfig(externalPlatformType, &runtime.RawExtension{Raw: []byte(raw)})
+		Expect(err).ToNot(HaveOccurred())
+
+		return providerConfig
+	}
+
+	It("reports the platform type given", func() {
+		Expect(newConfig(externalSpec).Type()).To(Equal(externalPlatformType))
+	})
+
+	Context("Equal", func() {
+		It("is equal when only the formatting and field order differ", func() {
+			other := newConfig(`{"kind":"ExampleMachineProviderSpec", "network": {"mtu": 1500.0, "name": "control-plane"}, "size": "large", "apiVersion": "example.com/v1"}`)
+
+			Expect(newConfig(externalSpec).Equal(other)).To(BeTrue())
+		})
+
+		It("is not equal when a nested field differs", func() {
+			other := newConfig(`{"apiVersion":"example.com/v1","kind":"ExampleMachineProviderSpec","size":"large","network":{"name":"control-plane","mtu":9000}}`)
+
+			Expect(newConfig(externalSpec).Equal(other)).To(BeFalse())
+		})
+
+		It("is not equal when a field is missing", func() {
+			other := newConfig(`{"apiVersion":"example.com/v1","kind":"ExampleMachineProviderSpec","size":"large"}`)
+
+			Expect(newConfig(externalSpec).Equal(other)).To(BeFalse())
+		})
+	})
+
+	Context("ChangedFields", func() {
+		It("returns the top level fields that differ", func() {
+			other := newConfig(`{"apiVersion":"example.com/v1","kind":"ExampleMachineProviderSpec","size":"small","network":{"name":"control-plane","mtu":9000}}`)
+
+			Expect(newConfig(externalSpec).ChangedFields(other)).To(Equal([]string{"network", "size"}))
+		})
+	})
+
+	Context("UnmanagedFields", func() {
+		It("returns no fields, as every field is compared", func() {
+			other := newConfig(`{"apiVersion":"example.com/v1","kind":"ExampleMachineProviderSpec","size":"small"}`)
+
+			Expect(newConfig(externalSpec).UnmanagedFields(other)).To(BeEmpty())
+		})
+	})
+
+	Context("RawConfig", func() {
+		It("passes the provider spec through unchanged", func() {
+			raw, err := newConfig(externalSpec).RawConfig()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(raw).To(MatchJSON(externalSpec))
+		})
+	})
+
+	Context("with failure domains, images and tags", func() {
+		It("has no failure domain", func() {
+			Expect(newConfig(externalSpec).ExtractFailureDomain()).To(BeNil())
+		})
+
+		It("does not support injecting an image", func() {
+			_, err := newConfig(externalSpec).InjectImage("image-1")
+			Expect(err).To(MatchError(errUnsupportedPlatformType))
+		})
+
+		It("does not support injecting tags", func() {
+			_, err := newConfig(externalSpec).InjectTags(map[string]string{"owner": "cluster"})
+			Expect(err).To(MatchError(errUnsupportedPlatformType))
+		})
+	})
+
+	It("cannot be compared with a provider config of another platform", func() {
+		other, err := newOpaqueProviderConfig(configv1.OvirtPlatformType, &runtime.RawExtension{Raw: []byte(externalSpec)})
+		Expect(err).ToNot(HaveOccurred())
+
+		_, err = newConfig(externalSpec).Equal(other)
+		Expect(err).To(MatchError(errMismatchedPlatformTypes))
+	})
+
+	It("returns an error when the provider spec is not a JSON object", func() {
+		_, err := newOpaqueProviderConfig(externalPlatformType, &runtime.RawExtension{Raw: []byte(`["not", "an", "object"]`)})
+		Expect(err).To(MatchError(ContainSubstring("could not decode External provider spec")))
+	})
+})
@@ -0,0 +1,300 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package providerconfig
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	configv1 "github.com/openshift/api/config/v1"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders/providers/openshift/machine/v1beta1/failuredomain"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+const (
+	// openStackAvailabilityZoneField is the name of the field of the OpenStack provider spec holding the Nova
+	// availability zone of the instance. The root volume holds its Cinder availability zone in a field of the
+	// same name.
+	openStackAvailabilityZoneField = "availabilityZone"
+
+	// openStackRootVolumeField is the name of the root volume field of the OpenStack provider spec.
+	openStackRootVolumeField = "rootVolume"
+
+	// openStackPortsField is the name of the field of the OpenStack provider spec holding the additional ports
+	// of the instance, including their trunk and port security configuration.
+	openStackPortsField = "ports"
+
+	// openStackNetworksField is the name of the field of the OpenStack provider spec holding the networks that
+	// the instance is attached to.
+	openStackNetworksField = "networks"
+
+	// openStackNetworkUUIDField is the name of the field of an OpenStack network holding its UUID.
+	openStackNetworkUUIDField = "uuid"
+
+	// openStackSecurityGroupsField is the name of the field of the OpenStack provider spec holding the security
+	// groups of the instance. Each port holds its own security groups in a field of the same name.
+	openStackSecurityGroupsField = "securityGroups"
+)
+
+// OpenStackProviderConfig holds the provider spec of an OpenStack Machine.
+// The OpenStack API is not a dependency of the operator, so the provider spec is passed through unchanged,
+// other than the availability zones of the instance and its root volume, which make up the failure domain.
+type OpenStackProviderConfig struct {
+	providerConfig openStackProviderSpec
+
+	// fields holds every top level field of the provider spec, so that the provider spec can be
+	// passed through, and compared, without losing the fields that are not decoded.
+	fields map[string]interface{}
+}
+
+// openStackProviderSpec is the subset of the OpenstackProviderSpec that the operator reads.
+type openStackProviderSpec struct {
+	metav1.TypeMeta `json:",inline"`
+
+	// Flavor is the flavor, the hardware configuration, of the instance.
+	Flavor string `json:"flavor,omitempty"`
+
+	// AvailabilityZone is the Nova availability zone in which the instance is created.
+	AvailabilityZone string `json:"availabilityZone,omitempty"`
+
+	// RootVolume, when set, configures the instance to boot from a Cinder volume.
+	RootVolume *openStackRootVolume `json:"rootVolume,omitempty"`
+}
+
+// openStackRootVolume is the subset of the root volume of the OpenstackProviderSpec that the operator reads.
+type openStackRootVolume struct {
+	// AvailabilityZone is the Cinder availability zone in which the root volume is created.
+	AvailabilityZone string `json:"availabilityZone,omitempty"`
+}
+
+// InjectFailureDomain returns a new OpenStackProviderConfig configured with the availability zones of the
+// failure domain. The Nova availability zone is set when the failure domain has one. The Cinder availability
+// zone of the root volume is set when the failure domain has one and the instance boots from a root volume.
+// Availability zones that the failure domain does not set are left unchanged.
+func (o OpenStackProviderConfig) InjectFailureDomain(fd failuredomain.OpenStackFailureDomain) OpenStackProviderConfig {
+	newOpenStackProviderConfig := o
+	fields := runtime.DeepCopyJSON(o.fields)
+
+	if fd.AvailabilityZone != "" {
+		newOpenStackProviderConfig.providerConfig.AvailabilityZone = fd.AvailabilityZone
+		fields[openStackAvailabilityZoneField] = fd.AvailabilityZone
+	}
+
+	if rootVolume, ok := fields[openStackRootVolumeField].(map[string]interface{}); ok && fd.RootVolumeAvailabilityZone != "" {
+		newOpenStackProviderConfig.providerConfig.RootVolume = &openStackRootVolume{
+			AvailabilityZone: fd.RootVolumeAvailabilityZone,
+		}
+		rootVolume[openStackAvailabilityZoneField] = fd.RootVolumeAvailabilityZone
+	}
+
+	newOpenStackProviderConfig.fields = fields
+
+	return newOpenStackProviderConfig
+}
+
+// ExtractFailureDomain returns the failure domain of the instance, the Nova availability zone of the instance
+// and the Cinder availability zone of its root volume. The root volume availability zone is empty when the
+// instance does not boot from a root volume.
+func (o OpenStackProviderConfig) ExtractFailureDomain() failuredomain.OpenStackFailureDomain {
+	fd := failuredomain.OpenStackFailureDomain{
+		AvailabilityZone: o.providerConfig.AvailabilityZone,
+	}
+
+	if o.providerConfig.RootVolume != nil {
+		fd.RootVolumeAvailabilityZone = o.providerConfig.RootVolume.AvailabilityZone
+	}
+
+	return fd
+}
+
+// ExtractNetworks returns the UUIDs of the networks, identified by UUID, that the instance is attached to.
+func (o OpenStackProviderConfig) ExtractNetworks() []string {
+	networks := []string{}
+
+	list, _ := o.fields[openStackNetworksField].([]interface{})
+	for _, network := range list {
+		network, _ := network.(map[string]interface{})
+		if uuid, ok := network[openStackNetworkUUIDField].(string); ok && uuid != "" {
+			networks = append(networks, uuid)
+		}
+	}
+
+	return networks
+}
+
+// InjectAdditionalNetworks returns a new OpenStackProviderConfig with the instance also attached to the networks
+// with the UUIDs. The networks are appended after those of the template, so that the primary network of the
+// instance is unchanged. Networks that the instance is already attached to are not added again.
+func (o OpenStackProviderConfig) InjectAdditionalNetworks(networks []string) OpenStackProviderConfig {
+	newOpenStackProviderConfig := o
+	fields := runtime.DeepCopyJSON(o.fields)
+
+	attached := sets.NewString(o.ExtractNetworks()...)
+	list, _ := fields[openStackNetworksField].([]interface{})
+
+	for _, uuid := range networks {
+		if attached.Has(uuid) {
+			continue
+		}
+
+		list = append(list, map[string]interface{}{openStackNetworkUUIDField: uuid})
+		attached.Insert(uuid)
+	}
+
+	if len(list) > 0 {
+		fields[openStackNetworksField] = list
+	}
+
+	newOpenStackProviderConfig.fields = fields
+
+	return newOpenStackProviderConfig
+}
+
+// ExtractFlavor returns the flavor of the instance.
+func (o OpenStackProviderConfig) ExtractFlavor() string {
+	return o.providerConfig.Flavor
+}
+
+// Equal compares the OpenStackProviderConfig with another OpenStackProviderConfig.
+// Every field of the provider spec is compared, other than the type information, so that a
+// provider spec that omits it compares as equal to one that sets it. An empty availability zone
+// compares as equal to an omitted one. The ports and security groups, of the instance and of each
+// port, are compared regardless of their order.
+func (o OpenStackProviderConfig) Equal(other OpenStackProviderConfig) bool {
+	return equality.Semantic.DeepEqual(normalisedOpenStackFields(o.fields), normalisedOpenStackFields(other.fields))
+}
+
+// UnmanagedFields returns the paths of the fields that differ between the OpenStackProviderConfigs
+// but where the difference is deliberately tolerated by Equal.
+// These are the type information of the provider specs.
+func (o OpenStackProviderConfig) UnmanagedFields(other OpenStackProviderConfig) []string {
+	var fields []string
+
+	if o.providerConfig.APIVersion != other.providerConfig.APIVersion {
+		fields = append(fields, "apiVersion")
+	}
+
+	if o.providerConfig.Kind != other.providerConfig.Kind {
+		fields = append(fields, "kind")
+	}
+
+	return fields
+}
+
+// ChangedFields returns the names of the top level fields of the provider spec that differ
+// between the OpenStackProviderConfigs.
+// The provider specs are normalised in the same way as within Equal, so differences that Equal
+// tolerates are not reported.
+func (o OpenStackProviderConfig) ChangedFields(other OpenStackProviderConfig) ([]string, error) {
+	return changedTopLevelFields(normalisedOpenStackFields(o.fields), normalisedOpenStackFields(other.fields))
+}
+
+// normalisedOpenStackFields returns a copy of the fields of the provider spec that is suitable for comparison.
+// The type information is removed, as the schema of the provider spec is identical across the API versions that
+// serve it. Empty availability zones, of the instance and of its root volume, are removed, as OpenStack treats an
+// empty availability zone as though it were omitted.
+// The ports and security groups, of the instance and of each port, are sorted, so that lists that hold the same
+// entries in a different order compare as equal. The trunk and port security settings of each port are compared
+// as they are written.
+func normalisedOpenStackFields(fields map[string]interface{}) map[string]interface{} {
+	out := runtime.DeepCopyJSON(fields)
+	delete(out, "apiVersion")
+	delete(out, "kind")
+
+	if zone, ok := out[openStackAvailabilityZoneField].(string); ok && zone == "" {
+		delete(out, openStackAvailabilityZoneField)
+	}
+
+	if rootVolume, ok := out[openStackRootVolumeField].(map[string]interface{}); ok {
+		if zone, ok := rootVolume[openStackAvailabilityZoneField].(string); ok && zone == "" {
+			delete(rootVolume, openStackAvailabilityZoneField)
+		}
+	}
+
+	if ports, ok := out[openStackPortsField].([]interface{}); ok {
+		for _, port := range ports {
+			if port, ok := port.(map[string]interface{}); ok {
+				sortOpenStackList(port[openStackSecurityGroupsField])
+			}
+		}
+
+		sortOpenStackList(ports)
+	}
+
+	sortOpenStackList(out[openStackSecurityGroupsField])
+
+	return out
+}
+
+// sortOpenStackList sorts, in place, a list of the provider spec by the JSON encoding of its entries.
+// The JSON encoding of an object sorts its keys, so entries that hold the same fields sort alongside each other.
+// Values other than lists are left unchanged.
+func sortOpenStackList(value interface{}) {
+	list, ok := value.([]interface{})
+	if !ok {
+		return
+	}
+
+	type keyedEntry struct {
+		key   string
+		value interface{}
+	}
+
+	entries := make([]keyedEntry, len(list))
+
+	for i, value := range list {
+		// The entries were decoded from JSON, so they can always be encoded again.
+		key, _ := json.Marshal(value)
+		entries[i] = keyedEntry{key: string(key), value: value}
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].key < entries[j].key
+	})
+
+	for i, entry := range entries {
+		list[i] = entry.value
+	}
+}
+
+// newOpenStackProviderConfig creates an OpenStackProviderConfig from the raw extension.
+// It should return an error if the provided RawExtension does not represent
+// an OpenstackProviderSpec.
+func newOpenStackProviderConfig(raw *runtime.RawExtension) (ProviderConfig, error) {
+	openStackMachineProviderSpec := openStackProviderSpec{}
+	if err := decodeProviderSpec(raw, openStackProviderConfigKind, &openStackMachineProviderSpec); err != nil {
+		return nil, fmt.Errorf("could not decode OpenStack provider spec: %w", err)
+	}
+
+	fields := map[string]interface{}{}
+	if err := json.Unmarshal(raw.Raw, &fields); err != nil {
+		return nil, fmt.Errorf("could not decode OpenStack provider spec: %w", err)
+	}
+
+	return providerConfig{
+		platformType: configv1.OpenStackPlatformType,
+		raw:          raw.Raw,
+		openStack: OpenStackProviderConfig{
+			providerConfig: openStackMachineProviderSpec,
+			fields:         fields,
+		},
+	}, nil
+}
@@ -0,0 +1,382 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package providerconfig
+
+import (
+	"encoding/json"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	configv1 "github.com/openshift/api/config/v1"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders/providers/openshift/machine/v1beta1/failuredomain"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/test/resourcebuilder"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+var _ = Describe("OpenStack Provider Config", func() {
+	openStackConfig := func(builder resourcebuilder.OpenStackProviderSpecBuilder) OpenStackProviderConfig {
+		providerConfig, err := newOpenStackProviderConfig(builder.BuildRawExtension())
+		Expect(err).ToNot(HaveOccurred())
+
+		return providerConfig.OpenStack()
+	}
+
+	Context("ExtractFailureDomain", func() {
+		It("returns the availability zones of the instance and the root volume", func() {
+			config := openStackConfig(resourcebuilder.OpenStackProviderSpec())
+
+			Expect(config.ExtractFailureDomain()).To(Equal(failuredomain.OpenStackFailureDomain{
+				AvailabilityZone:           "nova-az1",
+				RootVolumeAvailabilityZone: "cinder-az1",
+			}))
+		})
+
+		It("returns no root volume availability zone when the instance does not boot from a root volume", func() {
+			config := openStackConfig(resourcebuilder.OpenStackProviderSpec().WithRootVolume(false))
+
+			Expect(config.ExtractFailureDomain()).To(Equal(failuredomain.OpenStackFailureDomain{
+				AvailabilityZone: "nova-az1",
+			}))
+		})
+	})
+
+	Context("InjectFailureDomain", func() {
+		It("replaces the availability zones of the instance and the root volume", func() {
+			config := openStackConfig(resourcebuilder.OpenStackProviderSpec())
+			injected := config.InjectFailureDomain(failuredomain.OpenStackFailureDomain{
+				AvailabilityZone:           "nova-az2",
+				RootVolumeAvailabilityZone: "cinder-az2",
+			})
+
+			expected := openStackConfig(resourcebuilder.OpenStackProviderSpec().WithAvailabilityZone("nova-az2").WithRootVolumeAvailabilityZone("cinder-az2"))
+			Expect(injected.Equal(expected)).To(BeTrue())
+			Expect(injected.ExtractFailureDomain()).To(Equal(expected.ExtractFailureDomain()))
+			Expect(config.ExtractFailureDomain().AvailabilityZone).To(Equal("nova-az1"), "The original config should not be modified")
+			Expect(config.ExtractFailureDomain().RootVolumeAvailabilityZone).To(Equal("cinder-az1"), "The original config should not be modified")
+		})
+
+		It("leaves the root volume availability zone unchanged when the failure domain does not set it", func() {
+			config := openStackConfig(resourcebuilder.OpenStackProviderSpec())
+			injected := config.InjectFailureDomain(failuredomain.OpenStackFailureDomain{AvailabilityZone: "nova-az2"})
+
+			Expect(injected.Equal(openStackConfig(resourcebuilder.OpenStackProviderSpec().WithAvailabilityZone("nova-az2")))).To(BeTrue())
+		})
+
+		It("does not add a root volume when the instance does not boot from a root volume", func() {
+			config := openStackConfig(resourcebuilder.OpenStackProviderSpec().WithRootVolume(false))
+			injected := config.InjectFailureDomain(failuredomain.OpenStackFailureDomain{
+				AvailabilityZone:           "nova-az2",
+				RootVolumeAvailabilityZone: "cinder-az2",
+			})
+
+			Expect(injected.fields).ToNot(HaveKey("rootVolume"))
+			Expect(injected.ExtractFailureDomain()).To(Equal(failuredomain.OpenStackFailureDomain{AvailabilityZone: "nova-az2"}))
+		})
+
+		It("does not change the provider config when injecting its own failure domain", func() {
+			config := openStackConfig(resourcebuilder.OpenStackProviderSpec())
+
+			Expect(config.InjectFailureDomain(config.ExtractFailureDomain()).Equal(config)).To(BeTrue())
+		})
+	})
+
+	Context("InjectAdditionalNetworks", func() {
+		var config OpenStackProviderConfig
+
+		BeforeEach(func() {
+			config = openStackConfig(resourcebuilder.OpenStackProviderSpec())
+			config.fields["networks"] = []interface{}{
+				map[string]interface{}{"uuid": "00000000-0000-0000-0000-000000000001"},
+			}
+		})
+
+		It("attaches the instance to the networks after those of the template", func() {
+			injected := config.InjectAdditionalNetworks([]string{"00000000-0000-0000-0000-000000000002", "00000000-0000-0000-0000-000000000003"})
+
+			Expect(injected.ExtractNetworks()).To(Equal([]string{
+				"00000000-0000-0000-0000-000000000001",
+				"00000000-0000-0000-0000-000000000002",
+				"00000000-0000-0000-0000-000000000003",
+			}))
+			Expect(config.ExtractNetworks()).To(Equal([]string{"00000000-0000-0000-0000-000000000001"}), "The original config should not be modified")
+		})
+
+		It("does not attach the instance to a network more than once", func() {
+			injected := config.InjectAdditionalNetworks([]string{"00000000-0000-0000-0000-000000000001", "00000000-0000-0000-0000-000000000002"})
+
+			Expect(injected.ExtractNetworks()).To(Equal([]string{
+				"00000000-0000-0000-0000-000000000001",
+				"00000000-0000-0000-0000-000000000002",
+			}))
+			Expect(injected.InjectAdditionalNetworks([]string{"00000000-0000-0000-0000-000000000002"}).Equal(injected)).To(BeTrue())
+		})
+
+		It("adds the networks to a template without networks", func() {
+			withoutNetworks := openStackConfig(resourcebuilder.OpenStackProviderSpec())
+			injected := withoutNetworks.InjectAdditionalNetworks([]string{"00000000-0000-0000-0000-000000000002"})
+
+			Expect(injected.ExtractNetworks()).To(Equal([]string{"00000000-0000-0000-0000-000000000002"}))
+			Expect(injected.ChangedFields(withoutNetworks)).To(ConsistOf("networks"))
+		})
+	})
+
+	Context("Equal", func() {
+		type openStackEqualTableInput struct {
+			baseConfig    resourcebuilder.OpenStackProviderSpecBuilder
+			compareConfig resourcebuilder.OpenStackProviderSpecBuilder
+			expectedEqual bool
+
+			expectedUnmanagedFields []string
+			expectedChangedFields   []string
+		}
+
+		DescribeTable("should compare the provider configs", func(in openStackEqualTableInput) {
+			baseConfig := openStackConfig(in.baseConfig)
+			compareConfig := openStackConfig(in.compareConfig)
+
+			Expect(baseConfig.Equal(compareConfig)).To(Equal(in.expectedEqual))
+			Expect(compareConfig.Equal(baseConfig)).To(Equal(in.expectedEqual), "Equality should be symmetric")
+
+			Expect(baseConfig.UnmanagedFields(compareConfig)).To(ConsistOf(in.expectedUnmanagedFields))
+			Expect(compareConfig.UnmanagedFields(baseConfig)).To(ConsistOf(in.expectedUnmanagedFields), "Unmanaged fields should be symmetric")
+
+			Expect(baseConfig.ChangedFields(compareConfig)).To(ConsistOf(in.expectedChangedFields))
+		},
+			Entry("with matching configs", openStackEqualTableInput{
+				baseConfig:              resourcebuilder.OpenStackProviderSpec(),
+				compareConfig:           resourcebuilder.OpenStackProviderSpec(),
+				expectedEqual:           true,
+				expectedUnmanagedFields: []string{},
+				expectedChangedFields:   []string{},
+			}),
+			Entry("with a different flavor", openStackEqualTableInput{
+				baseConfig:              resourcebuilder.OpenStackProviderSpec(),
+				compareConfig:           resourcebuilder.OpenStackProviderSpec().WithFlavor("m1.2xlarge"),
+				expectedEqual:           false,
+				expectedUnmanagedFields: []string{},
+				expectedChangedFields:   []string{"flavor"},
+			}),
+			Entry("with a different availability zone", openStackEqualTableInput{
+				baseConfig:              resourcebuilder.OpenStackProviderSpec(),
+				compareConfig:           resourcebuilder.OpenStackProviderSpec().WithAvailabilityZone("nova-az2"),
+				expectedEqual:           false,
+				expectedUnmanagedFields: []string{},
+				expectedChangedFields:   []string{"availabilityZone"},
+			}),
+			Entry("with a different root volume availability zone", openStackEqualTableInput{
+				baseConfig:              resourcebuilder.OpenStackProviderSpec(),
+				compareConfig:           resourcebuilder.OpenStackProviderSpec().WithRootVolumeAvailabilityZone("cinder-az2"),
+				expectedEqual:           false,
+				expectedUnmanagedFields: []string{},
+				expectedChangedFields:   []string{"rootVolume"},
+			}),
+			Entry("with the legacy API version", openStackEqualTableInput{
+				baseConfig:              resourcebuilder.OpenStackProviderSpec(),
+				compareConfig:           resourcebuilder.OpenStackProviderSpec().WithAPIVersion("openstackproviderconfig.openshift.io/v1alpha1"),
+				expectedEqual:           true,
+				expectedUnmanagedFields: []string{"apiVersion"},
+				expectedChangedFields:   []string{},
+			}),
+		)
+
+		It("treats empty availability zones as omitted", func() {
+			config := openStackConfig(resourcebuilder.OpenStackProviderSpec().WithAvailabilityZone("").WithRootVolumeAvailabilityZone(""))
+			withEmptyZones := config
+			withEmptyZones.fields = runtime.DeepCopyJSON(config.fields)
+			withEmptyZones.fields["availabilityZone"] = ""
+			withEmptyZones.fields["rootVolume"].(map[string]interface{})["availabilityZone"] = ""
+
+			Expect(config.Equal(withEmptyZones)).To(BeTrue())
+			Expect(config.ChangedFields(withEmptyZones)).To(BeEmpty())
+		})
+
+		Context("with ports and security groups", func() {
+			withFields := func(rawFields string) OpenStackProviderConfig {
+				config := openStackConfig(resourcebuilder.OpenStackProviderSpec())
+				config.fields = runtime.DeepCopyJSON(config.fields)
+
+				fields := map[string]interface{}{}
+				Expect(json.Unmarshal([]byte(rawFields), &fields)).To(Succeed())
+
+				for name, value := range fields {
+					config.fields[name] = value
+				}
+
+				return config
+			}
+
+			config := func() OpenStackProviderConfig {
+				return withFields(`{
+					"securityGroups": [{"name": "master"}, {"uuid": "00000000-0000-0000-0000-000000000001"}],
+					"ports": [
+						{"networkID": "sriov-network", "vnicType": "direct", "portSecurity": false, "trunk": false},
+						{"networkID": "trunk-network", "trunk": true, "securityGroups": ["sg-1", "sg-2"]}
+					]
+				}`)
+			}
+
+			It("is equal when the ports and security groups are listed in a different order", func() {
+				reordered := withFields(`{
+					"securityGroups": [{"uuid": "00000000-0000-0000-0000-000000000001"}, {"name": "master"}],
+					"ports": [
+						{"networkID": "trunk-network", "trunk": true, "securityGroups": ["sg-2", "sg-1"]},
+						{"networkID": "sriov-network", "vnicType": "direct", "portSecurity": false, "trunk": false}
+					]
+				}`)
+
+				Expect(config().Equal(reordered)).To(BeTrue())
+				Expect(reordered.Equal(config())).To(BeTrue(), "Equality should be symmetric")
+				Expect(config().ChangedFields(reordered)).To(BeEmpty())
+			})
+
+			It("does not reorder the ports of the provider spec", func() {
+				reordered := withFields(`{
+					"ports": [{"networkID": "trunk-network"}, {"networkID": "sriov-network"}]
+				}`)
+
+				Expect(reordered.Equal(config())).To(BeFalse())
+				Expect(reordered.fields["ports"]).To(Equal([]interface{}{
+					map[string]interface{}{"networkID": "trunk-network"},
+					map[string]interface{}{"networkID": "sriov-network"},
+				}))
+			})
+
+			It("is not equal when the trunk setting of a port differs", func() {
+				withoutTrunk := withFields(`{
+					"securityGroups": [{"name": "master"}, {"uuid": "00000000-0000-0000-0000-000000000001"}],
+					"ports": [
+						{"networkID": "sriov-network", "vnicType": "direct", "portSecurity": false, "trunk": false},
+						{"networkID": "trunk-network", "trunk": false, "securityGroups": ["sg-1", "sg-2"]}
+					]
+				}`)
+
+				Expect(config().Equal(withoutTrunk)).To(BeFalse())
+				Expect(config().ChangedFields(withoutTrunk)).To(ConsistOf("ports"))
+			})
+
+			It("is not equal when the port security of a port differs", func() {
+				withPortSecurity := withFields(`{
+					"securityGroups": [{"name": "master"}, {"uuid": "00000000-0000-0000-0000-000000000001"}],
+					"ports": [
+						{"networkID": "sriov-network", "vnicType": "direct", "portSecurity": true, "trunk": false},
+						{"networkID": "trunk-network", "trunk": true, "securityGroups": ["sg-1", "sg-2"]}
+					]
+				}`)
+
+				Expect(config().Equal(withPortSecurity)).To(BeFalse())
+				Expect(config().ChangedFields(withPortSecurity)).To(ConsistOf("ports"))
+			})
+
+			It("is not equal when the security groups of a port differ", func() {
+				withOtherSecurityGroups := withFields(`{
+					"securityGroups": [{"name": "master"}, {"uuid": "00000000-0000-0000-0000-000000000001"}],
+					"ports": [
+						{"networkID": "sriov-network", "vnicType": "direct", "portSecurity": false, "trunk": false},
+						{"networkID": "trunk-network", "trunk": true, "securityGroups": ["sg-1", "sg-3"]}
+					]
+				}`)
+
+				Expect(config().Equal(withOtherSecurityGroups)).To(BeFalse())
+				Expect(config().ChangedFields(withOtherSecurityGroups)).To(ConsistOf("ports"))
+			})
+
+			It("is not equal when the security groups of the instance differ", func() {
+				withOtherSecurityGroups := withFields(`{
+					"securityGroups": [{"name": "master"}],
+					"ports": [
+						{"networkID": "sriov-network", "vnicType": "direct", "portSecurity": false, "trunk": false},
+						{"networkID": "trunk-network", "trunk": true, "securityGroups": ["sg-1", "sg-2"]}
+					]
+				}`)
+
+				Expect(config().Equal(withOtherSecurityGroups)).To(BeFalse())
+				Expect(config().ChangedFields(withOtherSecurityGroups)).To(ConsistOf("securityGroups"))
+			})
+		})
+	})
+
+	Context("newOpenStackProviderConfig", func() {
+		var providerConfig ProviderConfig
+		var rawConfig *runtime.RawExtension
+
+		BeforeEach(func() {
+			rawConfig = resourcebuilder.OpenStackProviderSpec().BuildRawExtension()
+
+			var err error
+			providerConfig, err = newOpenStackProviderConfig(rawConfig)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("sets the type to OpenStack", func() {
+			Expect(providerConfig.Type()).To(Equal(configv1.OpenStackPlatformType))
+		})
+
+		It("extracts the flavor as the instance type", func() {
+			Expect(providerConfig.ExtractInstanceType()).To(Equal("m1.xlarge"))
+		})
+
+		It("extracts the failure domain", func() {
+			Expect(providerConfig.ExtractFailureDomain()).To(Equal(failuredomain.NewOpenStackFailureDomain(failuredomain.OpenStackFailureDomain{
+				AvailabilityZone:           "nova-az1",
+				RootVolumeAvailabilityZone: "cinder-az1",
+			})))
+		})
+
+		It("passes every field of the provider spec through the raw config", func() {
+			raw, err := providerConfig.RawConfig()
+			Expect(err).ToNot(HaveOccurred())
+
+			var fields, expectedFields map[string]interface{}
+			Expect(json.Unmarshal(raw, &fields)).To(Succeed())
+			Expect(json.Unmarshal(rawConfig.Raw, &expectedFields)).To(Succeed())
+
+			Expect(fields).To(Equal(expectedFields))
+			Expect(fields).To(HaveKey("cloudsSecret"))
+		})
+
+		Context("when injecting a failure domain", func() {
+			It("updates the raw config with both availability zones", func() {
+				injected, err := providerConfig.InjectFailureDomain(failuredomain.NewOpenStackFailureDomain(failuredomain.OpenStackFailureDomain{
+					AvailabilityZone:           "nova-az3",
+					RootVolumeAvailabilityZone: "cinder-az3",
+				}))
+				Expect(err).ToNot(HaveOccurred())
+
+				raw, err := injected.RawConfig()
+				Expect(err).ToNot(HaveOccurred())
+
+				var fields map[string]interface{}
+				Expect(json.Unmarshal(raw, &fields)).To(Succeed())
+
+				Expect(fields).To(HaveKeyWithValue("availabilityZone", "nova-az3"))
+				Expect(fields).To(HaveKeyWithValue("rootVolume", HaveKeyWithValue("availabilityZone", "cinder-az3")))
+				Expect(fields).To(HaveKeyWithValue("rootVolume", HaveKeyWithValue("volumeType", "performance")))
+			})
+		})
+
+		Context("with a provider spec in an unknown API version", func() {
+			It("returns an error", func() {
+				_, err := newOpenStackProviderConfig(&runtime.RawExtension{
+					Raw: []byte(`{"apiVersion":"machine.openshift.io/v1beta1","kind":"OpenstackProviderSpec"}`),
+				})
+
+				Expect(err).To(MatchError("could not decode OpenStack provider spec: unknown provider spec API version: machine.openshift.io/v1beta1 does not serve OpenstackProviderSpec"))
+			})
+		})
+	})
+})
@@ -0,0 +1,183 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package providerconfig
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	machinev1beta1 "github.com/openshift/api/machine/v1beta1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders"
+)
+
+const (
+	// OwnedFieldsAnnotation is the annotation on the ControlPlaneMachineSet used by other controllers to claim fields
+	// of the provider spec of the Control Plane Machines. The value is a comma separated list of JSONPaths, relative to
+	// the provider spec, eg `.metadata.labels,{.userDataSecret}`. Claimed fields are excluded when Machines are compared
+	// with the template, and are omitted from the provider spec of new Machines, so that the owning controller may set
+	// them without causing the Machines to be replaced.
+	OwnedFieldsAnnotation = "controlplanemachineset.machine.openshift.io/externally-owned-fields"
+)
+
+var (
+	// errInvalidOwnedFields is used to denote that the externally owned fields annotation is not in the
+	// expected format.
+	errInvalidOwnedFields = machineproviders.NewConfigurationError(machineproviders.ReasonInvalidOwnedFields, fmt.Sprintf("invalid value for annotation %s: expected a comma separated list of JSONPaths of the form .<field>[.<field>...]", OwnedFieldsAnnotation))
+
+	// errUnownableField is used to denote that the externally owned fields annotation claims a field that identifies
+	// the provider spec, and so cannot be owned by another controller.
+	errUnownableField = machineproviders.NewConfigurationError(machineproviders.ReasonInvalidOwnedFields, fmt.Sprintf("invalid value for annotation %s: field cannot be owned by another controller", OwnedFieldsAnnotation))
+
+	// ownedFieldNameRegex matches a single field name within an owned field path.
+	ownedFieldNameRegex = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+)
+
+// OwnedFields are the fields of the provider spec claimed by other controllers through the externally owned fields
+// annotation. The zero value claims no fields.
+type OwnedFields struct {
+	// paths are the claimed fields, each as a list of field names from the root of the provider spec.
+	paths [][]string
+}
+
+// ParseOwnedFields parses the externally owned fields annotation from the annotations of the ControlPlaneMachineSet.
+// The apiVersion and kind of the provider spec cannot be claimed, as they are required to decode the provider spec.
+func ParseOwnedFields(annotations map[string]string) (OwnedFields, error) {
+	value, ok := annotations[OwnedFieldsAnnotation]
+	if !ok {
+		return OwnedFields{}, nil
+	}
+
+	paths := [][]string{}
+
+	for _, path := range strings.Split(value, ",") {
+		fields, err := parseOwnedFieldPath(strings.TrimSpace(path))
+		if err != nil {
+			return OwnedFields{}, err
+		}
+
+		paths = append(paths, fields)
+	}
+
+	return OwnedFields{paths: paths}, nil
+}
+
+// parseOwnedFieldPath parses a single JSONPath into the field names that it is made up of.
+// Only paths made up of field names are supported, as the fields are removed from both sides of each comparison,
+// and array indexes or filters would not identify the same field across provider specs.
+func parseOwnedFieldPath(path string) ([]string, error) {
+	if strings.HasPrefix(path, "{") && strings.HasSuffix(path, "}") {
+		path = strings.TrimSuffix(strings.TrimPrefix(path, "{"), "}")
+	}
+
+	if !strings.HasPrefix(path, ".") {
+		return nil, fmt.Errorf("%w, got %q", errInvalidOwnedFields, path)
+	}
+
+	fields := strings.Split(strings.TrimPrefix(path, "."), ".")
+	for _, field := range fields {
+		if !ownedFieldNameRegex.MatchString(field) {
+			return nil, fmt.Errorf("%w, got %q", errInvalidOwnedFields, path)
+		}
+	}
+
+	if len(fields) == 1 && (fields[0] == "apiVersion" || fields[0] == "kind") {
+		return nil, fmt.Errorf("%w, got %q", errUnownableField, path)
+	}
+
+	return fields, nil
+}
+
+// IsEmpty determines whether no fields are claimed.
+func (o OwnedFields) IsEmpty() bool {
+	return len(o.paths) == 0
+}
+
+// String returns the claimed fields as a comma separated list of JSONPaths.
+func (o OwnedFields) String() string {
+	paths := []string{}
+	for _, path := range o.paths {
+		paths = append(paths, "."+strings.Join(path, "."))
+	}
+
+	return strings.Join(paths, ",")
+}
+
+// RemoveFromRaw removes the claimed fields from the raw provider spec.
+// Claimed fields that are not present within the raw provider spec are ignored.
+func (o OwnedFields) RemoveFromRaw(raw []byte) ([]byte, error) {
+	if o.IsEmpty() {
+		return raw, nil
+	}
+
+	spec := map[string]interface{}{}
+	if err := json.Unmarshal(raw, &spec); err != nil {
+		return nil, fmt.Errorf("could not unmarshal provider spec: %w", err)
+	}
+
+	for _, path := range o.paths {
+		removeField(spec, path)
+	}
+
+	out, err := json.Marshal(spec)
+	if err != nil {
+		return nil, fmt.Errorf("could not marshal provider spec: %w", err)
+	}
+
+	return out, nil
+}
+
+// Remove returns a copy of the provider config with the claimed fields removed.
+func (o OwnedFields) Remove(pc ProviderConfig) (ProviderConfig, error) {
+	if o.IsEmpty() {
+		return pc, nil
+	}
+
+	raw, err := pc.RawConfig()
+	if err != nil {
+		return nil, fmt.Errorf("could not get raw provider config: %w", err)
+	}
+
+	raw, err = o.RemoveFromRaw(raw)
+	if err != nil {
+		return nil, fmt.Errorf("could not remove owned fields: %w", err)
+	}
+
+	return NewProviderConfigFromMachineSpec(machinev1beta1.MachineSpec{
+		ProviderSpec: machinev1beta1.ProviderSpec{
+			Value: &runtime.RawExtension{Raw: raw},
+		},
+	})
+}
+
+// removeField removes the field at the path from the object, when present.
+func removeField(obj map[string]interface{}, path []string) {
+	if len(path) == 1 {
+		delete(obj, path[0])
+		return
+	}
+
+	child, ok := obj[path[0]].(map[string]interface{})
+	if !ok {
+		return
+	}
+
+	removeField(child, path[1:])
+}
@@ -0,0 +1,150 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package providerconfig
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	machinev1 "github.com/openshift/api/machine/v1"
+	machinev1beta1 "github.com/openshift/api/machine/v1beta1"
+	"k8s.io/utils/pointer"
+
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/test/resourcebuilder"
+)
+
+var _ = Describe("Owned Fields", func() {
+	type parseOwnedFieldsTableInput struct {
+		annotations   map[string]string
+		expectedPaths string
+		expectedError error
+	}
+
+	DescribeTable("ParseOwnedFields", func(in parseOwnedFieldsTableInput) {
+		ownedFields, err := ParseOwnedFields(in.annotations)
+
+		if in.expectedError != nil {
+			Expect(err).To(MatchError(in.expectedError))
+			return
+		}
+
+		Expect(err).ToNot(HaveOccurred())
+		Expect(ownedFields.String()).To(Equal(in.expectedPaths))
+		Expect(ownedFields.IsEmpty()).To(Equal(in.expectedPaths == ""))
+	},
+		Entry("with no annotations", parseOwnedFieldsTableInput{
+			annotations:   nil,
+			expectedPaths: "",
+		}),
+		Entry("with a single field", parseOwnedFieldsTableInput{
+			annotations:   map[string]string{OwnedFieldsAnnotation: ".securityGroups"},
+			expectedPaths: ".securityGroups",
+		}),
+		Entry("with nested fields, in braces and with spaces", parseOwnedFieldsTableInput{
+			annotations:   map[string]string{OwnedFieldsAnnotation: "{.metadata.labels}, .userDataSecret"},
+			expectedPaths: ".metadata.labels,.userDataSecret",
+		}),
+		Entry("with an empty value", parseOwnedFieldsTableInput{
+			annotations:   map[string]string{OwnedFieldsAnnotation: ""},
+			expectedError: errInvalidOwnedFields,
+		}),
+		Entry("with a path without a leading dot", parseOwnedFieldsTableInput{
+			annotations:   map[string]string{OwnedFieldsAnnotation: "securityGroups"},
+			expectedError: errInvalidOwnedFields,
+		}),
+		Entry("with an array index", parseOwnedFieldsTableInput{
+			annotations:   map[string]string{OwnedFieldsAnnotation: ".securityGroups[0]"},
+			expectedError: errInvalidOwnedFields,
+		}),
+		Entry("with an empty field name", parseOwnedFieldsTableInput{
+			annotations:   map[string]string{OwnedFieldsAnnotation: ".metadata..labels"},
+			expectedError: errInvalidOwnedFields,
+		}),
+		Entry("with the kind", parseOwnedFieldsTableInput{
+			annotations:   map[string]string{OwnedFieldsAnnotation: ".securityGroups,.kind"},
+			expectedError: errUnownableField,
+		}),
+		Entry("with the apiVersion", parseOwnedFieldsTableInput{
+			annotations:   map[string]string{OwnedFieldsAnnotation: "{.apiVersion}"},
+			expectedError: errUnownableField,
+		}),
+	)
+
+	Context("Remove", func() {
+		newConfig := func(builder resourcebuilder.AWSProviderSpecBuilder) ProviderConfig {
+			providerConfig, err := NewProviderConfig(machinev1.OpenShiftMachineV1Beta1MachineTemplate{
+				Spec: machinev1beta1.MachineSpec{
+					ProviderSpec: machinev1beta1.ProviderSpec{
+						Value: builder.BuildRawExtension(),
+					},
+				},
+			})
+			Expect(err).ToNot(HaveOccurred())
+
+			return providerConfig
+		}
+
+		ownedFields := func(value string) OwnedFields {
+			ownedFields, err := ParseOwnedFields(map[string]string{OwnedFieldsAnnotation: value})
+			Expect(err).ToNot(HaveOccurred())
+
+			return ownedFields
+		}
+
+		template := newConfig(resourcebuilder.AWSProviderSpec())
+		patched := newConfig(resourcebuilder.AWSProviderSpec().WithSecurityGroups([]machinev1beta1.AWSResourceReference{
+			{ID: pointer.String("sg-tuned")},
+		}).WithAvailabilityZone("us-east-1b"))
+
+		It("makes configs that differ only in the owned fields equal", func() {
+			owned := ownedFields(".securityGroups,.placement.availabilityZone")
+
+			templateWithoutOwned, err := owned.Remove(template)
+			Expect(err).ToNot(HaveOccurred())
+
+			patchedWithoutOwned, err := owned.Remove(patched)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(templateWithoutOwned.Equal(patchedWithoutOwned)).To(BeTrue())
+			Expect(templateWithoutOwned.AWS().Config().Placement.Region).To(Equal(template.AWS().Config().Placement.Region))
+		})
+
+		It("still reports differences in fields that are not owned", func() {
+			owned := ownedFields(".securityGroups")
+
+			templateWithoutOwned, err := owned.Remove(template)
+			Expect(err).ToNot(HaveOccurred())
+
+			patchedWithoutOwned, err := owned.Remove(patched)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(templateWithoutOwned.Equal(patchedWithoutOwned)).To(BeFalse())
+			Expect(templateWithoutOwned.ChangedFields(patchedWithoutOwned)).To(ConsistOf("placement"))
+		})
+
+		It("returns the config unchanged when no fields are owned", func() {
+			Expect(OwnedFields{}.Remove(template)).To(Equal(template))
+		})
+
+		It("ignores owned fields that are not present", func() {
+			raw, err := ownedFields(".metadata.labels,.placement.availabilityZone.name").RemoveFromRaw([]byte(`{"kind":"AWSMachineProviderConfig","placement":{"availabilityZone":"us-east-1a"}}`))
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(raw).To(MatchJSON(`{"kind":"AWSMachineProviderConfig","placement":{"availabilityZone":"us-east-1a"}}`))
+		})
+	})
+})
@@ -0,0 +1,132 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package providerconfig
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	configv1 "github.com/openshift/api/config/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+const (
+	// powerVSProcessorsField is the name of the field of the Power VS provider spec holding the number of
+	// processors of the instance. It may be written as a number or as a string, for example 1 or "0.5".
+	powerVSProcessorsField = "processors"
+)
+
+// PowerVSProviderConfig holds the provider spec of a Power VS Machine.
+// The PowerVSMachineProviderConfig API is not vendored by the operator, so the provider spec is passed through
+// unchanged. The ControlPlaneMachineSet API has no Power VS failure domains, so nothing is injected into it.
+type PowerVSProviderConfig struct {
+	providerConfig powerVSProviderSpec
+
+	// fields holds every top level field of the provider spec, so that the provider spec can be
+	// passed through, and compared, without losing the fields that are not decoded.
+	fields map[string]interface{}
+}
+
+// powerVSProviderSpec is the subset of the PowerVSMachineProviderConfig that the operator reads.
+type powerVSProviderSpec struct {
+	metav1.TypeMeta `json:",inline"`
+
+	// SystemType is the type of system, the machine type, on which the instance is hosted.
+	SystemType string `json:"systemType,omitempty"`
+}
+
+// ExtractSystemType returns the type of system on which the instance is hosted.
+func (p PowerVSProviderConfig) ExtractSystemType() string {
+	return p.providerConfig.SystemType
+}
+
+// Equal compares the PowerVSProviderConfig with another PowerVSProviderConfig.
+// Every field of the provider spec is compared, other than the type information, so that a
+// provider spec that omits it compares as equal to one that sets it. The number of processors
+// is compared by its value, so 1 and "1" are equal.
+func (p PowerVSProviderConfig) Equal(other PowerVSProviderConfig) bool {
+	return equality.Semantic.DeepEqual(normalisedPowerVSFields(p.fields), normalisedPowerVSFields(other.fields))
+}
+
+// UnmanagedFields returns the paths of the fields that differ between the PowerVSProviderConfigs
+// but where the difference is deliberately tolerated by Equal.
+// These are the type information of the provider specs.
+func (p PowerVSProviderConfig) UnmanagedFields(other PowerVSProviderConfig) []string {
+	var fields []string
+
+	if p.providerConfig.APIVersion != other.providerConfig.APIVersion {
+		fields = append(fields, "apiVersion")
+	}
+
+	if p.providerConfig.Kind != other.providerConfig.Kind {
+		fields = append(fields, "kind")
+	}
+
+	return fields
+}
+
+// ChangedFields returns the names of the top level fields of the provider spec that differ
+// between the PowerVSProviderConfigs.
+// The provider specs are normalised in the same way as within Equal, so differences that Equal
+// tolerates are not reported.
+func (p PowerVSProviderConfig) ChangedFields(other PowerVSProviderConfig) ([]string, error) {
+	return changedTopLevelFields(normalisedPowerVSFields(p.fields), normalisedPowerVSFields(other.fields))
+}
+
+// normalisedPowerVSFields returns a copy of the fields of the provider spec that is suitable for comparison.
+// The type information is removed, so that a provider spec that omits it compares as equal to one that sets it.
+// A number of processors written as a string is converted to a number, as the Power VS API accepts either.
+func normalisedPowerVSFields(fields map[string]interface{}) map[string]interface{} {
+	out := runtime.DeepCopyJSON(fields)
+	delete(out, "apiVersion")
+	delete(out, "kind")
+
+	if processors, ok := out[powerVSProcessorsField].(string); ok {
+		if value, err := strconv.ParseFloat(processors, 64); err == nil {
+			out[powerVSProcessorsField] = value
+		}
+	}
+
+	return out
+}
+
+// newPowerVSProviderConfig creates a PowerVSProviderConfig from the raw extension.
+// It should return an error if the provided RawExtension does not represent
+// a PowerVSMachineProviderConfig.
+func newPowerVSProviderConfig(raw *runtime.RawExtension) (ProviderConfig, error) {
+	powerVSMachineProviderConfig := powerVSProviderSpec{}
+	if err := decodeProviderSpec(raw, powerVSProviderConfigKind, &powerVSMachineProviderConfig); err != nil {
+		return nil, fmt.Errorf("could not decode Power VS provider spec: %w", err)
+	}
+
+	fields := map[string]interface{}{}
+	if err := json.Unmarshal(raw.Raw, &fields); err != nil {
+		return nil, fmt.Errorf("could not decode Power VS provider spec: %w", err)
+	}
+
+	return providerConfig{
+		platformType: configv1.PowerVSPlatformType,
+		raw:          raw.Raw,
+		powerVS: PowerVSProviderConfig{
+			providerConfig: powerVSMachineProviderConfig,
+			fields:         fields,
+		},
+	}, nil
+}
@@ -0,0 +1,150 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package providerconfig
+
+import (
+	"encoding/json"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	configv1 "github.com/openshift/api/config/v1"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/test/resourcebuilder"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+var _ = Describe("Power VS Provider Config", func() {
+	powerVSConfig := func(builder resourcebuilder.PowerVSProviderSpecBuilder) PowerVSProviderConfig {
+		providerConfig, err := newPowerVSProviderConfig(builder.BuildRawExtension())
+		Expect(err).ToNot(HaveOccurred())
+
+		return providerConfig.PowerVS()
+	}
+
+	Context("Equal", func() {
+		type powerVSEqualTableInput struct {
+			baseConfig    resourcebuilder.PowerVSProviderSpecBuilder
+			compareConfig resourcebuilder.PowerVSProviderSpecBuilder
+			expectedEqual bool
+
+			expectedChangedFields []string
+		}
+
+		DescribeTable("should compare the provider configs", func(in powerVSEqualTableInput) {
+			baseConfig := powerVSConfig(in.baseConfig)
+			compareConfig := powerVSConfig(in.compareConfig)
+
+			Expect(baseConfig.Equal(compareConfig)).To(Equal(in.expectedEqual))
+			Expect(compareConfig.Equal(baseConfig)).To(Equal(in.expectedEqual), "Equality should be symmetric")
+
+			Expect(baseConfig.UnmanagedFields(compareConfig)).To(BeEmpty())
+			Expect(baseConfig.ChangedFields(compareConfig)).To(ConsistOf(in.expectedChangedFields))
+		},
+			Entry("with matching configs", powerVSEqualTableInput{
+				baseConfig:            resourcebuilder.PowerVSProviderSpec(),
+				compareConfig:         resourcebuilder.PowerVSProviderSpec(),
+				expectedEqual:         true,
+				expectedChangedFields: []string{},
+			}),
+			Entry("with more memory", powerVSEqualTableInput{
+				baseConfig:            resourcebuilder.PowerVSProviderSpec(),
+				compareConfig:         resourcebuilder.PowerVSProviderSpec().WithMemoryGiB(64),
+				expectedEqual:         false,
+				expectedChangedFields: []string{"memoryGiB"},
+			}),
+			Entry("with more processors", powerVSEqualTableInput{
+				baseConfig:            resourcebuilder.PowerVSProviderSpec(),
+				compareConfig:         resourcebuilder.PowerVSProviderSpec().WithProcessors("1"),
+				expectedEqual:         false,
+				expectedChangedFields: []string{"processors"},
+			}),
+			Entry("with the processors written as a number instead of a string", powerVSEqualTableInput{
+				baseConfig:            resourcebuilder.PowerVSProviderSpec().WithProcessors("1"),
+				compareConfig:         resourcebuilder.PowerVSProviderSpec().WithProcessors(1),
+				expectedEqual:         true,
+				expectedChangedFields: []string{},
+			}),
+			Entry("with a different system type", powerVSEqualTableInput{
+				baseConfig:            resourcebuilder.PowerVSProviderSpec(),
+				compareConfig:         resourcebuilder.PowerVSProviderSpec().WithSystemType("e980"),
+				expectedEqual:         false,
+				expectedChangedFields: []string{"systemType"},
+			}),
+		)
+
+		It("ignores the type information", func() {
+			config := powerVSConfig(resourcebuilder.PowerVSProviderSpec())
+			withoutTypeMeta := config
+			withoutTypeMeta.fields = runtime.DeepCopyJSON(config.fields)
+			withoutTypeMeta.providerConfig.TypeMeta.APIVersion = ""
+			withoutTypeMeta.providerConfig.TypeMeta.Kind = ""
+			delete(withoutTypeMeta.fields, "apiVersion")
+			delete(withoutTypeMeta.fields, "kind")
+
+			Expect(config.Equal(withoutTypeMeta)).To(BeTrue())
+			Expect(config.UnmanagedFields(withoutTypeMeta)).To(ConsistOf("apiVersion", "kind"))
+			Expect(config.ChangedFields(withoutTypeMeta)).To(BeEmpty())
+		})
+	})
+
+	Context("newPowerVSProviderConfig", func() {
+		var providerConfig ProviderConfig
+		var rawConfig *runtime.RawExtension
+
+		BeforeEach(func() {
+			rawConfig = resourcebuilder.PowerVSProviderSpec().BuildRawExtension()
+
+			var err error
+			providerConfig, err = newPowerVSProviderConfig(rawConfig)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("sets the type to PowerVS", func() {
+			Expect(providerConfig.Type()).To(Equal(configv1.PowerVSPlatformType))
+		})
+
+		It("extracts the system type", func() {
+			Expect(providerConfig.PowerVS().ExtractSystemType()).To(Equal("s922"))
+		})
+
+		It("has no failure domain", func() {
+			Expect(providerConfig.ExtractFailureDomain()).To(BeNil())
+		})
+
+		It("passes every field of the provider spec through the raw config", func() {
+			raw, err := providerConfig.RawConfig()
+			Expect(err).ToNot(HaveOccurred())
+
+			var fields, expectedFields map[string]interface{}
+			Expect(json.Unmarshal(raw, &fields)).To(Succeed())
+			Expect(json.Unmarshal(rawConfig.Raw, &expectedFields)).To(Succeed())
+
+			Expect(fields).To(Equal(expectedFields))
+			Expect(fields).To(HaveKey("serviceInstance"))
+		})
+
+		Context("with a provider spec in an unknown API version", func() {
+			It("returns an error", func() {
+				_, err := newPowerVSProviderConfig(&runtime.RawExtension{
+					Raw: []byte(`{"apiVersion":"machine.openshift.io/v1beta1","kind":"PowerVSMachineProviderConfig"}`),
+				})
+
+				Expect(err).To(MatchError("could not decode Power VS provider spec: unknown provider spec API version: machine.openshift.io/v1beta1 does not serve PowerVSMachineProviderConfig"))
+			})
+		})
+	})
+})
@@ -17,22 +17,31 @@ limitations under the License.
 package providerconfig
 
 import (
-	"errors"
+	"bytes"
+	"encoding/json"
 	"fmt"
 
 	configv1 "github.com/openshift/api/config/v1"
 	machinev1 "github.com/openshift/api/machine/v1"
+	machinev1beta1 "github.com/openshift/api/machine/v1beta1"
+
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders"
 	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders/providers/openshift/machine/v1beta1/failuredomain"
+	"k8s.io/apimachinery/pkg/api/equality"
 )
 
 var (
 	// errMismatchedPlatformTypes is an error used when two provider configs
 	// are being compared but are from different platform types.
-	errMismatchedPlatformTypes = errors.New("mistmatched platform types")
+	errMismatchedPlatformTypes = machineproviders.NewConfigurationError(machineproviders.ReasonTemplatePlatformMismatch, "mismatched platform types")
 
 	// errUnsupportedPlatformType is an error used when an unknown platform
 	// type is configured within the failure domain config.
-	errUnsupportedPlatformType = errors.New("unsupported platform type")
+	errUnsupportedPlatformType = machineproviders.NewConfigurationError(machineproviders.ReasonUnsupportedPlatform, "unsupported platform type")
+
+	// errUnknownProviderSpecKind is an error used when the platform type cannot be
+	// inferred from the kind of the provider spec.
+	errUnknownProviderSpecKind = machineproviders.NewConfigurationError(machineproviders.ReasonInvalidProviderSpec, "unknown provider spec kind")
 )
 
 // ProviderConfig is an interface that allows external code to interact
@@ -46,9 +55,67 @@ type ProviderConfig interface {
 	// ExtractFailureDomain is used to extract a failure domain from the ProviderConfig.
 	ExtractFailureDomain() failuredomain.FailureDomain
 
+	// InjectImage is used to inject an image ID into the ProviderConfig.
+	// The returned ProviderConfig will be a copy of the current ProviderConfig with
+	// the new image injected.
+	InjectImage(string) (ProviderConfig, error)
+
+	// ExtractImage is used to extract the image ID from the ProviderConfig.
+	// When the image is not referenced by ID, an empty string is returned.
+	ExtractImage() string
+
+	// ExtractInstanceType is used to extract the instance type from the ProviderConfig.
+	// When the platform has no concept of an instance type, an empty string is returned.
+	ExtractInstanceType() string
+
+	// InjectTags is used to add tags, applied to the cloud resources of the Machine, to the ProviderConfig.
+	// Tags that are already present are left unchanged. The returned ProviderConfig will be a copy of the current
+	// ProviderConfig with the new tags added.
+	InjectTags(map[string]string) (ProviderConfig, error)
+
+	// ExtractTags is used to extract the tags applied to the cloud resources of the Machine from the ProviderConfig.
+	// When the platform does not support tags, nil is returned.
+	ExtractTags() map[string]string
+
+	// InjectStorageContainer is used to set the storage container that the data disks of the Machine are placed in.
+	// The returned ProviderConfig will be a copy of the current ProviderConfig with the new storage container set.
+	InjectStorageContainer(uuid string) (ProviderConfig, error)
+
+	// InjectAdditionalNetworks is used to attach the Machine to further networks, after those it is already
+	// attached to.
+	// The returned ProviderConfig will be a copy of the current ProviderConfig with the networks added.
+	InjectAdditionalNetworks(networks []string) (ProviderConfig, error)
+
+	// InjectUserDataSecret is used to set the name of the secret holding the user data of the Machine.
+	// The returned ProviderConfig will be a copy of the current ProviderConfig with the new secret set.
+	InjectUserDataSecret(string) (ProviderConfig, error)
+
+	// ExtractUserDataSecret is used to extract the name of the secret holding the user data of the Machine.
+	// When the platform is not supported, or no secret is set, an empty string is returned.
+	ExtractUserDataSecret() string
+
+	// InjectSubnet is used to set the subnet, and optionally the network resource group, that the Machine is
+	// attached to. When no network resource group is given, the network resource group is left unchanged.
+	// The returned ProviderConfig will be a copy of the current ProviderConfig with the new subnet set.
+	InjectSubnet(subnet, networkResourceGroup string) (ProviderConfig, error)
+
+	// InjectPlacementGroup is used to set the placement group, and optionally the partition within it, that the
+	// Machine is placed in. When no partition is given, the partition is left unchanged.
+	// The returned ProviderConfig will be a copy of the current ProviderConfig with the new placement group set.
+	InjectPlacementGroup(name string, partitionNumber int32) (ProviderConfig, error)
+
 	// Equal compares two ProviderConfigs to determine whether or not they are equal.
 	Equal(ProviderConfig) (bool, error)
 
+	// UnmanagedFields compares two ProviderConfigs and returns the paths of the fields
+	// that differ between them, but where the difference is deliberately tolerated when
+	// determining whether or not they are equal.
+	UnmanagedFields(ProviderConfig) ([]string, error)
+
+	// ChangedFields compares two ProviderConfigs and returns the names of the top level
+	// fields that differ between them, ignoring any difference tolerated by Equal.
+	ChangedFields(ProviderConfig) ([]string, error)
+
 	// RawConfig marshalls the configuration into a JSON byte slice.
 	RawConfig() ([]byte, error)
 
@@ -57,9 +124,44 @@ type ProviderConfig interface {
 
 	// AWS returns the AWSProviderConfig if the platform type is AWS.
 	AWS() AWSProviderConfig
+
+	// VSphere returns the VSphereProviderConfig if the platform type is VSphere.
+	VSphere() VSphereProviderConfig
+
+	// GCP returns the GCPProviderConfig if the platform type is GCP.
+	GCP() GCPProviderConfig
+
+	// Azure returns the AzureProviderConfig if the platform type is Azure.
+	Azure() AzureProviderConfig
+
+	// Nutanix returns the NutanixProviderConfig if the platform type is Nutanix.
+	Nutanix() NutanixProviderConfig
+
+	// IBMCloud returns the IBMCloudProviderConfig if the platform type is IBMCloud.
+	IBMCloud() IBMCloudProviderConfig
+
+	// PowerVS returns the PowerVSProviderConfig if the platform type is PowerVS.
+	PowerVS() PowerVSProviderConfig
+
+	// AlibabaCloud returns the AlibabaCloudProviderConfig if the platform type is AlibabaCloud.
+	AlibabaCloud() AlibabaCloudProviderConfig
+
+	// BareMetal returns the BareMetalProviderConfig if the platform type is BareMetal.
+	BareMetal() BareMetalProviderConfig
+
+	// EquinixMetal returns the EquinixMetalProviderConfig if the platform type is EquinixMetal.
+	EquinixMetal() EquinixMetalProviderConfig
+
+	// OpenStack returns the OpenStackProviderConfig if the platform type is OpenStack.
+	OpenStack() OpenStackProviderConfig
+
+	// Opaque returns the OpaqueProviderConfig if the platform type does not have first class support.
+	Opaque() OpaqueProviderConfig
 }
 
 // NewProviderConfig creates a new ProviderConfig from the provided machine template.
+// Provider specs of platforms without first class support are treated as opaque, unless the template configures
+// failure domains, which such platforms do not support.
 func NewProviderConfig(tmpl machinev1.OpenShiftMachineV1Beta1MachineTemplate) (ProviderConfig, error) {
 	platformType, err := getPlatformType(tmpl)
 	if err != nil {
@@ -69,37 +171,542 @@ func NewProviderConfig(tmpl machinev1.OpenShiftMachineV1Beta1MachineTemplate) (P
 	switch platformType {
 	case configv1.AWSPlatformType:
 		return newAWSProviderConfig(tmpl.Spec.ProviderSpec.Value)
+	case configv1.VSpherePlatformType:
+		return newVSphereProviderConfig(tmpl.Spec.ProviderSpec.Value)
+	case configv1.GCPPlatformType:
+		return newGCPProviderConfig(tmpl.Spec.ProviderSpec.Value)
+	case configv1.AzurePlatformType:
+		return newAzureProviderConfig(tmpl.Spec.ProviderSpec.Value)
+	case configv1.NutanixPlatformType:
+		return newNutanixProviderConfig(tmpl.Spec.ProviderSpec.Value)
+	case configv1.IBMCloudPlatformType:
+		return newIBMCloudProviderConfig(tmpl.Spec.ProviderSpec.Value)
+	case configv1.PowerVSPlatformType:
+		return newPowerVSProviderConfig(tmpl.Spec.ProviderSpec.Value)
+	case configv1.AlibabaCloudPlatformType:
+		return newAlibabaCloudProviderConfig(tmpl.Spec.ProviderSpec.Value)
+	case configv1.BareMetalPlatformType:
+		return newBareMetalProviderConfig(tmpl.Spec.ProviderSpec.Value)
+	case configv1.EquinixMetalPlatformType:
+		return newEquinixMetalProviderConfig(tmpl.Spec.ProviderSpec.Value)
+	case configv1.OpenStackPlatformType:
+		return newOpenStackProviderConfig(tmpl.Spec.ProviderSpec.Value)
 	default:
-		return nil, fmt.Errorf("%w: %s", errUnsupportedPlatformType, platformType)
+		// Platforms without first class support have no failure domain support.
+		if tmpl.FailureDomains.Platform != "" {
+			return nil, unsupportedPlatformError(platformType, tmpl)
+		}
+
+		return newOpaqueProviderConfig(platformType, tmpl.Spec.ProviderSpec.Value)
 	}
 }
 
+// NewProviderConfigFromMachineSpec creates a new ProviderConfig from the provided machine spec.
+// This allows the provider config of an existing Machine to be compared with the provider
+// config of the machine template.
+func NewProviderConfigFromMachineSpec(machineSpec machinev1beta1.MachineSpec) (ProviderConfig, error) {
+	return NewProviderConfig(machinev1.OpenShiftMachineV1Beta1MachineTemplate{
+		Spec: machineSpec,
+	})
+}
+
 // providerConfig is an implementation of the ProviderConfig interface.
 type providerConfig struct {
 	platformType configv1.PlatformType
+
+	// raw is the raw provider spec from which the provider config was decoded.
+	// It is cleared once the provider config is modified, as it then no longer represents the provider config.
+	// When two provider configs were decoded from identical raw provider specs, the comparisons short-circuit,
+	// as Machines whose specs have not changed are otherwise normalised and compared on every reconcile.
+	raw []byte
+
 	aws          AWSProviderConfig
+	vsphere      VSphereProviderConfig
+	gcp          GCPProviderConfig
+	azure        AzureProviderConfig
+	nutanix      NutanixProviderConfig
+	ibmCloud     IBMCloudProviderConfig
+	powerVS      PowerVSProviderConfig
+	alibabaCloud AlibabaCloudProviderConfig
+	bareMetal    BareMetalProviderConfig
+	equinixMetal EquinixMetalProviderConfig
+	openStack    OpenStackProviderConfig
+	opaque       OpaqueProviderConfig
 }
 
 // InjectFailureDomain is used to inject a failure domain into the ProviderConfig.
 // The returned ProviderConfig will be a copy of the current ProviderConfig with
 // the new failure domain injected.
-func (p providerConfig) InjectFailureDomain(failuredomain.FailureDomain) (ProviderConfig, error) {
-	return p, nil
+func (p providerConfig) InjectFailureDomain(fd failuredomain.FailureDomain) (ProviderConfig, error) {
+	if fd == nil {
+		return p, nil
+	}
+
+	if p.platformType != fd.Type() {
+		return nil, errMismatchedPlatformTypes
+	}
+
+	newConfig := p
+
+	switch p.platformType {
+	case configv1.AWSPlatformType:
+		newConfig.aws = p.aws.InjectFailureDomain(fd.AWS())
+
+		if !equality.Semantic.DeepEqual(newConfig.aws.ExtractFailureDomain(), p.aws.ExtractFailureDomain()) {
+			newConfig.raw = nil
+		}
+	case configv1.AzurePlatformType:
+		newConfig.azure = p.azure.InjectFailureDomain(fd.Azure())
+
+		if !equality.Semantic.DeepEqual(newConfig.azure.ExtractFailureDomain(), p.azure.ExtractFailureDomain()) {
+			newConfig.raw = nil
+		}
+	case configv1.GCPPlatformType:
+		newConfig.gcp = p.gcp.InjectFailureDomain(fd.GCP())
+
+		if !equality.Semantic.DeepEqual(newConfig.gcp.ExtractFailureDomain(), p.gcp.ExtractFailureDomain()) {
+			newConfig.raw = nil
+		}
+	case configv1.VSpherePlatformType:
+		newConfig.vsphere = p.vsphere.InjectFailureDomain(fd.VSphere())
+
+		if !equality.Semantic.DeepEqual(newConfig.vsphere.ExtractFailureDomain(), p.vsphere.ExtractFailureDomain()) {
+			newConfig.raw = nil
+		}
+	case configv1.NutanixPlatformType:
+		newConfig.nutanix = p.nutanix.InjectFailureDomain(fd.Nutanix())
+
+		if !equality.Semantic.DeepEqual(newConfig.nutanix.ExtractFailureDomain(), p.nutanix.ExtractFailureDomain()) {
+			newConfig.raw = nil
+		}
+	case configv1.IBMCloudPlatformType:
+		newConfig.ibmCloud = p.ibmCloud.InjectFailureDomain(fd.IBMCloud())
+
+		if !equality.Semantic.DeepEqual(newConfig.ibmCloud.ExtractFailureDomain(), p.ibmCloud.ExtractFailureDomain()) {
+			newConfig.raw = nil
+		}
+	case configv1.OpenStackPlatformType:
+		newConfig.openStack = p.openStack.InjectFailureDomain(fd.OpenStack())
+
+		if !equality.Semantic.DeepEqual(newConfig.openStack.ExtractFailureDomain(), p.openStack.ExtractFailureDomain()) {
+			newConfig.raw = nil
+		}
+	default:
+		return nil, fmt.Errorf("%w: %s", errUnsupportedPlatformType, p.platformType)
+	}
+
+	return newConfig, nil
 }
 
 // ExtractFailureDomain is used to extract a failure domain from the ProviderConfig.
 func (p providerConfig) ExtractFailureDomain() failuredomain.FailureDomain {
-	return nil
+	switch p.platformType {
+	case configv1.AWSPlatformType:
+		return failuredomain.NewAWSFailureDomain(p.aws.ExtractFailureDomain())
+	case configv1.AzurePlatformType:
+		return failuredomain.NewAzureFailureDomain(p.azure.ExtractFailureDomain())
+	case configv1.GCPPlatformType:
+		return failuredomain.NewGCPFailureDomain(p.gcp.ExtractFailureDomain())
+	case configv1.VSpherePlatformType:
+		return failuredomain.NewVSphereFailureDomain(p.vsphere.ExtractFailureDomain())
+	case configv1.NutanixPlatformType:
+		return failuredomain.NewNutanixFailureDomain(p.nutanix.ExtractFailureDomain())
+	case configv1.IBMCloudPlatformType:
+		return failuredomain.NewIBMCloudFailureDomain(p.ibmCloud.ExtractFailureDomain())
+	case configv1.OpenStackPlatformType:
+		return failuredomain.NewOpenStackFailureDomain(p.openStack.ExtractFailureDomain())
+	default:
+		return nil
+	}
+}
+
+// InjectImage is used to inject an image ID into the ProviderConfig.
+// The returned ProviderConfig will be a copy of the current ProviderConfig with
+// the new image injected.
+func (p providerConfig) InjectImage(image string) (ProviderConfig, error) {
+	newConfig := p
+
+	switch p.platformType {
+	case configv1.AWSPlatformType:
+		newConfig.aws = p.aws.InjectAMI(image)
+
+		if !equality.Semantic.DeepEqual(newConfig.aws.providerConfig.AMI, p.aws.providerConfig.AMI) {
+			newConfig.raw = nil
+		}
+	default:
+		return nil, fmt.Errorf("%w: %s", errUnsupportedPlatformType, p.platformType)
+	}
+
+	return newConfig, nil
+}
+
+// ExtractImage is used to extract the image ID from the ProviderConfig.
+// When the image is not referenced by ID, an empty string is returned.
+func (p providerConfig) ExtractImage() string {
+	switch p.platformType {
+	case configv1.AWSPlatformType:
+		return p.aws.ExtractAMI()
+	default:
+		return ""
+	}
+}
+
+// ExtractInstanceType is used to extract the instance type from the ProviderConfig.
+// When the platform has no concept of an instance type, an empty string is returned.
+func (p providerConfig) ExtractInstanceType() string {
+	switch p.platformType {
+	case configv1.AWSPlatformType:
+		return p.aws.ExtractInstanceType()
+	case configv1.GCPPlatformType:
+		return p.gcp.ExtractInstanceType()
+	case configv1.AzurePlatformType:
+		return p.azure.ExtractInstanceType()
+	case configv1.IBMCloudPlatformType:
+		return p.ibmCloud.ExtractProfile()
+	case configv1.AlibabaCloudPlatformType:
+		return p.alibabaCloud.ExtractInstanceType()
+	case configv1.EquinixMetalPlatformType:
+		return p.equinixMetal.ExtractMachineType()
+	case configv1.OpenStackPlatformType:
+		return p.openStack.ExtractFlavor()
+	default:
+		return ""
+	}
+}
+
+// InjectTags is used to add tags, applied to the cloud resources of the Machine, to the ProviderConfig.
+// Tags that are already present are left unchanged. The returned ProviderConfig will be a copy of the current
+// ProviderConfig with the new tags added.
+func (p providerConfig) InjectTags(tags map[string]string) (ProviderConfig, error) {
+	if len(tags) == 0 {
+		return p, nil
+	}
+
+	newConfig := p
+
+	switch p.platformType {
+	case configv1.AWSPlatformType:
+		newConfig.aws = p.aws.InjectTags(tags)
+
+		if len(newConfig.aws.providerConfig.Tags) != len(p.aws.providerConfig.Tags) {
+			newConfig.raw = nil
+		}
+	default:
+		return nil, fmt.Errorf("%w: %s", errUnsupportedPlatformType, p.platformType)
+	}
+
+	return newConfig, nil
+}
+
+// ExtractTags is used to extract the tags applied to the cloud resources of the Machine from the ProviderConfig.
+// When the platform does not support tags, nil is returned.
+func (p providerConfig) ExtractTags() map[string]string {
+	switch p.platformType {
+	case configv1.AWSPlatformType:
+		return p.aws.ExtractTags()
+	default:
+		return nil
+	}
+}
+
+// InjectStorageContainer is used to set the storage container that the data disks of the Machine are placed in.
+// The returned ProviderConfig will be a copy of the current ProviderConfig with the new storage container set.
+// Only Nutanix supports injecting the storage container.
+func (p providerConfig) InjectStorageContainer(uuid string) (ProviderConfig, error) {
+	if p.platformType != configv1.NutanixPlatformType {
+		return nil, fmt.Errorf("%w: %s", errUnsupportedPlatformType, p.platformType)
+	}
+
+	newConfig := p
+	newConfig.raw = nil
+	newConfig.nutanix = p.nutanix.InjectStorageContainer(uuid)
+
+	return newConfig, nil
+}
+
+// InjectAdditionalNetworks is used to attach the Machine to further networks, after those it is already
+// attached to.
+// The returned ProviderConfig will be a copy of the current ProviderConfig with the networks added.
+// Only OpenStack supports injecting additional networks.
+func (p providerConfig) InjectAdditionalNetworks(networks []string) (ProviderConfig, error) {
+	if p.platformType != configv1.OpenStackPlatformType {
+		return nil, fmt.Errorf("%w: %s", errUnsupportedPlatformType, p.platformType)
+	}
+
+	newConfig := p
+	newConfig.raw = nil
+	newConfig.openStack = p.openStack.InjectAdditionalNetworks(networks)
+
+	return newConfig, nil
+}
+
+// InjectUserDataSecret is used to set the name of the secret holding the user data of the Machine.
+// The returned ProviderConfig will be a copy of the current ProviderConfig with the new secret set.
+func (p providerConfig) InjectUserDataSecret(name string) (ProviderConfig, error) {
+	if name == p.ExtractUserDataSecret() {
+		return p, nil
+	}
+
+	newConfig := p
+	newConfig.raw = nil
+
+	switch p.platformType {
+	case configv1.AWSPlatformType:
+		newConfig.aws = p.aws.InjectUserDataSecret(name)
+	case configv1.AzurePlatformType:
+		newConfig.azure = p.azure.InjectUserDataSecret(name)
+	case configv1.GCPPlatformType:
+		newConfig.gcp = p.gcp.InjectUserDataSecret(name)
+	default:
+		return nil, fmt.Errorf("%w: %s", errUnsupportedPlatformType, p.platformType)
+	}
+
+	return newConfig, nil
+}
+
+// ExtractUserDataSecret is used to extract the name of the secret holding the user data of the Machine.
+// When the platform is not supported, or no secret is set, an empty string is returned.
+func (p providerConfig) ExtractUserDataSecret() string {
+	switch p.platformType {
+	case configv1.AWSPlatformType:
+		return p.aws.ExtractUserDataSecret()
+	case configv1.AzurePlatformType:
+		return p.azure.ExtractUserDataSecret()
+	case configv1.GCPPlatformType:
+		return p.gcp.ExtractUserDataSecret()
+	default:
+		return ""
+	}
+}
+
+// InjectSubnet is used to set the subnet, and optionally the network resource group, that the Machine is
+// attached to. When no network resource group is given, the network resource group is left unchanged.
+// The returned ProviderConfig will be a copy of the current ProviderConfig with the new subnet set.
+// Only Azure supports injecting the subnet.
+func (p providerConfig) InjectSubnet(subnet, networkResourceGroup string) (ProviderConfig, error) {
+	if p.platformType != configv1.AzurePlatformType {
+		return nil, fmt.Errorf("%w: %s", errUnsupportedPlatformType, p.platformType)
+	}
+
+	newConfig := p
+	newConfig.raw = nil
+	newConfig.azure = p.azure.InjectSubnet(subnet, networkResourceGroup)
+
+	return newConfig, nil
+}
+
+// InjectPlacementGroup is used to set the placement group, and optionally the partition within it, that the
+// Machine is placed in. When no partition is given, the partition is left unchanged.
+// The returned ProviderConfig will be a copy of the current ProviderConfig with the new placement group set.
+// Only AWS supports injecting the placement group.
+func (p providerConfig) InjectPlacementGroup(name string, partitionNumber int32) (ProviderConfig, error) {
+	if p.platformType != configv1.AWSPlatformType {
+		return nil, fmt.Errorf("%w: %s", errUnsupportedPlatformType, p.platformType)
+	}
+
+	newConfig := p
+	newConfig.raw = nil
+	newConfig.aws = p.aws.InjectPlacementGroup(name, partitionNumber)
+
+	return newConfig, nil
 }
 
 // Equal compares two ProviderConfigs to determine whether or not they are equal.
-func (p providerConfig) Equal(ProviderConfig) (bool, error) {
-	return false, nil
+func (p providerConfig) Equal(other ProviderConfig) (bool, error) {
+	if other == nil {
+		return false, nil
+	}
+
+	if p.platformType != other.Type() {
+		return false, errMismatchedPlatformTypes
+	}
+
+	if p.rawEqual(other) {
+		return true, nil
+	}
+
+	switch p.platformType {
+	case configv1.AWSPlatformType:
+		return p.aws.Equal(other.AWS()), nil
+	case configv1.VSpherePlatformType:
+		return p.vsphere.Equal(other.VSphere()), nil
+	case configv1.GCPPlatformType:
+		return p.gcp.Equal(other.GCP()), nil
+	case configv1.AzurePlatformType:
+		return p.azure.Equal(other.Azure()), nil
+	case configv1.NutanixPlatformType:
+		return p.nutanix.Equal(other.Nutanix()), nil
+	case configv1.IBMCloudPlatformType:
+		return p.ibmCloud.Equal(other.IBMCloud()), nil
+	case configv1.PowerVSPlatformType:
+		return p.powerVS.Equal(other.PowerVS()), nil
+	case configv1.AlibabaCloudPlatformType:
+		return p.alibabaCloud.Equal(other.AlibabaCloud()), nil
+	case configv1.BareMetalPlatformType:
+		return p.bareMetal.Equal(other.BareMetal()), nil
+	case configv1.EquinixMetalPlatformType:
+		return p.equinixMetal.Equal(other.EquinixMetal()), nil
+	case configv1.OpenStackPlatformType:
+		return p.openStack.Equal(other.OpenStack()), nil
+	default:
+		if p.isOpaque() {
+			return p.opaque.Equal(other.Opaque()), nil
+		}
+
+		return false, fmt.Errorf("%w: %s", errUnsupportedPlatformType, p.platformType)
+	}
+}
+
+// UnmanagedFields compares two ProviderConfigs and returns the paths of the fields
+// that differ between them, but where the difference is deliberately tolerated when
+// determining whether or not they are equal.
+func (p providerConfig) UnmanagedFields(other ProviderConfig) ([]string, error) {
+	if other == nil {
+		return nil, nil
+	}
+
+	if p.platformType != other.Type() {
+		return nil, errMismatchedPlatformTypes
+	}
+
+	if p.rawEqual(other) {
+		return nil, nil
+	}
+
+	switch p.platformType {
+	case configv1.AWSPlatformType:
+		return p.aws.UnmanagedFields(other.AWS()), nil
+	case configv1.VSpherePlatformType:
+		return p.vsphere.UnmanagedFields(other.VSphere()), nil
+	case configv1.GCPPlatformType:
+		return p.gcp.UnmanagedFields(other.GCP()), nil
+	case configv1.AzurePlatformType:
+		return p.azure.UnmanagedFields(other.Azure()), nil
+	case configv1.NutanixPlatformType:
+		return p.nutanix.UnmanagedFields(other.Nutanix()), nil
+	case configv1.IBMCloudPlatformType:
+		return p.ibmCloud.UnmanagedFields(other.IBMCloud()), nil
+	case configv1.PowerVSPlatformType:
+		return p.powerVS.UnmanagedFields(other.PowerVS()), nil
+	case configv1.AlibabaCloudPlatformType:
+		return p.alibabaCloud.UnmanagedFields(other.AlibabaCloud()), nil
+	case configv1.BareMetalPlatformType:
+		return p.bareMetal.UnmanagedFields(other.BareMetal()), nil
+	case configv1.EquinixMetalPlatformType:
+		return p.equinixMetal.UnmanagedFields(other.EquinixMetal()), nil
+	case configv1.OpenStackPlatformType:
+		return p.openStack.UnmanagedFields(other.OpenStack()), nil
+	default:
+		if p.isOpaque() {
+			return p.opaque.UnmanagedFields(other.Opaque()), nil
+		}
+
+		return nil, fmt.Errorf("%w: %s", errUnsupportedPlatformType, p.platformType)
+	}
+}
+
+// ChangedFields compares two ProviderConfigs and returns the names of the top level
+// fields that differ between them, ignoring any difference tolerated by Equal.
+func (p providerConfig) ChangedFields(other ProviderConfig) ([]string, error) {
+	if other == nil {
+		return nil, nil
+	}
+
+	if p.platformType != other.Type() {
+		return nil, errMismatchedPlatformTypes
+	}
+
+	if p.rawEqual(other) {
+		return []string{}, nil
+	}
+
+	switch p.platformType {
+	case configv1.AWSPlatformType:
+		return p.aws.ChangedFields(other.AWS())
+	case configv1.VSpherePlatformType:
+		return p.vsphere.ChangedFields(other.VSphere())
+	case configv1.GCPPlatformType:
+		return p.gcp.ChangedFields(other.GCP())
+	case configv1.AzurePlatformType:
+		return p.azure.ChangedFields(other.Azure())
+	case configv1.NutanixPlatformType:
+		return p.nutanix.ChangedFields(other.Nutanix())
+	case configv1.IBMCloudPlatformType:
+		return p.ibmCloud.ChangedFields(other.IBMCloud())
+	case configv1.PowerVSPlatformType:
+		return p.powerVS.ChangedFields(other.PowerVS())
+	case configv1.AlibabaCloudPlatformType:
+		return p.alibabaCloud.ChangedFields(other.AlibabaCloud())
+	case configv1.BareMetalPlatformType:
+		return p.bareMetal.ChangedFields(other.BareMetal())
+	case configv1.EquinixMetalPlatformType:
+		return p.equinixMetal.ChangedFields(other.EquinixMetal())
+	case configv1.OpenStackPlatformType:
+		return p.openStack.ChangedFields(other.OpenStack())
+	default:
+		if p.isOpaque() {
+			return p.opaque.ChangedFields(other.Opaque())
+		}
+
+		return nil, fmt.Errorf("%w: %s", errUnsupportedPlatformType, p.platformType)
+	}
+}
+
+// rawEqual determines whether both ProviderConfigs were decoded from identical raw provider specs, and have not
+// since been modified. Provider configs decoded from identical raw provider specs are always equal, so the
+// comparisons may return early without normalising the provider specs.
+func (p providerConfig) rawEqual(other ProviderConfig) bool {
+	otherConfig, ok := other.(providerConfig)
+	if !ok || len(p.raw) == 0 || len(otherConfig.raw) == 0 {
+		return false
+	}
+
+	return bytes.Equal(p.raw, otherConfig.raw)
 }
 
 // RawConfig marshalls the configuration into a JSON byte slice.
 func (p providerConfig) RawConfig() ([]byte, error) {
-	return []byte{}, nil
+	var (
+		rawConfig []byte
+		err       error
+	)
+
+	switch p.platformType {
+	case configv1.AWSPlatformType:
+		rawConfig, err = json.Marshal(p.aws.rawProviderSpec())
+	case configv1.VSpherePlatformType:
+		rawConfig, err = json.Marshal(p.vsphere.providerConfig)
+	case configv1.GCPPlatformType:
+		rawConfig, err = json.Marshal(p.gcp.providerConfig)
+	case configv1.AzurePlatformType:
+		rawConfig, err = json.Marshal(p.azure.rawProviderSpec())
+	case configv1.NutanixPlatformType:
+		rawConfig, err = json.Marshal(p.nutanix.rawProviderSpec())
+	case configv1.IBMCloudPlatformType:
+		rawConfig, err = json.Marshal(p.ibmCloud.fields)
+	case configv1.PowerVSPlatformType:
+		rawConfig, err = json.Marshal(p.powerVS.fields)
+	case configv1.AlibabaCloudPlatformType:
+		rawConfig, err = json.Marshal(p.alibabaCloud.providerConfig)
+	case configv1.BareMetalPlatformType:
+		rawConfig, err = json.Marshal(p.bareMetal.fields)
+	case configv1.EquinixMetalPlatformType:
+		rawConfig, err = json.Marshal(p.equinixMetal.fields)
+	case configv1.OpenStackPlatformType:
+		rawConfig, err = json.Marshal(p.openStack.fields)
+	default:
+		if !p.isOpaque() {
+			return nil, fmt.Errorf("%w: %s", errUnsupportedPlatformType, p.platformType)
+		}
+
+		rawConfig, err = json.Marshal(p.opaque.providerConfig)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("could not marshal provider config: %w", err)
+	}
+
+	return rawConfig, nil
 }
 
 // Type returns the platform type of the provider config.
@@ -112,10 +719,101 @@ func (p providerConfig) AWS() AWSProviderConfig {
 	return p.aws
 }
 
+// VSphere returns the VSphereProviderConfig if the platform type is VSphere.
+func (p providerConfig) VSphere() VSphereProviderConfig {
+	return p.vsphere
+}
+
+// GCP returns the GCPProviderConfig if the platform type is GCP.
+func (p providerConfig) GCP() GCPProviderConfig {
+	return p.gcp
+}
+
+// Azure returns the AzureProviderConfig if the platform type is Azure.
+func (p providerConfig) Azure() AzureProviderConfig {
+	return p.azure
+}
+
+// Nutanix returns the NutanixProviderConfig if the platform type is Nutanix.
+func (p providerConfig) Nutanix() NutanixProviderConfig {
+	return p.nutanix
+}
+
+// IBMCloud returns the IBMCloudProviderConfig if the platform type is IBMCloud.
+func (p providerConfig) IBMCloud() IBMCloudProviderConfig {
+	return p.ibmCloud
+}
+
+// PowerVS returns the PowerVSProviderConfig if the platform type is PowerVS.
+func (p providerConfig) PowerVS() PowerVSProviderConfig {
+	return p.powerVS
+}
+
+// AlibabaCloud returns the AlibabaCloudProviderConfig if the platform type is AlibabaCloud.
+func (p providerConfig) AlibabaCloud() AlibabaCloudProviderConfig {
+	return p.alibabaCloud
+}
+
+// BareMetal returns the BareMetalProviderConfig if the platform type is BareMetal.
+func (p providerConfig) BareMetal() BareMetalProviderConfig {
+	return p.bareMetal
+}
+
+// EquinixMetal returns the EquinixMetalProviderConfig if the platform type is EquinixMetal.
+func (p providerConfig) EquinixMetal() EquinixMetalProviderConfig {
+	return p.equinixMetal
+}
+
+// OpenStack returns the OpenStackProviderConfig if the platform type is OpenStack.
+func (p providerConfig) OpenStack() OpenStackProviderConfig {
+	return p.openStack
+}
+
+// Opaque returns the OpaqueProviderConfig if the platform type does not have first class support.
+func (p providerConfig) Opaque() OpaqueProviderConfig {
+	return p.opaque
+}
+
+// isOpaque determines whether the provider spec is treated as opaque, as the platform does not have first class
+// support.
+func (p providerConfig) isOpaque() bool {
+	return p.opaque.providerConfig != nil
+}
+
 // getPlatformType extracts the platform type from the Machine template.
 // This can either be gathered from the platform type within the template failure domains,
-// or if that isn't present, by inspecting the providerSpec kind and inferring from there
-// what the configured platform type is.
+// or if that isn't present, by inspecting the providerSpec kind, or the API group of older
+// providerSpecs, and inferring from there what the configured platform type is.
+// A providerSpec with a kind that is not known to the Machine API is assumed to be for the External platform.
 func getPlatformType(tmpl machinev1.OpenShiftMachineV1Beta1MachineTemplate) (configv1.PlatformType, error) {
-	return "", nil
+	if tmpl.FailureDomains.Platform != "" {
+		return tmpl.FailureDomains.Platform, nil
+	}
+
+	typeMeta, err := providerSpecTypeMeta(tmpl.Spec.ProviderSpec.Value)
+	if err != nil {
+		return "", err
+	}
+
+	platformType, ok := inferPlatformType(typeMeta)
+	switch {
+	case !ok && typeMeta.Kind != "":
+		// Kinds unknown to the Machine API are assumed to belong to the External platform.
+		return externalPlatformType, nil
+	case !ok:
+		return "", fmt.Errorf("%w: %q with API version %q", errUnknownProviderSpecKind, typeMeta.Kind, typeMeta.APIVersion)
+	}
+
+	return platformType, nil
+}
+
+// unsupportedPlatformError builds the error returned when the platform type of the Machine template is not supported.
+// When the provider spec carries a kind, the kind is included so that the configuration can be identified.
+func unsupportedPlatformError(platformType configv1.PlatformType, tmpl machinev1.OpenShiftMachineV1Beta1MachineTemplate) error {
+	typeMeta, err := providerSpecTypeMeta(tmpl.Spec.ProviderSpec.Value)
+	if err != nil || typeMeta.Kind == "" {
+		return fmt.Errorf("%w: %s", errUnsupportedPlatformType, platformType)
+	}
+
+	return fmt.Errorf("%w: %s (provider spec kind %q)", errUnsupportedPlatformType, platformType, typeMeta.Kind)
 }
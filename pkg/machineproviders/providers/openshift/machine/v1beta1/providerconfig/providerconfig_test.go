@@ -25,8 +25,12 @@ import (
 
 	configv1 "github.com/openshift/api/config/v1"
 	machinev1 "github.com/openshift/api/machine/v1"
+	machinev1beta1 "github.com/openshift/api/machine/v1beta1"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders"
 	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders/providers/openshift/machine/v1beta1/failuredomain"
 	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/test/resourcebuilder"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 )
 
 var _ = Describe("Provider Config", func() {
@@ -61,28 +65,173 @@ var _ = Describe("Provider Config", func() {
 			Expect(providerConfig.Type()).To(Equal(in.expectedPlatformType))
 			Expect(providerConfig).To(in.providerConfigMatcher)
 		},
-			PEntry("with an invalid platform type", providerConfigTableInput{
+			Entry("with an invalid platform type", providerConfigTableInput{
 				modifyTemplate: func(in *machinev1.ControlPlaneMachineSetTemplate) {
 					// The platform type should be inferred from here first.
 					in.OpenShiftMachineV1Beta1Machine.FailureDomains.Platform = configv1.PlatformType("invalid")
 				},
 				expectedError: fmt.Errorf("%w: %s", errUnsupportedPlatformType, "invalid"),
 			}),
-			PEntry("with an AWS config with failure domains", providerConfigTableInput{
+			Entry("with an AWS config with failure domains", providerConfigTableInput{
 				expectedPlatformType:  configv1.AWSPlatformType,
 				failureDomainsBuilder: resourcebuilder.AWSFailureDomains(),
 				providerSpecBuilder:   resourcebuilder.AWSProviderSpec(),
-				providerConfigMatcher: HaveField(".AWS().Config()", *resourcebuilder.AWSProviderSpec().Build()),
+				providerConfigMatcher: HaveField("AWS().Config()", *resourcebuilder.AWSProviderSpec().Build()),
 			}),
-			PEntry("with an AWS config without failure domains", providerConfigTableInput{
+			Entry("with an AWS config without failure domains", providerConfigTableInput{
 				expectedPlatformType:  configv1.AWSPlatformType,
 				failureDomainsBuilder: nil,
 				providerSpecBuilder:   resourcebuilder.AWSProviderSpec(),
-				providerConfigMatcher: HaveField(".AWS().Config()", *resourcebuilder.AWSProviderSpec().Build()),
+				providerConfigMatcher: HaveField("AWS().Config()", *resourcebuilder.AWSProviderSpec().Build()),
+			}),
+			Entry("with an AWS config using the machine.openshift.io API version", providerConfigTableInput{
+				expectedPlatformType:  configv1.AWSPlatformType,
+				failureDomainsBuilder: nil,
+				providerSpecBuilder:   resourcebuilder.AWSProviderSpec().WithAPIVersion("machine.openshift.io/v1beta1"),
+				providerConfigMatcher: HaveField("AWS().Config()", *resourcebuilder.AWSProviderSpec().WithAPIVersion("machine.openshift.io/v1beta1").Build()),
+			}),
+			Entry("with an AWS config using an unknown API version", providerConfigTableInput{
+				failureDomainsBuilder: resourcebuilder.AWSFailureDomains(),
+				providerSpecBuilder:   resourcebuilder.AWSProviderSpec().WithAPIVersion("unknown.openshift.io/v1"),
+				expectedError: fmt.Errorf("could not decode AWS provider spec: %w",
+					fmt.Errorf("%w: unknown.openshift.io/v1 does not serve AWSMachineProviderConfig", errUnknownProviderSpecAPIVersion),
+				),
+			}),
+			Entry("with a vSphere config", providerConfigTableInput{
+				expectedPlatformType:  configv1.VSpherePlatformType,
+				providerSpecBuilder:   resourcebuilder.VSphereProviderSpec(),
+				providerConfigMatcher: HaveField("VSphere().Config()", *resourcebuilder.VSphereProviderSpec().Build()),
+			}),
+			Entry("with a vSphere config using the legacy API version", providerConfigTableInput{
+				expectedPlatformType:  configv1.VSpherePlatformType,
+				providerSpecBuilder:   resourcebuilder.VSphereProviderSpec().WithAPIVersion("vsphereprovider.openshift.io/v1beta1"),
+				providerConfigMatcher: HaveField("VSphere().Config()", *resourcebuilder.VSphereProviderSpec().WithAPIVersion("vsphereprovider.openshift.io/v1beta1").Build()),
+			}),
+			Entry("with a Nutanix config", providerConfigTableInput{
+				expectedPlatformType:  configv1.NutanixPlatformType,
+				providerSpecBuilder:   resourcebuilder.NutanixProviderSpec(),
+				providerConfigMatcher: HaveField("Nutanix().ExtractSubnet().Name", HaveValue(Equal("nutanix-subnet-1"))),
+			}),
+			Entry("with an Alibaba Cloud config", providerConfigTableInput{
+				expectedPlatformType:  configv1.AlibabaCloudPlatformType,
+				providerSpecBuilder:   resourcebuilder.AlibabaCloudProviderSpec(),
+				providerConfigMatcher: HaveField("AlibabaCloud().ExtractZone()", Equal("cn-hangzhou-h")),
+			}),
+			Entry("with a BareMetal config", providerConfigTableInput{
+				expectedPlatformType:  configv1.BareMetalPlatformType,
+				providerSpecBuilder:   resourcebuilder.BareMetalProviderSpec().WithHostSelector(map[string]string{"rack": "r1"}),
+				providerConfigMatcher: HaveField("BareMetal().ExtractHostSelector().MatchLabels", HaveKeyWithValue("rack", "r1")),
+			}),
+			Entry("with an EquinixMetal config", providerConfigTableInput{
+				expectedPlatformType:  configv1.EquinixMetalPlatformType,
+				providerSpecBuilder:   resourcebuilder.EquinixMetalProviderSpec(),
+				providerConfigMatcher: HaveField("EquinixMetal().ExtractLocation().Metro", Equal("da")),
+			}),
+			Entry("with a GCP config", providerConfigTableInput{
+				expectedPlatformType:  configv1.GCPPlatformType,
+				providerSpecBuilder:   resourcebuilder.GCPProviderSpec(),
+				providerConfigMatcher: HaveField("GCP().Config()", *resourcebuilder.GCPProviderSpec().Build()),
+			}),
+			Entry("with a GCP config using the legacy API version", providerConfigTableInput{
+				expectedPlatformType:  configv1.GCPPlatformType,
+				providerSpecBuilder:   resourcebuilder.GCPProviderSpec().WithAPIVersion("gcpprovider.openshift.io/v1beta1"),
+				providerConfigMatcher: HaveField("GCP().Config()", *resourcebuilder.GCPProviderSpec().WithAPIVersion("gcpprovider.openshift.io/v1beta1").Build()),
+			}),
+			Entry("with an Azure config", providerConfigTableInput{
+				expectedPlatformType:  configv1.AzurePlatformType,
+				providerSpecBuilder:   resourcebuilder.AzureProviderSpec(),
+				providerConfigMatcher: HaveField("Azure().Config()", *resourcebuilder.AzureProviderSpec().Build()),
+			}),
+			Entry("with an IBM Cloud config", providerConfigTableInput{
+				expectedPlatformType:  configv1.IBMCloudPlatformType,
+				failureDomainsBuilder: nil,
+				providerSpecBuilder:   resourcebuilder.IBMCloudProviderSpec(),
+				providerConfigMatcher: HaveField("IBMCloud().ExtractProfile()", "bx2-4x16"),
+			}),
+			Entry("with a Power VS config", providerConfigTableInput{
+				expectedPlatformType:  configv1.PowerVSPlatformType,
+				failureDomainsBuilder: nil,
+				providerSpecBuilder:   resourcebuilder.PowerVSProviderSpec(),
+				providerConfigMatcher: HaveField("PowerVS().ExtractSystemType()", "s922"),
+			}),
+			Entry("with an OpenStack config", providerConfigTableInput{
+				expectedPlatformType:  configv1.OpenStackPlatformType,
+				failureDomainsBuilder: nil,
+				providerSpecBuilder:   resourcebuilder.OpenStackProviderSpec(),
+				providerConfigMatcher: HaveField("OpenStack().ExtractFlavor()", "m1.xlarge"),
+			}),
+			Entry("with an AWS config using the legacy API version without a kind", providerConfigTableInput{
+				modifyTemplate: func(in *machinev1.ControlPlaneMachineSetTemplate) {
+					in.OpenShiftMachineV1Beta1Machine.Spec.ProviderSpec.Value = &runtime.RawExtension{
+						Raw: []byte(`{"apiVersion":"awsproviderconfig.openshift.io/v1beta1","instanceType":"m6i.xlarge"}`),
+					}
+				},
+				expectedPlatformType:  configv1.AWSPlatformType,
+				providerConfigMatcher: HaveField("AWS().Config().InstanceType", "m6i.xlarge"),
+			}),
+			Entry("with a provider spec for a platform that is not supported, treats the provider spec as opaque", providerConfigTableInput{
+				modifyTemplate: func(in *machinev1.ControlPlaneMachineSetTemplate) {
+					in.OpenShiftMachineV1Beta1Machine.Spec.ProviderSpec.Value = &runtime.RawExtension{
+						Raw: []byte(`{"apiVersion":"ovirtproviderconfig.machine.openshift.io/v1beta1","kind":"OvirtMachineProviderSpec","template_name":"rhcos"}`),
+					}
+				},
+				expectedPlatformType:  configv1.OvirtPlatformType,
+				providerConfigMatcher: HaveField("Opaque().Config()", HaveKeyWithValue("template_name", "rhcos")),
+			}),
+			Entry("with failure domains for a platform that is not supported", providerConfigTableInput{
+				modifyTemplate: func(in *machinev1.ControlPlaneMachineSetTemplate) {
+					in.OpenShiftMachineV1Beta1Machine.FailureDomains.Platform = configv1.OvirtPlatformType
+					in.OpenShiftMachineV1Beta1Machine.Spec.ProviderSpec.Value = &runtime.RawExtension{
+						Raw: []byte(`{"apiVersion":"ovirtproviderconfig.machine.openshift.io/v1beta1","kind":"OvirtMachineProviderSpec"}`),
+					}
+				},
+				expectedError: fmt.Errorf("%w: oVirt (provider spec kind \"OvirtMachineProviderSpec\")", errUnsupportedPlatformType),
+			}),
+			Entry("with a provider spec of an unknown kind, treats the provider spec as opaque on the External platform", providerConfigTableInput{
+				modifyTemplate: func(in *machinev1.ControlPlaneMachineSetTemplate) {
+					in.OpenShiftMachineV1Beta1Machine.Spec.ProviderSpec.Value = &runtime.RawExtension{
+						Raw: []byte(`{"apiVersion":"example.com/v1","kind":"ExampleMachineProviderSpec"}`),
+					}
+				},
+				expectedPlatformType:  externalPlatformType,
+				providerConfigMatcher: HaveField("Opaque().Config()", HaveKeyWithValue("kind", "ExampleMachineProviderSpec")),
+			}),
+			Entry("with a provider spec of an unknown API version without a kind", providerConfigTableInput{
+				modifyTemplate: func(in *machinev1.ControlPlaneMachineSetTemplate) {
+					in.OpenShiftMachineV1Beta1Machine.Spec.ProviderSpec.Value = &runtime.RawExtension{
+						Raw: []byte(`{"apiVersion":"example.com/v1"}`),
+					}
+				},
+				expectedError: fmt.Errorf("could not determine platform type: %w",
+					fmt.Errorf("%w: \"\" with API version \"example.com/v1\"", errUnknownProviderSpecKind),
+				),
+			}),
+			Entry("with no failure domains and no provider spec", providerConfigTableInput{
+				failureDomainsBuilder: nil,
+				providerSpecBuilder:   nil,
+				expectedError:         fmt.Errorf("could not determine platform type: %w", errNilProviderSpec),
 			}),
 		)
 	})
 
+	DescribeTable("inferPlatformType", func(typeMeta metav1.TypeMeta, expectedPlatformType configv1.PlatformType, expectedOK bool) {
+		platformType, ok := inferPlatformType(typeMeta)
+		Expect(ok).To(Equal(expectedOK))
+		Expect(platformType).To(Equal(expectedPlatformType))
+	},
+		Entry("with an AWS kind", metav1.TypeMeta{APIVersion: machineAPIVersion, Kind: awsProviderConfigKind}, configv1.AWSPlatformType, true),
+		Entry("with an Azure kind in the legacy API version", metav1.TypeMeta{APIVersion: azureLegacyAPIVersion, Kind: azureProviderConfigKind}, configv1.AzurePlatformType, true),
+		Entry("with an IBM Cloud kind", metav1.TypeMeta{APIVersion: ibmCloudAPIVersion, Kind: ibmCloudProviderConfigKind}, configv1.IBMCloudPlatformType, true),
+		Entry("with an OpenStack kind", metav1.TypeMeta{APIVersion: "openstackproviderconfig.openshift.io/v1alpha1", Kind: "OpenstackProviderSpec"}, configv1.OpenStackPlatformType, true),
+		Entry("with a PowerVS kind", metav1.TypeMeta{APIVersion: "machine.openshift.io/v1", Kind: "PowerVSMachineProviderConfig"}, configv1.PowerVSPlatformType, true),
+		Entry("with a legacy vSphere API version and no kind", metav1.TypeMeta{APIVersion: vsphereLegacyAPIVersion}, configv1.VSpherePlatformType, true),
+		Entry("with a legacy Equinix Metal API version and no kind", metav1.TypeMeta{APIVersion: equinixMetalLegacyAPIVersion}, configv1.EquinixMetalPlatformType, true),
+		Entry("with a legacy oVirt API group in another version and no kind", metav1.TypeMeta{APIVersion: "ovirtproviderconfig.machine.openshift.io/v1alpha1"}, configv1.OvirtPlatformType, true),
+		Entry("with the Machine API version and no kind", metav1.TypeMeta{APIVersion: machineAPIVersion}, configv1.PlatformType(""), false),
+		Entry("with an unknown kind", metav1.TypeMeta{APIVersion: "example.com/v1", Kind: "ExampleMachineProviderSpec"}, configv1.PlatformType(""), false),
+		Entry("with no type information", metav1.TypeMeta{}, configv1.PlatformType(""), false),
+	)
+
 	Context("InjectFailureDomain", func() {
 		type injectFailureDomainTableInput struct {
 			providerConfig   ProviderConfig
@@ -103,7 +252,7 @@ var _ = Describe("Provider Config", func() {
 
 			Expect(pc).To(HaveField(in.matchPath, Equal(in.matchExpectation)))
 		},
-			PEntry("when keeping an AWS availability zone the same", injectFailureDomainTableInput{
+			Entry("when keeping an AWS availability zone the same", injectFailureDomainTableInput{
 				providerConfig: &providerConfig{
 					platformType: configv1.AWSPlatformType,
 					aws: AWSProviderConfig{
@@ -116,7 +265,7 @@ var _ = Describe("Provider Config", func() {
 				matchPath:        "AWS().Config().Placement.AvailabilityZone",
 				matchExpectation: "us-east-1a",
 			}),
-			PEntry("when changing an AWS availability zone", injectFailureDomainTableInput{
+			Entry("when changing an AWS availability zone", injectFailureDomainTableInput{
 				providerConfig: &providerConfig{
 					platformType: configv1.AWSPlatformType,
 					aws: AWSProviderConfig{
@@ -129,6 +278,291 @@ var _ = Describe("Provider Config", func() {
 				matchPath:        "AWS().Config().Placement.AvailabilityZone",
 				matchExpectation: "us-east-1b",
 			}),
+			Entry("when changing an Azure zone", injectFailureDomainTableInput{
+				providerConfig: &providerConfig{
+					platformType: configv1.AzurePlatformType,
+					azure: AzureProviderConfig{
+						providerConfig: *resourcebuilder.AzureProviderSpec().WithZone("1").Build(),
+					},
+				},
+				failureDomain: failuredomain.NewAzureFailureDomain(
+					resourcebuilder.AzureFailureDomain().WithZone("2").Build(),
+				),
+				matchPath:        "Azure().ExtractFailureDomain().Zone",
+				matchExpectation: "2",
+			}),
+			Entry("when setting an Azure zone on a provider config without a zone", injectFailureDomainTableInput{
+				providerConfig: &providerConfig{
+					platformType: configv1.AzurePlatformType,
+					azure: AzureProviderConfig{
+						providerConfig: *resourcebuilder.AzureProviderSpec().Build(),
+					},
+				},
+				failureDomain: failuredomain.NewAzureFailureDomain(
+					resourcebuilder.AzureFailureDomain().WithZone("3").Build(),
+				),
+				matchPath:        "Azure().ExtractFailureDomain().Zone",
+				matchExpectation: "3",
+			}),
+			Entry("when changing a GCP zone", injectFailureDomainTableInput{
+				providerConfig: &providerConfig{
+					platformType: configv1.GCPPlatformType,
+					gcp: GCPProviderConfig{
+						providerConfig: *resourcebuilder.GCPProviderSpec().WithZone("us-central1-a").Build(),
+					},
+				},
+				failureDomain: failuredomain.NewGCPFailureDomain(
+					resourcebuilder.GCPFailureDomain().WithZone("us-central1-b").Build(),
+				),
+				matchPath:        "GCP().Config().Zone",
+				matchExpectation: "us-central1-b",
+			}),
+		)
+	})
+
+	Context("InjectImage", func() {
+		type injectImageTableInput struct {
+			providerConfig ProviderConfig
+			image          string
+			expectedImage  string
+			expectedError  error
+		}
+
+		DescribeTable("should inject the image into the provider config", func(in injectImageTableInput) {
+			pc, err := in.providerConfig.InjectImage(in.image)
+
+			if in.expectedError != nil {
+				Expect(err).To(MatchError(in.expectedError))
+				return
+			}
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(pc.ExtractImage()).To(Equal(in.expectedImage))
+			Expect(in.providerConfig.ExtractImage()).ToNot(Equal(in.expectedImage), "the original provider config should not be modified")
+		},
+			Entry("with an AWS config", injectImageTableInput{
+				providerConfig: &providerConfig{
+					platformType: configv1.AWSPlatformType,
+					aws: AWSProviderConfig{
+						providerConfig: *resourcebuilder.AWSProviderSpec().Build(),
+					},
+				},
+				image:         "aws-ami-87654321",
+				expectedImage: "aws-ami-87654321",
+			}),
+			Entry("with an unsupported platform", injectImageTableInput{
+				providerConfig: &providerConfig{
+					platformType: configv1.BareMetalPlatformType,
+				},
+				image:         "image",
+				expectedError: errUnsupportedPlatformType,
+			}),
+		)
+	})
+
+	Context("InjectTags", func() {
+		type injectTagsTableInput struct {
+			providerConfig ProviderConfig
+			tags           map[string]string
+			expectedTags   map[string]string
+			expectedError  error
+		}
+
+		DescribeTable("should inject the tags into the provider config", func(in injectTagsTableInput) {
+			pc, err := in.providerConfig.InjectTags(in.tags)
+
+			if in.expectedError != nil {
+				Expect(err).To(MatchError(in.expectedError))
+				return
+			}
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(pc.ExtractTags()).To(Equal(in.expectedTags))
+		},
+			Entry("with an AWS config", injectTagsTableInput{
+				providerConfig: &providerConfig{
+					platformType: configv1.AWSPlatformType,
+					aws: AWSProviderConfig{
+						providerConfig: *resourcebuilder.AWSProviderSpec().WithTags([]machinev1beta1.TagSpecification{{Name: "existing", Value: "value"}}).Build(),
+					},
+				},
+				tags:         map[string]string{"kubernetes.io/cluster/cluster-id": "owned"},
+				expectedTags: map[string]string{"existing": "value", "kubernetes.io/cluster/cluster-id": "owned"},
+			}),
+			Entry("with no tags on an unsupported platform", injectTagsTableInput{
+				providerConfig: &providerConfig{
+					platformType: configv1.BareMetalPlatformType,
+				},
+				tags:         map[string]string{},
+				expectedTags: nil,
+			}),
+			Entry("with tags on an unsupported platform", injectTagsTableInput{
+				providerConfig: &providerConfig{
+					platformType: configv1.BareMetalPlatformType,
+				},
+				tags:          map[string]string{"kubernetes.io/cluster/cluster-id": "owned"},
+				expectedError: errUnsupportedPlatformType,
+			}),
+		)
+	})
+
+	Context("InjectUserDataSecret", func() {
+		type injectUserDataSecretTableInput struct {
+			providerConfig         ProviderConfig
+			secretName             string
+			expectedProviderConfig ProviderConfig
+			expectedError          error
+		}
+
+		DescribeTable("should inject the user data secret into the provider config", func(in injectUserDataSecretTableInput) {
+			pc, err := in.providerConfig.InjectUserDataSecret(in.secretName)
+
+			if in.expectedError != nil {
+				Expect(err).To(MatchError(in.expectedError))
+				return
+			}
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(pc.ExtractUserDataSecret()).To(Equal(in.secretName))
+			Expect(pc.Equal(in.expectedProviderConfig)).To(BeTrue())
+		},
+			Entry("with an AWS config", injectUserDataSecretTableInput{
+				providerConfig: &providerConfig{
+					platformType: configv1.AWSPlatformType,
+					aws: AWSProviderConfig{
+						providerConfig: *resourcebuilder.AWSProviderSpec().Build(),
+					},
+				},
+				secretName: "master-user-data-1a",
+				expectedProviderConfig: &providerConfig{
+					platformType: configv1.AWSPlatformType,
+					aws: AWSProviderConfig{
+						providerConfig: func() machinev1beta1.AWSMachineProviderConfig {
+							spec := resourcebuilder.AWSProviderSpec().Build()
+							spec.UserDataSecret.Name = "master-user-data-1a"
+							return *spec
+						}(),
+					},
+				},
+			}),
+			Entry("with an Azure config", injectUserDataSecretTableInput{
+				providerConfig: &providerConfig{
+					platformType: configv1.AzurePlatformType,
+					azure: AzureProviderConfig{
+						providerConfig: *resourcebuilder.AzureProviderSpec().Build(),
+					},
+				},
+				secretName: "master-user-data-1",
+				expectedProviderConfig: &providerConfig{
+					platformType: configv1.AzurePlatformType,
+					azure: AzureProviderConfig{
+						providerConfig: func() machinev1beta1.AzureMachineProviderSpec {
+							spec := resourcebuilder.AzureProviderSpec().Build()
+							spec.UserDataSecret.Name = "master-user-data-1"
+							return *spec
+						}(),
+					},
+				},
+			}),
+			Entry("with a GCP config", injectUserDataSecretTableInput{
+				providerConfig: &providerConfig{
+					platformType: configv1.GCPPlatformType,
+					gcp: GCPProviderConfig{
+						providerConfig: *resourcebuilder.GCPProviderSpec().Build(),
+					},
+				},
+				secretName: "master-user-data-us-central1-a",
+				expectedProviderConfig: &providerConfig{
+					platformType: configv1.GCPPlatformType,
+					gcp: GCPProviderConfig{
+						providerConfig: func() machinev1beta1.GCPMachineProviderSpec {
+							spec := resourcebuilder.GCPProviderSpec().Build()
+							spec.UserDataSecret.Name = "master-user-data-us-central1-a"
+							return *spec
+						}(),
+					},
+				},
+			}),
+			Entry("with an unsupported platform", injectUserDataSecretTableInput{
+				providerConfig: &providerConfig{
+					platformType: configv1.BareMetalPlatformType,
+				},
+				secretName:    "master-user-data",
+				expectedError: errUnsupportedPlatformType,
+			}),
+		)
+	})
+
+	Context("InjectSubnet", func() {
+		type injectSubnetTableInput struct {
+			providerConfig         ProviderConfig
+			subnet                 string
+			networkResourceGroup   string
+			expectedProviderConfig ProviderConfig
+			expectedError          error
+		}
+
+		DescribeTable("should inject the subnet into the provider config", func(in injectSubnetTableInput) {
+			pc, err := in.providerConfig.InjectSubnet(in.subnet, in.networkResourceGroup)
+
+			if in.expectedError != nil {
+				Expect(err).To(MatchError(in.expectedError))
+				return
+			}
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(pc.Equal(in.expectedProviderConfig)).To(BeTrue())
+		},
+			Entry("with an Azure config and a network resource group", injectSubnetTableInput{
+				providerConfig: &providerConfig{
+					platformType: configv1.AzurePlatformType,
+					azure: AzureProviderConfig{
+						providerConfig: *resourcebuilder.AzureProviderSpec().Build(),
+					},
+				},
+				subnet:               "master-subnet-1",
+				networkResourceGroup: "network-rg-1",
+				expectedProviderConfig: &providerConfig{
+					platformType: configv1.AzurePlatformType,
+					azure: AzureProviderConfig{
+						providerConfig: func() machinev1beta1.AzureMachineProviderSpec {
+							spec := resourcebuilder.AzureProviderSpec().Build()
+							spec.Subnet = "master-subnet-1"
+							spec.NetworkResourceGroup = "network-rg-1"
+							return *spec
+						}(),
+					},
+				},
+			}),
+			Entry("with an Azure config and no network resource group", injectSubnetTableInput{
+				providerConfig: &providerConfig{
+					platformType: configv1.AzurePlatformType,
+					azure: AzureProviderConfig{
+						providerConfig: *resourcebuilder.AzureProviderSpec().Build(),
+					},
+				},
+				subnet: "master-subnet-1",
+				expectedProviderConfig: &providerConfig{
+					platformType: configv1.AzurePlatformType,
+					azure: AzureProviderConfig{
+						providerConfig: func() machinev1beta1.AzureMachineProviderSpec {
+							spec := resourcebuilder.AzureProviderSpec().Build()
+							spec.Subnet = "master-subnet-1"
+							return *spec
+						}(),
+					},
+				},
+			}),
+			Entry("with an AWS config", injectSubnetTableInput{
+				providerConfig: &providerConfig{
+					platformType: configv1.AWSPlatformType,
+					aws: AWSProviderConfig{
+						providerConfig: *resourcebuilder.AWSProviderSpec().Build(),
+					},
+				},
+				subnet:        "master-subnet-1",
+				expectedError: errUnsupportedPlatformType,
+			}),
 		)
 	})
 
@@ -143,7 +577,7 @@ var _ = Describe("Provider Config", func() {
 
 			Expect(fd).To(Equal(in.expectedFailureDomain))
 		},
-			PEntry("with an AWS us-east-1a failure domain", extractFailureDomainTableInput{
+			Entry("with an AWS us-east-1a failure domain", extractFailureDomainTableInput{
 				providerConfig: &providerConfig{
 					platformType: configv1.AWSPlatformType,
 					aws: AWSProviderConfig{
@@ -151,10 +585,18 @@ var _ = Describe("Provider Config", func() {
 					},
 				},
 				expectedFailureDomain: failuredomain.NewAWSFailureDomain(
-					resourcebuilder.AWSFailureDomain().WithAvailabilityZone("us-east-1a").Build(),
+					resourcebuilder.AWSFailureDomain().WithAvailabilityZone("us-east-1a").WithSubnet(machinev1.AWSResourceReference{
+						Type: machinev1.AWSFiltersReferenceType,
+						Filters: &[]machinev1.AWSResourceFilter{
+							{
+								Name:   "tag:Name",
+								Values: []string{"aws-subnet-12345678"},
+							},
+						},
+					}).Build(),
 				),
 			}),
-			PEntry("with an AWS us-east-1b failure domain", extractFailureDomainTableInput{
+			Entry("with an AWS us-east-1b failure domain", extractFailureDomainTableInput{
 				providerConfig: &providerConfig{
 					platformType: configv1.AWSPlatformType,
 					aws: AWSProviderConfig{
@@ -162,7 +604,37 @@ var _ = Describe("Provider Config", func() {
 					},
 				},
 				expectedFailureDomain: failuredomain.NewAWSFailureDomain(
-					resourcebuilder.AWSFailureDomain().WithAvailabilityZone("us-east-1b").Build(),
+					resourcebuilder.AWSFailureDomain().WithAvailabilityZone("us-east-1b").WithSubnet(machinev1.AWSResourceReference{
+						Type: machinev1.AWSFiltersReferenceType,
+						Filters: &[]machinev1.AWSResourceFilter{
+							{
+								Name:   "tag:Name",
+								Values: []string{"aws-subnet-12345678"},
+							},
+						},
+					}).Build(),
+				),
+			}),
+			Entry("with an Azure zone 1 failure domain", extractFailureDomainTableInput{
+				providerConfig: &providerConfig{
+					platformType: configv1.AzurePlatformType,
+					azure: AzureProviderConfig{
+						providerConfig: *resourcebuilder.AzureProviderSpec().WithZone("1").Build(),
+					},
+				},
+				expectedFailureDomain: failuredomain.NewAzureFailureDomain(
+					resourcebuilder.AzureFailureDomain().WithZone("1").Build(),
+				),
+			}),
+			Entry("with a GCP us-central1-a failure domain", extractFailureDomainTableInput{
+				providerConfig: &providerConfig{
+					platformType: configv1.GCPPlatformType,
+					gcp: GCPProviderConfig{
+						providerConfig: *resourcebuilder.GCPProviderSpec().WithZone("us-central1-a").Build(),
+					},
+				},
+				expectedFailureDomain: failuredomain.NewGCPFailureDomain(
+					resourcebuilder.GCPFailureDomain().WithZone("us-central1-a").Build(),
 				),
 			}),
 		)
@@ -170,10 +642,11 @@ var _ = Describe("Provider Config", func() {
 
 	Context("Equal", func() {
 		type equalTableInput struct {
-			basePC        ProviderConfig
-			comparePC     ProviderConfig
-			expectedEqual bool
-			expectedError error
+			basePC              ProviderConfig
+			comparePC           ProviderConfig
+			expectedEqual       bool
+			expectedError       error
+			expectedErrorReason machineproviders.ErrorReason
 		}
 
 		DescribeTable("should compare provider configs", func(in equalTableInput) {
@@ -185,19 +658,26 @@ var _ = Describe("Provider Config", func() {
 				Expect(err).ToNot(HaveOccurred())
 			}
 
+			if in.expectedErrorReason != "" {
+				reason, ok := machineproviders.ConfigurationErrorReason(err)
+				Expect(ok).To(BeTrue(), "The error should be a configuration error")
+				Expect(reason).To(Equal(in.expectedErrorReason))
+			}
+
 			Expect(equal).To(Equal(in.expectedEqual), "Equality of provider configs was not as expected")
 		},
-			PEntry("with different platform types", equalTableInput{
+			Entry("with different platform types", equalTableInput{
 				basePC: &providerConfig{
 					platformType: configv1.AWSPlatformType,
 				},
 				comparePC: &providerConfig{
 					platformType: configv1.AzurePlatformType,
 				},
-				expectedEqual: false,
-				expectedError: errMismatchedPlatformTypes,
+				expectedEqual:       false,
+				expectedError:       errMismatchedPlatformTypes,
+				expectedErrorReason: machineproviders.ReasonTemplatePlatformMismatch,
 			}),
-			PEntry("with matching AWS configs", equalTableInput{
+			Entry("with matching AWS configs", equalTableInput{
 				basePC: &providerConfig{
 					platformType: configv1.AWSPlatformType,
 					aws: AWSProviderConfig{
@@ -212,7 +692,7 @@ var _ = Describe("Provider Config", func() {
 				},
 				expectedEqual: true,
 			}),
-			PEntry("with mis-matched AWS configs", equalTableInput{
+			Entry("with mis-matched AWS configs", equalTableInput{
 				basePC: &providerConfig{
 					platformType: configv1.AWSPlatformType,
 					aws: AWSProviderConfig{
@@ -227,6 +707,233 @@ var _ = Describe("Provider Config", func() {
 				},
 				expectedEqual: false,
 			}),
+			Entry("with matching AWS configs using different API versions", equalTableInput{
+				basePC: &providerConfig{
+					platformType: configv1.AWSPlatformType,
+					aws: AWSProviderConfig{
+						providerConfig: *resourcebuilder.AWSProviderSpec().WithAPIVersion("awsproviderconfig.openshift.io/v1beta1").Build(),
+					},
+				},
+				comparePC: &providerConfig{
+					platformType: configv1.AWSPlatformType,
+					aws: AWSProviderConfig{
+						providerConfig: *resourcebuilder.AWSProviderSpec().WithAPIVersion("machine.openshift.io/v1beta1").Build(),
+					},
+				},
+				expectedEqual: true,
+			}),
+			Entry("with mis-matched AWS configs using different API versions", equalTableInput{
+				basePC: &providerConfig{
+					platformType: configv1.AWSPlatformType,
+					aws: AWSProviderConfig{
+						providerConfig: *resourcebuilder.AWSProviderSpec().WithAPIVersion("awsproviderconfig.openshift.io/v1beta1").Build(),
+					},
+				},
+				comparePC: &providerConfig{
+					platformType: configv1.AWSPlatformType,
+					aws: AWSProviderConfig{
+						providerConfig: *resourcebuilder.AWSProviderSpec().WithAPIVersion("machine.openshift.io/v1beta1").WithInstanceType("m6i.2xlarge").Build(),
+					},
+				},
+				expectedEqual: false,
+			}),
+			Entry("with matching vSphere configs using different API versions", equalTableInput{
+				basePC: &providerConfig{
+					platformType: configv1.VSpherePlatformType,
+					vsphere: VSphereProviderConfig{
+						providerConfig: *resourcebuilder.VSphereProviderSpec().WithAPIVersion("vsphereprovider.openshift.io/v1beta1").Build(),
+					},
+				},
+				comparePC: &providerConfig{
+					platformType: configv1.VSpherePlatformType,
+					vsphere: VSphereProviderConfig{
+						providerConfig: *resourcebuilder.VSphereProviderSpec().Build(),
+					},
+				},
+				expectedEqual: true,
+			}),
+			Entry("with vSphere configs using different templates", equalTableInput{
+				basePC: &providerConfig{
+					platformType: configv1.VSpherePlatformType,
+					vsphere: VSphereProviderConfig{
+						providerConfig: *resourcebuilder.VSphereProviderSpec().WithTemplate("rhcos-4.11").Build(),
+					},
+				},
+				comparePC: &providerConfig{
+					platformType: configv1.VSpherePlatformType,
+					vsphere: VSphereProviderConfig{
+						providerConfig: *resourcebuilder.VSphereProviderSpec().WithTemplate("rhcos-4.12").Build(),
+					},
+				},
+				expectedEqual: false,
+			}),
+		)
+	})
+
+	Context("UnmanagedFields", func() {
+		type unmanagedFieldsTableInput struct {
+			basePC         ProviderConfig
+			comparePC      ProviderConfig
+			expectedFields []string
+			expectedError  error
+		}
+
+		DescribeTable("should report the tolerated differences between provider configs", func(in unmanagedFieldsTableInput) {
+			fields, err := in.basePC.UnmanagedFields(in.comparePC)
+
+			if in.expectedError != nil {
+				Expect(err).To(MatchError(in.expectedError))
+			} else {
+				Expect(err).ToNot(HaveOccurred())
+			}
+
+			Expect(fields).To(ConsistOf(in.expectedFields))
+		},
+			Entry("with different platform types", unmanagedFieldsTableInput{
+				basePC: &providerConfig{
+					platformType: configv1.AWSPlatformType,
+				},
+				comparePC: &providerConfig{
+					platformType: configv1.AzurePlatformType,
+				},
+				expectedError: errMismatchedPlatformTypes,
+			}),
+			Entry("with matching AWS configs", unmanagedFieldsTableInput{
+				basePC: &providerConfig{
+					platformType: configv1.AWSPlatformType,
+					aws: AWSProviderConfig{
+						providerConfig: *resourcebuilder.AWSProviderSpec().Build(),
+					},
+				},
+				comparePC: &providerConfig{
+					platformType: configv1.AWSPlatformType,
+					aws: AWSProviderConfig{
+						providerConfig: *resourcebuilder.AWSProviderSpec().Build(),
+					},
+				},
+				expectedFields: []string{},
+			}),
+			Entry("with AWS configs using different API versions", unmanagedFieldsTableInput{
+				basePC: &providerConfig{
+					platformType: configv1.AWSPlatformType,
+					aws: AWSProviderConfig{
+						providerConfig: *resourcebuilder.AWSProviderSpec().WithAPIVersion("awsproviderconfig.openshift.io/v1beta1").Build(),
+					},
+				},
+				comparePC: &providerConfig{
+					platformType: configv1.AWSPlatformType,
+					aws: AWSProviderConfig{
+						providerConfig: *resourcebuilder.AWSProviderSpec().WithAPIVersion("machine.openshift.io/v1beta1").Build(),
+					},
+				},
+				expectedFields: []string{"apiVersion"},
+			}),
+			Entry("with AWS configs that differ in a managed field", unmanagedFieldsTableInput{
+				basePC: &providerConfig{
+					platformType: configv1.AWSPlatformType,
+					aws: AWSProviderConfig{
+						providerConfig: *resourcebuilder.AWSProviderSpec().WithInstanceType("m6i.xlarge").Build(),
+					},
+				},
+				comparePC: &providerConfig{
+					platformType: configv1.AWSPlatformType,
+					aws: AWSProviderConfig{
+						providerConfig: *resourcebuilder.AWSProviderSpec().WithInstanceType("m6i.2xlarge").Build(),
+					},
+				},
+				expectedFields: []string{},
+			}),
+		)
+	})
+
+	Context("ChangedFields", func() {
+		type changedFieldsTableInput struct {
+			basePC         ProviderConfig
+			comparePC      ProviderConfig
+			expectedFields []string
+			expectedError  error
+		}
+
+		DescribeTable("should report the fields that differ between provider configs", func(in changedFieldsTableInput) {
+			fields, err := in.basePC.ChangedFields(in.comparePC)
+
+			if in.expectedError != nil {
+				Expect(err).To(MatchError(in.expectedError))
+			} else {
+				Expect(err).ToNot(HaveOccurred())
+			}
+
+			Expect(fields).To(Equal(in.expectedFields))
+		},
+			Entry("with different platform types", changedFieldsTableInput{
+				basePC: &providerConfig{
+					platformType: configv1.AWSPlatformType,
+				},
+				comparePC: &providerConfig{
+					platformType: configv1.AzurePlatformType,
+				},
+				expectedError: errMismatchedPlatformTypes,
+			}),
+			Entry("with matching AWS configs", changedFieldsTableInput{
+				basePC: &providerConfig{
+					platformType: configv1.AWSPlatformType,
+					aws: AWSProviderConfig{
+						providerConfig: *resourcebuilder.AWSProviderSpec().Build(),
+					},
+				},
+				comparePC: &providerConfig{
+					platformType: configv1.AWSPlatformType,
+					aws: AWSProviderConfig{
+						providerConfig: *resourcebuilder.AWSProviderSpec().Build(),
+					},
+				},
+				expectedFields: []string{},
+			}),
+			Entry("with AWS configs that only differ by tolerated fields", changedFieldsTableInput{
+				basePC: &providerConfig{
+					platformType: configv1.AWSPlatformType,
+					aws: AWSProviderConfig{
+						providerConfig: *resourcebuilder.AWSProviderSpec().WithAPIVersion("awsproviderconfig.openshift.io/v1beta1").Build(),
+					},
+				},
+				comparePC: &providerConfig{
+					platformType: configv1.AWSPlatformType,
+					aws: AWSProviderConfig{
+						providerConfig: *resourcebuilder.AWSProviderSpec().WithAPIVersion("machine.openshift.io/v1beta1").Build(),
+					},
+				},
+				expectedFields: []string{},
+			}),
+			Entry("with AWS configs that differ in several fields", changedFieldsTableInput{
+				basePC: &providerConfig{
+					platformType: configv1.AWSPlatformType,
+					aws: AWSProviderConfig{
+						providerConfig: *resourcebuilder.AWSProviderSpec().WithInstanceType("m6i.xlarge").WithAvailabilityZone("us-east-1a").Build(),
+					},
+				},
+				comparePC: &providerConfig{
+					platformType: configv1.AWSPlatformType,
+					aws: AWSProviderConfig{
+						providerConfig: *resourcebuilder.AWSProviderSpec().WithInstanceType("m6i.2xlarge").WithAvailabilityZone("us-east-1b").Build(),
+					},
+				},
+				expectedFields: []string{"instanceType", "placement"},
+			}),
+			Entry("with vSphere configs using different templates", changedFieldsTableInput{
+				basePC: &providerConfig{
+					platformType: configv1.VSpherePlatformType,
+					vsphere: VSphereProviderConfig{
+						providerConfig: *resourcebuilder.VSphereProviderSpec().WithTemplate("rhcos-4.11").Build(),
+					},
+				},
+				comparePC: &providerConfig{
+					platformType: configv1.VSpherePlatformType,
+					vsphere: VSphereProviderConfig{
+						providerConfig: *resourcebuilder.VSphereProviderSpec().WithTemplate("/datacenter/vm/templates/rhcos-4.12").Build(),
+					},
+				},
+				expectedFields: []string{"template"},
+			}),
 		)
 	})
 
@@ -248,7 +955,7 @@ var _ = Describe("Provider Config", func() {
 
 			Expect(out).To(Equal(in.expectedOut))
 		},
-			PEntry("with an AWS config", rawConfigTableInput{
+			Entry("with an AWS config", rawConfigTableInput{
 				providerConfig: &providerConfig{
 					platformType: configv1.AWSPlatformType,
 					aws: AWSProviderConfig{
@@ -259,4 +966,73 @@ var _ = Describe("Provider Config", func() {
 			}),
 		)
 	})
+
+	Context("with provider configs decoded from identical raw provider specs", func() {
+		var basePC, comparePC ProviderConfig
+
+		BeforeEach(func() {
+			var err error
+
+			basePC, err = newAWSProviderConfig(resourcebuilder.AWSProviderSpec().WithAvailabilityZone("us-east-1a").BuildRawExtension())
+			Expect(err).ToNot(HaveOccurred())
+
+			comparePC, err = newAWSProviderConfig(resourcebuilder.AWSProviderSpec().WithAvailabilityZone("us-east-1a").BuildRawExtension())
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("compares the provider configs as equal", func() {
+			Expect(basePC.Equal(comparePC)).To(BeTrue())
+		})
+
+		It("reports no unmanaged fields", func() {
+			Expect(basePC.UnmanagedFields(comparePC)).To(BeEmpty())
+		})
+
+		It("reports no changed fields", func() {
+			Expect(basePC.ChangedFields(comparePC)).To(BeEmpty())
+		})
+
+		It("keeps the raw provider spec when injecting the same failure domain", func() {
+			injected, err := basePC.InjectFailureDomain(failuredomain.NewAWSFailureDomain(
+				resourcebuilder.AWSFailureDomain().WithAvailabilityZone("us-east-1a").Build(),
+			))
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(injected.(providerConfig).raw).To(Equal(basePC.(providerConfig).raw))
+		})
+
+		It("clears the raw provider spec when injecting a different failure domain", func() {
+			injected, err := basePC.InjectFailureDomain(failuredomain.NewAWSFailureDomain(
+				resourcebuilder.AWSFailureDomain().WithAvailabilityZone("us-east-1b").Build(),
+			))
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(injected.(providerConfig).raw).To(BeNil())
+			Expect(injected.Equal(comparePC)).To(BeFalse())
+			Expect(injected.ChangedFields(comparePC)).To(ConsistOf("placement"))
+		})
+
+		It("clears the raw provider spec when injecting a different image", func() {
+			injected, err := basePC.InjectImage("aws-ami-87654321")
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(injected.(providerConfig).raw).To(BeNil())
+			Expect(injected.Equal(comparePC)).To(BeFalse())
+		})
+
+		It("keeps the raw provider spec when injecting no new tags", func() {
+			injected, err := basePC.InjectTags(map[string]string{})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(injected.(providerConfig).raw).To(Equal(basePC.(providerConfig).raw))
+		})
+
+		It("clears the raw provider spec when injecting a new tag", func() {
+			injected, err := basePC.InjectTags(map[string]string{"kubernetes.io/cluster/cluster-id": "owned"})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(injected.(providerConfig).raw).To(BeNil())
+			Expect(injected.Equal(comparePC)).To(BeFalse())
+		})
+	})
 })
@@ -0,0 +1,319 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package providerconfig
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	configv1 "github.com/openshift/api/config/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders"
+)
+
+const (
+	// machineAPIVersion is the current API version for provider specs within the Machine API.
+	// Provider specs written by newer components of the Machine API use this version regardless
+	// of the platform, older components used a platform specific API group.
+	machineAPIVersion = "machine.openshift.io/v1beta1"
+
+	// awsProviderConfigKind is the kind of the AWS provider spec.
+	awsProviderConfigKind = "AWSMachineProviderConfig"
+
+	// awsLegacyAPIVersion is the platform specific API version that was used for AWS provider
+	// specs before they moved into the machine.openshift.io API group.
+	awsLegacyAPIVersion = "awsproviderconfig.openshift.io/v1beta1"
+
+	// vsphereProviderConfigKind is the kind of the vSphere provider spec.
+	vsphereProviderConfigKind = "VSphereMachineProviderSpec"
+
+	// vsphereLegacyAPIVersion is the platform specific API version that was used for vSphere
+	// provider specs before they moved into the machine.openshift.io API group.
+	vsphereLegacyAPIVersion = "vsphereprovider.openshift.io/v1beta1"
+
+	// gcpProviderConfigKind is the kind of the GCP provider spec.
+	gcpProviderConfigKind = "GCPMachineProviderSpec"
+
+	// gcpLegacyAPIVersion is the platform specific API version that was used for GCP provider
+	// specs before they moved into the machine.openshift.io API group.
+	gcpLegacyAPIVersion = "gcpprovider.openshift.io/v1beta1"
+
+	// azureProviderConfigKind is the kind of the Azure provider spec.
+	azureProviderConfigKind = "AzureMachineProviderSpec"
+
+	// azureLegacyAPIVersion is the platform specific API version that was used for Azure provider
+	// specs before they moved into the machine.openshift.io API group.
+	azureLegacyAPIVersion = "azureproviderconfig.openshift.io/v1beta1"
+
+	// ibmCloudProviderConfigKind is the kind of the IBM Cloud VPC provider spec.
+	ibmCloudProviderConfigKind = "IBMCloudMachineProviderSpec"
+
+	// ibmCloudAPIVersion is the API version of the IBM Cloud VPC provider spec.
+	// Unlike the other provider specs, the IBM Cloud provider spec has only ever been served by its platform
+	// specific API group.
+	ibmCloudAPIVersion = "ibmcloudproviderconfig.openshift.io/v1beta1"
+
+	// nutanixProviderConfigKind is the kind of the Nutanix provider spec.
+	nutanixProviderConfigKind = "NutanixMachineProviderConfig"
+
+	// powerVSProviderConfigKind is the kind of the Power VS provider spec.
+	powerVSProviderConfigKind = "PowerVSMachineProviderConfig"
+
+	// bareMetalProviderConfigKind is the kind of the bare metal (Metal3) provider spec.
+	bareMetalProviderConfigKind = "BareMetalMachineProviderSpec"
+
+	// bareMetalAPIVersion is the API version of the bare metal provider spec.
+	// The bare metal provider spec is served by the Metal3 API rather than the Machine API.
+	bareMetalAPIVersion = "baremetal.cluster.k8s.io/v1alpha1"
+
+	// alibabaCloudProviderConfigKind is the kind of the Alibaba Cloud provider spec.
+	alibabaCloudProviderConfigKind = "AlibabaCloudMachineProviderConfig"
+
+	// equinixMetalProviderConfigKind is the kind of the Equinix Metal provider spec.
+	// Equinix Metal was formerly known as Packet, and the provider spec kind retains the former name.
+	equinixMetalProviderConfigKind = "PacketMachineProviderConfig"
+
+	// equinixMetalLegacyAPIVersion is the platform specific API version that was used for Equinix Metal
+	// provider specs before they moved into the machine.openshift.io API group.
+	equinixMetalLegacyAPIVersion = "packetprovider.openshift.io/v1alpha1"
+
+	// machineV1APIVersion is the API version of the Nutanix, Power VS and Alibaba Cloud provider specs.
+	// Unlike the other provider specs, these provider specs are served by the machine.openshift.io/v1 API.
+	machineV1APIVersion = "machine.openshift.io/v1"
+
+	// openStackProviderConfigKind is the kind of the OpenStack provider spec.
+	openStackProviderConfigKind = "OpenstackProviderSpec"
+
+	// openStackAPIVersion is the API version of the OpenStack provider spec.
+	// Unlike the other provider specs, the OpenStack provider spec is served by the machine.openshift.io/v1alpha1 API.
+	openStackAPIVersion = "machine.openshift.io/v1alpha1"
+
+	// openStackLegacyAPIVersion is the platform specific API version that was used for OpenStack provider
+	// specs before they moved into the machine.openshift.io API group.
+	openStackLegacyAPIVersion = "openstackproviderconfig.openshift.io/v1alpha1"
+)
+
+var (
+	// providerSpecKindPlatforms maps the kind of each provider spec known to the Machine API to the platform that
+	// it configures. This includes platforms that the ControlPlaneMachineSet does not support, so that a Machine
+	// template for such a platform is reported as an unsupported platform, rather than as an unknown kind.
+	providerSpecKindPlatforms = map[string]configv1.PlatformType{
+		awsProviderConfigKind:          configv1.AWSPlatformType,
+		vsphereProviderConfigKind:      configv1.VSpherePlatformType,
+		gcpProviderConfigKind:          configv1.GCPPlatformType,
+		azureProviderConfigKind:        configv1.AzurePlatformType,
+		alibabaCloudProviderConfigKind: configv1.AlibabaCloudPlatformType,
+		bareMetalProviderConfigKind:    configv1.BareMetalPlatformType,
+		equinixMetalProviderConfigKind: configv1.EquinixMetalPlatformType,
+		ibmCloudProviderConfigKind:     configv1.IBMCloudPlatformType,
+		"KubevirtMachineProviderSpec":  configv1.KubevirtPlatformType,
+		"LibvirtMachineProviderConfig": configv1.LibvirtPlatformType,
+		nutanixProviderConfigKind:      configv1.NutanixPlatformType,
+		openStackProviderConfigKind:    configv1.OpenStackPlatformType,
+		"OvirtMachineProviderSpec":     configv1.OvirtPlatformType,
+		powerVSProviderConfigKind:      configv1.PowerVSPlatformType,
+	}
+
+	// legacyAPIGroupPlatforms maps the platform specific API groups, used by older components of the Machine API,
+	// to the platform that they configure. Any version within these groups configures the same platform.
+	// This allows the platform of a provider spec that omits its kind to be inferred from its API version.
+	legacyAPIGroupPlatforms = map[string]configv1.PlatformType{
+		"awsproviderconfig.openshift.io":           configv1.AWSPlatformType,
+		"vsphereprovider.openshift.io":             configv1.VSpherePlatformType,
+		"gcpprovider.openshift.io":                 configv1.GCPPlatformType,
+		"azureproviderconfig.openshift.io":         configv1.AzurePlatformType,
+		"baremetal.cluster.k8s.io":                 configv1.BareMetalPlatformType,
+		"packetprovider.openshift.io":              configv1.EquinixMetalPlatformType,
+		"ibmcloudproviderconfig.openshift.io":      configv1.IBMCloudPlatformType,
+		"kubevirtproviderconfig.openshift.io":      configv1.KubevirtPlatformType,
+		"libvirtproviderconfig.openshift.io":       configv1.LibvirtPlatformType,
+		"openstackproviderconfig.openshift.io":     configv1.OpenStackPlatformType,
+		"ovirtproviderconfig.machine.openshift.io": configv1.OvirtPlatformType,
+	}
+)
+
+var (
+	// errNilProviderSpec is an error used when provider spec is nil.
+	errNilProviderSpec = machineproviders.NewConfigurationError(machineproviders.ReasonInvalidProviderSpec, "provider spec is nil")
+
+	// errUnexpectedProviderSpecKind is an error used when the kind of the provider spec
+	// does not match the kind expected for the platform.
+	errUnexpectedProviderSpecKind = machineproviders.NewConfigurationError(machineproviders.ReasonInvalidProviderSpec, "unexpected provider spec kind")
+
+	// errUnknownProviderSpecAPIVersion is an error used when the provider spec has an API version
+	// that is not known to carry the provider spec kind.
+	errUnknownProviderSpecAPIVersion = machineproviders.NewConfigurationError(machineproviders.ReasonInvalidProviderSpec, "unknown provider spec API version")
+)
+
+// knownAPIVersions returns the API versions that are known to serve the given provider spec kind.
+// The schema of the provider spec is identical across these API versions, so a provider spec in any
+// of these versions can be decoded into the same Go type.
+func knownAPIVersions(kind string) []string {
+	switch kind {
+	case awsProviderConfigKind:
+		return []string{machineAPIVersion, awsLegacyAPIVersion}
+	case vsphereProviderConfigKind:
+		return []string{machineAPIVersion, vsphereLegacyAPIVersion}
+	case gcpProviderConfigKind:
+		return []string{machineAPIVersion, gcpLegacyAPIVersion}
+	case azureProviderConfigKind:
+		return []string{machineAPIVersion, azureLegacyAPIVersion}
+	case ibmCloudProviderConfigKind:
+		return []string{ibmCloudAPIVersion}
+	case nutanixProviderConfigKind, powerVSProviderConfigKind, alibabaCloudProviderConfigKind:
+		return []string{machineV1APIVersion}
+	case bareMetalProviderConfigKind:
+		return []string{bareMetalAPIVersion}
+	case equinixMetalProviderConfigKind:
+		return []string{machineAPIVersion, equinixMetalLegacyAPIVersion}
+	case openStackProviderConfigKind:
+		return []string{openStackAPIVersion, openStackLegacyAPIVersion}
+	default:
+		return []string{machineAPIVersion}
+	}
+}
+
+// inferPlatformType infers the platform type from the type information of a provider spec.
+// The platform is inferred from the kind of the provider spec when the kind is known, and otherwise from the API
+// group of the provider spec, when it is a platform specific API group.
+// It returns false when the platform type cannot be inferred.
+func inferPlatformType(typeMeta metav1.TypeMeta) (configv1.PlatformType, bool) {
+	if platformType, ok := providerSpecKindPlatforms[typeMeta.Kind]; ok {
+		return platformType, true
+	}
+
+	group := strings.SplitN(typeMeta.APIVersion, "/", 2)[0]
+	if platformType, ok := legacyAPIGroupPlatforms[group]; ok {
+		return platformType, true
+	}
+
+	return "", false
+}
+
+// providerSpecTypeMeta extracts the type information from the raw provider spec.
+func providerSpecTypeMeta(raw *runtime.RawExtension) (metav1.TypeMeta, error) {
+	if raw == nil {
+		return metav1.TypeMeta{}, errNilProviderSpec
+	}
+
+	typeMeta := metav1.TypeMeta{}
+	if err := json.Unmarshal(raw.Raw, &typeMeta); err != nil {
+		return metav1.TypeMeta{}, fmt.Errorf("could not unmarshal provider spec type information: %w", err)
+	}
+
+	return typeMeta, nil
+}
+
+// decodeProviderSpec decodes the raw provider spec into the object provided after checking that the
+// type information within the provider spec is compatible with the expected kind.
+// Clusters part way through an upgrade may have Machines with provider specs written in an older or
+// newer API version than the template, as long as the API version is known to serve the kind, the
+// provider spec will be decoded.
+// An empty kind or API version is allowed as these are not required by the Machine API.
+func decodeProviderSpec(raw *runtime.RawExtension, kind string, into interface{}) error {
+	typeMeta, err := providerSpecTypeMeta(raw)
+	if err != nil {
+		return err
+	}
+
+	if typeMeta.Kind != "" && typeMeta.Kind != kind {
+		return fmt.Errorf("%w: expected %s, got %s", errUnexpectedProviderSpecKind, kind, typeMeta.Kind)
+	}
+
+	if typeMeta.APIVersion != "" && !isKnownAPIVersion(kind, typeMeta.APIVersion) {
+		return fmt.Errorf("%w: %s does not serve %s", errUnknownProviderSpecAPIVersion, typeMeta.APIVersion, kind)
+	}
+
+	if err := json.Unmarshal(raw.Raw, into); err != nil {
+		return fmt.Errorf("could not unmarshal provider spec: %w", err)
+	}
+
+	return nil
+}
+
+// isKnownAPIVersion determines whether the API version is one of the known API versions for the kind.
+func isKnownAPIVersion(kind, apiVersion string) bool {
+	for _, knownAPIVersion := range knownAPIVersions(kind) {
+		if apiVersion == knownAPIVersion {
+			return true
+		}
+	}
+
+	return false
+}
+
+// normalisedTypeMeta returns the type information that provider specs of the given kind should be
+// compared with. Provider specs that only differ by API version represent the same configuration
+// and should not be considered as needing an update.
+func normalisedTypeMeta(kind string) metav1.TypeMeta {
+	return metav1.TypeMeta{
+		APIVersion: machineAPIVersion,
+		Kind:       kind,
+	}
+}
+
+// changedTopLevelFields marshals both provider specs and returns the sorted names of the top level
+// JSON fields whose values differ between them.
+func changedTopLevelFields(base, compare interface{}) ([]string, error) {
+	baseFields, err := topLevelFields(base)
+	if err != nil {
+		return nil, err
+	}
+
+	compareFields, err := topLevelFields(compare)
+	if err != nil {
+		return nil, err
+	}
+
+	changed := []string{}
+
+	for name, value := range baseFields {
+		if compareValue, ok := compareFields[name]; !ok || !reflect.DeepEqual(value, compareValue) {
+			changed = append(changed, name)
+		}
+	}
+
+	for name := range compareFields {
+		if _, ok := baseFields[name]; !ok {
+			changed = append(changed, name)
+		}
+	}
+
+	sort.Strings(changed)
+
+	return changed, nil
+}
+
+// topLevelFields marshals the provider spec and splits it into its top level JSON fields.
+func topLevelFields(providerSpec interface{}) (map[string]json.RawMessage, error) {
+	raw, err := json.Marshal(providerSpec)
+	if err != nil {
+		return nil, fmt.Errorf("could not marshal provider spec: %w", err)
+	}
+
+	fields := map[string]json.RawMessage{}
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return nil, fmt.Errorf("could not unmarshal provider spec fields: %w", err)
+	}
+
+	return fields, nil
+}
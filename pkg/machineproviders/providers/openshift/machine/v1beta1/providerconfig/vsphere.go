@@ -0,0 +1,202 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package providerconfig
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	configv1 "github.com/openshift/api/config/v1"
+	machinev1beta1 "github.com/openshift/api/machine/v1beta1"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders/providers/openshift/machine/v1beta1/failuredomain"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// VSphereProviderConfig holds the provider spec of a vSphere Machine.
+// It allows external code to gather the stored config.
+// The failure domain of a vSphere Machine is its workspace and the networks of its network devices.
+type VSphereProviderConfig struct {
+	providerConfig machinev1beta1.VSphereMachineProviderSpec
+}
+
+// Config returns the stored VSphereMachineProviderSpec.
+func (v VSphereProviderConfig) Config() machinev1beta1.VSphereMachineProviderSpec {
+	return v.providerConfig
+}
+
+// vsphereResourcePoolSuffix is the suffix of the path of the root resource pool of a compute cluster.
+const vsphereResourcePoolSuffix = "/Resources"
+
+// InjectFailureDomain returns a new VSphereProviderConfig configured with the failure domain
+// information provided.
+// The workspace is moved to the server, datacenter, datastore, folder and resource pool of the
+// failure domain, and the network devices are attached to the networks of the failure domain, in order.
+// Fields the failure domain leaves empty are left as they are within the provider spec. When the
+// failure domain has no resource pool, the root resource pool of its compute cluster is used.
+func (v VSphereProviderConfig) InjectFailureDomain(fd failuredomain.VSphereFailureDomain) VSphereProviderConfig {
+	newVSphereProviderConfig := v
+	newVSphereProviderConfig.providerConfig = *v.providerConfig.DeepCopy()
+
+	workspace := &machinev1beta1.Workspace{}
+	if v.providerConfig.Workspace != nil {
+		workspace = v.providerConfig.Workspace.DeepCopy()
+	}
+
+	resourcePool := fd.ResourcePool
+	if resourcePool == "" && fd.ComputeCluster != "" {
+		resourcePool = fd.ComputeCluster + vsphereResourcePoolSuffix
+	}
+
+	for _, field := range []struct {
+		value  string
+		target *string
+	}{
+		{value: fd.Server, target: &workspace.Server},
+		{value: fd.Datacenter, target: &workspace.Datacenter},
+		{value: fd.Datastore, target: &workspace.Datastore},
+		{value: fd.Folder, target: &workspace.Folder},
+		{value: resourcePool, target: &workspace.ResourcePool},
+	} {
+		if field.value != "" {
+			*field.target = field.value
+		}
+	}
+
+	if !equality.Semantic.DeepEqual(workspace, &machinev1beta1.Workspace{}) {
+		newVSphereProviderConfig.providerConfig.Workspace = workspace
+	}
+
+	devices := newVSphereProviderConfig.providerConfig.Network.Devices
+	for i := range devices {
+		if i < len(fd.Networks) && fd.Networks[i] != "" {
+			devices[i].NetworkName = fd.Networks[i]
+		}
+	}
+
+	return newVSphereProviderConfig
+}
+
+// ExtractFailureDomain returns a VSphereFailureDomain based on the failure domain
+// information stored within the VSphereProviderConfig.
+// The name and compute cluster of the failure domain cannot be determined from the provider spec,
+// so are left empty.
+func (v VSphereProviderConfig) ExtractFailureDomain() failuredomain.VSphereFailureDomain {
+	fd := failuredomain.VSphereFailureDomain{}
+
+	if workspace := v.providerConfig.Workspace; workspace != nil {
+		fd.Server = workspace.Server
+		fd.Datacenter = workspace.Datacenter
+		fd.Datastore = workspace.Datastore
+		fd.Folder = workspace.Folder
+		fd.ResourcePool = workspace.ResourcePool
+	}
+
+	for _, device := range v.providerConfig.Network.Devices {
+		fd.Networks = append(fd.Networks, device.NetworkName)
+	}
+
+	return fd
+}
+
+// ExtractTemplate returns the name, inventory path or instance UUID of the template from which
+// the virtual machine is cloned.
+func (v VSphereProviderConfig) ExtractTemplate() string {
+	return v.providerConfig.Template
+}
+
+// Equal compares the VSphereProviderConfig with another VSphereProviderConfig.
+// The type information of the provider specs is normalised before the comparison so that
+// provider specs using different API versions of the same kind compare as equal.
+// Any change to the clone template is a difference, so that Machines are replaced when the
+// template is updated. The order of the network devices is not significant.
+func (v VSphereProviderConfig) Equal(other VSphereProviderConfig) bool {
+	return equality.Semantic.DeepEqual(normalisedVSphereProviderConfig(v.providerConfig), normalisedVSphereProviderConfig(other.providerConfig))
+}
+
+// UnmanagedFields returns the paths of the fields that differ between the VSphereProviderConfigs
+// but where the difference is deliberately tolerated by Equal.
+// These are the type information of the provider specs.
+func (v VSphereProviderConfig) UnmanagedFields(other VSphereProviderConfig) []string {
+	var fields []string
+
+	if v.providerConfig.APIVersion != other.providerConfig.APIVersion {
+		fields = append(fields, "apiVersion")
+	}
+
+	if v.providerConfig.Kind != other.providerConfig.Kind {
+		fields = append(fields, "kind")
+	}
+
+	return fields
+}
+
+// ChangedFields returns the names of the top level fields of the provider spec that differ
+// between the VSphereProviderConfigs.
+// The provider specs are normalised in the same way as within Equal, so differences that Equal
+// tolerates are not reported.
+func (v VSphereProviderConfig) ChangedFields(other VSphereProviderConfig) ([]string, error) {
+	return changedTopLevelFields(normalisedVSphereProviderConfig(v.providerConfig), normalisedVSphereProviderConfig(other.providerConfig))
+}
+
+// normalisedVSphereProviderConfig returns a copy of the provider spec that is suitable for comparison.
+// The type information is normalised and the secondary network devices are ordered by their content.
+// The first network device is the primary NIC of the Machine, so it is kept in place.
+func normalisedVSphereProviderConfig(cfg machinev1beta1.VSphereMachineProviderSpec) *machinev1beta1.VSphereMachineProviderSpec {
+	out := cfg.DeepCopy()
+	out.TypeMeta = normalisedTypeMeta(vsphereProviderConfigKind)
+
+	if len(out.Network.Devices) > 1 {
+		secondary := out.Network.Devices[1:]
+
+		sort.SliceStable(secondary, func(i, j int) bool {
+			return networkDeviceSortKey(secondary[i]) < networkDeviceSortKey(secondary[j])
+		})
+	}
+
+	return out
+}
+
+// networkDeviceSortKey returns a key for the network device covering every field of the device, so that devices
+// sharing a network name are still ordered deterministically.
+func networkDeviceSortKey(device machinev1beta1.NetworkDeviceSpec) string {
+	key, err := json.Marshal(device)
+	if err != nil {
+		return device.NetworkName
+	}
+
+	return string(key)
+}
+
+// newVSphereProviderConfig creates a VSphereProviderConfig from the raw extension.
+// It should return an error if the provided RawExtension does not represent
+// a VSphereMachineProviderSpec.
+func newVSphereProviderConfig(raw *runtime.RawExtension) (ProviderConfig, error) {
+	vsphereMachineProviderSpec := machinev1beta1.VSphereMachineProviderSpec{}
+	if err := decodeProviderSpec(raw, vsphereProviderConfigKind, &vsphereMachineProviderSpec); err != nil {
+		return nil, fmt.Errorf("could not decode vSphere provider spec: %w", err)
+	}
+
+	return providerConfig{
+		platformType: configv1.VSpherePlatformType,
+		raw:          raw.Raw,
+		vsphere: VSphereProviderConfig{
+			providerConfig: vsphereMachineProviderSpec,
+		},
+	}, nil
+}
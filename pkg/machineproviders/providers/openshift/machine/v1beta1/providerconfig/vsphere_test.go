@@ -0,0 +1,251 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package providerconfig
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	configv1 "github.com/openshift/api/config/v1"
+	machinev1beta1 "github.com/openshift/api/machine/v1beta1"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders/providers/openshift/machine/v1beta1/failuredomain"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/test/resourcebuilder"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+var _ = Describe("vSphere Provider Config", func() {
+	Context("ExtractTemplate", func() {
+		It("returns the configured template", func() {
+			vsphereConfig := VSphereProviderConfig{providerConfig: *resourcebuilder.VSphereProviderSpec().WithTemplate("rhcos-template-87654321").Build()}
+
+			Expect(vsphereConfig.ExtractTemplate()).To(Equal("rhcos-template-87654321"))
+		})
+	})
+
+	Context("InjectFailureDomain", func() {
+		baseWorkspace := machinev1beta1.Workspace{
+			Datacenter:   "dc1",
+			Datastore:    "/dc1/datastore/datastore-a",
+			Folder:       "/dc1/vm/cluster-id",
+			ResourcePool: "/dc1/host/cluster-a/Resources",
+			Server:       "vcenter.example.com",
+		}
+
+		It("moves the workspace and networks into the failure domain", func() {
+			vsphereConfig := VSphereProviderConfig{providerConfig: *resourcebuilder.VSphereProviderSpec().WithWorkspace(baseWorkspace).WithNetworkNames("network-a").Build()}
+
+			injected := vsphereConfig.InjectFailureDomain(failuredomain.VSphereFailureDomain{
+				Name:           "zone-b",
+				Server:         "vcenter-b.example.com",
+				Datacenter:     "dc2",
+				ComputeCluster: "/dc2/host/cluster-b",
+				Datastore:      "/dc2/datastore/datastore-b",
+				Networks:       []string{"network-b"},
+			})
+
+			Expect(injected.Config().Workspace).To(Equal(&machinev1beta1.Workspace{
+				Datacenter:   "dc2",
+				Datastore:    "/dc2/datastore/datastore-b",
+				Folder:       "/dc1/vm/cluster-id",
+				ResourcePool: "/dc2/host/cluster-b/Resources",
+				Server:       "vcenter-b.example.com",
+			}))
+			Expect(injected.Config().Network.Devices).To(ConsistOf(machinev1beta1.NetworkDeviceSpec{NetworkName: "network-b"}))
+
+			By("leaving the original provider config unchanged")
+			Expect(vsphereConfig.Config().Workspace).To(Equal(&baseWorkspace))
+			Expect(vsphereConfig.Config().Network.Devices).To(ConsistOf(machinev1beta1.NetworkDeviceSpec{NetworkName: "network-a"}))
+		})
+
+		It("prefers the resource pool and folder of the failure domain", func() {
+			vsphereConfig := VSphereProviderConfig{providerConfig: *resourcebuilder.VSphereProviderSpec().WithWorkspace(baseWorkspace).Build()}
+
+			injected := vsphereConfig.InjectFailureDomain(failuredomain.VSphereFailureDomain{
+				ComputeCluster: "/dc1/host/cluster-b",
+				Folder:         "/dc1/vm/control-plane",
+				ResourcePool:   "/dc1/host/cluster-b/Resources/control-plane",
+			})
+
+			Expect(injected.Config().Workspace.Folder).To(Equal("/dc1/vm/control-plane"))
+			Expect(injected.Config().Workspace.ResourcePool).To(Equal("/dc1/host/cluster-b/Resources/control-plane"))
+		})
+
+		It("only replaces the networks of the network devices the failure domain defines", func() {
+			vsphereConfig := VSphereProviderConfig{providerConfig: *resourcebuilder.VSphereProviderSpec().WithNetworkNames("network-a", "network-storage").Build()}
+
+			injected := vsphereConfig.InjectFailureDomain(failuredomain.VSphereFailureDomain{Networks: []string{"network-b"}})
+
+			Expect(injected.Config().Network.Devices).To(Equal([]machinev1beta1.NetworkDeviceSpec{
+				{NetworkName: "network-b"},
+				{NetworkName: "network-storage"},
+			}))
+		})
+
+		It("round trips the extracted failure domain", func() {
+			machineConfig := VSphereProviderConfig{providerConfig: *resourcebuilder.VSphereProviderSpec().WithWorkspace(baseWorkspace).WithNetworkNames("network-a").Build()}
+			templateConfig := VSphereProviderConfig{providerConfig: *resourcebuilder.VSphereProviderSpec().Build()}
+
+			Expect(templateConfig.InjectFailureDomain(machineConfig.ExtractFailureDomain()).Equal(machineConfig)).To(BeTrue())
+		})
+	})
+
+	Context("ExtractFailureDomain", func() {
+		It("returns the workspace and networks of the provider spec", func() {
+			vsphereConfig := VSphereProviderConfig{providerConfig: *resourcebuilder.VSphereProviderSpec().WithNetworkNames("network-a", "network-b").Build()}
+
+			Expect(vsphereConfig.ExtractFailureDomain()).To(Equal(failuredomain.VSphereFailureDomain{
+				Server:       "vcenter.example.com",
+				Datacenter:   "vsphere-datacenter",
+				Datastore:    "vsphere-datastore",
+				Folder:       "/vsphere-datacenter/vm/cluster-id",
+				ResourcePool: "/vsphere-datacenter/host/vsphere-cluster/Resources",
+				Networks:     []string{"network-a", "network-b"},
+			}))
+		})
+	})
+
+	Context("Equal", func() {
+		type vsphereEqualTableInput struct {
+			baseBuilder    resourcebuilder.VSphereProviderSpecBuilder
+			compareBuilder resourcebuilder.VSphereProviderSpecBuilder
+			expectedEqual  bool
+
+			expectedUnmanagedFields []string
+			expectedChangedFields   []string
+		}
+
+		DescribeTable("should compare the provider configs", func(in vsphereEqualTableInput) {
+			baseConfig := VSphereProviderConfig{providerConfig: *in.baseBuilder.Build()}
+			compareConfig := VSphereProviderConfig{providerConfig: *in.compareBuilder.Build()}
+
+			Expect(baseConfig.Equal(compareConfig)).To(Equal(in.expectedEqual))
+			Expect(compareConfig.Equal(baseConfig)).To(Equal(in.expectedEqual), "Equality should be symmetric")
+
+			Expect(baseConfig.UnmanagedFields(compareConfig)).To(ConsistOf(in.expectedUnmanagedFields))
+			Expect(compareConfig.UnmanagedFields(baseConfig)).To(ConsistOf(in.expectedUnmanagedFields), "Unmanaged fields should be symmetric")
+
+			Expect(baseConfig.ChangedFields(compareConfig)).To(ConsistOf(in.expectedChangedFields))
+		},
+			Entry("with matching configs", vsphereEqualTableInput{
+				baseBuilder:             resourcebuilder.VSphereProviderSpec(),
+				compareBuilder:          resourcebuilder.VSphereProviderSpec(),
+				expectedEqual:           true,
+				expectedUnmanagedFields: []string{},
+				expectedChangedFields:   []string{},
+			}),
+			Entry("with matching configs using different API versions", vsphereEqualTableInput{
+				baseBuilder:             resourcebuilder.VSphereProviderSpec().WithAPIVersion("vsphereprovider.openshift.io/v1beta1"),
+				compareBuilder:          resourcebuilder.VSphereProviderSpec(),
+				expectedEqual:           true,
+				expectedUnmanagedFields: []string{"apiVersion"},
+				expectedChangedFields:   []string{},
+			}),
+			Entry("with secondary network devices in a different order", vsphereEqualTableInput{
+				baseBuilder:             resourcebuilder.VSphereProviderSpec().WithNetworkNames("vsphere-network-a", "vsphere-network-b", "vsphere-network-c"),
+				compareBuilder:          resourcebuilder.VSphereProviderSpec().WithNetworkNames("vsphere-network-a", "vsphere-network-c", "vsphere-network-b"),
+				expectedEqual:           true,
+				expectedUnmanagedFields: []string{},
+				expectedChangedFields:   []string{},
+			}),
+			Entry("with duplicate secondary network names in a different order", vsphereEqualTableInput{
+				baseBuilder:             resourcebuilder.VSphereProviderSpec().WithNetworkNames("vsphere-network-a", "vsphere-network-b", "vsphere-network-c", "vsphere-network-b"),
+				compareBuilder:          resourcebuilder.VSphereProviderSpec().WithNetworkNames("vsphere-network-a", "vsphere-network-b", "vsphere-network-b", "vsphere-network-c"),
+				expectedEqual:           true,
+				expectedUnmanagedFields: []string{},
+				expectedChangedFields:   []string{},
+			}),
+			Entry("with a different primary network device", vsphereEqualTableInput{
+				baseBuilder:             resourcebuilder.VSphereProviderSpec().WithNetworkNames("vsphere-network-a", "vsphere-network-b"),
+				compareBuilder:          resourcebuilder.VSphereProviderSpec().WithNetworkNames("vsphere-network-b", "vsphere-network-a"),
+				expectedEqual:           false,
+				expectedUnmanagedFields: []string{},
+				expectedChangedFields:   []string{"network"},
+			}),
+			Entry("with an additional network device", vsphereEqualTableInput{
+				baseBuilder:             resourcebuilder.VSphereProviderSpec().WithNetworkNames("vsphere-network-a"),
+				compareBuilder:          resourcebuilder.VSphereProviderSpec().WithNetworkNames("vsphere-network-a", "vsphere-network-b"),
+				expectedEqual:           false,
+				expectedUnmanagedFields: []string{},
+				expectedChangedFields:   []string{"network"},
+			}),
+			Entry("with a different network", vsphereEqualTableInput{
+				baseBuilder:             resourcebuilder.VSphereProviderSpec().WithNetworkNames("vsphere-network-a"),
+				compareBuilder:          resourcebuilder.VSphereProviderSpec().WithNetworkNames("vsphere-network-b"),
+				expectedEqual:           false,
+				expectedUnmanagedFields: []string{},
+				expectedChangedFields:   []string{"network"},
+			}),
+			Entry("with a different template", vsphereEqualTableInput{
+				baseBuilder:             resourcebuilder.VSphereProviderSpec(),
+				compareBuilder:          resourcebuilder.VSphereProviderSpec().WithTemplate("rhcos-template-87654321"),
+				expectedEqual:           false,
+				expectedUnmanagedFields: []string{},
+				expectedChangedFields:   []string{"template"},
+			}),
+			Entry("with a different number of vCPUs", vsphereEqualTableInput{
+				baseBuilder:             resourcebuilder.VSphereProviderSpec(),
+				compareBuilder:          resourcebuilder.VSphereProviderSpec().WithNumCPUs(8),
+				expectedEqual:           false,
+				expectedUnmanagedFields: []string{},
+				expectedChangedFields:   []string{"numCPUs"},
+			}),
+		)
+	})
+
+	Context("newVSphereProviderConfig", func() {
+		var providerConfig ProviderConfig
+		var expectedVSphereConfig machinev1beta1.VSphereMachineProviderSpec
+
+		BeforeEach(func() {
+			configBuilder := resourcebuilder.VSphereProviderSpec().WithNetworkNames("vsphere-network-a", "vsphere-network-b")
+			expectedVSphereConfig = *configBuilder.Build()
+			rawConfig := configBuilder.BuildRawExtension()
+
+			var err error
+			providerConfig, err = newVSphereProviderConfig(rawConfig)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("sets the type to VSphere", func() {
+			Expect(providerConfig.Type()).To(Equal(configv1.VSpherePlatformType))
+		})
+
+		It("returns the correct vSphere config", func() {
+			Expect(providerConfig.VSphere().Config()).To(Equal(expectedVSphereConfig))
+		})
+
+		It("round trips through the raw config, keeping the order of the network devices", func() {
+			rawConfig, err := providerConfig.RawConfig()
+			Expect(err).ToNot(HaveOccurred())
+
+			roundTripped, err := newVSphereProviderConfig(&runtime.RawExtension{Raw: rawConfig})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(roundTripped.VSphere().Config().Network.Devices).To(Equal(expectedVSphereConfig.Network.Devices))
+		})
+
+		Context("with a different provider spec kind", func() {
+			It("returns an error", func() {
+				_, err := newVSphereProviderConfig(&runtime.RawExtension{
+					Raw: []byte(`{"apiVersion":"machine.openshift.io/v1beta1","kind":"GCPMachineProviderSpec"}`),
+				})
+
+				Expect(err).To(MatchError("could not decode vSphere provider spec: unexpected provider spec kind: expected VSphereMachineProviderSpec, got GCPMachineProviderSpec"))
+			})
+		})
+	})
+})
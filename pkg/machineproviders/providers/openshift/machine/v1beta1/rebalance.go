@@ -0,0 +1,137 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	"fmt"
+	"strconv"
+
+	machinev1beta1 "github.com/openshift/api/machine/v1beta1"
+
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders/providers/openshift/machine/v1beta1/failuredomain"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders/providers/openshift/machine/v1beta1/providerconfig"
+)
+
+const (
+	// rebalanceFailureDomainsAnnotation is the annotation on the ControlPlaneMachineSet used to opt in to the
+	// rebalancing of the Control Plane Machines across the failure domains. When set to `true`, Machines that
+	// unbalance the configured spread of the failure domains need an update, so that they are replaced into the
+	// failure domain of their index.
+	rebalanceFailureDomainsAnnotation = "controlplanemachineset.machine.openshift.io/rebalance-failure-domains"
+)
+
+// errInvalidRebalanceFailureDomains is used to denote that the rebalance failure domains annotation is not a boolean.
+var errInvalidRebalanceFailureDomains = machineproviders.NewConfigurationError(machineproviders.ReasonInvalidFailureDomains, fmt.Sprintf("invalid value for annotation %s: expected true or false", rebalanceFailureDomainsAnnotation))
+
+// parseRebalanceFailureDomains determines whether the rebalancing of the failure domains is enabled by the
+// annotations of the ControlPlaneMachineSet. Rebalancing is disabled when the annotation is not present.
+func parseRebalanceFailureDomains(annotations map[string]string) (bool, error) {
+	value, ok := annotations[rebalanceFailureDomainsAnnotation]
+	if !ok {
+		return false, nil
+	}
+
+	rebalance, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("%w, got %q", errInvalidRebalanceFailureDomains, value)
+	}
+
+	return rebalance, nil
+}
+
+// failureDomainSpread counts the indexes mapped to each failure domain, keyed by the name of the failure domain.
+// This is the number of Control Plane Machines that each failure domain is configured to hold.
+func (m *openshiftMachineProvider) failureDomainSpread() map[string]int {
+	spread := map[string]int{}
+
+	for _, fd := range m.indexToFailureDomain {
+		spread[fd.String()]++
+	}
+
+	return spread
+}
+
+// failureDomainOccupancy counts the Machines within each failure domain of the failure domain mapping, keyed by the
+// name of the failure domain. Machines pending deletion are not counted, as they are about to be removed, and
+// neither are Machines that are not within any of the failure domains.
+func (m *openshiftMachineProvider) failureDomainOccupancy(machines []machinev1beta1.Machine) (map[string]int, error) {
+	occupancy := map[string]int{}
+
+	for _, machine := range machines {
+		if machine.GetDeletionTimestamp() != nil {
+			continue
+		}
+
+		machineProviderConfig, err := providerconfig.NewProviderConfigFromMachineSpec(machine.Spec)
+		if err != nil {
+			return nil, fmt.Errorf("could not get provider config for machine %s: %w", machine.GetName(), err)
+		}
+
+		fd, err := m.machineFailureDomain(machineProviderConfig)
+		if err != nil {
+			return nil, err
+		}
+
+		if fd != nil {
+			occupancy[fd.String()]++
+		}
+	}
+
+	return occupancy, nil
+}
+
+// machineFailureDomain finds the failure domain, within the failure domain mapping, that the Machine with the given
+// provider config is within. It returns nil when the Machine is not within any of the failure domains.
+func (m *openshiftMachineProvider) machineFailureDomain(machineProviderConfig providerconfig.ProviderConfig) (failuredomain.FailureDomain, error) {
+	for _, index := range m.sortedIndexes() {
+		fd := m.indexToFailureDomain[index]
+
+		matches, err := failureDomainMatches(machineProviderConfig, fd)
+		if err != nil {
+			return nil, fmt.Errorf("could not compare failure domain: %w", err)
+		}
+
+		if matches {
+			return fd, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// misplacedFailureDomain determines whether the Machine in the given index is within the failure domain of another
+// index, and that failure domain holds more Machines than it is configured to hold.
+// A Machine that has swapped failure domains with another Machine does not unbalance the spread, so is not misplaced.
+func (m *openshiftMachineProvider) misplacedFailureDomain(index int32, machineProviderConfig providerconfig.ProviderConfig, occupancy map[string]int) (bool, error) {
+	if len(m.indexToFailureDomain) == 0 {
+		return false, nil
+	}
+
+	if matches, err := failureDomainMatches(machineProviderConfig, m.indexToFailureDomain[index]); err != nil {
+		return false, fmt.Errorf("could not compare failure domain: %w", err)
+	} else if matches {
+		return false, nil
+	}
+
+	fd, err := m.machineFailureDomain(machineProviderConfig)
+	if err != nil || fd == nil {
+		return false, err
+	}
+
+	return occupancy[fd.String()] > m.failureDomainSpread()[fd.String()], nil
+}
@@ -0,0 +1,165 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	machinev1beta1 "github.com/openshift/api/machine/v1beta1"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders/providers/openshift/machine/v1beta1/failuredomain"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders/providers/openshift/machine/v1beta1/providerconfig"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/test/resourcebuilder"
+)
+
+var _ = Describe("Failure domain rebalancing", func() {
+	DescribeTable("parseRebalanceFailureDomains", func(annotations map[string]string, expected bool, expectedErr string) {
+		rebalance, err := parseRebalanceFailureDomains(annotations)
+
+		if expectedErr != "" {
+			Expect(err).To(MatchError(errInvalidRebalanceFailureDomains))
+			Expect(err).To(MatchError(ContainSubstring(expectedErr)))
+
+			return
+		}
+
+		Expect(err).ToNot(HaveOccurred())
+		Expect(rebalance).To(Equal(expected))
+	},
+		Entry("without the annotation", nil, false, ""),
+		Entry("when enabled", map[string]string{rebalanceFailureDomainsAnnotation: "true"}, true, ""),
+		Entry("when disabled", map[string]string{rebalanceFailureDomainsAnnotation: "false"}, false, ""),
+		Entry("with an invalid value", map[string]string{rebalanceFailureDomainsAnnotation: "always"}, false, `got "always"`),
+	)
+
+	Context("generateMachineInfo", func() {
+		const clusterID = "cpms-rebalance-cluster-id"
+
+		var provider *openshiftMachineProvider
+
+		providerSpecBuilder := resourcebuilder.AWSProviderSpec()
+
+		// machineIn builds a Control Plane Machine, with the given name suffix, in the zone given.
+		machineIn := func(suffix, zone string) machinev1beta1.Machine {
+			return *resourcebuilder.Machine().AsMaster().
+				WithName(clusterID + "-master-" + suffix).
+				WithProviderSpecBuilder(providerSpecBuilder.WithAvailabilityZone(zone)).
+				Build()
+		}
+
+		// needsUpdate generates the MachineInfo of each of the Machines, and returns whether each needs an update, keyed
+		// by the name of the Machine.
+		needsUpdate := func(machines ...machinev1beta1.Machine) map[string]bool {
+			occupancy, err := provider.failureDomainOccupancy(machines)
+			Expect(err).ToNot(HaveOccurred())
+
+			out := map[string]bool{}
+
+			for _, machine := range machines {
+				machineInfo, err := provider.generateMachineInfo(machine, occupancy)
+				Expect(err).ToNot(HaveOccurred())
+
+				out[machine.GetName()] = machineInfo.NeedsUpdate
+			}
+
+			return out
+		}
+
+		BeforeEach(func() {
+			template := resourcebuilder.OpenShiftMachineV1Beta1Template().
+				WithProviderSpecBuilder(providerSpecBuilder).
+				WithLabel(machinev1beta1.MachineClusterIDLabel, clusterID).
+				BuildTemplate().OpenShiftMachineV1Beta1Machine
+			Expect(template).ToNot(BeNil())
+
+			providerConfig, err := providerconfig.NewProviderConfig(*template)
+			Expect(err).ToNot(HaveOccurred())
+
+			provider = &openshiftMachineProvider{
+				indexToFailureDomain: map[int32]failuredomain.FailureDomain{
+					0: failuredomain.NewAWSFailureDomain(resourcebuilder.AWSFailureDomain().WithAvailabilityZone("us-east-1a").Build()),
+					1: failuredomain.NewAWSFailureDomain(resourcebuilder.AWSFailureDomain().WithAvailabilityZone("us-east-1b").Build()),
+					2: failuredomain.NewAWSFailureDomain(resourcebuilder.AWSFailureDomain().WithAvailabilityZone("us-east-1c").Build()),
+				},
+				machineTemplate: *template,
+				providerConfig:  providerConfig,
+			}
+		})
+
+		Context("with rebalancing disabled", func() {
+			It("reports the machine that unbalances the failure domains without requiring an update", func() {
+				machines := []machinev1beta1.Machine{
+					machineIn("0", "us-east-1a"),
+					machineIn("1", "us-east-1b"),
+					machineIn("2", "us-east-1a"),
+				}
+
+				occupancy, err := provider.failureDomainOccupancy(machines)
+				Expect(err).ToNot(HaveOccurred())
+
+				machineInfo, err := provider.generateMachineInfo(machines[2], occupancy)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(machineInfo.MisplacedFailureDomain).To(BeTrue())
+				Expect(machineInfo.NeedsUpdate).To(BeFalse())
+			})
+		})
+
+		Context("with rebalancing enabled", func() {
+			BeforeEach(func() {
+				provider.rebalanceFailureDomains = true
+			})
+
+			It("requires an update for the machine that unbalances the failure domains", func() {
+				Expect(needsUpdate(
+					machineIn("0", "us-east-1a"),
+					machineIn("1", "us-east-1b"),
+					machineIn("2", "us-east-1a"),
+				)).To(Equal(map[string]bool{
+					clusterID + "-master-0": false,
+					clusterID + "-master-1": false,
+					clusterID + "-master-2": true,
+				}))
+			})
+
+			It("does not require an update for machines that have swapped failure domains", func() {
+				Expect(needsUpdate(
+					machineIn("0", "us-east-1b"),
+					machineIn("1", "us-east-1a"),
+					machineIn("2", "us-east-1c"),
+				)).To(Equal(map[string]bool{
+					clusterID + "-master-0": false,
+					clusterID + "-master-1": false,
+					clusterID + "-master-2": false,
+				}))
+			})
+
+			It("continues to require an update while the replacement is pending", func() {
+				Expect(needsUpdate(
+					machineIn("0", "us-east-1a"),
+					machineIn("1", "us-east-1b"),
+					machineIn("2", "us-east-1a"),
+					machineIn("abcde-2", "us-east-1c"),
+				)).To(Equal(map[string]bool{
+					clusterID + "-master-0":       false,
+					clusterID + "-master-1":       false,
+					clusterID + "-master-2":       true,
+					clusterID + "-master-abcde-2": false,
+				}))
+			})
+		})
+	})
+})
@@ -0,0 +1,76 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	machinev1beta1 "github.com/openshift/api/machine/v1beta1"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// recordedNodeIndexes reads the indexes recorded for the Control Plane Machines, keyed by the name of their Node.
+// When no indexes have been recorded, no indexes are returned. Recorded values that are not valid indexes are
+// ignored, so that a damaged record cannot prevent the Machines from being reconciled.
+func recordedNodeIndexes(ctx context.Context, cl client.Client, namespace string) (map[string]int32, error) {
+	configMap := &corev1.ConfigMap{}
+	configMapKey := client.ObjectKey{Namespace: namespace, Name: machineproviders.MachineIndexesConfigMapName}
+
+	if err := cl.Get(ctx, configMapKey, configMap); apierrors.IsNotFound(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("could not fetch recorded machine indexes: %w", err)
+	}
+
+	indexes := map[string]int32{}
+
+	for nodeName, value := range configMap.Data {
+		index, err := strconv.ParseInt(value, 10, 32)
+		if err != nil || index < 0 {
+			continue
+		}
+
+		indexes[nodeName] = int32(index)
+	}
+
+	return indexes, nil
+}
+
+// recordedNodeIndex returns the index recorded for the Node of the Machine.
+// It returns false when the Machine has no Node, there is no index recorded for the Node, or when the recorded index
+// is not an index within the failure domain mapping.
+func (m *openshiftMachineProvider) recordedNodeIndex(machine machinev1beta1.Machine) (int32, bool) {
+	if machine.Status.NodeRef == nil {
+		return 0, false
+	}
+
+	index, ok := m.recordedIndexes[machine.Status.NodeRef.Name]
+	if !ok {
+		return 0, false
+	}
+
+	if _, ok := m.indexToFailureDomain[index]; len(m.indexToFailureDomain) > 0 && !ok {
+		return 0, false
+	}
+
+	return index, true
+}
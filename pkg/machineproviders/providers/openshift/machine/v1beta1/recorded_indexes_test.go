@@ -0,0 +1,122 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	machinev1beta1 "github.com/openshift/api/machine/v1beta1"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders/providers/openshift/machine/v1beta1/failuredomain"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/test"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/test/resourcebuilder"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("Recorded Indexes", func() {
+	Context("recordedNodeIndexes", func() {
+		var namespaceName string
+
+		BeforeEach(func() {
+			By("Setting up a namespace for the test")
+			ns := resourcebuilder.Namespace().WithGenerateName("control-plane-machine-set-indexes-").Build()
+			Expect(k8sClient.Create(ctx, ns)).To(Succeed())
+			namespaceName = ns.GetName()
+		})
+
+		AfterEach(func() {
+			test.CleanupResources(Default, ctx, cfg, k8sClient, namespaceName,
+				&corev1.ConfigMap{},
+			)
+		})
+
+		It("returns no indexes when none have been recorded", func() {
+			Expect(recordedNodeIndexes(ctx, k8sClient, namespaceName)).To(BeNil())
+		})
+
+		It("returns the recorded indexes, ignoring invalid values", func() {
+			Expect(k8sClient.Create(ctx, &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Namespace: namespaceName, Name: machineproviders.MachineIndexesConfigMapName},
+				Data: map[string]string{
+					"node-0": "0",
+					"node-1": "2",
+					"node-2": "two",
+					"node-3": "-1",
+				},
+			})).To(Succeed())
+
+			Expect(recordedNodeIndexes(ctx, k8sClient, namespaceName)).To(Equal(map[string]int32{
+				"node-0": 0,
+				"node-1": 2,
+			}))
+		})
+	})
+
+	Context("recordedNodeIndex", func() {
+		var provider *openshiftMachineProvider
+
+		machineWithNode := func(nodeName string) machinev1beta1.Machine {
+			return *resourcebuilder.Machine().AsMaster().WithName("restored-machine").WithNodeRef(corev1.ObjectReference{Name: nodeName}).Build()
+		}
+
+		BeforeEach(func() {
+			provider = &openshiftMachineProvider{
+				indexToFailureDomain: map[int32]failuredomain.FailureDomain{
+					0: failuredomain.NewAWSFailureDomain(resourcebuilder.AWSFailureDomain().WithAvailabilityZone("us-east-1a").Build()),
+					1: failuredomain.NewAWSFailureDomain(resourcebuilder.AWSFailureDomain().WithAvailabilityZone("us-east-1b").Build()),
+					2: failuredomain.NewAWSFailureDomain(resourcebuilder.AWSFailureDomain().WithAvailabilityZone("us-east-1c").Build()),
+				},
+				recordedIndexes: map[string]int32{
+					"node-0": 1,
+					"node-1": 4,
+				},
+			}
+		})
+
+		It("returns the index recorded for the node of the machine", func() {
+			index, ok := provider.recordedNodeIndex(machineWithNode("node-0"))
+			Expect(ok).To(BeTrue())
+			Expect(index).To(Equal(int32(1)))
+		})
+
+		It("returns false when the machine has no node", func() {
+			_, ok := provider.recordedNodeIndex(*resourcebuilder.Machine().AsMaster().WithName("restored-machine").Build())
+			Expect(ok).To(BeFalse())
+		})
+
+		It("returns false when no index is recorded for the node", func() {
+			_, ok := provider.recordedNodeIndex(machineWithNode("node-2"))
+			Expect(ok).To(BeFalse())
+		})
+
+		It("returns false when the recorded index is not within the failure domain mapping", func() {
+			_, ok := provider.recordedNodeIndex(machineWithNode("node-1"))
+			Expect(ok).To(BeFalse())
+		})
+
+		It("returns any recorded index when there is no failure domain mapping", func() {
+			provider.indexToFailureDomain = nil
+
+			index, ok := provider.recordedNodeIndex(machineWithNode("node-1"))
+			Expect(ok).To(BeTrue())
+			Expect(index).To(Equal(int32(4)))
+		})
+	})
+})
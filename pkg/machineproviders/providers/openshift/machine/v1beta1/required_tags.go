@@ -0,0 +1,180 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/go-logr/logr"
+	configv1 "github.com/openshift/api/config/v1"
+	machinev1beta1 "github.com/openshift/api/machine/v1beta1"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/clusterconfig"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders/providers/openshift/machine/v1beta1/providerconfig"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// clusterOwnershipTagPrefix is the prefix of the tag, followed by the cluster ID, that identifies the cloud
+	// resources owned by the cluster.
+	clusterOwnershipTagPrefix = "kubernetes.io/cluster/"
+
+	// clusterOwnershipTagValue is the value of the cluster ownership tag for cloud resources owned by the cluster.
+	clusterOwnershipTagValue = "owned"
+
+	// repairedMachineTags is a log message used to inform the user that the tags missing from a Machine have been
+	// added to its provider spec.
+	repairedMachineTags = "Repaired missing tags on machine"
+)
+
+// requiredMachineTags returns the tags that every Control Plane Machine is required to carry, by name.
+// These are the cluster ownership tag and the user defined tags of the Infrastructure resource.
+// Tags are only required on AWS, where the Infrastructure resource describes the user defined tags, and when the
+// Infrastructure resource does not exist, no tags are required.
+func requiredMachineTags(ctx context.Context, cl client.Client, pc providerconfig.ProviderConfig) (map[string]string, error) {
+	if pc.Type() != configv1.AWSPlatformType {
+		return nil, nil
+	}
+
+	infrastructure := &configv1.Infrastructure{}
+	if err := cl.Get(ctx, client.ObjectKey{Name: clusterconfig.InfrastructureName}, infrastructure); apierrors.IsNotFound(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to get infrastructure: %w", err)
+	}
+
+	tags := map[string]string{}
+
+	if infrastructure.Status.InfrastructureName != "" {
+		tags[clusterOwnershipTagPrefix+infrastructure.Status.InfrastructureName] = clusterOwnershipTagValue
+	}
+
+	if platformStatus := infrastructure.Status.PlatformStatus; platformStatus != nil && platformStatus.AWS != nil {
+		for _, tag := range platformStatus.AWS.ResourceTags {
+			tags[tag.Key] = tag.Value
+		}
+	}
+
+	return tags, nil
+}
+
+// missingTags returns, in name order, the names of the required tags that are not present within the tags, or nil
+// when no required tags are missing.
+// Required tags that are present with a different value are not reported, as the value may have been
+// deliberately overridden.
+func missingTags(tags, required map[string]string) []string {
+	var missing []string
+
+	for name := range required {
+		if _, ok := tags[name]; !ok {
+			missing = append(missing, name)
+		}
+	}
+
+	sort.Strings(missing)
+
+	return missing
+}
+
+// carriedTags returns the required tags that are present, with the required value, within the tags.
+// Injecting these into the template provider config means that a Machine carrying required tags, that the template
+// does not list, does not need an update. This allows missing tags to be repaired without replacing the Machine.
+func carriedTags(tags, required map[string]string) map[string]string {
+	carried := map[string]string{}
+
+	for name, value := range required {
+		if current, ok := tags[name]; ok && current == value {
+			carried[name] = value
+		}
+	}
+
+	return carried
+}
+
+// TemplateTagDrift returns, in name order, the names of the required tags that the template either does not list,
+// or lists with a different value. Machines created from the template are given the required tags regardless, so
+// drift does not cause Machines to be created without them, but the template no longer reflects the cluster.
+func (m *openshiftMachineProvider) TemplateTagDrift() []string {
+	templateTags := m.providerConfig.ExtractTags()
+
+	var drifted []string
+
+	for name, value := range m.requiredTags {
+		if current, ok := templateTags[name]; !ok || current != value {
+			drifted = append(drifted, name)
+		}
+	}
+
+	sort.Strings(drifted)
+
+	return drifted
+}
+
+// RepairMachineTags adds the required tags that are missing from the provider spec of the Machine, in place.
+// The Machine is not replaced. Whether the tags are applied to the existing cloud resources of the Machine is
+// determined by the Machine API.
+func (m *openshiftMachineProvider) RepairMachineTags(ctx context.Context, logger logr.Logger, machineRef *machineproviders.ObjectRef) error {
+	if machineRef.GroupVersionResource != machinev1beta1.GroupVersion.WithResource("machines") {
+		return fmt.Errorf("%w: expected %s, got %s", errUnknownGroupVersionResource, machinev1beta1.GroupVersion.WithResource("machines").String(), machineRef.GroupVersionResource.String())
+	}
+
+	machine := &machinev1beta1.Machine{}
+	if err := m.client.Get(ctx, client.ObjectKey{Namespace: machineRef.ObjectMeta.GetNamespace(), Name: machineRef.ObjectMeta.GetName()}, machine); err != nil {
+		return fmt.Errorf("failed to get machine: %w", err)
+	}
+
+	machineProviderConfig, err := providerconfig.NewProviderConfigFromMachineSpec(machine.Spec)
+	if err != nil {
+		return fmt.Errorf("could not get provider config for machine: %w", err)
+	}
+
+	missing := missingTags(machineProviderConfig.ExtractTags(), m.requiredTags)
+	if len(missing) == 0 {
+		return nil
+	}
+
+	repairedProviderConfig, err := machineProviderConfig.InjectTags(m.requiredTags)
+	if err != nil {
+		return fmt.Errorf("could not inject tags into provider config: %w", err)
+	}
+
+	rawConfig, err := repairedProviderConfig.RawConfig()
+	if err != nil {
+		return fmt.Errorf("could not get raw provider config: %w", err)
+	}
+
+	patchBase := client.MergeFrom(machine.DeepCopy())
+
+	machine.Spec.ProviderSpec.Value = &runtime.RawExtension{Raw: rawConfig}
+
+	if err := m.client.Patch(ctx, machine, patchBase); err != nil {
+		return fmt.Errorf("failed to patch machine: %w", err)
+	}
+
+	logger.V(2).Info(repairedMachineTags,
+		"machineNamespace", machine.GetNamespace(),
+		"machineName", machine.GetName(),
+		"tags", strings.Join(missing, ","),
+	)
+
+	return nil
+}
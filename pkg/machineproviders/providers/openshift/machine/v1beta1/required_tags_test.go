@@ -0,0 +1,216 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	configv1 "github.com/openshift/api/config/v1"
+	machinev1beta1 "github.com/openshift/api/machine/v1beta1"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/clusterconfig"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders/providers/openshift/machine/v1beta1/providerconfig"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/test"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/test/resourcebuilder"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"sigs.k8s.io/controller-runtime/pkg/envtest/komega"
+)
+
+var _ = Describe("Required Tags", func() {
+	const ownershipTag = "kubernetes.io/cluster/cpms-tags-test-id"
+
+	required := map[string]string{
+		ownershipTag:        "owned",
+		"example.com/owner": "team",
+	}
+
+	DescribeTable("missingTags", func(tags map[string]string, expectedMissing []string) {
+		Expect(missingTags(tags, required)).To(Equal(expectedMissing))
+	},
+		Entry("with all required tags", map[string]string{ownershipTag: "owned", "example.com/owner": "team"}, nil),
+		Entry("with no tags", nil, []string{"example.com/owner", ownershipTag}),
+		Entry("with one required tag", map[string]string{ownershipTag: "owned"}, []string{"example.com/owner"}),
+		Entry("with a required tag that has a different value", map[string]string{ownershipTag: "owned", "example.com/owner": "other"}, nil),
+	)
+
+	DescribeTable("carriedTags", func(tags map[string]string, expectedCarried map[string]string) {
+		Expect(carriedTags(tags, required)).To(Equal(expectedCarried))
+	},
+		Entry("with all required tags", map[string]string{ownershipTag: "owned", "example.com/owner": "team", "other": "value"}, required),
+		Entry("with no tags", nil, map[string]string{}),
+		Entry("with a required tag that has a different value", map[string]string{ownershipTag: "owned", "example.com/owner": "other"}, map[string]string{ownershipTag: "owned"}),
+	)
+
+	DescribeTable("TemplateTagDrift", func(templateTags []machinev1beta1.TagSpecification, expectedDrift []string) {
+		providerConfig, err := providerconfig.NewProviderConfigFromMachineSpec(resourcebuilder.Machine().WithProviderSpecBuilder(resourcebuilder.AWSProviderSpec().WithTags(templateTags)).Build().Spec)
+		Expect(err).ToNot(HaveOccurred())
+
+		provider := &openshiftMachineProvider{
+			providerConfig: providerConfig,
+			requiredTags:   required,
+		}
+
+		Expect(provider.TemplateTagDrift()).To(Equal(expectedDrift))
+	},
+		Entry("with all required tags", []machinev1beta1.TagSpecification{{Name: ownershipTag, Value: "owned"}, {Name: "example.com/owner", Value: "team"}}, nil),
+		Entry("with no tags", nil, []string{"example.com/owner", ownershipTag}),
+		Entry("with a required tag that has a different value", []machinev1beta1.TagSpecification{{Name: ownershipTag, Value: "owned"}, {Name: "example.com/owner", Value: "other"}}, []string{"example.com/owner"}),
+	)
+
+	Context("requiredMachineTags", func() {
+		var awsProviderConfig providerconfig.ProviderConfig
+
+		BeforeEach(func() {
+			var err error
+			awsProviderConfig, err = providerconfig.NewProviderConfigFromMachineSpec(resourcebuilder.Machine().WithProviderSpecBuilder(resourcebuilder.AWSProviderSpec()).Build().Spec)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		Context("when the Infrastructure does not exist", func() {
+			It("requires no tags", func() {
+				Expect(requiredMachineTags(ctx, k8sClient, awsProviderConfig)).To(BeNil())
+			})
+		})
+
+		Context("when the Infrastructure exists", func() {
+			var infrastructure *configv1.Infrastructure
+
+			BeforeEach(func() {
+				infrastructure = &configv1.Infrastructure{ObjectMeta: metav1.ObjectMeta{Name: clusterconfig.InfrastructureName}}
+				Expect(k8sClient.Create(ctx, infrastructure)).To(Succeed())
+
+				Expect(komega.UpdateStatus(infrastructure, func() {
+					infrastructure.Status.InfrastructureName = "cpms-tags-test-id"
+					infrastructure.Status.ControlPlaneTopology = configv1.HighlyAvailableTopologyMode
+					infrastructure.Status.InfrastructureTopology = configv1.HighlyAvailableTopologyMode
+					infrastructure.Status.PlatformStatus = &configv1.PlatformStatus{
+						Type: configv1.AWSPlatformType,
+						AWS: &configv1.AWSPlatformStatus{
+							Region: "us-east-1",
+							ResourceTags: []configv1.AWSResourceTag{
+								{Key: "example.com/owner", Value: "team"},
+							},
+						},
+					}
+				})()).To(Succeed())
+			})
+
+			AfterEach(func() {
+				Expect(k8sClient.Delete(ctx, infrastructure)).To(Succeed())
+			})
+
+			It("requires the ownership tag and the user defined tags", func() {
+				Expect(requiredMachineTags(ctx, k8sClient, awsProviderConfig)).To(Equal(required))
+			})
+
+			It("requires no tags on a platform other than AWS", func() {
+				azureProviderConfig, err := providerconfig.NewProviderConfigFromMachineSpec(resourcebuilder.Machine().WithProviderSpecBuilder(resourcebuilder.AzureProviderSpec()).Build().Spec)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(requiredMachineTags(ctx, k8sClient, azureProviderConfig)).To(BeNil())
+			})
+		})
+	})
+
+	Context("RepairMachineTags", func() {
+		var namespaceName string
+		var logger test.TestLogger
+		var provider *openshiftMachineProvider
+		var machine *machinev1beta1.Machine
+
+		BeforeEach(func() {
+			By("Setting up a namespace for the test")
+			ns := resourcebuilder.Namespace().WithGenerateName("control-plane-machine-set-tags-").Build()
+			Expect(k8sClient.Create(ctx, ns)).To(Succeed())
+			namespaceName = ns.GetName()
+
+			logger = test.NewTestLogger()
+
+			provider = &openshiftMachineProvider{
+				client:       k8sClient,
+				requiredTags: required,
+			}
+
+			machine = resourcebuilder.Machine().AsMaster().WithNamespace(namespaceName).WithName("master-0").
+				WithProviderSpecBuilder(resourcebuilder.AWSProviderSpec().WithTags([]machinev1beta1.TagSpecification{
+					{Name: "example.com/owner", Value: "other"},
+				})).Build()
+			Expect(k8sClient.Create(ctx, machine)).To(Succeed())
+		})
+
+		AfterEach(func() {
+			test.CleanupResources(Default, ctx, cfg, k8sClient, namespaceName,
+				&machinev1beta1.Machine{},
+			)
+		})
+
+		machineRef := func() *machineproviders.ObjectRef {
+			return &machineproviders.ObjectRef{
+				GroupVersionResource: machinev1beta1.GroupVersion.WithResource("machines"),
+				ObjectMeta:           machine.ObjectMeta,
+			}
+		}
+
+		It("adds the missing tags, preserving overridden values", func() {
+			Expect(provider.RepairMachineTags(ctx, logger.Logger(), machineRef())).To(Succeed())
+
+			Expect(komega.Get(machine)()).To(Succeed())
+			pc, err := providerconfig.NewProviderConfigFromMachineSpec(machine.Spec)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(pc.ExtractTags()).To(Equal(map[string]string{
+				ownershipTag:        "owned",
+				"example.com/owner": "other",
+			}))
+		})
+
+		It("logs the repaired tags", func() {
+			Expect(provider.RepairMachineTags(ctx, logger.Logger(), machineRef())).To(Succeed())
+
+			Expect(logger.Entries()).To(ConsistOf(test.LogEntry{
+				Level:   2,
+				Message: repairedMachineTags,
+				KeysAndValues: []interface{}{
+					"machineNamespace", namespaceName,
+					"machineName", "master-0",
+					"tags", ownershipTag,
+				},
+			}))
+		})
+
+		It("does not update a machine with no missing tags", func() {
+			provider.requiredTags = map[string]string{"example.com/owner": "team"}
+			resourceVersion := machine.GetResourceVersion()
+
+			Expect(provider.RepairMachineTags(ctx, logger.Logger(), machineRef())).To(Succeed())
+
+			Expect(komega.Object(machine)()).To(HaveField("ObjectMeta.ResourceVersion", Equal(resourceVersion)))
+			Expect(logger.Entries()).To(BeEmpty())
+		})
+
+		It("returns an error with an incorrect GVR", func() {
+			ref := machineRef()
+			ref.GroupVersionResource = corev1.SchemeGroupVersion.WithResource("nodes")
+
+			Expect(provider.RepairMachineTags(ctx, logger.Logger(), ref)).To(MatchError(ContainSubstring(errUnknownGroupVersionResource.Error())))
+		})
+	})
+})
@@ -0,0 +1,61 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders/providers/openshift/machine/v1beta1/providerconfig"
+)
+
+const (
+	// templateHashAnnotation is the annotation used to record, on each Machine created by the ControlPlaneMachineSet,
+	// the hash of the template provider config, for the index of the Machine, from which the Machine was created.
+	// This allows a replacement attempt that never joined the cluster to be matched against the current template.
+	templateHashAnnotation = "controlplanemachineset.machine.openshift.io/template-hash"
+
+	// templateHashLength is the number of hexadecimal characters of the template hash that are recorded.
+	templateHashLength = 16
+)
+
+// templateHash computes the hash of the provider config, as recorded within the template hash annotation.
+// The hash is computed over the raw provider config so that equal configurations always produce the same hash.
+func templateHash(pc providerconfig.ProviderConfig) (string, error) {
+	rawConfig, err := pc.RawConfig()
+	if err != nil {
+		return "", fmt.Errorf("could not get raw provider config: %w", err)
+	}
+
+	sum := sha256.Sum256(rawConfig)
+
+	return hex.EncodeToString(sum[:])[:templateHashLength], nil
+}
+
+// desiredTemplateHash computes the template hash that a Machine created for the index, from the current template,
+// would be annotated with.
+// Where images are resolved from an image stream, the hash is computed before the image is resolved, so that
+// updates to the image stream do not change the hash.
+func (m *openshiftMachineProvider) desiredTemplateHash(index int32) (string, error) {
+	providerConfig, err := m.injectFailureDomain(m.providerConfig, m.indexToFailureDomain[index])
+	if err != nil {
+		return "", fmt.Errorf("could not inject failure domain for index %d: %w", index, err)
+	}
+
+	return templateHash(providerConfig)
+}
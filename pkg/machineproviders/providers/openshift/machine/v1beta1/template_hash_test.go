@@ -0,0 +1,83 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders/providers/openshift/machine/v1beta1/failuredomain"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders/providers/openshift/machine/v1beta1/providerconfig"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/test/resourcebuilder"
+)
+
+var _ = Describe("Template hashes", func() {
+	newProviderConfig := func(instanceType string) providerconfig.ProviderConfig {
+		template := resourcebuilder.OpenShiftMachineV1Beta1Template().
+			WithProviderSpecBuilder(resourcebuilder.AWSProviderSpec().WithInstanceType(instanceType)).
+			BuildTemplate().OpenShiftMachineV1Beta1Machine
+		Expect(template).ToNot(BeNil())
+
+		providerConfig, err := providerconfig.NewProviderConfig(*template)
+		Expect(err).ToNot(HaveOccurred())
+
+		return providerConfig
+	}
+
+	Context("templateHash", func() {
+		It("should be the same for equal provider configs", func() {
+			first, err := templateHash(newProviderConfig("m6i.xlarge"))
+			Expect(err).ToNot(HaveOccurred())
+
+			second, err := templateHash(newProviderConfig("m6i.xlarge"))
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(first).To(HaveLen(templateHashLength))
+			Expect(first).To(Equal(second))
+		})
+
+		It("should differ for different provider configs", func() {
+			first, err := templateHash(newProviderConfig("m6i.xlarge"))
+			Expect(err).ToNot(HaveOccurred())
+
+			second, err := templateHash(newProviderConfig("m6i.2xlarge"))
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(first).ToNot(Equal(second))
+		})
+	})
+
+	Context("desiredTemplateHash", func() {
+		It("should differ between indexes with different failure domains", func() {
+			provider := &openshiftMachineProvider{
+				indexToFailureDomain: map[int32]failuredomain.FailureDomain{
+					0: failuredomain.NewAWSFailureDomain(resourcebuilder.AWSFailureDomain().WithAvailabilityZone("us-east-1a").Build()),
+					1: failuredomain.NewAWSFailureDomain(resourcebuilder.AWSFailureDomain().WithAvailabilityZone("us-east-1b").Build()),
+				},
+				providerConfig: newProviderConfig("m6i.xlarge"),
+			}
+
+			first, err := provider.desiredTemplateHash(0)
+			Expect(err).ToNot(HaveOccurred())
+
+			second, err := provider.desiredTemplateHash(1)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(first).ToNot(Equal(second))
+		})
+	})
+})
@@ -0,0 +1,95 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/validation"
+
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders/providers/openshift/machine/v1beta1/failuredomain"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders/providers/openshift/machine/v1beta1/providerconfig"
+)
+
+const (
+	// failureDomainUserDataSecretsAnnotation is the annotation on the ControlPlaneMachineSet used to override the
+	// user data secret of the template within particular failure domains. This allows sites where each zone has its
+	// own ignition endpoint, for example air-gapped sites with a proxy in each zone, to serve the correct ignition
+	// stub to each Machine.
+	// The value is a comma separated list of failure domains and secret names, eg
+	// `us-east-1a=master-user-data-1a,us-east-1b=master-user-data-1b`, where each failure domain is named by its zone.
+	failureDomainUserDataSecretsAnnotation = "controlplanemachineset.machine.openshift.io/failure-domain-user-data-secrets"
+)
+
+// errInvalidFailureDomainUserDataSecrets is used to denote that the failure domain user data secrets annotation is
+// not in the expected format.
+var errInvalidFailureDomainUserDataSecrets = machineproviders.NewConfigurationError(machineproviders.ReasonInvalidFailureDomains, fmt.Sprintf("invalid value for annotation %s: expected <failure-domain>=<secret-name>[,<failure-domain>=<secret-name>...]", failureDomainUserDataSecretsAnnotation))
+
+// parseFailureDomainUserDataSecrets parses the value of the failure domain user data secrets annotation into the
+// names of the user data secrets, keyed by the name of their failure domain.
+// When the annotation is not present, no overrides are returned.
+func parseFailureDomainUserDataSecrets(annotations map[string]string) (map[string]string, error) {
+	value, ok := annotations[failureDomainUserDataSecretsAnnotation]
+	if !ok {
+		return nil, nil //nolint:nilnil
+	}
+
+	secrets := map[string]string{}
+
+	for _, entry := range strings.Split(value, ",") {
+		failureDomainName, secretName, ok := strings.Cut(strings.TrimSpace(entry), "=")
+		failureDomainName, secretName = strings.TrimSpace(failureDomainName), strings.TrimSpace(secretName)
+
+		if !ok || failureDomainName == "" || secretName == "" {
+			return nil, fmt.Errorf("%w, got %q", errInvalidFailureDomainUserDataSecrets, value)
+		}
+
+		if _, duplicate := secrets[failureDomainName]; duplicate {
+			return nil, fmt.Errorf("%w, failure domain %q is listed more than once", errInvalidFailureDomainUserDataSecrets, failureDomainName)
+		}
+
+		if errs := validation.IsDNS1123Subdomain(secretName); len(errs) > 0 {
+			return nil, fmt.Errorf("%w, secret name %q is invalid: %s", errInvalidFailureDomainUserDataSecrets, secretName, strings.Join(errs, ", "))
+		}
+
+		secrets[failureDomainName] = secretName
+	}
+
+	return secrets, nil
+}
+
+// injectFailureDomainUserDataSecret injects the user data secret of the failure domain into the provider config,
+// when the user data secret of the failure domain is overridden.
+func (m *openshiftMachineProvider) injectFailureDomainUserDataSecret(pc providerconfig.ProviderConfig, fd failuredomain.FailureDomain) (providerconfig.ProviderConfig, error) {
+	if fd == nil {
+		return pc, nil
+	}
+
+	secretName, ok := m.userDataSecrets[fd.String()]
+	if !ok {
+		return pc, nil
+	}
+
+	injected, err := pc.InjectUserDataSecret(secretName)
+	if err != nil {
+		return nil, fmt.Errorf("could not inject user data secret for failure domain %s: %w", fd.String(), err)
+	}
+
+	return injected, nil
+}
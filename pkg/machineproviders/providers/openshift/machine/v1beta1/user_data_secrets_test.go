@@ -0,0 +1,145 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders/providers/openshift/machine/v1beta1/failuredomain"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders/providers/openshift/machine/v1beta1/providerconfig"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/test/resourcebuilder"
+)
+
+var _ = Describe("Failure Domain User Data Secrets", func() {
+	type parseFailureDomainUserDataSecretsTableInput struct {
+		annotations     map[string]string
+		expectedSecrets map[string]string
+		expectedError   string
+	}
+
+	DescribeTable("parseFailureDomainUserDataSecrets", func(in parseFailureDomainUserDataSecretsTableInput) {
+		secrets, err := parseFailureDomainUserDataSecrets(in.annotations)
+
+		if in.expectedError != "" {
+			Expect(err).To(MatchError(errInvalidFailureDomainUserDataSecrets))
+			Expect(err).To(MatchError(ContainSubstring(in.expectedError)))
+		} else {
+			Expect(err).ToNot(HaveOccurred())
+		}
+
+		Expect(secrets).To(Equal(in.expectedSecrets))
+	},
+		Entry("with no annotations", parseFailureDomainUserDataSecretsTableInput{
+			annotations:     nil,
+			expectedSecrets: nil,
+		}),
+		Entry("with a single failure domain", parseFailureDomainUserDataSecretsTableInput{
+			annotations: map[string]string{
+				failureDomainUserDataSecretsAnnotation: "us-east-1a=master-user-data-1a",
+			},
+			expectedSecrets: map[string]string{"us-east-1a": "master-user-data-1a"},
+		}),
+		Entry("with several failure domains and surrounding whitespace", parseFailureDomainUserDataSecretsTableInput{
+			annotations: map[string]string{
+				failureDomainUserDataSecretsAnnotation: "us-east-1a = master-user-data-1a, us-east-1b=master-user-data-1b",
+			},
+			expectedSecrets: map[string]string{"us-east-1a": "master-user-data-1a", "us-east-1b": "master-user-data-1b"},
+		}),
+		Entry("with an empty value", parseFailureDomainUserDataSecretsTableInput{
+			annotations: map[string]string{
+				failureDomainUserDataSecretsAnnotation: "",
+			},
+			expectedError: `got ""`,
+		}),
+		Entry("with a missing secret name", parseFailureDomainUserDataSecretsTableInput{
+			annotations: map[string]string{
+				failureDomainUserDataSecretsAnnotation: "us-east-1a=",
+			},
+			expectedError: `got "us-east-1a="`,
+		}),
+		Entry("with a failure domain listed twice", parseFailureDomainUserDataSecretsTableInput{
+			annotations: map[string]string{
+				failureDomainUserDataSecretsAnnotation: "us-east-1a=master-user-data-1a,us-east-1a=master-user-data",
+			},
+			expectedError: `failure domain "us-east-1a" is listed more than once`,
+		}),
+		Entry("with an invalid secret name", parseFailureDomainUserDataSecretsTableInput{
+			annotations: map[string]string{
+				failureDomainUserDataSecretsAnnotation: "us-east-1a=Master_User_Data",
+			},
+			expectedError: `secret name "Master_User_Data" is invalid`,
+		}),
+	)
+
+	Context("injectFailureDomain", func() {
+		var provider *openshiftMachineProvider
+
+		BeforeEach(func() {
+			template := resourcebuilder.OpenShiftMachineV1Beta1Template().
+				WithProviderSpecBuilder(resourcebuilder.AWSProviderSpec()).
+				BuildTemplate().OpenShiftMachineV1Beta1Machine
+			Expect(template).ToNot(BeNil())
+
+			providerConfig, err := providerconfig.NewProviderConfig(*template)
+			Expect(err).ToNot(HaveOccurred())
+
+			provider = &openshiftMachineProvider{
+				providerConfig:  providerConfig,
+				userDataSecrets: map[string]string{"us-east-1a": "master-user-data-1a"},
+			}
+		})
+
+		It("injects the user data secret of the failure domain", func() {
+			fd := failuredomain.NewAWSFailureDomain(resourcebuilder.AWSFailureDomain().WithAvailabilityZone("us-east-1a").Build())
+
+			injected, err := provider.injectFailureDomain(provider.providerConfig, fd)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(injected.ExtractUserDataSecret()).To(Equal("master-user-data-1a"))
+			Expect(injected.AWS().Config().Placement.AvailabilityZone).To(Equal("us-east-1a"))
+		})
+
+		It("keeps the user data secret of the template in other failure domains", func() {
+			fd := failuredomain.NewAWSFailureDomain(resourcebuilder.AWSFailureDomain().WithAvailabilityZone("us-east-1b").Build())
+
+			injected, err := provider.injectFailureDomain(provider.providerConfig, fd)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(injected.ExtractUserDataSecret()).To(Equal("aws-user-data-12345678"))
+		})
+
+		It("keeps the user data secret of the template without a failure domain", func() {
+			injected, err := provider.injectFailureDomain(provider.providerConfig, nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(injected.ExtractUserDataSecret()).To(Equal("aws-user-data-12345678"))
+		})
+
+		It("requires an update for Machines in the failure domain with the user data secret of the template", func() {
+			fd := failuredomain.NewAWSFailureDomain(resourcebuilder.AWSFailureDomain().WithAvailabilityZone("us-east-1a").Build())
+			provider.indexToFailureDomain = map[int32]failuredomain.FailureDomain{0: fd}
+
+			machineProviderConfig, err := provider.providerConfig.InjectFailureDomain(fd)
+			Expect(err).ToNot(HaveOccurred())
+
+			_, needsUpdate, err := provider.desiredProviderConfig(provider.providerConfig, 0, machineProviderConfig)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(needsUpdate).To(BeTrue())
+		})
+	})
+})
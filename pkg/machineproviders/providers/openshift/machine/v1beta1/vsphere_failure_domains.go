@@ -0,0 +1,129 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	"context"
+	"fmt"
+
+	configv1 "github.com/openshift/api/config/v1"
+	machinev1 "github.com/openshift/api/machine/v1"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/clusterconfig"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders/providers/openshift/machine/v1beta1/failuredomain"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// infrastructureVSpherePlatformSpec is the vSphere platform spec of the Infrastructure resource.
+// The vendored Infrastructure API does not yet describe vSphere failure domains, so the platform spec
+// is decoded from the unstructured Infrastructure resource.
+type infrastructureVSpherePlatformSpec struct {
+	FailureDomains []infrastructureVSphereFailureDomain `json:"failureDomains,omitempty"`
+}
+
+// infrastructureVSphereFailureDomain is a vSphere failure domain within the Infrastructure resource.
+type infrastructureVSphereFailureDomain struct {
+	Name     string                                     `json:"name"`
+	Server   string                                     `json:"server"`
+	Topology infrastructureVSphereFailureDomainTopology `json:"topology"`
+}
+
+// infrastructureVSphereFailureDomainTopology is the topology of a vSphere failure domain within the
+// Infrastructure resource.
+type infrastructureVSphereFailureDomainTopology struct {
+	Datacenter     string   `json:"datacenter"`
+	ComputeCluster string   `json:"computeCluster"`
+	Networks       []string `json:"networks,omitempty"`
+	Datastore      string   `json:"datastore"`
+	ResourcePool   string   `json:"resourcePool,omitempty"`
+	Folder         string   `json:"folder,omitempty"`
+}
+
+// newFailureDomains creates the failure domains of the ControlPlaneMachineSet.
+// vSphere and Nutanix failure domains are not held within the ControlPlaneMachineSet, so when the failure
+// domains platform is VSphere or Nutanix, the failure domains are those defined within the Infrastructure resource.
+func newFailureDomains(ctx context.Context, cl client.Reader, failureDomains machinev1.FailureDomains) ([]failuredomain.FailureDomain, error) {
+	switch failureDomains.Platform {
+	case configv1.VSpherePlatformType:
+		vsphereFailureDomains, err := infrastructureVSphereFailureDomains(ctx, cl)
+		if err != nil {
+			return nil, err
+		}
+
+		return failuredomain.NewVSphereFailureDomains(vsphereFailureDomains)
+	case configv1.NutanixPlatformType:
+		nutanixFailureDomains, err := infrastructureNutanixFailureDomains(ctx, cl)
+		if err != nil {
+			return nil, err
+		}
+
+		return failuredomain.NewNutanixFailureDomains(nutanixFailureDomains)
+	default:
+		return failuredomain.NewFailureDomains(failureDomains)
+	}
+}
+
+// infrastructureVSphereFailureDomains returns the vSphere failure domains defined within the Infrastructure
+// resource, in the order in which they are defined.
+// When the Infrastructure resource does not exist, there are no failure domains.
+func infrastructureVSphereFailureDomains(ctx context.Context, cl client.Reader) ([]failuredomain.VSphereFailureDomain, error) {
+	infrastructure := &unstructured.Unstructured{}
+	infrastructure.SetGroupVersionKind(configv1.GroupVersion.WithKind("Infrastructure"))
+
+	if err := cl.Get(ctx, client.ObjectKey{Name: clusterconfig.InfrastructureName}, infrastructure); apierrors.IsNotFound(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to get infrastructure: %w", err)
+	}
+
+	return vsphereFailureDomainsFromInfrastructure(infrastructure)
+}
+
+// vsphereFailureDomainsFromInfrastructure decodes the vSphere failure domains from the platform spec of the
+// unstructured Infrastructure resource.
+func vsphereFailureDomainsFromInfrastructure(infrastructure *unstructured.Unstructured) ([]failuredomain.VSphereFailureDomain, error) {
+	platformSpec, ok, err := unstructured.NestedMap(infrastructure.Object, "spec", "platformSpec", "vsphere")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read vSphere platform spec: %w", err)
+	} else if !ok {
+		return nil, nil
+	}
+
+	spec := infrastructureVSpherePlatformSpec{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(platformSpec, &spec); err != nil {
+		return nil, fmt.Errorf("failed to decode vSphere platform spec: %w", err)
+	}
+
+	out := []failuredomain.VSphereFailureDomain{}
+
+	for _, fd := range spec.FailureDomains {
+		out = append(out, failuredomain.VSphereFailureDomain{
+			Name:           fd.Name,
+			Server:         fd.Server,
+			Datacenter:     fd.Topology.Datacenter,
+			ComputeCluster: fd.Topology.ComputeCluster,
+			Datastore:      fd.Topology.Datastore,
+			Folder:         fd.Topology.Folder,
+			ResourcePool:   fd.Topology.ResourcePool,
+			Networks:       fd.Topology.Networks,
+		})
+	}
+
+	return out, nil
+}
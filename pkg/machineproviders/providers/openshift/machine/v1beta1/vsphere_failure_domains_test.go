@@ -0,0 +1,155 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	configv1 "github.com/openshift/api/config/v1"
+	machinev1 "github.com/openshift/api/machine/v1"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/clusterconfig"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders/providers/openshift/machine/v1beta1/failuredomain"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders/providers/openshift/machine/v1beta1/providerconfig"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/test/resourcebuilder"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+var _ = Describe("vSphere failure domains", func() {
+	infrastructureWithPlatformSpec := func(platformSpec map[string]interface{}) *unstructured.Unstructured {
+		return &unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": "config.openshift.io/v1",
+			"kind":       "Infrastructure",
+			"metadata":   map[string]interface{}{"name": clusterconfig.InfrastructureName},
+			"spec":       map[string]interface{}{"platformSpec": platformSpec},
+		}}
+	}
+
+	Context("vsphereFailureDomainsFromInfrastructure", func() {
+		It("decodes the failure domains in the order they are defined", func() {
+			infrastructure := infrastructureWithPlatformSpec(map[string]interface{}{
+				"type": "VSphere",
+				"vsphere": map[string]interface{}{
+					"failureDomains": []interface{}{
+						map[string]interface{}{
+							"name":   "zone-b",
+							"region": "region-a",
+							"zone":   "zone-b",
+							"server": "vcenter.example.com",
+							"topology": map[string]interface{}{
+								"datacenter":     "dc1",
+								"computeCluster": "/dc1/host/cluster-b",
+								"networks":       []interface{}{"network-b"},
+								"datastore":      "/dc1/datastore/datastore-b",
+							},
+						},
+						map[string]interface{}{
+							"name":   "zone-a",
+							"server": "vcenter.example.com",
+							"topology": map[string]interface{}{
+								"datacenter":     "dc1",
+								"computeCluster": "/dc1/host/cluster-a",
+								"networks":       []interface{}{"network-a"},
+								"datastore":      "/dc1/datastore/datastore-a",
+								"folder":         "/dc1/vm/cluster-id",
+								"resourcePool":   "/dc1/host/cluster-a/Resources/control-plane",
+							},
+						},
+					},
+				},
+			})
+
+			Expect(vsphereFailureDomainsFromInfrastructure(infrastructure)).To(Equal([]failuredomain.VSphereFailureDomain{
+				{
+					Name:           "zone-b",
+					Server:         "vcenter.example.com",
+					Datacenter:     "dc1",
+					ComputeCluster: "/dc1/host/cluster-b",
+					Datastore:      "/dc1/datastore/datastore-b",
+					Networks:       []string{"network-b"},
+				},
+				{
+					Name:           "zone-a",
+					Server:         "vcenter.example.com",
+					Datacenter:     "dc1",
+					ComputeCluster: "/dc1/host/cluster-a",
+					Datastore:      "/dc1/datastore/datastore-a",
+					Folder:         "/dc1/vm/cluster-id",
+					ResourcePool:   "/dc1/host/cluster-a/Resources/control-plane",
+					Networks:       []string{"network-a"},
+				},
+			}))
+		})
+
+		It("returns no failure domains without a vSphere platform spec", func() {
+			Expect(vsphereFailureDomainsFromInfrastructure(infrastructureWithPlatformSpec(map[string]interface{}{"type": "VSphere"}))).To(BeEmpty())
+		})
+
+		It("returns an error when the failure domains cannot be decoded", func() {
+			infrastructure := infrastructureWithPlatformSpec(map[string]interface{}{
+				"vsphere": map[string]interface{}{"failureDomains": "zone-a"},
+			})
+
+			_, err := vsphereFailureDomainsFromInfrastructure(infrastructure)
+			Expect(err).To(MatchError(ContainSubstring("failed to decode vSphere platform spec")))
+		})
+	})
+
+	Context("newFailureDomains", func() {
+		It("returns a configuration error when the Infrastructure does not exist", func() {
+			_, err := newFailureDomains(ctx, k8sClient, machinev1.FailureDomains{Platform: configv1.VSpherePlatformType})
+			Expect(err).To(MatchError("missing vSphere failure domains within the infrastructure resource"))
+		})
+
+		It("constructs the failure domains of other platforms from the ControlPlaneMachineSet", func() {
+			failureDomains, err := newFailureDomains(ctx, k8sClient, resourcebuilder.AzureFailureDomains().BuildFailureDomains())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(failureDomains).To(HaveLen(3))
+		})
+	})
+
+	Context("failureDomainMatches", func() {
+		var templateProviderConfig providerconfig.ProviderConfig
+
+		zoneA := failuredomain.NewVSphereFailureDomain(failuredomain.VSphereFailureDomain{
+			Name:           "zone-a",
+			Server:         "vcenter.example.com",
+			Datacenter:     "dc1",
+			ComputeCluster: "/dc1/host/cluster-a",
+			Datastore:      "/dc1/datastore/datastore-a",
+			Networks:       []string{"network-a"},
+		})
+
+		BeforeEach(func() {
+			var err error
+			templateProviderConfig, err = providerconfig.NewProviderConfigFromMachineSpec(resourcebuilder.Machine().WithProviderSpecBuilder(resourcebuilder.VSphereProviderSpec()).Build().Spec)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("matches a Machine created within the failure domain", func() {
+			machineProviderConfig, err := templateProviderConfig.InjectFailureDomain(zoneA)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(failureDomainMatches(machineProviderConfig, zoneA)).To(BeTrue())
+		})
+
+		It("does not match a Machine within another compute cluster", func() {
+			Expect(failureDomainMatches(templateProviderConfig, zoneA)).To(BeFalse())
+		})
+	})
+})
@@ -24,6 +24,15 @@ import (
 	"k8s.io/apimachinery/pkg/runtime/schema"
 )
 
+const (
+	// MachineIndexesConfigMapName is the name of the ConfigMap, within the namespace of the ControlPlaneMachineSet,
+	// in which the index of each Control Plane Machine is recorded, keyed by the name of its Node. Within OpenShift,
+	// each etcd member is named after its Node, so the record also maps etcd members to indexes.
+	// The record allows Machines that have been renamed, for example after a cluster restore, to be mapped back to
+	// their index.
+	MachineIndexesConfigMapName = "control-plane-machine-set-machine-indexes"
+)
+
 // MachineInfo collates information about a Control Plane Machine and Node.
 // This is used by the core of the ControlPlaneMachineSet controller to determine
 // actions required to be taken on the Machines within its control.
@@ -38,10 +47,45 @@ type MachineInfo struct {
 	// Node has joined the cluster and is operating as expected.
 	Ready bool
 
+	// InstanceMissing is set true when the Machine still reports that it is running, but the cloud instance
+	// identified by its providerID no longer exists. This is used to detect Machines whose instance has been
+	// removed from the cloud behind the back of the Machine API.
+	InstanceMissing bool
+
+	// InstanceRunning is set true once the cloud instance backing the Machine has been created and, as last observed
+	// by the Machine API, is running. This is independent of whether a Node has joined the cluster, and allows the
+	// controller to distinguish replacements stalled on the cloud provider from those stalled while bootstrapping.
+	InstanceRunning bool
+
+	// MachineAPIPaused is set true when the Machine API has paused the reconciliation of the Machine, for example
+	// while the Machine is migrated to the Cluster API. Changes to a paused Machine are not actioned, so this is used
+	// to hold all actions on the Control Plane Machines until the Machine API resumes.
+	MachineAPIPaused bool
+
+	// MissingTags lists, in name order, the tags that the Machine is required to carry, but which are missing from its
+	// spec. For example, the cluster ownership tag or the user defined tags of the cluster. This allows the controller
+	// to report, and optionally repair, Machines whose cloud resources would not be tagged as required.
+	MissingTags []string
+
 	// NeedsUpdate is set true when the existing spec of the Machine does not match the desired spec of the Machine.
 	// This is used to inform the controller about decisions related to rolling out new machines.
 	NeedsUpdate bool
 
+	// MisplacedFailureDomain is set true when the Machine is within the failure domain of another index, and that
+	// failure domain holds more Control Plane Machines than it is configured to hold. Such a Machine only needs an
+	// update when failure domain rebalancing is enabled. This allows the controller to report Control Plane Machines
+	// that are unbalanced across the failure domains, for example after manual intervention.
+	MisplacedFailureDomain bool
+
+	// OutsideFailureDomains is set true when failure domains are configured, but the Machine is not within any of
+	// them, for example because its failure domain has been removed from the ControlPlaneMachineSet.
+	OutsideFailureDomains bool
+
+	// FailureDomain is the failure domain, of those mapped to an index, that the Machine is within. This is empty when
+	// the Machine is not within any of them, or no failure domains are configured. This allows the controller to
+	// report the Control Plane Machines that would move to another failure domain when they are replaced.
+	FailureDomain string
+
 	// Index denotes the Control Plane Machine index. Each Control Plane Machine replica is index (typically 0-2 in a
 	// three node cluster) and the Index will be needed to generate a replacement of this replica,  if a replacement is
 	// required.
@@ -50,6 +94,44 @@ type MachineInfo struct {
 	// ErrorMessage is used to provide information about any errors that have occurred with the Machine. For example, if
 	// the Machine has an error state within its status, it should be propagated up via this error message.
 	ErrorMessage string
+
+	// ErrorReason is the machine readable reason for the error within the status of the Machine, for example
+	// InsufficientResources when the cloud provider had no capacity for the instance. It is empty unless the Machine
+	// has failed.
+	ErrorReason string
+
+	// UnmanagedFields lists the paths of the fields within the Machine spec that differ from the desired spec of the
+	// Machine, but where the difference is deliberately tolerated and does not cause the Machine to need an update.
+	// For example, a field omitted in one spec and set to its default value in the other. This allows the controller to
+	// publish the drift that is being tolerated for each Machine.
+	UnmanagedFields []string
+
+	// NodeTopologyLabels are the topology labels, and their values, that the Node backing the Machine is expected to
+	// carry based on the failure domain of the Machine. For example, the zone and region labels. This allows the
+	// controller to detect Nodes that have been labelled incorrectly, which would break zone aware scheduling.
+	NodeTopologyLabels map[string]string
+
+	// InstanceType is the instance type of the Machine, when the platform has a concept of instance types.
+	InstanceType string
+
+	// DesiredInstanceType is the instance type that the Machine should have, based on the desired spec of the
+	// Machine. When this differs from the InstanceType, replacing the Machine changes its instance type. This allows
+	// the controller to estimate the cost of a pending rollout.
+	DesiredInstanceType string
+
+	// ProviderID is the provider ID of the Machine, which identifies the cloud instance backing the Machine. This is
+	// empty until the instance has been created. This allows the controller to map each index to its cloud instances.
+	ProviderID string
+
+	// TemplateHash is the hash of the template from which the Machine was created, as recorded on the Machine when it
+	// was created by the ControlPlaneMachineSet. This is empty for Machines that were not created by the
+	// ControlPlaneMachineSet, or that were created before the hash was recorded.
+	TemplateHash string
+
+	// DesiredTemplateHash is the hash of the current template for the index of the Machine. When this differs from a
+	// non-empty TemplateHash, the Machine was created from an earlier version of the template. This allows the
+	// controller to recognise stale replacement attempts that never joined the cluster.
+	DesiredTemplateHash string
 }
 
 // ObjectRef allows you to uniquely identify a resource within a cluster.
@@ -80,4 +162,17 @@ type MachineProvider interface {
 	// RollingUpdate strategy of the ControlPlaneMachineSet so that it can remove old Machines once they have been
 	// replaced.
 	DeleteMachine(context.Context, logr.Logger, *ObjectRef) error
+
+	// RepairMachineTags is used to instruct the Machine Provider to add the required tags that are missing from a
+	// particular Machine, in place, without replacing the Machine.
+	RepairMachineTags(context.Context, logr.Logger, *ObjectRef) error
+
+	// TemplateTagDrift is used to find the required tags, by name, that the Machine template either does not carry,
+	// or carries with a different value, so that drift between the cluster tag policy and the template is reported.
+	TemplateTagDrift() []string
+
+	// IndexFailureDomains is used to find the failure domain mapped to each index. Indexes that are not mapped to a
+	// failure domain are omitted. This allows the controller to report where new Machines would be created without
+	// creating them.
+	IndexFailureDomains() map[int32]string
 }
@@ -0,0 +1,270 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package permissions describes the permissions that each component of the operator requires, and verifies that
+// the service account that a component runs as has been granted them.
+package permissions
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Component is a component of the operator that can be run on its own, with its own service account.
+type Component string
+
+const (
+	// ComponentAll runs every component of the operator within a single process.
+	ComponentAll Component = "all"
+
+	// ComponentController runs the ControlPlaneMachineSet and operator configuration controllers.
+	ComponentController Component = "controller"
+
+	// ComponentWebhook runs the ControlPlaneMachineSet admission webhook, and the operator configuration controller
+	// that the webhook reads its runtime configuration from.
+	ComponentWebhook Component = "webhook"
+)
+
+const (
+	// etcdNamespace is the namespace of the etcd endpoints ConfigMap.
+	etcdNamespace = "openshift-etcd"
+
+	// etcdEndpointsConfigMapName is the name of the ConfigMap listing the etcd members.
+	etcdEndpointsConfigMapName = "etcd-endpoints"
+
+	// machineIndexesConfigMapName is the name of the ConfigMap recording the index of each Control Plane Machine.
+	machineIndexesConfigMapName = "control-plane-machine-set-machine-indexes"
+)
+
+// errUnknownComponent is used to denote that the component is not one of the known components.
+var errUnknownComponent = fmt.Errorf("unknown component, expected one of %s, %s or %s", ComponentAll, ComponentController, ComponentWebhook)
+
+// ParseComponent parses the name of a component of the operator.
+func ParseComponent(value string) (Component, error) {
+	switch component := Component(value); component {
+	case ComponentAll, ComponentController, ComponentWebhook:
+		return component, nil
+	default:
+		return "", fmt.Errorf("%w, got %q", errUnknownComponent, value)
+	}
+}
+
+// RunsController determines whether the component runs the ControlPlaneMachineSet controller.
+func (c Component) RunsController() bool {
+	return c == ComponentAll || c == ComponentController
+}
+
+// RunsWebhook determines whether the component runs the ControlPlaneMachineSet admission webhook.
+func (c Component) RunsWebhook() bool {
+	return c == ComponentAll || c == ComponentWebhook
+}
+
+// Permission is a single verb on a resource that a component of the operator requires.
+type Permission struct {
+	// Group is the API group of the resource, empty for the core API group.
+	Group string
+
+	// Resource is the plural name of the resource.
+	Resource string
+
+	// Subresource is the subresource, if any, eg `status`.
+	Subresource string
+
+	// Name restricts the permission to a single object with this name, when set.
+	Name string
+
+	// Namespace is the namespace that the permission is required within, empty for cluster scoped resources or
+	// for resources that are required across every namespace.
+	Namespace string
+
+	// Verb is the verb, eg `get` or `update`.
+	Verb string
+}
+
+// String describes the permission, eg `update configmaps control-plane-machine-set-machine-indexes in namespace
+// openshift-machine-api`.
+func (p Permission) String() string {
+	var b strings.Builder
+
+	b.WriteString(p.Verb)
+	b.WriteString(" ")
+	b.WriteString(p.Resource)
+
+	if p.Group != "" {
+		b.WriteString(".")
+		b.WriteString(p.Group)
+	}
+
+	if p.Subresource != "" {
+		b.WriteString("/")
+		b.WriteString(p.Subresource)
+	}
+
+	if p.Name != "" {
+		b.WriteString(" ")
+		b.WriteString(p.Name)
+	}
+
+	if p.Namespace != "" {
+		b.WriteString(" in namespace ")
+		b.WriteString(p.Namespace)
+	}
+
+	return b.String()
+}
+
+// rule expands the verbs into the permissions for a resource.
+func rule(namespace, group, resource string, verbs ...string) []Permission {
+	permissions := []Permission{}
+
+	for _, verb := range verbs {
+		permissions = append(permissions, Permission{Group: group, Resource: resource, Namespace: namespace, Verb: verb})
+	}
+
+	return permissions
+}
+
+// withName restricts the permissions to the object with the given name.
+func withName(name string, permissions []Permission) []Permission {
+	for i := range permissions {
+		permissions[i].Name = name
+	}
+
+	return permissions
+}
+
+// withSubresource restricts the permissions to the given subresource.
+func withSubresource(subresource string, permissions []Permission) []Permission {
+	for i := range permissions {
+		permissions[i].Subresource = subresource
+	}
+
+	return permissions
+}
+
+// Controller returns the permissions required by the ControlPlaneMachineSet and operator configuration controllers,
+// which manage the Control Plane Machines in the given namespace.
+func Controller(namespace string) []Permission {
+	return concat(
+		rule(namespace, "machine.openshift.io", "machines", "get", "list", "watch", "create", "update", "patch", "delete"),
+		rule(namespace, "machine.openshift.io", "controlplanemachinesets", "get", "list", "watch", "update", "patch"),
+		rule(namespace, "machine.openshift.io", "machinesets", "list", "watch"),
+		rule(namespace, "autoscaling.openshift.io", "machineautoscalers", "list"),
+		rule(namespace, "", "configmaps", "get", "list", "watch", "create"),
+		withName(machineIndexesConfigMapName, rule(namespace, "", "configmaps", "update", "patch")),
+		withName(etcdEndpointsConfigMapName, rule(etcdNamespace, "", "configmaps", "get")),
+		rule("", "config.openshift.io", "clusteroperators", "create", "get", "update", "list"),
+		withSubresource("status", rule("", "config.openshift.io", "clusteroperators", "create", "get", "update", "list")),
+		rule("", "config.openshift.io", "infrastructures", "get", "list", "watch"),
+		rule("", "", "events", "create", "watch", "list", "patch"),
+		rule("", "", "nodes", "get", "list", "watch", "patch", "delete"),
+		rule("", "", "pods", "list"),
+		rule("", "policy", "poddisruptionbudgets", "list"),
+		rule("", "certificates.k8s.io", "certificatesigningrequests", "list"),
+	)
+}
+
+// Webhook returns the permissions required by the ControlPlaneMachineSet admission webhook, validating
+// ControlPlaneMachineSets in the given namespace. The webhook only reads the cluster, so requires no write access.
+func Webhook(namespace string) []Permission {
+	return concat(
+		rule(namespace, "machine.openshift.io", "machines", "list"),
+		rule(namespace, "", "configmaps", "get", "list", "watch"),
+		rule("", "config.openshift.io", "infrastructures", "get"),
+	)
+}
+
+// For returns the permissions required by the component, managing the Control Plane Machines in the given
+// namespace.
+func For(component Component, namespace string) []Permission {
+	var permissions []Permission
+
+	if component.RunsController() {
+		permissions = append(permissions, Controller(namespace)...)
+	}
+
+	if component.RunsWebhook() {
+		permissions = append(permissions, Webhook(namespace)...)
+	}
+
+	return permissions
+}
+
+// concat joins the lists of permissions.
+func concat(lists ...[]Permission) []Permission {
+	permissions := []Permission{}
+
+	for _, list := range lists {
+		permissions = append(permissions, list...)
+	}
+
+	return permissions
+}
+
+// Missing determines which of the required permissions the client has not been granted, by reviewing each
+// permission with a SelfSubjectAccessReview. Every authenticated user may create a SelfSubjectAccessReview, so no
+// permission is needed to verify the permissions. Each permission is only reviewed once, and the missing
+// permissions are returned in the order in which they are required.
+func Missing(ctx context.Context, cl client.Client, required []Permission) ([]Permission, error) {
+	missing := []Permission{}
+	reviewed := map[Permission]bool{}
+
+	for _, permission := range required {
+		if reviewed[permission] {
+			continue
+		}
+
+		reviewed[permission] = true
+
+		review := &authorizationv1.SelfSubjectAccessReview{
+			Spec: authorizationv1.SelfSubjectAccessReviewSpec{
+				ResourceAttributes: &authorizationv1.ResourceAttributes{
+					Namespace:   permission.Namespace,
+					Verb:        permission.Verb,
+					Group:       permission.Group,
+					Resource:    permission.Resource,
+					Subresource: permission.Subresource,
+					Name:        permission.Name,
+				},
+			},
+		}
+
+		if err := cl.Create(ctx, review); err != nil {
+			return nil, fmt.Errorf("error reviewing permission to %s: %w", permission, err)
+		}
+
+		if !review.Status.Allowed {
+			missing = append(missing, permission)
+		}
+	}
+
+	return missing, nil
+}
+
+// Summarise joins the descriptions of the permissions into a single, comma separated, string.
+func Summarise(permissions []Permission) string {
+	descriptions := make([]string, 0, len(permissions))
+
+	for _, permission := range permissions {
+		descriptions = append(descriptions, permission.String())
+	}
+
+	return strings.Join(descriptions, ", ")
+}
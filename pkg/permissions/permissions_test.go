@@ -0,0 +1,263 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package permissions
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/yaml"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/envtest"
+)
+
+const (
+	// operatorNamespace is the namespace that the operator manages Control Plane Machines within.
+	operatorNamespace = "openshift-machine-api"
+
+	// rbacManifest is the manifest defining the service accounts of the operator and their permissions.
+	rbacManifest = "0000_31_control-plane-machine-set-operator_01_rbac.yaml"
+)
+
+var _ = Describe("Permissions", func() {
+	type parseComponentTableInput struct {
+		value             string
+		expectedComponent Component
+		expectedError     string
+	}
+
+	DescribeTable("ParseComponent", func(in parseComponentTableInput) {
+		component, err := ParseComponent(in.value)
+
+		if in.expectedError != "" {
+			Expect(err).To(MatchError(errUnknownComponent))
+			Expect(err).To(MatchError(ContainSubstring(in.expectedError)))
+		} else {
+			Expect(err).ToNot(HaveOccurred())
+		}
+
+		Expect(component).To(Equal(in.expectedComponent))
+	},
+		Entry("with all", parseComponentTableInput{
+			value:             "all",
+			expectedComponent: ComponentAll,
+		}),
+		Entry("with the controller", parseComponentTableInput{
+			value:             "controller",
+			expectedComponent: ComponentController,
+		}),
+		Entry("with the webhook", parseComponentTableInput{
+			value:             "webhook",
+			expectedComponent: ComponentWebhook,
+		}),
+		Entry("with an empty value", parseComponentTableInput{
+			value:         "",
+			expectedError: `got ""`,
+		}),
+		Entry("with an unknown component", parseComponentTableInput{
+			value:         "generator",
+			expectedError: `got "generator"`,
+		}),
+	)
+
+	DescribeTable("Permission String", func(permission Permission, expected string) {
+		Expect(permission.String()).To(Equal(expected))
+	},
+		Entry("with a core resource across every namespace", Permission{Resource: "nodes", Verb: "list"}, "list nodes"),
+		Entry("with a namespaced resource", Permission{Group: "machine.openshift.io", Resource: "machines", Namespace: operatorNamespace, Verb: "delete"},
+			"delete machines.machine.openshift.io in namespace openshift-machine-api"),
+		Entry("with a named resource", Permission{Resource: "configmaps", Name: "etcd-endpoints", Namespace: "openshift-etcd", Verb: "get"},
+			"get configmaps etcd-endpoints in namespace openshift-etcd"),
+		Entry("with a subresource", Permission{Group: "config.openshift.io", Resource: "clusteroperators", Subresource: "status", Verb: "update"},
+			"update clusteroperators.config.openshift.io/status"),
+	)
+
+	Context("For", func() {
+		It("requires the controller permissions for the controller", func() {
+			Expect(For(ComponentController, operatorNamespace)).To(Equal(Controller(operatorNamespace)))
+		})
+
+		It("requires the webhook permissions for the webhook", func() {
+			Expect(For(ComponentWebhook, operatorNamespace)).To(Equal(Webhook(operatorNamespace)))
+		})
+
+		It("requires both sets of permissions for all components", func() {
+			Expect(For(ComponentAll, operatorNamespace)).To(Equal(append(Controller(operatorNamespace), Webhook(operatorNamespace)...)))
+		})
+
+		It("does not require the webhook to write to the cluster", func() {
+			for _, permission := range Webhook(operatorNamespace) {
+				Expect(permission.Verb).To(BeElementOf("get", "list", "watch"), "webhook permission %s", permission)
+			}
+		})
+	})
+
+	Context("Missing", func() {
+		var user *envtest.AuthenticatedUser
+		var userClient client.Client
+
+		BeforeEach(func() {
+			var err error
+			user, err = testEnv.AddUser(envtest.User{Name: "permissions-test-user", Groups: []string{"system:authenticated"}}, nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			userClient, err = client.New(user.Config(), client.Options{Scheme: testScheme})
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("reports no permissions missing for a cluster administrator", func() {
+			Expect(Missing(ctx, k8sClient, For(ComponentAll, operatorNamespace))).To(BeEmpty())
+		})
+
+		It("reports every permission missing for a user without any roles, once each", func() {
+			required := []Permission{
+				{Group: "machine.openshift.io", Resource: "machines", Namespace: operatorNamespace, Verb: "delete"},
+				{Resource: "nodes", Verb: "list"},
+				{Group: "machine.openshift.io", Resource: "machines", Namespace: operatorNamespace, Verb: "delete"},
+			}
+
+			Expect(Missing(ctx, userClient, required)).To(Equal(required[:2]))
+		})
+
+		Context("with a role granting some of the permissions", func() {
+			BeforeEach(func() {
+				role := &rbacv1.ClusterRole{
+					ObjectMeta: metav1.ObjectMeta{Name: "permissions-test-role"},
+					Rules: []rbacv1.PolicyRule{
+						{APIGroups: []string{""}, Resources: []string{"nodes"}, Verbs: []string{"list"}},
+						{APIGroups: []string{""}, Resources: []string{"configmaps"}, ResourceNames: []string{"etcd-endpoints"}, Verbs: []string{"get"}},
+					},
+				}
+				Expect(k8sClient.Create(ctx, role)).To(Succeed())
+
+				binding := &rbacv1.ClusterRoleBinding{
+					ObjectMeta: metav1.ObjectMeta{Name: "permissions-test-role"},
+					RoleRef:    rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "ClusterRole", Name: role.Name},
+					Subjects:   []rbacv1.Subject{{APIGroup: rbacv1.GroupName, Kind: rbacv1.UserKind, Name: "permissions-test-user"}},
+				}
+				Expect(k8sClient.Create(ctx, binding)).To(Succeed())
+
+				DeferCleanup(func() {
+					Expect(k8sClient.Delete(ctx, binding)).To(Succeed())
+					Expect(k8sClient.Delete(ctx, role)).To(Succeed())
+				})
+			})
+
+			It("reports only the permissions that have not been granted", func() {
+				required := []Permission{
+					{Resource: "nodes", Verb: "list"},
+					{Resource: "nodes", Verb: "delete"},
+					{Resource: "configmaps", Name: "etcd-endpoints", Namespace: "openshift-etcd", Verb: "get"},
+					{Resource: "configmaps", Name: "other", Namespace: "openshift-etcd", Verb: "get"},
+				}
+
+				Eventually(func() ([]Permission, error) {
+					return Missing(ctx, userClient, required)
+				}).Should(Equal([]Permission{required[1], required[3]}))
+			})
+		})
+	})
+
+	Context("with the RBAC manifest", func() {
+		serviceAccountClient := func(name string) client.Client {
+			user, err := testEnv.AddUser(envtest.User{
+				Name:   "system:serviceaccount:" + operatorNamespace + ":" + name,
+				Groups: []string{"system:serviceaccounts", "system:serviceaccounts:" + operatorNamespace, "system:authenticated"},
+			}, nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			cl, err := client.New(user.Config(), client.Options{Scheme: testScheme})
+			Expect(err).ToNot(HaveOccurred())
+
+			return cl
+		}
+
+		BeforeEach(func() {
+			for _, namespace := range []string{operatorNamespace, etcdNamespace} {
+				ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: namespace}}
+				if err := k8sClient.Create(ctx, ns); err != nil && !apierrors.IsAlreadyExists(err) {
+					Expect(err).ToNot(HaveOccurred())
+				}
+			}
+
+			file, err := os.Open(filepath.Join("..", "..", "manifests", rbacManifest))
+			Expect(err).ToNot(HaveOccurred())
+			defer file.Close()
+
+			decoder := yaml.NewYAMLOrJSONDecoder(file, 4096)
+
+			for {
+				obj := &unstructured.Unstructured{}
+
+				err := decoder.Decode(&obj.Object)
+				if errors.Is(err, io.EOF) {
+					break
+				}
+
+				Expect(err).ToNot(HaveOccurred())
+
+				if len(obj.Object) == 0 {
+					continue
+				}
+
+				if err := k8sClient.Create(ctx, obj); err != nil && !apierrors.IsAlreadyExists(err) {
+					Expect(err).ToNot(HaveOccurred())
+				}
+			}
+		})
+
+		It("grants the operator service account every permission of the controller", func() {
+			cl := serviceAccountClient("control-plane-machine-set-operator")
+
+			Eventually(func() ([]Permission, error) {
+				return Missing(ctx, cl, Controller(operatorNamespace))
+			}).Should(BeEmpty())
+		})
+
+		It("grants the webhook service account every permission of the webhook", func() {
+			cl := serviceAccountClient("control-plane-machine-set-webhook")
+
+			Eventually(func() ([]Permission, error) {
+				return Missing(ctx, cl, Webhook(operatorNamespace))
+			}).Should(BeEmpty())
+		})
+
+		It("does not grant the webhook service account the write permissions of the controller", func() {
+			cl := serviceAccountClient("control-plane-machine-set-webhook")
+
+			writes := []Permission{}
+			for _, permission := range Controller(operatorNamespace) {
+				if permission.Verb != "get" && permission.Verb != "list" && permission.Verb != "watch" {
+					writes = append(writes, permission)
+				}
+			}
+
+			Eventually(func() ([]Permission, error) {
+				return Missing(ctx, cl, writes)
+			}).Should(Equal(writes))
+		})
+	})
+})
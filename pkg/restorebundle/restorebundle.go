@@ -0,0 +1,194 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package restorebundle exports the ControlPlaneMachineSet, its Control Plane Machines and their recorded indexes as
+// a restore bundle, which can be replayed onto a cluster once it has been restored from backup.
+package restorebundle
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	machinev1 "github.com/openshift/api/machine/v1"
+	machinev1beta1 "github.com/openshift/api/machine/v1beta1"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// lastAppliedConfigurationAnnotation is set by client side applies, and holds the previous state of the object.
+	// It is not carried into the bundle as it would be stale once the bundle is replayed.
+	lastAppliedConfigurationAnnotation = "kubectl.kubernetes.io/last-applied-configuration"
+)
+
+// Export builds a restore bundle from the ControlPlaneMachineSet with the given namespace and name.
+// The bundle is a v1 List containing, in the order in which they should be replayed:
+// - the ConfigMap recording the index of each Control Plane Machine by Node, when the operator has recorded one,
+// - each Control Plane Machine selected by the ControlPlaneMachineSet that is not pending deletion, and
+// - the ControlPlaneMachineSet.
+// Each object is sanitized so that it can be created afresh: its status, and any metadata set by the API server or
+// referring to the UIDs of other objects, are removed. The scheme of the client must include the
+// machine.openshift.io/v1 and machine.openshift.io/v1beta1 types.
+func Export(ctx context.Context, cl client.Client, namespace, name string) (*corev1.List, error) {
+	cpms := &machinev1.ControlPlaneMachineSet{}
+	if err := cl.Get(ctx, client.ObjectKey{Namespace: namespace, Name: name}, cpms); err != nil {
+		return nil, fmt.Errorf("error fetching control plane machine set %s/%s: %w", namespace, name, err)
+	}
+
+	bundle := &corev1.List{
+		TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "List"},
+	}
+
+	machineIndexes, err := exportMachineIndexes(ctx, cl, namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	if machineIndexes != nil {
+		if err := appendObject(bundle, machineIndexes); err != nil {
+			return nil, err
+		}
+	}
+
+	machines, err := exportMachines(ctx, cl, cpms)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range machines {
+		if err := appendObject(bundle, &machines[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := appendObject(bundle, sanitizeControlPlaneMachineSet(cpms)); err != nil {
+		return nil, err
+	}
+
+	return bundle, nil
+}
+
+// exportMachineIndexes fetches the ConfigMap recording the index of each Control Plane Machine by Node.
+// It returns nil when the operator has not recorded any indexes.
+func exportMachineIndexes(ctx context.Context, cl client.Client, namespace string) (*corev1.ConfigMap, error) {
+	configMap := &corev1.ConfigMap{}
+	configMapKey := client.ObjectKey{Namespace: namespace, Name: machineproviders.MachineIndexesConfigMapName}
+
+	if err := cl.Get(ctx, configMapKey, configMap); apierrors.IsNotFound(err) {
+		return nil, nil //nolint:nilnil
+	} else if err != nil {
+		return nil, fmt.Errorf("error fetching recorded machine indexes: %w", err)
+	}
+
+	return &corev1.ConfigMap{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "ConfigMap"},
+		ObjectMeta: sanitizeObjectMeta(configMap.ObjectMeta),
+		Data:       configMap.Data,
+	}, nil
+}
+
+// exportMachines lists the Control Plane Machines selected by the ControlPlaneMachineSet, sorted by name.
+// Machines pending deletion are not exported, as they are being removed from the cluster.
+func exportMachines(ctx context.Context, cl client.Client, cpms *machinev1.ControlPlaneMachineSet) ([]machinev1beta1.Machine, error) {
+	selector, err := metav1.LabelSelectorAsSelector(&cpms.Spec.Selector)
+	if err != nil {
+		return nil, fmt.Errorf("could not convert label selector to selector: %w", err)
+	}
+
+	machineList := &machinev1beta1.MachineList{}
+	if err := cl.List(ctx, machineList, client.InNamespace(cpms.Namespace), client.MatchingLabelsSelector{Selector: selector}); err != nil {
+		return nil, fmt.Errorf("failed to list machines: %w", err)
+	}
+
+	machines := []machinev1beta1.Machine{}
+
+	for _, machine := range machineList.Items {
+		if machine.DeletionTimestamp != nil {
+			continue
+		}
+
+		machines = append(machines, sanitizeMachine(machine))
+	}
+
+	sort.Slice(machines, func(i, j int) bool {
+		return machines[i].Name < machines[j].Name
+	})
+
+	return machines, nil
+}
+
+// sanitizeMachine removes the status and server set metadata from the Machine.
+// The provider ID is kept, so that the restored Machine continues to refer to its existing instance.
+func sanitizeMachine(machine machinev1beta1.Machine) machinev1beta1.Machine {
+	return machinev1beta1.Machine{
+		TypeMeta:   metav1.TypeMeta{APIVersion: machinev1beta1.GroupVersion.String(), Kind: "Machine"},
+		ObjectMeta: sanitizeObjectMeta(machine.ObjectMeta),
+		Spec:       *machine.Spec.DeepCopy(),
+	}
+}
+
+// sanitizeControlPlaneMachineSet removes the status and server set metadata from the ControlPlaneMachineSet.
+func sanitizeControlPlaneMachineSet(cpms *machinev1.ControlPlaneMachineSet) *machinev1.ControlPlaneMachineSet {
+	return &machinev1.ControlPlaneMachineSet{
+		TypeMeta:   metav1.TypeMeta{APIVersion: machinev1.GroupVersion.String(), Kind: "ControlPlaneMachineSet"},
+		ObjectMeta: sanitizeObjectMeta(cpms.ObjectMeta),
+		Spec:       *cpms.Spec.DeepCopy(),
+	}
+}
+
+// sanitizeObjectMeta keeps only the name, namespace, labels and annotations of the object.
+// Owner references and finalizers are dropped, as the UIDs of the owners change when the objects are restored, and
+// the controllers that own the finalizers add them again.
+func sanitizeObjectMeta(meta metav1.ObjectMeta) metav1.ObjectMeta {
+	var annotations map[string]string
+
+	for key, value := range meta.Annotations {
+		if key == lastAppliedConfigurationAnnotation {
+			continue
+		}
+
+		if annotations == nil {
+			annotations = map[string]string{}
+		}
+
+		annotations[key] = value
+	}
+
+	return metav1.ObjectMeta{
+		Name:        meta.Name,
+		Namespace:   meta.Namespace,
+		Labels:      meta.Labels,
+		Annotations: annotations,
+	}
+}
+
+// appendObject serializes the object and appends it to the items of the bundle.
+func appendObject(bundle *corev1.List, obj runtime.Object) error {
+	raw, err := json.Marshal(obj)
+	if err != nil {
+		return fmt.Errorf("error serializing %s: %w", obj.GetObjectKind().GroupVersionKind().Kind, err)
+	}
+
+	bundle.Items = append(bundle.Items, runtime.RawExtension{Raw: raw})
+
+	return nil
+}
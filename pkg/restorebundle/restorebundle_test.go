@@ -0,0 +1,153 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package restorebundle
+
+import (
+	"encoding/json"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	machinev1 "github.com/openshift/api/machine/v1"
+	machinev1beta1 "github.com/openshift/api/machine/v1beta1"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/test"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/test/resourcebuilder"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var _ = Describe("Export", func() {
+	var namespaceName string
+	var cpms *machinev1.ControlPlaneMachineSet
+
+	// decodeItem decodes the raw item of the bundle into the object given.
+	decodeItem := func(item runtime.RawExtension, obj client.Object) {
+		Expect(json.Unmarshal(item.Raw, obj)).To(Succeed())
+	}
+
+	BeforeEach(func() {
+		By("Setting up a namespace for the test")
+		ns := resourcebuilder.Namespace().WithGenerateName("control-plane-machine-set-restore-bundle-").Build()
+		Expect(k8sClient.Create(ctx, ns)).To(Succeed())
+		namespaceName = ns.GetName()
+
+		cpms = resourcebuilder.ControlPlaneMachineSet().WithNamespace(namespaceName).Build()
+		Expect(k8sClient.Create(ctx, cpms)).To(Succeed())
+
+		machineBuilder := resourcebuilder.Machine().WithNamespace(namespaceName).WithProviderSpecBuilder(resourcebuilder.AWSProviderSpec())
+
+		for _, name := range []string{"master-2", "master-0", "master-1"} {
+			machine := machineBuilder.AsMaster().WithName(name).WithProviderID("aws:///us-east-1a/i-" + name).Build()
+			machine.SetAnnotations(map[string]string{
+				"example.com/annotation":           "value",
+				lastAppliedConfigurationAnnotation: "{}",
+			})
+			machine.SetOwnerReferences([]metav1.OwnerReference{{
+				APIVersion: machinev1.GroupVersion.String(),
+				Kind:       "ControlPlaneMachineSet",
+				Name:       cpms.Name,
+				UID:        cpms.UID,
+			}})
+
+			Expect(k8sClient.Create(ctx, machine)).To(Succeed())
+		}
+
+		worker := machineBuilder.AsWorker().WithName("worker-0").Build()
+		Expect(k8sClient.Create(ctx, worker)).To(Succeed())
+	})
+
+	AfterEach(func() {
+		test.CleanupResources(Default, ctx, cfg, k8sClient, namespaceName,
+			&machinev1beta1.Machine{},
+			&machinev1.ControlPlaneMachineSet{},
+			&corev1.ConfigMap{},
+		)
+	})
+
+	Context("when no machine indexes have been recorded", func() {
+		var bundle *corev1.List
+
+		BeforeEach(func() {
+			var err error
+			bundle, err = Export(ctx, k8sClient, namespaceName, cpms.Name)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("exports the Control Plane Machines, sorted by name, followed by the ControlPlaneMachineSet", func() {
+			Expect(bundle.Items).To(HaveLen(4))
+
+			for i, name := range []string{"master-0", "master-1", "master-2"} {
+				machine := &machinev1beta1.Machine{}
+				decodeItem(bundle.Items[i], machine)
+
+				Expect(machine.Kind).To(Equal("Machine"))
+				Expect(machine.Name).To(Equal(name))
+			}
+
+			exported := &machinev1.ControlPlaneMachineSet{}
+			decodeItem(bundle.Items[3], exported)
+
+			Expect(exported.Kind).To(Equal("ControlPlaneMachineSet"))
+			Expect(exported.Name).To(Equal(cpms.Name))
+			Expect(exported.Spec).To(Equal(cpms.Spec))
+		})
+
+		It("sanitizes the Control Plane Machines", func() {
+			machine := &machinev1beta1.Machine{}
+			decodeItem(bundle.Items[0], machine)
+
+			Expect(machine.UID).To(BeEmpty())
+			Expect(machine.ResourceVersion).To(BeEmpty())
+			Expect(machine.CreationTimestamp.IsZero()).To(BeTrue())
+			Expect(machine.OwnerReferences).To(BeEmpty())
+			Expect(machine.ManagedFields).To(BeEmpty())
+			Expect(machine.Annotations).To(Equal(map[string]string{"example.com/annotation": "value"}))
+			Expect(machine.Spec.ProviderID).To(HaveValue(Equal("aws:///us-east-1a/i-master-0")))
+		})
+	})
+
+	Context("when machine indexes have been recorded", func() {
+		BeforeEach(func() {
+			configMap := &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Namespace: namespaceName, Name: machineproviders.MachineIndexesConfigMapName},
+				Data:       map[string]string{"node-0": "0", "node-1": "1", "node-2": "2"},
+			}
+			Expect(k8sClient.Create(ctx, configMap)).To(Succeed())
+		})
+
+		It("exports the recorded machine indexes first", func() {
+			bundle, err := Export(ctx, k8sClient, namespaceName, cpms.Name)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(bundle.Items).To(HaveLen(5))
+
+			configMap := &corev1.ConfigMap{}
+			decodeItem(bundle.Items[0], configMap)
+
+			Expect(configMap.Kind).To(Equal("ConfigMap"))
+			Expect(configMap.Name).To(Equal(machineproviders.MachineIndexesConfigMapName))
+			Expect(configMap.UID).To(BeEmpty())
+			Expect(configMap.Data).To(Equal(map[string]string{"node-0": "0", "node-1": "1", "node-2": "2"}))
+		})
+	})
+
+	It("returns an error when the ControlPlaneMachineSet does not exist", func() {
+		_, err := Export(ctx, k8sClient, namespaceName, "does-not-exist")
+		Expect(err).To(MatchError(ContainSubstring("error fetching control plane machine set " + namespaceName + "/does-not-exist")))
+	})
+})
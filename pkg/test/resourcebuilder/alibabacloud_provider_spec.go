@@ -0,0 +1,111 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resourcebuilder
+
+import (
+	"encoding/json"
+
+	machinev1 "github.com/openshift/api/machine/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/utils/pointer"
+)
+
+// AlibabaCloudProviderSpec creates a new Alibaba Cloud machine config builder.
+func AlibabaCloudProviderSpec() AlibabaCloudProviderSpecBuilder {
+	return AlibabaCloudProviderSpecBuilder{
+		instanceType: "ecs.g6.xlarge",
+		vSwitchID:    "vsw-cn-hangzhou-h",
+		zone:         "cn-hangzhou-h",
+	}
+}
+
+// AlibabaCloudProviderSpecBuilder is used to build out an Alibaba Cloud machine config object.
+type AlibabaCloudProviderSpecBuilder struct {
+	instanceType string
+	vSwitchID    string
+	zone         string
+}
+
+// Build builds a new Alibaba Cloud machine config based on the configuration provided.
+func (m AlibabaCloudProviderSpecBuilder) Build() *machinev1.AlibabaCloudMachineProviderConfig {
+	return &machinev1.AlibabaCloudMachineProviderConfig{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "machine.openshift.io/v1",
+			Kind:       "AlibabaCloudMachineProviderConfig",
+		},
+		CredentialsSecret: &corev1.LocalObjectReference{
+			Name: "alibabacloud-credentials",
+		},
+		ImageID:      "m-rhcos-12345678",
+		InstanceType: m.instanceType,
+		RegionID:     "cn-hangzhou",
+		ResourceGroup: machinev1.AlibabaResourceReference{
+			Type: machinev1.AlibabaResourceReferenceTypeID,
+			ID:   pointer.String("rg-12345678"),
+		},
+		SecurityGroups: []machinev1.AlibabaResourceReference{
+			{
+				Type: machinev1.AlibabaResourceReferenceTypeID,
+				ID:   pointer.String("sg-12345678"),
+			},
+		},
+		UserDataSecret: &corev1.LocalObjectReference{
+			Name: "master-user-data",
+		},
+		VpcID: "vpc-12345678",
+		VSwitch: machinev1.AlibabaResourceReference{
+			Type: machinev1.AlibabaResourceReferenceTypeID,
+			ID:   pointer.String(m.vSwitchID),
+		},
+		ZoneID: m.zone,
+	}
+}
+
+// BuildRawExtension builds a new Alibaba Cloud machine config based on the configuration provided.
+func (m AlibabaCloudProviderSpecBuilder) BuildRawExtension() *runtime.RawExtension {
+	providerConfig := m.Build()
+
+	raw, err := json.Marshal(providerConfig)
+	if err != nil {
+		// As we are building the input to json.Marshal, this should never happen.
+		panic(err)
+	}
+
+	return &runtime.RawExtension{
+		Raw: raw,
+	}
+}
+
+// WithInstanceType sets the instance type for the Alibaba Cloud machine config builder.
+func (m AlibabaCloudProviderSpecBuilder) WithInstanceType(instanceType string) AlibabaCloudProviderSpecBuilder {
+	m.instanceType = instanceType
+	return m
+}
+
+// WithVSwitchID sets the ID of the vSwitch for the Alibaba Cloud machine config builder.
+func (m AlibabaCloudProviderSpecBuilder) WithVSwitchID(vSwitchID string) AlibabaCloudProviderSpecBuilder {
+	m.vSwitchID = vSwitchID
+	return m
+}
+
+// WithZone sets the zone for the Alibaba Cloud machine config builder.
+func (m AlibabaCloudProviderSpecBuilder) WithZone(zone string) AlibabaCloudProviderSpecBuilder {
+	m.zone = zone
+	return m
+}
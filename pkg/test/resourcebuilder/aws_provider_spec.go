@@ -28,6 +28,8 @@ import (
 // AWSProviderSpec creates a new AWS machine config builder.
 func AWSProviderSpec() AWSProviderSpecBuilder {
 	return AWSProviderSpecBuilder{
+		ami:              "aws-ami-12345678",
+		apiVersion:       "awsproviderconfig.openshift.io/v1beta1",
 		availabilityZone: "us-east-1a",
 		instanceType:     "m6i.xlarge",
 		securityGroups: []machinev1beta1.AWSResourceReference{
@@ -57,31 +59,50 @@ func AWSProviderSpec() AWSProviderSpecBuilder {
 
 // AWSProviderSpecBuilder is used to build out a AWS machine config object.
 type AWSProviderSpecBuilder struct {
+	ami              string
+	amiFilters       []machinev1beta1.Filter
+	apiVersion       string
 	availabilityZone string
+	blockDevices     []machinev1beta1.BlockDeviceMappingSpec
 	instanceType     string
+	publicIP         *bool
+	requirements     json.RawMessage
 	securityGroups   []machinev1beta1.AWSResourceReference
 	subnet           machinev1beta1.AWSResourceReference
+	tags             []machinev1beta1.TagSpecification
 }
 
 // Build builds a new AWS machine config based on the configuration provided.
 func (m AWSProviderSpecBuilder) Build() *machinev1beta1.AWSMachineProviderConfig {
+	var amiID *string
+	if len(m.amiFilters) == 0 {
+		amiID = stringPtr(m.ami)
+	}
+
+	blockDevices := []machinev1beta1.BlockDeviceMappingSpec{
+		{
+			EBS: &machinev1beta1.EBSBlockDeviceSpec{
+				Encrypted:  boolPtr(true),
+				VolumeSize: int64Ptr(120),
+				VolumeType: stringPtr("gp3"),
+			},
+		},
+	}
+
+	if m.blockDevices != nil {
+		blockDevices = m.blockDevices
+	}
+
 	return &machinev1beta1.AWSMachineProviderConfig{
 		TypeMeta: metav1.TypeMeta{
-			APIVersion: "awsproviderconfig.openshift.io/v1beta1",
+			APIVersion: m.apiVersion,
 			Kind:       "AWSMachineProviderConfig",
 		},
 		AMI: machinev1beta1.AWSResourceReference{
-			ID: stringPtr("aws-ami-12345678"),
-		},
-		BlockDevices: []machinev1beta1.BlockDeviceMappingSpec{
-			{
-				EBS: &machinev1beta1.EBSBlockDeviceSpec{
-					Encrypted:  boolPtr(true),
-					VolumeSize: int64Ptr(120),
-					VolumeType: stringPtr("gp3"),
-				},
-			},
+			ID:      amiID,
+			Filters: m.amiFilters,
 		},
+		BlockDevices: blockDevices,
 		CredentialsSecret: &corev1.LocalObjectReference{
 			Name: "aws-cloud-credentials",
 		},
@@ -103,8 +124,10 @@ func (m AWSProviderSpecBuilder) Build() *machinev1beta1.AWSMachineProviderConfig
 			Region:           "us-east-1",
 			AvailabilityZone: m.availabilityZone,
 		},
+		PublicIP:       m.publicIP,
 		SecurityGroups: m.securityGroups,
 		Subnet:         m.subnet,
+		Tags:           m.tags,
 		UserDataSecret: &corev1.LocalObjectReference{
 			Name: "aws-user-data-12345678",
 		},
@@ -112,6 +135,8 @@ func (m AWSProviderSpecBuilder) Build() *machinev1beta1.AWSMachineProviderConfig
 }
 
 // BuildRawExtension builds a new AWS machine config based on the configuration provided.
+// Any instance requirements are only included within the raw extension, as the
+// AWSMachineProviderConfig does not include them.
 func (m AWSProviderSpecBuilder) BuildRawExtension() *runtime.RawExtension {
 	providerConfig := m.Build()
 
@@ -121,23 +146,75 @@ func (m AWSProviderSpecBuilder) BuildRawExtension() *runtime.RawExtension {
 		panic(err)
 	}
 
+	if m.requirements != nil {
+		fields := map[string]json.RawMessage{}
+		if err := json.Unmarshal(raw, &fields); err != nil {
+			panic(err)
+		}
+
+		fields["instanceRequirements"] = m.requirements
+
+		if raw, err = json.Marshal(fields); err != nil {
+			// The instance requirements must be valid JSON.
+			panic(err)
+		}
+	}
+
 	return &runtime.RawExtension{
 		Raw: raw,
 	}
 }
 
+// WithAMI sets the ID of the AMI for the AWS machine config builder.
+func (m AWSProviderSpecBuilder) WithAMI(ami string) AWSProviderSpecBuilder {
+	m.ami = ami
+	return m
+}
+
+// WithAMIFilters sets the filters used to select the AMI for the AWS machine config builder.
+// The AMI is then referenced by the filters alone, rather than by ID.
+func (m AWSProviderSpecBuilder) WithAMIFilters(filters ...machinev1beta1.Filter) AWSProviderSpecBuilder {
+	m.amiFilters = filters
+	return m
+}
+
+// WithAPIVersion sets the apiVersion for the AWS machine config builder.
+func (m AWSProviderSpecBuilder) WithAPIVersion(apiVersion string) AWSProviderSpecBuilder {
+	m.apiVersion = apiVersion
+	return m
+}
+
 // WithAvailabilityZone sets the availabilityZone for the AWS machine config builder.
 func (m AWSProviderSpecBuilder) WithAvailabilityZone(az string) AWSProviderSpecBuilder {
 	m.availabilityZone = az
 	return m
 }
 
+// WithBlockDevices sets the block devices for the AWS machine config builder.
+// When no block devices are set, a single encrypted 120GiB gp3 root volume is used.
+func (m AWSProviderSpecBuilder) WithBlockDevices(blockDevices []machinev1beta1.BlockDeviceMappingSpec) AWSProviderSpecBuilder {
+	m.blockDevices = blockDevices
+	return m
+}
+
+// WithInstanceRequirements sets the JSON encoded instance requirements for the AWS machine config builder.
+func (m AWSProviderSpecBuilder) WithInstanceRequirements(requirements string) AWSProviderSpecBuilder {
+	m.requirements = json.RawMessage(requirements)
+	return m
+}
+
 // WithInstanceType sets the isntanceType for the AWS machine config builder.
 func (m AWSProviderSpecBuilder) WithInstanceType(instanceType string) AWSProviderSpecBuilder {
 	m.instanceType = instanceType
 	return m
 }
 
+// WithPublicIP sets the publicIp for the AWS machine config builder.
+func (m AWSProviderSpecBuilder) WithPublicIP(publicIP bool) AWSProviderSpecBuilder {
+	m.publicIP = &publicIP
+	return m
+}
+
 // WithSecurityGroups sets the securityGroups for the AWS machine config builder.
 func (m AWSProviderSpecBuilder) WithSecurityGroups(sgs []machinev1beta1.AWSResourceReference) AWSProviderSpecBuilder {
 	m.securityGroups = sgs
@@ -149,3 +226,9 @@ func (m AWSProviderSpecBuilder) WithSubnet(subnet machinev1beta1.AWSResourceRefe
 	m.subnet = subnet
 	return m
 }
+
+// WithTags sets the tags for the AWS machine config builder.
+func (m AWSProviderSpecBuilder) WithTags(tags []machinev1beta1.TagSpecification) AWSProviderSpecBuilder {
+	m.tags = tags
+	return m
+}
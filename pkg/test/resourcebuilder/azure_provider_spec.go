@@ -0,0 +1,170 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resourcebuilder
+
+import (
+	"encoding/json"
+
+	machinev1beta1 "github.com/openshift/api/machine/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// AzureProviderSpec creates a new Azure machine config builder.
+func AzureProviderSpec() AzureProviderSpecBuilder {
+	return AzureProviderSpecBuilder{
+		apiVersion:      "machine.openshift.io/v1beta1",
+		managedIdentity: "cluster-id-identity",
+		osDiskType:      "Premium_LRS",
+		vmSize:          "Standard_D8s_v3",
+	}
+}
+
+// AzureProviderSpecBuilder is used to build out an Azure machine config object.
+type AzureProviderSpecBuilder struct {
+	acceleratedNetworking bool
+	apiVersion            string
+	dataDisks             []machinev1beta1.DataDisk
+	diagnostics           json.RawMessage
+	managedIdentity       string
+	osDiskType            string
+	vmSize                string
+	zone                  string
+}
+
+// Build builds a new Azure machine config based on the configuration provided.
+func (m AzureProviderSpecBuilder) Build() *machinev1beta1.AzureMachineProviderSpec {
+	var zone *string
+	if m.zone != "" {
+		zone = stringPtr(m.zone)
+	}
+
+	return &machinev1beta1.AzureMachineProviderSpec{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: m.apiVersion,
+			Kind:       "AzureMachineProviderSpec",
+		},
+		AcceleratedNetworking: m.acceleratedNetworking,
+		DataDisks:             m.dataDisks,
+		CredentialsSecret: &corev1.SecretReference{
+			Name:      "azure-cloud-credentials",
+			Namespace: "openshift-machine-api",
+		},
+		Image: machinev1beta1.Image{
+			ResourceID: "/resourceGroups/cluster-id-rg/providers/Microsoft.Compute/images/cluster-id",
+		},
+		Location:             "centralus",
+		ManagedIdentity:      m.managedIdentity,
+		NetworkResourceGroup: "cluster-id-rg",
+		OSDisk: machinev1beta1.OSDisk{
+			DiskSizeGB: 1024,
+			ManagedDisk: machinev1beta1.OSDiskManagedDiskParameters{
+				StorageAccountType: m.osDiskType,
+			},
+			OSType: "Linux",
+		},
+		PublicIP:      false,
+		ResourceGroup: "cluster-id-rg",
+		Subnet:        "cluster-id-master-subnet",
+		UserDataSecret: &corev1.SecretReference{
+			Name: "master-user-data",
+		},
+		VMSize: m.vmSize,
+		Vnet:   "cluster-id-vnet",
+		Zone:   zone,
+	}
+}
+
+// BuildRawExtension builds a new Azure machine config based on the configuration provided.
+// Any diagnostics are only included within the raw extension, as the
+// AzureMachineProviderSpec does not include them.
+func (m AzureProviderSpecBuilder) BuildRawExtension() *runtime.RawExtension {
+	providerConfig := m.Build()
+
+	raw, err := json.Marshal(providerConfig)
+	if err != nil {
+		// As we are building the input to json.Marshal, this should never happen.
+		panic(err)
+	}
+
+	if m.diagnostics != nil {
+		fields := map[string]json.RawMessage{}
+		if err := json.Unmarshal(raw, &fields); err != nil {
+			panic(err)
+		}
+
+		fields["diagnostics"] = m.diagnostics
+
+		if raw, err = json.Marshal(fields); err != nil {
+			// The diagnostics must be valid JSON.
+			panic(err)
+		}
+	}
+
+	return &runtime.RawExtension{
+		Raw: raw,
+	}
+}
+
+// WithAcceleratedNetworking sets whether accelerated networking is enabled for the Azure machine config builder.
+func (m AzureProviderSpecBuilder) WithAcceleratedNetworking(acceleratedNetworking bool) AzureProviderSpecBuilder {
+	m.acceleratedNetworking = acceleratedNetworking
+	return m
+}
+
+// WithAPIVersion sets the apiVersion for the Azure machine config builder.
+func (m AzureProviderSpecBuilder) WithAPIVersion(apiVersion string) AzureProviderSpecBuilder {
+	m.apiVersion = apiVersion
+	return m
+}
+
+// WithDataDisks sets the data disks for the Azure machine config builder.
+func (m AzureProviderSpecBuilder) WithDataDisks(dataDisks ...machinev1beta1.DataDisk) AzureProviderSpecBuilder {
+	m.dataDisks = dataDisks
+	return m
+}
+
+// WithDiagnostics sets the JSON encoded diagnostics for the Azure machine config builder.
+func (m AzureProviderSpecBuilder) WithDiagnostics(diagnostics string) AzureProviderSpecBuilder {
+	m.diagnostics = json.RawMessage(diagnostics)
+	return m
+}
+
+// WithManagedIdentity sets the managed identity for the Azure machine config builder.
+func (m AzureProviderSpecBuilder) WithManagedIdentity(managedIdentity string) AzureProviderSpecBuilder {
+	m.managedIdentity = managedIdentity
+	return m
+}
+
+// WithOSDiskType sets the storage account type of the OS disk for the Azure machine config builder.
+func (m AzureProviderSpecBuilder) WithOSDiskType(osDiskType string) AzureProviderSpecBuilder {
+	m.osDiskType = osDiskType
+	return m
+}
+
+// WithVMSize sets the VM size for the Azure machine config builder.
+func (m AzureProviderSpecBuilder) WithVMSize(vmSize string) AzureProviderSpecBuilder {
+	m.vmSize = vmSize
+	return m
+}
+
+// WithZone sets the zone for the Azure machine config builder.
+func (m AzureProviderSpecBuilder) WithZone(zone string) AzureProviderSpecBuilder {
+	m.zone = zone
+	return m
+}
@@ -0,0 +1,105 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resourcebuilder
+
+import (
+	"encoding/json"
+
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// BareMetalProviderSpec creates a new bare metal (Metal3) machine config builder.
+// The Metal3 API is not a dependency of the operator, so the machine config is built as raw JSON.
+func BareMetalProviderSpec() BareMetalProviderSpecBuilder {
+	return BareMetalProviderSpecBuilder{
+		apiVersion:   "baremetal.cluster.k8s.io/v1alpha1",
+		hostSelector: map[string]string{},
+		imageURL:     "http://172.22.0.3:6181/images/rhcos-ootpa-latest.qcow2/rhcos-ootpa-latest.qcow2",
+		userData:     "master-user-data-managed",
+	}
+}
+
+// BareMetalProviderSpecBuilder is used to build out a bare metal machine config object.
+type BareMetalProviderSpecBuilder struct {
+	apiVersion   string
+	hostSelector map[string]string
+	imageURL     string
+	userData     string
+}
+
+// Build builds a new bare metal machine config based on the configuration provided.
+func (m BareMetalProviderSpecBuilder) Build() map[string]interface{} {
+	spec := map[string]interface{}{
+		"apiVersion": m.apiVersion,
+		"kind":       "BareMetalMachineProviderSpec",
+		"customDeploy": map[string]interface{}{
+			"method": "install_coreos",
+		},
+		"image": map[string]interface{}{
+			"url":      m.imageURL,
+			"checksum": m.imageURL + "/cached.md5sum",
+		},
+		"userData": map[string]interface{}{
+			"name": m.userData,
+		},
+	}
+
+	if len(m.hostSelector) > 0 {
+		spec["hostSelector"] = map[string]interface{}{
+			"matchLabels": m.hostSelector,
+		}
+	}
+
+	return spec
+}
+
+// BuildRawExtension builds a new bare metal machine config based on the configuration provided.
+func (m BareMetalProviderSpecBuilder) BuildRawExtension() *runtime.RawExtension {
+	raw, err := json.Marshal(m.Build())
+	if err != nil {
+		// As we are building the input to json.Marshal, this should never happen.
+		panic(err)
+	}
+
+	return &runtime.RawExtension{
+		Raw: raw,
+	}
+}
+
+// WithAPIVersion sets the API version for the bare metal machine config builder.
+func (m BareMetalProviderSpecBuilder) WithAPIVersion(apiVersion string) BareMetalProviderSpecBuilder {
+	m.apiVersion = apiVersion
+	return m
+}
+
+// WithHostSelector sets the labels that the host must match for the bare metal machine config builder.
+func (m BareMetalProviderSpecBuilder) WithHostSelector(matchLabels map[string]string) BareMetalProviderSpecBuilder {
+	m.hostSelector = matchLabels
+	return m
+}
+
+// WithImageURL sets the URL of the image for the bare metal machine config builder.
+func (m BareMetalProviderSpecBuilder) WithImageURL(imageURL string) BareMetalProviderSpecBuilder {
+	m.imageURL = imageURL
+	return m
+}
+
+// WithUserData sets the name of the user data secret for the bare metal machine config builder.
+func (m BareMetalProviderSpecBuilder) WithUserData(userData string) BareMetalProviderSpecBuilder {
+	m.userData = userData
+	return m
+}
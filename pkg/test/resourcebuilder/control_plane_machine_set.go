@@ -46,6 +46,7 @@ func ControlPlaneMachineSet() ControlPlaneMachineSetBuilder {
 
 // ControlPlaneMachineSetBuilder is used to build out a controlplanemachineset object.
 type ControlPlaneMachineSetBuilder struct {
+	annotations            map[string]string
 	generation             int64
 	machineTemplateBuilder ControlPlaneMachineSetTemplateBuilder
 	name                   string
@@ -60,9 +61,10 @@ type ControlPlaneMachineSetBuilder struct {
 func (m ControlPlaneMachineSetBuilder) Build() *machinev1.ControlPlaneMachineSet {
 	cpms := &machinev1.ControlPlaneMachineSet{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:       m.name,
-			Namespace:  m.namespace,
-			Generation: m.generation,
+			Annotations: m.annotations,
+			Name:        m.name,
+			Namespace:   m.namespace,
+			Generation:  m.generation,
 		},
 		Spec: machinev1.ControlPlaneMachineSetSpec{
 			Replicas: int32Ptr(m.replicas),
@@ -83,6 +85,16 @@ func (m ControlPlaneMachineSetBuilder) Build() *machinev1.ControlPlaneMachineSet
 	return cpms
 }
 
+// WithAnnotations sets the annotations for the controlplanemachineset builder.
+func (m ControlPlaneMachineSetBuilder) WithAnnotations(annotations map[string]string) ControlPlaneMachineSetBuilder {
+	m.annotations = map[string]string{}
+	for k, v := range annotations {
+		m.annotations[k] = v
+	}
+
+	return m
+}
+
 // WithMachineTemplateBuilder sets the machine template builder for the controlplanemachineset builder.
 func (m ControlPlaneMachineSetBuilder) WithMachineTemplateBuilder(builder ControlPlaneMachineSetTemplateBuilder) ControlPlaneMachineSetBuilder {
 	m.machineTemplateBuilder = builder
@@ -0,0 +1,112 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resourcebuilder
+
+import (
+	"encoding/json"
+
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// EquinixMetalProviderSpec creates a new Equinix Metal (formerly Packet) machine config builder.
+// The Equinix Metal API is not a dependency of the operator, so the machine config is built as raw JSON.
+func EquinixMetalProviderSpec() EquinixMetalProviderSpecBuilder {
+	return EquinixMetalProviderSpecBuilder{
+		apiVersion:  "machine.openshift.io/v1beta1",
+		facility:    "da11",
+		machineType: "c3.small.x86",
+		metro:       "da",
+		os:          "rhcos_4_12",
+	}
+}
+
+// EquinixMetalProviderSpecBuilder is used to build out an Equinix Metal machine config object.
+type EquinixMetalProviderSpecBuilder struct {
+	apiVersion  string
+	facility    string
+	machineType string
+	metro       string
+	os          string
+}
+
+// Build builds a new Equinix Metal machine config based on the configuration provided.
+func (m EquinixMetalProviderSpecBuilder) Build() map[string]interface{} {
+	spec := map[string]interface{}{
+		"apiVersion":   m.apiVersion,
+		"kind":         "PacketMachineProviderConfig",
+		"billingCycle": "hourly",
+		"machineType":  m.machineType,
+		"os":           m.os,
+		"projectID":    "3f4eb5b2-5a07-4a49-8f6e-0b0e4f2d4c1a",
+		"userDataSecret": map[string]interface{}{
+			"name": "master-user-data",
+		},
+	}
+
+	if m.metro != "" {
+		spec["metro"] = m.metro
+	}
+
+	if m.facility != "" {
+		spec["facility"] = m.facility
+	}
+
+	return spec
+}
+
+// BuildRawExtension builds a new Equinix Metal machine config based on the configuration provided.
+func (m EquinixMetalProviderSpecBuilder) BuildRawExtension() *runtime.RawExtension {
+	raw, err := json.Marshal(m.Build())
+	if err != nil {
+		// As we are building the input to json.Marshal, this should never happen.
+		panic(err)
+	}
+
+	return &runtime.RawExtension{
+		Raw: raw,
+	}
+}
+
+// WithAPIVersion sets the API version for the Equinix Metal machine config builder.
+func (m EquinixMetalProviderSpecBuilder) WithAPIVersion(apiVersion string) EquinixMetalProviderSpecBuilder {
+	m.apiVersion = apiVersion
+	return m
+}
+
+// WithFacility sets the facility for the Equinix Metal machine config builder.
+func (m EquinixMetalProviderSpecBuilder) WithFacility(facility string) EquinixMetalProviderSpecBuilder {
+	m.facility = facility
+	return m
+}
+
+// WithMachineType sets the machine type for the Equinix Metal machine config builder.
+func (m EquinixMetalProviderSpecBuilder) WithMachineType(machineType string) EquinixMetalProviderSpecBuilder {
+	m.machineType = machineType
+	return m
+}
+
+// WithMetro sets the metro for the Equinix Metal machine config builder.
+func (m EquinixMetalProviderSpecBuilder) WithMetro(metro string) EquinixMetalProviderSpecBuilder {
+	m.metro = metro
+	return m
+}
+
+// WithOS sets the operating system for the Equinix Metal machine config builder.
+func (m EquinixMetalProviderSpecBuilder) WithOS(os string) EquinixMetalProviderSpecBuilder {
+	m.os = os
+	return m
+}
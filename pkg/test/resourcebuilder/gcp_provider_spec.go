@@ -0,0 +1,196 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resourcebuilder
+
+import (
+	"encoding/json"
+
+	machinev1beta1 "github.com/openshift/api/machine/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// GCPProviderSpec creates a new GCP machine config builder.
+func GCPProviderSpec() GCPProviderSpecBuilder {
+	return GCPProviderSpecBuilder{
+		apiVersion:  "machine.openshift.io/v1beta1",
+		bootDisk:    GCPDisk().WithBoot(true),
+		machineType: "n1-standard-4",
+		zone:        "us-central1-a",
+	}
+}
+
+// GCPProviderSpecBuilder is used to build out a GCP machine config object.
+type GCPProviderSpecBuilder struct {
+	apiVersion  string
+	bootDisk    GCPDiskBuilder
+	extraDisks  []GCPDiskBuilder
+	machineType string
+	zone        string
+}
+
+// Build builds a new GCP machine config based on the configuration provided.
+func (m GCPProviderSpecBuilder) Build() *machinev1beta1.GCPMachineProviderSpec {
+	disks := []*machinev1beta1.GCPDisk{m.bootDisk.Build()}
+	for _, disk := range m.extraDisks {
+		disks = append(disks, disk.Build())
+	}
+
+	return &machinev1beta1.GCPMachineProviderSpec{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: m.apiVersion,
+			Kind:       "GCPMachineProviderSpec",
+		},
+		CanIPForward: false,
+		CredentialsSecret: &corev1.LocalObjectReference{
+			Name: "gcp-cloud-credentials",
+		},
+		DeletionProtection: false,
+		Disks:              disks,
+		MachineType:        m.machineType,
+		NetworkInterfaces: []*machinev1beta1.GCPNetworkInterface{
+			{
+				Network:    "gcp-network-12345678",
+				Subnetwork: "gcp-master-subnet-12345678",
+			},
+		},
+		ProjectID: "gcp-project-12345678",
+		Region:    "us-central1",
+		ServiceAccounts: []machinev1beta1.GCPServiceAccount{
+			{
+				Email:  "master-12345678@gcp-project-12345678.iam.gserviceaccount.com",
+				Scopes: []string{"https://www.googleapis.com/auth/cloud-platform"},
+			},
+		},
+		Tags: []string{"cluster-id-master"},
+		UserDataSecret: &corev1.LocalObjectReference{
+			Name: "master-user-data",
+		},
+		Zone: m.zone,
+	}
+}
+
+// BuildRawExtension builds a new GCP machine config based on the configuration provided.
+func (m GCPProviderSpecBuilder) BuildRawExtension() *runtime.RawExtension {
+	providerConfig := m.Build()
+
+	raw, err := json.Marshal(providerConfig)
+	if err != nil {
+		// As we are building the input to json.Marshal, this should never happen.
+		panic(err)
+	}
+
+	return &runtime.RawExtension{
+		Raw: raw,
+	}
+}
+
+// WithAPIVersion sets the apiVersion for the GCP machine config builder.
+func (m GCPProviderSpecBuilder) WithAPIVersion(apiVersion string) GCPProviderSpecBuilder {
+	m.apiVersion = apiVersion
+	return m
+}
+
+// WithBootDisk sets the boot disk for the GCP machine config builder.
+func (m GCPProviderSpecBuilder) WithBootDisk(disk GCPDiskBuilder) GCPProviderSpecBuilder {
+	m.bootDisk = disk.WithBoot(true)
+	return m
+}
+
+// WithExtraDisks sets the disks, in addition to the boot disk, for the GCP machine config builder.
+func (m GCPProviderSpecBuilder) WithExtraDisks(disks ...GCPDiskBuilder) GCPProviderSpecBuilder {
+	m.extraDisks = disks
+	return m
+}
+
+// WithZone sets the zone for the GCP machine config builder.
+func (m GCPProviderSpecBuilder) WithZone(zone string) GCPProviderSpecBuilder {
+	m.zone = zone
+	return m
+}
+
+// WithMachineType sets the machine type for the GCP machine config builder.
+func (m GCPProviderSpecBuilder) WithMachineType(machineType string) GCPProviderSpecBuilder {
+	m.machineType = machineType
+	return m
+}
+
+// GCPDisk creates a new GCP disk builder.
+func GCPDisk() GCPDiskBuilder {
+	return GCPDiskBuilder{
+		diskType: "pd-ssd",
+		image:    "projects/rhcos-cloud/global/images/rhcos-12345678",
+		sizeGB:   128,
+	}
+}
+
+// GCPDiskBuilder is used to build out a GCP disk.
+type GCPDiskBuilder struct {
+	boot     bool
+	diskType string
+	image    string
+	kmsKey   *machinev1beta1.GCPKMSKeyReference
+	sizeGB   int64
+}
+
+// Build builds a new GCP disk based on the configuration provided.
+func (d GCPDiskBuilder) Build() *machinev1beta1.GCPDisk {
+	disk := &machinev1beta1.GCPDisk{
+		AutoDelete: true,
+		Boot:       d.boot,
+		SizeGB:     d.sizeGB,
+		Type:       d.diskType,
+		Labels:     map[string]string{},
+	}
+
+	if d.boot {
+		disk.Image = d.image
+	}
+
+	if d.kmsKey != nil {
+		disk.EncryptionKey = &machinev1beta1.GCPEncryptionKeyReference{
+			KMSKey: d.kmsKey.DeepCopy(),
+		}
+	}
+
+	return disk
+}
+
+// WithBoot sets whether the disk is the boot disk for the GCP disk builder.
+func (d GCPDiskBuilder) WithBoot(boot bool) GCPDiskBuilder {
+	d.boot = boot
+	return d
+}
+
+// WithKMSKey sets the KMS key used to encrypt the disk for the GCP disk builder.
+func (d GCPDiskBuilder) WithKMSKey(kmsKey machinev1beta1.GCPKMSKeyReference) GCPDiskBuilder {
+	d.kmsKey = &kmsKey
+	return d
+}
+
+// WithSizeGB sets the size, in GB, for the GCP disk builder.
+func (d GCPDiskBuilder) WithSizeGB(sizeGB int64) GCPDiskBuilder {
+	d.sizeGB = sizeGB
+	return d
+}
+
+// WithType sets the disk type for the GCP disk builder.
+func (d GCPDiskBuilder) WithType(diskType string) GCPDiskBuilder {
+	d.diskType = diskType
+	return d
+}
@@ -0,0 +1,100 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resourcebuilder
+
+import (
+	"encoding/json"
+
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// IBMCloudProviderSpec creates a new IBM Cloud VPC machine config builder.
+// The IBM Cloud provider spec is not part of the vendored Machine API, so the machine config is built as raw JSON.
+func IBMCloudProviderSpec() IBMCloudProviderSpecBuilder {
+	return IBMCloudProviderSpecBuilder{
+		apiVersion: "ibmcloudproviderconfig.openshift.io/v1beta1",
+		profile:    "bx2-4x16",
+		zone:       "us-south-1",
+	}
+}
+
+// IBMCloudProviderSpecBuilder is used to build out an IBM Cloud VPC machine config object.
+type IBMCloudProviderSpecBuilder struct {
+	apiVersion string
+	profile    string
+	zone       string
+}
+
+// Build builds a new IBM Cloud VPC machine config based on the configuration provided.
+func (m IBMCloudProviderSpecBuilder) Build() map[string]interface{} {
+	spec := map[string]interface{}{
+		"apiVersion": m.apiVersion,
+		"kind":       "IBMCloudMachineProviderSpec",
+		"credentialsSecret": map[string]interface{}{
+			"name": "ibmcloud-credentials",
+		},
+		"image":   "rhcos-4-12",
+		"profile": m.profile,
+		"primaryNetworkInterface": map[string]interface{}{
+			"securityGroups": []interface{}{"cluster-sg-control-plane"},
+			"subnet":         "cluster-subnet-control-plane-" + m.zone,
+		},
+		"region":        "us-south",
+		"resourceGroup": "cluster-rg",
+		"userDataSecret": map[string]interface{}{
+			"name": "master-user-data",
+		},
+		"vpc": "cluster-vpc",
+	}
+
+	if m.zone != "" {
+		spec["zone"] = m.zone
+	}
+
+	return spec
+}
+
+// BuildRawExtension builds a new IBM Cloud VPC machine config based on the configuration provided.
+func (m IBMCloudProviderSpecBuilder) BuildRawExtension() *runtime.RawExtension {
+	raw, err := json.Marshal(m.Build())
+	if err != nil {
+		// As we are building the input to json.Marshal, this should never happen.
+		panic(err)
+	}
+
+	return &runtime.RawExtension{
+		Raw: raw,
+	}
+}
+
+// WithAPIVersion sets the API version for the IBM Cloud VPC machine config builder.
+func (m IBMCloudProviderSpecBuilder) WithAPIVersion(apiVersion string) IBMCloudProviderSpecBuilder {
+	m.apiVersion = apiVersion
+	return m
+}
+
+// WithProfile sets the instance profile for the IBM Cloud VPC machine config builder.
+func (m IBMCloudProviderSpecBuilder) WithProfile(profile string) IBMCloudProviderSpecBuilder {
+	m.profile = profile
+	return m
+}
+
+// WithZone sets the VPC zone for the IBM Cloud VPC machine config builder.
+func (m IBMCloudProviderSpecBuilder) WithZone(zone string) IBMCloudProviderSpecBuilder {
+	m.zone = zone
+	return m
+}
@@ -20,6 +20,7 @@ import (
 	machinev1beta1 "github.com/openshift/api/machine/v1beta1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 )
 
 const (
@@ -37,13 +38,18 @@ type MachineBuilder struct {
 	generateName        string
 	name                string
 	namespace           string
+	annotations         map[string]string
 	labels              map[string]string
+	providerID          *string
 	providerSpecBuilder RawExtensionBuilder
 
 	// status fields
-	errorMessage *string
-	nodeRef      *corev1.ObjectReference
-	phase        *string
+	conditions     machinev1beta1.Conditions
+	errorMessage   *string
+	errorReason    *machinev1beta1.MachineStatusError
+	nodeRef        *corev1.ObjectReference
+	phase          *string
+	providerStatus *runtime.RawExtension
 }
 
 // Build builds a new machine based on the configuration provided.
@@ -53,12 +59,19 @@ func (m MachineBuilder) Build() *machinev1beta1.Machine {
 			GenerateName: m.generateName,
 			Name:         m.name,
 			Namespace:    m.namespace,
+			Annotations:  m.annotations,
 			Labels:       m.labels,
 		},
+		Spec: machinev1beta1.MachineSpec{
+			ProviderID: m.providerID,
+		},
 		Status: machinev1beta1.MachineStatus{
-			ErrorMessage: m.errorMessage,
-			Phase:        m.phase,
-			NodeRef:      m.nodeRef,
+			Conditions:     m.conditions,
+			ErrorMessage:   m.errorMessage,
+			ErrorReason:    m.errorReason,
+			Phase:          m.phase,
+			NodeRef:        m.nodeRef,
+			ProviderStatus: m.providerStatus,
 		},
 	}
 
@@ -83,6 +96,19 @@ func (m MachineBuilder) AsMaster() MachineBuilder {
 		WithLabel(machineTypeLabelName, "master")
 }
 
+// WithAnnotation sets an annotation for the machine builder.
+func (m MachineBuilder) WithAnnotation(key, value string) MachineBuilder {
+	annotations := map[string]string{}
+	for k, v := range m.annotations {
+		annotations[k] = v
+	}
+
+	annotations[key] = value
+	m.annotations = annotations
+
+	return m
+}
+
 // WithGenerateName sets the generateName for the machine builder.
 func (m MachineBuilder) WithGenerateName(generateName string) MachineBuilder {
 	m.generateName = generateName
@@ -90,12 +116,15 @@ func (m MachineBuilder) WithGenerateName(generateName string) MachineBuilder {
 }
 
 // WithLabel sets the labels for the machine builder.
+// The labels are copied so that builders derived from the same builder do not share labels.
 func (m MachineBuilder) WithLabel(key, value string) MachineBuilder {
-	if m.labels == nil {
-		m.labels = make(map[string]string)
+	labels := make(map[string]string)
+	for k, v := range m.labels {
+		labels[k] = v
 	}
 
-	m.labels[key] = value
+	labels[key] = value
+	m.labels = labels
 
 	return m
 }
@@ -118,6 +147,12 @@ func (m MachineBuilder) WithNamespace(namespace string) MachineBuilder {
 	return m
 }
 
+// WithProviderID sets the provider ID for the machine builder.
+func (m MachineBuilder) WithProviderID(providerID string) MachineBuilder {
+	m.providerID = &providerID
+	return m
+}
+
 // WithProviderSpecBuilder sets the providerSpec builder for the machine builder.
 func (m MachineBuilder) WithProviderSpecBuilder(builder RawExtensionBuilder) MachineBuilder {
 	m.providerSpecBuilder = builder
@@ -126,12 +161,24 @@ func (m MachineBuilder) WithProviderSpecBuilder(builder RawExtensionBuilder) Mac
 
 // Status Fields
 
+// WithConditions sets the conditions status field for the machine builder.
+func (m MachineBuilder) WithConditions(conditions machinev1beta1.Conditions) MachineBuilder {
+	m.conditions = conditions
+	return m
+}
+
 // WithErrorMessage sets the error message status field for the machine builder.
 func (m MachineBuilder) WithErrorMessage(errorMsg string) MachineBuilder {
 	m.errorMessage = &errorMsg
 	return m
 }
 
+// WithErrorReason sets the error reason status field for the machine builder.
+func (m MachineBuilder) WithErrorReason(errorReason machinev1beta1.MachineStatusError) MachineBuilder {
+	m.errorReason = &errorReason
+	return m
+}
+
 // WithPhase sets the phase status field for the machine builder.
 func (m MachineBuilder) WithPhase(phase string) MachineBuilder {
 	m.phase = &phase
@@ -143,3 +190,9 @@ func (m MachineBuilder) WithNodeRef(nodeRef corev1.ObjectReference) MachineBuild
 	m.nodeRef = &nodeRef
 	return m
 }
+
+// WithProviderStatus sets the provider status for the machine builder.
+func (m MachineBuilder) WithProviderStatus(providerStatus *runtime.RawExtension) MachineBuilder {
+	m.providerStatus = providerStatus
+	return m
+}
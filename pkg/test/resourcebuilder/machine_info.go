@@ -32,36 +32,77 @@ func MachineInfo() MachineInfoBuilder {
 
 // MachineInfoBuilder is used to build out a machineinfo object.
 type MachineInfoBuilder struct {
+	machineAnnotations       map[string]string
+	machineCreationTimestamp metav1.Time
 	machineDeletiontimestamp *metav1.Time
+	machineFinalizers        []string
 	machineGVR               schema.GroupVersionResource
 	machineName              string
 	machineNamespace         string
 	machineLabels            map[string]string
 	machineOwnerRefs         []metav1.OwnerReference
 
-	nodeGVR  schema.GroupVersionResource
-	nodeName string
+	nodeGVR            schema.GroupVersionResource
+	nodeName           string
+	nodeTopologyLabels map[string]string
 
-	errorMessage string
-	index        int32
-	needsUpdate  bool
-	ready        bool
+	failureDomain string
+
+	instanceType        string
+	desiredInstanceType string
+	providerID          string
+	templateHash        string
+	desiredTemplateHash string
+
+	errorMessage     string
+	errorReason      string
+	index            int32
+	instanceMissing  bool
+	instanceRunning  bool
+	machineAPIPaused bool
+	misplaced        bool
+	missingTags      []string
+	needsUpdate      bool
+	outside          bool
+	ready            bool
+	unmanagedFields  []string
 }
 
 // Build builds a new machineinfo based on the configuration provided.
 func (m MachineInfoBuilder) Build() machineproviders.MachineInfo {
 	info := machineproviders.MachineInfo{
-		ErrorMessage: m.errorMessage,
-		Index:        m.index,
-		Ready:        m.ready,
-		NeedsUpdate:  m.needsUpdate,
+		ErrorMessage:     m.errorMessage,
+		ErrorReason:      m.errorReason,
+		Index:            m.index,
+		InstanceMissing:  m.instanceMissing,
+		InstanceRunning:  m.instanceRunning,
+		MachineAPIPaused: m.machineAPIPaused,
+		MissingTags:      m.missingTags,
+		Ready:            m.ready,
+		NeedsUpdate:      m.needsUpdate,
+
+		MisplacedFailureDomain: m.misplaced,
+		OutsideFailureDomains:  m.outside,
+		FailureDomain:          m.failureDomain,
+
+		UnmanagedFields:    m.unmanagedFields,
+		NodeTopologyLabels: m.nodeTopologyLabels,
+
+		InstanceType:        m.instanceType,
+		DesiredInstanceType: m.desiredInstanceType,
+		ProviderID:          m.providerID,
+		TemplateHash:        m.templateHash,
+		DesiredTemplateHash: m.desiredTemplateHash,
 	}
 
 	if m.machineName != "" {
 		info.MachineRef = &machineproviders.ObjectRef{
 			GroupVersionResource: m.machineGVR,
 			ObjectMeta: metav1.ObjectMeta{
+				Annotations:       m.machineAnnotations,
+				CreationTimestamp: m.machineCreationTimestamp,
 				DeletionTimestamp: m.machineDeletiontimestamp,
+				Finalizers:        m.machineFinalizers,
 				Labels:            m.machineLabels,
 				Name:              m.machineName,
 				Namespace:         m.machineNamespace,
@@ -82,12 +123,30 @@ func (m MachineInfoBuilder) Build() machineproviders.MachineInfo {
 	return info
 }
 
+// WithMachineAnnotations sets the machine annotations for the machineinfo builder.
+func (m MachineInfoBuilder) WithMachineAnnotations(annotations map[string]string) MachineInfoBuilder {
+	m.machineAnnotations = annotations
+	return m
+}
+
+// WithMachineCreationTimestamp sets the machine creation timestamp for the machineinfo builder.
+func (m MachineInfoBuilder) WithMachineCreationTimestamp(creation metav1.Time) MachineInfoBuilder {
+	m.machineCreationTimestamp = creation
+	return m
+}
+
 // WithMachineDeletionTimestamp sets the machine deletion timestamp for the machineinfo builder.
 func (m MachineInfoBuilder) WithMachineDeletionTimestamp(deletion metav1.Time) MachineInfoBuilder {
 	m.machineDeletiontimestamp = &deletion
 	return m
 }
 
+// WithMachineFinalizers sets the machine finalizers for the machineinfo builder.
+func (m MachineInfoBuilder) WithMachineFinalizers(finalizers ...string) MachineInfoBuilder {
+	m.machineFinalizers = finalizers
+	return m
+}
+
 // WithMachineGVR sets the machine groupversionresource for the machineinfo builder.
 func (m MachineInfoBuilder) WithMachineGVR(gvr schema.GroupVersionResource) MachineInfoBuilder {
 	m.machineGVR = gvr
@@ -142,20 +201,112 @@ func (m MachineInfoBuilder) WithErrorMessage(errorMsg string) MachineInfoBuilder
 	return m
 }
 
+// WithErrorReason sets the error reason for the machineinfo builder.
+func (m MachineInfoBuilder) WithErrorReason(errorReason string) MachineInfoBuilder {
+	m.errorReason = errorReason
+	return m
+}
+
+// WithFailureDomain sets the failure domain for the machineinfo builder.
+func (m MachineInfoBuilder) WithFailureDomain(failureDomain string) MachineInfoBuilder {
+	m.failureDomain = failureDomain
+	return m
+}
+
+// WithInstanceType sets the instance type for the machineinfo builder.
+func (m MachineInfoBuilder) WithInstanceType(instanceType string) MachineInfoBuilder {
+	m.instanceType = instanceType
+	return m
+}
+
+// WithDesiredInstanceType sets the desired instance type for the machineinfo builder.
+func (m MachineInfoBuilder) WithDesiredInstanceType(instanceType string) MachineInfoBuilder {
+	m.desiredInstanceType = instanceType
+	return m
+}
+
+// WithProviderID sets the provider ID for the machineinfo builder.
+func (m MachineInfoBuilder) WithProviderID(providerID string) MachineInfoBuilder {
+	m.providerID = providerID
+	return m
+}
+
+// WithTemplateHash sets the template hash for the machineinfo builder.
+func (m MachineInfoBuilder) WithTemplateHash(hash string) MachineInfoBuilder {
+	m.templateHash = hash
+	return m
+}
+
+// WithDesiredTemplateHash sets the desired template hash for the machineinfo builder.
+func (m MachineInfoBuilder) WithDesiredTemplateHash(hash string) MachineInfoBuilder {
+	m.desiredTemplateHash = hash
+	return m
+}
+
 // WithIndex sets the index for the machineinfo builder.
 func (m MachineInfoBuilder) WithIndex(index int32) MachineInfoBuilder {
 	m.index = index
 	return m
 }
 
+// WithInstanceMissing sets the instance missing for the machineinfo builder.
+func (m MachineInfoBuilder) WithInstanceMissing(instanceMissing bool) MachineInfoBuilder {
+	m.instanceMissing = instanceMissing
+	return m
+}
+
+// WithInstanceRunning sets the instance running for the machineinfo builder.
+func (m MachineInfoBuilder) WithInstanceRunning(instanceRunning bool) MachineInfoBuilder {
+	m.instanceRunning = instanceRunning
+	return m
+}
+
+// WithMachineAPIPaused sets whether the Machine API has paused the machine for the machineinfo builder.
+func (m MachineInfoBuilder) WithMachineAPIPaused(paused bool) MachineInfoBuilder {
+	m.machineAPIPaused = paused
+	return m
+}
+
+// WithMisplacedFailureDomain sets whether the machine unbalances the spread of the failure domains for the
+// machineinfo builder.
+func (m MachineInfoBuilder) WithMisplacedFailureDomain(misplaced bool) MachineInfoBuilder {
+	m.misplaced = misplaced
+	return m
+}
+
+// WithMissingTags sets the names of the required tags missing from the machine for the machineinfo builder.
+func (m MachineInfoBuilder) WithMissingTags(missingTags []string) MachineInfoBuilder {
+	m.missingTags = missingTags
+	return m
+}
+
 // WithNeedsUpdate sets the needsupdate for the machineinfo builder.
 func (m MachineInfoBuilder) WithNeedsUpdate(needsUpdate bool) MachineInfoBuilder {
 	m.needsUpdate = needsUpdate
 	return m
 }
 
+// WithOutsideFailureDomains sets whether the machine is outside every configured failure domain for the
+// machineinfo builder.
+func (m MachineInfoBuilder) WithOutsideFailureDomains(outside bool) MachineInfoBuilder {
+	m.outside = outside
+	return m
+}
+
 // WithReady sets the ready for the machineinfo builder.
 func (m MachineInfoBuilder) WithReady(ready bool) MachineInfoBuilder {
 	m.ready = ready
 	return m
 }
+
+// WithNodeTopologyLabels sets the node topology labels for the machineinfo builder.
+func (m MachineInfoBuilder) WithNodeTopologyLabels(labels map[string]string) MachineInfoBuilder {
+	m.nodeTopologyLabels = labels
+	return m
+}
+
+// WithUnmanagedFields sets the unmanaged fields for the machineinfo builder.
+func (m MachineInfoBuilder) WithUnmanagedFields(fields ...string) MachineInfoBuilder {
+	m.unmanagedFields = fields
+	return m
+}
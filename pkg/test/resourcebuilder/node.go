@@ -69,11 +69,13 @@ func (m NodeBuilder) WithGenerateName(generateName string) NodeBuilder {
 
 // WithLabel sets the labels for the node builder.
 func (m NodeBuilder) WithLabel(key, value string) NodeBuilder {
-	if m.labels == nil {
-		m.labels = make(map[string]string)
+	labels := make(map[string]string)
+	for k, v := range m.labels {
+		labels[k] = v
 	}
 
-	m.labels[key] = value
+	labels[key] = value
+	m.labels = labels
 
 	return m
 }
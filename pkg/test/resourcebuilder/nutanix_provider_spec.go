@@ -0,0 +1,117 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resourcebuilder
+
+import (
+	"encoding/json"
+
+	machinev1 "github.com/openshift/api/machine/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/utils/pointer"
+)
+
+// NutanixProviderSpec creates a new Nutanix machine config builder.
+func NutanixProviderSpec() NutanixProviderSpecBuilder {
+	return NutanixProviderSpecBuilder{
+		clusterName: "nutanix-prism-element-1",
+		memorySize:  resource.MustParse("16Gi"),
+		subnetName:  "nutanix-subnet-1",
+		vcpuSockets: 4,
+	}
+}
+
+// NutanixProviderSpecBuilder is used to build out a Nutanix machine config object.
+type NutanixProviderSpecBuilder struct {
+	clusterName string
+	memorySize  resource.Quantity
+	subnetName  string
+	vcpuSockets int32
+}
+
+// Build builds a new Nutanix machine config based on the configuration provided.
+func (m NutanixProviderSpecBuilder) Build() *machinev1.NutanixMachineProviderConfig {
+	return &machinev1.NutanixMachineProviderConfig{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "machine.openshift.io/v1",
+			Kind:       "NutanixMachineProviderConfig",
+		},
+		Cluster: machinev1.NutanixResourceIdentifier{
+			Type: machinev1.NutanixIdentifierName,
+			Name: pointer.String(m.clusterName),
+		},
+		CredentialsSecret: &corev1.LocalObjectReference{
+			Name: "nutanix-credentials",
+		},
+		Image: machinev1.NutanixResourceIdentifier{
+			Type: machinev1.NutanixIdentifierName,
+			Name: pointer.String("rhcos-image-12345678"),
+		},
+		MemorySize: m.memorySize,
+		Subnet: machinev1.NutanixResourceIdentifier{
+			Type: machinev1.NutanixIdentifierName,
+			Name: pointer.String(m.subnetName),
+		},
+		SystemDiskSize: resource.MustParse("120Gi"),
+		UserDataSecret: &corev1.LocalObjectReference{
+			Name: "master-user-data",
+		},
+		VCPUSockets:    m.vcpuSockets,
+		VCPUsPerSocket: 1,
+	}
+}
+
+// BuildRawExtension builds a new Nutanix machine config based on the configuration provided.
+func (m NutanixProviderSpecBuilder) BuildRawExtension() *runtime.RawExtension {
+	providerConfig := m.Build()
+
+	raw, err := json.Marshal(providerConfig)
+	if err != nil {
+		// As we are building the input to json.Marshal, this should never happen.
+		panic(err)
+	}
+
+	return &runtime.RawExtension{
+		Raw: raw,
+	}
+}
+
+// WithClusterName sets the name of the Prism Element (cluster) for the Nutanix machine config builder.
+func (m NutanixProviderSpecBuilder) WithClusterName(clusterName string) NutanixProviderSpecBuilder {
+	m.clusterName = clusterName
+	return m
+}
+
+// WithMemorySize sets the memory size for the Nutanix machine config builder.
+func (m NutanixProviderSpecBuilder) WithMemorySize(memorySize resource.Quantity) NutanixProviderSpecBuilder {
+	m.memorySize = memorySize
+	return m
+}
+
+// WithSubnetName sets the name of the subnet for the Nutanix machine config builder.
+func (m NutanixProviderSpecBuilder) WithSubnetName(subnetName string) NutanixProviderSpecBuilder {
+	m.subnetName = subnetName
+	return m
+}
+
+// WithVCPUSockets sets the number of vCPU sockets for the Nutanix machine config builder.
+func (m NutanixProviderSpecBuilder) WithVCPUSockets(vcpuSockets int32) NutanixProviderSpecBuilder {
+	m.vcpuSockets = vcpuSockets
+	return m
+}
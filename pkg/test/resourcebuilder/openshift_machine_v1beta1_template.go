@@ -35,6 +35,7 @@ func OpenShiftMachineV1Beta1Template() OpenShiftMachineV1Beta1TemplateBuilder {
 
 // OpenShiftMachineV1Beta1TemplateBuilder is used to build out an OpenShift machine template.
 type OpenShiftMachineV1Beta1TemplateBuilder struct {
+	annotations           map[string]string
 	failureDomainsBuilder OpenShiftMachineV1Beta1FailureDomainsBuilder
 	labels                map[string]string
 	providerSpecBuilder   RawExtensionBuilder
@@ -46,7 +47,8 @@ func (m OpenShiftMachineV1Beta1TemplateBuilder) BuildTemplate() machinev1.Contro
 		MachineType: machinev1.OpenShiftMachineV1Beta1MachineType,
 		OpenShiftMachineV1Beta1Machine: &machinev1.OpenShiftMachineV1Beta1MachineTemplate{
 			ObjectMeta: machinev1.ControlPlaneMachineSetTemplateObjectMeta{
-				Labels: m.labels,
+				Annotations: m.annotations,
+				Labels:      m.labels,
 			},
 		},
 	}
@@ -62,6 +64,12 @@ func (m OpenShiftMachineV1Beta1TemplateBuilder) BuildTemplate() machinev1.Contro
 	return template
 }
 
+// WithAnnotations sets the annotations for the machine template builder.
+func (m OpenShiftMachineV1Beta1TemplateBuilder) WithAnnotations(annotations map[string]string) OpenShiftMachineV1Beta1TemplateBuilder {
+	m.annotations = annotations
+	return m
+}
+
 // WithFailureDomainsBuilder sets the failure domains builder for the machine template builder.
 func (m OpenShiftMachineV1Beta1TemplateBuilder) WithFailureDomainsBuilder(fdsBuilder OpenShiftMachineV1Beta1FailureDomainsBuilder) OpenShiftMachineV1Beta1TemplateBuilder {
 	m.failureDomainsBuilder = fdsBuilder
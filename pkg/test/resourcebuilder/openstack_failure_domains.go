@@ -0,0 +1,81 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resourcebuilder
+
+import (
+	configv1 "github.com/openshift/api/config/v1"
+	machinev1 "github.com/openshift/api/machine/v1"
+)
+
+// OpenStackFailureDomains creates a new failure domains builder for OpenStack.
+func OpenStackFailureDomains() OpenStackFailureDomainsBuilder {
+	return OpenStackFailureDomainsBuilder{
+		failureDomainsBuilders: []OpenStackFailureDomainBuilder{
+			OpenStackFailureDomain().WithAvailabilityZone("nova-az1"),
+			OpenStackFailureDomain().WithAvailabilityZone("nova-az2"),
+			OpenStackFailureDomain().WithAvailabilityZone("nova-az3"),
+		},
+	}
+}
+
+// OpenStackFailureDomainsBuilder is used to build a failuredomains.
+type OpenStackFailureDomainsBuilder struct {
+	failureDomainsBuilders []OpenStackFailureDomainBuilder
+}
+
+// BuildFailureDomains builds a failuredomains from the configuration.
+func (o OpenStackFailureDomainsBuilder) BuildFailureDomains() machinev1.FailureDomains {
+	fds := machinev1.FailureDomains{
+		Platform:  configv1.OpenStackPlatformType,
+		OpenStack: &[]machinev1.OpenStackFailureDomain{},
+	}
+
+	for _, builder := range o.failureDomainsBuilders {
+		*fds.OpenStack = append(*fds.OpenStack, builder.Build())
+	}
+
+	return fds
+}
+
+// WithFailureDomainBuilders replaces the failure domains builder's builders with the given builders.
+func (o OpenStackFailureDomainsBuilder) WithFailureDomainBuilders(fdBuilders ...OpenStackFailureDomainBuilder) OpenStackFailureDomainsBuilder {
+	o.failureDomainsBuilders = fdBuilders
+	return o
+}
+
+// OpenStackFailureDomain creates a new failure domain builder for OpenStack.
+func OpenStackFailureDomain() OpenStackFailureDomainBuilder {
+	return OpenStackFailureDomainBuilder{}
+}
+
+// OpenStackFailureDomainBuilder is used to build an OpenStack failuredomain.
+type OpenStackFailureDomainBuilder struct {
+	availabilityZone string
+}
+
+// Build builds an OpenStack failuredomain from the configuration.
+func (o OpenStackFailureDomainBuilder) Build() machinev1.OpenStackFailureDomain {
+	return machinev1.OpenStackFailureDomain{
+		AvailabilityZone: o.availabilityZone,
+	}
+}
+
+// WithAvailabilityZone sets the availability zone for the OpenStack failuredomain builder.
+func (o OpenStackFailureDomainBuilder) WithAvailabilityZone(zone string) OpenStackFailureDomainBuilder {
+	o.availabilityZone = zone
+	return o
+}
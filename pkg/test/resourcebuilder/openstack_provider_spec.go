@@ -0,0 +1,125 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resourcebuilder
+
+import (
+	"encoding/json"
+
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// OpenStackProviderSpec creates a new OpenStack machine config builder.
+// The OpenStack provider spec is not part of the vendored Machine API, so the machine config is built as raw JSON.
+func OpenStackProviderSpec() OpenStackProviderSpecBuilder {
+	return OpenStackProviderSpecBuilder{
+		apiVersion:                 "machine.openshift.io/v1alpha1",
+		availabilityZone:           "nova-az1",
+		flavor:                     "m1.xlarge",
+		rootVolume:                 true,
+		rootVolumeAvailabilityZone: "cinder-az1",
+	}
+}
+
+// OpenStackProviderSpecBuilder is used to build out an OpenStack machine config object.
+type OpenStackProviderSpecBuilder struct {
+	apiVersion                 string
+	availabilityZone           string
+	flavor                     string
+	rootVolume                 bool
+	rootVolumeAvailabilityZone string
+}
+
+// Build builds a new OpenStack machine config based on the configuration provided.
+func (m OpenStackProviderSpecBuilder) Build() map[string]interface{} {
+	spec := map[string]interface{}{
+		"apiVersion": m.apiVersion,
+		"kind":       "OpenstackProviderSpec",
+		"cloudName":  "openstack",
+		"cloudsSecret": map[string]interface{}{
+			"name":      "openstack-cloud-credentials",
+			"namespace": "openshift-machine-api",
+		},
+		"flavor": m.flavor,
+		"image":  "rhcos-4.12",
+		"userDataSecret": map[string]interface{}{
+			"name": "master-user-data",
+		},
+	}
+
+	if m.availabilityZone != "" {
+		spec["availabilityZone"] = m.availabilityZone
+	}
+
+	if m.rootVolume {
+		rootVolume := map[string]interface{}{
+			"diskSize":   int64(100),
+			"volumeType": "performance",
+		}
+
+		if m.rootVolumeAvailabilityZone != "" {
+			rootVolume["availabilityZone"] = m.rootVolumeAvailabilityZone
+		}
+
+		spec["rootVolume"] = rootVolume
+	}
+
+	return spec
+}
+
+// BuildRawExtension builds a new OpenStack machine config based on the configuration provided.
+func (m OpenStackProviderSpecBuilder) BuildRawExtension() *runtime.RawExtension {
+	raw, err := json.Marshal(m.Build())
+	if err != nil {
+		// As we are building the input to json.Marshal, this should never happen.
+		panic(err)
+	}
+
+	return &runtime.RawExtension{
+		Raw: raw,
+	}
+}
+
+// WithAPIVersion sets the API version for the OpenStack machine config builder.
+func (m OpenStackProviderSpecBuilder) WithAPIVersion(apiVersion string) OpenStackProviderSpecBuilder {
+	m.apiVersion = apiVersion
+	return m
+}
+
+// WithAvailabilityZone sets the Nova availability zone for the OpenStack machine config builder.
+func (m OpenStackProviderSpecBuilder) WithAvailabilityZone(zone string) OpenStackProviderSpecBuilder {
+	m.availabilityZone = zone
+	return m
+}
+
+// WithFlavor sets the flavor for the OpenStack machine config builder.
+func (m OpenStackProviderSpecBuilder) WithFlavor(flavor string) OpenStackProviderSpecBuilder {
+	m.flavor = flavor
+	return m
+}
+
+// WithRootVolume sets whether the OpenStack machine config builder boots from a root volume.
+func (m OpenStackProviderSpecBuilder) WithRootVolume(rootVolume bool) OpenStackProviderSpecBuilder {
+	m.rootVolume = rootVolume
+	return m
+}
+
+// WithRootVolumeAvailabilityZone sets the Cinder availability zone of the root volume for the OpenStack machine
+// config builder.
+func (m OpenStackProviderSpecBuilder) WithRootVolumeAvailabilityZone(zone string) OpenStackProviderSpecBuilder {
+	m.rootVolumeAvailabilityZone = zone
+	return m
+}
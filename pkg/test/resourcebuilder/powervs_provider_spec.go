@@ -0,0 +1,103 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resourcebuilder
+
+import (
+	"encoding/json"
+
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// PowerVSProviderSpec creates a new Power VS machine config builder.
+// The Power VS provider spec is not part of the vendored Machine API, so the machine config is built as raw JSON.
+func PowerVSProviderSpec() PowerVSProviderSpecBuilder {
+	return PowerVSProviderSpecBuilder{
+		memoryGiB:  32,
+		processors: "0.5",
+		systemType: "s922",
+	}
+}
+
+// PowerVSProviderSpecBuilder is used to build out a Power VS machine config object.
+type PowerVSProviderSpecBuilder struct {
+	memoryGiB  int64
+	processors interface{}
+	systemType string
+}
+
+// Build builds a new Power VS machine config based on the configuration provided.
+func (m PowerVSProviderSpecBuilder) Build() map[string]interface{} {
+	return map[string]interface{}{
+		"apiVersion": "machine.openshift.io/v1",
+		"kind":       "PowerVSMachineProviderConfig",
+		"credentialsSecret": map[string]interface{}{
+			"name": "powervs-credentials",
+		},
+		"image": map[string]interface{}{
+			"type": "Name",
+			"name": "rhcos-4-12",
+		},
+		"keyPairName": "cluster-key",
+		"memoryGiB":   m.memoryGiB,
+		"network": map[string]interface{}{
+			"type":  "RegEx",
+			"regex": "^DHCPSERVER.*Private$",
+		},
+		"processorType": "Shared",
+		"processors":    m.processors,
+		"serviceInstance": map[string]interface{}{
+			"type": "ID",
+			"id":   "00000000-0000-0000-0000-000000000001",
+		},
+		"systemType": m.systemType,
+		"userDataSecret": map[string]interface{}{
+			"name": "master-user-data",
+		},
+	}
+}
+
+// BuildRawExtension builds a new Power VS machine config based on the configuration provided.
+func (m PowerVSProviderSpecBuilder) BuildRawExtension() *runtime.RawExtension {
+	raw, err := json.Marshal(m.Build())
+	if err != nil {
+		// As we are building the input to json.Marshal, this should never happen.
+		panic(err)
+	}
+
+	return &runtime.RawExtension{
+		Raw: raw,
+	}
+}
+
+// WithMemoryGiB sets the memory, in GiB, for the Power VS machine config builder.
+func (m PowerVSProviderSpecBuilder) WithMemoryGiB(memoryGiB int64) PowerVSProviderSpecBuilder {
+	m.memoryGiB = memoryGiB
+	return m
+}
+
+// WithProcessors sets the number of processors for the Power VS machine config builder.
+// The processors may be a number or a string, as within the Power VS provider spec.
+func (m PowerVSProviderSpecBuilder) WithProcessors(processors interface{}) PowerVSProviderSpecBuilder {
+	m.processors = processors
+	return m
+}
+
+// WithSystemType sets the system type for the Power VS machine config builder.
+func (m PowerVSProviderSpecBuilder) WithSystemType(systemType string) PowerVSProviderSpecBuilder {
+	m.systemType = systemType
+	return m
+}
@@ -0,0 +1,135 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resourcebuilder
+
+import (
+	"encoding/json"
+
+	machinev1beta1 "github.com/openshift/api/machine/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// VSphereProviderSpec creates a new vSphere machine config builder.
+func VSphereProviderSpec() VSphereProviderSpecBuilder {
+	return VSphereProviderSpecBuilder{
+		apiVersion:   "machine.openshift.io/v1beta1",
+		memoryMiB:    16384,
+		networkNames: []string{"vsphere-network-12345678"},
+		numCPUs:      4,
+		template:     "rhcos-template-12345678",
+		workspace: machinev1beta1.Workspace{
+			Datacenter:   "vsphere-datacenter",
+			Datastore:    "vsphere-datastore",
+			Folder:       "/vsphere-datacenter/vm/cluster-id",
+			ResourcePool: "/vsphere-datacenter/host/vsphere-cluster/Resources",
+			Server:       "vcenter.example.com",
+		},
+	}
+}
+
+// VSphereProviderSpecBuilder is used to build out a vSphere machine config object.
+type VSphereProviderSpecBuilder struct {
+	apiVersion   string
+	memoryMiB    int64
+	networkNames []string
+	numCPUs      int32
+	template     string
+	workspace    machinev1beta1.Workspace
+}
+
+// Build builds a new vSphere machine config based on the configuration provided.
+func (m VSphereProviderSpecBuilder) Build() *machinev1beta1.VSphereMachineProviderSpec {
+	devices := []machinev1beta1.NetworkDeviceSpec{}
+	for _, networkName := range m.networkNames {
+		devices = append(devices, machinev1beta1.NetworkDeviceSpec{NetworkName: networkName})
+	}
+
+	return &machinev1beta1.VSphereMachineProviderSpec{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: m.apiVersion,
+			Kind:       "VSphereMachineProviderSpec",
+		},
+		CredentialsSecret: &corev1.LocalObjectReference{
+			Name: "vsphere-cloud-credentials",
+		},
+		DiskGiB:   120,
+		MemoryMiB: m.memoryMiB,
+		Network: machinev1beta1.NetworkSpec{
+			Devices: devices,
+		},
+		NumCPUs:           m.numCPUs,
+		NumCoresPerSocket: 2,
+		Template:          m.template,
+		UserDataSecret: &corev1.LocalObjectReference{
+			Name: "vsphere-user-data-12345678",
+		},
+		Workspace: m.workspace.DeepCopy(),
+	}
+}
+
+// BuildRawExtension builds a new vSphere machine config based on the configuration provided.
+func (m VSphereProviderSpecBuilder) BuildRawExtension() *runtime.RawExtension {
+	providerConfig := m.Build()
+
+	raw, err := json.Marshal(providerConfig)
+	if err != nil {
+		// As we are building the input to json.Marshal, this should never happen.
+		panic(err)
+	}
+
+	return &runtime.RawExtension{
+		Raw: raw,
+	}
+}
+
+// WithAPIVersion sets the apiVersion for the vSphere machine config builder.
+func (m VSphereProviderSpecBuilder) WithAPIVersion(apiVersion string) VSphereProviderSpecBuilder {
+	m.apiVersion = apiVersion
+	return m
+}
+
+// WithMemoryMiB sets the memory, in MiB, for the vSphere machine config builder.
+func (m VSphereProviderSpecBuilder) WithMemoryMiB(memoryMiB int64) VSphereProviderSpecBuilder {
+	m.memoryMiB = memoryMiB
+	return m
+}
+
+// WithNetworkNames sets the networks of the network devices, in order, for the vSphere machine config builder.
+func (m VSphereProviderSpecBuilder) WithNetworkNames(networkNames ...string) VSphereProviderSpecBuilder {
+	m.networkNames = networkNames
+	return m
+}
+
+// WithNumCPUs sets the number of vCPUs for the vSphere machine config builder.
+func (m VSphereProviderSpecBuilder) WithNumCPUs(numCPUs int32) VSphereProviderSpecBuilder {
+	m.numCPUs = numCPUs
+	return m
+}
+
+// WithTemplate sets the clone template for the vSphere machine config builder.
+func (m VSphereProviderSpecBuilder) WithTemplate(template string) VSphereProviderSpecBuilder {
+	m.template = template
+	return m
+}
+
+// WithWorkspace sets the workspace for the vSphere machine config builder.
+func (m VSphereProviderSpecBuilder) WithWorkspace(workspace machinev1beta1.Workspace) VSphereProviderSpecBuilder {
+	m.workspace = workspace
+	return m
+}
@@ -0,0 +1,208 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controlplanemachineset
+
+import (
+	"fmt"
+	"strings"
+
+	configv1 "github.com/openshift/api/config/v1"
+	machinev1 "github.com/openshift/api/machine/v1"
+	machinev1beta1 "github.com/openshift/api/machine/v1beta1"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders/providers/openshift/machine/v1beta1/failuredomain"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders/providers/openshift/machine/v1beta1/providerconfig"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+const (
+	// edgeZoneSubnetRequiredFormat is the format of the error returned when a Local Zone or Wavelength Zone is used
+	// without a subnet. Edge zones have no default subnet, and the subnet of the template is within another zone.
+	edgeZoneSubnetRequiredFormat = "a subnet within %s %s must be referenced, as edge zones have no default subnet"
+
+	// wavelengthPublicIPMessage is the error returned when a template within a Wavelength Zone requests a public IP
+	// address. Wavelength Zones assign carrier IP addresses through the carrier gateway instead.
+	wavelengthPublicIPMessage = "public IP addresses cannot be assigned within Wavelength Zones, which assign carrier IP addresses through the carrier gateway instead"
+
+	// edgeZoneMixedWarning is the warning returned when the failure domains span both the Availability Zones of the
+	// region and edge zones.
+	edgeZoneMixedWarning = "spec.template.machines_v1beta1_machine_openshift_io.failureDomains: failure domains span both Availability Zones and Local Zones or Wavelength Zones, the latency between the zones may exceed the latency tolerated by etcd"
+
+	// edgeZoneInstanceTypeWarningFormat is the format of the warning returned when the instance type of the template
+	// is not typically offered within a type of edge zone.
+	edgeZoneInstanceTypeWarningFormat = "%s: instance type %s is not typically offered within %ss, which offer %s"
+
+	// wavelengthVolumeTypeWarningFormat is the format of the warning returned when a block device uses a volume type
+	// that Wavelength Zones do not offer.
+	wavelengthVolumeTypeWarningFormat = "%s: volume type %s is not offered within Wavelength Zones, which only offer gp2 volumes"
+
+	// wavelengthVolumeType is the only EBS volume type offered within Wavelength Zones.
+	wavelengthVolumeType = "gp2"
+)
+
+var (
+	// edgeZoneDescriptions describes each type of edge zone within errors and warnings.
+	edgeZoneDescriptions = map[failuredomain.AWSZoneType]string{
+		failuredomain.AWSLocalZoneType:      "Local Zone",
+		failuredomain.AWSWavelengthZoneType: "Wavelength Zone",
+	}
+
+	// localZoneInstanceFamilies are the instance families typically offered within Local Zones, in order. The families
+	// offered vary by Local Zone, so instance types of other families are warned about rather than rejected.
+	localZoneInstanceFamilies = []string{"c5", "c5d", "c6i", "g4dn", "i3en", "m5", "m5d", "m6i", "r5", "r5d", "r6i", "t3"}
+
+	// wavelengthZoneInstanceTypes are the instance types offered within Wavelength Zones, in order.
+	wavelengthZoneInstanceTypes = []string{"g4dn.2xlarge", "r5.2xlarge", "t3.medium", "t3.xlarge"}
+)
+
+// awsTemplateZone is a zone in which the template places Control Plane Machines.
+type awsTemplateZone struct {
+	// subnetPath is the path of the subnet of the failure domain, or of the provider spec, that sets the zone.
+	subnetPath *field.Path
+
+	// zone is the name of the zone.
+	zone string
+
+	// zoneType is the type of the zone.
+	zoneType failuredomain.AWSZoneType
+
+	// subnet is whether a subnet is referenced for the zone.
+	subnet bool
+}
+
+// awsTemplateZones returns the zones in which the template places Control Plane Machines. These are the zones of the
+// AWS failure domains, or the zone of the provider spec when there are no failure domains.
+func awsTemplateZones(templatePath, providerSpecPath *field.Path, failureDomains machinev1.FailureDomains, config providerconfig.AWSProviderConfig) []awsTemplateZone {
+	providerConfig := config.Config()
+
+	if failureDomains.Platform != configv1.AWSPlatformType || failureDomains.AWS == nil || len(*failureDomains.AWS) == 0 {
+		zone := providerConfig.Placement.AvailabilityZone
+
+		return []awsTemplateZone{{
+			subnetPath: providerSpecPath.Child("subnet"),
+			zone:       zone,
+			zoneType:   failuredomain.AWSZoneTypeOf(zone),
+			subnet:     awsSubnetReferenced(providerConfig.Subnet),
+		}}
+	}
+
+	zones := []awsTemplateZone{}
+	awsPath := templatePath.Child("machines_v1beta1_machine_openshift_io", "failureDomains", "aws")
+
+	for i, fd := range *failureDomains.AWS {
+		zone := fd.Placement.AvailabilityZone
+		if zone == "" {
+			zone = providerConfig.Placement.AvailabilityZone
+		}
+
+		zones = append(zones, awsTemplateZone{
+			subnetPath: awsPath.Index(i).Child("subnet"),
+			zone:       zone,
+			zoneType:   failuredomain.AWSZoneTypeOf(zone),
+			subnet:     fd.Subnet != nil,
+		})
+	}
+
+	return zones
+}
+
+// awsSubnetReferenced returns whether the subnet of a provider spec is referenced by ID, ARN or filters.
+func awsSubnetReferenced(subnet machinev1beta1.AWSResourceReference) bool {
+	return (subnet.ID != nil && *subnet.ID != "") || (subnet.ARN != nil && *subnet.ARN != "") || len(subnet.Filters) > 0
+}
+
+// validateAWSEdgeZones checks the constraints of the AWS Local Zones and Wavelength Zones used by the template.
+// Edge zones have no default subnet, so a subnet must be referenced for each edge zone, and Wavelength Zones cannot
+// assign public IP addresses.
+func validateAWSEdgeZones(templatePath, providerSpecPath *field.Path, failureDomains machinev1.FailureDomains, config providerconfig.AWSProviderConfig) field.ErrorList {
+	var errs field.ErrorList
+
+	wavelength := false
+
+	for _, zone := range awsTemplateZones(templatePath, providerSpecPath, failureDomains, config) {
+		if zone.zoneType == failuredomain.AWSAvailabilityZoneType {
+			continue
+		}
+
+		wavelength = wavelength || zone.zoneType == failuredomain.AWSWavelengthZoneType
+
+		if !zone.subnet {
+			errs = append(errs, field.Required(zone.subnetPath, fmt.Sprintf(edgeZoneSubnetRequiredFormat, edgeZoneDescriptions[zone.zoneType], zone.zone)))
+		}
+	}
+
+	if publicIP := config.Config().PublicIP; wavelength && publicIP != nil && *publicIP {
+		errs = append(errs, field.Forbidden(providerSpecPath.Child("publicIp"), wavelengthPublicIPMessage))
+	}
+
+	return errs
+}
+
+// awsEdgeZoneWarnings returns warnings about templates using AWS Local Zones or Wavelength Zones that are valid, but
+// that are likely to fail to launch, or to destabilise etcd. The instance types and volume types offered within
+// edge zones vary by zone and change over time, so they are warned about rather than rejected.
+// Templates that cannot be parsed, or that are not on AWS, are not checked here.
+func awsEdgeZoneWarnings(templatePath *field.Path, template machinev1.ControlPlaneMachineSetTemplate) []string {
+	if template.OpenShiftMachineV1Beta1Machine == nil {
+		return nil
+	}
+
+	providerConfig, err := providerconfig.NewProviderConfig(*template.OpenShiftMachineV1Beta1Machine)
+	if err != nil || providerConfig.Type() != configv1.AWSPlatformType {
+		return nil
+	}
+
+	providerSpecPath := templatePath.Child("machines_v1beta1_machine_openshift_io", "spec", "providerSpec", "value")
+	config := providerConfig.AWS().Config()
+
+	zoneTypes := map[failuredomain.AWSZoneType]struct{}{}
+	for _, zone := range awsTemplateZones(templatePath, providerSpecPath, template.OpenShiftMachineV1Beta1Machine.FailureDomains, providerConfig.AWS()) {
+		zoneTypes[zone.zoneType] = struct{}{}
+	}
+
+	_, availabilityZone := zoneTypes[failuredomain.AWSAvailabilityZoneType]
+	_, localZone := zoneTypes[failuredomain.AWSLocalZoneType]
+	_, wavelengthZone := zoneTypes[failuredomain.AWSWavelengthZoneType]
+
+	var warnings []string
+
+	if availabilityZone && (localZone || wavelengthZone) {
+		warnings = append(warnings, edgeZoneMixedWarning)
+	}
+
+	instanceTypePath := providerSpecPath.Child("instanceType").String()
+
+	if localZone && config.InstanceType != "" && !containsString(localZoneInstanceFamilies, strings.SplitN(config.InstanceType, ".", 2)[0]) {
+		warnings = append(warnings, fmt.Sprintf(edgeZoneInstanceTypeWarningFormat, instanceTypePath, config.InstanceType, edgeZoneDescriptions[failuredomain.AWSLocalZoneType], "the "+strings.Join(localZoneInstanceFamilies, ", ")+" instance families"))
+	}
+
+	if wavelengthZone && config.InstanceType != "" && !containsString(wavelengthZoneInstanceTypes, config.InstanceType) {
+		warnings = append(warnings, fmt.Sprintf(edgeZoneInstanceTypeWarningFormat, instanceTypePath, config.InstanceType, edgeZoneDescriptions[failuredomain.AWSWavelengthZoneType], "the "+strings.Join(wavelengthZoneInstanceTypes, ", ")+" instance types"))
+	}
+
+	if wavelengthZone {
+		for i, device := range config.BlockDevices {
+			if device.EBS == nil || device.EBS.VolumeType == nil || *device.EBS.VolumeType == "" || *device.EBS.VolumeType == wavelengthVolumeType {
+				continue
+			}
+
+			volumeTypePath := providerSpecPath.Child("blockDevices").Index(i).Child("ebs", "volumeType").String()
+			warnings = append(warnings, fmt.Sprintf(wavelengthVolumeTypeWarningFormat, volumeTypePath, *device.EBS.VolumeType))
+		}
+	}
+
+	return warnings
+}
@@ -0,0 +1,144 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controlplanemachineset
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	machinev1 "github.com/openshift/api/machine/v1"
+	machinev1beta1 "github.com/openshift/api/machine/v1beta1"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders/providers/openshift/machine/v1beta1/providerconfig"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/test/resourcebuilder"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/utils/pointer"
+)
+
+var _ = Describe("AWS edge zones", func() {
+	const providerSpecPath = "spec.template.machines_v1beta1_machine_openshift_io.spec.providerSpec.value"
+	const failureDomainsPath = "spec.template.machines_v1beta1_machine_openshift_io.failureDomains.aws"
+	const localZone = "us-east-1-bos-1a"
+	const wavelengthZone = "us-east-1-wl1-bos-wlz-1"
+
+	templatePath := field.NewPath("spec", "template")
+
+	subnet := machinev1.AWSResourceReference{Type: machinev1.AWSIDReferenceType, ID: pointer.String("subnet-edge")}
+
+	failureDomain := func(zone string, subnet *machinev1.AWSResourceReference) resourcebuilder.AWSFailureDomainBuilder {
+		fd := resourcebuilder.AWSFailureDomain().WithAvailabilityZone(zone)
+		if subnet != nil {
+			fd = fd.WithSubnet(*subnet)
+		}
+
+		return fd
+	}
+
+	templateFor := func(providerSpec resourcebuilder.AWSProviderSpecBuilder, fds ...resourcebuilder.AWSFailureDomainBuilder) machinev1.ControlPlaneMachineSetTemplate {
+		machineTemplate := resourcebuilder.OpenShiftMachineV1Beta1Template().WithProviderSpecBuilder(providerSpec)
+		if len(fds) > 0 {
+			machineTemplate = machineTemplate.WithFailureDomainsBuilder(resourcebuilder.AWSFailureDomains().WithFailureDomainBuilders(fds...))
+		} else {
+			machineTemplate = machineTemplate.WithFailureDomainsBuilder(nil)
+		}
+
+		return resourcebuilder.ControlPlaneMachineSet().WithMachineTemplateBuilder(machineTemplate).Build().Spec.Template
+	}
+
+	Context("validateAWSEdgeZones", func() {
+		validate := func(template machinev1.ControlPlaneMachineSetTemplate) field.ErrorList {
+			providerConfig, err := providerconfig.NewProviderConfig(*template.OpenShiftMachineV1Beta1Machine)
+			Expect(err).ToNot(HaveOccurred())
+
+			return validateAWSEdgeZones(templatePath, field.NewPath(providerSpecPath), template.OpenShiftMachineV1Beta1Machine.FailureDomains, providerConfig.AWS())
+		}
+
+		It("should allow availability zones without a subnet", func() {
+			Expect(validate(templateFor(resourcebuilder.AWSProviderSpec(), failureDomain("us-east-1a", nil)))).To(BeEmpty())
+		})
+
+		It("should allow edge zones that reference a subnet", func() {
+			Expect(validate(templateFor(resourcebuilder.AWSProviderSpec(), failureDomain(localZone, &subnet), failureDomain(wavelengthZone, &subnet)))).To(BeEmpty())
+		})
+
+		It("should require a subnet for each edge zone failure domain", func() {
+			Expect(validate(templateFor(resourcebuilder.AWSProviderSpec(), failureDomain("us-east-1a", nil), failureDomain(localZone, nil), failureDomain(wavelengthZone, nil)))).To(ConsistOf(
+				field.Required(field.NewPath(failureDomainsPath).Index(1).Child("subnet"), "a subnet within Local Zone us-east-1-bos-1a must be referenced, as edge zones have no default subnet"),
+				field.Required(field.NewPath(failureDomainsPath).Index(2).Child("subnet"), "a subnet within Wavelength Zone us-east-1-wl1-bos-wlz-1 must be referenced, as edge zones have no default subnet"),
+			))
+		})
+
+		It("should require a subnet in the provider spec of an edge zone without failure domains", func() {
+			providerSpec := resourcebuilder.AWSProviderSpec().WithAvailabilityZone(localZone).WithSubnet(machinev1beta1.AWSResourceReference{})
+
+			Expect(validate(templateFor(providerSpec))).To(ConsistOf(
+				field.Required(field.NewPath(providerSpecPath, "subnet"), "a subnet within Local Zone us-east-1-bos-1a must be referenced, as edge zones have no default subnet"),
+			))
+		})
+
+		It("should forbid public IP addresses within Wavelength Zones", func() {
+			Expect(validate(templateFor(resourcebuilder.AWSProviderSpec().WithPublicIP(true), failureDomain(wavelengthZone, &subnet)))).To(ConsistOf(
+				field.Forbidden(field.NewPath(providerSpecPath, "publicIp"), wavelengthPublicIPMessage),
+			))
+		})
+
+		It("should allow public IP addresses within Local Zones", func() {
+			Expect(validate(templateFor(resourcebuilder.AWSProviderSpec().WithPublicIP(true), failureDomain(localZone, &subnet)))).To(BeEmpty())
+		})
+	})
+
+	Context("awsEdgeZoneWarnings", func() {
+		gp2 := []machinev1beta1.BlockDeviceMappingSpec{{EBS: &machinev1beta1.EBSBlockDeviceSpec{VolumeType: pointer.String("gp2")}}}
+
+		It("should not warn about availability zones", func() {
+			Expect(awsEdgeZoneWarnings(templatePath, templateFor(resourcebuilder.AWSProviderSpec(), failureDomain("us-east-1a", nil)))).To(BeEmpty())
+		})
+
+		It("should not warn about Local Zones with an offered instance type", func() {
+			providerSpec := resourcebuilder.AWSProviderSpec().WithInstanceType("m5.xlarge")
+
+			Expect(awsEdgeZoneWarnings(templatePath, templateFor(providerSpec, failureDomain(localZone, &subnet)))).To(BeEmpty())
+		})
+
+		It("should warn when the failure domains mix availability zones and edge zones", func() {
+			providerSpec := resourcebuilder.AWSProviderSpec().WithInstanceType("m5.xlarge")
+
+			Expect(awsEdgeZoneWarnings(templatePath, templateFor(providerSpec, failureDomain("us-east-1a", nil), failureDomain(localZone, &subnet)))).To(ConsistOf(edgeZoneMixedWarning))
+		})
+
+		It("should warn about instance types not offered within Local Zones", func() {
+			providerSpec := resourcebuilder.AWSProviderSpec().WithInstanceType("m7g.xlarge")
+
+			Expect(awsEdgeZoneWarnings(templatePath, templateFor(providerSpec, failureDomain(localZone, &subnet)))).To(ConsistOf(
+				providerSpecPath + ".instanceType: instance type m7g.xlarge is not typically offered within Local Zones, which offer the c5, c5d, c6i, g4dn, i3en, m5, m5d, m6i, r5, r5d, r6i, t3 instance families",
+			))
+		})
+
+		It("should warn about instance types and volume types not offered within Wavelength Zones", func() {
+			providerSpec := resourcebuilder.AWSProviderSpec().WithInstanceType("m5.xlarge")
+
+			Expect(awsEdgeZoneWarnings(templatePath, templateFor(providerSpec, failureDomain(wavelengthZone, &subnet)))).To(ConsistOf(
+				providerSpecPath+".instanceType: instance type m5.xlarge is not typically offered within Wavelength Zones, which offer the g4dn.2xlarge, r5.2xlarge, t3.medium, t3.xlarge instance types",
+				providerSpecPath+".blockDevices[0].ebs.volumeType: volume type gp3 is not offered within Wavelength Zones, which only offer gp2 volumes",
+			))
+		})
+
+		It("should not warn about Wavelength Zones with an offered instance type and volume type", func() {
+			providerSpec := resourcebuilder.AWSProviderSpec().WithInstanceType("r5.2xlarge").WithBlockDevices(gp2)
+
+			Expect(awsEdgeZoneWarnings(templatePath, templateFor(providerSpec, failureDomain(wavelengthZone, &subnet)))).To(BeEmpty())
+		})
+	})
+})
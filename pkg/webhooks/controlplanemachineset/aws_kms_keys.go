@@ -0,0 +1,199 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controlplanemachineset
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	configv1 "github.com/openshift/api/config/v1"
+	machinev1 "github.com/openshift/api/machine/v1"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders/providers/openshift/machine/v1beta1/providerconfig"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/apimachinery/pkg/util/yaml"
+)
+
+const (
+	// kmsKeyNotFoundFormat is the format of the error returned when a KMS key used by the template does not exist
+	// within the region of the template.
+	kmsKeyNotFoundFormat = "KMS key %s, used by block device %s, does not exist in region %s"
+
+	// kmsKeyUnencryptedMessage is the error returned when a block device sets a KMS key without being encrypted.
+	// EC2 rejects volumes with a KMS key that are not encrypted, so the Machine would fail once created.
+	kmsKeyUnencryptedMessage = "encrypted must be true when a KMS key is set"
+
+	// kmsKeyFiltersMessage is the error returned when a block device references its KMS key only by filters. The
+	// Machine API does not resolve filters for KMS keys, so the volume would be encrypted with the default key.
+	kmsKeyFiltersMessage = "KMS keys cannot be selected by filters, reference the key by ID or ARN"
+
+	// kmsKeyARNFormat is the format of the error returned when the ARN of a KMS key is not the ARN of a KMS key.
+	kmsKeyARNFormat = "%q is not the ARN of a KMS key"
+)
+
+// AWSKMSKeyCatalog provides the KMS keys that exist within each AWS region, so that templates encrypting their
+// volumes with a key that does not exist can be rejected before any Machine is created.
+type AWSKMSKeyCatalog interface {
+	// KMSKeyExists returns whether the KMS key, referenced by ID, alias or ARN, exists within the region, and
+	// whether the keys of the region are known to the catalog.
+	KMSKeyExists(region, key string) (bool, bool)
+}
+
+// staticAWSKMSKeyCatalog is an AWSKMSKeyCatalog with a fixed set of keys, loaded from a file.
+type staticAWSKMSKeyCatalog struct {
+	// Regions maps each region to the IDs and aliases of the KMS keys within the region.
+	Regions map[string][]string `json:"regions"`
+}
+
+// LoadAWSKMSKeyCatalog loads an AWSKMSKeyCatalog from the YAML or JSON file at the path given.
+// The file specifies the IDs and aliases of the KMS keys within each region, eg:
+//
+//	regions:
+//	  us-east-1:
+//	  - 1234abcd-12ab-34cd-56ef-1234567890ab
+//	  - alias/control-plane
+func LoadAWSKMSKeyCatalog(path string) (AWSKMSKeyCatalog, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read AWS KMS key catalog: %w", err)
+	}
+
+	catalog := &staticAWSKMSKeyCatalog{}
+	if err := yaml.UnmarshalStrict(data, catalog); err != nil {
+		return nil, fmt.Errorf("could not parse AWS KMS key catalog: %w", err)
+	}
+
+	return catalog, nil
+}
+
+// KMSKeyExists returns whether the KMS key exists within the region, and whether the keys of the region are known
+// to the catalog. Keys referenced by ARN are matched by the ID or alias within the ARN, within the region of the
+// ARN.
+func (c *staticAWSKMSKeyCatalog) KMSKeyExists(region, key string) (bool, bool) {
+	if arnRegion, resource, ok := parseKMSKeyARN(key); ok {
+		region, key = arnRegion, resource
+	}
+
+	keys, ok := c.Regions[region]
+	if !ok {
+		return false, false
+	}
+
+	return containsString(keys, key), true
+}
+
+// parseKMSKeyARN returns the region and the resource, eg key/1234abcd-12ab-34cd-56ef-1234567890ab or
+// alias/control-plane, of the ARN of a KMS key, eg
+// arn:aws:kms:us-east-1:111122223333:key/1234abcd-12ab-34cd-56ef-1234567890ab.
+// Key resources are returned as the ID of the key.
+func parseKMSKeyARN(arn string) (string, string, bool) {
+	parts := strings.SplitN(arn, ":", 6)
+	if len(parts) != 6 || parts[0] != "arn" || parts[2] != "kms" || parts[3] == "" {
+		return "", "", false
+	}
+
+	resource := parts[5]
+
+	switch {
+	case strings.HasPrefix(resource, "key/") && len(resource) > len("key/"):
+		return parts[3], strings.TrimPrefix(resource, "key/"), true
+	case strings.HasPrefix(resource, "alias/") && len(resource) > len("alias/"):
+		return parts[3], resource, true
+	default:
+		return "", "", false
+	}
+}
+
+// validateAWSBlockDeviceEncryption checks that each EBS volume of the template that sets a KMS key is encrypted, and
+// references the key by ID or ARN, rather than by filters. EC2 rejects volumes with a KMS key that are not
+// encrypted, and the Machine API ignores filters for KMS keys, so either would only be noticed once a Machine is
+// created during a rollout.
+func validateAWSBlockDeviceEncryption(providerSpecPath *field.Path, config providerconfig.AWSProviderConfig) field.ErrorList {
+	var errs field.ErrorList
+
+	for _, encryption := range config.BlockDeviceEncryption() {
+		ebsPath := providerSpecPath.Child("blockDevices").Index(encryption.Index).Child("ebs")
+		ebs := config.Config().BlockDevices[encryption.Index].EBS
+
+		if encryption.KMSKeyFilters {
+			errs = append(errs, field.Invalid(ebsPath.Child("kmsKey", "filters"), ebs.KMSKey.Filters, kmsKeyFiltersMessage))
+			continue
+		}
+
+		if encryption.KMSKeyID == "" {
+			continue
+		}
+
+		if ebs.KMSKey.ID == nil || *ebs.KMSKey.ID == "" {
+			if _, _, ok := parseKMSKeyARN(encryption.KMSKeyID); !ok {
+				errs = append(errs, field.Invalid(ebsPath.Child("kmsKey", "arn"), encryption.KMSKeyID, fmt.Sprintf(kmsKeyARNFormat, encryption.KMSKeyID)))
+			}
+		}
+
+		switch {
+		case encryption.Encrypted == nil:
+			errs = append(errs, field.Required(ebsPath.Child("encrypted"), kmsKeyUnencryptedMessage))
+		case !*encryption.Encrypted:
+			errs = append(errs, field.Invalid(ebsPath.Child("encrypted"), false, kmsKeyUnencryptedMessage))
+		}
+	}
+
+	return errs
+}
+
+// validateAWSKMSKeys checks that the KMS keys used to encrypt the EBS volumes of the template exist within the
+// region of the template, or within the region of their ARN, according to the AWS KMS key catalog.
+// Regions that are not known to the catalog are not validated, nor are volumes using the default key, or when no
+// catalog is configured.
+func (r *ControlPlaneMachineSetWebhook) validateAWSKMSKeys(templatePath *field.Path, template machinev1.ControlPlaneMachineSetTemplate) field.ErrorList {
+	if r.AWSKMSKeyCatalog == nil || template.OpenShiftMachineV1Beta1Machine == nil {
+		return nil
+	}
+
+	providerConfig, err := providerconfig.NewProviderConfig(*template.OpenShiftMachineV1Beta1Machine)
+	if err != nil || providerConfig.Type() != configv1.AWSPlatformType {
+		return nil
+	}
+
+	providerSpecPath := templatePath.Child("machines_v1beta1_machine_openshift_io", "spec", "providerSpec", "value")
+	config := providerConfig.AWS()
+	region := config.Config().Placement.Region
+
+	var errs field.ErrorList
+
+	for _, encryption := range config.BlockDeviceEncryption() {
+		if encryption.KMSKeyID == "" {
+			continue
+		}
+
+		exists, ok := r.AWSKMSKeyCatalog.KMSKeyExists(region, encryption.KMSKeyID)
+		if !ok || exists {
+			continue
+		}
+
+		keyRegion := region
+		if arnRegion, _, ok := parseKMSKeyARN(encryption.KMSKeyID); ok {
+			keyRegion = arnRegion
+		}
+
+		errs = append(errs, field.Invalid(providerSpecPath.Child("blockDevices").Index(encryption.Index).Child("ebs", "kmsKey"), encryption.KMSKeyID,
+			fmt.Sprintf(kmsKeyNotFoundFormat, encryption.KMSKeyID, encryption.DeviceName, keyRegion),
+		))
+	}
+
+	return errs
+}
@@ -0,0 +1,195 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controlplanemachineset
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	machinev1 "github.com/openshift/api/machine/v1"
+	machinev1beta1 "github.com/openshift/api/machine/v1beta1"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders/providers/openshift/machine/v1beta1/providerconfig"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/test/resourcebuilder"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/utils/pointer"
+)
+
+var _ = Describe("AWS KMS keys", func() {
+	const providerSpecPath = "spec.template.machines_v1beta1_machine_openshift_io.spec.providerSpec.value"
+	const keyID = "1234abcd-12ab-34cd-56ef-1234567890ab"
+	const keyARN = "arn:aws:kms:us-east-1:111122223333:key/" + keyID
+
+	templatePath := field.NewPath("spec", "template")
+
+	writeCatalog := func(content string) string {
+		dir, err := os.MkdirTemp("", "aws-kms-key-catalog-")
+		Expect(err).ToNot(HaveOccurred())
+
+		DeferCleanup(func() {
+			Expect(os.RemoveAll(dir)).To(Succeed())
+		})
+
+		path := filepath.Join(dir, "catalog.yaml")
+		Expect(os.WriteFile(path, []byte(content), 0600)).To(Succeed())
+
+		return path
+	}
+
+	encryptedWith := func(encrypted *bool, kmsKey machinev1beta1.AWSResourceReference) resourcebuilder.AWSProviderSpecBuilder {
+		return resourcebuilder.AWSProviderSpec().WithBlockDevices([]machinev1beta1.BlockDeviceMappingSpec{
+			{EBS: &machinev1beta1.EBSBlockDeviceSpec{Encrypted: encrypted, KMSKey: kmsKey, VolumeSize: pointer.Int64(120)}},
+		})
+	}
+
+	templateFor := func(providerSpec resourcebuilder.AWSProviderSpecBuilder) machinev1.ControlPlaneMachineSetTemplate {
+		return resourcebuilder.ControlPlaneMachineSet().WithMachineTemplateBuilder(
+			resourcebuilder.OpenShiftMachineV1Beta1Template().WithProviderSpecBuilder(providerSpec),
+		).Build().Spec.Template
+	}
+
+	Context("LoadAWSKMSKeyCatalog", func() {
+		It("should load the keys from the file", func() {
+			catalog, err := LoadAWSKMSKeyCatalog(writeCatalog("regions:\n  us-east-1:\n  - " + keyID + "\n  - alias/control-plane\n"))
+			Expect(err).ToNot(HaveOccurred())
+
+			exists, ok := catalog.KMSKeyExists("us-east-1", keyID)
+			Expect(ok).To(BeTrue())
+			Expect(exists).To(BeTrue())
+
+			exists, ok = catalog.KMSKeyExists("us-east-1", "alias/other")
+			Expect(ok).To(BeTrue())
+			Expect(exists).To(BeFalse())
+
+			_, ok = catalog.KMSKeyExists("us-west-2", keyID)
+			Expect(ok).To(BeFalse())
+		})
+
+		It("should match keys referenced by ARN within the region of the ARN", func() {
+			catalog, err := LoadAWSKMSKeyCatalog(writeCatalog("regions:\n  us-east-1:\n  - " + keyID + "\n  - alias/control-plane\n"))
+			Expect(err).ToNot(HaveOccurred())
+
+			exists, ok := catalog.KMSKeyExists("us-west-2", keyARN)
+			Expect(ok).To(BeTrue())
+			Expect(exists).To(BeTrue())
+
+			exists, ok = catalog.KMSKeyExists("us-east-1", "arn:aws:kms:us-east-1:111122223333:alias/control-plane")
+			Expect(ok).To(BeTrue())
+			Expect(exists).To(BeTrue())
+		})
+
+		It("should reject unknown fields", func() {
+			_, err := LoadAWSKMSKeyCatalog(writeCatalog("keys:\n  us-east-1:\n  - " + keyID + "\n"))
+			Expect(err).To(MatchError(ContainSubstring("could not parse AWS KMS key catalog")))
+		})
+
+		It("should return an error when the file does not exist", func() {
+			_, err := LoadAWSKMSKeyCatalog(filepath.Join(os.TempDir(), "does-not-exist", "catalog.yaml"))
+			Expect(err).To(MatchError(ContainSubstring("could not read AWS KMS key catalog")))
+		})
+	})
+
+	Context("validateAWSBlockDeviceEncryption", func() {
+		validate := func(providerSpec resourcebuilder.AWSProviderSpecBuilder) field.ErrorList {
+			providerConfig, err := providerconfig.NewProviderConfig(*templateFor(providerSpec).OpenShiftMachineV1Beta1Machine)
+			Expect(err).ToNot(HaveOccurred())
+
+			return validateAWSBlockDeviceEncryption(field.NewPath(providerSpecPath), providerConfig.AWS())
+		}
+
+		It("allows volumes encrypted with the default key", func() {
+			Expect(validate(resourcebuilder.AWSProviderSpec())).To(BeEmpty())
+		})
+
+		It("allows encrypted volumes with a KMS key referenced by ID or ARN", func() {
+			Expect(validate(encryptedWith(pointer.Bool(true), machinev1beta1.AWSResourceReference{ID: pointer.String("alias/control-plane")}))).To(BeEmpty())
+			Expect(validate(encryptedWith(pointer.Bool(true), machinev1beta1.AWSResourceReference{ARN: pointer.String(keyARN)}))).To(BeEmpty())
+		})
+
+		It("rejects a KMS key on a volume that is not encrypted", func() {
+			Expect(validate(encryptedWith(pointer.Bool(false), machinev1beta1.AWSResourceReference{ARN: pointer.String(keyARN)})).ToAggregate()).To(MatchError(
+				providerSpecPath + ".blockDevices[0].ebs.encrypted: Invalid value: false: encrypted must be true when a KMS key is set",
+			))
+		})
+
+		It("rejects a KMS key on a volume that does not set encrypted", func() {
+			Expect(validate(encryptedWith(nil, machinev1beta1.AWSResourceReference{ARN: pointer.String(keyARN)})).ToAggregate()).To(MatchError(
+				providerSpecPath + ".blockDevices[0].ebs.encrypted: Required value: encrypted must be true when a KMS key is set",
+			))
+		})
+
+		It("rejects a KMS key selected by filters", func() {
+			Expect(validate(encryptedWith(pointer.Bool(true), machinev1beta1.AWSResourceReference{
+				Filters: []machinev1beta1.Filter{{Name: "alias", Values: []string{"control-plane"}}},
+			})).ToAggregate()).To(MatchError(ContainSubstring(
+				providerSpecPath + ".blockDevices[0].ebs.kmsKey.filters: Invalid value",
+			)))
+		})
+
+		It("rejects an ARN that is not the ARN of a KMS key", func() {
+			Expect(validate(encryptedWith(pointer.Bool(true), machinev1beta1.AWSResourceReference{
+				ARN: pointer.String("arn:aws:iam::111122223333:role/control-plane"),
+			})).ToAggregate()).To(MatchError(
+				providerSpecPath + `.blockDevices[0].ebs.kmsKey.arn: Invalid value: "arn:aws:iam::111122223333:role/control-plane": "arn:aws:iam::111122223333:role/control-plane" is not the ARN of a KMS key`,
+			))
+		})
+	})
+
+	Context("validateAWSKMSKeys", func() {
+		var wh *ControlPlaneMachineSetWebhook
+
+		BeforeEach(func() {
+			catalog, err := LoadAWSKMSKeyCatalog(writeCatalog("regions:\n  us-east-1:\n  - " + keyID + "\n  - alias/control-plane\n"))
+			Expect(err).ToNot(HaveOccurred())
+
+			wh = &ControlPlaneMachineSetWebhook{AWSKMSKeyCatalog: catalog}
+		})
+
+		It("allows KMS keys that exist", func() {
+			template := templateFor(encryptedWith(pointer.Bool(true), machinev1beta1.AWSResourceReference{ARN: pointer.String(keyARN)}))
+
+			Expect(wh.validateAWSKMSKeys(templatePath, template)).To(BeEmpty())
+		})
+
+		It("rejects a KMS key that does not exist", func() {
+			template := templateFor(encryptedWith(pointer.Bool(true), machinev1beta1.AWSResourceReference{ID: pointer.String("alias/deleted")}))
+
+			Expect(wh.validateAWSKMSKeys(templatePath, template).ToAggregate()).To(MatchError(
+				providerSpecPath + `.blockDevices[0].ebs.kmsKey: Invalid value: "alias/deleted": KMS key alias/deleted, used by block device root, does not exist in region us-east-1`,
+			))
+		})
+
+		It("does not validate volumes encrypted with the default key", func() {
+			Expect(wh.validateAWSKMSKeys(templatePath, templateFor(resourcebuilder.AWSProviderSpec()))).To(BeEmpty())
+		})
+
+		It("does not validate regions unknown to the catalog", func() {
+			wh.AWSKMSKeyCatalog = &staticAWSKMSKeyCatalog{Regions: map[string][]string{"eu-west-1": {keyID}}}
+			template := templateFor(encryptedWith(pointer.Bool(true), machinev1beta1.AWSResourceReference{ID: pointer.String("alias/deleted")}))
+
+			Expect(wh.validateAWSKMSKeys(templatePath, template)).To(BeEmpty())
+		})
+
+		It("does not validate templates without a catalog", func() {
+			wh.AWSKMSKeyCatalog = nil
+			template := templateFor(encryptedWith(pointer.Bool(true), machinev1beta1.AWSResourceReference{ID: pointer.String("alias/deleted")}))
+
+			Expect(wh.validateAWSKMSKeys(templatePath, template)).To(BeEmpty())
+		})
+	})
+})
@@ -0,0 +1,184 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controlplanemachineset
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	configv1 "github.com/openshift/api/config/v1"
+	machinev1 "github.com/openshift/api/machine/v1"
+	machinev1beta1 "github.com/openshift/api/machine/v1beta1"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders/providers/openshift/machine/v1beta1/providerconfig"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/apimachinery/pkg/util/yaml"
+)
+
+const (
+	// diskSKUUnavailableFormat is the format of the error returned when a disk SKU used by the template is not
+	// available within one of the zones of the Azure failure domains.
+	diskSKUUnavailableFormat = "disk SKU %s, used by %s, is not available in zone %s of region %s"
+)
+
+// AzureDiskSKUCatalog provides the zones, within each Azure region, in which the managed disk SKUs are available,
+// so that templates using premium or ultra disks in a zone without them can be rejected before any Machine is
+// created.
+type AzureDiskSKUCatalog interface {
+	// DiskSKUZones returns the zones of the region in which the disk SKU is available, and whether the
+	// availability of the disk SKU within the region is known to the catalog.
+	DiskSKUZones(region, sku string) ([]string, bool)
+}
+
+// staticAzureDiskSKUCatalog is an AzureDiskSKUCatalog with a fixed set of zones, loaded from a file.
+type staticAzureDiskSKUCatalog struct {
+	// Regions maps each region to the zones in which each disk SKU is available.
+	Regions map[string]map[string][]string `json:"regions"`
+}
+
+// LoadAzureDiskSKUCatalog loads an AzureDiskSKUCatalog from the YAML or JSON file at the path given.
+// The file specifies the zones in which each disk SKU is available within each region, eg:
+//
+//	regions:
+//	  eastus:
+//	    UltraSSD_LRS: ["1", "3"]
+//	    Premium_LRS: ["1", "2", "3"]
+func LoadAzureDiskSKUCatalog(path string) (AzureDiskSKUCatalog, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read Azure disk SKU catalog: %w", err)
+	}
+
+	catalog := &staticAzureDiskSKUCatalog{}
+	if err := yaml.UnmarshalStrict(data, catalog); err != nil {
+		return nil, fmt.Errorf("could not parse Azure disk SKU catalog: %w", err)
+	}
+
+	regions := map[string]map[string][]string{}
+	for region, skus := range catalog.Regions {
+		regions[normaliseAzureRegion(region)] = skus
+	}
+
+	catalog.Regions = regions
+
+	return catalog, nil
+}
+
+// DiskSKUZones returns the zones of the region in which the disk SKU is available, and whether the availability
+// of the disk SKU within the region is known to the catalog.
+func (c *staticAzureDiskSKUCatalog) DiskSKUZones(region, sku string) ([]string, bool) {
+	zones, ok := c.Regions[normaliseAzureRegion(region)][sku]
+	return zones, ok
+}
+
+// normaliseAzureRegion normalises the name of an Azure region, so that the display name, eg East US, matches the
+// name of the region, eg eastus.
+func normaliseAzureRegion(region string) string {
+	return strings.ToLower(strings.ReplaceAll(region, " ", ""))
+}
+
+// validateAzureDiskZones checks that the premium and ultra disk SKUs used by the template are available within
+// each zone of the Azure failure domains of the template, according to the Azure disk SKU catalog.
+// Disk SKUs, and regions, that are not known to the catalog are not validated, nor are templates without Azure
+// failure domains, or when no catalog is configured.
+func (r *ControlPlaneMachineSetWebhook) validateAzureDiskZones(templatePath *field.Path, template machinev1.ControlPlaneMachineSetTemplate) field.ErrorList {
+	if r.AzureDiskSKUCatalog == nil || template.OpenShiftMachineV1Beta1Machine == nil {
+		return nil
+	}
+
+	failureDomains := template.OpenShiftMachineV1Beta1Machine.FailureDomains
+	if failureDomains.Platform != configv1.AzurePlatformType || failureDomains.Azure == nil {
+		return nil
+	}
+
+	providerConfig, err := providerconfig.NewProviderConfig(*template.OpenShiftMachineV1Beta1Machine)
+	if err != nil || providerConfig.Type() != configv1.AzurePlatformType {
+		return nil
+	}
+
+	machinePath := templatePath.Child("machines_v1beta1_machine_openshift_io")
+	failureDomainsPath := machinePath.Child("failureDomains", "azure")
+	disks := zonalAzureDisks(machinePath.Child("spec", "providerSpec", "value"), providerConfig.Azure().Config())
+	region := providerConfig.Azure().Config().Location
+
+	var errs field.ErrorList
+
+	for i, fd := range *failureDomains.Azure {
+		for _, disk := range disks {
+			zones, ok := r.AzureDiskSKUCatalog.DiskSKUZones(region, disk.sku)
+			if !ok || containsString(zones, fd.Zone) {
+				continue
+			}
+
+			errs = append(errs, field.Invalid(failureDomainsPath.Index(i).Child("zone"), fd.Zone,
+				fmt.Sprintf(diskSKUUnavailableFormat, disk.sku, disk.path, fd.Zone, region),
+			))
+		}
+	}
+
+	return errs
+}
+
+// azureDisk is a managed disk of the template, identified by the path of the disk within the provider spec.
+type azureDisk struct {
+	// path is the path of the disk.
+	path *field.Path
+
+	// sku is the storage account type of the disk.
+	sku string
+}
+
+// zonalAzureDisks returns the OS disk and the data disks of the provider spec whose disk SKU is a premium or ultra
+// SKU, as only these SKUs are unavailable within some zones.
+// Data disks without a storage account type default to Premium_LRS.
+func zonalAzureDisks(providerSpecPath *field.Path, spec machinev1beta1.AzureMachineProviderSpec) []azureDisk {
+	var disks []azureDisk
+
+	if sku := spec.OSDisk.ManagedDisk.StorageAccountType; isZonalAzureDiskSKU(sku) {
+		disks = append(disks, azureDisk{path: providerSpecPath.Child("osDisk"), sku: sku})
+	}
+
+	for i, dataDisk := range spec.DataDisks {
+		sku := string(dataDisk.ManagedDisk.StorageAccountType)
+		if sku == "" {
+			sku = string(machinev1beta1.StorageAccountPremiumLRS)
+		}
+
+		if isZonalAzureDiskSKU(sku) {
+			disks = append(disks, azureDisk{path: providerSpecPath.Child("dataDisks").Index(i), sku: sku})
+		}
+	}
+
+	return disks
+}
+
+// isZonalAzureDiskSKU returns true for the locally redundant premium and ultra disk SKUs, eg Premium_LRS and
+// UltraSSD_LRS. Zone redundant SKUs are not tied to a single zone.
+func isZonalAzureDiskSKU(sku string) bool {
+	return (strings.HasPrefix(sku, "Premium") || strings.HasPrefix(sku, "UltraSSD")) && strings.HasSuffix(sku, "_LRS")
+}
+
+// containsString returns true when the list contains the string.
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,160 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controlplanemachineset
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	machinev1 "github.com/openshift/api/machine/v1"
+	machinev1beta1 "github.com/openshift/api/machine/v1beta1"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/test/resourcebuilder"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+var _ = Describe("Azure disk zones", func() {
+	writeCatalog := func(content string) string {
+		dir, err := os.MkdirTemp("", "azure-disk-sku-catalog-")
+		Expect(err).ToNot(HaveOccurred())
+
+		DeferCleanup(func() {
+			Expect(os.RemoveAll(dir)).To(Succeed())
+		})
+
+		path := filepath.Join(dir, "catalog.yaml")
+		Expect(os.WriteFile(path, []byte(content), 0600)).To(Succeed())
+
+		return path
+	}
+
+	Context("LoadAzureDiskSKUCatalog", func() {
+		It("should load the zones from the file", func() {
+			catalog, err := LoadAzureDiskSKUCatalog(writeCatalog("regions:\n  centralus:\n    UltraSSD_LRS: [\"1\", \"3\"]\n"))
+			Expect(err).ToNot(HaveOccurred())
+
+			zones, ok := catalog.DiskSKUZones("centralus", "UltraSSD_LRS")
+			Expect(ok).To(BeTrue())
+			Expect(zones).To(ConsistOf("1", "3"))
+
+			_, ok = catalog.DiskSKUZones("centralus", "Premium_LRS")
+			Expect(ok).To(BeFalse())
+		})
+
+		It("should match the display name of the region", func() {
+			catalog, err := LoadAzureDiskSKUCatalog(writeCatalog("regions:\n  Central US:\n    UltraSSD_LRS: [\"1\"]\n"))
+			Expect(err).ToNot(HaveOccurred())
+
+			zones, ok := catalog.DiskSKUZones("centralus", "UltraSSD_LRS")
+			Expect(ok).To(BeTrue())
+			Expect(zones).To(ConsistOf("1"))
+		})
+
+		It("should reject unknown fields", func() {
+			_, err := LoadAzureDiskSKUCatalog(writeCatalog("zones:\n  centralus:\n    UltraSSD_LRS: [\"1\"]\n"))
+			Expect(err).To(MatchError(ContainSubstring("could not parse Azure disk SKU catalog")))
+		})
+
+		It("should return an error when the file does not exist", func() {
+			_, err := LoadAzureDiskSKUCatalog(filepath.Join(os.TempDir(), "does-not-exist", "catalog.yaml"))
+			Expect(err).To(MatchError(ContainSubstring("could not read Azure disk SKU catalog")))
+		})
+	})
+
+	Context("validateAzureDiskZones", func() {
+		const failureDomainsPath = "spec.template.machines_v1beta1_machine_openshift_io.failureDomains.azure"
+		const providerSpecPath = "spec.template.machines_v1beta1_machine_openshift_io.spec.providerSpec.value"
+
+		var wh *ControlPlaneMachineSetWebhook
+
+		templatePath := field.NewPath("spec", "template")
+
+		ultraDataDisk := machinev1beta1.DataDisk{
+			NameSuffix: "etcd",
+			DiskSizeGB: 256,
+			Lun:        0,
+			ManagedDisk: machinev1beta1.DataDiskManagedDiskParameters{
+				StorageAccountType: machinev1beta1.StorageAccountUltraSSDLRS,
+			},
+		}
+
+		templateFor := func(failureDomains resourcebuilder.OpenShiftMachineV1Beta1FailureDomainsBuilder, providerSpec resourcebuilder.AzureProviderSpecBuilder) machinev1.ControlPlaneMachineSetTemplate {
+			return resourcebuilder.ControlPlaneMachineSet().WithMachineTemplateBuilder(
+				resourcebuilder.OpenShiftMachineV1Beta1Template().WithFailureDomainsBuilder(failureDomains).WithProviderSpecBuilder(providerSpec),
+			).Build().Spec.Template
+		}
+
+		BeforeEach(func() {
+			catalog, err := LoadAzureDiskSKUCatalog(writeCatalog("regions:\n  centralus:\n    UltraSSD_LRS: [\"1\", \"3\"]\n    Premium_LRS: [\"1\", \"2\", \"3\"]\n"))
+			Expect(err).ToNot(HaveOccurred())
+
+			wh = &ControlPlaneMachineSetWebhook{AzureDiskSKUCatalog: catalog}
+		})
+
+		It("allows disk SKUs that are available in every zone", func() {
+			template := templateFor(resourcebuilder.AzureFailureDomains(), resourcebuilder.AzureProviderSpec())
+
+			Expect(wh.validateAzureDiskZones(templatePath, template)).To(BeEmpty())
+		})
+
+		It("rejects a disk SKU that is unavailable in one of the zones", func() {
+			template := templateFor(resourcebuilder.AzureFailureDomains(), resourcebuilder.AzureProviderSpec().WithDataDisks(ultraDataDisk))
+
+			Expect(wh.validateAzureDiskZones(templatePath, template).ToAggregate()).To(MatchError(
+				failureDomainsPath + `[1].zone: Invalid value: "2": disk SKU UltraSSD_LRS, used by ` + providerSpecPath + `.dataDisks[0], is not available in zone 2 of region centralus`,
+			))
+		})
+
+		It("rejects an OS disk SKU that is unavailable in any of the zones", func() {
+			template := templateFor(resourcebuilder.AzureFailureDomains().WithFailureDomainBuilders(
+				resourcebuilder.AzureFailureDomain().WithZone("2"),
+				resourcebuilder.AzureFailureDomain().WithZone("4"),
+			), resourcebuilder.AzureProviderSpec().WithOSDiskType("Premium_LRS"))
+
+			Expect(wh.validateAzureDiskZones(templatePath, template).ToAggregate()).To(MatchError(
+				failureDomainsPath + `[1].zone: Invalid value: "4": disk SKU Premium_LRS, used by ` + providerSpecPath + `.osDisk, is not available in zone 4 of region centralus`,
+			))
+		})
+
+		It("does not validate disk SKUs unknown to the catalog", func() {
+			template := templateFor(resourcebuilder.AzureFailureDomains(), resourcebuilder.AzureProviderSpec().WithOSDiskType("PremiumV2_LRS"))
+
+			Expect(wh.validateAzureDiskZones(templatePath, template)).To(BeEmpty())
+		})
+
+		It("does not validate standard disk SKUs", func() {
+			wh.AzureDiskSKUCatalog = &staticAzureDiskSKUCatalog{Regions: map[string]map[string][]string{"centralus": {"Standard_LRS": {}}}}
+			template := templateFor(resourcebuilder.AzureFailureDomains(), resourcebuilder.AzureProviderSpec().WithOSDiskType("Standard_LRS"))
+
+			Expect(wh.validateAzureDiskZones(templatePath, template)).To(BeEmpty())
+		})
+
+		It("does not validate templates without failure domains", func() {
+			template := templateFor(nil, resourcebuilder.AzureProviderSpec().WithDataDisks(ultraDataDisk))
+
+			Expect(wh.validateAzureDiskZones(templatePath, template)).To(BeEmpty())
+		})
+
+		It("does not validate templates without a catalog", func() {
+			wh.AzureDiskSKUCatalog = nil
+			template := templateFor(resourcebuilder.AzureFailureDomains(), resourcebuilder.AzureProviderSpec().WithDataDisks(ultraDataDisk))
+
+			Expect(wh.validateAzureDiskZones(templatePath, template)).To(BeEmpty())
+		})
+	})
+})
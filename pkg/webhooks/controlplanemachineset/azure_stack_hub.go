@@ -0,0 +1,98 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controlplanemachineset
+
+import (
+	"context"
+	"fmt"
+
+	configv1 "github.com/openshift/api/config/v1"
+	machinev1 "github.com/openshift/api/machine/v1"
+	machinev1beta1 "github.com/openshift/api/machine/v1beta1"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/clusterconfig"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders/providers/openshift/machine/v1beta1/providerconfig"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// azureStackHubStorageAccountTypes are the managed disk storage account types that are available on Azure Stack Hub.
+var azureStackHubStorageAccountTypes = []string{
+	string(machinev1beta1.StorageAccountStandardLRS),
+	string(machinev1beta1.StorageAccountPremiumLRS),
+}
+
+// isAzureStackHub determines whether the template is an Azure template on an Azure Stack Hub cluster.
+// The Infrastructure resource is only read for Azure templates. When it does not exist, the cluster is assumed to be
+// on public Azure, as it is when the webhook has no client, for example when templates are linted offline.
+func (r *ControlPlaneMachineSetWebhook) isAzureStackHub(ctx context.Context, template machinev1.ControlPlaneMachineSetTemplate) (bool, error) {
+	if r.client == nil || template.OpenShiftMachineV1Beta1Machine == nil {
+		return false, nil
+	}
+
+	providerConfig, err := providerconfig.NewProviderConfig(*template.OpenShiftMachineV1Beta1Machine)
+	if err != nil || providerConfig.Type() != configv1.AzurePlatformType {
+		return false, nil
+	}
+
+	infrastructure := &configv1.Infrastructure{}
+	if err := r.client.Get(ctx, client.ObjectKey{Name: clusterconfig.InfrastructureName}, infrastructure); apierrors.IsNotFound(err) {
+		return false, nil
+	} else if err != nil {
+		return false, fmt.Errorf("could not determine whether the cluster is on Azure Stack Hub: %w", err)
+	}
+
+	return providerconfig.IsAzureStackHub(infrastructure), nil
+}
+
+// validateAzureStackHubTemplate checks that an Azure template only uses the features that are available on Azure
+// Stack Hub.
+// Azure Stack Hub has no availability zones, so neither failure domains nor a zone may be set, does not support
+// accelerated networking, and only supports standard and premium locally redundant managed disks.
+// Managed identities and boot diagnostics are not validated, as their validation assumes the public Azure clouds.
+func validateAzureStackHubTemplate(machinePath *field.Path, failureDomains machinev1.FailureDomains, config providerconfig.AzureProviderConfig) field.ErrorList {
+	var errs field.ErrorList
+
+	providerSpecPath := machinePath.Child("spec", "providerSpec", "value")
+	spec := config.Config()
+
+	if failureDomains.Azure != nil && len(*failureDomains.Azure) > 0 {
+		errs = append(errs, field.Forbidden(machinePath.Child("failureDomains", "azure"), "Azure Stack Hub does not support availability zones"))
+	}
+
+	if spec.Zone != nil && *spec.Zone != "" {
+		errs = append(errs, field.Forbidden(providerSpecPath.Child("zone"), "Azure Stack Hub does not support availability zones"))
+	}
+
+	if spec.AcceleratedNetworking {
+		errs = append(errs, field.Forbidden(providerSpecPath.Child("acceleratedNetworking"), "Azure Stack Hub does not support accelerated networking"))
+	}
+
+	if spec.OSDisk.ManagedDisk.StorageAccountType != "" && !containsString(azureStackHubStorageAccountTypes, spec.OSDisk.ManagedDisk.StorageAccountType) {
+		errs = append(errs, field.NotSupported(providerSpecPath.Child("osDisk", "managedDisk", "storageAccountType"), spec.OSDisk.ManagedDisk.StorageAccountType, azureStackHubStorageAccountTypes))
+	}
+
+	for i, dataDisk := range spec.DataDisks {
+		storageAccountType := string(dataDisk.ManagedDisk.StorageAccountType)
+
+		if storageAccountType != "" && !containsString(azureStackHubStorageAccountTypes, storageAccountType) {
+			errs = append(errs, field.NotSupported(providerSpecPath.Child("dataDisks").Index(i).Child("managedDisk", "storageAccountType"), storageAccountType, azureStackHubStorageAccountTypes))
+		}
+	}
+
+	return errs
+}
@@ -0,0 +1,94 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controlplanemachineset
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	machinev1beta1 "github.com/openshift/api/machine/v1beta1"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders/providers/openshift/machine/v1beta1/providerconfig"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/test/resourcebuilder"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+var _ = Describe("Azure Stack Hub", func() {
+	Context("validateAzureStackHubTemplate", func() {
+		const machinePath = "spec.template.machines_v1beta1_machine_openshift_io"
+		const providerSpecPath = machinePath + ".spec.providerSpec.value"
+
+		validate := func(failureDomains resourcebuilder.OpenShiftMachineV1Beta1FailureDomainsBuilder, providerSpec resourcebuilder.AzureProviderSpecBuilder) field.ErrorList {
+			template := resourcebuilder.ControlPlaneMachineSet().WithMachineTemplateBuilder(
+				resourcebuilder.OpenShiftMachineV1Beta1Template().WithFailureDomainsBuilder(failureDomains).WithProviderSpecBuilder(providerSpec),
+			).Build().Spec.Template
+
+			providerConfig, err := providerconfig.NewProviderConfig(*template.OpenShiftMachineV1Beta1Machine)
+			Expect(err).ToNot(HaveOccurred())
+
+			return validateAzureStackHubTemplate(field.NewPath("spec", "template", "machines_v1beta1_machine_openshift_io"), template.OpenShiftMachineV1Beta1Machine.FailureDomains, providerConfig.Azure())
+		}
+
+		It("allows a template without zones", func() {
+			Expect(validate(nil, resourcebuilder.AzureProviderSpec().WithOSDiskType("Premium_LRS"))).To(BeEmpty())
+		})
+
+		It("rejects failure domains", func() {
+			Expect(validate(resourcebuilder.AzureFailureDomains(), resourcebuilder.AzureProviderSpec()).ToAggregate()).To(MatchError(
+				machinePath + ".failureDomains.azure: Forbidden: Azure Stack Hub does not support availability zones",
+			))
+		})
+
+		It("rejects a zone", func() {
+			Expect(validate(nil, resourcebuilder.AzureProviderSpec().WithZone("2")).ToAggregate()).To(MatchError(
+				providerSpecPath + ".zone: Forbidden: Azure Stack Hub does not support availability zones",
+			))
+		})
+
+		It("rejects accelerated networking", func() {
+			Expect(validate(nil, resourcebuilder.AzureProviderSpec().WithAcceleratedNetworking(true)).ToAggregate()).To(MatchError(
+				providerSpecPath + ".acceleratedNetworking: Forbidden: Azure Stack Hub does not support accelerated networking",
+			))
+		})
+
+		It("rejects disk storage account types that are unavailable on Azure Stack Hub", func() {
+			providerSpec := resourcebuilder.AzureProviderSpec().WithOSDiskType("StandardSSD_LRS").WithDataDisks(machinev1beta1.DataDisk{
+				NameSuffix: "etcd",
+				DiskSizeGB: 256,
+				ManagedDisk: machinev1beta1.DataDiskManagedDiskParameters{
+					StorageAccountType: machinev1beta1.StorageAccountUltraSSDLRS,
+				},
+			})
+
+			Expect(validate(nil, providerSpec).ToAggregate()).To(MatchError(
+				"[" + providerSpecPath + `.osDisk.managedDisk.storageAccountType: Unsupported value: "StandardSSD_LRS": supported values: "Standard_LRS", "Premium_LRS", ` +
+					providerSpecPath + `.dataDisks[0].managedDisk.storageAccountType: Unsupported value: "UltraSSD_LRS": supported values: "Standard_LRS", "Premium_LRS"]`,
+			))
+		})
+	})
+
+	Context("validateTemplate", func() {
+		It("validates Azure templates against Azure Stack Hub only on Azure Stack Hub", func() {
+			template := resourcebuilder.ControlPlaneMachineSet().WithMachineTemplateBuilder(
+				resourcebuilder.OpenShiftMachineV1Beta1Template().WithFailureDomainsBuilder(nil).WithProviderSpecBuilder(
+					resourcebuilder.AzureProviderSpec().WithAcceleratedNetworking(true),
+				),
+			).Build().Spec.Template
+
+			Expect(validateTemplate(field.NewPath("spec", "template"), template, false)).To(BeEmpty())
+			Expect(validateTemplate(field.NewPath("spec", "template"), template, true)).To(HaveLen(1))
+		})
+	})
+})
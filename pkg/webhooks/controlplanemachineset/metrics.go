@@ -0,0 +1,129 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controlplanemachineset
+
+import (
+	"regexp"
+
+	"github.com/prometheus/client_golang/prometheus"
+	admissionv1 "k8s.io/api/admission/v1"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+const (
+	// decisionAllowed is the decision label value of an admission request that was allowed.
+	decisionAllowed = "Allowed"
+
+	// decisionDenied is the decision label value of an admission request that was denied.
+	decisionDenied = "Denied"
+
+	// warningReasonMachinesReplaced is the reason label value of a warning that existing control plane Machines will
+	// be replaced, or that it could not be determined which will be replaced.
+	warningReasonMachinesReplaced = "MachinesReplaced"
+
+	// warningReasonMachinesUnmanaged is the reason label value of a warning that existing control plane Machines do
+	// not match the selector, and will not be managed.
+	warningReasonMachinesUnmanaged = "MachinesUnmanaged"
+
+	// warningReasonUndersizedMachine is the reason label value of a warning that the template configures control
+	// plane Machines smaller than recommended.
+	warningReasonUndersizedMachine = "UndersizedMachine"
+
+	// warningReasonUnverifiedTemplate is the reason label value of a warning that the vSphere template, from which
+	// control plane Machines are cloned, cannot be verified to exist.
+	warningReasonUnverifiedTemplate = "UnverifiedTemplate"
+
+	// warningReasonAWSEdgeZone is the reason label value of a warning that the template uses AWS Local Zones or
+	// Wavelength Zones in a way that is likely to fail to launch, or to destabilise etcd.
+	warningReasonAWSEdgeZone = "AWSEdgeZone"
+
+	// denialReasonUnknown is the reason label value of a denial for which the response carries no reason.
+	denialReasonUnknown = "Unknown"
+)
+
+var (
+	// admissionRequestsTotal counts the admission requests handled by the webhook, by operation and decision.
+	admissionRequestsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "control_plane_machine_set_webhook_admission_requests_total",
+		Help: "Number of ControlPlaneMachineSet admission requests handled by the webhook, by operation and decision.",
+	}, []string{"operation", "decision"})
+
+	// admissionDenialsTotal counts the causes of the admission requests denied by the webhook, by operation, field
+	// and reason. A request denied by several validations counts once for each.
+	admissionDenialsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "control_plane_machine_set_webhook_admission_denials_total",
+		Help: "Number of validations failed by denied ControlPlaneMachineSet admission requests, by operation, field and reason.",
+	}, []string{"operation", "field", "reason"})
+
+	// admissionWarningsTotal counts the warnings returned on the admission requests allowed by the webhook, by
+	// operation and reason.
+	admissionWarningsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "control_plane_machine_set_webhook_admission_warnings_total",
+		Help: "Number of warnings returned on allowed ControlPlaneMachineSet admission requests, by operation and reason.",
+	}, []string{"operation", "reason"})
+
+	// fieldIndexPattern matches the list indexes and map keys within a field path, so that they can be removed to
+	// bound the number of distinct field label values.
+	fieldIndexPattern = regexp.MustCompile(`\[[^\]]*\]`)
+)
+
+func init() {
+	metrics.Registry.MustRegister(admissionRequestsTotal, admissionDenialsTotal, admissionWarningsTotal)
+}
+
+// recordAdmissionDecision records the decision of the webhook on the admission request. When the request was denied,
+// each cause of the denial is recorded by the field and the reason of the cause. A denial without causes is recorded
+// by the reason of the response.
+func recordAdmissionDecision(operation admissionv1.Operation, resp admission.Response) {
+	if resp.Allowed {
+		admissionRequestsTotal.WithLabelValues(string(operation), decisionAllowed).Inc()
+		return
+	}
+
+	admissionRequestsTotal.WithLabelValues(string(operation), decisionDenied).Inc()
+
+	if resp.Result == nil {
+		admissionDenialsTotal.WithLabelValues(string(operation), "", denialReasonUnknown).Inc()
+		return
+	}
+
+	if resp.Result.Details == nil || len(resp.Result.Details.Causes) == 0 {
+		reason := string(resp.Result.Reason)
+		if reason == "" {
+			reason = denialReasonUnknown
+		}
+
+		admissionDenialsTotal.WithLabelValues(string(operation), "", reason).Inc()
+
+		return
+	}
+
+	for _, cause := range resp.Result.Details.Causes {
+		admissionDenialsTotal.WithLabelValues(string(operation), fieldIndexPattern.ReplaceAllString(cause.Field, "[]"), string(cause.Type)).Inc()
+	}
+}
+
+// recordAdmissionWarnings records each of the warnings given, with the reason given, and returns them so that they
+// can be appended to the warnings of the response.
+func recordAdmissionWarnings(operation admissionv1.Operation, reason string, warnings []string) []string {
+	if len(warnings) > 0 {
+		admissionWarningsTotal.WithLabelValues(string(operation), reason).Add(float64(len(warnings)))
+	}
+
+	return warnings
+}
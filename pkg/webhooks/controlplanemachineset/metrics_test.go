@@ -0,0 +1,90 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controlplanemachineset
+
+import (
+	"errors"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	admissionv1 "k8s.io/api/admission/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// counterValue returns the current value of the counter with the label values given.
+func counterValue(counter *prometheus.CounterVec, labelValues ...string) float64 {
+	metric := &dto.Metric{}
+	Expect(counter.WithLabelValues(labelValues...).Write(metric)).To(Succeed())
+
+	return metric.GetCounter().GetValue()
+}
+
+var _ = Describe("Admission metrics", func() {
+	Context("recordAdmissionDecision", func() {
+		It("records each cause of a denial with the list indexes removed from the field", func() {
+			field := "spec.template.machines_v1beta1_machine_openshift_io.failureDomains.aws[]"
+			before := counterValue(admissionDenialsTotal, "UPDATE", field, "FieldValueRequired")
+
+			recordAdmissionDecision(admissionv1.Update, admission.Response{
+				AdmissionResponse: admissionv1.AdmissionResponse{
+					Allowed: false,
+					Result: &metav1.Status{
+						Reason: metav1.StatusReasonInvalid,
+						Details: &metav1.StatusDetails{
+							Causes: []metav1.StatusCause{
+								{Type: metav1.CauseTypeFieldValueRequired, Field: "spec.template.machines_v1beta1_machine_openshift_io.failureDomains.aws[0]"},
+								{Type: metav1.CauseTypeFieldValueRequired, Field: "spec.template.machines_v1beta1_machine_openshift_io.failureDomains.aws[2]"},
+							},
+						},
+					},
+				},
+			})
+
+			Expect(counterValue(admissionDenialsTotal, "UPDATE", field, "FieldValueRequired")).To(Equal(before + 2))
+		})
+
+		It("records a denial without causes by the reason of the response", func() {
+			before := counterValue(admissionDenialsTotal, "UPDATE", "", string(metav1.StatusReasonForbidden))
+
+			recordAdmissionDecision(admissionv1.Update, admission.Denied(string(metav1.StatusReasonForbidden)))
+
+			Expect(counterValue(admissionDenialsTotal, "UPDATE", "", string(metav1.StatusReasonForbidden))).To(Equal(before + 1))
+		})
+
+		It("records a denial without a reason as unknown", func() {
+			before := counterValue(admissionDenialsTotal, "UPDATE", "", denialReasonUnknown)
+
+			recordAdmissionDecision(admissionv1.Update, admission.Errored(400, errors.New("could not decode object")))
+
+			Expect(counterValue(admissionDenialsTotal, "UPDATE", "", denialReasonUnknown)).To(Equal(before + 1))
+		})
+	})
+
+	Context("recordAdmissionWarnings", func() {
+		It("records each warning and returns the warnings", func() {
+			before := counterValue(admissionWarningsTotal, "UPDATE", warningReasonUndersizedMachine)
+
+			warnings := []string{"first", "second"}
+			Expect(recordAdmissionWarnings(admissionv1.Update, warningReasonUndersizedMachine, warnings)).To(Equal(warnings))
+
+			Expect(counterValue(admissionWarningsTotal, "UPDATE", warningReasonUndersizedMachine)).To(Equal(before + 2))
+		})
+	})
+})
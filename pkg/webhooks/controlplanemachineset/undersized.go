@@ -0,0 +1,456 @@
+/*
+Copyright 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controlplanemachineset
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	configv1 "github.com/openshift/api/config/v1"
+	machinev1 "github.com/openshift/api/machine/v1"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders/providers/openshift/machine/v1beta1/providerconfig"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+const (
+	// undersizedWarningFormat is the format of the warning returned when the template of the ControlPlaneMachineSet
+	// configures control plane Machines that are smaller than recommended. Such Machines can be created, but may not
+	// be able to run the control plane reliably.
+	undersizedWarningFormat = "%s: %s is smaller than recommended for control plane machines, which should have at least %d vCPUs and %dMiB of memory"
+
+	// undersizedErrorFormat is the format of the error returned, when undersized Machines are rejected, for a field
+	// that configures control plane Machines smaller than the minimum for the platform.
+	undersizedErrorFormat = "%s is smaller than the minimum for control plane machines on %s, which is %d vCPUs and %dMiB of memory"
+
+	// bytesPerMiB is the number of bytes within a MiB.
+	bytesPerMiB = 1024 * 1024
+)
+
+// machineSize is the number of vCPUs, and the memory, of a Machine.
+type machineSize struct {
+	// vCPUs is the number of vCPUs.
+	vCPUs int64
+
+	// memoryMiB is the memory, in MiB.
+	memoryMiB int64
+}
+
+// smallerThan returns true when the Machine has fewer vCPUs, or less memory, than the minimum.
+// Unknown values, which are zero, are not compared.
+func (m machineSize) smallerThan(minimum machineSize) bool {
+	return (m.vCPUs > 0 && m.vCPUs < minimum.vCPUs) || (m.memoryMiB > 0 && m.memoryMiB < minimum.memoryMiB)
+}
+
+// String describes the size for use within warnings and errors.
+func (m machineSize) String() string {
+	return fmt.Sprintf("%d vCPUs, %dMiB of memory", m.vCPUs, m.memoryMiB)
+}
+
+var (
+	// defaultControlPlaneMinimumSize is the minimum size of control plane Machines on platforms that are not listed
+	// within controlPlaneMinimumSizes, as documented for OpenShift control plane machines.
+	defaultControlPlaneMinimumSize = machineSize{vCPUs: 4, memoryMiB: 16384}
+
+	// controlPlaneMinimumSizes are the minimum sizes of control plane Machines on each platform.
+	// GCP allows 15GiB of memory so that n1-standard-4, the former installer default, is not considered undersized.
+	controlPlaneMinimumSizes = map[configv1.PlatformType]machineSize{
+		configv1.AWSPlatformType:     defaultControlPlaneMinimumSize,
+		configv1.AzurePlatformType:   defaultControlPlaneMinimumSize,
+		configv1.GCPPlatformType:     {vCPUs: 4, memoryMiB: 15360},
+		configv1.NutanixPlatformType: defaultControlPlaneMinimumSize,
+		configv1.VSpherePlatformType: defaultControlPlaneMinimumSize,
+	}
+
+	// awsUndersizedInstanceSizes are the sizes of AWS instance types that have fewer vCPUs, or less memory, than
+	// recommended for control plane Machines, regardless of the instance family.
+	awsUndersizedInstanceSizes = map[string]struct{}{
+		"nano":   {},
+		"micro":  {},
+		"small":  {},
+		"medium": {},
+		"large":  {},
+	}
+
+	// awsMemoryPerVCPUMiB is the memory, in MiB, per vCPU of the AWS instance families within each instance
+	// class, for example c within c5.
+	awsMemoryPerVCPUMiB = map[string]int64{
+		"c": 2048,
+		"m": 4096,
+		"r": 8192,
+		"t": 4096,
+	}
+
+	// azureMemoryPerVCPUMiB is the memory, in MiB, per vCPU of the Azure VM series, by series and version, for
+	// example D_v3 for Standard_D4s_v3. Only series where the VM size is the number of vCPUs are listed.
+	azureMemoryPerVCPUMiB = map[string]int64{
+		"D_v3": 4096,
+		"D_v4": 4096,
+		"D_v5": 4096,
+		"E_v3": 8192,
+		"E_v4": 8192,
+		"E_v5": 8192,
+		"F_v2": 2048,
+	}
+
+	// gcpMemoryPerVCPUMiB is the memory, in MiB, per vCPU of the predefined GCP machine types, by machine family
+	// and type, for example n2 and standard for n2-standard-4.
+	gcpMemoryPerVCPUMiB = map[string]map[string]int64{
+		"n1":  {"standard": 3840, "highmem": 6656, "highcpu": 921},
+		"n2":  {"standard": 4096, "highmem": 8192, "highcpu": 1024},
+		"n2d": {"standard": 4096, "highmem": 8192, "highcpu": 1024},
+		"e2":  {"standard": 4096, "highmem": 8192, "highcpu": 1024},
+		"c2":  {"standard": 4096},
+		"t2d": {"standard": 4096},
+	}
+
+	// awsInstanceSizeRegexp matches the size of AWS instance types with at least 4 vCPUs, for example 2xlarge.
+	awsInstanceSizeRegexp = regexp.MustCompile(`^(\d*)xlarge$`)
+
+	// azureVMSizeRegexp matches Azure VM sizes, capturing the series, the number of vCPUs and the version.
+	azureVMSizeRegexp = regexp.MustCompile(`^Standard_([A-Z]+)(\d+)[a-z]*_(v\d+)$`)
+
+	// gcpMachineTypeRegexp matches predefined GCP machine types, capturing the family, the type and the number of
+	// vCPUs.
+	gcpMachineTypeRegexp = regexp.MustCompile(`^([a-z0-9]+)-(standard|highmem|highcpu)-(\d+)$`)
+
+	// gcpCustomMachineTypeRegexp matches custom GCP machine types, capturing the number of vCPUs and the memory in
+	// MiB.
+	gcpCustomMachineTypeRegexp = regexp.MustCompile(`^(?:[a-z0-9]+-)?custom-(\d+)-(\d+)(?:-ext)?$`)
+)
+
+// undersizedField is a field of the template that configures control plane Machines smaller than the minimum for
+// the platform.
+type undersizedField struct {
+	// path is the path of the field.
+	path *field.Path
+
+	// value is the value of the field.
+	value interface{}
+
+	// description describes the size configured by the field.
+	description string
+
+	// platform is the platform of the template.
+	platform configv1.PlatformType
+
+	// minimum is the minimum size of control plane Machines on the platform.
+	minimum machineSize
+}
+
+// warning formats a warning about the undersized field.
+func (u undersizedField) warning() string {
+	return fmt.Sprintf(undersizedWarningFormat, u.path.String(), u.description, u.minimum.vCPUs, u.minimum.memoryMiB)
+}
+
+// error formats an error rejecting the undersized field.
+func (u undersizedField) error() *field.Error {
+	return field.Invalid(u.path, u.value, fmt.Sprintf(undersizedErrorFormat, u.description, u.platform, u.minimum.vCPUs, u.minimum.memoryMiB))
+}
+
+// templateWarnings returns warnings about configurations within the template of the ControlPlaneMachineSet that
+// are valid, but are likely to cause problems for the control plane.
+// Templates that cannot be parsed are not checked here.
+func templateWarnings(templatePath *field.Path, template machinev1.ControlPlaneMachineSetTemplate) []string {
+	var warnings []string
+
+	for _, undersized := range undersizedTemplateFields(templatePath, template) {
+		warnings = append(warnings, undersized.warning())
+	}
+
+	return warnings
+}
+
+// TemplateWarnings returns the warnings that admitting the ControlPlaneMachineSet would raise about its template,
+// that do not depend on the state of the cluster.
+func TemplateWarnings(cpms *machinev1.ControlPlaneMachineSet) []string {
+	templatePath := field.NewPath("spec", "template")
+
+	return append(templateWarnings(templatePath, cpms.Spec.Template), awsEdgeZoneWarnings(templatePath, cpms.Spec.Template)...)
+}
+
+// validateTemplateSize rejects templates that configure control plane Machines smaller than the minimum for the
+// platform, when undersized Machines are rejected.
+// On update, fields that were already undersized within the old template are not rejected, so that a
+// ControlPlaneMachineSet created before undersized Machines were rejected can still be updated.
+func (r *ControlPlaneMachineSetWebhook) validateTemplateSize(templatePath *field.Path, oldTemplate *machinev1.ControlPlaneMachineSetTemplate, template machinev1.ControlPlaneMachineSetTemplate) field.ErrorList {
+	if !r.OperatorConfig.RejectUndersizedMachines(r.RejectUndersizedMachines) {
+		return nil
+	}
+
+	existing := map[string]struct{}{}
+
+	if oldTemplate != nil {
+		for _, undersized := range undersizedTemplateFields(templatePath, *oldTemplate) {
+			existing[undersized.warning()] = struct{}{}
+		}
+	}
+
+	var errs field.ErrorList
+
+	for _, undersized := range undersizedTemplateFields(templatePath, template) {
+		if _, ok := existing[undersized.warning()]; ok {
+			continue
+		}
+
+		errs = append(errs, undersized.error())
+	}
+
+	return errs
+}
+
+// undersizedTemplateFields returns the fields of the template that configure control plane Machines smaller than
+// the minimum for the platform.
+// Templates that cannot be parsed are not checked here.
+func undersizedTemplateFields(templatePath *field.Path, template machinev1.ControlPlaneMachineSetTemplate) []undersizedField {
+	if template.OpenShiftMachineV1Beta1Machine == nil {
+		return nil
+	}
+
+	providerConfig, err := providerconfig.NewProviderConfig(*template.OpenShiftMachineV1Beta1Machine)
+	if err != nil {
+		return nil
+	}
+
+	providerSpecPath := templatePath.Child("machines_v1beta1_machine_openshift_io", "spec", "providerSpec", "value")
+
+	minimum, ok := controlPlaneMinimumSizes[providerConfig.Type()]
+	if !ok {
+		minimum = defaultControlPlaneMinimumSize
+	}
+
+	var candidates []undersizedField
+
+	switch providerConfig.Type() {
+	case configv1.AWSPlatformType:
+		candidates = awsUndersizedFields(providerSpecPath, providerConfig.AWS(), minimum)
+	case configv1.AzurePlatformType:
+		candidates = azureUndersizedFields(providerSpecPath, providerConfig.Azure(), minimum)
+	case configv1.GCPPlatformType:
+		candidates = gcpUndersizedFields(providerSpecPath, providerConfig.GCP(), minimum)
+	case configv1.NutanixPlatformType:
+		candidates = nutanixUndersizedFields(providerSpecPath, providerConfig.Nutanix(), minimum)
+	case configv1.VSpherePlatformType:
+		candidates = vsphereUndersizedFields(providerSpecPath, providerConfig.VSphere(), minimum)
+	default:
+		return nil
+	}
+
+	undersized := []undersizedField{}
+
+	for _, candidate := range candidates {
+		candidate.platform = providerConfig.Type()
+		candidate.minimum = minimum
+		undersized = append(undersized, candidate)
+	}
+
+	return undersized
+}
+
+// awsUndersizedFields finds the instance type, or the minimums of the instance requirements, that allow control
+// plane Machines smaller than the minimum.
+// The size of the instance type, for example large within m6i.large, determines whether it is undersized. Larger
+// instance types are sized by their instance class, for example c within c5.xlarge, when it is known.
+func awsUndersizedFields(providerSpecPath *field.Path, config providerconfig.AWSProviderConfig, minimum machineSize) []undersizedField {
+	var undersized []undersizedField
+
+	instanceTypePath := providerSpecPath.Child("instanceType")
+
+	instanceType := config.Config().InstanceType
+	if parts := strings.Split(instanceType, "."); len(parts) > 1 {
+		if _, ok := awsUndersizedInstanceSizes[parts[len(parts)-1]]; ok {
+			undersized = append(undersized, undersizedField{path: instanceTypePath, value: instanceType, description: fmt.Sprintf("instance type %s", instanceType)})
+		} else if size, ok := awsInstanceTypeSize(instanceType); ok && size.smallerThan(minimum) {
+			undersized = append(undersized, undersizedField{path: instanceTypePath, value: instanceType, description: fmt.Sprintf("instance type %s (%s)", instanceType, size)})
+		}
+	}
+
+	requirements := config.InstanceRequirements()
+	if requirements == nil {
+		return undersized
+	}
+
+	requirementsPath := providerSpecPath.Child("instanceRequirements")
+
+	if requirements.VCPUCount != nil && requirements.VCPUCount.Min != nil && int64(*requirements.VCPUCount.Min) < minimum.vCPUs {
+		minVCPUs := *requirements.VCPUCount.Min
+		undersized = append(undersized, undersizedField{path: requirementsPath.Child("vCpuCount", "min"), value: minVCPUs, description: fmt.Sprintf("a minimum of %d vCPUs", minVCPUs)})
+	}
+
+	if requirements.MemoryMiB != nil && requirements.MemoryMiB.Min != nil && int64(*requirements.MemoryMiB.Min) < minimum.memoryMiB {
+		minMemoryMiB := *requirements.MemoryMiB.Min
+		undersized = append(undersized, undersizedField{path: requirementsPath.Child("memoryMiB", "min"), value: minMemoryMiB, description: fmt.Sprintf("a minimum of %dMiB of memory", minMemoryMiB)})
+	}
+
+	return undersized
+}
+
+// azureUndersizedFields finds the VM size, when it is smaller than the minimum.
+// VM sizes of series that are not known are not checked.
+func azureUndersizedFields(providerSpecPath *field.Path, config providerconfig.AzureProviderConfig, minimum machineSize) []undersizedField {
+	vmSize := config.ExtractInstanceType()
+
+	size, ok := azureVMSizeSize(vmSize)
+	if !ok || !size.smallerThan(minimum) {
+		return nil
+	}
+
+	return []undersizedField{{path: providerSpecPath.Child("vmSize"), value: vmSize, description: fmt.Sprintf("VM size %s (%s)", vmSize, size)}}
+}
+
+// gcpUndersizedFields finds the machine type, when it is smaller than the minimum.
+// Machine types of families that are not known are not checked.
+func gcpUndersizedFields(providerSpecPath *field.Path, config providerconfig.GCPProviderConfig, minimum machineSize) []undersizedField {
+	machineType := config.ExtractInstanceType()
+
+	size, ok := gcpMachineTypeSize(machineType)
+	if !ok || !size.smallerThan(minimum) {
+		return nil
+	}
+
+	return []undersizedField{{path: providerSpecPath.Child("machineType"), value: machineType, description: fmt.Sprintf("machine type %s (%s)", machineType, size)}}
+}
+
+// nutanixUndersizedFields finds the number of vCPUs, or the memory, of the virtual machines, when it is smaller than
+// the minimum. The number of vCPUs is the number of sockets multiplied by the number of vCPUs per socket.
+func nutanixUndersizedFields(providerSpecPath *field.Path, config providerconfig.NutanixProviderConfig, minimum machineSize) []undersizedField {
+	var undersized []undersizedField
+
+	spec := config.Config()
+
+	if vCPUs := int64(spec.VCPUSockets) * int64(spec.VCPUsPerSocket); vCPUs > 0 && vCPUs < minimum.vCPUs {
+		undersized = append(undersized, undersizedField{
+			path:        providerSpecPath.Child("vcpuSockets"),
+			value:       spec.VCPUSockets,
+			description: fmt.Sprintf("%d socket(s) of %d vCPU(s)", spec.VCPUSockets, spec.VCPUsPerSocket),
+		})
+	}
+
+	if memoryMiB := spec.MemorySize.Value() / bytesPerMiB; memoryMiB > 0 && memoryMiB < minimum.memoryMiB {
+		undersized = append(undersized, undersizedField{
+			path:        providerSpecPath.Child("memorySize"),
+			value:       spec.MemorySize.String(),
+			description: fmt.Sprintf("%dMiB of memory", memoryMiB),
+		})
+	}
+
+	return undersized
+}
+
+// vsphereUndersizedFields finds the number of vCPUs, or the memory, of the virtual machines, when it is smaller than
+// the minimum. When omitted, these are taken from the clone template, which cannot be inspected here.
+func vsphereUndersizedFields(providerSpecPath *field.Path, config providerconfig.VSphereProviderConfig, minimum machineSize) []undersizedField {
+	var undersized []undersizedField
+
+	spec := config.Config()
+
+	if spec.NumCPUs > 0 && int64(spec.NumCPUs) < minimum.vCPUs {
+		undersized = append(undersized, undersizedField{path: providerSpecPath.Child("numCPUs"), value: spec.NumCPUs, description: fmt.Sprintf("%d vCPUs", spec.NumCPUs)})
+	}
+
+	if spec.MemoryMiB > 0 && spec.MemoryMiB < minimum.memoryMiB {
+		undersized = append(undersized, undersizedField{path: providerSpecPath.Child("memoryMiB"), value: spec.MemoryMiB, description: fmt.Sprintf("%dMiB of memory", spec.MemoryMiB)})
+	}
+
+	return undersized
+}
+
+// awsInstanceTypeSize returns the size of an AWS instance type with a size of xlarge, or larger, when its instance
+// class is known. An xlarge instance type has 4 vCPUs, and a 2xlarge instance type 8 vCPUs.
+func awsInstanceTypeSize(instanceType string) (machineSize, bool) {
+	parts := strings.Split(instanceType, ".")
+	if len(parts) != 2 || parts[0] == "" {
+		return machineSize{}, false
+	}
+
+	memoryPerVCPU, ok := awsMemoryPerVCPUMiB[parts[0][:1]]
+	if !ok {
+		return machineSize{}, false
+	}
+
+	match := awsInstanceSizeRegexp.FindStringSubmatch(parts[1])
+	if match == nil {
+		return machineSize{}, false
+	}
+
+	multiplier := int64(1)
+
+	if match[1] != "" {
+		var err error
+
+		if multiplier, err = strconv.ParseInt(match[1], 10, 64); err != nil {
+			return machineSize{}, false
+		}
+	}
+
+	vCPUs := 4 * multiplier
+
+	return machineSize{vCPUs: vCPUs, memoryMiB: vCPUs * memoryPerVCPU}, true
+}
+
+// azureVMSizeSize returns the size of an Azure VM size, for example Standard_D4s_v3, when its series is known.
+func azureVMSizeSize(vmSize string) (machineSize, bool) {
+	match := azureVMSizeRegexp.FindStringSubmatch(vmSize)
+	if match == nil {
+		return machineSize{}, false
+	}
+
+	memoryPerVCPU, ok := azureMemoryPerVCPUMiB[match[1]+"_"+match[3]]
+	if !ok {
+		return machineSize{}, false
+	}
+
+	vCPUs, err := strconv.ParseInt(match[2], 10, 64)
+	if err != nil {
+		return machineSize{}, false
+	}
+
+	return machineSize{vCPUs: vCPUs, memoryMiB: vCPUs * memoryPerVCPU}, true
+}
+
+// gcpMachineTypeSize returns the size of a GCP machine type, for example n2-standard-4, when its family is known.
+// Custom machine types, for example custom-4-16384, carry their size within their name.
+func gcpMachineTypeSize(machineType string) (machineSize, bool) {
+	if match := gcpCustomMachineTypeRegexp.FindStringSubmatch(machineType); match != nil {
+		vCPUs, vCPUsErr := strconv.ParseInt(match[1], 10, 64)
+		memoryMiB, memoryErr := strconv.ParseInt(match[2], 10, 64)
+
+		if vCPUsErr != nil || memoryErr != nil {
+			return machineSize{}, false
+		}
+
+		return machineSize{vCPUs: vCPUs, memoryMiB: memoryMiB}, true
+	}
+
+	match := gcpMachineTypeRegexp.FindStringSubmatch(machineType)
+	if match == nil {
+		return machineSize{}, false
+	}
+
+	memoryPerVCPU, ok := gcpMemoryPerVCPUMiB[match[1]][match[2]]
+	if !ok {
+		return machineSize{}, false
+	}
+
+	vCPUs, err := strconv.ParseInt(match[3], 10, 64)
+	if err != nil {
+		return machineSize{}, false
+	}
+
+	return machineSize{vCPUs: vCPUs, memoryMiB: vCPUs * memoryPerVCPU}, true
+}
@@ -18,12 +18,31 @@ package controlplanemachineset
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sort"
+	"strings"
 
+	configv1 "github.com/openshift/api/config/v1"
 	machinev1 "github.com/openshift/api/machine/v1"
+	machinev1beta1 "github.com/openshift/api/machine/v1beta1"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/clusterconfig"
+	cpmscontroller "github.com/openshift/cluster-control-plane-machine-set-operator/pkg/controllers/controlplanemachineset"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/controllers/operatorconfig"
+	openshiftmachinev1beta1 "github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders/providers/openshift/machine/v1beta1"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders/providers/openshift/machine/v1beta1/providerconfig"
+	admissionv1 "k8s.io/api/admission/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation/field"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
 )
 
 const (
@@ -38,20 +57,86 @@ const (
 	// masterMachineRole is the master role/type that is required to be set on
 	// all OpenShift Machine API Machine templates.
 	masterMachineRole = "master"
+
+	// validatingWebhookPath is the path on which the ControlPlaneMachineSet validating webhook is served.
+	validatingWebhookPath = "/validate-machine-openshift-io-v1-controlplanemachineset"
+
+	// rolloutWarningFormat is the format of the warning returned when admitting the ControlPlaneMachineSet
+	// will cause existing control plane Machines to be replaced.
+	rolloutWarningFormat = "%d of %d control plane machine(s) do not match the template and will be replaced, changed provider spec fields: %s"
+
+	// rolloutWarningUnknownFormat is the format of the warning returned when the webhook cannot determine
+	// whether or not admitting the ControlPlaneMachineSet will cause existing control plane Machines to be replaced.
+	rolloutWarningUnknownFormat = "could not determine which control plane machines will be replaced: %v"
+
+	// selectorTemplateMismatchMessage is the error returned when the selector of the ControlPlaneMachineSet does
+	// not match the labels of the Machine template. Machines created from the template would not be selected, and
+	// would be replaced indefinitely.
+	selectorTemplateMismatchMessage = "selector does not match the template labels: ensure every label required by spec.selector is set, with a matching value, in the template labels"
+
+	// vsphereTemplateWarningFormat is the format of the warning returned when admitting the ControlPlaneMachineSet
+	// will clone the control plane virtual machines from a different template. The webhook has no access to vCenter,
+	// so cannot check that the template exists.
+	vsphereTemplateWarningFormat = "control plane machines will be cloned from the vSphere template %q, whose existence within vCenter cannot be verified; if it does not exist, replacement machines will fail to be created and the existing machines will not be removed"
+
+	// unselectedMachinesWarningFormat is the format of the warning returned when admitting the ControlPlaneMachineSet
+	// will leave existing control plane Machines outside of the selector, and therefore unmanaged.
+	unselectedMachinesWarningFormat = "%d of %d control plane machine(s) do not match spec.selector and will not be managed: %s; ensure spec.selector matches the labels of the existing control plane machines"
+)
+
+var (
+	// errObjNotCPMS is used when the object passed to the webhook is not a ControlPlaneMachineSet.
+	errObjNotCPMS = errors.New("object is not a ControlPlaneMachineSet")
 )
 
 // ControlPlaneMachineSetWebhook acts as a webhook validator for the
 // machinev1beta1.ControlPlaneMachineSet resource.
-type ControlPlaneMachineSetWebhook struct{}
+type ControlPlaneMachineSetWebhook struct {
+	// client is used to read the existing control plane Machines when summarising
+	// the rollout that admitting a ControlPlaneMachineSet will trigger.
+	client client.Reader
+
+	// EnableScaleDown allows the replicas of the ControlPlaneMachineSet to be decreased.
+	// The operator does not remove the Machines from the indexes beyond the new replica count, so
+	// decreases are rejected unless this is set.
+	EnableScaleDown bool
+
+	// RejectUndersizedMachines rejects templates that configure control plane Machines smaller than the minimum for
+	// the platform. Otherwise, such templates are admitted with a warning.
+	RejectUndersizedMachines bool
+
+	// AzureDiskSKUCatalog provides the zones in which Azure disk SKUs are available. When set, templates that use a
+	// premium or ultra disk SKU that is unavailable in one of their Azure failure domains are rejected.
+	AzureDiskSKUCatalog AzureDiskSKUCatalog
+
+	// AWSKMSKeyCatalog provides the KMS keys that exist within each AWS region. When set, templates that encrypt a
+	// volume with a KMS key that does not exist are rejected.
+	AWSKMSKeyCatalog AWSKMSKeyCatalog
+
+	// OperatorConfig holds the runtime configuration of the operator, which may override EnableScaleDown and
+	// RejectUndersizedMachines without restarting the operator. When nil, the fields of the webhook apply.
+	OperatorConfig *operatorconfig.Store
+
+	// ControlPlaneMachineSetName is the name of the ControlPlaneMachineSet singleton that the controller reconciles.
+	// When set, the creation of a ControlPlaneMachineSet with any other name is rejected, as the controller would
+	// ignore it. When empty, the name is not validated.
+	ControlPlaneMachineSetName string
+}
 
 // SetupWebhookWithManager sets up a new ControlPlaneMachineSet webhook with the manager.
+// The validator is wrapped so that, once a request has been allowed, warnings describing the
+// rollout the request will trigger can be added to the response.
 func (r *ControlPlaneMachineSetWebhook) SetupWebhookWithManager(mgr ctrl.Manager) error {
-	if err := ctrl.NewWebhookManagedBy(mgr).
-		WithValidator(r).
-		For(&machinev1.ControlPlaneMachineSet{}).
-		Complete(); err != nil {
-		return fmt.Errorf("error constructing ControlPlaneMachineSet webhook: %w", err)
-	}
+	r.client = mgr.GetAPIReader()
+
+	validator := admission.WithCustomValidator(&machinev1.ControlPlaneMachineSet{}, r)
+
+	mgr.GetWebhookServer().Register(validatingWebhookPath, &webhook.Admission{
+		Handler: &rolloutWarningHandler{
+			validator: validator.Handler,
+			webhook:   r,
+		},
+	})
 
 	return nil
 }
@@ -62,11 +147,74 @@ var _ webhook.CustomValidator = &ControlPlaneMachineSetWebhook{}
 
 // ValidateCreate implements webhook.Validator so a webhook will be registered for the type.
 func (r *ControlPlaneMachineSetWebhook) ValidateCreate(ctx context.Context, obj runtime.Object) error {
+	cpms, ok := obj.(*machinev1.ControlPlaneMachineSet)
+	if !ok {
+		return errObjNotCPMS
+	}
+
+	errs := r.validateName(field.NewPath("metadata", "name"), cpms.Name)
+	errs = append(errs, cpmscontroller.ValidateAnnotations(field.NewPath("metadata", "annotations"), cpms.GetAnnotations())...)
+	errs = append(errs, openshiftmachinev1beta1.ValidateAnnotations(ctx, r.client, field.NewPath("metadata", "annotations"), cpms)...)
+	errs = append(errs, validateSelectorMatchesTemplate(field.NewPath("spec"), cpms.Spec)...)
+
+	azureStackHub, err := r.isAzureStackHub(ctx, cpms.Spec.Template)
+	if err != nil {
+		errs = append(errs, field.InternalError(field.NewPath("spec", "template"), err))
+	}
+
+	errs = append(errs, validateTemplate(field.NewPath("spec", "template"), cpms.Spec.Template, azureStackHub)...)
+	errs = append(errs, r.validateTemplateSize(field.NewPath("spec", "template"), nil, cpms.Spec.Template)...)
+
+	if !azureStackHub {
+		errs = append(errs, r.validateAzureDiskZones(field.NewPath("spec", "template"), cpms.Spec.Template)...)
+	}
+
+	errs = append(errs, r.validateAWSKMSKeys(field.NewPath("spec", "template"), cpms.Spec.Template)...)
+
+	if len(errs) > 0 {
+		return apierrors.NewInvalid(schema.GroupKind{Group: machinev1.GroupName, Kind: "ControlPlaneMachineSet"}, cpms.Name, errs)
+	}
+
 	return nil
 }
 
 // ValidateUpdate implements webhook.Validator so a webhook will be registered for the type.
 func (r *ControlPlaneMachineSetWebhook) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) error {
+	oldCPMS, ok := oldObj.(*machinev1.ControlPlaneMachineSet)
+	if !ok {
+		return errObjNotCPMS
+	}
+
+	newCPMS, ok := newObj.(*machinev1.ControlPlaneMachineSet)
+	if !ok {
+		return errObjNotCPMS
+	}
+
+	errs := cpmscontroller.ValidateAnnotations(field.NewPath("metadata", "annotations"), newCPMS.GetAnnotations())
+	errs = append(errs, openshiftmachinev1beta1.ValidateAnnotations(ctx, r.client, field.NewPath("metadata", "annotations"), newCPMS)...)
+	errs = append(errs, validateSelectorMatchesTemplate(field.NewPath("spec"), newCPMS.Spec)...)
+
+	azureStackHub, err := r.isAzureStackHub(ctx, newCPMS.Spec.Template)
+	if err != nil {
+		errs = append(errs, field.InternalError(field.NewPath("spec", "template"), err))
+	}
+
+	errs = append(errs, validateTemplate(field.NewPath("spec", "template"), newCPMS.Spec.Template, azureStackHub)...)
+	errs = append(errs, r.validateTemplateSize(field.NewPath("spec", "template"), &oldCPMS.Spec.Template, newCPMS.Spec.Template)...)
+
+	if !azureStackHub {
+		errs = append(errs, r.validateAzureDiskZones(field.NewPath("spec", "template"), newCPMS.Spec.Template)...)
+	}
+
+	errs = append(errs, r.validateAWSKMSKeys(field.NewPath("spec", "template"), newCPMS.Spec.Template)...)
+
+	errs = append(errs, validateTemplateUpdate(field.NewPath("spec", "template"), oldCPMS.Spec.Template, newCPMS.Spec.Template)...)
+	errs = append(errs, r.validateReplicasUpdate(ctx, field.NewPath("spec", "replicas"), oldCPMS.Spec.Replicas, newCPMS.Spec.Replicas)...)
+
+	if len(errs) > 0 {
+		return apierrors.NewInvalid(schema.GroupKind{Group: machinev1.GroupName, Kind: "ControlPlaneMachineSet"}, newCPMS.Name, errs)
+	}
+
 	return nil
 }
 
@@ -74,3 +222,546 @@ func (r *ControlPlaneMachineSetWebhook) ValidateUpdate(ctx context.Context, oldO
 func (r *ControlPlaneMachineSetWebhook) ValidateDelete(ctx context.Context, obj runtime.Object) error {
 	return nil
 }
+
+// validateName checks that the ControlPlaneMachineSet has the name of the singleton that the controller reconciles.
+// ControlPlaneMachineSets are singletons, so a ControlPlaneMachineSet with any other name would never be reconciled.
+func (r *ControlPlaneMachineSetWebhook) validateName(namePath *field.Path, name string) field.ErrorList {
+	if r.ControlPlaneMachineSetName == "" || name == r.ControlPlaneMachineSetName {
+		return field.ErrorList{}
+	}
+
+	return field.ErrorList{
+		field.Invalid(namePath, name, fmt.Sprintf("ControlPlaneMachineSet is a singleton and must be named %q", r.ControlPlaneMachineSetName)),
+	}
+}
+
+// validateSelectorMatchesTemplate checks that the selector of the ControlPlaneMachineSet matches the labels of the
+// Machine template, so that the Machines created from the template are managed by the ControlPlaneMachineSet.
+func validateSelectorMatchesTemplate(specPath *field.Path, spec machinev1.ControlPlaneMachineSetSpec) field.ErrorList {
+	selector, err := metav1.LabelSelectorAsSelector(&spec.Selector)
+	if err != nil {
+		return field.ErrorList{field.Invalid(specPath.Child("selector"), spec.Selector, fmt.Sprintf("could not parse selector: %v", err))}
+	}
+
+	if spec.Template.OpenShiftMachineV1Beta1Machine == nil {
+		return nil
+	}
+
+	templateLabels := spec.Template.OpenShiftMachineV1Beta1Machine.ObjectMeta.Labels
+	if !selector.Matches(labels.Set(templateLabels)) {
+		labelsPath := specPath.Child("template", "machines_v1beta1_machine_openshift_io", "metadata", "labels")
+
+		return field.ErrorList{field.Invalid(labelsPath, templateLabels, selectorTemplateMismatchMessage)}
+	}
+
+	return nil
+}
+
+// validateTemplate validates the provider spec of the template of the ControlPlaneMachineSet.
+// Templates that cannot be parsed are not validated here. Azure templates on Azure Stack Hub are validated against
+// the features that Azure Stack Hub supports, rather than those of the public Azure clouds.
+func validateTemplate(templatePath *field.Path, template machinev1.ControlPlaneMachineSetTemplate, azureStackHub bool) field.ErrorList {
+	if template.OpenShiftMachineV1Beta1Machine == nil {
+		return nil
+	}
+
+	providerConfig, err := providerconfig.NewProviderConfig(*template.OpenShiftMachineV1Beta1Machine)
+	if err != nil {
+		return nil
+	}
+
+	providerSpecPath := templatePath.Child("machines_v1beta1_machine_openshift_io", "spec", "providerSpec", "value")
+
+	switch providerConfig.Type() {
+	case configv1.AWSPlatformType:
+		errs := validateAWSInstanceRequirements(providerSpecPath, providerConfig.AWS())
+		errs = append(errs, validateAWSBlockDeviceEncryption(providerSpecPath, providerConfig.AWS())...)
+
+		return append(errs, validateAWSEdgeZones(templatePath, providerSpecPath, template.OpenShiftMachineV1Beta1Machine.FailureDomains, providerConfig.AWS())...)
+	case configv1.VSpherePlatformType:
+		return validateVSphereTemplate(providerSpecPath, providerConfig.VSphere())
+	case configv1.AzurePlatformType:
+		if azureStackHub {
+			return validateAzureStackHubTemplate(templatePath.Child("machines_v1beta1_machine_openshift_io"), template.OpenShiftMachineV1Beta1Machine.FailureDomains, providerConfig.Azure())
+		}
+
+		return validateAzureTemplate(providerSpecPath, providerConfig.Azure())
+	default:
+		return nil
+	}
+}
+
+// validateAWSInstanceRequirements checks that, when the instance type is selected by attribute, the instance
+// requirements can be used to select an instance type.
+// The instance type and the instance requirements are mutually exclusive, and both the vCPU count and the memory
+// must have a minimum, which must not exceed the maximum.
+func validateAWSInstanceRequirements(providerSpecPath *field.Path, config providerconfig.AWSProviderConfig) field.ErrorList {
+	requirements := config.InstanceRequirements()
+	if requirements == nil {
+		return nil
+	}
+
+	requirementsPath := providerSpecPath.Child("instanceRequirements")
+
+	var errs field.ErrorList
+
+	if config.Config().InstanceType != "" {
+		errs = append(errs, field.Forbidden(requirementsPath, "instanceRequirements cannot be set when instanceType is set"))
+	}
+
+	errs = append(errs, validateAWSInstanceRequirementsRange(requirementsPath.Child("vCpuCount"), requirements.VCPUCount)...)
+	errs = append(errs, validateAWSInstanceRequirementsRange(requirementsPath.Child("memoryMiB"), requirements.MemoryMiB)...)
+
+	return errs
+}
+
+// validateVSphereTemplate checks that the clone template is set, as every control plane virtual machine is cloned
+// from it. Any change to the template causes the Machines to be replaced.
+// Whether the template exists within vCenter cannot be determined without access to vCenter, so this is left to
+// the Machine API, which fails to create the replacement Machine when the template does not exist.
+func validateVSphereTemplate(providerSpecPath *field.Path, config providerconfig.VSphereProviderConfig) field.ErrorList {
+	if config.ExtractTemplate() == "" {
+		return field.ErrorList{field.Required(providerSpecPath.Child("template"), "a template is required to clone the control plane virtual machines from")}
+	}
+
+	return nil
+}
+
+// validateAzureTemplate checks that the managed identity and the boot diagnostics of the template can be used to
+// create the control plane virtual machines.
+// A managed identity given by resource ID must be the resource ID of a user assigned identity, a bare name is resolved
+// by the Machine API to an identity within the resource group of the Machine.
+// Accelerated networking is not validated, as whether the VM size supports it cannot be determined without access to
+// Azure.
+func validateAzureTemplate(providerSpecPath *field.Path, config providerconfig.AzureProviderConfig) field.ErrorList {
+	var errs field.ErrorList
+
+	if identity := config.Config().ManagedIdentity; strings.HasPrefix(identity, "/") {
+		if _, _, ok := providerconfig.ParseAzureUserAssignedIdentityID(identity); !ok {
+			errs = append(errs, field.Invalid(providerSpecPath.Child("managedIdentity"), identity,
+				"must be the name or the resource ID of a user assigned managed identity, eg /subscriptions/<subscription>/resourceGroups/<resource group>/providers/Microsoft.ManagedIdentity/userAssignedIdentities/<name>",
+			))
+		}
+	}
+
+	if diagnostics := config.Diagnostics(); diagnostics != nil && diagnostics.Boot != nil {
+		errs = append(errs, validateAzureBootDiagnostics(providerSpecPath.Child("diagnostics", "boot"), diagnostics.Boot)...)
+	}
+
+	return errs
+}
+
+// validateAzureBootDiagnostics checks that the storage account type of the boot diagnostics is known, and that a
+// storage account URI is given when, and only when, the storage account is managed by the user.
+func validateAzureBootDiagnostics(bootPath *field.Path, boot *providerconfig.AzureBootDiagnostics) field.ErrorList {
+	customerManagedPath := bootPath.Child("customerManaged")
+
+	switch boot.StorageAccountType {
+	case providerconfig.AzureManagedBootDiagnosticsStorage:
+		if boot.CustomerManaged != nil {
+			return field.ErrorList{field.Forbidden(customerManagedPath, "customerManaged cannot be set when the storage account type is AzureManaged")}
+		}
+	case providerconfig.CustomerManagedBootDiagnosticsStorage:
+		if boot.CustomerManaged == nil || boot.CustomerManaged.StorageAccountURI == "" {
+			return field.ErrorList{field.Required(customerManagedPath.Child("storageAccountURI"), "a storage account URI is required when the storage account type is CustomerManaged")}
+		}
+
+		if !strings.HasPrefix(boot.CustomerManaged.StorageAccountURI, "https://") {
+			return field.ErrorList{field.Invalid(customerManagedPath.Child("storageAccountURI"), boot.CustomerManaged.StorageAccountURI, "must be an https URI")}
+		}
+	case "":
+		return field.ErrorList{field.Required(bootPath.Child("storageAccountType"), "a storage account type is required when configuring boot diagnostics")}
+	default:
+		return field.ErrorList{field.NotSupported(bootPath.Child("storageAccountType"), boot.StorageAccountType, []string{
+			string(providerconfig.AzureManagedBootDiagnosticsStorage),
+			string(providerconfig.CustomerManagedBootDiagnosticsStorage),
+		})}
+	}
+
+	return nil
+}
+
+// validateAWSInstanceRequirementsRange checks that the range has a non-negative minimum, and that the
+// maximum, when set, is not less than the minimum.
+func validateAWSInstanceRequirementsRange(rangePath *field.Path, requirementsRange *providerconfig.AWSInstanceRequirementsRange) field.ErrorList {
+	if requirementsRange == nil || requirementsRange.Min == nil {
+		return field.ErrorList{field.Required(rangePath.Child("min"), "a minimum is required when selecting the instance type by attribute")}
+	}
+
+	if *requirementsRange.Min < 0 {
+		return field.ErrorList{field.Invalid(rangePath.Child("min"), *requirementsRange.Min, "must not be negative")}
+	}
+
+	if requirementsRange.Max != nil && *requirementsRange.Max < *requirementsRange.Min {
+		return field.ErrorList{field.Invalid(rangePath.Child("max"), *requirementsRange.Max, fmt.Sprintf("must not be less than the minimum of %d", *requirementsRange.Min))}
+	}
+
+	return nil
+}
+
+// validateReplicasUpdate checks that the change to the replicas of the ControlPlaneMachineSet, if any, is a
+// supported transition. The control plane topology of the cluster is only read when the replicas are decreased to a
+// single control plane Machine, as this is only supported on single node clusters.
+func (r *ControlPlaneMachineSetWebhook) validateReplicasUpdate(ctx context.Context, replicasPath *field.Path, oldReplicas, newReplicas *int32) field.ErrorList {
+	if oldReplicas == nil || newReplicas == nil || *oldReplicas == *newReplicas {
+		return nil
+	}
+
+	singleNode := false
+
+	if *newReplicas == 1 {
+		infrastructure := &configv1.Infrastructure{}
+		if err := r.client.Get(ctx, client.ObjectKey{Name: clusterconfig.InfrastructureName}, infrastructure); err != nil {
+			return field.ErrorList{field.InternalError(replicasPath, fmt.Errorf("could not determine the control plane topology: %w", err))}
+		}
+
+		singleNode = infrastructure.Status.ControlPlaneTopology == configv1.SingleReplicaTopologyMode
+	}
+
+	return validateReplicasTransition(replicasPath, *oldReplicas, *newReplicas, singleNode, r.OperatorConfig.EnableScaleDown(r.EnableScaleDown))
+}
+
+// validateReplicasTransition checks that the replicas of the ControlPlaneMachineSet may be changed from the old to the
+// new value. Each check returns a specific error, rather than relying on the replicas being immutable:
+// - An even number of replicas is forbidden, as it does not improve the fault tolerance of etcd over one fewer member.
+// - A single replica is forbidden outside of single node clusters, as etcd would have no fault tolerance.
+// - Decreases are forbidden unless scale down is enabled, as the operator does not remove the excess Machines.
+func validateReplicasTransition(replicasPath *field.Path, oldReplicas, newReplicas int32, singleNode, scaleDownEnabled bool) field.ErrorList {
+	switch {
+	case newReplicas%2 == 0:
+		return field.ErrorList{field.Invalid(replicasPath, newReplicas,
+			"an even number of control plane machines is not supported, as it does not improve the fault tolerance of etcd over one fewer machine",
+		)}
+	case newReplicas == 1 && !singleNode:
+		return field.ErrorList{field.Forbidden(replicasPath,
+			fmt.Sprintf("cannot decrease replicas from %d to 1: a single control plane machine is only supported on single node clusters", oldReplicas),
+		)}
+	case newReplicas < oldReplicas && !scaleDownEnabled:
+		return field.ErrorList{field.Forbidden(replicasPath,
+			fmt.Sprintf("cannot decrease replicas from %d to %d: decreasing the number of control plane machines requires control plane scale down to be enabled", oldReplicas, newReplicas),
+		)}
+	default:
+		return nil
+	}
+}
+
+// validateTemplateUpdate validates changes to the template of the ControlPlaneMachineSet that cannot be
+// rolled out to the control plane Machines.
+// Templates that cannot be parsed are not validated here.
+func validateTemplateUpdate(templatePath *field.Path, oldTemplate, newTemplate machinev1.ControlPlaneMachineSetTemplate) field.ErrorList {
+	if oldTemplate.OpenShiftMachineV1Beta1Machine == nil || newTemplate.OpenShiftMachineV1Beta1Machine == nil {
+		return nil
+	}
+
+	oldProviderConfig, err := providerconfig.NewProviderConfig(*oldTemplate.OpenShiftMachineV1Beta1Machine)
+	if err != nil {
+		return nil
+	}
+
+	newProviderConfig, err := providerconfig.NewProviderConfig(*newTemplate.OpenShiftMachineV1Beta1Machine)
+	if err != nil {
+		return nil
+	}
+
+	if oldProviderConfig.Type() != newProviderConfig.Type() {
+		return nil
+	}
+
+	providerSpecPath := templatePath.Child("machines_v1beta1_machine_openshift_io", "spec", "providerSpec", "value")
+
+	switch newProviderConfig.Type() {
+	case configv1.AWSPlatformType:
+		return validateAWSBlockDeviceSizes(providerSpecPath, oldProviderConfig.AWS(), newProviderConfig.AWS())
+	case configv1.GCPPlatformType:
+		return validateGCPDiskSizes(providerSpecPath, oldProviderConfig.GCP(), newProviderConfig.GCP())
+	default:
+		return nil
+	}
+}
+
+// validateAWSBlockDeviceSizes checks that the volume size of no block device is decreased.
+// Increasing the volume size is allowed and causes the Machines to be replaced. AWS cannot launch an
+// instance with a volume smaller than the snapshot it is created from, so decreases are rejected at
+// admission rather than causing a rollout that may never complete.
+func validateAWSBlockDeviceSizes(providerSpecPath *field.Path, oldConfig, newConfig providerconfig.AWSProviderConfig) field.ErrorList {
+	var errs field.ErrorList
+
+	for _, decrease := range oldConfig.BlockDeviceSizeDecreases(newConfig) {
+		errs = append(errs, field.Forbidden(
+			providerSpecPath.Child("blockDevices").Index(decrease.Index).Child("ebs", "volumeSize"),
+			fmt.Sprintf("volume size of block device %s cannot be decreased from %dGiB to %dGiB", decrease.DeviceName, decrease.CurrentSize, decrease.DesiredSize),
+		))
+	}
+
+	return errs
+}
+
+// validateGCPDiskSizes checks that the size of no disk is decreased.
+// Increasing the size is allowed and causes the Machines to be replaced. GCP cannot create a disk
+// smaller than the image it is created from, so decreases are rejected at admission rather than
+// causing a rollout that may never complete.
+func validateGCPDiskSizes(providerSpecPath *field.Path, oldConfig, newConfig providerconfig.GCPProviderConfig) field.ErrorList {
+	var errs field.ErrorList
+
+	for _, decrease := range oldConfig.DiskSizeDecreases(newConfig) {
+		errs = append(errs, field.Forbidden(
+			providerSpecPath.Child("disks").Index(decrease.Index).Child("sizeGb"),
+			fmt.Sprintf("disk size cannot be decreased from %dGB to %dGB", decrease.CurrentSize, decrease.DesiredSize),
+		))
+	}
+
+	return errs
+}
+
+// rolloutWarningHandler wraps the ControlPlaneMachineSet validator and adds admission warnings
+// summarising the rollout that an allowed request will trigger.
+type rolloutWarningHandler struct {
+	validator admission.Handler
+	webhook   *ControlPlaneMachineSetWebhook
+	decoder   *admission.Decoder
+}
+
+var _ admission.DecoderInjector = &rolloutWarningHandler{}
+
+// InjectDecoder injects the decoder into the handler and the validator it wraps.
+func (h *rolloutWarningHandler) InjectDecoder(d *admission.Decoder) error {
+	h.decoder = d
+
+	if _, err := admission.InjectDecoderInto(d, h.validator); err != nil {
+		return fmt.Errorf("could not inject decoder into validator: %w", err)
+	}
+
+	return nil
+}
+
+// Handle validates the request and, when the request is allowed, warns about any control plane Machines
+// that will be replaced, or that will no longer be managed, as a result of the request.
+// Configurations that are valid, but suspicious, such as undersized control plane Machines, are also reported as
+// warnings rather than being rejected, so that they inform users without blocking automation. Undersized control
+// plane Machines are only rejected when RejectUndersizedMachines is set.
+// Rollout and template warnings are only added when the ControlPlaneMachineSet is created, or when its template is updated,
+// as these are the requests that may cause an unexpected rollout of the control plane. Likewise, warnings about
+// unselected Machines are only added when the ControlPlaneMachineSet is created, or when its selector is updated.
+// Each decision, and each warning, is recorded within the admission metrics.
+func (h *rolloutWarningHandler) Handle(ctx context.Context, req admission.Request) admission.Response {
+	resp := h.handle(ctx, req)
+	recordAdmissionDecision(req.Operation, resp)
+
+	return resp
+}
+
+// handle validates the request and adds the warnings described by Handle.
+func (h *rolloutWarningHandler) handle(ctx context.Context, req admission.Request) admission.Response {
+	resp := h.validator.Handle(ctx, req)
+	if !resp.Allowed {
+		return resp
+	}
+
+	cpms := &machinev1.ControlPlaneMachineSet{}
+	oldCPMS := &machinev1.ControlPlaneMachineSet{}
+
+	var templateChanged, selectorChanged bool
+
+	switch req.Operation {
+	case admissionv1.Create:
+		if err := h.decoder.DecodeRaw(req.Object, cpms); err != nil {
+			return resp
+		}
+
+		templateChanged, selectorChanged = true, true
+	case admissionv1.Update:
+		if err := h.decoder.DecodeRaw(req.Object, cpms); err != nil {
+			return resp
+		}
+
+		if err := h.decoder.DecodeRaw(req.OldObject, oldCPMS); err != nil {
+			return resp
+		}
+
+		templateChanged = !equality.Semantic.DeepEqual(oldCPMS.Spec.Template, cpms.Spec.Template)
+		selectorChanged = !equality.Semantic.DeepEqual(oldCPMS.Spec.Selector, cpms.Spec.Selector)
+	default:
+		return resp
+	}
+
+	warnings := []string{}
+
+	if templateChanged {
+		warnings = append(warnings, recordAdmissionWarnings(req.Operation, warningReasonUndersizedMachine, templateWarnings(field.NewPath("spec", "template"), cpms.Spec.Template))...)
+		warnings = append(warnings, recordAdmissionWarnings(req.Operation, warningReasonAWSEdgeZone, awsEdgeZoneWarnings(field.NewPath("spec", "template"), cpms.Spec.Template))...)
+		warnings = append(warnings, recordAdmissionWarnings(req.Operation, warningReasonMachinesReplaced, h.webhook.rolloutWarnings(ctx, cpms))...)
+		warnings = append(warnings, recordAdmissionWarnings(req.Operation, warningReasonUnverifiedTemplate, vsphereTemplateWarnings(oldCPMS, cpms))...)
+	}
+
+	if selectorChanged {
+		warnings = append(warnings, recordAdmissionWarnings(req.Operation, warningReasonMachinesUnmanaged, h.webhook.unselectedMachineWarnings(ctx, cpms))...)
+	}
+
+	if len(warnings) == 0 {
+		return resp
+	}
+
+	return resp.WithWarnings(warnings...)
+}
+
+// vsphereTemplateWarnings returns a warning when the ControlPlaneMachineSet clones the control plane virtual machines
+// from a different vSphere template than before, or, when the ControlPlaneMachineSet is created, from any template.
+// The existence of the template within vCenter cannot be checked, so the user is warned instead.
+func vsphereTemplateWarnings(oldCPMS, cpms *machinev1.ControlPlaneMachineSet) []string {
+	template := vsphereTemplate(cpms)
+	if template == "" || template == vsphereTemplate(oldCPMS) {
+		return nil
+	}
+
+	return []string{fmt.Sprintf(vsphereTemplateWarningFormat, template)}
+}
+
+// vsphereTemplate returns the vSphere clone template of the ControlPlaneMachineSet, or an empty string when the
+// ControlPlaneMachineSet does not have a vSphere provider spec.
+func vsphereTemplate(cpms *machinev1.ControlPlaneMachineSet) string {
+	if cpms.Spec.Template.OpenShiftMachineV1Beta1Machine == nil {
+		return ""
+	}
+
+	providerConfig, err := providerconfig.NewProviderConfig(*cpms.Spec.Template.OpenShiftMachineV1Beta1Machine)
+	if err != nil || providerConfig.Type() != configv1.VSpherePlatformType {
+		return ""
+	}
+
+	return providerConfig.VSphere().ExtractTemplate()
+}
+
+// unselectedMachineWarnings returns a warning listing the existing control plane Machines, identified by their
+// role label, that are not matched by the selector of the ControlPlaneMachineSet. These Machines will not be managed
+// by the ControlPlaneMachineSet, and when no Machine is matched, the ControlPlaneMachineSet will be degraded.
+func (r *ControlPlaneMachineSetWebhook) unselectedMachineWarnings(ctx context.Context, cpms *machinev1.ControlPlaneMachineSet) []string {
+	if r.client == nil {
+		return nil
+	}
+
+	selector, err := metav1.LabelSelectorAsSelector(&cpms.Spec.Selector)
+	if err != nil {
+		return nil
+	}
+
+	machineList := &machinev1beta1.MachineList{}
+	if err := r.client.List(ctx, machineList, client.InNamespace(cpms.Namespace), client.MatchingLabels{openshiftMachineRoleLabel: masterMachineRole}); err != nil {
+		return nil
+	}
+
+	unselected := []string{}
+
+	for _, machine := range machineList.Items {
+		if !selector.Matches(labels.Set(machine.GetLabels())) {
+			unselected = append(unselected, machine.GetName())
+		}
+	}
+
+	if len(unselected) == 0 {
+		return nil
+	}
+
+	sort.Strings(unselected)
+
+	return []string{fmt.Sprintf(unselectedMachinesWarningFormat, len(unselected), len(machineList.Items), strings.Join(unselected, ", "))}
+}
+
+// rolloutWarnings compares the template of the ControlPlaneMachineSet with the existing control plane
+// Machines and returns a warning summarising how many of the Machines will be replaced, and which fields
+// of their provider spec will change.
+// The failure domain of each Machine is injected into the template before comparing, so that only
+// changes to the configuration of the Machines, rather than their placement, are reported.
+func (r *ControlPlaneMachineSetWebhook) rolloutWarnings(ctx context.Context, cpms *machinev1.ControlPlaneMachineSet) []string {
+	if r.client == nil || cpms.Spec.Template.OpenShiftMachineV1Beta1Machine == nil {
+		return nil
+	}
+
+	selector, err := metav1.LabelSelectorAsSelector(&cpms.Spec.Selector)
+	if err != nil {
+		return []string{fmt.Sprintf(rolloutWarningUnknownFormat, fmt.Errorf("could not parse selector: %w", err))}
+	}
+
+	machineList := &machinev1beta1.MachineList{}
+	if err := r.client.List(ctx, machineList, client.InNamespace(cpms.Namespace), client.MatchingLabelsSelector{Selector: selector}); err != nil {
+		return []string{fmt.Sprintf(rolloutWarningUnknownFormat, fmt.Errorf("could not list machines: %w", err))}
+	}
+
+	if len(machineList.Items) == 0 {
+		return nil
+	}
+
+	templateProviderConfig, err := providerconfig.NewProviderConfig(*cpms.Spec.Template.OpenShiftMachineV1Beta1Machine)
+	if err != nil {
+		return []string{fmt.Sprintf(rolloutWarningUnknownFormat, fmt.Errorf("could not parse template provider spec: %w", err))}
+	}
+
+	ownedFields, err := providerconfig.ParseOwnedFields(cpms.GetAnnotations())
+	if err != nil {
+		return []string{fmt.Sprintf(rolloutWarningUnknownFormat, fmt.Errorf("could not parse externally owned fields: %w", err))}
+	}
+
+	replaced := 0
+	changedFields := map[string]struct{}{}
+
+	for _, machine := range machineList.Items {
+		fields, err := machineChangedFields(templateProviderConfig, machine, ownedFields)
+		if err != nil {
+			return []string{fmt.Sprintf(rolloutWarningUnknownFormat, fmt.Errorf("could not compare machine %s: %w", machine.Name, err))}
+		}
+
+		if len(fields) == 0 {
+			continue
+		}
+
+		replaced++
+
+		for _, field := range fields {
+			changedFields[field] = struct{}{}
+		}
+	}
+
+	if replaced == 0 {
+		return nil
+	}
+
+	fieldNames := []string{}
+	for field := range changedFields {
+		fieldNames = append(fieldNames, field)
+	}
+
+	sort.Strings(fieldNames)
+
+	return []string{fmt.Sprintf(rolloutWarningFormat, replaced, len(machineList.Items), strings.Join(fieldNames, ", "))}
+}
+
+// machineChangedFields returns the provider spec fields of the Machine that differ from the template,
+// once the failure domain of the Machine has been injected into the template.
+// Fields owned by other controllers are excluded, as they do not cause Machines to be replaced.
+func machineChangedFields(templateProviderConfig providerconfig.ProviderConfig, machine machinev1beta1.Machine, ownedFields providerconfig.OwnedFields) ([]string, error) {
+	machineProviderConfig, err := providerconfig.NewProviderConfigFromMachineSpec(machine.Spec)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse machine provider spec: %w", err)
+	}
+
+	desiredProviderConfig, err := templateProviderConfig.InjectFailureDomain(machineProviderConfig.ExtractFailureDomain())
+	if err != nil {
+		return nil, fmt.Errorf("could not inject failure domain: %w", err)
+	}
+
+	desiredProviderConfig, err = ownedFields.Remove(desiredProviderConfig)
+	if err != nil {
+		return nil, fmt.Errorf("could not remove externally owned fields from template: %w", err)
+	}
+
+	machineProviderConfig, err = ownedFields.Remove(machineProviderConfig)
+	if err != nil {
+		return nil, fmt.Errorf("could not remove externally owned fields from machine: %w", err)
+	}
+
+	fields, err := machineProviderConfig.ChangedFields(desiredProviderConfig)
+	if err != nil {
+		return nil, fmt.Errorf("could not compare provider specs: %w", err)
+	}
+
+	return fields, nil
+}
@@ -18,18 +18,56 @@ package controlplanemachineset
 
 import (
 	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	configv1 "github.com/openshift/api/config/v1"
 	machinev1 "github.com/openshift/api/machine/v1"
 	machinev1beta1 "github.com/openshift/api/machine/v1beta1"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/clusterconfig"
+	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/machineproviders/providers/openshift/machine/v1beta1/providerconfig"
 	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/test"
 	"github.com/openshift/cluster-control-plane-machine-set-operator/pkg/test/resourcebuilder"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/client-go/rest"
+	"k8s.io/utils/pointer"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/envtest/komega"
 )
 
+// warningRecorder records the warnings returned by the API server.
+type warningRecorder struct {
+	lock     sync.Mutex
+	warnings []string
+}
+
+// HandleWarningHeader implements rest.WarningHandler to record the warnings.
+func (w *warningRecorder) HandleWarningHeader(code int, agent string, text string) {
+	if code != 299 || text == "" {
+		return
+	}
+
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	w.warnings = append(w.warnings, text)
+}
+
+// Warnings returns the warnings recorded so far.
+func (w *warningRecorder) Warnings() []string {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	return append([]string{}, w.warnings...)
+}
+
 var _ = Describe("Webhooks", func() {
 	var mgrCancel context.CancelFunc
 	var mgrDone chan struct{}
@@ -52,7 +90,9 @@ var _ = Describe("Webhooks", func() {
 		})
 		Expect(err).ToNot(HaveOccurred(), "Manager should be able to be created")
 
-		wh := &ControlPlaneMachineSetWebhook{}
+		wh := &ControlPlaneMachineSetWebhook{
+			ControlPlaneMachineSetName: "cluster",
+		}
 		Expect(wh.SetupWebhookWithManager(mgr)).To(Succeed(), "Webhook should be able to register with manager")
 
 		By("Starting the manager")
@@ -66,6 +106,17 @@ var _ = Describe("Webhooks", func() {
 
 			Expect(mgr.Start(mgrCtx)).To(Succeed())
 		}()
+
+		By("Waiting for the webhook server to accept connections")
+		webhookAddress := net.JoinHostPort(testEnv.WebhookInstallOptions.LocalServingHost, strconv.Itoa(testEnv.WebhookInstallOptions.LocalServingPort))
+		Eventually(func() error {
+			conn, err := net.Dial("tcp", webhookAddress)
+			if err != nil {
+				return err
+			}
+
+			return conn.Close()
+		}).Should(Succeed(), "Webhook server should start serving")
 	})
 
 	AfterEach(func() {
@@ -92,13 +143,47 @@ var _ = Describe("Webhooks", func() {
 		})
 
 		It("with a valid spec", func() {
+			allowed := counterValue(admissionRequestsTotal, "CREATE", decisionAllowed)
+
 			cpms := builder.Build()
 			Expect(k8sClient.Create(ctx, cpms)).To(Succeed())
+
+			Expect(counterValue(admissionRequestsTotal, "CREATE", decisionAllowed)).To(Equal(allowed+1), "The allowed request should be counted")
+		})
+
+		It("with a valid Alibaba Cloud spec", func() {
+			cpms := builder.WithMachineTemplateBuilder(resourcebuilder.OpenShiftMachineV1Beta1Template().WithFailureDomainsBuilder(nil).WithProviderSpecBuilder(
+				resourcebuilder.AlibabaCloudProviderSpec(),
+			)).Build()
+
+			Expect(k8sClient.Create(ctx, cpms)).To(Succeed())
+		})
+
+		It("with a valid BareMetal spec", func() {
+			cpms := builder.WithMachineTemplateBuilder(resourcebuilder.OpenShiftMachineV1Beta1Template().WithFailureDomainsBuilder(nil).WithProviderSpecBuilder(
+				resourcebuilder.BareMetalProviderSpec(),
+			)).Build()
+
+			Expect(k8sClient.Create(ctx, cpms)).To(Succeed())
 		})
 
-		PIt("with a disallowed name", func() {
+		It("with a valid EquinixMetal spec", func() {
+			cpms := builder.WithMachineTemplateBuilder(resourcebuilder.OpenShiftMachineV1Beta1Template().WithFailureDomainsBuilder(nil).WithProviderSpecBuilder(
+				resourcebuilder.EquinixMetalProviderSpec(),
+			)).Build()
+
+			Expect(k8sClient.Create(ctx, cpms)).To(Succeed())
+		})
+
+		It("with a disallowed name", func() {
+			denied := counterValue(admissionRequestsTotal, "CREATE", decisionDenied)
+			denials := counterValue(admissionDenialsTotal, "CREATE", "metadata.name", "FieldValueInvalid")
+
 			cpms := builder.WithName("disallowed").Build()
-			Expect(k8sClient.Create(ctx, cpms)).To(MatchError(ContainSubstring("TODO")))
+			Expect(k8sClient.Create(ctx, cpms)).To(MatchError(ContainSubstring(`metadata.name: Invalid value: "disallowed": ControlPlaneMachineSet is a singleton and must be named "cluster"`)))
+
+			Expect(counterValue(admissionRequestsTotal, "CREATE", decisionDenied)).To(Equal(denied+1), "The denied request should be counted")
+			Expect(counterValue(admissionDenialsTotal, "CREATE", "metadata.name", "FieldValueInvalid")).To(Equal(denials+1), "The denial should be counted by field and reason")
 		})
 
 		It("with 4 replicas", func() {
@@ -106,7 +191,7 @@ var _ = Describe("Webhooks", func() {
 			Expect(k8sClient.Create(ctx, cpms)).To(MatchError(ContainSubstring("Unsupported value: 4: supported values: \"3\", \"5\"")))
 		})
 
-		PIt("with mismatched selector and machine labels", func() {
+		It("with mismatched selector and machine labels", func() {
 			cpms := builder.WithSelector(metav1.LabelSelector{
 				MatchLabels: map[string]string{
 					openshiftMachineRoleLabel:            masterMachineRole,
@@ -121,7 +206,7 @@ var _ = Describe("Webhooks", func() {
 				}),
 			).Build()
 
-			Expect(k8sClient.Create(ctx, cpms)).To(MatchError(ContainSubstring("TODO")))
+			Expect(k8sClient.Create(ctx, cpms)).To(MatchError(ContainSubstring(`spec.template.machines_v1beta1_machine_openshift_io.metadata.labels: Invalid value: map[string]string{"machine.openshift.io/cluster-api-cluster":"different-id", "machine.openshift.io/cluster-api-machine-role":"master", "machine.openshift.io/cluster-api-machine-type":"master"}: selector does not match the template labels`)))
 		})
 
 		PIt("with no cluster ID label is set", func() {
@@ -186,6 +271,291 @@ var _ = Describe("Webhooks", func() {
 			Expect(k8sClient.Create(ctx, cpms)).To(MatchError(ContainSubstring("spec.template.machines_v1beta1_machine_openshift_io: Required value")))
 		})
 
+		Context("when validating the annotations", func() {
+			It("with valid stage timeouts", func() {
+				cpms := builder.WithAnnotations(map[string]string{
+					"controlplanemachineset.machine.openshift.io/timeouts": "deletion=2h,drain=45m",
+				}).Build()
+
+				Expect(k8sClient.Create(ctx, cpms)).To(Succeed())
+			})
+
+			It("with invalid stage timeouts", func() {
+				cpms := builder.WithAnnotations(map[string]string{
+					"controlplanemachineset.machine.openshift.io/timeouts": "node=20m",
+				}).Build()
+
+				Expect(k8sClient.Create(ctx, cpms)).To(MatchError(ContainSubstring(
+					`metadata.annotations[controlplanemachineset.machine.openshift.io/timeouts]: Invalid value: "node=20m": invalid value for annotation controlplanemachineset.machine.openshift.io/timeouts`,
+				)))
+			})
+
+			It("with a valid replacement budget", func() {
+				cpms := builder.WithAnnotations(map[string]string{
+					"controlplanemachineset.machine.openshift.io/replacement-budget": "1/24h",
+				}).Build()
+
+				Expect(k8sClient.Create(ctx, cpms)).To(Succeed())
+			})
+
+			It("with an invalid replacement budget", func() {
+				cpms := builder.WithAnnotations(map[string]string{
+					"controlplanemachineset.machine.openshift.io/replacement-budget": "1/-24h",
+				}).Build()
+
+				Expect(k8sClient.Create(ctx, cpms)).To(MatchError(ContainSubstring(
+					`metadata.annotations[controlplanemachineset.machine.openshift.io/replacement-budget]: Invalid value: "1/-24h": invalid value for annotation controlplanemachineset.machine.openshift.io/replacement-budget`,
+				)))
+			})
+
+			It("with rebalancing enabled", func() {
+				cpms := builder.WithAnnotations(map[string]string{
+					"controlplanemachineset.machine.openshift.io/rebalance-failure-domains": "true",
+				}).Build()
+
+				Expect(k8sClient.Create(ctx, cpms)).To(Succeed())
+			})
+
+			It("with an invalid rebalancing value", func() {
+				cpms := builder.WithAnnotations(map[string]string{
+					"controlplanemachineset.machine.openshift.io/rebalance-failure-domains": "always",
+				}).Build()
+
+				Expect(k8sClient.Create(ctx, cpms)).To(MatchError(ContainSubstring(
+					`metadata.annotations[controlplanemachineset.machine.openshift.io/rebalance-failure-domains]: Invalid value: "always": invalid value for annotation controlplanemachineset.machine.openshift.io/rebalance-failure-domains`,
+				)))
+			})
+
+			Context("with failure domains on GCP", func() {
+				var gcpBuilder resourcebuilder.ControlPlaneMachineSetBuilder
+
+				BeforeEach(func() {
+					gcpBuilder = builder.WithMachineTemplateBuilder(resourcebuilder.OpenShiftMachineV1Beta1Template().
+						WithFailureDomainsBuilder(resourcebuilder.GCPFailureDomains()).
+						WithProviderSpecBuilder(resourcebuilder.GCPProviderSpec()),
+					)
+				})
+
+				It("with valid failure domain weights", func() {
+					cpms := gcpBuilder.WithAnnotations(map[string]string{
+						"controlplanemachineset.machine.openshift.io/failure-domain-weights": "us-central1-a=2,us-central1-b=1",
+					}).Build()
+
+					Expect(k8sClient.Create(ctx, cpms)).To(Succeed())
+				})
+
+				It("with weights for a failure domain that is not configured", func() {
+					cpms := gcpBuilder.WithAnnotations(map[string]string{
+						"controlplanemachineset.machine.openshift.io/failure-domain-weights": "us-central1-d=2",
+					}).Build()
+
+					Expect(k8sClient.Create(ctx, cpms)).To(MatchError(ContainSubstring(
+						`metadata.annotations[controlplanemachineset.machine.openshift.io/failure-domain-weights]: Invalid value: "us-central1-d=2": invalid value for annotation controlplanemachineset.machine.openshift.io/failure-domain-weights`,
+					)))
+				})
+
+				It("with a cordoned failure domain", func() {
+					cpms := gcpBuilder.WithAnnotations(map[string]string{
+						"controlplanemachineset.machine.openshift.io/cordoned-failure-domains": "us-central1-a",
+					}).Build()
+
+					Expect(k8sClient.Create(ctx, cpms)).To(Succeed())
+				})
+
+				It("with every failure domain cordoned", func() {
+					cpms := gcpBuilder.WithAnnotations(map[string]string{
+						"controlplanemachineset.machine.openshift.io/cordoned-failure-domains": "us-central1-a,us-central1-b,us-central1-c",
+					}).Build()
+
+					Expect(k8sClient.Create(ctx, cpms)).To(MatchError(ContainSubstring(
+						`metadata.annotations[controlplanemachineset.machine.openshift.io/cordoned-failure-domains]: Invalid value: "us-central1-a,us-central1-b,us-central1-c": invalid value for annotation controlplanemachineset.machine.openshift.io/cordoned-failure-domains`,
+					)))
+				})
+			})
+		})
+
+		Context("when selecting the instance type by attribute on AWS", func() {
+			const requirements = `{"vCpuCount":{"min":4,"max":8},"memoryMiB":{"min":16384}}`
+
+			withProviderSpec := func(providerSpec resourcebuilder.AWSProviderSpecBuilder) *machinev1.ControlPlaneMachineSet {
+				return builder.WithMachineTemplateBuilder(machineTemplate.WithProviderSpecBuilder(providerSpec)).Build()
+			}
+
+			It("with valid instance requirements", func() {
+				cpms := withProviderSpec(resourcebuilder.AWSProviderSpec().WithAvailabilityZone("us-east-1").WithInstanceType("").WithInstanceRequirements(requirements))
+
+				Expect(k8sClient.Create(ctx, cpms)).To(Succeed())
+			})
+
+			It("with both an instance type and instance requirements", func() {
+				cpms := withProviderSpec(resourcebuilder.AWSProviderSpec().WithAvailabilityZone("us-east-1").WithInstanceRequirements(requirements))
+
+				Expect(k8sClient.Create(ctx, cpms)).To(MatchError(ContainSubstring(
+					"spec.template.machines_v1beta1_machine_openshift_io.spec.providerSpec.value.instanceRequirements: Forbidden: instanceRequirements cannot be set when instanceType is set",
+				)))
+			})
+
+			It("with no minimum memory", func() {
+				cpms := withProviderSpec(resourcebuilder.AWSProviderSpec().WithAvailabilityZone("us-east-1").WithInstanceType("").WithInstanceRequirements(`{"vCpuCount":{"min":4}}`))
+
+				Expect(k8sClient.Create(ctx, cpms)).To(MatchError(ContainSubstring(
+					"spec.template.machines_v1beta1_machine_openshift_io.spec.providerSpec.value.instanceRequirements.memoryMiB.min: Required value: a minimum is required when selecting the instance type by attribute",
+				)))
+			})
+
+			It("with a maximum vCPU count less than the minimum", func() {
+				cpms := withProviderSpec(resourcebuilder.AWSProviderSpec().WithAvailabilityZone("us-east-1").WithInstanceType("").WithInstanceRequirements(`{"vCpuCount":{"min":8,"max":4},"memoryMiB":{"min":16384}}`))
+
+				Expect(k8sClient.Create(ctx, cpms)).To(MatchError(ContainSubstring(
+					"spec.template.machines_v1beta1_machine_openshift_io.spec.providerSpec.value.instanceRequirements.vCpuCount.max: Invalid value: 4: must not be less than the minimum of 8",
+				)))
+			})
+		})
+
+		Context("when validating the clone template on vSphere", func() {
+			withProviderSpec := func(providerSpec resourcebuilder.RawExtensionBuilder) *machinev1.ControlPlaneMachineSet {
+				// The ControlPlaneMachineSet API has no vSphere failure domains.
+				return builder.WithMachineTemplateBuilder(machineTemplate.WithFailureDomainsBuilder(nil).WithProviderSpecBuilder(providerSpec)).Build()
+			}
+
+			It("with a template", func() {
+				Expect(k8sClient.Create(ctx, withProviderSpec(resourcebuilder.VSphereProviderSpec()))).To(Succeed())
+			})
+
+			It("with no template", func() {
+				Expect(k8sClient.Create(ctx, withProviderSpec(resourcebuilder.VSphereProviderSpec().WithTemplate("")))).To(MatchError(ContainSubstring(
+					"spec.template.machines_v1beta1_machine_openshift_io.spec.providerSpec.value.template: Required value: a template is required to clone the control plane virtual machines from",
+				)))
+			})
+
+			type vsphereTemplateWarningsTableInput struct {
+				oldProviderSpec  resourcebuilder.RawExtensionBuilder
+				providerSpec     resourcebuilder.RawExtensionBuilder
+				expectedWarnings []string
+			}
+
+			DescribeTable("should warn that the template cannot be verified", func(in vsphereTemplateWarningsTableInput) {
+				oldCPMS := &machinev1.ControlPlaneMachineSet{}
+				if in.oldProviderSpec != nil {
+					oldCPMS = withProviderSpec(in.oldProviderSpec)
+				}
+
+				Expect(vsphereTemplateWarnings(oldCPMS, withProviderSpec(in.providerSpec))).To(Equal(in.expectedWarnings))
+			},
+				Entry("when creating with a template", vsphereTemplateWarningsTableInput{
+					providerSpec:     resourcebuilder.VSphereProviderSpec().WithTemplate("rhcos-4.12"),
+					expectedWarnings: []string{fmt.Sprintf(vsphereTemplateWarningFormat, "rhcos-4.12")},
+				}),
+				Entry("when changing the template", vsphereTemplateWarningsTableInput{
+					oldProviderSpec:  resourcebuilder.VSphereProviderSpec().WithTemplate("rhcos-4.11"),
+					providerSpec:     resourcebuilder.VSphereProviderSpec().WithTemplate("rhcos-4.12"),
+					expectedWarnings: []string{fmt.Sprintf(vsphereTemplateWarningFormat, "rhcos-4.12")},
+				}),
+				Entry("when keeping the template the same", vsphereTemplateWarningsTableInput{
+					oldProviderSpec:  resourcebuilder.VSphereProviderSpec().WithTemplate("rhcos-4.12"),
+					providerSpec:     resourcebuilder.VSphereProviderSpec().WithTemplate("rhcos-4.12").WithAPIVersion("vsphereprovider.openshift.io/v1beta1"),
+					expectedWarnings: nil,
+				}),
+				Entry("on another platform", vsphereTemplateWarningsTableInput{
+					providerSpec:     resourcebuilder.AWSProviderSpec(),
+					expectedWarnings: nil,
+				}),
+			)
+		})
+
+		Context("when validating the managed identity and boot diagnostics on Azure", func() {
+			withProviderSpec := func(providerSpec resourcebuilder.AzureProviderSpecBuilder) *machinev1.ControlPlaneMachineSet {
+				// The failure domain package does not yet support Azure.
+				return builder.WithMachineTemplateBuilder(machineTemplate.WithFailureDomainsBuilder(nil).WithProviderSpecBuilder(providerSpec)).Build()
+			}
+
+			It("with a managed identity resource ID and customer managed boot diagnostics", func() {
+				cpms := withProviderSpec(resourcebuilder.AzureProviderSpec().
+					WithManagedIdentity("/subscriptions/sub-12345678/resourceGroups/cluster-id-rg/providers/Microsoft.ManagedIdentity/userAssignedIdentities/cluster-id-identity").
+					WithDiagnostics(`{"boot":{"storageAccountType":"CustomerManaged","customerManaged":{"storageAccountURI":"https://example.blob.core.windows.net/"}}}`).
+					WithAcceleratedNetworking(true))
+
+				Expect(k8sClient.Create(ctx, cpms)).To(Succeed())
+			})
+
+			It("with a managed identity resource ID of a different resource type", func() {
+				cpms := withProviderSpec(resourcebuilder.AzureProviderSpec().
+					WithManagedIdentity("/subscriptions/sub-12345678/resourceGroups/cluster-id-rg/providers/Microsoft.Compute/virtualMachines/master-0"))
+
+				Expect(k8sClient.Create(ctx, cpms)).To(MatchError(ContainSubstring(
+					"spec.template.machines_v1beta1_machine_openshift_io.spec.providerSpec.value.managedIdentity: Invalid value: \"/subscriptions/sub-12345678/resourceGroups/cluster-id-rg/providers/Microsoft.Compute/virtualMachines/master-0\": must be the name or the resource ID of a user assigned managed identity",
+				)))
+			})
+
+			It("with customer managed boot diagnostics and no storage account URI", func() {
+				cpms := withProviderSpec(resourcebuilder.AzureProviderSpec().WithDiagnostics(`{"boot":{"storageAccountType":"CustomerManaged"}}`))
+
+				Expect(k8sClient.Create(ctx, cpms)).To(MatchError(ContainSubstring(
+					"spec.template.machines_v1beta1_machine_openshift_io.spec.providerSpec.value.diagnostics.boot.customerManaged.storageAccountURI: Required value: a storage account URI is required when the storage account type is CustomerManaged",
+				)))
+			})
+
+			It("with Azure managed boot diagnostics and a customer managed storage account", func() {
+				cpms := withProviderSpec(resourcebuilder.AzureProviderSpec().
+					WithDiagnostics(`{"boot":{"storageAccountType":"AzureManaged","customerManaged":{"storageAccountURI":"https://example.blob.core.windows.net/"}}}`))
+
+				Expect(k8sClient.Create(ctx, cpms)).To(MatchError(ContainSubstring(
+					"spec.template.machines_v1beta1_machine_openshift_io.spec.providerSpec.value.diagnostics.boot.customerManaged: Forbidden: customerManaged cannot be set when the storage account type is AzureManaged",
+				)))
+			})
+
+			It("with an unknown boot diagnostics storage account type", func() {
+				cpms := withProviderSpec(resourcebuilder.AzureProviderSpec().WithDiagnostics(`{"boot":{"storageAccountType":"Disabled"}}`))
+
+				Expect(k8sClient.Create(ctx, cpms)).To(MatchError(ContainSubstring(
+					"spec.template.machines_v1beta1_machine_openshift_io.spec.providerSpec.value.diagnostics.boot.storageAccountType: Unsupported value: \"Disabled\": supported values: \"AzureManaged\", \"CustomerManaged\"",
+				)))
+			})
+
+			Context("on Azure Stack Hub", func() {
+				BeforeEach(func() {
+					infrastructure := &configv1.Infrastructure{ObjectMeta: metav1.ObjectMeta{Name: clusterconfig.InfrastructureName}}
+					Expect(k8sClient.Create(ctx, infrastructure)).To(Succeed())
+
+					DeferCleanup(func() {
+						Expect(k8sClient.Delete(ctx, infrastructure)).To(Succeed())
+					})
+
+					Expect(komega.UpdateStatus(infrastructure, func() {
+						infrastructure.Status.ControlPlaneTopology = configv1.HighlyAvailableTopologyMode
+						infrastructure.Status.InfrastructureTopology = configv1.HighlyAvailableTopologyMode
+						infrastructure.Status.PlatformStatus = &configv1.PlatformStatus{
+							Type: configv1.AzurePlatformType,
+							Azure: &configv1.AzurePlatformStatus{
+								ResourceGroupName: "cluster-id-rg",
+								CloudName:         configv1.AzureStackCloud,
+							},
+						}
+					})()).To(Succeed())
+				})
+
+				It("with a template without availability zones", func() {
+					// A managed identity that is not a user assigned identity is not validated on Azure Stack Hub.
+					cpms := withProviderSpec(resourcebuilder.AzureProviderSpec().
+						WithManagedIdentity("/subscriptions/sub-12345678/resourceGroups/cluster-id-rg/providers/Microsoft.Compute/virtualMachines/master-0"))
+
+					Expect(k8sClient.Create(ctx, cpms)).To(Succeed())
+				})
+
+				It("with a zone and accelerated networking", func() {
+					cpms := withProviderSpec(resourcebuilder.AzureProviderSpec().WithZone("1").WithAcceleratedNetworking(true))
+
+					err := k8sClient.Create(ctx, cpms)
+					Expect(err).To(MatchError(ContainSubstring(
+						"spec.template.machines_v1beta1_machine_openshift_io.spec.providerSpec.value.zone: Forbidden: Azure Stack Hub does not support availability zones",
+					)))
+					Expect(err).To(MatchError(ContainSubstring(
+						"spec.template.machines_v1beta1_machine_openshift_io.spec.providerSpec.value.acceleratedNetworking: Forbidden: Azure Stack Hub does not support accelerated networking",
+					)))
+				})
+			})
+		})
+
 		Context("when validating failure domains on AWS", func() {
 			var builder resourcebuilder.ControlPlaneMachineSetBuilder
 			var usEast1aBuilder = resourcebuilder.AWSFailureDomain().WithAvailabilityZone("us-east-1a")
@@ -270,6 +640,237 @@ var _ = Describe("Webhooks", func() {
 		})
 	})
 
+	Context("when summarising the rollout", func() {
+		var warnings *warningRecorder
+		var warningClient client.Client
+		var builder resourcebuilder.ControlPlaneMachineSetBuilder
+
+		const instanceType = "m6i.xlarge"
+
+		BeforeEach(func() {
+			By("Setting up a client that records warnings")
+			warnings = &warningRecorder{}
+
+			warningCfg := rest.CopyConfig(cfg)
+			warningCfg.WarningHandler = warnings
+
+			var err error
+			warningClient, err = client.New(warningCfg, client.Options{Scheme: testScheme})
+			Expect(err).ToNot(HaveOccurred())
+
+			By("Creating control plane Machines")
+			providerSpec := resourcebuilder.AWSProviderSpec().WithInstanceType(instanceType)
+			machineBuilder := resourcebuilder.Machine().WithNamespace(namespaceName).WithGenerateName("control-plane-machine-").AsMaster()
+
+			for _, az := range []string{"us-east-1a", "us-east-1b", "us-east-1c"} {
+				Expect(k8sClient.Create(ctx, machineBuilder.WithProviderSpecBuilder(providerSpec.WithAvailabilityZone(az)).Build())).To(Succeed())
+			}
+
+			builder = resourcebuilder.ControlPlaneMachineSet().WithNamespace(namespaceName)
+		})
+
+		Context("on create", func() {
+			It("with a template matching the existing machines", func() {
+				cpms := builder.WithMachineTemplateBuilder(resourcebuilder.OpenShiftMachineV1Beta1Template().WithProviderSpecBuilder(
+					resourcebuilder.AWSProviderSpec().WithInstanceType(instanceType),
+				)).Build()
+
+				Expect(warningClient.Create(ctx, cpms)).To(Succeed())
+				Expect(warnings.Warnings()).To(BeEmpty(), "No machines should need replacing")
+			})
+
+			It("with a template that differs from the existing machines", func() {
+				cpms := builder.WithMachineTemplateBuilder(resourcebuilder.OpenShiftMachineV1Beta1Template().WithProviderSpecBuilder(
+					resourcebuilder.AWSProviderSpec().WithInstanceType("m6i.2xlarge"),
+				)).Build()
+
+				replaced := counterValue(admissionWarningsTotal, "CREATE", warningReasonMachinesReplaced)
+
+				Expect(warningClient.Create(ctx, cpms)).To(Succeed(), "Differences should only warn, not reject the request")
+				Expect(warnings.Warnings()).To(ConsistOf(
+					"3 of 3 control plane machine(s) do not match the template and will be replaced, changed provider spec fields: instanceType",
+				))
+
+				Expect(counterValue(admissionWarningsTotal, "CREATE", warningReasonMachinesReplaced)).To(Equal(replaced+1), "The warning should be counted by reason")
+			})
+
+			It("with a template that differs from some of the existing machines", func() {
+				Expect(k8sClient.Create(ctx, resourcebuilder.Machine().WithNamespace(namespaceName).WithGenerateName("control-plane-machine-").AsMaster().
+					WithProviderSpecBuilder(resourcebuilder.AWSProviderSpec().WithInstanceType("m6i.2xlarge").WithAMI("aws-ami-different")).Build(),
+				)).To(Succeed())
+
+				cpms := builder.WithMachineTemplateBuilder(resourcebuilder.OpenShiftMachineV1Beta1Template().WithProviderSpecBuilder(
+					resourcebuilder.AWSProviderSpec().WithInstanceType(instanceType),
+				)).Build()
+
+				Expect(warningClient.Create(ctx, cpms)).To(Succeed())
+				Expect(warnings.Warnings()).To(ConsistOf(
+					"1 of 4 control plane machine(s) do not match the template and will be replaced, changed provider spec fields: ami, instanceType",
+				))
+			})
+
+			It("with a template that differs from the existing machines only in externally owned fields", func() {
+				cpms := builder.WithAnnotations(map[string]string{
+					providerconfig.OwnedFieldsAnnotation: ".instanceType",
+				}).WithMachineTemplateBuilder(resourcebuilder.OpenShiftMachineV1Beta1Template().WithProviderSpecBuilder(
+					resourcebuilder.AWSProviderSpec().WithInstanceType("m6i.2xlarge"),
+				)).Build()
+
+				Expect(warningClient.Create(ctx, cpms)).To(Succeed())
+				Expect(warnings.Warnings()).To(BeEmpty(), "Owned fields should not cause machines to be replaced")
+			})
+
+			It("with a selector that does not match the existing machines", func() {
+				cpms := builder.WithSelector(metav1.LabelSelector{
+					MatchLabels: map[string]string{
+						openshiftMachineRoleLabel:            masterMachineRole,
+						openshiftMachineTypeLabel:            masterMachineRole,
+						machinev1beta1.MachineClusterIDLabel: "cpms-cluster-test-id",
+					},
+				}).WithMachineTemplateBuilder(resourcebuilder.OpenShiftMachineV1Beta1Template().WithProviderSpecBuilder(
+					resourcebuilder.AWSProviderSpec().WithInstanceType(instanceType),
+				)).Build()
+
+				Expect(warningClient.Create(ctx, cpms)).To(Succeed(), "Unselected machines should only warn, not reject the request")
+				Expect(warnings.Warnings()).To(ConsistOf(SatisfyAll(
+					HavePrefix("3 of 3 control plane machine(s) do not match spec.selector and will not be managed: control-plane-machine-"),
+					HaveSuffix("; ensure spec.selector matches the labels of the existing control plane machines"),
+				)))
+			})
+		})
+
+		Context("with undersized control plane machines", func() {
+			const providerSpecPath = "spec.template.machines_v1beta1_machine_openshift_io.spec.providerSpec.value"
+
+			It("with a small AWS instance type", func() {
+				cpms := builder.WithMachineTemplateBuilder(resourcebuilder.OpenShiftMachineV1Beta1Template().WithProviderSpecBuilder(
+					resourcebuilder.AWSProviderSpec().WithInstanceType("m6i.large"),
+				)).Build()
+
+				Expect(warningClient.Create(ctx, cpms)).To(Succeed(), "Undersized machines should only warn, not reject the request")
+				Expect(warnings.Warnings()).To(ContainElement(
+					providerSpecPath + ".instanceType: instance type m6i.large is smaller than recommended for control plane machines, which should have at least 4 vCPUs and 16384MiB of memory",
+				))
+			})
+
+			It("with AWS instance requirements that allow small instance types", func() {
+				cpms := builder.WithMachineTemplateBuilder(resourcebuilder.OpenShiftMachineV1Beta1Template().WithProviderSpecBuilder(
+					resourcebuilder.AWSProviderSpec().WithInstanceType("").WithInstanceRequirements(`{"vCpuCount":{"min":2},"memoryMiB":{"min":8192}}`),
+				)).Build()
+
+				Expect(warningClient.Create(ctx, cpms)).To(Succeed())
+				Expect(warnings.Warnings()).To(ContainElements(
+					providerSpecPath+".instanceRequirements.vCpuCount.min: a minimum of 2 vCPUs is smaller than recommended for control plane machines, which should have at least 4 vCPUs and 16384MiB of memory",
+					providerSpecPath+".instanceRequirements.memoryMiB.min: a minimum of 8192MiB of memory is smaller than recommended for control plane machines, which should have at least 4 vCPUs and 16384MiB of memory",
+				))
+			})
+
+			It("with small vSphere virtual machines", func() {
+				cpms := builder.WithMachineTemplateBuilder(resourcebuilder.OpenShiftMachineV1Beta1Template().WithFailureDomainsBuilder(nil).WithProviderSpecBuilder(
+					resourcebuilder.VSphereProviderSpec().WithNumCPUs(2).WithMemoryMiB(8192),
+				)).Build()
+
+				Expect(warningClient.Create(ctx, cpms)).To(Succeed())
+				Expect(warnings.Warnings()).To(ContainElements(
+					providerSpecPath+".numCPUs: 2 vCPUs is smaller than recommended for control plane machines, which should have at least 4 vCPUs and 16384MiB of memory",
+					providerSpecPath+".memoryMiB: 8192MiB of memory is smaller than recommended for control plane machines, which should have at least 4 vCPUs and 16384MiB of memory",
+				))
+			})
+
+			It("with an AWS instance type with too little memory", func() {
+				cpms := builder.WithMachineTemplateBuilder(resourcebuilder.OpenShiftMachineV1Beta1Template().WithProviderSpecBuilder(
+					resourcebuilder.AWSProviderSpec().WithInstanceType("c5.xlarge"),
+				)).Build()
+
+				Expect(warningClient.Create(ctx, cpms)).To(Succeed())
+				Expect(warnings.Warnings()).To(ContainElement(
+					providerSpecPath + ".instanceType: instance type c5.xlarge (4 vCPUs, 8192MiB of memory) is smaller than recommended for control plane machines, which should have at least 4 vCPUs and 16384MiB of memory",
+				))
+			})
+
+			It("with a small Azure VM size", func() {
+				cpms := builder.WithMachineTemplateBuilder(resourcebuilder.OpenShiftMachineV1Beta1Template().WithFailureDomainsBuilder(nil).WithProviderSpecBuilder(
+					resourcebuilder.AzureProviderSpec().WithVMSize("Standard_D2s_v3"),
+				)).Build()
+
+				Expect(warningClient.Create(ctx, cpms)).To(Succeed())
+				Expect(warnings.Warnings()).To(ContainElement(
+					providerSpecPath + ".vmSize: VM size Standard_D2s_v3 (2 vCPUs, 8192MiB of memory) is smaller than recommended for control plane machines, which should have at least 4 vCPUs and 16384MiB of memory",
+				))
+			})
+
+			It("with a small GCP machine type", func() {
+				cpms := builder.WithMachineTemplateBuilder(resourcebuilder.OpenShiftMachineV1Beta1Template().WithFailureDomainsBuilder(nil).WithProviderSpecBuilder(
+					resourcebuilder.GCPProviderSpec().WithMachineType("e2-standard-2"),
+				)).Build()
+
+				Expect(warningClient.Create(ctx, cpms)).To(Succeed())
+				Expect(warnings.Warnings()).To(ContainElement(
+					providerSpecPath + ".machineType: machine type e2-standard-2 (2 vCPUs, 8192MiB of memory) is smaller than recommended for control plane machines, which should have at least 4 vCPUs and 15360MiB of memory",
+				))
+			})
+
+			It("with the minimum GCP machine type", func() {
+				cpms := builder.WithMachineTemplateBuilder(resourcebuilder.OpenShiftMachineV1Beta1Template().WithFailureDomainsBuilder(nil).WithProviderSpecBuilder(
+					resourcebuilder.GCPProviderSpec().WithMachineType("n1-standard-4"),
+				)).Build()
+
+				Expect(warningClient.Create(ctx, cpms)).To(Succeed())
+				Expect(warnings.Warnings()).ToNot(ContainElement(ContainSubstring("smaller than recommended")))
+			})
+
+			It("with small Nutanix virtual machines", func() {
+				cpms := builder.WithMachineTemplateBuilder(resourcebuilder.OpenShiftMachineV1Beta1Template().WithFailureDomainsBuilder(nil).WithProviderSpecBuilder(
+					resourcebuilder.NutanixProviderSpec().WithVCPUSockets(2).WithMemorySize(resource.MustParse("8Gi")),
+				)).Build()
+
+				Expect(warningClient.Create(ctx, cpms)).To(Succeed())
+				Expect(warnings.Warnings()).To(ContainElements(
+					providerSpecPath+".vcpuSockets: 2 socket(s) of 1 vCPU(s) is smaller than recommended for control plane machines, which should have at least 4 vCPUs and 16384MiB of memory",
+					providerSpecPath+".memorySize: 8192MiB of memory is smaller than recommended for control plane machines, which should have at least 4 vCPUs and 16384MiB of memory",
+				))
+			})
+		})
+
+		Context("on update", func() {
+			var cpms *machinev1.ControlPlaneMachineSet
+
+			BeforeEach(func() {
+				cpms = builder.WithMachineTemplateBuilder(resourcebuilder.OpenShiftMachineV1Beta1Template().WithProviderSpecBuilder(
+					resourcebuilder.AWSProviderSpec().WithInstanceType(instanceType),
+				)).Build()
+
+				Expect(warningClient.Create(ctx, cpms)).To(Succeed())
+				Expect(warnings.Warnings()).To(BeEmpty())
+			})
+
+			It("with an update to the template that differs from the existing machines", func() {
+				cpms.Spec.Template.OpenShiftMachineV1Beta1Machine.Spec.ProviderSpec.Value = resourcebuilder.AWSProviderSpec().WithInstanceType("m6i.2xlarge").BuildRawExtension()
+
+				Expect(warningClient.Update(ctx, cpms)).To(Succeed())
+				Expect(warnings.Warnings()).To(ConsistOf(
+					"3 of 3 control plane machine(s) do not match the template and will be replaced, changed provider spec fields: instanceType",
+				))
+			})
+
+			It("with an update to the selector that does not match the existing machines", func() {
+				cpms.Spec.Selector.MatchLabels[machinev1beta1.MachineClusterIDLabel] = "cpms-cluster-test-id"
+
+				Expect(warningClient.Update(ctx, cpms)).To(Succeed())
+				Expect(warnings.Warnings()).To(ConsistOf(
+					HavePrefix("3 of 3 control plane machine(s) do not match spec.selector and will not be managed: "),
+				))
+			})
+
+			It("with an update that does not change the template", func() {
+				cpms.Labels = map[string]string{"new": "value"}
+
+				Expect(warningClient.Update(ctx, cpms)).To(Succeed())
+				Expect(warnings.Warnings()).To(BeEmpty())
+			})
+		})
+	})
+
 	Context("on update", func() {
 		var cpms *machinev1.ControlPlaneMachineSet
 
@@ -292,6 +893,66 @@ var _ = Describe("Webhooks", func() {
 			})).Should(Succeed())
 		})
 
+		It("with an invalid annotation", func() {
+			Eventually(komega.Update(cpms, func() {
+				cpms.SetAnnotations(map[string]string{
+					"controlplanemachineset.machine.openshift.io/timeouts": "drain=-1h",
+				})
+			})).Should(MatchError(ContainSubstring(
+				`metadata.annotations[controlplanemachineset.machine.openshift.io/timeouts]: Invalid value: "drain=-1h"`,
+			)), "Invalid annotations should be rejected")
+		})
+
+		It("with an increase to the root volume size", func() {
+			rawProviderSpec := resourcebuilder.AWSProviderSpec().WithAvailabilityZone("us-east-1").WithBlockDevices([]machinev1beta1.BlockDeviceMappingSpec{
+				{
+					EBS: &machinev1beta1.EBSBlockDeviceSpec{
+						VolumeSize: pointer.Int64(240),
+					},
+				},
+			}).BuildRawExtension()
+
+			Eventually(komega.Update(cpms, func() {
+				cpms.Spec.Template.OpenShiftMachineV1Beta1Machine.Spec.ProviderSpec.Value = rawProviderSpec
+			})).Should(Succeed(), "Volume size increases should be allowed")
+		})
+
+		It("with a decrease to the root volume size", func() {
+			rawProviderSpec := resourcebuilder.AWSProviderSpec().WithAvailabilityZone("us-east-1").WithBlockDevices([]machinev1beta1.BlockDeviceMappingSpec{
+				{
+					EBS: &machinev1beta1.EBSBlockDeviceSpec{
+						VolumeSize: pointer.Int64(60),
+					},
+				},
+			}).BuildRawExtension()
+
+			Eventually(komega.Update(cpms, func() {
+				cpms.Spec.Template.OpenShiftMachineV1Beta1Machine.Spec.ProviderSpec.Value = rawProviderSpec
+			})).Should(MatchError(ContainSubstring(
+				"spec.template.machines_v1beta1_machine_openshift_io.spec.providerSpec.value.blockDevices[0].ebs.volumeSize: Forbidden: volume size of block device root cannot be decreased from 120GiB to 60GiB",
+			)), "Volume size decreases should be rejected")
+		})
+
+		It("with a decrease to the volume size of a reordered block device", func() {
+			By("Adding a named block device")
+			Eventually(komega.Update(cpms, func() {
+				cpms.Spec.Template.OpenShiftMachineV1Beta1Machine.Spec.ProviderSpec.Value = resourcebuilder.AWSProviderSpec().WithAvailabilityZone("us-east-1").WithBlockDevices([]machinev1beta1.BlockDeviceMappingSpec{
+					{EBS: &machinev1beta1.EBSBlockDeviceSpec{VolumeSize: pointer.Int64(120)}},
+					{DeviceName: pointer.String("/dev/xvdb"), EBS: &machinev1beta1.EBSBlockDeviceSpec{VolumeSize: pointer.Int64(100)}},
+				}).BuildRawExtension()
+			})).Should(Succeed())
+
+			By("Reordering the block devices and decreasing the size of the named block device")
+			Eventually(komega.Update(cpms, func() {
+				cpms.Spec.Template.OpenShiftMachineV1Beta1Machine.Spec.ProviderSpec.Value = resourcebuilder.AWSProviderSpec().WithAvailabilityZone("us-east-1").WithBlockDevices([]machinev1beta1.BlockDeviceMappingSpec{
+					{DeviceName: pointer.String("/dev/xvdb"), EBS: &machinev1beta1.EBSBlockDeviceSpec{VolumeSize: pointer.Int64(50)}},
+					{EBS: &machinev1beta1.EBSBlockDeviceSpec{VolumeSize: pointer.Int64(120)}},
+				}).BuildRawExtension()
+			})).Should(MatchError(ContainSubstring(
+				"blockDevices[0].ebs.volumeSize: Forbidden: volume size of block device /dev/xvdb cannot be decreased from 100GiB to 50GiB",
+			)), "Block devices should be matched by device name")
+		})
+
 		It("with 4 replicas", func() {
 			// This is an openapi validation but it makes sense to include it here as well
 			Eventually(komega.Update(cpms, func() {
@@ -300,12 +961,29 @@ var _ = Describe("Webhooks", func() {
 			})).Should(MatchError(ContainSubstring("Unsupported value: 4: supported values: \"3\", \"5\"")))
 		})
 
-		PIt("with 5 replicas", func() {
-			// Five replicas is a valid value but the existing CPMS has three replicas
+		It("with 5 replicas", func() {
 			Eventually(komega.Update(cpms, func() {
 				five := int32(5)
 				cpms.Spec.Replicas = &five
-			})).Should(MatchError(ContainSubstring("TODO")), "Replicas should be immutable")
+			})).Should(Succeed(), "Replica increases to an odd number should be allowed")
+		})
+
+		Context("with 5 replicas", func() {
+			BeforeEach(func() {
+				Eventually(komega.Update(cpms, func() {
+					five := int32(5)
+					cpms.Spec.Replicas = &five
+				})).Should(Succeed())
+			})
+
+			It("with a decrease to 3 replicas", func() {
+				Eventually(komega.Update(cpms, func() {
+					three := int32(3)
+					cpms.Spec.Replicas = &three
+				})).Should(MatchError(ContainSubstring(
+					"spec.replicas: Forbidden: cannot decrease replicas from 5 to 3: decreasing the number of control plane machines requires control plane scale down to be enabled",
+				)), "Replica decreases should be rejected unless scale down is enabled")
+			})
 		})
 
 		It("when modifying the machine labels and the selector still matches", func() {
@@ -314,13 +992,13 @@ var _ = Describe("Webhooks", func() {
 			})).Should(Succeed(), "Machine label updates are allowed provided the selector still matches")
 		})
 
-		PIt("when modifying the machine labels so that the selector no longer matches", func() {
+		It("when modifying the machine labels so that the selector no longer matches", func() {
 			Eventually(komega.Update(cpms, func() {
 				cpms.Spec.Template.OpenShiftMachineV1Beta1Machine.ObjectMeta.Labels = map[string]string{
 					"different":                          "labels",
 					machinev1beta1.MachineClusterIDLabel: "cluster-id",
 				}
-			})).Should(MatchError(ContainSubstring("TODO")), "The selector must always match the machine labels")
+			})).Should(MatchError(ContainSubstring("selector does not match the template labels: ensure every label required by spec.selector is set, with a matching value, in the template labels")), "The selector must always match the machine labels")
 		})
 
 		PIt("when modifying the machine labels to remove the cluster ID label", func() {
@@ -335,4 +1013,177 @@ var _ = Describe("Webhooks", func() {
 			})).Should(MatchError(ContainSubstring("TODO")), "The selector should be immutable")
 		})
 	})
+
+	Context("validateReplicasTransition", func() {
+		type replicasTransitionTableInput struct {
+			oldReplicas      int32
+			newReplicas      int32
+			singleNode       bool
+			scaleDownEnabled bool
+			expectedError    string
+		}
+
+		DescribeTable("should validate changes to the replicas", func(in replicasTransitionTableInput) {
+			errs := validateReplicasTransition(field.NewPath("spec", "replicas"), in.oldReplicas, in.newReplicas, in.singleNode, in.scaleDownEnabled)
+
+			if in.expectedError == "" {
+				Expect(errs).To(BeEmpty())
+			} else {
+				Expect(errs.ToAggregate()).To(MatchError(in.expectedError))
+			}
+		},
+			Entry("with an increase from 3 to 5", replicasTransitionTableInput{
+				oldReplicas: 3,
+				newReplicas: 5,
+			}),
+			Entry("with an increase to an even number", replicasTransitionTableInput{
+				oldReplicas:   3,
+				newReplicas:   4,
+				expectedError: "spec.replicas: Invalid value: 4: an even number of control plane machines is not supported, as it does not improve the fault tolerance of etcd over one fewer machine",
+			}),
+			Entry("with a decrease to an even number with scale down enabled", replicasTransitionTableInput{
+				oldReplicas:      3,
+				newReplicas:      2,
+				scaleDownEnabled: true,
+				expectedError:    "spec.replicas: Invalid value: 2: an even number of control plane machines is not supported, as it does not improve the fault tolerance of etcd over one fewer machine",
+			}),
+			Entry("with a decrease from 3 to 1 outside of a single node cluster", replicasTransitionTableInput{
+				oldReplicas:      3,
+				newReplicas:      1,
+				scaleDownEnabled: true,
+				expectedError:    "spec.replicas: Forbidden: cannot decrease replicas from 3 to 1: a single control plane machine is only supported on single node clusters",
+			}),
+			Entry("with a decrease from 3 to 1 on a single node cluster with scale down enabled", replicasTransitionTableInput{
+				oldReplicas:      3,
+				newReplicas:      1,
+				singleNode:       true,
+				scaleDownEnabled: true,
+			}),
+			Entry("with a decrease from 5 to 3 with scale down disabled", replicasTransitionTableInput{
+				oldReplicas:   5,
+				newReplicas:   3,
+				expectedError: "spec.replicas: Forbidden: cannot decrease replicas from 5 to 3: decreasing the number of control plane machines requires control plane scale down to be enabled",
+			}),
+			Entry("with a decrease from 5 to 3 with scale down enabled", replicasTransitionTableInput{
+				oldReplicas:      5,
+				newReplicas:      3,
+				scaleDownEnabled: true,
+			}),
+		)
+	})
+
+	Context("validateTemplateSize", func() {
+		templatePath := field.NewPath("spec", "template")
+
+		templateWithProviderSpec := func(providerSpec resourcebuilder.RawExtensionBuilder) machinev1.ControlPlaneMachineSetTemplate {
+			return resourcebuilder.ControlPlaneMachineSet().WithMachineTemplateBuilder(
+				resourcebuilder.OpenShiftMachineV1Beta1Template().WithFailureDomainsBuilder(nil).WithProviderSpecBuilder(providerSpec),
+			).Build().Spec.Template
+		}
+
+		It("does not reject undersized machines by default", func() {
+			wh := &ControlPlaneMachineSetWebhook{}
+
+			Expect(wh.validateTemplateSize(templatePath, nil, templateWithProviderSpec(resourcebuilder.AWSProviderSpec().WithInstanceType("m6i.large")))).To(BeEmpty())
+		})
+
+		Context("when rejecting undersized machines", func() {
+			var wh *ControlPlaneMachineSetWebhook
+
+			BeforeEach(func() {
+				wh = &ControlPlaneMachineSetWebhook{RejectUndersizedMachines: true}
+			})
+
+			It("rejects an undersized instance type", func() {
+				errs := wh.validateTemplateSize(templatePath, nil, templateWithProviderSpec(resourcebuilder.AzureProviderSpec().WithVMSize("Standard_F4s_v2")))
+
+				Expect(errs.ToAggregate()).To(MatchError("spec.template.machines_v1beta1_machine_openshift_io.spec.providerSpec.value.vmSize: Invalid value: \"Standard_F4s_v2\": " +
+					"VM size Standard_F4s_v2 (4 vCPUs, 8192MiB of memory) is smaller than the minimum for control plane machines on Azure, which is 4 vCPUs and 16384MiB of memory"))
+			})
+
+			It("allows an instance type that meets the minimum", func() {
+				Expect(wh.validateTemplateSize(templatePath, nil, templateWithProviderSpec(resourcebuilder.AzureProviderSpec().WithVMSize("Standard_D4s_v3")))).To(BeEmpty())
+			})
+
+			It("does not reject an update that keeps an undersized instance type", func() {
+				oldTemplate := templateWithProviderSpec(resourcebuilder.AWSProviderSpec().WithInstanceType("m6i.large"))
+				newTemplate := templateWithProviderSpec(resourcebuilder.AWSProviderSpec().WithInstanceType("m6i.large").WithAvailabilityZone("us-east-1b"))
+
+				Expect(wh.validateTemplateSize(templatePath, &oldTemplate, newTemplate)).To(BeEmpty())
+			})
+
+			It("rejects an update to an undersized instance type", func() {
+				oldTemplate := templateWithProviderSpec(resourcebuilder.AWSProviderSpec().WithInstanceType("m6i.xlarge"))
+				newTemplate := templateWithProviderSpec(resourcebuilder.AWSProviderSpec().WithInstanceType("m6i.large"))
+
+				Expect(wh.validateTemplateSize(templatePath, &oldTemplate, newTemplate)).To(HaveLen(1))
+			})
+		})
+	})
+
+	DescribeTable("instance type sizes", func(sizeOf func(string) (machineSize, bool), instanceType string, expected machineSize, expectedOK bool) {
+		size, ok := sizeOf(instanceType)
+		Expect(ok).To(Equal(expectedOK))
+		Expect(size).To(Equal(expected))
+	},
+		Entry("with an AWS xlarge instance type", awsInstanceTypeSize, "m6i.xlarge", machineSize{vCPUs: 4, memoryMiB: 16384}, true),
+		Entry("with an AWS 2xlarge compute optimised instance type", awsInstanceTypeSize, "c5.2xlarge", machineSize{vCPUs: 8, memoryMiB: 16384}, true),
+		Entry("with an AWS instance type of an unknown class", awsInstanceTypeSize, "i3.xlarge", machineSize{}, false),
+		Entry("with an AWS metal instance type", awsInstanceTypeSize, "m5.metal", machineSize{}, false),
+		Entry("with an Azure VM size", azureVMSizeSize, "Standard_E8as_v4", machineSize{vCPUs: 8, memoryMiB: 65536}, true),
+		Entry("with an Azure VM size of an unknown series", azureVMSizeSize, "Standard_DS2_v2", machineSize{}, false),
+		Entry("with a GCP machine type", gcpMachineTypeSize, "n1-highcpu-8", machineSize{vCPUs: 8, memoryMiB: 7368}, true),
+		Entry("with a GCP custom machine type", gcpMachineTypeSize, "n2-custom-4-12288", machineSize{vCPUs: 4, memoryMiB: 12288}, true),
+		Entry("with a GCP machine type of an unknown family", gcpMachineTypeSize, "a2-highgpu-1g", machineSize{}, false),
+	)
+
+	Context("on update on GCP", func() {
+		var cpms *machinev1.ControlPlaneMachineSet
+
+		BeforeEach(func() {
+			providerSpec := resourcebuilder.GCPProviderSpec().WithExtraDisks(resourcebuilder.GCPDisk().WithSizeGB(100))
+			// The failure domain package does not yet support GCP.
+			machineTemplate := resourcebuilder.OpenShiftMachineV1Beta1Template().WithFailureDomainsBuilder(nil).WithProviderSpecBuilder(providerSpec)
+			cpms = resourcebuilder.ControlPlaneMachineSet().WithNamespace(namespaceName).WithMachineTemplateBuilder(machineTemplate).Build()
+
+			By("Creating a valid ControlPlaneMachineSet")
+			Expect(k8sClient.Create(ctx, cpms)).To(Succeed())
+		})
+
+		It("with a change to the disk type and an increase to the disk sizes", func() {
+			rawProviderSpec := resourcebuilder.GCPProviderSpec().
+				WithBootDisk(resourcebuilder.GCPDisk().WithType("pd-balanced").WithSizeGB(256)).
+				WithExtraDisks(resourcebuilder.GCPDisk().WithSizeGB(200)).
+				BuildRawExtension()
+
+			Eventually(komega.Update(cpms, func() {
+				cpms.Spec.Template.OpenShiftMachineV1Beta1Machine.Spec.ProviderSpec.Value = rawProviderSpec
+			})).Should(Succeed(), "Disk type changes and size increases should be allowed")
+		})
+
+		It("with a decrease to the boot disk size", func() {
+			rawProviderSpec := resourcebuilder.GCPProviderSpec().
+				WithBootDisk(resourcebuilder.GCPDisk().WithSizeGB(64)).
+				WithExtraDisks(resourcebuilder.GCPDisk().WithSizeGB(100)).
+				BuildRawExtension()
+
+			Eventually(komega.Update(cpms, func() {
+				cpms.Spec.Template.OpenShiftMachineV1Beta1Machine.Spec.ProviderSpec.Value = rawProviderSpec
+			})).Should(MatchError(ContainSubstring(
+				"spec.template.machines_v1beta1_machine_openshift_io.spec.providerSpec.value.disks[0].sizeGb: Forbidden: disk size cannot be decreased from 128GB to 64GB",
+			)), "Disk size decreases should be rejected")
+		})
+
+		It("with a decrease to the size of an additional disk", func() {
+			rawProviderSpec := resourcebuilder.GCPProviderSpec().
+				WithExtraDisks(resourcebuilder.GCPDisk().WithSizeGB(50)).
+				BuildRawExtension()
+
+			Eventually(komega.Update(cpms, func() {
+				cpms.Spec.Template.OpenShiftMachineV1Beta1Machine.Spec.ProviderSpec.Value = rawProviderSpec
+			})).Should(MatchError(ContainSubstring(
+				"disks[1].sizeGb: Forbidden: disk size cannot be decreased from 100GB to 50GB",
+			)), "Disk size decreases should be rejected")
+		})
+	})
 })